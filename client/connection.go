@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"net/http"
@@ -69,16 +70,28 @@ type ConnectionArgs struct {
 //
 // Unless the remote server is trusted by the system CA, the remote certificate must be provided (TLSServerCert).
 func ConnectLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
+	return ConnectLXDWithContext(context.Background(), url, args)
+}
+
+// ConnectLXDWithContext lets you connect to a remote LXD daemon over HTTPs with context.Context.
+//
+// The context is used for all the requests made through the returned client, use UseContext to change it.
+func ConnectLXDWithContext(ctx context.Context, url string, args *ConnectionArgs) (InstanceServer, error) {
 	logger.Debugf("Connecting to a remote LXD over HTTPs")
 
 	// Cleanup URL
 	url = strings.TrimSuffix(url, "/")
 
-	return httpsLXD(url, args)
+	return httpsLXD(ctx, url, args)
 }
 
 // ConnectLXDHTTP lets you connect to a VM agent over a VM socket.
 func ConnectLXDHTTP(args *ConnectionArgs, client *http.Client) (InstanceServer, error) {
+	return ConnectLXDHTTPWithContext(context.Background(), args, client)
+}
+
+// ConnectLXDHTTPWithContext lets you connect to a VM agent over a VM socket with context.Context.
+func ConnectLXDHTTPWithContext(ctx context.Context, args *ConnectionArgs, client *http.Client) (InstanceServer, error) {
 	logger.Debugf("Connecting to a VM agent over a VM socket")
 
 	// Use empty args if not specified
@@ -88,6 +101,7 @@ func ConnectLXDHTTP(args *ConnectionArgs, client *http.Client) (InstanceServer,
 
 	// Initialize the client struct
 	server := ProtocolLXD{
+		ctx:           ctx,
 		httpHost:      "https://custom.socket",
 		httpProtocol:  "custom",
 		httpUserAgent: args.UserAgent,
@@ -117,6 +131,11 @@ func ConnectLXDHTTP(args *ConnectionArgs, client *http.Client) (InstanceServer,
 // unset $LXD_DIR/unix.socket will be used and if that one isn't set
 // either, then the path will default to /var/lib/lxd/unix.socket.
 func ConnectLXDUnix(path string, args *ConnectionArgs) (InstanceServer, error) {
+	return ConnectLXDUnixWithContext(context.Background(), path, args)
+}
+
+// ConnectLXDUnixWithContext lets you connect to a remote LXD daemon over a local unix socket with context.Context.
+func ConnectLXDUnixWithContext(ctx context.Context, path string, args *ConnectionArgs) (InstanceServer, error) {
 	logger.Debugf("Connecting to a local LXD over a Unix socket")
 
 	// Use empty args if not specified
@@ -126,6 +145,7 @@ func ConnectLXDUnix(path string, args *ConnectionArgs) (InstanceServer, error) {
 
 	// Initialize the client struct
 	server := ProtocolLXD{
+		ctx:           ctx,
 		httpHost:      "http://unix.socket",
 		httpUnixPath:  path,
 		httpProtocol:  "unix",
@@ -173,12 +193,17 @@ func ConnectLXDUnix(path string, args *ConnectionArgs) (InstanceServer, error) {
 //
 // Unless the remote server is trusted by the system CA, the remote certificate must be provided (TLSServerCert).
 func ConnectPublicLXD(url string, args *ConnectionArgs) (ImageServer, error) {
+	return ConnectPublicLXDWithContext(context.Background(), url, args)
+}
+
+// ConnectPublicLXDWithContext lets you connect to a remote public LXD daemon over HTTPs with context.Context.
+func ConnectPublicLXDWithContext(ctx context.Context, url string, args *ConnectionArgs) (ImageServer, error) {
 	logger.Debugf("Connecting to a remote public LXD over HTTPS")
 
 	// Cleanup URL
 	url = strings.TrimSuffix(url, "/")
 
-	return httpsLXD(url, args)
+	return httpsLXD(ctx, url, args)
 }
 
 // ConnectSimpleStreams lets you connect to a remote SimpleStreams image server over HTTPs.
@@ -241,7 +266,7 @@ func ConnectSimpleStreams(url string, args *ConnectionArgs) (ImageServer, error)
 }
 
 // Internal function called by ConnectLXD and ConnectPublicLXD
-func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
+func httpsLXD(ctx context.Context, url string, args *ConnectionArgs) (InstanceServer, error) {
 	// Use empty args if not specified
 	if args == nil {
 		args = &ConnectionArgs{}
@@ -249,6 +274,7 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 
 	// Initialize the client struct
 	server := ProtocolLXD{
+		ctx:              ctx,
 		httpCertificate:  args.TLSServerCert,
 		httpHost:         url,
 		httpProtocol:     "https",
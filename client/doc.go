@@ -142,4 +142,36 @@
 //  if err != nil {
 //    return err
 //  }
+//
+// Example - timeouts and cancellation
+//
+// This stops a container, giving up after a minute
+//
+//  // Connect to LXD over the Unix socket
+//  c, err := lxd.ConnectLXDUnix("", nil)
+//  if err != nil {
+//    return err
+//  }
+//
+//  ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//  defer cancel()
+//
+//  // Requests made through the returned client use the context
+//  reqState := api.InstanceStatePut{
+//    Action: "stop",
+//    Timeout: -1,
+//  }
+//
+//  op, err := c.UseContext(ctx).UpdateInstanceState("my-container", reqState, "")
+//  if err != nil {
+//    return err
+//  }
+//
+//  // Wait for the operation to complete or the context to expire
+//  err = op.WaitContext(ctx)
+//  if err != nil {
+//    // The operation keeps running on the server unless cancelled
+//    op.Cancel()
+//    return err
+//  }
 package lxd
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -14,6 +17,9 @@ type EventListener struct {
 	disconnected bool
 	err          error
 
+	// Connection dedicated to this listener (nil when sharing the connection of the client).
+	conn *websocket.Conn
+
 	targets     []*EventTarget
 	targetsLock sync.Mutex
 }
@@ -69,6 +75,20 @@ func (e *EventListener) RemoveHandler(target *EventTarget) error {
 	return fmt.Errorf("Couldn't find this function and event types combination")
 }
 
+// dispatch calls the handlers of the listener interested in the event.
+func (e *EventListener) dispatch(event api.Event) {
+	e.targetsLock.Lock()
+	defer e.targetsLock.Unlock()
+
+	for _, target := range e.targets {
+		if target.types != nil && !shared.StringInSlice(event.Type, target.types) {
+			continue
+		}
+
+		go target.function(event)
+	}
+}
+
 // Disconnect must be used once done listening for events
 func (e *EventListener) Disconnect() {
 	if e.disconnected {
@@ -89,6 +109,11 @@ func (e *EventListener) Disconnect() {
 		}
 	}
 
+	// Close the dedicated connection
+	if e.conn != nil {
+		e.conn.Close()
+	}
+
 	// Turn off the handler
 	e.err = nil
 	e.disconnected = true
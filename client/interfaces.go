@@ -1,8 +1,10 @@
 package lxd
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -20,6 +22,7 @@ type Operation interface {
 	RemoveHandler(target *EventTarget) (err error)
 	Refresh() (err error)
 	Wait() (err error)
+	WaitContext(ctx context.Context) (err error)
 }
 
 // The RemoteOperation type represents an Operation that may be using multiple servers.
@@ -28,6 +31,7 @@ type RemoteOperation interface {
 	CancelTarget() (err error)
 	GetTarget() (op *api.Operation, err error)
 	Wait() (err error)
+	WaitContext(ctx context.Context) (err error)
 }
 
 // The Server type represents a generic read-only server.
@@ -69,12 +73,15 @@ type InstanceServer interface {
 	// Server functions
 	GetServer() (server *api.Server, ETag string, err error)
 	GetServerResources() (resources *api.Resources, err error)
+	GetServerIdmap() (idmap *api.Idmap, err error)
+	GetDeprecatedConfigKeys() (keys []api.DeprecatedConfigKey, err error)
 	UpdateServer(server api.ServerPut, ETag string) (err error)
 	HasExtension(extension string) (exists bool)
 	RequireAuthenticated(authenticated bool)
 	IsClustered() (clustered bool)
 	UseTarget(name string) (client InstanceServer)
 	UseProject(name string) (client InstanceServer)
+	UseContext(ctx context.Context) (client InstanceServer)
 
 	// Certificate functions
 	GetCertificateFingerprints() (fingerprints []string, err error)
@@ -144,6 +151,7 @@ type InstanceServer interface {
 	// Instance functions.
 	GetInstanceNames(instanceType api.InstanceType) (names []string, err error)
 	GetInstances(instanceType api.InstanceType) (instances []api.Instance, err error)
+	GetInstancesWithFilter(instanceType api.InstanceType, filters []string) (instances []api.Instance, err error)
 	GetInstancesFull(instanceType api.InstanceType) (instances []api.InstanceFull, err error)
 	GetInstance(name string) (instance *api.Instance, ETag string, err error)
 	CreateInstance(instance api.InstancesPost) (op Operation, err error)
@@ -160,11 +168,15 @@ type InstanceServer interface {
 	ConsoleInstanceDynamic(instanceName string, console api.InstanceConsolePost, args *InstanceConsoleArgs) (Operation, func(io.ReadWriteCloser) error, error)
 
 	GetInstanceConsoleLog(instanceName string, args *InstanceConsoleLogArgs) (content io.ReadCloser, err error)
+	GetInstanceConsoleLogEntries(instanceName string, args *InstanceConsoleLogArgs) (entries []api.InstanceConsoleLogEntry, err error)
 	DeleteInstanceConsoleLog(instanceName string, args *InstanceConsoleLogArgs) (err error)
 
 	GetInstanceFile(instanceName string, path string) (content io.ReadCloser, resp *InstanceFileResponse, err error)
 	CreateInstanceFile(instanceName string, path string, args InstanceFileArgs) (err error)
 	DeleteInstanceFile(instanceName string, path string) (err error)
+	GetInstanceFileRecursive(instanceName string, path string) (content io.ReadCloser, err error)
+	CreateInstanceFileRecursive(instanceName string, path string, content io.Reader) (err error)
+	WatchInstanceFiles(instanceName string, watch api.InstanceFileWatchPost, handler func(event api.InstanceFileEvent)) (op Operation, err error)
 
 	GetInstanceSnapshotNames(instanceName string) (names []string, err error)
 	GetInstanceSnapshots(instanceName string) (snapshots []api.InstanceSnapshot, err error)
@@ -195,6 +207,17 @@ type InstanceServer interface {
 	GetInstanceMetadata(name string) (metadata *api.ImageMetadata, ETag string, err error)
 	UpdateInstanceMetadata(name string, metadata api.ImageMetadata, ETag string) (err error)
 
+	GetInstanceNVRAM(name string) (nvram *api.InstanceNVRAM, ETag string, err error)
+	UpdateInstanceNVRAM(name string, nvram api.InstanceNVRAMPut, ETag string) (err error)
+	ResetInstanceNVRAM(name string) (err error)
+
+	GetInstanceMachineType(name string) (machineType *api.InstanceMachineType, err error)
+	UpgradeInstanceMachineType(name string, machineType api.InstanceMachineTypePost) (err error)
+
+	GetInstanceProcesses(name string, sortBy string, limit int) (top *api.InstanceProcesses, err error)
+	GetInstanceSEV(name string) (sev *api.InstanceSEV, err error)
+	GetInstanceSEVReport(name string, req api.InstanceSEVReportPost) (report *api.InstanceSEVReport, err error)
+
 	GetInstanceTemplateFiles(instanceName string) (templates []string, err error)
 	GetInstanceTemplateFile(instanceName string, templateName string) (content io.ReadCloser, err error)
 	CreateInstanceTemplateFile(instanceName string, templateName string, content io.ReadSeeker) (err error)
@@ -202,8 +225,10 @@ type InstanceServer interface {
 
 	// Event handling functions
 	GetEvents() (listener *EventListener, err error)
+	GetInstanceEvents(name string, types []string) (listener *EventListener, err error)
 
 	// Image functions
+	GetImagesWithFilter(filters []string) (images []api.Image, err error)
 	CreateImage(image api.ImagesPost, args *ImageCreateArgs) (op Operation, err error)
 	CopyImage(source ImageServer, image api.Image, args *ImageCopyArgs) (op RemoteOperation, err error)
 	UpdateImage(fingerprint string, image api.ImagePut, ETag string) (err error)
@@ -218,9 +243,11 @@ type InstanceServer interface {
 	// Network functions ("network" API extension)
 	GetNetworkNames() (names []string, err error)
 	GetNetworks() (networks []api.Network, err error)
+	GetNetworksWithFilter(filters []string) (networks []api.Network, err error)
 	GetNetwork(name string) (network *api.Network, ETag string, err error)
 	GetNetworkLeases(name string) (leases []api.NetworkLease, err error)
 	GetNetworkState(name string) (state *api.NetworkState, err error)
+	GetNetworkFirewall(name string) (firewall *api.NetworkFirewall, err error)
 	CreateNetwork(network api.NetworksPost) (err error)
 	UpdateNetwork(name string, network api.NetworkPut, ETag string) (err error)
 	RenameNetwork(name string, network api.NetworkPost) (err error)
@@ -258,6 +285,7 @@ type InstanceServer interface {
 	GetProjects() (projects []api.Project, err error)
 	GetProject(name string) (project *api.Project, ETag string, err error)
 	GetProjectState(name string) (project *api.ProjectState, err error)
+	GetProjectUsage(name string, start time.Time, end time.Time, interval string) (usage *api.ProjectUsage, err error)
 	CreateProject(project api.ProjectsPost) (err error)
 	UpdateProject(name string, project api.ProjectPut, ETag string) (err error)
 	RenameProject(name string, project api.ProjectPost) (op Operation, err error)
@@ -270,11 +298,13 @@ type InstanceServer interface {
 	GetStoragePoolResources(name string) (resources *api.ResourcesStoragePool, err error)
 	CreateStoragePool(pool api.StoragePoolsPost) (err error)
 	UpdateStoragePool(name string, pool api.StoragePoolPut, ETag string) (err error)
+	UpdateStoragePoolSource(name string, req api.StoragePoolSourcePost) (op Operation, err error)
 	DeleteStoragePool(name string) (err error)
 
 	// Storage volume functions ("storage" API extension)
 	GetStoragePoolVolumeNames(pool string) (names []string, err error)
 	GetStoragePoolVolumes(pool string) (volumes []api.StorageVolume, err error)
+	GetStoragePoolVolumesWithFilter(pool string, filters []string) (volumes []api.StorageVolume, err error)
 	GetStoragePoolVolume(pool string, volType string, name string) (volume *api.StorageVolume, ETag string, err error)
 	GetStoragePoolVolumeState(pool string, volType string, name string) (state *api.StorageVolumeState, err error)
 	CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) (err error)
@@ -303,6 +333,7 @@ type InstanceServer interface {
 	DeleteStoragePoolVolumeBackup(pool string, volName string, name string) (op Operation, err error)
 	GetStoragePoolVolumeBackupFile(pool string, volName string, name string, req *BackupFileRequest) (resp *BackupFileResponse, err error)
 	CreateStoragePoolVolumeFromBackup(pool string, args StoragePoolVolumeBackupArgs) (op Operation, err error)
+	CreateStoragePoolVolumeFromISO(pool string, args StoragePoolVolumeBackupArgs) (op Operation, err error)
 
 	// Cluster functions ("cluster" API extensions)
 	GetCluster() (cluster *api.Cluster, ETag string, err error)
@@ -316,6 +347,11 @@ type InstanceServer interface {
 	CreateClusterMember(member api.ClusterMembersPost) (op Operation, err error)
 	UpdateClusterCertificate(certs api.ClusterCertificatePut, ETag string) (err error)
 	UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (op Operation, err error)
+	GetClusterFailureDomainNames() (names []string, err error)
+	GetClusterFailureDomain(name string) (domain *api.ClusterFailureDomain, ETag string, err error)
+	UpdateClusterFailureDomain(name string, domain api.ClusterFailureDomainPut, ETag string) (err error)
+	GetClusterUpgrade() (upgrade *api.ClusterUpgrade, err error)
+	UpgradeCluster(upgrade api.ClusterUpgradePost) (op Operation, err error)
 
 	// Warning functions
 	GetWarningUUIDs() (uuids []string, err error)
@@ -396,6 +432,10 @@ type ImageFileRequest struct {
 	// Path retriever for image delta downloads
 	// If set, it must return the path to the image file or an empty string if not available
 	DeltaSourceRetriever func(fingerprint string, file string) string
+
+	// Fingerprint of a base image to export the image as a delta of
+	// If set, a split image whose root filesystem only contains the changes from the base image is returned
+	BaseImage string
 }
 
 // The ImageFileResponse struct is used as the response for image downloads.
@@ -451,9 +491,12 @@ type StoragePoolVolumeCopyArgs struct {
 // during storage volume move.
 type StoragePoolVolumeMoveArgs struct {
 	StoragePoolVolumeCopyArgs
+
+	// API extension: storage_volume_move_location
+	Location string
 }
 
-// The StoragePoolVolumeBackupArgs struct is used when creating a storage volume from a backup.
+// The StoragePoolVolumeBackupArgs struct is used when creating a storage volume from a backup or an ISO image.
 // API extension: custom_volume_backup
 type StoragePoolVolumeBackupArgs struct {
 	// The backup file
@@ -523,6 +566,8 @@ type InstanceConsoleArgs struct {
 // The InstanceConsoleLogArgs struct is used to pass additional options during a
 // instance console log request.
 type InstanceConsoleLogArgs struct {
+	// Only retrieve the output captured at or after this time (requires console_log_history)
+	Since time.Time
 }
 
 // The InstanceExecArgs struct is used to pass additional options during instance exec.
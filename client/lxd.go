@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ import (
 
 // ProtocolLXD represents a LXD API server
 type ProtocolLXD struct {
+	ctx         context.Context
 	server      *api.Server
 	chConnected chan struct{}
 
@@ -101,6 +103,9 @@ func (r *ProtocolLXD) do(req *http.Request) (*http.Response, error) {
 		req.Header.Set("User-Agent", r.httpUserAgent)
 	}
 
+	// Tie the request to the context of the client
+	req = req.WithContext(r.getContext())
+
 	// Send the request through
 	if r.bakeryClient != nil {
 		r.addMacaroonHeaders(req)
@@ -118,6 +123,15 @@ func (r *ProtocolLXD) addMacaroonHeaders(req *http.Request) {
 	}
 }
 
+// getContext returns the context used for the requests made by the client.
+func (r *ProtocolLXD) getContext() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
 // RequireAuthenticated sets whether we expect to be authenticated with the server
 func (r *ProtocolLXD) RequireAuthenticated(authenticated bool) {
 	r.requireAuthenticated = authenticated
@@ -377,7 +391,7 @@ func (r *ProtocolLXD) rawWebsocket(url string) (*websocket.Conn, error) {
 	}
 
 	// Establish the connection
-	conn, _, err := dialer.Dial(url, headers)
+	conn, _, err := dialer.DialContext(r.getContext(), url, headers)
 	if err != nil {
 		return nil, err
 	}
@@ -191,3 +191,83 @@ func (r *ProtocolLXD) UpdateClusterMemberState(name string, state api.ClusterMem
 
 	return op, nil
 }
+
+// GetClusterFailureDomainNames returns the names of the failure domains of the cluster members
+func (r *ProtocolLXD) GetClusterFailureDomainNames() ([]string, error) {
+	if !r.HasExtension("clustering_failure_domains_config") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_failure_domains_config\" API extension")
+	}
+
+	urls := []string{}
+	_, err := r.queryStruct("GET", "/cluster/failure-domains", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/cluster/failure-domains/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetClusterFailureDomain returns information about the given failure domain
+func (r *ProtocolLXD) GetClusterFailureDomain(name string) (*api.ClusterFailureDomain, string, error) {
+	if !r.HasExtension("clustering_failure_domains_config") {
+		return nil, "", fmt.Errorf("The server is missing the required \"clustering_failure_domains_config\" API extension")
+	}
+
+	domain := api.ClusterFailureDomain{}
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/cluster/failure-domains/%s", name), nil, "", &domain)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &domain, etag, nil
+}
+
+// UpdateClusterFailureDomain updates the configuration overrides of the given failure domain
+func (r *ProtocolLXD) UpdateClusterFailureDomain(name string, domain api.ClusterFailureDomainPut, ETag string) error {
+	if !r.HasExtension("clustering_failure_domains_config") {
+		return fmt.Errorf("The server is missing the required \"clustering_failure_domains_config\" API extension")
+	}
+
+	_, _, err := r.query("PUT", fmt.Sprintf("/cluster/failure-domains/%s", name), domain, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetClusterUpgrade returns the versions run by the cluster members
+func (r *ProtocolLXD) GetClusterUpgrade() (*api.ClusterUpgrade, error) {
+	if !r.HasExtension("clustering_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_upgrade\" API extension")
+	}
+
+	upgrade := api.ClusterUpgrade{}
+	_, err := r.queryStruct("GET", "/cluster/upgrade", nil, "", &upgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upgrade, nil
+}
+
+// UpgradeCluster upgrades the cluster members one after the other
+func (r *ProtocolLXD) UpgradeCluster(upgrade api.ClusterUpgradePost) (Operation, error) {
+	if !r.HasExtension("clustering_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_upgrade\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/upgrade", upgrade, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
@@ -1766,7 +1766,7 @@ func (r *ProtocolLXD) GetContainerBackupFile(containerName string, name string,
 	}
 
 	// Start the request
-	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request)
+	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request.WithContext(r.getContext()))
 	if err != nil {
 		return nil, err
 	}
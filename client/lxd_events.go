@@ -2,9 +2,11 @@ package lxd
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
-	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -106,17 +108,79 @@ func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
 			// Send the message to all handlers
 			r.eventListenersLock.Lock()
 			for _, listener := range r.eventListeners {
-				listener.targetsLock.Lock()
-				for _, target := range listener.targets {
-					if target.types != nil && !shared.StringInSlice(event.Type, target.types) {
-						continue
-					}
+				listener.dispatch(event)
+			}
+			r.eventListenersLock.Unlock()
+		}
+	}()
+
+	return &listener, nil
+}
 
-					go target.function(event)
+// GetInstanceEvents connects to the LXD monitoring interface for the events related to an instance only,
+// including the lines logged by its lxc or qemu process. The types to listen for can be restricted
+// (all types when empty).
+func (r *ProtocolLXD) GetInstanceEvents(name string, types []string) (*EventListener, error) {
+	if !r.HasExtension("events_instance_logging") {
+		return nil, fmt.Errorf("The server is missing the required \"events_instance_logging\" API extension")
+	}
+
+	values := url.Values{}
+	values.Set("instance", name)
+	if len(types) > 0 {
+		values.Set("type", strings.Join(types, ","))
+	}
+
+	// Setup a new connection with LXD, dedicated to this listener as filtered server side.
+	uri, err := r.setQueryAttributes(fmt.Sprintf("/events?%s", values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.websocket(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := EventListener{
+		r:        r,
+		chActive: make(chan bool),
+		conn:     conn,
+	}
+
+	// Spawn the listener
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				r.eventListenersLock.Lock()
+				defer r.eventListenersLock.Unlock()
+
+				// Tell the listener about the failure, unless disconnected already.
+				if !listener.disconnected {
+					listener.err = err
+					listener.disconnected = true
+					close(listener.chActive)
 				}
-				listener.targetsLock.Unlock()
+
+				conn.Close()
+
+				return
 			}
-			r.eventListenersLock.Unlock()
+
+			// Attempt to unpack the message
+			event := api.Event{}
+			err = json.Unmarshal(data, &event)
+			if err != nil {
+				continue
+			}
+
+			// Extract the message type
+			if event.Type == "" {
+				continue
+			}
+
+			listener.dispatch(event)
 		}
 	}()
 
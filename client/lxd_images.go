@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -33,6 +34,26 @@ func (r *ProtocolLXD) GetImages() ([]api.Image, error) {
 	return images, nil
 }
 
+// GetImagesWithFilter returns a filtered list of available images as Image structs.
+func (r *ProtocolLXD) GetImagesWithFilter(filters []string) ([]api.Image, error) {
+	if !r.HasExtension("api_filtering") {
+		return nil, fmt.Errorf("The server is missing the required \"api_filtering\" API extension")
+	}
+
+	images := []api.Image{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	v.Set("filter", filterParam(filters))
+
+	_, err := r.queryStruct("GET", fmt.Sprintf("/images?%s", v.Encode()), nil, "", &images)
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
 // GetImageFingerprints returns a list of available image fingerprints
 func (r *ProtocolLXD) GetImageFingerprints() ([]string, error) {
 	urls := []string{}
@@ -117,6 +138,17 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		return nil, err
 	}
 
+	if req.BaseImage != "" {
+		if !r.HasExtension("image_delta_export") {
+			return nil, fmt.Errorf("The server is missing the required \"image_delta_export\" API extension")
+		}
+
+		uri, err = setQueryParam(uri, "base", req.BaseImage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Attempt to download from host
 	if secret == "" && shared.PathExists("/dev/lxd/sock") && os.Geteuid() == 0 {
 		unixURI := fmt.Sprintf("http://unix.socket%s", uri)
@@ -124,7 +156,7 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		// Setup the HTTP client
 		devlxdHTTP, err := unixHTTPClient(nil, "/dev/lxd/sock")
 		if err == nil {
-			resp, err := lxdDownloadImage(fingerprint, unixURI, r.httpUserAgent, devlxdHTTP, req)
+			resp, err := lxdDownloadImage(r.getContext(), fingerprint, unixURI, r.httpUserAgent, devlxdHTTP, req)
 			if err == nil {
 				return resp, nil
 			}
@@ -140,15 +172,15 @@ func (r *ProtocolLXD) GetPrivateImageFile(fingerprint string, secret string, req
 		}
 	}
 
-	return lxdDownloadImage(fingerprint, uri, r.httpUserAgent, r.http, req)
+	return lxdDownloadImage(r.getContext(), fingerprint, uri, r.httpUserAgent, r.http, req)
 }
 
-func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *http.Client, req ImageFileRequest) (*ImageFileResponse, error) {
+func lxdDownloadImage(ctx context.Context, fingerprint string, uri string, userAgent string, client *http.Client, req ImageFileRequest) (*ImageFileResponse, error) {
 	// Prepare the response
 	resp := ImageFileResponse{}
 
 	// Prepare the download request
-	request, err := http.NewRequest("GET", uri, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +236,14 @@ func lxdDownloadImage(fingerprint string, uri string, userAgent string, client *
 	// Hashing
 	sha256 := sha256.New()
 
+	// Image deltas don't match the fingerprint of the image, check against the one provided by the server.
+	if req.BaseImage != "" {
+		fingerprint = response.Header.Get("X-LXD-fingerprint")
+		if fingerprint == "" {
+			return nil, fmt.Errorf("Missing fingerprint of the image delta")
+		}
+	}
+
 	// Deal with split images
 	if ctype == "multipart/form-data" {
 		if req.MetaFile == nil || req.RootfsFile == nil {
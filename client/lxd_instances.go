@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -91,6 +93,31 @@ func (r *ProtocolLXD) GetInstances(instanceType api.InstanceType) ([]api.Instanc
 	return instances, nil
 }
 
+// GetInstancesWithFilter returns a filtered list of instances.
+func (r *ProtocolLXD) GetInstancesWithFilter(instanceType api.InstanceType, filters []string) ([]api.Instance, error) {
+	if !r.HasExtension("api_filtering") {
+		return nil, fmt.Errorf("The server is missing the required \"api_filtering\" API extension")
+	}
+
+	instances := []api.Instance{}
+
+	path, v, err := r.instanceTypeToPath(instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Set("recursion", "1")
+	v.Set("filter", filterParam(filters))
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s?%s", path, v.Encode()), nil, "", &instances)
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
 // UpdateInstances updates all instances to match the requested state.
 func (r *ProtocolLXD) UpdateInstances(state api.InstancesPut, ETag string) (Operation, error) {
 	path, v, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -242,6 +269,12 @@ func (r *ProtocolLXD) CreateInstance(instance api.InstancesPost) (Operation, err
 		}
 	}
 
+	if instance.Source.Type == "conversion" {
+		if !r.HasExtension("instance_conversion") {
+			return nil, fmt.Errorf("The server is missing the required \"instance_conversion\" API extension")
+		}
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", path, instance, "")
 	if err != nil {
@@ -704,6 +737,10 @@ func (r *ProtocolLXD) MigrateInstance(name string, instance api.InstancePost) (O
 		return nil, fmt.Errorf("The server is missing the required \"instance_pool_move\" API extension")
 	}
 
+	if instance.Project != "" && !r.HasExtension("instance_project_move") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_project_move\" API extension")
+	}
+
 	// Quick check.
 	if !instance.Migration {
 		return nil, fmt.Errorf("Can't ask for a rename through MigrateInstance")
@@ -748,6 +785,12 @@ func (r *ProtocolLXD) ExecInstance(instanceName string, exec api.InstanceExecPos
 		}
 	}
 
+	if exec.ResumeTimeout > 0 {
+		if !r.HasExtension("instance_exec_resume") {
+			return nil, fmt.Errorf("The server is missing the required \"instance_exec_resume\" API extension")
+		}
+	}
+
 	var uri string
 
 	if r.IsAgent() {
@@ -800,6 +843,11 @@ func (r *ProtocolLXD) ExecInstance(instanceName string, exec api.InstanceExecPos
 					return nil, err
 				}
 
+				if exec.ResumeTimeout > 0 {
+					go r.execResumable(op, fds, conn, args, time.Duration(exec.ResumeTimeout)*time.Second)
+					return op, nil
+				}
+
 				// And attach stdin and stdout to it
 				go func() {
 					shared.WebsocketSendStream(conn, args.Stdin, -1)
@@ -890,6 +938,115 @@ func (r *ProtocolLXD) ExecInstance(instanceName string, exec api.InstanceExecPos
 	return op, nil
 }
 
+// execResumable attaches stdin and stdout to the data websocket of an interactive session, reconnecting the data
+// and control websockets when the connection drops until the operation is gone or the resume timeout expires.
+func (r *ProtocolLXD) execResumable(op Operation, fds map[string]string, conn *websocket.Conn, args *InstanceExecArgs, timeout time.Duration) {
+	if args.DataDone != nil {
+		defer close(args.DataDone)
+	}
+
+	in := shared.ReaderToChannel(args.Stdin, -1)
+	var pending []byte
+
+	for {
+		keepaliveDone := make(chan struct{})
+		shared.WebsocketKeepalive(conn, 10*time.Second, keepaliveDone)
+
+		chOutput := make(chan error, 1)
+		go func(conn *websocket.Conn) {
+			chOutput <- execRecvOutput(conn, args.Stdout)
+		}(conn)
+
+		for {
+			if pending != nil {
+				err := conn.WriteMessage(websocket.BinaryMessage, pending)
+				if err != nil {
+					break
+				}
+
+				pending = nil
+			}
+
+			var err error
+			select {
+			case buf, ok := <-in:
+				if ok {
+					pending = buf
+					continue
+				}
+
+				// Let the server know the input reached EOF.
+				in = nil
+				err = conn.WriteMessage(websocket.TextMessage, []byte{})
+				if err == nil {
+					continue
+				}
+			case err = <-chOutput:
+				if err == nil {
+					// The session is over.
+					close(keepaliveDone)
+					conn.Close()
+					return
+				}
+			}
+
+			break
+		}
+
+		close(keepaliveDone)
+		conn.Close()
+
+		// Reconnect until the operation is gone or the timeout expires.
+		deadline := time.Now().Add(timeout)
+		for {
+			var err error
+			conn, err = r.GetOperationWebsocket(op.Get().ID, fds["0"])
+			if err == nil {
+				break
+			}
+
+			refreshErr := op.Refresh()
+			if time.Now().After(deadline) || (refreshErr == nil && op.Get().StatusCode.IsFinal()) {
+				return
+			}
+
+			time.Sleep(time.Second)
+		}
+
+		if args.Control != nil && fds["control"] != "" {
+			control, err := r.GetOperationWebsocket(op.Get().ID, fds["control"])
+			if err == nil {
+				go args.Control(control)
+			}
+		}
+	}
+}
+
+// execRecvOutput writes the data received on the websocket to w. It returns nil once the server sent all the
+// output and an error if the websocket failed.
+func execRecvOutput(conn *websocket.Conn, w io.Writer) error {
+	for {
+		mt, r, err := conn.NextReader()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+
+			return err
+		}
+
+		// A text message is the barrier sent once the process is gone.
+		if mt == websocket.TextMessage {
+			return nil
+		}
+
+		_, err = io.Copy(w, r)
+		if err != nil {
+			return nil
+		}
+	}
+}
+
 // GetInstanceFile retrieves the provided path from the instance.
 func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.ReadCloser, *InstanceFileResponse, error) {
 	var err error
@@ -1088,6 +1245,156 @@ func (r *ProtocolLXD) DeleteInstanceFile(instanceName string, filePath string) e
 	return nil
 }
 
+// GetInstanceFileRecursive retrieves the tree at the given path of the instance as a tarball.
+func (r *ProtocolLXD) GetInstanceFileRecursive(instanceName string, filePath string) (io.ReadCloser, error) {
+	if !r.HasExtension("instance_files_recursive") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_files_recursive\" API extension")
+	}
+
+	requestURL, err := r.instanceFileRecursiveURL(instanceName, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	resp, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the return value for a cleaner error
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Body, nil
+}
+
+// CreateInstanceFileRecursive extracts the tarball read from content into the given directory of the instance.
+func (r *ProtocolLXD) CreateInstanceFileRecursive(instanceName string, filePath string, content io.Reader) error {
+	if !r.HasExtension("instance_files_recursive") {
+		return fmt.Errorf("The server is missing the required \"instance_files_recursive\" API extension")
+	}
+
+	requestURL, err := r.instanceFileRecursiveURL(instanceName, filePath)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", requestURL, content)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-tar")
+
+	// Send the request
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+
+	// Check the return value for a cleaner error
+	_, _, err = lxdParseResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// instanceFileRecursiveURL returns the URL of the recursive file API for the given path of the instance.
+func (r *ProtocolLXD) instanceFileRecursiveURL(instanceName string, filePath string) (string, error) {
+	var requestURL string
+
+	if r.IsAgent() {
+		requestURL = fmt.Sprintf("%s/1.0/files?path=%s&recursive=1", r.httpHost, url.QueryEscape(filePath))
+	} else {
+		path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+		if err != nil {
+			return "", err
+		}
+
+		requestURL = fmt.Sprintf("%s/1.0%s/%s/files?path=%s&recursive=1", r.httpHost, path, url.PathEscape(instanceName), url.QueryEscape(filePath))
+	}
+
+	return r.setQueryAttributes(requestURL)
+}
+
+// WatchInstanceFiles watches a path of the instance for changes, calling handler for every event until the
+// returned operation is cancelled or the watched path is deleted.
+func (r *ProtocolLXD) WatchInstanceFiles(instanceName string, watch api.InstanceFileWatchPost, handler func(event api.InstanceFileEvent)) (Operation, error) {
+	if !r.HasExtension("instance_files_watch") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_files_watch\" API extension")
+	}
+
+	var uri string
+
+	if r.IsAgent() {
+		uri = "/files/watch"
+	} else {
+		path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+		if err != nil {
+			return nil, err
+		}
+
+		uri = fmt.Sprintf("%s/%s/files/watch", path, url.PathEscape(instanceName))
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", uri, watch, "")
+	if err != nil {
+		return nil, err
+	}
+	opAPI := op.Get()
+
+	// Parse the fds
+	fds := map[string]string{}
+
+	value, ok := opAPI.Metadata["fds"]
+	if ok {
+		values := value.(map[string]interface{})
+		for k, v := range values {
+			fds[k] = v.(string)
+		}
+	}
+
+	if fds["0"] == "" {
+		return nil, fmt.Errorf("Did not receive a file descriptor for the events")
+	}
+
+	// Connect to the websocket
+	conn, err := r.GetOperationWebsocket(opAPI.ID, fds["0"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Pass the events to the handler until the server closes the connection
+	go func() {
+		defer conn.Close()
+
+		for {
+			event := api.InstanceFileEvent{}
+			err := conn.ReadJSON(&event)
+			if err != nil {
+				return
+			}
+
+			handler(event)
+		}
+	}()
+
+	return op, nil
+}
+
 // GetInstanceSnapshotNames returns a list of snapshot names for the instance.
 func (r *ProtocolLXD) GetInstanceSnapshotNames(instanceName string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -1687,6 +1994,184 @@ func (r *ProtocolLXD) UpdateInstanceMetadata(name string, metadata api.ImageMeta
 	return nil
 }
 
+// GetInstanceNVRAM returns the UEFI variables of a virtual machine.
+func (r *ProtocolLXD) GetInstanceNVRAM(name string) (*api.InstanceNVRAM, string, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !r.HasExtension("instance_nvram") {
+		return nil, "", fmt.Errorf("The server is missing the required \"instance_nvram\" API extension")
+	}
+
+	nvram := api.InstanceNVRAM{}
+
+	url := fmt.Sprintf("%s/%s/nvram", path, url.PathEscape(name))
+	etag, err := r.queryStruct("GET", url, nil, "", &nvram)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &nvram, etag, err
+}
+
+// UpdateInstanceNVRAM sets the boot order and secure boot keys of a stopped virtual machine.
+func (r *ProtocolLXD) UpdateInstanceNVRAM(name string, nvram api.InstanceNVRAMPut, ETag string) error {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	if !r.HasExtension("instance_nvram") {
+		return fmt.Errorf("The server is missing the required \"instance_nvram\" API extension")
+	}
+
+	url := fmt.Sprintf("%s/%s/nvram", path, url.PathEscape(name))
+	_, _, err = r.query("PUT", url, nvram, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResetInstanceNVRAM recreates the UEFI variables of a stopped virtual machine from the firmware defaults.
+func (r *ProtocolLXD) ResetInstanceNVRAM(name string) error {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	if !r.HasExtension("instance_nvram") {
+		return fmt.Errorf("The server is missing the required \"instance_nvram\" API extension")
+	}
+
+	url := fmt.Sprintf("%s/%s/nvram", path, url.PathEscape(name))
+	_, _, err = r.query("DELETE", url, nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetInstanceMachineType returns the machine type of a virtual machine and the ones it can be upgraded to.
+func (r *ProtocolLXD) GetInstanceMachineType(name string) (*api.InstanceMachineType, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("instance_machine_type") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_machine_type\" API extension")
+	}
+
+	machineType := api.InstanceMachineType{}
+
+	url := fmt.Sprintf("%s/%s/machine-type", path, url.PathEscape(name))
+	_, err = r.queryStruct("GET", url, nil, "", &machineType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &machineType, nil
+}
+
+// UpgradeInstanceMachineType moves a stopped virtual machine to a newer machine type.
+func (r *ProtocolLXD) UpgradeInstanceMachineType(name string, machineType api.InstanceMachineTypePost) error {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	if !r.HasExtension("instance_machine_type") {
+		return fmt.Errorf("The server is missing the required \"instance_machine_type\" API extension")
+	}
+
+	url := fmt.Sprintf("%s/%s/machine-type", path, url.PathEscape(name))
+	_, _, err = r.query("POST", url, machineType, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetInstanceProcesses returns the processes of a running instance using the most CPU (sortBy "cpu") or
+// memory (sortBy "memory"), up to the limit (all of them when 0).
+func (r *ProtocolLXD) GetInstanceProcesses(name string, sortBy string, limit int) (*api.InstanceProcesses, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("instance_processes") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_processes\" API extension")
+	}
+
+	values := url.Values{}
+	if sortBy != "" {
+		values.Set("sort", sortBy)
+	}
+
+	values.Set("limit", strconv.Itoa(limit))
+
+	top := api.InstanceProcesses{}
+
+	url := fmt.Sprintf("%s/%s/processes?%s", path, url.PathEscape(name), values.Encode())
+	_, err = r.queryStruct("GET", url, nil, "", &top)
+	if err != nil {
+		return nil, err
+	}
+
+	return &top, nil
+}
+
+// GetInstanceSEV returns the AMD SEV memory encryption state of a virtual machine.
+func (r *ProtocolLXD) GetInstanceSEV(name string) (*api.InstanceSEV, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("instance_sev") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_sev\" API extension")
+	}
+
+	sev := api.InstanceSEV{}
+
+	url := fmt.Sprintf("%s/%s/sev", path, url.PathEscape(name))
+	_, err = r.queryStruct("GET", url, nil, "", &sev)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sev, nil
+}
+
+// GetInstanceSEVReport returns an attestation report of a running virtual machine including the nonce.
+func (r *ProtocolLXD) GetInstanceSEVReport(name string, req api.InstanceSEVReportPost) (*api.InstanceSEVReport, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("instance_sev") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_sev\" API extension")
+	}
+
+	report := api.InstanceSEVReport{}
+
+	url := fmt.Sprintf("%s/%s/sev/report", path, url.PathEscape(name))
+	_, err = r.queryStruct("POST", url, req, "", &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
 // GetInstanceTemplateFiles returns the list of names of template files for a instance.
 func (r *ProtocolLXD) GetInstanceTemplateFiles(instanceName string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -1986,6 +2471,14 @@ func (r *ProtocolLXD) GetInstanceConsoleLog(instanceName string, args *InstanceC
 	// Prepare the HTTP request
 	url := fmt.Sprintf("%s/1.0%s/%s/console", r.httpHost, path, url.PathEscape(instanceName))
 
+	if args != nil && !args.Since.IsZero() {
+		if !r.HasExtension("console_log_history") {
+			return nil, fmt.Errorf("The server is missing the required \"console_log_history\" API extension")
+		}
+
+		url = fmt.Sprintf("%s?since=%s", url, args.Since.UTC().Format(time.RFC3339))
+	}
+
 	url, err = r.setQueryAttributes(url)
 	if err != nil {
 		return nil, err
@@ -2013,6 +2506,35 @@ func (r *ProtocolLXD) GetInstanceConsoleLog(instanceName string, args *InstanceC
 	return resp.Body, err
 }
 
+// GetInstanceConsoleLogEntries returns the timestamped console output of the instance.
+func (r *ProtocolLXD) GetInstanceConsoleLogEntries(instanceName string, args *InstanceConsoleLogArgs) ([]api.InstanceConsoleLogEntry, error) {
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.HasExtension("console_log_history") {
+		return nil, fmt.Errorf("The server is missing the required \"console_log_history\" API extension")
+	}
+
+	values := url.Values{}
+	values.Set("timestamps", "1")
+
+	if args != nil && !args.Since.IsZero() {
+		values.Set("since", args.Since.UTC().Format(time.RFC3339))
+	}
+
+	entries := []api.InstanceConsoleLogEntry{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/console?%s", path, url.PathEscape(instanceName), values.Encode()), nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
 // DeleteInstanceConsoleLog deletes the requested instance's console log.
 func (r *ProtocolLXD) DeleteInstanceConsoleLog(instanceName string, args *InstanceConsoleLogArgs) error {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -2115,6 +2637,10 @@ func (r *ProtocolLXD) CreateInstanceBackup(instanceName string, backup api.Insta
 		return nil, fmt.Errorf("The server is missing the required \"container_backup\" API extension")
 	}
 
+	if backup.Format != "" && !r.HasExtension("instance_backup_format") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_backup_format\" API extension")
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/backups", path, url.PathEscape(instanceName)), backup, "")
 	if err != nil {
@@ -2192,7 +2718,7 @@ func (r *ProtocolLXD) GetInstanceBackupFile(instanceName string, name string, re
 	}
 
 	// Start the request
-	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request)
+	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request.WithContext(r.getContext()))
 	if err != nil {
 		return nil, err
 	}
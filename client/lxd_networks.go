@@ -49,6 +49,27 @@ func (r *ProtocolLXD) GetNetworks() ([]api.Network, error) {
 	return networks, nil
 }
 
+// GetNetworksWithFilter returns a filtered list of Network struct.
+func (r *ProtocolLXD) GetNetworksWithFilter(filters []string) ([]api.Network, error) {
+	if !r.HasExtension("api_filtering_operators") {
+		return nil, fmt.Errorf("The server is missing the required \"api_filtering_operators\" API extension")
+	}
+
+	networks := []api.Network{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	v.Set("filter", filterParam(filters))
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks?%s", v.Encode()), nil, "", &networks)
+	if err != nil {
+		return nil, err
+	}
+
+	return networks, nil
+}
+
 // GetNetwork returns a Network entry for the provided name
 func (r *ProtocolLXD) GetNetwork(name string) (*api.Network, string, error) {
 	if !r.HasExtension("network") {
@@ -100,6 +121,23 @@ func (r *ProtocolLXD) GetNetworkState(name string) (*api.NetworkState, error) {
 	return &state, nil
 }
 
+// GetNetworkFirewall returns the firewall rules generated for the network and its instances
+func (r *ProtocolLXD) GetNetworkFirewall(name string) (*api.NetworkFirewall, error) {
+	if !r.HasExtension("network_firewall") {
+		return nil, fmt.Errorf("The server is missing the required \"network_firewall\" API extension")
+	}
+
+	firewall := api.NetworkFirewall{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/firewall", url.PathEscape(name)), nil, "", &firewall)
+	if err != nil {
+		return nil, err
+	}
+
+	return &firewall, nil
+}
+
 // CreateNetwork defines a new network using the provided Network struct
 func (r *ProtocolLXD) CreateNetwork(network api.NetworksPost) error {
 	if !r.HasExtension("network") {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/shared/api"
 )
@@ -85,6 +86,40 @@ func (r *ProtocolLXD) GetProjectState(name string) (*api.ProjectState, error) {
 	return &projectState, nil
 }
 
+// GetProjectUsage returns the resource usage of a project over a time range.
+// Zero start and end times and an empty interval use the server defaults.
+func (r *ProtocolLXD) GetProjectUsage(name string, start time.Time, end time.Time, interval string) (*api.ProjectUsage, error) {
+	if !r.HasExtension("project_usage_metering") {
+		return nil, fmt.Errorf("The server is missing the required \"project_usage_metering\" API extension")
+	}
+
+	values := url.Values{}
+	if !start.IsZero() {
+		values.Set("start", start.UTC().Format(time.RFC3339))
+	}
+
+	if !end.IsZero() {
+		values.Set("end", end.UTC().Format(time.RFC3339))
+	}
+
+	if interval != "" {
+		values.Set("interval", interval)
+	}
+
+	path := fmt.Sprintf("/projects/%s/usage", url.PathEscape(name))
+	if len(values) > 0 {
+		path = fmt.Sprintf("%s?%s", path, values.Encode())
+	}
+
+	usage := api.ProjectUsage{}
+	_, err := r.queryStruct("GET", path, nil, "", &usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
 // CreateProject defines a new container project
 func (r *ProtocolLXD) CreateProject(project api.ProjectsPost) error {
 	if !r.HasExtension("projects") {
@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lxc/lxd/shared"
@@ -89,9 +90,43 @@ func (r *ProtocolLXD) GetServerResources() (*api.Resources, error) {
 	return &resources, nil
 }
 
+// GetDeprecatedConfigKeys returns the deprecated configuration keys still set on the entities of the server.
+func (r *ProtocolLXD) GetDeprecatedConfigKeys() ([]api.DeprecatedConfigKey, error) {
+	if !r.HasExtension("config_deprecated_keys") {
+		return nil, fmt.Errorf("The server is missing the required \"config_deprecated_keys\" API extension")
+	}
+
+	keys := []api.DeprecatedConfigKey{}
+
+	_, err := r.queryStruct("GET", "/deprecated-config-keys", nil, "", &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetServerIdmap returns the uid/gid allocation of the LXD server and how it's split between projects and instances.
+func (r *ProtocolLXD) GetServerIdmap() (*api.Idmap, error) {
+	if !r.HasExtension("idmap_management") {
+		return nil, fmt.Errorf("The server is missing the required \"idmap_management\" API extension")
+	}
+
+	idmap := api.Idmap{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/idmap", nil, "", &idmap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &idmap, nil
+}
+
 // UseProject returns a client that will use a specific project.
 func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 	return &ProtocolLXD{
+		ctx:                  r.ctx,
 		server:               r.server,
 		http:                 r.http,
 		httpCertificate:      r.httpCertificate,
@@ -111,6 +146,7 @@ func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 // placement, preparing a new storage pool or network, ...
 func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 	return &ProtocolLXD{
+		ctx:                  r.ctx,
 		server:               r.server,
 		http:                 r.http,
 		httpCertificate:      r.httpCertificate,
@@ -125,6 +161,25 @@ func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 	}
 }
 
+// UseContext returns a client that will make its requests using the given context.
+// Cancelling the context aborts any ongoing request and websocket connection attempt.
+func (r *ProtocolLXD) UseContext(ctx context.Context) InstanceServer {
+	return &ProtocolLXD{
+		ctx:                  ctx,
+		server:               r.server,
+		http:                 r.http,
+		httpCertificate:      r.httpCertificate,
+		httpHost:             r.httpHost,
+		httpProtocol:         r.httpProtocol,
+		httpUserAgent:        r.httpUserAgent,
+		bakeryClient:         r.bakeryClient,
+		bakeryInteractor:     r.bakeryInteractor,
+		requireAuthenticated: r.requireAuthenticated,
+		clusterTarget:        r.clusterTarget,
+		project:              r.project,
+	}
+}
+
 // IsAgent returns true if the server is a LXD agent.
 func (r *ProtocolLXD) IsAgent() bool {
 	return r.server != nil && r.server.Environment.Server == "lxd-agent"
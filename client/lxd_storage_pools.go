@@ -102,6 +102,21 @@ func (r *ProtocolLXD) UpdateStoragePool(name string, pool api.StoragePoolPut, ET
 	return nil
 }
 
+// UpdateStoragePoolSource moves a loop file backed storage pool onto a block device.
+func (r *ProtocolLXD) UpdateStoragePoolSource(name string, req api.StoragePoolSourcePost) (Operation, error) {
+	if !r.HasExtension("storage_pool_move_to_block") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_pool_move_to_block\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/storage-pools/%s/source", url.PathEscape(name)), req, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // DeleteStoragePool deletes a storage pool
 func (r *ProtocolLXD) DeleteStoragePool(name string) error {
 	if !r.HasExtension("storage") {
@@ -57,6 +57,27 @@ func (r *ProtocolLXD) GetStoragePoolVolumes(pool string) ([]api.StorageVolume, e
 	return volumes, nil
 }
 
+// GetStoragePoolVolumesWithFilter returns a filtered list of StorageVolume entries for the provided pool.
+func (r *ProtocolLXD) GetStoragePoolVolumesWithFilter(pool string, filters []string) ([]api.StorageVolume, error) {
+	if !r.HasExtension("api_filtering_operators") {
+		return nil, fmt.Errorf("The server is missing the required \"api_filtering_operators\" API extension")
+	}
+
+	volumes := []api.StorageVolume{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	v.Set("filter", filterParam(filters))
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/volumes?%s", url.PathEscape(pool), v.Encode()), nil, "", &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
 // GetStoragePoolVolume returns a StorageVolume entry for the provided pool and volume name
 func (r *ProtocolLXD) GetStoragePoolVolume(pool string, volType string, name string) (*api.StorageVolume, string, error) {
 	if !r.HasExtension("storage") {
@@ -584,9 +605,15 @@ func (r *ProtocolLXD) MoveStoragePoolVolume(pool string, source InstanceServer,
 		return nil, fmt.Errorf("Moving storage volumes between remotes is not implemented")
 	}
 
+	if args.Location != "" && !r.HasExtension("storage_volume_move_location") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_volume_move_location\" API extension")
+	}
+
 	req := api.StorageVolumePost{
-		Name: args.Name,
-		Pool: pool,
+		Name:       args.Name,
+		Pool:       pool,
+		VolumeOnly: args.VolumeOnly,
+		Location:   args.Location,
 	}
 
 	// Send the request
@@ -787,7 +814,7 @@ func (r *ProtocolLXD) GetStoragePoolVolumeBackupFile(pool string, volName string
 	}
 
 	// Start the request
-	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request)
+	response, doneCh, err := cancel.CancelableDownload(req.Canceler, r.http, request.WithContext(r.getContext()))
 	if err != nil {
 		return nil, err
 	}
@@ -883,3 +910,59 @@ func (r *ProtocolLXD) CreateStoragePoolVolumeFromBackup(pool string, args Storag
 
 	return &op, nil
 }
+
+// CreateStoragePoolVolumeFromISO creates a custom ISO volume from an ISO image.
+func (r *ProtocolLXD) CreateStoragePoolVolumeFromISO(pool string, args StoragePoolVolumeBackupArgs) (Operation, error) {
+	if !r.HasExtension("custom_volume_iso") {
+		return nil, fmt.Errorf(`The server is missing the required "custom_volume_iso" API extension`)
+	}
+
+	if args.Name == "" {
+		return nil, fmt.Errorf("Missing volume name")
+	}
+
+	path := fmt.Sprintf("/storage-pools/%s/volumes/custom", url.PathEscape(pool))
+
+	// Prepare the HTTP request.
+	reqURL, err := r.setQueryAttributes(fmt.Sprintf("%s/1.0%s", r.httpHost, path))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, args.BackupFile)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-LXD-name", args.Name)
+	req.Header.Set("X-LXD-type", "iso")
+
+	// Send the request.
+	resp, err := r.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Handle errors.
+	response, _, err := lxdParseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get to the operation.
+	respOperation, err := response.MetadataAsOperation()
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup an Operation wrapper.
+	op := operation{
+		Operation: *respOperation,
+		r:         r,
+		chActive:  make(chan bool),
+	}
+
+	return &op, nil
+}
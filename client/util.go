@@ -187,3 +187,8 @@ func setQueryParam(uri, param, value string) (string, error) {
 
 	return fields.String(), nil
 }
+
+// filterParam returns the value of the filter query parameter matching all the given clauses.
+func filterParam(filters []string) string {
+	return strings.Join(filters, " and ")
+}
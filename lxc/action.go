@@ -2,12 +2,10 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/lxc/lxd/lxc/config"
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -97,12 +95,15 @@ func (c *cmdStop) Command() *cobra.Command {
 type cmdAction struct {
 	global *cmdGlobal
 
-	flagAll       bool
-	flagConsole   string
-	flagForce     bool
-	flagStateful  bool
-	flagStateless bool
-	flagTimeout   int
+	flagAll            bool
+	flagAllProjects    bool
+	flagConsole        string
+	flagForce          bool
+	flagForceProtected bool
+	flagStateful       bool
+	flagStateless      bool
+	flagTags           []string
+	flagTimeout        int
 }
 
 func (c *cmdAction) Command(action string) *cobra.Command {
@@ -110,6 +111,8 @@ func (c *cmdAction) Command(action string) *cobra.Command {
 	cmd.RunE = c.Run
 
 	cmd.Flags().BoolVar(&c.flagAll, "all", false, i18n.G("Run against all instances"))
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Match the instances of all projects"))
+	cmd.Flags().StringArrayVar(&c.flagTags, "tag", nil, i18n.G("Only run against the instances having this tag (with --all)")+"``")
 
 	if action == "stop" {
 		cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Store the instance state"))
@@ -125,23 +128,13 @@ func (c *cmdAction) Command(action string) *cobra.Command {
 	if shared.StringInSlice(action, []string{"restart", "stop"}) {
 		cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Force the instance to shutdown"))
 		cmd.Flags().IntVar(&c.flagTimeout, "timeout", -1, i18n.G("Time to wait for the instance before killing it")+"``")
+		cmd.Flags().BoolVar(&c.flagForceProtected, "force-protected", false, i18n.G("Stop the instance even if it's protected against it (security.protection.stop)"))
 	}
 
 	return cmd
 }
 
-func (c *cmdAction) doActionAll(action string, resource remoteResource) error {
-	if resource.name != "" {
-		// both --all and instance name given.
-		return fmt.Errorf(i18n.G("Both --all and instance name given"))
-	}
-
-	remote := resource.remote
-	d, err := c.global.conf.GetInstanceServer(remote)
-	if err != nil {
-		return err
-	}
-
+func (c *cmdAction) doActionAll(action string, d lxd.InstanceServer) error {
 	// Pause is called freeze.
 	if action == "pause" {
 		action = "freeze"
@@ -155,11 +148,13 @@ func (c *cmdAction) doActionAll(action string, resource remoteResource) error {
 
 	req := api.InstancesPut{
 		State: &api.InstanceStatePut{
-			Action:   action,
-			Timeout:  c.flagTimeout,
-			Force:    c.flagForce,
-			Stateful: state,
+			Action:         action,
+			Timeout:        c.flagTimeout,
+			Force:          c.flagForce,
+			Stateful:       state,
+			ForceProtected: c.flagForceProtected,
 		},
+		Tags: c.flagTags,
 	}
 
 	// Update all instances.
@@ -189,7 +184,7 @@ func (c *cmdAction) doActionAll(action string, resource remoteResource) error {
 	return nil
 }
 
-func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string) error {
+func (c *cmdAction) doAction(action string, target instanceTarget) error {
 	state := false
 
 	// Pause is called freeze
@@ -206,18 +201,10 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 		return fmt.Errorf(i18n.G("--console can't be used while forcing instance shutdown"))
 	}
 
-	remote, name, err := conf.ParseRemote(nameArg)
-	if err != nil {
-		return err
-	}
-
-	d, err := conf.GetInstanceServer(remote)
-	if err != nil {
-		return err
-	}
-
+	d := target.server
+	name := target.name
 	if name == "" {
-		return fmt.Errorf(i18n.G("Must supply instance name for: ")+"\"%s\"", nameArg)
+		return fmt.Errorf(i18n.G("Must supply instance name for: ")+"\"%s\"", target.display)
 	}
 
 	if action == "start" {
@@ -238,10 +225,11 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 	}
 
 	req := api.InstanceStatePut{
-		Action:   action,
-		Timeout:  c.flagTimeout,
-		Force:    c.flagForce,
-		Stateful: state,
+		Action:         action,
+		Timeout:        c.flagTimeout,
+		Force:          c.flagForce,
+		Stateful:       state,
+		ForceProtected: c.flagForceProtected,
 	}
 
 	op, err := d.UpdateInstanceState(name, req, "")
@@ -270,7 +258,12 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 	err = utils.CancelableWait(op, &progress)
 	if err != nil {
 		progress.Done("")
-		return fmt.Errorf("%s\n"+i18n.G("Try `lxc info --show-log %s` for more info"), err, nameArg)
+		ref := target.display
+		if target.project != "" {
+			ref = fmt.Sprintf("--project %s %s", target.project, target.display)
+		}
+
+		return fmt.Errorf("%s\n"+i18n.G("Try `lxc info --show-log %s` for more info"), err, ref)
 	}
 
 	progress.Done("")
@@ -289,7 +282,11 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 	conf := c.global.conf
 
-	var names []string
+	if len(c.flagTags) > 0 && !c.flagAll {
+		return fmt.Errorf(i18n.G("--tag can only be used with --all"))
+	}
+
+	var targets []instanceTarget
 	if c.flagAll {
 		// If no server passed, use current default.
 		if len(args) == 0 {
@@ -308,42 +305,72 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf(i18n.G("Both --all and instance name given"))
 			}
 
-			// See if we can use the bulk API.
-			if resource.server.HasExtension("instance_bulk_state_change") {
-				err = c.doActionAll(cmd.Name(), resource)
+			projects := []string{""}
+			if c.flagAllProjects {
+				projects, err = resource.server.GetProjectNames()
 				if err != nil {
-					return fmt.Errorf("%s: %v", resource.remote, err)
+					return err
 				}
-
-				continue
 			}
 
-			ctslist, err := resource.server.GetInstances(api.InstanceTypeAny)
-			if err != nil {
-				return err
-			}
+			for _, projectName := range projects {
+				server := resource.server
+				if projectName != "" {
+					server = server.UseProject(projectName)
+				}
 
-			for _, ct := range ctslist {
-				switch cmd.Name() {
-				case "start":
-					if ct.StatusCode == api.Running {
-						continue
+				if len(c.flagTags) > 0 && !server.HasExtension("tags") {
+					return fmt.Errorf(i18n.G("The server doesn't support tags"))
+				}
+
+				// See if we can use the bulk API.
+				if server.HasExtension("instance_bulk_state_change") {
+					err = c.doActionAll(cmd.Name(), server)
+					if err != nil {
+						return fmt.Errorf("%s: %v", resource.remote, err)
 					}
-				case "stop":
-					if ct.StatusCode == api.Stopped {
-						continue
+
+					continue
+				}
+
+				ctslist, err := server.GetInstances(api.InstanceTypeAny)
+				if err != nil {
+					return err
+				}
+
+				for _, ct := range ctslist {
+					switch cmd.Name() {
+					case "start":
+						if ct.StatusCode == api.Running {
+							continue
+						}
+					case "stop":
+						if ct.StatusCode == api.Stopped {
+							continue
+						}
 					}
+
+					targets = append(targets, instanceTarget{
+						server:  server,
+						remote:  resource.remote,
+						project: projectName,
+						name:    ct.Name,
+						display: fmt.Sprintf("%s:%s", resource.remote, ct.Name),
+					})
 				}
-				names = append(names, fmt.Sprintf("%s:%s", resource.remote, ct.Name))
 			}
 		}
 	} else {
-		names = args
-
 		if len(args) == 0 {
 			cmd.Usage()
 			return nil
 		}
+
+		var err error
+		targets, err = c.global.ParseInstanceTargets(c.flagAllProjects, args...)
+		if err != nil {
+			return err
+		}
 	}
 
 	if c.flagConsole != "" {
@@ -351,36 +378,21 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf(i18n.G("--console can't be used with --all"))
 		}
 
-		if len(names) != 1 {
+		if len(targets) != 1 {
 			return fmt.Errorf(i18n.G("--console only works with a single instance"))
 		}
 	}
 
 	// Run the action for every listed instance
-	results := runBatch(names, func(name string) error { return c.doAction(cmd.Name(), conf, name) })
+	results := runTargets(targets, func(target instanceTarget) error { return c.doAction(cmd.Name(), target) })
 
 	// Single instance is easy
 	if len(results) == 1 {
 		return results[0].err
 	}
 
-	// Do fancier rendering for batches
-	success := true
-
-	for _, result := range results {
-		if result.err == nil {
-			continue
-		}
-
-		success = false
-		msg := fmt.Sprintf(i18n.G("error: %v"), result.err)
-		for _, line := range strings.Split(msg, "\n") {
-			fmt.Fprintf(os.Stderr, "%s: %s\n", result.name, line)
-		}
-	}
-
-	if !success {
-		fmt.Fprintln(os.Stderr, "")
+	// Render a summary for batches
+	if !printTargetResults(results, c.global.flagQuiet) {
 		return fmt.Errorf(i18n.G("Some instances failed to %s"), cmd.Name())
 	}
 
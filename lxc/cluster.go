@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -79,6 +80,14 @@ func (c *cmdCluster) Command() *cobra.Command {
 	cmdClusterRestore := cmdClusterRestore{global: c.global, cluster: c}
 	cmd.AddCommand(cmdClusterRestore.Command())
 
+	// Upgrade cluster members
+	cmdClusterUpgrade := cmdClusterUpgrade{global: c.global, cluster: c}
+	cmd.AddCommand(cmdClusterUpgrade.Command())
+
+	// Show the versions of the cluster members
+	cmdClusterUpgradeStatus := cmdClusterUpgradeStatus{global: c.global, cluster: c}
+	cmd.AddCommand(cmdClusterUpgradeStatus.Command())
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
@@ -91,7 +100,9 @@ type cmdClusterList struct {
 	global  *cmdGlobal
 	cluster *cmdCluster
 
-	flagFormat string
+	flagFormat  string
+	flagWatch   bool
+	flagRefresh int
 }
 
 func (c *cmdClusterList) Command() *cobra.Command {
@@ -102,6 +113,8 @@ func (c *cmdClusterList) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List all the cluster members`))
 	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().BoolVarP(&c.flagWatch, "watch", "w", false, i18n.G("Keep the list up to date as cluster members change"))
+	cmd.Flags().IntVar(&c.flagRefresh, "refresh", 0, i18n.G("Also refresh the list every N seconds (implies --watch)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -138,36 +151,44 @@ func (c *cmdClusterList) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("LXD server isn't part of a cluster"))
 	}
 
-	// Get the cluster members
-	members, err := resource.server.GetClusterMembers()
-	if err != nil {
-		return err
-	}
+	render := func() error {
+		// Get the cluster members
+		members, err := resource.server.GetClusterMembers()
+		if err != nil {
+			return err
+		}
 
-	// Render the table
-	data := [][]string{}
-	for _, member := range members {
-		database := "NO"
-		if member.Database {
-			database = "YES"
+		// Render the table
+		data := [][]string{}
+		for _, member := range members {
+			database := "NO"
+			if member.Database {
+				database = "YES"
+			}
+			line := []string{member.ServerName, member.URL, database, member.Architecture, member.FailureDomain, member.Description, strings.ToUpper(member.Status), member.Message}
+			data = append(data, line)
 		}
-		line := []string{member.ServerName, member.URL, database, member.Architecture, member.FailureDomain, member.Description, strings.ToUpper(member.Status), member.Message}
-		data = append(data, line)
+		sort.Sort(byName(data))
+
+		header := []string{
+			i18n.G("NAME"),
+			i18n.G("URL"),
+			i18n.G("DATABASE"),
+			i18n.G("ARCHITECTURE"),
+			i18n.G("FAILURE DOMAIN"),
+			i18n.G("DESCRIPTION"),
+			i18n.G("STATE"),
+			i18n.G("MESSAGE"),
+		}
+
+		return utils.RenderTable(c.flagFormat, header, data, members)
 	}
-	sort.Sort(byName(data))
 
-	header := []string{
-		i18n.G("NAME"),
-		i18n.G("URL"),
-		i18n.G("DATABASE"),
-		i18n.G("ARCHITECTURE"),
-		i18n.G("FAILURE DOMAIN"),
-		i18n.G("DESCRIPTION"),
-		i18n.G("STATE"),
-		i18n.G("MESSAGE"),
+	if c.flagWatch || c.flagRefresh > 0 {
+		return watchRender(resource.server, []string{"lifecycle"}, time.Duration(c.flagRefresh)*time.Second, render)
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, members)
+	return render()
 }
 
 // Show
@@ -549,46 +570,6 @@ func (c *cmdClusterEdit) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func clusterJoinTokenOperationToAPI(op *api.Operation) (*api.ClusterMemberJoinToken, error) {
-	serverName, ok := op.Metadata["serverName"].(string)
-	if !ok {
-		return nil, fmt.Errorf("Operation serverName is type %T not string", op.Metadata["serverName"])
-	}
-
-	secret, ok := op.Metadata["secret"].(string)
-	if !ok {
-		return nil, fmt.Errorf("Operation secret is type %T not string", op.Metadata["secret"])
-	}
-
-	fingerprint, ok := op.Metadata["fingerprint"].(string)
-	if !ok {
-		return nil, fmt.Errorf("Operation fingerprint is type %T not string", op.Metadata["fingerprint"])
-	}
-
-	addresses, ok := op.Metadata["addresses"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("Operation addresses is type %T not []interface{}", op.Metadata["addresses"])
-	}
-
-	joinToken := api.ClusterMemberJoinToken{
-		ServerName:  serverName,
-		Secret:      secret,
-		Fingerprint: fingerprint,
-		Addresses:   make([]string, 0, len(addresses)),
-	}
-
-	for i, address := range addresses {
-		addressString, ok := address.(string)
-		if !ok {
-			return nil, fmt.Errorf("Operation address index %d is type %T not string", i, address)
-		}
-
-		joinToken.Addresses = append(joinToken.Addresses, addressString)
-	}
-
-	return &joinToken, nil
-}
-
 // Add
 type cmdClusterAdd struct {
 	global  *cmdGlobal
@@ -637,7 +618,7 @@ func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
 
 	if !c.global.flagQuiet {
 		opAPI := op.Get()
-		joinToken, err := clusterJoinTokenOperationToAPI(&opAPI)
+		joinToken, err := opAPI.ToClusterJoinToken()
 		if err != nil {
 			return errors.Wrapf(err, "Failed converting token operation to join token")
 		}
@@ -722,7 +703,7 @@ func (c *cmdClusterListTokens) Run(cmd *cobra.Command, args []string) error {
 			continue // Tokens are single use, so if cancelled but not deleted yet its not available.
 		}
 
-		joinToken, err := clusterJoinTokenOperationToAPI(&op)
+		joinToken, err := op.ToClusterJoinToken()
 		if err != nil {
 			continue // Operation is not a valid cluster member join token operation.
 		}
@@ -804,7 +785,7 @@ func (c *cmdClusterRevokeToken) Run(cmd *cobra.Command, args []string) error {
 			continue // Tokens are single use, so if cancelled but not deleted yet its not available.
 		}
 
-		joinToken, err := clusterJoinTokenOperationToAPI(&op)
+		joinToken, err := op.ToClusterJoinToken()
 		if err != nil {
 			continue // Operation is not a valid cluster member join token operation.
 		}
@@ -926,7 +907,8 @@ func (c *cmdClusterUpdateCertificate) Run(cmd *cobra.Command, args []string) err
 type cmdClusterEvacuateAction struct {
 	global *cmdGlobal
 
-	flagForce bool
+	flagForce          bool
+	flagForceProtected bool
 }
 
 // Cluster member evacuation
@@ -972,6 +954,9 @@ func (c *cmdClusterEvacuateAction) Command(action string) *cobra.Command {
 	cmd := &cobra.Command{}
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagForce, "force", false, i18n.G(`Force evacuation without user confirmation`)+"``")
+	if action == "evacuate" {
+		cmd.Flags().BoolVar(&c.flagForceProtected, "force-protected", false, i18n.G("Also stop the instances protected against it (security.protection.stop)"))
+	}
 
 	return cmd
 }
@@ -1007,7 +992,8 @@ func (c *cmdClusterEvacuateAction) Run(cmd *cobra.Command, args []string) error
 	}
 
 	state := api.ClusterMemberStatePost{
-		Action: cmd.Name(),
+		Action:         cmd.Name(),
+		ForceProtected: c.flagForceProtected,
 	}
 
 	op, err := resource.server.UpdateClusterMemberState(resource.name, state)
@@ -1043,3 +1029,159 @@ func (c *cmdClusterEvacuateAction) Run(cmd *cobra.Command, args []string) error
 	progress.Done("")
 	return nil
 }
+
+// Cluster upgrade
+type cmdClusterUpgrade struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagSkipEvacuation bool
+}
+
+func (c *cmdClusterUpgrade) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("upgrade", i18n.G("[<remote>:][<member>] [<member>...]"))
+	cmd.Short = i18n.G("Upgrade cluster members")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Upgrade cluster members
+
+The members are upgraded one after the other by running the update executable of their environment
+(e.g. refreshing the snap), being evacuated beforehand and restored once upgraded.
+
+When no member is given, the members running an older version are upgraded, or all of them if none is.
+The member the client is connected to is upgraded last, without being evacuated.`))
+	cmd.Flags().BoolVar(&c.flagSkipEvacuation, "skip-evacuation", false, i18n.G("Don't evacuate the members before upgrading them"))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterUpgrade) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 0, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	req := api.ClusterUpgradePost{
+		Evacuate: !c.flagSkipEvacuation,
+	}
+
+	if resource.name != "" {
+		req.Members = append(req.Members, resource.name)
+	}
+
+	if len(args) > 1 {
+		req.Members = append(req.Members, args[1:]...)
+	}
+
+	op, err := resource.server.UpgradeCluster(req)
+	if err != nil {
+		return err
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Upgrading the cluster: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done(i18n.G("Cluster members upgraded"))
+	return nil
+}
+
+// Cluster upgrade status
+type cmdClusterUpgradeStatus struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagFormat string
+}
+
+func (c *cmdClusterUpgradeStatus) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("upgrade-status", i18n.G("[<remote>:]"))
+	cmd.Short = i18n.G("Show the versions run by the cluster members")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the versions run by the cluster members
+
+The members running an older version than the others block the upgraded members until upgraded.`))
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterUpgradeStatus) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	upgrade, err := resource.server.GetClusterUpgrade()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, member := range upgrade.Members {
+		outdated := "NO"
+		if member.Outdated {
+			outdated = "YES"
+		}
+
+		data = append(data, []string{member.ServerName, member.Version, fmt.Sprintf("%d", member.Schema), fmt.Sprintf("%d", member.APIExtensions), strings.ToUpper(member.Status), outdated})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("VERSION"),
+		i18n.G("SCHEMA"),
+		i18n.G("API EXTENSIONS"),
+		i18n.G("STATE"),
+		i18n.G("OUTDATED"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, upgrade)
+}
@@ -42,10 +42,18 @@ func (c *cmdConfig) Command() *cobra.Command {
 	configGetCmd := cmdConfigGet{global: c.global, config: c}
 	cmd.AddCommand(configGetCmd.Command())
 
+	// Machine type
+	configMachineTypeCmd := cmdConfigMachineType{global: c.global, config: c}
+	cmd.AddCommand(configMachineTypeCmd.Command())
+
 	// Metadata
 	configMetadataCmd := cmdConfigMetadata{global: c.global, config: c}
 	cmd.AddCommand(configMetadataCmd.Command())
 
+	// NVRAM
+	configNVRAMCmd := cmdConfigNVRAM{global: c.global, config: c}
+	cmd.AddCommand(configNVRAMCmd.Command())
+
 	// Profile
 	configProfileCmd := cmdProfile{global: c.global}
 	profileCmd := configProfileCmd.Command()
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdConfigMachineType struct {
+	global *cmdGlobal
+	config *cmdConfig
+}
+
+func (c *cmdConfigMachineType) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("machine-type")
+	cmd.Short = i18n.G("Manage virtual machine QEMU machine types")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage virtual machine QEMU machine types
+
+Virtual machines keep the machine type they were first started with so that QEMU upgrades
+don't change the virtual hardware exposed to them. They can be moved to a newer machine
+type while stopped.`))
+
+	// Show
+	configMachineTypeShowCmd := cmdConfigMachineTypeShow{global: c.global, config: c.config, configMachineType: c}
+	cmd.AddCommand(configMachineTypeShowCmd.Command())
+
+	// Upgrade
+	configMachineTypeUpgradeCmd := cmdConfigMachineTypeUpgrade{global: c.global, config: c.config, configMachineType: c}
+	cmd.AddCommand(configMachineTypeUpgradeCmd.Command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
+	return cmd
+}
+
+// Show
+type cmdConfigMachineTypeShow struct {
+	global            *cmdGlobal
+	config            *cmdConfig
+	configMachineType *cmdConfigMachineType
+}
+
+func (c *cmdConfigMachineTypeShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("show", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Show virtual machine machine types")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the machine type of a virtual machine and the ones it can be upgraded to`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigMachineTypeShow) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Show the machine type
+	machineType, err := resource.server.GetInstanceMachineType(resource.name)
+	if err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(machineType)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s", content)
+
+	return nil
+}
+
+// Upgrade
+type cmdConfigMachineTypeUpgrade struct {
+	global            *cmdGlobal
+	config            *cmdConfig
+	configMachineType *cmdConfigMachineType
+
+	flagAll         bool
+	flagAllProjects bool
+	flagMachineType string
+}
+
+func (c *cmdConfigMachineTypeUpgrade) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("upgrade", i18n.G("[<remote>:]<instance> [[<remote>:]<instance>...]"))
+	cmd.Short = i18n.G("Upgrade virtual machine machine types")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Upgrade virtual machine machine types
+
+The virtual machines must be stopped. They're moved to the latest machine type of their family
+unless another one is requested. With --all, all the stopped virtual machines running an older
+machine type are upgraded.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc config machine-type upgrade v1 v2
+    Upgrade v1 and v2 to the latest machine type.
+
+lxc config machine-type upgrade --all --all-projects
+    Upgrade all the stopped virtual machines of all projects.`))
+
+	cmd.Flags().BoolVar(&c.flagAll, "all", false, i18n.G("Upgrade all stopped virtual machines"))
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Upgrade virtual machines from all projects"))
+	cmd.Flags().StringVar(&c.flagMachineType, "machine-type", "", i18n.G("Machine type to upgrade to (defaults to the latest)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigMachineTypeUpgrade) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	var targets []instanceTarget
+	if c.flagAll {
+		// If no server passed, use current default.
+		if len(args) == 0 {
+			args = []string{fmt.Sprintf("%s:", conf.DefaultRemote)}
+		}
+
+		resources, err := c.global.ParseServers(args...)
+		if err != nil {
+			return err
+		}
+
+		for _, resource := range resources {
+			// We don't allow instance names with --all.
+			if resource.name != "" {
+				return fmt.Errorf(i18n.G("Both --all and instance name given"))
+			}
+
+			projects := []string{""}
+			if c.flagAllProjects {
+				projects, err = resource.server.GetProjectNames()
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, projectName := range projects {
+				server := resource.server
+				if projectName != "" {
+					server = server.UseProject(projectName)
+				}
+
+				vms, err := server.GetInstances(api.InstanceTypeVM)
+				if err != nil {
+					return err
+				}
+
+				for _, vm := range vms {
+					if vm.StatusCode != api.Stopped {
+						continue
+					}
+
+					targets = append(targets, instanceTarget{
+						server:  server,
+						remote:  resource.remote,
+						project: projectName,
+						name:    vm.Name,
+						display: fmt.Sprintf("%s:%s", resource.remote, vm.Name),
+					})
+				}
+			}
+		}
+	} else {
+		exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+		if exit {
+			return err
+		}
+
+		targets, err = c.global.ParseInstanceTargets(c.flagAllProjects, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	results := runTargets(targets, c.upgradeTarget)
+
+	// Single instance is easy
+	if len(results) == 1 {
+		return results[0].err
+	}
+
+	// Render a summary for batches
+	if !printTargetResults(results, c.global.flagQuiet) {
+		return fmt.Errorf(i18n.G("Some instances failed to upgrade"))
+	}
+
+	return nil
+}
+
+func (c *cmdConfigMachineTypeUpgrade) upgradeTarget(target instanceTarget) error {
+	machineType, err := target.server.GetInstanceMachineType(target.name)
+	if err != nil {
+		return err
+	}
+
+	// Virtual machines which never started get the latest machine type on first start.
+	if c.flagMachineType == "" && (machineType.Current == "" || machineType.Current == machineType.Latest) {
+		return nil
+	}
+
+	return target.server.UpgradeInstanceMachineType(target.name, api.InstanceMachineTypePost{MachineType: c.flagMachineType})
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdConfigNVRAM struct {
+	global *cmdGlobal
+	config *cmdConfig
+}
+
+func (c *cmdConfigNVRAM) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("nvram")
+	cmd.Short = i18n.G("Manage virtual machine UEFI variables")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage virtual machine UEFI variables
+
+The boot order and secure boot keys can be changed while the virtual machine is stopped.`))
+
+	// Edit
+	configNVRAMEditCmd := cmdConfigNVRAMEdit{global: c.global, config: c.config, configNVRAM: c}
+	cmd.AddCommand(configNVRAMEditCmd.Command())
+
+	// Reset
+	configNVRAMResetCmd := cmdConfigNVRAMReset{global: c.global, config: c.config, configNVRAM: c}
+	cmd.AddCommand(configNVRAMResetCmd.Command())
+
+	// Show
+	configNVRAMShowCmd := cmdConfigNVRAMShow{global: c.global, config: c.config, configNVRAM: c}
+	cmd.AddCommand(configNVRAMShowCmd.Command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
+	return cmd
+}
+
+// Edit
+type cmdConfigNVRAMEdit struct {
+	global      *cmdGlobal
+	config      *cmdConfig
+	configNVRAM *cmdConfigNVRAM
+}
+
+func (c *cmdConfigNVRAMEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("edit", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Edit virtual machine UEFI variables")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit virtual machine UEFI variables`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigNVRAMEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the virtual machine UEFI variables.
+### Any line starting with a '# will be ignored.
+###
+### Only the boot order and secure boot keys can be changed, the boot
+### entries are listed for reference.
+###
+### A sample configuration looks like:
+###
+### boot_order:
+### - "0001"
+### - "0000"
+### secureboot_keys: true
+### boot_entries:
+### - id: "0000"
+###   description: UiApp
+###   active: false
+### - id: "0001"
+###   description: UEFI QEMU QEMU HARDDISK
+###   active: true`)
+}
+
+func (c *cmdConfigNVRAMEdit) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Edit the variables
+	if !termios.IsTerminal(getStdinFd()) {
+		nvram := api.InstanceNVRAMPut{}
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(content, &nvram)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateInstanceNVRAM(resource.name, nvram, "")
+	}
+
+	nvram, etag, err := resource.server.GetInstanceNVRAM(resource.name)
+	if err != nil {
+		return err
+	}
+
+	origContent, err := yaml.Marshal(nvram)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(origContent)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		nvram := api.InstanceNVRAMPut{}
+		err = yaml.Unmarshal(content, &nvram)
+		if err == nil {
+			err = resource.server.UpdateInstanceNVRAM(resource.name, nvram, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again or ctrl+c to abort change"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		break
+	}
+
+	return nil
+}
+
+// Reset
+type cmdConfigNVRAMReset struct {
+	global      *cmdGlobal
+	config      *cmdConfig
+	configNVRAM *cmdConfigNVRAM
+}
+
+func (c *cmdConfigNVRAMReset) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("reset", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Reset virtual machine UEFI variables")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Reset virtual machine UEFI variables
+
+The NVRAM is recreated from the firmware defaults, the boot entries get rebuilt on next boot.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigNVRAMReset) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	return resource.server.ResetInstanceNVRAM(resource.name)
+}
+
+// Show
+type cmdConfigNVRAMShow struct {
+	global      *cmdGlobal
+	config      *cmdConfig
+	configNVRAM *cmdConfigNVRAM
+}
+
+func (c *cmdConfigNVRAMShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("show", i18n.G("[<remote>:]<instance>"))
+	cmd.Short = i18n.G("Show virtual machine UEFI variables")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show virtual machine UEFI variables`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigNVRAMShow) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Show the UEFI variables
+	nvram, _, err := resource.server.GetInstanceNVRAM(resource.name)
+	if err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(nvram)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s", content)
+
+	return nil
+}
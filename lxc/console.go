@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
@@ -26,6 +27,7 @@ type cmdConsole struct {
 	global *cmdGlobal
 
 	flagShowLog bool
+	flagSince   string
 	flagType    string
 }
 
@@ -41,6 +43,7 @@ as well as retrieve past log entries from it.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagShowLog, "show-log", false, i18n.G("Retrieve the instance's console log"))
+	cmd.Flags().StringVar(&c.flagSince, "since", "", i18n.G("Only show the console log since the given time (RFC3339) or duration (e.g. 10m)")+"``")
 	cmd.Flags().StringVarP(&c.flagType, "type", "t", "console", i18n.G("Type of connection to establish: 'console' for serial console, 'vga' for SPICE graphical output")+"``")
 
 	return cmd
@@ -123,6 +126,10 @@ func (c *cmdConsole) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if c.flagSince != "" && !c.flagShowLog {
+		return fmt.Errorf(i18n.G("The --since flag requires --show-log"))
+	}
+
 	// Show the current log if requested
 	if c.flagShowLog {
 		if c.flagType != "console" {
@@ -130,6 +137,18 @@ func (c *cmdConsole) Run(cmd *cobra.Command, args []string) error {
 		}
 
 		console := &lxd.InstanceConsoleLogArgs{}
+		if c.flagSince != "" {
+			console.Since, err = time.Parse(time.RFC3339, c.flagSince)
+			if err != nil {
+				duration, durationErr := time.ParseDuration(c.flagSince)
+				if durationErr != nil {
+					return fmt.Errorf(i18n.G("Invalid --since value %q"), c.flagSince)
+				}
+
+				console.Since = time.Now().Add(-duration)
+			}
+		}
+
 		log, err := d.GetInstanceConsoleLog(name, console)
 		if err != nil {
 			return err
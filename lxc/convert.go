@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdConvert struct {
+	global *cmdGlobal
+
+	flagBase    string
+	flagConfig  []string
+	flagStorage string
+	flagSize    string
+}
+
+func (c *cmdConvert) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("convert", i18n.G("[<remote>:]<container> <vm>"))
+	cmd.Short = i18n.G("Convert containers into virtual machines")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Convert containers into virtual machines
+
+The container must be stopped. The virtual machine is created from the base image,
+a local virtual machine image of the same distribution as the container, whose
+kernel and bootloader are kept while the rest of its root filesystem is replaced
+by the one of the container.
+
+The configuration and devices of the container which apply to virtual machines
+are carried over.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc convert c1 v1 --base ubuntu-vm --size 20GiB
+    Create the virtual machine v1 with a 20GiB root disk from the container c1 and the local image ubuntu-vm.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagBase, "base", "", i18n.G("Local virtual machine image providing the kernel and bootloader")+"``")
+	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the new instance")+"``")
+	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
+	cmd.Flags().StringVar(&c.flagSize, "size", "", i18n.G("Size of the root disk of the virtual machine")+"``")
+
+	return cmd
+}
+
+func (c *cmdConvert) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	if c.flagBase == "" {
+		return fmt.Errorf(i18n.G("A base virtual machine image must be specified with --base"))
+	}
+
+	// Parse the remotes
+	remote, sourceName, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(args[1], ":") {
+		return fmt.Errorf(i18n.G("The virtual machine is created on the remote of the container"))
+	}
+
+	d, err := conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	// Parse the config overrides
+	configMap := map[string]string{}
+	for _, entry := range c.flagConfig {
+		if !strings.Contains(entry, "=") {
+			return fmt.Errorf(i18n.G("Bad key=value pair: %s"), entry)
+		}
+
+		fields := strings.SplitN(entry, "=", 2)
+		configMap[fields[0]] = fields[1]
+	}
+
+	req := api.InstancesPost{
+		Name: args[1],
+		Type: api.InstanceTypeVM,
+		Source: api.InstanceSource{
+			Type:   "conversion",
+			Source: sourceName,
+		},
+		InstancePut: api.InstancePut{
+			Config: configMap,
+		},
+	}
+
+	// Resolve the base image alias
+	alias, _, err := d.GetImageAliasType("virtual-machine", c.flagBase)
+	if err == nil {
+		req.Source.Fingerprint = alias.Target
+	} else {
+		req.Source.Fingerprint = c.flagBase
+	}
+
+	// Override the root disk
+	if c.flagStorage != "" || c.flagSize != "" {
+		source, _, err := d.GetInstance(sourceName)
+		if err != nil {
+			return err
+		}
+
+		rootDiskDeviceKey, rootDiskDevice, _ := shared.GetRootDiskDevice(source.ExpandedDevices)
+		if rootDiskDeviceKey == "" {
+			rootDiskDeviceKey = "root"
+			rootDiskDevice = map[string]string{
+				"type": "disk",
+				"path": "/",
+			}
+		}
+
+		device := map[string]string{}
+		for k, v := range rootDiskDevice {
+			device[k] = v
+		}
+
+		if c.flagStorage != "" {
+			device["pool"] = c.flagStorage
+		}
+
+		if c.flagSize != "" {
+			device["size"] = c.flagSize
+		}
+
+		req.Devices = map[string]map[string]string{rootDiskDeviceKey: device}
+	}
+
+	op, err := d.CreateInstance(req)
+	if err != nil {
+		return err
+	}
+
+	// Watch the background operation
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Converting: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+	return nil
+}
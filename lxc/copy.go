@@ -30,6 +30,7 @@ type cmdCopy struct {
 	flagTarget        string
 	flagTargetProject string
 	flagRefresh       bool
+	flagFormat        string
 }
 
 func (c *cmdCopy) Command() *cobra.Command {
@@ -53,6 +54,7 @@ func (c *cmdCopy) Command() *cobra.Command {
 	cmd.Flags().StringVar(&c.flagTargetProject, "target-project", "", i18n.G("Copy to a project different from the source")+"``")
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagRefresh, "refresh", false, i18n.G("Perform an incremental copy"))
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Print the new instance in the given format (json|yaml)")+"``")
 
 	return cmd
 }
@@ -405,7 +407,10 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 
 		// Extract the name of the instance
 		fields := strings.Split(instances[0], "/")
-		fmt.Printf(i18n.G("Instance name is: %s")+"\n", fields[len(fields)-1])
+		destName = fields[len(fields)-1]
+		if !c.global.flagQuiet {
+			fmt.Printf(i18n.G("Instance name is: %s")+"\n", destName)
+		}
 	}
 
 	// Start the instance if needed
@@ -425,6 +430,15 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 		}
 	}
 
+	if c.flagFormat != "" {
+		inst, _, err := dest.GetInstance(destName)
+		if err != nil {
+			return err
+		}
+
+		return utils.RenderResult(c.flagFormat, inst)
+	}
+
 	return nil
 }
 
@@ -437,6 +451,11 @@ func (c *cmdCopy) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// For copies, default to non-ephemeral and allow override (move uses -1)
 	ephem := 0
 	if c.flagEphemeral {
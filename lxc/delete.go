@@ -18,6 +18,7 @@ import (
 type cmdDelete struct {
 	global *cmdGlobal
 
+	flagAllProjects    bool
 	flagForce          bool
 	flagForceProtected bool
 	flagInteractive    bool
@@ -29,10 +30,17 @@ func (c *cmdDelete) Command() *cobra.Command {
 	cmd.Aliases = []string{"rm"}
 	cmd.Short = i18n.G("Delete instances and snapshots")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Delete instances and snapshots`))
+		`Delete instances and snapshots
+
+Instance names can be glob patterns, in which case all the matching instances are deleted.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc delete --force "test-*"
+    Delete all the instances whose name starts with "test-", stopping them first if needed.`))
 
 	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Match the instances of all projects"))
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Force the removal of running instances"))
+	cmd.Flags().BoolVar(&c.flagForceProtected, "force-protected", false, i18n.G("Remove the instances even if they're protected (security.protection.delete and security.protection.stop)"))
 	cmd.Flags().BoolVarP(&c.flagInteractive, "interactive", "i", false, i18n.G("Require user confirmation"))
 
 	return cmd
@@ -77,77 +85,93 @@ func (c *cmdDelete) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Parse remote
-	resources, err := c.global.ParseServers(args...)
+	// Resolve the instances
+	targets, err := c.global.ParseInstanceTargets(c.flagAllProjects, args...)
 	if err != nil {
 		return err
 	}
 
-	for _, resource := range resources {
-		if c.flagInteractive {
-			err := c.promptDelete(resource.name)
+	if c.flagInteractive {
+		for _, target := range targets {
+			err := c.promptDelete(target.display)
 			if err != nil {
 				return err
 			}
 		}
+	}
 
-		if shared.IsSnapshot(resource.name) {
-			return c.doDelete(resource.server, resource.name)
-		}
+	// Delete them all in parallel
+	results := runTargets(targets, c.deleteTarget)
 
-		ct, _, err := resource.server.GetInstance(resource.name)
-		if err != nil {
-			return err
-		}
+	// Single instance is easy
+	if len(results) == 1 {
+		return results[0].err
+	}
 
-		if ct.StatusCode != 0 && ct.StatusCode != api.Stopped {
-			if !c.flagForce {
-				return fmt.Errorf(i18n.G("The instance is currently running, stop it first or pass --force"))
-			}
+	// Render a summary for batches
+	if !printTargetResults(results, c.global.flagQuiet) {
+		return fmt.Errorf(i18n.G("Some instances failed to %s"), cmd.Name())
+	}
 
-			req := api.InstanceStatePut{
-				Action:  "stop",
-				Timeout: -1,
-				Force:   true,
-			}
+	return nil
+}
 
-			op, err := resource.server.UpdateInstanceState(resource.name, req, "")
-			if err != nil {
-				return err
-			}
+func (c *cmdDelete) deleteTarget(target instanceTarget) error {
+	if shared.IsSnapshot(target.name) {
+		return c.doDelete(target.server, target.name)
+	}
 
-			err = op.Wait()
-			if err != nil {
-				return fmt.Errorf(i18n.G("Stopping the instance failed: %s"), err)
-			}
+	ct, _, err := target.server.GetInstance(target.name)
+	if err != nil {
+		return err
+	}
 
-			if ct.Ephemeral {
-				return nil
-			}
+	if ct.StatusCode != 0 && ct.StatusCode != api.Stopped {
+		if !c.flagForce {
+			return fmt.Errorf(i18n.G("The instance is currently running, stop it first or pass --force"))
 		}
 
-		if c.flagForceProtected && shared.IsTrue(ct.ExpandedConfig["security.protection.delete"]) {
-			// Refresh in case we had to stop it above.
-			ct, etag, err := resource.server.GetInstance(resource.name)
-			if err != nil {
-				return err
-			}
+		req := api.InstanceStatePut{
+			Action:         "stop",
+			Timeout:        -1,
+			Force:          true,
+			ForceProtected: c.flagForceProtected,
+		}
 
-			ct.Config["security.protection.delete"] = "false"
-			op, err := resource.server.UpdateInstance(resource.name, ct.Writable(), etag)
-			if err != nil {
-				return err
-			}
+		op, err := target.server.UpdateInstanceState(target.name, req, "")
+		if err != nil {
+			return err
+		}
 
-			err = op.Wait()
-			if err != nil {
-				return err
-			}
+		err = op.Wait()
+		if err != nil {
+			return fmt.Errorf(i18n.G("Stopping the instance failed: %s"), err)
 		}
 
-		if err := c.doDelete(resource.server, resource.name); err != nil {
+		if ct.Ephemeral {
+			return nil
+		}
+	}
+
+	// The protection may also come from the project defaults, so only skip explicitly unprotected instances.
+	if c.flagForceProtected && ct.ExpandedConfig["security.protection.delete"] != "false" {
+		// Refresh in case we had to stop it above.
+		ct, etag, err := target.server.GetInstance(target.name)
+		if err != nil {
+			return err
+		}
+
+		ct.Config["security.protection.delete"] = "false"
+		op, err := target.server.UpdateInstance(target.name, ct.Writable(), etag)
+		if err != nil {
+			return err
+		}
+
+		err = op.Wait()
+		if err != nil {
 			return err
 		}
 	}
-	return nil
+
+	return c.doDelete(target.server, target.name)
 }
@@ -8,11 +8,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
 
 	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
@@ -31,6 +33,26 @@ type cmdExec struct {
 	flagUser                uint32
 	flagGroup               uint32
 	flagCwd                 string
+	flagResumeTimeout       int
+	flagFormat              string
+}
+
+// execResult is the result of a command in the structured formats.
+type execResult struct {
+	Command    []string `json:"command" yaml:"command"`
+	ReturnCode int      `json:"return_code" yaml:"return_code"`
+	Stdout     string   `json:"stdout" yaml:"stdout"`
+	Stderr     string   `json:"stderr" yaml:"stderr"`
+}
+
+// execBuffer captures the output of a command in the structured formats.
+type execBuffer struct {
+	bytes.Buffer
+}
+
+// Close implements io.Closer.
+func (b *execBuffer) Close() error {
+	return nil
 }
 
 func (c *cmdExec) Command() *cobra.Command {
@@ -58,6 +80,8 @@ Mode defaults to non-interactive, interactive mode is selected if both stdin AND
 	cmd.Flags().Uint32Var(&c.flagUser, "user", 0, i18n.G("User ID to run the command as (default 0)")+"``")
 	cmd.Flags().Uint32Var(&c.flagGroup, "group", 0, i18n.G("Group ID to run the command as (default 0)")+"``")
 	cmd.Flags().StringVar(&c.flagCwd, "cwd", "", i18n.G("Directory to run the command in (default /root)")+"``")
+	cmd.Flags().IntVar(&c.flagResumeTimeout, "resume-timeout", 0, i18n.G("Seconds to keep an interactive session alive after a connection drop, reconnecting meanwhile")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Print the output and return code of the command in the given format (json|yaml)")+"``")
 
 	return cmd
 }
@@ -96,6 +120,15 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("You can't pass -t or -T at the same time as --mode"))
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
+	if c.flagFormat != "" && (c.flagMode == "interactive" || c.flagForceInteractive) {
+		return fmt.Errorf(i18n.G("Interactive mode can't be used with --format"))
+	}
+
 	// Connect to the daemon
 	remote, name, err := conf.ParseRemote(args[0])
 	if err != nil {
@@ -136,7 +169,7 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 		interactive = false
 	} else if c.flagMode == "interactive" || c.flagForceInteractive {
 		interactive = true
-	} else if c.flagMode == "non-interactive" || c.flagForceNonInteractive {
+	} else if c.flagMode == "non-interactive" || c.flagForceNonInteractive || c.flagFormat != "" {
 		interactive = false
 	} else {
 		interactive = stdinTerminal && stdoutTerminal
@@ -174,7 +207,18 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 		stdin = ioutil.NopCloser(bytes.NewReader(nil))
 	}
 
-	stdout := getStdout()
+	var stdout io.WriteCloser
+	var stderr io.WriteCloser
+	stdout = getStdout()
+	stderr = os.Stderr
+
+	// Capture the output to include it in the result.
+	stdoutBuf := &execBuffer{}
+	stderrBuf := &execBuffer{}
+	if c.flagFormat != "" {
+		stdout = stdoutBuf
+		stderr = stderrBuf
+	}
 
 	// Prepare the command
 	req := api.InstanceExecPost{
@@ -189,10 +233,14 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 		Cwd:         c.flagCwd,
 	}
 
+	if interactive {
+		req.ResumeTimeout = c.flagResumeTimeout
+	}
+
 	execArgs := lxd.InstanceExecArgs{
 		Stdin:    stdin,
 		Stdout:   stdout,
-		Stderr:   os.Stderr,
+		Stderr:   stderr,
 		Control:  handler,
 		DataDone: make(chan bool),
 	}
@@ -204,7 +252,14 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Wait for the operation to complete
-	err = op.Wait()
+	if req.ResumeTimeout > 0 {
+		// The event listener doesn't survive connection drops, look at the operation once the session is over.
+		<-execArgs.DataDone
+		err = c.waitResumed(op)
+	} else {
+		err = op.Wait()
+	}
+
 	if err != nil {
 		return err
 	}
@@ -214,5 +269,32 @@ func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
 	<-execArgs.DataDone
 
 	c.global.ret = int(opAPI.Metadata["return"].(float64))
-	return nil
+
+	return utils.RenderResult(c.flagFormat, execResult{
+		Command:    req.Command,
+		ReturnCode: c.global.ret,
+		Stdout:     stdoutBuf.String(),
+		Stderr:     stderrBuf.String(),
+	})
+}
+
+// waitResumed waits for the operation of a resumable session to complete.
+func (c *cmdExec) waitResumed(op lxd.Operation) error {
+	for {
+		err := op.Refresh()
+		if err != nil {
+			return err
+		}
+
+		opAPI := op.Get()
+		if opAPI.StatusCode.IsFinal() {
+			if opAPI.Err != "" {
+				return fmt.Errorf("%s", opAPI.Err)
+			}
+
+			return nil
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -23,17 +24,24 @@ type cmdExport struct {
 	flagInstanceOnly         bool
 	flagOptimizedStorage     bool
 	flagCompressionAlgorithm string
+	flagFormat               string
 }
 
 func (c *cmdExport) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = usage("export", i18n.G("[<remote>:]<instance> [target] [--instance-only] [--optimized-storage]"))
+	cmd.Use = usage("export", i18n.G("[<remote>:]<instance> [target] [--instance-only] [--optimized-storage] [--format=tarball|qcow2|ova]"))
 	cmd.Short = i18n.G("Export instance backups")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Export instances as backup tarballs.`))
+		`Export instances as backup tarballs.
+
+Virtual machines can also be exported as a qcow2 disk image or as an OVA appliance
+to be imported by other virtualization platforms. The instance must be stopped.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc export u1 backup0.tar.gz
-    Download a backup tarball of the u1 instance.`))
+    Download a backup tarball of the u1 instance.
+
+lxc export v1 v1.ova --format=ova
+    Export the v1 virtual machine as an OVA appliance.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagInstanceOnly, "instance-only", false,
@@ -41,6 +49,7 @@ func (c *cmdExport) Command() *cobra.Command {
 	cmd.Flags().BoolVar(&c.flagOptimizedStorage, "optimized-storage", false,
 		i18n.G("Use storage driver optimized format (can only be restored on a similar pool)"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Compression algorithm to use (`none` for uncompressed)"))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "", i18n.G("Format of the export (tarball, or qcow2 and ova for virtual machines)")+"``")
 
 	return cmd
 }
@@ -74,6 +83,7 @@ func (c *cmdExport) Run(cmd *cobra.Command, args []string) error {
 		InstanceOnly:         instanceOnly,
 		OptimizedStorage:     c.flagOptimizedStorage,
 		CompressionAlgorithm: c.flagCompressionAlgorithm,
+		Format:               c.flagFormat,
 	}
 
 	op, err := d.CreateInstanceBackup(name, req)
@@ -121,6 +131,8 @@ func (c *cmdExport) Run(cmd *cobra.Command, args []string) error {
 	var targetName string
 	if len(args) > 1 {
 		targetName = args[1]
+	} else if c.flagFormat == "qcow2" || c.flagFormat == "ova" {
+		targetName = fmt.Sprintf("%s.%s", name, c.flagFormat)
 	} else {
 		targetName = "backup.tar.gz"
 	}
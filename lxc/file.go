@@ -17,10 +17,12 @@ import (
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/tarstream"
 	"github.com/lxc/lxd/shared/termios"
 	"github.com/lxc/lxd/shared/units"
 )
@@ -34,6 +36,15 @@ type cmdFile struct {
 
 	flagMkdir     bool
 	flagRecursive bool
+	flagFormat    string
+}
+
+// fileTransferResult is the result of a file transfer in the structured formats.
+type fileTransferResult struct {
+	Source string `json:"source" yaml:"source"`
+	Target string `json:"target" yaml:"target"`
+	Type   string `json:"type" yaml:"type"`
+	Size   int64  `json:"size,omitempty" yaml:"size,omitempty"`
 }
 
 func fileGetWrapper(server lxd.InstanceServer, inst string, path string) (buf io.ReadCloser, resp *lxd.InstanceFileResponse, err error) {
@@ -88,6 +99,10 @@ func (c *cmdFile) Command() *cobra.Command {
 	fileEditCmd := cmdFileEdit{global: c.global, file: c, filePull: &filePullCmd, filePush: &filePushCmd}
 	cmd.AddCommand(fileEditCmd.Command())
 
+	// Watch
+	fileWatchCmd := cmdFileWatch{global: c.global, file: c}
+	cmd.AddCommand(fileWatchCmd.Command())
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
@@ -207,6 +222,83 @@ func (c *cmdFileEdit) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Watch
+type cmdFileWatch struct {
+	global *cmdGlobal
+	file   *cmdFile
+
+	flagRecursive bool
+}
+
+func (c *cmdFileWatch) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("watch", i18n.G("[<remote>:]<instance>/<path>"))
+	cmd.Short = i18n.G("Watch files in instances for changes")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Watch files in instances for changes
+
+Every file created, modified or deleted under the path is printed as it happens, until interrupted
+or until the path itself is deleted. The instance must be running.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc file watch -r foo/srv/app
+   To watch /srv/app and all its subdirectories in the instance "foo".`))
+
+	cmd.Flags().BoolVarP(&c.flagRecursive, "recursive", "r", false, i18n.G("Watch the subdirectories too"))
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdFileWatch) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	pathSpec := strings.SplitN(resource.name, "/", 2)
+	if len(pathSpec) != 2 {
+		return fmt.Errorf(i18n.G("Invalid path %s"), resource.name)
+	}
+
+	watch := api.InstanceFileWatchPost{
+		Path:      "/" + pathSpec[1],
+		Recursive: c.flagRecursive,
+	}
+
+	op, err := resource.server.WatchInstanceFiles(pathSpec[0], watch, func(event api.InstanceFileEvent) {
+		name := event.Path
+		if event.Directory {
+			name += "/"
+		}
+
+		fmt.Printf("%s\t%s\n", event.Type, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Stop watching on interrupt.
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, os.Interrupt)
+	defer signal.Stop(chSignal)
+
+	go func() {
+		<-chSignal
+		_ = op.Cancel()
+	}()
+
+	return op.Wait()
+}
+
 // Pull
 type cmdFilePull struct {
 	global *cmdGlobal
@@ -225,6 +317,7 @@ func (c *cmdFilePull) Command() *cobra.Command {
 
 	cmd.Flags().BoolVarP(&c.file.flagMkdir, "create-dirs", "p", false, i18n.G("Create any directories necessary"))
 	cmd.Flags().BoolVarP(&c.file.flagRecursive, "recursive", "r", false, i18n.G("Recursively transfer files"))
+	cmd.Flags().StringVarP(&c.file.flagFormat, "format", "f", "", i18n.G("Print the transferred files in the given format (json|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -237,8 +330,16 @@ func (c *cmdFilePull) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.file.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Determine the target
 	target := shared.HostPathFollow(filepath.Clean(args[len(args)-1]))
+	if target == "-" && c.file.flagFormat != "" {
+		return fmt.Errorf(i18n.G("Can't pull to stdout with --format"))
+	}
 	targetIsDir := false
 	sb, err := os.Stat(target)
 	if err != nil && !os.IsNotExist(err) {
@@ -272,6 +373,7 @@ func (c *cmdFilePull) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	results := []fileTransferResult{}
 	for _, resource := range resources {
 		pathSpec := strings.SplitN(resource.name, "/", 2)
 		if len(pathSpec) != 2 {
@@ -294,11 +396,17 @@ func (c *cmdFilePull) Run(cmd *cobra.Command, args []string) error {
 					targetIsDir = true
 				}
 
-				err := c.file.recursivePullFile(resource.server, pathSpec[0], pathSpec[1], target)
+				if resource.server.HasExtension("instance_files_recursive") {
+					err = c.file.recursivePullTar(resource.server, pathSpec[0], pathSpec[1], target)
+				} else {
+					err = c.file.recursivePullFile(resource.server, pathSpec[0], pathSpec[1], target)
+				}
+
 				if err != nil {
 					return err
 				}
 
+				results = append(results, fileTransferResult{Source: resource.name, Target: target, Type: resp.Type})
 				continue
 			} else {
 				return fmt.Errorf(i18n.G("Can't pull a directory without --recursive"))
@@ -343,6 +451,7 @@ func (c *cmdFilePull) Run(cmd *cobra.Command, args []string) error {
 					return err
 				}
 
+				results = append(results, fileTransferResult{Source: resource.name, Target: targetPath, Type: resp.Type})
 				continue
 			}
 		}
@@ -384,15 +493,17 @@ func (c *cmdFilePull) Run(cmd *cobra.Command, args []string) error {
 			},
 		}
 
-		_, err = io.Copy(writer, buf)
+		size, err := io.Copy(writer, buf)
 		if err != nil {
 			progress.Done("")
 			return err
 		}
 		progress.Done("")
+
+		results = append(results, fileTransferResult{Source: resource.name, Target: targetPath, Type: resp.Type, Size: size})
 	}
 
-	return nil
+	return utils.RenderResult(c.file.flagFormat, results)
 }
 
 // Push
@@ -418,6 +529,7 @@ func (c *cmdFilePush) Command() *cobra.Command {
 	cmd.Flags().IntVar(&c.file.flagUID, "uid", -1, i18n.G("Set the file's uid on push")+"``")
 	cmd.Flags().IntVar(&c.file.flagGID, "gid", -1, i18n.G("Set the file's gid on push")+"``")
 	cmd.Flags().StringVar(&c.file.flagMode, "mode", "", i18n.G("Set the file's perms on push")+"``")
+	cmd.Flags().StringVarP(&c.file.flagFormat, "format", "f", "", i18n.G("Print the transferred files in the given format (json|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -430,6 +542,11 @@ func (c *cmdFilePush) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.file.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Parse the destination
 	target := args[len(args)-1]
 	pathSpec := strings.SplitN(target, "/", 2)
@@ -477,6 +594,8 @@ func (c *cmdFilePush) Run(cmd *cobra.Command, args []string) error {
 		mode = os.FileMode(m)
 	}
 
+	results := []fileTransferResult{}
+
 	// Recursive calls
 	if c.file.flagRecursive {
 		// Quick checks.
@@ -506,13 +625,25 @@ func (c *cmdFilePush) Run(cmd *cobra.Command, args []string) error {
 
 		// Transfer the files
 		for _, fname := range sourcefilenames {
-			err := c.file.recursivePushFile(resource.server, resource.name, fname, targetPath)
+			if resource.server.HasExtension("instance_files_recursive") {
+				err = c.file.recursivePushTar(resource.server, resource.name, fname, targetPath)
+			} else {
+				err = c.file.recursivePushFile(resource.server, resource.name, fname, targetPath)
+			}
+
 			if err != nil {
 				return err
 			}
+
+			fileType := "file"
+			if shared.IsDir(fname) {
+				fileType = "directory"
+			}
+
+			results = append(results, fileTransferResult{Source: fname, Target: fmt.Sprintf("%s%s", resource.name, targetPath), Type: fileType})
 		}
 
-		return nil
+		return utils.RenderResult(c.file.flagFormat, results)
 	}
 
 	// Determine the target uid
@@ -646,9 +777,11 @@ func (c *cmdFilePush) Run(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		progress.Done("")
+
+		results = append(results, fileTransferResult{Source: f.Name(), Target: fmt.Sprintf("%s%s", resource.name, fpath), Type: args.Type, Size: fstat.Size()})
 	}
 
-	return nil
+	return utils.RenderResult(c.file.flagFormat, results)
 }
 
 func (c *cmdFile) recursivePullFile(d lxd.InstanceServer, inst string, p string, targetDir string) error {
@@ -824,6 +957,82 @@ func (c *cmdFile) recursivePushFile(d lxd.InstanceServer, inst string, source st
 	return filepath.Walk(source, sendFile)
 }
 
+// recursivePullTar pulls the tree at p into targetDir as a single tarball.
+func (c *cmdFile) recursivePullTar(d lxd.InstanceServer, inst string, p string, targetDir string) error {
+	logger.Infof("Pulling %s from %s (tarball)", targetDir, p)
+
+	content, err := d.GetInstanceFileRecursive(inst, p)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	progress := utils.ProgressRenderer{
+		Format: fmt.Sprintf(i18n.G("Pulling %s from %s: %%s"), p, targetDir),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	reader := &ioprogress.ProgressReader{
+		ReadCloser: content,
+		Tracker: &ioprogress.ProgressTracker{
+			Handler: func(bytesReceived int64, speed int64) {
+				progress.UpdateProgress(ioprogress.ProgressData{
+					Text: fmt.Sprintf("%s (%s/s)",
+						units.GetByteSizeString(bytesReceived, 2),
+						units.GetByteSizeString(speed, 2))})
+			},
+		},
+	}
+
+	err = tarstream.Extract(reader, targetDir, nil, false)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+	return nil
+}
+
+// recursivePushTar pushes the tree at source into the target directory as a single tarball.
+func (c *cmdFile) recursivePushTar(d lxd.InstanceServer, inst string, source string, target string) error {
+	source = filepath.Clean(source)
+	logger.Infof("Pushing %s to %s (tarball)", source, target)
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+
+	go func() {
+		writer.CloseWithError(tarstream.Write(writer, source, filepath.Base(source), nil))
+	}()
+
+	progress := utils.ProgressRenderer{
+		Format: fmt.Sprintf(i18n.G("Pushing %s to %s: %%s"), source, target),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	content := &ioprogress.ProgressReader{
+		ReadCloser: reader,
+		Tracker: &ioprogress.ProgressTracker{
+			Handler: func(bytesSent int64, speed int64) {
+				progress.UpdateProgress(ioprogress.ProgressData{
+					Text: fmt.Sprintf("%s (%s/s)",
+						units.GetByteSizeString(bytesSent, 2),
+						units.GetByteSizeString(speed, 2))})
+			},
+		},
+	}
+
+	err := d.CreateInstanceFileRecursive(inst, target, content)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+	return nil
+}
+
 func (c *cmdFile) recursiveMkdir(d lxd.InstanceServer, inst string, p string, mode *os.FileMode, uid int64, gid int64) error {
 	/* special case, every instance has a /, we don't need to do anything */
 	if p == "/" {
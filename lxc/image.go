@@ -134,6 +134,7 @@ type cmdImageCopy struct {
 	flagAutoUpdate  bool
 	flagVM          bool
 	flagMode        string
+	flagFormat      string
 }
 
 func (c *cmdImageCopy) Command() *cobra.Command {
@@ -153,6 +154,7 @@ It requires the source to be an alias and for it to be public.`))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Copy virtual machine images"))
 	cmd.Flags().StringVar(&c.flagMode, "mode", "pull", i18n.G("Transfer mode. One of pull (default), push or relay")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Print the copied image in the given format (json|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -167,6 +169,11 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	if c.flagMode != "pull" && c.flagAutoUpdate {
 		return fmt.Errorf(i18n.G("Auto update is only available in pull mode"))
 	}
@@ -273,7 +280,30 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	err = ensureImageAliases(destinationServer, aliases, fp)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if c.flagFormat != "" {
+		// The fingerprint isn't known yet when copying by alias from simplestreams.
+		if fp == "" {
+			opAPI, err := op.GetTarget()
+			if err != nil {
+				return err
+			}
+
+			fp, _ = opAPI.Metadata["fingerprint"].(string)
+		}
+
+		image, _, err := destinationServer.GetImage(fp)
+		if err != nil {
+			return err
+		}
+
+		return utils.RenderResult(c.flagFormat, image)
+	}
+
+	return nil
 }
 
 // Delete
@@ -456,7 +486,10 @@ type cmdImageExport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagVM bool
+	flagVM     bool
+	flagBase   string
+	flagBundle bool
+	flagSign   string
 }
 
 func (c *cmdImageExport) Command() *cobra.Command {
@@ -466,9 +499,20 @@ func (c *cmdImageExport) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Export and download images
 
-The output target is optional and defaults to the working directory.`))
+The output target is optional and defaults to the working directory.
+
+When a base image is provided, the image is exported in split format with its root filesystem
+only containing the changes from the base image. Such images can only be imported on servers
+which have the base image.
+
+With --bundle, the image is added to the image bundle in the target directory. Image bundles are
+laid out as a simplestreams image server, so they can be imported with "lxc import-bundle" or
+served by any web server as a simplestreams remote. Their metadata can be signed with gpg.`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.Flags().StringVar(&c.flagBase, "base", "", i18n.G("Export the image as a delta of a base image")+"``")
+	cmd.Flags().BoolVar(&c.flagBundle, "bundle", false, i18n.G("Add the image to the image bundle in the target directory"))
+	cmd.Flags().StringVar(&c.flagSign, "sign", "", i18n.G("GPG key to sign the bundle metadata with")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -500,6 +544,23 @@ func (c *cmdImageExport) Run(cmd *cobra.Command, args []string) error {
 
 	fingerprint := c.image.dereferenceAlias(remoteServer, imageType, name)
 
+	if c.flagBundle {
+		if c.flagBase != "" {
+			return fmt.Errorf(i18n.G("Delta images can't be added to bundles"))
+		}
+
+		target := "."
+		if len(args) > 1 {
+			target = args[1]
+		}
+
+		return c.exportBundle(remoteServer, fingerprint, target)
+	}
+
+	if c.flagSign != "" {
+		return fmt.Errorf(i18n.G("--sign can only be used with --bundle"))
+	}
+
 	// Default target is current directory
 	target := "."
 	targetMeta := fingerprint
@@ -539,6 +600,10 @@ func (c *cmdImageExport) Run(cmd *cobra.Command, args []string) error {
 		ProgressHandler: progress.UpdateProgress,
 	}
 
+	if c.flagBase != "" {
+		req.BaseImage = c.image.dereferenceAlias(remoteServer, imageType, c.flagBase)
+	}
+
 	// Download the image
 	resp, err := remoteServer.GetImageFile(fingerprint, req)
 	if err != nil {
@@ -616,6 +681,7 @@ type cmdImageImport struct {
 
 	flagPublic  bool
 	flagAliases []string
+	flagFormat  string
 }
 
 func (c *cmdImageImport) Command() *cobra.Command {
@@ -629,6 +695,7 @@ Directory import is only available on Linux and must be performed as root.`))
 
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Print the imported image in the given format (json|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -663,6 +730,11 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Import the image
 	var imageFile string
 	var rootfsFile string
@@ -827,6 +899,15 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if c.flagFormat != "" {
+		image, _, err := d.GetImage(fingerprint)
+		if err != nil {
+			return err
+		}
+
+		return utils.RenderResult(c.flagFormat, image)
+	}
+
 	return nil
 }
 
@@ -835,7 +916,8 @@ type cmdImageInfo struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagVM bool
+	flagVM     bool
+	flagFormat string
 }
 
 func (c *cmdImageInfo) Command() *cobra.Command {
@@ -846,6 +928,7 @@ func (c *cmdImageInfo) Command() *cobra.Command {
 		`Show useful information about images`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Format (json|yaml)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -858,6 +941,11 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	// Parse remote
 	remoteName, name, err := c.global.conf.ParseRemote(args[0])
 	if err != nil {
@@ -881,6 +969,10 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if c.flagFormat != "" {
+		return utils.RenderResult(c.flagFormat, info)
+	}
+
 	public := i18n.G("no")
 	if info.Public {
 		public = i18n.G("yes")
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// Image bundles are directories laid out as a simplestreams image server, holding the image files along with the
+// streams/v1/index.json and streams/v1/images.json metadata (and their .sjson signed versions when signed).
+// They can be imported with "lxc import-bundle" or served as is by any web server as a simplestreams remote.
+const bundleIndexPath = "streams/v1/index.json"
+const bundleImagesPath = "streams/v1/images.json"
+
+// bundleLoadJSON reads the metadata file of the bundle into target. When a signed version of the file exists, its
+// signature is checked with gpg and the signed content is used instead.
+func bundleLoadJSON(dir string, path string, target interface{}) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, path))
+	if err != nil {
+		return err
+	}
+
+	signedPath := filepath.Join(dir, strings.TrimSuffix(path, ".json")+".sjson")
+	if shared.PathExists(signedPath) {
+		stderr := bytes.Buffer{}
+		cmd := exec.Command("gpg", "--batch", "--decrypt", signedPath)
+		cmd.Stderr = &stderr
+		content, err = cmd.Output()
+		if err != nil {
+			return fmt.Errorf(i18n.G("Failed verifying the signature of %s: %v (%s)"), path, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	return json.Unmarshal(content, target)
+}
+
+// bundleWriteJSON writes the metadata file of the bundle, signing it with the gpg key if one is given.
+func bundleWriteJSON(dir string, path string, data interface{}, signKey string) error {
+	content, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(dir, path)
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(target, append(content, '\n'), 0644)
+	if err != nil {
+		return err
+	}
+
+	signedTarget := strings.TrimSuffix(target, ".json") + ".sjson"
+	if signKey == "" {
+		// Don't leave a stale signature behind.
+		err = os.Remove(signedTarget)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	out, err := exec.Command("gpg", "--batch", "--yes", "--local-user", signKey, "--clearsign", "--output", signedTarget, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf(i18n.G("Failed signing %s: %v (%s)"), path, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// bundleHashFile returns the SHA256 hash and size of the file.
+func bundleHashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", -1, err
+	}
+
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", -1, err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), size, nil
+}
+
+// bundleRootType returns the simplestreams file type of the root file of a split image.
+func bundleRootType(imageType string, path string) (string, error) {
+	if imageType == "virtual-machine" {
+		return "disk-kvm.img", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(f, magic)
+	if err != nil {
+		return "", err
+	}
+
+	if string(magic) == "hsqs" {
+		return "squashfs", nil
+	}
+
+	return "root.tar.xz", nil
+}
+
+// exportBundle downloads the image into the bundle directory and adds it to the bundle metadata.
+func (c *cmdImageExport) exportBundle(remoteServer lxd.ImageServer, fingerprint string, dir string) error {
+	dir = shared.HostPathFollow(dir)
+
+	info, _, err := remoteServer.GetImage(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	imageDir := filepath.Join("images", info.Fingerprint)
+	err = os.MkdirAll(filepath.Join(dir, imageDir), 0755)
+	if err != nil {
+		return err
+	}
+
+	metaPath := filepath.Join(dir, imageDir, "lxd.tar.xz")
+	rootPath := filepath.Join(dir, imageDir, "rootfs")
+
+	dest, err := os.Create(metaPath)
+	if err != nil {
+		return err
+	}
+
+	defer dest.Close()
+
+	destRootfs, err := os.Create(rootPath)
+	if err != nil {
+		return err
+	}
+
+	defer destRootfs.Close()
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Exporting the image: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	req := lxd.ImageFileRequest{
+		MetaFile:        io.WriteSeeker(dest),
+		RootfsFile:      io.WriteSeeker(destRootfs),
+		ProgressHandler: progress.UpdateProgress,
+	}
+
+	resp, err := remoteServer.GetImageFile(info.Fingerprint, req)
+	if err != nil {
+		os.RemoveAll(filepath.Join(dir, imageDir))
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	err = dest.Truncate(resp.MetaSize)
+	if err != nil {
+		return err
+	}
+
+	items := map[string]simplestreams.ProductVersionItem{}
+	if resp.RootfsSize == 0 {
+		// Unified image.
+		destRootfs.Close()
+		os.Remove(rootPath)
+
+		unifiedPath := filepath.Join(dir, imageDir, "lxd_combined.tar.gz")
+		err = os.Rename(metaPath, unifiedPath)
+		if err != nil {
+			return err
+		}
+
+		hash, size, err := bundleHashFile(unifiedPath)
+		if err != nil {
+			return err
+		}
+
+		items["lxd_combined.tar.gz"] = simplestreams.ProductVersionItem{
+			FileType:   "lxd_combined.tar.gz",
+			Path:       filepath.ToSlash(filepath.Join(imageDir, "lxd_combined.tar.gz")),
+			HashSha256: hash,
+			Size:       size,
+		}
+	} else {
+		err = destRootfs.Truncate(resp.RootfsSize)
+		if err != nil {
+			return err
+		}
+
+		rootType, err := bundleRootType(info.Type, rootPath)
+		if err != nil {
+			return err
+		}
+
+		finalRootPath := filepath.Join(dir, imageDir, rootType)
+		err = os.Rename(rootPath, finalRootPath)
+		if err != nil {
+			return err
+		}
+
+		metaHash, metaSize, err := bundleHashFile(metaPath)
+		if err != nil {
+			return err
+		}
+
+		rootHash, rootSize, err := bundleHashFile(finalRootPath)
+		if err != nil {
+			return err
+		}
+
+		meta := simplestreams.ProductVersionItem{
+			FileType:   "lxd.tar.xz",
+			Path:       filepath.ToSlash(filepath.Join(imageDir, "lxd.tar.xz")),
+			HashSha256: metaHash,
+			Size:       metaSize,
+		}
+
+		// The fingerprint of split images is the hash of both files.
+		switch rootType {
+		case "squashfs":
+			meta.LXDHashSha256SquashFs = info.Fingerprint
+		case "root.tar.xz":
+			meta.LXDHashSha256RootXz = info.Fingerprint
+		case "disk-kvm.img":
+			meta.LXDHashSha256DiskKvmImg = info.Fingerprint
+		}
+
+		items["lxd.tar.xz"] = meta
+		items[rootType] = simplestreams.ProductVersionItem{
+			FileType:   rootType,
+			Path:       filepath.ToSlash(filepath.Join(imageDir, rootType)),
+			HashSha256: rootHash,
+			Size:       rootSize,
+		}
+	}
+
+	// Load the existing bundle metadata, if any.
+	products := simplestreams.Products{}
+	if shared.PathExists(filepath.Join(dir, bundleImagesPath)) {
+		err = bundleLoadJSON(dir, bundleImagesPath, &products)
+		if err != nil {
+			return err
+		}
+	}
+
+	if products.Products == nil {
+		products.ContentID = "images"
+		products.DataType = "image-downloads"
+		products.Format = "products:1.0"
+		products.Products = map[string]simplestreams.Product{}
+	}
+
+	// Group the images by distribution, release, variant and architecture like public image servers do.
+	property := func(key string, fallback string) string {
+		value := info.Properties[key]
+		if value == "" {
+			return fallback
+		}
+
+		return value
+	}
+
+	osName := property("os", "unknown")
+	release := property("release", info.Fingerprint[0:12])
+	variant := property("variant", "default")
+	productName := strings.Join([]string{osName, release, variant, info.Architecture}, ":")
+
+	product, ok := products.Products[productName]
+	if !ok {
+		product = simplestreams.Product{
+			Architecture:    info.Architecture,
+			OperatingSystem: osName,
+			Release:         release,
+			ReleaseTitle:    release,
+			Variant:         info.Properties["variant"],
+			Version:         info.Properties["version"],
+			Versions:        map[string]simplestreams.ProductVersion{},
+		}
+	}
+
+	aliases := bundleSplitAliases(product.Aliases)
+	for _, alias := range info.Aliases {
+		if !shared.StringInSlice(alias.Name, aliases) {
+			aliases = append(aliases, alias.Name)
+		}
+	}
+
+	product.Aliases = strings.Join(aliases, ",")
+
+	// Version names must start with the creation date.
+	createdAt := info.CreatedAt
+	if createdAt.IsZero() || createdAt.Unix() <= 0 {
+		createdAt = time.Now()
+	}
+
+	versionName := fmt.Sprintf("%s_%s", createdAt.UTC().Format("20060102_1504"), info.Fingerprint[0:12])
+	product.Versions[versionName] = simplestreams.ProductVersion{
+		Items: items,
+		Label: info.Properties["label"],
+	}
+
+	products.Products[productName] = product
+	products.Updated = time.Now().UTC().Format(time.RFC1123Z)
+
+	productNames := []string{}
+	for name := range products.Products {
+		productNames = append(productNames, name)
+	}
+
+	sort.Strings(productNames)
+
+	stream := simplestreams.Stream{
+		Format:  "index:1.0",
+		Updated: products.Updated,
+		Index: map[string]simplestreams.StreamIndex{
+			"images": {
+				DataType: "image-downloads",
+				Path:     bundleImagesPath,
+				Updated:  products.Updated,
+				Products: productNames,
+				Format:   "products:1.0",
+			},
+		},
+	}
+
+	err = bundleWriteJSON(dir, bundleImagesPath, products, c.flagSign)
+	if err != nil {
+		return err
+	}
+
+	err = bundleWriteJSON(dir, bundleIndexPath, stream, c.flagSign)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Image %s added to bundle %s")+"\n", info.Fingerprint, dir)
+	}
+
+	return nil
+}
+
+// bundleSplitAliases splits a comma separated list, ignoring empty entries.
+func bundleSplitAliases(value string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Import bundle
+type cmdImportBundle struct {
+	global *cmdGlobal
+
+	flagPublic    bool
+	flagNoAliases bool
+}
+
+func (c *cmdImportBundle) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("import-bundle", i18n.G("<directory> [<remote>:]"))
+	cmd.Short = i18n.G("Import the images of an image bundle")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Import the images of an image bundle
+
+Image bundles are created with "lxc image export --bundle". The files are checked against
+the hashes of the bundle metadata, whose signature is checked with gpg when the bundle is signed.
+Images already present on the server are skipped.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image export ubuntu:20.04 --bundle /media/usb/bundle
+lxc import-bundle /media/usb/bundle
+    Carry an image over to an airgapped server.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make the images public"))
+	cmd.Flags().BoolVar(&c.flagNoAliases, "no-aliases", false, i18n.G("Don't create the aliases of the images"))
+
+	return cmd
+}
+
+func (c *cmdImportBundle) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	if exit {
+		return err
+	}
+
+	dir := shared.HostPathFollow(args[0])
+
+	remote := ""
+	if len(args) > 1 {
+		remote, _, err = conf.ParseRemote(args[1])
+		if err != nil {
+			return err
+		}
+	} else {
+		remote = conf.DefaultRemote
+	}
+
+	d, err := conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	// Load the bundle metadata.
+	stream := simplestreams.Stream{}
+	err = bundleLoadJSON(dir, bundleIndexPath, &stream)
+	if err != nil {
+		return err
+	}
+
+	index, ok := stream.Index["images"]
+	if !ok {
+		return fmt.Errorf(i18n.G("No images in bundle %s"), dir)
+	}
+
+	products := simplestreams.Products{}
+	err = bundleLoadJSON(dir, index.Path, &products)
+	if err != nil {
+		return err
+	}
+
+	images, downloads := products.ToLXD()
+	for _, image := range images {
+		files := map[string]string{}
+		for _, download := range downloads[image.Fingerprint] {
+			// Deltas aren't part of bundles.
+			if download[2] != "meta" && download[2] != "root" {
+				continue
+			}
+
+			path := filepath.Join(dir, filepath.FromSlash(download[0]))
+			hash, _, err := bundleHashFile(path)
+			if err != nil {
+				return err
+			}
+
+			if hash != download[1] {
+				return fmt.Errorf(i18n.G("Hash mismatch for %s: %s != %s"), download[0], hash, download[1])
+			}
+
+			files[download[2]] = path
+		}
+
+		_, _, err := d.GetImage(image.Fingerprint)
+		if err == nil {
+			if !c.global.flagQuiet {
+				fmt.Printf(i18n.G("Image %s already present")+"\n", image.Fingerprint)
+			}
+		} else {
+			err = c.importImage(d, image, files)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !c.flagNoAliases {
+			err = ensureImageAliases(d, image.Aliases, image.Fingerprint)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// importImage uploads the files of the bundle image to the server.
+func (c *cmdImportBundle) importImage(d lxd.InstanceServer, image api.Image, files map[string]string) error {
+	meta, err := os.Open(files["meta"])
+	if err != nil {
+		return err
+	}
+
+	defer meta.Close()
+
+	progress := utils.ProgressRenderer{
+		Format: fmt.Sprintf(i18n.G("Importing image %s: %%s"), image.Fingerprint[0:12]),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	createArgs := &lxd.ImageCreateArgs{
+		MetaFile:        meta,
+		MetaName:        filepath.Base(files["meta"]),
+		ProgressHandler: progress.UpdateProgress,
+		Type:            image.Type,
+	}
+
+	if files["root"] != "" {
+		rootfs, err := os.Open(files["root"])
+		if err != nil {
+			return err
+		}
+
+		defer rootfs.Close()
+
+		createArgs.RootfsFile = rootfs
+		createArgs.RootfsName = filepath.Base(files["root"])
+	}
+
+	req := api.ImagesPost{}
+	req.Public = c.flagPublic
+	req.Properties = image.Properties
+	req.Filename = createArgs.MetaName
+
+	op, err := d.CreateImage(req, createArgs)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	fingerprint, _ := op.Get().Metadata["fingerprint"].(string)
+	if fingerprint != image.Fingerprint {
+		return fmt.Errorf(i18n.G("Imported image fingerprint %s doesn't match bundle fingerprint %s"), fingerprint, image.Fingerprint)
+	}
+
+	progress.Done(fmt.Sprintf(i18n.G("Image imported with fingerprint: %s"), fingerprint))
+
+	return nil
+}
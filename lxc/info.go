@@ -26,6 +26,8 @@ type cmdInfo struct {
 	flagShowLog   bool
 	flagResources bool
 	flagTarget    string
+	flagFormat    string
+	flagProcesses bool
 }
 
 func (c *cmdInfo) Command() *cobra.Command {
@@ -38,13 +40,18 @@ func (c *cmdInfo) Command() *cobra.Command {
 		`lxc info [<remote>:]<instance> [--show-log]
     For instance information.
 
+lxc info [<remote>:]<instance> --processes
+    For instance information including the 10 processes using the most CPU.
+
 lxc info [<remote>:] [--resources]
     For LXD server information.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagShowLog, "show-log", false, i18n.G("Show the instance's last 100 log lines?"))
 	cmd.Flags().BoolVar(&c.flagResources, "resources", false, i18n.G("Show the resources available to the server"))
+	cmd.Flags().BoolVar(&c.flagProcesses, "processes", false, i18n.G("Show the processes of the instance using the most CPU"))
 	cmd.Flags().StringVar(&c.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Format (json|yaml)")+"``")
 
 	return cmd
 }
@@ -58,6 +65,19 @@ func (c *cmdInfo) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
+	if c.flagFormat != "" && c.flagShowLog {
+		return fmt.Errorf(i18n.G("--show-log can't be used with --format"))
+	}
+
+	if c.flagFormat != "" && c.flagProcesses {
+		return fmt.Errorf(i18n.G("--processes can't be used with --format"))
+	}
+
 	var remote string
 	var cName string
 	if len(args) == 1 {
@@ -98,6 +118,10 @@ func (c *cmdInfo) renderGPU(gpu api.ResourcesGPUCard, prefix string, initial boo
 		fmt.Printf(prefix+i18n.G("Product: %v (%v)")+"\n", gpu.Product, gpu.ProductID)
 	}
 
+	if gpu.Allocated > 0 {
+		fmt.Printf(prefix+i18n.G("Allocated: %.0f%%")+"\n", gpu.Allocated*100)
+	}
+
 	if gpu.PCIAddress != "" {
 		fmt.Printf(prefix+i18n.G("PCI address: %v")+"\n", gpu.PCIAddress)
 	}
@@ -345,6 +369,10 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 			return err
 		}
 
+		if c.flagFormat != "" {
+			return utils.RenderResult(c.flagFormat, resources)
+		}
+
 		// CPU
 		if len(resources.CPU.Sockets) == 1 {
 			fmt.Printf(i18n.G("CPU (%s):")+"\n", resources.CPU.Architecture)
@@ -357,6 +385,21 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 			}
 		}
 
+		if resources.CPU.SEV != nil {
+			modes := []string{"sev"}
+			if resources.CPU.SEV.ES {
+				modes = append(modes, "sev-es")
+			}
+
+			if resources.CPU.SEV.SNP {
+				modes = append(modes, "sev-snp")
+			}
+
+			fmt.Printf("\n" + i18n.G("Memory encryption:") + "\n")
+			fmt.Printf("  "+i18n.G("Modes: %s")+"\n", strings.Join(modes, ", "))
+			fmt.Printf("  "+i18n.G("Maximum guests: %d")+"\n", resources.CPU.SEV.MaxGuests)
+		}
+
 		// Memory
 		fmt.Printf("\n" + i18n.G("Memory:") + "\n")
 		if resources.Memory.HugepagesTotal > 0 {
@@ -379,6 +422,15 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 				fmt.Printf("      "+i18n.G("Free: %v")+"\n", units.GetByteSizeString(int64(node.Total-node.Used), 2))
 				fmt.Printf("      "+i18n.G("Used: %v")+"\n", units.GetByteSizeString(int64(node.Used), 2))
 				fmt.Printf("      "+i18n.G("Total: %v")+"\n", units.GetByteSizeString(int64(node.Total), 2))
+
+				if len(node.Distances) > 0 {
+					distances := []string{}
+					for _, distance := range node.Distances {
+						distances = append(distances, fmt.Sprintf("%d=%d", distance.NUMANode, distance.Distance))
+					}
+
+					fmt.Printf("      "+i18n.G("Distances: %s")+"\n", strings.Join(distances, ", "))
+				}
 			}
 		}
 
@@ -430,6 +482,10 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 		return err
 	}
 
+	if c.flagFormat != "" {
+		return utils.RenderResult(c.flagFormat, serverStatus)
+	}
+
 	data, err := yaml.Marshal(&serverStatus)
 	if err != nil {
 		return err
@@ -455,6 +511,10 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 		return err
 	}
 
+	if c.flagFormat != "" {
+		return utils.RenderResult(c.flagFormat, api.InstanceFull{Instance: *ct, State: cs})
+	}
+
 	const layout = "2006/01/02 15:04 UTC"
 
 	fmt.Printf(i18n.G("Name: %s")+"\n", ct.Name)
@@ -559,6 +619,9 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 				if net.Mtu != 0 {
 					networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("MTU"), net.Mtu)
 				}
+				if net.LinkSpeed != 0 {
+					networkInfo += fmt.Sprintf("      %s: %dMbit/s\n", i18n.G("Link speed"), net.LinkSpeed)
+				}
 				networkInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes received"), units.GetByteSizeString(net.Counters.BytesReceived, 2))
 				networkInfo += fmt.Sprintf("      %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(net.Counters.BytesSent, 2))
 				networkInfo += fmt.Sprintf("      %s: %d\n", i18n.G("Packets received"), net.Counters.PacketsReceived)
@@ -692,6 +755,38 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 		_ = utils.RenderTable(utils.TableFormatTable, backupHeader, backupData, backups)
 	}
 
+	if c.flagProcesses && cs.Status == "Running" {
+		top, err := d.GetInstanceProcesses(name, "cpu", 10)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("\n" + i18n.G("Processes:"))
+
+		processData := [][]string{}
+		for _, process := range top.Processes {
+			processData = append(processData, []string{
+				fmt.Sprintf("%d", process.PID),
+				fmt.Sprintf("%d", process.UID),
+				process.State,
+				fmt.Sprintf("%.1f", process.CPUUsage),
+				units.GetByteSizeString(process.MemoryUsage, 2),
+				process.Command,
+			})
+		}
+
+		processHeader := []string{
+			i18n.G("PID"),
+			i18n.G("UID"),
+			i18n.G("State"),
+			i18n.G("CPU %"),
+			i18n.G("Memory"),
+			i18n.G("Command"),
+		}
+
+		_ = utils.RenderTable(utils.TableFormatTable, processHeader, processData, top.Processes)
+	}
+
 	if showLog {
 		var log io.Reader
 		if ct.Type == "container" {
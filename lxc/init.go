@@ -32,6 +32,7 @@ type cmdInit struct {
 	flagNoProfiles bool
 	flagEmpty      bool
 	flagVM         bool
+	flagFormat     string
 }
 
 func (c *cmdInit) Command() *cobra.Command {
@@ -56,6 +57,7 @@ lxc init ubuntu:18.04 u1 < config.yaml
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagEmpty, "empty", false, i18n.G("Create an empty instance"))
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Create a virtual machine"))
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "", i18n.G("Print the new instance in the given format (json|yaml)")+"``")
 
 	return cmd
 }
@@ -72,8 +74,31 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	_, _, err = c.create(c.global.conf, args)
-	return err
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
+	d, name, err := c.create(c.global.conf, args)
+	if err != nil {
+		return err
+	}
+
+	return c.renderInstance(d, name)
+}
+
+// renderInstance prints the instance in the structured formats.
+func (c *cmdInit) renderInstance(d lxd.InstanceServer, name string) error {
+	if c.flagFormat == "" {
+		return nil
+	}
+
+	inst, _, err := d.GetInstance(name)
+	if err != nil {
+		return err
+	}
+
+	return utils.RenderResult(c.flagFormat, inst)
 }
 
 func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer, string, error) {
@@ -361,7 +386,9 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 	if len(instances) == 1 && name == "" {
 		fields := strings.Split(instances[0], "/")
 		name = fields[len(fields)-1]
-		fmt.Printf(i18n.G("Instance name is: %s")+"\n", name)
+		if !c.global.flagQuiet {
+			fmt.Printf(i18n.G("Instance name is: %s")+"\n", name)
+		}
 	}
 
 	// Validate the network setup
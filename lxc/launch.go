@@ -48,6 +48,15 @@ func (c *cmdLaunch) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.init.flagFormat)
+	if err != nil {
+		return err
+	}
+
+	if c.init.flagFormat != "" && c.flagConsole != "" {
+		return fmt.Errorf(i18n.G("--console can't be used with --format"))
+	}
+
 	// Call the matching code from init
 	d, name, err := c.init.create(conf, args)
 	if err != nil {
@@ -113,5 +122,5 @@ func (c *cmdLaunch) Run(cmd *cobra.Command, args []string) error {
 		return console.Console(d, name)
 	}
 
-	return nil
+	return c.init.renderInstance(d, name)
 }
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -37,6 +38,8 @@ type cmdList struct {
 	flagColumns string
 	flagFast    bool
 	flagFormat  string
+	flagWatch   bool
+	flagRefresh int
 
 	shorthandFilters map[string]func(*api.Instance, *api.InstanceState, string) bool
 }
@@ -63,6 +66,7 @@ A key/value pair where the key is a shorthand. Multiple values must be delimited
   - location={location name}
   - ipv4={ip or CIDR}
   - ipv6={ip or CIDR}
+  - tag={instance tag}
 
 Examples:
   - "user.blah=abc" will list all instances with the "blah" user property set to "abc".
@@ -71,6 +75,7 @@ Examples:
   - "s.privileged=true" will do the same
   - "type=container" will list all container instances
   - "type=container status=running" will list all running container instances
+  - "tag=prod" will list all instances tagged "prod"
 
 A regular expression matching a configuration item or its value. (e.g. volatile.eth0.hwaddr=00:16:3e:.*).
 
@@ -131,6 +136,8 @@ lxc list -c ns,user.comment:comment
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", defaultColumns, i18n.G("Columns")+"``")
 	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
 	cmd.Flags().BoolVar(&c.flagFast, "fast", false, i18n.G("Fast mode (same as --columns=nsacPt)"))
+	cmd.Flags().BoolVarP(&c.flagWatch, "watch", "w", false, i18n.G("Keep the list up to date as instances change"))
+	cmd.Flags().IntVar(&c.flagRefresh, "refresh", 0, i18n.G("Also refresh the list every N seconds (implies --watch)")+"``")
 
 	return cmd
 }
@@ -450,34 +457,42 @@ func (c *cmdList) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if !nameFilter && needsData && d.HasExtension("container_full") {
-		// Using the GetInstancesFull shortcut
-		cts, err := d.GetInstancesFull(api.InstanceTypeAny)
+	render := func() error {
+		if !nameFilter && needsData && d.HasExtension("container_full") {
+			// Using the GetInstancesFull shortcut
+			cts, err := d.GetInstancesFull(api.InstanceTypeAny)
+			if err != nil {
+				return err
+			}
+
+			return c.showInstances(cts, filters, columns)
+		}
+
+		// Get the list of instances
+		var cts []api.Instance
+		ctslist, err := d.GetInstances(api.InstanceTypeAny)
 		if err != nil {
 			return err
 		}
 
-		return c.showInstances(cts, filters, columns)
-	}
-
-	// Get the list of instances
-	var cts []api.Instance
-	ctslist, err := d.GetInstances(api.InstanceTypeAny)
-	if err != nil {
-		return err
-	}
+		// Apply filters
+		for _, cinfo := range ctslist {
+			if !c.shouldShow(filters, &cinfo, nil, true) {
+				continue
+			}
 
-	// Apply filters
-	for _, cinfo := range ctslist {
-		if !c.shouldShow(filters, &cinfo, nil, true) {
-			continue
+			cts = append(cts, cinfo)
 		}
 
-		cts = append(cts, cinfo)
+		// Fetch any remaining data and render the table
+		return c.listInstances(conf, d, cts, filters, columns)
 	}
 
-	// Fetch any remaining data and render the table
-	return c.listInstances(conf, d, cts, filters, columns)
+	if c.flagWatch || c.flagRefresh > 0 {
+		return watchRender(d, []string{"lifecycle"}, time.Duration(c.flagRefresh)*time.Second, render)
+	}
+
+	return render()
 }
 
 func (c *cmdList) parseColumns(clustered bool) ([]column, bool, error) {
@@ -857,6 +872,10 @@ func (c *cmdList) matchByLocation(cInfo *api.Instance, cState *api.InstanceState
 	return strings.EqualFold(cInfo.Location, query)
 }
 
+func (c *cmdList) matchByTag(cInfo *api.Instance, cState *api.InstanceState, query string) bool {
+	return shared.StringInSlice(query, cInfo.Tags)
+}
+
 func (c *cmdList) matchByNet(cInfo *api.Instance, cState *api.InstanceState, query string, family string) bool {
 	// Skip if no state.
 	if cState == nil {
@@ -912,5 +931,6 @@ func (c *cmdList) mapShorthandFilters() {
 		"location":     c.matchByLocation,
 		"ipv4":         c.matchByIPV4,
 		"ipv6":         c.matchByIPV6,
+		"tag":          c.matchByTag,
 	}
 }
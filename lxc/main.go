@@ -11,6 +11,7 @@ import (
 
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
@@ -90,6 +91,10 @@ For help with any of those, simply call them with --help.`))
 	consoleCmd := cmdConsole{global: &globalCmd}
 	app.AddCommand(consoleCmd.Command())
 
+	// convert sub-command
+	convertCmd := cmdConvert{global: &globalCmd}
+	app.AddCommand(convertCmd.Command())
+
 	// copy sub-command
 	copyCmd := cmdCopy{global: &globalCmd}
 	app.AddCommand(copyCmd.Command())
@@ -114,6 +119,10 @@ For help with any of those, simply call them with --help.`))
 	importCmd := cmdImport{global: &globalCmd}
 	app.AddCommand(importCmd.Command())
 
+	// import-bundle sub-command
+	importBundleCmd := cmdImportBundle{global: &globalCmd}
+	app.AddCommand(importBundleCmd.Command())
+
 	// info sub-command
 	infoCmd := cmdInfo{global: &globalCmd}
 	app.AddCommand(infoCmd.Command())
@@ -432,3 +441,18 @@ func (c *cmdGlobal) CheckArgs(cmd *cobra.Command, args []string, minArgs int, ma
 
 	return false, nil
 }
+
+// CheckResultFormat validates the result format of a command. In the structured formats, the progress and
+// informational messages are disabled so that only the result is printed.
+func (c *cmdGlobal) CheckResultFormat(format string) error {
+	err := utils.ValidateResultFormat(format)
+	if err != nil {
+		return err
+	}
+
+	if format != "" {
+		c.flagQuiet = true
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
@@ -23,6 +24,7 @@ type cmdMonitor struct {
 	flagLogLevel    string
 	flagAllProjects bool
 	flagFormat      string
+	flagInstance    string
 }
 
 func (c *cmdMonitor) Command() *cobra.Command {
@@ -41,7 +43,10 @@ lxc monitor --pretty --type=logging --loglevel=info
     Show a pretty log of messages with info level or higher.
 
 lxc monitor --type=lifecycle
-    Only show lifecycle events.`))
+    Only show lifecycle events.
+
+lxc monitor --type=logging --instance=c1
+    Only show log messages related to instance c1, including the ones of its lxc or qemu process.`))
 	cmd.Hidden = true
 
 	cmd.RunE = c.Run
@@ -50,6 +55,7 @@ lxc monitor --type=lifecycle
 	cmd.Flags().StringArrayVar(&c.flagType, "type", nil, i18n.G("Event type to listen for")+"``")
 	cmd.Flags().StringVar(&c.flagLogLevel, "loglevel", "", i18n.G("Minimum level for log messages")+"``")
 	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "yaml", i18n.G("Format (json|pretty|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagInstance, "instance", "", i18n.G("Only show events related to this instance")+"``")
 
 	return cmd
 }
@@ -94,10 +100,20 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	if c.flagAllProjects {
+		if c.flagInstance != "" {
+			return fmt.Errorf(i18n.G("--instance can't be used with --all-projects"))
+		}
+
 		d = d.UseProject("*")
 	}
 
-	listener, err := d.GetEvents()
+	var listener *lxd.EventListener
+	if c.flagInstance != "" {
+		listener, err = d.GetInstanceEvents(c.flagInstance, c.flagType)
+	} else {
+		listener, err = d.GetEvents()
+	}
+
 	if err != nil {
 		return err
 	}
@@ -44,7 +44,10 @@ lxc move <old name> <new name> [--instance-only]
     Rename a local instance.
 
 lxc move <instance>/<old snapshot name> <instance>/<new snapshot name>
-    Rename a snapshot.`))
+    Rename a snapshot.
+
+lxc move <instance> --target-project <project>
+    Move an instance, along with its snapshots, into another project.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the target instance")+"``")
@@ -56,7 +59,7 @@ lxc move <instance>/<old snapshot name> <instance>/<new snapshot name>
 	cmd.Flags().BoolVar(&c.flagStateless, "stateless", false, i18n.G("Copy a stateful instance stateless"))
 	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
 	cmd.Flags().StringVar(&c.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
-	cmd.Flags().StringVar(&c.flagTargetProject, "target-project", "", i18n.G("Copy to a project different from the source")+"``")
+	cmd.Flags().StringVar(&c.flagTargetProject, "target-project", "", i18n.G("Move to a project different from the source")+"``")
 
 	return cmd
 }
@@ -182,6 +185,27 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Support for server-side project move.
+	overrides := c.flagConfig != nil || c.flagDevice != nil || c.flagProfile != nil || c.flagNoProfiles
+	if c.flagTargetProject != "" && c.flagStorage == "" && !overrides && sourceRemote == destRemote {
+		source, err := conf.GetInstanceServer(sourceRemote)
+		if err != nil {
+			return err
+		}
+
+		if source.HasExtension("instance_project_move") {
+			if c.flagStateless {
+				return fmt.Errorf(i18n.G("The --stateless flag can't be used with --target-project"))
+			}
+
+			if c.flagMode != moveDefaultMode {
+				return fmt.Errorf(i18n.G("The --mode flag can't be used with --target-project"))
+			}
+
+			return moveInstanceProject(conf, sourceResource, destResource, c.flagInstanceOnly, c.flagTargetProject)
+		}
+	}
+
 	// Support for server-side pool move.
 	if c.flagStorage != "" && sourceRemote == destRemote {
 		source, err := conf.GetInstanceServer(sourceRemote)
@@ -356,5 +380,55 @@ func moveInstancePool(conf *config.Config, sourceResource string, destResource s
 	return nil
 }
 
+// Move an instance to another project using special POST /instances/<name> API.
+func moveInstanceProject(conf *config.Config, sourceResource string, destResource string, instanceOnly bool, targetProject string) error {
+	// Parse the source.
+	sourceRemote, sourceName, err := conf.ParseRemote(sourceResource)
+	if err != nil {
+		return err
+	}
+
+	// Parse the destination.
+	_, destName, err := conf.ParseRemote(destResource)
+	if err != nil {
+		return err
+	}
+
+	// Make sure we have an instance name.
+	if sourceName == "" {
+		return fmt.Errorf(i18n.G("You must specify a source instance name"))
+	}
+
+	// The destination name is optional.
+	if destName == "" {
+		destName = sourceName
+	}
+
+	source, err := conf.GetInstanceServer(sourceRemote)
+	if err != nil {
+		return err
+	}
+
+	// Pass the new project to the migration API.
+	req := api.InstancePost{
+		Name:         destName,
+		Migration:    true,
+		Project:      targetProject,
+		InstanceOnly: instanceOnly,
+	}
+
+	op, err := source.MigrateInstance(sourceName, req)
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Migration API failure"))
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Migration operation failure"))
+	}
+
+	return nil
+}
+
 // Default migration mode when moving an instance.
 const moveDefaultMode = "pull"
@@ -810,6 +810,46 @@ func (c *cmdNetworkInfo) Run(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  %s: %d\n", i18n.G("Packets received"), state.Counters.PacketsReceived)
 	fmt.Printf("  %s: %d\n", i18n.G("Packets sent"), state.Counters.PacketsSent)
 
+	// OVN gateway
+	if state.OVN != nil {
+		fmt.Println("")
+		fmt.Println(i18n.G("OVN:"))
+		fmt.Printf("  %s: %s\n", i18n.G("Chassis"), state.OVN.Chassis)
+		for _, gateway := range state.OVN.Gateways {
+			fmt.Printf("  %s: %s (%s: %d)\n", i18n.G("Gateway"), gateway.Chassis, i18n.G("priority"), gateway.Priority)
+		}
+	}
+
+	// LLDP neighbours
+	if len(state.LLDP) > 0 {
+		fmt.Println("")
+		fmt.Println(i18n.G("LLDP neighbours:"))
+		for _, neighbour := range state.LLDP {
+			fmt.Printf("  %s: %s (%s)\n", neighbour.Interface, neighbour.SystemName, neighbour.ChassisID)
+			fmt.Printf("    %s: %s\n", i18n.G("Port"), neighbour.PortID)
+			if neighbour.PortDescription != "" {
+				fmt.Printf("    %s: %s\n", i18n.G("Port description"), neighbour.PortDescription)
+			}
+
+			if neighbour.PortVLAN > 0 {
+				fmt.Printf("    %s: %d\n", i18n.G("Port VLAN"), neighbour.PortVLAN)
+			}
+
+			if len(neighbour.VLANs) > 0 {
+				vlans := make([]string, 0, len(neighbour.VLANs))
+				for _, vlan := range neighbour.VLANs {
+					vlans = append(vlans, fmt.Sprintf("%d", vlan))
+				}
+
+				fmt.Printf("    %s: %s\n", i18n.G("VLANs"), strings.Join(vlans, ", "))
+			}
+
+			if len(neighbour.ManagementAddresses) > 0 {
+				fmt.Printf("    %s: %s\n", i18n.G("Management addresses"), strings.Join(neighbour.ManagementAddresses, ", "))
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -94,7 +95,9 @@ type cmdOperationList struct {
 	global    *cmdGlobal
 	operation *cmdOperation
 
-	flagFormat string
+	flagFormat  string
+	flagWatch   bool
+	flagRefresh int
 }
 
 func (c *cmdOperationList) Command() *cobra.Command {
@@ -105,6 +108,8 @@ func (c *cmdOperationList) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List background operations`))
 	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().BoolVarP(&c.flagWatch, "watch", "w", false, i18n.G("Keep the list up to date as operations change"))
+	cmd.Flags().IntVar(&c.flagRefresh, "refresh", 0, i18n.G("Also refresh the list every N seconds (implies --watch)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -134,41 +139,49 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
 	}
 
-	// Get operations
-	operations, err := resource.server.GetOperations()
-	if err != nil {
-		return err
-	}
-
-	// Render the table
-	data := [][]string{}
-	for _, op := range operations {
-		cancelable := i18n.G("NO")
-		if op.MayCancel {
-			cancelable = i18n.G("YES")
+	render := func() error {
+		// Get operations
+		operations, err := resource.server.GetOperations()
+		if err != nil {
+			return err
 		}
 
-		entry := []string{op.ID, strings.ToUpper(op.Class), op.Description, strings.ToUpper(op.Status), cancelable, op.CreatedAt.UTC().Format("2006/01/02 15:04 UTC")}
+		// Render the table
+		data := [][]string{}
+		for _, op := range operations {
+			cancelable := i18n.G("NO")
+			if op.MayCancel {
+				cancelable = i18n.G("YES")
+			}
+
+			entry := []string{op.ID, strings.ToUpper(op.Class), op.Description, strings.ToUpper(op.Status), cancelable, op.CreatedAt.UTC().Format("2006/01/02 15:04 UTC")}
+			if resource.server.IsClustered() {
+				entry = append(entry, op.Location)
+			}
+
+			data = append(data, entry)
+		}
+		sort.Sort(byName(data))
+
+		header := []string{
+			i18n.G("ID"),
+			i18n.G("TYPE"),
+			i18n.G("DESCRIPTION"),
+			i18n.G("STATUS"),
+			i18n.G("CANCELABLE"),
+			i18n.G("CREATED")}
 		if resource.server.IsClustered() {
-			entry = append(entry, op.Location)
+			header = append(header, i18n.G("LOCATION"))
 		}
 
-		data = append(data, entry)
+		return utils.RenderTable(c.flagFormat, header, data, operations)
 	}
-	sort.Sort(byName(data))
-
-	header := []string{
-		i18n.G("ID"),
-		i18n.G("TYPE"),
-		i18n.G("DESCRIPTION"),
-		i18n.G("STATUS"),
-		i18n.G("CANCELABLE"),
-		i18n.G("CREATED")}
-	if resource.server.IsClustered() {
-		header = append(header, i18n.G("LOCATION"))
+
+	if c.flagWatch || c.flagRefresh > 0 {
+		return watchRender(resource.server, []string{"operation"}, time.Duration(c.flagRefresh)*time.Second, render)
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, operations)
+	return render()
 }
 
 // Show
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -70,6 +71,10 @@ func (c *cmdProject) Command() *cobra.Command {
 	projectGetInfo := cmdProjectInfo{global: c.global, project: c}
 	cmd.AddCommand(projectGetInfo.Command())
 
+	// Usage
+	projectUsageCmd := cmdProjectUsage{global: c.global, project: c}
+	cmd.AddCommand(projectUsageCmd.Command())
+
 	// Set default
 	projectSwitchCmd := cmdProjectSwitch{global: c.global, project: c}
 	cmd.AddCommand(projectSwitchCmd.Command())
@@ -810,3 +815,131 @@ func (c *cmdProjectInfo) Run(cmd *cobra.Command, args []string) error {
 
 	return utils.RenderTable(c.flagFormat, header, data, projectState)
 }
+
+// Usage
+type cmdProjectUsage struct {
+	global  *cmdGlobal
+	project *cmdProject
+
+	flagStart    string
+	flagEnd      string
+	flagInterval string
+	flagFormat   string
+}
+
+func (c *cmdProjectUsage) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("usage", i18n.G("[<remote>:]<project>"))
+	cmd.Short = i18n.G("Show the resource usage history of a project")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the resource usage history of a project
+
+The CPU time, memory, disk space and network traffic used by the instances of the
+project are accounted over time, for chargeback or showback.
+Times are either dates (YYYY-MM-DD) or RFC3339 timestamps, in UTC.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc project usage foo --start 2021-06-01 --end 2021-07-01 --interval month
+    Show the usage of the "foo" project for June 2021.`))
+	cmd.Flags().StringVar(&c.flagStart, "start", "", i18n.G("Start of the time range (defaults to 30 days before the end)")+"``")
+	cmd.Flags().StringVar(&c.flagEnd, "end", "", i18n.G("End of the time range (defaults to now)")+"``")
+	cmd.Flags().StringVar(&c.flagInterval, "interval", "day", i18n.G("Granularity of the usage (hour|day|month)")+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+// parseTime parses a date or RFC3339 timestamp.
+func (c *cmdProjectUsage) parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(i18n.G("Invalid time %q (must be YYYY-MM-DD or RFC3339)"), value)
+	}
+
+	return t, nil
+}
+
+func (c *cmdProjectUsage) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	start, err := c.parseTime(c.flagStart)
+	if err != nil {
+		return err
+	}
+
+	end, err := c.parseTime(c.flagEnd)
+	if err != nil {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing project name"))
+	}
+
+	usage, err := resource.server.GetProjectUsage(resource.name, start, end, c.flagInterval)
+	if err != nil {
+		return err
+	}
+
+	// Render the output
+	periodFormat := "2006-01-02 15:04"
+	switch usage.Interval {
+	case "day":
+		periodFormat = "2006-01-02"
+	case "month":
+		periodFormat = "2006-01"
+	}
+
+	row := func(name string, period api.ProjectUsagePeriod) []string {
+		return []string{
+			name,
+			(time.Duration(period.CPUSeconds) * time.Second).String(),
+			fmt.Sprintf("%s-h", units.GetByteSizeStringIEC(int64(period.MemoryByteHours), 2)),
+			fmt.Sprintf("%s-h", units.GetByteSizeStringIEC(int64(period.DiskByteHours), 2)),
+			units.GetByteSizeStringIEC(period.NetworkRxBytes, 2),
+			units.GetByteSizeStringIEC(period.NetworkTxBytes, 2),
+		}
+	}
+
+	data := [][]string{}
+	for _, period := range usage.Periods {
+		data = append(data, row(period.Start.Format(periodFormat), period))
+	}
+
+	if c.flagFormat == "table" {
+		data = append(data, row(i18n.G("TOTAL"), usage.Total))
+	}
+
+	header := []string{
+		i18n.G("PERIOD"),
+		i18n.G("CPU TIME"),
+		i18n.G("MEMORY"),
+		i18n.G("DISK"),
+		i18n.G("NETWORK RX"),
+		i18n.G("NETWORK TX"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, usage)
+}
@@ -25,6 +25,7 @@ type cmdPublish struct {
 	flagExpiresAt            string
 	flagMakePublic           bool
 	flagForce                bool
+	flagFormat               string
 }
 
 func (c *cmdPublish) Command() *cobra.Command {
@@ -40,6 +41,7 @@ func (c *cmdPublish) Command() *cobra.Command {
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Stop the instance if currently running"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Compression algorithm to use (`none` for uncompressed)"))
 	cmd.Flags().StringVar(&c.flagExpiresAt, "expire", "", i18n.G("Image expiration date (format: rfc3339)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "", i18n.G("Print the new image in the given format (json|yaml)")+"``")
 
 	return cmd
 }
@@ -58,6 +60,11 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	err = c.global.CheckResultFormat(c.flagFormat)
+	if err != nil {
+		return err
+	}
+
 	cRemote, cName, err := conf.ParseRemote(args[0])
 	if err != nil {
 		return err
@@ -286,6 +293,15 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if c.flagFormat != "" {
+		image, _, err := d.GetImage(fingerprint)
+		if err != nil {
+			return err
+		}
+
+		return utils.RenderResult(c.flagFormat, image)
+	}
+
 	fmt.Printf(i18n.G("Instance published with fingerprint: %s")+"\n", fingerprint)
 
 	return nil
@@ -16,9 +16,10 @@ import (
 type cmdSnapshot struct {
 	global *cmdGlobal
 
-	flagStateful bool
-	flagNoExpiry bool
-	flagReuse    bool
+	flagStateful    bool
+	flagAllProjects bool
+	flagNoExpiry    bool
+	flagReuse       bool
 }
 
 func (c *cmdSnapshot) Command() *cobra.Command {
@@ -29,22 +30,27 @@ func (c *cmdSnapshot) Command() *cobra.Command {
 		`Create instance snapshots
 
 When --stateful is used, LXD attempts to checkpoint the instance's
-running state, including process memory state, TCP connections, ...`))
+running state, including process memory state, TCP connections, ...
+
+The instance name can be a glob pattern, in which case all the matching
+instances are snapshotted in parallel.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc snapshot u1 snap0
-    Create a snapshot of "u1" called "snap0".`))
+    Create a snapshot of "u1" called "snap0".
+
+lxc snapshot --all-projects "web-*" before-upgrade
+    Create a snapshot called "before-upgrade" of all the instances whose name starts with "web-", in all projects.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Whether or not to snapshot the instance's running state"))
 	cmd.Flags().BoolVar(&c.flagNoExpiry, "no-expiry", false, i18n.G("Ignore any configured auto-expiry for the instance"))
 	cmd.Flags().BoolVar(&c.flagReuse, "reuse", false, i18n.G("If the snapshot name already exists, delete and create a new one"))
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Match the instances of all projects"))
 
 	return cmd
 }
 
 func (c *cmdSnapshot) Run(cmd *cobra.Command, args []string) error {
-	conf := c.global.conf
-
 	// Quick checks.
 	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
 	if exit {
@@ -58,11 +64,30 @@ func (c *cmdSnapshot) Run(cmd *cobra.Command, args []string) error {
 		snapname = args[1]
 	}
 
-	remote, name, err := conf.ParseRemote(args[0])
+	// Resolve the instances
+	targets, err := c.global.ParseInstanceTargets(c.flagAllProjects, args[0])
 	if err != nil {
 		return err
 	}
 
+	// Snapshot them all in parallel
+	results := runTargets(targets, func(target instanceTarget) error { return c.snapshot(target, snapname) })
+
+	// Single instance is easy
+	if len(results) == 1 {
+		return results[0].err
+	}
+
+	// Render a summary for batches
+	if !printTargetResults(results, c.global.flagQuiet) {
+		return fmt.Errorf(i18n.G("Some instances failed to %s"), cmd.Name())
+	}
+
+	return nil
+}
+
+func (c *cmdSnapshot) snapshot(target instanceTarget, snapname string) error {
+	name := target.name
 	if shared.IsSnapshot(name) {
 		if snapname == "" {
 			fields := strings.SplitN(name, shared.SnapshotDelimiter, 2)
@@ -73,10 +98,7 @@ func (c *cmdSnapshot) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	d, err := conf.GetInstanceServer(remote)
-	if err != nil {
-		return err
-	}
+	d := target.server
 
 	if c.flagReuse && snapname != "" {
 		snap, _, _ := d.GetInstanceSnapshot(name, snapname)
@@ -57,6 +57,10 @@ func (c *cmdStorage) Command() *cobra.Command {
 	storageListCmd := cmdStorageList{global: c.global, storage: c}
 	cmd.AddCommand(storageListCmd.Command())
 
+	// Move source
+	storageMoveSourceCmd := cmdStorageMoveSource{global: c.global, storage: c}
+	cmd.AddCommand(storageMoveSourceCmd.Command())
+
 	// Set
 	storageSetCmd := cmdStorageSet{global: c.global, storage: c}
 	cmd.AddCommand(storageSetCmd.Command())
@@ -583,6 +587,90 @@ func (c *cmdStorageList) Run(cmd *cobra.Command, args []string) error {
 	return utils.RenderTable(c.flagFormat, header, data, pools)
 }
 
+// Move source
+type cmdStorageMoveSource struct {
+	global  *cmdGlobal
+	storage *cmdStorage
+}
+
+func (c *cmdStorageMoveSource) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = usage("move-source", i18n.G("[<remote>:]<pool> <block device>"))
+	cmd.Short = i18n.G("Move loop file backed storage pools onto block devices")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Move loop file backed storage pools onto block devices
+
+The data is moved while the storage pool is in use and the loop file is removed once done.
+This is supported for the btrfs, lvm and zfs storage pools.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage move-source default /dev/sdb
+    Moves the "default" storage pool from its loop file onto /dev/sdb.`))
+
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageMoveSource) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	client := resource.server
+
+	// If a target member was specified, move the source of the pool on that member
+	if c.storage.flagTarget != "" {
+		client = client.UseTarget(c.storage.flagTarget)
+	}
+
+	op, err := client.UpdateStoragePoolSource(resource.name, api.StoragePoolSourcePost{Source: args[1]})
+	if err != nil {
+		return err
+	}
+
+	// Register progress handler
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Moving the storage pool: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage pool %s moved onto %s")+"\n", resource.name, args[1])
+	}
+
+	return nil
+}
+
 // Set
 type cmdStorageSet struct {
 	global  *cmdGlobal
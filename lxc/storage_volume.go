@@ -317,9 +317,10 @@ type cmdStorageVolumeCopy struct {
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
 
-	flagMode          string
-	flagVolumeOnly    bool
-	flagTargetProject string
+	flagMode              string
+	flagVolumeOnly        bool
+	flagTargetProject     string
+	flagDestinationTarget string
 }
 
 func (c *cmdStorageVolumeCopy) Command() *cobra.Command {
@@ -389,6 +390,18 @@ func (c *cmdStorageVolumeCopy) Run(cmd *cobra.Command, args []string) error {
 		dstServer = dstServer.UseProject(c.flagTargetProject)
 	}
 
+	// Moves to another cluster member are done by the server in a single operation.
+	if c.flagDestinationTarget != "" {
+		if srcServer != dstServer {
+			return fmt.Errorf(i18n.G("--destination-target can only be used within the same remote and project"))
+		}
+
+		if c.storage.flagTarget != "" {
+			srcServer = srcServer.UseTarget(c.storage.flagTarget)
+			dstServer = srcServer
+		}
+	}
+
 	var op lxd.RemoteOperation
 
 	// Messages
@@ -426,6 +439,7 @@ func (c *cmdStorageVolumeCopy) Run(cmd *cobra.Command, args []string) error {
 		args.Name = dstVolName
 		args.Mode = mode
 		args.VolumeOnly = false
+		args.Location = c.flagDestinationTarget
 
 		if isSnapshot {
 			srcVol.Name = srcVolName
@@ -1373,10 +1387,14 @@ func (c *cmdStorageVolumeMove) Command() *cobra.Command {
 	cmd.Short = i18n.G("Move storage volumes between pools")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Move storage volumes between pools`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage volume move default/foo default/foo --target lxd01 --destination-target lxd02
+    Move the "foo" volume of the "default" pool from cluster member lxd01 to lxd02.`))
 
 	cmd.Flags().StringVar(&c.storageVolumeCopy.flagMode, "mode", "pull", i18n.G("Transfer mode, one of pull (default), push or relay")+"``")
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
 	cmd.Flags().StringVar(&c.storageVolumeCopy.flagTargetProject, "target-project", "", i18n.G("Move to a project different from the source")+"``")
+	cmd.Flags().StringVar(&c.storageVolumeCopy.flagDestinationTarget, "destination-target", "", i18n.G("Cluster member to move the volume to")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -2025,6 +2043,8 @@ type cmdStorageVolumeImport struct {
 	global        *cmdGlobal
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
+
+	flagType string
 }
 
 func (c *cmdStorageVolumeImport) Command() *cobra.Command {
@@ -2032,11 +2052,15 @@ func (c *cmdStorageVolumeImport) Command() *cobra.Command {
 	cmd.Use = usage("import", i18n.G("[<remote>:]<pool> <backup file> [<volume name>]"))
 	cmd.Short = i18n.G("Import custom storage volumes")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Import backups of custom volumes including their snapshots.`))
+		`Import backups of custom volumes including their snapshots, or ISO images as custom ISO volumes.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc storage volume import default backup0.tar.gz
-		Create a new custom volume using backup0.tar.gz as the source.`))
+		Create a new custom volume using backup0.tar.gz as the source.
+
+lxc storage volume import default ubuntu.iso ubuntu-iso --type=iso
+		Create a new custom ISO volume using ubuntu.iso as the source.`))
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVar(&c.flagType, "type", "backup", i18n.G("Import type, backup or iso")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -2083,6 +2107,14 @@ func (c *cmdStorageVolumeImport) Run(cmd *cobra.Command, args []string) error {
 		volName = args[2]
 	}
 
+	if !shared.StringInSlice(c.flagType, []string{"backup", "iso"}) {
+		return fmt.Errorf(i18n.G("Invalid import type %q"), c.flagType)
+	}
+
+	if c.flagType == "iso" && volName == "" {
+		return fmt.Errorf(i18n.G("A volume name is required when importing an ISO image"))
+	}
+
 	progress := utils.ProgressRenderer{
 		Format: i18n.G("Importing custom volume: %s"),
 		Quiet:  c.global.flagQuiet,
@@ -2101,7 +2133,13 @@ func (c *cmdStorageVolumeImport) Run(cmd *cobra.Command, args []string) error {
 		Name: volName,
 	}
 
-	op, err := d.CreateStoragePoolVolumeFromBackup(pool, createArgs)
+	var op lxd.Operation
+	if c.flagType == "iso" {
+		op, err = d.CreateStoragePoolVolumeFromISO(pool, createArgs)
+	} else {
+		op, err = d.CreateStoragePoolVolumeFromBackup(pool, createArgs)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fvbommel/sortorder"
 	"github.com/pkg/errors"
 
 	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/lxc/lxd/shared/termios"
@@ -99,29 +104,177 @@ func (a byNameAndType) Less(i, j int) bool {
 	return sortorder.NaturalLess(a[i][1], a[j][1])
 }
 
-// Batch operations
-type batchResult struct {
-	err  error
-	name string
+// instanceTarget is an instance to run a command against, resolved from the command line arguments.
+type instanceTarget struct {
+	server  lxd.InstanceServer // Connected to the project of the instance.
+	remote  string
+	project string // Only set when targeting all projects.
+	name    string
+	display string // How the instance is referred to in the output.
 }
 
-func runBatch(names []string, action func(name string) error) []batchResult {
-	chResult := make(chan batchResult, len(names))
+// targetResult is the outcome of running a command against an instance target.
+type targetResult struct {
+	target instanceTarget
+	err    error
+}
+
+// isInstancePattern returns whether the instance name is a glob pattern.
+func isInstancePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchInstances returns the names of the instances matching the glob pattern.
+func matchInstances(pattern string, instances []api.Instance) ([]string, error) {
+	names := []string{}
+	for _, inst := range instances {
+		match, err := filepath.Match(pattern, inst.Name)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.G("Invalid instance pattern %q: %v"), pattern, err)
+		}
 
-	for _, name := range names {
-		go func(name string) {
-			chResult <- batchResult{action(name), name}
-		}(name)
+		if match {
+			names = append(names, inst.Name)
+		}
 	}
 
-	results := []batchResult{}
-	for range names {
-		results = append(results, <-chResult)
+	return names, nil
+}
+
+// ParseInstanceTargets resolves [<remote>:]<instance> arguments into the instances to run a command against.
+// Instance names can be glob patterns, matched against the instances of the current project or, when allProjects
+// is set, of all projects. Snapshot names are never expanded.
+func (c *cmdGlobal) ParseInstanceTargets(allProjects bool, args ...string) ([]instanceTarget, error) {
+	resources, err := c.ParseServers(args...)
+	if err != nil {
+		return nil, err
 	}
 
+	targets := []instanceTarget{}
+	seen := map[string]bool{}
+
+	for i, resource := range resources {
+		if shared.IsSnapshot(resource.name) || (!isInstancePattern(resource.name) && !allProjects) {
+			targets = append(targets, instanceTarget{server: resource.server, remote: resource.remote, name: resource.name, display: args[i]})
+			continue
+		}
+
+		// Keep the remote in the output only if it was specified.
+		prefix := ""
+		if strings.Contains(args[i], ":") {
+			prefix = fmt.Sprintf("%s:", resource.remote)
+		}
+
+		projects := []string{""}
+		if allProjects {
+			projects, err = resource.server.GetProjectNames()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		found := false
+		for _, projectName := range projects {
+			server := resource.server
+			if projectName != "" {
+				server = server.UseProject(projectName)
+			}
+
+			instances, err := server.GetInstances(api.InstanceTypeAny)
+			if err != nil {
+				return nil, err
+			}
+
+			names, err := matchInstances(resource.name, instances)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, name := range names {
+				found = true
+
+				key := fmt.Sprintf("%s:%s/%s", resource.remote, projectName, name)
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+				targets = append(targets, instanceTarget{server: server, remote: resource.remote, project: projectName, name: name, display: prefix + name})
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf(i18n.G("No instance matches %q"), args[i])
+		}
+	}
+
+	return targets, nil
+}
+
+// runTargets runs the action against all the targets in parallel and returns the results in the order of the
+// targets.
+func runTargets(targets []instanceTarget, action func(target instanceTarget) error) []targetResult {
+	results := make([]targetResult, len(targets))
+
+	wg := sync.WaitGroup{}
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target instanceTarget) {
+			defer wg.Done()
+			results[i] = targetResult{target: target, err: action(target)}
+		}(i, target)
+	}
+
+	wg.Wait()
+
 	return results
 }
 
+// printTargetResults renders a summary table of the results, unless quiet, and returns whether they all succeeded.
+func printTargetResults(results []targetResult, quiet bool) bool {
+	success := true
+	showProjects := false
+	for _, result := range results {
+		if result.err != nil {
+			success = false
+		}
+
+		if result.target.project != "" {
+			showProjects = true
+		}
+	}
+
+	if quiet && success {
+		return success
+	}
+
+	data := [][]string{}
+	for _, result := range results {
+		status := i18n.G("OK")
+		if result.err != nil {
+			status = fmt.Sprintf(i18n.G("Error: %s"), strings.Replace(result.err.Error(), "\n", " ", -1))
+		}
+
+		row := []string{result.target.display}
+		if showProjects {
+			row = append(row, result.target.project)
+		}
+
+		data = append(data, append(row, status))
+	}
+
+	header := []string{i18n.G("NAME")}
+	if showProjects {
+		header = append(header, i18n.G("PROJECT"))
+	}
+
+	header = append(header, i18n.G("RESULT"))
+
+	utils.RenderTable(utils.TableFormatTable, header, data, nil)
+
+	return success
+}
+
 // Add a device to an instance
 func instanceDeviceAdd(client lxd.InstanceServer, name string, devName string, dev map[string]string) error {
 	// Get the instance entry
@@ -265,3 +418,74 @@ func usage(name string, args ...string) string {
 
 	return name + " " + args[0]
 }
+
+// watchMinInterval is the minimum time between two renders in watch mode.
+const watchMinInterval = time.Second
+
+// watchRender runs render and runs it again, clearing the terminal first, whenever an event of one of the given
+// types is received from the server and every refresh interval (if not zero), until interrupted.
+func watchRender(d lxd.InstanceServer, eventTypes []string, refresh time.Duration, render func() error) error {
+	listener, err := d.GetEvents()
+	if err != nil {
+		return err
+	}
+
+	defer listener.Disconnect()
+
+	// Bursts of events only trigger a single render.
+	changed := make(chan struct{}, 1)
+	_, err = listener.AddHandler(eventTypes, func(event api.Event) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	chError := make(chan error, 1)
+	go func() {
+		chError <- listener.Wait()
+	}()
+
+	var tick <-chan time.Time
+	if refresh > 0 {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	clear := termios.IsTerminal(getStdoutFd())
+	for {
+		if clear {
+			fmt.Print("\033[H\033[2J")
+		}
+
+		err := render()
+		if err != nil {
+			return err
+		}
+
+		lastRender := time.Now()
+
+		select {
+		case <-changed:
+		case <-tick:
+		case err := <-chError:
+			if err != nil {
+				return errors.Wrap(err, i18n.G("Lost connection to the event stream"))
+			}
+
+			return nil
+		}
+
+		time.Sleep(time.Until(lastRender.Add(watchMinInterval)))
+
+		// Drop the events received in the meantime as they're covered by the next render.
+		select {
+		case <-changed:
+		default:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// ValidateResultFormat checks that the format is a supported result format. The empty format is the default
+// human readable output of the commands.
+func ValidateResultFormat(format string) error {
+	switch format {
+	case "", TableFormatJSON, TableFormatYAML:
+		return nil
+	}
+
+	return fmt.Errorf(i18n.G("Invalid format %q"), format)
+}
+
+// RenderResult prints the result of a command in JSON or YAML format. Nothing is printed for the default format,
+// the commands printing their own human readable messages instead.
+func RenderResult(format string, result interface{}) error {
+	switch format {
+	case "":
+		return nil
+	case TableFormatJSON:
+		return json.NewEncoder(os.Stdout).Encode(result)
+	case TableFormatYAML:
+		out, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s", out)
+		return nil
+	}
+
+	return fmt.Errorf(i18n.G("Invalid format %q"), format)
+}
@@ -57,3 +57,26 @@ func (s *utilsTestSuite) TestGetExistingAliasesEmpty() {
 	aliases := GetExistingAliases([]string{"other1", "other2"}, images)
 	s.Exactly([]api.ImageAliasesEntry{}, aliases)
 }
+
+func (s *utilsTestSuite) TestMatchInstances() {
+	instances := []api.Instance{
+		{Name: "web-1"},
+		{Name: "web-2"},
+		{Name: "db-1"},
+	}
+
+	names, err := matchInstances("web-*", instances)
+	s.NoError(err)
+	s.Equal([]string{"web-1", "web-2"}, names)
+
+	names, err = matchInstances("*-1", instances)
+	s.NoError(err)
+	s.Equal([]string{"web-1", "db-1"}, names)
+
+	names, err = matchInstances("db-1", instances)
+	s.NoError(err)
+	s.Equal([]string{"db-1"}, names)
+
+	_, err = matchInstances("web-[", instances)
+	s.Error(err)
+}
@@ -19,9 +19,12 @@ var api10 = []APIEndpoint{
 	execCmd,
 	eventsCmd,
 	fileCmd,
+	fileWatchCmd,
 	operationsCmd,
 	operationCmd,
 	operationWebsocket,
+	processesCmd,
+	sevReportCmd,
 	stateCmd,
 }
 
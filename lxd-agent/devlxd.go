@@ -33,9 +33,10 @@ type devLxdResponse struct {
 }
 
 type instanceData struct {
-	Name     string            `json:"name"`
-	Location string            `json:"location"`
-	Config   map[string]string `json:"config,omitempty"`
+	Name        string            `json:"name"`
+	Location    string            `json:"location"`
+	Config      map[string]string `json:"config,omitempty"`
+	Environment map[string]string `json:"environment,omitempty"`
 }
 
 func okResponse(ct interface{}, ctype string) *devLxdResponse {
@@ -102,6 +103,26 @@ var devlxdConfigKeyGet = devLxdHandler{"/1.0/config/{key}", func(d *Daemon, w ht
 	return okResponse(value, "raw")
 }}
 
+var devlxdEnvironmentGet = devLxdHandler{"/1.0/environment", func(d *Daemon, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	data, err := ioutil.ReadFile("instance-data")
+	if err != nil {
+		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+	}
+
+	var instance instanceData
+
+	err = json.Unmarshal(data, &instance)
+	if err != nil {
+		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+	}
+
+	if instance.Environment == nil {
+		instance.Environment = map[string]string{}
+	}
+
+	return okResponse(instance.Environment, "json")
+}}
+
 var devlxdMetadataGet = devLxdHandler{"/1.0/meta-data", func(d *Daemon, w http.ResponseWriter, r *http.Request) *devLxdResponse {
 	data, err := ioutil.ReadFile("instance-data")
 	if err != nil {
@@ -150,6 +171,7 @@ var handlers = []devLxdHandler{
 	devlxdAPIGet,
 	devlxdConfigGet,
 	devlxdConfigKeyGet,
+	devlxdEnvironmentGet,
 	devlxdMetadataGet,
 	devLxdEventsGet,
 }
@@ -9,10 +9,8 @@ import (
 	"os/exec"
 	"strconv"
 	"sync"
-	"syscall"
 
 	"github.com/gorilla/websocket"
-	"golang.org/x/sys/unix"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/operations"
@@ -20,7 +18,6 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
-	"github.com/lxc/lxd/shared/netutils"
 )
 
 var execCmd = APIEndpoint{
@@ -50,43 +47,9 @@ func execPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	// Set default value for PATH
-	_, ok := env["PATH"]
-	if !ok {
-		env["PATH"] = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
-	}
-
-	if shared.PathExists("/snap/bin") {
-		env["PATH"] = fmt.Sprintf("%s:/snap/bin", env["PATH"])
-	}
-
-	// If running as root, set some env variables
-	if post.User == 0 {
-		// Set default value for HOME
-		_, ok = env["HOME"]
-		if !ok {
-			env["HOME"] = "/root"
-		}
-
-		// Set default value for USER
-		_, ok = env["USER"]
-		if !ok {
-			env["USER"] = "root"
-		}
-	}
-
-	// Set default value for LANG
-	_, ok = env["LANG"]
-	if !ok {
-		env["LANG"] = "C.UTF-8"
-	}
-
-	// Set the default working directory
-	if post.Cwd == "" {
-		post.Cwd = env["HOME"]
-		if post.Cwd == "" {
-			post.Cwd = "/"
-		}
+	err = execDefaults(env, &post)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
 	ws := &execWs{}
@@ -221,7 +184,7 @@ func (s *execWs) Do(op *operations.Operation) error {
 	if s.interactive {
 		ttys = make([]*os.File, 1)
 		ptys = make([]*os.File, 1)
-		ptys[0], ttys[0], err = shared.OpenPty(int64(s.uid), int64(s.gid))
+		ptys[0], ttys[0], err = execOpenPty(s.uid, s.gid)
 		if err != nil {
 			return err
 		}
@@ -231,7 +194,7 @@ func (s *execWs) Do(op *operations.Operation) error {
 		stderr = ttys[0]
 
 		if s.width > 0 && s.height > 0 {
-			shared.SetSize(int(ptys[0].Fd()), s.width, s.height)
+			execSetSize(ptys[0], s.width, s.height)
 		}
 	} else {
 		ttys = make([]*os.File, 3)
@@ -291,7 +254,7 @@ func (s *execWs) Do(op *operations.Operation) error {
 					}
 
 					// If an abnormal closure occurred, kill the attached process.
-					err := unix.Kill(attachedChildPid, unix.SIGKILL)
+					err := execKill(attachedChildPid, execSignalKill)
 					if err != nil {
 						logger.Errorf("Failed to send SIGKILL to pid %d", attachedChildPid)
 					} else {
@@ -326,13 +289,13 @@ func (s *execWs) Do(op *operations.Operation) error {
 						continue
 					}
 
-					err = shared.SetSize(int(ptys[0].Fd()), winchWidth, winchHeight)
+					err = execSetSize(ptys[0], winchWidth, winchHeight)
 					if err != nil {
 						logger.Errorf("Failed to set window size to: %dx%d", winchWidth, winchHeight)
 						continue
 					}
 				} else if command.Command == "signal" {
-					if err := unix.Kill(attachedChildPid, unix.Signal(command.Signal)); err != nil {
+					if err := execKill(attachedChildPid, command.Signal); err != nil {
 						logger.Errorf("Failed forwarding signal '%d' to PID %d", command.Signal, attachedChildPid)
 						continue
 					}
@@ -347,7 +310,7 @@ func (s *execWs) Do(op *operations.Operation) error {
 			s.connsLock.Unlock()
 
 			logger.Info("Started mirroring websocket")
-			readDone, writeDone := netutils.WebsocketExecMirror(conn, ptys[0], ptys[0], attachedChildIsDead, int(ptys[0].Fd()))
+			readDone, writeDone := execMirror(conn, ptys[0], attachedChildIsDead)
 
 			<-readDone
 			<-writeDone
@@ -430,24 +393,7 @@ func (s *execWs) Do(op *operations.Operation) error {
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Credential: &syscall.Credential{
-			Uid: s.uid,
-			Gid: s.gid,
-		},
-		// Creates a new session if the calling process is not a process group leader.
-		// The calling process is the leader of the new session, the process group leader of
-		// the new process group, and has no controlling terminal.
-		// This is important to allow remote shells to handle ctrl+c.
-		Setsid: true,
-	}
-
-	// Make the given terminal the controlling terminal of the calling process.
-	// The calling process must be a session leader and not have a controlling terminal already.
-	// This is important as allows ctrl+c to work as expected for non-shell programs.
-	if s.interactive {
-		cmd.SysProcAttr.Setctty = true
-	}
+	cmd.SysProcAttr = execSysProcAttr(s.uid, s.gid, s.interactive)
 
 	cmd.Dir = s.cwd
 
@@ -465,18 +411,5 @@ func (s *execWs) Do(op *operations.Operation) error {
 		return finisher(0, nil)
 	}
 
-	exitErr, ok := err.(*exec.ExitError)
-	if ok {
-		status, ok := exitErr.Sys().(syscall.WaitStatus)
-		if ok {
-			return finisher(status.ExitStatus(), nil)
-		}
-
-		if status.Signaled() {
-			// 128 + n == Fatal error signal "n"
-			return finisher(128+int(status.Signal()), nil)
-		}
-	}
-
-	return finisher(-1, nil)
+	return finisher(execExitStatus(err), nil)
 }
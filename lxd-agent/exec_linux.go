@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/netutils"
+)
+
+// execSignalKill is the signal sent to the process when the client goes away.
+const execSignalKill = int(unix.SIGKILL)
+
+// execDefaults fills in the environment and working directory of the command when not provided.
+func execDefaults(env map[string]string, post *api.ContainerExecPost) error {
+	// Set default value for PATH
+	_, ok := env["PATH"]
+	if !ok {
+		env["PATH"] = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	}
+
+	if shared.PathExists("/snap/bin") {
+		env["PATH"] = fmt.Sprintf("%s:/snap/bin", env["PATH"])
+	}
+
+	// If running as root, set some env variables
+	if post.User == 0 {
+		// Set default value for HOME
+		_, ok = env["HOME"]
+		if !ok {
+			env["HOME"] = "/root"
+		}
+
+		// Set default value for USER
+		_, ok = env["USER"]
+		if !ok {
+			env["USER"] = "root"
+		}
+	}
+
+	// Set default value for LANG
+	_, ok = env["LANG"]
+	if !ok {
+		env["LANG"] = "C.UTF-8"
+	}
+
+	// Set the default working directory
+	if post.Cwd == "" {
+		post.Cwd = env["HOME"]
+		if post.Cwd == "" {
+			post.Cwd = "/"
+		}
+	}
+
+	return nil
+}
+
+func execOpenPty(uid uint32, gid uint32) (*os.File, *os.File, error) {
+	return shared.OpenPty(int64(uid), int64(gid))
+}
+
+func execSetSize(pty *os.File, width int, height int) error {
+	return shared.SetSize(int(pty.Fd()), width, height)
+}
+
+func execKill(pid int, signal int) error {
+	return unix.Kill(pid, unix.Signal(signal))
+}
+
+func execMirror(conn *websocket.Conn, pty *os.File, exited chan struct{}) (chan bool, chan bool) {
+	return netutils.WebsocketExecMirror(conn, pty, pty, exited, int(pty.Fd()))
+}
+
+func execSysProcAttr(uid uint32, gid uint32, interactive bool) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid: uid,
+			Gid: gid,
+		},
+		// Creates a new session if the calling process is not a process group leader.
+		// The calling process is the leader of the new session, the process group leader of
+		// the new process group, and has no controlling terminal.
+		// This is important to allow remote shells to handle ctrl+c.
+		Setsid: true,
+	}
+
+	// Make the given terminal the controlling terminal of the calling process.
+	// The calling process must be a session leader and not have a controlling terminal already.
+	// This is important as allows ctrl+c to work as expected for non-shell programs.
+	if interactive {
+		attr.Setctty = true
+	}
+
+	return attr
+}
+
+// execExitStatus returns the exit status of the command from the error returned when waiting for it.
+func execExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if ok {
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		if ok {
+			return status.ExitStatus()
+		}
+
+		if status.Signaled() {
+			// 128 + n == Fatal error signal "n"
+			return 128 + int(status.Signal())
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// execSignalKill is the signal sent to the process when the client goes away.
+const execSignalKill = 9
+
+// execDefaults fills in the environment and working directory of the command when not provided.
+// Windows programs need most of the agent's environment (SystemRoot, ProgramFiles...), so it's used as the base.
+func execDefaults(env map[string]string, post *api.ContainerExecPost) error {
+	if post.Interactive {
+		return fmt.Errorf("Interactive sessions aren't supported on Windows, run the command in non-interactive mode")
+	}
+
+	for _, entry := range os.Environ() {
+		fields := strings.SplitN(entry, "=", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+
+		_, ok := env[fields[0]]
+		if !ok {
+			env[fields[0]] = fields[1]
+		}
+	}
+
+	// Set the default working directory
+	if post.Cwd == "" {
+		post.Cwd = env["SystemDrive"] + `\`
+	}
+
+	return nil
+}
+
+func execOpenPty(uid uint32, gid uint32) (*os.File, *os.File, error) {
+	return nil, nil, fmt.Errorf("Interactive sessions aren't supported on Windows")
+}
+
+func execSetSize(pty *os.File, width int, height int) error {
+	return fmt.Errorf("Interactive sessions aren't supported on Windows")
+}
+
+// execKill terminates the process, other signals don't exist on Windows.
+func execKill(pid int, signal int) error {
+	if signal != execSignalKill {
+		return fmt.Errorf("Only the kill signal is supported on Windows")
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return p.Kill()
+}
+
+func execMirror(conn *websocket.Conn, pty *os.File, exited chan struct{}) (chan bool, chan bool) {
+	readDone := make(chan bool, 1)
+	writeDone := make(chan bool, 1)
+	readDone <- true
+	writeDone <- true
+
+	return readDone, writeDone
+}
+
+// execSysProcAttr returns no attributes, commands run as the agent's user on Windows.
+func execSysProcAttr(uid uint32, gid uint32, interactive bool) *syscall.SysProcAttr {
+	return nil
+}
+
+// execExitStatus returns the exit status of the command from the error returned when waiting for it.
+func execExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if ok {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
@@ -8,10 +8,9 @@ import (
 	"os"
 	"path/filepath"
 
-	"golang.org/x/sys/unix"
-
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/tarstream"
 )
 
 var fileCmd = APIEndpoint{
@@ -29,10 +28,20 @@ func fileHandler(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("missing path argument"))
 	}
 
+	recursive := shared.IsTrue(r.FormValue("recursive"))
+
 	switch r.Method {
 	case "GET":
+		if recursive {
+			return &fileTarResponse{path: path}
+		}
+
 		return fileGet(path, r)
 	case "POST":
+		if recursive {
+			return filePostRecursive(path, r)
+		}
+
 		return filePost(path, r)
 	case "DELETE":
 		return fileDelete(path, r)
@@ -41,6 +50,26 @@ func fileHandler(d *Daemon, r *http.Request) response.Response {
 	}
 }
 
+// fileTarResponse streams the tree at a path as a tarball.
+type fileTarResponse struct {
+	path string
+}
+
+func (r *fileTarResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	name := filepath.Base(r.path)
+	if name == "/" {
+		name = "."
+	}
+
+	return tarstream.Write(w, r.path, name, nil)
+}
+
+func (r *fileTarResponse) String() string {
+	return fmt.Sprintf("tarball of %s", r.path)
+}
+
 func fileGet(path string, r *http.Request) response.Response {
 	uid, gid, mode, fType, dirEnts, err := getFileInfo(path)
 	if err != nil {
@@ -152,6 +181,15 @@ func filePost(path string, r *http.Request) response.Response {
 	return response.BadRequest(fmt.Errorf("Bad file type: %s", fType))
 }
 
+func filePostRecursive(path string, r *http.Request) response.Response {
+	err := tarstream.Extract(r.Body, path, nil, true)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 func fileDelete(path string, r *http.Request) response.Response {
 	err := os.Remove(path)
 	if err != nil {
@@ -162,8 +200,6 @@ func fileDelete(path string, r *http.Request) response.Response {
 }
 
 func getFileInfo(path string) (int64, int64, os.FileMode, string, []string, error) {
-	var stat unix.Stat_t
-
 	err := os.Chdir("/")
 	if err != nil {
 		return -1, -1, 0, "", nil, err
@@ -174,7 +210,7 @@ func getFileInfo(path string) (int64, int64, os.FileMode, string, []string, erro
 		return -1, -1, 0, "", nil, err
 	}
 
-	err = unix.Lstat(path, &stat)
+	uid, gid, err := fileOwner(path)
 	if err != nil {
 		return -1, -1, 0, "", nil, err
 	}
@@ -204,7 +240,7 @@ func getFileInfo(path string) (int64, int64, os.FileMode, string, []string, erro
 	}
 
 	// 0xFFF = 0b7777
-	return int64(stat.Uid), int64(stat.Gid), fi.Mode() & 0xFFF, fType, dirEnts, nil
+	return uid, gid, fi.Mode() & 0xFFF, fType, dirEnts, nil
 }
 
 func filePush(fType string, srcpath string, dstpath string, uid int64, gid int64, mode int, write string) error {
@@ -249,7 +285,7 @@ func filePush(fType string, srcpath string, dstpath string, uid int64, gid int64
 			return err
 		}
 
-		err = os.Chown(dst.Name(), int(uid), int(gid))
+		err = fileChown(dst.Name(), uid, gid)
 		if err != nil {
 			return err
 		}
@@ -269,7 +305,7 @@ func filePush(fType string, srcpath string, dstpath string, uid int64, gid int64
 			return err
 		}
 
-		err = os.Lchown(dstpath, int(uid), int(gid))
+		err = fileLchown(dstpath, uid, gid)
 		if err != nil {
 			return err
 		}
@@ -293,7 +329,7 @@ func filePush(fType string, srcpath string, dstpath string, uid int64, gid int64
 			return err
 		}
 
-		err = os.Chown(dstpath, int(uid), int(gid))
+		err = fileChown(dstpath, uid, gid)
 		if err != nil {
 			return err
 		}
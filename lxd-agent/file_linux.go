@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the owner of the path, without following symlinks.
+func fileOwner(path string) (int64, int64, error) {
+	var stat unix.Stat_t
+
+	err := unix.Lstat(path, &stat)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	return int64(stat.Uid), int64(stat.Gid), nil
+}
+
+func fileChown(path string, uid int64, gid int64) error {
+	return os.Chown(path, int(uid), int(gid))
+}
+
+func fileLchown(path string, uid int64, gid int64) error {
+	return os.Lchown(path, int(uid), int(gid))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/filewatch"
+)
+
+var fileWatchCmd = APIEndpoint{
+	Name: "fileWatch",
+	Path: "files/watch",
+
+	Post: APIEndpointAction{Handler: fileWatchPost},
+}
+
+func fileWatchPost(d *Daemon, r *http.Request) response.Response {
+	post := api.InstanceFileWatchPost{}
+
+	err := json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if post.Path == "" {
+		return response.BadRequest(fmt.Errorf("A path must be provided"))
+	}
+
+	if !shared.PathExists(post.Path) {
+		return response.NotFound(fmt.Errorf("Path %q not found", post.Path))
+	}
+
+	ws := &fileWatchWs{}
+	ws.path = post.Path
+	ws.recursive = post.Recursive
+	ws.connected = make(chan struct{})
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+
+	ws.secret, err = shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resources := map[string][]string{}
+
+	op, err := operations.OperationCreate(nil, "", operations.OperationClassWebsocket, db.OperationInstanceFileWatch, resources, ws.Metadata(), ws.Do, ws.Cancel, ws.Connect, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Link the operation to the agent's event server.
+	op.SetEventServer(d.events)
+
+	return operations.OperationResponse(op)
+}
+
+type fileWatchWs struct {
+	path      string
+	recursive bool
+	secret    string
+	conn      *websocket.Conn
+	connected chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func (s *fileWatchWs) Metadata() interface{} {
+	return shared.Jmap{"fds": shared.Jmap{"0": s.secret}}
+}
+
+func (s *fileWatchWs) Connect(op *operations.Operation, r *http.Request, w http.ResponseWriter) error {
+	if r.FormValue("secret") != s.secret {
+		// If we didn't find the right secret, the user provided a bad one,
+		// which 403, not 404, since this operation actually exists.
+		return os.ErrPermission
+	}
+
+	if s.conn != nil {
+		return fmt.Errorf("Already connected")
+	}
+
+	conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	close(s.connected)
+
+	return nil
+}
+
+func (s *fileWatchWs) Do(op *operations.Operation) error {
+	select {
+	case <-s.connected:
+	case <-s.ctx.Done():
+		return nil
+	}
+
+	defer s.conn.Close()
+
+	// Stop watching once the client goes away.
+	go func() {
+		for {
+			_, _, err := s.conn.NextReader()
+			if err != nil {
+				s.cancel()
+				return
+			}
+		}
+	}()
+
+	return filewatch.Watch(s.ctx, s.path, s.recursive, func(event api.InstanceFileEvent) {
+		err := s.conn.WriteJSON(event)
+		if err != nil {
+			s.cancel()
+		}
+	})
+}
+
+func (s *fileWatchWs) Cancel(op *operations.Operation) error {
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+// fileOwner reports files as owned by root, Windows has no numeric owners.
+func fileOwner(path string) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+// fileChown does nothing, new files inherit the permissions of their parent directory on Windows.
+func fileChown(path string, uid int64, gid int64) error {
+	return nil
+}
+
+func fileLchown(path string, uid int64, gid int64) error {
+	return nil
+}
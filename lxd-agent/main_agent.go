@@ -2,22 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"golang.org/x/sys/unix"
 
-	"github.com/lxc/lxd/lxd/instance/instancetype"
-	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/lxd/vsock"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -55,70 +48,12 @@ func (c *cmdAgent) Run(cmd *cobra.Command, args []string) error {
 	logger.Info("Starting")
 	defer logger.Info("Stopped")
 
-	// Apply the templated files.
-	files, err := templatesApply("files/")
+	// Prepare the guest operating system.
+	err = c.setupOS()
 	if err != nil {
 		return err
 	}
 
-	// Sync the hostname.
-	if shared.PathExists("/proc/sys/kernel/hostname") && shared.StringInSlice("/etc/hostname", files) {
-		// Open the two files.
-		src, err := os.Open("/etc/hostname")
-		if err != nil {
-			return err
-		}
-
-		dst, err := os.Create("/proc/sys/kernel/hostname")
-		if err != nil {
-			return err
-		}
-
-		// Copy the data.
-		_, err = io.Copy(dst, src)
-		if err != nil {
-			return err
-		}
-
-		// Close the files.
-		src.Close()
-		dst.Close()
-	}
-
-	// Run cloud-init.
-	if shared.PathExists("/etc/cloud") && shared.StringInSlice("/var/lib/cloud/seed/nocloud-net/meta-data", files) {
-		logger.Info("Seeding cloud-init")
-
-		cloudInitPath := "/run/cloud-init"
-		if shared.PathExists(cloudInitPath) {
-			logger.Info(fmt.Sprintf("Removing %q", cloudInitPath))
-			err = os.RemoveAll(cloudInitPath)
-			if err != nil {
-				return err
-			}
-		}
-
-		logger.Info("Rebooting")
-		shared.RunCommand("reboot")
-
-		// Wait up to 5min for the reboot to actually happen, if it doesn't, then move on to allowing connections.
-		time.Sleep(300 * time.Second)
-	}
-
-	// Load the kernel driver.
-	logger.Info("Loading vsock module")
-	err = util.LoadModule("vsock")
-	if err != nil {
-		return errors.Wrap(err, "Unable to load the vsock kernel module")
-	}
-
-	// Wait for vsock device to appear.
-	for i := 0; i < 5; i++ {
-		if !shared.PathExists("/dev/vsock") {
-			time.Sleep(1 * time.Second)
-		}
-	}
-
 	// Setup the listener.
 	l, err := vsock.Listen(shared.DefaultPort)
 	if err != nil {
@@ -154,14 +89,6 @@ func (c *cmdAgent) Run(cmd *cobra.Command, args []string) error {
 	// Prepare the HTTP server.
 	servers["http"] = restServer(tlsConfig, cert, c.global.flagLogDebug, d)
 
-	// Prepare the devlxd server.
-	devlxdListener, err := createDevLxdlListener("/dev")
-	if err != nil {
-		return err
-	}
-
-	servers["devlxd"] = devLxdServer(d)
-
 	// Create a cancellation context.
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
@@ -178,22 +105,17 @@ func (c *cmdAgent) Run(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	// Only start the devlxd listener if instance-data is present.
-	if shared.PathExists("instance-data") {
-		go func() {
-			err := servers["devlxd"].Serve(devlxdListener)
-			if err != nil {
-				errChan <- err
-			}
-		}()
+	// Start the devlxd server.
+	err = startDevlxd(servers, d, errChan)
+	if err != nil {
+		return err
 	}
 
-	// Cancel context when SIGTEM is received.
-	chSignal := make(chan os.Signal, 1)
-	signal.Notify(chSignal, unix.SIGTERM)
+	// Cancel context when the agent is asked to stop.
+	signal.Notify(agentStop, agentStopSignals...)
 
 	select {
-	case <-chSignal:
+	case <-agentStop:
 		cancelFunc()
 		os.Exit(0)
 	case err := <-errChan:
@@ -228,81 +150,17 @@ func (c *cmdAgent) startStatusNotifier(ctx context.Context) {
 
 // writeStatus writes a status code to the vserial ring buffer used to detect agent status on host.
 func (c *cmdAgent) writeStatus(status string) error {
-	if shared.PathExists("/dev/virtio-ports/org.linuxcontainers.lxd") {
-		vSerial, err := os.OpenFile("/dev/virtio-ports/org.linuxcontainers.lxd", os.O_RDWR, 0600)
-		if err != nil {
-			return err
-		}
-		vSerial.Write([]byte(fmt.Sprintf("%s\n", status)))
-		vSerial.Close()
-	}
-
-	return nil
-}
-
-// mountHostShares reads the agent-mounts.json file from config share and mounts the shares requested.
-func (c *cmdAgent) mountHostShares() {
-	agentMountsFile := "./agent-mounts.json"
-	if !shared.PathExists(agentMountsFile) {
-		return
-	}
-
-	b, err := ioutil.ReadFile(agentMountsFile)
+	vSerial, err := os.OpenFile(agentSerialPath, os.O_RDWR, 0600)
 	if err != nil {
-		logger.Errorf("Failed to load agent mounts file %q: %v", agentMountsFile, err)
-	}
-
-	var agentMounts []instancetype.VMAgentMount
-	err = json.Unmarshal(b, &agentMounts)
-	if err != nil {
-		logger.Errorf("Failed to parse agent mounts file %q: %v", agentMountsFile, err)
-		return
-	}
-
-	for _, mount := range agentMounts {
-		// Convert relative mounts to absolute from / otherwise dir creation fails or mount fails.
-		if !strings.HasPrefix(mount.Target, "/") {
-			mount.Target = fmt.Sprintf("/%s", mount.Target)
-		}
-
-		if !shared.PathExists(mount.Target) {
-			err := os.MkdirAll(mount.Target, 0755)
-			if err != nil {
-				logger.Errorf("Failed to create mount target %q", mount.Target)
-				continue // Don't try to mount if mount point can't be created.
-			}
+		if os.IsNotExist(err) {
+			return nil
 		}
 
-		if mount.FSType == "9p" {
-			// Before mounting with 9p, try virtio-fs and use 9p as the fallback.
-			args := []string{"-t", "virtiofs", mount.Source, mount.Target}
-
-			for _, opt := range mount.Options {
-				// Ignore the 'trans-virtio' mount option as that's specific to 9p.
-				if opt != "trans=virtio" {
-					args = append(args, "-o", opt)
-				}
-			}
-
-			_, err = shared.RunCommand("mount", args...)
-			if err == nil {
-				logger.Infof("Mounted %q (Type: %q, Options: %v) to %q", mount.Source, "virtiofs", mount.Options, mount.Target)
-				continue
-			}
-		}
-
-		args := []string{"-t", mount.FSType, mount.Source, mount.Target}
-
-		for _, opt := range mount.Options {
-			args = append(args, "-o", opt)
-		}
+		return err
+	}
 
-		_, err = shared.RunCommand("mount", args...)
-		if err != nil {
-			logger.Errorf("Failed mount %q (Type: %q, Options: %v) to %q: %v", mount.Source, mount.FSType, mount.Options, mount.Target, err)
-			continue
-		}
+	vSerial.Write([]byte(fmt.Sprintf("%s\n", status)))
+	vSerial.Close()
 
-		logger.Infof("Mounted %q (Type: %q, Options: %v) to %q", mount.Source, mount.FSType, mount.Options, mount.Target)
-	}
+	return nil
 }
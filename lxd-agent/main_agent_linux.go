@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// agentSerialPath is the virtio serial port the agent reports its status on.
+const agentSerialPath = "/dev/virtio-ports/org.linuxcontainers.lxd"
+
+// agentStop receives the signals asking the agent to stop.
+var agentStop = make(chan os.Signal, 1)
+var agentStopSignals = []os.Signal{unix.SIGTERM}
+
+// setupOS applies the instance templates, syncs the hostname, seeds cloud-init and waits for vsock to be usable.
+func (c *cmdAgent) setupOS() error {
+	// Apply the templated files.
+	files, err := templatesApply("files/")
+	if err != nil {
+		return err
+	}
+
+	// Sync the hostname.
+	if shared.PathExists("/proc/sys/kernel/hostname") && shared.StringInSlice("/etc/hostname", files) {
+		// Open the two files.
+		src, err := os.Open("/etc/hostname")
+		if err != nil {
+			return err
+		}
+
+		dst, err := os.Create("/proc/sys/kernel/hostname")
+		if err != nil {
+			return err
+		}
+
+		// Copy the data.
+		_, err = io.Copy(dst, src)
+		if err != nil {
+			return err
+		}
+
+		// Close the files.
+		src.Close()
+		dst.Close()
+	}
+
+	// Run cloud-init.
+	if shared.PathExists("/etc/cloud") && shared.StringInSlice("/var/lib/cloud/seed/nocloud-net/meta-data", files) {
+		logger.Info("Seeding cloud-init")
+
+		cloudInitPath := "/run/cloud-init"
+		if shared.PathExists(cloudInitPath) {
+			logger.Info(fmt.Sprintf("Removing %q", cloudInitPath))
+			err = os.RemoveAll(cloudInitPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		logger.Info("Rebooting")
+		shared.RunCommand("reboot")
+
+		// Wait up to 5min for the reboot to actually happen, if it doesn't, then move on to allowing connections.
+		time.Sleep(300 * time.Second)
+	}
+
+	// Load the kernel driver.
+	logger.Info("Loading vsock module")
+	err = util.LoadModule("vsock")
+	if err != nil {
+		return errors.Wrap(err, "Unable to load the vsock kernel module")
+	}
+
+	// Wait for vsock device to appear.
+	for i := 0; i < 5; i++ {
+		if !shared.PathExists("/dev/vsock") {
+			time.Sleep(1 * time.Second)
+		}
+	}
+
+	return nil
+}
+
+// startDevlxd starts the devlxd server if instance-data is present.
+func startDevlxd(servers map[string]*http.Server, d *Daemon, errChan chan error) error {
+	// Prepare the devlxd server.
+	devlxdListener, err := createDevLxdlListener("/dev")
+	if err != nil {
+		return err
+	}
+
+	servers["devlxd"] = devLxdServer(d)
+
+	// Only start the devlxd listener if instance-data is present.
+	if shared.PathExists("instance-data") {
+		go func() {
+			err := servers["devlxd"].Serve(devlxdListener)
+			if err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
+	return nil
+}
+
+// mountHostShares reads the agent-mounts.json file from config share and mounts the shares requested.
+func (c *cmdAgent) mountHostShares() {
+	agentMountsFile := "./agent-mounts.json"
+	if !shared.PathExists(agentMountsFile) {
+		return
+	}
+
+	b, err := ioutil.ReadFile(agentMountsFile)
+	if err != nil {
+		logger.Errorf("Failed to load agent mounts file %q: %v", agentMountsFile, err)
+	}
+
+	var agentMounts []instancetype.VMAgentMount
+	err = json.Unmarshal(b, &agentMounts)
+	if err != nil {
+		logger.Errorf("Failed to parse agent mounts file %q: %v", agentMountsFile, err)
+		return
+	}
+
+	for _, mount := range agentMounts {
+		// Convert relative mounts to absolute from / otherwise dir creation fails or mount fails.
+		if !strings.HasPrefix(mount.Target, "/") {
+			mount.Target = fmt.Sprintf("/%s", mount.Target)
+		}
+
+		if !shared.PathExists(mount.Target) {
+			err := os.MkdirAll(mount.Target, 0755)
+			if err != nil {
+				logger.Errorf("Failed to create mount target %q", mount.Target)
+				continue // Don't try to mount if mount point can't be created.
+			}
+		}
+
+		if mount.FSType == "9p" {
+			// Before mounting with 9p, try virtio-fs and use 9p as the fallback.
+			args := []string{"-t", "virtiofs", mount.Source, mount.Target}
+
+			for _, opt := range mount.Options {
+				// Ignore the 'trans-virtio' mount option as that's specific to 9p.
+				if opt != "trans=virtio" {
+					args = append(args, "-o", opt)
+				}
+			}
+
+			_, err = shared.RunCommand("mount", args...)
+			if err == nil {
+				logger.Infof("Mounted %q (Type: %q, Options: %v) to %q", mount.Source, "virtiofs", mount.Options, mount.Target)
+				continue
+			}
+		}
+
+		args := []string{"-t", mount.FSType, mount.Source, mount.Target}
+
+		for _, opt := range mount.Options {
+			args = append(args, "-o", opt)
+		}
+
+		_, err = shared.RunCommand("mount", args...)
+		if err != nil {
+			logger.Errorf("Failed mount %q (Type: %q, Options: %v) to %q: %v", mount.Source, mount.FSType, mount.Options, mount.Target, err)
+			continue
+		}
+
+		logger.Infof("Mounted %q (Type: %q, Options: %v) to %q", mount.Source, mount.FSType, mount.Options, mount.Target)
+	}
+}
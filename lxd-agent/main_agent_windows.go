@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows/svc"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// agentSerialPath is the virtio serial port the agent reports its status on, as exposed by the virtio-win
+// serial driver.
+const agentSerialPath = `\\.\Global\org.linuxcontainers.lxd`
+
+// agentStop receives the requests asking the agent to stop, either from the console or the service manager.
+var agentStop = make(chan os.Signal, 1)
+var agentStopSignals = []os.Signal{os.Interrupt}
+
+// setupOS moves to the directory holding the agent and its certificates and reports to the service manager when
+// started as a Windows service.
+func (c *cmdAgent) setupOS() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chdir(filepath.Dir(exe))
+	if err != nil {
+		return err
+	}
+
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+
+	if isService {
+		go func() {
+			err := svc.Run("lxd-agent", &agentService{})
+			if err != nil {
+				logger.Errorf("Failed running as a Windows service: %v", err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// startDevlxd does nothing, devlxd isn't available to Windows guests.
+func startDevlxd(servers map[string]*http.Server, d *Daemon, errChan chan error) error {
+	return nil
+}
+
+// mountHostShares does nothing, Windows guests mount the host shares through the virtio-win virtiofs service.
+func (c *cmdAgent) mountHostShares() {
+}
+
+// agentService handles the requests of the Windows service manager.
+type agentService struct{}
+
+func (s *agentService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			status <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			agentStop <- os.Interrupt
+			return false, 0
+		}
+	}
+
+	return false, 0
+}
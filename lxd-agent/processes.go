@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/instance/processes"
+	"github.com/lxc/lxd/lxd/response"
+)
+
+var processesCmd = APIEndpoint{
+	Name: "processes",
+	Path: "processes",
+
+	Get: APIEndpointAction{Handler: processesGet},
+}
+
+// processesGet returns the processes of the guest using the most CPU or memory.
+func processesGet(d *Daemon, r *http.Request) response.Response {
+	sortBy, limit, err := processes.ParseQuery(r.URL.Query())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	top, err := processes.Top(nil, nil, sortBy, limit)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, top)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var sevReportCmd = APIEndpoint{
+	Name: "sevReport",
+	Path: "sev/report",
+
+	Post: APIEndpointAction{Handler: sevReportPost},
+}
+
+// sevReportPost returns a SEV-SNP attestation report, which only the guest can request from the firmware.
+func sevReportPost(d *Daemon, r *http.Request) response.Response {
+	req := api.InstanceSEVReportPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(req.Nonce)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid nonce: %v", err))
+	}
+
+	if len(nonce) > 64 {
+		return response.BadRequest(fmt.Errorf("The nonce can't be longer than 64 bytes"))
+	}
+
+	report, err := sevSNPReport(nonce)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, api.InstanceSEVReport{Report: base64.StdEncoding.EncodeToString(report)})
+}
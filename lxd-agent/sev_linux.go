@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// snpGetReport is the SNP_GET_REPORT ioctl of the sev-guest driver.
+const snpGetReport = 0xc0205300
+
+// snpGuestRequest mirrors struct snp_guest_request_ioctl from linux/sev-guest.h.
+type snpGuestRequest struct {
+	msgVersion uint8
+	_          [7]byte
+	reqData    uint64
+	respData   uint64
+	exitInfo2  uint64
+}
+
+// snpReportRequest mirrors struct snp_report_req from linux/sev-guest.h.
+type snpReportRequest struct {
+	userData [64]byte
+	vmpl     uint32
+	_        [28]byte
+}
+
+// sevSNPReport requests an attestation report including the user data from the SEV-SNP firmware.
+func sevSNPReport(userData []byte) ([]byte, error) {
+	f, err := os.OpenFile("/dev/sev-guest", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed opening the SEV guest device")
+	}
+	defer f.Close()
+
+	reportReq := snpReportRequest{}
+	copy(reportReq.userData[:], userData)
+
+	// Response layout: status, report size, 24 reserved bytes and the report.
+	reportResp := make([]byte, 4000)
+
+	req := snpGuestRequest{
+		msgVersion: 1,
+		reqData:    uint64(uintptr(unsafe.Pointer(&reportReq))),
+		respData:   uint64(uintptr(unsafe.Pointer(&reportResp[0]))),
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), snpGetReport, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return nil, errors.Wrapf(errno, "Failed requesting the attestation report (firmware error %#x)", req.exitInfo2)
+	}
+
+	status := binary.LittleEndian.Uint32(reportResp[0:4])
+	if status != 0 {
+		return nil, fmt.Errorf("The firmware failed generating the attestation report (status %#x)", status)
+	}
+
+	size := binary.LittleEndian.Uint32(reportResp[4:8])
+	if size > uint32(len(reportResp)-32) {
+		return nil, fmt.Errorf("Invalid attestation report size %d", size)
+	}
+
+	return reportResp[32 : 32+size], nil
+}
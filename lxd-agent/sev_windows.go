@@ -0,0 +1,10 @@
+package main
+
+import (
+	"fmt"
+)
+
+// sevSNPReport isn't supported on Windows, the sev-guest driver is Linux only.
+func sevSNPReport(userData []byte) ([]byte, error) {
+	return nil, fmt.Errorf("SEV-SNP attestation reports aren't supported on Windows")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lxc/lxd/client"
@@ -35,8 +36,8 @@ func PrintServerInfo(c lxd.ContainerServer) error {
 	return nil
 }
 
-// LaunchContainers launches a set of containers.
-func LaunchContainers(c lxd.ContainerServer, count int, parallel int, image string, privileged bool, start bool, freeze bool) (time.Duration, error) {
+// LaunchContainers launches a set of instances of the given type.
+func LaunchContainers(c lxd.ContainerServer, count int, parallel int, image string, privileged bool, instanceType api.InstanceType, start bool, freeze bool, metrics *Metrics) (time.Duration, error) {
 	var duration time.Duration
 
 	batchSize, err := getBatchSize(parallel)
@@ -44,7 +45,7 @@ func LaunchContainers(c lxd.ContainerServer, count int, parallel int, image stri
 		return duration, err
 	}
 
-	printTestConfig(count, batchSize, image, privileged, freeze)
+	printTestConfig(count, batchSize, image, privileged, instanceType, freeze)
 
 	fingerprint, err := ensureImage(c, image)
 	if err != nil {
@@ -56,21 +57,21 @@ func LaunchContainers(c lxd.ContainerServer, count int, parallel int, image stri
 
 		name := getContainerName(count, index)
 
-		err := createContainer(c, fingerprint, name, privileged)
+		err := metrics.Time("create", func() error { return createContainer(c, fingerprint, name, privileged, instanceType) })
 		if err != nil {
 			logf("Failed to launch container '%s': %s", name, err)
 			return
 		}
 
 		if start {
-			err := startContainer(c, name)
+			err := metrics.Time("start", func() error { return startContainer(c, name) })
 			if err != nil {
 				logf("Failed to start container '%s': %s", name, err)
 				return
 			}
 
 			if freeze {
-				err := freezeContainer(c, name)
+				err := metrics.Time("freeze", func() error { return freezeContainer(c, name) })
 				if err != nil {
 					logf("Failed to freeze container '%s': %s", name, err)
 					return
@@ -83,8 +84,8 @@ func LaunchContainers(c lxd.ContainerServer, count int, parallel int, image stri
 	return duration, nil
 }
 
-// CreateContainers create the specified number of containers.
-func CreateContainers(c lxd.ContainerServer, count int, parallel int, fingerprint string, privileged bool) (time.Duration, error) {
+// CreateContainers create the specified number of instances of the given type.
+func CreateContainers(c lxd.ContainerServer, count int, parallel int, fingerprint string, privileged bool, instanceType api.InstanceType, metrics *Metrics) (time.Duration, error) {
 	var duration time.Duration
 
 	batchSize, err := getBatchSize(parallel)
@@ -97,7 +98,7 @@ func CreateContainers(c lxd.ContainerServer, count int, parallel int, fingerprin
 
 		name := getContainerName(count, index)
 
-		err := createContainer(c, fingerprint, name, privileged)
+		err := metrics.Time("create", func() error { return createContainer(c, fingerprint, name, privileged, instanceType) })
 		if err != nil {
 			logf("Failed to launch container '%s': %s", name, err)
 			return
@@ -109,11 +110,11 @@ func CreateContainers(c lxd.ContainerServer, count int, parallel int, fingerprin
 	return duration, nil
 }
 
-// GetContainers returns containers created by the benchmark.
-func GetContainers(c lxd.ContainerServer) ([]api.Container, error) {
-	containers := []api.Container{}
+// GetContainers returns the containers and virtual machines created by the benchmark.
+func GetContainers(c lxd.ContainerServer) ([]api.Instance, error) {
+	containers := []api.Instance{}
 
-	allContainers, err := c.GetContainers()
+	allContainers, err := c.GetInstances(api.InstanceTypeAny)
 	if err != nil {
 		return containers, err
 	}
@@ -128,7 +129,7 @@ func GetContainers(c lxd.ContainerServer) ([]api.Container, error) {
 }
 
 // StartContainers starts containers created by the benchmark.
-func StartContainers(c lxd.ContainerServer, containers []api.Container, parallel int) (time.Duration, error) {
+func StartContainers(c lxd.ContainerServer, containers []api.Instance, parallel int, metrics *Metrics) (time.Duration, error) {
 	var duration time.Duration
 
 	batchSize, err := getBatchSize(parallel)
@@ -144,7 +145,7 @@ func StartContainers(c lxd.ContainerServer, containers []api.Container, parallel
 
 		container := containers[index]
 		if !container.IsActive() {
-			err := startContainer(c, container.Name)
+			err := metrics.Time("start", func() error { return startContainer(c, container.Name) })
 			if err != nil {
 				logf("Failed to start container '%s': %s", container.Name, err)
 				return
@@ -157,7 +158,7 @@ func StartContainers(c lxd.ContainerServer, containers []api.Container, parallel
 }
 
 // StopContainers stops containers created by the benchmark.
-func StopContainers(c lxd.ContainerServer, containers []api.Container, parallel int) (time.Duration, error) {
+func StopContainers(c lxd.ContainerServer, containers []api.Instance, parallel int, metrics *Metrics) (time.Duration, error) {
 	var duration time.Duration
 
 	batchSize, err := getBatchSize(parallel)
@@ -173,7 +174,7 @@ func StopContainers(c lxd.ContainerServer, containers []api.Container, parallel
 
 		container := containers[index]
 		if container.IsActive() {
-			err := stopContainer(c, container.Name)
+			err := metrics.Time("stop", func() error { return stopContainer(c, container.Name) })
 			if err != nil {
 				logf("Failed to stop container '%s': %s", container.Name, err)
 				return
@@ -186,7 +187,7 @@ func StopContainers(c lxd.ContainerServer, containers []api.Container, parallel
 }
 
 // DeleteContainers removes containers created by the benchmark.
-func DeleteContainers(c lxd.ContainerServer, containers []api.Container, parallel int) (time.Duration, error) {
+func DeleteContainers(c lxd.ContainerServer, containers []api.Instance, parallel int, metrics *Metrics) (time.Duration, error) {
 	var duration time.Duration
 
 	batchSize, err := getBatchSize(parallel)
@@ -203,14 +204,14 @@ func DeleteContainers(c lxd.ContainerServer, containers []api.Container, paralle
 		container := containers[index]
 		name := container.Name
 		if container.IsActive() {
-			err := stopContainer(c, name)
+			err := metrics.Time("stop", func() error { return stopContainer(c, name) })
 			if err != nil {
 				logf("Failed to stop container '%s': %s", name, err)
 				return
 			}
 		}
 
-		err = deleteContainer(c, name)
+		err = metrics.Time("delete", func() error { return deleteContainer(c, name) })
 		if err != nil {
 			logf("Failed to delete container: %s", name)
 			return
@@ -221,6 +222,195 @@ func DeleteContainers(c lxd.ContainerServer, containers []api.Container, paralle
 	return duration, nil
 }
 
+// ExecContainers runs the given command the specified number of times, spread over the running instances
+// created by the benchmark.
+func ExecContainers(c lxd.ContainerServer, containers []api.Instance, count int, parallel int, command []string, metrics *Metrics) (time.Duration, error) {
+	var duration time.Duration
+
+	batchSize, err := getBatchSize(parallel)
+	if err != nil {
+		return duration, err
+	}
+
+	running := getRunning(containers)
+	if len(running) == 0 {
+		return duration, fmt.Errorf("No running benchmark instances")
+	}
+
+	logf("Running %d commands in %d instances", count, len(running))
+
+	batchExec := func(index int, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		name := running[index%len(running)].Name
+		err := metrics.Time("exec", func() error { return execInstance(c, name, command) })
+		if err != nil {
+			logf("Failed to run command in '%s': %s", name, err)
+			return
+		}
+	}
+
+	duration = processBatch(count, batchSize, batchExec)
+	return duration, nil
+}
+
+// ConsoleContainers attaches to and detaches from the console the specified number of times, spread over the
+// running instances created by the benchmark.
+func ConsoleContainers(c lxd.ContainerServer, containers []api.Instance, count int, parallel int, metrics *Metrics) (time.Duration, error) {
+	var duration time.Duration
+
+	batchSize, err := getBatchSize(parallel)
+	if err != nil {
+		return duration, err
+	}
+
+	running := getRunning(containers)
+	if len(running) == 0 {
+		return duration, fmt.Errorf("No running benchmark instances")
+	}
+
+	logf("Attaching %d consoles to %d instances", count, len(running))
+
+	batchConsole := func(index int, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		name := running[index%len(running)].Name
+		err := metrics.Time("console", func() error { return consoleInstance(c, name) })
+		if err != nil {
+			logf("Failed to attach to the console of '%s': %s", name, err)
+			return
+		}
+	}
+
+	duration = processBatch(count, batchSize, batchConsole)
+	return duration, nil
+}
+
+// QueryAPI sends the specified number of GET requests, cycling through the given API paths.
+func QueryAPI(c lxd.ContainerServer, paths []string, count int, parallel int, metrics *Metrics) (time.Duration, error) {
+	var duration time.Duration
+
+	batchSize, err := getBatchSize(parallel)
+	if err != nil {
+		return duration, err
+	}
+
+	logf("Sending %d requests to %d endpoints", count, len(paths))
+
+	batchQuery := func(index int, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		path := paths[index%len(paths)]
+		err := metrics.Time(fmt.Sprintf("GET %s", path), func() error {
+			_, _, err := c.RawQuery("GET", path, nil, "")
+			return err
+		})
+		if err != nil {
+			logf("Failed to query '%s': %s", path, err)
+			return
+		}
+	}
+
+	duration = processBatch(count, batchSize, batchQuery)
+	return duration, nil
+}
+
+// Soak repeatedly creates, starts, runs a command in (if any), stops and deletes instances until the given
+// duration is elapsed, using one worker per parallel slot.
+func Soak(c lxd.ContainerServer, duration time.Duration, parallel int, image string, privileged bool, instanceType api.InstanceType, command []string, metrics *Metrics) (time.Duration, error) {
+	workers, err := getBatchSize(parallel)
+	if err != nil {
+		return 0, err
+	}
+
+	printTestConfig(workers, workers, image, privileged, instanceType, false)
+
+	fingerprint, err := ensureImage(c, image)
+	if err != nil {
+		return 0, err
+	}
+
+	var cycles int64
+	var failures int64
+	deadline := time.Now().Add(duration)
+
+	cycle := func(name string) error {
+		err := metrics.Time("create", func() error { return createContainer(c, fingerprint, name, privileged, instanceType) })
+		if err != nil {
+			return err
+		}
+
+		defer metrics.Time("delete", func() error { return deleteContainer(c, name) })
+
+		err = metrics.Time("start", func() error { return startContainer(c, name) })
+		if err != nil {
+			return err
+		}
+
+		if len(command) > 0 {
+			err = metrics.Time("exec", func() error { return execInstance(c, name, command) })
+			if err != nil {
+				return err
+			}
+		}
+
+		return metrics.Time("stop", func() error { return stopContainer(c, name) })
+	}
+
+	logf("Soak testing with %d workers for %s", workers, duration)
+	timeStart := time.Now()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			for time.Now().Before(deadline) {
+				err := cycle(name)
+				if err != nil {
+					atomic.AddInt64(&failures, 1)
+					logf("Failed soak cycle of '%s': %s", name, err)
+				}
+
+				atomic.AddInt64(&cycles, 1)
+			}
+		}(fmt.Sprintf("benchmark-soak-%d", i+1))
+	}
+
+	// Report progress every minute.
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			logf("Completed %d cycles (%d failed) in %s", atomic.LoadInt64(&cycles), atomic.LoadInt64(&failures), time.Since(timeStart).Round(time.Second))
+		case <-done:
+			elapsed := time.Since(timeStart)
+			logf("Soak test completed %d cycles (%d failed) in %.3fs", cycles, failures, elapsed.Seconds())
+			return elapsed, nil
+		}
+	}
+}
+
+func getRunning(containers []api.Instance) []api.Instance {
+	running := []api.Instance{}
+	for _, container := range containers {
+		if container.IsActive() {
+			running = append(running, container)
+		}
+	}
+
+	return running
+}
+
 func ensureImage(c lxd.ContainerServer, image string) (string, error) {
 	var fingerprint string
 
@@ -0,0 +1,200 @@
+package benchmark
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds (in seconds) of the latency histogram buckets.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// histogram tracks the latency distribution of a single operation.
+type histogram struct {
+	buckets []uint64 // Non-cumulative count per bucket, the last one being +Inf.
+	count   uint64
+	sum     float64
+	errors  uint64
+}
+
+// Metrics collects the latency of the operations done by the benchmark and exports them as Prometheus
+// histograms.
+type Metrics struct {
+	labels     map[string]string
+	histograms map[string]*histogram
+	mu         sync.Mutex
+}
+
+// NewMetrics returns a new metrics collector. The given labels are added to every exported sample.
+func NewMetrics(labels map[string]string) *Metrics {
+	return &Metrics{
+		labels:     labels,
+		histograms: map[string]*histogram{},
+	}
+}
+
+// Observe records the latency of an operation. Failed operations are only counted as errors.
+func (m *Metrics) Observe(operation string, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.histograms[operation]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets)+1)}
+		m.histograms[operation] = h
+	}
+
+	if err != nil {
+		h.errors++
+		return
+	}
+
+	seconds := latency.Seconds()
+	i := sort.SearchFloat64s(latencyBuckets, seconds)
+	h.buckets[i]++
+	h.count++
+	h.sum += seconds
+}
+
+// Time runs the given function, records its latency under the operation name and returns its error. The latency
+// isn't recorded if the collector is nil.
+func (m *Metrics) Time(operation string, f func() error) error {
+	if m == nil {
+		return f()
+	}
+
+	start := time.Now()
+	err := f()
+	m.Observe(operation, time.Since(start), err)
+
+	return err
+}
+
+// WritePrometheus writes the histograms in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	operations := make([]string, 0, len(m.histograms))
+	for operation := range m.histograms {
+		operations = append(operations, operation)
+	}
+
+	sort.Strings(operations)
+
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "# HELP lxd_benchmark_operation_duration_seconds Latency of the successful benchmark operations.\n")
+	fmt.Fprintf(out, "# TYPE lxd_benchmark_operation_duration_seconds histogram\n")
+	for _, operation := range operations {
+		h := m.histograms[operation]
+
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(out, "lxd_benchmark_operation_duration_seconds_bucket{%s,le=\"%g\"} %d\n", m.formatLabels(operation), bound, cumulative)
+		}
+
+		fmt.Fprintf(out, "lxd_benchmark_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", m.formatLabels(operation), h.count)
+		fmt.Fprintf(out, "lxd_benchmark_operation_duration_seconds_sum{%s} %g\n", m.formatLabels(operation), h.sum)
+		fmt.Fprintf(out, "lxd_benchmark_operation_duration_seconds_count{%s} %d\n", m.formatLabels(operation), h.count)
+	}
+
+	fmt.Fprintf(out, "# HELP lxd_benchmark_operation_errors_total Number of failed benchmark operations.\n")
+	fmt.Fprintf(out, "# TYPE lxd_benchmark_operation_errors_total counter\n")
+	for _, operation := range operations {
+		fmt.Fprintf(out, "lxd_benchmark_operation_errors_total{%s} %d\n", m.formatLabels(operation), m.histograms[operation].errors)
+	}
+
+	return out.Flush()
+}
+
+// WriteFile writes the histograms in the Prometheus text exposition format to the given file.
+func (m *Metrics) WriteFile(filename string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = m.WritePrometheus(file)
+	if err != nil {
+		return err
+	}
+
+	logf("Written metrics file %s", filename)
+	return file.Close()
+}
+
+// Serve exposes the histograms on the /metrics endpoint of the given address until the program exits.
+func (m *Metrics) Serve(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WritePrometheus(w)
+	})
+
+	go http.Serve(listener, mux)
+
+	logf("Serving metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+// PrintSummary prints the number of operations, errors and the mean latency of each operation.
+func (m *Metrics) PrintSummary() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.histograms) == 0 {
+		return
+	}
+
+	operations := make([]string, 0, len(m.histograms))
+	for operation := range m.histograms {
+		operations = append(operations, operation)
+	}
+
+	sort.Strings(operations)
+
+	fmt.Printf("\nLatency summary:\n")
+	for _, operation := range operations {
+		h := m.histograms[operation]
+
+		mean := 0.0
+		if h.count > 0 {
+			mean = h.sum / float64(h.count)
+		}
+
+		fmt.Printf("  %s: %d operations, %d errors, %.3fs mean\n", operation, h.count, h.errors, mean)
+	}
+}
+
+// formatLabels returns the Prometheus label list of the operation, including the global labels.
+func (m *Metrics) formatLabels(operation string) string {
+	labels := []string{fmt.Sprintf("operation=%q", operation)}
+
+	keys := make([]string, 0, len(m.labels))
+	for key := range m.labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		labels = append(labels, fmt.Sprintf("%s=%q", key, m.labels[key]))
+	}
+
+	return strings.Join(labels, ",")
+}
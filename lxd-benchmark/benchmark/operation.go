@@ -1,27 +1,34 @@
 package benchmark
 
 import (
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared/api"
 )
 
-func createContainer(c lxd.ContainerServer, fingerprint string, name string, privileged bool) error {
+func createContainer(c lxd.ContainerServer, fingerprint string, name string, privileged bool, instanceType api.InstanceType) error {
 	config := map[string]string{}
 	if privileged {
 		config["security.privileged"] = "true"
 	}
 	config[userConfigKey] = "true"
 
-	req := api.ContainersPost{
+	req := api.InstancesPost{
 		Name: name,
-		Source: api.ContainerSource{
+		Source: api.InstanceSource{
 			Type:        "image",
 			Fingerprint: fingerprint,
 		},
+		Type: instanceType,
 	}
 	req.Config = config
 
-	op, err := c.CreateContainer(req)
+	op, err := c.CreateInstance(req)
 	if err != nil {
 		return err
 	}
@@ -30,8 +37,8 @@ func createContainer(c lxd.ContainerServer, fingerprint string, name string, pri
 }
 
 func startContainer(c lxd.ContainerServer, name string) error {
-	op, err := c.UpdateContainerState(
-		name, api.ContainerStatePut{Action: "start", Timeout: -1}, "")
+	op, err := c.UpdateInstanceState(
+		name, api.InstanceStatePut{Action: "start", Timeout: -1}, "")
 	if err != nil {
 		return err
 	}
@@ -40,8 +47,8 @@ func startContainer(c lxd.ContainerServer, name string) error {
 }
 
 func stopContainer(c lxd.ContainerServer, name string) error {
-	op, err := c.UpdateContainerState(
-		name, api.ContainerStatePut{Action: "stop", Timeout: -1, Force: true}, "")
+	op, err := c.UpdateInstanceState(
+		name, api.InstanceStatePut{Action: "stop", Timeout: -1, Force: true}, "")
 	if err != nil {
 		return err
 	}
@@ -50,8 +57,8 @@ func stopContainer(c lxd.ContainerServer, name string) error {
 }
 
 func freezeContainer(c lxd.ContainerServer, name string) error {
-	op, err := c.UpdateContainerState(
-		name, api.ContainerStatePut{Action: "freeze", Timeout: -1}, "")
+	op, err := c.UpdateInstanceState(
+		name, api.InstanceStatePut{Action: "freeze", Timeout: -1}, "")
 	if err != nil {
 		return err
 	}
@@ -60,11 +67,59 @@ func freezeContainer(c lxd.ContainerServer, name string) error {
 }
 
 func deleteContainer(c lxd.ContainerServer, name string) error {
-	op, err := c.DeleteContainer(name)
+	op, err := c.DeleteInstance(name)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+func execInstance(c lxd.ContainerServer, name string, command []string) error {
+	req := api.InstanceExecPost{
+		Command:   command,
+		WaitForWS: true,
+	}
+
+	dataDone := make(chan bool)
+	args := lxd.InstanceExecArgs{
+		Stdout:   nopWriteCloser{ioutil.Discard},
+		Stderr:   nopWriteCloser{ioutil.Discard},
+		DataDone: dataDone,
+	}
+
+	op, err := c.ExecInstance(name, req, &args)
+	if err != nil {
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return err
+	}
+
+	<-dataDone
+	return nil
+}
+
+func consoleInstance(c lxd.ContainerServer, name string) error {
+	terminal := newNullTerminal()
+	disconnect := make(chan bool)
+	args := lxd.InstanceConsoleArgs{
+		Terminal:          terminal,
+		Control:           func(conn *websocket.Conn) {},
+		ConsoleDisconnect: disconnect,
+	}
+
+	op, err := c.ConsoleInstance(name, api.InstanceConsolePost{Width: 80, Height: 24}, &args)
 	if err != nil {
 		return err
 	}
 
+	// Detach right away, this measures the cost of setting up and tearing down a console session.
+	close(disconnect)
+	terminal.Close()
+
 	return op.Wait()
 }
 
@@ -76,3 +131,36 @@ func copyImage(c lxd.ContainerServer, s lxd.ImageServer, image api.Image) error
 
 	return op.Wait()
 }
+
+// nopWriteCloser discards everything written to it.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// nullTerminal is a console terminal which never sends any input and discards the output.
+type nullTerminal struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newNullTerminal() *nullTerminal {
+	return &nullTerminal{closed: make(chan struct{})}
+}
+
+func (t *nullTerminal) Read(p []byte) (int, error) {
+	<-t.closed
+	return 0, io.EOF
+}
+
+func (t *nullTerminal) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (t *nullTerminal) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
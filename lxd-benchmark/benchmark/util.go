@@ -3,6 +3,8 @@ package benchmark
 import (
 	"fmt"
 	"time"
+
+	"github.com/lxc/lxd/shared/api"
 )
 
 func getContainerName(count int, index int) string {
@@ -14,11 +16,15 @@ func logf(format string, args ...interface{}) {
 	fmt.Printf(fmt.Sprintf("[%s] %s\n", time.Now().Format(time.StampMilli), format), args...)
 }
 
-func printTestConfig(count int, batchSize int, image string, privileged bool, freeze bool) {
+func printTestConfig(count int, batchSize int, image string, privileged bool, instanceType api.InstanceType, freeze bool) {
 	privilegedStr := "unprivileged"
 	if privileged {
 		privilegedStr = "privileged"
 	}
+	typeStr := "container"
+	if instanceType == api.InstanceTypeVM {
+		typeStr = "virtual-machine"
+	}
 	mode := "normal startup"
 	if freeze {
 		mode = "start and freeze"
@@ -29,6 +35,7 @@ func printTestConfig(count int, batchSize int, image string, privileged bool, fr
 	fmt.Printf("Test variables:\n")
 	fmt.Printf("  Container count: %d\n", count)
 	fmt.Printf("  Container mode: %s\n", privilegedStr)
+	fmt.Printf("  Instance type: %s\n", typeStr)
 	fmt.Printf("  Startup mode: %s\n", mode)
 	fmt.Printf("  Image: %s\n", image)
 	fmt.Printf("  Batches: %d\n", batches)
@@ -13,13 +13,16 @@ import (
 )
 
 type cmdGlobal struct {
-	flagHelp        bool
-	flagParallel    int
-	flagReportFile  string
-	flagReportLabel string
-	flagVersion     bool
+	flagHelp           bool
+	flagMetricsAddress string
+	flagMetricsFile    string
+	flagParallel       int
+	flagReportFile     string
+	flagReportLabel    string
+	flagVersion        bool
 
 	srv            lxd.ContainerServer
+	metrics        *benchmark.Metrics
 	report         *benchmark.CSVReport
 	reportDuration time.Duration
 }
@@ -35,6 +38,25 @@ func (c *cmdGlobal) Run(cmd *cobra.Command, args []string) error {
 	// Print the initial header
 	benchmark.PrintServerInfo(srv)
 
+	// Setup latency metrics, labelled with the server version so runs against different releases can be compared
+	labels := map[string]string{"action": cmd.Name()}
+	server, _, err := srv.GetServer()
+	if err == nil {
+		labels["server_version"] = server.Environment.ServerVersion
+	}
+
+	if c.flagReportLabel != "" {
+		labels["label"] = c.flagReportLabel
+	}
+
+	c.metrics = benchmark.NewMetrics(labels)
+	if c.flagMetricsAddress != "" {
+		err := c.metrics.Serve(c.flagMetricsAddress)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Setup report handling
 	if c.flagReportFile != "" {
 		c.report = &benchmark.CSVReport{Filename: c.flagReportFile}
@@ -50,7 +72,16 @@ func (c *cmdGlobal) Run(cmd *cobra.Command, args []string) error {
 }
 
 func (c *cmdGlobal) Teardown(cmd *cobra.Command, args []string) error {
-	// Nothing to do with not reporting
+	c.metrics.PrintSummary()
+
+	if c.flagMetricsFile != "" {
+		err := c.metrics.WriteFile(c.flagMetricsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Nothing else to do with not reporting
 	if c.report == nil {
 		return nil
 	}
@@ -84,6 +115,10 @@ func main() {
   when doing changes to the LXD codebase.
 
   A CSV report can be produced to be consumed by graphing software.
+
+  The latency of the individual operations is collected as histograms
+  which can be written to a file or served over HTTP in the Prometheus
+  text format.
 `
 	app.Example = `  # Spawn 20 Ubuntu containers in batches of 4
   lxd-benchmark launch --count 20 --parallel 4
@@ -91,6 +126,18 @@ func main() {
   # Create 50 Alpine containers in batches of 10
   lxd-benchmark init --count 50 --parallel 10 images:alpine/edge
 
+  # Spawn 4 Ubuntu virtual machines
+  lxd-benchmark launch --count 4 --vm
+
+  # Run 1000 commands in the test instances and export the latency histograms
+  lxd-benchmark exec --count 1000 --metrics-file exec.prom -- true
+
+  # Send 10000 read requests to the API
+  lxd-benchmark query --count 10000
+
+  # Create, start, stop and delete containers in a loop for 12 hours
+  lxd-benchmark soak --duration 12h --metrics-address :9100
+
   # Delete all test containers using dynamic batch size
   lxd-benchmark delete`
 	app.SilenceUsage = true
@@ -105,6 +152,8 @@ func main() {
 	app.PersistentFlags().IntVarP(&globalCmd.flagParallel, "parallel", "P", -1, "Number of threads to use"+"``")
 	app.PersistentFlags().StringVar(&globalCmd.flagReportFile, "report-file", "", "Path to the CSV report file"+"``")
 	app.PersistentFlags().StringVar(&globalCmd.flagReportLabel, "report-label", "", "Label for the new entry in the report [default=ACTION]"+"``")
+	app.PersistentFlags().StringVar(&globalCmd.flagMetricsFile, "metrics-file", "", "Path to write the latency histograms to, in the Prometheus text format"+"``")
+	app.PersistentFlags().StringVar(&globalCmd.flagMetricsAddress, "metrics-address", "", "Address to serve the latency histograms on (/metrics) while running"+"``")
 
 	// Version handling
 	app.SetVersionTemplate("{{.Version}}\n")
@@ -130,6 +179,22 @@ func main() {
 	deleteCmd := cmdDelete{global: &globalCmd}
 	app.AddCommand(deleteCmd.Command())
 
+	// exec sub-command
+	execCmd := cmdExec{global: &globalCmd}
+	app.AddCommand(execCmd.Command())
+
+	// console sub-command
+	consoleCmd := cmdConsole{global: &globalCmd}
+	app.AddCommand(consoleCmd.Command())
+
+	// query sub-command
+	queryCmd := cmdQuery{global: &globalCmd}
+	app.AddCommand(queryCmd.Command())
+
+	// soak sub-command
+	soakCmd := cmdSoak{global: &globalCmd}
+	app.AddCommand(soakCmd.Command())
+
 	// Run the main command and handle errors
 	err := app.Execute()
 	if err != nil {
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxd-benchmark/benchmark"
+)
+
+type cmdConsole struct {
+	global *cmdGlobal
+
+	flagCount int
+}
+
+func (c *cmdConsole) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "console"
+	cmd.Short = "Attach to and detach from the console of running containers"
+	cmd.RunE = c.Run
+	cmd.Flags().IntVarP(&c.flagCount, "count", "C", 100, "Number of console sessions"+"``")
+
+	return cmd
+}
+
+func (c *cmdConsole) Run(cmd *cobra.Command, args []string) error {
+	// Get the containers
+	containers, err := benchmark.GetContainers(c.global.srv)
+	if err != nil {
+		return err
+	}
+
+	// Run the test
+	duration, err := benchmark.ConsoleContainers(c.global.srv, containers, c.flagCount, c.global.flagParallel, c.global.metrics)
+	if err != nil {
+		return err
+	}
+
+	c.global.reportDuration = duration
+
+	return nil
+}
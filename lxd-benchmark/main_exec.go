@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxd-benchmark/benchmark"
+)
+
+type cmdExec struct {
+	global *cmdGlobal
+
+	flagCount int
+}
+
+func (c *cmdExec) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "exec [--] [<command>...]"
+	cmd.Short = "Run commands in running containers"
+	cmd.RunE = c.Run
+	cmd.Flags().IntVarP(&c.flagCount, "count", "C", 100, "Number of commands to run"+"``")
+
+	return cmd
+}
+
+func (c *cmdExec) Run(cmd *cobra.Command, args []string) error {
+	// Choose the command
+	command := []string{"true"}
+	if len(args) > 0 {
+		command = args
+	}
+
+	// Get the containers
+	containers, err := benchmark.GetContainers(c.global.srv)
+	if err != nil {
+		return err
+	}
+
+	// Run the test
+	duration, err := benchmark.ExecContainers(c.global.srv, containers, c.flagCount, c.global.flagParallel, command, c.global.metrics)
+	if err != nil {
+		return err
+	}
+
+	c.global.reportDuration = duration
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lxc/lxd/lxd-benchmark/benchmark"
+	"github.com/lxc/lxd/shared/api"
 )
 
 type cmdInit struct {
@@ -11,6 +12,7 @@ type cmdInit struct {
 
 	flagCount      int
 	flagPrivileged bool
+	flagVM         bool
 }
 
 func (c *cmdInit) Command() *cobra.Command {
@@ -20,6 +22,7 @@ func (c *cmdInit) Command() *cobra.Command {
 	cmd.RunE = c.Run
 	cmd.Flags().IntVarP(&c.flagCount, "count", "C", 1, "Number of containers to create"+"``")
 	cmd.Flags().BoolVar(&c.flagPrivileged, "privileged", false, "Use privileged containers")
+	cmd.Flags().BoolVar(&c.flagVM, "vm", false, "Create virtual machines instead of containers")
 
 	return cmd
 }
@@ -32,7 +35,7 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run the test
-	duration, err := benchmark.LaunchContainers(c.global.srv, c.flagCount, c.global.flagParallel, image, c.flagPrivileged, false, false)
+	duration, err := benchmark.LaunchContainers(c.global.srv, c.flagCount, c.global.flagParallel, image, c.flagPrivileged, c.instanceType(), false, false, c.global.metrics)
 	if err != nil {
 		return err
 	}
@@ -41,3 +44,12 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// instanceType returns the type of the instances to create.
+func (c *cmdInit) instanceType() api.InstanceType {
+	if c.flagVM {
+		return api.InstanceTypeVM
+	}
+
+	return api.InstanceTypeContainer
+}
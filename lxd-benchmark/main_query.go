@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxd-benchmark/benchmark"
+)
+
+// defaultQueryPaths are the API endpoints queried when none is specified.
+var defaultQueryPaths = []string{
+	"/1.0",
+	"/1.0/instances?recursion=1",
+	"/1.0/images?recursion=1",
+	"/1.0/networks?recursion=1",
+	"/1.0/storage-pools?recursion=1",
+	"/1.0/operations?recursion=1",
+}
+
+type cmdQuery struct {
+	global *cmdGlobal
+
+	flagCount int
+}
+
+func (c *cmdQuery) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "query [<path>...]"
+	cmd.Short = "Send read requests to the API"
+	cmd.RunE = c.Run
+	cmd.Flags().IntVarP(&c.flagCount, "count", "C", 1000, "Number of requests to send"+"``")
+
+	return cmd
+}
+
+func (c *cmdQuery) Run(cmd *cobra.Command, args []string) error {
+	// Choose the endpoints
+	paths := defaultQueryPaths
+	if len(args) > 0 {
+		paths = args
+	}
+
+	// Run the test
+	duration, err := benchmark.QueryAPI(c.global.srv, paths, c.flagCount, c.global.flagParallel, c.global.metrics)
+	if err != nil {
+		return err
+	}
+
+	c.global.reportDuration = duration
+
+	return nil
+}
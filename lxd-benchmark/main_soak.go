@@ -0,0 +1,55 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxd-benchmark/benchmark"
+	"github.com/lxc/lxd/shared/api"
+)
+
+type cmdSoak struct {
+	global *cmdGlobal
+
+	flagCommand    []string
+	flagDuration   time.Duration
+	flagPrivileged bool
+	flagVM         bool
+}
+
+func (c *cmdSoak) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "soak [[<remote>:]<image>]"
+	cmd.Short = "Create, start, stop and delete containers in a loop"
+	cmd.RunE = c.Run
+	cmd.Flags().DurationVarP(&c.flagDuration, "duration", "d", time.Hour, "How long to run the test for"+"``")
+	cmd.Flags().StringArrayVar(&c.flagCommand, "exec", nil, "Command to run in each container after start (can be repeated for arguments)"+"``")
+	cmd.Flags().BoolVar(&c.flagPrivileged, "privileged", false, "Use privileged containers")
+	cmd.Flags().BoolVar(&c.flagVM, "vm", false, "Create virtual machines instead of containers")
+
+	return cmd
+}
+
+func (c *cmdSoak) Run(cmd *cobra.Command, args []string) error {
+	// Choose the image
+	image := "ubuntu:"
+	if len(args) > 0 {
+		image = args[0]
+	}
+
+	instanceType := api.InstanceTypeContainer
+	if c.flagVM {
+		instanceType = api.InstanceTypeVM
+	}
+
+	// Run the test
+	duration, err := benchmark.Soak(c.global.srv, c.flagDuration, c.global.flagParallel, image, c.flagPrivileged, instanceType, c.flagCommand, c.global.metrics)
+	if err != nil {
+		return err
+	}
+
+	c.global.reportDuration = duration
+
+	return nil
+}
@@ -27,7 +27,7 @@ func (c *cmdStop) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run the test
-	duration, err := benchmark.StopContainers(c.global.srv, containers, c.global.flagParallel)
+	duration, err := benchmark.StopContainers(c.global.srv, containers, c.global.flagParallel, c.global.metrics)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// vmxDiskPattern matches the VMware disk keys (e.g. scsi0:0), capturing their bus, controller and unit.
+var vmxDiskPattern = regexp.MustCompile(`^(nvme|scsi|sata|ide)(\d+):(\d+)$`)
+
+// vmxNICPattern matches the VMware network interface keys (e.g. ethernet0).
+var vmxNICPattern = regexp.MustCompile(`^ethernet(\d+)$`)
+
+// vmxBuses lists the VMware disk buses in the order the disks are attached to the virtual machine.
+var vmxBuses = []string{"nvme", "scsi", "sata", "ide"}
+
+// vmSource represents a virtual machine to migrate from its disk images.
+type vmSource struct {
+	path   string            // Temporary directory holding the converted disks.
+	disks  []string          // Raw disk images, the first one being the root disk.
+	nics   []string          // MAC addresses of the network interfaces (empty if generated).
+	config map[string]string // Instance configuration matching the source virtual machine.
+}
+
+// setupVMSource converts the disk images (or the disks of a VMware .vmx file) to raw disk images. When virt-v2v is
+// available and inject is set, it converts the root disk, injecting the virtio drivers into the guest so that it
+// can boot from the LXD virtual machine disks and network interfaces.
+func setupVMSource(images []string, inject bool, out io.Writer) (*vmSource, error) {
+	s := &vmSource{
+		config: map[string]string{},
+	}
+
+	vmx := ""
+	if len(images) == 1 && strings.HasSuffix(images[0], ".vmx") {
+		vmx = images[0]
+
+		var err error
+		images, err = s.parseVMX(vmx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %q", vmx)
+		}
+
+		if len(images) == 0 {
+			return nil, fmt.Errorf("No disks found in %q", vmx)
+		}
+	}
+
+	path, err := ioutil.TempDir("", "lxd-p2c_disks_")
+	if err != nil {
+		return nil, err
+	}
+
+	s.path = path
+
+	if inject {
+		_, err = exec.LookPath("virt-v2v")
+		if err != nil {
+			fmt.Fprintln(out, "virt-v2v not found, the virtio drivers won't be injected into the guest")
+			inject = false
+		}
+	}
+
+	// Convert the disks handled by virt-v2v, that is all of them with a .vmx file and only the root disk otherwise.
+	if inject {
+		args := []string{"-o", "local", "-os", s.path, "-of", "raw", "-on", "lxd-p2c"}
+		if vmx != "" {
+			args = append([]string{"-i", "vmx", vmx}, args...)
+		} else {
+			args = append([]string{"-i", "disk", images[0]}, args...)
+		}
+
+		fmt.Fprintln(out, "Converting the guest and injecting the virtio drivers")
+		err = runVerbose(out, "virt-v2v", args...)
+		if err != nil {
+			return nil, err
+		}
+
+		s.disks, err = filepath.Glob(filepath.Join(s.path, "lxd-p2c-sd*"))
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(s.disks)
+
+		if len(s.disks) == 0 {
+			return nil, fmt.Errorf("virt-v2v didn't output any disk")
+		}
+
+		if vmx != "" {
+			images = nil
+		} else {
+			images = images[1:]
+		}
+	}
+
+	// Convert the remaining disks as-is.
+	for _, image := range images {
+		disk := filepath.Join(s.path, fmt.Sprintf("disk%d.img", len(s.disks)))
+
+		fmt.Fprintf(out, "Converting %s\n", image)
+		err = runVerbose(out, "qemu-img", "convert", "-p", "-O", "raw", image, disk)
+		if err != nil {
+			return nil, err
+		}
+
+		s.disks = append(s.disks, disk)
+	}
+
+	if !inject {
+		fmt.Fprintln(out, "The guest must already have the virtio-scsi and virtio-net drivers to boot and reach the network")
+	}
+
+	return s, nil
+}
+
+// parseVMX loads the CPU, memory, network interfaces and firmware settings of a VMware virtual machine, returning
+// the paths of its disks in the order they are attached.
+func (s *vmSource) parseVMX(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "=", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(fields[0]))
+		config[key] = strings.Trim(strings.TrimSpace(fields[1]), `"`)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	present := func(device string) bool {
+		return strings.EqualFold(config[device+".present"], "TRUE")
+	}
+
+	// Disks, skipping the CD-ROM drives.
+	disks := []string{}
+	for key, fileName := range config {
+		device := strings.TrimSuffix(key, ".filename")
+		if device == key || !vmxDiskPattern.MatchString(device) || !present(device) {
+			continue
+		}
+
+		if strings.Contains(config[device+".devicetype"], "cdrom") || !strings.HasSuffix(strings.ToLower(fileName), ".vmdk") {
+			continue
+		}
+
+		disks = append(disks, device)
+	}
+
+	sort.Slice(disks, func(i, j int) bool {
+		return vmxDiskLess(vmxDiskPattern.FindStringSubmatch(disks[i]), vmxDiskPattern.FindStringSubmatch(disks[j]))
+	})
+
+	for i, device := range disks {
+		fileName := config[device+".filename"]
+		if !filepath.IsAbs(fileName) {
+			fileName = filepath.Join(filepath.Dir(path), fileName)
+		}
+
+		disks[i] = fileName
+	}
+
+	// Network interfaces, keeping their MAC addresses as the guest configuration may depend on them.
+	nics := []string{}
+	for key := range config {
+		device := strings.TrimSuffix(key, ".present")
+		if device == key || !vmxNICPattern.MatchString(device) || !present(device) {
+			continue
+		}
+
+		nics = append(nics, device)
+	}
+
+	sort.Slice(nics, func(i, j int) bool {
+		return len(nics[i]) < len(nics[j]) || len(nics[i]) == len(nics[j]) && nics[i] < nics[j]
+	})
+
+	for _, device := range nics {
+		hwaddr := config[device+".address"]
+		if hwaddr == "" {
+			hwaddr = config[device+".generatedaddress"]
+		}
+
+		s.nics = append(s.nics, hwaddr)
+	}
+
+	// Resources and firmware.
+	if config["numvcpus"] != "" {
+		s.config["limits.cpu"] = config["numvcpus"]
+	}
+
+	if config["memsize"] != "" {
+		s.config["limits.memory"] = fmt.Sprintf("%sMiB", config["memsize"])
+	}
+
+	if config["firmware"] != "efi" {
+		return nil, fmt.Errorf("Only UEFI virtual machines can be migrated")
+	}
+
+	if !strings.EqualFold(config["uefi.secureboot.enabled"], "TRUE") {
+		s.config["security.secureboot"] = "false"
+	}
+
+	return disks, nil
+}
+
+// vmxDiskLess compares two VMware disk keys split by vmxDiskPattern.
+func vmxDiskLess(a []string, b []string) bool {
+	busIndex := func(bus string) int {
+		for i, entry := range vmxBuses {
+			if entry == bus {
+				return i
+			}
+		}
+
+		return len(vmxBuses)
+	}
+
+	if a[1] != b[1] {
+		return busIndex(a[1]) < busIndex(b[1])
+	}
+
+	for i := 2; i < 4; i++ {
+		if a[i] != b[i] {
+			return len(a[i]) < len(b[i]) || len(a[i]) == len(b[i]) && a[i] < b[i]
+		}
+	}
+
+	return false
+}
+
+// runVerbose runs a command, writing its output to out.
+func runVerbose(out io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to run %s", name)
+	}
+
+	return nil
+}
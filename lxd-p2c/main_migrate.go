@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
@@ -13,7 +16,9 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/osarch"
 )
@@ -29,14 +34,17 @@ type cmdMigrate struct {
 	flagType        string
 	flagRsyncArgs   string
 	flagNoProfiles  bool
+	flagNoDrivers   bool
+	flagManifest    string
+	flagParallel    int
 }
 
 func (c *cmdMigrate) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = "lxd-p2c <target URL> <container name> <filesystem root> [<filesystem mounts>...]"
-	cmd.Short = "Physical to container migration tool"
+	cmd.Use = "lxd-p2c <target URL> [<instance name> <filesystem root | disk image | .vmx file> [<filesystem mounts> | <disk images>...]]"
+	cmd.Short = "Physical to instance migration tool"
 	cmd.Long = `Description:
-  Physical to container migration tool
+  Physical to instance migration tool
 
   This tool lets you turn any Linux filesystem (including your current one)
   into a LXD container on a remote LXD host.
@@ -45,7 +53,28 @@ func (c *cmdMigrate) Command() *cobra.Command {
   additional mount you list, then transfer this through LXD's migration
   API to create a new container from it.
 
+  It can also turn VMware and Hyper-V virtual machines into LXD virtual
+  machines, either from their disk images (VMDK, VHDX, VHD, QCOW2 or raw),
+  the first one being the root disk, or from a VMware .vmx file whose disks,
+  network interfaces, CPU and memory are then replicated. When virt-v2v is
+  available, the virtio drivers are injected into the guest.
+
   The same set of options as ` + "`lxc launch`" + ` are also supported.
+
+  Many instances can be migrated at once from a YAML manifest listing them
+  (--manifest), running several migrations in parallel (--parallel). The
+  instances successfully migrated are recorded next to the manifest so that
+  running the same command again only retries the remaining ones.
+
+  Example manifest:
+    instances:
+    - name: c1
+      sources: [/mnt/c1]
+    - name: vm1
+      sources: [/srv/vmware/vm1/vm1.vmx]
+      network: lxdbr0
+      config:
+        limits.memory: 4GiB
 `
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, "Configuration key and value to set on the container"+"``")
@@ -56,6 +85,9 @@ func (c *cmdMigrate) Command() *cobra.Command {
 	cmd.Flags().StringVarP(&c.flagType, "type", "t", "", "Instance type to use for the container"+"``")
 	cmd.Flags().StringVar(&c.flagRsyncArgs, "rsync-args", "", "Extra arguments to pass to rsync"+"``")
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, "Create the container with no profiles applied")
+	cmd.Flags().BoolVar(&c.flagNoDrivers, "no-driver-injection", false, "Don't inject the virtio drivers into virtual machines")
+	cmd.Flags().StringVar(&c.flagManifest, "manifest", "", "YAML manifest of the instances to migrate"+"``")
+	cmd.Flags().IntVar(&c.flagParallel, "parallel", 2, "Number of instances to migrate in parallel (with --manifest)"+"``")
 
 	return cmd
 }
@@ -76,12 +108,14 @@ func (c *cmdMigrate) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if c.flagNoProfiles && len(c.flagProfile) != 0 {
-		return fmt.Errorf("no-profiles can't be specified alongside profiles")
-	}
+	// Instances described in a manifest
+	if c.flagManifest != "" {
+		if len(args) != 1 {
+			cmd.Help()
+			return fmt.Errorf("Only the target URL can be passed alongside a manifest")
+		}
 
-	if c.flagStorageSize != "" && c.flagStorage == "" {
-		return fmt.Errorf("--storage-size requires --storage be passed")
+		return c.runManifest(args[0])
 	}
 
 	// Handle mandatory arguments
@@ -90,66 +124,148 @@ func (c *cmdMigrate) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Missing required arguments")
 	}
 
-	// Get and sort the mounts
-	mounts := args[2:]
-	sort.Strings(mounts)
+	item, err := c.defaultItem()
+	if err != nil {
+		return err
+	}
 
-	// Create the mount namespace and ensure we're not moved around
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
+	item.Name = args[1]
+	item.Sources = args[2:]
 
-	// Unshare a new mntns so our mounts don't leak
-	err = unix.Unshare(unix.CLONE_NEWNS)
+	err = item.validate()
 	if err != nil {
-		return errors.Wrap(err, "Failed to unshare mount namespace")
+		return err
 	}
 
-	// Prevent mount propagation back to initial namespace
-	err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	URL, err := parseURL(args[0])
 	if err != nil {
-		return errors.Wrap(err, "Failed to disable mount propagation")
+		return err
 	}
 
-	// Create the temporary directory to be used for the mounts
-	path, err := ioutil.TempDir("", "lxd-p2c_mount_")
+	// Connect to the target
+	dst, err := connectTarget(URL)
 	if err != nil {
 		return err
 	}
 
-	// Automatically clean-up the temporary path on exit
-	defer func(path string) {
-		unix.Unmount(path, unix.MNT_DETACH)
-		os.Remove(path)
-	}(path)
+	newProgress := func(format string) migrationProgress {
+		return &utils.ProgressRenderer{Format: format}
+	}
+
+	return c.migrate(dst, item, os.Stdout, newProgress)
+}
 
-	// Create the rootfs directory
-	fullPath := fmt.Sprintf("%s/rootfs", path)
-	err = os.Mkdir(fullPath, 0755)
-	if err != nil {
-		return err
+// defaultItem returns the instance options set by the command line flags.
+func (c *cmdMigrate) defaultItem() (*migrationItem, error) {
+	item := &migrationItem{
+		Config:      map[string]string{},
+		Network:     c.flagNetwork,
+		Profiles:    c.flagProfile,
+		NoProfiles:  c.flagNoProfiles,
+		Storage:     c.flagStorage,
+		StorageSize: c.flagStorageSize,
+		Type:        c.flagType,
 	}
 
-	// Setup the source (mounts)
-	err = setupSource(fullPath, mounts)
-	if err != nil {
-		return fmt.Errorf("Failed to setup the source: %v", err)
+	for _, entry := range c.flagConfig {
+		if !strings.Contains(entry, "=") {
+			return nil, fmt.Errorf("Bad key=value configuration: %v", entry)
+		}
+
+		fields := strings.SplitN(entry, "=", 2)
+		item.Config[fields[0]] = fields[1]
 	}
 
-	URL, err := parseURL(args[0])
-	if err != nil {
-		return err
+	return item, nil
+}
+
+// migrate creates an instance on the target from the sources of the item. The informational messages (including
+// the output of the disk conversions) are written to out while the transfers are reported through newProgress.
+func (c *cmdMigrate) migrate(dst lxd.ContainerServer, item *migrationItem, out io.Writer, newProgress func(format string) migrationProgress) error {
+	var err error
+
+	// Disk images are turned into a virtual machine, filesystems into a container
+	instanceType := api.InstanceTypeContainer
+	if !shared.IsDir(item.Sources[0]) {
+		instanceType = api.InstanceTypeVM
+
+		_, err = exec.LookPath("qemu-img")
+		if err != nil {
+			return err
+		}
 	}
 
-	// Connect to the target
-	dst, err := connectTarget(URL)
-	if err != nil {
-		return err
+	var fullPath string
+	var vm *vmSource
+	if instanceType == api.InstanceTypeVM {
+		// Convert the disks
+		vm, err = setupVMSource(item.Sources, !c.flagNoDrivers, out)
+		if vm != nil {
+			defer os.RemoveAll(vm.path)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to setup the source: %v", err)
+		}
+
+		// Create the empty config directory sent alongside the root disk
+		fullPath = shared.AddSlash(filepath.Join(vm.path, "config"))
+		err = os.Mkdir(fullPath, 0700)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Get and sort the mounts
+		mounts := append([]string{}, item.Sources...)
+		sort.Strings(mounts)
+
+		// Create the mount namespace and ensure we're not moved around. The thread is left locked so that it
+		// exits with the goroutine rather than being reused with the private mount namespace.
+		runtime.LockOSThread()
+
+		// Unshare a new mntns so our mounts don't leak
+		err = unix.Unshare(unix.CLONE_NEWNS)
+		if err != nil {
+			return errors.Wrap(err, "Failed to unshare mount namespace")
+		}
+
+		// Prevent mount propagation back to initial namespace
+		err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+		if err != nil {
+			return errors.Wrap(err, "Failed to disable mount propagation")
+		}
+
+		// Create the temporary directory to be used for the mounts
+		path, err := ioutil.TempDir("", "lxd-p2c_mount_")
+		if err != nil {
+			return err
+		}
+
+		// Automatically clean-up the temporary path on exit
+		defer func(path string) {
+			unix.Unmount(path, unix.MNT_DETACH)
+			os.Remove(path)
+		}(path)
+
+		// Create the rootfs directory
+		fullPath = fmt.Sprintf("%s/rootfs", path)
+		err = os.Mkdir(fullPath, 0755)
+		if err != nil {
+			return err
+		}
+
+		// Setup the source (mounts)
+		err = setupSource(fullPath, mounts)
+		if err != nil {
+			return fmt.Errorf("Failed to setup the source: %v", err)
+		}
 	}
 
-	// Container creation request
-	apiArgs := api.ContainersPost{}
-	apiArgs.Name = args[1]
-	apiArgs.Source = api.ContainerSource{
+	// Instance creation request
+	apiArgs := api.InstancesPost{}
+	apiArgs.Name = item.Name
+	apiArgs.Type = instanceType
+	apiArgs.Source = api.InstanceSource{
 		Type: "migration",
 		Mode: "push",
 	}
@@ -162,42 +278,56 @@ func (c *cmdMigrate) Run(cmd *cobra.Command, args []string) error {
 	apiArgs.Architecture = architectureName
 
 	// Instance type
-	apiArgs.InstanceType = c.flagType
+	apiArgs.InstanceType = item.Type
 
 	// Config overrides
 	apiArgs.Config = map[string]string{}
-	for _, entry := range c.flagConfig {
-		if !strings.Contains(entry, "=") {
-			return fmt.Errorf("Bad key=value configuration: %v", entry)
+	if vm != nil {
+		for k, v := range vm.config {
+			apiArgs.Config[k] = v
 		}
+	}
 
-		fields := strings.SplitN(entry, "=", 2)
-		apiArgs.Config[fields[0]] = fields[1]
+	for k, v := range item.Config {
+		apiArgs.Config[k] = v
 	}
 
 	// Profiles
-	if len(c.flagProfile) != 0 {
-		apiArgs.Profiles = c.flagProfile
+	if len(item.Profiles) != 0 {
+		apiArgs.Profiles = item.Profiles
 	}
 
-	if c.flagNoProfiles {
+	if item.NoProfiles {
 		apiArgs.Profiles = []string{}
 	}
 
 	// Devices
 	apiArgs.Devices = map[string]map[string]string{}
 
-	network := c.flagNetwork
+	network := item.Network
 	if network != "" {
-		apiArgs.Devices["eth0"] = map[string]string{
-			"type":    "nic",
-			"nictype": "bridged",
-			"parent":  network,
-			"name":    "eth0",
+		// Replicate the network interfaces of the virtual machine
+		nics := []string{""}
+		if vm != nil && len(vm.nics) > 0 {
+			nics = vm.nics
+		}
+
+		for i, hwaddr := range nics {
+			name := fmt.Sprintf("eth%d", i)
+			apiArgs.Devices[name] = map[string]string{
+				"type":    "nic",
+				"nictype": "bridged",
+				"parent":  network,
+				"name":    name,
+			}
+
+			if hwaddr != "" {
+				apiArgs.Devices[name]["hwaddr"] = hwaddr
+			}
 		}
 	}
 
-	storage := c.flagStorage
+	storage := item.Storage
 	if storage != "" {
 		apiArgs.Devices["root"] = map[string]string{
 			"type": "disk",
@@ -205,45 +335,152 @@ func (c *cmdMigrate) Run(cmd *cobra.Command, args []string) error {
 			"path": "/",
 		}
 
-		storageSize := c.flagStorageSize
+		storageSize := item.StorageSize
 		if storageSize != "" {
 			apiArgs.Devices["root"]["size"] = storageSize
 		}
 	}
 
-	// Check if the container already exists
-	_, _, err = dst.GetContainer(apiArgs.Name)
+	// Check if the instance already exists
+	_, _, err = dst.GetInstance(apiArgs.Name)
 	if err == nil {
-		return fmt.Errorf("Container '%s' already exists", apiArgs.Name)
+		return fmt.Errorf("Instance '%s' already exists", apiArgs.Name)
 	}
 
-	// Create the container
+	// Create the instance
 	success := false
-	op, err := dst.CreateContainer(apiArgs)
+	op, err := dst.CreateInstance(apiArgs)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if !success {
-			dst.DeleteContainer(apiArgs.Name)
+			op, err := dst.DeleteInstance(apiArgs.Name)
+			if err == nil {
+				op.Wait()
+			}
 		}
 	}()
 
-	progress := utils.ProgressRenderer{Format: "Transferring container: %s"}
+	progress := newProgress("Transferring instance: %s")
 	_, err = op.AddHandler(progress.UpdateOp)
 	if err != nil {
 		progress.Done("")
 		return err
 	}
 
-	err = transferRootfs(dst, op, fullPath, c.flagRsyncArgs)
+	rootDisk := ""
+	if vm != nil {
+		rootDisk = vm.disks[0]
+	}
+
+	err = transferRootfs(dst, op, fullPath, c.flagRsyncArgs, rootDisk)
 	if err != nil {
 		return err
 	}
 
-	progress.Done(fmt.Sprintf("Container %s successfully created", apiArgs.Name))
+	progress.Done("")
+
+	// Attach the other disks of the virtual machine
+	if vm != nil && len(vm.disks) > 1 {
+		err = c.transferDisks(dst, apiArgs.Name, vm.disks[1:], newProgress)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "Instance %s successfully created\n", apiArgs.Name)
 	success = true
 
 	return nil
 }
+
+// transferDisks creates a custom block volume from each disk image, in the storage pool of the root disk of the
+// instance, and attaches them to the instance in the same order.
+func (c *cmdMigrate) transferDisks(dst lxd.ContainerServer, name string, disks []string, newProgress func(format string) migrationProgress) error {
+	inst, etag, err := dst.GetInstance(name)
+	if err != nil {
+		return err
+	}
+
+	pool := inst.ExpandedDevices["root"]["pool"]
+	if pool == "" {
+		return fmt.Errorf("Failed to find the storage pool of instance '%s'", name)
+	}
+
+	success := false
+	volNames := []string{}
+	defer func() {
+		if !success {
+			for _, volName := range volNames {
+				dst.DeleteStoragePoolVolume(pool, "custom", volName)
+			}
+		}
+	}()
+
+	for i, disk := range disks {
+		volName := fmt.Sprintf("%s-disk%d", name, i+1)
+
+		info, err := os.Stat(disk)
+		if err != nil {
+			return err
+		}
+
+		req := api.StorageVolumesPost{
+			Name:        volName,
+			Type:        "custom",
+			ContentType: "block",
+			Source: api.StorageVolumeSource{
+				Type: "migration",
+				Mode: "push",
+			},
+		}
+
+		req.Config = map[string]string{
+			"size": fmt.Sprintf("%dB", info.Size()),
+		}
+
+		// Volume creation
+		op, _, err := dst.RawOperation("POST", fmt.Sprintf("/storage-pools/%s/volumes/custom", url.PathEscape(pool)), req, "")
+		if err != nil {
+			return err
+		}
+
+		volNames = append(volNames, volName)
+
+		progress := newProgress(fmt.Sprintf("Transferring disk %s: %%s", volName))
+		_, err = op.AddHandler(progress.UpdateOp)
+		if err != nil {
+			progress.Done("")
+			return err
+		}
+
+		err = transferRootfs(dst, op, "", c.flagRsyncArgs, disk)
+		if err != nil {
+			return err
+		}
+
+		progress.Done("")
+
+		devName := fmt.Sprintf("disk%d", i+1)
+		inst.Devices[devName] = map[string]string{
+			"type":   "disk",
+			"pool":   pool,
+			"source": volName,
+		}
+	}
+
+	op, err := dst.UpdateInstance(name, inst.Writable(), etag)
+	if err != nil {
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		return err
+	}
+
+	success = true
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// migrationItem represents an instance to migrate and the options to create it with.
+type migrationItem struct {
+	Name        string            `yaml:"name"`
+	Sources     []string          `yaml:"sources"`
+	Config      map[string]string `yaml:"config"`
+	Network     string            `yaml:"network"`
+	Profiles    []string          `yaml:"profiles"`
+	NoProfiles  bool              `yaml:"no_profiles"`
+	Storage     string            `yaml:"storage"`
+	StorageSize string            `yaml:"storage_size"`
+	Type        string            `yaml:"type"`
+}
+
+// validate checks the item options are consistent.
+func (i *migrationItem) validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("Missing instance name")
+	}
+
+	if len(i.Sources) == 0 {
+		return fmt.Errorf("Missing sources for instance %q", i.Name)
+	}
+
+	if i.NoProfiles && len(i.Profiles) != 0 {
+		return fmt.Errorf("no-profiles can't be specified alongside profiles")
+	}
+
+	if i.StorageSize != "" && i.Storage == "" {
+		return fmt.Errorf("--storage-size requires --storage be passed")
+	}
+
+	return nil
+}
+
+// migrationManifest represents a set of instances to migrate.
+type migrationManifest struct {
+	Instances []*migrationItem `yaml:"instances"`
+}
+
+// migrationState records the instances of a manifest which were successfully migrated.
+type migrationState struct {
+	Done map[string]time.Time `yaml:"done"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// markDone records the instance as migrated, saving the state right away so that it survives an interruption.
+func (s *migrationState) markDone(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Done[name] = time.Now().UTC()
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, data, 0600)
+}
+
+// migrationProgress reports the progress of the transfers of a migration.
+type migrationProgress interface {
+	UpdateOp(op api.Operation)
+	Done(msg string)
+}
+
+// itemProgressInterval is how often the progress of each of the migrations of a manifest is reported.
+const itemProgressInterval = 10 * time.Second
+
+// itemProgressLock prevents the status lines of the migrations of a manifest from being interleaved.
+var itemProgressLock sync.Mutex
+
+// itemStatus prints a status line for one of the migrations of a manifest.
+func itemStatus(name string, msg string) {
+	itemProgressLock.Lock()
+	defer itemProgressLock.Unlock()
+
+	fmt.Printf("%s: %s\n", name, msg)
+}
+
+// itemProgress reports the progress of one of the migrations of a manifest as status lines.
+type itemProgress struct {
+	name   string
+	format string
+
+	last time.Time
+	lock sync.Mutex
+}
+
+// UpdateOp reports the progress of the operation, at most once every itemProgressInterval.
+func (p *itemProgress) UpdateOp(op api.Operation) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if time.Since(p.last) < itemProgressInterval {
+		return
+	}
+
+	for key, value := range op.Metadata {
+		if !strings.HasSuffix(key, "_progress") {
+			continue
+		}
+
+		p.last = time.Now()
+		itemStatus(p.name, fmt.Sprintf(p.format, value))
+		break
+	}
+}
+
+// Done reports the final status of the transfer, if any.
+func (p *itemProgress) Done(msg string) {
+	if msg != "" {
+		itemStatus(p.name, msg)
+	}
+}
+
+// runManifest migrates the instances of the manifest which weren't migrated yet, flagParallel at a time. The output
+// of each migration is written to a log file next to the manifest.
+func (c *cmdMigrate) runManifest(targetURL string) error {
+	data, err := ioutil.ReadFile(c.flagManifest)
+	if err != nil {
+		return err
+	}
+
+	manifest := migrationManifest{}
+	err = yaml.Unmarshal(data, &manifest)
+	if err != nil {
+		return fmt.Errorf("Failed to parse the manifest: %v", err)
+	}
+
+	if c.flagParallel < 1 {
+		return fmt.Errorf("At least one instance must be migrated at a time")
+	}
+
+	// Load the instances migrated by the previous runs.
+	state := &migrationState{
+		Done: map[string]time.Time{},
+		path: c.flagManifest + ".state",
+	}
+
+	if shared.PathExists(state.path) {
+		data, err := ioutil.ReadFile(state.path)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(data, state)
+		if err != nil {
+			return fmt.Errorf("Failed to parse %q: %v", state.path, err)
+		}
+
+		if state.Done == nil {
+			state.Done = map[string]time.Time{}
+		}
+	}
+
+	// Apply the command line options to the instances which don't override them.
+	defaults, err := c.defaultItem()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	items := []*migrationItem{}
+	for _, item := range manifest.Instances {
+		if names[item.Name] {
+			return fmt.Errorf("Instance %q is listed more than once", item.Name)
+		}
+
+		names[item.Name] = true
+
+		config := map[string]string{}
+		for k, v := range defaults.Config {
+			config[k] = v
+		}
+
+		for k, v := range item.Config {
+			config[k] = v
+		}
+
+		item.Config = config
+
+		if item.Network == "" {
+			item.Network = defaults.Network
+		}
+
+		if len(item.Profiles) == 0 && !item.NoProfiles {
+			item.Profiles = defaults.Profiles
+			item.NoProfiles = defaults.NoProfiles
+		}
+
+		if item.Storage == "" {
+			item.Storage = defaults.Storage
+			if item.StorageSize == "" {
+				item.StorageSize = defaults.StorageSize
+			}
+		}
+
+		if item.Type == "" {
+			item.Type = defaults.Type
+		}
+
+		err = item.validate()
+		if err != nil {
+			return err
+		}
+
+		_, done := state.Done[item.Name]
+		if done {
+			itemStatus(item.Name, "Already migrated, skipping")
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("All the instances of the manifest were already migrated")
+		return nil
+	}
+
+	URL, err := parseURL(targetURL)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the target
+	dst, err := connectTarget(URL)
+	if err != nil {
+		return err
+	}
+
+	logDir := c.flagManifest + ".logs"
+	err = os.MkdirAll(logDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan *migrationItem, len(items))
+	for _, item := range items {
+		queue <- item
+	}
+
+	close(queue)
+
+	failed := 0
+	failedLock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	migrateItem := func(item *migrationItem) error {
+		logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", item.Name))
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+
+		itemStatus(item.Name, fmt.Sprintf("Migrating (log: %s)", logPath))
+
+		newProgress := func(format string) migrationProgress {
+			return &itemProgress{name: item.Name, format: format}
+		}
+
+		err = c.migrate(dst, item, logFile, newProgress)
+		if err != nil {
+			fmt.Fprintf(logFile, "Error: %v\n", err)
+			return err
+		}
+
+		return state.markDone(item.Name)
+	}
+
+	for i := 0; i < c.flagParallel && i < len(items); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for item := range queue {
+				err := migrateItem(item)
+				if err != nil {
+					itemStatus(item.Name, fmt.Sprintf("Failed: %v", err))
+
+					failedLock.Lock()
+					failed++
+					failedLock.Unlock()
+					continue
+				}
+
+				itemStatus(item.Name, "Done")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to migrate %d of %d instances, run the same command again to retry them", failed, len(items))
+	}
+
+	fmt.Printf("All the instances of the manifest were successfully migrated\n")
+	return nil
+}
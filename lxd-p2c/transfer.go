@@ -48,6 +48,25 @@ func rsyncSend(conn *websocket.Conn, path string, rsyncArgs string) error {
 	return nil
 }
 
+// Send a raw disk image over a websocket
+func blockSend(conn *websocket.Conn, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	wsIO := &shared.WebsocketIO{Conn: conn}
+
+	_, err = io.Copy(wsIO, f)
+	if err != nil {
+		return fmt.Errorf("Failed to send %s: %v", path, err)
+	}
+
+	// Indicate to the target that the disk was fully sent.
+	return wsIO.Close()
+}
+
 // Spawn the rsync process
 func rsyncSendSetup(path string, rsyncArgs string) (*exec.Cmd, net.Conn, io.ReadCloser, error) {
 	auds := fmt.Sprintf("@lxd-p2c/%s", uuid.NewRandom().String())
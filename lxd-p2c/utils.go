@@ -5,6 +5,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net/url"
+	"os"
 	"strings"
 
 	"golang.org/x/sys/unix"
@@ -17,7 +18,9 @@ import (
 	"github.com/lxc/lxd/shared/version"
 )
 
-func transferRootfs(dst lxd.ContainerServer, op lxd.Operation, rootfs string, rsyncArgs string) error {
+// transferRootfs sends the filesystem at rootfs (if any) followed by the raw disk image at disk (if any) to the
+// migration operation.
+func transferRootfs(dst lxd.ContainerServer, op lxd.Operation, rootfs string, rsyncArgs string, disk string) error {
 	opAPI := op.Get()
 
 	// Connect to the websockets
@@ -43,6 +46,19 @@ func transferRootfs(dst lxd.ContainerServer, op lxd.Operation, rootfs string, rs
 		Fs: &fs,
 	}
 
+	if disk != "" {
+		fs = migration.MigrationFSType_BLOCK_AND_RSYNC
+
+		info, err := os.Stat(disk)
+		if err != nil {
+			return err
+		}
+
+		// Size the volume after the disk image.
+		size := info.Size()
+		header.VolumeSize = &size
+	}
+
 	err = migration.ProtoSend(wsControl, &header)
 	if err != nil {
 		protoSendError(wsControl, err)
@@ -61,9 +77,18 @@ func transferRootfs(dst lxd.ContainerServer, op lxd.Operation, rootfs string, rs
 		return err
 	}
 
-	err = rsyncSend(wsFs, rootfs, rsyncArgs)
-	if err != nil {
-		return abort(err)
+	if rootfs != "" {
+		err = rsyncSend(wsFs, rootfs, rsyncArgs)
+		if err != nil {
+			return abort(err)
+		}
+	}
+
+	if disk != "" {
+		err = blockSend(wsFs, disk)
+		if err != nil {
+			return abort(err)
+		}
 	}
 
 	// Check the result
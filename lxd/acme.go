@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// acmeRenewBefore is how long before its expiry the certificate is renewed.
+const acmeRenewBefore = 30 * 24 * time.Hour
+
+// acmeTimeout is how long obtaining a certificate may take.
+const acmeTimeout = 5 * time.Minute
+
+// acmeChallengePath is the path under which the HTTP-01 challenges are served.
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// acmeChallengeTypes maps the values of acme.challenge to the ACME challenge types.
+var acmeChallengeTypes = map[string]string{
+	"HTTP-01": "http-01",
+	"DNS-01":  "dns-01",
+}
+
+// acmeForwardInterval is the minimum delay between two challenge requests forwarded to the cluster leader, so that
+// the unauthenticated clients can't have the members flood it.
+const acmeForwardInterval = 100 * time.Millisecond
+
+// acmeTokenRegexp matches the valid ACME challenge tokens (base64url encoded).
+var acmeTokenRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// acmeChallenges holds the key authorizations of the pending HTTP-01 challenges, indexed by token.
+var acmeChallenges = map[string]string{}
+var acmeChallengesMu sync.Mutex
+var acmeForwardLast time.Time
+
+// acmeUpdateMu serializes the certificate updates.
+var acmeUpdateMu sync.Mutex
+
+// acmeServer returns the server of the plain HTTP endpoint, only answering the ACME HTTP-01 challenges.
+func acmeServer(d *Daemon) *http.Server {
+	mux := mux.NewRouter()
+	mux.HandleFunc(acmeChallengePath+"{token}", func(w http.ResponseWriter, r *http.Request) {
+		<-d.setupChan
+		acmeProvideChallenge(d, w, r)
+	})
+
+	return &http.Server{Handler: mux}
+}
+
+// acmeProvideChallenge serves the key authorization of a pending HTTP-01 challenge.
+// As the certificate is obtained by the cluster leader, the other members fetch the challenges from it. As the
+// requests aren't authenticated, they're only forwarded when the HTTP-01 challenge is in use and no more often
+// than acmeForwardInterval.
+func acmeProvideChallenge(d *Daemon, w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	acmeChallengesMu.Lock()
+	keyAuth, found := acmeChallenges[token]
+	forward := !found && acmeTokenRegexp.MatchString(token) && time.Since(acmeForwardLast) >= acmeForwardInterval
+	if forward {
+		acmeForwardLast = time.Now()
+	}
+	acmeChallengesMu.Unlock()
+
+	if !found {
+		if !forward || !acmeHTTPChallengeEnabled(d) {
+			http.NotFound(w, r)
+			return
+		}
+
+		var err error
+		keyAuth, err = acmeLeaderChallenge(d, token)
+		if err != nil {
+			logger.Debug("Failed getting ACME challenge from leader", log.Ctx{"token": token, "err": err})
+			http.NotFound(w, r)
+			return
+		}
+
+		if keyAuth == "" {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
+// acmeHTTPChallengeEnabled returns whether a certificate is to be obtained through the HTTP-01 challenge.
+func acmeHTTPChallengeEnabled(d *Daemon) bool {
+	var domain, challenge string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		domain, _, _, challenge, _ = config.ACME()
+		return nil
+	})
+	if err != nil {
+		return false
+	}
+
+	return domain != "" && challenge == "HTTP-01"
+}
+
+// acmeLeaderChallenge fetches the key authorization of a pending HTTP-01 challenge from the cluster leader.
+// Returns an empty string if this member isn't clustered or is the leader.
+func acmeLeaderChallenge(d *Daemon, token string) (string, error) {
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil || localAddress == "" {
+		return "", err
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		return "", err
+	}
+
+	if leader == localAddress {
+		return "", nil
+	}
+
+	client, err := cluster.Connect(leader, d.endpoints.NetworkCert(), d.serverCert(), nil, true)
+	if err != nil {
+		return "", err
+	}
+
+	httpClient, err := client.GetHTTPClient()
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Get(fmt.Sprintf("https://%s%s%s", leader, acmeChallengePath, token))
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil
+	}
+
+	keyAuth, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+
+	return string(keyAuth), nil
+}
+
+// acmeRenewTask checks the REST API certificate daily, obtaining a new one when acme.domain is set and the
+// current certificate doesn't cover it or is about to expire.
+func acmeRenewTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := acmeUpdateCertificate(ctx, d)
+		if err != nil {
+			logger.Error("Failed updating the certificate through ACME", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Daily()
+}
+
+// acmeUpdateCertificate obtains a new certificate through ACME if needed and replaces the REST API certificate
+// with it, on all members when clustered. Only the cluster leader obtains certificates.
+func acmeUpdateCertificate(ctx context.Context, d *Daemon) error {
+	acmeUpdateMu.Lock()
+	defer acmeUpdateMu.Unlock()
+
+	var domain, email, caURL, challenge, dnsHook string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		domain, email, caURL, challenge, dnsHook = config.ACME()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if domain == "" {
+		return nil
+	}
+
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return err
+	}
+
+	if localAddress != "" {
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil {
+			return errors.Wrap(err, "Failed getting leader address")
+		}
+
+		if leader != localAddress {
+			return nil
+		}
+	}
+
+	if !acmeCertificateNeedsRenewal(d.endpoints.NetworkCert(), domain, time.Now()) {
+		return nil
+	}
+
+	logger.Info("Obtaining certificate through ACME", log.Ctx{"domain": domain, "ca": caURL, "challenge": challenge})
+
+	ctx, cancel := context.WithTimeout(ctx, acmeTimeout)
+	defer cancel()
+
+	certBytes, keyBytes, err := acmeObtainCertificate(ctx, d, domain, email, caURL, challenge, dnsHook)
+	if err != nil {
+		return err
+	}
+
+	if localAddress != "" {
+		err = clusterCertificateRotate(d, nil, certBytes, keyBytes)
+		if err != nil {
+			return err
+		}
+
+		d.State().Events.SendLifecycle(project.Default, lifecycle.ClusterCertificateUpdated.Event("certificate", nil, nil))
+	} else {
+		err = util.WriteCert(d.os.VarDir, "server", certBytes, keyBytes, nil)
+		if err != nil {
+			return err
+		}
+
+		cert, err := util.LoadCert(d.os.VarDir)
+		if err != nil {
+			return err
+		}
+
+		d.endpoints.NetworkUpdateCert(cert)
+	}
+
+	logger.Info("Updated certificate through ACME", log.Ctx{"domain": domain})
+
+	return nil
+}
+
+// acmeCertificateNeedsRenewal returns whether the certificate doesn't cover the domain or is about to expire.
+func acmeCertificateNeedsRenewal(cert *shared.CertInfo, domain string, now time.Time) bool {
+	if cert == nil {
+		return true
+	}
+
+	x509Cert, err := x509.ParseCertificate(cert.KeyPair().Certificate[0])
+	if err != nil {
+		return true
+	}
+
+	if x509Cert.VerifyHostname(domain) != nil {
+		return true
+	}
+
+	return now.Add(acmeRenewBefore).After(x509Cert.NotAfter)
+}
+
+// acmeAccountKey returns the ACME account key, generating it on first use.
+func acmeAccountKey(varDir string) (crypto.Signer, error) {
+	path := filepath.Join(varDir, "acme.key")
+
+	if shared.PathExists(path) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("Invalid ACME account key %q", path)
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// acmeCheckDNSHook checks that the DNS-01 hook is an executable file. As it's run as root, only a path to an
+// existing executable is accepted.
+func acmeCheckDNSHook(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("ACME DNS hook %q isn't an absolute path", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to access ACME DNS hook %q", path)
+	}
+
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("ACME DNS hook %q isn't an executable file", path)
+	}
+
+	return nil
+}
+
+// acmeObtainCertificate obtains a certificate for the domain from the ACME CA, returning the PEM encoded
+// certificate chain and key.
+func acmeObtainCertificate(ctx context.Context, d *Daemon, domain string, email string, caURL string, challengeType string, dnsHook string) ([]byte, []byte, error) {
+	accountKey, err := acmeAccountKey(d.os.VarDir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed loading ACME account key")
+	}
+
+	httpClient, err := util.HTTPClient("", d.proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: caURL,
+		HTTPClient:   httpClient,
+		UserAgent:    "LXD",
+	}
+
+	account := &acme.Account{}
+	if email != "" {
+		account.Contact = []string{fmt.Sprintf("mailto:%s", email)}
+	}
+
+	_, err = client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, nil, errors.Wrap(err, "Failed registering ACME account")
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed creating ACME order")
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		err := acmeAuthorize(ctx, client, authzURL, challengeType, dnsHook)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed waiting for ACME order")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed getting certificate from ACME CA")
+	}
+
+	certBytes := []byte{}
+	for _, der := range chain {
+		certBytes = append(certBytes, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBytes := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certBytes, keyBytes, nil
+}
+
+// acmeAuthorize completes the challenge of the given type for the authorization.
+func acmeAuthorize(ctx context.Context, client *acme.Client, authzURL string, challengeType string, dnsHook string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return errors.Wrap(err, "Failed getting ACME authorization")
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == acmeChallengeTypes[challengeType] {
+			challenge = c
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("ACME CA doesn't offer the %s challenge for %q", challengeType, authz.Identifier.Value)
+	}
+
+	switch challengeType {
+	case "HTTP-01":
+		keyAuth, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return err
+		}
+
+		acmeChallengesMu.Lock()
+		acmeChallenges[challenge.Token] = keyAuth
+		acmeChallengesMu.Unlock()
+
+		defer func() {
+			acmeChallengesMu.Lock()
+			delete(acmeChallenges, challenge.Token)
+			acmeChallengesMu.Unlock()
+		}()
+	case "DNS-01":
+		if dnsHook == "" {
+			return fmt.Errorf("The DNS-01 challenge requires acme.dns_hook to be set")
+		}
+
+		// The hook was checked when set but may be missing on this member.
+		err = acmeCheckDNSHook(dnsHook)
+		if err != nil {
+			return err
+		}
+
+		record, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return err
+		}
+
+		fqdn := fmt.Sprintf("_acme-challenge.%s", authz.Identifier.Value)
+
+		_, err = shared.RunCommand(dnsHook, "present", fqdn, record)
+		if err != nil {
+			return errors.Wrap(err, "Failed running ACME DNS hook")
+		}
+
+		defer func() {
+			_, err := shared.RunCommand(dnsHook, "cleanup", fqdn, record)
+			if err != nil {
+				logger.Warn("Failed running ACME DNS hook cleanup", log.Ctx{"domain": fqdn, "err": err})
+			}
+		}()
+	}
+
+	_, err = client.Accept(ctx, challenge)
+	if err != nil {
+		return errors.Wrap(err, "Failed accepting ACME challenge")
+	}
+
+	_, err = client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return errors.Wrapf(err, "Failed ACME %s challenge for %q", challengeType, authz.Identifier.Value)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/shared"
+)
+
+func Test_acmeCertificateNeedsRenewal(t *testing.T) {
+	cert := shared.TestingKeyPair()
+
+	// The test certificate is valid for "UbuntuPro" until July 12th 2025.
+	assert.False(t, acmeCertificateNeedsRenewal(cert, "ubuntupro", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, acmeCertificateNeedsRenewal(cert, "ubuntupro", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, acmeCertificateNeedsRenewal(cert, "lxd.example.net", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, acmeCertificateNeedsRenewal(nil, "ubuntupro", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	log "github.com/lxc/lxd/shared/log15"
@@ -64,6 +66,10 @@ func restServer(d *Daemon) *http.Server {
 		response.SyncResponse(true, []string{"/1.0"}).Render(w)
 	})
 
+	mux.HandleFunc(acmeChallengePath+"{token}", func(w http.ResponseWriter, r *http.Request) {
+		acmeProvideChallenge(d, w, r)
+	})
+
 	for endpoint, f := range d.gateway.HandlerFuncs(d.NodeRefreshTask, d.getTrustedCertificates) {
 		mux.HandleFunc(endpoint, f)
 	}
@@ -82,6 +88,11 @@ func restServer(d *Daemon) *http.Server {
 		}
 	}
 
+	// Endpoints of the plugins compiled in by downstream distributions.
+	for _, c := range pluginEndpoints() {
+		d.createCmd(mux, "1.0", c)
+	}
+
 	for _, c := range apiInternal {
 		d.createCmd(mux, "internal", c)
 	}
@@ -167,6 +178,53 @@ func projectParam(request *http.Request) string {
 	return projectParam
 }
 
+// Extract the limit and after query parameters selecting a page of a collection.
+func paginationParam(request *http.Request) (db.Pagination, error) {
+	page := db.Pagination{After: queryParam(request, "after")}
+
+	limit := queryParam(request, "limit")
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return page, fmt.Errorf("Invalid limit %q", limit)
+		}
+
+		page.Limit = n
+	}
+
+	return page, nil
+}
+
+// Return a sync response for a page of a collection. When the page is full, the
+// cursor of its last entry is returned in the X-LXD-next header so that the
+// next page can be requested with it.
+func paginatedResponse(page db.Pagination, count int, last string, metadata interface{}) response.Response {
+	if page.Limit > 0 && count >= page.Limit {
+		return response.SyncResponseHeaders(true, metadata, map[string]string{"X-LXD-next": last})
+	}
+
+	return response.SyncResponse(true, metadata)
+}
+
+// Extract the fields query parameter selecting the fields to return for each
+// object of a response.
+func fieldsParam(request *http.Request) []string {
+	value := queryParam(request, "fields")
+	if value == "" {
+		return nil
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
 // Extract the given query parameter directly from the URL, never from an
 // encoded body.
 func queryParam(request *http.Request, key string) string {
@@ -35,14 +35,20 @@ var api10Cmd = APIEndpoint{
 
 var api10 = []APIEndpoint{
 	api10Cmd,
+	api10IdmapCmd,
 	api10ResourcesCmd,
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
 	clusterNodeCmd,
 	clusterNodeStateCmd,
+	clusterUpgradeCmd,
 	clusterNodesCmd,
 	clusterCertificateCmd,
+	clusterFailureDomainCmd,
+	clusterFailureDomainsCmd,
+	configPlanCmd,
+	deprecatedConfigKeysCmd,
 	instanceBackupCmd,
 	instanceBackupExportCmd,
 	instanceBackupsCmd,
@@ -50,10 +56,16 @@ var api10 = []APIEndpoint{
 	instanceConsoleCmd,
 	instanceExecCmd,
 	instanceFileCmd,
+	instanceFileWatchCmd,
 	instanceLogCmd,
 	instanceLogsCmd,
 	instanceMetadataCmd,
 	instanceMetadataTemplatesCmd,
+	instanceNVRAMCmd,
+	instanceMachineTypeCmd,
+	instanceProcessesCmd,
+	instanceSEVCmd,
+	instanceSEVReportCmd,
 	instancesCmd,
 	instanceSnapshotCmd,
 	instanceSnapshotsCmd,
@@ -67,6 +79,7 @@ var api10 = []APIEndpoint{
 	imagesCmd,
 	imageSecretCmd,
 	networkCmd,
+	networkFirewallCmd,
 	networkLeasesCmd,
 	networksCmd,
 	networkStateCmd,
@@ -81,7 +94,9 @@ var api10 = []APIEndpoint{
 	projectCmd,
 	projectsCmd,
 	projectStateCmd,
+	projectUsageCmd,
 	storagePoolCmd,
+	storagePoolSourceCmd,
 	storagePoolResourcesCmd,
 	storagePoolsCmd,
 	storagePoolVolumesCmd,
@@ -596,6 +611,15 @@ func doApi10Update(d *Daemon, r *http.Request, req api.ServerPut, patch bool) re
 		if hasCandid && hasRBAC {
 			return response.BadRequest(fmt.Errorf("RBAC and Candid are mutually exclusive"))
 		}
+
+		// The hook is run as root, so make sure it's an existing executable.
+		hook, ok := v.(string)
+		if k == "acme.dns_hook" && ok {
+			err := acmeCheckDNSHook(hook)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+		}
 	}
 
 	// Then deal with cluster wide configuration
@@ -666,6 +690,8 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 	maasChanged := false
 	candidChanged := false
 	rbacChanged := false
+	acmeChanged := false
+	webhookChanged := false
 
 	for key := range clusterChanged {
 		switch key {
@@ -697,6 +723,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			if !d.os.MockMode {
 				d.taskPruneImages.Reset()
 			}
+		case "scheduler.cpu_rebalance_interval":
+			if !d.os.MockMode {
+				d.taskCPURebalance.Reset()
+			}
+		case "scheduler.memory_pressure_interval":
+			if !d.os.MockMode {
+				d.taskMemoryPressure.Reset()
+			}
 		case "rbac.agent.url":
 			fallthrough
 		case "rbac.agent.username":
@@ -711,9 +745,33 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "rbac.expiry":
 			rbacChanged = true
+		case "authorization.webhook.url":
+			fallthrough
+		case "authorization.webhook.token":
+			fallthrough
+		case "authorization.webhook.expiry":
+			webhookChanged = true
+		case "acme.domain":
+			fallthrough
+		case "acme.email":
+			fallthrough
+		case "acme.ca_url":
+			fallthrough
+		case "acme.challenge":
+			fallthrough
+		case "acme.dns_hook":
+			acmeChanged = true
 		}
 	}
 
+	if webhookChanged {
+		d.setupAuthorizer(clusterConfig.AuthorizationWebhook())
+	}
+
+	if acmeChanged && !d.os.MockMode {
+		d.taskACMERenew.Reset()
+	}
+
 	// Look for changed values. We do it sequentially because some keys are
 	// correlated with others, and need to be processed first (for example
 	// core.https_address need to be processed before
@@ -724,6 +782,11 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		maasChanged = true
 	}
 
+	_, ok = nodeChanged["backups.database_interval"]
+	if ok && !d.os.MockMode {
+		d.taskDatabaseBackup.Reset()
+	}
+
 	value, ok := nodeChanged["core.https_address"]
 	if ok {
 		err := d.endpoints.NetworkUpdateAddress(value)
@@ -750,6 +813,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	value, ok = nodeChanged["acme.http_address"]
+	if ok {
+		err := d.endpoints.ACMEUpdateAddress(value)
+		if err != nil {
+			return err
+		}
+	}
+
 	value, ok = nodeChanged["storage.backups_volume"]
 	if ok {
 		err := daemonStorageMove(s, "backups", value)
@@ -766,6 +837,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	for _, key := range []string{"operations.max_image_downloads", "operations.max_migrations", "operations.max_backups"} {
+		_, ok = nodeChanged[key]
+		if ok {
+			operationsSetQueueLimits(nodeConfig)
+			break
+		}
+	}
+
 	if maasChanged {
 		url, key := clusterConfig.MAASController()
 		machine := nodeConfig.MAASMachine()
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"context"
+	"crypto/tls"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -141,6 +141,16 @@ var internalClusterRaftNodeCmd = APIEndpoint{
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func clusterGet(d *Daemon, r *http.Request) response.Response {
+	cluster, err := clusterGetInfo(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, cluster, cluster)
+}
+
+// clusterGetInfo returns the current cluster configuration of this member.
+func clusterGetInfo(d *Daemon) (api.Cluster, error) {
 	name := ""
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
@@ -148,7 +158,7 @@ func clusterGet(d *Daemon, r *http.Request) response.Response {
 		return err
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return api.Cluster{}, err
 	}
 
 	// If the name is set to the hard-coded default node name, then
@@ -159,7 +169,7 @@ func clusterGet(d *Daemon, r *http.Request) response.Response {
 
 	memberConfig, err := clusterGetMemberConfig(d.cluster)
 	if err != nil {
-		return response.SmartError(err)
+		return api.Cluster{}, err
 	}
 
 	cluster := api.Cluster{
@@ -168,7 +178,7 @@ func clusterGet(d *Daemon, r *http.Request) response.Response {
 		MemberConfig: memberConfig,
 	}
 
-	return response.SyncResponseETag(true, cluster, cluster)
+	return cluster, nil
 }
 
 // Fetch information about all node-specific configuration keys set on the
@@ -272,10 +282,21 @@ func clusterGetMemberConfig(cluster *db.Cluster) ([]api.ClusterMemberConfigKey,
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func clusterPut(d *Daemon, r *http.Request) response.Response {
+	// Validate the ETag
+	current, err := clusterGetInfo(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = util.EtagCheck(r, current)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
 	req := api.ClusterPut{}
 
 	// Parse the request
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		return response.BadRequest(err)
 	}
@@ -1657,8 +1678,12 @@ func clusterNodeDelete(d *Daemon, r *http.Request) response.Response {
 //
 // Update the certificate for the cluster
 //
-// Replaces existing cluster certificate and reloads LXD on each cluster
-// member.
+// Replaces existing cluster certificate on each cluster member.
+//
+// The new keypair is first staged on all members, then each member switches
+// to it while still accepting the previous certificate from the others.
+// Once all members present the new certificate, the previous one is dropped.
+// If any member fails, all members are switched back to the previous keypair.
 //
 // ---
 // consumes:
@@ -1703,50 +1728,22 @@ func clusterCertificatePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Private key must be base64 encoded PEM key: %v", err))
 	}
 
-	// First node forwards request to all other cluster nodes
-	if !isClusterNotification(r) {
-		servers, err := d.gateway.NodeStore().Get(context.Background())
-		if err != nil {
-			return response.SmartError(err)
-		}
-
-		localAddress, err := node.ClusterAddress(d.db)
-		if err != nil {
-			return response.SmartError(err)
-		}
-
-		for _, server := range servers {
-			if server.Address == localAddress {
-				continue
-			}
-
-			client, err := cluster.Connect(server.Address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
-			if err != nil {
-				return response.SmartError(err)
-			}
-
-			err = client.UpdateClusterCertificate(req, "")
-			if err != nil {
-				return response.SmartError(err)
-			}
-		}
+	_, err = tls.X509KeyPair(certBytes, keyBytes)
+	if err != nil {
+		return response.BadRequest(errors.Wrap(err, "Invalid cluster certificate keypair"))
 	}
 
-	err = util.WriteCert(d.os.VarDir, "cluster", certBytes, keyBytes, nil)
-	if err != nil {
-		return response.SmartError(err)
+	if isClusterNotification(r) {
+		// Members not supporting staged rotations replace the certificate on all members at once.
+		err = clusterCertificateReplace(d, certBytes, keyBytes)
+	} else {
+		err = clusterCertificateRotate(d, r, certBytes, keyBytes)
 	}
 
-	// Get the new cluster certificate struct
-	cert, err := util.LoadClusterCert(d.os.VarDir)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	// Update the certificate on the network endpoint and gateway
-	d.endpoints.NetworkUpdateCert(cert)
-	d.gateway.NetworkUpdateCert(cert)
-
 	requestor := request.CreateRequestor(r)
 	d.State().Events.SendLifecycle(projectParam(r), lifecycle.ClusterCertificateUpdated.Event("certificate", requestor, nil))
 
@@ -2288,7 +2285,7 @@ func clusterNodeStatePost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if req.Action == "evacuate" {
-		return evacuateClusterMember(d, r)
+		return evacuateClusterMember(d, r, req.ForceProtected)
 	} else if req.Action == "restore" {
 		return restoreClusterMember(d, r)
 	}
@@ -2296,7 +2293,7 @@ func clusterNodeStatePost(d *Daemon, r *http.Request) response.Response {
 	return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
 }
 
-func evacuateClusterMember(d *Daemon, r *http.Request) response.Response {
+func evacuateClusterMember(d *Daemon, r *http.Request, forceProtected bool) response.Response {
 	var err error
 	var node db.NodeInfo
 
@@ -2370,6 +2367,20 @@ func evacuateClusterMember(d *Daemon, r *http.Request) response.Response {
 		instances[i] = inst
 	}
 
+	// Refuse to stop the instances protected against it unless explicitly asked to.
+	if !forceProtected {
+		for _, inst := range instances {
+			if !inst.IsRunning() {
+				continue
+			}
+
+			err := instanceCheckStopProtection(d.State(), inst)
+			if err != nil {
+				return response.BadRequest(err)
+			}
+		}
+	}
+
 	var targetNodeName string
 	var targetNode db.NodeInfo
 
@@ -2412,7 +2423,7 @@ func evacuateClusterMember(d *Daemon, r *http.Request) response.Response {
 			inst.VolatileSet(map[string]string{"volatile.evacuate.origin": nodeName})
 
 			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-				targetNodeName, err = tx.GetNodeWithLeastInstances([]int{node.Architecture}, -1)
+				targetNodeName, err = tx.GetNodeWithLeastInstances([]int{node.Architecture}, -1, nil)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Steps of a cluster certificate rotation, run on every member.
+const (
+	// clusterCertificateStage writes the new keypair next to the current one and starts accepting it from the
+	// other members.
+	clusterCertificateStage = "stage"
+	// clusterCertificateApply switches to the staged keypair, keeping the previous one around and accepting it
+	// from the other members.
+	clusterCertificateApply = "apply"
+	// clusterCertificateRollback switches back to the previous keypair.
+	clusterCertificateRollback = "rollback"
+	// clusterCertificateFinalize removes the previous keypair and stops accepting it.
+	clusterCertificateFinalize = "finalize"
+	// clusterCertificateDiscard removes the staged keypair and stops accepting it.
+	clusterCertificateDiscard = "discard"
+)
+
+// Prefixes of the staged and previous cluster keypairs in the var dir.
+const clusterCertificateStagedPrefix = "cluster.new"
+const clusterCertificatePreviousPrefix = "cluster.old"
+
+var internalClusterCertificateCmd = APIEndpoint{
+	Path: "cluster/certificate",
+
+	Put: APIEndpointAction{Handler: internalClusterCertificatePut},
+}
+
+type internalClusterCertificatePutRequest struct {
+	Action                string `json:"action" yaml:"action"`
+	ClusterCertificate    string `json:"cluster_certificate" yaml:"cluster_certificate"`
+	ClusterCertificateKey string `json:"cluster_certificate_key" yaml:"cluster_certificate_key"`
+}
+
+// Serializes the rotations coordinated by this member.
+var clusterCertificateRotateMu sync.Mutex
+
+// Serializes the rotation steps run on this member.
+var clusterCertificateStepMu sync.Mutex
+
+// Runs a step of a cluster certificate rotation coordinated by another member.
+func internalClusterCertificatePut(d *Daemon, r *http.Request) response.Response {
+	req := internalClusterCertificatePutRequest{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = clusterCertificateStep(d, req.Action, []byte(req.ClusterCertificate), []byte(req.ClusterCertificateKey))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterCertificateRotate replaces the cluster certificate on all members without interrupting the connections
+// between them. The new keypair is first staged on all members, which then switch to it one by one while still
+// accepting the previous one from the others. Once all members are verified to present the new certificate, the
+// previous keypair is dropped. If any step fails, all members are switched back to the previous keypair.
+func clusterCertificateRotate(d *Daemon, r *http.Request, certBytes []byte, keyBytes []byte) error {
+	clusterCertificateRotateMu.Lock()
+	defer clusterCertificateRotateMu.Unlock()
+
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return err
+	}
+
+	addresses := []string{}
+	if localAddress != "" {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			nodes, err := tx.GetNodes()
+			if err != nil {
+				return err
+			}
+
+			for _, node := range nodes {
+				if node.Address != localAddress {
+					addresses = append(addresses, node.Address)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Failed getting cluster members")
+		}
+	}
+
+	// Switch the local member last so that it keeps reaching the others with the current certificate.
+	addresses = append(addresses, localAddress)
+
+	step := func(address string, action string) error {
+		if address == localAddress {
+			return clusterCertificateStep(d, action, certBytes, keyBytes)
+		}
+
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+		if err != nil {
+			return err
+		}
+
+		req := internalClusterCertificatePutRequest{
+			Action:                action,
+			ClusterCertificate:    string(certBytes),
+			ClusterCertificateKey: string(keyBytes),
+		}
+
+		_, _, err = client.RawQuery("PUT", "/internal/cluster/certificate", req, "")
+		return err
+	}
+
+	// Runs a cleanup step on the members, carrying on past the failures.
+	stepAll := func(addresses []string, action string) {
+		for _, address := range addresses {
+			err := step(address, action)
+			if err != nil {
+				logger.Warn("Failed cluster certificate rotation step", log.Ctx{"member": address, "action": action, "err": err})
+			}
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	revert.Add(func() { stepAll(addresses, clusterCertificateDiscard) })
+
+	for _, address := range addresses {
+		err := step(address, clusterCertificateStage)
+		if err != nil {
+			return errors.Wrapf(err, "Failed staging the cluster certificate on member %q", address)
+		}
+	}
+
+	applied := []string{}
+	revert.Add(func() { stepAll(applied, clusterCertificateRollback) })
+
+	for _, address := range addresses {
+		applied = append(applied, address)
+
+		err := step(address, clusterCertificateApply)
+		if err != nil {
+			return errors.Wrapf(err, "Failed switching to the cluster certificate on member %q", address)
+		}
+	}
+
+	// The local member was switched last, so its certificate is the new one.
+	newCert := d.endpoints.NetworkCert()
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+
+		err := cluster.VerifyNetworkCert(address, newCert, d.serverCert())
+		if err != nil {
+			return errors.Wrapf(err, "Failed verifying the cluster certificate of member %q", address)
+		}
+	}
+
+	revert.Success()
+
+	stepAll(addresses, clusterCertificateFinalize)
+
+	logger.Info("Rotated cluster certificate", log.Ctx{"fingerprint": newCert.Fingerprint()})
+
+	return nil
+}
+
+// clusterCertificateReplace replaces the local cluster certificate straight away.
+func clusterCertificateReplace(d *Daemon, certBytes []byte, keyBytes []byte) error {
+	clusterCertificateStepMu.Lock()
+	defer clusterCertificateStepMu.Unlock()
+
+	err := util.WriteCert(d.os.VarDir, "cluster", certBytes, keyBytes, nil)
+	if err != nil {
+		return err
+	}
+
+	// Get the new cluster certificate struct
+	cert, err := util.LoadClusterCert(d.os.VarDir)
+	if err != nil {
+		return err
+	}
+
+	// Update the certificate on the network endpoint and gateway
+	d.endpoints.NetworkUpdateCert(cert)
+	d.gateway.NetworkUpdateCert(cert)
+
+	return nil
+}
+
+// clusterCertificateStep runs a step of a cluster certificate rotation on the local member.
+func clusterCertificateStep(d *Daemon, action string, certBytes []byte, keyBytes []byte) error {
+	clusterCertificateStepMu.Lock()
+	defer clusterCertificateStepMu.Unlock()
+
+	varDir := d.os.VarDir
+
+	switch action {
+	case clusterCertificateStage:
+		err := util.WriteCert(varDir, clusterCertificateStagedPrefix, certBytes, keyBytes, nil)
+		if err != nil {
+			return errors.Wrap(err, "Failed writing staged cluster certificate")
+		}
+
+		cert, err := shared.KeyPairAndCA(varDir, clusterCertificateStagedPrefix, shared.CertServer, true)
+		if err != nil {
+			clusterCertificateRemove(varDir, clusterCertificateStagedPrefix)
+			return errors.Wrap(err, "Failed loading staged cluster certificate")
+		}
+
+		// Accept the new certificate from the members which switch to it before this one.
+		cluster.SetTransitionNetworkCert(cert)
+	case clusterCertificateApply:
+		if !shared.PathExists(filepath.Join(varDir, clusterCertificateStagedPrefix+".crt")) {
+			return fmt.Errorf("No staged cluster certificate")
+		}
+
+		oldCert := d.endpoints.NetworkCert()
+
+		err := clusterCertificateRename(varDir, "cluster", clusterCertificatePreviousPrefix)
+		if err != nil {
+			return errors.Wrap(err, "Failed keeping previous cluster certificate")
+		}
+
+		err = clusterCertificateRename(varDir, clusterCertificateStagedPrefix, "cluster")
+		if err != nil {
+			clusterCertificateRename(varDir, clusterCertificatePreviousPrefix, "cluster")
+			return errors.Wrap(err, "Failed switching to staged cluster certificate")
+		}
+
+		cert, err := util.LoadClusterCert(varDir)
+		if err != nil {
+			clusterCertificateRename(varDir, clusterCertificatePreviousPrefix, "cluster")
+			return err
+		}
+
+		d.endpoints.NetworkUpdateCert(cert)
+		d.gateway.NetworkUpdateCert(cert)
+
+		// Keep accepting the previous certificate from the members which haven't switched yet.
+		cluster.SetTransitionNetworkCert(oldCert)
+	case clusterCertificateRollback:
+		if !shared.PathExists(filepath.Join(varDir, clusterCertificatePreviousPrefix+".crt")) {
+			return nil
+		}
+
+		newCert := d.endpoints.NetworkCert()
+
+		err := clusterCertificateRename(varDir, clusterCertificatePreviousPrefix, "cluster")
+		if err != nil {
+			return errors.Wrap(err, "Failed restoring previous cluster certificate")
+		}
+
+		cert, err := util.LoadClusterCert(varDir)
+		if err != nil {
+			return err
+		}
+
+		d.endpoints.NetworkUpdateCert(cert)
+		d.gateway.NetworkUpdateCert(cert)
+
+		// Keep accepting the new certificate from the members which haven't switched back yet.
+		cluster.SetTransitionNetworkCert(newCert)
+	case clusterCertificateFinalize:
+		clusterCertificateRemove(varDir, clusterCertificatePreviousPrefix)
+		cluster.SetTransitionNetworkCert(nil)
+	case clusterCertificateDiscard:
+		clusterCertificateRemove(varDir, clusterCertificateStagedPrefix)
+		cluster.SetTransitionNetworkCert(nil)
+	default:
+		return fmt.Errorf("Unknown cluster certificate rotation step %q", action)
+	}
+
+	return nil
+}
+
+// clusterCertificateRename renames the keypair with the given prefix in the var dir.
+func clusterCertificateRename(dir string, from string, to string) error {
+	for _, ext := range []string{".crt", ".key"} {
+		err := os.Rename(filepath.Join(dir, from+ext), filepath.Join(dir, to+ext))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterCertificateRemove removes the keypair with the given prefix from the var dir.
+func clusterCertificateRemove(dir string, prefix string) {
+	for _, ext := range []string{".crt", ".key"} {
+		err := os.Remove(filepath.Join(dir, prefix+ext))
+		if err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed removing cluster certificate file", log.Ctx{"file": prefix + ext, "err": err})
+		}
+	}
+}
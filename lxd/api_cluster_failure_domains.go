@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var clusterFailureDomainsCmd = APIEndpoint{
+	Path: "cluster/failure-domains",
+
+	Get: APIEndpointAction{Handler: clusterFailureDomainsGet, AccessHandler: allowAuthenticated},
+}
+
+var clusterFailureDomainCmd = APIEndpoint{
+	Path: "cluster/failure-domains/{name}",
+
+	Get: APIEndpointAction{Handler: clusterFailureDomainGet, AccessHandler: allowAuthenticated},
+	Put: APIEndpointAction{Handler: clusterFailureDomainPut},
+}
+
+// clusterFailureDomainsLoad returns the failure domains of the cluster members along with their members and
+// config overrides, keyed by name.
+func clusterFailureDomainsLoad(tx *db.ClusterTx) (map[string]*api.ClusterFailureDomain, error) {
+	names, err := tx.GetFailureDomainsNames()
+	if err != nil {
+		return nil, err
+	}
+
+	nodesDomains, err := tx.GetNodesFailureDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := tx.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := map[string]*api.ClusterFailureDomain{}
+	for _, name := range names {
+		config, err := tx.GetFailureDomainConfig(name)
+		if err != nil {
+			return nil, err
+		}
+
+		domains[name] = &api.ClusterFailureDomain{
+			ClusterFailureDomainPut: api.ClusterFailureDomainPut{Config: config},
+			Name:                    name,
+			Members:                 []string{},
+		}
+	}
+
+	for _, node := range nodes {
+		domain, ok := domains[names[nodesDomains[node.Address]]]
+		if ok {
+			domain.Members = append(domain.Members, node.Name)
+		}
+	}
+
+	return domains, nil
+}
+
+// swagger:operation GET /1.0/cluster/failure-domains cluster cluster_failure_domains_get
+//
+// Get the failure domains
+//
+// Returns a list of failure domains (URLs).
+//
+// ---
+// produces:
+//   - application/json
+// responses:
+//   "200":
+//     description: API endpoints
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of endpoints
+//           items:
+//             type: string
+//           example: |-
+//             [
+//               "/1.0/cluster/failure-domains/default",
+//               "/1.0/cluster/failure-domains/edge"
+//             ]
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+
+// swagger:operation GET /1.0/cluster/failure-domains?recursion=1 cluster cluster_failure_domains_get_recursion1
+//
+// Get the failure domains
+//
+// Returns a list of failure domains (structs).
+//
+// ---
+// produces:
+//   - application/json
+// responses:
+//   "200":
+//     description: API endpoints
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of failure domains
+//           items:
+//             $ref: "#/definitions/ClusterFailureDomain"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func clusterFailureDomainsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var domains map[string]*api.ClusterFailureDomain
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		domains, err = clusterFailureDomainsLoad(tx)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	names := make([]string, 0, len(domains))
+	for name := range domains {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if recursion {
+		result := []api.ClusterFailureDomain{}
+		for _, name := range names {
+			result = append(result, *domains[name])
+		}
+
+		return response.SyncResponse(true, result)
+	}
+
+	urls := []string{}
+	for _, name := range names {
+		urls = append(urls, fmt.Sprintf("/%s/cluster/failure-domains/%s", version.APIVersion, name))
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+// swagger:operation GET /1.0/cluster/failure-domains/{name} cluster cluster_failure_domain_get
+//
+// Get the failure domain
+//
+// Gets a specific failure domain.
+//
+// ---
+// produces:
+//   - application/json
+// responses:
+//   "200":
+//     description: Failure domain
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/ClusterFailureDomain"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "404":
+//     $ref: "#/responses/NotFound"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func clusterFailureDomainGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var domains map[string]*api.ClusterFailureDomain
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		domains, err = clusterFailureDomainsLoad(tx)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	domain, ok := domains[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("Failure domain %q not found", name))
+	}
+
+	return response.SyncResponseETag(true, domain, domain.Writable())
+}
+
+// swagger:operation PUT /1.0/cluster/failure-domains/{name} cluster cluster_failure_domain_put
+//
+// Update the failure domain
+//
+// Replaces the server configuration keys overridden for the members of the failure domain.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: body
+//     name: failure domain
+//     description: Failure domain configuration
+//     required: true
+//     schema:
+//       $ref: "#/definitions/ClusterFailureDomainPut"
+// responses:
+//   "200":
+//     $ref: "#/responses/EmptySyncResponse"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "404":
+//     $ref: "#/responses/NotFound"
+//   "412":
+//     $ref: "#/responses/PreconditionFailed"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func clusterFailureDomainPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	req := api.ClusterFailureDomainPut{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// If this is a notification from a cluster member, just reschedule the tasks using the overridden keys.
+	if isClusterNotification(r) {
+		clusterFailureDomainUpdateTriggers(d)
+		return response.EmptySyncResponse
+	}
+
+	err = cluster.ValidateFailureDomainConfig(req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var domains map[string]*api.ClusterFailureDomain
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		domains, err = clusterFailureDomainsLoad(tx)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	domain, ok := domains[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("Failure domain %q not found", name))
+	}
+
+	err = util.EtagCheck(r, domain.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateFailureDomainConfig(name, req.Config)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Notify the other members so that they reschedule their tasks.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), d.serverCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.UpdateClusterFailureDomain(name, req, "")
+	})
+	if err != nil {
+		logger.Debugf("Failed to notify other members about failure domain config change: %v", err)
+		return response.SmartError(err)
+	}
+
+	clusterFailureDomainUpdateTriggers(d)
+
+	return response.EmptySyncResponse
+}
+
+// clusterFailureDomainUpdateTriggers reschedules the tasks whose interval can be overridden per failure domain.
+func clusterFailureDomainUpdateTriggers(d *Daemon) {
+	if d.os.MockMode {
+		return
+	}
+
+	d.taskCPURebalance.Reset()
+	d.taskMemoryPressure.Reset()
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var clusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Get:  APIEndpointAction{Handler: clusterUpgradeGet, AccessHandler: allowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterUpgradePost},
+}
+
+var internalClusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Post: APIEndpointAction{Handler: internalClusterPostUpgrade},
+}
+
+// clusterUpgradeTimeout is how long to wait for a member to be back once its upgrade was triggered.
+const clusterUpgradeTimeout = 30 * time.Minute
+
+// clusterUpgradeRestorePath returns the path of the marker left by a member evacuated for its upgrade, so that it
+// restores itself once restarted by it.
+func clusterUpgradeRestorePath() string {
+	return shared.VarPath("cluster.upgrade.restore")
+}
+
+// swagger:operation GET /1.0/cluster/upgrade cluster cluster_upgrade_get
+//
+// Get the versions of the cluster members
+//
+// Returns the LXD version, database schema version and number of API extensions of each cluster member, along
+// with the members running an older version and so blocking the members already upgraded.
+//
+// ---
+// produces:
+//   - application/json
+// responses:
+//   "200":
+//     description: Cluster upgrade status
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/ClusterUpgrade"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func clusterUpgradeGet(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	var nodes []db.NodeInfo
+	var offlineThreshold time.Duration
+	var maxVersion [2]int
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		nodes, err = tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		maxVersion, err = tx.GetNodeMaxVersion()
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	upgrade := api.ClusterUpgrade{
+		Schema:        maxVersion[0],
+		APIExtensions: maxVersion[1],
+		Blocking:      []string{},
+		Members:       make([]api.ClusterUpgradeMember, len(nodes)),
+	}
+
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		member := &upgrade.Members[i]
+		member.ServerName = node.Name
+		member.Schema = node.Schema
+		member.APIExtensions = node.APIExtensions
+		member.Outdated = node.Version() != maxVersion
+
+		if member.Outdated {
+			upgrade.Blocking = append(upgrade.Blocking, node.Name)
+		}
+
+		if node.IsOffline(offlineThreshold) {
+			member.Status = "Offline"
+			continue
+		}
+
+		member.Status = "Online"
+
+		if node.ID == d.cluster.GetNodeID() {
+			member.Version = version.Version
+			continue
+		}
+
+		// Ask the other members for their LXD version.
+		wg.Add(1)
+		go func(node db.NodeInfo) {
+			defer wg.Done()
+
+			client, err := cluster.Connect(node.Address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+			if err != nil {
+				return
+			}
+
+			server, _, err := client.GetServer()
+			if err != nil {
+				return
+			}
+
+			member.Version = server.Environment.ServerVersion
+		}(node)
+	}
+
+	wg.Wait()
+
+	return response.SyncResponse(true, upgrade)
+}
+
+// swagger:operation POST /1.0/cluster/upgrade cluster cluster_upgrade_post
+//
+// Upgrade the cluster members
+//
+// Upgrades the cluster members one after the other by running the LXD_CLUSTER_UPDATE executable of their
+// environment (e.g. refreshing the snap), evacuating them beforehand and restoring them once upgraded if requested.
+// The member handling the request is upgraded last, without being evacuated, once the operation is done.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: body
+//     name: cluster
+//     description: Cluster upgrade request
+//     required: true
+//     schema:
+//       $ref: "#/definitions/ClusterUpgradePost"
+// responses:
+//   "202":
+//     $ref: "#/responses/Operation"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func clusterUpgradePost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterUpgradePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	var nodes []db.NodeInfo
+	var maxVersion [2]int
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		nodes, err = tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		maxVersion, err = tx.GetNodeMaxVersion()
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	nodesByName := map[string]db.NodeInfo{}
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+
+	// Default to the outdated members, or to all of them to pick a new version.
+	members := req.Members
+	if len(members) == 0 {
+		for _, node := range nodes {
+			if node.Version() != maxVersion {
+				members = append(members, node.Name)
+			}
+		}
+	}
+
+	if len(members) == 0 {
+		for _, node := range nodes {
+			members = append(members, node.Name)
+		}
+	}
+
+	// The operation doesn't survive the restart of the local member, so it is upgraded last.
+	local := ""
+	remotes := []db.NodeInfo{}
+	for _, name := range members {
+		node, ok := nodesByName[name]
+		if !ok {
+			return response.BadRequest(fmt.Errorf("Cluster member %q not found", name))
+		}
+
+		if node.ID == d.cluster.GetNodeID() {
+			local = name
+			continue
+		}
+
+		remotes = append(remotes, node)
+	}
+
+	if local != "" && os.Getenv("LXD_CLUSTER_UPDATE") == "" {
+		return response.BadRequest(fmt.Errorf("No LXD_CLUSTER_UPDATE variable set, the member %q can't be upgraded", local))
+	}
+
+	run := func(op *operations.Operation) error {
+		metadata := map[string]interface{}{}
+
+		for i, node := range remotes {
+			metadata["upgrade_progress"] = fmt.Sprintf("Upgrading %s (%d/%d)", node.Name, i+1, len(members))
+			op.UpdateMetadata(metadata)
+
+			err := clusterUpgradeMember(d, r, node, req.Evacuate)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to upgrade cluster member %q", node.Name)
+			}
+		}
+
+		if local == "" {
+			return nil
+		}
+
+		metadata["upgrade_progress"] = fmt.Sprintf("Upgrading %s (%d/%d)", local, len(members), len(members))
+		op.UpdateMetadata(metadata)
+
+		// Leave some time for the clients to see the operation complete before LXD gets restarted.
+		go func() {
+			time.Sleep(5 * time.Second)
+
+			err := cluster.RunUpdateHook()
+			if err != nil {
+				logger.Error("Failed to upgrade cluster member", log.Ctx{"member": local, "err": err})
+			}
+		}()
+
+		return nil
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterUpgrade, nil, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterUpgradeMember upgrades a remote member, waiting for it to be back. If requested, the member is evacuated
+// beforehand and restored once upgraded (by the member itself if restarted by the upgrade).
+func clusterUpgradeMember(d *Daemon, r *http.Request, node db.NodeInfo, evacuate bool) error {
+	client, err := cluster.Connect(node.Address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+	if err != nil {
+		return err
+	}
+
+	// Leave the members evacuated beforehand as they are.
+	evacuate = evacuate && node.State == db.ClusterMemberStateCreated
+	if evacuate {
+		op, err := client.UpdateClusterMemberState(node.Name, api.ClusterMemberStatePost{Action: "evacuate"})
+		if err == nil {
+			err = op.Wait()
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Failed to evacuate")
+		}
+	}
+
+	_, _, err = client.RawQuery("POST", "/internal/cluster/upgrade", internalClusterPostUpgradeRequest{Restore: evacuate}, "")
+	if err == nil {
+		// The member wasn't restarted as no upgrade was available.
+		if evacuate {
+			op, err := client.UpdateClusterMemberState(node.Name, api.ClusterMemberStatePost{Action: "restore"})
+			if err == nil {
+				err = op.Wait()
+			}
+
+			if err != nil {
+				return errors.Wrap(err, "Failed to restore")
+			}
+		}
+
+		return nil
+	}
+
+	// Losing the connection means that LXD was restarted by the upgrade, anything else is a failure.
+	_, ok := errors.Cause(err).(*url.Error)
+	if !ok {
+		return err
+	}
+
+	return clusterUpgradeWait(d, r, node)
+}
+
+// clusterUpgradeWait waits for a member to be back after its upgrade. The members upgraded to a more recent
+// database schema only come back once all the members are upgraded, so their version being updated in the database
+// is enough.
+func clusterUpgradeWait(d *Daemon, r *http.Request, node db.NodeInfo) error {
+	timeout := time.After(clusterUpgradeTimeout)
+
+	for {
+		select {
+		case <-timeout:
+			return fmt.Errorf("Timeout waiting for the member to be back")
+		case <-time.After(5 * time.Second):
+		}
+
+		var current db.NodeInfo
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			current, err = tx.GetNodeByName(node.Name)
+			return err
+		})
+		if err == nil && current.Version() != node.Version() {
+			return nil
+		}
+
+		client, err := cluster.Connect(node.Address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+		if err != nil {
+			continue
+		}
+
+		_, _, err = client.GetServer()
+		if err == nil {
+			return nil
+		}
+	}
+}
+
+type internalClusterPostUpgradeRequest struct {
+	Restore bool `json:"restore" yaml:"restore"`
+}
+
+// Used to upgrade this member as part of a cluster upgrade.
+func internalClusterPostUpgrade(d *Daemon, r *http.Request) response.Response {
+	req := internalClusterPostUpgradeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Have the member restore itself if restarted by the upgrade.
+	if req.Restore {
+		err = ioutil.WriteFile(clusterUpgradeRestorePath(), nil, 0600)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	err = cluster.RunUpdateHook()
+
+	// Not restarted, the restoration is left to the caller.
+	if req.Restore {
+		os.Remove(clusterUpgradeRestorePath())
+	}
+
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterUpgradeRestore restores this member if it was evacuated for an upgrade which restarted it.
+func clusterUpgradeRestore(d *Daemon) {
+	if !shared.PathExists(clusterUpgradeRestorePath()) {
+		return
+	}
+
+	err := os.Remove(clusterUpgradeRestorePath())
+	if err != nil {
+		logger.Error("Failed to remove cluster upgrade marker", log.Ctx{"err": err})
+		return
+	}
+
+	go func() {
+		var name string
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			name, err = tx.GetLocalNodeName()
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed to restore cluster member after upgrade", log.Ctx{"err": err})
+			return
+		}
+
+		client, err := lxd.ConnectLXDUnix(d.UnixSocket(), nil)
+		if err != nil {
+			logger.Error("Failed to restore cluster member after upgrade", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Restoring cluster member after upgrade", log.Ctx{"member": name})
+
+		op, err := client.UpdateClusterMemberState(name, api.ClusterMemberStatePost{Action: "restore"})
+		if err == nil {
+			err = op.Wait()
+		}
+
+		if err != nil {
+			logger.Error("Failed to restore cluster member after upgrade", log.Ctx{"member": name, "err": err})
+		}
+	}()
+}
@@ -42,6 +42,7 @@ import (
 var apiInternal = []APIEndpoint{
 	internalReadyCmd,
 	internalShutdownCmd,
+	internalReloadCmd,
 	internalContainerOnStartCmd,
 	internalContainerOnStopNSCmd,
 	internalContainerOnStopCmd,
@@ -52,8 +53,11 @@ var apiInternal = []APIEndpoint{
 	internalClusterInstanceMovedCmd,
 	internalGarbageCollectorCmd,
 	internalRAFTSnapshotCmd,
+	internalDatabaseBackupCmd,
 	internalClusterHandoverCmd,
+	internalClusterUpgradeCmd,
 	internalClusterRaftNodeCmd,
+	internalClusterCertificateCmd,
 	internalImageRefreshCmd,
 	internalImageOptimizeCmd,
 	internalWarningCreateCmd,
@@ -65,6 +69,12 @@ var internalShutdownCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: internalShutdown},
 }
 
+var internalReloadCmd = APIEndpoint{
+	Path: "reload",
+
+	Put: APIEndpointAction{Handler: internalReload},
+}
+
 var internalReadyCmd = APIEndpoint{
 	Path: "ready",
 
@@ -108,6 +118,12 @@ var internalRAFTSnapshotCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: internalRAFTSnapshot},
 }
 
+var internalDatabaseBackupCmd = APIEndpoint{
+	Path: "database/backup",
+
+	Post: APIEndpointAction{Handler: internalDatabaseBackup},
+}
+
 var internalImageRefreshCmd = APIEndpoint{
 	Path: "testing/image-refresh",
 
@@ -236,6 +252,16 @@ func internalShutdown(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+func internalReload(d *Daemon, r *http.Request) response.Response {
+	select {
+	case d.reloadChan <- struct{}{}:
+	default:
+		return response.BadRequest(fmt.Errorf("A reload is already in progress"))
+	}
+
+	return response.EmptySyncResponse
+}
+
 // internalContainerHookLoadFromRequestReference loads the container from the instance reference in the request.
 // It detects whether the instance reference is an instance ID or instance name and loads instance accordingly.
 func internalContainerHookLoadFromReference(s *state.State, r *http.Request) (instance.Instance, error) {
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -57,10 +61,28 @@ type internalRecoverValidateVolume struct {
 	Pool          string `json:"pool" yaml:"pool"`                   // Pool the volume belongs to.
 }
 
+// internalRecoverValidateDependency provides info about a missing dependency that will be recreated on import.
+type internalRecoverValidateDependency struct {
+	Name    string `json:"name" yaml:"name"`       // Name of the entity.
+	Type    string `json:"type" yaml:"type"`       // Type of the entity (profile).
+	Project string `json:"project" yaml:"project"` // Project the entity belongs to.
+	Source  string `json:"source" yaml:"source"`   // What the entity will be recreated from.
+}
+
 // internalRecoverValidateResult returns the result of the validation scan.
 type internalRecoverValidateResult struct {
-	UnknownVolumes   []internalRecoverValidateVolume // Volumes that could be imported.
-	DependencyErrors []string                        // Errors that are preventing import from proceeding.
+	UnknownVolumes      []internalRecoverValidateVolume     // Volumes that could be imported.
+	DependencyErrors    []string                            // Errors that are preventing import from proceeding.
+	RecoverDependencies []internalRecoverValidateDependency // Missing dependencies that will be recreated on import.
+	Conflicts           []string                            // Conflicts between volumes that are preventing import from proceeding.
+}
+
+// internalRecoverProfile is a missing profile reconstructed from an instance or snapshot backup file.
+type internalRecoverProfile struct {
+	profile  *api.ProfilePut
+	source   string    // Instance or snapshot the profile was reconstructed from.
+	snapshot bool      // Whether the profile was reconstructed from a snapshot.
+	date     time.Time // Creation date of the snapshot it was reconstructed from.
 }
 
 // internalRecoverImportPost is used to initiate a recovert import.
@@ -137,9 +159,77 @@ func internalRecoverScan(d *Daemon, userPools []api.StoragePoolsPost, validateOn
 		}
 	}
 
+	// addConflict adds an error to the list of conflicts if not already present in list.
+	addConflict := func(err error) {
+		errStr := err.Error()
+
+		if !shared.StringInSlice(errStr, res.Conflicts) {
+			res.Conflicts = append(res.Conflicts, errStr)
+		}
+	}
+
 	// Used to store the unknown volumes for each pool & project.
 	poolsProjectVols := make(map[string]map[string][]*backup.Config)
 
+	// Used to store the missing profiles which can be reconstructed, keyed by profile project and name.
+	recoverProfiles := make(map[string]map[string]*internalRecoverProfile)
+
+	// Used to detect the same instance being found on multiple pools, keyed by project and name.
+	instancePools := make(map[string]map[string]string)
+
+	// checkProfiles records the profiles used by an instance or snapshot which don't exist and tries to
+	// reconstruct them from the instance or snapshot config.
+	checkProfiles := func(projectName string, profileProjectName string, source string, snapshotDate *time.Time, profileNames []string, localConfig map[string]string, expandedConfig map[string]string, localDevices map[string]map[string]string, expandedDevices map[string]map[string]string) {
+		for _, profileName := range profileNames {
+			foundProfile := false
+			for _, profile := range projectProfiles[profileProjectName] {
+				if profile.Name == profileName {
+					foundProfile = true
+					break
+				}
+			}
+
+			if foundProfile {
+				continue
+			}
+
+			profile, err := internalRecoverProfileFromInstance(profileName, profileNames, projectProfiles[profileProjectName], localConfig, expandedConfig, localDevices, expandedDevices)
+			if err != nil {
+				logger.Debug("Unable to reconstruct missing profile", log.Ctx{"project": projectName, "profile": profileName, "source": source, "err": err})
+				addDependencyError(fmt.Errorf("Profile %q in project %q", profileName, projectName))
+				continue
+			}
+
+			candidate := &internalRecoverProfile{profile: profile, source: source}
+			if snapshotDate != nil {
+				candidate.snapshot = true
+				candidate.date = *snapshotDate
+			}
+
+			if recoverProfiles[profileProjectName] == nil {
+				recoverProfiles[profileProjectName] = make(map[string]*internalRecoverProfile)
+			}
+
+			current := recoverProfiles[profileProjectName][profileName]
+			switch {
+			case current == nil:
+				recoverProfiles[profileProjectName][profileName] = candidate
+			case current.snapshot && !candidate.snapshot:
+				// Instances reflect the latest state of the profile so are preferred over snapshots.
+				recoverProfiles[profileProjectName][profileName] = candidate
+			case current.snapshot && candidate.snapshot:
+				// Use the most recent snapshot.
+				if candidate.date.After(current.date) {
+					recoverProfiles[profileProjectName][profileName] = candidate
+				}
+			case !current.snapshot && !candidate.snapshot:
+				if !reflect.DeepEqual(current.profile, candidate.profile) {
+					addConflict(fmt.Errorf("Profile %q in project %q has different settings in %q and %q", profileName, projectName, current.source, candidate.source))
+				}
+			}
+		}
+	}
+
 	// Used to store a handle to each pool containing user supplied config.
 	pools := make(map[string]storagePools.Pool)
 
@@ -233,18 +323,25 @@ func internalRecoverScan(d *Daemon, userPools []api.StoragePoolsPost, validateOn
 					continue // Skip dependency checks for non-instance volumes.
 				}
 
-				// Check that the instance's profile dependencies are met.
-				for _, poolInstProfileName := range poolVol.Container.Profiles {
-					foundProfile := false
-					for _, profile := range projectProfiles[profileProjectname] {
-						if profile.Name == poolInstProfileName {
-							foundProfile = true
-						}
-					}
+				// Check the instance wasn't found on another pool.
+				if instancePools[projectName] == nil {
+					instancePools[projectName] = make(map[string]string)
+				}
 
-					if !foundProfile {
-						addDependencyError(fmt.Errorf("Profile %q in project %q", poolInstProfileName, projectName))
-					}
+				otherPool, found := instancePools[projectName][poolVol.Container.Name]
+				if found {
+					addConflict(fmt.Errorf("Instance %q in project %q found on both pool %q and pool %q", poolVol.Container.Name, projectName, otherPool, p.Name))
+				} else {
+					instancePools[projectName][poolVol.Container.Name] = p.Name
+				}
+
+				// Check that the instance's profile dependencies are met, reconstructing missing ones.
+				checkProfiles(projectName, profileProjectname, poolVol.Container.Name, nil, poolVol.Container.Profiles, poolVol.Container.Config, poolVol.Container.ExpandedConfig, poolVol.Container.Devices, poolVol.Container.ExpandedDevices)
+
+				for _, snap := range poolVol.Snapshots {
+					snapName := poolVol.Container.Name + shared.SnapshotDelimiter + snap.Name
+					snapDate := snap.CreatedAt
+					checkProfiles(projectName, profileProjectname, snapName, &snapDate, snap.Profiles, snap.Config, snap.ExpandedConfig, snap.Devices, snap.ExpandedDevices)
 				}
 
 				// Check that the instance's NIC network dependencies are met.
@@ -265,17 +362,45 @@ func internalRecoverScan(d *Daemon, userPools []api.StoragePoolsPost, validateOn
 						}
 					}
 
-					if !foundNetwork {
-						addDependencyError(fmt.Errorf("Network %q in project %q", devConfig["network"], projectName))
+					if foundNetwork {
+						continue
 					}
+
+					// The network config isn't part of the instance backup, so it can't be recreated as it was.
+					addDependencyError(fmt.Errorf("Network %q in project %q", devConfig["network"], projectName))
 				}
 			}
 		}
 	}
 
-	// If in validation mode or if there are dependency errors, return discovered unknown volumes, along with
-	// any dependency errors.
-	if validateOnly || len(res.DependencyErrors) > 0 {
+	// Report the missing dependencies which will be recreated.
+	for projectName, profiles := range recoverProfiles {
+		for profileName, profile := range profiles {
+			res.RecoverDependencies = append(res.RecoverDependencies, internalRecoverValidateDependency{
+				Name:    profileName,
+				Type:    "profile",
+				Project: projectName,
+				Source:  profile.source,
+			})
+		}
+	}
+
+	sort.Slice(res.RecoverDependencies, func(i, j int) bool {
+		a, b := res.RecoverDependencies[i], res.RecoverDependencies[j]
+		if a.Type != b.Type {
+			return a.Type > b.Type
+		}
+
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+
+		return a.Name < b.Name
+	})
+
+	// If in validation mode or if there are dependency errors or conflicts, return discovered unknown volumes,
+	// along with any dependency errors and conflicts.
+	if validateOnly || len(res.DependencyErrors) > 0 || len(res.Conflicts) > 0 {
 		for poolName, poolProjectVols := range poolsProjectVols {
 			for projectName, poolVols := range poolProjectVols {
 				for _, poolVol := range poolVols {
@@ -309,77 +434,122 @@ func internalRecoverScan(d *Daemon, userPools []api.StoragePoolsPost, validateOn
 
 	// If in import mode and no dependency errors, then re-create missing DB records.
 
-	// Create any missing instance and storage volume records.
-	for _, pool := range pools {
-		for projectName, poolVols := range poolsProjectVols[pool.Name()] {
-			projectInfo := projects[projectName]
+	// Create any missing storage pool records.
+	for poolName, pool := range pools {
+		if pool.ID() != storagePools.PoolIDTemporary {
+			continue
+		}
 
-			if projectInfo == nil {
-				// Shouldn't happen as we validated this above, but be sure for safety.
-				return response.SmartError(fmt.Errorf("Project %q not found", projectName))
+		var instPoolVol *backup.Config // Instance volume used for new pool record.
+		var poolID int64               // Pool ID of created pool record.
+
+		// Search unknown volumes looking for an instance volume that can be used to
+		// restore the pool DB config from. This is preferable over using the user
+		// supplied config as it will include any additional settings not supplied.
+		for _, poolVols := range poolsProjectVols[poolName] {
+			for _, poolVol := range poolVols {
+				if poolVol.Pool != nil && poolVol.Pool.Config != nil {
+					instPoolVol = poolVol
+					break // Stop search once we've found an instance with pool config.
+				}
 			}
 
-			profileProjectName := project.ProfileProjectFromRecord(projectInfo)
-			customStorageProjectName := project.StorageVolumeProjectFromRecord(projectInfo, db.StoragePoolVolumeTypeCustom)
+			if instPoolVol != nil {
+				break
+			}
+		}
 
-			// Create missing storage pool DB record if neeed.
-			if pool.ID() == storagePools.PoolIDTemporary {
-				var instPoolVol *backup.Config // Instance volume used for new pool record.
-				var poolID int64               // Pool ID of created pool record.
+		if instPoolVol != nil {
+			// Create storage pool DB record from config in the instance.
+			logger.Info("Creating storage pool DB record from instance config", log.Ctx{"name": instPoolVol.Pool.Name, "description": instPoolVol.Pool.Description, "driver": instPoolVol.Pool.Driver, "config": instPoolVol.Pool.Config})
+			poolID, err = dbStoragePoolCreateAndUpdateCache(d.State(), instPoolVol.Pool.Name, instPoolVol.Pool.Description, instPoolVol.Pool.Driver, instPoolVol.Pool.Config)
+			if err != nil {
+				return response.SmartError(errors.Wrapf(err, "Failed creating storage pool %q database entry", pool.Name()))
+			}
+		} else {
+			// Create storage pool DB record from config supplied by user if not
+			// instance volume pool config found.
+			poolDriverName := pool.Driver().Info().Name
+			poolDriverConfig := pool.Driver().Config()
+			logger.Info("Creating storage pool DB record from user config", log.Ctx{"name": pool.Name(), "driver": poolDriverName, "config": poolDriverConfig})
+			poolID, err = dbStoragePoolCreateAndUpdateCache(d.State(), pool.Name(), "", poolDriverName, poolDriverConfig)
+			if err != nil {
+				return response.SmartError(errors.Wrapf(err, "Failed creating storage pool %q database entry", pool.Name()))
+			}
+		}
 
-				// Search unknown volumes looking for an instance volume that can be used to
-				// restore the pool DB config from. This is preferable over using the user
-				// supplied config as it will include any additional settings not supplied.
-				for _, poolVol := range poolVols {
-					if poolVol.Pool != nil && poolVol.Pool.Config != nil {
-						instPoolVol = poolVol
-						break // Stop search once we've found an instance with pool config.
-					}
-				}
+		// Capture the loop variable for the reverter.
+		poolName := poolName
+		revert.Add(func() {
+			dbStoragePoolDeleteAndUpdateCache(d.State(), poolName)
+		})
+
+		// Set storage pool node to storagePoolCreated.
+		// Must come before storage pool is loaded from the database.
+		err = d.State().Cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.StoragePoolNodeCreated(poolID)
+		})
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed marking storage pool %q local status as created", pool.Name()))
+		}
+		logger.Debug("Marked storage pool local status as created", log.Ctx{"pool": pool.Name()})
 
-				if instPoolVol != nil {
-					// Create storage pool DB record from config in the instance.
-					logger.Info("Creating storage pool DB record from instance config", log.Ctx{"name": instPoolVol.Pool.Name, "description": instPoolVol.Pool.Description, "driver": instPoolVol.Pool.Driver, "config": instPoolVol.Pool.Config})
-					poolID, err = dbStoragePoolCreateAndUpdateCache(d.State(), instPoolVol.Pool.Name, instPoolVol.Pool.Description, instPoolVol.Pool.Driver, instPoolVol.Pool.Config)
-					if err != nil {
-						return response.SmartError(errors.Wrapf(err, "Failed creating storage pool %q database entry", pool.Name()))
-					}
-				} else {
-					// Create storage pool DB record from config supplied by user if not
-					// instance volume pool config found.
-					poolDriverName := pool.Driver().Info().Name
-					poolDriverConfig := pool.Driver().Config()
-					logger.Info("Creating storage pool DB record from user config", log.Ctx{"name": pool.Name(), "driver": poolDriverName, "config": poolDriverConfig})
-					poolID, err = dbStoragePoolCreateAndUpdateCache(d.State(), pool.Name(), "", poolDriverName, poolDriverConfig)
-					if err != nil {
-						return response.SmartError(errors.Wrapf(err, "Failed creating storage pool %q database entry", pool.Name()))
-					}
-				}
+		newPool, err := storagePools.GetPoolByName(d.State(), pool.Name())
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed loading created storage pool %q", pool.Name()))
+		}
+
+		// Record this newly created pool so that defer doesn't unmount on return.
+		pools[poolName] = newPool
+	}
 
-				revert.Add(func() {
-					dbStoragePoolDeleteAndUpdateCache(d.State(), pool.Name())
+	// Recreate missing profiles from the reconstructed config.
+	for projectName, profiles := range recoverProfiles {
+		for profileName, profile := range profiles {
+			logger.Info("Creating profile DB record from instance config", log.Ctx{"project": projectName, "name": profileName, "source": profile.source})
+			err = d.State().Cluster.Transaction(func(tx *db.ClusterTx) error {
+				_, err := tx.CreateProfile(db.Profile{
+					Project: projectName,
+					Name:    profileName,
+					Config:  profile.profile.Config,
+					Devices: profile.profile.Devices,
 				})
 
-				// Set storage pool node to storagePoolCreated.
-				// Must come before storage pool is loaded from the database.
-				err = d.State().Cluster.Transaction(func(tx *db.ClusterTx) error {
-					return tx.StoragePoolNodeCreated(poolID)
+				return err
+			})
+			if err != nil {
+				return response.SmartError(errors.Wrapf(err, "Failed creating profile %q in project %q", profileName, projectName))
+			}
+
+			// Capture loop variables for the reverter.
+			projectName := projectName
+			profileName := profileName
+			revert.Add(func() {
+				d.State().Cluster.Transaction(func(tx *db.ClusterTx) error {
+					return tx.DeleteProfile(projectName, profileName)
 				})
-				if err != nil {
-					return response.SmartError(errors.Wrapf(err, "Failed marking storage pool %q local status as created", pool.Name()))
-				}
-				logger.Debug("Marked storage pool local status as created", log.Ctx{"pool": pool.Name()})
+			})
 
-				newPool, err := storagePools.GetPoolByName(d.State(), pool.Name())
-				if err != nil {
-					return response.SmartError(errors.Wrapf(err, "Failed loading created storage pool %q", pool.Name()))
-				}
+			projectProfiles[projectName] = append(projectProfiles[projectName], &api.Profile{
+				Name:       profileName,
+				ProfilePut: *profile.profile,
+			})
+		}
+	}
 
-				// Record this newly created pool so that defer doesn't unmount on return.
-				pools[pool.Name()] = newPool
-				pool = newPool // Replace temporary pool handle with proper one from DB.
+	// Create any missing instance and storage volume records.
+	for _, pool := range pools {
+		for projectName, poolVols := range poolsProjectVols[pool.Name()] {
+			projectInfo := projects[projectName]
+
+			if projectInfo == nil {
+				// Shouldn't happen as we validated this above, but be sure for safety.
+				return response.SmartError(fmt.Errorf("Project %q not found", projectName))
 			}
 
+			profileProjectName := project.ProfileProjectFromRecord(projectInfo)
+			customStorageProjectName := project.StorageVolumeProjectFromRecord(projectInfo, db.StoragePoolVolumeTypeCustom)
+
 			// Recover unknown custom volumes (do this first before recovering instances so that any
 			// instances that reference unknown custom volume disk devices can be created).
 			for _, poolVol := range poolVols {
@@ -457,6 +627,125 @@ func internalRecoverScan(d *Daemon, userPools []api.StoragePoolsPost, validateOn
 	return response.EmptySyncResponse
 }
 
+// internalRecoverProfileFromInstance reconstructs the config and devices of a missing profile from the local and
+// expanded config and devices of an instance (or snapshot) using it. All the other profiles used by the instance
+// must exist. Settings shadowed by later profiles or by the instance itself can't be recovered and are skipped.
+func internalRecoverProfileFromInstance(profileName string, profileNames []string, existingProfiles []*api.Profile, localConfig map[string]string, expandedConfig map[string]string, localDevices map[string]map[string]string, expandedDevices map[string]map[string]string) (*api.ProfilePut, error) {
+	// Split the instance profiles into the ones applied before and after the missing profile.
+	var before, after []*api.Profile
+	missingIndex := -1
+	for i, name := range profileNames {
+		if name == profileName {
+			missingIndex = i
+			continue
+		}
+
+		var profile *api.Profile
+		for _, existingProfile := range existingProfiles {
+			if existingProfile.Name == name {
+				profile = existingProfile
+				break
+			}
+		}
+
+		if profile == nil {
+			return nil, fmt.Errorf("Profile %q is missing too", name)
+		}
+
+		if missingIndex < 0 {
+			before = append(before, profile)
+		} else {
+			after = append(after, profile)
+		}
+	}
+
+	if missingIndex < 0 {
+		return nil, fmt.Errorf("Profile %q isn't used", profileName)
+	}
+
+	profile := &api.ProfilePut{
+		Config:  map[string]string{},
+		Devices: map[string]map[string]string{},
+	}
+
+	for key, value := range expandedConfig {
+		if strings.HasPrefix(key, "volatile.") {
+			continue
+		}
+
+		_, ok := localConfig[key]
+		if ok {
+			continue
+		}
+
+		shadowed := false
+		for _, p := range after {
+			_, ok := p.Config[key]
+			if ok {
+				shadowed = true
+				break
+			}
+		}
+
+		if shadowed {
+			continue
+		}
+
+		// Skip values inherited from an earlier profile.
+		inherited := false
+		for i := len(before) - 1; i >= 0; i-- {
+			profileValue, ok := before[i].Config[key]
+			if ok {
+				inherited = profileValue == value
+				break
+			}
+		}
+
+		if inherited {
+			continue
+		}
+
+		profile.Config[key] = value
+	}
+
+	for name, device := range expandedDevices {
+		_, ok := localDevices[name]
+		if ok {
+			continue
+		}
+
+		shadowed := false
+		for _, p := range after {
+			_, ok := p.Devices[name]
+			if ok {
+				shadowed = true
+				break
+			}
+		}
+
+		if shadowed {
+			continue
+		}
+
+		inherited := false
+		for i := len(before) - 1; i >= 0; i-- {
+			profileDevice, ok := before[i].Devices[name]
+			if ok {
+				inherited = reflect.DeepEqual(profileDevice, device)
+				break
+			}
+		}
+
+		if inherited {
+			continue
+		}
+
+		profile.Devices[name] = device
+	}
+
+	return profile, nil
+}
+
 // internalRecoverImportInstance recreates the database records for an instance and returns the new instance.
 func internalRecoverImportInstance(s *state.State, pool storagePools.Pool, projectName string, poolVol *backup.Config, profiles []api.Profile, revert *revert.Reverter) (instance.Instance, error) {
 	if poolVol.Container == nil {
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestInternalRecoverProfileFromInstance(t *testing.T) {
+	existing := []*api.Profile{
+		{
+			Name: "default",
+			ProfilePut: api.ProfilePut{
+				Config: map[string]string{"limits.cpu": "1", "limits.memory": "1GiB"},
+				Devices: map[string]map[string]string{
+					"root": {"type": "disk", "path": "/", "pool": "default"},
+					"eth0": {"type": "nic", "network": "lxdbr0"},
+				},
+			},
+		},
+		{
+			Name: "net",
+			ProfilePut: api.ProfilePut{
+				Config: map[string]string{"limits.memory": "2GiB"},
+				Devices: map[string]map[string]string{
+					"eth0": {"type": "nic", "network": "lxdbr1"},
+				},
+			},
+		},
+	}
+
+	localConfig := map[string]string{"security.nesting": "true"}
+	expandedConfig := map[string]string{
+		"limits.cpu":           "1",     // Inherited from default.
+		"limits.memory":        "2GiB",  // Shadowed by net.
+		"security.nesting":     "true",  // Local.
+		"security.privileged":  "true",  // From the missing profile.
+		"volatile.eth0.hwaddr": "value", // Volatile.
+	}
+
+	localDevices := map[string]map[string]string{}
+	expandedDevices := map[string]map[string]string{
+		"root": {"type": "disk", "path": "/", "pool": "default"},
+		"eth0": {"type": "nic", "network": "lxdbr1"},
+		"data": {"type": "disk", "path": "/data", "source": "/srv/data"},
+	}
+
+	profile, err := internalRecoverProfileFromInstance("missing", []string{"default", "missing", "net"}, existing, localConfig, expandedConfig, localDevices, expandedDevices)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"security.privileged": "true"}, profile.Config)
+	assert.Equal(t, map[string]map[string]string{"data": {"type": "disk", "path": "/data", "source": "/srv/data"}}, profile.Devices)
+
+	// An overridden value from an earlier profile belongs to the missing profile.
+	expandedConfig["limits.cpu"] = "4"
+	profile, err = internalRecoverProfileFromInstance("missing", []string{"default", "missing", "net"}, existing, localConfig, expandedConfig, localDevices, expandedDevices)
+	require.NoError(t, err)
+	assert.Equal(t, "4", profile.Config["limits.cpu"])
+
+	// All the other profiles must exist.
+	_, err = internalRecoverProfileFromInstance("missing", []string{"default", "missing", "other"}, existing, localConfig, expandedConfig, localDevices, expandedDevices)
+	assert.Error(t, err)
+}
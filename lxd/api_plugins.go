@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/plugin"
+	"github.com/lxc/lxd/lxd/response"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// pluginEndpoints returns the API endpoints of the registered plugins, skipping those whose path is already served
+// by a built-in endpoint or by a previously registered plugin.
+func pluginEndpoints() []APIEndpoint {
+	paths := map[string]bool{}
+	for _, c := range api10 {
+		paths[c.Path] = true
+		for _, alias := range c.Aliases {
+			paths[alias.Path] = true
+		}
+	}
+
+	endpoints := []APIEndpoint{}
+	for _, p := range plugin.Plugins() {
+		for _, e := range p.Endpoints() {
+			if paths[e.Path] {
+				logger.Warn("Skipping plugin endpoint conflicting with an existing one", log.Ctx{"plugin": p.Name(), "path": e.Path})
+				continue
+			}
+
+			paths[e.Path] = true
+
+			endpoints = append(endpoints, APIEndpoint{
+				Name:   p.Name(),
+				Path:   e.Path,
+				Get:    pluginAction(e.Get),
+				Put:    pluginAction(e.Put),
+				Post:   pluginAction(e.Post),
+				Delete: pluginAction(e.Delete),
+				Patch:  pluginAction(e.Patch),
+			})
+		}
+	}
+
+	return endpoints
+}
+
+// pluginAction converts the action of a plugin endpoint into the action of an API endpoint.
+func pluginAction(action plugin.Action) APIEndpointAction {
+	result := APIEndpointAction{AllowUntrusted: action.AllowUntrusted}
+
+	if action.Handler != nil {
+		result.Handler = func(d *Daemon, r *http.Request) response.Response {
+			return action.Handler(d.State(), r)
+		}
+	}
+
+	if action.AccessHandler != nil {
+		result.AccessHandler = func(d *Daemon, r *http.Request) response.Response {
+			return action.AccessHandler(d.State(), r)
+		}
+	}
+
+	return result
+}
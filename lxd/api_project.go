@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -26,6 +27,7 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/validate"
 	"github.com/lxc/lxd/shared/version"
 )
@@ -145,7 +147,7 @@ func projectsGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
 	var result interface{}
-	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	err := d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 		filter := db.ProjectFilter{}
 		if recursion {
 			projects, err := tx.GetProjects(filter)
@@ -526,6 +528,22 @@ func projectPatch(d *Daemon, r *http.Request) response.Response {
 
 // Common logic between PUT and PATCH.
 func projectChange(d *Daemon, project *db.Project, req api.ProjectPut) response.Response {
+	// Volatile keys are managed by LXD, keep their current value.
+	config := map[string]string{}
+	for key, value := range req.Config {
+		if !strings.HasPrefix(key, shared.ConfigVolatilePrefix) {
+			config[key] = value
+		}
+	}
+
+	for key, value := range project.Config {
+		if strings.HasPrefix(key, shared.ConfigVolatilePrefix) {
+			config[key] = value
+		}
+	}
+
+	req.Config = config
+
 	// Make a list of config keys that have changed.
 	configChanged := []string{}
 	for key := range project.Config {
@@ -599,6 +617,14 @@ func projectChange(d *Daemon, project *db.Project, req api.ProjectPut) response.
 		return response.SmartError(err)
 	}
 
+	// Apply the new aggregate network limits to the local shapers, the other members pick them up periodically.
+	if shared.StringInSlice("limits.network.ingress", configChanged) || shared.StringInSlice("limits.network.egress", configChanged) {
+		err = network.ProjectShaperUpdate(project.Name, req.Config)
+		if err != nil {
+			logger.Warn("Failed to update project network shapers", log.Ctx{"project": project.Name, "err": err})
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -759,6 +785,22 @@ func projectDelete(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Clean up the MAAS devices left behind in the project's domain. The project is gone at this point, so
+	// only report the failures.
+	if d.maas != nil {
+		err = d.maas.DeleteProject(name)
+		if err != nil {
+			logger.Warn("Failed to delete MAAS records of project", log.Ctx{"project": name, "err": err})
+			d.cluster.UpsertWarningLocalNode("", -1, -1, db.WarningMAASOperationFailed, fmt.Sprintf("Failed to delete MAAS records of project %q: %v", name, err))
+		}
+	}
+
+	// Remove the local devices shaping the network traffic of the project.
+	err = network.ProjectShaperDelete(name)
+	if err != nil {
+		logger.Warn("Failed to delete project network shapers", log.Ctx{"project": name, "err": err})
+	}
+
 	requestor := request.CreateRequestor(r)
 	d.State().Events.SendLifecycle(name, lifecycle.ProjectDeleted.Event(name, requestor, nil))
 
@@ -811,7 +853,7 @@ func projectStateGet(d *Daemon, r *http.Request) response.Response {
 	state := api.ProjectState{}
 
 	// Get current limits and usage.
-	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	err := d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 		result, err := projecthelpers.GetCurrentAllocations(tx, name)
 		if err != nil {
 			return err
@@ -847,6 +889,17 @@ func isEitherAllowOrBlockOrManaged(value string) error {
 	return validate.Optional(validate.IsOneOf("block", "allow", "managed"))(value)
 }
 
+func isAppArmorRuleClassList(value string) error {
+	for _, class := range strings.Split(value, ",") {
+		class = strings.TrimSpace(class)
+		if !shared.StringInSlice(class, projecthelpers.AppArmorRuleClasses) {
+			return fmt.Errorf("Invalid AppArmor rule class %q", class)
+		}
+	}
+
+	return nil
+}
+
 func projectValidateConfig(s *state.State, config map[string]string) error {
 	// Validate the project configuration.
 	projectConfigKeys := map[string]func(value string) error{
@@ -868,13 +921,18 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 		"limits.cpu":                           validate.Optional(validate.IsUint32),
 		"limits.disk":                          validate.Optional(validate.IsSize),
 		"limits.networks":                      validate.Optional(validate.IsUint32),
+		"limits.network.ingress":               validate.Optional(validate.IsNetworkRate),
+		"limits.network.egress":                validate.Optional(validate.IsNetworkRate),
 		"restricted":                           validate.Optional(validate.IsBool),
+		"restricted.apparmor.extra":            validate.Optional(isAppArmorRuleClassList),
 		"restricted.backups":                   isEitherAllowOrBlock,
 		"restricted.cluster.target":            isEitherAllowOrBlock,
 		"restricted.containers.nesting":        isEitherAllowOrBlock,
 		"restricted.containers.lowlevel":       isEitherAllowOrBlock,
 		"restricted.containers.privilege":      validate.Optional(validate.IsOneOf("allow", "unprivileged", "isolated")),
+		"restricted.containers.interception":   validate.Optional(validate.IsOneOf("allow", "block", "full")),
 		"restricted.virtual-machines.lowlevel": isEitherAllowOrBlock,
+		"restricted.devices.kvm":               isEitherAllowOrBlock,
 		"restricted.devices.unix-char":         isEitherAllowOrBlock,
 		"restricted.devices.unix-block":        isEitherAllowOrBlock,
 		"restricted.devices.unix-hotplug":      isEitherAllowOrBlock,
@@ -887,7 +945,31 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 		"restricted.networks.subnets": validate.Optional(func(value string) error {
 			return projectValidateRestrictedSubnets(s, value)
 		}),
-		"restricted.snapshots": isEitherAllowOrBlock,
+		"restricted.snapshots":      isEitherAllowOrBlock,
+		"restricted.volumes.export": isEitherAllowOrBlock,
+		"security.idmap.isolated_ranges": validate.Optional(func(value string) error {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return err
+			}
+
+			if size < 65536 {
+				return fmt.Errorf("The range must contain at least 65536 uid/gid")
+			}
+
+			return nil
+		}),
+		projecthelpers.IdmapRangeOffsetKey: validate.Optional(validate.IsInt64),
+		"security.protection.defaults": validate.Optional(func(value string) error {
+			for _, protection := range strings.Split(value, ",") {
+				protection = strings.TrimSpace(protection)
+				if !shared.StringInSlice(protection, projecthelpers.InstanceProtections) {
+					return fmt.Errorf("Invalid protection %q (must be one of %s)", protection, strings.Join(projecthelpers.InstanceProtections, ", "))
+				}
+			}
+
+			return nil
+		}),
 	}
 
 	for k, v := range config {
@@ -898,6 +980,16 @@ func projectValidateConfig(s *state.State, config map[string]string) error {
 			continue
 		}
 
+		// Environment keys are enforced on all instances of the project.
+		if strings.HasPrefix(key, projecthelpers.EnvironmentPrefix) {
+			err := projecthelpers.ValidEnvironmentKey(key)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid project configuration key %q", k)
+			}
+
+			continue
+		}
+
 		// Then validate.
 		validator, ok := projectConfigKeys[key]
 		if !ok {
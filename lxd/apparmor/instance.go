@@ -148,6 +148,15 @@ func instanceProfile(state *state.State, inst instance) (string, error) {
 		}
 	}
 
+	// Prepare raw.apparmor.extra (validated when set).
+	extraContent := ""
+	extraApparmor, ok := inst.ExpandedConfig()["raw.apparmor.extra"]
+	if ok {
+		for _, line := range strings.Split(strings.Trim(extraApparmor, "\n"), "\n") {
+			extraContent += fmt.Sprintf("  %s\n", strings.TrimSpace(line))
+		}
+	}
+
 	// Check for features.
 	unixSupported, err := parserSupports(state, "unix")
 	if err != nil {
@@ -166,6 +175,7 @@ func instanceProfile(state *state.State, inst instance) (string, error) {
 			"namespace":        InstanceNamespaceName(inst),
 			"nesting":          shared.IsTrue(inst.ExpandedConfig()["security.nesting"]),
 			"raw":              rawContent,
+			"raw_extra":        extraContent,
 			"unprivileged":     !shared.IsTrue(inst.ExpandedConfig()["security.privileged"]) || state.OS.RunningInUserNS,
 		})
 		if err != nil {
@@ -210,6 +220,7 @@ func instanceProfile(state *state.State, inst instance) (string, error) {
 			"name":             InstanceProfileName(inst),
 			"path":             path,
 			"raw":              rawContent,
+			"raw_extra":        extraContent,
 			"rootPath":         rootPath,
 			"snap":             shared.InSnap(),
 			"ovmfPath":         ovmfPath,
@@ -33,6 +33,9 @@ profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted) {
   capability fsetid,
   capability kill,
   capability net_bind_service,
+{{- if .listenIface }}
+  capability net_raw,
+{{- end }}
   capability setgid,
   capability setuid,
   capability sys_admin,
@@ -154,6 +157,7 @@ func forkproxyProfile(state *state.State, inst instance, dev device) (string, er
 		"logPath":     inst.LogPath(),
 		"libraryPath": strings.Split(os.Getenv("LD_LIBRARY_PATH"), ":"),
 		"sockets":     sockets,
+		"listenIface": dev.Config()["listen_interface"] != "",
 	})
 	if err != nil {
 		return "", err
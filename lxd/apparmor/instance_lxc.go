@@ -572,5 +572,11 @@ profile "{{ .name }}" flags=(attach_disconnected,mediate_deleted) {
   ### Configuration: raw.apparmor
 {{ .raw }}
 {{- end }}
+
+{{- if .raw_extra }}
+
+  ### Configuration: raw.apparmor.extra
+{{ .raw_extra }}
+{{- end }}
 }
 `))
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
 )
 
 // Internal copy of the network interface.
@@ -14,6 +15,11 @@ type network interface {
 	Name() string
 }
 
+// networkUsesForkdns returns whether the network runs forkdns (fan mode) or its DNS64 variant (NAT64).
+func networkUsesForkdns(n network) bool {
+	return n.Config()["bridge.mode"] == "fan" || shared.IsTrue(n.Config()["ipv6.nat64"])
+}
+
 // NetworkLoad ensures that the network's profiles are loaded into the kernel.
 func NetworkLoad(state *state.State, n network) error {
 	/* In order to avoid forcing a profile parse (potentially slow) on
@@ -53,7 +59,7 @@ func NetworkLoad(state *state.State, n network) error {
 	}
 
 	// forkdns
-	if n.Config()["bridge.mode"] == "fan" {
+	if networkUsesForkdns(n) {
 		profile := filepath.Join(aaPath, "profiles", forkdnsProfileFilename(n))
 		content, err := ioutil.ReadFile(profile)
 		if err != nil && !os.IsNotExist(err) {
@@ -91,7 +97,7 @@ func NetworkUnload(state *state.State, n network) error {
 	}
 
 	// forkdns
-	if n.Config()["bridge.mode"] == "fan" {
+	if networkUsesForkdns(n) {
 		err := unloadProfile(state, ForkdnsProfileName(n), forkdnsProfileFilename(n))
 		if err != nil {
 			return err
@@ -108,7 +114,7 @@ func NetworkDelete(state *state.State, n network) error {
 		return err
 	}
 
-	if n.Config()["bridge.mode"] == "fan" {
+	if networkUsesForkdns(n) {
 		err := deleteProfile(state, ForkdnsProfileName(n), forkdnsProfileFilename(n))
 		if err != nil {
 			return err
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// webhookTimeout is how long the authorization webhook has to answer a request.
+const webhookTimeout = 10 * time.Second
+
+// webhookCacheSize is the number of decisions above which the expired ones are pruned from the cache.
+const webhookCacheSize = 10000
+
+// Actor is the user making an API request.
+type Actor struct {
+	Username string `json:"username"`
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+}
+
+// Entity is the API resource a request applies to.
+type Entity struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Project string `json:"project"`
+	Path    string `json:"path"`
+}
+
+// Request is an authorization request sent to the webhook.
+type Request struct {
+	Actor  Actor  `json:"actor"`
+	Entity Entity `json:"entity"`
+	Action string `json:"action"`
+}
+
+// webhookEntityRoutes maps the API paths, relative to /1.0, to the type of the entity they apply to. The most
+// specific paths come first. The {name} segment is the name of the entity, {name...} also spanning the following
+// segments, while the other segments in braces match anything. The paths not listed apply to the server.
+var webhookEntityRoutes = []struct {
+	path       string
+	entityType string
+}{
+	{"certificates/{name}", "certificate"},
+	{"certificates", "certificate"},
+	{"cluster/members/{name}", "cluster-member"},
+	{"cluster/members", "cluster-member"},
+	{"cluster", "cluster"},
+	{"containers/{name}", "instance"},
+	{"containers", "instance"},
+	{"images/aliases/{name...}", "image-alias"},
+	{"images/aliases", "image-alias"},
+	{"images/{name}", "image"},
+	{"images", "image"},
+	{"instances/{name}", "instance"},
+	{"instances", "instance"},
+	{"network-acls/{name}", "network-acl"},
+	{"network-acls", "network-acl"},
+	{"networks/{name}", "network"},
+	{"networks", "network"},
+	{"operations/{name}", "operation"},
+	{"operations", "operation"},
+	{"profiles/{name}", "profile"},
+	{"profiles", "profile"},
+	{"projects/{name}", "project"},
+	{"projects", "project"},
+	{"storage-pools/{pool}/volumes/{type}/{name}", "storage-volume"},
+	{"storage-pools/{pool}/volumes", "storage-volume"},
+	{"storage-pools/{name}", "storage-pool"},
+	{"storage-pools", "storage-pool"},
+	{"virtual-machines/{name}", "instance"},
+	{"virtual-machines", "instance"},
+	{"warnings/{name}", "warning"},
+	{"warnings", "warning"},
+}
+
+// webhookEntity returns the type and name of the entity the given API path applies to.
+func webhookEntity(path string) (string, string) {
+	fields := strings.Split(strings.Trim(path, "/"), "/")
+	if len(fields) < 2 || fields[0] != "1.0" {
+		return "server", ""
+	}
+
+	fields = fields[1:]
+
+	for _, route := range webhookEntityRoutes {
+		routeFields := strings.Split(route.path, "/")
+		if len(fields) < len(routeFields) {
+			continue
+		}
+
+		name := ""
+		matched := true
+		for i, routeField := range routeFields {
+			switch {
+			case routeField == "{name}":
+				name = fields[i]
+			case routeField == "{name...}":
+				name = strings.Join(fields[i:], "/")
+			case strings.HasPrefix(routeField, "{"):
+			case routeField != fields[i]:
+				matched = false
+			}
+
+			if !matched {
+				break
+			}
+		}
+
+		if matched {
+			return route.entityType, name
+		}
+	}
+
+	return "server", ""
+}
+
+// webhookDecision is a cached authorization decision.
+type webhookDecision struct {
+	allowed bool
+	reason  string
+	expiry  time.Time
+}
+
+// WebhookAuthorizer authorizes API requests by asking an external HTTP endpoint, typically a policy engine such
+// as Open Policy Agent.
+//
+// The request is posted as {"input": <Request>} and the endpoint must answer with {"result": true|false} or
+// {"result": {"allow": true|false, "reason": "..."}}, which is what an OPA data API endpoint returns.
+type WebhookAuthorizer struct {
+	url    string
+	token  string
+	expiry time.Duration
+	client *http.Client
+
+	cache     map[Request]webhookDecision
+	cacheLock sync.Mutex
+}
+
+// NewWebhookAuthorizer returns an authorizer querying the given URL, optionally with a bearer token, and caching
+// its decisions for expiry.
+func NewWebhookAuthorizer(apiURL string, token string, expiry time.Duration, proxy func(req *http.Request) (*url.URL, error)) *WebhookAuthorizer {
+	return &WebhookAuthorizer{
+		url:    apiURL,
+		token:  token,
+		expiry: expiry,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{Proxy: proxy},
+		},
+		cache: map[Request]webhookDecision{},
+	}
+}
+
+// NewRequest returns the authorization request of an API call.
+func NewRequest(r *http.Request, username string, protocol string, project string) Request {
+	req := Request{
+		Actor: Actor{
+			Username: username,
+			Protocol: protocol,
+			Address:  r.RemoteAddr,
+		},
+		Entity: Entity{
+			Project: project,
+			Path:    r.URL.Path,
+		},
+		Action: strings.ToLower(r.Method),
+	}
+
+	// Ignore the port of the client as it would defeat the cache.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err == nil {
+		req.Actor.Address = host
+	}
+
+	// Derive the entity from the path, e.g. /1.0/instances/c1/snapshots is the instance c1.
+	req.Entity.Type, req.Entity.Name = webhookEntity(r.URL.Path)
+
+	return req
+}
+
+// Authorize returns whether the request is allowed, along with the reason given by the webhook if any. Errors
+// reaching the webhook are returned, in which case the request must be denied.
+func (a *WebhookAuthorizer) Authorize(req Request) (bool, string, error) {
+	now := time.Now()
+
+	a.cacheLock.Lock()
+	decision, ok := a.cache[req]
+	a.cacheLock.Unlock()
+
+	if ok && now.Before(decision.expiry) {
+		return decision.allowed, decision.reason, nil
+	}
+
+	allowed, reason, err := a.query(req)
+	if err != nil {
+		return false, "", err
+	}
+
+	a.cacheLock.Lock()
+	defer a.cacheLock.Unlock()
+
+	if len(a.cache) >= webhookCacheSize {
+		for key, entry := range a.cache {
+			if now.After(entry.expiry) {
+				delete(a.cache, key)
+			}
+		}
+	}
+
+	if a.expiry > 0 && len(a.cache) < webhookCacheSize {
+		a.cache[req] = webhookDecision{allowed: allowed, reason: reason, expiry: now.Add(a.expiry)}
+	}
+
+	return allowed, reason, nil
+}
+
+// query asks the webhook whether the request is allowed.
+func (a *WebhookAuthorizer) query(req Request) (bool, string, error) {
+	body, err := json.Marshal(map[string]Request{"input": req})
+	if err != nil {
+		return false, "", err
+	}
+
+	httpReq, err := http.NewRequest("POST", a.url, bytes.NewReader(body))
+	if err != nil {
+		return false, "", err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.token))
+	}
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return false, "", errors.Wrap(err, "Failed querying the authorization webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("Authorization webhook returned status %d", resp.StatusCode)
+	}
+
+	result := struct {
+		Result json.RawMessage `json:"result"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return false, "", errors.Wrap(err, "Failed parsing the authorization webhook response")
+	}
+
+	// An undefined OPA decision has no result and denies the request.
+	if len(result.Result) == 0 {
+		return false, "", nil
+	}
+
+	var allowed bool
+	err = json.Unmarshal(result.Result, &allowed)
+	if err == nil {
+		return allowed, "", nil
+	}
+
+	decision := struct {
+		Allow  bool   `json:"allow"`
+		Reason string `json:"reason"`
+	}{}
+
+	err = json.Unmarshal(result.Result, &decision)
+	if err != nil {
+		return false, "", errors.Wrap(err, "Failed parsing the authorization webhook decision")
+	}
+
+	return decision.Allow, decision.Reason, nil
+}
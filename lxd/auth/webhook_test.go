@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequest(t *testing.T) {
+	r := httptest.NewRequest("PATCH", "/1.0/instances/c1/snapshots/snap0?project=foo", nil)
+	r.RemoteAddr = "[2001:db8::1]:51234"
+
+	req := NewRequest(r, "alice", "tls", "foo")
+	assert.Equal(t, Actor{Username: "alice", Protocol: "tls", Address: "2001:db8::1"}, req.Actor)
+	assert.Equal(t, Entity{Type: "instance", Name: "c1", Project: "foo", Path: "/1.0/instances/c1/snapshots/snap0"}, req.Entity)
+	assert.Equal(t, "patch", req.Action)
+
+	cases := []struct {
+		path       string
+		entityType string
+		name       string
+	}{
+		{"/1.0", "server", ""},
+		{"/1.0/events", "server", ""},
+		{"/1.0/resources", "server", ""},
+		{"/1.0/instances", "instance", ""},
+		{"/1.0/virtual-machines/v1/console", "instance", "v1"},
+		{"/1.0/images/aliases", "image-alias", ""},
+		{"/1.0/images/aliases/ubuntu/focal", "image-alias", "ubuntu/focal"},
+		{"/1.0/images/abcdef/export", "image", "abcdef"},
+		{"/1.0/storage-pools/default", "storage-pool", "default"},
+		{"/1.0/storage-pools/default/resources", "storage-pool", "default"},
+		{"/1.0/storage-pools/default/volumes", "storage-volume", ""},
+		{"/1.0/storage-pools/default/volumes/custom", "storage-volume", ""},
+		{"/1.0/storage-pools/default/volumes/custom/vol1/snapshots", "storage-volume", "vol1"},
+		{"/1.0/network-acls/acl1", "network-acl", "acl1"},
+		{"/1.0/networks/lxdbr0/leases", "network", "lxdbr0"},
+		{"/1.0/cluster", "cluster", ""},
+		{"/1.0/cluster/members/node1/state", "cluster-member", "node1"},
+		{"/1.0/cluster/certificate", "cluster", ""},
+		{"/1.0/operations/1234/wait", "operation", "1234"},
+	}
+
+	for _, c := range cases {
+		r = httptest.NewRequest("GET", c.path, nil)
+		req = NewRequest(r, "alice", "tls", "default")
+		assert.Equal(t, c.entityType, req.Entity.Type, c.path)
+		assert.Equal(t, c.name, req.Entity.Name, c.path)
+	}
+}
+
+func TestWebhookAuthorizer(t *testing.T) {
+	queries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		body := struct {
+			Input Request `json:"input"`
+		}{}
+
+		err := json.NewDecoder(r.Body).Decode(&body)
+		require.NoError(t, err)
+
+		switch body.Input.Actor.Username {
+		case "alice":
+			fmt.Fprint(w, `{"result": true}`)
+		case "bob":
+			fmt.Fprint(w, `{"result": {"allow": false, "reason": "Read-only user"}}`)
+		case "carol":
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	authorizer := NewWebhookAuthorizer(server.URL, "secret", time.Minute, nil)
+
+	newRequest := func(username string) Request {
+		return Request{Actor: Actor{Username: username, Protocol: "tls"}, Entity: Entity{Type: "instance", Name: "c1"}, Action: "get"}
+	}
+
+	allowed, _, err := authorizer.Authorize(newRequest("alice"))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, reason, err := authorizer.Authorize(newRequest("bob"))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, "Read-only user", reason)
+
+	// Undefined decisions deny the request.
+	allowed, _, err = authorizer.Authorize(newRequest("carol"))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Decisions are cached.
+	allowed, _, err = authorizer.Authorize(newRequest("alice"))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 3, queries)
+
+	// Failures aren't.
+	_, _, err = authorizer.Authorize(newRequest("dave"))
+	assert.Error(t, err)
+
+	_, _, err = authorizer.Authorize(newRequest("dave"))
+	assert.Error(t, err)
+	assert.Equal(t, 5, queries)
+}
@@ -35,7 +35,7 @@ import (
 )
 
 // Create a new backup.
-func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.Instance) error {
+func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.Instance, format string) error {
 	logger := logging.AddContext(logger.Log, log.Ctx{"project": sourceInst.Project(), "instance": sourceInst.Name(), "name": args.Name})
 	logger.Debug("Instance backup started")
 	defer logger.Debug("Instance backup finished")
@@ -107,6 +107,22 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 
 	target := shared.VarPath("backups", "instances", project.Instance(sourceInst.Project(), b.Name()))
 
+	// Virtual machines can also be exported for other platforms rather than as a tarball.
+	if format == backupFormatQcow2 || format == backupFormatOVA {
+		logger.Debug("Exporting backup disk", log.Ctx{"path": target, "format": format})
+		revert.Add(func() { os.Remove(target) })
+
+		err = backupWriteDisk(sourceInst, pool, format, target)
+		if err != nil {
+			return errors.Wrapf(err, "Error exporting %s", format)
+		}
+
+		revert.Success()
+		s.Events.SendLifecycle(sourceInst.Project(), lifecycle.InstanceBackupCreated.Event(args.Name, b.Instance(), nil))
+
+		return nil
+	}
+
 	// Setup the tarball writer.
 	logger.Debug("Opening backup tarball for writing", log.Ctx{"path": target})
 	tarFileWriter, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0600)
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/resources"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// Backup formats.
+const (
+	backupFormatTarball = "tarball"
+	backupFormatQcow2   = "qcow2"
+	backupFormatOVA     = "ova"
+)
+
+// ovfTemplate is the OVF descriptor of the virtual machines exported as an OVA appliance.
+var ovfTemplate = template.Must(template.New("ovf").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf">
+  <References>
+    <File ovf:href="{{.DiskFile}}" ovf:id="file1" ovf:size="{{.DiskFileSize}}"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="{{.DiskCapacity}}" ovf:capacityAllocationUnits="byte" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+{{- range .Networks}}
+    <Network ovf:name="{{.}}">
+      <Description>The {{.}} network</Description>
+    </Network>
+{{- end}}
+  </NetworkSection>
+  <VirtualSystem ovf:id="{{.Name}}">
+    <Info>A virtual machine exported from LXD</Info>
+    <Name>{{.Name}}</Name>
+    <OperatingSystemSection ovf:id="1">
+      <Info>The kind of installed guest operating system</Info>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>
+        <vssd:InstanceID>0</vssd:InstanceID>
+        <vssd:VirtualSystemIdentifier>{{.Name}}</vssd:VirtualSystemIdentifier>
+        <vssd:VirtualSystemType>vmx-14</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:Description>Number of Virtual CPUs</rasd:Description>
+        <rasd:ElementName>{{.CPUs}} virtual CPU(s)</rasd:ElementName>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.CPUs}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:Description>Memory Size</rasd:Description>
+        <rasd:ElementName>{{.MemoryMiB}}MB of memory</rasd:ElementName>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>{{.MemoryMiB}}</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:Address>0</rasd:Address>
+        <rasd:Description>SCSI Controller</rasd:Description>
+        <rasd:ElementName>SCSI Controller 0</rasd:ElementName>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>lsilogic</rasd:ResourceSubType>
+        <rasd:ResourceType>6</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:ElementName>Hard Disk 1</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>4</rasd:InstanceID>
+        <rasd:Parent>3</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+{{- range $i, $nic := .NICs}}
+      <Item>
+        {{- if $nic.HWAddr}}
+        <rasd:Address>{{$nic.HWAddr}}</rasd:Address>
+        {{- end}}
+        <rasd:AddressOnParent>{{$i}}</rasd:AddressOnParent>
+        <rasd:AutomaticAllocation>true</rasd:AutomaticAllocation>
+        <rasd:Connection>{{$nic.Network}}</rasd:Connection>
+        <rasd:ElementName>Network adapter {{$i}}</rasd:ElementName>
+        <rasd:InstanceID>{{$nic.ID}}</rasd:InstanceID>
+        <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+{{- end}}
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`))
+
+// ovfNIC represents a network interface in the OVF descriptor.
+type ovfNIC struct {
+	ID      int
+	Network string
+	HWAddr  string
+}
+
+// backupWriteDisk exports the root disk of a stopped virtual machine to target, either as a qcow2 disk image or
+// as an OVA appliance made of an OVF descriptor, a streamOptimized VMDK disk and a manifest of their checksums.
+func backupWriteDisk(inst instance.Instance, pool storagePools.Pool, format string, target string) error {
+	if inst.IsRunning() {
+		return fmt.Errorf("The instance must be stopped to be exported as %s", format)
+	}
+
+	mountInfo, err := pool.MountInstance(inst, nil)
+	if err != nil {
+		return err
+	}
+	defer pool.UnmountInstance(inst, nil)
+
+	if mountInfo.DiskPath == "" {
+		return fmt.Errorf("No disk path available from mount")
+	}
+
+	if format == backupFormatQcow2 {
+		_, err = shared.RunCommand("qemu-img", "convert", "-c", "-O", "qcow2", mountInfo.DiskPath, target)
+		if err != nil {
+			return errors.Wrap(err, "Failed converting disk to qcow2")
+		}
+
+		return nil
+	}
+
+	tmpPath, err := ioutil.TempDir(shared.VarPath("backups"), "lxd_export_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpPath)
+
+	// Disk.
+	diskFile := fmt.Sprintf("%s-disk1.vmdk", inst.Name())
+	_, err = shared.RunCommand("qemu-img", "convert", "-O", "vmdk", "-o", "subformat=streamOptimized", mountInfo.DiskPath, filepath.Join(tmpPath, diskFile))
+	if err != nil {
+		return errors.Wrap(err, "Failed converting disk to vmdk")
+	}
+
+	diskInfo, err := os.Stat(filepath.Join(tmpPath, diskFile))
+	if err != nil {
+		return err
+	}
+
+	imgJSON, err := shared.RunCommand("qemu-img", "info", "--output=json", filepath.Join(tmpPath, diskFile))
+	if err != nil {
+		return errors.Wrap(err, "Failed reading disk size")
+	}
+
+	imgInfo := struct {
+		VirtualSize int64 `json:"virtual-size"`
+	}{}
+
+	err = json.Unmarshal([]byte(imgJSON), &imgInfo)
+	if err != nil {
+		return errors.Wrap(err, "Failed parsing disk size")
+	}
+
+	// Descriptor.
+	descriptor, err := backupOVFDescriptor(inst, diskFile, diskInfo.Size(), imgInfo.VirtualSize)
+	if err != nil {
+		return err
+	}
+
+	descriptorFile := fmt.Sprintf("%s.ovf", inst.Name())
+	err = ioutil.WriteFile(filepath.Join(tmpPath, descriptorFile), descriptor, 0600)
+	if err != nil {
+		return err
+	}
+
+	// Manifest.
+	manifest := &bytes.Buffer{}
+	for _, name := range []string{descriptorFile, diskFile} {
+		hash, err := backupFileSHA256(filepath.Join(tmpPath, name))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(manifest, "SHA256(%s)= %s\n", name, hash)
+	}
+
+	manifestFile := fmt.Sprintf("%s.mf", inst.Name())
+	err = ioutil.WriteFile(filepath.Join(tmpPath, manifestFile), manifest.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	// The OVA is a tarball starting with the descriptor.
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, name := range []string{descriptorFile, manifestFile, diskFile} {
+		err = backupTarAddFile(tw, filepath.Join(tmpPath, name), name)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// backupOVFDescriptor generates the OVF descriptor of the virtual machine, with its CPU, memory and network
+// interfaces.
+func backupOVFDescriptor(inst instance.Instance, diskFile string, diskFileSize int64, diskCapacity int64) ([]byte, error) {
+	config := inst.ExpandedConfig()
+	devices := inst.ExpandedDevices()
+
+	cpus := 1
+	if config["limits.cpu"] != "" {
+		count, err := strconv.Atoi(config["limits.cpu"])
+		if err != nil {
+			cpuset, err := resources.ParseCpuset(config["limits.cpu"])
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed parsing limits.cpu")
+			}
+
+			count = len(cpuset)
+		}
+
+		cpus = count
+	}
+
+	memory := int64(1024)
+	if config["limits.memory"] != "" && !strings.HasSuffix(config["limits.memory"], "%") {
+		size, err := units.ParseByteSizeString(config["limits.memory"])
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed parsing limits.memory")
+		}
+
+		memory = size / 1024 / 1024
+	}
+
+	nicNames := []string{}
+	for name, dev := range devices {
+		if dev["type"] == "nic" {
+			nicNames = append(nicNames, name)
+		}
+	}
+
+	sort.Strings(nicNames)
+
+	nics := []ovfNIC{}
+	networks := []string{}
+	for i, name := range nicNames {
+		dev := devices[name]
+
+		network := dev["network"]
+		if network == "" {
+			network = dev["parent"]
+		}
+
+		if network == "" {
+			network = name
+		}
+
+		if !shared.StringInSlice(network, networks) {
+			networks = append(networks, network)
+		}
+
+		hwaddr := dev["hwaddr"]
+		if hwaddr == "" {
+			hwaddr = config[fmt.Sprintf("volatile.%s.hwaddr", name)]
+		}
+
+		nics = append(nics, ovfNIC{
+			ID:      5 + i,
+			Network: network,
+			HWAddr:  hwaddr,
+		})
+	}
+
+	descriptor := &bytes.Buffer{}
+	err := ovfTemplate.Execute(descriptor, map[string]interface{}{
+		"Name":         inst.Name(),
+		"DiskFile":     diskFile,
+		"DiskFileSize": diskFileSize,
+		"DiskCapacity": diskCapacity,
+		"CPUs":         cpus,
+		"MemoryMiB":    memory,
+		"Networks":     networks,
+		"NICs":         nics,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return descriptor.Bytes(), nil
+}
+
+// backupFileSHA256 returns the SHA256 checksum of the file.
+func backupFileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// backupTarAddFile adds the file at path to the tarball under the given name.
+func backupTarAddFile(tw *tar.Writer, path string, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// OVA requires the USTAR format.
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime().Truncate(time.Second),
+		Format:   tar.FormatUSTAR,
+	}
+
+	err = tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -559,3 +559,18 @@ func (cg *CGroup) SetCpuset(limit string) error {
 
 	return ErrUnknownVersion
 }
+
+// SetCpusetMems set the currently allowed set of memory nodes for the cgroups
+func (cg *CGroup) SetCpusetMems(limit string) error {
+	version := cgControllers["cpuset"]
+	switch version {
+	case Unavailable:
+		return ErrControllerMissing
+	case V1:
+		return cg.rw.Set(version, "cpuset", "cpuset.mems", limit)
+	case V2:
+		return cg.rw.Set(version, "cpuset", "cpuset.mems", limit)
+	}
+
+	return ErrUnknownVersion
+}
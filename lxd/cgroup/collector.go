@@ -0,0 +1,116 @@
+package cgroup
+
+import (
+	"sync"
+	"time"
+)
+
+// collectorExpiry is the number of intervals after which cgroups which aren't requested anymore stop being
+// refreshed by the collector.
+const collectorExpiry = 10
+
+// Stats represents the resource usage of a cgroup. Values which couldn't be read are set to -1.
+type Stats struct {
+	CPUUsage        int64
+	MemoryUsage     int64
+	MemoryUsagePeak int64
+	SwapUsage       int64
+	SwapUsagePeak   int64
+	Processes       int64
+}
+
+// Collector caches the resource usage of a set of cgroups. The cached values are refreshed at most once per
+// interval, in a single pass over all the cgroups which were recently requested, so that the cost of reading
+// cgroupfs doesn't grow with the number of requests.
+type Collector struct {
+	interval time.Duration
+	entries  map[string]*collectorEntry
+	lastPass time.Time
+	mu       sync.Mutex
+}
+
+type collectorEntry struct {
+	cg       *CGroup
+	stats    Stats
+	lastUsed time.Time
+}
+
+// NewCollector returns a new collector refreshing the usage of the cgroups at most once per interval.
+func NewCollector(interval time.Duration) *Collector {
+	return &Collector{
+		interval: interval,
+		entries:  map[string]*collectorEntry{},
+	}
+}
+
+// Get returns the resource usage of the cgroup identified by the key, calling load to get a reader for it the first
+// time it's requested. The reader is kept for as long as the key is requested, so the key must change along with
+// the cgroup (for example by including the PID of the instance). If the collector is nil, the usage is read
+// directly.
+func (c *Collector) Get(key string, load func() (*CGroup, error)) (Stats, error) {
+	if c == nil {
+		cg, err := load()
+		if err != nil {
+			return Stats{}, err
+		}
+
+		return readStats(cg), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		cg, err := load()
+		if err != nil {
+			return Stats{}, err
+		}
+
+		entry = &collectorEntry{cg: cg, stats: readStats(cg)}
+		c.entries[key] = entry
+	} else if now.Sub(c.lastPass) >= c.interval {
+		c.refresh(now)
+	}
+
+	entry.lastUsed = now
+
+	return entry.stats, nil
+}
+
+// refresh reads the usage of all the cgroups which were requested recently and forgets about the other ones.
+func (c *Collector) refresh(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.lastUsed) > collectorExpiry*c.interval {
+			delete(c.entries, key)
+			continue
+		}
+
+		entry.stats = readStats(entry.cg)
+	}
+
+	c.lastPass = now
+}
+
+// readStats reads the resource usage of a cgroup.
+func readStats(cg *CGroup) Stats {
+	return Stats{
+		CPUUsage:        readStat(cg.GetCPUAcctUsage),
+		MemoryUsage:     readStat(cg.GetMemoryUsage),
+		MemoryUsagePeak: readStat(cg.GetMemoryMaxUsage),
+		SwapUsage:       readStat(cg.GetMemorySwapUsage),
+		SwapUsagePeak:   readStat(cg.GetMemorySwapMaxUsage),
+		Processes:       readStat(cg.GetProcessesUsage),
+	}
+}
+
+func readStat(get func() (int64, error)) int64 {
+	value, err := get()
+	if err != nil {
+		return -1
+	}
+
+	return value
+}
@@ -62,6 +62,36 @@ func NewFileReadWriter(pid int, unifiedCapable bool) (*CGroup, error) {
 	return cg, nil
 }
 
+// NewContainerFileReadWriter returns a CGroup instance using the filesystem as its backend for the cgroup of the
+// named liblxc container, located through the cgroup of one of its processes. Cgroups nested within the one of the
+// container (such as the init.scope of systemd) are ignored so the usage of the whole container is reported.
+func NewContainerFileReadWriter(pid int, name string, unifiedCapable bool) (*CGroup, error) {
+	cg, err := NewFileReadWriter(pid, unifiedCapable)
+	if err != nil {
+		return nil, err
+	}
+
+	rw := cg.rw.(*fileReadWriter)
+	for ctrl, path := range rw.paths {
+		rw.paths[ctrl] = containerPath(path, name)
+	}
+
+	return cg, nil
+}
+
+// containerPath returns the given cgroup path truncated after the cgroup of the named liblxc container. Both the
+// current (lxc.payload.NAME) and the older (lxc/NAME and lxc.payload/NAME) layouts are handled.
+func containerPath(path string, name string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == fmt.Sprintf("lxc.payload.%s", name) || (part == name && i > 0 && (parts[i-1] == "lxc" || parts[i-1] == "lxc.payload")) {
+			return strings.Join(parts[:i+1], "/")
+		}
+	}
+
+	return path
+}
+
 type fileReadWriter struct {
 	paths map[string]string
 }
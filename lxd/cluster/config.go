@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -39,6 +41,26 @@ func ConfigLoad(tx *db.ClusterTx) (*Config, error) {
 	return &Config{tx: tx, m: m}, nil
 }
 
+// MemberInt64 returns the value of the given key for the local member, which is
+// the one set on its failure domain if any or the cluster-wide one otherwise.
+func (c *Config) MemberInt64(key string) (int64, error) {
+	domain, err := c.tx.GetNodeFailureDomain(c.tx.GetNodeID())
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to load failure domain of local member")
+	}
+
+	overrides, err := c.tx.GetFailureDomainConfig(domain)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to load config of failure domain %q", domain)
+	}
+
+	if overrides[key] != "" {
+		return strconv.ParseInt(overrides[key], 10, 64)
+	}
+
+	return c.m.GetInt64(key), nil
+}
+
 // HTTPSAllowedHeaders returns the relevant CORS setting.
 func (c *Config) HTTPSAllowedHeaders() string {
 	return c.m.GetString("core.https_allowed_headers")
@@ -89,6 +111,24 @@ func (c *Config) RBACServer() (string, string, int64, string, string, string, st
 		c.m.GetString("rbac.agent.public_key")
 }
 
+// AuthorizationWebhook returns the URL and bearer token of the authorization webhook, and how long its decisions
+// are cached for.
+func (c *Config) AuthorizationWebhook() (string, string, time.Duration) {
+	return c.m.GetString("authorization.webhook.url"),
+		c.m.GetString("authorization.webhook.token"),
+		time.Duration(c.m.GetInt64("authorization.webhook.expiry")) * time.Second
+}
+
+// ACME returns all the ACME settings needed to obtain a certificate for the REST API: the domain, contact
+// email, directory URL, challenge type and DNS hook.
+func (c *Config) ACME() (string, string, string, string, string) {
+	return c.m.GetString("acme.domain"),
+		c.m.GetString("acme.email"),
+		c.m.GetString("acme.ca_url"),
+		c.m.GetString("acme.challenge"),
+		c.m.GetString("acme.dns_hook")
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -148,6 +188,25 @@ func (c *Config) ShutdownTimeout() time.Duration {
 	return time.Duration(n) * time.Minute
 }
 
+// InstancesShutdownParallelism returns the maximum number of instances to stop at the same time when the
+// LXD server shuts down (0 means no limit).
+func (c *Config) InstancesShutdownParallelism() int64 {
+	return c.m.GetInt64("instances.shutdown_parallelism")
+}
+
+// InstancesShutdownTimeout returns how long to wait for instances which don't set boot.host_shutdown_timeout to
+// shutdown cleanly when the LXD server shuts down.
+func (c *Config) InstancesShutdownTimeout() time.Duration {
+	n := c.m.GetInt64("instances.shutdown_timeout")
+	return time.Duration(n) * time.Second
+}
+
+// UsageRetention returns how long the resource usage history of the projects is kept (0 means forever).
+func (c *Config) UsageRetention() time.Duration {
+	n := c.m.GetInt64("usage.retention")
+	return time.Duration(n) * 24 * time.Hour
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -225,6 +284,27 @@ func ConfigGetInt64(cluster *db.Cluster, key string) (int64, error) {
 	return config.m.GetInt64(key), nil
 }
 
+// MemberConfigGetInt64 is a convenience for loading the cluster configuration and
+// returning the value of a particular key for the local member, taking the
+// overrides of its failure domain into account.
+func MemberConfigGetInt64(cluster *db.Cluster, key string) (int64, error) {
+	var value int64
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		value, err = config.MemberInt64(key)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
 func configGet(cluster *db.Cluster) (*Config, error) {
 	var config *Config
 	err := cluster.Transaction(func(tx *db.ClusterTx) error {
@@ -235,43 +315,91 @@ func configGet(cluster *db.Cluster) (*Config, error) {
 	return config, err
 }
 
+// FailureDomainConfigKeys are the server configuration keys which can be
+// overridden per failure domain.
+var FailureDomainConfigKeys = []string{
+	"images.auto_update_interval",
+	"scheduler.cpu_rebalance_interval",
+	"scheduler.memory_pressure_interval",
+	"scheduler.memory_pressure_threshold",
+}
+
+// ValidateFailureDomainConfig checks that the config only overrides keys
+// which can be set per failure domain, with valid values.
+func ValidateFailureDomainConfig(config map[string]string) error {
+	for k, v := range config {
+		found := false
+		for _, key := range FailureDomainConfigKeys {
+			if k == key {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Key %q can't be set per failure domain", k)
+		}
+
+		err := validate.Optional(validate.IsInt64)(v)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid value for key %q", k)
+		}
+	}
+
+	return nil
+}
+
 // ConfigSchema defines available server configuration keys.
 var ConfigSchema = config.Schema{
-	"backups.compression_algorithm":  {Default: "gzip", Validator: validate.IsCompressionAlgorithm},
-	"cluster.offline_threshold":      {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
-	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
-	"cluster.max_voters":             {Type: config.Int64, Default: "3", Validator: maxVotersValidator},
-	"cluster.max_standby":            {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
-	"core.https_allowed_headers":     {},
-	"core.https_allowed_methods":     {},
-	"core.https_allowed_origin":      {},
-	"core.https_allowed_credentials": {Type: config.Bool},
-	"core.https_trusted_proxy":       {},
-	"core.proxy_http":                {},
-	"core.proxy_https":               {},
-	"core.proxy_ignore_hosts":        {},
-	"core.shutdown_timeout":          {Type: config.Int64, Default: "5"},
-	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
-	"core.trust_ca_certificates":     {Type: config.Bool},
-	"candid.api.key":                 {},
-	"candid.api.url":                 {},
-	"candid.domains":                 {},
-	"candid.expiry":                  {Type: config.Int64, Default: "3600"},
-	"images.auto_update_cached":      {Type: config.Bool, Default: "true"},
-	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
-	"images.compression_algorithm":   {Default: "gzip", Validator: validate.IsCompressionAlgorithm},
-	"images.default_architecture":    {Validator: validate.Optional(validate.IsArchitecture)},
-	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
-	"maas.api.key":                   {},
-	"maas.api.url":                   {},
-	"rbac.agent.url":                 {},
-	"rbac.agent.username":            {},
-	"rbac.agent.private_key":         {},
-	"rbac.agent.public_key":          {},
-	"rbac.api.expiry":                {Type: config.Int64, Default: "3600"},
-	"rbac.api.key":                   {},
-	"rbac.api.url":                   {},
-	"rbac.expiry":                    {Type: config.Int64, Default: "3600"},
+	"acme.ca_url":                         {Default: "https://acme-v02.api.letsencrypt.org/directory"},
+	"acme.challenge":                      {Default: "HTTP-01", Validator: validate.IsOneOf("HTTP-01", "DNS-01")},
+	"acme.dns_hook":                       {Validator: validate.Optional(acmeDNSHookValidator)},
+	"acme.domain":                         {Validator: validate.Optional(validate.IsHostname)},
+	"acme.email":                          {},
+	"authorization.webhook.expiry":        {Type: config.Int64, Default: "60", Validator: validate.IsUint32},
+	"authorization.webhook.token":         {Hidden: true},
+	"authorization.webhook.url":           {Validator: validate.Optional(authorizationWebhookURLValidator)},
+	"backups.compression_algorithm":       {Default: "gzip", Validator: validate.IsCompressionAlgorithm},
+	"cluster.offline_threshold":           {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
+	"cluster.images_minimal_replica":      {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
+	"cluster.max_voters":                  {Type: config.Int64, Default: "3", Validator: maxVotersValidator},
+	"cluster.max_standby":                 {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
+	"core.https_allowed_headers":          {},
+	"core.https_allowed_methods":          {},
+	"core.https_allowed_origin":           {},
+	"core.https_allowed_credentials":      {Type: config.Bool},
+	"core.https_trusted_proxy":            {},
+	"core.proxy_http":                     {},
+	"core.proxy_https":                    {},
+	"core.proxy_ignore_hosts":             {},
+	"core.shutdown_timeout":               {Type: config.Int64, Default: "5"},
+	"core.trust_password":                 {Hidden: true, Setter: passwordSetter},
+	"core.trust_ca_certificates":          {Type: config.Bool},
+	"candid.api.key":                      {},
+	"candid.api.url":                      {},
+	"candid.domains":                      {},
+	"candid.expiry":                       {Type: config.Int64, Default: "3600"},
+	"images.auto_update_cached":           {Type: config.Bool, Default: "true"},
+	"images.auto_update_interval":         {Type: config.Int64, Default: "6"},
+	"images.compression_algorithm":        {Default: "gzip", Validator: validate.IsCompressionAlgorithm},
+	"images.default_architecture":         {Validator: validate.Optional(validate.IsArchitecture)},
+	"images.remote_cache_expiry":          {Type: config.Int64, Default: "10"},
+	"instances.shutdown_parallelism":      {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+	"instances.shutdown_timeout":          {Type: config.Int64, Default: "30", Validator: validate.IsUint32},
+	"maas.api.key":                        {},
+	"maas.api.url":                        {},
+	"rbac.agent.url":                      {},
+	"rbac.agent.username":                 {},
+	"rbac.agent.private_key":              {},
+	"rbac.agent.public_key":               {},
+	"rbac.api.expiry":                     {Type: config.Int64, Default: "3600"},
+	"rbac.api.key":                        {},
+	"rbac.api.url":                        {},
+	"rbac.expiry":                         {Type: config.Int64, Default: "3600"},
+	"scheduler.cpu_rebalance_interval":    {Type: config.Int64, Default: "0"},
+	"scheduler.memory_pressure_interval":  {Type: config.Int64, Default: "0"},
+	"scheduler.memory_pressure_threshold": {Type: config.Int64, Default: "10"},
+	"usage.retention":                     {Type: config.Int64, Default: "365", Validator: validate.IsUint32},
 
 	// Keys deprecated since the implementation of the storage api.
 	"storage.lvm_fstype":           {Setter: deprecatedStorage, Default: "ext4"},
@@ -288,6 +416,23 @@ var ConfigSchema = config.Schema{
 	"network.ovn.northbound_connection": {Default: "unix:/var/run/ovn/ovnnb_db.sock"},
 }
 
+func acmeDNSHookValidator(value string) error {
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("Value must be an absolute path")
+	}
+
+	return nil
+}
+
+func authorizationWebhookURLValidator(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Value must be an HTTP or HTTPS URL")
+	}
+
+	return nil
+}
+
 func offlineThresholdDefault() string {
 	return strconv.Itoa(db.DefaultOfflineThreshold)
 }
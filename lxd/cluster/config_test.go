@@ -121,3 +121,36 @@ func TestConfig_PatchKeepsValues(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, map[string]string{"core.proxy_http": "foo.bar"}, values)
 }
+
+// The config of the failure domain of the local member overrides the cluster-wide one.
+func TestConfig_MemberInt64(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	err := tx.UpdateConfig(map[string]string{"images.auto_update_interval": "12"})
+	require.NoError(t, err)
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	value, err := config.MemberInt64("images.auto_update_interval")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), value)
+
+	err = tx.UpdateFailureDomainConfig("default", map[string]string{"images.auto_update_interval": "0"})
+	require.NoError(t, err)
+
+	value, err = config.MemberInt64("images.auto_update_interval")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), value)
+
+	value, err = config.MemberInt64("scheduler.memory_pressure_threshold")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), value)
+}
+
+func TestValidateFailureDomainConfig(t *testing.T) {
+	assert.NoError(t, cluster.ValidateFailureDomainConfig(map[string]string{"images.auto_update_interval": "0", "scheduler.cpu_rebalance_interval": ""}))
+	assert.Error(t, cluster.ValidateFailureDomainConfig(map[string]string{"core.proxy_http": "foo"}))
+	assert.Error(t, cluster.ValidateFailureDomainConfig(map[string]string{"images.auto_update_interval": "soon"}))
+}
@@ -55,6 +55,9 @@ func Connect(address string, networkCert *shared.CertInfo, serverCert *shared.Ce
 		}
 	}
 
+	// Pin the certificate the member presents while the cluster certificate is being rotated.
+	networkCert = tlsPeerNetworkCert(address, networkCert, serverCert)
+
 	args := &lxd.ConnectionArgs{
 		TLSServerCert: string(networkCert.PublicKey()),
 		TLSClientCert: string(serverCert.PublicKey()),
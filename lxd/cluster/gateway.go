@@ -606,6 +606,36 @@ func (g *Gateway) Sync() {
 	}
 }
 
+// DumpDatabase writes the files of the global database, as currently held by the leader, into the given
+// directory.
+func (g *Gateway) DumpDatabase(dir string) error {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := client.FindLeader(ctx, g.NodeStore(), client.WithDialFunc(g.raftDial()), client.WithLogFunc(DqliteLog))
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to cluster leader")
+	}
+	defer client.Close()
+
+	files, err := client.Dump(ctx, "db.bin")
+	if err != nil {
+		return errors.Wrap(err, "Failed to dump the global database")
+	}
+
+	for _, file := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, file.Name), file.Data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (g *Gateway) getClient() (*client.Client, error) {
 	return client.New(context.Background(), g.bindAddress)
 }
@@ -1,10 +1,14 @@
 package cluster
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
@@ -48,9 +52,104 @@ func tlsClientConfig(networkCert *shared.CertInfo, serverCert *shared.CertInfo)
 		config.ServerName = netCert.DNSNames[0]
 	}
 
+	// While the cluster certificate is being rotated, members may present either the current or the transition
+	// certificate, which may not share the same DNS names. Pin both certificates instead.
+	transitionCert := TransitionNetworkCert()
+	if transitionCert != nil {
+		tlsPinCerts(config, networkCert, transitionCert)
+	}
+
 	return config, nil
 }
 
+// transitionNetworkCert is the certificate accepted from other members alongside the cluster certificate while
+// it's being rotated.
+var transitionNetworkCert *shared.CertInfo
+var transitionNetworkCertMu sync.Mutex
+
+// SetTransitionNetworkCert sets the certificate accepted from other members alongside the cluster certificate
+// while it's being rotated (nil once the rotation is over).
+func SetTransitionNetworkCert(cert *shared.CertInfo) {
+	transitionNetworkCertMu.Lock()
+	defer transitionNetworkCertMu.Unlock()
+
+	transitionNetworkCert = cert
+}
+
+// TransitionNetworkCert returns the certificate accepted from other members alongside the cluster certificate,
+// if a rotation is in progress.
+func TransitionNetworkCert() *shared.CertInfo {
+	transitionNetworkCertMu.Lock()
+	defer transitionNetworkCertMu.Unlock()
+
+	return transitionNetworkCert
+}
+
+// tlsPinCerts makes the TLS configuration only accept the given certificates from the server.
+func tlsPinCerts(config *tls.Config, certs ...*shared.CertInfo) {
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("No certificate presented by the server")
+		}
+
+		for _, cert := range certs {
+			if bytes.Equal(rawCerts[0], cert.KeyPair().Certificate[0]) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Server certificate doesn't match the cluster certificate")
+	}
+}
+
+// tlsPeerNetworkCert returns which of the cluster and transition certificates the member at the given address
+// presents, so that it can be pinned. Returns the cluster certificate if no rotation is in progress.
+func tlsPeerNetworkCert(address string, networkCert *shared.CertInfo, serverCert *shared.CertInfo) *shared.CertInfo {
+	transitionCert := TransitionNetworkCert()
+	if transitionCert == nil {
+		return networkCert
+	}
+
+	config, err := tlsClientConfig(networkCert, serverCert)
+	if err != nil {
+		return networkCert
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, config)
+	if err != nil {
+		return networkCert
+	}
+
+	defer conn.Close()
+
+	peerCerts := conn.ConnectionState().PeerCertificates
+	if len(peerCerts) > 0 && bytes.Equal(peerCerts[0].Raw, transitionCert.KeyPair().Certificate[0]) {
+		return transitionCert
+	}
+
+	return networkCert
+}
+
+// VerifyNetworkCert checks that the member at the given address presents the given cluster certificate.
+func VerifyNetworkCert(address string, networkCert *shared.CertInfo, serverCert *shared.CertInfo) error {
+	config, err := tlsClientConfig(networkCert, serverCert)
+	if err != nil {
+		return err
+	}
+
+	tlsPinCerts(config, networkCert)
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, config)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
 // tlsCheckCert checks certificate access, returns true if certificate is trusted.
 func tlsCheckCert(r *http.Request, networkCert *shared.CertInfo, serverCert *shared.CertInfo, trustedCerts map[db.CertificateType]map[string]x509.Certificate) bool {
 	_, err := x509.ParseCertificate(networkCert.KeyPair().Certificate[0])
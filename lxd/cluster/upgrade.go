@@ -121,6 +121,24 @@ func triggerUpdate() error {
 	return nil
 }
 
+// RunUpdateHook runs LXD_CLUSTER_UPDATE right away, as requested when the cluster upgrade is orchestrated. It
+// returns once the executable exits, unless LXD gets restarted by it first.
+func RunUpdateHook() error {
+	updateExecutable := os.Getenv("LXD_CLUSTER_UPDATE")
+	if updateExecutable == "" {
+		return fmt.Errorf("No LXD_CLUSTER_UPDATE variable set, the member can't be upgraded")
+	}
+
+	logger.Infof("Triggering cluster update using: %s", updateExecutable)
+
+	_, err := shared.RunCommand(updateExecutable)
+	if err != nil {
+		return errors.Wrap(err, "Cluster upgrade failed")
+	}
+
+	return nil
+}
+
 // UpgradeMembersWithoutRole assigns the Spare raft role to all cluster members
 // that are not currently part of the raft configuration. It's used for
 // upgrading a cluster from a version without roles support.
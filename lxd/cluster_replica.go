@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// clusterReplicaTask keeps the local replica of the global database fresh enough for the read-only API queries to
+// be served from it rather than through the leader. It is controlled through cluster.replica_staleness (0 disables
+// it), the replica being refreshed on demand when a read couldn't use it, at most once per half of the staleness
+// bound so that frequent changes don't have the leader dump the database over and over.
+func clusterReplicaTask(d *Daemon) (task.Func, task.Schedule) {
+	dir := filepath.Join(d.os.VarDir, "database", "replica")
+	first := true
+	var lastRefresh time.Time
+
+	f := func(ctx context.Context) {
+		// Remove the replicas left behind by a previous run.
+		if first {
+			first = false
+
+			err := os.RemoveAll(dir)
+			if err != nil {
+				logger.Warn("Failed to remove the global database replicas", log.Ctx{"err": err})
+			}
+		}
+
+		var staleness time.Duration
+		err := d.db.Transaction(func(tx *db.NodeTx) error {
+			config, err := node.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			staleness = time.Duration(config.ClusterReplicaStaleness()) * time.Second
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to load node config", log.Ctx{"err": err})
+			return
+		}
+
+		d.cluster.SetReplicaStaleness(staleness)
+		if staleness <= 0 || time.Since(lastRefresh) < staleness/2 || !d.cluster.ReplicaRefreshRequested() {
+			return
+		}
+
+		lastRefresh = time.Now()
+
+		err = clusterReplicaRefresh(d, dir)
+		if err != nil {
+			logger.Warn("Failed to refresh the global database replica", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Second)
+}
+
+// clusterReplicaRefresh dumps the global database from the leader and makes it the local replica.
+func clusterReplicaRefresh(d *Daemon, dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	path, err := ioutil.TempDir(dir, "")
+	if err != nil {
+		return err
+	}
+
+	synced := time.Now()
+	writes := d.cluster.ReplicaWrites()
+
+	err = d.gateway.DumpDatabase(path)
+	if err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+
+	err = d.cluster.UpdateReplica(path, synced, writes)
+	if err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var configPlanCmd = APIEndpoint{
+	Path: "plan",
+
+	Post: APIEndpointAction{Handler: configPlanPost},
+}
+
+// swagger:operation POST /1.0/plan server plan_post
+//
+// Plan a declarative configuration
+//
+// Returns the changes which "lxd apply-config" would make to bring the server to the given declarative
+// configuration (in the format of "lxd export-config"), without making them.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: body
+//     name: config
+//     description: Declarative server configuration
+//     required: true
+//     schema:
+//       type: object
+// responses:
+//   "200":
+//     description: Planned changes
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of changes, in the order they would be made
+//           items:
+//             $ref: "#/definitions/ConfigPlanAction"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func configPlanPost(d *Daemon, r *http.Request) response.Response {
+	config := initDataNode{}
+	err := json.NewDecoder(r.Body).Decode(&config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Connect to ourselves to compute the plan the same way "lxd apply-config" does.
+	client, err := lxd.ConnectLXDUnix(d.UnixSocket(), nil)
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to connect to local LXD"))
+	}
+
+	changes, err := configApplyPlan(client, config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	actions := make([]api.ConfigPlanAction, 0, len(changes))
+	for _, change := range changes {
+		actions = append(actions, change.planAction())
+	}
+
+	return response.SyncResponse(true, actions)
+}
@@ -0,0 +1,46 @@
+package consolelog
+
+import (
+	"sync"
+)
+
+// captures holds the functions capturing the console output of the running instances, keyed by log directory.
+var captures = map[string]func() error{}
+var capturesMu sync.Mutex
+
+// Track makes CaptureAll call capture for the instance using the given log directory, until Untrack is called.
+// It's meant to be called when the instance starts, if it has a console output to capture.
+func Track(logPath string, capture func() error) {
+	capturesMu.Lock()
+	defer capturesMu.Unlock()
+
+	captures[logPath] = capture
+}
+
+// Untrack stops capturing the console output of the instance using the given log directory.
+func Untrack(logPath string) {
+	capturesMu.Lock()
+	defer capturesMu.Unlock()
+
+	delete(captures, logPath)
+}
+
+// CaptureAll captures the console output of the tracked instances, returning the errors by log directory.
+func CaptureAll() map[string]error {
+	capturesMu.Lock()
+	funcs := make(map[string]func() error, len(captures))
+	for logPath, capture := range captures {
+		funcs[logPath] = capture
+	}
+	capturesMu.Unlock()
+
+	errs := map[string]error{}
+	for logPath, capture := range funcs {
+		err := capture()
+		if err != nil {
+			errs[logPath] = err
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,32 @@
+package consolelog
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureAll(t *testing.T) {
+	captured := []string{}
+
+	Track("/logs/c1", func() error {
+		captured = append(captured, "c1")
+		return nil
+	})
+
+	Track("/logs/c2", func() error {
+		return fmt.Errorf("Failed")
+	})
+
+	errs := CaptureAll()
+	assert.Equal(t, []string{"c1"}, captured)
+	assert.Len(t, errs, 1)
+	assert.Error(t, errs["/logs/c2"])
+
+	Untrack("/logs/c1")
+	Untrack("/logs/c2")
+
+	assert.Len(t, CaptureAll(), 0)
+	assert.Equal(t, []string{"c1"}, captured)
+}
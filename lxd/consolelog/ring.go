@@ -0,0 +1,186 @@
+// Package consolelog keeps the console output of instances in timestamped, size bounded logs which persist across
+// restarts of the daemon.
+package consolelog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// DefaultSize is the maximum size in bytes of the console log of an instance.
+const DefaultSize = 1024 * 1024
+
+// ringFile is the name of the console log in the log directory of an instance.
+const ringFile = "console.ring"
+
+// rings holds the console log of the instances, keyed by log directory, so they all share the same lock.
+var rings = map[string]*Ring{}
+var ringsMu sync.Mutex
+
+// Ring is a size bounded log of console output. It's made of two files, the oldest one being dropped once the
+// current one reaches half the size, so roughly between half and all of the size is kept at any time.
+type Ring struct {
+	path string
+	size int64
+	mu   sync.Mutex
+}
+
+// Get returns the console log stored in the given instance log directory.
+func Get(logPath string) *Ring {
+	ringsMu.Lock()
+	defer ringsMu.Unlock()
+
+	r, found := rings[logPath]
+	if !found {
+		r = NewRing(filepath.Join(logPath, ringFile), DefaultSize)
+		rings[logPath] = r
+	}
+
+	return r
+}
+
+// NewRing returns a console log stored at path and bounded to size bytes.
+func NewRing(path string, size int64) *Ring {
+	return &Ring{path: path, size: size}
+}
+
+// Write adds the output captured at the given time to the log.
+func (r *Ring) Write(t time.Time, output []byte) error {
+	if len(output) == 0 {
+		return nil
+	}
+
+	// Only keep the end of outputs too large to fit. The limit applies to the encoded line, which can be several
+	// times larger than the output once escaped, so that the lines can always be read back.
+	max := int(r.size / 4)
+	if len(output) > max {
+		output = output[len(output)-max:]
+	}
+
+	var line []byte
+	for {
+		var err error
+		line, err = json.Marshal(api.InstanceConsoleLogEntry{Time: t.UTC(), Output: string(output)})
+		if err != nil {
+			return err
+		}
+
+		if len(line) <= max || len(output) == 0 {
+			break
+		}
+
+		// Keep the share of the output which would fit if it was escaped evenly, then check again.
+		keep := len(output) * max / len(line)
+		if keep >= len(output) {
+			keep = len(output) - 1
+		}
+
+		output = output[len(output)-keep:]
+
+		// Don't start with the end of a multi-byte character, which would take more space once encoded.
+		for len(output) > 0 && !utf8.RuneStart(output[0]) {
+			output = output[1:]
+		}
+	}
+
+	if len(line) > max {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open console log %q", r.path)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write console log %q", r.path)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if fi.Size() >= r.size/2 {
+		err = os.Rename(r.path, r.path+".1")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to rotate console log %q", r.path)
+		}
+	}
+
+	return f.Close()
+}
+
+// Entries returns the entries of the log captured at or after since, oldest first.
+func (r *Ring) Entries(since time.Time) ([]api.InstanceConsoleLogEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []api.InstanceConsoleLogEntry{}
+
+	for _, path := range []string{r.path + ".1", r.path} {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, int(r.size))
+		for scanner.Scan() {
+			entry := api.InstanceConsoleLogEntry{}
+
+			// Skip entries which were only partially written.
+			err = json.Unmarshal(scanner.Bytes(), &entry)
+			if err != nil {
+				continue
+			}
+
+			if entry.Time.Before(since) {
+				continue
+			}
+
+			entries = append(entries, entry)
+		}
+
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read console log %q", path)
+		}
+	}
+
+	return entries, nil
+}
+
+// Clear removes all the entries of the log.
+func (r *Ring) Clear() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, path := range []string{r.path + ".1", r.path} {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package consolelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := NewRing(filepath.Join(dir, "console.ring"), 4096)
+
+	start := time.Now()
+	require.NoError(t, r.Write(start, []byte("first")))
+	require.NoError(t, r.Write(start.Add(time.Second), []byte("second")))
+
+	entries, err := r.Entries(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "first", entries[0].Output)
+	assert.Equal(t, "second", entries[1].Output)
+
+	entries, err = r.Entries(start.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Output)
+
+	require.NoError(t, r.Clear())
+
+	entries, err = r.Entries(time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestRingBounded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := NewRing(filepath.Join(dir, "console.ring"), 4096)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, r.Write(time.Now(), []byte(strings.Repeat("x", 100))))
+	}
+
+	size := int64(0)
+	for _, name := range []string{"console.ring", "console.ring.1"} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err == nil {
+			size += fi.Size()
+		}
+	}
+
+	assert.True(t, size <= 4096)
+
+	entries, err := r.Entries(time.Time{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+}
+
+// Outputs which grow a lot once escaped are trimmed so that their lines can still be read back.
+func TestRingEscapedOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := NewRing(filepath.Join(dir, "console.ring"), 4096)
+
+	// Control characters and invalid UTF-8 are escaped as \u0001 and �.
+	outputs := [][]byte{
+		[]byte(strings.Repeat("\x01", 4096)),
+		[]byte(strings.Repeat("\xff", 4096)),
+		[]byte(strings.Repeat("\"€\\", 2048)),
+	}
+
+	for _, output := range outputs {
+		require.NoError(t, r.Clear())
+		require.NoError(t, r.Write(time.Now(), output))
+
+		entries, err := r.Entries(time.Time{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.NotEmpty(t, entries[0].Output)
+		assert.True(t, len(entries[0].Output) < len(output))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "console.ring"))
+		require.NoError(t, err)
+		assert.True(t, len(data) <= 4096/4+1)
+	}
+
+	// The end of the output is kept, starting on a whole character.
+	require.NoError(t, r.Clear())
+	require.NoError(t, r.Write(time.Now(), []byte(strings.Repeat("€\x01", 1024)+"end")))
+
+	entries, err := r.Entries(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Output, "end"))
+	assert.True(t, strings.HasPrefix(entries[0].Output, "€") || strings.HasPrefix(entries[0].Output, "\x01"))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// cpuTimes holds the busy and total time spent by a CPU (in USER_HZ).
+type cpuTimes struct {
+	busy  uint64
+	total uint64
+}
+
+// cpuRebalanceTask periodically rebalances the instances which aren't pinned to specific CPUs based on the
+// observed per-CPU load. The interval is controlled through scheduler.cpu_rebalance_interval (0 disables it).
+func cpuRebalanceTask(d *Daemon) (task.Func, task.Schedule) {
+	var previous map[int64]cpuTimes
+
+	f := func(ctx context.Context) {
+		current, err := cpuRebalanceTimes()
+		if err != nil {
+			logger.Error("Failed to read CPU usage", log.Ctx{"err": err})
+			return
+		}
+
+		// The first run only records the baseline usage.
+		if previous == nil {
+			previous = current
+			return
+		}
+
+		load := cpuRebalanceLoad(previous, current)
+		previous = current
+
+		opRun := func(op *operations.Operation) error {
+			s := d.State()
+
+			changes := deviceTaskBalanceInstances(s, load)
+			for _, change := range changes {
+				logger.Info("Rebalanced instance CPUs", log.Ctx{"project": change.inst.Project(), "instance": change.inst.Name(), "old": change.old, "new": change.new})
+
+				ctx := map[string]interface{}{"old_cpus": change.old, "new_cpus": change.new}
+				s.Events.SendLifecycle(change.inst.Project(), lifecycle.InstanceCPURebalanced.Event(change.inst, ctx))
+			}
+
+			return nil
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationInstancesCPURebalance, nil, nil, opRun, nil, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start CPU rebalance operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Debug("Rebalancing instances CPU usage")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to rebalance instances CPU usage", log.Ctx{"err": err})
+		}
+		logger.Debug("Done rebalancing instances CPU usage")
+	}
+
+	schedule := func() (time.Duration, error) {
+		interval, err := cluster.MemberConfigGetInt64(d.cluster, "scheduler.cpu_rebalance_interval")
+		if err != nil {
+			return 0, err
+		}
+
+		// Drop the stale baseline so that re-enabling the task starts from fresh data.
+		if interval <= 0 {
+			previous = nil
+			return 0, nil
+		}
+
+		return time.Duration(interval) * time.Minute, nil
+	}
+
+	return f, schedule
+}
+
+// cpuRebalanceTimes returns the busy and total time of each CPU as reported by /proc/stat.
+func cpuRebalanceTimes() (map[int64]cpuTimes, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open /proc/stat")
+	}
+	defer f.Close()
+
+	times := map[int64]cpuTimes{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "cpu"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entry := cpuTimes{}
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse usage of CPU %d", id)
+			}
+
+			entry.total += value
+
+			// Idle (3) and iowait (4) don't count as busy time.
+			if i != 3 && i != 4 {
+				entry.busy += value
+			}
+		}
+
+		times[id] = entry
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read /proc/stat")
+	}
+
+	return times, nil
+}
+
+// cpuRebalanceLoad computes the load of each CPU (between 0 and 1) between two samples.
+func cpuRebalanceLoad(previous map[int64]cpuTimes, current map[int64]cpuTimes) map[int64]float64 {
+	load := map[int64]float64{}
+	for id, cur := range current {
+		prev, ok := previous[id]
+		if !ok || cur.total <= prev.total || cur.busy < prev.busy {
+			continue
+		}
+
+		load[id] = float64(cur.busy-prev.busy) / float64(cur.total-prev.total)
+	}
+
+	return load
+}
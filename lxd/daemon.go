@@ -30,6 +30,8 @@ import (
 	"gopkg.in/macaroon-bakery.v2/bakery/identchecker"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 
+	"github.com/lxc/lxd/lxd/auth"
+	"github.com/lxc/lxd/lxd/cgroup"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
@@ -47,6 +49,7 @@ import (
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/seccomp"
 	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/lxd/storage/filesystem"
 	"github.com/lxc/lxd/lxd/sys"
@@ -62,6 +65,9 @@ import (
 	log "github.com/lxc/lxd/shared/log15"
 )
 
+// instanceStatsInterval is how often the resource usage of the instance cgroups is refreshed at most.
+const instanceStatsInterval = 5 * time.Second
+
 // A Daemon can respond to requests from a shared client.
 type Daemon struct {
 	clientCerts  *certificateCache
@@ -70,10 +76,14 @@ type Daemon struct {
 	firewall     firewall.Firewall
 	maas         *maas.Controller
 	rbac         *rbac.Server
+	authorizer   *auth.WebhookAuthorizer // Protected by authorizerMu as replaced on config changes
+	authorizerMu sync.RWMutex
 	cluster      *db.Cluster
 	setupChan    chan struct{} // Closed when basic Daemon setup is completed
 	readyChan    chan struct{} // Closed when LXD is fully ready
 	shutdownChan chan struct{}
+	reloadChan   chan struct{} // Filled when a reload is requested
+	handingOff   bool          // Whether the endpoints are being handed off to the next process on reload
 
 	// Event servers
 	devlxdEvents *events.Server
@@ -87,10 +97,17 @@ type Daemon struct {
 	// Indexes of tasks that need to be reset when their execution interval changes
 	taskPruneImages      *task.Task
 	taskClusterHeartbeat *task.Task
+	taskCPURebalance     *task.Task
+	taskMemoryPressure   *task.Task
+	taskDatabaseBackup   *task.Task
+	taskACMERenew        *task.Task
 
 	// Stores startup time of daemon
 	startTime time.Time
 
+	// Cached resource usage of the instance cgroups
+	instanceStats *cgroup.Collector
+
 	config    *DaemonConfig
 	endpoints *endpoints.Endpoints
 	gateway   *cluster.Gateway
@@ -176,8 +193,11 @@ func newDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 		setupChan:    make(chan struct{}),
 		readyChan:    make(chan struct{}),
 		shutdownChan: make(chan struct{}),
+		reloadChan:   make(chan struct{}, 1),
 		ctx:          ctx,
 		cancel:       cancel,
+
+		instanceStats: cgroup.NewCollector(instanceStatsInterval),
 	}
 
 	d.serverCert = func() *shared.CertInfo { return d.serverCertInt }
@@ -422,6 +442,7 @@ func (d *Daemon) State() *state.State {
 		ServerCert:             d.serverCert,
 		UpdateCertificateCache: func() { updateCertificateCache(d) },
 		InstanceTypes:          supportedInstanceTypes,
+		InstanceStats:          d.instanceStats,
 	}
 }
 
@@ -620,6 +641,27 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 				return response.NotImplemented(nil)
 			}
 
+			// Ask the authorization webhook, if any, about the authenticated remote requests.
+			d.authorizerMu.RLock()
+			authorizer := d.authorizer
+			d.authorizerMu.RUnlock()
+
+			if authorizer != nil && trusted && version == "1.0" && !shared.StringInSlice(protocol, []string{"unix", "cluster"}) {
+				allowed, reason, err := authorizer.Authorize(auth.NewRequest(r, username, protocol, projectParam(r)))
+				if err != nil {
+					logger.Warn("Rejecting request as the authorization webhook failed", log.Ctx{"url": r.URL.RequestURI(), "username": username, "err": err})
+					return response.Forbidden(fmt.Errorf("Authorization webhook unavailable"))
+				}
+
+				if !allowed {
+					if reason != "" {
+						return response.Forbidden(fmt.Errorf("%s", reason))
+					}
+
+					return response.Forbidden(nil)
+				}
+			}
+
 			if action.AccessHandler != nil {
 				// Defer access control to custom handler
 				resp := action.AccessHandler(d, r)
@@ -651,6 +693,16 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
 		}
 
+		// Only return the requested fields of the objects
+		if r.Method == "GET" {
+			selected, err := response.SelectFields(resp, fieldsParam(r))
+			if err != nil {
+				selected = response.InternalError(err)
+			}
+
+			resp = selected
+		}
+
 		// Handle errors
 		if err := resp.Render(w); err != nil {
 			err := response.InternalError(err).Render(w)
@@ -1000,6 +1052,11 @@ func (d *Daemon) init() error {
 		return errors.Wrap(err, "Failed to fetch debug address")
 	}
 
+	acmeAddress, err := node.ACMEAddress(d.db)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch ACME address")
+	}
+
 	/* Setup the web server */
 	config := &endpoints.Config{
 		Dir:                  d.os.VarDir,
@@ -1011,6 +1068,8 @@ func (d *Daemon) init() error {
 		NetworkAddress:       address,
 		ClusterAddress:       clusterAddress,
 		DebugAddress:         debugAddress,
+		ACMEServer:           acmeServer(d),
+		ACMEAddress:          acmeAddress,
 	}
 	d.endpoints, err = endpoints.Up(config)
 	if err != nil {
@@ -1080,7 +1139,22 @@ func (d *Daemon) init() error {
 		return errors.Wrap(err, "Failed to open cluster database")
 	}
 
-	d.firewall = firewall.New()
+	firewallMode := ""
+	err = d.db.Transaction(func(tx *db.NodeTx) error {
+		config, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		firewallMode = config.NetworkFirewallMode()
+		operationsSetQueueLimits(config)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.firewall = firewall.New(firewallMode)
 	logger.Info("Firewall loaded driver", log.Ctx{"driver": d.firewall})
 
 	err = cluster.NotifyUpgradeCompleted(d.State(), networkCert, d.serverCert())
@@ -1150,6 +1224,9 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	// Export the custom volumes again.
+	storagePools.RestoreCustomVolumeExports(d.State())
+
 	// Cleanup leftover images.
 	pruneLeftoverImages(d)
 
@@ -1171,6 +1248,10 @@ func (d *Daemon) init() error {
 	maasAPIKey := ""
 	maasMachine := ""
 
+	webhookURL := ""
+	webhookToken := ""
+	webhookExpiry := time.Duration(0)
+
 	err = d.db.Transaction(func(tx *db.NodeTx) error {
 		config, err := node.ConfigLoad(tx)
 		if err != nil {
@@ -1198,6 +1279,7 @@ func (d *Daemon) init() error {
 		candidAPIURL, candidAPIKey, candidExpiry, candidDomains = config.CandidServer()
 		maasAPIURL, maasAPIKey = config.MAASController()
 		rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey = config.RBACServer()
+		webhookURL, webhookToken, webhookExpiry = config.AuthorizationWebhook()
 		d.gateway.HeartbeatOfflineThreshold = config.OfflineThreshold()
 
 		d.endpoints.NetworkUpdateTrustedProxy(config.HTTPSTrustedProxy())
@@ -1215,6 +1297,8 @@ func (d *Daemon) init() error {
 		}
 	}
 
+	d.setupAuthorizer(webhookURL, webhookToken, webhookExpiry)
+
 	if candidAPIURL != "" {
 		err = d.setupExternalAuthentication(candidAPIURL, candidAPIKey, candidExpiry, candidDomains)
 		if err != nil {
@@ -1336,6 +1420,9 @@ func (d *Daemon) startClusterTasks() {
 	// Auto-sync images across the cluster (hourly)
 	d.clusterTasks.Add(autoSyncImagesTask(d))
 
+	// Refresh the local replica of the global database (disabled by default, configurable)
+	d.clusterTasks.Add(clusterReplicaTask(d))
+
 	// Start all background tasks
 	d.clusterTasks.Start(d.ctx)
 }
@@ -1343,6 +1430,7 @@ func (d *Daemon) startClusterTasks() {
 func (d *Daemon) stopClusterTasks() {
 	d.clusterTasks.Stop(3 * time.Second)
 	d.clusterTasks = task.Group{}
+	d.cluster.SetReplicaStaleness(0)
 }
 
 func (d *Daemon) Ready() error {
@@ -1394,6 +1482,36 @@ func (d *Daemon) Ready() error {
 
 		// Remove resolved warnings (daily)
 		d.tasks.Add(pruneResolvedWarningsTask(d))
+
+		// Rebalance instances over the CPUs (disabled by default, configurable)
+		d.taskCPURebalance = d.tasks.Add(cpuRebalanceTask(d))
+
+		// Adjust instance memory targets to the host memory pressure (disabled by default, configurable)
+		d.taskMemoryPressure = d.tasks.Add(memoryPressureTask(d))
+
+		// Sample storage pool usage and warn about pools filling up (every 10 minutes)
+		d.tasks.Add(storagePoolUsageTask(d))
+
+		// Account the resource usage of the instances to their project (every 5 minutes)
+		d.tasks.Add(projectUsageTask(d))
+
+		// Back up the database (disabled by default, configurable)
+		d.taskDatabaseBackup = d.tasks.Add(databaseBackupTask(d))
+
+		// Re-add missing routed NIC proxy neighbours (minutely)
+		d.tasks.Add(deviceRoutedNeighProxyTask(d))
+
+		// Apply the aggregate network limits of the projects to the local shapers (minutely)
+		d.tasks.Add(deviceProjectShaperTask(d))
+
+		// Check the local networks for changes made outside of LXD (every 5 minutes)
+		d.tasks.Add(networkDriftTask(d))
+
+		// Capture the console output of instances (every 5s)
+		d.tasks.Add(instanceConsoleLogTask(d))
+
+		// Obtain and renew the REST API certificate through ACME (daily, when configured)
+		d.taskACMERenew = d.tasks.Add(acmeRenewTask(d))
 	}
 
 	// Start all background tasks
@@ -1402,6 +1520,9 @@ func (d *Daemon) Ready() error {
 	// Get daemon state struct
 	s := d.State()
 
+	// Pick up the state passed on by the previous process if reloading.
+	daemonReloadRestore(s)
+
 	// Restore containers
 	if !d.cluster.LocalNodeIsEvacuated() {
 		instancesRestart(s)
@@ -1413,6 +1534,9 @@ func (d *Daemon) Ready() error {
 	// Unblock incoming requests
 	close(d.readyChan)
 
+	// Restore this member if it was evacuated for an upgrade which restarted it.
+	clusterUpgradeRestore(d)
+
 	return nil
 }
 
@@ -1507,7 +1631,8 @@ func (d *Daemon) Stop() error {
 		trackError(d.endpoints.Down(), "Shutdown endpoints")
 	}
 
-	if shouldUnmount {
+	// The next process keeps using the sockets in the temporary filesystems on reload.
+	if shouldUnmount && !d.handingOff {
 		logger.Infof("Unmounting temporary filesystems")
 
 		unix.Unmount(shared.VarPath("devlxd"), unix.MNT_DETACH)
@@ -1620,6 +1745,23 @@ func (d *Daemon) setupExternalAuthentication(authEndpoint string, authPubkey str
 	return nil
 }
 
+// setupAuthorizer sets up the authorization webhook, replacing the current one (and its cached decisions) if any.
+// The webhook is disabled if the URL is empty.
+func (d *Daemon) setupAuthorizer(webhookURL string, webhookToken string, webhookExpiry time.Duration) {
+	var authorizer *auth.WebhookAuthorizer
+	if webhookURL != "" {
+		proxy := func(req *http.Request) (*url.URL, error) {
+			return d.proxy(req)
+		}
+
+		authorizer = auth.NewWebhookAuthorizer(webhookURL, webhookToken, webhookExpiry, proxy)
+	}
+
+	d.authorizerMu.Lock()
+	d.authorizer = authorizer
+	d.authorizerMu.Unlock()
+}
+
 // Setup RBAC
 func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int64, rbacAgentURL string, rbacAgentUsername string, rbacAgentPrivateKey string, rbacAgentPublicKey string) error {
 	if d.rbac != nil || rbacURL == "" || rbacAgentURL == "" || rbacAgentUsername == "" || rbacAgentPrivateKey == "" || rbacAgentPublicKey == "" {
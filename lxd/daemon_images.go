@@ -44,6 +44,7 @@ type ImageDownloadArgs struct {
 	AutoUpdate   bool
 	StoragePool  string
 	Budget       int64
+	Priority     operations.QueuePriority
 }
 
 // imageDownloadLock acquires a lock for downloading/transferring an image and returns the unlock function.
@@ -125,7 +126,7 @@ func (d *Daemon) ImageDownload(r *http.Request, op *operations.Operation, args *
 	// server/protocol/alias, regardless of whether it's stale or
 	// not (we can assume that it will be not *too* stale since
 	// auto-update is on).
-	interval, err := cluster.ConfigGetInt64(d.cluster, "images.auto_update_interval")
+	interval, err := cluster.MemberConfigGetInt64(d.cluster, "images.auto_update_interval")
 	if err != nil {
 		return nil, err
 	}
@@ -243,6 +244,13 @@ func (d *Daemon) ImageDownload(r *http.Request, op *operations.Operation, args *
 		return info, nil
 	}
 
+	// Wait for our turn if the number of concurrent image downloads is limited.
+	releaseQueue, err := operations.QueueAcquire(d.ctx, op, operations.QueueImageDownloads, args.Priority)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed waiting for image download slot")
+	}
+	defer releaseQueue()
+
 	// Begin downloading
 	if op == nil {
 		ctxMap = log.Ctx{"alias": alias, "server": args.Server}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/endpoints"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// daemonReloadState is the state passed on to the next LXD process on reload.
+type daemonReloadState struct {
+	Tokens []operations.TokenState `json:"tokens"`
+}
+
+// daemonReloadStatePath returns the path of the file holding the state passed on to the next LXD process.
+func daemonReloadStatePath() string {
+	return shared.VarPath("reload.json")
+}
+
+// daemonHandoff holds what's handed off to the next LXD process on reload.
+type daemonHandoff struct {
+	files map[string]*os.File
+}
+
+// daemonReloadPrepare gets the sockets of the endpoints and saves the outstanding tokens for the next LXD process,
+// then stops accepting connections. The connections made from then on are queued until the next process takes
+// over the sockets, while the running operations finish. The temporary filesystems holding the sockets are kept
+// mounted when stopping the daemon.
+func daemonReloadPrepare(d *Daemon) (*daemonHandoff, error) {
+	files, err := d.endpoints.Handoff()
+	if err != nil {
+		return nil, err
+	}
+
+	reloadState := daemonReloadState{Tokens: operations.Tokens()}
+
+	data, err := json.Marshal(reloadState)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tokens hold secrets.
+	err = ioutil.WriteFile(daemonReloadStatePath(), data, 0600)
+	if err != nil {
+		for _, file := range files {
+			file.Close()
+		}
+
+		return nil, errors.Wrap(err, "Failed saving reload state")
+	}
+
+	err = d.endpoints.Down()
+	if err != nil {
+		logger.Warn("Failed bringing down endpoints for reload", log.Ctx{"err": err})
+	}
+
+	d.handingOff = true
+
+	return &daemonHandoff{files: files}, nil
+}
+
+// exec replaces the current process with the LXD binary, passing on the sockets of the endpoints.
+// It only returns on failure.
+func (h *daemonHandoff) exec() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "Failed finding LXD binary")
+	}
+
+	names := make([]string, 0, len(h.files))
+	for name := range h.files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fds := make([]string, 0, len(names))
+	for _, name := range names {
+		fd := h.files[name].Fd()
+
+		// Let the file descriptor survive the exec.
+		_, err := unix.FcntlInt(fd, unix.F_SETFD, 0)
+		if err != nil {
+			return errors.Wrapf(err, "Failed passing on %s socket", name)
+		}
+
+		fds = append(fds, fmt.Sprintf("%s=%d", name, fd))
+	}
+
+	env := []string{}
+	for _, value := range os.Environ() {
+		if strings.HasPrefix(value, "LISTEN_") || strings.HasPrefix(value, endpoints.HandoffEnv+"=") {
+			continue
+		}
+
+		env = append(env, value)
+	}
+
+	env = append(env, fmt.Sprintf("%s=%s", endpoints.HandoffEnv, strings.Join(fds, ",")))
+
+	logger.Info("Reloading LXD", log.Ctx{"binary": exe})
+
+	return unix.Exec(exe, os.Args, env)
+}
+
+// daemonReloadRestore picks up the state passed on by the previous LXD process on reload, if any.
+func daemonReloadRestore(s *state.State) {
+	data, err := ioutil.ReadFile(daemonReloadStatePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Failed reading reload state", log.Ctx{"err": err})
+		}
+
+		return
+	}
+
+	os.Remove(daemonReloadStatePath())
+
+	reloadState := daemonReloadState{}
+	err = json.Unmarshal(data, &reloadState)
+	if err != nil {
+		logger.Warn("Failed parsing reload state", log.Ctx{"err": err})
+		return
+	}
+
+	for _, token := range reloadState.Tokens {
+		_, err := operations.RestoreToken(s, token)
+		if err != nil {
+			logger.Warn("Failed restoring token", log.Ctx{"operation": token.ID, "err": err})
+		}
+	}
+
+	// Reconnect to the monitors of the running virtual machines straight away so that the events which
+	// happen from now on aren't missed.
+	insts, err := instance.LoadNodeAll(s, instancetype.VM)
+	if err != nil {
+		logger.Warn("Failed loading virtual machines to reconnect their monitors", log.Ctx{"err": err})
+		return
+	}
+
+	for _, inst := range insts {
+		inst.IsRunning()
+	}
+
+	logger.Info("Restored state from before reload", log.Ctx{"tokens": len(reloadState.Tokens)})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/cluster"
+	dbNode "github.com/lxc/lxd/lxd/db/node"
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// databaseBackupPrefix is the file name prefix of the database backup tarballs.
+const databaseBackupPrefix = "lxd_database_"
+
+// databaseBackupInfo is stored as metadata.yaml in the database backup tarballs.
+type databaseBackupInfo struct {
+	CreatedAt     time.Time `yaml:"created_at"`
+	Version       string    `yaml:"version"`
+	GlobalSchema  int       `yaml:"global_schema"`
+	APIExtensions int       `yaml:"api_extensions"`
+}
+
+type internalDatabaseBackupResult struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+// Create a backup of the global and local databases.
+func internalDatabaseBackup(d *Daemon, r *http.Request) response.Response {
+	path, err := databaseBackupCreate(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, internalDatabaseBackupResult{Path: path})
+}
+
+// databaseBackupCreate dumps the content of the global and local databases into a new tarball under the
+// backups/database directory and returns its path. The dumps are taken within a transaction so this can be done
+// while LXD is running.
+//
+// The tarball contains a patch.global.sql and a patch.local.sql file which replace the content of the databases
+// with the one from the backup when placed into the database directory before LXD starts.
+func databaseBackupCreate(d *Daemon) (string, error) {
+	globalDump, err := databaseBackupDump(d.cluster.DB(), cluster.FreshSchema())
+	if err != nil {
+		return "", errors.Wrap(err, "Failed dumping global database")
+	}
+
+	localDump, err := databaseBackupDump(d.db.DB(), dbNode.FreshSchema())
+	if err != nil {
+		return "", errors.Wrap(err, "Failed dumping local database")
+	}
+
+	info := databaseBackupInfo{
+		CreatedAt:     time.Now().UTC(),
+		Version:       version.Version,
+		GlobalSchema:  cluster.SchemaVersion,
+		APIExtensions: version.APIExtensionsCount(),
+	}
+
+	infoData, err := yaml.Marshal(&info)
+	if err != nil {
+		return "", err
+	}
+
+	backupsPath := shared.VarPath("backups", "database")
+	err = os.MkdirAll(backupsPath, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(backupsPath, fmt.Sprintf("%s%s.tar.gz", databaseBackupPrefix, info.CreatedAt.Format("20060102150405")))
+
+	// Write to a temporary file first so that partial backups are never picked up.
+	f, err := ioutil.TempFile(backupsPath, ".tmp_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gzWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"metadata.yaml", infoData},
+		{"patch.global.sql", []byte(globalDump)},
+		{"patch.local.sql", []byte(localDump)},
+	}
+
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.data)),
+			ModTime: info.CreatedAt,
+		}
+
+		err = tarWriter.WriteHeader(hdr)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed writing %q header", file.name)
+		}
+
+		_, err = tarWriter.Write(file.data)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed writing %q", file.name)
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = gzWriter.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(f.Name(), target)
+	if err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+// databaseBackupDump returns the data of the given database as SQL statements.
+func databaseBackupDump(database *sql.DB, schema string) (string, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	return query.DumpData(tx, schema)
+}
+
+// databaseBackupPrune removes the oldest database backups, keeping the given number of them.
+func databaseBackupPrune(retention int) error {
+	backupsPath := shared.VarPath("backups", "database")
+
+	entries, err := ioutil.ReadDir(backupsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	// The timestamp in the name makes lexical order match creation order.
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), databaseBackupPrefix) {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+
+	sort.Strings(names)
+
+	for len(names) > retention {
+		err = os.Remove(filepath.Join(backupsPath, names[0]))
+		if err != nil {
+			return err
+		}
+
+		names = names[1:]
+	}
+
+	return nil
+}
+
+// databaseBackupTask periodically backs up the database. The interval and the number of backups to keep are
+// controlled through backups.database_interval (0 disables it) and backups.database_retention.
+func databaseBackupTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		opRun := func(op *operations.Operation) error {
+			path, err := databaseBackupCreate(d)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Created database backup", log.Ctx{"path": path})
+
+			retention, err := databaseBackupRetention(d)
+			if err != nil {
+				return err
+			}
+
+			return databaseBackupPrune(int(retention))
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationDatabaseBackup, nil, nil, opRun, nil, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start database backup operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Backing up the database")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to back up the database", log.Ctx{"err": err})
+		}
+		logger.Info("Done backing up the database")
+	}
+
+	first := true
+	schedule := func() (time.Duration, error) {
+		var interval int64
+		err := d.db.Transaction(func(tx *db.NodeTx) error {
+			config, err := node.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			interval = config.BackupsDatabaseInterval()
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if interval <= 0 {
+			return 0, nil
+		}
+
+		// Don't take a backup every time LXD starts.
+		if first {
+			first = false
+			return time.Duration(interval) * time.Hour, task.ErrSkip
+		}
+
+		return time.Duration(interval) * time.Hour, nil
+	}
+
+	return f, schedule
+}
+
+// databaseBackupRetention returns the number of automatic database backups to keep.
+func databaseBackupRetention(d *Daemon) (int64, error) {
+	var retention int64
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		config, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		retention = config.BackupsDatabaseRetention()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return retention, nil
+}
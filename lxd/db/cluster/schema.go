@@ -97,6 +97,13 @@ CREATE TABLE images_source (
     alias TEXT NOT NULL,
     FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE
 );
+CREATE TABLE images_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	image_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+	UNIQUE (image_id, tag)
+);
 CREATE TABLE "instances" (
     id INTEGER primary key AUTOINCREMENT NOT NULL,
     node_id INTEGER NOT NULL,
@@ -291,6 +298,13 @@ CREATE VIEW instances_snapshots_devices_ref (
      JOIN instances ON instances.id=instances_snapshots.instance_id
      JOIN projects ON projects.id=instances.project_id
      JOIN instances_snapshots ON instances_snapshots.id=instances_snapshots_devices.instance_snapshot_id;
+CREATE TABLE instances_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	instance_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE,
+	UNIQUE (instance_id, tag)
+);
 CREATE TABLE "networks" (
     id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
     project_id INTEGER NOT NULL,
@@ -358,6 +372,13 @@ CREATE TABLE nodes_failure_domains (
     name TEXT NOT NULL,
     UNIQUE (name)
 );
+CREATE TABLE nodes_failure_domains_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	failure_domain TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	UNIQUE (failure_domain, key)
+);
 CREATE TABLE nodes_roles (
     node_id INTEGER NOT NULL,
     role INTEGER NOT NULL,
@@ -474,6 +495,20 @@ CREATE VIEW projects_config_ref (name,
     projects_config.value
      FROM projects_config
      JOIN projects ON projects.id=projects_config.project_id;
+CREATE TABLE projects_usage (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	project_id INTEGER NOT NULL,
+	node_id INTEGER NOT NULL,
+	period DATETIME NOT NULL,
+	cpu_seconds REAL NOT NULL DEFAULT 0,
+	memory_byte_hours REAL NOT NULL DEFAULT 0,
+	disk_byte_hours REAL NOT NULL DEFAULT 0,
+	network_rx_bytes INTEGER NOT NULL DEFAULT 0,
+	network_tx_bytes INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+	FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+	UNIQUE (project_id, node_id, period)
+);
 CREATE VIEW projects_used_by_ref (name,
     value) AS
   SELECT projects.name,
@@ -637,6 +672,13 @@ CREATE TABLE storage_volumes_snapshots_config (
     FOREIGN KEY (storage_volume_snapshot_id) REFERENCES storage_volumes_snapshots (id) ON DELETE CASCADE,
     UNIQUE (storage_volume_snapshot_id, key)
 );
+CREATE TABLE storage_volumes_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	storage_volume_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE,
+	UNIQUE (storage_volume_id, tag)
+);
 CREATE TABLE warnings (
 	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
 	node_id INTEGER,
@@ -657,5 +699,5 @@ CREATE TABLE warnings (
 );
 CREATE UNIQUE INDEX warnings_unique_node_id_project_id_entity_type_code_entity_id_type_code ON warnings(IFNULL(node_id, -1), IFNULL(project_id, -1), entity_type_code, entity_id, type_code);
 
-INSERT INTO schema (version, updated_at) VALUES (49, strftime("%s"))
+INSERT INTO schema (version, updated_at) VALUES (52, strftime("%s"))
 `
@@ -88,6 +88,84 @@ var updates = map[int]schema.Update{
 	47: updateFromV46,
 	48: updateFromV47,
 	49: updateFromV48,
+	50: updateFromV49,
+	51: updateFromV50,
+	52: updateFromV51,
+}
+
+// updateFromV51 adds the projects_usage table holding the hourly resource usage of the projects.
+func updateFromV51(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE projects_usage (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	project_id INTEGER NOT NULL,
+	node_id INTEGER NOT NULL,
+	period DATETIME NOT NULL,
+	cpu_seconds REAL NOT NULL DEFAULT 0,
+	memory_byte_hours REAL NOT NULL DEFAULT 0,
+	disk_byte_hours REAL NOT NULL DEFAULT 0,
+	network_rx_bytes INTEGER NOT NULL DEFAULT 0,
+	network_tx_bytes INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+	FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+	UNIQUE (project_id, node_id, period)
+);
+`)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create projects_usage table")
+	}
+
+	return nil
+}
+
+// updateFromV50 adds config overrides to failure domains.
+func updateFromV50(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE nodes_failure_domains_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	failure_domain TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	UNIQUE (failure_domain, key)
+);
+`)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create nodes_failure_domains_config table")
+	}
+
+	return nil
+}
+
+// updateFromV49 adds tags to instances, images and storage volumes.
+func updateFromV49(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+CREATE TABLE images_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	image_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+	UNIQUE (image_id, tag)
+);
+CREATE TABLE instances_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	instance_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE,
+	UNIQUE (instance_id, tag)
+);
+CREATE TABLE storage_volumes_tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	storage_volume_id INTEGER NOT NULL,
+	tag TEXT NOT NULL,
+	FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE,
+	UNIQUE (storage_volume_id, tag)
+);
+`)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create images_tags, instances_tags and storage_volumes_tags tables")
+	}
+
+	return nil
 }
 
 // updateFromV48 renames the "pending" column to "state" in the "nodes" table.
@@ -0,0 +1,269 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+)
+
+// Writes returns the number of changes committed to the given cluster database through this process, as opened
+// by Open. It's used to tell whether a copy of the database made at some point is still up to date without
+// asking the leader.
+func Writes(db *sql.DB) uint64 {
+	d, ok := db.Driver().(*writesDriver)
+	if !ok {
+		return 0
+	}
+
+	return atomic.LoadUint64(&d.writes)
+}
+
+// writesDriver wraps a database driver to count the transactions which executed statements and got committed, as
+// well as the statements executed outside of a transaction. Read-only transactions, only running queries, aren't
+// counted.
+type writesDriver struct {
+	driver.Driver
+	writes uint64
+}
+
+// Open returns a new connection to the database.
+func (d *writesDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writesConn{conn: conn, driver: d}, nil
+}
+
+// writesConn is a connection keeping track of whether the current transaction, if any, executed statements.
+// The database/sql package never uses a connection concurrently.
+type writesConn struct {
+	conn   driver.Conn
+	driver *writesDriver
+	inTx   bool
+	dirty  bool
+}
+
+// executed records that a statement was executed on the connection.
+func (c *writesConn) executed() {
+	if c.inTx {
+		c.dirty = true
+		return
+	}
+
+	atomic.AddUint64(&c.driver.writes, 1)
+}
+
+// Prepare returns a prepared statement.
+func (c *writesConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a prepared statement.
+func (c *writesConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+
+	preparer, ok := c.conn.(driver.ConnPrepareContext)
+	if ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &writesStmt{stmt: stmt, conn: c}, nil
+}
+
+// Close closes the connection.
+func (c *writesConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin starts a transaction.
+func (c *writesConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction.
+func (c *writesConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+
+	beginner, ok := c.conn.(driver.ConnBeginTx)
+	if ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.inTx = true
+	c.dirty = false
+
+	return &writesTx{tx: tx, conn: c}, nil
+}
+
+// ExecContext executes a statement without preparing it.
+func (c *writesConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.executed()
+
+	return result, nil
+}
+
+// QueryContext runs a query without preparing it.
+func (c *writesConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+// Ping checks that the connection is still alive.
+func (c *writesConn) Ping(ctx context.Context) error {
+	pinger, ok := c.conn.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+
+	return pinger.Ping(ctx)
+}
+
+// ResetSession is called before reusing the connection.
+func (c *writesConn) ResetSession(ctx context.Context) error {
+	resetter, ok := c.conn.(driver.SessionResetter)
+	if !ok {
+		return nil
+	}
+
+	return resetter.ResetSession(ctx)
+}
+
+// CheckNamedValue checks and converts the arguments of a statement.
+func (c *writesConn) CheckNamedValue(value *driver.NamedValue) error {
+	checker, ok := c.conn.(driver.NamedValueChecker)
+	if !ok {
+		return driver.ErrSkip
+	}
+
+	return checker.CheckNamedValue(value)
+}
+
+// writesTx is a transaction counted as a change if it executed statements and got committed.
+type writesTx struct {
+	tx   driver.Tx
+	conn *writesConn
+}
+
+// Commit commits the transaction.
+func (t *writesTx) Commit() error {
+	t.conn.inTx = false
+
+	// Count failed commits too, as the changes may have been applied anyway.
+	err := t.tx.Commit()
+	if t.conn.dirty {
+		atomic.AddUint64(&t.conn.driver.writes, 1)
+	}
+
+	t.conn.dirty = false
+
+	return err
+}
+
+// Rollback aborts the transaction.
+func (t *writesTx) Rollback() error {
+	t.conn.inTx = false
+	t.conn.dirty = false
+
+	return t.tx.Rollback()
+}
+
+// writesStmt is a prepared statement recording its executions on its connection.
+type writesStmt struct {
+	stmt driver.Stmt
+	conn *writesConn
+}
+
+// Close closes the statement.
+func (s *writesStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput returns the number of placeholders of the statement.
+func (s *writesStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec executes the statement.
+func (s *writesStmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.stmt.Exec(args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn.executed()
+
+	return result, nil
+}
+
+// ExecContext executes the statement.
+func (s *writesStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		return s.Exec(namedValuesToValues(args))
+	}
+
+	result, err := execer.ExecContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn.executed()
+
+	return result, nil
+}
+
+// Query runs the statement.
+func (s *writesStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.stmt.Query(args)
+}
+
+// QueryContext runs the statement.
+func (s *writesStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		return s.Query(namedValuesToValues(args))
+	}
+
+	return queryer.QueryContext(ctx, args)
+}
+
+// namedValuesToValues drops the names of the arguments, for the drivers not supporting them.
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	return values
+}
@@ -0,0 +1,216 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// Types of the entities whose configuration can be queried through GetConfigEntries.
+const (
+	ConfigEntityInstance         = "instance"
+	ConfigEntityInstanceSnapshot = "instance-snapshot"
+	ConfigEntityProfile          = "profile"
+	ConfigEntityNetwork          = "network"
+	ConfigEntityStoragePool      = "storage-pool"
+	ConfigEntityStorageVolume    = "storage-volume"
+)
+
+// ConfigEntityTypes lists the types of the entities whose configuration can be queried through GetConfigEntries.
+var ConfigEntityTypes = []string{
+	ConfigEntityInstance,
+	ConfigEntityInstanceSnapshot,
+	ConfigEntityProfile,
+	ConfigEntityNetwork,
+	ConfigEntityStoragePool,
+	ConfigEntityStorageVolume,
+}
+
+// configEntryTable describes a table holding configuration keys and how to resolve the entity they belong to.
+type configEntryTable struct {
+	table string
+	query string // Returns the project, entity, location, device, device type, key and value of the rows.
+}
+
+// configEntryTables maps the entity types to their configuration tables, the second one (if any) holding the
+// configuration of their devices.
+var configEntryTables = map[string][]configEntryTable{
+	ConfigEntityInstance: {
+		{table: "instances_config", query: `
+SELECT instances_config.id, projects.name, instances.name, nodes.name, '', -1, instances_config.key, coalesce(instances_config.value, '')
+  FROM instances_config
+  JOIN instances ON instances.id = instances_config.instance_id
+  JOIN projects ON projects.id = instances.project_id
+  JOIN nodes ON nodes.id = instances.node_id
+  WHERE instances_config.key IN %s`},
+		{table: "instances_devices_config", query: `
+SELECT instances_devices_config.id, projects.name, instances.name, nodes.name, instances_devices.name, instances_devices.type, instances_devices_config.key, coalesce(instances_devices_config.value, '')
+  FROM instances_devices_config
+  JOIN instances_devices ON instances_devices.id = instances_devices_config.instance_device_id
+  JOIN instances ON instances.id = instances_devices.instance_id
+  JOIN projects ON projects.id = instances.project_id
+  JOIN nodes ON nodes.id = instances.node_id
+  WHERE instances_devices_config.key IN %s`},
+	},
+	ConfigEntityInstanceSnapshot: {
+		{table: "instances_snapshots_config", query: `
+SELECT instances_snapshots_config.id, projects.name, printf('%%s/%%s', instances.name, instances_snapshots.name), nodes.name, '', -1, instances_snapshots_config.key, coalesce(instances_snapshots_config.value, '')
+  FROM instances_snapshots_config
+  JOIN instances_snapshots ON instances_snapshots.id = instances_snapshots_config.instance_snapshot_id
+  JOIN instances ON instances.id = instances_snapshots.instance_id
+  JOIN projects ON projects.id = instances.project_id
+  JOIN nodes ON nodes.id = instances.node_id
+  WHERE instances_snapshots_config.key IN %s`},
+		{table: "instances_snapshots_devices_config", query: `
+SELECT instances_snapshots_devices_config.id, projects.name, printf('%%s/%%s', instances.name, instances_snapshots.name), nodes.name, instances_snapshots_devices.name, instances_snapshots_devices.type, instances_snapshots_devices_config.key, coalesce(instances_snapshots_devices_config.value, '')
+  FROM instances_snapshots_devices_config
+  JOIN instances_snapshots_devices ON instances_snapshots_devices.id = instances_snapshots_devices_config.instance_snapshot_device_id
+  JOIN instances_snapshots ON instances_snapshots.id = instances_snapshots_devices.instance_snapshot_id
+  JOIN instances ON instances.id = instances_snapshots.instance_id
+  JOIN projects ON projects.id = instances.project_id
+  JOIN nodes ON nodes.id = instances.node_id
+  WHERE instances_snapshots_devices_config.key IN %s`},
+	},
+	ConfigEntityProfile: {
+		{table: "profiles_config", query: `
+SELECT profiles_config.id, projects.name, profiles.name, '', '', -1, profiles_config.key, coalesce(profiles_config.value, '')
+  FROM profiles_config
+  JOIN profiles ON profiles.id = profiles_config.profile_id
+  JOIN projects ON projects.id = profiles.project_id
+  WHERE profiles_config.key IN %s`},
+		{table: "profiles_devices_config", query: `
+SELECT profiles_devices_config.id, projects.name, profiles.name, '', profiles_devices.name, profiles_devices.type, profiles_devices_config.key, coalesce(profiles_devices_config.value, '')
+  FROM profiles_devices_config
+  JOIN profiles_devices ON profiles_devices.id = profiles_devices_config.profile_device_id
+  JOIN profiles ON profiles.id = profiles_devices.profile_id
+  JOIN projects ON projects.id = profiles.project_id
+  WHERE profiles_devices_config.key IN %s`},
+	},
+	ConfigEntityNetwork: {
+		{table: "networks_config", query: `
+SELECT networks_config.id, projects.name, networks.name, coalesce(nodes.name, ''), '', -1, networks_config.key, coalesce(networks_config.value, '')
+  FROM networks_config
+  JOIN networks ON networks.id = networks_config.network_id
+  JOIN projects ON projects.id = networks.project_id
+  LEFT JOIN nodes ON nodes.id = networks_config.node_id
+  WHERE networks_config.key IN %s`},
+	},
+	ConfigEntityStoragePool: {
+		{table: "storage_pools_config", query: `
+SELECT storage_pools_config.id, '', storage_pools.name, coalesce(nodes.name, ''), '', -1, storage_pools_config.key, coalesce(storage_pools_config.value, '')
+  FROM storage_pools_config
+  JOIN storage_pools ON storage_pools.id = storage_pools_config.storage_pool_id
+  LEFT JOIN nodes ON nodes.id = storage_pools_config.node_id
+  WHERE storage_pools_config.key IN %s`},
+	},
+	ConfigEntityStorageVolume: {
+		{table: "storage_volumes_config", query: `
+SELECT storage_volumes_config.id, projects.name, printf('%%s/%%s', storage_pools.name, storage_volumes_all.name), coalesce(nodes.name, ''), '', -1, storage_volumes_config.key, coalesce(storage_volumes_config.value, '')
+  FROM storage_volumes_config
+  JOIN storage_volumes_all ON storage_volumes_all.id = storage_volumes_config.storage_volume_id
+  JOIN storage_pools ON storage_pools.id = storage_volumes_all.storage_pool_id
+  JOIN projects ON projects.id = storage_volumes_all.project_id
+  LEFT JOIN nodes ON nodes.id = storage_volumes_all.node_id
+  WHERE storage_volumes_config.key IN %s`},
+	},
+}
+
+// ConfigEntry is a configuration key of an entity or of one of its devices.
+type ConfigEntry struct {
+	ID         int64
+	EntityType string
+	Project    string // Empty for storage pools.
+	Entity     string // <instance>/<snapshot> for snapshots, <pool>/<volume> for storage volumes.
+	Location   string // Cluster member of the entity or of the member specific key, if any.
+	Device     string // Device name for the device keys, empty otherwise.
+	DeviceType string
+	Key        string
+	Value      string
+}
+
+// GetConfigEntries returns the given configuration keys set on any entity of the given type or on their devices.
+func (c *ClusterTx) GetConfigEntries(entityType string, keys []string) ([]ConfigEntry, error) {
+	tables, ok := configEntryTables[entityType]
+	if !ok {
+		return nil, fmt.Errorf("Unknown entity type %q", entityType)
+	}
+
+	entries := []ConfigEntry{}
+	if len(keys) == 0 {
+		return entries, nil
+	}
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+
+	for _, table := range tables {
+		sql := fmt.Sprintf(table.query, query.Params(len(keys)))
+		rows, err := c.tx.Query(sql, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var deviceType int
+			entry := ConfigEntry{EntityType: entityType}
+
+			err = rows.Scan(&entry.ID, &entry.Project, &entry.Entity, &entry.Location, &entry.Device, &deviceType, &entry.Key, &entry.Value)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			if entry.Device != "" {
+				entry.DeviceType, err = deviceTypeToString(deviceType)
+				if err != nil {
+					rows.Close()
+					return nil, err
+				}
+			}
+
+			entries = append(entries, entry)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// UpdateConfigEntry replaces the configuration entry by the given key and value, removing it if the key is empty.
+// If the entity already has the new key set, its value is kept and the entry is removed.
+func (c *ClusterTx) UpdateConfigEntry(entry ConfigEntry, key string, value string) error {
+	tables := configEntryTables[entry.EntityType]
+	if len(tables) == 0 || (entry.Device != "" && len(tables) < 2) {
+		return fmt.Errorf("Unknown configuration of entity type %q", entry.EntityType)
+	}
+
+	table := tables[0].table
+	if entry.Device != "" {
+		table = tables[1].table
+	}
+
+	if key != "" {
+		_, err := c.tx.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET key = ?, value = ? WHERE id = ?", table), key, value, entry.ID)
+		if err != nil {
+			return err
+		}
+
+		if key == entry.Key {
+			return nil
+		}
+	}
+
+	// Remove the entry, which still has its old key if the new one was already set.
+	_, err := c.tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND key = ?", table), entry.ID, entry.Key)
+	return err
+}
@@ -0,0 +1,81 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+)
+
+func TestGetConfigEntries(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	addContainer(t, tx, 1, "c1")
+	addContainerConfig(t, tx, "c1", "security.syscalls.blacklist", "mknod")
+	addContainerConfig(t, tx, "c1", "limits.cpu", "2")
+	addContainerDevice(t, tx, "c1", "data", "disk", map[string]string{"optional": "true", "path": "/data"})
+
+	entries, err := tx.GetConfigEntries(db.ConfigEntityInstance, []string{"security.syscalls.blacklist", "optional"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "default", entries[0].Project)
+	assert.Equal(t, "c1", entries[0].Entity)
+	assert.Equal(t, "", entries[0].Device)
+	assert.Equal(t, "security.syscalls.blacklist", entries[0].Key)
+	assert.Equal(t, "mknod", entries[0].Value)
+
+	assert.Equal(t, "data", entries[1].Device)
+	assert.Equal(t, "disk", entries[1].DeviceType)
+	assert.Equal(t, "optional", entries[1].Key)
+	assert.Equal(t, "true", entries[1].Value)
+
+	_, err = tx.GetConfigEntries("foo", []string{"optional"})
+	assert.Error(t, err)
+}
+
+func TestUpdateConfigEntry(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	addContainer(t, tx, 1, "c1")
+	addContainerConfig(t, tx, "c1", "security.syscalls.blacklist", "mknod")
+	addContainerConfig(t, tx, "c1", "security.syscalls.whitelist", "read")
+	addContainerConfig(t, tx, "c1", "security.syscalls.allow", "write")
+	addContainerDevice(t, tx, "c1", "data", "disk", map[string]string{"optional": "true", "path": "/data"})
+
+	keys := []string{"security.syscalls.blacklist", "security.syscalls.whitelist", "optional"}
+	entries, err := tx.GetConfigEntries(db.ConfigEntityInstance, keys)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	replacements := map[string][2]string{
+		"security.syscalls.blacklist": {"security.syscalls.deny", "mknod"},
+		"security.syscalls.whitelist": {"security.syscalls.allow", "read"}, // Already set.
+		"optional":                    {"required", "false"},
+	}
+
+	for _, entry := range entries {
+		replacement := replacements[entry.Key]
+		err = tx.UpdateConfigEntry(entry, replacement[0], replacement[1])
+		require.NoError(t, err)
+	}
+
+	containers, err := tx.GetInstances(db.InstanceTypeFilter(instancetype.Container))
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+
+	assert.Equal(t, map[string]string{"security.syscalls.deny": "mknod", "security.syscalls.allow": "write"}, containers[0].Config)
+	assert.Equal(t, map[string]string{"type": "disk", "path": "/data", "required": "false"}, containers[0].Devices["data"])
+
+	entries, err = tx.GetConfigEntries(db.ConfigEntityInstance, keys)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
@@ -138,6 +138,11 @@ type Cluster struct {
 	stmts     map[int]*sql.Stmt // Prepared statements by code.
 	closing   bool              // True when daemon is shutting down, prevents retries
 	clusterMu sync.Mutex
+
+	replica          *replica      // Local copy of the cluster database, if enabled.
+	replicaStaleness time.Duration // How old the replica may be to serve reads.
+	replicaRefresh   chan struct{} // Signaled when a read couldn't use the replica.
+	replicaMu        sync.RWMutex
 }
 
 // OpenCluster creates a new Cluster object for interacting with the dqlite
@@ -198,6 +198,12 @@ func (c *ClusterTx) imageFill(id int, image *api.Image, create, expire, used, up
 
 	image.Aliases = aliases
 
+	// Get the tags
+	image.Tags, err = c.GetImageTags(id)
+	if err != nil {
+		return err
+	}
+
 	_, source, err := c.GetImageSource(id)
 	if err == nil {
 		image.UpdateSource = &source
@@ -235,14 +241,50 @@ WHERE images_profiles.image_id = ? AND projects.name = ?
 
 // GetImagesFingerprints returns the names of all images (optionally only the public ones).
 func (c *Cluster) GetImagesFingerprints(project string, public bool) ([]string, error) {
+	filter := ImageFilter{}
+	if public {
+		filter.Public = &public
+	}
+
+	return c.GetImagesFingerprintsPage(project, filter, Pagination{})
+}
+
+// GetImagesFingerprintsPage returns the names of the images matching the given
+// filter and part of the given page, sorted by fingerprint. The project of the
+// filter is ignored in favor of the given one.
+func (c *Cluster) GetImagesFingerprintsPage(project string, filter ImageFilter, page Pagination) ([]string, error) {
 	q := `
 SELECT fingerprint
   FROM images
   JOIN projects ON projects.id = images.project_id
  WHERE projects.name = ?
 `
-	if public == true {
-		q += " AND public=1"
+	args := []interface{}{}
+
+	if filter.Fingerprint != nil {
+		q += " AND fingerprint = ?"
+		args = append(args, *filter.Fingerprint)
+	}
+
+	if filter.Public != nil {
+		q += " AND public = ?"
+		args = append(args, *filter.Public)
+	}
+
+	if filter.Cached != nil {
+		q += " AND cached = ?"
+		args = append(args, *filter.Cached)
+	}
+
+	if filter.AutoUpdate != nil {
+		q += " AND auto_update = ?"
+		args = append(args, *filter.AutoUpdate)
+	}
+
+	if page.IsSet() {
+		where, whereArgs := page.where("fingerprint")
+		q += where + page.suffix("fingerprint")
+		args = append(args, whereArgs...)
 	}
 
 	var fingerprints []string
@@ -255,7 +297,7 @@ SELECT fingerprint
 		if !enabled {
 			project = "default"
 		}
-		fingerprints, err = query.SelectStrings(tx.tx, q, project)
+		fingerprints, err = query.SelectStrings(tx.tx, q, append([]interface{}{project}, args...)...)
 		return err
 	})
 	if err != nil {
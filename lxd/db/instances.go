@@ -271,13 +271,16 @@ SELECT nodes.id, nodes.address
 // string, to distinguish it from remote nodes.
 //
 // Containers whose node is down are addeded to the special address "0.0.0.0".
-func (c *ClusterTx) GetInstanceNamesByNodeAddress(project string, filter InstanceFilter) (map[string][]string, error) {
+//
+// If the given pagination is set, only the instances part of the page (by name)
+// are returned.
+func (c *ClusterTx) GetInstanceNamesByNodeAddress(project string, filter InstanceFilter, page Pagination) (map[string][]string, error) {
 	offlineThreshold, err := c.GetNodeOfflineThreshold()
 	if err != nil {
 		return nil, err
 	}
 
-	args := make([]interface{}, 0, 2) // Expect up to 2 filters.
+	args := make([]interface{}, 0, 4) // Expect up to 4 filters.
 	var filters strings.Builder
 
 	// Project filter.
@@ -290,14 +293,34 @@ func (c *ClusterTx) GetInstanceNamesByNodeAddress(project string, filter Instanc
 		args = append(args, *filter.Type)
 	}
 
+	// Instance name filter.
+	if filter.Name != nil {
+		filters.WriteString(" AND instances.name = ?")
+		args = append(args, *filter.Name)
+	}
+
+	// Cluster member filter.
+	if filter.Node != nil {
+		filters.WriteString(" AND nodes.name = ?")
+		args = append(args, *filter.Node)
+	}
+
+	order := " ORDER BY instances.id"
+	if page.IsSet() {
+		where, whereArgs := page.where("instances.name")
+		filters.WriteString(where)
+		args = append(args, whereArgs...)
+		order = page.suffix("instances.name")
+	}
+
 	stmt := fmt.Sprintf(`
 SELECT instances.name, nodes.id, nodes.address, nodes.heartbeat
   FROM instances
   JOIN nodes ON nodes.id = instances.node_id
   JOIN projects ON projects.id = instances.project_id
   WHERE %s
-  ORDER BY instances.id
-`, filters.String())
+ %s
+`, filters.String(), order)
 
 	rows, err := c.tx.Query(stmt, args...)
 	if err != nil {
@@ -414,8 +437,11 @@ func (c *Cluster) InstanceList(filter *InstanceFilter, instanceFunc func(inst In
 
 // GetInstanceToNodeMap returns a map associating the name of each
 // instance in the given project to the name of the node hosting the instance.
-func (c *ClusterTx) GetInstanceToNodeMap(project string, filter InstanceFilter) (map[string]string, error) {
-	args := make([]interface{}, 0, 2) // Expect up to 2 filters.
+//
+// If the given pagination is set, only the instances part of the page (by name)
+// are returned.
+func (c *ClusterTx) GetInstanceToNodeMap(project string, filter InstanceFilter, page Pagination) (map[string]string, error) {
+	args := make([]interface{}, 0, 4) // Expect up to 4 filters.
 	var filters strings.Builder
 
 	// Project filter.
@@ -428,13 +454,34 @@ func (c *ClusterTx) GetInstanceToNodeMap(project string, filter InstanceFilter)
 		args = append(args, *filter.Type)
 	}
 
+	// Instance name filter.
+	if filter.Name != nil {
+		filters.WriteString(" AND instances.name = ?")
+		args = append(args, *filter.Name)
+	}
+
+	// Cluster member filter.
+	if filter.Node != nil {
+		filters.WriteString(" AND nodes.name = ?")
+		args = append(args, *filter.Node)
+	}
+
+	order := ""
+	if page.IsSet() {
+		where, whereArgs := page.where("instances.name")
+		filters.WriteString(where)
+		args = append(args, whereArgs...)
+		order = page.suffix("instances.name")
+	}
+
 	stmt := fmt.Sprintf(`
 SELECT instances.name, nodes.name
   FROM instances
   JOIN nodes ON nodes.id = instances.node_id
   JOIN projects ON projects.id = instances.project_id
   WHERE %s
-`, filters.String())
+ %s
+`, filters.String(), order)
 
 	rows, err := c.tx.Query(stmt, args...)
 	if err != nil {
@@ -463,10 +510,10 @@ SELECT instances.name, nodes.name
 
 // UpdateInstanceNode changes the node hosting an instance.
 //
-// It's meant to be used when moving a non-running instance backed by ceph
-// from one cluster node to another.
+// It's meant to be used when moving a non-running instance backed by remote
+// storage (such as ceph) from one cluster node to another.
 func (c *ClusterTx) UpdateInstanceNode(project, oldName, newName, newNode string) error {
-	// First check that the container to be moved is backed by a ceph
+	// First check that the container to be moved is backed by a remote
 	// volume.
 	poolName, err := c.GetInstancePool(project, oldName)
 	if err != nil {
@@ -483,8 +530,8 @@ func (c *ClusterTx) UpdateInstanceNode(project, oldName, newName, newNode string
 		return errors.Wrap(err, "Failed to get instance's storage pool driver")
 	}
 
-	if poolDriver != "ceph" {
-		return fmt.Errorf("Instance's storage pool is not of type ceph")
+	if !shared.StringInSlice(poolDriver, StorageRemoteDriverNames()) {
+		return fmt.Errorf("Instance's storage pool is not remote")
 	}
 
 	// Update the name of the container and of its snapshots, and the node
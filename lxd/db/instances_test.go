@@ -332,7 +332,7 @@ func TestGetInstanceNamesByNodeAddress(t *testing.T) {
 	addContainer(t, tx, nodeID3, "c3")
 	addContainer(t, tx, nodeID2, "c4")
 
-	result, err := tx.GetInstanceNamesByNodeAddress("default", db.InstanceTypeFilter(instancetype.Container))
+	result, err := tx.GetInstanceNamesByNodeAddress("default", db.InstanceTypeFilter(instancetype.Container), db.Pagination{})
 	require.NoError(t, err)
 	assert.Equal(
 		t,
@@ -341,6 +341,30 @@ func TestGetInstanceNamesByNodeAddress(t *testing.T) {
 			"1.2.3.4:666": {"c1", "c4"},
 			"0.0.0.0":     {"c3"},
 		}, result)
+
+	// Only the instances after the cursor are part of the page.
+	result, err = tx.GetInstanceNamesByNodeAddress("default", db.InstanceTypeFilter(instancetype.Container), db.Pagination{Limit: 2, After: "c1"})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string][]string{
+			"":        {"c2"},
+			"0.0.0.0": {"c3"},
+		}, result)
+
+	// Only the instances matching the name and member filters are returned.
+	filter := db.InstanceTypeFilter(instancetype.Container)
+	node := "node2"
+	filter.Node = &node
+	result, err = tx.GetInstanceNamesByNodeAddress("default", filter, db.Pagination{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"1.2.3.4:666": {"c1", "c4"}}, result)
+
+	name := "c4"
+	filter.Name = &name
+	result, err = tx.GetInstanceNamesByNodeAddress("default", filter, db.Pagination{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"1.2.3.4:666": {"c4"}}, result)
 }
 
 // Instances are associated with their node name.
@@ -356,7 +380,7 @@ func TestGetInstanceToNodeMap(t *testing.T) {
 	addContainer(t, tx, nodeID2, "c1")
 	addContainer(t, tx, nodeID1, "c2")
 
-	result, err := tx.GetInstanceToNodeMap("default", db.InstanceTypeFilter(instancetype.Container))
+	result, err := tx.GetInstanceToNodeMap("default", db.InstanceTypeFilter(instancetype.Container), db.Pagination{})
 	require.NoError(t, err)
 	assert.Equal(
 		t,
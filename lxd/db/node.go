@@ -806,6 +806,38 @@ func (c *ClusterTx) GetFailureDomainsNames() (map[uint64]string, error) {
 	return domains, nil
 }
 
+// GetFailureDomainConfig returns the config overrides of the failure domain with the given name.
+func (c *ClusterTx) GetFailureDomainConfig(domain string) (map[string]string, error) {
+	return query.SelectConfig(c.tx, "nodes_failure_domains_config", "failure_domain=?", domain)
+}
+
+// UpdateFailureDomainConfig replaces the config overrides of the failure domain with the given name.
+func (c *ClusterTx) UpdateFailureDomainConfig(domain string, config map[string]string) error {
+	_, err := c.tx.Exec("DELETE FROM nodes_failure_domains_config WHERE failure_domain=?", domain)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := c.tx.Prepare("INSERT INTO nodes_failure_domains_config (failure_domain, key, value) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(domain, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // RemoveNode removes the node with the given id.
 func (c *ClusterTx) RemoveNode(id int64) error {
 	result, err := c.tx.Exec("DELETE FROM nodes WHERE id=?", id)
@@ -903,9 +935,15 @@ SELECT fingerprint, node_id FROM images JOIN images_nodes ON images.id=images_no
 	}
 
 	// Check if the node has any custom volumes.
+	remoteDrivers := StorageRemoteDriverNames()
+	args := []interface{}{id, StoragePoolVolumeTypeCustom}
+	for _, driver := range remoteDrivers {
+		args = append(args, driver)
+	}
+
 	volumes, err := query.SelectStrings(
-		c.tx, "SELECT storage_volumes.name FROM storage_volumes JOIN storage_pools ON storage_volumes.storage_pool_id=storage_pools.id WHERE storage_volumes.node_id=? AND storage_volumes.type=? AND storage_pools.driver NOT IN ('ceph', 'cephfs')",
-		id, StoragePoolVolumeTypeCustom)
+		c.tx, fmt.Sprintf("SELECT storage_volumes.name FROM storage_volumes JOIN storage_pools ON storage_volumes.storage_pool_id=storage_pools.id WHERE storage_volumes.node_id=? AND storage_volumes.type=? AND storage_pools.driver NOT IN %s", query.Params(len(remoteDrivers))),
+		args...)
 	if err != nil {
 		return "", errors.Wrapf(err, "Failed to get custom volumes for node %d", id)
 	}
@@ -978,8 +1016,8 @@ func (c *ClusterTx) GetNodeOfflineThreshold() (time.Duration, error) {
 // GetNodeWithLeastInstances returns the name of the non-offline node with with
 // the least number of containers (either already created or being created with
 // an operation). If archs is not empty, then return only nodes with an
-// architecture in that list.
-func (c *ClusterTx) GetNodeWithLeastInstances(archs []int, defaultArch int) (string, error) {
+// architecture in that list. Nodes listed in excluded are never returned.
+func (c *ClusterTx) GetNodeWithLeastInstances(archs []int, defaultArch int, excluded []string) (string, error) {
 	threshold, err := c.GetNodeOfflineThreshold()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get offline threshold")
@@ -998,6 +1036,10 @@ func (c *ClusterTx) GetNodeWithLeastInstances(archs []int, defaultArch int) (str
 			continue
 		}
 
+		if shared.StringInSlice(node.Name, excluded) {
+			continue
+		}
+
 		// Get personalities too.
 		personalities, err := osarch.ArchitecturePersonalities(node.Architecture)
 		if err != nil {
@@ -315,7 +315,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 `)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil, -1)
+	name, err := tx.GetNodeWithLeastInstances(nil, -1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -339,7 +339,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	err = tx.SetNodeHeartbeat("0.0.0.0", time.Now().Add(-time.Minute))
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil, -1)
+	name, err := tx.GetNodeWithLeastInstances(nil, -1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -359,11 +359,31 @@ INSERT INTO operations (id, uuid, node_id, type, project_id) VALUES (1, 'abc', 1
 `, db.OperationInstanceCreate)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil, -1)
+	name, err := tx.GetNodeWithLeastInstances(nil, -1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
 
+// Excluded nodes are never returned, even if they have the least number of
+// containers.
+func TestGetNodeWithLeastInstances_Excluded(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	// Add a container to the default node (ID 1)
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	name, err := tx.GetNodeWithLeastInstances(nil, -1, []string{"buzz"})
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+}
+
 // If specific architectures were selected, return only nodes with those
 // architectures.
 func TestGetNodeWithLeastInstances_Architecture(t *testing.T) {
@@ -388,7 +408,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	require.NoError(t, err)
 
 	// The local node is returned despite it has more containers.
-	name, err := tx.GetNodeWithLeastInstances([]int{localArch}, -1)
+	name, err := tx.GetNodeWithLeastInstances([]int{localArch}, -1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "none", name)
 }
@@ -442,7 +462,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 `, id)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil, testArch)
+	name, err := tx.GetNodeWithLeastInstances(nil, testArch, nil)
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 
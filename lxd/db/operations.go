@@ -52,6 +52,44 @@ SELECT DISTINCT nodes.address
 	return query.SelectStrings(c.tx, stmt, project)
 }
 
+// GetOperationUUIDsByNodeAddress returns the UUIDs of the operations of the
+// given project which are part of the page (sorted by UUID), grouped by the
+// address of the node running them.
+func (c *ClusterTx) GetOperationUUIDsByNodeAddress(project string, page Pagination) (map[string][]string, error) {
+	where, args := page.where("operations.uuid")
+	stmt := `
+SELECT operations.uuid, nodes.address
+  FROM operations
+  LEFT OUTER JOIN projects ON projects.id = operations.project_id
+  JOIN nodes ON nodes.id = operations.node_id
+ WHERE (projects.name = ? OR operations.project_id IS NULL)` + where + page.suffix("operations.uuid")
+
+	rows, err := c.tx.Query(stmt, append([]interface{}{project}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := map[string][]string{}
+	for rows.Next() {
+		var uuid string
+		var address string
+		err := rows.Scan(&uuid, &address)
+		if err != nil {
+			return nil, err
+		}
+
+		result[address] = append(result[address], uuid)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 // GetOperationsOfType returns a list operations that belong to the specified project and have the desired type.
 func (c *ClusterTx) GetOperationsOfType(projectName string, opType OperationType) ([]Operation, error) {
 	var ops []Operation
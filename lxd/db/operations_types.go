@@ -65,6 +65,12 @@ const (
 	OperationVolumeSnapshotRename
 	OperationClusterMemberEvacuate
 	OperationClusterMemberRestore
+	OperationInstancesCPURebalance
+	OperationDatabaseBackup
+	OperationInstanceFileWatch
+	OperationCustomVolumeISOImport
+	OperationStoragePoolMoveToBlock
+	OperationClusterUpgrade
 )
 
 // Description return a human-readable description of the operation type.
@@ -178,6 +184,18 @@ func (t OperationType) Description() string {
 		return "Evacuating cluster member"
 	case OperationClusterMemberRestore:
 		return "Restoring cluster member"
+	case OperationInstancesCPURebalance:
+		return "Rebalancing instances CPU usage"
+	case OperationDatabaseBackup:
+		return "Backing up the database"
+	case OperationInstanceFileWatch:
+		return "Watching instance files"
+	case OperationCustomVolumeISOImport:
+		return "Importing custom volume ISO image"
+	case OperationStoragePoolMoveToBlock:
+		return "Moving storage pool onto block device"
+	case OperationClusterUpgrade:
+		return "Upgrading cluster"
 	default:
 		return "Executing operation"
 	}
@@ -196,6 +214,8 @@ func (t OperationType) Permission() string {
 		return "operate-containers"
 	case OperationConsoleShow:
 		return "operate-containers"
+	case OperationInstanceFileWatch:
+		return "operate-containers"
 	case OperationInstanceFreeze:
 		return "operate-containers"
 	case OperationInstanceUnfreeze:
@@ -257,6 +277,8 @@ func (t OperationType) Permission() string {
 		return "manage-storage-volumes"
 	case OperationCustomVolumeBackupRestore:
 		return "manage-storage-volumes"
+	case OperationCustomVolumeISOImport:
+		return "manage-storage-volumes"
 	}
 
 	return ""
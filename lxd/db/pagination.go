@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pagination selects a page of a collection, which is then sorted by the
+// cursor of its entries (usually their name).
+type Pagination struct {
+	Limit int    // Maximum number of entries to return (0 means no limit).
+	After string // Only return entries whose cursor sorts after this one.
+}
+
+// IsSet returns whether only a page of the collection is selected.
+func (p Pagination) IsSet() bool {
+	return p.Limit > 0 || p.After != ""
+}
+
+// where returns the SQL condition selecting the entries after the cursor,
+// along with its arguments, to be appended to an existing WHERE clause.
+func (p Pagination) where(column string) (string, []interface{}) {
+	if p.After == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf(" AND %s > ?", column), []interface{}{p.After}
+}
+
+// suffix returns the SQL ORDER BY and LIMIT clauses for the page.
+func (p Pagination) suffix(column string) string {
+	suffix := fmt.Sprintf(" ORDER BY %s", column)
+	if p.Limit > 0 {
+		suffix += fmt.Sprintf(" LIMIT %d", p.Limit)
+	}
+
+	return suffix
+}
+
+// Strings sorts the given cursors and returns the ones part of the page.
+func (p Pagination) Strings(cursors []string) []string {
+	sort.Strings(cursors)
+
+	start := sort.SearchStrings(cursors, p.After)
+	for start < len(cursors) && cursors[start] == p.After && p.After != "" {
+		start++
+	}
+
+	cursors = cursors[start:]
+	if p.Limit > 0 && len(cursors) > p.Limit {
+		cursors = cursors[:p.Limit]
+	}
+
+	return cursors
+}
@@ -0,0 +1,28 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+func TestPagination_Strings(t *testing.T) {
+	cases := []struct {
+		page   db.Pagination
+		result []string
+	}{
+		{db.Pagination{}, []string{"a", "b", "c", "d"}},
+		{db.Pagination{Limit: 2}, []string{"a", "b"}},
+		{db.Pagination{After: "b"}, []string{"c", "d"}},
+		{db.Pagination{Limit: 1, After: "b"}, []string{"c"}},
+		{db.Pagination{Limit: 2, After: "bb"}, []string{"c", "d"}},
+		{db.Pagination{After: "d"}, []string{}},
+	}
+
+	for _, c := range cases {
+		result := c.page.Strings([]string{"c", "a", "d", "b"})
+		assert.Equal(t, c.result, result)
+	}
+}
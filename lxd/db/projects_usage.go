@@ -0,0 +1,113 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProjectUsage is the resource usage of a project over a period.
+type ProjectUsage struct {
+	Period          time.Time
+	CPUSeconds      float64
+	MemoryByteHours float64
+	DiskByteHours   float64
+	NetworkRxBytes  int64
+	NetworkTxBytes  int64
+}
+
+// Add adds the counters of another usage to this one.
+func (u *ProjectUsage) Add(other ProjectUsage) {
+	u.CPUSeconds += other.CPUSeconds
+	u.MemoryByteHours += other.MemoryByteHours
+	u.DiskByteHours += other.DiskByteHours
+	u.NetworkRxBytes += other.NetworkRxBytes
+	u.NetworkTxBytes += other.NetworkTxBytes
+}
+
+// ProjectUsagePeriod returns the start of the accounting period the given time falls in.
+// Usage is accounted in hourly periods.
+func ProjectUsagePeriod(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// AddProjectUsage adds the given usage to the accounting period of the local member for the project.
+func (c *ClusterTx) AddProjectUsage(project string, usage ProjectUsage) error {
+	projectID, err := c.GetProjectID(project)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get project ID for %q", project)
+	}
+
+	period := ProjectUsagePeriod(usage.Period)
+
+	var id int64
+	err = c.tx.QueryRow("SELECT id FROM projects_usage WHERE project_id = ? AND node_id = ? AND period = ?", projectID, c.nodeID, period).Scan(&id)
+	if err == sql.ErrNoRows {
+		_, err = c.tx.Exec(`
+INSERT INTO projects_usage (project_id, node_id, period, cpu_seconds, memory_byte_hours, disk_byte_hours, network_rx_bytes, network_tx_bytes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`, projectID, c.nodeID, period, usage.CPUSeconds, usage.MemoryByteHours, usage.DiskByteHours, usage.NetworkRxBytes, usage.NetworkTxBytes)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec(`
+UPDATE projects_usage SET
+	cpu_seconds = cpu_seconds + ?,
+	memory_byte_hours = memory_byte_hours + ?,
+	disk_byte_hours = disk_byte_hours + ?,
+	network_rx_bytes = network_rx_bytes + ?,
+	network_tx_bytes = network_tx_bytes + ?
+WHERE id = ?
+`, usage.CPUSeconds, usage.MemoryByteHours, usage.DiskByteHours, usage.NetworkRxBytes, usage.NetworkTxBytes, id)
+
+	return err
+}
+
+// GetProjectUsage returns the usage of the project over the accounting periods between start (included) and
+// end (excluded), summed over all cluster members and ordered by period.
+func (c *ClusterTx) GetProjectUsage(project string, start time.Time, end time.Time) ([]ProjectUsage, error) {
+	q := `
+SELECT projects_usage.period,
+	SUM(projects_usage.cpu_seconds),
+	SUM(projects_usage.memory_byte_hours),
+	SUM(projects_usage.disk_byte_hours),
+	SUM(projects_usage.network_rx_bytes),
+	SUM(projects_usage.network_tx_bytes)
+FROM projects_usage
+	JOIN projects ON projects.id = projects_usage.project_id
+WHERE projects.name = ? AND projects_usage.period >= ? AND projects_usage.period < ?
+GROUP BY projects_usage.period
+ORDER BY projects_usage.period
+`
+	rows, err := c.tx.Query(q, project, ProjectUsagePeriod(start), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []ProjectUsage{}
+	for rows.Next() {
+		usage := ProjectUsage{}
+		err := rows.Scan(&usage.Period, &usage.CPUSeconds, &usage.MemoryByteHours, &usage.DiskByteHours, &usage.NetworkRxBytes, &usage.NetworkTxBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		usage.Period = usage.Period.UTC()
+		usages = append(usages, usage)
+	}
+
+	return usages, rows.Err()
+}
+
+// DeleteProjectUsageBefore deletes the usage of all projects for the accounting periods before the given time.
+func (c *ClusterTx) DeleteProjectUsageBefore(t time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM projects_usage WHERE period < ?", ProjectUsagePeriod(t))
+	return err
+}
@@ -0,0 +1,51 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// Usage samples are summed into hourly periods and can be pruned.
+func TestProjectUsage(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	samples := []db.ProjectUsage{
+		{Period: start.Add(5 * time.Minute), CPUSeconds: 10, MemoryByteHours: 100, NetworkRxBytes: 1000},
+		{Period: start.Add(55 * time.Minute), CPUSeconds: 5, DiskByteHours: 50, NetworkTxBytes: 500},
+		{Period: start.Add(65 * time.Minute), CPUSeconds: 1},
+	}
+
+	for _, sample := range samples {
+		require.NoError(t, tx.AddProjectUsage("default", sample))
+	}
+
+	usages, err := tx.GetProjectUsage("default", start, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+
+	assert.Equal(t, db.ProjectUsage{Period: start, CPUSeconds: 15, MemoryByteHours: 100, DiskByteHours: 50, NetworkRxBytes: 1000, NetworkTxBytes: 500}, usages[0])
+	assert.Equal(t, db.ProjectUsage{Period: start.Add(time.Hour), CPUSeconds: 1}, usages[1])
+
+	// The end of the range is excluded.
+	usages, err = tx.GetProjectUsage("default", start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, usages, 1)
+
+	require.NoError(t, tx.DeleteProjectUsageBefore(start.Add(time.Hour)))
+
+	usages, err = tx.GetProjectUsage("default", start, start.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, start.Add(time.Hour), usages[0].Period)
+}
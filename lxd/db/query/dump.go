@@ -61,6 +61,45 @@ BEGIN TRANSACTION;
 	return dump, nil
 }
 
+// DumpData returns a SQL text replacing the rows of all tables with their
+// current content. Unlike Dump it doesn't contain the table definitions nor
+// the schema table and it's not wrapped in a transaction, so that it can be
+// applied on top of an existing database with the same schema version (for
+// example as a patch.global.sql or patch.local.sql file).
+func DumpData(tx *sql.Tx, schema string) (string, error) {
+	schemas := dumpParseSchema(schema)
+
+	tables := make([]string, 0)
+	for table := range schemas {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	// Foreign keys are only checked once all rows have been inserted.
+	dump := "PRAGMA defer_foreign_keys=ON;\n"
+
+	// Delete all rows first, since deleting from one table can cascade to others.
+	for _, table := range tables {
+		dump += fmt.Sprintf("DELETE FROM %s;\n", table)
+	}
+
+	for _, table := range tables {
+		tableDump, err := dumpTable(tx, table, "")
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to dump table %s", table)
+		}
+		dump += tableDump
+	}
+
+	tableDump, err := dumpTable(tx, "sqlite_sequence", "DELETE FROM sqlite_sequence;")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to dump table sqlite_sequence")
+	}
+	dump += tableDump
+
+	return dump, nil
+}
+
 // Return a map from table names to their schema definition, taking a full
 // schema SQL text generated with schema.Schema.Dump().
 func dumpParseSchema(schema string) map[string]string {
@@ -77,9 +116,12 @@ func dumpParseSchema(schema string) map[string]string {
 }
 
 // Dump a single table, returning a SQL text containing statements for its
-// schema and data.
+// schema (if not empty) and data.
 func dumpTable(tx *sql.Tx, table, schema string) (string, error) {
-	statements := []string{schema}
+	statements := []string{}
+	if schema != "" {
+		statements = append(statements, schema)
+	}
 
 	// Query all rows.
 	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", table))
@@ -111,9 +153,9 @@ func dumpTable(tx *sql.Tx, table, schema string) (string, error) {
 			case int64:
 				values[j] = strconv.FormatInt(v, 10)
 			case string:
-				values[j] = fmt.Sprintf("'%s'", v)
+				values[j] = fmt.Sprintf("'%s'", strings.Replace(v, "'", "''", -1))
 			case []byte:
-				values[j] = fmt.Sprintf("'%s'", string(v))
+				values[j] = fmt.Sprintf("'%s'", strings.Replace(string(v), "'", "''", -1))
 			case time.Time:
 				values[j] = strconv.FormatInt(v.Unix(), 10)
 			default:
@@ -126,6 +168,11 @@ func dumpTable(tx *sql.Tx, table, schema string) (string, error) {
 		statement := fmt.Sprintf("INSERT INTO %s VALUES(%s);", table, strings.Join(values, ","))
 		statements = append(statements, statement)
 	}
+
+	if len(statements) == 0 {
+		return "", nil
+	}
+
 	return strings.Join(statements, "\n") + "\n", nil
 }
 
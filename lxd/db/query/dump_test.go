@@ -49,6 +49,22 @@ COMMIT;
 `, dump)
 }
 
+func TestDumpData(t *testing.T) {
+	tx := newTxForDump(t, "local")
+	dump, err := query.DumpData(tx, schemas["local"])
+	require.NoError(t, err)
+	assert.Equal(t, `PRAGMA defer_foreign_keys=ON;
+DELETE FROM config;
+DELETE FROM patches;
+DELETE FROM raft_nodes;
+INSERT INTO patches VALUES(1,'invalid_profile_names',1523946366);
+INSERT INTO patches VALUES(2,'leftover_profile_config',1523946366);
+DELETE FROM sqlite_sequence;
+INSERT INTO sqlite_sequence VALUES('schema',1);
+INSERT INTO sqlite_sequence VALUES('patches',2);
+`, dump)
+}
+
 func TestDumpTablePatches(t *testing.T) {
 	tx := newTxForDump(t, "local")
 	tables := query.DumpParseSchema(schemas["local"])
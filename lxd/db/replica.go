@@ -0,0 +1,181 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+func init() {
+	sql.Register("sqlite3_replica", &sqlite3.SQLiteDriver{ConnectHook: sqliteReplicaConnect})
+}
+
+// replica is a local read-only copy of the cluster database, dumped from the leader.
+type replica struct {
+	dir    string
+	db     *sql.DB
+	stmts  map[int]*sql.Stmt
+	synced time.Time // When the dump was started.
+	writes uint64    // Number of changes committed by this member when the dump was started.
+}
+
+// close closes the replica database and removes its files.
+func (r *replica) close() {
+	for _, stmt := range r.stmts {
+		stmt.Close()
+	}
+
+	err := r.db.Close()
+	if err != nil {
+		logger.Warnf("Failed to close the global database replica: %v", err)
+	}
+
+	err = os.RemoveAll(r.dir)
+	if err != nil {
+		logger.Warnf("Failed to remove the global database replica: %v", err)
+	}
+}
+
+// Prevent any change to the replica, the changes must go through the leader.
+func sqliteReplicaConnect(conn *sqlite3.SQLiteConn) error {
+	_, err := conn.Exec("PRAGMA query_only=1;", nil)
+	return err
+}
+
+// SetReplicaStaleness sets how old the local replica of the cluster database may be for ReadTransaction to use
+// it. A zero staleness disables the replica, removing it.
+func (c *Cluster) SetReplicaStaleness(staleness time.Duration) {
+	c.replicaMu.Lock()
+	defer c.replicaMu.Unlock()
+
+	c.replicaStaleness = staleness
+
+	if c.replicaRefresh == nil {
+		c.replicaRefresh = make(chan struct{}, 1)
+	}
+
+	if staleness <= 0 && c.replica != nil {
+		c.replica.close()
+		c.replica = nil
+	}
+}
+
+// ReplicaRefreshRequested returns whether a read couldn't use the local replica of the cluster database since the
+// last call, because it is missing, outdated or too old, so that it should be refreshed.
+func (c *Cluster) ReplicaRefreshRequested() bool {
+	c.replicaMu.RLock()
+	defer c.replicaMu.RUnlock()
+
+	select {
+	case <-c.replicaRefresh:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReplicaWrites returns the number of changes committed to the cluster database by this member. It's to be recorded
+// before dumping the database for the replica, so that the replica isn't used once a change was made since.
+func (c *Cluster) ReplicaWrites() uint64 {
+	return cluster.Writes(c.db)
+}
+
+// UpdateReplica replaces the local replica of the cluster database with the database dumped from the leader into
+// dir, the dump having been started at the given time, after the given number of changes (see ReplicaWrites). The
+// replica takes ownership of dir, removing it once replaced.
+func (c *Cluster) UpdateReplica(dir string, synced time.Time, writes uint64) error {
+	db, err := sql.Open("sqlite3_replica", filepath.Join(dir, "db.bin"))
+	if err != nil {
+		return errors.Wrap(err, "Failed to open the global database replica")
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	stmts, err := cluster.PrepareStmts(db, false)
+	if err != nil {
+		db.Close()
+		return errors.Wrap(err, "Failed to prepare statements on the global database replica")
+	}
+
+	c.replicaMu.Lock()
+	defer c.replicaMu.Unlock()
+
+	if c.replica != nil {
+		c.replica.close()
+	}
+
+	c.replica = &replica{
+		dir:    dir,
+		db:     db,
+		stmts:  stmts,
+		synced: synced,
+		writes: writes,
+	}
+
+	return nil
+}
+
+// ReadTransaction executes the given read-only cluster database interactions against the local replica of the
+// cluster database if no change was made by this member since it was synced and if it is no older than the
+// configured staleness, saving the round trips to the leader. Otherwise, or if an object isn't found in the
+// replica, they are executed through Transaction. A refresh of the replica is requested when it couldn't be used.
+func (c *Cluster) ReadTransaction(f func(*ClusterTx) error) error {
+	err := c.replicaTransaction(f)
+	if err == errReplicaUnavailable || errors.Cause(err) == ErrNoSuchObject {
+		return c.Transaction(f)
+	}
+
+	return err
+}
+
+// errReplicaUnavailable is returned when the replica is missing or too old to be used.
+var errReplicaUnavailable = fmt.Errorf("Global database replica unavailable")
+
+func (c *Cluster) replicaTransaction(f func(*ClusterTx) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	c.replicaMu.RLock()
+	defer c.replicaMu.RUnlock()
+
+	if c.replicaStaleness <= 0 {
+		return errReplicaUnavailable
+	}
+
+	r := c.replica
+	if r == nil || r.writes != cluster.Writes(c.db) || time.Since(r.synced) > c.replicaStaleness {
+		c.requestReplicaRefresh()
+		return errReplicaUnavailable
+	}
+
+	clusterTx := &ClusterTx{
+		nodeID: c.nodeID,
+		stmts:  r.stmts,
+	}
+
+	return query.Transaction(r.db, func(tx *sql.Tx) error {
+		clusterTx.tx = tx
+		return f(clusterTx)
+	})
+}
+
+// requestReplicaRefresh has the replica refreshed, unless already requested. The replicaMu lock must be held.
+func (c *Cluster) requestReplicaRefresh() {
+	select {
+	case c.replicaRefresh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,69 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/cluster"
+)
+
+// Reads don't make the replica outdated, only committed changes do.
+func TestReplica_Outdated(t *testing.T) {
+	c, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "lxd-db-replica-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	replicaDB, err := sql.Open("sqlite3", filepath.Join(dir, "db.bin"))
+	require.NoError(t, err)
+	_, err = replicaDB.Exec(cluster.FreshSchema())
+	require.NoError(t, err)
+	require.NoError(t, replicaDB.Close())
+
+	c.SetReplicaStaleness(time.Minute)
+	defer c.SetReplicaStaleness(0)
+
+	require.NoError(t, c.UpdateReplica(dir, time.Now(), c.ReplicaWrites()))
+
+	read := func(tx *db.ClusterTx) error {
+		_, err := tx.GetProjectNames()
+		return err
+	}
+
+	require.NoError(t, c.Transaction(read))
+	require.NoError(t, c.ReadTransaction(read))
+	assert.False(t, c.ReplicaRefreshRequested())
+
+	// A rolled back change doesn't either.
+	err = c.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.UpdateConfig(map[string]string{"user.foo": "bar"})
+		require.NoError(t, err)
+
+		return os.ErrInvalid
+	})
+	require.Equal(t, os.ErrInvalid, err)
+
+	require.NoError(t, c.ReadTransaction(read))
+	assert.False(t, c.ReplicaRefreshRequested())
+
+	err = c.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateConfig(map[string]string{"user.foo": "bar"})
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, c.ReadTransaction(read))
+	assert.True(t, c.ReplicaRefreshRequested())
+}
@@ -379,7 +379,7 @@ type StoragePoolState int
 const (
 	storagePoolPending StoragePoolState = iota // Storage pool defined but not yet created globally or on specific node.
 	storagePoolCreated                         // Storage pool created globally or on specific node.
-	storagePoolErrored                         // Deprecated (should no longer occur).
+	storagePoolErrored                         // Storage pool failed to mount on a specific node.
 )
 
 // StoragePoolNode represents a storage pool node.
@@ -535,6 +535,11 @@ func (c *ClusterTx) StoragePoolNodeCreated(poolID int64) error {
 	return c.storagePoolNodeState(poolID, storagePoolCreated)
 }
 
+// StoragePoolNodeErrored sets the state of the given storage pool for the local member to storagePoolErrored.
+func (c *ClusterTx) StoragePoolNodeErrored(poolID int64) error {
+	return c.storagePoolNodeState(poolID, storagePoolErrored)
+}
+
 // storagePoolNodeState updates the storage pool member state for the local member and specified network ID.
 func (c *ClusterTx) storagePoolNodeState(poolID int64, state StoragePoolState) error {
 	stmt := "UPDATE storage_pools_nodes SET state=? WHERE storage_pool_id = ? and node_id = ?"
@@ -122,8 +122,13 @@ WHERE storage_volumes.id = ?
 // GetStoragePoolVolumes returns all storage volumes attached to a given
 // storage pool on any node. If there are no volumes, it returns an
 // empty list and no error.
-func (c *Cluster) GetStoragePoolVolumes(project string, poolID int64, volumeTypes []int) ([]*api.StorageVolume, error) {
+//
+// If the given pagination is set, only the volumes part of the page (by
+// StorageVolumeCursor) are returned and loaded.
+func (c *Cluster) GetStoragePoolVolumes(project string, poolID int64, volumeTypes []int, page Pagination) ([]*api.StorageVolume, error) {
 	var nodeIDs []int
+	var poolName string
+	nodeNames := map[int64]string{}
 
 	remoteDrivers := StorageRemoteDriverNames()
 
@@ -145,7 +150,36 @@ SELECT DISTINCT node_id
 		}
 
 		nodeIDs, err = query.SelectIntegers(tx.tx, s, args...)
-		return err
+		if err != nil {
+			return err
+		}
+
+		// The pool and member names are part of the cursors of the volumes.
+		if !page.IsSet() {
+			return nil
+		}
+
+		poolNames, err := query.SelectStrings(tx.tx, "SELECT name FROM storage_pools WHERE id=?", poolID)
+		if err != nil {
+			return err
+		}
+
+		if len(poolNames) != 1 {
+			return ErrNoSuchObject
+		}
+
+		poolName = poolNames[0]
+
+		for _, nodeID := range nodeIDs {
+			node, err := tx.GetNodeWithID(nodeID)
+			if err != nil {
+				return err
+			}
+
+			nodeNames[int64(nodeID)] = node.Name
+		}
+
+		return nil
 	})
 	if err != nil {
 		return nil, err
@@ -154,7 +188,7 @@ SELECT DISTINCT node_id
 	volumes := []*api.StorageVolume{}
 
 	for _, nodeID := range nodeIDs {
-		nodeVolumes, err := c.storagePoolVolumesGet(project, poolID, int64(nodeID), volumeTypes)
+		nodeVolumes, err := c.storagePoolVolumesGet(project, poolID, int64(nodeID), volumeTypes, page, poolName, nodeNames[int64(nodeID)])
 		if err != nil {
 			if err == ErrNoSuchObject {
 				continue
@@ -172,7 +206,8 @@ SELECT DISTINCT node_id
 	}
 
 	if isRemoteStorage {
-		nodeVolumes, err := c.storagePoolVolumesGet(project, poolID, c.nodeID, volumeTypes)
+		// Volumes on remote pools have no location.
+		nodeVolumes, err := c.storagePoolVolumesGet(project, poolID, c.nodeID, volumeTypes, page, poolName, "")
 		if err != nil && err != ErrNoSuchObject {
 			return nil, err
 		}
@@ -180,20 +215,60 @@ SELECT DISTINCT node_id
 		volumes = append(volumes, nodeVolumes...)
 	}
 
+	if page.IsSet() {
+		volumes = PaginateStorageVolumes(poolName, volumes, page)
+	}
+
 	return volumes, nil
 }
 
+// StorageVolumeCursor returns the pagination cursor of a storage volume, made
+// of its pool, type, name and location, as volumes on local pools can have the
+// same name on different members.
+func StorageVolumeCursor(poolName string, volume *api.StorageVolume) string {
+	return storageVolumeCursor(poolName, volume.Type, volume.Name, volume.Location)
+}
+
+// storageVolumeCursor returns the pagination cursor of a storage volume from
+// its fields. Only the volume name may contain a slash (for snapshots), so
+// the cursors are unique.
+func storageVolumeCursor(poolName string, typeName string, volumeName string, location string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", poolName, typeName, volumeName, location)
+}
+
+// PaginateStorageVolumes returns the given volumes of the given pool which are
+// part of the page, sorted by StorageVolumeCursor.
+func PaginateStorageVolumes(poolName string, volumes []*api.StorageVolume, page Pagination) []*api.StorageVolume {
+	byCursor := make(map[string]*api.StorageVolume, len(volumes))
+	cursors := make([]string, 0, len(volumes))
+	for _, volume := range volumes {
+		cursor := StorageVolumeCursor(poolName, volume)
+		byCursor[cursor] = volume
+		cursors = append(cursors, cursor)
+	}
+
+	result := []*api.StorageVolume{}
+	for _, cursor := range page.Strings(cursors) {
+		result = append(result, byCursor[cursor])
+	}
+
+	return result
+}
+
 // GetLocalStoragePoolVolumes returns all storage volumes attached to a given
 // storage pool on the current node. If there are no volumes, it returns an
 // empty list as well as ErrNoSuchObject.
 func (c *Cluster) GetLocalStoragePoolVolumes(project string, poolID int64, volumeTypes []int) ([]*api.StorageVolume, error) {
-	return c.storagePoolVolumesGet(project, poolID, c.nodeID, volumeTypes)
+	return c.storagePoolVolumesGet(project, poolID, c.nodeID, volumeTypes, Pagination{}, "", "")
 }
 
 // Returns all storage volumes attached to a given storage pool on the given
 // node. If there are no volumes, it returns an empty list as well as
 // ErrNoSuchObject.
-func (c *Cluster) storagePoolVolumesGet(project string, poolID, nodeID int64, volumeTypes []int) ([]*api.StorageVolume, error) {
+//
+// If the given pagination is set, only the volumes part of the page are
+// loaded, their cursors being made of the given pool name and location.
+func (c *Cluster) storagePoolVolumesGet(project string, poolID, nodeID int64, volumeTypes []int, page Pagination, poolName string, location string) ([]*api.StorageVolume, error) {
 	// Get all storage volumes of all types attached to a given storage pool.
 	result := []*api.StorageVolume{}
 	for _, volumeType := range volumeTypes {
@@ -202,6 +277,23 @@ func (c *Cluster) storagePoolVolumesGet(project string, poolID, nodeID int64, vo
 			return nil, errors.Wrap(err, "Failed to fetch volume types")
 		}
 
+		// Only load the volumes part of the page.
+		if page.IsSet() {
+			typeName := StoragePoolVolumeTypeNames[volumeType]
+			byCursor := make(map[string]string, len(volumeNames))
+			cursors := make([]string, 0, len(volumeNames))
+			for _, volumeName := range volumeNames {
+				cursor := storageVolumeCursor(poolName, typeName, volumeName, location)
+				byCursor[cursor] = volumeName
+				cursors = append(cursors, cursor)
+			}
+
+			volumeNames = volumeNames[:0]
+			for _, cursor := range page.Strings(cursors) {
+				volumeNames = append(volumeNames, byCursor[cursor])
+			}
+		}
+
 		for _, volumeName := range volumeNames {
 			_, volume, err := c.storagePoolVolumeGetType(project, volumeName, volumeType, poolID, nodeID)
 			if err != nil {
@@ -366,6 +458,14 @@ func (c *Cluster) storagePoolVolumeGetType(project string, volumeName string, vo
 	storageVolume.Location = volumeNode
 	storageVolume.ContentType = volumeContentTypeName
 
+	// Snapshots don't have tags.
+	if !isSnapshot {
+		storageVolume.Tags, err = c.GetStorageVolumeTags(volumeID)
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+
 	return volumeID, &storageVolume, nil
 }
 
@@ -631,12 +731,14 @@ var StoragePoolVolumeTypeNames = map[int]string{
 const (
 	StoragePoolVolumeContentTypeFS = iota
 	StoragePoolVolumeContentTypeBlock
+	StoragePoolVolumeContentTypeISO
 )
 
 // Content type names.
 const (
 	StoragePoolVolumeContentTypeNameFS    string = "filesystem"
 	StoragePoolVolumeContentTypeNameBlock string = "block"
+	StoragePoolVolumeContentTypeNameISO   string = "iso"
 )
 
 // StorageVolumeArgs is a value object holding all db-related details about a
@@ -1011,6 +1113,8 @@ func storagePoolVolumeContentTypeToName(contentType int) (string, error) {
 		return StoragePoolVolumeContentTypeNameFS, nil
 	case StoragePoolVolumeContentTypeBlock:
 		return StoragePoolVolumeContentTypeNameBlock, nil
+	case StoragePoolVolumeContentTypeISO:
+		return StoragePoolVolumeContentTypeNameISO, nil
 	}
 
 	return "", fmt.Errorf("Invalid storage volume content type")
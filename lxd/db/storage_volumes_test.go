@@ -67,3 +67,50 @@ INSERT INTO storage_volumes(storage_pool_id, node_id, name, type, project_id) VA
 	_, err := tx.Tx().Exec(stmt, poolID, nodeID, name)
 	require.NoError(t, err)
 }
+
+// Volumes with the same name on different members are all part of the pages.
+func TestGetStoragePoolVolumes_Pagination(t *testing.T) {
+	c, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	var poolID int64
+	err := c.Transaction(func(tx *db.ClusterTx) error {
+		nodeID2, err := tx.CreateNode("node2", "1.2.3.4:666")
+		require.NoError(t, err)
+
+		poolID = addPool(t, tx, "pool1")
+		addVolume(t, tx, poolID, 1, "volume1")
+		addVolume(t, tx, poolID, nodeID2, "volume1")
+		addVolume(t, tx, poolID, nodeID2, "volume2")
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	volumeTypes := []int{db.StoragePoolVolumeTypeImage}
+
+	volumes, err := c.GetStoragePoolVolumes("default", poolID, volumeTypes, db.Pagination{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, volumes, 2)
+	assert.Equal(t, "volume1", volumes[0].Name)
+	assert.Equal(t, "none", volumes[0].Location)
+	assert.Equal(t, "volume1", volumes[1].Name)
+	assert.Equal(t, "node2", volumes[1].Location)
+
+	after := db.StorageVolumeCursor("pool1", volumes[1])
+	volumes, err = c.GetStoragePoolVolumes("default", poolID, volumeTypes, db.Pagination{Limit: 2, After: after})
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	assert.Equal(t, "volume2", volumes[0].Name)
+	assert.Equal(t, "node2", volumes[0].Location)
+
+	// Paginating the volumes of several queries gives the same result.
+	volumes, err = c.GetStoragePoolVolumes("default", poolID, volumeTypes, db.Pagination{})
+	require.NoError(t, err)
+	require.Len(t, volumes, 3)
+
+	volumes = db.PaginateStorageVolumes("pool1", volumes, db.Pagination{After: "pool1/image/volume1/none"})
+	require.Len(t, volumes, 2)
+	assert.Equal(t, "node2", volumes[0].Location)
+	assert.Equal(t, "node2", volumes[1].Location)
+}
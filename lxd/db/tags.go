@@ -0,0 +1,113 @@
+//go:build linux && cgo && !agent
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// GetInstanceTags returns the tags of the instance with the given ID.
+func (c *ClusterTx) GetInstanceTags(id int) ([]string, error) {
+	return tagsGet(c.tx, "instances_tags", "instance_id", int64(id))
+}
+
+// GetInstancesTags returns the tags of the instances of the given project, keyed by instance name.
+func (c *ClusterTx) GetInstancesTags(project string) (map[string][]string, error) {
+	q := `
+SELECT instances.name, instances_tags.tag FROM instances_tags
+	JOIN instances ON instances.id = instances_tags.instance_id
+	JOIN projects ON projects.id = instances.project_id
+WHERE projects.name = ?
+ORDER BY instances_tags.tag
+`
+	rows, err := c.tx.Query(q, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[string][]string{}
+	for rows.Next() {
+		var name, tag string
+		err := rows.Scan(&name, &tag)
+		if err != nil {
+			return nil, err
+		}
+
+		tags[name] = append(tags[name], tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// UpdateInstanceTags replaces the tags of the instance with the given ID.
+func (c *ClusterTx) UpdateInstanceTags(id int, tags []string) error {
+	return tagsUpdate(c.tx, "instances_tags", "instance_id", int64(id), tags)
+}
+
+// GetImageTags returns the tags of the image with the given ID.
+func (c *ClusterTx) GetImageTags(id int) ([]string, error) {
+	return tagsGet(c.tx, "images_tags", "image_id", int64(id))
+}
+
+// UpdateImageTags replaces the tags of the image with the given ID.
+func (c *ClusterTx) UpdateImageTags(id int, tags []string) error {
+	return tagsUpdate(c.tx, "images_tags", "image_id", int64(id), tags)
+}
+
+// GetStorageVolumeTags returns the tags of the storage volume with the given ID.
+func (c *Cluster) GetStorageVolumeTags(volumeID int64) ([]string, error) {
+	var tags []string
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		tags, err = tagsGet(tx.tx, "storage_volumes_tags", "storage_volume_id", volumeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// UpdateStoragePoolVolumeTags replaces the tags of the storage volume attached to a given storage pool.
+func (c *Cluster) UpdateStoragePoolVolumeTags(project, volumeName string, volumeType int, poolID int64, tags []string) error {
+	volumeID, _, err := c.GetLocalStoragePoolVolume(project, volumeName, volumeType, poolID)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		return storagePoolVolumeReplicateIfCeph(tx.tx, volumeID, project, volumeName, volumeType, poolID, func(volumeID int64) error {
+			return tagsUpdate(tx.tx, "storage_volumes_tags", "storage_volume_id", volumeID, tags)
+		})
+	})
+}
+
+// tagsGet returns the sorted tags of the entity with the given ID from the table.
+func tagsGet(tx *sql.Tx, table string, column string, id int64) ([]string, error) {
+	q := fmt.Sprintf("SELECT tag FROM %s WHERE %s = ? ORDER BY tag", table, column)
+	return query.SelectStrings(tx, q, id)
+}
+
+// tagsUpdate replaces the tags of the entity with the given ID in the table.
+func tagsUpdate(tx *sql.Tx, table string, column string, id int64, tags []string) error {
+	_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, column), id)
+	if err != nil {
+		return err
+	}
+
+	stmt := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s, tag) VALUES (?, ?)", table, column)
+	for _, tag := range tags {
+		_, err := tx.Exec(stmt, id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
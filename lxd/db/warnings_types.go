@@ -53,6 +53,20 @@ const (
 	WarningOfflineClusterMember
 	// WarningInstanceAutostartFailure represents the failure of instance autostart process after three retries
 	WarningInstanceAutostartFailure
+	// WarningIdmapRangesExhausted represents the lack of uid/gid available for a project's dedicated range
+	WarningIdmapRangesExhausted
+	// WarningStoragePoolUnavailable represents a storage pool which couldn't be mounted at startup
+	WarningStoragePoolUnavailable
+	// WarningNetworkFirewallDrift represents network firewall rules removed or overridden by other tools
+	WarningNetworkFirewallDrift
+	// WarningStoragePoolUsageThreshold represents a storage pool whose usage is above its warning threshold
+	WarningStoragePoolUsageThreshold
+	// WarningStoragePoolUsageForecast represents a storage pool projected to fill up within its forecast horizon
+	WarningStoragePoolUsageForecast
+	// WarningMAASOperationFailed represents a failure to update the MAAS records of an instance or project
+	WarningMAASOperationFailed
+	// WarningNetworkConfigDrift represents a network whose host state no longer matches its configuration
+	WarningNetworkConfigDrift
 )
 
 // WarningTypeNames associates a warning code to its name.
@@ -80,6 +94,13 @@ var WarningTypeNames = map[WarningType]string{
 	WarningNetworkStartupFailure:                  "Failed to start network",
 	WarningOfflineClusterMember:                   "Offline cluster member",
 	WarningInstanceAutostartFailure:               "Failed to autostart instance",
+	WarningIdmapRangesExhausted:                   "Not enough uid/gid available for project",
+	WarningStoragePoolUnavailable:                 "Storage pool unavailable",
+	WarningNetworkFirewallDrift:                   "Network firewall rules altered by another tool",
+	WarningStoragePoolUsageThreshold:              "Storage pool usage above threshold",
+	WarningStoragePoolUsageForecast:               "Storage pool projected to fill up",
+	WarningMAASOperationFailed:                    "Failed to update MAAS records",
+	WarningNetworkConfigDrift:                     "Network altered outside of LXD",
 }
 
 // WarningTypes associates a warning type to its type code.
@@ -140,6 +161,20 @@ func (t WarningType) Severity() WarningSeverity {
 		return WarningSeverityLow
 	case WarningInstanceAutostartFailure:
 		return WarningSeverityLow
+	case WarningIdmapRangesExhausted:
+		return WarningSeverityModerate
+	case WarningStoragePoolUnavailable:
+		return WarningSeverityModerate
+	case WarningNetworkFirewallDrift:
+		return WarningSeverityModerate
+	case WarningStoragePoolUsageThreshold:
+		return WarningSeverityHigh
+	case WarningStoragePoolUsageForecast:
+		return WarningSeverityModerate
+	case WarningMAASOperationFailed:
+		return WarningSeverityModerate
+	case WarningNetworkConfigDrift:
+		return WarningSeverityModerate
 	}
 
 	return WarningSeverityLow
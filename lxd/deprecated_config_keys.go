@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/deprecation"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var deprecatedConfigKeysCmd = APIEndpoint{
+	Path: "deprecated-config-keys",
+
+	Get: APIEndpointAction{Handler: deprecatedConfigKeysGet},
+}
+
+// swagger:operation GET /1.0/deprecated-config-keys server deprecated_config_keys_get
+//
+// Get the deprecated configuration keys in use
+//
+// Returns the deprecated configuration keys still set on the instances, snapshots, profiles, networks, storage
+// pools and storage volumes of the whole cluster, along with their replacement.
+//
+// ---
+// produces:
+//   - application/json
+// responses:
+//   "200":
+//     description: Deprecated configuration keys
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           type: array
+//           description: List of deprecated configuration keys
+//           items:
+//             $ref: "#/definitions/DeprecatedConfigKey"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func deprecatedConfigKeysGet(d *Daemon, r *http.Request) response.Response {
+	var keys []api.DeprecatedConfigKey
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		keys, err = deprecation.InUse(tx)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, keys)
+}
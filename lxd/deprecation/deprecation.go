@@ -0,0 +1,140 @@
+package deprecation
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Key is a deprecated configuration key and how to migrate it.
+type Key struct {
+	Version     int      // Configuration schema version deprecating the key.
+	EntityTypes []string // Types of the entities the key applies to.
+	DeviceType  string   // Type of the devices the key applies to, empty for the configuration of the entities.
+	Name        string
+	Replacement string                    // Key replacing the deprecated one, empty if it's dropped.
+	Convert     func(value string) string // Conversion of the value for the replacement key, if needed.
+}
+
+// instanceEntityTypes are the entity types holding instance configuration and devices.
+var instanceEntityTypes = []string{db.ConfigEntityInstance, db.ConfigEntityInstanceSnapshot, db.ConfigEntityProfile}
+
+// Keys lists the deprecated configuration keys, by configuration schema version.
+//
+// Only append to this list. Deprecating keys in a new schema version requires bumping Version and appending the
+// matching config_keys_v<version> patch.
+var Keys = []Key{
+	{Version: 1, EntityTypes: instanceEntityTypes, Name: "security.syscalls.blacklist", Replacement: "security.syscalls.deny"},
+	{Version: 1, EntityTypes: instanceEntityTypes, Name: "security.syscalls.blacklist_compat", Replacement: "security.syscalls.deny_compat"},
+	{Version: 1, EntityTypes: instanceEntityTypes, Name: "security.syscalls.blacklist_default", Replacement: "security.syscalls.deny_default"},
+	{Version: 1, EntityTypes: instanceEntityTypes, Name: "security.syscalls.whitelist", Replacement: "security.syscalls.allow"},
+	{Version: 1, EntityTypes: instanceEntityTypes, DeviceType: "disk", Name: "optional", Replacement: "required", Convert: invertBool},
+}
+
+// Version is the current configuration schema version.
+const Version = 1
+
+// invertBool converts a boolean value into its opposite.
+func invertBool(value string) string {
+	if shared.IsTrue(value) {
+		return "false"
+	}
+
+	return "true"
+}
+
+// Find returns the deprecated key matching the configuration entry, if any.
+func Find(entityType string, deviceType string, name string) *Key {
+	for i, key := range Keys {
+		if key.Name != name || key.DeviceType != deviceType || !shared.StringInSlice(entityType, key.EntityTypes) {
+			continue
+		}
+
+		return &Keys[i]
+	}
+
+	return nil
+}
+
+// entityKeys returns the names of the deprecated keys applying to the entity type, up to the given schema version.
+func entityKeys(entityType string, version int) []string {
+	names := []string{}
+	for _, key := range Keys {
+		if key.Version > version || !shared.StringInSlice(entityType, key.EntityTypes) {
+			continue
+		}
+
+		if !shared.StringInSlice(key.Name, names) {
+			names = append(names, key.Name)
+		}
+	}
+
+	return names
+}
+
+// Migrate rewrites the keys deprecated by the given configuration schema version on all the entities.
+func Migrate(tx *db.ClusterTx, version int) error {
+	for _, entityType := range db.ConfigEntityTypes {
+		entries, err := tx.GetConfigEntries(entityType, entityKeys(entityType, version))
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading the configuration of %s entities", entityType)
+		}
+
+		for _, entry := range entries {
+			key := Find(entityType, entry.DeviceType, entry.Key)
+			if key == nil || key.Version != version {
+				continue
+			}
+
+			value := entry.Value
+			if key.Convert != nil {
+				value = key.Convert(value)
+			}
+
+			ctx := log.Ctx{"type": entityType, "project": entry.Project, "name": entry.Entity, "device": entry.Device, "key": entry.Key, "replacement": key.Replacement}
+			logger.Info("Migrating deprecated configuration key", ctx)
+
+			err = tx.UpdateConfigEntry(entry, key.Replacement, value)
+			if err != nil {
+				return errors.Wrapf(err, "Failed migrating %q of %s %q", entry.Key, entityType, entry.Entity)
+			}
+		}
+	}
+
+	return nil
+}
+
+// InUse returns the deprecated keys still set on any entity.
+func InUse(tx *db.ClusterTx) ([]api.DeprecatedConfigKey, error) {
+	result := []api.DeprecatedConfigKey{}
+	for _, entityType := range db.ConfigEntityTypes {
+		entries, err := tx.GetConfigEntries(entityType, entityKeys(entityType, Version))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed loading the configuration of %s entities", entityType)
+		}
+
+		for _, entry := range entries {
+			key := Find(entityType, entry.DeviceType, entry.Key)
+			if key == nil {
+				continue
+			}
+
+			result = append(result, api.DeprecatedConfigKey{
+				EntityType:  entityType,
+				Project:     entry.Project,
+				Entity:      entry.Entity,
+				Location:    entry.Location,
+				Device:      entry.Device,
+				Key:         entry.Key,
+				Value:       entry.Value,
+				Replacement: key.Replacement,
+			})
+		}
+	}
+
+	return result, nil
+}
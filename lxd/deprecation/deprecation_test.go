@@ -0,0 +1,34 @@
+package deprecation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+func TestFind(t *testing.T) {
+	key := Find(db.ConfigEntityProfile, "", "security.syscalls.whitelist")
+	require.NotNil(t, key)
+	assert.Equal(t, "security.syscalls.allow", key.Replacement)
+
+	key = Find(db.ConfigEntityInstance, "disk", "optional")
+	require.NotNil(t, key)
+	assert.Equal(t, "required", key.Replacement)
+	assert.Equal(t, "false", key.Convert("true"))
+	assert.Equal(t, "true", key.Convert("false"))
+
+	// Only disk devices are concerned.
+	assert.Nil(t, Find(db.ConfigEntityInstance, "nic", "optional"))
+
+	// Keys of other entity types aren't.
+	assert.Nil(t, Find(db.ConfigEntityNetwork, "", "security.syscalls.whitelist"))
+}
+
+func TestKeysVersion(t *testing.T) {
+	for _, key := range Keys {
+		assert.True(t, key.Version >= 1 && key.Version <= Version, "Key %q has an invalid schema version", key.Name)
+	}
+}
@@ -80,3 +80,8 @@ type device interface {
 type NICState interface {
 	State() (*api.InstanceStateNetwork, error)
 }
+
+// NICHostState provides the ability to access the host-side link state of NICs which aren't backed by a veth pair.
+type NICHostState interface {
+	HostState() (*api.InstanceStateNetwork, error)
+}
@@ -2,8 +2,10 @@ package device
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -47,6 +49,45 @@ func IsBlockdev(path string) bool {
 	return false
 }
 
+// diskResolveMultipath returns the path of the multipath device the block device is a path of, or the path
+// itself if it isn't part of a multipath device. Using a single path of a multipath device directly would bypass
+// the failover and could corrupt the data written through the other paths.
+func diskResolveMultipath(devPath string) (string, error) {
+	realPath, err := filepath.EvalSymlinks(devPath)
+	if err != nil {
+		return "", err
+	}
+
+	holders, err := ioutil.ReadDir(filepath.Join("/sys/class/block", filepath.Base(realPath), "holders"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return devPath, nil
+		}
+
+		return "", err
+	}
+
+	for _, holder := range holders {
+		uuid, err := ioutil.ReadFile(filepath.Join("/sys/class/block", holder.Name(), "dm", "uuid"))
+		if err != nil {
+			continue
+		}
+
+		if !strings.HasPrefix(string(uuid), "mpath-") {
+			continue
+		}
+
+		name, err := ioutil.ReadFile(filepath.Join("/sys/class/block", holder.Name(), "dm", "name"))
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join("/dev/mapper", strings.TrimSpace(string(name))), nil
+	}
+
+	return devPath, nil
+}
+
 // DiskMount mounts a disk device.
 func DiskMount(srcPath string, dstPath string, readonly bool, recursive bool, propagation string, rawMountOptions string, fsName string) error {
 	var err error
@@ -1,7 +1,10 @@
 package device
 
 import (
+	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/state"
 )
 
 // instanceSupported is a helper function to check instance type is supported for validation.
@@ -20,3 +23,14 @@ func instanceSupported(instType instancetype.Type, supportedTypes ...instancetyp
 
 	return false
 }
+
+// instanceDeviceHotplugEvent sends the lifecycle event of a host device which appeared in ("add") or disappeared
+// from ("remove") a running instance.
+func instanceDeviceHotplugEvent(s *state.State, inst instance.Instance, action string, ctx map[string]interface{}) {
+	switch action {
+	case "add":
+		s.Events.SendLifecycle(inst.Project(), lifecycle.InstanceDeviceAdded.Event(inst, ctx))
+	case "remove":
+		s.Events.SendLifecycle(inst.Project(), lifecycle.InstanceDeviceRemoved.Event(inst, ctx))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,9 +16,12 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/ip"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/validate"
@@ -418,7 +422,9 @@ func networkNICRouteDelete(routeDev string, routes ...string) {
 }
 
 // networkSetupHostVethLimits applies any network rate limits to the veth device specified in the config.
-func networkSetupHostVethLimits(m deviceConfig.Device) error {
+// The traffic is also redirected through the devices shaping the aggregate traffic of the instances of the project
+// when the project sets limits.network.ingress or limits.network.egress.
+func networkSetupHostVethLimits(s *state.State, projectName string, m deviceConfig.Device) error {
 	var err error
 
 	veth := m["host_name"]
@@ -450,6 +456,17 @@ func networkSetupHostVethLimits(m deviceConfig.Device) error {
 		}
 	}
 
+	// Get the devices shaping the aggregate traffic of the project.
+	p, err := s.Cluster.GetProject(projectName)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading project %q", projectName)
+	}
+
+	projectIngress, projectEgress, err := network.ProjectShaperSetup(projectName, p.Config)
+	if err != nil {
+		return err
+	}
+
 	// Clean any existing entry
 	qdisc := &ip.Qdisc{Dev: veth, Root: true}
 	qdisc.Delete()
@@ -457,35 +474,55 @@ func networkSetupHostVethLimits(m deviceConfig.Device) error {
 	qdisc.Delete()
 
 	// Apply new limits
-	if m["limits.ingress"] != "" {
+	if m["limits.ingress"] != "" || projectIngress != "" {
 		qdiscHTB := &ip.QdiscHTB{Qdisc: ip.Qdisc{Dev: veth, Handle: "1:0", Root: true}, Default: "10"}
 		err := qdiscHTB.Add()
 		if err != nil {
 			return fmt.Errorf("Failed to create root tc qdisc: %s", err)
 		}
 
-		classHTB := &ip.ClassHTB{Class: ip.Class{Dev: veth, Parent: "1:0", Classid: "1:10"}, Rate: fmt.Sprintf("%dbit", ingressInt)}
-		err = classHTB.Add()
-		if err != nil {
-			return fmt.Errorf("Failed to create limit tc class: %s", err)
+		if m["limits.ingress"] != "" {
+			classHTB := &ip.ClassHTB{Class: ip.Class{Dev: veth, Parent: "1:0", Classid: "1:10"}, Rate: fmt.Sprintf("%dbit", ingressInt)}
+			err = classHTB.Add()
+			if err != nil {
+				return fmt.Errorf("Failed to create limit tc class: %s", err)
+			}
+
+			filter := &ip.U32Filter{Filter: ip.Filter{Dev: veth, Parent: "1:0", Protocol: "all", Flowid: "1:1"}, Value: "0", Mask: "0"}
+			err = filter.Add()
+			if err != nil {
+				return fmt.Errorf("Failed to create tc filter: %s", err)
+			}
 		}
 
-		filter := &ip.U32Filter{Filter: ip.Filter{Dev: veth, Parent: "1:0", Protocol: "all", Flowid: "1:1"}, Value: "0", Mask: "0"}
-		err = filter.Add()
-		if err != nil {
-			return fmt.Errorf("Failed to create tc filter: %s", err)
+		// Send the traffic through the project's shaper first, it then comes back through the default class.
+		if projectIngress != "" {
+			mirred := &ip.ActionMirred{Direction: "egress", Redirect: true, Dev: projectIngress}
+			filter := &ip.U32Filter{Filter: ip.Filter{Dev: veth, Parent: "1:0", Protocol: "all"}, Priority: "1", Value: "0", Mask: "0", Actions: []ip.Action{mirred}}
+			err = filter.Add()
+			if err != nil {
+				return fmt.Errorf("Failed to create project shaper tc filter: %s", err)
+			}
 		}
 	}
 
-	if m["limits.egress"] != "" {
+	if m["limits.egress"] != "" || projectEgress != "" {
 		qdisc = &ip.Qdisc{Dev: veth, Handle: "ffff:0", Ingress: true}
 		err := qdisc.Add()
 		if err != nil {
 			return fmt.Errorf("Failed to create ingress tc qdisc: %s", err)
 		}
 
-		police := &ip.ActionPolice{Rate: fmt.Sprintf("%dbit", egressInt), Burst: "1024k", Mtu: "64kb", Drop: true}
-		filter := &ip.U32Filter{Filter: ip.Filter{Dev: veth, Parent: "ffff:0", Protocol: "all"}, Value: "0", Mask: "0", Actions: []ip.Action{police}}
+		actions := []ip.Action{}
+		if m["limits.egress"] != "" {
+			actions = append(actions, &ip.ActionPolice{Rate: fmt.Sprintf("%dbit", egressInt), Burst: "1024k", Mtu: "64kb", Drop: true, Pipe: projectEgress != ""})
+		}
+
+		if projectEgress != "" {
+			actions = append(actions, &ip.ActionMirred{Direction: "egress", Redirect: true, Dev: projectEgress})
+		}
+
+		filter := &ip.U32Filter{Filter: ip.Filter{Dev: veth, Parent: "ffff:0", Protocol: "all"}, Value: "0", Mask: "0", Actions: actions}
 		err = filter.Add()
 		if err != nil {
 			return fmt.Errorf("Failed to create ingress tc filter: %s", err)
@@ -542,3 +579,113 @@ func networkParsePortRange(r string) (int64, int64, error) {
 
 	return base, size, nil
 }
+
+// networkHostLinkState returns the state of a host interface, including its carrier, speed and counters.
+func networkHostLinkState(name string) (*api.InstanceStateNetwork, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	state := api.InstanceStateNetwork{
+		Addresses: []api.InstanceStateNetworkAddress{},
+		Hwaddr:    iface.HardwareAddr.String(),
+		HostName:  name,
+		Mtu:       iface.MTU,
+		State:     "down",
+		Type:      "broadcast",
+	}
+
+	if iface.Flags&net.FlagUp != 0 {
+		state.State = "up"
+	}
+
+	// The carrier can't be read while the interface is down.
+	carrier, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/carrier", name))
+	state.Carrier = err == nil && strings.TrimSpace(string(carrier)) == "1"
+
+	// The speed is reported as -1 (or can't be read) when unknown.
+	speed, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/speed", name))
+	if err == nil {
+		value, err := strconv.ParseInt(strings.TrimSpace(string(speed)), 10, 64)
+		if err == nil && value > 0 {
+			state.LinkSpeed = uint64(value)
+		}
+	}
+
+	counters, err := resources.GetNetworkCounters(name)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Counters = api.InstanceStateNetworkCounters{
+		BytesReceived:   counters.BytesReceived,
+		BytesSent:       counters.BytesSent,
+		PacketsReceived: counters.PacketsReceived,
+		PacketsSent:     counters.PacketsSent,
+	}
+
+	return &state, nil
+}
+
+// networkInterfacePF returns the PCI address of the physical function backing the interface (the function itself
+// for VFs), or the interface name if it isn't backed by a PCI device.
+func networkInterfacePF(name string) (string, error) {
+	devicePath := fmt.Sprintf("/sys/class/net/%s/device", name)
+	if !shared.PathExists(devicePath) {
+		return name, nil
+	}
+
+	if shared.PathExists(filepath.Join(devicePath, "physfn")) {
+		devicePath = filepath.Join(devicePath, "physfn")
+	}
+
+	target, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to find the PCI device of %q", name)
+	}
+
+	return filepath.Base(target), nil
+}
+
+// nicGroupCheckPFs checks that the parents of the NICs of the group are on distinct physical functions, so that
+// the bond formed inside the guest survives the failure of an uplink.
+func nicGroupCheckPFs(s *state.State, inst instance.Instance, group string) error {
+	seen := map[string]string{}
+
+	for _, entry := range inst.ExpandedDevices().Sorted() {
+		devConfig := entry.Config
+		if devConfig["type"] != "nic" || devConfig["group"] != group {
+			continue
+		}
+
+		parent := devConfig["parent"]
+		if devConfig["network"] != "" {
+			// project.Default is used here as sriov networks don't support projects.
+			n, err := network.LoadByName(s, project.Default, devConfig["network"])
+			if err != nil {
+				return errors.Wrapf(err, "Error loading network config for %q", devConfig["network"])
+			}
+
+			parent = n.Config()["parent"]
+		}
+
+		if parent == "" {
+			continue
+		}
+
+		pf, err := networkInterfacePF(parent)
+		if err != nil {
+			return err
+		}
+
+		other, found := seen[pf]
+		if found {
+			return fmt.Errorf("NICs %q and %q of group %q are on the same physical function %q", other, entry.Name, group, pf)
+		}
+
+		seen[pf] = entry.Name
+	}
+
+	return nil
+}
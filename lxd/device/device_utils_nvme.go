@@ -0,0 +1,210 @@
+package device
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	pcidev "github.com/lxc/lxd/lxd/device/pci"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+// Disk "source" prefix used for passing an NVMe namespace to a VM, followed by "<PCI address>/<namespace ID>".
+const diskSourceNVMePrefix = "nvme:"
+
+// nvmeClass is the PCI class of NVMe controllers.
+const nvmeClass = "0x010802"
+
+// nvmeParseSource returns the normalised PCI address of the controller and the namespace ID from a disk source.
+func nvmeParseSource(source string) (string, uint32, error) {
+	if !strings.HasPrefix(source, diskSourceNVMePrefix) {
+		return "", 0, fmt.Errorf("Source %q isn't an NVMe namespace", source)
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(source, diskSourceNVMePrefix), "/", 2)
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf(`NVMe source must be in the form "nvme:<PCI address>/<namespace ID>"`)
+	}
+
+	err := validate.IsPCIAddress(fields[0])
+	if err != nil {
+		return "", 0, err
+	}
+
+	namespace, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil || namespace < 1 {
+		return "", 0, fmt.Errorf("Invalid NVMe namespace ID %q", fields[1])
+	}
+
+	return pcidev.NormaliseAddress(fields[0]), uint32(namespace), nil
+}
+
+// nvmeBlockDevices returns the names of the block devices (namespaces and their partitions) of an NVMe controller
+// currently bound to the host driver.
+func nvmeBlockDevices(address string) ([]string, error) {
+	ctrls, err := filepath.Glob(filepath.Join("/sys/bus/pci/devices", address, "nvme", "nvme*"))
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []string{}
+	for _, ctrl := range ctrls {
+		namespaces, err := filepath.Glob(filepath.Join("/sys/block", fmt.Sprintf("%sn*", filepath.Base(ctrl))))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range namespaces {
+			devices = append(devices, filepath.Base(ns))
+
+			partitions, err := filepath.Glob(filepath.Join(ns, fmt.Sprintf("%sp*", filepath.Base(ns))))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, part := range partitions {
+				devices = append(devices, filepath.Base(part))
+			}
+		}
+	}
+
+	return devices, nil
+}
+
+// nvmeCheckController checks that the PCI device is an NVMe controller which can be handed over to the VM.
+// It must not be used by another instance, by a storage pool or by the host (mounted or held by device mapper,
+// LVM, md etc) as binding it to vfio-pci detaches all of its namespaces from the host.
+func nvmeCheckController(s *state.State, inst instance.Instance, address string) error {
+	class, err := ioutil.ReadFile(filepath.Join("/sys/bus/pci/devices", address, "class"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("Invalid PCI address (no device found): %s", address)
+		}
+
+		return err
+	}
+
+	if strings.TrimSpace(string(class)) != nvmeClass {
+		return fmt.Errorf("PCI device %q isn't an NVMe controller", address)
+	}
+
+	// Check other instances on this member.
+	instances, err := instance.LoadNodeAll(s, instancetype.VM)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range instances {
+		if other.Name() == inst.Name() && other.Project() == inst.Project() {
+			continue
+		}
+
+		if !other.IsRunning() {
+			continue
+		}
+
+		for devName, dev := range other.ExpandedDevices() {
+			if nvmeDeviceUsesController(dev, address) {
+				return fmt.Errorf("NVMe controller %q is already used by device %q of instance %q in project %q", address, devName, other.Name(), other.Project())
+			}
+		}
+	}
+
+	devices, err := nvmeBlockDevices(address)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		return nil
+	}
+
+	// Check storage pools.
+	poolNames, err := s.Cluster.GetStoragePoolNames()
+	if err != nil && err != db.ErrNoSuchObject {
+		return errors.Wrapf(err, "Failed loading storage pools")
+	}
+
+	for _, poolName := range poolNames {
+		_, pool, _, err := s.Cluster.GetStoragePool(poolName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading storage pool %q", poolName)
+		}
+
+		source, err := filepath.EvalSymlinks(pool.Config["source"])
+		if err != nil {
+			continue
+		}
+
+		if shared.StringInSlice(filepath.Base(source), devices) && strings.HasPrefix(source, "/dev/") {
+			return fmt.Errorf("NVMe controller %q is used by storage pool %q", address, poolName)
+		}
+	}
+
+	// Check block devices in use on the host.
+	mounts, err := nvmeMountedDevices()
+	if err != nil {
+		return err
+	}
+
+	for _, dev := range devices {
+		if shared.StringInSlice(dev, mounts) {
+			return fmt.Errorf("NVMe controller %q is in use on the host (%q is mounted)", address, dev)
+		}
+
+		holders, err := ioutil.ReadDir(filepath.Join("/sys/class/block", dev, "holders"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if len(holders) > 0 {
+			return fmt.Errorf("NVMe controller %q is in use on the host (%q is held by %q)", address, dev, holders[0].Name())
+		}
+	}
+
+	return nil
+}
+
+// nvmeDeviceUsesController returns whether the device config uses the NVMe controller at the PCI address.
+func nvmeDeviceUsesController(dev map[string]string, address string) bool {
+	switch dev["type"] {
+	case "disk":
+		devAddress, _, err := nvmeParseSource(dev["source"])
+		return err == nil && devAddress == address
+	case "pci":
+		return pcidev.NormaliseAddress(dev["address"]) == address
+	}
+
+	return false
+}
+
+// nvmeMountedDevices returns the names of the block devices mounted on the host.
+func nvmeMountedDevices() ([]string, error) {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "/dev/") {
+			devices = append(devices, filepath.Base(fields[0]))
+		}
+	}
+
+	return devices, scanner.Err()
+}
@@ -1,6 +1,7 @@
 package device
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strconv"
@@ -61,3 +62,58 @@ func ProxyParseAddr(addr string) (*deviceConfig.ProxyAddress, error) {
 
 	return newProxyAddr, nil
 }
+
+// ProxyProtocolVersion returns the version of the PROXY protocol ("v1" or "v2") selected by the given
+// proxy_protocol value, or an empty string if no PROXY header should be sent. Boolean values are still accepted,
+// true selecting version 1.
+func ProxyProtocolVersion(value string) string {
+	if value == "v1" || value == "v2" {
+		return value
+	}
+
+	if shared.IsTrue(value) {
+		return "v1"
+	}
+
+	return ""
+}
+
+// ProxyProtocolV2Header returns the binary PROXY protocol version 2 header describing a TCP connection from src to
+// dst. If either address isn't a TCP one (e.g. unix sockets), the header carries the UNSPEC family and no address.
+func ProxyProtocolV2Header(src net.Addr, dst net.Addr) []byte {
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	// Protocol version 2 with the PROXY command.
+	header = append(header, 0x21)
+
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		return append(header, 0x00, 0x00, 0x00)
+	}
+
+	var family byte
+	var addrs []byte
+	if srcTCP.IP.To4() != nil && dstTCP.IP.To4() != nil {
+		family = 0x11 // TCP over IPv4.
+		addrs = append(addrs, srcTCP.IP.To4()...)
+		addrs = append(addrs, dstTCP.IP.To4()...)
+	} else {
+		family = 0x21 // TCP over IPv6.
+		addrs = append(addrs, srcTCP.IP.To16()...)
+		addrs = append(addrs, dstTCP.IP.To16()...)
+	}
+
+	ports := make([]byte, 4)
+	binary.BigEndian.PutUint16(ports[0:2], uint16(srcTCP.Port))
+	binary.BigEndian.PutUint16(ports[2:4], uint16(dstTCP.Port))
+	addrs = append(addrs, ports...)
+
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(addrs)))
+
+	header = append(header, family)
+	header = append(header, length...)
+
+	return append(header, addrs...)
+}
@@ -0,0 +1,40 @@
+package device
+
+import (
+	"fmt"
+	"net"
+)
+
+func Example_proxyProtocolV2Header() {
+	tests := []struct {
+		src net.Addr
+		dst net.Addr
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}, &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 443}},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}, &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}, &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}},
+		{&net.UnixAddr{Name: "@client", Net: "unix"}, &net.UnixAddr{Name: "/run/server.socket", Net: "unix"}},
+	}
+
+	for _, test := range tests {
+		fmt.Printf("%x\n", ProxyProtocolV2Header(test.src, test.dst)[12:])
+	}
+
+	// Output: 2111000cc0000201c0000202dc0401bb
+	// 2121002420010db800000000000000000000000120010db8000000000000000000000002dc0401bb
+	// 2121002400000000000000000000ffffc000020120010db8000000000000000000000002dc0401bb
+	// 21000000
+}
+
+func Example_proxyProtocolVersion() {
+	for _, value := range []string{"", "false", "true", "v1", "v2", "yes"} {
+		fmt.Printf("%q: %q\n", value, ProxyProtocolVersion(value))
+	}
+
+	// Output: "": ""
+	// "false": ""
+	// "true": "v1"
+	// "v1": "v1"
+	// "v2": "v2"
+	// "yes": "v1"
+}
@@ -357,6 +357,23 @@ func unixDeviceSetupBlockNum(s *state.State, devicesPath string, typePrefix stri
 	return unixDeviceSetup(s, devicesPath, typePrefix, deviceName, configCopy, defaultMode, runConf)
 }
 
+// UnixDeviceSetupKVM creates the /dev/kvm device of a container allowed to run nested virtual machines and
+// configures the supplied RunConfig with the mount and cgroup rule instructions to have it be attached.
+func UnixDeviceSetupKVM(s *state.State, devicesPath string, runConf *deviceConfig.RunConfig) error {
+	if !shared.PathExists("/dev/kvm") {
+		return fmt.Errorf("KVM isn't available on this host (/dev/kvm is missing)")
+	}
+
+	m := deviceConfig.Device{
+		"type":   "unix-char",
+		"source": "/dev/kvm",
+		"path":   "/dev/kvm",
+		"mode":   "0660",
+	}
+
+	return unixDeviceSetup(s, devicesPath, "kvm", "nesting", m, true, runConf)
+}
+
 // UnixDeviceExists checks if the unix device already exists in devices path.
 func UnixDeviceExists(devicesPath string, prefix string, path string) bool {
 	relativeDestPath := strings.TrimPrefix(path, "/")
@@ -146,7 +146,26 @@ func unixRunHandlers(state *state.State, event *UnixEvent) {
 				logger.Error("Unix event instance handler failed", log.Ctx{"err": err, "project": projectName, "instance": instanceName, "device": deviceName})
 				continue
 			}
+
+			instanceDeviceHotplugEvent(state, instance, event.Action, map[string]interface{}{"device": deviceName, "path": sub.Path})
+		}
+	}
+}
+
+// UnixRunPathHandlers executes any handlers registered for the device nodes (and their symlinks) reported by udev.
+// This complements inotify which doesn't report the changes in directories which aren't watched yet.
+func UnixRunPathHandlers(state *state.State, paths []string) {
+	subscribed := unixGetSubcribedPaths()
+
+	for _, path := range paths {
+		_, found := subscribed[path]
+		if !found {
+			continue
 		}
+
+		// Derive the action from the path as the device node may have been recreated since.
+		event := unixNewEvent("", path)
+		unixRunHandlers(state, &event)
 	}
 }
 
@@ -90,6 +90,8 @@ func UnixHotplugRunHandlers(state *state.State, event *UnixHotplugEvent) {
 				logger.Error("Unix hotplug event instance handler failed", log.Ctx{"err": err, "project": projectName, "instance": instanceName, "device": deviceName})
 				continue
 			}
+
+			instanceDeviceHotplugEvent(state, instance, event.Action, map[string]interface{}{"device": deviceName, "vendorid": event.Vendor, "productid": event.Product, "path": event.Path})
 		}
 	}
 }
@@ -93,6 +93,8 @@ func USBRunHandlers(state *state.State, event *USBEvent) {
 				logger.Error("USB event instance handler failed", log.Ctx{"err": err, "project": projectName, "instance": instanceName, "device": deviceName})
 				continue
 			}
+
+			instanceDeviceHotplugEvent(state, instance, event.Action, map[string]interface{}{"device": deviceName, "vendorid": event.Vendor, "productid": event.Product, "path": event.Path})
 		}
 	}
 }
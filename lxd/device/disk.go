@@ -18,6 +18,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/db/cluster"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	pcidev "github.com/lxc/lxd/lxd/device/pci"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/project"
@@ -43,6 +44,14 @@ const diskSourceCloudInit = "cloud-init:config"
 // the QEMU driver.
 const DiskVirtiofsdSockMountOpt = "virtiofsdSock"
 
+// DiskSerialMountOpt indicates the mount option prefix used to provide the serial number exposed to the guest to
+// the QEMU driver.
+const DiskSerialMountOpt = "serial"
+
+// DiskVFIOGroupMountOpt indicates the mount option prefix used to provide the IOMMU group of a passed through
+// NVMe controller to the QEMU driver.
+const DiskVFIOGroupMountOpt = "vfioGroup"
+
 type diskBlockLimit struct {
 	readBps   int64
 	readIops  int64
@@ -54,6 +63,16 @@ type disk struct {
 	deviceCommon
 }
 
+// CanHotPlug returns whether the device can be managed whilst the instance is running.
+// Host block devices (such as partitions and LVM logical volumes) can be hot plugged into VMs.
+func (d *disk) CanHotPlug() bool {
+	if d.inst.Type() == instancetype.Container {
+		return true
+	}
+
+	return d.config["pool"] == "" && d.sourceIsLocalPath(d.config["source"]) && shared.IsBlockdevPath(shared.HostPath(d.config["source"]))
+}
+
 // CanMigrate returns whether the device can be migrated to any other cluster member.
 func (d *disk) CanMigrate() bool {
 	// Root disk is always migratable.
@@ -97,7 +116,7 @@ func (d *disk) sourceIsLocalPath(source string) bool {
 		return false
 	}
 
-	if shared.StringHasPrefix(d.config["source"], "ceph:", "cephfs:") {
+	if shared.StringHasPrefix(d.config["source"], "ceph:", "cephfs:", diskSourceNVMePrefix) {
 		return false
 	}
 
@@ -141,6 +160,11 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		"ceph.user_name":    validate.IsAny,
 		"boot.priority":     validate.Optional(validate.IsUint32),
 		"path":              validate.IsAny,
+		"nvme.queues":       validate.Optional(validate.IsUint32),
+		"nvme.queue_size":   validate.Optional(diskValidateQueueSize),
+		"nvme.vectors":      validate.Optional(validate.IsUint32),
+		"nvme.iothread":     validate.Optional(validate.IsBool),
+		"serial":            validate.Optional(diskValidateSerial),
 	}
 
 	err := d.config.Validate(rules)
@@ -185,6 +209,24 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf("Invalid options ceph.cluster_name/ceph.user_name for source %q", d.config["source"])
 	}
 
+	if d.config["serial"] != "" && (instConf.Type() == instancetype.Container || d.config["path"] == "/") {
+		return fmt.Errorf(`The "serial" property can only be used on additional VM disks`)
+	}
+
+	// Check NVMe options are only used when an NVMe namespace is passed to a VM.
+	if strings.HasPrefix(d.config["source"], diskSourceNVMePrefix) {
+		err := d.validateConfigNVMe(instConf)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, key := range []string{"nvme.queues", "nvme.queue_size", "nvme.vectors", "nvme.iothread"} {
+			if d.config[key] != "" {
+				return fmt.Errorf("Invalid option %s for source %q", key, d.config["source"])
+			}
+		}
+	}
+
 	// Check no other devices also have the same path as us. Use LocalDevices for this check so
 	// that we can check before the config is expanded or when a profile is being checked.
 	// Don't take into account the device names, only count active devices that point to the
@@ -250,23 +292,34 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 				return errors.Wrapf(err, "Failed loading custom volume")
 			}
 
-			// Check storage volume is available to mount on this cluster member.
-			remoteInstance, err := storagePools.VolumeUsedByExclusiveRemoteInstancesWithProfiles(d.state, d.config["pool"], storageProjectName, vol)
+			contentType, err := storagePools.VolumeContentTypeNameToContentType(vol.ContentType)
 			if err != nil {
-				return errors.Wrapf(err, "Failed checking if custom volume is exclusively attached to another instance")
+				return err
 			}
 
-			if remoteInstance != nil {
-				return fmt.Errorf("Custom volume is already attached to an instance on a different node")
-			}
+			// Check storage volume is available to mount on this cluster member.
+			// ISO volumes are read-only and can be attached to any number of instances.
+			if contentType != db.StoragePoolVolumeContentTypeISO {
+				remoteInstance, err := storagePools.VolumeUsedByExclusiveRemoteInstancesWithProfiles(d.state, d.config["pool"], storageProjectName, vol)
+				if err != nil {
+					return errors.Wrapf(err, "Failed checking if custom volume is exclusively attached to another instance")
+				}
 
-			// Check that block volumes are *only* attached to VM instances.
-			contentType, err := storagePools.VolumeContentTypeNameToContentType(vol.ContentType)
-			if err != nil {
-				return err
+				if remoteInstance != nil {
+					return fmt.Errorf("Custom volume is already attached to an instance on a different node")
+				}
 			}
 
-			if contentType == db.StoragePoolVolumeContentTypeBlock {
+			// Check that block and ISO volumes are *only* attached to VM instances.
+			if contentType == db.StoragePoolVolumeContentTypeISO {
+				if instConf.Type() == instancetype.Container {
+					return fmt.Errorf("Custom ISO volumes cannot be used on containers")
+				}
+
+				if d.config["path"] != "" {
+					return fmt.Errorf("Custom ISO volumes cannot have a path defined")
+				}
+			} else if contentType == db.StoragePoolVolumeContentTypeBlock {
 				if instConf.Type() == instancetype.Container {
 					return fmt.Errorf("Custom block volumes cannot be used on containers")
 				}
@@ -283,6 +336,60 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 	return nil
 }
 
+// validateConfigNVMe checks the config of a disk passing an NVMe namespace to a VM.
+func (d *disk) validateConfigNVMe(instConf instance.ConfigReader) error {
+	if instConf.Type() == instancetype.Container {
+		return fmt.Errorf("NVMe namespaces cannot be used on containers")
+	}
+
+	if d.config["pool"] != "" || d.config["path"] != "" {
+		return fmt.Errorf(`NVMe namespaces cannot have a "pool" or "path" defined`)
+	}
+
+	address, _, err := nvmeParseSource(d.config["source"])
+	if err != nil {
+		return err
+	}
+
+	// The whole controller is passed to QEMU so it can only be used once per instance.
+	for devName, devConfig := range instConf.ExpandedDevices() {
+		if devName != d.name && nvmeDeviceUsesController(devConfig, address) {
+			return fmt.Errorf("NVMe controller %q is also used by device %q", address, devName)
+		}
+	}
+
+	return nil
+}
+
+// diskValidateQueueSize validates a virtio queue size, which must be a power of two.
+func diskValidateQueueSize(value string) error {
+	size, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return fmt.Errorf("Invalid queue size %q", value)
+	}
+
+	if size < 2 || size&(size-1) != 0 {
+		return fmt.Errorf("Queue size must be a power of two of at least 2")
+	}
+
+	return nil
+}
+
+// diskValidateSerial validates the serial number exposed to the guest, which is used for its stable device names.
+func diskValidateSerial(value string) error {
+	if len(value) > 20 {
+		return fmt.Errorf("Serial must be at most 20 characters long")
+	}
+
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return fmt.Errorf("Serial can only contain alphanumeric characters, hyphens and underscores")
+		}
+	}
+
+	return nil
+}
+
 // getDevicePath returns the absolute path on the host for this instance and supplied device config.
 func (d *disk) getDevicePath(devName string, devConfig deviceConfig.Device) string {
 	relativeDestPath := strings.TrimPrefix(devConfig["path"], "/")
@@ -478,7 +585,7 @@ func (d *disk) startContainer() (*deviceConfig.RunConfig, error) {
 		var poolVolSrcPath string
 		if d.config["pool"] != "" {
 			var err error
-			poolVolSrcPath, err = d.mountPoolVolume(revert)
+			poolVolSrcPath, _, err = d.mountPoolVolume(revert)
 			if err != nil {
 				if !isRequired {
 					d.logger.Warn(err.Error())
@@ -567,6 +674,8 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 		}
 
 		return &runConf, nil
+	} else if strings.HasPrefix(d.config["source"], diskSourceNVMePrefix) {
+		return d.startVMNVMe()
 	} else if d.config["source"] != "" {
 		revert := revert.New()
 		defer revert.Fail()
@@ -595,13 +704,14 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 			}
 		} else {
 			srcPath := shared.HostPath(d.config["source"])
+			var contentType string
 			var err error
 
 			// Mount the pool volume and update srcPath to mount path so it can be recognised as dir
 			// if the volume is a filesystem volume type (if it is a block volume the srcPath will
 			// be returned as the path to the block device).
 			if d.config["pool"] != "" {
-				srcPath, err = d.mountPoolVolume(revert)
+				srcPath, contentType, err = d.mountPoolVolume(revert)
 				if err != nil {
 					if !isRequired {
 						logger.Warn(err.Error())
@@ -612,17 +722,43 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 				}
 			}
 
+			// Use the multipath device rather than one of its paths.
+			if d.config["pool"] == "" && shared.IsBlockdevPath(srcPath) {
+				mpathPath, err := diskResolveMultipath(srcPath)
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed checking whether %q is part of a multipath device", srcPath)
+				}
+
+				if mpathPath != srcPath {
+					d.logger.Warn("Using multipath device instead of one of its paths", log.Ctx{"source": srcPath, "multipath": mpathPath})
+					srcPath = mpathPath
+				}
+			}
+
 			// Default to block device or image file passthrough first.
 			mount := deviceConfig.MountEntryItem{
 				DevPath: srcPath,
 				DevName: d.name,
 			}
 
+			if d.config["serial"] != "" {
+				mount.Opts = append(mount.Opts, fmt.Sprintf("%s=%s", DiskSerialMountOpt, d.config["serial"]))
+			}
+
 			readonly := shared.IsTrue(d.config["readonly"])
 			if readonly {
 				mount.Opts = append(mount.Opts, "ro")
 			}
 
+			// ISO volumes are always attached read-only as a cdrom.
+			if contentType == db.StoragePoolVolumeContentTypeNameISO {
+				if !readonly {
+					mount.Opts = append(mount.Opts, "ro")
+				}
+
+				mount.FSType = "iso9660"
+			}
+
 			// If the source being added is a directory or cephfs share, then we will use the lxd-agent
 			// directory sharing feature to mount the directory inside the VM, and as such we need to
 			// indicate to the VM the target path to mount to.
@@ -770,6 +906,75 @@ func (d *disk) startVM() (*deviceConfig.RunConfig, error) {
 	return nil, fmt.Errorf("Disk type not supported for VMs")
 }
 
+// startVMNVMe binds the NVMe controller to vfio-pci so QEMU's userspace NVMe driver can access the namespace.
+func (d *disk) startVMNVMe() (*deviceConfig.RunConfig, error) {
+	address, namespace, err := nvmeParseSource(d.config["source"])
+	if err != nil {
+		return nil, err
+	}
+
+	if shared.IsTrue(d.inst.ExpandedConfig()["migration.stateful"]) {
+		return nil, fmt.Errorf("NVMe namespaces cannot be used when migration.stateful is enabled")
+	}
+
+	err = nvmeCheckController(d.state, d.inst, address)
+	if err != nil {
+		return nil, err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	pciDev, err := pcidev.ParseUeventFile(filepath.Join("/sys/bus/pci/devices", address, "uevent"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get PCI device info for %q", address)
+	}
+
+	saveData := map[string]string{
+		"last_state.pci.slot.name": pciDev.SlotName,
+		"last_state.pci.driver":    pciDev.Driver,
+	}
+
+	err = pcidev.DeviceDriverOverride(pciDev, "vfio-pci")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to override IOMMU group driver")
+	}
+
+	revert.Add(func() {
+		pcidev.DeviceDriverOverride(pcidev.Device{Driver: "vfio-pci", SlotName: pciDev.SlotName}, pciDev.Driver)
+	})
+
+	iommuGroup, err := pcidev.DeviceIOMMUGroup(pciDev.SlotName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.volatileSet(saveData)
+	if err != nil {
+		return nil, err
+	}
+
+	mount := deviceConfig.MountEntryItem{
+		DevPath: fmt.Sprintf("nvme://%s/%d", pciDev.SlotName, namespace),
+		DevName: d.name,
+		Opts:    []string{fmt.Sprintf("%s=%d", DiskVFIOGroupMountOpt, iommuGroup)},
+	}
+
+	if shared.IsTrue(d.config["readonly"]) {
+		mount.Opts = append(mount.Opts, "ro")
+	}
+
+	// Pass the queue tuning to the QEMU driver.
+	for _, key := range []string{"nvme.queues", "nvme.queue_size", "nvme.vectors", "nvme.iothread"} {
+		if d.config[key] != "" {
+			mount.Opts = append(mount.Opts, fmt.Sprintf("%s=%s", key, d.config[key]))
+		}
+	}
+
+	revert.Success()
+	return &deviceConfig.RunConfig{Mounts: []deviceConfig.MountEntryItem{mount}}, nil
+}
+
 // postStart is run after the instance is started.
 func (d *disk) postStart() error {
 	devPath := d.getDevicePath(d.name, d.config)
@@ -1002,8 +1207,9 @@ func (w *cgroupWriter) Set(version cgroup.Backend, controller string, key string
 }
 
 // mountPoolVolume mounts the pool volume specified in d.config["source"] from pool specified in d.config["pool"]
-// and return the mount path. If the instance type is container volume will be shifted if needed.
-func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, error) {
+// and return the mount path and the volume content type. If the instance type is container volume will be shifted
+// if needed.
+func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, string, error) {
 	// Deal with mounting storage volumes created via the storage api. Extract the name of the storage volume
 	// that we are supposed to attach. We assume that the only syntactically valid ways of specifying a
 	// storage volume are:
@@ -1012,7 +1218,7 @@ func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, error) {
 	// Currently, <type> must either be empty or "custom".
 	// We do not yet support instance mounts.
 	if filepath.IsAbs(d.config["source"]) {
-		return "", fmt.Errorf(`When the "pool" property is set "source" must specify the name of a volume, not a path`)
+		return "", "", fmt.Errorf(`When the "pool" property is set "source" must specify the name of a volume, not a path`)
 	}
 
 	volumeTypeName := ""
@@ -1030,7 +1236,7 @@ func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, error) {
 	// Check volume type name is custom.
 	switch volumeTypeName {
 	case db.StoragePoolVolumeTypeNameContainer:
-		return "", fmt.Errorf("Using instance storage volumes is not supported")
+		return "", "", fmt.Errorf("Using instance storage volumes is not supported")
 	case "":
 		// We simply received the name of a storage volume.
 		volumeTypeName = db.StoragePoolVolumeTypeNameCustom
@@ -1038,15 +1244,15 @@ func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, error) {
 	case db.StoragePoolVolumeTypeNameCustom:
 		break
 	case db.StoragePoolVolumeTypeNameImage:
-		return "", fmt.Errorf("Using image storage volumes is not supported")
+		return "", "", fmt.Errorf("Using image storage volumes is not supported")
 	default:
-		return "", fmt.Errorf("Unknown storage type prefix %q found", volumeTypeName)
+		return "", "", fmt.Errorf("Unknown storage type prefix %q found", volumeTypeName)
 	}
 
 	// Only custom volumes can be attached currently.
 	storageProjectName, err := project.StorageVolumeProject(d.state.Cluster, d.inst.Project(), db.StoragePoolVolumeTypeCustom)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	volStorageName := project.StorageVolume(storageProjectName, volumeName)
@@ -1054,39 +1260,39 @@ func (d *disk) mountPoolVolume(revert *revert.Reverter) (string, error) {
 
 	pool, err := storagePools.GetPoolByName(d.state, d.config["pool"])
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	err = pool.MountCustomVolume(storageProjectName, volumeName, nil)
 	if err != nil {
-		return "", errors.Wrapf(err, "Failed mounting storage volume %q of type %q on storage pool %q", volumeName, volumeTypeName, pool.Name())
+		return "", "", errors.Wrapf(err, "Failed mounting storage volume %q of type %q on storage pool %q", volumeName, volumeTypeName, pool.Name())
 	}
 	revert.Add(func() { pool.UnmountCustomVolume(storageProjectName, volumeName, nil) })
 
 	_, vol, err := d.state.Cluster.GetLocalStoragePoolVolume(storageProjectName, volumeName, db.StoragePoolVolumeTypeCustom, pool.ID())
 	if err != nil {
-		return "", errors.Wrapf(err, "Failed to fetch local storage volume record")
+		return "", "", errors.Wrapf(err, "Failed to fetch local storage volume record")
 	}
 
 	if d.inst.Type() == instancetype.Container {
 		if vol.ContentType == db.StoragePoolVolumeContentTypeNameFS {
 			err = d.storagePoolVolumeAttachShift(storageProjectName, pool.Name(), volumeName, db.StoragePoolVolumeTypeCustom, srcPath)
 			if err != nil {
-				return "", errors.Wrapf(err, "Failed shifting storage volume %q of type %q on storage pool %q", volumeName, volumeTypeName, pool.Name())
+				return "", "", errors.Wrapf(err, "Failed shifting storage volume %q of type %q on storage pool %q", volumeName, volumeTypeName, pool.Name())
 			}
 		} else {
-			return "", fmt.Errorf("Only filesystem volumes are supported for containers")
+			return "", "", fmt.Errorf("Only filesystem volumes are supported for containers")
 		}
 	}
 
-	if vol.ContentType == db.StoragePoolVolumeContentTypeNameBlock {
+	if vol.ContentType == db.StoragePoolVolumeContentTypeNameBlock || vol.ContentType == db.StoragePoolVolumeContentTypeNameISO {
 		srcPath, err = pool.GetCustomVolumeDisk(storageProjectName, volumeName)
 		if err != nil {
-			return "", errors.Wrapf(err, "Failed to get disk path")
+			return "", "", errors.Wrapf(err, "Failed to get disk path")
 		}
 	}
 
-	return srcPath, nil
+	return srcPath, vol.ContentType, nil
 }
 
 // createDevice creates a disk device mount on host.
@@ -1479,6 +1685,30 @@ func (d *disk) postStop() error {
 		}
 	}
 
+	// Unbind the NVMe controller from vfio-pci and bind it back to the host driver.
+	if strings.HasPrefix(d.config["source"], diskSourceNVMePrefix) {
+		v := d.volatileGet()
+		if v["last_state.pci.slot.name"] != "" {
+			pciDev := pcidev.Device{
+				Driver:   "vfio-pci",
+				SlotName: v["last_state.pci.slot.name"],
+			}
+
+			err := pcidev.DeviceDriverOverride(pciDev, v["last_state.pci.driver"])
+			if err != nil {
+				return err
+			}
+		}
+
+		err := d.volatileSet(map[string]string{
+			"last_state.pci.slot.name": "",
+			"last_state.pci.driver":    "",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
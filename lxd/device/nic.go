@@ -3,6 +3,7 @@ package device
 import (
 	"fmt"
 
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/shared"
@@ -19,6 +20,7 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"mtu":                                  validate.Optional(validate.IsNetworkMTU),
 		"vlan":                                 validate.IsNetworkVLAN,
 		"gvrp":                                 validate.Optional(validate.IsBool),
+		"group":                                validate.Optional(validate.IsInterfaceName, func(value string) error { return nicCheckGroup(instConf, value) }),
 		"hwaddr":                               validate.IsNetworkMAC,
 		"host_name":                            validate.IsAny,
 		"limits.ingress":                       validate.IsAny,
@@ -28,13 +30,18 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"security.ipv4_filtering":              validate.IsAny,
 		"security.ipv6_filtering":              validate.IsAny,
 		"security.port_isolation":              validate.Optional(validate.IsBool),
+		"qos.dscp":                             validate.IsNetworkDSCP,
 		"maas.subnet.ipv4":                     validate.IsAny,
 		"maas.subnet.ipv6":                     validate.IsAny,
+		"maas.ipv4.address":                    validate.Optional(validate.IsNetworkAddressV4),
+		"maas.ipv6.address":                    validate.Optional(validate.IsNetworkAddressV6),
 		"ipv4.address":                         validate.Optional(validate.IsNetworkAddressV4),
 		"ipv6.address":                         validate.Optional(validate.IsNetworkAddressV6),
 		"ipv4.routes":                          validate.Optional(validate.IsNetworkV4List),
 		"ipv6.routes":                          validate.Optional(validate.IsNetworkV6List),
 		"boot.priority":                        validate.Optional(validate.IsUint32),
+		"dns.name":                             validate.Optional(validate.IsHostname),
+		"dns.aliases":                          validate.Optional(validate.IsHostnameList),
 		"ipv4.gateway":                         networkValidGateway,
 		"ipv6.gateway":                         networkValidGateway,
 		"ipv4.host_address":                    validate.Optional(validate.IsNetworkAddressV4),
@@ -43,6 +50,8 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 		"ipv6.host_table":                      validate.Optional(validate.IsUint32),
 		"ipv4.routes.external":                 validate.Optional(validate.IsNetworkV4List),
 		"ipv6.routes.external":                 validate.Optional(validate.IsNetworkV6List),
+		"ipv4.instance_routes":                 validate.Optional(validate.IsNetworkV4List),
+		"ipv6.instance_routes":                 validate.Optional(validate.IsNetworkV6List),
 		"security.acls":                        validate.IsAny,
 		"security.acls.default.ingress.action": validate.Optional(validate.IsOneOf(acl.ValidActions...)),
 		"security.acls.default.egress.action":  validate.Optional(validate.IsOneOf(acl.ValidActions...)),
@@ -94,6 +103,15 @@ func nicValidationRules(requiredFields []string, optionalFields []string, instCo
 	return validators
 }
 
+// nicSecurityDefaults applies the security settings of the NIC's managed network to the keys the NIC doesn't set.
+func nicSecurityDefaults(config deviceConfig.Device, netConfig map[string]string, keys ...string) {
+	for _, key := range keys {
+		if config[key] == "" && netConfig[key] != "" {
+			config[key] = netConfig[key]
+		}
+	}
+}
+
 // nicHasAutoGateway takes the value of the "ipv4.gateway" or "ipv6.gateway" config keys and returns whether they
 // specify whether the gateway mode is automatic or not
 func nicHasAutoGateway(value string) bool {
@@ -104,6 +122,43 @@ func nicHasAutoGateway(value string) bool {
 	return false
 }
 
+// nicCheckGroup checks that the NICs of the group in the instConf's expanded devices don't share a parent or a
+// network and don't filter MAC addresses, as they are meant to be bonded together inside the guest.
+func nicCheckGroup(instConf instance.ConfigReader, group string) error {
+	parents := map[string]string{}
+	networks := map[string]string{}
+
+	for _, entry := range instConf.ExpandedDevices().Sorted() {
+		devConfig := entry.Config
+		if devConfig["type"] != "nic" || devConfig["group"] != group {
+			continue
+		}
+
+		if shared.IsTrue(devConfig["security.mac_filtering"]) {
+			return fmt.Errorf("NIC %q of group %q cannot use MAC filtering", entry.Name, group)
+		}
+
+		if devConfig["network"] != "" {
+			other, found := networks[devConfig["network"]]
+			if found {
+				return fmt.Errorf("NICs %q and %q of group %q use the same network %q", other, entry.Name, group, devConfig["network"])
+			}
+
+			networks[devConfig["network"]] = entry.Name
+			continue
+		}
+
+		other, found := parents[devConfig["parent"]]
+		if found {
+			return fmt.Errorf("NICs %q and %q of group %q use the same parent %q", other, entry.Name, group, devConfig["parent"])
+		}
+
+		parents[devConfig["parent"]] = entry.Name
+	}
+
+	return nil
+}
+
 // nicCheckNamesUnique checks that all the NICs in the instConf's expanded devices have a unique (or unset) name.
 func nicCheckNamesUnique(instConf instance.ConfigReader) error {
 	seenNICNames := []string{}
@@ -26,6 +26,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/ip"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/ipam"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/resources"
@@ -38,6 +39,9 @@ import (
 	"github.com/lxc/lxd/shared/validate"
 )
 
+// nicBridgedSecurityKeys are the security keys which default to the value set on the managed network.
+var nicBridgedSecurityKeys = []string{"security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "security.port_isolation"}
+
 type nicBridged struct {
 	deviceCommon
 }
@@ -77,10 +81,15 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"security.ipv4_filtering",
 		"security.ipv6_filtering",
 		"security.port_isolation",
+		"qos.dscp",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
+		"maas.ipv4.address",
+		"maas.ipv6.address",
 		"boot.priority",
 		"vlan",
+		"dns.name",
+		"dns.aliases",
 	}
 
 	// checkWithManagedNetwork validates the device's settings against the managed network.
@@ -142,6 +151,11 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 					return fmt.Errorf("VLAN tagged ID 0 is not allowed for native Linux bridges")
 				}
 			}
+
+			// Check VLAN filtering hasn't been disabled on the managed bridge when VLANs are used.
+			if (d.config["vlan"] != "" || d.config["vlan.tagged"] != "") && netConfig["bridge.vlan_filtering"] != "" && !shared.IsTrue(netConfig["bridge.vlan_filtering"]) {
+				return fmt.Errorf(`Cannot use VLANs when "bridge.vlan_filtering" is disabled on network %q`, n.Name())
+			}
 		}
 
 		return nil
@@ -168,6 +182,9 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 			return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
 		}
 
+		// Apply the network's security defaults to the keys the NIC doesn't set.
+		nicSecurityDefaults(d.config, n.Config(), nicBridgedSecurityKeys...)
+
 		// Validate NIC settings with managed network.
 		err = checkWithManagedNetwork(n)
 		if err != nil {
@@ -200,6 +217,9 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		// project.Default is used here as bridge networks don't support projects.
 		n, _ = network.LoadByName(d.state, project.Default, d.config["parent"])
 		if n != nil {
+			// Apply the network's security defaults to the keys the NIC doesn't set.
+			nicSecurityDefaults(d.config, n.Config(), nicBridgedSecurityKeys...)
+
 			// Validate NIC settings with managed network.
 			err := checkWithManagedNetwork(n)
 			if err != nil {
@@ -387,13 +407,19 @@ func (d *nicBridged) UpdatableFields(oldDevice Type) []string {
 		return []string{}
 	}
 
-	return []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering"}
+	return []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "dns.name", "dns.aliases", "qos.dscp"}
 }
 
 // Add is run when a device is added to a non-snapshot instance whether or not the instance is running.
 func (d *nicBridged) Add() error {
+	// Allocate the addresses from the network's IPAM backend if needed.
+	_, err := d.ipamAllocate()
+	if err != nil {
+		return err
+	}
+
 	// Rebuild dnsmasq entry if needed and reload.
-	err := d.rebuildDnsmasqEntry()
+	err = d.rebuildDnsmasqEntry()
 	if err != nil {
 		return err
 	}
@@ -408,6 +434,20 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	// Allocate the addresses from the network's IPAM backend if not done yet (when the backend was configured
+	// after the device was added).
+	allocated, err := d.ipamAllocate()
+	if err != nil {
+		return nil, err
+	}
+
+	if allocated {
+		err = d.rebuildDnsmasqEntry()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -446,7 +486,7 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 	}
 
 	// Apply host-side limits.
-	err = networkSetupHostVethLimits(d.config)
+	err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 	if err != nil {
 		return nil, err
 	}
@@ -465,6 +505,13 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 	}
 	revert.Add(func() { d.removeFilters(d.config) })
 
+	// Apply host-side DSCP marking (uses enriched host_name from networkVethFillFromVolatile).
+	err = d.setupHostDSCP(nil)
+	if err != nil {
+		return nil, err
+	}
+	revert.Add(func() { d.removeHostDSCP() })
+
 	// Attach host side veth interface to bridge.
 	err = network.AttachInterface(d.config["parent"], saveData["host_name"])
 	if err != nil {
@@ -566,7 +613,7 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 		}
 
 		// Apply host-side limits.
-		err = networkSetupHostVethLimits(d.config)
+		err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 		if err != nil {
 			return err
 		}
@@ -576,6 +623,12 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 		if err != nil {
 			return err
 		}
+
+		// Apply host-side DSCP marking.
+		err = d.setupHostDSCP(oldConfig)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Rebuild dnsmasq entry if needed and reload.
@@ -639,6 +692,10 @@ func (d *nicBridged) postStop() error {
 	networkNICRouteDelete(d.config["parent"], append(util.SplitNTrimSpace(d.config["ipv4.routes"], ",", -1, true), util.SplitNTrimSpace(d.config["ipv6.routes"], ",", -1, true)...)...)
 	d.removeFilters(d.config)
 
+	if d.config["qos.dscp"] != "" {
+		d.removeHostDSCP()
+	}
+
 	return nil
 }
 
@@ -670,9 +727,145 @@ func (d *nicBridged) Remove() error {
 		}
 	}
 
+	// Give the addresses back to the network's IPAM backend.
+	err := d.ipamRelease()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// ipamRequest returns the IPAM request for the device.
+func (d *nicBridged) ipamRequest(subnet string, address string) ipam.Request {
+	return ipam.Request{
+		Network:  d.config["parent"],
+		Project:  d.inst.Project(),
+		Instance: d.inst.Name(),
+		Device:   d.name,
+		MAC:      d.config["hwaddr"],
+		Subnet:   subnet,
+		Address:  address,
+	}
+}
+
+// ipamAllocate allocates the addresses not set statically from the IPAM backend of the parent network, if the
+// network uses an external one. Returns whether any address was allocated.
+func (d *nicBridged) ipamAllocate() (bool, error) {
+	// Use project.Default here as bridge networks don't support projects.
+	n, err := network.LoadByName(d.state, project.Default, d.config["parent"])
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	backend, err := ipam.Load(n.Config())
+	if err != nil {
+		return false, err
+	}
+
+	if backend == nil {
+		return false, nil
+	}
+
+	// Static IPv6 addresses can only be handed out through stateful DHCPv6.
+	subnets := map[string]*net.IPNet{"ipv4.address": n.DHCPv4Subnet()}
+	if shared.IsTrue(n.Config()["ipv6.dhcp.stateful"]) {
+		subnets["ipv6.address"] = n.DHCPv6Subnet()
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	allocated := map[string]string{}
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		subnet := subnets[key]
+
+		// Skip the addresses set statically or already allocated.
+		if subnet == nil || d.config[key] != "" {
+			continue
+		}
+
+		ip, err := backend.Allocate(d.ipamRequest(subnet.String(), ""), subnet)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed allocating %q from IPAM", key)
+		}
+
+		if ip == nil {
+			continue
+		}
+
+		revert.Add(func() { backend.Release(d.ipamRequest(subnet.String(), ip.String())) })
+		allocated[fmt.Sprintf("ipam.%s", key)] = ip.String()
+	}
+
+	if len(allocated) == 0 {
+		return false, nil
+	}
+
+	err = d.volatileSet(allocated)
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range allocated {
+		d.config[strings.TrimPrefix(k, "ipam.")] = v
+	}
+
+	logger.Debug("Allocated addresses from IPAM", log.Ctx{"project": d.inst.Project(), "instance": d.inst.Name(), "device": d.name, "addresses": allocated})
+
+	revert.Success()
+	return true, nil
+}
+
+// ipamRelease gives the addresses allocated by ipamAllocate back to the IPAM backend of the parent network.
+func (d *nicBridged) ipamRelease() error {
+	v := d.volatileGet()
+
+	keys := []string{}
+	for _, key := range []string{"ipam.ipv4.address", "ipam.ipv6.address"} {
+		if v[key] != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// The addresses can't be released anymore if the network is gone.
+	n, err := network.LoadByName(d.state, project.Default, d.config["parent"])
+	if err != nil && err != db.ErrNoSuchObject {
+		return err
+	}
+
+	if n != nil {
+		backend, err := ipam.Load(n.Config())
+		if err != nil {
+			return err
+		}
+
+		if backend != nil {
+			for _, key := range keys {
+				err = backend.Release(d.ipamRequest("", v[key]))
+				if err != nil {
+					return errors.Wrapf(err, "Failed releasing %q to IPAM", v[key])
+				}
+			}
+		}
+	}
+
+	clear := map[string]string{}
+	for _, key := range keys {
+		clear[key] = ""
+	}
+
+	return d.volatileSet(clear)
+}
+
 // rebuildDnsmasqEntry rebuilds the dnsmasq host entry if connected to a LXD managed network and reloads dnsmasq.
 func (d *nicBridged) rebuildDnsmasqEntry() error {
 	// Rebuild dnsmasq config if a bridged device has changed and parent is a managed network.
@@ -710,7 +903,12 @@ func (d *nicBridged) rebuildDnsmasqEntry() error {
 		}
 	}
 
-	err = dnsmasq.UpdateStaticEntry(d.config["parent"], d.inst.Project(), d.inst.Name(), netConfig, d.config["hwaddr"], ipv4Address, ipv6Address)
+	err = dnsmasq.UpdateStaticEntry(d.config["parent"], d.inst.Project(), d.inst.Name(), d.config["dns.name"], netConfig, d.config["hwaddr"], ipv4Address, ipv6Address)
+	if err != nil {
+		return err
+	}
+
+	err = dnsmasq.UpdateAliasesEntry(d.config["parent"], d.inst.Project(), d.inst.Name(), netConfig, util.SplitNTrimSpace(d.config["dns.aliases"], ",", -1, true), ipv4Address, ipv6Address)
 	if err != nil {
 		return err
 	}
@@ -756,6 +954,39 @@ func (d *nicBridged) setupHostFilters(oldConfig deviceConfig.Device) error {
 	return nil
 }
 
+// setupHostDSCP applies the DSCP marking of the traffic coming from the instance on the host side interface.
+// This is controlled by the qos.dscp config key.
+func (d *nicBridged) setupHostDSCP(oldConfig deviceConfig.Device) error {
+	// Remove any old marking if non-empty oldConfig supplied as part of update.
+	if oldConfig != nil && oldConfig["qos.dscp"] != "" {
+		d.removeHostDSCP()
+	}
+
+	if d.config["qos.dscp"] == "" {
+		return nil
+	}
+
+	// The marking is done by the firewall's bridge family rules which don't see traffic on openvswitch bridges.
+	if !network.IsNativeBridge(d.config["parent"]) {
+		return fmt.Errorf("qos.dscp requires a native Linux bridge parent")
+	}
+
+	err := d.state.Firewall.InstanceSetupDSCP(d.inst.Project(), d.inst.Name(), d.name, d.config["host_name"], d.config["qos.dscp"])
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// removeHostDSCP removes any DSCP marking set up for the instance.
+func (d *nicBridged) removeHostDSCP() {
+	err := d.state.Firewall.InstanceClearDSCP(d.inst.Project(), d.inst.Name(), d.name)
+	if err != nil {
+		logger.Errorf("Failed to remove DSCP marking for %q: %v", d.name, err)
+	}
+}
+
 // removeFilters removes any network level filters defined for the instance.
 func (d *nicBridged) removeFilters(m deviceConfig.Device) {
 	if m["hwaddr"] == "" {
@@ -863,6 +1094,7 @@ func (d *nicBridged) setFilters() (err error) {
 		opts := &dhcpalloc.Options{
 			ProjectName: d.inst.Project(),
 			HostName:    d.inst.Name(),
+			DNSName:     d.config["dns.name"],
 			HostMAC:     mac,
 			Network:     n,
 		}
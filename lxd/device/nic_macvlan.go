@@ -44,8 +44,11 @@ func (d *nicMACVLAN) validateConfig(instConf instance.ConfigReader) error {
 		"mtu",
 		"hwaddr",
 		"vlan",
+		"security.mac_filtering",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
+		"maas.ipv4.address",
+		"maas.ipv6.address",
 		"boot.priority",
 		"gvrp",
 	}
@@ -88,11 +91,18 @@ func (d *nicMACVLAN) validateConfig(instConf instance.ConfigReader) error {
 				d.config[inheritKey] = netConfig[inheritKey]
 			}
 		}
+
+		nicSecurityDefaults(d.config, netConfig, "security.mac_filtering")
 	} else {
 		// If no network property supplied, then parent property is required.
 		requiredFields = append(requiredFields, "parent")
 	}
 
+	// The filter is applied to the host-side macvtap interface, containers could change their MAC address.
+	if instConf.Type() == instancetype.Container && shared.IsTrue(d.config["security.mac_filtering"]) {
+		return fmt.Errorf("MAC filtering on macvlan NICs is only supported for virtual machines")
+	}
+
 	err := d.config.Validate(nicValidationRules(requiredFields, optionalFields, instConf))
 	if err != nil {
 		return err
@@ -208,6 +218,14 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed to bring up interface %s: %v", saveData["host_name"], err)
 		}
+
+		// Drop frames sent by the instance with a different MAC address.
+		if shared.IsTrue(d.config["security.mac_filtering"]) {
+			err := d.setupMACFilter(saveData["host_name"])
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed setting up MAC filter on %q", saveData["host_name"])
+			}
+		}
 	}
 
 	err = d.volatileSet(saveData)
@@ -235,6 +253,30 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 	return &runConf, nil
 }
 
+// setupMACFilter only lets frames from the NIC's MAC address leave the macvtap interface. The frames sent by the
+// instance are transmitted through the macvtap interface, so the filter applies to its egress.
+func (d *nicMACVLAN) setupMACFilter(hostName string) error {
+	qdisc := &ip.QdiscClsact{Dev: hostName}
+	err := qdisc.Add()
+	if err != nil {
+		return err
+	}
+
+	filters := []ip.FlowerFilter{
+		{Filter: ip.Filter{Dev: hostName, Parent: "ffff:fff3", Protocol: "all"}, Priority: "1", SrcMAC: d.config["hwaddr"], Action: "pass"},
+		{Filter: ip.Filter{Dev: hostName, Parent: "ffff:fff3", Protocol: "all"}, Priority: "2", Action: "drop"},
+	}
+
+	for _, filter := range filters {
+		err = filter.Add()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *nicMACVLAN) Stop() (*deviceConfig.RunConfig, error) {
 	v := d.volatileGet()
@@ -283,3 +325,26 @@ func (d *nicMACVLAN) postStop() error {
 
 	return nil
 }
+
+// HostState gets the host-side state of a macvlan NIC. The macvtap interface of VMs stays on the host, whereas only
+// the carrier and speed of the parent interface are available for containers.
+func (d *nicMACVLAN) HostState() (*api.InstanceStateNetwork, error) {
+	v := d.volatileGet()
+
+	if v["host_name"] != "" && network.InterfaceExists(v["host_name"]) {
+		return networkHostLinkState(v["host_name"])
+	}
+
+	parent, err := networkHostLinkState(network.GetHostDevice(d.config["parent"], d.config["vlan"]))
+	if err != nil {
+		return nil, err
+	}
+
+	state := api.InstanceStateNetwork{
+		Addresses: []api.InstanceStateNetworkAddress{},
+		Carrier:   parent.Carrier,
+		LinkSpeed: parent.LinkSpeed,
+	}
+
+	return &state, nil
+}
@@ -55,7 +55,7 @@ func (d *nicOVN) UpdatableFields(oldDevice Type) []string {
 		return []string{}
 	}
 
-	return []string{"security.acls"}
+	return []string{"security.acls", "qos.dscp"}
 }
 
 // getIntegrationBridgeName returns the OVS integration bridge to use.
@@ -95,6 +95,7 @@ func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
 		"security.acls.default.egress.action",
 		"security.acls.default.ingress.logged",
 		"security.acls.default.egress.logged",
+		"qos.dscp",
 	}
 
 	// The NIC's network may be a non-default project, so lookup project and get network's project name.
@@ -274,7 +275,7 @@ func (d *nicOVN) Start() (*deviceConfig.RunConfig, error) {
 	networkVethFillFromVolatile(d.config, saveData)
 
 	// Apply host-side limits.
-	err = networkSetupHostVethLimits(d.config)
+	err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 	if err != nil {
 		return nil, err
 	}
@@ -385,8 +386,8 @@ func (d *nicOVN) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		}
 	}
 
-	// Apply any changes needed when assigned ACLs change.
-	if d.config["security.acls"] != oldConfig["security.acls"] {
+	// Apply any changes needed when assigned ACLs or DSCP marking change.
+	if d.config["security.acls"] != oldConfig["security.acls"] || d.config["qos.dscp"] != oldConfig["qos.dscp"] {
 		// Work out which ACLs have been removed and remove logical port from those groups.
 		oldACLs := util.SplitNTrimSpace(oldConfig["security.acls"], ",", -1, true)
 		newACLs := util.SplitNTrimSpace(d.config["security.acls"], ",", -1, true)
@@ -111,7 +111,7 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 	}
 
 	// Apply host-side limits.
-	err = networkSetupHostVethLimits(d.config)
+	err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +169,7 @@ func (d *nicP2P) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 	}
 
 	// Apply host-side limits.
-	err = networkSetupHostVethLimits(d.config)
+	err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 	if err != nil {
 		return err
 	}
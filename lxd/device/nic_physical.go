@@ -36,8 +36,11 @@ func (d *nicPhysical) validateConfig(instConf instance.ConfigReader) error {
 		"name",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
+		"maas.ipv4.address",
+		"maas.ipv6.address",
 		"boot.priority",
 		"gvrp",
+		"group",
 	}
 
 	if instConf.Type() == instancetype.Container || instConf.Type() == instancetype.Any {
@@ -66,6 +69,13 @@ func (d *nicPhysical) validateEnvironment() error {
 		return fmt.Errorf("Parent device '%s' doesn't exist", d.config["parent"])
 	}
 
+	if d.config["group"] != "" {
+		err := nicGroupCheckPFs(d.state, d.inst, d.config["group"])
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -2,6 +2,7 @@ package device
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
 
@@ -68,12 +69,16 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 		"ipv6.host_address",
 		"ipv4.host_table",
 		"ipv6.host_table",
+		"ipv4.instance_routes",
+		"ipv6.instance_routes",
 		"gvrp",
 	}
 
 	rules := nicValidationRules(requiredFields, optionalFields, instConf)
 	rules["ipv4.address"] = validate.Optional(validate.IsNetworkAddressV4List)
 	rules["ipv6.address"] = validate.Optional(validate.IsNetworkAddressV6List)
+	rules["ipv4.host_table"] = validate.Optional(nicRoutedValidTables)
+	rules["ipv6.host_table"] = validate.Optional(nicRoutedValidTables)
 	rules["gvrp"] = validate.Optional(validate.IsBool)
 
 	err = d.config.Validate(rules)
@@ -97,6 +102,25 @@ func (d *nicRouted) validateConfig(instConf instance.ConfigReader) error {
 		}
 	}
 
+	// Routes inside the instance go through the host-side address, so need an address of the same family.
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if d.config[fmt.Sprintf("%s.instance_routes", family)] != "" && d.config[fmt.Sprintf("%s.address", family)] == "" {
+			return fmt.Errorf("%s.instance_routes requires %s.address to be set", family, family)
+		}
+	}
+
+	return nil
+}
+
+// nicRoutedValidTables validates a comma separated list of routing table IDs.
+func nicRoutedValidTables(value string) error {
+	for _, table := range util.SplitNTrimSpace(value, ",", -1, true) {
+		err := validate.IsUint32(table)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid routing table %q", table)
+		}
+	}
+
 	return nil
 }
 
@@ -335,7 +359,7 @@ func (d *nicRouted) Update(oldDevices deviceConfig.Devices, isRunning bool) erro
 		networkVethFillFromVolatile(d.config, v)
 
 		// Apply host-side limits.
-		err = networkSetupHostVethLimits(d.config)
+		err = networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 		if err != nil {
 			return err
 		}
@@ -352,7 +376,7 @@ func (d *nicRouted) postStart() error {
 	networkVethFillFromVolatile(d.config, v)
 
 	// Apply host-side limits.
-	err := networkSetupHostVethLimits(d.config)
+	err := networkSetupHostVethLimits(d.state, d.inst.Project(), d.config)
 	if err != nil {
 		return err
 	}
@@ -389,17 +413,17 @@ func (d *nicRouted) postStart() error {
 			return err
 		}
 
-		// Add static routes to instance IPs to custom routing tables if specified.
+		// Add static routes to instance IPs to each of the custom routing tables if specified.
 		// This is in addition to the static route added by liblxc to the main routing table, which
 		// is still critical to ensure that reverse path filtering doesn't kick in blocking traffic
 		// from the instance.
-		if d.config["ipv4.host_table"] != "" {
+		for _, table := range util.SplitNTrimSpace(d.config["ipv4.host_table"], ",", -1, true) {
 			for _, addr := range strings.Split(d.config["ipv4.address"], ",") {
 				addr = strings.TrimSpace(addr)
 				r := &ip.Route{
 					DevName: d.config["host_name"],
 					Route:   fmt.Sprintf("%s/32", addr),
-					Table:   d.config["ipv4.host_table"],
+					Table:   table,
 					Family:  ip.FamilyV4,
 				}
 				err := r.Add()
@@ -424,17 +448,17 @@ func (d *nicRouted) postStart() error {
 			return err
 		}
 
-		// Add static routes to instance IPs to custom routing tables if specified.
+		// Add static routes to instance IPs to each of the custom routing tables if specified.
 		// This is in addition to the static route added by liblxc to the main routing table, which
 		// is still critical to ensure that reverse path filtering doesn't kick in blocking traffic
 		// from the instance.
-		if d.config["ipv6.host_table"] != "" {
+		for _, table := range util.SplitNTrimSpace(d.config["ipv6.host_table"], ",", -1, true) {
 			for _, addr := range strings.Split(d.config["ipv6.address"], ",") {
 				addr = strings.TrimSpace(addr)
 				r := &ip.Route{
 					DevName: d.config["host_name"],
 					Route:   fmt.Sprintf("%s/128", addr),
-					Table:   d.config["ipv6.host_table"],
+					Table:   table,
 					Family:  ip.FamilyV6,
 				}
 				err := r.Add()
@@ -445,6 +469,25 @@ func (d *nicRouted) postStart() error {
 		}
 	}
 
+	// Add the static routes inside the instance, going through the host-side addresses.
+	for _, family := range []string{"4", "6"} {
+		routes := util.SplitNTrimSpace(d.config[fmt.Sprintf("ipv%s.instance_routes", family)], ",", -1, true)
+		if len(routes) == 0 {
+			continue
+		}
+
+		gateway := d.ipv4HostAddress()
+		if family == "6" {
+			gateway = d.ipv6HostAddress()
+		}
+
+		args := []string{"forknet", "route", "--", fmt.Sprintf("/proc/%d/ns/net", d.inst.InitPID()), family, d.config["name"], gateway}
+		_, err := shared.RunCommand(d.state.OS.ExecPath, append(args, routes...)...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding IPv%s routes inside the instance", family)
+		}
+	}
+
 	return nil
 }
 
@@ -551,3 +594,50 @@ func (d *nicRouted) isUniqueWithGatewayAutoMode(instConf instance.ConfigReader)
 
 	return nil
 }
+
+// NICRoutedNeighProxyRepair re-adds the proxy neighbour entries of the routed NICs of a running instance which went
+// missing from their parent interface, for instance because the parent was reconfigured by another tool.
+// Returns the addresses whose entry was re-added.
+func NICRoutedNeighProxyRepair(inst instance.Instance) ([]string, error) {
+	repaired := []string{}
+
+	for _, devConfig := range inst.ExpandedDevices() {
+		if devConfig["type"] != "nic" || devConfig["nictype"] != "routed" || devConfig["parent"] == "" {
+			continue
+		}
+
+		parentName := network.GetHostDevice(devConfig["parent"], devConfig["vlan"])
+		if !network.InterfaceExists(parentName) {
+			continue
+		}
+
+		neigh := &ip.Neigh{DevName: parentName}
+		entries, err := neigh.ShowProxy()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed listing proxy neighbours on %q", parentName)
+		}
+
+		existing := make(map[string]struct{}, len(entries))
+		for _, entry := range entries {
+			existing[net.ParseIP(entry).String()] = struct{}{}
+		}
+
+		addresses := append(util.SplitNTrimSpace(devConfig["ipv4.address"], ",", -1, true), util.SplitNTrimSpace(devConfig["ipv6.address"], ",", -1, true)...)
+		for _, addr := range addresses {
+			_, found := existing[net.ParseIP(addr).String()]
+			if found {
+				continue
+			}
+
+			neigh := &ip.Neigh{DevName: parentName, Proxy: addr}
+			err := neigh.AddProxy()
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed adding proxy neighbour %q on %q", addr, parentName)
+			}
+
+			repaired = append(repaired, addr)
+		}
+	}
+
+	return repaired, nil
+}
@@ -3,6 +3,7 @@ package device
 import (
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -49,7 +50,10 @@ func (d *nicSRIOV) validateConfig(instConf instance.ConfigReader) error {
 		"security.mac_filtering",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
+		"maas.ipv4.address",
+		"maas.ipv6.address",
 		"boot.priority",
+		"group",
 	}
 
 	// Check that if network proeperty is set that conflicting keys are not present.
@@ -90,6 +94,12 @@ func (d *nicSRIOV) validateConfig(instConf instance.ConfigReader) error {
 				d.config[inheritKey] = netConfig[inheritKey]
 			}
 		}
+
+		nicSecurityDefaults(d.config, netConfig, "security.mac_filtering")
+
+		if d.config["group"] != "" && shared.IsTrue(d.config["security.mac_filtering"]) {
+			return fmt.Errorf("NICs of a group cannot use MAC filtering (enabled on network %q)", d.config["network"])
+		}
 	} else {
 		// If no network property supplied, then parent property is required.
 		requiredFields = append(requiredFields, "parent")
@@ -122,6 +132,13 @@ func (d *nicSRIOV) validateEnvironment() error {
 		return fmt.Errorf("Parent device %q doesn't exist", d.config["parent"])
 	}
 
+	if d.config["group"] != "" {
+		err := nicGroupCheckPFs(d.state, d.inst, d.config["group"])
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -524,3 +541,62 @@ func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 	revert.Success()
 	return nil
 }
+
+// HostState gets the host-side state of a SR-IOV NIC from its parent and virtual function (VF).
+func (d *nicSRIOV) HostState() (*api.InstanceStateNetwork, error) {
+	v := d.volatileGet()
+
+	// Nothing to report until a VF has been allocated.
+	if v["last_state.vf.id"] == "" {
+		return nil, nil
+	}
+
+	vfID, err := strconv.Atoi(v["last_state.vf.id"])
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := networkHostLinkState(d.config["parent"])
+	if err != nil {
+		return nil, err
+	}
+
+	link := &ip.Link{Name: d.config["parent"]}
+	vfInfo, err := link.GetVFInfo(vfID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed getting VF %d info on %q", vfID, d.config["parent"])
+	}
+
+	hwaddr := vfInfo.Address
+	if hwaddr == "" {
+		hwaddr = vfInfo.MAC
+	}
+
+	state := api.InstanceStateNetwork{
+		Addresses: []api.InstanceStateNetworkAddress{},
+		Hwaddr:    hwaddr,
+		State:     parent.State,
+		Type:      "broadcast",
+		Carrier:   parent.Carrier,
+		LinkSpeed: parent.LinkSpeed,
+	}
+
+	// The link state of the VF can be forced down independently from the parent.
+	if vfInfo.LinkState == "disable" {
+		state.State = "down"
+		state.Carrier = false
+	}
+
+	// Not all drivers report the counters of the VFs.
+	stats, err := link.GetVFStats(vfID)
+	if err == nil {
+		state.Counters = api.InstanceStateNetworkCounters{
+			BytesReceived:   int64(stats.RxBytes),
+			BytesSent:       int64(stats.TxBytes),
+			PacketsReceived: int64(stats.RxPackets),
+			PacketsSent:     int64(stats.TxPackets),
+		}
+	}
+
+	return &state, nil
+}
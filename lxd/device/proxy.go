@@ -48,6 +48,7 @@ type proxyProcInfo struct {
 	securityUID    string
 	securityGID    string
 	proxyProtocol  string
+	listenIface    string
 	inheritFds     []*os.File
 }
 
@@ -72,17 +73,36 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return nil
 	}
 
+	// The PROXY protocol version can be selected with "v1" or "v2", boolean values enable version 1.
+	validateProxyProtocol := func(input string) error {
+		if input == "v1" || input == "v2" {
+			return nil
+		}
+
+		return validate.IsBool(input)
+	}
+
+	// NAT mode is enabled with a boolean or with "nft" to require the rules to be implemented with nftables.
+	validateNAT := func(input string) error {
+		if input == "nft" {
+			return nil
+		}
+
+		return validate.IsBool(input)
+	}
+
 	rules := map[string]func(string) error{
-		"listen":         validateAddr,
-		"connect":        validateAddr,
-		"bind":           validateBind,
-		"mode":           unixValidOctalFileMode,
-		"nat":            validate.Optional(validate.IsBool),
-		"gid":            unixValidUserID,
-		"uid":            unixValidUserID,
-		"security.uid":   unixValidUserID,
-		"security.gid":   unixValidUserID,
-		"proxy_protocol": validate.Optional(validate.IsBool),
+		"listen":           validateAddr,
+		"connect":          validateAddr,
+		"bind":             validateBind,
+		"mode":             unixValidOctalFileMode,
+		"nat":              validate.Optional(validateNAT),
+		"gid":              unixValidUserID,
+		"uid":              unixValidUserID,
+		"security.uid":     unixValidUserID,
+		"security.gid":     unixValidUserID,
+		"proxy_protocol":   validate.Optional(validateProxyProtocol),
+		"listen_interface": validate.Optional(validate.IsInterfaceName),
 	}
 
 	err := d.config.Validate(rules)
@@ -90,7 +110,7 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return err
 	}
 
-	if instConf.Type() == instancetype.VM && !shared.IsTrue(d.config["nat"]) {
+	if instConf.Type() == instancetype.VM && !d.isNAT() {
 		return fmt.Errorf("Only NAT mode is supported for proxies on VM instances")
 	}
 
@@ -109,16 +129,26 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf("Cannot map a single port to multiple ports")
 	}
 
-	if shared.IsTrue(d.config["proxy_protocol"]) && (!strings.HasPrefix(d.config["connect"], "tcp") || shared.IsTrue(d.config["nat"])) {
+	if ProxyProtocolVersion(d.config["proxy_protocol"]) != "" && (!strings.HasPrefix(d.config["connect"], "tcp") || d.isNAT()) {
 		return fmt.Errorf("The PROXY header can only be sent to tcp servers in non-nat mode")
 	}
 
+	if d.config["listen_interface"] != "" {
+		if d.config["bind"] != "" && d.config["bind"] != "host" {
+			return fmt.Errorf("Only host-bound proxies can listen on a specific interface")
+		}
+
+		if listenAddr.ConnType == "unix" || d.isNAT() {
+			return fmt.Errorf("Listening on a specific interface is only supported for tcp and udp in non-nat mode")
+		}
+	}
+
 	if (!strings.HasPrefix(d.config["listen"], "unix:") || strings.HasPrefix(d.config["listen"], "unix:@")) &&
 		(d.config["uid"] != "" || d.config["gid"] != "" || d.config["mode"] != "") {
 		return fmt.Errorf("Only proxy devices for non-abstract unix sockets can carry uid, gid, or mode properties")
 	}
 
-	if shared.IsTrue(d.config["nat"]) {
+	if d.isNAT() {
 		if d.config["bind"] != "" && d.config["bind"] != "host" {
 			return fmt.Errorf("Only host-bound proxies can use NAT")
 		}
@@ -169,12 +199,25 @@ func (d *proxy) validateConfig(instConf instance.ConfigReader) error {
 	return nil
 }
 
+// isNAT returns whether the proxy is implemented with NAT rules rather than a forkproxy process.
+func (d *proxy) isNAT() bool {
+	return d.config["nat"] == "nft" || shared.IsTrue(d.config["nat"])
+}
+
 // validateEnvironment checks the runtime environment for correctness.
 func (d *proxy) validateEnvironment() error {
 	if d.name == "" {
 		return fmt.Errorf("Device name cannot be empty")
 	}
 
+	if d.config["listen_interface"] != "" && !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", d.config["listen_interface"])) {
+		return fmt.Errorf("Listen interface %q doesn't exist", d.config["listen_interface"])
+	}
+
+	if d.config["nat"] == "nft" && d.state.Firewall.String() != "nftables" {
+		return fmt.Errorf("Proxy NAT mode %q requires the nftables firewall driver (currently using %s)", d.config["nat"], d.state.Firewall.String())
+	}
+
 	return nil
 }
 
@@ -189,7 +232,7 @@ func (d *proxy) Start() (*deviceConfig.RunConfig, error) {
 	runConf := deviceConfig.RunConfig{}
 	runConf.PostHooks = []func() error{
 		func() error {
-			if shared.IsTrue(d.config["nat"]) {
+			if d.isNAT() {
 				return d.setupNAT()
 			}
 
@@ -225,6 +268,7 @@ func (d *proxy) Start() (*deviceConfig.RunConfig, error) {
 				proxyValues.securityGID,
 				proxyValues.securityUID,
 				proxyValues.proxyProtocol,
+				proxyValues.listenIface,
 			}
 
 			p, err := subprocess.NewProcess(command, forkproxyargs, logPath, logPath)
@@ -557,7 +601,8 @@ func (d *proxy) setupProxyProcInfo() (*proxyProcInfo, error) {
 		listenAddrMode: listenAddrMode,
 		securityGID:    d.config["security.gid"],
 		securityUID:    d.config["security.uid"],
-		proxyProtocol:  d.config["proxy_protocol"],
+		proxyProtocol:  ProxyProtocolVersion(d.config["proxy_protocol"]),
+		listenIface:    d.config["listen_interface"],
 		inheritFds:     inheritFd,
 	}
 
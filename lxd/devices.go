@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -8,6 +9,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/sys/unix"
 
@@ -15,11 +18,16 @@ import (
 	_ "github.com/lxc/lxd/lxd/include"
 
 	"github.com/lxc/lxd/lxd/cgroup"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/device"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/shared"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
@@ -61,14 +69,18 @@ type deviceTaskCPU struct {
 	id    int64
 	strId string
 	count *int
+	load  float64
 }
 type deviceTaskCPUs []deviceTaskCPU
 
-func (c deviceTaskCPUs) Len() int           { return len(c) }
-func (c deviceTaskCPUs) Less(i, j int) bool { return *c[i].count < *c[j].count }
-func (c deviceTaskCPUs) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c deviceTaskCPUs) Len() int { return len(c) }
+func (c deviceTaskCPUs) Less(i, j int) bool {
+	// A fully loaded CPU weighs as much as one extra instance.
+	return float64(*c[i].count)+c[i].load < float64(*c[j].count)+c[j].load
+}
+func (c deviceTaskCPUs) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 
-func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent, chan device.UnixHotplugEvent, error) {
+func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent, chan device.UnixHotplugEvent, chan []string, error) {
 	NETLINK_KOBJECT_UEVENT := 15
 	UEVENT_BUFFER_SIZE := 2048
 
@@ -77,7 +89,7 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 		NETLINK_KOBJECT_UEVENT,
 	)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 
 	nl := unix.SockaddrNetlink{
@@ -88,15 +100,16 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 
 	err = unix.Bind(fd, &nl)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 
 	chCPU := make(chan []string, 1)
 	chNetwork := make(chan []string, 0)
 	chUSB := make(chan device.USBEvent)
 	chUnix := make(chan device.UnixHotplugEvent)
+	chUnixPaths := make(chan []string)
 
-	go func(chCPU chan []string, chNetwork chan []string, chUSB chan device.USBEvent, chUnix chan device.UnixHotplugEvent) {
+	go func(chCPU chan []string, chNetwork chan []string, chUSB chan device.USBEvent, chUnix chan device.UnixHotplugEvent, chUnixPaths chan []string) {
 		b := make([]byte, UEVENT_BUFFER_SIZE*2)
 		for {
 			r, err := unix.Read(fd, b)
@@ -238,6 +251,17 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 				chUSB <- usb
 			}
 
+			// unix-char and unix-block devices are matched on the device node and symlinks created by udev
+			if udevEvent && (props["ACTION"] == "add" || props["ACTION"] == "remove") && props["DEVNAME"] != "" {
+				paths := []string{props["DEVNAME"]}
+				if !strings.HasPrefix(paths[0], "/") {
+					paths[0] = filepath.Join("/dev", paths[0])
+				}
+
+				paths = append(paths, strings.Fields(props["DEVLINKS"])...)
+				chUnixPaths <- paths
+			}
+
 			// unix hotplug device events rely on information added by udev
 			if udevEvent {
 				action := props["ACTION"]
@@ -311,12 +335,37 @@ func deviceNetlinkListener() (chan []string, chan []string, chan device.USBEvent
 			}
 
 		}
-	}(chCPU, chNetwork, chUSB, chUnix)
+	}(chCPU, chNetwork, chUSB, chUnix, chUnixPaths)
+
+	return chCPU, chNetwork, chUSB, chUnix, chUnixPaths, nil
+}
 
-	return chCPU, chNetwork, chUSB, chUnix, nil
+// deviceTaskBalanceChange records a change of CPU pinning applied to an instance by the balancer.
+type deviceTaskBalanceChange struct {
+	inst instance.Instance
+	old  string
+	new  string
 }
 
+// deviceTaskVMPin is the CPU set last applied to the vCPUs of a running VM.
+type deviceTaskVMPin struct {
+	pid  int // QEMU process the CPU set was applied to.
+	cpus string
+}
+
+// deviceTaskVMPinning tracks the CPU sets last applied to the vCPUs of running VMs.
+var deviceTaskVMPinning = map[string]deviceTaskVMPin{}
+var deviceTaskVMPinningMu sync.Mutex
+
 func deviceTaskBalance(s *state.State) {
+	deviceTaskBalanceInstances(s, nil)
+}
+
+// deviceTaskBalanceInstances spreads the instances which aren't pinned to specific CPUs over the available CPUs.
+// When provided, the observed per-CPU load (in CPU worth of time between 0 and 1) is taken into account on top
+// of the number of instances already using each CPU. Virtual machines are only considered when the periodic
+// rebalancing is enabled. Returns the list of pinning changes which were applied.
+func deviceTaskBalanceInstances(s *state.State, load map[int64]float64) []deviceTaskBalanceChange {
 	min := func(x, y int) int {
 		if x < y {
 			return x
@@ -326,14 +375,14 @@ func deviceTaskBalance(s *state.State) {
 
 	// Don't bother running when CGroup support isn't there
 	if !s.OS.CGInfo.Supports(cgroup.CPUSet, nil) {
-		return
+		return nil
 	}
 
 	// Get effective cpus list - those are all guaranteed to be online
 	cg, err := cgroup.NewFileReadWriter(1, true)
 	if err != nil {
 		logger.Errorf("Unable to load cgroup writer: %v", err)
-		return
+		return nil
 	}
 
 	effectiveCpus, err := cg.GetEffectiveCpuset()
@@ -342,14 +391,14 @@ func deviceTaskBalance(s *state.State) {
 		effectiveCpus, err = cg.GetCpuset()
 		if err != nil {
 			logger.Errorf("Error reading host's cpuset.cpus")
-			return
+			return nil
 		}
 	}
 
 	effectiveCpusInt, err := resources.ParseCpuset(effectiveCpus)
 	if err != nil {
 		logger.Errorf("Error parsing effective CPU set")
-		return
+		return nil
 	}
 
 	isolatedCpusInt := resources.GetCPUIsolated()
@@ -366,39 +415,81 @@ func deviceTaskBalance(s *state.State) {
 	cpus, err := resources.ParseCpuset(effectiveCpus)
 	if err != nil {
 		logger.Error("Error parsing host's cpu set", log.Ctx{"cpuset": effectiveCpus, "err": err})
-		return
+		return nil
+	}
+
+	// Only include virtual machines when the periodic rebalancing is enabled.
+	instanceType := instancetype.Container
+	interval, err := cluster.MemberConfigGetInt64(s.Cluster, "scheduler.cpu_rebalance_interval")
+	if err == nil && interval > 0 {
+		instanceType = instancetype.Any
 	}
 
 	// Iterate through the instances
-	instances, err := instance.LoadNodeAll(s, instancetype.Container)
+	instances, err := instance.LoadNodeAll(s, instanceType)
 	if err != nil {
 		logger.Error("Problem loading instances list", log.Ctx{"err": err})
-		return
+		return nil
 	}
 
 	fixedInstances := map[int64][]instance.Instance{}
 	balancedInstances := map[instance.Instance]int{}
+	instanceNodeCpus := map[instance.Instance][]int64{}
 	for _, c := range instances {
 		conf := c.ExpandedConfig()
 		cpulimit, ok := conf["limits.cpu"]
 		if !ok || cpulimit == "" {
 			cpulimit = effectiveCpus
+
+			// Virtual machines default to a single vCPU.
+			if c.Type() == instancetype.VM {
+				cpulimit = "1"
+			}
 		}
 
 		if !c.IsRunning() {
+			// Forget the vCPU placement of stopped VMs, QEMU places them again when restarted.
+			if c.Type() == instancetype.VM {
+				deviceTaskVMPinningMu.Lock()
+				delete(deviceTaskVMPinning, project.Instance(c.Project(), c.Name()))
+				deviceTaskVMPinningMu.Unlock()
+			}
+
 			continue
 		}
 
+		// Apply the NUMA node restrictions.
+		nodeCpus, err := deviceTaskNUMANodes(c)
+		if err != nil {
+			logger.Error("balance: Unable to apply NUMA nodes", log.Ctx{"name": c.Name(), "err": err})
+		}
+
+		// Without an explicit CPU limit, use all the CPUs of the selected NUMA nodes. Virtual machines keep
+		// their single vCPU, balanced over the CPUs of the nodes.
+		if conf["limits.cpu"] == "" && len(nodeCpus) > 0 && c.Type() == instancetype.Container {
+			nodeCpusSlice := []string{}
+			for _, id := range nodeCpus {
+				nodeCpusSlice = append(nodeCpusSlice, fmt.Sprintf("%d", id))
+			}
+
+			cpulimit = strings.Join(nodeCpusSlice, ",")
+		}
+
 		count, err := strconv.Atoi(cpulimit)
 		if err == nil {
 			// Load-balance
+			if nodeCpus != nil {
+				instanceNodeCpus[c] = nodeCpus
+				count = min(count, len(nodeCpus))
+			}
+
 			count = min(count, len(cpus))
 			balancedInstances[c] = count
 		} else {
 			// Pinned
 			containerCpus, err := resources.ParseCpuset(cpulimit)
 			if err != nil {
-				return
+				return nil
 			}
 			for _, nr := range containerCpus {
 				if !shared.Int64InSlice(nr, cpus) {
@@ -449,15 +540,24 @@ func deviceTaskBalance(s *state.State) {
 
 	sortedUsage := make(deviceTaskCPUs, 0)
 	for _, value := range usage {
+		value.load = load[value.id]
 		sortedUsage = append(sortedUsage, value)
 	}
 
 	for ctn, count := range balancedInstances {
+		nodeCpus, nodeRestricted := instanceNodeCpus[ctn]
+
 		sort.Sort(sortedUsage)
 		for _, cpu := range sortedUsage {
 			if count == 0 {
 				break
 			}
+
+			// Only consider CPUs from the selected NUMA nodes.
+			if nodeRestricted && !shared.Int64InSlice(cpu.id, nodeCpus) {
+				continue
+			}
+
 			count -= 1
 
 			id := cpu.strId
@@ -472,6 +572,7 @@ func deviceTaskBalance(s *state.State) {
 	}
 
 	// Set the new pinning
+	changes := []deviceTaskBalanceChange{}
 	for ctn, set := range pinning {
 		// Confirm the container didn't just stop
 		if !ctn.IsRunning() {
@@ -479,17 +580,109 @@ func deviceTaskBalance(s *state.State) {
 		}
 
 		sort.Strings(set)
+		value := strings.Join(set, ",")
+
+		if ctn.Type() == instancetype.VM {
+			// Pinned virtual machines already have their vCPUs placed at startup.
+			_, balanced := balancedInstances[ctn]
+			if !balanced {
+				continue
+			}
+
+			vm, ok := ctn.(instance.VM)
+			if !ok {
+				continue
+			}
+
+			// Only trust the recorded placement if it was applied to the current QEMU process, a restarted
+			// VM is back to the default placement.
+			key := project.Instance(ctn.Project(), ctn.Name())
+			pid := ctn.InitPID()
+			deviceTaskVMPinningMu.Lock()
+			pin, ok := deviceTaskVMPinning[key]
+			deviceTaskVMPinningMu.Unlock()
+
+			old := ""
+			if ok && pin.pid == pid {
+				old = pin.cpus
+			}
+
+			if old == value {
+				continue
+			}
+
+			err = vm.SetAffinity(set)
+			if err != nil {
+				logger.Error("balance: Unable to set vCPU affinity", log.Ctx{"name": ctn.Name(), "err": err, "value": value})
+				continue
+			}
+
+			deviceTaskVMPinningMu.Lock()
+			deviceTaskVMPinning[key] = deviceTaskVMPin{pid: pid, cpus: value}
+			deviceTaskVMPinningMu.Unlock()
+
+			changes = append(changes, deviceTaskBalanceChange{inst: ctn, old: old, new: value})
+			continue
+		}
+
 		cg, err := ctn.CGroup()
 		if err != nil {
-			logger.Error("balance: Unable to get cgroup struct", log.Ctx{"name": ctn.Name(), "err": err, "value": strings.Join(set, ",")})
+			logger.Error("balance: Unable to get cgroup struct", log.Ctx{"name": ctn.Name(), "err": err, "value": value})
 			continue
 		}
 
-		err = cg.SetCpuset(strings.Join(set, ","))
+		old, _ := cg.GetCpuset()
+		if old == value {
+			continue
+		}
+
+		err = cg.SetCpuset(value)
 		if err != nil {
-			logger.Error("balance: Unable to set cpuset", log.Ctx{"name": ctn.Name(), "err": err, "value": strings.Join(set, ",")})
+			logger.Error("balance: Unable to set cpuset", log.Ctx{"name": ctn.Name(), "err": err, "value": value})
+			continue
 		}
+
+		changes = append(changes, deviceTaskBalanceChange{inst: ctn, old: old, new: value})
+	}
+
+	return changes
+}
+
+// deviceTaskNUMANodes binds the memory of a container to the NUMA nodes listed in limits.cpu.nodes and
+// returns the list of CPUs belonging to those nodes (nil if the instance isn't restricted to specific nodes).
+// Virtual machines have no cgroup to update, their nodes are only read from the configuration.
+func deviceTaskNUMANodes(inst instance.Instance) ([]int64, error) {
+	limit := inst.ExpandedConfig()["limits.cpu.nodes"]
+	if limit == "" {
+		return nil, nil
+	}
+
+	nodes, err := resources.ParseCpuset(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cpus, err := resources.GetNUMANodeCPUs(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Virtual machine memory is bound by QEMU itself.
+	if inst.Type() != instancetype.Container {
+		return cpus, nil
+	}
+
+	cg, err := inst.CGroup()
+	if err != nil {
+		return nil, err
 	}
+
+	err = cg.SetCpusetMems(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return cpus, nil
 }
 
 func deviceNetworkPriority(s *state.State, netif string) {
@@ -528,7 +721,7 @@ func deviceNetworkPriority(s *state.State, netif string) {
 }
 
 func deviceEventListener(s *state.State) {
-	chNetlinkCPU, chNetlinkNetwork, chUSB, chUnix, err := deviceNetlinkListener()
+	chNetlinkCPU, chNetlinkNetwork, chUSB, chUnix, chUnixPaths, err := deviceNetlinkListener()
 	if err != nil {
 		logger.Errorf("scheduler: Couldn't setup netlink listener: %v", err)
 		return
@@ -565,6 +758,8 @@ func deviceEventListener(s *state.State) {
 			device.USBRunHandlers(s, &e)
 		case e := <-chUnix:
 			device.UnixHotplugRunHandlers(s, &e)
+		case e := <-chUnixPaths:
+			device.UnixRunPathHandlers(s, e)
 		case e := <-cgroup.DeviceSchedRebalance:
 			if len(e) != 3 {
 				logger.Errorf("Scheduler: received an invalid rebalance event")
@@ -601,6 +796,62 @@ func devicesRegister(s *state.State) {
 	}
 }
 
+// deviceRoutedNeighProxyTask periodically checks that the proxy neighbour entries of the routed NICs of the running
+// instances are still present on their parent interface and re-adds the ones which went missing.
+func deviceRoutedNeighProxyTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		instances, err := instance.LoadNodeAll(d.State(), instancetype.Container)
+		if err != nil {
+			logger.Error("Problem loading instances list", log.Ctx{"err": err})
+			return
+		}
+
+		for _, inst := range instances {
+			if !inst.IsRunning() {
+				continue
+			}
+
+			repaired, err := device.NICRoutedNeighProxyRepair(inst)
+			if err != nil {
+				logger.Error("Failed checking routed NIC proxy neighbours", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				continue
+			}
+
+			if len(repaired) > 0 {
+				logger.Warn("Re-added missing routed NIC proxy neighbours", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "addresses": repaired})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// deviceProjectShaperTask periodically applies the aggregate network limits of the projects to the local devices
+// shaping their traffic, picking up the changes made through the other cluster members.
+func deviceProjectShaperTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		var projects []db.Project
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			projects, err = tx.GetProjects(db.ProjectFilter{})
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed loading projects", log.Ctx{"err": err})
+			return
+		}
+
+		for _, p := range projects {
+			err := network.ProjectShaperUpdate(p.Name, p.Config)
+			if err != nil {
+				logger.Warn("Failed updating project network shapers", log.Ctx{"project": p.Name, "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
 func getHidrawDevInfo(fd int) (string, string, error) {
 	info := C.struct_hidraw_devinfo{}
 	ret, err := C.get_hidraw_devinfo(C.int(fd), &info)
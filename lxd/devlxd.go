@@ -85,6 +85,15 @@ var devlxdConfigKeyGet = devLxdHandler{"/1.0/config/{key}", func(d *Daemon, c in
 	return okResponse(value, "raw")
 }}
 
+var devlxdEnvironmentGet = devLxdHandler{"/1.0/environment", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	p, err := d.cluster.GetProject(c.Project())
+	if err != nil {
+		return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+	}
+
+	return okResponse(project.Environment(p, c.ExpandedConfig()), "json")
+}}
+
 var devlxdImageExport = devLxdHandler{"/1.0/images/{fingerprint}/export", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
 	if !shared.IsTrue(c.ExpandedConfig()["security.devlxd.images"]) {
 		return &devLxdResponse{"not authorized", http.StatusForbidden, "raw"}
@@ -172,9 +181,12 @@ var handlers = []devLxdHandler{
 	devlxdAPIGet,
 	devlxdConfigGet,
 	devlxdConfigKeyGet,
+	devlxdEnvironmentGet,
 	devlxdMetadataGet,
 	devlxdEventsGet,
 	devlxdImageExport,
+	devlxdSnapshots,
+	devlxdRootDiskHandler,
 }
 
 func hoistReq(f func(*Daemon, instance.Instance, http.ResponseWriter, *http.Request) *devLxdResponse, d *Daemon) func(http.ResponseWriter, *http.Request) {
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+// Scopes which can be granted to an instance through security.devlxd.management.
+const (
+	devlxdManagementSnapshots = "snapshots"
+	devlxdManagementRootSize  = "root_size"
+)
+
+// devlxdManagementSnapshotsMax is the number of snapshots above which an instance can't create new ones, unless
+// security.devlxd.management.snapshots_max says otherwise.
+const devlxdManagementSnapshotsMax = 10
+
+// devlxdManagementAllowed returns whether the instance was granted the management scope.
+func devlxdManagementAllowed(c instance.Instance, scope string) bool {
+	value := c.ExpandedConfig()["security.devlxd.management"]
+	if value == "" {
+		return false
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// devlxdSnapshotsPost is the body of a snapshot creation request over devlxd.
+type devlxdSnapshotsPost struct {
+	Name string `json:"name"`
+}
+
+var devlxdSnapshots = devLxdHandler{"/1.0/snapshots", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	if !devlxdManagementAllowed(c, devlxdManagementSnapshots) {
+		return &devLxdResponse{"not authorized", http.StatusForbidden, "raw"}
+	}
+
+	switch r.Method {
+	case "GET":
+		snaps, err := c.Snapshots()
+		if err != nil {
+			return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+		}
+
+		names := []string{}
+		for _, snap := range snaps {
+			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snap.Name())
+			names = append(names, snapName)
+		}
+
+		return okResponse(names, "json")
+	case "POST":
+		req := devlxdSnapshotsPost{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return &devLxdResponse{err.Error(), http.StatusBadRequest, "raw"}
+		}
+
+		err = devlxdSnapshotCreate(d, c, req.Name)
+		if err != nil {
+			return &devLxdResponse{err.Error(), http.StatusBadRequest, "raw"}
+		}
+
+		return okResponse("", "raw")
+	}
+
+	return &devLxdResponse{"method not allowed", http.StatusMethodNotAllowed, "raw"}
+}}
+
+// devlxdSnapshotCreate creates a snapshot of the instance on its own request, as long as it has fewer snapshots than
+// allowed by security.devlxd.management.snapshots_max. The snapshot expires according to the snapshots.expiry key
+// of the instance, same as the snapshots created through the API without an expiry date.
+func devlxdSnapshotCreate(d *Daemon, c instance.Instance, name string) error {
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return project.AllowSnapshotCreation(tx, c.Project())
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshotsMax := int64(devlxdManagementSnapshotsMax)
+	if c.ExpandedConfig()["security.devlxd.management.snapshots_max"] != "" {
+		snapshotsMax, err = strconv.ParseInt(c.ExpandedConfig()["security.devlxd.management.snapshots_max"], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "Invalid security.devlxd.management.snapshots_max")
+		}
+	}
+
+	snaps, err := c.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	if int64(len(snaps)) >= snapshotsMax {
+		return fmt.Errorf("The instance already has the maximum of %d snapshots", snapshotsMax)
+	}
+
+	if name == "" {
+		name, err = instance.NextSnapshotName(d.State(), c, "snap%d")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validate.IsURLSegmentSafe(name)
+	if err != nil {
+		return errors.Wrap(err, "Invalid snapshot name")
+	}
+
+	expiry, err := shared.GetSnapshotExpiry(time.Now(), c.ExpandedConfig()["snapshots.expiry"])
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Creating snapshot on request of the instance", log.Ctx{"project": c.Project(), "instance": c.Name(), "snapshot": name})
+
+	return c.Snapshot(name, expiry, false)
+}
+
+// devlxdRootDisk is the root disk of an instance as seen over devlxd.
+type devlxdRootDisk struct {
+	Size    string `json:"size"`
+	SizeMax string `json:"size_max"`
+}
+
+var devlxdRootDiskHandler = devLxdHandler{"/1.0/root-disk", func(d *Daemon, c instance.Instance, w http.ResponseWriter, r *http.Request) *devLxdResponse {
+	if !devlxdManagementAllowed(c, devlxdManagementRootSize) {
+		return &devLxdResponse{"not authorized", http.StatusForbidden, "raw"}
+	}
+
+	switch r.Method {
+	case "GET":
+		_, rootDev, err := shared.GetRootDiskDevice(c.ExpandedDevices().CloneNative())
+		if err != nil {
+			return &devLxdResponse{"not found", http.StatusNotFound, "raw"}
+		}
+
+		size, err := devlxdRootDiskSize(d, c, rootDev)
+		if err != nil {
+			return &devLxdResponse{"internal server error", http.StatusInternalServerError, "raw"}
+		}
+
+		return okResponse(devlxdRootDisk{Size: size, SizeMax: c.ExpandedConfig()["security.devlxd.management.root_size_max"]}, "json")
+	case "PATCH":
+		req := devlxdRootDisk{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return &devLxdResponse{err.Error(), http.StatusBadRequest, "raw"}
+		}
+
+		err = devlxdRootDiskResize(d, c, req.Size)
+		if err != nil {
+			return &devLxdResponse{err.Error(), http.StatusBadRequest, "raw"}
+		}
+
+		return okResponse("", "raw")
+	}
+
+	return &devLxdResponse{"method not allowed", http.StatusMethodNotAllowed, "raw"}
+}}
+
+// devlxdRootDiskSize returns the current size of the root disk of the instance. When the root disk device doesn't
+// set it, it's the size the root volume was created with, as set on the volume or by default on its pool (empty if
+// the volume has no size limit).
+func devlxdRootDiskSize(d *Daemon, c instance.Instance, rootDev map[string]string) (string, error) {
+	if rootDev["size"] != "" {
+		return rootDev["size"], nil
+	}
+
+	pool, err := storagePools.GetPoolByInstance(d.State(), c)
+	if err != nil {
+		return "", err
+	}
+
+	volType, err := storagePools.InstanceTypeToVolumeType(c.Type())
+	if err != nil {
+		return "", err
+	}
+
+	volDBType, err := storagePools.VolumeTypeToDBType(volType)
+	if err != nil {
+		return "", err
+	}
+
+	_, dbVol, err := d.cluster.GetLocalStoragePoolVolume(c.Project(), c.Name(), volDBType, pool.ID())
+	if err != nil {
+		return "", err
+	}
+
+	contentType := storageDrivers.ContentTypeFS
+	if c.Type() == instancetype.VM {
+		contentType = storageDrivers.ContentTypeBlock
+	}
+
+	vol := storageDrivers.NewVolume(pool.Driver(), pool.Name(), volType, contentType, project.Instance(c.Project(), c.Name()), dbVol.Config, pool.Driver().Config())
+
+	return vol.ConfigSize(), nil
+}
+
+// devlxdRootDiskResize grows the root disk of the instance on its own request, up to the size set in
+// security.devlxd.management.root_size_max. The root disk is overridden locally if it comes from a profile.
+func devlxdRootDiskResize(d *Daemon, c instance.Instance, size string) error {
+	sizeMaxValue := c.ExpandedConfig()["security.devlxd.management.root_size_max"]
+	if sizeMaxValue == "" {
+		return fmt.Errorf("No maximum root disk size set for the instance")
+	}
+
+	sizeMax, err := units.ParseByteSizeString(sizeMaxValue)
+	if err != nil {
+		return errors.Wrap(err, "Invalid security.devlxd.management.root_size_max")
+	}
+
+	newSize, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return errors.Wrap(err, "Invalid size")
+	}
+
+	if newSize > sizeMax {
+		return fmt.Errorf("Size is above the maximum of %s", sizeMaxValue)
+	}
+
+	rootName, rootDev, err := shared.GetRootDiskDevice(c.ExpandedDevices().CloneNative())
+	if err != nil {
+		return err
+	}
+
+	currentSizeValue, err := devlxdRootDiskSize(d, c, rootDev)
+	if err != nil {
+		return err
+	}
+
+	// Setting a size on a root disk without one would limit it rather than grow it.
+	if currentSizeValue == "" {
+		return fmt.Errorf("The root disk has no size limit")
+	}
+
+	currentSize, err := units.ParseByteSizeString(currentSizeValue)
+	if err != nil {
+		return err
+	}
+
+	if newSize < currentSize {
+		return fmt.Errorf("The root disk can only be grown")
+	}
+
+	if newSize == currentSize {
+		return nil
+	}
+
+	devices := c.LocalDevices().CloneNative()
+	rootDev["size"] = size
+	devices[rootName] = rootDev
+
+	req := api.InstancePut{
+		Config:      c.LocalConfig(),
+		Description: c.Description(),
+		Devices:     devices,
+		Ephemeral:   c.IsEphemeral(),
+		Profiles:    c.Profiles(),
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return project.AllowInstanceUpdate(tx, c.Project(), c.Name(), req, c.LocalConfig())
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Resizing root disk on request of the instance", log.Ctx{"project": c.Project(), "instance": c.Name(), "size": size})
+
+	args := db.InstanceArgs{
+		Architecture: c.Architecture(),
+		Config:       c.LocalConfig(),
+		Description:  c.Description(),
+		Devices:      deviceConfig.NewDevices(devices),
+		Ephemeral:    c.IsEphemeral(),
+		Profiles:     c.Profiles(),
+		Project:      c.Project(),
+	}
+
+	return c.Update(args, true)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/revert"
+)
+
+// devlxdManagementCreate creates a container with the given config and devices for the devlxd management tests.
+func (suite *containerTestSuite) devlxdManagementCreate(config map[string]string, devices deviceConfig.Devices) instance.Instance {
+	args := db.InstanceArgs{
+		Type:      instancetype.Container,
+		Ephemeral: false,
+		Config:    config,
+		Devices:   devices,
+		Name:      "testFoo",
+	}
+
+	c, op, err := instance.CreateInternal(suite.d.State(), args, true, nil, revert.New())
+	suite.Req.Nil(err)
+	op.Done(nil)
+
+	return c
+}
+
+// devlxdManagementRequest runs the devlxd handler on behalf of the instance.
+func (suite *containerTestSuite) devlxdManagementRequest(handler devLxdHandler, c instance.Instance, method string, body string) *devLxdResponse {
+	r := httptest.NewRequest(method, handler.path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	return handler.f(suite.d, c, w, r)
+}
+
+func (suite *containerTestSuite) TestDevlxdManagement_Forbidden() {
+	c := suite.devlxdManagementCreate(map[string]string{"security.devlxd.management": "snapshots"}, nil)
+	defer c.Delete(true)
+
+	resp := suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "GET", "")
+	suite.Equal(http.StatusForbidden, resp.code)
+
+	resp = suite.devlxdManagementRequest(devlxdSnapshots, c, "GET", "")
+	suite.Equal(http.StatusOK, resp.code)
+
+	resp = suite.devlxdManagementRequest(devlxdSnapshots, c, "DELETE", "")
+	suite.Equal(http.StatusMethodNotAllowed, resp.code)
+}
+
+func (suite *containerTestSuite) TestDevlxdManagement_SnapshotsMax() {
+	c := suite.devlxdManagementCreate(map[string]string{
+		"security.devlxd.management":               "snapshots",
+		"security.devlxd.management.snapshots_max": "0",
+	}, nil)
+	defer c.Delete(true)
+
+	resp := suite.devlxdManagementRequest(devlxdSnapshots, c, "POST", `{"name": "snap0"}`)
+	suite.Equal(http.StatusBadRequest, resp.code)
+	suite.Contains(resp.content, "maximum of 0 snapshots")
+
+	snaps, err := c.Snapshots()
+	suite.Req.Nil(err)
+	suite.Len(snaps, 0)
+}
+
+func (suite *containerTestSuite) TestDevlxdManagement_RootDisk() {
+	c := suite.devlxdManagementCreate(map[string]string{
+		"security.devlxd.management":               "root_size",
+		"security.devlxd.management.root_size_max": "20GiB",
+	}, deviceConfig.Devices{
+		"root": deviceConfig.Device{
+			"type": "disk",
+			"path": "/",
+			"pool": lxdTestSuiteDefaultStoragePool,
+			"size": "10GiB",
+		},
+	})
+	defer c.Delete(true)
+
+	resp := suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "GET", "")
+	suite.Req.Equal(http.StatusOK, resp.code)
+
+	content, err := json.Marshal(resp.content)
+	suite.Req.Nil(err)
+
+	rootDisk := devlxdRootDisk{}
+	suite.Req.Nil(json.Unmarshal(content, &rootDisk))
+	suite.Equal(devlxdRootDisk{Size: "10GiB", SizeMax: "20GiB"}, rootDisk)
+
+	resp = suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "PATCH", `{"size": "30GiB"}`)
+	suite.Equal(http.StatusBadRequest, resp.code)
+	suite.Contains(resp.content, "above the maximum")
+
+	resp = suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "PATCH", `{"size": "5GiB"}`)
+	suite.Equal(http.StatusBadRequest, resp.code)
+	suite.Contains(resp.content, "can only be grown")
+
+	resp = suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "PATCH", `{"size": "invalid"}`)
+	suite.Equal(http.StatusBadRequest, resp.code)
+}
+
+func (suite *containerTestSuite) TestDevlxdManagement_RootDiskUnlimited() {
+	c := suite.devlxdManagementCreate(map[string]string{
+		"security.devlxd.management":               "root_size",
+		"security.devlxd.management.root_size_max": "20GiB",
+	}, nil)
+	defer c.Delete(true)
+
+	// The root disk from the default profile has no size and neither has its volume on the test pool.
+	resp := suite.devlxdManagementRequest(devlxdRootDiskHandler, c, "PATCH", `{"size": "10GiB"}`)
+	suite.Equal(http.StatusBadRequest, resp.code)
+	suite.Contains(resp.content, "no size limit")
+}
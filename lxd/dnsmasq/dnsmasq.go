@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -28,7 +29,8 @@ type DHCPAllocation struct {
 var ConfigMutex sync.Mutex
 
 // UpdateStaticEntry writes a single dhcp-host line for a network/instance combination.
-func UpdateStaticEntry(network string, projectName string, instanceName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string) error {
+// The host name handed out is dnsName, or the instance name if empty.
+func UpdateStaticEntry(network string, projectName string, instanceName string, dnsName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string) error {
 	hwaddr = strings.ToLower(hwaddr)
 	line := hwaddr
 
@@ -42,7 +44,11 @@ func UpdateStaticEntry(network string, projectName string, instanceName string,
 	}
 
 	if netConfig["dns.mode"] == "" || netConfig["dns.mode"] == "managed" {
-		line += fmt.Sprintf(",%s", project.DNS(projectName, instanceName))
+		if dnsName == "" {
+			dnsName = instanceName
+		}
+
+		line += fmt.Sprintf(",%s", project.DNS(projectName, dnsName))
 	}
 
 	if line == hwaddr {
@@ -57,13 +63,59 @@ func UpdateStaticEntry(network string, projectName string, instanceName string,
 	return nil
 }
 
-// RemoveStaticEntry removes a single dhcp-host line for a network/instance combination.
+// UpdateAliasesEntry writes the hosts file serving the additional DNS names of a network/instance combination.
+// The names are served for the static addresses of the instance, the file is removed if there are none.
+func UpdateAliasesEntry(network string, projectName string, instanceName string, netConfig map[string]string, aliases []string, ipv4Address string, ipv6Address string) error {
+	path := shared.VarPath("networks", network, "dnsmasq.aliases", project.Instance(projectName, instanceName))
+
+	if len(aliases) == 0 || (ipv4Address == "" && ipv6Address == "") || (netConfig["dns.mode"] != "" && netConfig["dns.mode"] != "managed") {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	dnsDomain := netConfig["dns.domain"]
+	if dnsDomain == "" {
+		dnsDomain = "lxd"
+	}
+
+	// Hosts files aren't expanded with the domain, so list both the short and qualified names.
+	names := []string{}
+	for _, alias := range aliases {
+		name := project.DNS(projectName, alias)
+		names = append(names, name, fmt.Sprintf("%s.%s", name, dnsDomain))
+	}
+
+	content := ""
+	for _, address := range []string{ipv4Address, ipv6Address} {
+		if address != "" {
+			content += fmt.Sprintf("%s %s\n", address, strings.Join(names, " "))
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(content), 0644)
+}
+
+// RemoveStaticEntry removes a single dhcp-host line (and the additional DNS names) for a network/instance combination.
 func RemoveStaticEntry(network string, projectName string, instanceName string) error {
 	err := os.Remove(shared.VarPath("networks", network, "dnsmasq.hosts", project.Instance(projectName, instanceName)))
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
+	err = os.Remove(shared.VarPath("networks", network, "dnsmasq.aliases", project.Instance(projectName, instanceName)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
 	return nil
 }
 
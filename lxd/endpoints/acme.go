@@ -0,0 +1,107 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// ACMEDefaultPort is the port of the ACME endpoint when its address doesn't specify one, the one on which the ACME
+// CAs validate the HTTP-01 challenges.
+const ACMEDefaultPort = 80
+
+// acmeCanonicalAddress returns the given address of the ACME endpoint with the default port filled in if missing.
+func acmeCanonicalAddress(address string) string {
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return util.CanonicalNetworkAddressFromAddressAndPort(address, ACMEDefaultPort)
+	}
+
+	return address
+}
+
+// ACMEAddress returns the network address of the ACME endpoint, or an empty string if there's no ACME endpoint.
+func (e *Endpoints) ACMEAddress() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	listener := e.listeners[acme]
+	if listener == nil {
+		return ""
+	}
+
+	return listener.Addr().String()
+}
+
+// ACMEUpdateAddress updates the address of the plain HTTP endpoint serving the ACME HTTP-01 challenges, shutting
+// it down and restarting it.
+func (e *Endpoints) ACMEUpdateAddress(address string) error {
+	if address != "" {
+		address = acmeCanonicalAddress(address)
+	}
+
+	oldAddress := e.ACMEAddress()
+	if address == oldAddress {
+		return nil
+	}
+
+	logger.Infof("Update ACME address")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Close the previous socket
+	e.closeListener(acme)
+
+	// If turning off listening, we're done
+	if address == "" {
+		return nil
+	}
+
+	if e.servers[acme] == nil {
+		return fmt.Errorf("No ACME server configured")
+	}
+
+	// Attempt to setup the new listening socket
+	getListener := func(address string) (net.Listener, error) {
+		var err error
+		var listener net.Listener
+
+		for i := 0; i < 10; i++ { // Ten retries over a second seems reasonable.
+			listener, err = net.Listen("tcp", address)
+			if err == nil {
+				break
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Cannot listen on ACME socket: %v", err)
+		}
+
+		return listener, nil
+	}
+
+	listener, err := getListener(address)
+	if err != nil {
+		// Attempt to revert to the previous address
+		if oldAddress != "" {
+			listener, err1 := getListener(oldAddress)
+			if err1 == nil {
+				e.listeners[acme] = listener
+				e.serveHTTP(acme)
+			}
+		}
+
+		return err
+	}
+
+	e.listeners[acme] = listener
+	e.serveHTTP(acme)
+
+	return nil
+}
@@ -58,6 +58,14 @@ type Config struct {
 	//
 	// It can be updated after the endpoints are up using PprofUpdateAddress().
 	DebugAddress string
+
+	// HTTP server answering the ACME HTTP-01 challenges over plain HTTP.
+	ACMEServer *http.Server
+
+	// ACMEAddress sets the address for the ACME endpoint. If not set, the ACME endpoint won't be started.
+	//
+	// It can be updated after the endpoints are up using ACMEUpdateAddress().
+	ACMEAddress string
 }
 
 // Up brings up all applicable LXD endpoints and starts accepting HTTP
@@ -161,15 +169,26 @@ func (e *Endpoints) up(config *Config) error {
 		network: config.RestServer,
 		cluster: config.RestServer,
 		pprof:   pprofCreateServer(),
+		acme:    config.ACMEServer,
 	}
 	e.cert = config.Cert
 	e.inherited = map[kind]bool{}
 
-	var err error
+	// Check for listeners handed off by the previous process on reload. As it was running with the same
+	// configuration, they're used as they are.
+	handedOff, err := handedOffListeners(e.cert)
+	if err != nil {
+		return err
+	}
 
 	// Check for socket activation.
 	systemdListeners := util.GetListeners(e.systemdListenFDsStart)
-	if len(systemdListeners) > 0 {
+	if len(handedOff) > 0 {
+		e.listeners = handedOff
+		for kind := range e.listeners {
+			e.inherited[kind] = true
+		}
+	} else if len(systemdListeners) > 0 {
 		e.listeners = activatedListeners(systemdListeners, e.cert)
 		for kind := range e.listeners {
 			e.inherited[kind] = true
@@ -184,14 +203,16 @@ func (e *Endpoints) up(config *Config) error {
 	}
 
 	// Start the devlxd listener
-	e.listeners[devlxd], err = createDevLxdlListener(config.Dir)
-	if err != nil {
-		return err
+	if handedOff[devlxd] == nil {
+		e.listeners[devlxd], err = createDevLxdlListener(config.Dir)
+		if err != nil {
+			return err
+		}
 	}
 
 	if config.NetworkAddress != "" {
 		listener, ok := e.listeners[network]
-		if ok {
+		if ok && handedOff[network] == nil {
 			logger.Infof("Replacing inherited TCP socket with configured one")
 			listener.Close()
 			e.inherited[network] = false
@@ -201,7 +222,9 @@ func (e *Endpoints) up(config *Config) error {
 		var networkAddressErr error
 		attempts := 0
 	againHttps:
-		e.listeners[network], networkAddressErr = networkCreateListener(config.NetworkAddress, e.cert)
+		if handedOff[network] == nil {
+			e.listeners[network], networkAddressErr = networkCreateListener(config.NetworkAddress, e.cert)
+		}
 
 		isCovered := util.IsAddressCovered(config.ClusterAddress, config.NetworkAddress)
 		if config.ClusterAddress != "" {
@@ -220,7 +243,7 @@ func (e *Endpoints) up(config *Config) error {
 
 					return networkAddressErr
 				}
-			} else {
+			} else if handedOff[cluster] == nil {
 			againCluster:
 				e.listeners[cluster], err = networkCreateListener(config.ClusterAddress, e.cert)
 				if err != nil {
@@ -254,15 +277,29 @@ func (e *Endpoints) up(config *Config) error {
 	}
 
 	if config.DebugAddress != "" {
-		e.listeners[pprof], err = pprofCreateListener(config.DebugAddress)
-		if err != nil {
-			return err
+		if handedOff[pprof] == nil {
+			e.listeners[pprof], err = pprofCreateListener(config.DebugAddress)
+			if err != nil {
+				return err
+			}
 		}
 
 		logger.Infof("Starting pprof handler:")
 		e.serveHTTP(pprof)
 	}
 
+	if config.ACMEAddress != "" && config.ACMEServer != nil {
+		if handedOff[acme] == nil {
+			e.listeners[acme], err = net.Listen("tcp", acmeCanonicalAddress(config.ACMEAddress))
+			if err != nil {
+				return fmt.Errorf("ACME endpoint: %v", err)
+			}
+		}
+
+		logger.Infof("Starting ACME handler:")
+		e.serveHTTP(acme)
+	}
+
 	logger.Infof("Starting /dev/lxd handler:")
 	e.serveHTTP(devlxd)
 
@@ -315,6 +352,14 @@ func (e *Endpoints) Down() error {
 		}
 	}
 
+	if e.listeners[acme] != nil {
+		logger.Infof("Stopping ACME handler:")
+		err := e.closeListener(acme)
+		if err != nil {
+			return err
+		}
+	}
+
 	if e.tomb != nil {
 		e.tomb.Kill(nil)
 		e.tomb.Wait()
@@ -397,6 +442,7 @@ const (
 	network
 	pprof
 	cluster
+	acme
 )
 
 // Human-readable descriptions of the various kinds of endpoints.
@@ -406,4 +452,5 @@ var descriptions = map[kind]string{
 	network: "TCP socket",
 	pprof:   "pprof socket",
 	cluster: "cluster socket",
+	acme:    "ACME socket",
 }
@@ -0,0 +1,129 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// HandoffEnv is the environment variable through which the listeners are passed to the next LXD process on reload.
+// It holds a comma separated list of <endpoint>=<fd> pairs.
+const HandoffEnv = "LXD_HANDOFF_FDS"
+
+// Names of the endpoints in HandoffEnv.
+var handoffNames = map[kind]string{
+	local:   "local",
+	devlxd:  "devlxd",
+	network: "network",
+	pprof:   "pprof",
+	cluster: "cluster",
+	acme:    "acme",
+}
+
+// Handoff returns the files of the sockets of the endpoints, indexed by endpoint name, so that they can be passed
+// on to the next LXD process. The sockets stay open (and the unix socket files in place) after the endpoints are
+// brought down, with incoming connections queued until the next process starts accepting them.
+func (e *Endpoints) Handoff() (map[string]*os.File, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	files := map[string]*os.File{}
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	for kind, listener := range e.listeners {
+		// Network listeners are wrapped to handle TLS.
+		tlsListener, ok := listener.(*networkListener)
+		if ok {
+			listener = tlsListener.Listener
+		}
+
+		var file *os.File
+		var err error
+
+		switch l := listener.(type) {
+		case *net.UnixListener:
+			// Keep the socket file for the next process.
+			l.SetUnlinkOnClose(false)
+			file, err = l.File()
+		case *net.TCPListener:
+			file, err = l.File()
+		default:
+			err = fmt.Errorf("Unsupported listener type %T", listener)
+		}
+
+		if err != nil {
+			closeFiles()
+			return nil, errors.Wrapf(err, "Failed getting file of %s", descriptions[kind])
+		}
+
+		files[handoffNames[kind]] = file
+	}
+
+	return files, nil
+}
+
+// handedOffListeners returns the listeners passed on by the previous LXD process on reload, if any.
+func handedOffListeners(cert *shared.CertInfo) (map[kind]net.Listener, error) {
+	value := os.Getenv(HandoffEnv)
+	if value == "" {
+		return nil, nil
+	}
+
+	defer os.Unsetenv(HandoffEnv)
+
+	listeners := map[kind]net.Listener{}
+	for _, entry := range strings.Split(value, ",") {
+		fields := strings.SplitN(entry, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid handed off listener %q", entry)
+		}
+
+		var listenerKind kind
+		found := false
+		for k, name := range handoffNames {
+			if name == fields[0] {
+				listenerKind = k
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Unknown handed off endpoint %q", fields[0])
+		}
+
+		fd, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid handed off listener %q", entry)
+		}
+
+		// The listener uses a duplicate of the file descriptor, so close the inherited one.
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("handoff-%s", fields[0]))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed using handed off %s", descriptions[listenerKind])
+		}
+
+		if listenerKind == network || listenerKind == cluster {
+			listener = networkTLSListener(listener, cert)
+		}
+
+		logger.Debug("Using handed off listener", log.Ctx{"endpoint": fields[0], "socket": listener.Addr()})
+		listeners[listenerKind] = listener
+	}
+
+	return listeners, nil
+}
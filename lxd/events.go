@@ -5,11 +5,18 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -62,6 +69,11 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 		return nil
 	}
 
+	instanceName := queryParam(r, "instance")
+	if instanceName != "" {
+		return eventsInstanceSocket(d, r, w, projectName, instanceName, types)
+	}
+
 	// Upgrade the connection to websocket
 	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -101,19 +113,115 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// is closed (see above), we instead enter into a repeat read loop of the connection in
 	// order to detect when the client connection is closed. This should be fine as for the
 	// events route there is no expectation to read any useful data from the client.
-	go func() {
-		for {
-			_, _, err := c.NextReader()
+	go eventsCancelOnClose(c, cancel)
+
+	listener.Wait(ctx)
+	logger.Debugf("Event listener finished: %s", listener.ID())
+
+	return nil
+}
+
+// eventsCancelOnClose reads from the websocket until the client closes it, then cancels the context.
+func eventsCancelOnClose(c *websocket.Conn, cancel context.CancelFunc) {
+	for {
+		_, _, err := c.NextReader()
+		if err != nil {
+			// Client read error (likely premature close), so cancel context.
+			cancel()
+			return
+		}
+	}
+}
+
+// eventsInstanceSocket streams the events related to an instance, including the lines logged by its lxc or qemu
+// process when listening for logging events. If the instance is running on another cluster member, the events
+// are relayed from that member.
+func eventsInstanceSocket(d *Daemon, r *http.Request, w http.ResponseWriter, projectName string, instanceName string, types []string) error {
+	if projectName == "*" {
+		response.BadRequest(fmt.Errorf("Instance events can't be requested for all projects")).Render(w)
+		return nil
+	}
+
+	client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, projectName, instanceName, d.endpoints.NetworkCert(), d.serverCert(), r, instancetype.Any)
+	if err != nil {
+		response.SmartError(err).Render(w)
+		return nil
+	}
+
+	var inst instance.Instance
+	if client == nil {
+		inst, err = instance.LoadByProjectAndName(d.State(), projectName, instanceName)
+		if err != nil {
+			response.SmartError(err).Render(w)
+			return nil
+		}
+	}
+
+	// Upgrade the connection to websocket
+	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close() // This ensures the go routines below are ended when this function ends.
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go eventsCancelOnClose(c, cancel)
+
+	// Relay the events of the member running the instance.
+	if client != nil {
+		remoteListener, err := client.UseProject(projectName).GetInstanceEvents(instanceName, types)
+		if err != nil {
+			return err
+		}
+		defer remoteListener.Disconnect()
+
+		var lock sync.Mutex
+		_, err = remoteListener.AddHandler(nil, func(event api.Event) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			err := c.WriteJSON(event)
 			if err != nil {
-				// Client read error (likely premature close), so cancel context.
 				cancel()
-				return
 			}
+		})
+		if err != nil {
+			return err
 		}
-	}()
+
+		go func() {
+			remoteListener.Wait()
+			cancel()
+		}()
+
+		<-ctx.Done()
+		return nil
+	}
+
+	var serverName string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		serverName, err = tx.GetLocalNodeName()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	listener, err := d.events.AddInstanceListener(projectName, instanceName, c, types, serverName, isClusterNotification(r))
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("New instance event listener: %s", listener.ID())
+
+	if shared.StringInSlice("logging", types) {
+		go instanceLogFollow(ctx, d.events, listener, inst)
+	}
 
 	listener.Wait(ctx)
-	logger.Debugf("Event listener finished: %s", listener.ID())
+	logger.Debugf("Instance event listener finished: %s", listener.ID())
 
 	return nil
 }
@@ -138,6 +246,11 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 //     description: Event type(s), comma separated (valid types are logging, operation or lifecycle)
 //     type: string
 //     example: logging,lifecycle
+//   - in: query
+//     name: instance
+//     description: Only send the events related to this instance, including the lines logged by its lxc or qemu process
+//     type: string
+//     example: c1
 // responses:
 //   "200":
 //     description: Websocket message (JSON)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,8 +39,19 @@ func NewServer(debug bool, verbose bool) *Server {
 
 // AddListener creates and returns a new event listener.
 func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+	return s.addListener(group, "", connection, messageTypes, location, noForward)
+}
+
+// AddInstanceListener creates and returns a new event listener only notified of the events related to the given
+// instance of the group.
+func (s *Server) AddInstanceListener(group string, instance string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+	return s.addListener(group, instance, connection, messageTypes, location, noForward)
+}
+
+func (s *Server) addListener(group string, instance string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
 	listener := &Listener{
 		group:        group,
+		instance:     instance,
 		connection:   connection,
 		messageTypes: messageTypes,
 		location:     location,
@@ -79,6 +92,24 @@ func (s *Server) Send(group, eventType string, eventMessage interface{}) error {
 	return s.broadcast(group, event, false)
 }
 
+// SendToListener sends a custom event to the given listener only.
+func (s *Server) SendToListener(listener *Listener, eventType string, eventMessage interface{}) error {
+	encodedMessage, err := json.Marshal(eventMessage)
+	if err != nil {
+		return err
+	}
+
+	event := api.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Metadata:  encodedMessage,
+	}
+
+	s.deliver(listener, event)
+
+	return nil
+}
+
 // Forward to the local events dispatcher an event received from another node.
 func (s *Server) Forward(id int64, event api.Event) {
 	if event.Type == "logging" {
@@ -120,56 +151,116 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 			continue
 		}
 
-		go func(listener *Listener, event api.Event) {
-			// Check that the listener still exists
-			if listener == nil {
-				return
-			}
+		if listener.instance != "" && !eventMatchesInstance(event, listener.group, listener.instance) {
+			continue
+		}
 
-			// Ensure there is only a single even going out at the time
-			listener.lock.Lock()
-			defer listener.lock.Unlock()
+		go s.deliver(listener, event)
+	}
+	s.lock.Unlock()
 
-			// Make sure we're not done already
-			if listener.done {
-				return
-			}
+	return nil
+}
 
-			// Set the Location to the expected serverName
-			if event.Location == "" {
-				eventCopy := api.Event{}
-				err := shared.DeepCopy(&event, &eventCopy)
-				if err != nil {
-					return
-				}
-				eventCopy.Location = listener.location
+// deliver sends the event to the listener, disconnecting it on failure.
+func (s *Server) deliver(listener *Listener, event api.Event) {
+	// Check that the listener still exists
+	if listener == nil {
+		return
+	}
 
-				event = eventCopy
-			}
+	// Ensure there is only a single even going out at the time
+	listener.lock.Lock()
+	defer listener.lock.Unlock()
+
+	// Make sure we're not done already
+	if listener.done {
+		return
+	}
+
+	// Set the Location to the expected serverName
+	if event.Location == "" {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return
+		}
+		eventCopy.Location = listener.location
+
+		event = eventCopy
+	}
+
+	err := listener.connection.WriteJSON(event)
+	if err != nil {
+		// Remove the listener from the list
+		s.lock.Lock()
+		delete(s.listeners, listener.id)
+		s.lock.Unlock()
+
+		// Disconnect the listener
+		listener.connection.Close()
+		listener.active <- false
+		listener.done = true
+		logger.Debugf("Disconnected event listener: %s", listener.id)
+	}
+}
+
+// eventMatchesInstance returns whether the event relates to the given instance of the group: log messages carrying
+// its name, lifecycle events of the instance or its sub-resources and operations acting on it.
+func eventMatchesInstance(event api.Event, group string, instance string) bool {
+	instanceURL := fmt.Sprintf("/1.0/instances/%s", url.PathEscape(instance))
+	matchesURL := func(u string) bool {
+		path := strings.SplitN(u, "?", 2)[0]
+		return path == instanceURL || strings.HasPrefix(path, instanceURL+"/")
+	}
+
+	switch event.Type {
+	case "logging":
+		logEntry := api.EventLogging{}
+		err := json.Unmarshal(event.Metadata, &logEntry)
+		if err != nil {
+			return false
+		}
+
+		if logEntry.Context["instance"] != instance {
+			return false
+		}
+
+		// Log messages are sent to all groups, so filter on the project of the instance.
+		projectName, ok := logEntry.Context["project"]
+		return !ok || group == "*" || projectName == group
+	case "lifecycle":
+		lifecycleEntry := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycleEntry)
+		if err != nil {
+			return false
+		}
 
-			err := listener.connection.WriteJSON(event)
-			if err != nil {
-				// Remove the listener from the list
-				s.lock.Lock()
-				delete(s.listeners, listener.id)
-				s.lock.Unlock()
-
-				// Disconnect the listener
-				listener.connection.Close()
-				listener.active <- false
-				listener.done = true
-				logger.Debugf("Disconnected event listener: %s", listener.id)
+		return matchesURL(lifecycleEntry.Source)
+	case "operation":
+		op := api.Operation{}
+		err := json.Unmarshal(event.Metadata, &op)
+		if err != nil {
+			return false
+		}
+
+		for _, u := range op.Resources["instances"] {
+			// Operation resources don't escape the instance names.
+			if u == fmt.Sprintf("/1.0/instances/%s", instance) || strings.HasPrefix(u, fmt.Sprintf("/1.0/instances/%s/", instance)) {
+				return true
 			}
-		}(listener, event)
+		}
+
+		return false
 	}
-	s.lock.Unlock()
 
-	return nil
+	return false
 }
 
 // Listener describes an event listener.
 type Listener struct {
 	group        string
+	instance     string
 	connection   *websocket.Conn
 	messageTypes []string
 	active       chan bool
@@ -0,0 +1,478 @@
+package exports
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unicode/utf16"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/md4"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// ProtocolNFS exports a directory over NFS using the kernel NFS server.
+const ProtocolNFS = "nfs"
+
+// ProtocolSMB exports a directory over SMB using ksmbd.
+const ProtocolSMB = "smb"
+
+// NFSExportsPath is the exports file managed by LXD, read by exportfs along with /etc/exports.
+var NFSExportsPath = "/etc/exports.d/lxd.exports"
+
+// usernameRegexp matches the SMB user names accepted for exports.
+var usernameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// nameRegexp matches the export names generated by Name.
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9.%_-]+$`)
+
+// passwordHashPrefix marks the SMB passwords which have already been hashed by PasswordHash.
+const passwordHashPrefix = "nthash:"
+
+// passwordHashRegexp matches the SMB password hashes returned by PasswordHash.
+var passwordHashRegexp = regexp.MustCompile(`^` + passwordHashPrefix + `[0-9a-f]{32}$`)
+
+// Export represents a directory exported to clients over NFS or SMB.
+type Export struct {
+	// Name of the export, used as SMB share name.
+	Name string `json:"name"`
+
+	// Path of the exported directory.
+	Path string `json:"path"`
+
+	// Protocol used for the export (nfs or smb).
+	Protocol string `json:"protocol"`
+
+	// Clients allowed to access the export (IP addresses or subnets). Any client can access SMB exports
+	// if empty.
+	Clients []string `json:"clients"`
+
+	// Whether the clients can only read the export.
+	ReadOnly bool `json:"readonly"`
+
+	// Credentials required to access SMB exports, the password being hashed with PasswordHash.
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// mu serializes changes to the exports.
+var mu sync.Mutex
+
+// exportsPath returns the path of the given file in the directory holding the exports.
+func exportsPath(name ...string) string {
+	return shared.VarPath(append([]string{"exports"}, name...)...)
+}
+
+// ValidateUsername checks that the user name is valid for SMB exports.
+func ValidateUsername(value string) error {
+	if !usernameRegexp.MatchString(value) {
+		return fmt.Errorf("Invalid user name %q", value)
+	}
+
+	return nil
+}
+
+// Name returns the name of an export from the names of the objects it belongs to, separated by underscores.
+// Underscores and the characters which can't be used in SMB share names are escaped in the parts so that
+// different objects can't get the same export name.
+func Name(parts ...string) string {
+	escaped := make([]string, 0, len(parts))
+	for _, part := range parts {
+		var sb strings.Builder
+		for _, b := range []byte(part) {
+			if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '.' || b == '-' {
+				sb.WriteByte(b)
+			} else {
+				fmt.Fprintf(&sb, "%%%02X", b)
+			}
+		}
+
+		escaped = append(escaped, sb.String())
+	}
+
+	return strings.Join(escaped, "_")
+}
+
+// PasswordHash returns the NT hash of an SMB password, as used by ksmbd, so that the password itself doesn't
+// need to be stored. Values which are already hashes are returned unchanged.
+func PasswordHash(password string) string {
+	if password == "" || passwordHashRegexp.MatchString(password) {
+		return password
+	}
+
+	h := md4.New()
+	b := make([]byte, 2)
+	for _, c := range utf16.Encode([]rune(password)) {
+		binary.LittleEndian.PutUint16(b, c)
+		h.Write(b)
+	}
+
+	return passwordHashPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Validate checks that the export is complete.
+func (e Export) Validate() error {
+	if !nameRegexp.MatchString(e.Name) {
+		return fmt.Errorf("Invalid export name %q", e.Name)
+	}
+
+	if !filepath.IsAbs(e.Path) || strings.ContainsAny(e.Path, "\r\n") {
+		return fmt.Errorf("Invalid export path %q", e.Path)
+	}
+
+	switch e.Protocol {
+	case ProtocolNFS:
+		if len(e.Clients) == 0 {
+			return fmt.Errorf("NFS exports require a list of allowed clients")
+		}
+	case ProtocolSMB:
+		if e.Username == "" || e.PasswordHash == "" {
+			return fmt.Errorf("SMB exports require a user name and a password")
+		}
+
+		err := ValidateUsername(e.Username)
+		if err != nil {
+			return err
+		}
+
+		if !passwordHashRegexp.MatchString(e.PasswordHash) {
+			return fmt.Errorf("Invalid SMB password hash")
+		}
+	default:
+		return fmt.Errorf("Invalid export protocol %q", e.Protocol)
+	}
+
+	return nil
+}
+
+// Add sets up the export, replacing any existing export with the same name.
+func Add(e Export) error {
+	err := e.Validate()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return err
+	}
+
+	// The same SMB user can only have one password.
+	if e.Protocol == ProtocolSMB {
+		for _, other := range all {
+			if other.Name != e.Name && other.Protocol == ProtocolSMB && other.Username == e.Username && other.PasswordHash != e.PasswordHash {
+				return fmt.Errorf("SMB user %q is already used by export %q with another password", e.Username, other.Name)
+			}
+		}
+	}
+
+	protocols := []string{e.Protocol}
+	old, ok := all[e.Name]
+	if ok && old.Protocol != e.Protocol {
+		protocols = append(protocols, old.Protocol)
+	}
+
+	err = os.MkdirAll(exportsPath(), 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(exportsPath(e.Name+".json"), data, 0600)
+	if err != nil {
+		return err
+	}
+
+	all[e.Name] = e
+
+	return apply(all, protocols...)
+}
+
+// Remove removes the export with the given name if it exists.
+func Remove(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return err
+	}
+
+	e, ok := all[name]
+	if !ok {
+		return nil
+	}
+
+	err = os.Remove(exportsPath(name + ".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	delete(all, name)
+
+	return apply(all, e.Protocol)
+}
+
+// Restore sets up all the exports again, for use on startup.
+func Restore() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return err
+	}
+
+	if len(all) == 0 {
+		return nil
+	}
+
+	return apply(all, ProtocolNFS, ProtocolSMB)
+}
+
+// load returns the exports which have been set up, keyed by name.
+func load() (map[string]Export, error) {
+	all := map[string]Export{}
+
+	files, err := filepath.Glob(exportsPath("*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		e := Export{}
+		err = json.Unmarshal(data, &e)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed parsing export %q", file)
+		}
+
+		all[e.Name] = e
+	}
+
+	return all, nil
+}
+
+// apply regenerates the configuration of the given protocols from the exports and reloads their servers.
+func apply(all map[string]Export, protocols ...string) error {
+	// Sort the exports so that the generated files are stable.
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, protocol := range protocols {
+		exports := []Export{}
+		for _, name := range names {
+			if all[name].Protocol == protocol {
+				exports = append(exports, all[name])
+			}
+		}
+
+		var err error
+		switch protocol {
+		case ProtocolNFS:
+			err = applyNFS(exports)
+		case ProtocolSMB:
+			err = applySMB(exports)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "Failed applying %s exports", strings.ToUpper(protocol))
+		}
+	}
+
+	return nil
+}
+
+// applyNFS writes the NFS exports file and makes the kernel NFS server reload it.
+func applyNFS(exports []Export) error {
+	if len(exports) == 0 {
+		err := os.Remove(NFSExportsPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+	} else {
+		var sb strings.Builder
+		sb.WriteString("# Managed by LXD, do not edit.\n")
+		for _, e := range exports {
+			options := "rw"
+			if e.ReadOnly {
+				options = "ro"
+			}
+
+			// Set an explicit fsid as the exported volumes may not have a stable device number.
+			options += fmt.Sprintf(",sync,no_subtree_check,fsid=%s", uuid.NewSHA1(uuid.NameSpace_OID, []byte(e.Name)))
+
+			fmt.Fprintf(&sb, "%q", e.Path)
+			for _, client := range e.Clients {
+				fmt.Fprintf(&sb, " %s(%s)", client, options)
+			}
+
+			sb.WriteString("\n")
+		}
+
+		err := os.MkdirAll(filepath.Dir(NFSExportsPath), 0755)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(NFSExportsPath, []byte(sb.String()), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := exec.LookPath("exportfs")
+	if err != nil {
+		return fmt.Errorf("NFS exports require exportfs (from nfs-kernel-server or nfs-utils)")
+	}
+
+	_, err = shared.RunCommand("exportfs", "-ra")
+	return err
+}
+
+// smbOwner returns the names of the user and group owning the exported directory, which SMB clients act as.
+func smbOwner(path string) (string, string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", fmt.Errorf("Failed getting the owner of %q", path)
+	}
+
+	u, err := user.LookupId(strconv.FormatUint(uint64(stat.Uid), 10))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "SMB exports require the owner of %q (uid %d) to have a user name on the host", path, stat.Uid)
+	}
+
+	g, err := user.LookupGroupId(strconv.FormatUint(uint64(stat.Gid), 10))
+	if err != nil {
+		return "", "", errors.Wrapf(err, "SMB exports require the group of %q (gid %d) to have a group name on the host", path, stat.Gid)
+	}
+
+	return u.Username, g.Name, nil
+}
+
+// applySMB writes the ksmbd configuration and user database and makes ksmbd reload them, starting it if needed.
+func applySMB(exports []Export) error {
+	confPath := exportsPath("ksmbd.conf")
+	usersPath := exportsPath("ksmbdpwd.db")
+
+	if len(exports) == 0 && !shared.PathExists(confPath) {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Managed by LXD, do not edit.\n[global]\n\tnetbios name = LXD\n\tmap to guest = never\n")
+	for _, e := range exports {
+		// Clients act as the owner of the volume rather than root, so that they get the same access as
+		// the instances using it.
+		owner, group, err := smbOwner(e.Path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&sb, "\n[%s]\n\tpath = %s\n\tvalid users = %s\n\tforce user = %s\n\tforce group = %s\n", e.Name, e.Path, e.Username, owner, group)
+		if e.ReadOnly {
+			sb.WriteString("\tread only = yes\n")
+		} else {
+			sb.WriteString("\tread only = no\n")
+		}
+
+		if len(e.Clients) > 0 {
+			fmt.Fprintf(&sb, "\thosts allow = %s\n", strings.Join(e.Clients, " "))
+		}
+	}
+
+	err := os.MkdirAll(exportsPath(), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(confPath, []byte(sb.String()), 0600)
+	if err != nil {
+		return err
+	}
+
+	// Write the user database from the password hashes, in the format used by ksmbd.adduser, so that the
+	// passwords are neither stored nor passed on the command line. Regenerating it from scratch makes sure
+	// that removed users don't linger.
+	var users strings.Builder
+	added := map[string]bool{}
+	for _, e := range exports {
+		if added[e.Username] {
+			continue
+		}
+
+		hash, err := hex.DecodeString(strings.TrimPrefix(e.PasswordHash, passwordHashPrefix))
+		if err != nil {
+			return errors.Wrapf(err, "Invalid password hash of SMB user %q", e.Username)
+		}
+
+		fmt.Fprintf(&users, "%s:%s\n", e.Username, base64.StdEncoding.EncodeToString(hash))
+		added[e.Username] = true
+	}
+
+	err = ioutil.WriteFile(usersPath, []byte(users.String()), 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.LookPath("ksmbd.mountd")
+	if err != nil {
+		if len(exports) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("SMB exports require ksmbd.mountd (from ksmbd-tools)")
+	}
+
+	// Reload ksmbd if already running, start it otherwise.
+	_, err = shared.RunCommand("ksmbd.control", "--reload")
+	if err == nil {
+		return nil
+	}
+
+	if len(exports) == 0 {
+		return nil
+	}
+
+	_, err = shared.RunCommand("ksmbd.mountd", "-c", confPath, "-u", usersPath)
+	return err
+}
@@ -0,0 +1,56 @@
+package exports
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportValidate(t *testing.T) {
+	nfs := Export{Name: "default_default_vol", Path: "/tmp", Protocol: ProtocolNFS}
+	assert.Error(t, nfs.Validate())
+
+	nfs.Clients = []string{"10.0.0.0/24"}
+	assert.NoError(t, nfs.Validate())
+
+	smb := Export{Name: "default_default_vol", Path: "/tmp", Protocol: ProtocolSMB, Username: "backup"}
+	assert.Error(t, smb.Validate())
+
+	smb.PasswordHash = "secret"
+	assert.Error(t, smb.Validate())
+
+	smb.PasswordHash = PasswordHash("secret")
+	assert.NoError(t, smb.Validate())
+
+	smb.Username = "Not Valid"
+	assert.Error(t, smb.Validate())
+
+	assert.Error(t, Export{Protocol: "ftp"}.Validate())
+
+	nfs.Name = "default_default_vol]\n[other"
+	assert.Error(t, nfs.Validate())
+
+	nfs.Name = "default_default_vol"
+	nfs.Path = "/tmp/vol\n\tpath = /"
+	assert.Error(t, nfs.Validate())
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "default_default_vol", Name("default", "default", "vol"))
+	assert.Equal(t, "default_my%5Fproject_vol%20%2B", Name("default", "my_project", "vol +"))
+
+	// Names with underscores don't collide.
+	assert.NotEqual(t, Name("a_b", "c", "d"), Name("a", "b_c", "d"))
+	assert.NotEqual(t, Name("a", "b_c", "d"), Name("a", "b", "c_d"))
+	assert.Regexp(t, nameRegexp, Name("pool", "proj", "vol]\n"))
+}
+
+func TestPasswordHash(t *testing.T) {
+	// NT hash of "password".
+	hash := PasswordHash("password")
+	assert.Equal(t, "nthash:8846f7eaee8fb117ad06bdd830b7586c", hash)
+
+	// Hashes are kept as they are.
+	assert.Equal(t, hash, PasswordHash(hash))
+	assert.Equal(t, "", PasswordHash(""))
+}
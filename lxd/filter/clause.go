@@ -7,6 +7,9 @@ import (
 	"github.com/lxc/lxd/shared"
 )
 
+// Operators lists the comparison operators supported in filter clauses.
+var Operators = []string{"eq", "ne", "lt", "le", "gt", "ge"}
+
 // Clause is a single filter clause in a filter string.
 type Clause struct {
 	PrevLogical string
@@ -45,6 +48,9 @@ func Parse(s string) ([]Clause, error) {
 			return nil, fmt.Errorf("clause has no operator")
 		}
 		clause.Operator = parts[index]
+		if !shared.StringInSlice(clause.Operator, Operators) {
+			return nil, fmt.Errorf("invalid operator %q", clause.Operator)
+		}
 
 		index++
 		if index == len(parts) {
@@ -53,7 +59,9 @@ func Parse(s string) ([]Clause, error) {
 		value := parts[index]
 
 		// support strings with spaces that are quoted
-		if strings.HasPrefix(value, "\"") {
+		if len(value) > 1 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+			value = value[1 : len(value)-1]
+		} else if strings.HasPrefix(value, "\"") {
 			value = value[1:]
 			for {
 				index++
@@ -87,3 +95,31 @@ func Parse(s string) ([]Clause, error) {
 
 	return clauses, nil
 }
+
+// ExactValue returns the value the given field must be equal to for an object to match the clauses.
+// This is only known when all the clauses must match and one of them is a plain equality on the field,
+// which allows narrowing down the objects to consider before loading them.
+func ExactValue(clauses []Clause, field string) (string, bool) {
+	value := ""
+	found := false
+
+	for _, clause := range clauses {
+		if clause.PrevLogical != "and" {
+			return "", false
+		}
+
+		if clause.Field != field || clause.Not || clause.Operator != "eq" || strings.Contains(clause.Value, "*") {
+			continue
+		}
+
+		// Conflicting values can't be handled by the caller.
+		if found && clause.Value != value {
+			return "", false
+		}
+
+		value = clause.Value
+		found = true
+	}
+
+	return value, found
+}
@@ -18,6 +18,7 @@ func TestParse_Error(t *testing.T) {
 		"foo eq bar and":         "unterminated compound clause",
 		"foo eq \"bar egg\" and": "unterminated compound clause",
 		"foo eq bar xxx":         "invalid clause composition",
+		"foo is bar":             "invalid operator \"is\"",
 	}
 	for s, message := range cases {
 		t.Run(s, func(t *testing.T) {
@@ -45,3 +46,39 @@ func TestParse(t *testing.T) {
 	assert.Equal(t, "eq", clause2.Operator)
 	assert.Equal(t, "yuk", clause2.Value)
 }
+
+func TestParse_QuotedWord(t *testing.T) {
+	clauses, err := filter.Parse("foo eq \"bar\"")
+	require.NoError(t, err)
+	assert.Len(t, clauses, 1)
+	assert.Equal(t, "bar", clauses[0].Value)
+}
+
+func TestExactValue(t *testing.T) {
+	cases := []struct {
+		filter string
+		value  string
+		ok     bool
+	}{
+		{"name eq c1", "c1", true},
+		{"status eq Running and name eq c1", "c1", true},
+		{"name eq c1 and name eq c1", "c1", true},
+		{"name eq c1 and name eq c2", "", false},
+		{"name eq c1 or status eq Running", "", false},
+		{"not name eq c1", "", false},
+		{"name ne c1", "", false},
+		{"name eq c*", "", false},
+		{"status eq Running", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.filter, func(t *testing.T) {
+			clauses, err := filter.Parse(c.filter)
+			require.NoError(t, err)
+
+			value, ok := filter.ExactValue(clauses, "name")
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.value, value)
+		})
+	}
+}
@@ -1,15 +1,41 @@
 package filter
 
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Match returns true if the given object matches the given filter.
 func Match(obj interface{}, clauses []Clause) bool {
 	match := true
 
 	for _, clause := range clauses {
 		value := ValueOf(obj, clause.Field)
-		clauseMatch := value == clause.Value
 
-		if clause.Operator == "ne" {
-			clauseMatch = !clauseMatch
+		var clauseMatch bool
+		switch clause.Operator {
+		case "eq":
+			clauseMatch = matchEqual(value, clause.Value)
+		case "ne":
+			clauseMatch = !matchEqual(value, clause.Value)
+		default:
+			cmp, ok := compare(value, clause.Value)
+			if ok {
+				switch clause.Operator {
+				case "lt":
+					clauseMatch = cmp < 0
+				case "le":
+					clauseMatch = cmp <= 0
+				case "gt":
+					clauseMatch = cmp > 0
+				case "ge":
+					clauseMatch = cmp >= 0
+				}
+			}
 		}
 
 		// Finish out logic
@@ -29,3 +55,104 @@ func Match(obj interface{}, clauses []Clause) bool {
 
 	return match
 }
+
+// matchEqual returns whether the value is equal to the one of the clause, which may contain "*" wildcards. Lists
+// match if any of their elements does.
+func matchEqual(value interface{}, clauseValue string) bool {
+	if value == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			if matchEqual(rv.Index(i).Interface(), clauseValue) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	str := toString(value)
+
+	if strings.Contains(clauseValue, "*") {
+		pattern := strings.Replace(regexp.QuoteMeta(clauseValue), `\*`, ".*", -1)
+		match, err := regexp.MatchString(fmt.Sprintf("^%s$", pattern), str)
+		return err == nil && match
+	}
+
+	return str == clauseValue
+}
+
+// compare compares the value with the one of the clause. Numbers and timestamps are compared by value, other
+// strings lexically. Returns false if the values can't be compared.
+func compare(value interface{}, clauseValue string) (int, bool) {
+	switch v := value.(type) {
+	case nil, bool:
+		return 0, false
+	case time.Time:
+		t, err := time.Parse(time.RFC3339, clauseValue)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", clauseValue)
+			if err != nil {
+				return 0, false
+			}
+		}
+
+		if v.Before(t) {
+			return -1, true
+		} else if v.After(t) {
+			return 1, true
+		}
+
+		return 0, true
+	case string:
+		a, errA := strconv.ParseFloat(v, 64)
+		b, errB := strconv.ParseFloat(clauseValue, 64)
+		if errA != nil || errB != nil {
+			return strings.Compare(v, clauseValue), true
+		}
+
+		return compareFloat(a, b), true
+	}
+
+	b, err := strconv.ParseFloat(clauseValue, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloat(float64(rv.Int()), b), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareFloat(float64(rv.Uint()), b), true
+	case reflect.Float32, reflect.Float64:
+		return compareFloat(rv.Float(), b), true
+	}
+
+	return 0, false
+}
+
+func compareFloat(a float64, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+// toString returns the string representation of a field value used for equality checks.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
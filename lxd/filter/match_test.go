@@ -18,6 +18,7 @@ func TestMatch_Instance(t *testing.T) {
 				"image.os": "BusyBox",
 			},
 			Stateful: false,
+			Tags:     []string{"prod", "web"},
 		},
 		CreatedAt: time.Date(2020, 1, 29, 11, 10, 32, 0, time.UTC),
 		Name:      "c1",
@@ -40,6 +41,17 @@ func TestMatch_Instance(t *testing.T) {
 		"config.image.os eq BusyBox and expanded_devices.root.path eq /": true,
 		"name eq c2 or status eq Running":                                true,
 		"name eq c2 or name eq c3":                                       false,
+		"name eq c*":                                                     true,
+		"name ne c*":                                                     false,
+		"config.image.os eq *Box":                                        true,
+		"stateful eq false":                                              true,
+		"created_at gt 2020-01-01":                                       true,
+		"created_at lt 2020-01-29T11:00:00Z":                             false,
+		"name ge c1 and name lt c2":                                      true,
+		"tags eq prod":                                                   true,
+		"tags eq we*":                                                    true,
+		"tags eq dev":                                                    false,
+		"tags ne web":                                                    false,
 	}
 	for s := range cases {
 		t.Run(s, func(t *testing.T) {
@@ -61,10 +73,15 @@ func TestMatch_Image(t *testing.T) {
 			},
 		},
 		Architecture: "i686",
+		Size:         10000,
 	}
 	cases := map[string]interface{}{
 		"properties.os eq Ubuntu": true,
 		"architecture eq x86_64":  false,
+		"public eq true":          true,
+		"size gt 1000":            true,
+		"size le 1000":            false,
+		"size gt 100000":          false,
 	}
 	for s := range cases {
 		t.Run(s, func(t *testing.T) {
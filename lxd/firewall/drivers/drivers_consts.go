@@ -27,6 +27,7 @@ type Opts struct {
 	FeaturesV6 *FeatureOpts // Enable IPv6 firewall with specified options. Off if not provided.
 	SNATV4     *SNATOpts    // Enable IPv4 SNAT with specified options. Off if not provided.
 	SNATV6     *SNATOpts    // Enable IPv6 SNAT with specified options. Off if not provided.
+	SNATNAT64  *SNATOpts    // Enable IPv4 SNAT of the NAT64 translator pool with specified options. Off if not provided.
 	ACL        bool         // Enable ACL during setup.
 }
 
@@ -43,4 +44,18 @@ type ACLRule struct {
 	DestinationPort string
 	ICMPType        string
 	ICMPCode        string
+	DSCP            string // DSCP value to set on matched packets (optional).
+}
+
+// InstanceRef identifies an instance whose rules are looked up.
+type InstanceRef struct {
+	Project string
+	Name    string
+}
+
+// Ruleset represents the rules generated for a network and the instances connected to it.
+type Ruleset struct {
+	Network   []string // Rules generated for the network itself.
+	Instances []string // Rules generated for the devices of the instances.
+	Conflicts []string // Problems caused by other tools (e.g. default drop policies) which may affect the rules.
 }
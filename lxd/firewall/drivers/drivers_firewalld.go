@@ -0,0 +1,132 @@
+package drivers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// firewalldZone is the firewalld zone LXD network interfaces are added to.
+const firewalldZone = "trusted"
+
+// Firewalld registers LXD networks with firewalld through firewall-cmd. Both the runtime and the permanent
+// configuration are changed so that the registration survives reloads of firewalld.
+type Firewalld struct{}
+
+// String returns the firewall manager name.
+func (d Firewalld) String() string {
+	return "firewalld"
+}
+
+// Available returns whether firewalld is running.
+func (d Firewalld) Available() bool {
+	output, err := shared.RunCommand("firewall-cmd", "--state")
+	return err == nil && strings.TrimSpace(output) == "running"
+}
+
+// firewallCmd runs firewall-cmd with the given arguments against both the runtime and the permanent configuration.
+func (d Firewalld) firewallCmd(args ...string) error {
+	_, err := shared.RunCommand("firewall-cmd", args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("firewall-cmd", append([]string{"--permanent"}, args...)...)
+	return err
+}
+
+// networkComment returns the comment added to the direct rules of the network.
+// firewall-cmd quotes comments containing spaces when listing rules, so this one has none.
+func (d Firewalld) networkComment(networkName string) string {
+	return fmt.Sprintf("lxd-network-%s", networkName)
+}
+
+// NetworkRegister adds the network's interface to the trusted zone so that firewalld lets the DNS, DHCP and
+// forwarded traffic through, and adds direct rules for the outbound NAT of the network.
+func (d Firewalld) NetworkRegister(networkName string, opts Opts) error {
+	err := d.firewallCmd(fmt.Sprintf("--zone=%s", firewalldZone), fmt.Sprintf("--change-interface=%s", networkName))
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding interface %q to firewalld zone %q", networkName, firewalldZone)
+	}
+
+	// Replace the direct rules as the subnets may have changed.
+	err = d.removeDirectRules(networkName)
+	if err != nil {
+		return err
+	}
+
+	for family, snat := range map[string]*SNATOpts{"ipv4": opts.SNATV4, "ipv6": opts.SNATV6} {
+		if snat == nil || snat.Subnet == nil {
+			continue
+		}
+
+		args := []string{"--direct", "--add-rule", family, "nat", "POSTROUTING", "0", "-s", snat.Subnet.String(), "!", "-d", snat.Subnet.String(), "-m", "comment", "--comment", d.networkComment(networkName)}
+		if snat.SNATAddress != nil {
+			args = append(args, "-j", "SNAT", "--to-source", snat.SNATAddress.String())
+		} else {
+			args = append(args, "-j", "MASQUERADE")
+		}
+
+		err = d.firewallCmd(args...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding firewalld %s NAT rule for network %q", family, networkName)
+		}
+	}
+
+	return nil
+}
+
+// NetworkUnregister removes the network's interface from the trusted zone along with its direct rules.
+func (d Firewalld) NetworkUnregister(networkName string) error {
+	err := d.removeDirectRules(networkName)
+	if err != nil {
+		return err
+	}
+
+	// Removing an interface which isn't part of the zone fails, so the error is ignored.
+	_ = d.firewallCmd(fmt.Sprintf("--zone=%s", firewalldZone), fmt.Sprintf("--remove-interface=%s", networkName))
+
+	return nil
+}
+
+// removeDirectRules removes the direct rules of the network from the runtime and the permanent configuration.
+func (d Firewalld) removeDirectRules(networkName string) error {
+	for _, permanent := range []bool{false, true} {
+		args := []string{"--direct", "--get-all-rules"}
+		if permanent {
+			args = append([]string{"--permanent"}, args...)
+		}
+
+		output, err := shared.RunCommand("firewall-cmd", args...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed listing firewalld direct rules")
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			// Arguments such as "!" are listed quoted.
+			fields := strings.Fields(line)
+			for i := range fields {
+				fields[i] = strings.Trim(fields[i], "'")
+			}
+
+			if !shared.StringInSlice(d.networkComment(networkName), fields) {
+				continue
+			}
+
+			args := append([]string{"--direct", "--remove-rule"}, fields...)
+			if permanent {
+				args = append([]string{"--permanent"}, args...)
+			}
+
+			_, err = shared.RunCommand("firewall-cmd", args...)
+			if err != nil {
+				return errors.Wrapf(err, "Failed removing firewalld direct rule %q", line)
+			}
+		}
+	}
+
+	return nil
+}
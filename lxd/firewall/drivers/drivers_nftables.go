@@ -133,6 +133,8 @@ type nftGenericItem struct {
 	Table    string `json:"table"`  // Table the item belongs to (for chains and rules).
 	Chain    string `json:"chain"`  // Chain the item belongs to (for rules).
 	Name     string `json:"name"`   // Name of item (for tables and chains).
+	Hook     string `json:"hook"`   // Hook of item (for base chains).
+	Policy   string `json:"policy"` // Policy of item (for base chains).
 }
 
 // nftParseRuleset parses the ruleset and returns the generic parts as a slice of items.
@@ -229,11 +231,17 @@ func (d Nftables) networkSetupForwardingPolicy(networkName string, ip4Allow *boo
 	return nil
 }
 
+// nftablesSNATRule is an outbound NAT rule along with the family it applies to.
+type nftablesSNATRule struct {
+	*SNATOpts
+	Family string
+}
+
 // networkSetupOutboundNAT configures outbound NAT.
 // If srcIP is non-nil then SNAT is used with the specified address, otherwise MASQUERADE mode is used.
 // Append mode is always on and so the append argument is ignored.
-func (d Nftables) networkSetupOutboundNAT(networkName string, SNATV4 *SNATOpts, SNATV6 *SNATOpts) error {
-	rules := make(map[string]*SNATOpts, 0)
+func (d Nftables) networkSetupOutboundNAT(networkName string, SNATV4 *SNATOpts, SNATV6 *SNATOpts, SNATNAT64 *SNATOpts) error {
+	rules := []nftablesSNATRule{}
 
 	tplFields := map[string]interface{}{
 		"namespace":      nftablesNamespace,
@@ -244,11 +252,15 @@ func (d Nftables) networkSetupOutboundNAT(networkName string, SNATV4 *SNATOpts,
 
 	// If SNAT IP not supplied then use the IP of the outbound interface (MASQUERADE).
 	if SNATV4 != nil {
-		rules["ip"] = SNATV4
+		rules = append(rules, nftablesSNATRule{SNATOpts: SNATV4, Family: "ip"})
+	}
+
+	if SNATNAT64 != nil {
+		rules = append(rules, nftablesSNATRule{SNATOpts: SNATNAT64, Family: "ip"})
 	}
 
 	if SNATV6 != nil {
-		rules["ip6"] = SNATV6
+		rules = append(rules, nftablesSNATRule{SNATOpts: SNATV6, Family: "ip6"})
 	}
 
 	tplFields["rules"] = rules
@@ -321,8 +333,8 @@ func (d Nftables) NetworkSetup(networkName string, opts Opts) error {
 		}
 	}
 
-	if opts.SNATV4 != nil || opts.SNATV6 != nil {
-		err := d.networkSetupOutboundNAT(networkName, opts.SNATV4, opts.SNATV6)
+	if opts.SNATV4 != nil || opts.SNATV6 != nil || opts.SNATNAT64 != nil {
+		err := d.networkSetupOutboundNAT(networkName, opts.SNATV4, opts.SNATV6, opts.SNATNAT64)
 		if err != nil {
 			return err
 		}
@@ -375,6 +387,66 @@ func (d Nftables) NetworkClear(networkName string, _ bool, _ []uint) error {
 	return nil
 }
 
+// NetworkRuleset returns the rules generated for the network and the instances, along with the base chains of
+// other tables dropping traffic by default as those can prevent the rules from taking effect.
+func (d Nftables) NetworkRuleset(networkName string, instances []InstanceRef) (*Ruleset, error) {
+	items, err := d.nftParseRuleset()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing nftables existing ruleset")
+	}
+
+	ruleset := &Ruleset{
+		Network:   []string{},
+		Instances: []string{},
+		Conflicts: []string{},
+	}
+
+	for _, item := range items {
+		if item.ItemType != "chain" {
+			continue
+		}
+
+		if item.Table != nftablesNamespace {
+			if item.Policy == "drop" && shared.StringInSlice(item.Hook, []string{"input", "forward"}) {
+				ruleset.Conflicts = append(ruleset.Conflicts, fmt.Sprintf("Chain %q of %s table %q drops %s traffic by default", item.Name, item.Family, item.Table, item.Hook))
+			}
+
+			continue
+		}
+
+		// Chains are named after the network or the instance device they are generated for.
+		fields := strings.SplitN(item.Name, nftablesChainSeparator, 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		var rules *[]string
+		if fields[1] == networkName {
+			rules = &ruleset.Network
+		} else {
+			for _, inst := range instances {
+				if strings.HasPrefix(fields[1], fmt.Sprintf("%s%s", project.Instance(inst.Project, inst.Name), nftablesChainSeparator)) {
+					rules = &ruleset.Instances
+					break
+				}
+			}
+		}
+
+		if rules == nil {
+			continue
+		}
+
+		output, err := shared.RunCommand("nft", "-nn", "list", "chain", item.Family, nftablesNamespace, item.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed listing nftables chain %q (%s)", item.Name, item.Family)
+		}
+
+		*rules = append(*rules, strings.TrimSpace(output))
+	}
+
+	return ruleset, nil
+}
+
 //instanceDeviceLabel returns the unique label used for instance device chains.
 func (d Nftables) instanceDeviceLabel(projectName, instanceName, deviceName string) string {
 	return fmt.Sprintf("%s%s%s", project.Instance(projectName, instanceName), nftablesChainSeparator, deviceName)
@@ -614,6 +686,38 @@ func (d Nftables) InstanceSetupRPFilter(projectName string, instanceName string,
 	return nil
 }
 
+// InstanceSetupDSCP sets the DSCP of the traffic coming from the specified instance device's host interface.
+func (d Nftables) InstanceSetupDSCP(projectName string, instanceName string, deviceName string, hostName string, dscp string) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+	tplFields := map[string]interface{}{
+		"namespace":      nftablesNamespace,
+		"chainSeparator": nftablesChainSeparator,
+		"deviceLabel":    deviceLabel,
+		"hostName":       hostName,
+		"dscp":           dscp,
+		"family":         "bridge",
+	}
+
+	err := d.applyNftConfig(nftablesInstanceDSCP, tplFields)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding DSCP rules for instance device %q (%s)", deviceLabel, tplFields["family"])
+	}
+
+	return nil
+}
+
+// InstanceClearDSCP removes the DSCP rules for the specified instance device.
+func (d Nftables) InstanceClearDSCP(projectName string, instanceName string, deviceName string) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+
+	err := d.removeChains([]string{"bridge"}, deviceLabel, "qos")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing DSCP rules for instance device %q", deviceLabel)
+	}
+
+	return nil
+}
+
 // InstanceClearRPFilter removes reverse path filtering for the specified instance device on the host interface.
 func (d Nftables) InstanceClearRPFilter(projectName string, instanceName string, deviceName string) error {
 	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
@@ -630,7 +734,29 @@ func (d Nftables) InstanceClearRPFilter(projectName string, instanceName string,
 // NetworkApplyACLRules applies ACL rules to the existing firewall chains.
 func (d Nftables) NetworkApplyACLRules(networkName string, rules []ACLRule) error {
 	nftRules := make([]string, 0)
+	nftQoSRules := make([]string, 0)
 	for _, rule := range rules {
+		// DSCP marking needs to apply to all the packets of the flow and not just the first one, so add separate
+		// rules without a verdict that get evaluated before the established connection rule.
+		if rule.DSCP != "" {
+			qosRule := rule
+			qosRule.Action = ""
+			qosRule.Log = false
+
+			for _, ipVersion := range []uint{4, 6} {
+				nftRule, _, err := d.aclRuleCriteriaToRules(networkName, ipVersion, &qosRule)
+				if err != nil {
+					return err
+				}
+
+				if nftRule != "" {
+					nftQoSRules = append(nftQoSRules, nftRule)
+				}
+			}
+
+			rule.DSCP = ""
+		}
+
 		// First try generating rules with IPv4 or IP agnostic criteria.
 		nftRule, partial, err := d.aclRuleCriteriaToRules(networkName, 4, &rule)
 		if err != nil {
@@ -665,6 +791,7 @@ func (d Nftables) NetworkApplyACLRules(networkName string, rules []ACLRule) erro
 		"networkName":    networkName,
 		"family":         "inet",
 		"rules":          nftRules,
+		"qosRules":       nftQoSRules,
 	}
 	config := &strings.Builder{}
 	err := nftablesNetACLRules.Execute(config, tplFields)
@@ -690,6 +817,15 @@ func (d Nftables) aclRuleCriteriaToRules(networkName string, ipVersion uint, rul
 		args = append(args, "iifname", networkName) // Coming from network's interface into host.
 	}
 
+	// DSCP marking is specific to the IP version, so restrict the rule to the matching packets.
+	if rule.DSCP != "" {
+		if ipVersion == 4 {
+			args = append(args, "meta", "nfproto", "ipv4")
+		} else {
+			args = append(args, "meta", "nfproto", "ipv6")
+		}
+	}
+
 	// Add subject filters.
 	isPartialRule := false
 
@@ -786,13 +922,24 @@ func (d Nftables) aclRuleCriteriaToRules(networkName string, ipVersion uint, rul
 		}
 	}
 
+	// Handle DSCP marking.
+	if rule.DSCP != "" {
+		if ipVersion == 4 {
+			args = append(args, "ip", "dscp", "set", rule.DSCP)
+		} else {
+			args = append(args, "ip6", "dscp", "set", rule.DSCP)
+		}
+	}
+
 	// Handle action.
 	action := rule.Action
 	if action == "allow" {
 		action = "accept"
 	}
 
-	args = append(args, action)
+	if action != "" {
+		args = append(args, action)
+	}
 
 	return strings.Join(args, " "), isPartialRule, nil
 }
@@ -30,11 +30,11 @@ var nftablesNetOutboundNAT = template.Must(template.New("nftablesNetOutboundNAT"
 chain pstrt{{.chainSeparator}}{{.networkName}} {
 	type nat hook postrouting priority 100; policy accept;
 
-	{{- range $ipFamily, $config := .rules}}
-	{{if $config.SNATAddress -}}
-	{{$ipFamily}} saddr {{$config.Subnet}} {{$ipFamily}} daddr != {{$config.Subnet}} snat {{$config.SNATAddress}}
+	{{- range .rules}}
+	{{if .SNATAddress -}}
+	{{.Family}} saddr {{.Subnet}} {{.Family}} daddr != {{.Subnet}} snat {{.SNATAddress}}
 	{{else -}}
-	{{$ipFamily}} saddr {{$config.Subnet}} {{$ipFamily}} daddr != {{$config.Subnet}} masquerade
+	{{.Family}} saddr {{.Subnet}} {{.Family}} daddr != {{.Subnet}} masquerade
 	{{- end}}
 	{{- end}}
 }
@@ -157,6 +157,10 @@ flush chain {{.family}} {{.namespace}} acl{{.chainSeparator}}{{.networkName}}
 
 table {{.family}} {{.namespace}} {
 	chain acl{{.chainSeparator}}{{.networkName}} {
+		{{- range .qosRules}}
+		{{.}}
+		{{- end}}
+
                 ct state established,related accept
 
 		{{- range .rules}}
@@ -231,6 +235,15 @@ chain fwd{{.chainSeparator}}{{.deviceLabel}} {
 }
 `))
 
+// nftablesInstanceDSCP defines the rules to set the DSCP of the traffic coming from a bridged instance device.
+var nftablesInstanceDSCP = template.Must(template.New("nftablesInstanceDSCP").Parse(`
+chain qos{{.chainSeparator}}{{.deviceLabel}} {
+	type filter hook prerouting priority -150; policy accept;
+	iifname "{{.hostName}}" ether type ip ip dscp set {{.dscp}}
+	iifname "{{.hostName}}" ether type ip6 ip6 dscp set {{.dscp}}
+}
+`))
+
 // nftablesInstanceRPFilter defines the rules to perform reverse path filtering.
 var nftablesInstanceRPFilter = template.Must(template.New("nftablesInstanceRPFilter").Parse(`
 chain prert{{.chainSeparator}}{{.deviceLabel}} {
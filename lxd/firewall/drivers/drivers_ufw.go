@@ -0,0 +1,92 @@
+package drivers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// ufwApplicationsPath is the directory ufw loads the application profiles from.
+const ufwApplicationsPath = "/etc/ufw/applications.d"
+
+// Ufw registers LXD networks with ufw. The DNS and DHCP services of each network are described by an application
+// profile and allowed on the network's interface, and traffic routed through the interface is allowed. Those are
+// stored as ufw rules and so survive reloads of ufw.
+type Ufw struct{}
+
+// String returns the firewall manager name.
+func (d Ufw) String() string {
+	return "ufw"
+}
+
+// Available returns whether ufw is active.
+func (d Ufw) Available() bool {
+	output, err := shared.RunCommand("ufw", "status")
+	return err == nil && strings.Contains(output, "Status: active")
+}
+
+// applicationName returns the name of the ufw application profile of the network.
+func (d Ufw) applicationName(networkName string) string {
+	return fmt.Sprintf("lxd-%s", networkName)
+}
+
+// rules returns the ufw rules allowing the traffic of the network.
+func (d Ufw) rules(networkName string) [][]string {
+	return [][]string{
+		{"allow", "in", "on", networkName, "to", "any", "app", d.applicationName(networkName)},
+		{"route", "allow", "in", "on", networkName},
+		{"route", "allow", "out", "on", networkName},
+	}
+}
+
+// NetworkRegister writes the application profile of the network and adds the ufw rules allowing its traffic.
+func (d Ufw) NetworkRegister(networkName string, opts Opts) error {
+	name := d.applicationName(networkName)
+	profile := fmt.Sprintf("[%s]\ntitle=LXD network %s\ndescription=DNS and DHCP services of the LXD network %s\nports=53|67,547/udp\n", name, networkName, networkName)
+
+	err := ioutil.WriteFile(filepath.Join(ufwApplicationsPath, name), []byte(profile), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "Failed writing ufw application profile for network %q", networkName)
+	}
+
+	_, err = shared.RunCommand("ufw", "app", "update", name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading ufw application profile %q", name)
+	}
+
+	for _, rule := range d.rules(networkName) {
+		_, err = shared.RunCommand("ufw", rule...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding ufw rule %q", strings.Join(rule, " "))
+		}
+	}
+
+	return nil
+}
+
+// NetworkUnregister removes the ufw rules and the application profile of the network.
+func (d Ufw) NetworkUnregister(networkName string) error {
+	for _, rule := range d.rules(networkName) {
+		// Route rules are deleted with "ufw route delete <rule>", the others with "ufw delete <rule>".
+		args := append([]string{"delete"}, rule...)
+		if rule[0] == "route" {
+			args = append([]string{"route", "delete"}, rule[1:]...)
+		}
+
+		// Deleting a rule which doesn't exist is harmless, so the error is ignored.
+		_, _ = shared.RunCommand("ufw", args...)
+	}
+
+	err := os.Remove(filepath.Join(ufwApplicationsPath, d.applicationName(networkName)))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Failed removing ufw application profile for network %q", networkName)
+	}
+
+	return nil
+}
@@ -449,6 +449,13 @@ func (d Xtables) NetworkSetup(networkName string, opts Opts) error {
 		}
 	}
 
+	if opts.SNATNAT64 != nil {
+		err := d.networkSetupOutboundNAT(networkName, opts.SNATNAT64.Subnet, opts.SNATNAT64.SNATAddress, opts.SNATNAT64.Append)
+		if err != nil {
+			return err
+		}
+	}
+
 	if opts.FeaturesV4 != nil {
 		if opts.FeaturesV4.ICMPDHCPDNSAccess {
 			err := d.networkSetupICMPDHCPDNSAccess(networkName, 4)
@@ -507,6 +514,12 @@ func (d Xtables) NetworkSetup(networkName string, opts Opts) error {
 func (d Xtables) NetworkApplyACLRules(networkName string, rules []ACLRule) error {
 	chain := fmt.Sprintf("%s_%s", iptablesChainACLFilterPrefix, networkName)
 
+	for _, rule := range rules {
+		if rule.DSCP != "" {
+			return fmt.Errorf("DSCP marking in ACL rules requires the nftables firewall driver")
+		}
+	}
+
 	// Parse rules for both IP families before applying either family of rules.
 	iptCmdRules := make(map[string][][]string)
 	for _, ipVersion := range []uint{4, 6} {
@@ -787,6 +800,70 @@ func (d Xtables) NetworkClear(networkName string, delete bool, ipVersions []uint
 	return nil
 }
 
+// NetworkRuleset returns the iptables and ip6tables rules generated for the network and the instances, along with
+// the built-in chains whose default policy drops traffic as those can prevent the rules from taking effect.
+func (d Xtables) NetworkRuleset(networkName string, instances []InstanceRef) (*Ruleset, error) {
+	ruleset := &Ruleset{
+		Network:   []string{},
+		Instances: []string{},
+		Conflicts: []string{},
+	}
+
+	networkComment := fmt.Sprintf("generated for %s", d.networkIPTablesComment(networkName))
+	networkChains := []string{
+		fmt.Sprintf("%s_%s", iptablesChainNICFilterPrefix, networkName),
+		fmt.Sprintf("%s_%s", iptablesChainACLFilterPrefix, networkName),
+	}
+
+	// Instance device comments all start with the same prefix for a given instance.
+	instanceComments := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		instanceComments = append(instanceComments, fmt.Sprintf("generated for LXD container %s (", project.Instance(inst.Project, inst.Name)))
+	}
+
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		_, err := exec.LookPath(cmd)
+		if err != nil {
+			continue
+		}
+
+		for _, table := range []string{"filter", "nat", "mangle", "raw"} {
+			output, err := shared.TryRunCommand(cmd, "-w", "-t", table, "-S")
+			if err != nil {
+				// The table may not be available on this system.
+				continue
+			}
+
+			for _, line := range strings.Split(output, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 3 {
+					continue
+				}
+
+				if fields[0] == "-P" && fields[2] == "DROP" && shared.StringInSlice(fields[1], []string{"INPUT", "FORWARD"}) {
+					ruleset.Conflicts = append(ruleset.Conflicts, fmt.Sprintf("Default policy of %s %s chain %q is DROP", cmd, table, fields[1]))
+					continue
+				}
+
+				rule := fmt.Sprintf("%s -t %s %s", cmd, table, line)
+				if strings.Contains(line, networkComment) || (fields[0] == "-A" && shared.StringInSlice(fields[1], networkChains)) {
+					ruleset.Network = append(ruleset.Network, rule)
+					continue
+				}
+
+				for _, comment := range instanceComments {
+					if strings.Contains(line, comment) {
+						ruleset.Instances = append(ruleset.Instances, rule)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return ruleset, nil
+}
+
 //instanceDeviceIPTablesComment returns the iptables comment that is added to each instance device related rule.
 func (d Xtables) instanceDeviceIPTablesComment(projectName string, instanceName string, deviceName string) string {
 	return fmt.Sprintf("LXD container %s (%s)", project.Instance(projectName, instanceName), deviceName)
@@ -1293,6 +1370,16 @@ func (d Xtables) InstanceClearRPFilter(projectName string, instanceName string,
 	return nil
 }
 
+// InstanceSetupDSCP isn't supported with xtables as ebtables can't set the DSCP of bridged traffic.
+func (d Xtables) InstanceSetupDSCP(projectName string, instanceName string, deviceName string, hostName string, dscp string) error {
+	return fmt.Errorf("DSCP marking requires the nftables firewall driver")
+}
+
+// InstanceClearDSCP is a no-op with xtables as no DSCP rules are ever added.
+func (d Xtables) InstanceClearDSCP(projectName string, instanceName string, deviceName string) error {
+	return nil
+}
+
 // iptablesChainExists checks whether a chain exists in a table, and whether it has any rules.
 func (d Xtables) iptablesChainExists(ipVersion uint, table string, chain string) (bool, bool, error) {
 	var cmd string
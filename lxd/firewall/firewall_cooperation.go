@@ -0,0 +1,59 @@
+package firewall
+
+import (
+	"github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// manager represents a firewall management tool of the host (firewalld or ufw) LXD networks are registered with.
+type manager interface {
+	String() string
+	Available() bool
+	NetworkRegister(networkName string, opts drivers.Opts) error
+	NetworkUnregister(networkName string) error
+}
+
+// cooperation is a firewall which, on top of the rules of the native driver, registers the networks with the
+// firewall manager of the host. This lets the DNS, DHCP and NAT traffic of the networks through the rules of the
+// manager and keeps it working when the manager reloads its configuration.
+type cooperation struct {
+	Firewall
+
+	manager manager
+}
+
+// NetworkSetup applies the network rules with the native driver and registers the network with the manager.
+func (c cooperation) NetworkSetup(networkName string, opts drivers.Opts) error {
+	err := c.Firewall.NetworkSetup(networkName, opts)
+	if err != nil {
+		return err
+	}
+
+	return c.manager.NetworkRegister(networkName, opts)
+}
+
+// NetworkClear removes the network rules of the native driver, and unregisters the network from the manager when
+// the network is being deleted.
+func (c cooperation) NetworkClear(networkName string, delete bool, ipVersions []uint) error {
+	err := c.Firewall.NetworkClear(networkName, delete, ipVersions)
+	if err != nil {
+		return err
+	}
+
+	if !delete {
+		return nil
+	}
+
+	return c.manager.NetworkUnregister(networkName)
+}
+
+// newCooperation returns a firewall cooperating with the given manager, or the native driver if the manager isn't
+// running.
+func newCooperation(native Firewall, manager manager) Firewall {
+	if !manager.Available() {
+		logger.Warnf(`Firewall cooperation with %q requested but it isn't running, using "%s" only`, manager, native)
+		return native
+	}
+
+	return cooperation{Firewall: native, manager: manager}
+}
@@ -15,6 +15,7 @@ type Firewall interface {
 	NetworkSetup(networkName string, opts drivers.Opts) error
 	NetworkClear(networkName string, delete bool, ipVersions []uint) error
 	NetworkApplyACLRules(networkName string, rules []drivers.ACLRule) error
+	NetworkRuleset(networkName string, instances []drivers.InstanceRef) (*drivers.Ruleset, error)
 
 	InstanceSetupBridgeFilter(projectName string, instanceName string, deviceName string, parentName string, hostName string, hwAddr string, IPv4 net.IP, IPv6 net.IP, parentManaged bool) error
 	InstanceClearBridgeFilter(projectName string, instanceName string, deviceName string, parentName string, hostName string, hwAddr string, IPv4 net.IP, IPv6 net.IP) error
@@ -24,4 +25,7 @@ type Firewall interface {
 
 	InstanceSetupRPFilter(projectName string, instanceName string, deviceName string, hostName string) error
 	InstanceClearRPFilter(projectName string, instanceName string, deviceName string) error
+
+	InstanceSetupDSCP(projectName string, instanceName string, deviceName string, hostName string, dscp string) error
+	InstanceClearDSCP(projectName string, instanceName string, deviceName string) error
 }
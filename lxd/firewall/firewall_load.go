@@ -7,7 +7,22 @@ import (
 
 // New returns an appropriate firewall implementation.
 // Uses xtables if nftables isn't compatible or isn't in use already, otherwise uses nftables.
-func New() Firewall {
+// When mode is "firewalld" or "ufw", networks are also registered with that firewall manager.
+func New(mode string) Firewall {
+	native := newNative()
+
+	switch mode {
+	case "firewalld":
+		return newCooperation(native, drivers.Firewalld{})
+	case "ufw":
+		return newCooperation(native, drivers.Ufw{})
+	}
+
+	return native
+}
+
+// newNative returns the nftables or xtables driver.
+func newNative() Firewall {
 	nftables := drivers.Nftables{}
 	xtables := drivers.Xtables{}
 
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	pcidev "github.com/lxc/lxd/lxd/device/pci"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/resources"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// gpuMIGSlices is the number of MIG slices a GPU is accounted as being split into (seven compute slices on the
+// largest MIG capable GPUs).
+const gpuMIGSlices = 7
+
+// gpuAllocationEpsilon absorbs rounding errors when adding up fractions of GPUs.
+const gpuAllocationEpsilon = 1e-6
+
+// gpuRequest is a share of a GPU requested by an instance device.
+type gpuRequest struct {
+	device  string
+	gpuType string
+	config  map[string]string
+}
+
+// gpuRequests returns the shares of GPUs requested by the devices of an instance. Physical GPUs are only accounted
+// for VMs as containers share them with the host.
+func gpuRequests(instType instancetype.Type, devices deviceConfig.Devices) []gpuRequest {
+	requests := []gpuRequest{}
+	for _, name := range devices.Sorted() {
+		dev := name.Config
+		if dev["type"] != "gpu" {
+			continue
+		}
+
+		gpuType := dev["gputype"]
+		if gpuType == "" {
+			gpuType = "physical"
+		}
+
+		if gpuType == "physical" && instType != instancetype.VM {
+			continue
+		}
+
+		requests = append(requests, gpuRequest{device: name.Name, gpuType: gpuType, config: dev})
+	}
+
+	return requests
+}
+
+// gpuCardMatches returns whether the GPU card matches the pci, id, vendorid and productid settings of a device.
+func gpuCardMatches(card api.ResourcesGPUCard, config map[string]string) bool {
+	if config["pci"] != "" && card.PCIAddress != pcidev.NormaliseAddress(config["pci"]) {
+		return false
+	}
+
+	if config["id"] != "" && (card.DRM == nil || strconv.FormatUint(card.DRM.ID, 10) != config["id"]) {
+		return false
+	}
+
+	if config["vendorid"] != "" && card.VendorID != config["vendorid"] {
+		return false
+	}
+
+	if config["productid"] != "" && card.ProductID != config["productid"] {
+		return false
+	}
+
+	return true
+}
+
+// gpuShare returns the share of the card used by the request, or 0 if the card can't serve it.
+func gpuShare(card api.ResourcesGPUCard, req gpuRequest) float64 {
+	if !gpuCardMatches(card, req.config) {
+		return 0
+	}
+
+	switch req.gpuType {
+	case "physical":
+		return 1
+	case "mdev":
+		profile, ok := card.Mdev[req.config["mdev"]]
+		if !ok {
+			return 0
+		}
+
+		slots := profile.Available + uint64(len(profile.Devices))
+		if slots == 0 {
+			return 0
+		}
+
+		return 1 / float64(slots)
+	case "mig":
+		if card.Nvidia == nil {
+			return 0
+		}
+
+		return 1.0 / gpuMIGSlices
+	case "sriov":
+		if card.SRIOV == nil || card.SRIOV.MaximumVFs == 0 {
+			return 0
+		}
+
+		return 1 / float64(card.SRIOV.MaximumVFs)
+	}
+
+	return 0
+}
+
+// gpuAllocate assigns each request to the matching card with the most capacity left, adding the shares to
+// allocated (keyed by PCI address). It fails if a request can't be served.
+func gpuAllocate(cards []api.ResourcesGPUCard, allocated map[string]float64, requests []gpuRequest) error {
+	for _, req := range requests {
+		best := ""
+		bestShare := 0.0
+		bestLeft := 0.0
+		for _, card := range cards {
+			share := gpuShare(card, req)
+			if share == 0 {
+				continue
+			}
+
+			left := 1 - allocated[card.PCIAddress]
+			if left+gpuAllocationEpsilon < share {
+				continue
+			}
+
+			if best == "" || left > bestLeft {
+				best = card.PCIAddress
+				bestShare = share
+				bestLeft = left
+			}
+		}
+
+		if best == "" {
+			return fmt.Errorf("No GPU has enough capacity left for device %q (%s)", req.device, req.gpuType)
+		}
+
+		allocated[best] += bestShare
+	}
+
+	return nil
+}
+
+// gpuLocalAllocations returns the share of each GPU of this member (keyed by PCI address) allocated to its
+// instances, whether they're running or not. Instances whose devices don't fit anymore aren't accounted.
+func gpuLocalAllocations(s *state.State, cards []api.ResourcesGPUCard) (map[string]float64, error) {
+	allocated := map[string]float64{}
+
+	instances, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allocate in a stable order so that the result doesn't change between calls.
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Project() != instances[j].Project() {
+			return instances[i].Project() < instances[j].Project()
+		}
+
+		return instances[i].Name() < instances[j].Name()
+	})
+
+	for _, inst := range instances {
+		requests := gpuRequests(inst.Type(), inst.ExpandedDevices())
+		if len(requests) == 0 {
+			continue
+		}
+
+		instAllocated := map[string]float64{}
+		for k, v := range allocated {
+			instAllocated[k] = v
+		}
+
+		err := gpuAllocate(cards, instAllocated, requests)
+		if err != nil {
+			continue
+		}
+
+		allocated = instAllocated
+	}
+
+	return allocated, nil
+}
+
+// gpuFillAllocations fills the allocated and available shares of the GPUs in the resources of this member.
+func gpuFillAllocations(s *state.State, res *api.Resources) error {
+	allocated, err := gpuLocalAllocations(s, res.GPU.Cards)
+	if err != nil {
+		return err
+	}
+
+	res.GPU.Available = 0
+	for i, card := range res.GPU.Cards {
+		used := allocated[card.PCIAddress]
+		if used > 1 {
+			used = 1
+		}
+
+		res.GPU.Cards[i].Allocated = used
+		res.GPU.Cards[i].Available = 1 - used
+		res.GPU.Available += 1 - used
+	}
+
+	return nil
+}
+
+// gpuFits returns whether the GPU requests fit in the capacity left on a member as reported by its resources.
+func gpuFits(res *api.Resources, requests []gpuRequest) bool {
+	allocated := map[string]float64{}
+	for _, card := range res.GPU.Cards {
+		allocated[card.PCIAddress] = card.Allocated
+	}
+
+	return gpuAllocate(res.GPU.Cards, allocated, requests) == nil
+}
+
+// gpuExcludedMembers returns the cluster members whose GPUs don't have enough capacity left for the GPU devices
+// of the instance to create. It fails if no member has enough capacity.
+func gpuExcludedMembers(d *Daemon, r *http.Request, projectName string, req api.InstancesPost) ([]string, error) {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !clustered {
+		return nil, nil
+	}
+
+	instType, err := instancetype.New(string(req.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	profileNames := req.Profiles
+	if profileNames == nil {
+		profileNames = []string{"default"}
+	}
+
+	profiles, err := d.cluster.GetProfiles(projectName, profileNames)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := gpuRequests(instType, db.ExpandInstanceDevices(deviceConfig.NewDevices(req.Devices), profiles))
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	var members []db.NodeInfo
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		threshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.State == db.ClusterMemberStateEvacuated || node.IsOffline(threshold) {
+				continue
+			}
+
+			members = append(members, node)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := []string{}
+	for _, member := range members {
+		res, err := gpuMemberResources(d, r, member.Name)
+		if err != nil {
+			logger.Warn("Failed to get GPU resources of cluster member", log.Ctx{"member": member.Name, "err": err})
+			excluded = append(excluded, member.Name)
+			continue
+		}
+
+		if !gpuFits(res, requests) {
+			excluded = append(excluded, member.Name)
+		}
+	}
+
+	if len(excluded) == len(members) {
+		return nil, fmt.Errorf("No cluster member has enough GPU capacity left for the instance")
+	}
+
+	return excluded, nil
+}
+
+// gpuMemberResources returns the resources of a cluster member, including the allocation of its GPUs.
+func gpuMemberResources(d *Daemon, r *http.Request, name string) (*api.Resources, error) {
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if address == "" {
+		res, err := resources.GetResources()
+		if err != nil {
+			return nil, err
+		}
+
+		err = gpuFillAllocations(d.State(), res)
+		if err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetServerResources()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestGPUAllocate(t *testing.T) {
+	cards := []api.ResourcesGPUCard{
+		{
+			PCIAddress: "0000:01:00.0",
+			VendorID:   "10de",
+			Nvidia:     &api.ResourcesGPUCardNvidia{},
+			SRIOV:      &api.ResourcesGPUCardSRIOV{MaximumVFs: 4},
+		},
+		{
+			PCIAddress: "0000:02:00.0",
+			VendorID:   "8086",
+			Mdev: map[string]api.ResourcesGPUCardMdev{
+				"i915-GVTg_V5_4": {Available: 1, Devices: []string{"a"}},
+			},
+		},
+	}
+
+	devices := deviceConfig.Devices{
+		"gpu0": {"type": "gpu", "gputype": "mdev", "mdev": "i915-GVTg_V5_4"},
+		"gpu1": {"type": "gpu", "gputype": "sriov", "vendorid": "10de"},
+		"gpu2": {"type": "gpu"},
+		"eth0": {"type": "nic", "nictype": "bridged", "parent": "lxdbr0"},
+	}
+
+	// Physical GPUs are only accounted for VMs.
+	require.Len(t, gpuRequests(instancetype.Container, devices), 2)
+	requests := gpuRequests(instancetype.VM, devices)
+	require.Len(t, requests, 3)
+
+	// The whole NVIDIA card can't be passed once one of its VFs is used.
+	allocated := map[string]float64{}
+	err := gpuAllocate(cards, allocated, requests)
+	assert.Error(t, err)
+
+	allocated = map[string]float64{}
+	err = gpuAllocate(cards, allocated, requests[:2])
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, allocated["0000:02:00.0"], gpuAllocationEpsilon)
+	assert.InDelta(t, 0.25, allocated["0000:01:00.0"], gpuAllocationEpsilon)
+
+	// Fill up the NVIDIA card with MIG slices.
+	mig := []gpuRequest{}
+	for i := 0; i < 5; i++ {
+		mig = append(mig, gpuRequest{device: "mig", gpuType: "mig", config: map[string]string{"pci": "01:00.0"}})
+	}
+
+	err = gpuAllocate(cards, allocated, mig)
+	require.NoError(t, err)
+
+	err = gpuAllocate(cards, allocated, mig[:1])
+	assert.Error(t, err)
+
+	// Reported allocations are taken into account.
+	cards[0].Allocated = 1
+	cards[1].Allocated = 0.5
+	res := &api.Resources{GPU: api.ResourcesGPU{Cards: cards}}
+	assert.True(t, gpuFits(res, requests[:1]))
+	assert.False(t, gpuFits(res, requests[:2]))
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/idmap"
+)
+
+var api10IdmapCmd = APIEndpoint{
+	Path: "idmap",
+
+	Get: APIEndpointAction{Handler: api10IdmapGet, AccessHandler: allowAuthenticated},
+}
+
+// swagger:operation GET /1.0/idmap server idmap_get
+//
+// Get the uid/gid allocation
+//
+// Gets the uid/gid allocation of the LXD server and the ranges assigned to projects and instances.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+// responses:
+//   "200":
+//     description: Uid/gid allocation
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/Idmap"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func api10IdmapGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	var config *node.Config
+	err := d.db.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = node.ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := api.Idmap{
+		Managed:   config.InstancesIdmapManage(),
+		Host:      []api.IdmapEntry{},
+		Projects:  map[string]api.IdmapRange{},
+		Instances: []api.IdmapInstance{},
+	}
+
+	// Without an allocation, all instances are privileged.
+	if d.os.IdmapSet == nil || len(d.os.IdmapSet.Idmap) == 0 {
+		return response.SyncResponse(true, result)
+	}
+
+	result.Host = idmapToAPI(d.os.IdmapSet)
+
+	var projectIDs map[int64]string
+	var projectConfigs map[string]map[string]string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectIDs, err = tx.GetProjectIDsToNames()
+		if err != nil {
+			return err
+		}
+
+		projectConfigs, err = tx.ProjectConfigRef(db.ProjectFilter{})
+		return err
+	})
+	if err != nil {
+		return response.SmartError(errors.Wrap(err, "Failed to load projects"))
+	}
+
+	hostMap := d.os.IdmapSet.Idmap[0]
+	ranges, _, _, err := project.IsolatedIdmapRanges(projectIDs, projectConfigs, hostMap.Hostid, hostMap.Maprange)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for name, r := range ranges {
+		result.Projects[name] = api.IdmapRange{Base: r.Base, Size: r.Size}
+	}
+
+	instances, err := instance.LoadNodeAll(d.State(), instancetype.Container)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, inst := range instances {
+		if inst.IsPrivileged() {
+			continue
+		}
+
+		c, ok := inst.(instance.Container)
+		if !ok {
+			continue
+		}
+
+		// Running containers report their current map, others the one they'll use on next start.
+		var set *idmap.IdmapSet
+		if inst.IsRunning() {
+			set, err = c.CurrentIdmap()
+		} else {
+			set, err = c.NextIdmap()
+		}
+
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed to load idmap of instance %q in project %q", inst.Name(), inst.Project()))
+		}
+
+		_, inProjectRange := ranges[inst.Project()]
+		result.Instances = append(result.Instances, api.IdmapInstance{
+			Name:     inst.Name(),
+			Project:  inst.Project(),
+			Isolated: inProjectRange || shared.IsTrue(inst.ExpandedConfig()["security.idmap.isolated"]),
+			Map:      idmapToAPI(set),
+		})
+	}
+
+	sort.Slice(result.Instances, func(i, j int) bool {
+		if result.Instances[i].Project != result.Instances[j].Project {
+			return result.Instances[i].Project < result.Instances[j].Project
+		}
+
+		return result.Instances[i].Name < result.Instances[j].Name
+	})
+
+	return response.SyncResponse(true, result)
+}
+
+// idmapToAPI converts an idmap set to its API representation.
+func idmapToAPI(set *idmap.IdmapSet) []api.IdmapEntry {
+	entries := []api.IdmapEntry{}
+	if set == nil {
+		return entries
+	}
+
+	for _, entry := range set.Idmap {
+		entries = append(entries, api.IdmapEntry{
+			UID:    entry.Isuid,
+			GID:    entry.Isgid,
+			HostID: entry.Hostid,
+			NSID:   entry.Nsid,
+			Range:  entry.Maprange,
+		})
+	}
+
+	return entries
+}
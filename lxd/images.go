@@ -590,6 +590,29 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			return nil, err
 		}
 
+		// Rebuild the full image from the delta and its locally available base image.
+		if imageMeta.BaseImage != "" {
+			if info.Type != instancetype.Container.String() {
+				return nil, fmt.Errorf("Only container images can be imported as a delta")
+			}
+
+			_, baseInfo, err := d.cluster.GetImage(imageMeta.BaseImage, db.ImageFilter{Project: &project})
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed loading base image %q", imageMeta.BaseImage)
+			}
+
+			if !shared.PathExists(shared.VarPath("images", baseInfo.Fingerprint)) {
+				return nil, fmt.Errorf("Base image %q isn't available on this member", baseInfo.Fingerprint)
+			}
+
+			info.Fingerprint, info.Size, err = imageDeltaApply(imageTarf.Name(), rootfsTarf.Name(), baseInfo.Fingerprint, builddir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed applying image delta")
+			}
+
+			imageMeta.BaseImage = ""
+		}
+
 		imgfname := shared.VarPath("images", info.Fingerprint)
 		err = shared.FileMove(imageTarf.Name(), imgfname)
 		if err != nil {
@@ -636,6 +659,10 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			logger.Error("Failed to get image metadata", log.Ctx{"err": err})
 			return nil, err
 		}
+
+		if imageMeta.BaseImage != "" {
+			return nil, fmt.Errorf("Image deltas must use the split image format")
+		}
 		info.Type = imageType
 
 		imgfname := shared.VarPath("images", info.Fingerprint)
@@ -901,6 +928,14 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 		imageUpload = true
 	}
 
+	if !imageUpload {
+		err = validateTags(req.Tags)
+		if err != nil {
+			cleanup(builddir, post)
+			return response.BadRequest(err)
+		}
+	}
+
 	if !imageUpload && req.Source.Mode == "push" {
 		cleanup(builddir, post)
 
@@ -1013,6 +1048,18 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
+		if req.Tags != nil {
+			id, _, err := d.cluster.GetImage(info.Fingerprint, db.ImageFilter{Project: &projectName})
+			if err != nil {
+				return errors.Wrapf(err, "Fetch image %q", info.Fingerprint)
+			}
+
+			err = imageUpdateTags(d.State(), id, req.Tags)
+			if err != nil {
+				return errors.Wrapf(err, "Set image tags")
+			}
+		}
+
 		// Sync the images between each node in the cluster on demand
 		err = imageSyncBetweenNodes(d, r, projectName, info.Fingerprint)
 		if err != nil {
@@ -1149,10 +1196,53 @@ func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
 	return &result, imageType, nil
 }
 
-func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses []filter.Clause) (interface{}, error) {
-	results, err := d.cluster.GetImagesFingerprints(project, public)
+// imagesDBFilter returns the database filter selecting the images which may match the clauses.
+func imagesDBFilter(public bool, clauses []filter.Clause) db.ImageFilter {
+	dbFilter := db.ImageFilter{}
+	if public {
+		dbFilter.Public = &public
+	}
+
+	fingerprint, ok := filter.ExactValue(clauses, "fingerprint")
+	if ok {
+		dbFilter.Fingerprint = &fingerprint
+	}
+
+	boolValue := func(field string) *bool {
+		value, ok := filter.ExactValue(clauses, field)
+		if !ok {
+			return nil
+		}
+
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil
+		}
+
+		return &b
+	}
+
+	if dbFilter.Public == nil {
+		dbFilter.Public = boolValue("public")
+	}
+
+	dbFilter.Cached = boolValue("cached")
+	dbFilter.AutoUpdate = boolValue("auto_update")
+
+	return dbFilter
+}
+
+// doImagesGet returns the images part of the given page, along with the number of images in the page (before
+// filtering) and the fingerprint of the last one.
+func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses []filter.Clause, page db.Pagination) (interface{}, int, string, error) {
+	results, err := d.cluster.GetImagesFingerprintsPage(project, imagesDBFilter(public, clauses), page)
 	if err != nil {
-		return []string{}, err
+		return []string{}, 0, "", err
+	}
+
+	last := ""
+	if len(results) > 0 {
+		last = results[len(results)-1]
 	}
 
 	resultString := []string{}
@@ -1183,10 +1273,10 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 				resultString = append(resultString, url)
 			}
 		}
-		return resultString, nil
+		return resultString, len(results), last, nil
 	}
 
-	return resultMap, nil
+	return resultMap, len(results), last, nil
 }
 
 // swagger:operation GET /1.0/images?public images images_get_untrusted
@@ -1205,6 +1295,15 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
@@ -1259,6 +1358,15 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
@@ -1308,6 +1416,15 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
@@ -1362,6 +1479,15 @@ func doImagesGet(d *Daemon, recursion bool, project string, public bool, clauses
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
@@ -1408,11 +1534,17 @@ func imagesGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	result, err := doImagesGet(d, util.IsRecursionRequest(r), projectName, public, clauses)
+	page, err := paginationParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	result, count, last, err := doImagesGet(d, util.IsRecursionRequest(r), projectName, public, clauses, page)
 	if err != nil {
 		return response.SmartError(err)
 	}
-	return response.SyncResponse(true, result)
+
+	return paginatedResponse(page, count, last, result)
 }
 
 func autoUpdateImagesTask(d *Daemon) (task.Func, task.Schedule) {
@@ -1782,7 +1914,7 @@ func autoUpdateImage(ctx context.Context, d *Daemon, op *operations.Operation, i
 				return nil, errors.Wrap(err, "Unable to fetch project configuration")
 			}
 		} else {
-			interval, err = cluster.ConfigGetInt64(d.cluster, "images.auto_update_interval")
+			interval, err = cluster.MemberConfigGetInt64(d.cluster, "images.auto_update_interval")
 			if err != nil {
 				return nil, errors.Wrap(err, "Unable to fetch cluster configuration")
 			}
@@ -1851,6 +1983,12 @@ func autoUpdateImage(ctx context.Context, d *Daemon, op *operations.Operation, i
 	hash := fingerprint
 	var newInfo *api.Image
 
+	// Scheduled updates make way for the downloads requested through the API.
+	priority := operations.QueuePriorityNormal
+	if !manual {
+		priority = operations.QueuePriorityLow
+	}
+
 	for _, poolName := range poolNames {
 		select {
 		case <-ctx.Done():
@@ -1868,6 +2006,7 @@ func autoUpdateImage(ctx context.Context, d *Daemon, op *operations.Operation, i
 			StoragePool: poolName,
 			ProjectName: projectName,
 			Budget:      -1,
+			Priority:    priority,
 		})
 		if err != nil {
 			logger.Error("Failed to update the image", log.Ctx{"err": err, "fingerprint": fingerprint})
@@ -2058,6 +2197,12 @@ func pruneExpiredImages(ctx context.Context, d *Daemon, op *operations.Operation
 		}
 	}
 
+	// Drop the chunks no longer shared with any new image volume.
+	err = storagePools.ImageDedupPrune(d.State())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2536,6 +2681,11 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Get ExpiresAt
 	if !req.ExpiresAt.IsZero() {
 		info.ExpiresAt = req.ExpiresAt
@@ -2561,6 +2711,11 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = imageUpdateTags(d.State(), id, req.Tags)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	requestor := request.CreateRequestor(r)
 	d.State().Events.SendLifecycle(projectName, lifecycle.ImageUpdated.Event(info.Fingerprint, projectName, requestor, nil))
 
@@ -2635,6 +2790,11 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Get AutoUpdate
 	autoUpdate, err := reqRaw.GetBool("auto_update")
 	if err == nil {
@@ -2665,6 +2825,11 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = imageUpdateTags(d.State(), id, req.Tags)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	requestor := request.CreateRequestor(r)
 	d.State().Events.SendLifecycle(projectName, lifecycle.ImageUpdated.Event(info.Fingerprint, projectName, requestor, nil))
 
@@ -3264,6 +3429,8 @@ func imageAliasPost(d *Daemon, r *http.Request) response.Response {
 //
 // Download the raw image file(s) from the server.
 // If the image is in split format, a multipart http transfer occurs.
+// If a base image is provided, the image is returned in split format with its root filesystem only containing the
+// changes from the base image.
 //
 // ---
 // produces:
@@ -3275,6 +3442,11 @@ func imageAliasPost(d *Daemon, r *http.Request) response.Response {
 //     description: Project name
 //     type: string
 //     example: default
+//   - in: query
+//     name: base
+//     description: Fingerprint of the base image to export a delta of
+//     type: string
+//     example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
 // responses:
 //   "200":
 //     description: Raw image data
@@ -3332,6 +3504,12 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		return response.ForwardedResponse(client, r)
 	}
 
+	// Export the image as a delta of a base image if requested.
+	baseFingerprint := r.FormValue("base")
+	if baseFingerprint != "" {
+		return imageExportDelta(d, r, projectName, imgInfo, baseFingerprint)
+	}
+
 	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
 	rootfsPath := imagePath + ".rootfs"
 
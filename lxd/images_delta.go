@@ -0,0 +1,711 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/request"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// imageDeltaWhiteoutPrefix is the file name prefix marking a path of the base image as deleted in a delta.
+const imageDeltaWhiteoutPrefix = ".wh."
+
+// imageDeltaEntry holds the attributes of a root filesystem entry used to detect changes from the base image.
+type imageDeltaEntry struct {
+	typeflag byte
+	mode     int64
+	uid      int
+	gid      int
+	size     int64
+	modTime  int64
+	linkname string
+	devmajor int64
+	devminor int64
+	xattrs   string
+}
+
+// imageDeltaEntryFromHeader returns the delta entry for a tar header.
+func imageDeltaEntryFromHeader(hdr *tar.Header, linkname string) imageDeltaEntry {
+	xattrs := []string{}
+	for k, v := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "SCHILY.xattr.") {
+			xattrs = append(xattrs, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	sort.Strings(xattrs)
+
+	typeflag := hdr.Typeflag
+	if typeflag == tar.TypeRegA {
+		typeflag = tar.TypeReg
+	}
+
+	return imageDeltaEntry{
+		typeflag: typeflag,
+		mode:     hdr.Mode,
+		uid:      hdr.Uid,
+		gid:      hdr.Gid,
+		size:     hdr.Size,
+		modTime:  hdr.ModTime.Unix(),
+		linkname: linkname,
+		devmajor: hdr.Devmajor,
+		devminor: hdr.Devminor,
+		xattrs:   strings.Join(xattrs, "\x00"),
+	}
+}
+
+// imageDeltaRootfsPath returns the path of an entry relative to the root filesystem, given the prefix of the
+// root filesystem entries in the tarball. Returns false if the entry isn't part of the root filesystem.
+func imageDeltaRootfsPath(name string, prefix string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	if prefix == "" {
+		if name == "" {
+			return ".", true
+		}
+
+		return name, true
+	}
+
+	if name == prefix {
+		return ".", true
+	}
+
+	if !strings.HasPrefix(name, prefix+"/") {
+		return "", false
+	}
+
+	return strings.TrimPrefix(name, prefix+"/"), true
+}
+
+// imageDeltaIsDeleted returns whether the path, or one of its parents, is in the deleted paths.
+func imageDeltaIsDeleted(deleted map[string]bool, name string) bool {
+	for name != "." && name != "/" && name != "" {
+		if deleted[name] {
+			return true
+		}
+
+		name = path.Dir(name)
+	}
+
+	return false
+}
+
+// imageDeltaTarReader returns a tar reader for the (optionally compressed) tarball or squashfs file.
+func imageDeltaTarReader(fname string) (*tar.Reader, func(), error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, algo, unpacker, err := shared.DetectCompressionFile(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	if unpacker == nil || algo == ".qcow2" {
+		f.Close()
+		return nil, nil, fmt.Errorf("Unsupported image compression")
+	}
+
+	if algo == ".squashfs" {
+		// sqfs2tar can only read from a file.
+		unpacker = append(unpacker, fname)
+	}
+
+	tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), f, unpacker)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return tr, func() {
+		cancelFunc()
+		f.Close()
+	}, nil
+}
+
+// imageDeltaRootfsReader returns a tar reader for the root filesystem of a local image along with the prefix of
+// the root filesystem entries in it (empty for split images).
+func imageDeltaRootfsReader(fingerprint string) (*tar.Reader, string, func(), error) {
+	fname := shared.VarPath("images", fingerprint+".rootfs")
+	prefix := ""
+	if !shared.PathExists(fname) {
+		fname = shared.VarPath("images", fingerprint)
+		prefix = "rootfs"
+	}
+
+	tr, cleanup, err := imageDeltaTarReader(fname)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return tr, prefix, cleanup, nil
+}
+
+// imageDeltaIndex returns the entries of the root filesystem of a local image.
+func imageDeltaIndex(fingerprint string) (map[string]imageDeltaEntry, error) {
+	tr, prefix, cleanup, err := imageDeltaRootfsReader(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	entries := map[string]imageDeltaEntry{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := imageDeltaRootfsPath(hdr.Name, prefix)
+		if !ok {
+			continue
+		}
+
+		linkname := hdr.Linkname
+		if hdr.Typeflag == tar.TypeLink {
+			linkname, _ = imageDeltaRootfsPath(hdr.Linkname, prefix)
+		}
+
+		entries[name] = imageDeltaEntryFromHeader(hdr, linkname)
+	}
+
+	return entries, nil
+}
+
+// imageDeltaWriteEntry writes the tar entry under the new name, copying its content from the reader.
+func imageDeltaWriteEntry(tw *tar.Writer, hdr *tar.Header, name string, linkname string, r io.Reader) error {
+	newHdr := *hdr
+	newHdr.Name = name
+	if hdr.Typeflag == tar.TypeLink {
+		newHdr.Linkname = linkname
+	}
+
+	err := tw.WriteHeader(&newHdr)
+	if err != nil {
+		return err
+	}
+
+	if r != nil && hdr.Size > 0 {
+		_, err = io.Copy(tw, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// imageDeltaWriteMetadata writes the metadata entries of an image tarball, setting the base image in its
+// metadata.yaml. Entries that are part of the root filesystem (when using the given prefix) are passed to rootfs.
+func imageDeltaWriteMetadata(tw *tar.Writer, tr *tar.Reader, prefix string, baseImage string, rootfs func(hdr *tar.Header, name string) error) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if prefix != "" {
+			name, ok := imageDeltaRootfsPath(hdr.Name, prefix)
+			if ok {
+				err = rootfs(hdr, name)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		name, _ := imageDeltaRootfsPath(hdr.Name, "")
+		if name != "metadata.yaml" {
+			err = imageDeltaWriteEntry(tw, hdr, name, hdr.Linkname, tr)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		metadata := api.ImageMetadata{}
+		err = yaml.Unmarshal(content, &metadata)
+		if err != nil {
+			return errors.Wrap(err, "Failed parsing metadata.yaml")
+		}
+
+		metadata.BaseImage = baseImage
+
+		content, err = yaml.Marshal(&metadata)
+		if err != nil {
+			return err
+		}
+
+		newHdr := *hdr
+		newHdr.Size = int64(len(content))
+		err = imageDeltaWriteEntry(tw, &newHdr, name, "", bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// imageDeltaCreateFile creates a gzip compressed tarball in dir, returning the tar writer and a function
+// flushing and closing the file.
+func imageDeltaCreateFile(dir string) (*os.File, *tar.Writer, func() error, error) {
+	f, err := ioutil.TempFile(dir, "lxd_delta_")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	closeFunc := func() error {
+		err := tw.Close()
+		if err != nil {
+			return err
+		}
+
+		err = gw.Close()
+		if err != nil {
+			return err
+		}
+
+		return f.Close()
+	}
+
+	return f, tw, closeFunc, nil
+}
+
+// imageDeltaHash returns the fingerprint and size of the image made of the files.
+func imageDeltaHash(fnames ...string) (string, int64, error) {
+	hash := sha256.New()
+	var size int64
+
+	for _, fname := range fnames {
+		f, err := os.Open(fname)
+		if err != nil {
+			return "", -1, err
+		}
+
+		n, err := io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", -1, err
+		}
+
+		size += n
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), size, nil
+}
+
+// imageDeltaExport writes the container image as a split image whose root filesystem only contains the changes
+// from the base image. Paths of the base image which were removed are marked with whiteout files.
+// Returns the paths of the metadata and root filesystem files along with the fingerprint of the delta.
+func imageDeltaExport(fingerprint string, baseFingerprint string, dir string) (string, string, string, error) {
+	baseEntries, err := imageDeltaIndex(baseFingerprint)
+	if err != nil {
+		return "", "", "", errors.Wrapf(err, "Failed reading base image %q", baseFingerprint)
+	}
+
+	metaFile, metaTw, metaClose, err := imageDeltaCreateFile(dir)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	rootfsFile, rootfsTw, rootfsClose, err := imageDeltaCreateFile(dir)
+	if err != nil {
+		metaClose()
+		os.Remove(metaFile.Name())
+		return "", "", "", err
+	}
+
+	err = func() error {
+		seen := map[string]bool{}
+		rootfs := func(hdr *tar.Header, name string, prefix string, tr *tar.Reader) error {
+			seen[name] = true
+
+			linkname := hdr.Linkname
+			if hdr.Typeflag == tar.TypeLink {
+				linkname, _ = imageDeltaRootfsPath(hdr.Linkname, prefix)
+			}
+
+			baseEntry, found := baseEntries[name]
+			if found && baseEntry == imageDeltaEntryFromHeader(hdr, linkname) {
+				return nil
+			}
+
+			return imageDeltaWriteEntry(rootfsTw, hdr, name, linkname, tr)
+		}
+
+		// Write the metadata (and the root filesystem of unified images).
+		tr, cleanup, err := imageDeltaTarReader(shared.VarPath("images", fingerprint))
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		split := shared.PathExists(shared.VarPath("images", fingerprint+".rootfs"))
+		prefix := "rootfs"
+		if split {
+			prefix = ""
+		}
+
+		err = imageDeltaWriteMetadata(metaTw, tr, prefix, baseFingerprint, func(hdr *tar.Header, name string) error {
+			return rootfs(hdr, name, prefix, tr)
+		})
+		if err != nil {
+			return err
+		}
+
+		// Write the root filesystem of split images.
+		if split {
+			tr, _, cleanup, err := imageDeltaRootfsReader(fingerprint)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					return err
+				}
+
+				name, _ := imageDeltaRootfsPath(hdr.Name, "")
+				err = rootfs(hdr, name, "", tr)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Mark the removed paths, skipping the content of removed directories.
+		removed := []string{}
+		for name := range baseEntries {
+			if !seen[name] {
+				removed = append(removed, name)
+			}
+		}
+
+		sort.Strings(removed)
+
+		deleted := map[string]bool{}
+		for _, name := range removed {
+			if imageDeltaIsDeleted(deleted, path.Dir(name)) {
+				continue
+			}
+
+			deleted[name] = true
+
+			err = rootfsTw.WriteHeader(&tar.Header{
+				Name:     path.Join(path.Dir(name), imageDeltaWhiteoutPrefix+path.Base(name)),
+				Typeflag: tar.TypeReg,
+				Mode:     0600,
+				ModTime:  time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	closeErr := metaClose()
+	if err == nil {
+		err = closeErr
+	}
+
+	closeErr = rootfsClose()
+	if err == nil {
+		err = closeErr
+	}
+
+	var hash string
+	if err == nil {
+		hash, _, err = imageDeltaHash(metaFile.Name(), rootfsFile.Name())
+	}
+
+	if err != nil {
+		os.Remove(metaFile.Name())
+		os.Remove(rootfsFile.Name())
+		return "", "", "", err
+	}
+
+	return metaFile.Name(), rootfsFile.Name(), hash, nil
+}
+
+// imageDeltaApply rebuilds the full split image from a delta and its base image, replacing the delta's metadata
+// and root filesystem files. Returns the fingerprint and size of the rebuilt image.
+func imageDeltaApply(metaPath string, rootfsPath string, baseFingerprint string, dir string) (string, int64, error) {
+	// Index the paths replaced (along with their type) or removed by the delta.
+	replaced := map[string]byte{}
+	deleted := map[string]bool{}
+
+	err := func() error {
+		tr, cleanup, err := imageDeltaTarReader(rootfsPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			name, _ := imageDeltaRootfsPath(hdr.Name, "")
+			if strings.HasPrefix(path.Base(name), imageDeltaWhiteoutPrefix) {
+				deleted[path.Join(path.Dir(name), strings.TrimPrefix(path.Base(name), imageDeltaWhiteoutPrefix))] = true
+				continue
+			}
+
+			replaced[name] = hdr.Typeflag
+		}
+
+		return nil
+	}()
+	if err != nil {
+		return "", -1, errors.Wrap(err, "Failed reading image delta")
+	}
+
+	metaFile, metaTw, metaClose, err := imageDeltaCreateFile(dir)
+	if err != nil {
+		return "", -1, err
+	}
+	defer os.Remove(metaFile.Name())
+
+	rootfsFile, rootfsTw, rootfsClose, err := imageDeltaCreateFile(dir)
+	if err != nil {
+		metaClose()
+		return "", -1, err
+	}
+	defer os.Remove(rootfsFile.Name())
+
+	err = func() error {
+		// Write the metadata, clearing the base image.
+		tr, cleanup, err := imageDeltaTarReader(metaPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		err = imageDeltaWriteMetadata(metaTw, tr, "", "", nil)
+		if err != nil {
+			return err
+		}
+
+		// Write the unchanged entries of the base image. Hard links to replaced files are deferred until
+		// after their target is written.
+		tr, prefix, cleanup, err := imageDeltaRootfsReader(baseFingerprint)
+		if err != nil {
+			return errors.Wrapf(err, "Failed reading base image %q", baseFingerprint)
+		}
+		defer cleanup()
+
+		deferredLinks := []tar.Header{}
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			name, ok := imageDeltaRootfsPath(hdr.Name, prefix)
+			if !ok || imageDeltaIsDeleted(deleted, name) {
+				continue
+			}
+
+			// Directories replaced by directories are kept so that their content can be written
+			// before the delta updates them.
+			typeflag, found := replaced[name]
+			if found && (typeflag != tar.TypeDir || hdr.Typeflag != tar.TypeDir) {
+				continue
+			}
+
+			linkname := hdr.Linkname
+			if hdr.Typeflag == tar.TypeLink {
+				linkname, _ = imageDeltaRootfsPath(hdr.Linkname, prefix)
+				if imageDeltaIsDeleted(deleted, linkname) {
+					continue
+				}
+
+				_, found := replaced[linkname]
+				if found {
+					link := *hdr
+					link.Name = name
+					link.Linkname = linkname
+					deferredLinks = append(deferredLinks, link)
+					continue
+				}
+			}
+
+			err = imageDeltaWriteEntry(rootfsTw, hdr, name, linkname, tr)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Write the entries of the delta.
+		tr, cleanup, err = imageDeltaTarReader(rootfsPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			name, _ := imageDeltaRootfsPath(hdr.Name, "")
+			if strings.HasPrefix(path.Base(name), imageDeltaWhiteoutPrefix) {
+				continue
+			}
+
+			err = imageDeltaWriteEntry(rootfsTw, hdr, name, hdr.Linkname, tr)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := range deferredLinks {
+			err = rootfsTw.WriteHeader(&deferredLinks[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	closeErr := metaClose()
+	if err == nil {
+		err = closeErr
+	}
+
+	closeErr = rootfsClose()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", -1, err
+	}
+
+	err = os.Rename(metaFile.Name(), metaPath)
+	if err != nil {
+		return "", -1, err
+	}
+
+	err = os.Rename(rootfsFile.Name(), rootfsPath)
+	if err != nil {
+		return "", -1, err
+	}
+
+	return imageDeltaHash(metaPath, rootfsPath)
+}
+
+// imageExportDelta returns the container image as a split image made of the changes from the base image.
+func imageExportDelta(d *Daemon, r *http.Request, projectName string, imgInfo *api.Image, baseFingerprint string) response.Response {
+	if imgInfo.Type != instancetype.Container.String() {
+		return response.BadRequest(fmt.Errorf("Only container images can be exported as a delta"))
+	}
+
+	_, baseInfo, err := d.cluster.GetImage(baseFingerprint, db.ImageFilter{Project: &projectName})
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed loading base image %q", baseFingerprint))
+	}
+
+	if baseInfo.Fingerprint == imgInfo.Fingerprint {
+		return response.BadRequest(fmt.Errorf("An image can't be exported as a delta of itself"))
+	}
+
+	if baseInfo.Type != imgInfo.Type {
+		return response.BadRequest(fmt.Errorf("The base image must be a container image"))
+	}
+
+	if !shared.PathExists(shared.VarPath("images", baseInfo.Fingerprint)) {
+		return response.BadRequest(fmt.Errorf("Base image %q isn't available on this member", baseInfo.Fingerprint))
+	}
+
+	metaPath, rootfsPath, hash, err := imageDeltaExport(imgInfo.Fingerprint, baseInfo.Fingerprint, shared.VarPath("images"))
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed exporting image delta"))
+	}
+
+	files := []response.FileResponseEntry{
+		{
+			Identifier: "metadata",
+			Path:       metaPath,
+			Filename:   fmt.Sprintf("meta-%s.tar.gz", hash),
+		},
+		{
+			Identifier: "rootfs",
+			Path:       rootfsPath,
+			Filename:   fmt.Sprintf("%s.tar.gz", hash),
+		},
+	}
+
+	requestor := request.CreateRequestor(r)
+	d.State().Events.SendLifecycle(projectName, lifecycle.ImageRetrieved.Event(imgInfo.Fingerprint, projectName, requestor, nil))
+
+	return response.FileResponse(r, files, map[string]string{"X-LXD-fingerprint": hash}, true)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type imageDeltaTestEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// imageDeltaTestWrite writes a tarball made of the entries.
+func imageDeltaTestWrite(t *testing.T, fname string, modTime time.Time, entries []imageDeltaTestEntry) {
+	buf := bytes.Buffer{}
+	tw := tar.NewWriter(&buf)
+
+	for _, entry := range entries {
+		hdr := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+			Linkname: entry.linkname,
+			ModTime:  modTime,
+		}
+
+		if entry.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, ioutil.WriteFile(fname, buf.Bytes(), 0600))
+}
+
+// imageDeltaTestRead returns the content of the regular files and the targets of the links in the tarball.
+func imageDeltaTestRead(t *testing.T, fname string) map[string]string {
+	tr, cleanup, err := imageDeltaTarReader(fname)
+	require.NoError(t, err)
+	defer cleanup()
+
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			content, err := ioutil.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = string(content)
+		case tar.TypeSymlink, tar.TypeLink:
+			files[hdr.Name] = "-> " + hdr.Linkname
+		case tar.TypeDir:
+			files[hdr.Name] = "dir"
+		}
+	}
+
+	return files
+}
+
+func Test_imageDeltaRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd_image_delta_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("LXD_DIR", dir)
+	defer os.Unsetenv("LXD_DIR")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "images"), 0700))
+
+	baseTime := time.Unix(1600000000, 0)
+	newTime := time.Unix(1700000000, 0)
+
+	// Base image in split format.
+	imageDeltaTestWrite(t, filepath.Join(dir, "images", "base"), baseTime, []imageDeltaTestEntry{
+		{name: "metadata.yaml", typeflag: tar.TypeReg, content: "architecture: x86_64\ncreation_date: 1\n"},
+	})
+
+	imageDeltaTestWrite(t, filepath.Join(dir, "images", "base.rootfs"), baseTime, []imageDeltaTestEntry{
+		{name: "./", typeflag: tar.TypeDir},
+		{name: "./etc/", typeflag: tar.TypeDir},
+		{name: "./etc/hostname", typeflag: tar.TypeReg, content: "base"},
+		{name: "./etc/os-release", typeflag: tar.TypeReg, content: "ubuntu"},
+		{name: "./usr/", typeflag: tar.TypeDir},
+		{name: "./usr/bin/", typeflag: tar.TypeDir},
+		{name: "./usr/bin/tool", typeflag: tar.TypeReg, content: "v1"},
+		{name: "./usr/bin/tool-link", typeflag: tar.TypeLink, linkname: "./usr/bin/tool"},
+		{name: "./var/", typeflag: tar.TypeDir},
+		{name: "./var/cache/", typeflag: tar.TypeDir},
+		{name: "./var/cache/a", typeflag: tar.TypeReg, content: "a"},
+		{name: "./var/cache/b", typeflag: tar.TypeReg, content: "b"},
+	})
+
+	// Derived image in unified format.
+	unified := []imageDeltaTestEntry{
+		{name: "metadata.yaml", typeflag: tar.TypeReg, content: "architecture: x86_64\ncreation_date: 2\n"},
+		{name: "rootfs/", typeflag: tar.TypeDir},
+		{name: "rootfs/etc/", typeflag: tar.TypeDir},
+		{name: "rootfs/etc/hostname", typeflag: tar.TypeReg, content: "derived"},
+		{name: "rootfs/etc/os-release", typeflag: tar.TypeReg, content: "ubuntu"},
+		{name: "rootfs/etc/new", typeflag: tar.TypeReg, content: "new"},
+		{name: "rootfs/usr/", typeflag: tar.TypeDir},
+		{name: "rootfs/usr/bin/", typeflag: tar.TypeDir},
+		{name: "rootfs/usr/bin/tool", typeflag: tar.TypeReg, content: "v2"},
+		{name: "rootfs/usr/bin/tool-link", typeflag: tar.TypeLink, linkname: "rootfs/usr/bin/tool"},
+		{name: "rootfs/var/", typeflag: tar.TypeDir},
+	}
+
+	buf := bytes.Buffer{}
+	tw := tar.NewWriter(&buf)
+	for _, entry := range unified {
+		modTime := baseTime
+		if entry.content == "derived" || entry.content == "new" || entry.content == "v2" || entry.name == "rootfs/etc/" || entry.name == "rootfs/var/" {
+			modTime = newTime
+		}
+
+		hdr := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644, Size: int64(len(entry.content)), Linkname: entry.linkname, ModTime: modTime}
+		if entry.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "images", "derived"), buf.Bytes(), 0600))
+
+	// Export the delta.
+	metaPath, rootfsPath, hash, err := imageDeltaExport("derived", "base", dir)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	delta := imageDeltaTestRead(t, rootfsPath)
+	names := []string{}
+	for name := range delta {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	assert.Equal(t, []string{"etc", "etc/hostname", "etc/new", "usr/bin/tool", "var", "var/.wh.cache"}, names)
+
+	meta := imageDeltaTestRead(t, metaPath)
+	assert.Contains(t, meta["metadata.yaml"], "base_image: base")
+
+	// Rebuild the full image.
+	fingerprint, size, err := imageDeltaApply(metaPath, rootfsPath, "base", dir)
+	require.NoError(t, err)
+	assert.Len(t, fingerprint, 64)
+	assert.Greater(t, size, int64(0))
+
+	meta = imageDeltaTestRead(t, metaPath)
+	assert.NotContains(t, meta["metadata.yaml"], "base_image")
+	assert.Contains(t, meta["metadata.yaml"], "creation_date: 2")
+
+	rootfs := imageDeltaTestRead(t, rootfsPath)
+	assert.Equal(t, map[string]string{
+		".":                 "dir",
+		"etc":               "dir",
+		"etc/hostname":      "derived",
+		"etc/os-release":    "ubuntu",
+		"etc/new":           "new",
+		"usr":               "dir",
+		"usr/bin":           "dir",
+		"usr/bin/tool":      "v2",
+		"usr/bin/tool-link": "-> usr/bin/tool",
+		"var":               "dir",
+	}, rootfs)
+}
@@ -16,8 +16,23 @@ type initDataNode struct {
 	api.ServerPut `yaml:",inline"`
 	Networks      []internalClusterPostNetwork `json:"networks" yaml:"networks"`
 	StoragePools  []api.StoragePoolsPost       `json:"storage_pools" yaml:"storage_pools"`
-	Profiles      []api.ProfilesPost           `json:"profiles" yaml:"profiles"`
+	Profiles      []initDataProfile            `json:"profiles" yaml:"profiles"`
 	Projects      []api.ProjectsPost           `json:"projects" yaml:"projects"`
+	NetworkACLs   []initDataNetworkACL         `json:"network_acls" yaml:"network_acls"`
+}
+
+// initDataProfile is a profile definition in the given project (default project if empty).
+type initDataProfile struct {
+	api.ProfilesPost `yaml:",inline"`
+
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// initDataNetworkACL is a network ACL definition in the given project (default project if empty).
+type initDataNetworkACL struct {
+	api.NetworkACLsPost `yaml:",inline"`
+
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
 }
 
 type initDataCluster struct {
@@ -67,6 +82,172 @@ func initDataNodeApply(d lxd.InstanceServer, config initDataNode) (func(), error
 		}
 	}
 
+	// Apply project configuration.
+	if config.Projects != nil && len(config.Projects) > 0 {
+		// Get the list of projects.
+		projectNames, err := d.GetProjectNames()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to retrieve list of projects")
+		}
+
+		// Project creator.
+		createProject := func(project api.ProjectsPost) error {
+			// Create the project if doesn't exist.
+			err := d.CreateProject(project)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to create project '%s'", project.Name)
+			}
+
+			// Setup reverter.
+			revert.Add(func() { d.DeleteProject(project.Name) })
+			return nil
+		}
+
+		// Project updater.
+		updateProject := func(project api.ProjectsPost) error {
+			// Get the current project.
+			currentProject, etag, err := d.GetProject(project.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to retrieve current project '%s'", project.Name)
+			}
+
+			// Setup reverter.
+			revert.Add(func() { d.UpdateProject(currentProject.Name, currentProject.Writable(), "") })
+
+			// Prepare the update.
+			newProject := api.ProjectPut{}
+			err = shared.DeepCopy(currentProject.Writable(), &newProject)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to copy configuration of project '%s'", project.Name)
+			}
+
+			// Description override.
+			if project.Description != "" {
+				newProject.Description = project.Description
+			}
+
+			// Config overrides.
+			for k, v := range project.Config {
+				newProject.Config[k] = fmt.Sprintf("%v", v)
+			}
+
+			// Apply it.
+			err = d.UpdateProject(currentProject.Name, newProject, etag)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to update project '%s'", project.Name)
+			}
+
+			return nil
+		}
+
+		for _, project := range config.Projects {
+			// New project.
+			if !shared.StringInSlice(project.Name, projectNames) {
+				err := createProject(project)
+				if err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			// Existing project.
+			err := updateProject(project)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Apply network ACL configuration.
+	if config.NetworkACLs != nil && len(config.NetworkACLs) > 0 {
+		// Network ACL creator.
+		createNetworkACL := func(acl initDataNetworkACL) error {
+			// Create the network ACL if doesn't exist.
+			err := d.UseProject(acl.Project).CreateNetworkACL(acl.NetworkACLsPost)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to create network ACL %q in project %q", acl.Name, acl.Project)
+			}
+
+			// Setup reverter.
+			revert.Add(func() { d.UseProject(acl.Project).DeleteNetworkACL(acl.Name) })
+			return nil
+		}
+
+		// Network ACL updater.
+		updateNetworkACL := func(acl initDataNetworkACL) error {
+			// Get the current network ACL.
+			currentACL, etag, err := d.UseProject(acl.Project).GetNetworkACL(acl.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to retrieve current network ACL %q in project %q", acl.Name, acl.Project)
+			}
+
+			// Setup reverter.
+			revert.Add(func() {
+				d.UseProject(acl.Project).UpdateNetworkACL(currentACL.Name, currentACL.Writable(), "")
+			})
+
+			// Prepare the update.
+			newACL := api.NetworkACLPut{}
+			err = shared.DeepCopy(currentACL.Writable(), &newACL)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to copy configuration of network ACL %q in project %q", acl.Name, acl.Project)
+			}
+
+			// Description override.
+			if acl.Description != "" {
+				newACL.Description = acl.Description
+			}
+
+			// Rules overrides.
+			if acl.Ingress != nil {
+				newACL.Ingress = acl.Ingress
+			}
+
+			if acl.Egress != nil {
+				newACL.Egress = acl.Egress
+			}
+
+			// Config overrides.
+			if newACL.Config == nil {
+				newACL.Config = map[string]string{}
+			}
+
+			for k, v := range acl.Config {
+				newACL.Config[k] = fmt.Sprintf("%v", v)
+			}
+
+			// Apply it.
+			err = d.UseProject(acl.Project).UpdateNetworkACL(currentACL.Name, newACL, etag)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to update network ACL %q in project %q", acl.Name, acl.Project)
+			}
+
+			return nil
+		}
+
+		for _, acl := range config.NetworkACLs {
+			if acl.Project == "" {
+				acl.Project = project.Default
+			}
+
+			_, _, err := d.UseProject(acl.Project).GetNetworkACL(acl.Name)
+			if err != nil {
+				// New network ACL.
+				err = createNetworkACL(acl)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				// Existing network ACL.
+				err = updateNetworkACL(acl)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	// Apply network configuration.
 	if config.Networks != nil && len(config.Networks) > 0 {
 		// Network creator.
@@ -229,41 +410,37 @@ func initDataNodeApply(d lxd.InstanceServer, config initDataNode) (func(), error
 
 	// Apply profile configuration.
 	if config.Profiles != nil && len(config.Profiles) > 0 {
-		// Get the list of profiles.
-		profileNames, err := d.GetProfileNames()
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to retrieve list of profiles")
-		}
-
 		// Profile creator.
-		createProfile := func(profile api.ProfilesPost) error {
+		createProfile := func(profile initDataProfile) error {
 			// Create the profile if doesn't exist.
-			err := d.CreateProfile(profile)
+			err := d.UseProject(profile.Project).CreateProfile(profile.ProfilesPost)
 			if err != nil {
-				return errors.Wrapf(err, "Failed to create profile '%s'", profile.Name)
+				return errors.Wrapf(err, "Failed to create profile %q in project %q", profile.Name, profile.Project)
 			}
 
 			// Setup reverter.
-			revert.Add(func() { d.DeleteProfile(profile.Name) })
+			revert.Add(func() { d.UseProject(profile.Project).DeleteProfile(profile.Name) })
 			return nil
 		}
 
 		// Profile updater.
-		updateProfile := func(profile api.ProfilesPost) error {
+		updateProfile := func(profile initDataProfile) error {
 			// Get the current profile.
-			currentProfile, etag, err := d.GetProfile(profile.Name)
+			currentProfile, etag, err := d.UseProject(profile.Project).GetProfile(profile.Name)
 			if err != nil {
-				return errors.Wrapf(err, "Failed to retrieve current profile '%s'", profile.Name)
+				return errors.Wrapf(err, "Failed to retrieve current profile %q in project %q", profile.Name, profile.Project)
 			}
 
 			// Setup reverter.
-			revert.Add(func() { d.UpdateProfile(currentProfile.Name, currentProfile.Writable(), "") })
+			revert.Add(func() {
+				d.UseProject(profile.Project).UpdateProfile(currentProfile.Name, currentProfile.Writable(), "")
+			})
 
 			// Prepare the update.
 			newProfile := api.ProfilePut{}
 			err = shared.DeepCopy(currentProfile.Writable(), &newProfile)
 			if err != nil {
-				return errors.Wrapf(err, "Failed to copy configuration of profile '%s'", profile.Name)
+				return errors.Wrapf(err, "Failed to copy configuration of profile %q in project %q", profile.Name, profile.Project)
 			}
 
 			// Description override.
@@ -292,95 +469,35 @@ func initDataNodeApply(d lxd.InstanceServer, config initDataNode) (func(), error
 			}
 
 			// Apply it.
-			err = d.UpdateProfile(currentProfile.Name, newProfile, etag)
+			err = d.UseProject(profile.Project).UpdateProfile(currentProfile.Name, newProfile, etag)
 			if err != nil {
-				return errors.Wrapf(err, "Failed to update profile '%s'", profile.Name)
+				return errors.Wrapf(err, "Failed to update profile %q in project %q", profile.Name, profile.Project)
 			}
 
 			return nil
 		}
 
-		for _, profile := range config.Profiles {
-			// New profile.
-			if !shared.StringInSlice(profile.Name, profileNames) {
-				err := createProfile(profile)
-				if err != nil {
-					return nil, err
-				}
-
-				continue
-			}
-
-			// Existing profile.
-			err := updateProfile(profile)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	// Apply project configuration.
-	if config.Projects != nil && len(config.Projects) > 0 {
-		// Get the list of projects.
-		projectNames, err := d.GetProjectNames()
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to retrieve list of projects")
-		}
+		// Lists of existing profiles, by project.
+		profileNames := map[string][]string{}
 
-		// Project creator.
-		createProject := func(project api.ProjectsPost) error {
-			// Create the project if doesn't exist.
-			err := d.CreateProject(project)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to create project '%s'", project.Name)
-			}
-
-			// Setup reverter.
-			revert.Add(func() { d.DeleteProject(project.Name) })
-			return nil
-		}
-
-		// Project updater.
-		updateProject := func(project api.ProjectsPost) error {
-			// Get the current project.
-			currentProject, etag, err := d.GetProject(project.Name)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to retrieve current project '%s'", project.Name)
-			}
-
-			// Setup reverter.
-			revert.Add(func() { d.UpdateProject(currentProject.Name, currentProject.Writable(), "") })
-
-			// Prepare the update.
-			newProject := api.ProjectPut{}
-			err = shared.DeepCopy(currentProject.Writable(), &newProject)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to copy configuration of project '%s'", project.Name)
-			}
-
-			// Description override.
-			if project.Description != "" {
-				newProject.Description = project.Description
+		for _, profile := range config.Profiles {
+			if profile.Project == "" {
+				profile.Project = project.Default
 			}
 
-			// Config overrides.
-			for k, v := range project.Config {
-				newProject.Config[k] = fmt.Sprintf("%v", v)
-			}
+			_, ok := profileNames[profile.Project]
+			if !ok {
+				names, err := d.UseProject(profile.Project).GetProfileNames()
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed to retrieve list of profiles in project %q", profile.Project)
+				}
 
-			// Apply it.
-			err = d.UpdateProject(currentProject.Name, newProject, etag)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to update project '%s'", project.Name)
+				profileNames[profile.Project] = names
 			}
 
-			return nil
-		}
-
-		for _, project := range config.Projects {
-			// New project.
-			if !shared.StringInSlice(project.Name, projectNames) {
-				err := createProject(project)
+			// New profile.
+			if !shared.StringInSlice(profile.Name, profileNames[profile.Project]) {
+				err := createProfile(profile)
 				if err != nil {
 					return nil, err
 				}
@@ -388,8 +505,8 @@ func initDataNodeApply(d lxd.InstanceServer, config initDataNode) (func(), error
 				continue
 			}
 
-			// Existing project.
-			err := updateProject(project)
+			// Existing profile.
+			err := updateProfile(profile)
 			if err != nil {
 				return nil, err
 			}
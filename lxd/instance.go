@@ -443,8 +443,14 @@ func autoCreateContainerSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
 				continue
 			}
 
+			delay, err := snapshotScheduleDelay(c.ExpandedConfig()["snapshots.schedule.offset"], c.ExpandedConfig()["snapshots.schedule.jitter"], int64(c.ID()))
+			if err != nil {
+				logger.Error("Failed getting snapshot schedule delay", log.Ctx{"err": err, "instance": c.Name(), "project": c.Project()})
+				continue
+			}
+
 			// Check if snapshot is scheduled
-			if !snapshotIsScheduledNow(schedule, int64(c.ID())) {
+			if !snapshotIsScheduledNow(schedule, int64(c.ID()), delay) {
 				continue
 			}
 
@@ -461,6 +467,11 @@ func autoCreateContainerSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
 		}
 
 		opRun := func(op *operations.Operation) error {
+			// Take turns with the other cluster members to avoid stalling shared storage.
+			if !snapshotWaitMemberSlot(ctx, d) {
+				return nil
+			}
+
 			return autoCreateContainerSnapshots(ctx, d, instances)
 		}
 
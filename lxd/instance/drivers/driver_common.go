@@ -2,7 +2,9 @@ package drivers
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -26,6 +28,7 @@ import (
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/warnings"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
@@ -207,7 +210,14 @@ func (d *common) DeferTemplateApply(trigger instance.TemplateTrigger) error {
 		return nil
 	}
 
-	err := d.VolatileSet(map[string]string{"volatile.apply_template": string(trigger)})
+	volatileSet := map[string]string{"volatile.apply_template": string(trigger)}
+
+	// Copies also get the clone customization hooks run on their first start.
+	if trigger == instance.TemplateTriggerCopy {
+		volatileSet["volatile.clone_pending"] = "true"
+	}
+
+	err := d.VolatileSet(volatileSet)
 	if err != nil {
 		return errors.Wrap(err, "Failed to set apply_template volatile key")
 	}
@@ -215,6 +225,126 @@ func (d *common) DeferTemplateApply(trigger instance.TemplateTrigger) error {
 	return nil
 }
 
+// cloneScript returns the shell script applying the clone customization hooks enabled through the clone.* keys, or
+// an empty string if none are.
+func cloneScript(name string, config map[string]string) string {
+	sb := &strings.Builder{}
+
+	if shared.IsTrue(config["clone.regenerate.machine_id"]) {
+		sb.WriteString(`
+# Regenerate the machine ID.
+if [ -e /etc/machine-id ]; then
+    rm -f /etc/machine-id
+    if command -v systemd-machine-id-setup >/dev/null 2>&1; then
+        systemd-machine-id-setup
+    else
+        tr -d - < /proc/sys/kernel/random/uuid > /etc/machine-id
+    fi
+fi
+if [ -f /var/lib/dbus/machine-id ] && [ ! -L /var/lib/dbus/machine-id ]; then
+    rm -f /var/lib/dbus/machine-id
+    ln -s /etc/machine-id /var/lib/dbus/machine-id
+fi
+`)
+	}
+
+	if shared.IsTrue(config["clone.regenerate.ssh_host_keys"]) {
+		sb.WriteString(`
+# Regenerate the SSH host keys.
+if [ -d /etc/ssh ] && command -v ssh-keygen >/dev/null 2>&1; then
+    rm -f /etc/ssh/ssh_host_*
+    ssh-keygen -A
+    if command -v systemctl >/dev/null 2>&1; then
+        systemctl try-restart ssh.service sshd.service || true
+    fi
+fi
+`)
+	}
+
+	// Instance names are valid hostnames so can be used as is.
+	if shared.IsTrue(config["clone.hostname"]) {
+		fmt.Fprintf(sb, `
+# Set the hostname.
+echo %[1]s > /etc/hostname
+hostname %[1]s || true
+if [ -f /etc/hosts ]; then
+    sed -i "s/^127\.0\.1\.1[[:space:]].*/127.0.1.1\t%[1]s/" /etc/hosts
+fi
+`, name)
+	}
+
+	if config["clone.script"] != "" {
+		fmt.Fprintf(sb, `
+# Run the user script.
+script="$(mktemp)"
+trap 'rm -f "${script}"' EXIT
+echo %s | base64 -d > "${script}"
+chmod 0700 "${script}"
+"${script}"
+`, base64.StdEncoding.EncodeToString([]byte(config["clone.script"])))
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	return "set -eu\n" + sb.String()
+}
+
+// cloneCustomize runs the clone customization hooks inside a copied instance which just started and then clears
+// volatile.clone_pending. Running them is retried until the instance is ready to run commands (VMs need their
+// agent), if the instance stops meanwhile they're run on its next start instead.
+func (d *common) cloneCustomize(inst instance.Instance) {
+	script := cloneScript(d.name, d.expandedConfig)
+	if script != "" {
+		logFile, err := os.OpenFile(filepath.Join(d.LogPath(), "clone.log"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			d.logger.Error("Failed creating clone customization log", log.Ctx{"err": err})
+			return
+		}
+		defer logFile.Close()
+
+		req := api.InstanceExecPost{
+			Command: []string{"/bin/sh", "-c", script},
+			Environment: map[string]string{
+				"PATH": "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+				"HOME": "/root",
+			},
+		}
+
+		deadline := time.Now().Add(5 * time.Minute)
+		for {
+			if !inst.IsRunning() {
+				return
+			}
+
+			cmd, err := inst.Exec(req, nil, logFile, logFile)
+			if err == nil {
+				exitCode, err := cmd.Wait()
+				if err != nil || exitCode != 0 {
+					d.logger.Warn("Clone customization failed, see clone.log", log.Ctx{"exitCode": exitCode, "err": err})
+				} else {
+					d.logger.Info("Applied clone customization")
+				}
+
+				break
+			}
+
+			if time.Now().After(deadline) {
+				d.logger.Warn("Gave up on clone customization", log.Ctx{"err": err})
+				break
+			}
+
+			time.Sleep(5 * time.Second)
+		}
+	}
+
+	err := d.VolatileSet(map[string]string{"volatile.clone_pending": ""})
+	if err != nil {
+		d.logger.Error("Failed clearing clone_pending volatile key", log.Ctx{"err": err})
+	}
+}
+
 // SetOperation sets the current operation.
 func (d *common) SetOperation(op *operations.Operation) {
 	d.op = op
@@ -295,6 +425,17 @@ func (d *common) VolatileSet(changes map[string]string) error {
 	return nil
 }
 
+// isProtected returns whether the given protection (delete, shift or stop) is enabled on the instance, taking
+// the defaults of its project into account.
+func (d *common) isProtected(protection string) (bool, error) {
+	p, err := d.state.Cluster.GetProject(d.project)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed loading project %q", d.project)
+	}
+
+	return project.InstanceIsProtected(p, d.expandedConfig, protection), nil
+}
+
 //
 // SECTION: path getters
 //
@@ -682,8 +823,44 @@ func (d *common) startupSnapshot(inst instance.Instance) error {
 	return inst.Snapshot(name, expiry, false)
 }
 
+// maasInstance identifies an instance in MAAS under a name other than its current one.
+type maasInstance struct {
+	name    string
+	project string
+}
+
+// Name returns the name of the instance.
+func (i maasInstance) Name() string {
+	return i.name
+}
+
+// Project returns the project of the instance.
+func (i maasInstance) Project() string {
+	return i.project
+}
+
+// maasReport raises a warning on the instance when a MAAS operation fails, and resolves it once one succeeds.
+func (d *common) maasReport(action string, err error) {
+	if err == nil {
+		err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(d.state.Cluster, d.project, db.WarningMAASOperationFailed, dbCluster.TypeInstance, d.id)
+		if err != nil {
+			d.logger.Warn("Failed to resolve MAAS warning", log.Ctx{"err": err})
+		}
+
+		return
+	}
+
+	d.logger.Error("Failed MAAS operation", log.Ctx{"action": action, "err": err})
+
+	msg := fmt.Sprintf("Failed to %s MAAS record: %v", action, err)
+	err = d.state.Cluster.UpsertWarningLocalNode(d.project, dbCluster.TypeInstance, d.id, db.WarningMAASOperationFailed, msg)
+	if err != nil {
+		d.logger.Warn("Failed to create MAAS warning", log.Ctx{"err": err})
+	}
+}
+
 // Internal MAAS handling.
-func (d *common) maasUpdate(inst instance.Instance, oldDevices map[string]map[string]string) error {
+func (d *common) maasUpdate(inst instance.Instance, oldDevices map[string]map[string]string) (err error) {
 	// Check if MAAS is configured
 	maasURL, err := cluster.ConfigGetString(d.state.Cluster, "maas.api.url")
 	if err != nil {
@@ -712,6 +889,8 @@ func (d *common) maasUpdate(inst instance.Instance, oldDevices map[string]map[st
 		return nil
 	}
 
+	defer func() { d.maasReport("update", err) }()
+
 	// See if we're connected to MAAS
 	if d.state.MAAS == nil {
 		return fmt.Errorf("Can't perform the operation because MAAS is currently unavailable")
@@ -740,6 +919,10 @@ func (d *common) maasInterfaces(inst instance.Instance, devices map[string]map[s
 			continue
 		}
 
+		if (m["maas.ipv4.address"] != "" && m["maas.subnet.ipv4"] == "") || (m["maas.ipv6.address"] != "" && m["maas.subnet.ipv6"] == "") {
+			return nil, fmt.Errorf("Device %q sets a MAAS address without the matching MAAS subnet", k)
+		}
+
 		if m["maas.subnet.ipv4"] == "" && m["maas.subnet.ipv6"] == "" {
 			continue
 		}
@@ -758,6 +941,11 @@ func (d *common) maasInterfaces(inst instance.Instance, devices map[string]map[s
 				Address: m["ipv4.address"],
 			}
 
+			// An address reserved specifically in MAAS takes precedence.
+			if m["maas.ipv4.address"] != "" {
+				subnet.Address = m["maas.ipv4.address"]
+			}
+
 			subnets = append(subnets, subnet)
 		}
 
@@ -768,6 +956,10 @@ func (d *common) maasInterfaces(inst instance.Instance, devices map[string]map[s
 				Address: m["ipv6.address"],
 			}
 
+			if m["maas.ipv6.address"] != "" {
+				subnet.Address = m["maas.ipv6.address"]
+			}
+
 			subnets = append(subnets, subnet)
 		}
 
@@ -783,7 +975,7 @@ func (d *common) maasInterfaces(inst instance.Instance, devices map[string]map[s
 	return interfaces, nil
 }
 
-func (d *common) maasRename(inst instance.Instance, newName string) error {
+func (d *common) maasRename(inst instance.Instance, newName string) (err error) {
 	maasURL, err := cluster.ConfigGetString(d.state.Cluster, "maas.api.url")
 	if err != nil {
 		return err
@@ -802,6 +994,8 @@ func (d *common) maasRename(inst instance.Instance, newName string) error {
 		return nil
 	}
 
+	defer func() { d.maasReport("rename", err) }()
+
 	if d.state.MAAS == nil {
 		return fmt.Errorf("Can't perform the operation because MAAS is currently unavailable")
 	}
@@ -811,14 +1005,15 @@ func (d *common) maasRename(inst instance.Instance, newName string) error {
 		return err
 	}
 
+	// Register the instance straight away under its new name if it's missing from MAAS.
 	if !exists {
-		return d.maasUpdate(inst, nil)
+		return d.state.MAAS.CreateContainer(maasInstance{name: newName, project: d.project}, interfaces)
 	}
 
 	return d.state.MAAS.RenameContainer(d, newName)
 }
 
-func (d *common) maasDelete(inst instance.Instance) error {
+func (d *common) maasDelete(inst instance.Instance) (err error) {
 	maasURL, err := cluster.ConfigGetString(d.state.Cluster, "maas.api.url")
 	if err != nil {
 		return err
@@ -837,6 +1032,8 @@ func (d *common) maasDelete(inst instance.Instance) error {
 		return nil
 	}
 
+	defer func() { d.maasReport("delete", err) }()
+
 	if d.state.MAAS == nil {
 		return fmt.Errorf("Can't perform the operation because MAAS is currently unavailable")
 	}
@@ -2,6 +2,8 @@ package drivers
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/user"
 	"path"
 	"path/filepath"
 	"runtime"
@@ -27,16 +30,20 @@ import (
 
 	"github.com/lxc/lxd/lxd/apparmor"
 	"github.com/lxc/lxd/lxd/cgroup"
+	"github.com/lxc/lxd/lxd/consolelog"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/device"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/device/nictype"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/operationlock"
+	"github.com/lxc/lxd/lxd/instance/processes"
 	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/seccomp"
@@ -45,6 +52,7 @@ import (
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/lxd/template"
 	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/warnings"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
@@ -284,6 +292,7 @@ func lxcCreate(s *state.State, args db.InstanceArgs, volumeConfig map[string]str
 		idmap, base, err = findIdmap(
 			s,
 			args.Name,
+			args.Project,
 			d.expandedConfig["security.idmap.isolated"],
 			d.expandedConfig["security.idmap.base"],
 			d.expandedConfig["security.idmap.size"],
@@ -489,7 +498,88 @@ func idmapSize(state *state.State, isolatedStr string, size string) (int64, erro
 
 var idmapLock sync.Mutex
 
-func findIdmap(state *state.State, cName string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
+// isolatedIdmapRanges returns the uid/gid ranges dedicated to the projects setting security.idmap.isolated_ranges,
+// recording the newly allocated ones in the project configuration. A warning is recorded for every project whose
+// range couldn't be allocated, those are also returned.
+func isolatedIdmapRanges(state *state.State) (map[string]project.IdmapRange, []string, error) {
+	if state.OS.IdmapSet == nil || len(state.OS.IdmapSet.Idmap) == 0 {
+		return map[string]project.IdmapRange{}, nil, nil
+	}
+
+	hostMap := state.OS.IdmapSet.Idmap[0]
+
+	var projectIDs map[int64]string
+	var projectConfigs map[string]map[string]string
+	var ranges map[string]project.IdmapRange
+	var exhausted []string
+	err := state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectIDs, err = tx.GetProjectIDsToNames()
+		if err != nil {
+			return err
+		}
+
+		projectConfigs, err = tx.ProjectConfigRef(db.ProjectFilter{})
+		if err != nil {
+			return err
+		}
+
+		var updates map[string]string
+		ranges, updates, exhausted, err = project.IsolatedIdmapRanges(projectIDs, projectConfigs, hostMap.Hostid, hostMap.Maprange)
+		if err != nil {
+			return err
+		}
+
+		// Record the allocations in the same transaction so that other members see them right away.
+		for name, offset := range updates {
+			p, err := tx.GetProject(name)
+			if err != nil {
+				return err
+			}
+
+			if p.Config == nil {
+				p.Config = map[string]string{}
+			}
+
+			if offset == "" {
+				delete(p.Config, project.IdmapRangeOffsetKey)
+			} else {
+				p.Config[project.IdmapRangeOffsetKey] = offset
+			}
+
+			err = tx.UpdateProject(name, api.ProjectPut{Description: p.Description, Config: p.Config})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to allocate the project uid/gid ranges")
+	}
+
+	for id, name := range projectIDs {
+		if projectConfigs[name]["security.idmap.isolated_ranges"] == "" {
+			continue
+		}
+
+		if shared.StringInSlice(name, exhausted) {
+			msg := fmt.Sprintf("Unable to allocate a range of %s uid/gid", projectConfigs[name]["security.idmap.isolated_ranges"])
+			err = state.Cluster.UpsertWarningLocalNode(name, dbCluster.TypeProject, int(id), db.WarningIdmapRangesExhausted, msg)
+		} else {
+			err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(state.Cluster, name, db.WarningIdmapRangesExhausted, dbCluster.TypeProject, int(id))
+		}
+
+		if err != nil {
+			logger.Warn("Failed to update idmap range warning", log.Ctx{"project": name, "err": err})
+		}
+	}
+
+	return ranges, exhausted, nil
+}
+
+func findIdmap(state *state.State, cName string, cProject string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
 	isolated := false
 	if shared.IsTrue(isolatedStr) {
 		isolated = true
@@ -500,6 +590,22 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return nil, 0, err
 	}
 
+	// Instances of projects with a dedicated range are always isolated.
+	ranges, exhausted, err := isolatedIdmapRanges(state)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if shared.StringInSlice(cProject, exhausted) {
+		return nil, 0, fmt.Errorf("Not enough uid/gid available for the dedicated range of project %q", cProject)
+	}
+
+	projectRange, hasProjectRange := ranges[cProject]
+	if hasProjectRange {
+		isolated = true
+		isolatedStr = "true"
+	}
+
 	if !isolated {
 		newIdmapset := idmap.IdmapSet{Idmap: make([]idmap.IdmapEntry, len(state.OS.IdmapSet.Idmap))}
 		copy(newIdmapset.Idmap, state.OS.IdmapSet.Idmap)
@@ -541,6 +647,10 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			return nil, 0, err
 		}
 
+		if hasProjectRange && (offset < projectRange.Base || offset+size > projectRange.Base+projectRange.Size) {
+			return nil, 0, fmt.Errorf("The requested uid/gid base is outside of the range dedicated to project %q", cProject)
+		}
+
 		set, err := mkIdmap(offset, size)
 		if err != nil && err == idmap.ErrHostIdIsSubId {
 			return nil, 0, err
@@ -557,9 +667,20 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return nil, 0, err
 	}
 
+	// Allocate from the project's dedicated range if any, otherwise from the host allocation around the ranges
+	// dedicated to projects.
 	offset := state.OS.IdmapSet.Idmap[0].Hostid + 65536
-
+	end := state.OS.IdmapSet.Idmap[0].Hostid + state.OS.IdmapSet.Idmap[0].Maprange
 	mapentries := idmap.ByHostid{}
+	if hasProjectRange {
+		offset = projectRange.Base
+		end = projectRange.Base + projectRange.Size
+	} else {
+		for _, r := range ranges {
+			mapentries = append(mapentries, &idmap.IdmapEntry{Hostid: r.Base, Maprange: r.Size})
+		}
+	}
+
 	for _, container := range cts {
 		if container.Type() != instancetype.Container {
 			continue
@@ -568,7 +689,7 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		name := container.Name()
 
 		/* Don't change our map Just Because. */
-		if name == cName {
+		if name == cName && container.Project() == cProject {
 			continue
 		}
 
@@ -576,7 +697,8 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			continue
 		}
 
-		if !shared.IsTrue(container.ExpandedConfig()["security.idmap.isolated"]) {
+		_, inProjectRange := ranges[container.Project()]
+		if !inProjectRange && !shared.IsTrue(container.ExpandedConfig()["security.idmap.isolated"]) {
 			continue
 		}
 
@@ -588,49 +710,34 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			}
 		}
 
-		cSize, err := idmapSize(state, container.ExpandedConfig()["security.idmap.isolated"], container.ExpandedConfig()["security.idmap.size"])
+		cSize, err := idmapSize(state, "true", container.ExpandedConfig()["security.idmap.size"])
 		if err != nil {
 			return nil, 0, err
 		}
 
+		// Maps of any project count as long as they overlap with where the new map is allocated, including
+		// those allocated in a range before it was dedicated to a project.
+		if cBase+cSize <= offset || cBase >= end {
+			continue
+		}
+
 		mapentries = append(mapentries, &idmap.IdmapEntry{Hostid: int64(cBase), Maprange: cSize})
 	}
 
 	sort.Sort(mapentries)
 
-	for i := range mapentries {
-		if i == 0 {
-			if mapentries[0].Hostid < offset+size {
-				offset = mapentries[0].Hostid + mapentries[0].Maprange
-				continue
-			}
-
-			set, err := mkIdmap(offset, size)
-			if err != nil && err == idmap.ErrHostIdIsSubId {
-				return nil, 0, err
-			}
-
-			return set, offset, nil
+	// Use the first gap large enough for the map.
+	for _, entry := range mapentries {
+		if offset+size <= entry.Hostid {
+			break
 		}
 
-		if mapentries[i-1].Hostid+mapentries[i-1].Maprange > offset {
-			offset = mapentries[i-1].Hostid + mapentries[i-1].Maprange
-			continue
+		if entry.Hostid+entry.Maprange > offset {
+			offset = entry.Hostid + entry.Maprange
 		}
-
-		offset = mapentries[i-1].Hostid + mapentries[i-1].Maprange
-		if offset+size < mapentries[i].Hostid {
-			set, err := mkIdmap(offset, size)
-			if err != nil && err == idmap.ErrHostIdIsSubId {
-				return nil, 0, err
-			}
-
-			return set, offset, nil
-		}
-		offset = mapentries[i].Hostid + mapentries[i].Maprange
 	}
 
-	if offset+size < state.OS.IdmapSet.Idmap[0].Hostid+state.OS.IdmapSet.Idmap[0].Maprange {
+	if offset+size <= end {
 		set, err := mkIdmap(offset, size)
 		if err != nil && err == idmap.ErrHostIdIsSubId {
 			return nil, 0, err
@@ -639,7 +746,77 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return set, offset, nil
 	}
 
-	return nil, 0, fmt.Errorf("Not enough uid/gid available for the container")
+	if hasProjectRange {
+		return nil, 0, fmt.Errorf("Not enough uid/gid available in the range dedicated to project %q (needs %d from %d, range ends at %d)", cProject, size, offset, end)
+	}
+
+	// Grow the host allocation to fit the container if LXD is allowed to.
+	err = idmapExpand(state, offset+size)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "Not enough uid/gid available for the container (needs %d from %d, allocation ends at %d)", size, offset, end)
+	}
+
+	set, err := mkIdmap(offset, size)
+	if err != nil && err == idmap.ErrHostIdIsSubId {
+		return nil, 0, err
+	}
+
+	return set, offset, nil
+}
+
+// idmapExpand grows the uid/gid allocation of LXD in /etc/subuid and /etc/subgid so that it extends up to end,
+// when allowed through instances.idmap_manage. The caller must hold idmapLock.
+func idmapExpand(state *state.State, end int64) error {
+	var config *node.Config
+	err := state.Node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = node.ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !config.InstancesIdmapManage() {
+		return fmt.Errorf("Grow the allocation in /etc/subuid and /etc/subgid or set instances.idmap_manage")
+	}
+
+	if state.OS.RunningInUserNS {
+		return fmt.Errorf("The allocation can't be grown when LXD runs in a user namespace")
+	}
+
+	hostMap := state.OS.IdmapSet.Idmap
+	if len(hostMap) != 2 || hostMap[0].Hostid != hostMap[1].Hostid {
+		return fmt.Errorf("The allocation can only be grown when uids and gids start at the same id")
+	}
+
+	// Grow by whole blocks of 65536 ids.
+	size := end - hostMap[0].Hostid
+	if size%65536 != 0 {
+		size += 65536 - size%65536
+	}
+
+	if size > config.InstancesIdmapMaxSize() {
+		return fmt.Errorf("Growing the allocation to %d ids would exceed instances.idmap_max_size", size)
+	}
+
+	currentUser, err := user.Current()
+	if err != nil {
+		return err
+	}
+
+	err = idmap.ExpandShadowMap("", currentUser.Username, hostMap[0].Hostid, size)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Grew the uid/gid allocation", log.Ctx{"base": hostMap[0].Hostid, "old": hostMap[0].Maprange, "new": size})
+
+	for i := range hostMap {
+		hostMap[i].Maprange = size
+	}
+
+	return nil
 }
 
 func (d *lxc) init() error {
@@ -1028,13 +1205,16 @@ func (d *lxc) initLXC(config bool) error {
 		}
 	}
 
-	// Setup environment
-	for k, v := range d.expandedConfig {
-		if strings.HasPrefix(k, "environment.") {
-			err = lxcSetConfigItem(cc, "lxc.environment", fmt.Sprintf("%s=%s", strings.TrimPrefix(k, "environment."), v))
-			if err != nil {
-				return err
-			}
+	// Setup environment, including the values enforced by the project.
+	p, err := d.state.Cluster.GetProject(d.project)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading project %q", d.project)
+	}
+
+	for k, v := range project.Environment(p, d.expandedConfig) {
+		err = lxcSetConfigItem(cc, "lxc.environment", fmt.Sprintf("%s=%s", k, v))
+		if err != nil {
+			return err
 		}
 	}
 
@@ -1890,7 +2070,12 @@ func (d *lxc) handleIdmappedStorage() (idmap.IdmapStorageType, *idmap.IdmapSet,
 
 	// We need to change the on-disk idmap but the container is protected
 	// against idmap changes.
-	if shared.IsTrue(d.expandedConfig["security.protection.shift"]) {
+	protected, err := d.isProtected("shift")
+	if err != nil {
+		return idmap.IdmapStorageNone, nil, err
+	}
+
+	if protected {
 		return idmap.IdmapStorageNone, nil, fmt.Errorf("Container is protected against filesystem shifting")
 	}
 
@@ -2056,10 +2241,20 @@ func (d *lxc) startCommon() (string, []func() error, error) {
 	nicID := -1
 	nvidiaDevices := []string{}
 
+	// Devices detached when checkpointing aren't part of the saved state, they get attached after the restore.
+	detachedDevices := []string{}
+	if d.stateful {
+		detachedDevices = d.checkpointDetachedDevices()
+	}
+
 	// Setup devices in sorted order, this ensures that device mounts are added in path order.
 	for _, entry := range d.expandedDevices.Sorted() {
 		dev := entry // Ensure device variable has local scope for revert.
 
+		if shared.StringInSlice(dev.Name, detachedDevices) {
+			continue
+		}
+
 		// Start the device.
 		runConf, err := d.deviceStart(dev.Name, dev.Config, false)
 		if err != nil {
@@ -2227,6 +2422,41 @@ func (d *lxc) startCommon() (string, []func() error, error) {
 		}
 	}
 
+	// Pass /dev/kvm to containers allowed to run nested virtual machines.
+	if shared.IsTrue(d.expandedConfig["security.nesting.kvm"]) {
+		runConf := deviceConfig.RunConfig{}
+		err = device.UnixDeviceSetupKVM(d.state, d.DevicesPath(), &runConf)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "Failed to setup nested KVM")
+		}
+
+		err = d.deviceStaticShiftMounts(runConf.Mounts)
+		if err != nil {
+			return "", nil, err
+		}
+
+		for _, mount := range runConf.Mounts {
+			mntVal := fmt.Sprintf("%s %s %s %s %d %d", shared.EscapePathFstab(mount.DevPath), shared.EscapePathFstab(mount.TargetPath), mount.FSType, strings.Join(mount.Opts, ","), mount.Freq, mount.PassNo)
+			err = lxcSetConfigItem(d.c, "lxc.mount.entry", mntVal)
+			if err != nil {
+				return "", nil, errors.Wrapf(err, "Failed to setup nested KVM mount")
+			}
+		}
+
+		if d.isCurrentlyPrivileged() && !d.state.OS.RunningInUserNS {
+			for _, rule := range runConf.CGroups {
+				if d.state.OS.CGInfo.Layout == cgroup.CgroupsUnified {
+					err = lxcSetConfigItem(d.c, fmt.Sprintf("lxc.cgroup2.%s", rule.Key), rule.Value)
+				} else {
+					err = lxcSetConfigItem(d.c, fmt.Sprintf("lxc.cgroup.%s", rule.Key), rule.Value)
+				}
+				if err != nil {
+					return "", nil, errors.Wrapf(err, "Failed to setup nested KVM cgroup")
+				}
+			}
+		}
+	}
+
 	// Override NVIDIA_VISIBLE_DEVICES if we have devices that need it.
 	if len(nvidiaDevices) > 0 {
 		err = lxcSetConfigItem(d.c, "lxc.environment", fmt.Sprintf("NVIDIA_VISIBLE_DEVICES=%s", strings.Join(nvidiaDevices, ",")))
@@ -2367,6 +2597,15 @@ func (d *lxc) Start(stateful bool) error {
 		return err
 	}
 
+	// Discard any existing state when starting statelessly, before the devices get started.
+	if !stateful && d.stateful {
+		err = d.checkpointDiscard()
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+	}
+
 	// Run the shared start code
 	configPath, postStartHooks, err := d.startCommon()
 	if err != nil {
@@ -2405,19 +2644,25 @@ func (d *lxc) Start(stateful bool) error {
 
 		err := d.Migrate(&criuMigrationArgs)
 		if err != nil && !d.IsRunning() {
+			// Leave the container in a state it can be started statelessly from.
+			d.checkpointRestoreFailed()
+
+			err = errors.Wrap(err, "Failed restoring the container state, it was discarded so the container can be started statelessly")
 			op.Done(err)
-			return errors.Wrap(err, "Migrate")
+			return err
 		}
 
-		os.RemoveAll(d.StatePath())
-		d.stateful = false
+		detachedDevices := d.checkpointDetachedDevices()
 
-		err = d.state.Cluster.UpdateInstanceStatefulFlag(d.id, false)
+		err = d.checkpointDiscard()
 		if err != nil {
 			op.Done(err)
 			return errors.Wrap(err, "Start container")
 		}
 
+		// Attach the devices detached when checkpointing.
+		d.checkpointAttachDevices(detachedDevices)
+
 		// Run any post start hooks.
 		err = d.runHooks(postStartHooks)
 		if err != nil {
@@ -2429,25 +2674,13 @@ func (d *lxc) Start(stateful bool) error {
 			return err
 		}
 
+		d.consoleLogTrack()
+
 		if op.Action() == "start" {
 			d.logger.Info("Started container", ctxMap)
 			d.state.Events.SendLifecycle(d.project, lifecycle.InstanceStarted.Event(d, nil))
 		}
 		return nil
-	} else if d.stateful {
-		/* stateless start required when we have state, let's delete it */
-		err := os.RemoveAll(d.StatePath())
-		if err != nil {
-			op.Done(err)
-			return err
-		}
-
-		d.stateful = false
-		err = d.state.Cluster.UpdateInstanceStatefulFlag(d.id, false)
-		if err != nil {
-			op.Done(err)
-			return errors.Wrap(err, "Persist stateful flag")
-		}
 	}
 
 	name := project.Instance(d.Project(), d.name)
@@ -2505,11 +2738,18 @@ func (d *lxc) Start(stateful bool) error {
 		return err
 	}
 
+	d.consoleLogTrack()
+
 	if op.Action() == "start" {
 		d.logger.Info("Started container", ctxMap)
 		d.state.Events.SendLifecycle(d.project, lifecycle.InstanceStarted.Event(d, nil))
 	}
 
+	// Apply the clone customization hooks on the first start of a copy.
+	if shared.IsTrue(d.localConfig["volatile.clone_pending"]) {
+		go d.cloneCustomize(d)
+	}
+
 	return nil
 }
 
@@ -2609,6 +2849,12 @@ func (d *lxc) Stop(stateful bool) error {
 		return ErrInstanceIsStopped
 	}
 
+	// The console ringbuffer goes away with the container.
+	err := d.ConsoleLogCapture()
+	if err != nil {
+		d.logger.Warn("Failed capturing console output", log.Ctx{"err": err})
+	}
+
 	// Setup a new operation
 	exists, op, err := operationlock.CreateWaitGet(d.id, "stop", []string{"restart", "restore"}, false, true)
 	if err != nil {
@@ -2632,12 +2878,27 @@ func (d *lxc) Stop(stateful bool) error {
 
 	// Handle stateful stop
 	if stateful {
+		// Check that the container can be checkpointed before touching it.
+		detachDevices, err := d.checkpointPreflight()
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+
 		// Cleanup any existing state
 		stateDir := d.StatePath()
 		os.RemoveAll(stateDir)
 
-		err := os.MkdirAll(stateDir, 0700)
+		err = os.MkdirAll(stateDir, 0700)
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+
+		// Detach the devices CRIU can't checkpoint, they're attached again on restore.
+		err = d.checkpointDetachDevices(detachDevices)
 		if err != nil {
+			os.RemoveAll(stateDir)
 			op.Done(err)
 			return err
 		}
@@ -2655,6 +2916,12 @@ func (d *lxc) Stop(stateful bool) error {
 		// Checkpoint
 		err = d.Migrate(&criuMigrationArgs)
 		if err != nil {
+			// CRIU resumes the container when the dump fails, so give it its devices back.
+			os.RemoveAll(stateDir)
+			if d.IsRunning() {
+				d.checkpointAttachDevices(detachDevices)
+			}
+
 			op.Done(err)
 			return err
 		}
@@ -2762,6 +3029,12 @@ func (d *lxc) Shutdown(timeout time.Duration) error {
 		return ErrInstanceIsStopped
 	}
 
+	// The console ringbuffer goes away with the container.
+	err := d.ConsoleLogCapture()
+	if err != nil {
+		d.logger.Warn("Failed capturing console output", log.Ctx{"err": err})
+	}
+
 	// Setup a new operation
 	exists, op, err := operationlock.CreateWaitGet(d.id, "stop", []string{"restart"}, true, false)
 	if err != nil {
@@ -2876,6 +3149,9 @@ func (d *lxc) onStopNS(args map[string]string) error {
 func (d *lxc) onStop(args map[string]string) error {
 	target := args["target"]
 
+	// The console ringbuffer goes away with the container.
+	consolelog.Untrack(d.LogPath())
+
 	// Validate target
 	if !shared.StringInSlice(target, []string{"stop", "reboot"}) {
 		d.logger.Error("Container sent invalid target to OnStop", log.Ctx{"target": target})
@@ -3001,7 +3277,14 @@ func (d *lxc) onStop(args map[string]string) error {
 // Accepts a stopHookNetnsPath argument which is required when run from the onStopNS hook before the
 // container's network namespace is unmounted (which is required for NIC device cleanup).
 func (d *lxc) cleanupDevices(instanceRunning bool, stopHookNetnsPath string) {
+	// Devices detached when checkpointing were already stopped.
+	detachedDevices := d.checkpointDetachedDevices()
+
 	for _, dev := range d.expandedDevices.Reversed() {
+		if shared.StringInSlice(dev.Name, detachedDevices) {
+			continue
+		}
+
 		// Only stop NIC devices when run from the onStopNS hook, and stop all other devices when run from
 		// the onStop hook. This way disk devices are stopped after the instance has been fully stopped.
 		if (stopHookNetnsPath != "" && dev.Config["type"] != "nic") || (stopHookNetnsPath == "" && dev.Config["type"] == "nic") {
@@ -3302,6 +3585,40 @@ func (d *lxc) RenderState() (*api.InstanceState, error) {
 	return d.renderState(d.statusCode())
 }
 
+// Processes returns the processes of the container using the most CPU or memory, those sharing its PID namespace.
+func (d *lxc) Processes(sortBy string, limit int) (*api.InstanceProcesses, error) {
+	pid := d.InitPID()
+	if pid == -1 {
+		return nil, fmt.Errorf("The instance isn't running")
+	}
+
+	pidNS, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	idmapset, err := d.CurrentIdmap()
+	if err != nil {
+		return nil, err
+	}
+
+	selector := func(pid int64) bool {
+		ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+		return err == nil && ns == pidNS
+	}
+
+	mapUID := func(uid int64) int64 {
+		if idmapset == nil {
+			return uid
+		}
+
+		nsUID, _ := idmapset.ShiftFromNs(uid, -1)
+		return nsUID
+	}
+
+	return processes.Top(selector, mapUID, sortBy, limit)
+}
+
 // Snapshot takes a new snapshot.
 func (d *lxc) Snapshot(name string, expiry time.Time, stateful bool) error {
 	// Deal with state.
@@ -3566,10 +3883,17 @@ func (d *lxc) Delete(force bool) error {
 
 	d.logger.Info("Deleting container", ctxMap)
 
-	if !force && shared.IsTrue(d.expandedConfig["security.protection.delete"]) && !d.IsSnapshot() {
-		err := fmt.Errorf("Container is protected")
-		d.logger.Warn("Failed to delete container", log.Ctx{"err": err})
-		return err
+	if !force && !d.IsSnapshot() {
+		protected, err := d.isProtected("delete")
+		if err != nil {
+			return err
+		}
+
+		if protected {
+			err := fmt.Errorf("Container is protected")
+			d.logger.Warn("Failed to delete container", log.Ctx{"err": err})
+			return err
+		}
 	}
 
 	// Delete any persistent warnings for instance.
@@ -4110,7 +4434,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 	}
 
 	// If apparmor changed, re-validate the apparmor profile (even if not running).
-	if shared.StringInSlice("raw.apparmor", changedConfig) || shared.StringInSlice("security.nesting", changedConfig) {
+	if shared.StringInSlice("raw.apparmor", changedConfig) || shared.StringInSlice("raw.apparmor.extra", changedConfig) || shared.StringInSlice("security.nesting", changedConfig) {
 		err = apparmor.InstanceValidate(d.state, d)
 		if err != nil {
 			return errors.Wrap(err, "Parse AppArmor profile")
@@ -4125,6 +4449,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 			idmap, base, err = findIdmap(
 				d.state,
 				d.Name(),
+				d.Project(),
 				d.expandedConfig["security.idmap.isolated"],
 				d.expandedConfig["security.idmap.base"],
 				d.expandedConfig["security.idmap.size"],
@@ -4162,7 +4487,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 
 	// Update MAAS (must run after the MAC addresses have been generated).
 	updateMAAS := false
-	for _, key := range []string{"maas.subnet.ipv4", "maas.subnet.ipv6", "ipv4.address", "ipv6.address"} {
+	for _, key := range []string{"maas.subnet.ipv4", "maas.subnet.ipv6", "maas.ipv4.address", "maas.ipv6.address", "ipv4.address", "ipv6.address"} {
 		if shared.StringInSlice(key, allUpdatedKeys) {
 			updateMAAS = true
 			break
@@ -4182,7 +4507,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 		for _, key := range changedConfig {
 			value := d.expandedConfig[key]
 
-			if key == "raw.apparmor" || key == "security.nesting" {
+			if key == "raw.apparmor" || key == "raw.apparmor.extra" || key == "security.nesting" {
 				// Update the AppArmor profile
 				err = apparmor.InstanceLoad(d.state, d)
 				if err != nil {
@@ -4395,7 +4720,7 @@ func (d *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 				if err != nil {
 					return err
 				}
-			} else if key == "limits.cpu" {
+			} else if key == "limits.cpu" || key == "limits.cpu.nodes" {
 				// Trigger a scheduler re-run
 				cgroup.TaskSchedulerTrigger("container", d.name, "changed")
 			} else if key == "limits.cpu.priority" || key == "limits.cpu.allowance" {
@@ -5101,6 +5426,184 @@ func (d *lxc) Migrate(args *instance.CriuMigrationArgs) error {
 	return nil
 }
 
+// checkpointDetachedDevicesKey lists the devices detached from the container when checkpointing it to disk.
+const checkpointDetachedDevicesKey = "volatile.detached_devices"
+
+// checkpointFeatures are the CRIU features checked before checkpointing a container and what needs them.
+var checkpointFeatures = []struct {
+	name         string
+	usedFor      string
+	unprivileged bool
+}{
+	{name: "seccomp_filters", usedFor: "the syscall filtering of the container"},
+	{name: "cgroupns", usedFor: "the cgroup namespace of the container"},
+	{name: "userns", usedFor: "unprivileged containers", unprivileged: true},
+}
+
+// checkpointDeviceExcluded returns whether CRIU can't checkpoint the device, which then needs detaching from the
+// container beforehand. CRIU only restores the veth based NICs and can't deal with host devices or the proxy
+// processes holding sockets into the container.
+func (d *lxc) checkpointDeviceExcluded(config deviceConfig.Device) (bool, error) {
+	switch config["type"] {
+	case "gpu", "infiniband", "proxy", "unix-hotplug", "usb":
+		return true, nil
+	case "nic":
+		nicType, err := nictype.NICType(d.state, d.Project(), config)
+		if err != nil {
+			return false, err
+		}
+
+		return !shared.StringInSlice(nicType, []string{"bridged", "ovn", "p2p", "routed"}), nil
+	}
+
+	return false, nil
+}
+
+// checkpointPreflight checks that the container can be checkpointed to disk, returning an actionable error if
+// not, and returns the devices to detach from it beforehand.
+func (d *lxc) checkpointPreflight() ([]string, error) {
+	_, err := exec.LookPath("criu")
+	if err != nil {
+		return nil, fmt.Errorf("Stateful stop requires CRIU, install it on the host or stop the container statelessly")
+	}
+
+	if shared.IsTrue(d.expandedConfig["security.nesting"]) {
+		return nil, fmt.Errorf("Stateful stop isn't supported with security.nesting as CRIU can't checkpoint nested containers, stop the container statelessly")
+	}
+
+	for _, feature := range checkpointFeatures {
+		if feature.unprivileged && d.IsPrivileged() {
+			continue
+		}
+
+		_, err := shared.RunCommand("criu", "check", "--feature", feature.name)
+		if err != nil {
+			return nil, fmt.Errorf("CRIU or the kernel lacks the %q feature needed for %s, upgrade them or stop the container statelessly", feature.name, feature.usedFor)
+		}
+	}
+
+	detachDevices := []string{}
+	for _, entry := range d.expandedDevices.Sorted() {
+		excluded, err := d.checkpointDeviceExcluded(entry.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		if !excluded {
+			continue
+		}
+
+		dev, _, err := d.deviceLoad(entry.Name, entry.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		if !dev.CanHotPlug() {
+			return nil, fmt.Errorf("Device %q can't be checkpointed by CRIU nor detached from the running container, remove it or stop the container statelessly", entry.Name)
+		}
+
+		detachDevices = append(detachDevices, entry.Name)
+	}
+
+	return detachDevices, nil
+}
+
+// checkpointDetachedDevices returns the devices detached from the container when it was checkpointed.
+func (d *lxc) checkpointDetachedDevices() []string {
+	value := d.localConfig[checkpointDetachedDevicesKey]
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+// checkpointDetachDevices detaches the devices from the running container, recording them so that they get
+// attached again once its state is restored.
+func (d *lxc) checkpointDetachDevices(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	err := d.VolatileSet(map[string]string{checkpointDetachedDevicesKey: strings.Join(names, ",")})
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting volatile keys")
+	}
+
+	for i, name := range names {
+		err := d.deviceStop(name, d.expandedDevices[name], true, "")
+		if err != nil {
+			d.checkpointAttachDevices(names[:i])
+			return errors.Wrapf(err, "Failed detaching device %q before checkpointing", name)
+		}
+	}
+
+	return nil
+}
+
+// checkpointAttachDevices attaches the devices detached when checkpointing back to the running container.
+// Failures are only logged as the container itself is running fine.
+func (d *lxc) checkpointAttachDevices(names []string) {
+	for _, name := range names {
+		config, ok := d.expandedDevices[name]
+		if !ok {
+			continue // Device removed since.
+		}
+
+		_, err := d.deviceStart(name, config, true)
+		if err != nil {
+			d.logger.Error("Failed attaching device after checkpoint", log.Ctx{"device": name, "err": err})
+		}
+	}
+
+	err := d.VolatileSet(map[string]string{checkpointDetachedDevicesKey: ""})
+	if err != nil {
+		d.logger.Error("Failed clearing detached devices", log.Ctx{"err": err})
+	}
+}
+
+// checkpointDiscard deletes the saved state of the container.
+func (d *lxc) checkpointDiscard() error {
+	err := os.RemoveAll(d.StatePath())
+	if err != nil {
+		return err
+	}
+
+	d.stateful = false
+	err = d.state.Cluster.UpdateInstanceStatefulFlag(d.id, false)
+	if err != nil {
+		return errors.Wrap(err, "Persist stateful flag")
+	}
+
+	return nil
+}
+
+// checkpointRestoreFailed stops the devices started for a failed restore and discards the saved state, so that
+// the container can be started statelessly. The CRIU log is kept in the container's log directory.
+func (d *lxc) checkpointRestoreFailed() {
+	detachedDevices := d.checkpointDetachedDevices()
+	for _, dev := range d.expandedDevices.Reversed() {
+		if shared.StringInSlice(dev.Name, detachedDevices) {
+			continue
+		}
+
+		err := d.deviceStop(dev.Name, dev.Config, false, "")
+		if err != nil && err != device.ErrUnsupportedDevType {
+			d.logger.Error("Failed to stop device after failed restore", log.Ctx{"device": dev.Name, "err": err})
+		}
+	}
+
+	err := d.checkpointDiscard()
+	if err != nil {
+		d.logger.Error("Failed discarding state after failed restore", log.Ctx{"err": err})
+	}
+
+	err = d.VolatileSet(map[string]string{checkpointDetachedDevicesKey: ""})
+	if err != nil {
+		d.logger.Error("Failed clearing detached devices", log.Ctx{"err": err})
+	}
+}
+
 func (d *lxc) templateApplyNow(trigger instance.TemplateTrigger) error {
 	// If there's no metadata, just return
 	fname := filepath.Join(d.Path(), "metadata.yaml")
@@ -5593,6 +6096,156 @@ func (d *lxc) FileRemove(path string) error {
 	return nil
 }
 
+// FilePullRecursive writes the tree at the given path of the instance to w as a tarball.
+func (d *lxc) FilePullRecursive(srcPath string, w io.Writer) error {
+	err := d.fileTar("pull-tar", srcPath, nil, w)
+	if err != nil {
+		return err
+	}
+
+	d.state.Events.SendLifecycle(d.project, lifecycle.InstanceFileRetrieved.Event(d, log.Ctx{"file-source": srcPath, "recursive": true}))
+	return nil
+}
+
+// FilePushRecursive extracts the tarball read from r into the given directory of the instance.
+func (d *lxc) FilePushRecursive(dstPath string, r io.Reader) error {
+	err := d.fileTar("push-tar", dstPath, r, nil)
+	if err != nil {
+		return err
+	}
+
+	d.state.Events.SendLifecycle(d.project, lifecycle.InstanceFilePushed.Event(d, log.Ctx{"file-destination": dstPath, "recursive": true}))
+	return nil
+}
+
+// FileWatch calls handler for each change made under the given path of the instance until ctx is done.
+func (d *lxc) FileWatch(ctx context.Context, path string, recursive bool, handler func(event api.InstanceFileEvent)) error {
+	if !d.IsRunning() {
+		return fmt.Errorf("Instance is not running")
+	}
+
+	pidFdNr, pidFd := d.inheritInitPidFd()
+	if pidFdNr >= 0 {
+		defer pidFd.Close()
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(
+		ctx,
+		d.state.OS.ExecPath,
+		"forkfile",
+		"watch",
+		d.RootfsPath(),
+		fmt.Sprintf("%d", d.InitPID()),
+		fmt.Sprintf("%d", pidFdNr),
+		path,
+		fmt.Sprintf("%t", recursive),
+	)
+	cmd.ExtraFiles = []*os.File{pidFd}
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(stdout)
+	for {
+		event := api.InstanceFileEvent{}
+		err = decoder.Decode(&event)
+		if err != nil {
+			break
+		}
+
+		handler(event)
+	}
+
+	err = cmd.Wait()
+	if err != nil && ctx.Err() == nil {
+		msg := strings.TrimPrefix(strings.TrimSpace(stderr.String()), "Error: ")
+		if msg == "" {
+			return err
+		}
+
+		return fmt.Errorf("%s", msg)
+	}
+
+	return nil
+}
+
+// fileTar runs a forkfile tarball subcommand on the given path, attached to the container.
+func (d *lxc) fileTar(command string, path string, stdin io.Reader, stdout io.Writer) error {
+	// Check for ongoing operations (that may involve shifting).
+	operationlock.Get(d.id).Wait()
+
+	// Stopped containers are accessed from the host, so let forkfile shift the IDs.
+	idmapJSON := ""
+	if !d.IsRunning() {
+		idmapset, err := d.DiskIdmap()
+		if err != nil {
+			return err
+		}
+
+		if idmapset != nil {
+			idmapJSON, err = idmap.JSONMarshal(idmapset)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Setup container storage if needed
+	_, err := d.mount()
+	if err != nil {
+		return err
+	}
+	defer d.unmount()
+
+	pidFdNr, pidFd := d.inheritInitPidFd()
+	if pidFdNr >= 0 {
+		defer pidFd.Close()
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(
+		d.state.OS.ExecPath,
+		"forkfile",
+		command,
+		d.RootfsPath(),
+		fmt.Sprintf("%d", d.InitPID()),
+		fmt.Sprintf("%d", pidFdNr),
+		path,
+		idmapJSON,
+	)
+	cmd.ExtraFiles = []*os.File{pidFd}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		msg := strings.TrimPrefix(strings.TrimSpace(stderr.String()), "Error: ")
+		if msg == "" {
+			return err
+		}
+
+		if strings.Contains(msg, "no such file or directory") {
+			return os.ErrNotExist
+		}
+
+		return fmt.Errorf("%s", msg)
+	}
+
+	return nil
+}
+
 // Console attaches to the instance console.
 func (d *lxc) Console(protocol string) (*os.File, chan error, error) {
 	if protocol != instance.ConsoleTypeConsole {
@@ -5669,6 +6322,42 @@ func (d *lxc) ConsoleLog(opts liblxc.ConsoleLogOptions) (string, error) {
 	return string(msg), nil
 }
 
+// consoleLogTrack makes the console log task capture the console output of the started container, if it has a
+// console ringbuffer.
+func (d *lxc) consoleLogTrack() {
+	if !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) {
+		return
+	}
+
+	consolelog.Track(d.LogPath(), d.ConsoleLogCapture)
+}
+
+// ConsoleLogCapture moves the output accumulated in the console ringbuffer of the running container to its
+// persistent console log.
+func (d *lxc) ConsoleLogCapture() error {
+	if !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) || !d.IsRunning() {
+		return nil
+	}
+
+	err := d.initLXC(false)
+	if err != nil {
+		return err
+	}
+
+	// Reading and clearing the ringbuffer happens in a single request so no output is lost in between.
+	msg, err := d.c.ConsoleLog(liblxc.ConsoleLogOptions{ReadLog: true, ClearLog: true})
+	if err != nil {
+		errno, isErrno := shared.GetErrno(err)
+		if isErrno && errno == unix.ENODATA {
+			return nil
+		}
+
+		return err
+	}
+
+	return consolelog.Get(d.LogPath()).Write(time.Now(), msg)
+}
+
 // Exec executes a command inside the instance.
 func (d *lxc) Exec(req api.InstanceExecPost, stdin *os.File, stdout *os.File, stderr *os.File) (instance.Cmd, error) {
 	// Prepare the environment
@@ -5776,13 +6465,13 @@ func (d *lxc) cpuState() api.InstanceStateCPU {
 		return cpu
 	}
 
-	value, err := cg.GetCPUAcctUsage()
+	stats, err := d.cgroupStats()
 	if err != nil {
 		cpu.Usage = -1
 		return cpu
 	}
 
-	cpu.Usage = value
+	cpu.Usage = stats.CPUUsage
 
 	return cpu
 }
@@ -5846,35 +6535,30 @@ func (d *lxc) memoryState() api.InstanceStateMemory {
 		return memory
 	}
 
+	stats, err := d.cgroupStats()
+	if err != nil {
+		return memory
+	}
+
 	// Memory in bytes
-	value, err := cg.GetMemoryUsage()
-	if err == nil {
-		memory.Usage = value
+	if stats.MemoryUsage >= 0 {
+		memory.Usage = stats.MemoryUsage
 	}
 
 	// Memory peak in bytes
-	if d.state.OS.CGInfo.Supports(cgroup.MemoryMaxUsage, cg) {
-		value, err = cg.GetMemoryMaxUsage()
-		if err == nil {
-			memory.UsagePeak = value
-		}
+	if d.state.OS.CGInfo.Supports(cgroup.MemoryMaxUsage, cg) && stats.MemoryUsagePeak >= 0 {
+		memory.UsagePeak = stats.MemoryUsagePeak
 	}
 
 	if d.state.OS.CGInfo.Supports(cgroup.MemorySwapUsage, cg) {
 		// Swap in bytes
-		if memory.Usage > 0 {
-			value, err := cg.GetMemorySwapUsage()
-			if err == nil {
-				memory.SwapUsage = value
-			}
+		if memory.Usage > 0 && stats.SwapUsage >= 0 {
+			memory.SwapUsage = stats.SwapUsage
 		}
 
 		// Swap peak in bytes
-		if memory.UsagePeak > 0 {
-			value, err = cg.GetMemorySwapMaxUsage()
-			if err == nil {
-				memory.SwapUsagePeak = value
-			}
+		if memory.UsagePeak > 0 && stats.SwapUsagePeak >= 0 {
+			memory.SwapUsagePeak = stats.SwapUsagePeak
 		}
 	}
 
@@ -5945,6 +6629,39 @@ func (d *lxc) networkState() map[string]api.InstanceStateNetwork {
 		}
 	}
 
+	// Add the link speed of the NICs which aren't backed by a veth pair from their host-side state.
+	for k, m := range d.ExpandedDevices() {
+		if m["type"] != "nic" {
+			continue
+		}
+
+		netStatus, found := result[m["name"]]
+		if !found {
+			continue
+		}
+
+		dev, _, err := d.deviceLoad(k, m)
+		if err != nil {
+			continue
+		}
+
+		nic, ok := dev.(device.NICHostState)
+		if !ok {
+			continue
+		}
+
+		hostState, err := nic.HostState()
+		if err != nil {
+			d.logger.Warn("Failed getting NIC host state", log.Ctx{"device": k, "err": err})
+			continue
+		}
+
+		if hostState != nil {
+			netStatus.LinkSpeed = hostState.LinkSpeed
+			result[m["name"]] = netStatus
+		}
+	}
+
 	return result
 }
 
@@ -5961,12 +6678,12 @@ func (d *lxc) processesState() int64 {
 	}
 
 	if d.state.OS.CGInfo.Supports(cgroup.Pids, cg) {
-		value, err := cg.GetProcessesUsage()
+		stats, err := d.cgroupStats()
 		if err != nil {
 			return -1
 		}
 
-		return value
+		return stats.Processes
 	}
 
 	pids := []int64{int64(pid)}
@@ -6343,7 +7060,7 @@ func (d *lxc) removeUnixDevices() error {
 	// Go through all the unix devices
 	for _, f := range dents {
 		// Skip non-Unix devices
-		if !strings.HasPrefix(f.Name(), "forkmknod.unix.") && !strings.HasPrefix(f.Name(), "unix.") && !strings.HasPrefix(f.Name(), "infiniband.unix.") {
+		if !strings.HasPrefix(f.Name(), "forkmknod.unix.") && !strings.HasPrefix(f.Name(), "unix.") && !strings.HasPrefix(f.Name(), "infiniband.unix.") && !strings.HasPrefix(f.Name(), "kvm.") {
 			continue
 		}
 
@@ -6492,6 +7209,16 @@ func (d *lxc) FillNetworkDevice(name string, m deviceConfig.Device) (deviceConfi
 		newDevice["name"] = volatileName
 	}
 
+	// Fill in the addresses allocated by the network's IPAM backend, unless set statically.
+	if nicType == "bridged" {
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			address := d.localConfig[fmt.Sprintf("volatile.%s.ipam.%s", name, key)]
+			if m[key] == "" && address != "" {
+				newDevice[key] = address
+			}
+		}
+	}
+
 	return newDevice, nil
 }
 
@@ -6751,6 +7478,22 @@ func (d *lxc) cgroup(cc *liblxc.Container) (*cgroup.CGroup, error) {
 	return cg, nil
 }
 
+// cgroupStats returns the resource usage of the running container. It's read through the daemon wide collector, so
+// rendering the state of many containers only reads cgroupfs once per interval rather than once per request.
+func (d *lxc) cgroupStats() (cgroup.Stats, error) {
+	pid := d.InitPID()
+	if pid < 1 {
+		return cgroup.Stats{}, fmt.Errorf("The container isn't running")
+	}
+
+	cname := project.Instance(d.Project(), d.Name())
+	key := fmt.Sprintf("%s/%d", cname, pid)
+
+	return d.state.InstanceStats.Get(key, func() (*cgroup.CGroup, error) {
+		return cgroup.NewContainerFileReadWriter(pid, cname, liblxc.HasApiExtension("cgroup2"))
+	})
+}
+
 type lxcCgroupReadWriter struct {
 	cc   *liblxc.Container
 	conf bool
@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -31,17 +34,20 @@ import (
 	"github.com/lxc/lxd/lxd/apparmor"
 	"github.com/lxc/lxd/lxd/cgroup"
 	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/consolelog"
 	"github.com/lxc/lxd/lxd/db"
 	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/device"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/device/nictype"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/drivers/edk2"
 	"github.com/lxc/lxd/lxd/instance/drivers/qmp"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/operationlock"
 	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/revert"
@@ -63,6 +69,7 @@ import (
 	"github.com/lxc/lxd/shared/subprocess"
 	"github.com/lxc/lxd/shared/termios"
 	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/version"
 )
 
 // qemuAsyncIO is used to indicate disk should use unsafe cache I/O.
@@ -80,6 +87,9 @@ const qemuPCIDeviceIDStart = 4
 // qemuDeviceIDPrefix used as part of the name given QEMU devices generated from user added devices.
 const qemuDeviceIDPrefix = "dev-lxd_"
 
+// qemuDriveIDPrefix used as part of the name given QEMU drives generated from user added devices.
+const qemuDriveIDPrefix = "lxd_"
+
 // qemuNetDevIDPrefix used as part of the name given QEMU netdevs generated from user added devices.
 const qemuNetDevIDPrefix = "lxd_"
 
@@ -635,6 +645,13 @@ func (d *qemu) onStop(target string) error {
 	// Reset timeout to 30s.
 	op.Reset()
 
+	// Capture the last console output.
+	consolelog.Untrack(d.LogPath())
+	err = d.ConsoleLogCapture()
+	if err != nil {
+		d.logger.Warn("Failed capturing console output", log.Ctx{"err": err})
+	}
+
 	// Cleanup.
 	d.cleanupDevices() // Must be called before unmount.
 	os.Remove(d.pidFilePath())
@@ -1006,6 +1023,18 @@ func (d *qemu) Start(stateful bool) error {
 		volatileSet["volatile.uuid"] = instUUID
 	}
 
+	// Pin the machine type on first start and check that QEMU still supports it (do this before
+	// UpdateBackupFile() call).
+	machineType, err := d.startMachineType()
+	if err != nil {
+		op.Done(err)
+		return err
+	}
+
+	if d.localConfig["volatile.machine_type"] != machineType {
+		volatileSet["volatile.machine_type"] = machineType
+	}
+
 	// Apply any volatile changes that need to be made.
 	err = d.VolatileSet(volatileSet)
 	if err != nil {
@@ -1158,6 +1187,12 @@ func (d *qemu) Start(stateful bool) error {
 		return err
 	}
 
+	cpuModel, err := d.cpuModel()
+	if err != nil {
+		op.Done(err)
+		return err
+	}
+
 	// Start QEMU.
 	qemuCmd := []string{
 		"--",
@@ -1166,7 +1201,7 @@ func (d *qemu) Start(stateful bool) error {
 		"-name", d.Name(),
 		"-uuid", instUUID,
 		"-daemonize",
-		"-cpu", "host",
+		"-cpu", cpuModel,
 		"-nographic",
 		"-serial", "chardev:console",
 		"-nodefaults",
@@ -1361,42 +1396,79 @@ func (d *qemu) Start(stateful bool) error {
 		return err
 	}
 
-	// Apply CPU pinning.
-	cpuLimit, ok := d.expandedConfig["limits.cpu"]
-	if ok && cpuLimit != "" {
-		_, err := strconv.Atoi(cpuLimit)
+	// Apply CPU pinning. A VM without a CPU limit has a single vCPU which still needs restricting to the
+	// selected NUMA nodes.
+	cpuLimit := d.expandedConfig["limits.cpu"]
+	if cpuLimit == "" {
+		cpuLimit = "1"
+	}
+
+	_, err = strconv.Atoi(cpuLimit)
+	if err == nil && d.expandedConfig["limits.cpu.nodes"] != "" {
+		// Restrict the vCPUs to the CPUs of the selected NUMA nodes.
+		nodes, err := resources.ParseCpuset(d.expandedConfig["limits.cpu.nodes"])
 		if err != nil {
-			// Expand to a set of CPU identifiers and get the pinning map.
-			_, _, _, pins, _, err := d.cpuTopology(cpuLimit)
-			if err != nil {
-				op.Done(err)
-				return err
-			}
+			op.Done(err)
+			return err
+		}
+
+		nodeCPUs, err := resources.GetNUMANodeCPUs(nodes)
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+
+		// Get the list of PIDs from the VM.
+		pids, err := monitor.GetCPUs()
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+
+		set := unix.CPUSet{}
+		for _, cpu := range nodeCPUs {
+			set.Set(int(cpu))
+		}
 
-			// Get the list of PIDs from the VM.
-			pids, err := monitor.GetCPUs()
+		for _, pid := range pids {
+			// Apply the pin.
+			err := unix.SchedSetaffinity(pid, &set)
 			if err != nil {
 				op.Done(err)
 				return err
 			}
+		}
+	} else if err != nil {
+		// Expand to a set of CPU identifiers and get the pinning map.
+		_, _, _, pins, _, err := d.cpuTopology(cpuLimit)
+		if err != nil {
+			op.Done(err)
+			return err
+		}
 
-			// Confirm nothing weird is going on.
-			if len(pins) != len(pids) {
-				err = fmt.Errorf("QEMU has less vCPUs than configured")
-				op.Done(err)
-				return err
-			}
+		// Get the list of PIDs from the VM.
+		pids, err := monitor.GetCPUs()
+		if err != nil {
+			op.Done(err)
+			return err
+		}
 
-			for i, pid := range pids {
-				set := unix.CPUSet{}
-				set.Set(int(pins[uint64(i)]))
+		// Confirm nothing weird is going on.
+		if len(pins) != len(pids) {
+			err = fmt.Errorf("QEMU has less vCPUs than configured")
+			op.Done(err)
+			return err
+		}
 
-				// Apply the pin.
-				err := unix.SchedSetaffinity(pid, &set)
-				if err != nil {
-					op.Done(err)
-					return err
-				}
+		for i, pid := range pids {
+			set := unix.CPUSet{}
+			set.Set(int(pins[uint64(i)]))
+
+			// Apply the pin.
+			err := unix.SchedSetaffinity(pid, &set)
+			if err != nil {
+				op.Done(err)
+				return err
 			}
 		}
 	}
@@ -1486,10 +1558,18 @@ func (d *qemu) Start(stateful bool) error {
 		return err
 	}
 
+	// Make the console log task capture the console output.
+	consolelog.Track(d.LogPath(), d.ConsoleLogCapture)
+
 	if op.Action() == "start" {
 		d.state.Events.SendLifecycle(d.project, lifecycle.InstanceStarted.Event(d, nil))
 	}
 
+	// Apply the clone customization hooks on the first start of a copy.
+	if shared.IsTrue(d.localConfig["volatile.clone_pending"]) {
+		go d.cloneCustomize(d)
+	}
+
 	return nil
 }
 
@@ -1681,6 +1761,16 @@ func (d *qemu) deviceStart(deviceName string, rawConfig deviceConfig.Device, ins
 				}
 			}
 
+			// Attach disk if requested.
+			for _, mount := range runConf.Mounts {
+				err = d.deviceAttachBlockDevice(deviceName, mount)
+				if err != nil {
+					return nil, err
+				}
+
+				revert.Add(func() { d.deviceDetachBlockDevice(deviceName) })
+			}
+
 			// If running, run post start hooks now (if not running LXD will run them
 			// once the instance is started).
 			err = d.runHooks(runConf.PostHooks)
@@ -1760,6 +1850,105 @@ func (d *qemu) deviceAttachNIC(deviceName string, configCopy map[string]string,
 	return nil
 }
 
+// deviceAttachBlockDevice live attaches a block device to the instance.
+func (d *qemu) deviceAttachBlockDevice(deviceName string, mount deviceConfig.MountEntryItem) error {
+	if !shared.IsBlockdevPath(mount.DevPath) {
+		return fmt.Errorf("Only block devices can be attached to a running instance")
+	}
+
+	// Check if the agent is running.
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	bootIndexes, err := d.deviceBootPriorities()
+	if err != nil {
+		return errors.Wrap(err, "Error calculating boot indexes")
+	}
+
+	readonly := shared.StringInSlice("ro", mount.Opts)
+
+	// Pass the device to QEMU by file descriptor as the AppArmor profile doesn't allow its path.
+	flags := os.O_RDWR
+	if readonly {
+		flags = os.O_RDONLY
+	}
+
+	f, err := os.OpenFile(mount.DevPath, flags, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening block device %q", mount.DevPath)
+	}
+	defer f.Close()
+
+	nodeName := fmt.Sprintf("%s%s", qemuDriveIDPrefix, deviceName)
+
+	fdSetID, err := monitor.AddFDSet(nodeName, f)
+	if err != nil {
+		return err
+	}
+
+	blockDev := map[string]interface{}{
+		"driver":    "raw",
+		"node-name": nodeName,
+		"read-only": readonly,
+		"discard":   "unmap",
+		"cache": map[string]interface{}{
+			"direct":   true,
+			"no-flush": false,
+		},
+		"file": map[string]interface{}{
+			"driver":   "host_device",
+			"filename": fmt.Sprintf("/dev/fdset/%d", fdSetID),
+			"aio":      "native",
+			"locking":  "off",
+		},
+	}
+
+	// Matches the drive config used when the instance is started.
+	qemuDev := map[string]string{
+		"driver":    "scsi-hd",
+		"id":        fmt.Sprintf("%s%s", qemuDeviceIDPrefix, deviceName),
+		"bus":       "qemu_scsi.0",
+		"channel":   "0",
+		"scsi-id":   fmt.Sprintf("%d", bootIndexes[deviceName]),
+		"lun":       "1",
+		"drive":     nodeName,
+		"bootindex": fmt.Sprintf("%d", bootIndexes[deviceName]),
+	}
+
+	serial := qemuDriveSerial(mount)
+	if serial != "" {
+		qemuDev["serial"] = serial
+	}
+
+	err = monitor.AddBlockDevice(blockDev, qemuDev)
+	if err != nil {
+		monitor.RemoveFDSet(nodeName)
+		return err
+	}
+
+	return nil
+}
+
+// deviceDetachBlockDevice detaches a block device from a running instance.
+func (d *qemu) deviceDetachBlockDevice(deviceName string) error {
+	// Check if the agent is running.
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	nodeName := fmt.Sprintf("%s%s", qemuDriveIDPrefix, deviceName)
+
+	err = monitor.RemoveBlockDevice(nodeName, fmt.Sprintf("%s%s", qemuDeviceIDPrefix, deviceName))
+	if err != nil {
+		return err
+	}
+
+	return monitor.RemoveFDSet(nodeName)
+}
+
 // deviceStop loads a new device and calls its Stop() function.
 func (d *qemu) deviceStop(deviceName string, rawConfig deviceConfig.Device, instanceRunning bool) error {
 	logger := logging.AddContext(d.logger, log.Ctx{"device": deviceName, "type": rawConfig["type"]})
@@ -1802,6 +1991,14 @@ func (d *qemu) deviceStop(deviceName string, rawConfig deviceConfig.Device, inst
 					return err
 				}
 			}
+
+			// Detach disk from running instance.
+			if rawConfig["type"] == "disk" && instanceRunning {
+				err = d.deviceDetachBlockDevice(deviceName)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 		// Run post stop hooks irrespective of run state of instance.
@@ -1956,62 +2153,67 @@ func (d *qemu) generateConfigShare() error {
 		return err
 	}
 
-	// Add the VM agent.
-	lxdAgentSrcPath, err := exec.LookPath("lxd-agent")
-	if err != nil {
-		d.logger.Warn("lxd-agent not found, skipping its inclusion in the VM config drive", log.Ctx{"err": err})
-	} else {
-		// Install agent into config drive dir if found.
-		lxdAgentSrcPath, err = filepath.EvalSymlinks(lxdAgentSrcPath)
-		if err != nil {
-			return err
-		}
-
-		lxdAgentSrcInfo, err := os.Stat(lxdAgentSrcPath)
+	// Add the VM agents, lxd-agent.exe being the build for Windows guests.
+	for _, agentName := range []string{"lxd-agent", "lxd-agent.exe"} {
+		lxdAgentSrcPath, err := exec.LookPath(agentName)
 		if err != nil {
-			return errors.Wrapf(err, "Failed getting info for lxd-agent source %q", lxdAgentSrcPath)
-		}
-
-		lxdAgentInstallPath := filepath.Join(configDrivePath, "lxd-agent")
-		lxdAgentNeedsInstall := true
-
-		if shared.PathExists(lxdAgentInstallPath) {
-			lxdAgentInstallInfo, err := os.Stat(lxdAgentInstallPath)
-			if err != nil {
-				return errors.Wrapf(err, "Failed getting info for existing lxd-agent install %q", lxdAgentInstallPath)
+			// The Windows agent is optional.
+			if agentName == "lxd-agent" {
+				d.logger.Warn("lxd-agent not found, skipping its inclusion in the VM config drive", log.Ctx{"err": err})
 			}
-
-			if lxdAgentInstallInfo.ModTime() == lxdAgentSrcInfo.ModTime() && lxdAgentInstallInfo.Size() == lxdAgentSrcInfo.Size() {
-				lxdAgentNeedsInstall = false
-			}
-		}
-
-		// Only install the lxd-agent into config drive if the existing one is different to the source one.
-		// Otherwise we would end up copying it again and this can cause unnecessary snapshot usage.
-		if lxdAgentNeedsInstall {
-			d.logger.Debug("Installing lxd-agent", log.Ctx{"srcPath": lxdAgentSrcPath, "installPath": lxdAgentInstallPath})
-			err = shared.FileCopy(lxdAgentSrcPath, lxdAgentInstallPath)
+		} else {
+			// Install agent into config drive dir if found.
+			lxdAgentSrcPath, err = filepath.EvalSymlinks(lxdAgentSrcPath)
 			if err != nil {
 				return err
 			}
 
-			err = os.Chmod(lxdAgentInstallPath, 0500)
+			lxdAgentSrcInfo, err := os.Stat(lxdAgentSrcPath)
 			if err != nil {
-				return err
+				return errors.Wrapf(err, "Failed getting info for %s source %q", agentName, lxdAgentSrcPath)
 			}
 
-			err = os.Chown(lxdAgentInstallPath, 0, 0)
-			if err != nil {
-				return err
+			lxdAgentInstallPath := filepath.Join(configDrivePath, agentName)
+			lxdAgentNeedsInstall := true
+
+			if shared.PathExists(lxdAgentInstallPath) {
+				lxdAgentInstallInfo, err := os.Stat(lxdAgentInstallPath)
+				if err != nil {
+					return errors.Wrapf(err, "Failed getting info for existing %s install %q", agentName, lxdAgentInstallPath)
+				}
+
+				if lxdAgentInstallInfo.ModTime() == lxdAgentSrcInfo.ModTime() && lxdAgentInstallInfo.Size() == lxdAgentSrcInfo.Size() {
+					lxdAgentNeedsInstall = false
+				}
 			}
 
-			// Ensure we copy the source file's timestamps so they can be used for comparison later.
-			err = os.Chtimes(lxdAgentInstallPath, lxdAgentSrcInfo.ModTime(), lxdAgentSrcInfo.ModTime())
-			if err != nil {
-				return errors.Wrapf(err, "Failed setting lxd-agent timestamps")
+			// Only install the agent into config drive if the existing one is different to the source one.
+			// Otherwise we would end up copying it again and this can cause unnecessary snapshot usage.
+			if lxdAgentNeedsInstall {
+				d.logger.Debug("Installing agent", log.Ctx{"agent": agentName, "srcPath": lxdAgentSrcPath, "installPath": lxdAgentInstallPath})
+				err = shared.FileCopy(lxdAgentSrcPath, lxdAgentInstallPath)
+				if err != nil {
+					return err
+				}
+
+				err = os.Chmod(lxdAgentInstallPath, 0500)
+				if err != nil {
+					return err
+				}
+
+				err = os.Chown(lxdAgentInstallPath, 0, 0)
+				if err != nil {
+					return err
+				}
+
+				// Ensure we copy the source file's timestamps so they can be used for comparison later.
+				err = os.Chtimes(lxdAgentInstallPath, lxdAgentSrcInfo.ModTime(), lxdAgentSrcInfo.ModTime())
+				if err != nil {
+					return errors.Wrapf(err, "Failed setting %s timestamps", agentName)
+				}
+			} else {
+				d.logger.Debug("Skipping agent install as unchanged", log.Ctx{"agent": agentName, "srcPath": lxdAgentSrcPath, "installPath": lxdAgentInstallPath})
 			}
-		} else {
-			d.logger.Debug("Skipping lxd-agent install as unchanged", log.Ctx{"srcPath": lxdAgentSrcPath, "installPath": lxdAgentInstallPath})
 		}
 	}
 
@@ -2160,6 +2362,40 @@ echo "To start it now, unmount this filesystem and run: systemctl start lxd-agen
 		return err
 	}
 
+	// Install script for Windows guests, the agent is registered as a service using a copy of the config drive
+	// files as the share is only mounted once the virtio-win virtiofs service runs.
+	lxdConfigShareInstallWindows := `$ErrorActionPreference = "Stop"
+
+if (!(Test-Path (Join-Path $PSScriptRoot "lxd-agent.exe"))) {
+    Write-Error "This script must be run from within the config drive share"
+}
+
+$dir = Join-Path $env:ProgramData "LXD\agent"
+New-Item -ItemType Directory -Force -Path $dir | Out-Null
+icacls $dir /inheritance:r /grant:r "SYSTEM:(OI)(CI)F" "Administrators:(OI)(CI)F" | Out-Null
+
+# Cleanup former service.
+if (Get-Service lxd-agent -ErrorAction SilentlyContinue) {
+    Stop-Service lxd-agent
+    sc.exe delete lxd-agent | Out-Null
+}
+
+# Install the agent and its certificates.
+foreach ($file in "lxd-agent.exe", "server.crt", "agent.crt", "agent.key") {
+    Copy-Item -Force (Join-Path $PSScriptRoot $file) $dir
+}
+
+New-Service -Name lxd-agent -DisplayName "LXD agent" -StartupType Automatic -BinaryPathName (Join-Path $dir "lxd-agent.exe") | Out-Null
+Start-Service lxd-agent
+
+Write-Output "LXD agent has been installed and started."
+`
+
+	err = ioutil.WriteFile(filepath.Join(configDrivePath, "install.ps1"), []byte(lxdConfigShareInstallWindows), 0400)
+	if err != nil {
+		return err
+	}
+
 	// Instance data for devlxd.
 	err = d.writeInstanceData()
 	if err != nil {
@@ -2369,13 +2605,45 @@ func (d *qemu) generateQemuConfigFile(mountInfo *storagePools.MountInfo, busName
 	var sb *strings.Builder = &strings.Builder{}
 	var monHooks []monitorHook
 
+	// Check that the host can run the VM with the requested memory encryption.
+	sevMode := d.sevMode()
+	var sev *api.ResourcesCPUSEV
+	if sevMode != "" {
+		var err error
+		sev, err = d.sevCapabilities(sevMode)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
 	err := qemuBase.Execute(sb, map[string]interface{}{
-		"architecture": d.architectureName,
+		"architecture":   d.architectureName,
+		"machineType":    d.localConfig["volatile.machine_type"],
+		"consoleLogPath": d.ConsoleBufferLogPath(),
+		"sev":            sev != nil,
 	})
 	if err != nil {
 		return "", nil, err
 	}
 
+	if sev != nil {
+		// QEMU requires at least one bit of physical address space reduction.
+		reducedPhysBits := sev.ReducedPhysBits
+		if reducedPhysBits == 0 {
+			reducedPhysBits = 1
+		}
+
+		err = qemuSEV.Execute(sb, map[string]interface{}{
+			"snp":             sevMode == "sev-snp",
+			"cbitpos":         sev.CBitPosition,
+			"reducedPhysBits": reducedPhysBits,
+			"policy":          qemuSEVPolicy(sevMode),
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
 	cpuCount, err := d.addCPUMemoryConfig(sb)
 	if err != nil {
 		return "", nil, err
@@ -2573,6 +2841,8 @@ func (d *qemu) generateQemuConfigFile(mountInfo *storagePools.MountInfo, busName
 					err = d.addRootDriveConfig(sb, mountInfo, bootIndexes, drive)
 				} else if drive.FSType == "9p" {
 					err = d.addDriveDirConfig(sb, bus, fdFiles, &agentMounts, drive)
+				} else if strings.HasPrefix(drive.DevPath, "nvme://") {
+					err = d.addDriveNVMeConfig(sb, bus, bootIndexes, drive)
 				} else {
 					err = d.addDriveConfig(sb, bootIndexes, drive)
 				}
@@ -2641,6 +2911,44 @@ func (d *qemu) generateQemuConfigFile(mountInfo *storagePools.MountInfo, busName
 
 	}
 
+	// Attach the virtio-win drivers ISO so the drivers can be installed during Windows setup.
+	if shared.IsTrue(d.expandedConfig["boot.virtio_drivers"]) {
+		var isoPath string
+		err = d.state.Node.Transaction(func(tx *db.NodeTx) error {
+			config, err := node.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			isoPath = config.InstancesVirtioDriversISO()
+			return nil
+		})
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Failed to load member configuration")
+		}
+
+		if isoPath == "" {
+			return "", nil, fmt.Errorf("The instances.virtio_drivers_iso server setting must be set to use boot.virtio_drivers")
+		}
+
+		if !shared.PathExists(isoPath) {
+			return "", nil, fmt.Errorf("The virtio drivers ISO %q doesn't exist", isoPath)
+		}
+
+		// Give it the lowest boot priority, its index is also used as its SCSI ID so must be unique.
+		bootIndexes["virtio-drivers"] = len(bootIndexes)
+
+		err = d.addDriveConfig(sb, bootIndexes, deviceConfig.MountEntryItem{
+			DevName: "virtio-drivers",
+			DevPath: isoPath,
+			FSType:  "iso9660",
+			Opts:    []string{"ro"},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
 	// Allocate 4 PCI slots for hotplug devices.
 	for i := 0; i < 4; i++ {
 		bus.allocate(busFunctionGroupNone)
@@ -2685,6 +2993,9 @@ func (d *qemu) addCPUMemoryConfig(sb *strings.Builder) (int, error) {
 		ctx["cpuCores"] = cpuCount
 		ctx["cpuThreads"] = 1
 		hostNodes = []uint64{0}
+
+		// Bind the memory to the selected NUMA nodes.
+		ctx["memoryHostNodes"] = d.expandedConfig["limits.cpu.nodes"]
 	} else {
 		// Expand to a set of CPU identifiers and get the pinning map.
 		nrSockets, nrCores, nrThreads, vcpus, numaNodes, err := d.cpuTopology(cpus)
@@ -2942,6 +3253,11 @@ func (d *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int, d
 		}
 	}
 
+	// ISO volumes are attached as cdroms whether they're backed by a file or a block device.
+	if driveConf.FSType == "iso9660" {
+		media = "cdrom"
+	}
+
 	if !strings.HasPrefix(driveConf.DevPath, "rbd:") {
 		// Add path to external devPaths. This way, the path will be included in the apparmor profile.
 		d.devPaths = append(d.devPaths, driveConf.DevPath)
@@ -2956,9 +3272,73 @@ func (d *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int, d
 		"media":     media,
 		"shared":    driveConf.TargetPath != "/" && !strings.HasPrefix(driveConf.DevPath, "rbd:"),
 		"readonly":  readonly,
+		"serial":    qemuDriveSerial(driveConf),
 	})
 }
 
+// qemuDriveSerial returns the serial number to expose to the guest for the drive, if any.
+func qemuDriveSerial(driveConf deviceConfig.MountEntryItem) string {
+	for _, opt := range driveConf.Opts {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) == 2 && parts[0] == device.DiskSerialMountOpt {
+			return parts[1]
+		}
+	}
+
+	return ""
+}
+
+// addDriveNVMeConfig adds the qemu config required for passing an NVMe namespace to the VM.
+func (d *qemu) addDriveNVMeConfig(sb *strings.Builder, bus *qemuBus, bootIndexes map[string]int, driveConf deviceConfig.MountEntryItem) error {
+	tplFields := map[string]interface{}{
+		"bus":       bus.name,
+		"devName":   driveConf.DevName,
+		"devPath":   driveConf.DevPath, // Accessed through vfio, so don't add to d.devPaths for apparmor access.
+		"bootIndex": bootIndexes[driveConf.DevName],
+		"readonly":  shared.StringInSlice("ro", driveConf.Opts),
+	}
+
+	var vfioGroup string
+	for _, opt := range driveConf.Opts {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case device.DiskVFIOGroupMountOpt:
+			vfioGroup = parts[1]
+		case "nvme.queues":
+			tplFields["queues"] = parts[1]
+		case "nvme.queue_size":
+			tplFields["queueSize"] = parts[1]
+		case "nvme.vectors":
+			tplFields["vectors"] = parts[1]
+		case "nvme.iothread":
+			tplFields["iothread"] = shared.IsTrue(parts[1])
+		}
+	}
+
+	if d.state.OS.UnprivUser != "" {
+		if vfioGroup == "" {
+			return fmt.Errorf("No PCI IOMMU group supplied")
+		}
+
+		vfioGroupFile := fmt.Sprintf("/dev/vfio/%s", vfioGroup)
+		err := os.Chown(vfioGroupFile, int(d.state.OS.UnprivUID), -1)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to chown vfio group device %q", vfioGroupFile)
+		}
+	}
+
+	devBus, devAddr, multi := bus.allocate(fmt.Sprintf("lxd_%s", driveConf.DevName))
+	tplFields["devBus"] = devBus
+	tplFields["devAddr"] = devAddr
+	tplFields["multifunction"] = multi
+
+	return qemuDriveNVMe.Execute(sb, tplFields)
+}
+
 // addNetDevConfig adds the qemu config required for adding a network device.
 // The qemuDev map is expected to be preconfigured with the settings for an existing port to use for the device.
 func (d *qemu) addNetDevConfig(cpuCount int, busName string, qemuDev map[string]string, bootIndexes map[string]int, nicConfig []deviceConfig.RunConfigItem) (monitorHook, error) {
@@ -3985,7 +4365,7 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 	}
 
 	// If apparmor changed, re-validate the apparmor profile (even if not running).
-	if shared.StringInSlice("raw.apparmor", changedConfig) {
+	if shared.StringInSlice("raw.apparmor", changedConfig) || shared.StringInSlice("raw.apparmor.extra", changedConfig) {
 		err = apparmor.InstanceValidate(d.state, d)
 		if err != nil {
 			return errors.Wrap(err, "Parse AppArmor profile")
@@ -4028,7 +4408,7 @@ func (d *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 
 	// Update MAAS (must run after the MAC addresses have been generated).
 	updateMAAS := false
-	for _, key := range []string{"maas.subnet.ipv4", "maas.subnet.ipv6", "ipv4.address", "ipv6.address"} {
+	for _, key := range []string{"maas.subnet.ipv4", "maas.subnet.ipv6", "maas.ipv4.address", "maas.ipv6.address", "ipv4.address", "ipv6.address"} {
 		if shared.StringInSlice(key, allUpdatedKeys) {
 			updateMAAS = true
 			break
@@ -4404,8 +4784,15 @@ func (d *qemu) Delete(force bool) error {
 	d.logger.Info("Deleting instance", ctxMap)
 
 	// Check if instance is delete protected.
-	if !force && shared.IsTrue(d.expandedConfig["security.protection.delete"]) && !d.IsSnapshot() {
-		return fmt.Errorf("Instance is protected")
+	if !force && !d.IsSnapshot() {
+		protected, err := d.isProtected("delete")
+		if err != nil {
+			return err
+		}
+
+		if protected {
+			return fmt.Errorf("Instance is protected")
+		}
 	}
 
 	// Delete any persistent warnings for instance.
@@ -4925,12 +5312,129 @@ func (d *qemu) FileRemove(path string) error {
 	return nil
 }
 
-// Console gets access to the instance's console.
-func (d *qemu) Console(protocol string) (*os.File, chan error, error) {
-	switch protocol {
-	case instance.ConsoleTypeConsole:
-		return d.console()
-	case instance.ConsoleTypeVGA:
+// FilePullRecursive writes the tree at the given path of the instance to w as a tarball.
+func (d *qemu) FilePullRecursive(srcPath string, w io.Writer) error {
+	client, err := d.getAgentClient()
+	if err != nil {
+		return err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		d.logger.Error("Failed to connect to lxd-agent", log.Ctx{"err": err})
+		return fmt.Errorf("Failed to connect to lxd-agent")
+	}
+	defer agent.Disconnect()
+
+	content, err := agent.GetInstanceFileRecursive("", srcPath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		return err
+	}
+
+	d.state.Events.SendLifecycle(d.project, lifecycle.InstanceFileRetrieved.Event(d, log.Ctx{"file-source": srcPath, "recursive": true}))
+	return nil
+}
+
+// FilePushRecursive extracts the tarball read from r into the given directory of the instance.
+func (d *qemu) FilePushRecursive(dstPath string, r io.Reader) error {
+	client, err := d.getAgentClient()
+	if err != nil {
+		return err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		d.logger.Error("Failed to connect to lxd-agent", log.Ctx{"err": err})
+		return fmt.Errorf("Failed to connect to lxd-agent")
+	}
+	defer agent.Disconnect()
+
+	err = agent.CreateInstanceFileRecursive("", dstPath, r)
+	if err != nil {
+		return err
+	}
+
+	d.state.Events.SendLifecycle(d.project, lifecycle.InstanceFilePushed.Event(d, log.Ctx{"file-destination": dstPath, "recursive": true}))
+	return nil
+}
+
+// FileWatch calls handler for the changes made under the given path of the instance until ctx is done.
+func (d *qemu) FileWatch(ctx context.Context, path string, recursive bool, handler func(event api.InstanceFileEvent)) error {
+	client, err := d.getAgentClient()
+	if err != nil {
+		return err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		d.logger.Error("Failed to connect to lxd-agent", log.Ctx{"err": err})
+		return fmt.Errorf("Failed to connect to lxd-agent")
+	}
+	defer agent.Disconnect()
+
+	op, err := agent.WatchInstanceFiles("", api.InstanceFileWatchPost{Path: path, Recursive: recursive}, handler)
+	if err != nil {
+		return err
+	}
+
+	chWait := make(chan error, 1)
+	go func() {
+		chWait <- op.Wait()
+	}()
+
+	select {
+	case err = <-chWait:
+		return err
+	case <-ctx.Done():
+		// The operation may already be gone if the watch just ended.
+		_ = op.Cancel()
+		return nil
+	}
+}
+
+// ConsoleLogCapture moves the output QEMU wrote to the console log file since the last capture to the persistent
+// console log.
+func (d *qemu) ConsoleLogCapture() error {
+	f, err := os.OpenFile(d.ConsoleBufferLogPath(), os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	output, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	if len(output) == 0 {
+		return nil
+	}
+
+	err = consolelog.Get(d.LogPath()).Write(time.Now(), output)
+	if err != nil {
+		return err
+	}
+
+	// QEMU appends to the file, so only the output written since it was read is lost by truncating it.
+	return f.Truncate(0)
+}
+
+// Console gets access to the instance's console.
+func (d *qemu) Console(protocol string) (*os.File, chan error, error) {
+	switch protocol {
+	case instance.ConsoleTypeConsole:
+		return d.console()
+	case instance.ConsoleTypeVGA:
 		return d.vga()
 	default:
 		return nil, nil, fmt.Errorf("Unknown protocol %q", protocol)
@@ -5228,12 +5732,16 @@ func (d *qemu) renderState(statusCode api.StatusCode) (*api.InstanceState, error
 				}
 
 				// Only some NIC types support fallback state mechanisms when there is no agent.
-				nic, ok := dev.(device.NICState)
-				if !ok {
+				var network *api.InstanceStateNetwork
+				switch nic := dev.(type) {
+				case device.NICState:
+					network, err = nic.State()
+				case device.NICHostState:
+					network, err = nic.HostState()
+				default:
 					continue
 				}
 
-				network, err := nic.State()
 				if err != nil {
 					return nil, errors.Wrapf(err, "Failed getting NIC state for %q", k)
 				}
@@ -5259,14 +5767,32 @@ func (d *qemu) renderState(statusCode api.StatusCode) (*api.InstanceState, error
 				hwaddr = d.localConfig[fmt.Sprintf("volatile.%s.hwaddr", k)]
 			}
 
+			// Get the link speed of the NICs which aren't backed by a tap interface from their host-side state.
+			var hostState *api.InstanceStateNetwork
+			dev, _, err := d.deviceLoad(k, m)
+			if err == nil {
+				nic, ok := dev.(device.NICHostState)
+				if ok {
+					hostState, err = nic.HostState()
+					if err != nil {
+						d.logger.Warn("Failed getting NIC host state", log.Ctx{"device": k, "err": err})
+					}
+				}
+			}
+
 			// We have to match on hwaddr as device name can be different from the configured device
 			// name when reported from the lxd-agent inside the VM (due to the guest OS choosing name).
 			for netName, netStatus := range status.Network {
 				if netStatus.Hwaddr == hwaddr {
 					if netStatus.HostName == "" {
 						netStatus.HostName = d.localConfig[fmt.Sprintf("volatile.%s.host_name", k)]
-						status.Network[netName] = netStatus
 					}
+
+					if hostState != nil {
+						netStatus.LinkSpeed = hostState.LinkSpeed
+					}
+
+					status.Network[netName] = netStatus
 				}
 			}
 		}
@@ -5288,6 +5814,50 @@ func (d *qemu) RenderState() (*api.InstanceState, error) {
 	return d.renderState(d.statusCode())
 }
 
+// Processes returns the processes of the guest using the most CPU or memory, as reported by the agent.
+func (d *qemu) Processes(sortBy string, limit int) (*api.InstanceProcesses, error) {
+	if !d.IsRunning() {
+		return nil, fmt.Errorf("The instance isn't running")
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	if !monitor.AgentReady() {
+		return nil, errQemuAgentOffline
+	}
+
+	client, err := d.getAgentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed connecting to agent")
+	}
+	defer agent.Disconnect()
+
+	values := url.Values{}
+	values.Set("sort", sortBy)
+	values.Set("limit", strconv.Itoa(limit))
+
+	resp, _, err := agent.RawQuery("GET", fmt.Sprintf("/1.0/processes?%s", values.Encode()), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	top := api.InstanceProcesses{}
+	err = resp.MetadataAsStruct(&top)
+	if err != nil {
+		return nil, err
+	}
+
+	return &top, nil
+}
+
 // diskState gets disk usage info.
 func (d *qemu) diskState() (map[string]api.InstanceStateDisk, error) {
 	pool, err := d.getStoragePool()
@@ -5494,6 +6064,16 @@ func (d *qemu) FillNetworkDevice(name string, m deviceConfig.Device) (deviceConf
 		newDevice["hwaddr"] = volatileHwaddr
 	}
 
+	// Fill in the addresses allocated by the network's IPAM backend, unless set statically.
+	if nicType == "bridged" {
+		for _, key := range []string{"ipv4.address", "ipv6.address"} {
+			address := d.localConfig[fmt.Sprintf("volatile.%s.ipam.%s", name, key)]
+			if m[key] == "" && address != "" {
+				newDevice[key] = address
+			}
+		}
+	}
+
 	return newDevice, nil
 }
 
@@ -5625,6 +6205,625 @@ func (d *qemu) cpuTopology(limit string) (int, int, int, map[uint64]uint64, map[
 	return nrSockets, nrCores, nrThreads, vcpus, numaNodes, nil
 }
 
+// SetAffinity restricts all the vCPU threads of a running VM to the provided set of host CPUs.
+func (d *qemu) SetAffinity(set []string) error {
+	if !d.IsRunning() {
+		return fmt.Errorf("The instance isn't running")
+	}
+
+	cpus, err := resources.ParseCpuset(strings.Join(set, ","))
+	if err != nil {
+		return err
+	}
+
+	// Connect to the monitor.
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	// Get the list of PIDs from the VM.
+	pids, err := monitor.GetCPUs()
+	if err != nil {
+		return err
+	}
+
+	cpuSet := unix.CPUSet{}
+	for _, cpu := range cpus {
+		cpuSet.Set(int(cpu))
+	}
+
+	for _, pid := range pids {
+		err := unix.SchedSetaffinity(pid, &cpuSet)
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting affinity of vCPU thread %d", pid)
+		}
+	}
+
+	return nil
+}
+
+// cpuModel returns the QEMU CPU model definition from migration.cpu_model and limits.cpu.flags.
+// A named model makes the guest CPU the same on all hosts, which is what allows moving the VM's state between
+// hosts with different CPU generations.
+func (d *qemu) cpuModel() (string, error) {
+	model := d.expandedConfig["migration.cpu_model"]
+	if model == "" {
+		model = "host"
+	}
+
+	props := []string{model}
+
+	// Refuse to start rather than silently dropping features the host CPU doesn't have.
+	if model != "host" && d.architecture == osarch.ARCH_64BIT_INTEL_X86 {
+		props = append(props, "enforce=on")
+	}
+
+	for _, flag := range strings.Split(d.expandedConfig["limits.cpu.flags"], ",") {
+		flag = strings.TrimSpace(flag)
+		if flag == "" {
+			continue
+		}
+
+		if strings.HasPrefix(flag, "-") {
+			props = append(props, fmt.Sprintf("%s=off", strings.TrimPrefix(flag, "-")))
+		} else {
+			props = append(props, fmt.Sprintf("%s=on", strings.TrimPrefix(flag, "+")))
+		}
+	}
+
+	// Hide the virtualization extensions unless nested virtualization is allowed.
+	if d.architecture == osarch.ARCH_64BIT_INTEL_X86 {
+		nesting := d.expandedConfig["security.nesting.kvm"]
+		if nesting == "" {
+			p, err := d.state.Cluster.GetProject(d.project)
+			if err != nil {
+				return "", errors.Wrapf(err, "Failed loading project %q", d.project)
+			}
+
+			if !project.AllowNestedKVM(p) {
+				nesting = "false"
+			}
+		}
+
+		if nesting != "" && !shared.IsTrue(nesting) {
+			props = append(props, "vmx=off", "svm=off")
+		}
+	}
+
+	return strings.Join(props, ","), nil
+}
+
+// SetMemoryBalloon resizes the balloon of a running VM so that the guest has sizeBytes of memory available.
+// Unlike changing limits.memory, this doesn't wait for the guest to release the memory.
+func (d *qemu) SetMemoryBalloon(sizeBytes int64) error {
+	if !d.IsRunning() {
+		return fmt.Errorf("The instance isn't running")
+	}
+
+	if shared.IsTrue(d.expandedConfig["limits.memory.hugepages"]) {
+		return fmt.Errorf("Cannot resize the memory balloon when using huge pages")
+	}
+
+	// Connect to the monitor.
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	baseSizeBytes, err := monitor.GetMemorySizeBytes()
+	if err != nil {
+		return err
+	}
+
+	if sizeBytes > baseSizeBytes {
+		sizeBytes = baseSizeBytes
+	}
+
+	return monitor.SetMemoryBalloonSizeBytes(sizeBytes)
+}
+
+// nvramLoad parses the variable store of the VM's NVRAM file, the config volume must be mounted.
+func (d *qemu) nvramLoad() (*edk2.VarStore, error) {
+	if !shared.IntInSlice(d.architecture, []int{osarch.ARCH_64BIT_INTEL_X86, osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN}) {
+		return nil, fmt.Errorf("UEFI isn't supported on this architecture")
+	}
+
+	data, err := ioutil.ReadFile(d.nvramPath())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed reading NVRAM %q", d.nvramPath())
+	}
+
+	store, err := edk2.Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing NVRAM %q", d.nvramPath())
+	}
+
+	return store, nil
+}
+
+// NVRAM returns the boot entries, boot order and secure boot state held in the UEFI variables of the VM.
+func (d *qemu) NVRAM() (*api.InstanceNVRAM, error) {
+	// Mount the instance's config volume.
+	_, err := d.mount()
+	if err != nil {
+		return nil, err
+	}
+	defer d.unmount()
+
+	store, err := d.nvramLoad()
+	if err != nil {
+		return nil, err
+	}
+
+	nvram := api.InstanceNVRAM{}
+
+	nvram.BootOrder, err = store.BootOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	nvram.SecureBootKeys, err = store.SecureBootKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := store.BootOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	nvram.BootEntries = make([]api.InstanceNVRAMBootEntry, 0, len(options))
+	for _, option := range options {
+		nvram.BootEntries = append(nvram.BootEntries, api.InstanceNVRAMBootEntry{
+			ID:          option.ID,
+			Description: option.Description,
+			Active:      option.Active,
+		})
+	}
+
+	return &nvram, nil
+}
+
+// NVRAMUpdate sets the boot order of the VM and enrolls or removes its secure boot keys.
+// The firmware only reads its variables when starting, so the VM must be stopped.
+func (d *qemu) NVRAMUpdate(nvram api.InstanceNVRAMPut) error {
+	if d.IsRunning() {
+		return fmt.Errorf("The instance must be stopped to modify its NVRAM")
+	}
+
+	// Mount the instance's config volume.
+	_, err := d.mount()
+	if err != nil {
+		return err
+	}
+	defer d.unmount()
+
+	store, err := d.nvramLoad()
+	if err != nil {
+		return err
+	}
+
+	bootOrder, err := store.BootOrder()
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(bootOrder, ",") != strings.Join(nvram.BootOrder, ",") {
+		err = store.SetBootOrder(nvram.BootOrder)
+		if err != nil {
+			return err
+		}
+	}
+
+	secureBootKeys, err := store.SecureBootKeys()
+	if err != nil {
+		return err
+	}
+
+	if nvram.SecureBootKeys && !secureBootKeys {
+		// Take the keys from the template the NVRAM is created from when secure boot is enabled.
+		data, err := ioutil.ReadFile(filepath.Join(d.ovmfPath(), "OVMF_VARS.ms.fd"))
+		if err != nil {
+			return errors.Wrap(err, "Failed reading the secure boot firmware settings")
+		}
+
+		template, err := edk2.Parse(data)
+		if err != nil {
+			return errors.Wrap(err, "Failed parsing the secure boot firmware settings")
+		}
+
+		err = store.EnrollSecureBootKeys(template)
+		if err != nil {
+			return err
+		}
+	} else if !nvram.SecureBootKeys && secureBootKeys {
+		err = store.ClearSecureBootKeys()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ioutil.WriteFile(d.nvramPath(), store.Bytes(), 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Failed writing NVRAM %q", d.nvramPath())
+	}
+
+	return nil
+}
+
+// NVRAMReset recreates the NVRAM of the VM from the firmware defaults, dropping all its boot entries.
+func (d *qemu) NVRAMReset() error {
+	if d.IsRunning() {
+		return fmt.Errorf("The instance must be stopped to reset its NVRAM")
+	}
+
+	if !shared.IntInSlice(d.architecture, []int{osarch.ARCH_64BIT_INTEL_X86, osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN}) {
+		return fmt.Errorf("UEFI isn't supported on this architecture")
+	}
+
+	return d.setupNvram()
+}
+
+// qemuMachineTypeFamilyRegex matches versioned machine types (such as pc-q35-6.2 or virt-6.2).
+var qemuMachineTypeFamilyRegex = regexp.MustCompile(`^(.+)-([0-9]+\.[0-9]+)$`)
+
+// qemuParseMachineTypes parses the output of "qemu-system-* -machine help" into the list of machine types and a
+// map of the aliases (such as q35) to the machine types they currently resolve to.
+func qemuParseMachineTypes(output string) ([]string, map[string]string) {
+	machineTypes := []string{}
+	aliases := map[string]string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasSuffix(line, ":") {
+			continue
+		}
+
+		// Alias lines look like "q35  Standard PC (Q35 + ICH9, 2009) (alias of pc-q35-6.2)".
+		idx := strings.Index(line, "(alias of ")
+		if idx >= 0 {
+			aliases[fields[0]] = strings.TrimSuffix(strings.TrimSpace(line[idx+len("(alias of "):]), ")")
+			continue
+		}
+
+		machineTypes = append(machineTypes, fields[0])
+	}
+
+	return machineTypes, aliases
+}
+
+// machineTypes returns the machine types supported by QEMU for the VM's architecture along with the latest
+// machine type of the family LXD uses for it.
+func (d *qemu) machineTypes() ([]string, string, error) {
+	qemuPath, _, err := d.qemuArchConfig(d.architecture)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out, err := exec.Command(qemuPath, "-machine", "help").Output()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Failed listing the QEMU machine types")
+	}
+
+	machineTypes, aliases := qemuParseMachineTypes(string(out))
+
+	var alias string
+	switch d.architecture {
+	case osarch.ARCH_64BIT_INTEL_X86:
+		alias = "q35"
+	case osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN:
+		alias = "virt"
+	case osarch.ARCH_64BIT_POWERPC_LITTLE_ENDIAN:
+		alias = "pseries"
+	case osarch.ARCH_64BIT_S390_BIG_ENDIAN:
+		alias = "s390-ccw-virtio"
+	}
+
+	latest := aliases[alias]
+	if latest == "" {
+		return nil, "", fmt.Errorf("QEMU doesn't support the %q machine type", alias)
+	}
+
+	return machineTypes, latest, nil
+}
+
+// machineTypeCompatible returns an error if a VM can't be moved from one machine type to another. Only upgrades
+// within the same family are allowed as the guest may not cope with going back to older virtual hardware.
+func machineTypeCompatible(from string, to string) error {
+	fromFields := qemuMachineTypeFamilyRegex.FindStringSubmatch(from)
+	toFields := qemuMachineTypeFamilyRegex.FindStringSubmatch(to)
+	if fromFields == nil || toFields == nil || fromFields[1] != toFields[1] {
+		return fmt.Errorf("Machine type %q isn't compatible with %q", to, from)
+	}
+
+	fromVersion, err := version.Parse(fromFields[2])
+	if err != nil {
+		return err
+	}
+
+	toVersion, err := version.Parse(toFields[2])
+	if err != nil {
+		return err
+	}
+
+	if toVersion.Compare(fromVersion) < 0 {
+		return fmt.Errorf("Downgrading the machine type from %q to %q isn't supported", from, to)
+	}
+
+	return nil
+}
+
+// startMachineType returns the machine type to start the VM with. The latest machine type is used on first start
+// and then kept so that QEMU upgrades don't change the virtual hardware exposed to the guest.
+func (d *qemu) startMachineType() (string, error) {
+	machineTypes, latest, err := d.machineTypes()
+	if err != nil {
+		return "", err
+	}
+
+	current := d.localConfig["volatile.machine_type"]
+	if current == "" {
+		return latest, nil
+	}
+
+	if !shared.StringInSlice(current, machineTypes) {
+		return "", fmt.Errorf("Machine type %q isn't supported by this server's QEMU (latest is %q), upgrade the instance's machine type first", current, latest)
+	}
+
+	return current, nil
+}
+
+// MachineType returns the machine type of the VM and the machine types it can be upgraded to.
+func (d *qemu) MachineType() (*api.InstanceMachineType, error) {
+	machineTypes, latest, err := d.machineTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	current := d.localConfig["volatile.machine_type"]
+	from := current
+	if from == "" {
+		from = latest
+	}
+
+	supported := []string{}
+	for _, machineType := range machineTypes {
+		if machineTypeCompatible(from, machineType) == nil {
+			supported = append(supported, machineType)
+		}
+	}
+
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i] != supported[j] && machineTypeCompatible(supported[i], supported[j]) == nil
+	})
+
+	return &api.InstanceMachineType{
+		Current:   current,
+		Latest:    latest,
+		Supported: supported,
+	}, nil
+}
+
+// MachineTypeUpgrade moves the VM to another machine type of the same family, or to the latest one if empty.
+// The virtual hardware is only set up when starting, so the VM must be stopped.
+func (d *qemu) MachineTypeUpgrade(machineType string) error {
+	if d.IsRunning() {
+		return fmt.Errorf("The instance must be stopped to change its machine type")
+	}
+
+	if d.stateful {
+		return fmt.Errorf("The instance's saved runtime state is tied to its current machine type, start it first")
+	}
+
+	machineTypes, latest, err := d.machineTypes()
+	if err != nil {
+		return err
+	}
+
+	if machineType == "" {
+		machineType = latest
+	}
+
+	if !shared.StringInSlice(machineType, machineTypes) {
+		return fmt.Errorf("Machine type %q isn't supported by this server's QEMU", machineType)
+	}
+
+	current := d.localConfig["volatile.machine_type"]
+	if current == machineType {
+		return nil
+	}
+
+	// Instances which never started get pinned to the latest machine type on their first start.
+	from := current
+	if from == "" {
+		from = latest
+	}
+
+	err = machineTypeCompatible(from, machineType)
+	if err != nil {
+		return err
+	}
+
+	err = d.VolatileSet(map[string]string{"volatile.machine_type": machineType})
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting volatile keys")
+	}
+
+	d.logger.Info("Upgraded machine type", log.Ctx{"from": current, "to": machineType})
+
+	return nil
+}
+
+// sevMode returns the AMD SEV memory encryption mode requested for the VM (sev, sev-es or sev-snp), or an empty
+// string if disabled.
+func (d *qemu) sevMode() string {
+	if !shared.IsTrue(d.expandedConfig["security.sev"]) {
+		return ""
+	}
+
+	if shared.IsTrue(d.expandedConfig["security.sev.snp"]) {
+		return "sev-snp"
+	}
+
+	if shared.IsTrue(d.expandedConfig["security.sev.policy.es"]) {
+		return "sev-es"
+	}
+
+	return "sev"
+}
+
+// qemuSEVPolicy returns the guest policy for the memory encryption mode. Debugging the guest is never allowed.
+func qemuSEVPolicy(mode string) string {
+	switch mode {
+	case "sev-snp":
+		return "0x30000" // SMT allowed and the reserved bit which must be set.
+	case "sev-es":
+		return "0x5" // No debugging and encrypted register state.
+	}
+
+	return "0x1" // No debugging.
+}
+
+// sevCapabilities returns the host capabilities to run the VM with the memory encryption mode, or an error if the
+// host doesn't support it.
+func (d *qemu) sevCapabilities(mode string) (*api.ResourcesCPUSEV, error) {
+	if d.architecture != osarch.ARCH_64BIT_INTEL_X86 {
+		return nil, fmt.Errorf("AMD SEV is only supported on x86_64")
+	}
+
+	// The position of the encryption bit is read through the CPUID device.
+	err := util.LoadModule("cpuid")
+	if err != nil {
+		return nil, err
+	}
+
+	sev, err := resources.GetCPUSEV()
+	if err != nil {
+		return nil, err
+	}
+
+	if sev == nil {
+		return nil, fmt.Errorf("This host doesn't support AMD SEV guests")
+	}
+
+	if mode == "sev-es" && !sev.ES {
+		return nil, fmt.Errorf("This host doesn't support AMD SEV-ES guests")
+	}
+
+	if mode == "sev-snp" && !sev.SNP {
+		return nil, fmt.Errorf("This host doesn't support AMD SEV-SNP guests")
+	}
+
+	if sev.CBitPosition == 0 {
+		return nil, fmt.Errorf("Failed to determine the memory encryption bit position")
+	}
+
+	return sev, nil
+}
+
+// SEV returns the memory encryption state of the VM.
+func (d *qemu) SEV() (*api.InstanceSEV, error) {
+	mode := d.sevMode()
+	if mode == "" {
+		return nil, fmt.Errorf("AMD SEV isn't enabled on the instance")
+	}
+
+	sev := api.InstanceSEV{
+		Mode:   mode,
+		Policy: qemuSEVPolicy(mode),
+	}
+
+	if !d.IsRunning() {
+		return &sev, nil
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := monitor.QuerySEV()
+	if err != nil {
+		return nil, err
+	}
+
+	sev.State = info.State
+	sev.APIVersion = fmt.Sprintf("%d.%d", info.APIMajor, info.APIMinor)
+	sev.BuildID = info.BuildID
+
+	// SEV-SNP guests are measured by the firmware into their attestation reports instead.
+	if mode != "sev-snp" {
+		sev.Measurement, err = monitor.SEVLaunchMeasurement()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &sev, nil
+}
+
+// SEVReport returns an attestation report of the running VM including the nonce.
+func (d *qemu) SEVReport(nonce []byte) ([]byte, error) {
+	mode := d.sevMode()
+	if mode == "" {
+		return nil, fmt.Errorf("AMD SEV isn't enabled on the instance")
+	}
+
+	if !d.IsRunning() {
+		return nil, fmt.Errorf("The instance must be running to get an attestation report")
+	}
+
+	// Only the guest can request SEV-SNP attestation reports, so go through the agent.
+	if mode == "sev-snp" {
+		if len(nonce) > 64 {
+			return nil, fmt.Errorf("The nonce can't be longer than 64 bytes")
+		}
+
+		client, err := d.getAgentClient()
+		if err != nil {
+			return nil, err
+		}
+
+		agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+		if err != nil {
+			d.logger.Error("Failed to connect to lxd-agent", log.Ctx{"err": err})
+			return nil, fmt.Errorf("Failed to connect to lxd-agent")
+		}
+		defer agent.Disconnect()
+
+		req := api.InstanceSEVReportPost{Nonce: base64.StdEncoding.EncodeToString(nonce)}
+		resp, _, err := agent.RawQuery("POST", "/1.0/sev/report", req, "")
+		if err != nil {
+			return nil, err
+		}
+
+		report := api.InstanceSEVReport{}
+		err = resp.MetadataAsStruct(&report)
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.DecodeString(report.Report)
+	}
+
+	if len(nonce) != 16 {
+		return nil, fmt.Errorf("The nonce must be 16 bytes long")
+	}
+
+	monitor, err := qmp.Connect(d.monitorPath(), qemuSerialChardevName, d.getMonitorEventHandler())
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := monitor.SEVAttestationReport(base64.StdEncoding.EncodeToString(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(report)
+}
+
 func (d *qemu) devlxdEventSend(eventType string, eventMessage interface{}) error {
 	event := shared.Jmap{}
 	event["type"] = eventType
@@ -5678,11 +6877,17 @@ func (d *qemu) writeInstanceData() error {
 		location = d.Location()
 	}
 
+	p, err := d.state.Cluster.GetProject(d.project)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading project %q", d.project)
+	}
+
 	out, err := json.Marshal(struct {
-		Name     string            `json:"name"`
-		Location string            `json:"location"`
-		Config   map[string]string `json:"config,omitempty"`
-	}{d.Name(), location, userConfig})
+		Name        string            `json:"name"`
+		Location    string            `json:"location"`
+		Config      map[string]string `json:"config,omitempty"`
+		Environment map[string]string `json:"environment,omitempty"`
+	}{d.Name(), location, userConfig, project.Environment(p, d.expandedConfig)})
 	if err != nil {
 		return err
 	}
@@ -4,23 +4,17 @@ import (
 	"text/template"
 )
 
-// Base config. This is common for all VMs and has no variables in it.
+// Base config. This is common for all VMs.
 var qemuBase = template.Must(template.New("qemuBase").Parse(`
 # Machine
 [machine]
 graphics = "off"
-{{if eq .architecture "x86_64" -}}
-type = "q35"
-{{end -}}
+type = "{{.machineType}}"
 {{if eq .architecture "aarch64" -}}
-type = "virt"
 gic-version = "max"
 {{end -}}
-{{if eq .architecture "ppc64le" -}}
-type = "pseries"
-{{end -}}
-{{if eq .architecture "s390x" -}}
-type = "s390-ccw-virtio"
+{{if .sev -}}
+confidential-guest-support = "sev0"
 {{end -}}
 accel = "kvm"
 usb = "off"
@@ -43,6 +37,21 @@ strict = "on"
 # Console
 [chardev "console"]
 backend = "pty"
+logfile = "{{.consoleLogPath}}"
+logappend = "on"
+`))
+
+var qemuSEV = template.Must(template.New("qemuSEV").Parse(`
+# Memory encryption
+[object "sev0"]
+{{- if .snp}}
+qom-type = "sev-snp-guest"
+{{- else}}
+qom-type = "sev-guest"
+{{- end}}
+cbitpos = "{{.cbitpos}}"
+reduced-phys-bits = "{{.reducedPhysBits}}"
+policy = "{{.policy}}"
 `))
 
 var qemuMemory = template.Must(template.New("qemuMemory").Parse(`
@@ -276,6 +285,10 @@ qom-type = "memory-backend-memfd"
 {{- end }}
 size = "{{$memory}}M"
 share = "on"
+{{if .memoryHostNodes -}}
+host-nodes = "{{.memoryHostNodes}}"
+policy = "bind"
+{{- end }}
 
 [numa]
 type = "node"
@@ -451,6 +464,60 @@ scsi-id = "{{.bootIndex}}"
 lun = "1"
 drive = "lxd_{{.devName}}"
 bootindex = "{{.bootIndex}}"
+{{if .serial -}}
+serial = "{{.serial}}"
+{{- end }}
+{{if .multifunction -}}
+multifunction = "on"
+{{- end }}
+`))
+
+// Devices use "lxd_" prefix indicating that this is a user named device.
+// The namespace is accessed through QEMU's userspace NVMe driver and exposed to the guest as a virtio-blk disk.
+var qemuDriveNVMe = template.Must(template.New("qemuDriveNVMe").Parse(`
+# {{.devName}} drive (NVMe)
+{{- if .iothread }}
+[object "iothread-lxd_{{.devName}}"]
+qom-type = "iothread"
+{{ end }}
+[drive "lxd_{{.devName}}"]
+file = "{{.devPath}}"
+format = "raw"
+if = "none"
+cache = "none"
+discard = "on"
+{{- if .readonly}}
+readonly = "on"
+{{- else}}
+readonly = "off"
+{{- end}}
+
+[device "dev-lxd_{{.devName}}"]
+{{- if eq .bus "pci" "pcie"}}
+driver = "virtio-blk-pci"
+bus = "{{.devBus}}"
+addr = "{{.devAddr}}"
+{{- end}}
+{{if eq .bus "ccw" -}}
+driver = "virtio-blk-ccw"
+{{- end}}
+drive = "lxd_{{.devName}}"
+serial = "lxd_{{.devName}}"
+{{if .bootIndex -}}
+bootindex = "{{.bootIndex}}"
+{{- end }}
+{{if .queues -}}
+num-queues = "{{.queues}}"
+{{- end }}
+{{if .queueSize -}}
+queue-size = "{{.queueSize}}"
+{{- end }}
+{{if .vectors -}}
+vectors = "{{.vectors}}"
+{{- end }}
+{{if .iothread -}}
+iothread = "iothread-lxd_{{.devName}}"
+{{- end }}
 {{if .multifunction -}}
 multifunction = "on"
 {{- end }}
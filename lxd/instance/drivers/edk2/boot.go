@@ -0,0 +1,162 @@
+package edk2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes of the variables the firmware itself sets.
+const (
+	AttributeNonVolatile       = 0x1
+	AttributeBootServiceAccess = 0x2
+	AttributeRuntimeAccess     = 0x4
+)
+
+// loadOptionActive is the attribute of the boot options the firmware will try.
+const loadOptionActive = 0x1
+
+// SecureBootVariables are the variables holding the secure boot keys and signature databases.
+var SecureBootVariables = []struct {
+	Name   string
+	Vendor GUID
+}{
+	{"PK", GlobalVariable},
+	{"KEK", GlobalVariable},
+	{"db", ImageSecurityDatabase},
+	{"dbx", ImageSecurityDatabase},
+}
+
+var bootOptionName = regexp.MustCompile(`^Boot([0-9A-F]{4})$`)
+
+// BootOption is a firmware boot entry (Boot#### variable).
+type BootOption struct {
+	ID          string
+	Description string
+	Active      bool
+}
+
+// BootOptions returns the boot entries of the store, sorted by ID.
+func (s *VarStore) BootOptions() ([]BootOption, error) {
+	vars, err := s.Variables()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []BootOption{}
+	for _, v := range vars {
+		match := bootOptionName.FindStringSubmatch(v.Name)
+		if match == nil || v.Vendor != GlobalVariable {
+			continue
+		}
+
+		// EFI_LOAD_OPTION: attributes, file path list length and NUL terminated description.
+		if len(v.Data) < 6 {
+			continue
+		}
+
+		options = append(options, BootOption{
+			ID:          match[1],
+			Description: decodeName(v.Data[6:]),
+			Active:      binary.LittleEndian.Uint32(v.Data)&loadOptionActive != 0,
+		})
+	}
+
+	sort.Slice(options, func(i, j int) bool { return options[i].ID < options[j].ID })
+
+	return options, nil
+}
+
+// BootOrder returns the IDs of the boot entries in the order the firmware tries them.
+func (s *VarStore) BootOrder() ([]string, error) {
+	v, err := s.Get("BootOrder", GlobalVariable)
+	if err != nil {
+		return nil, err
+	}
+
+	order := []string{}
+	if v == nil {
+		return order, nil
+	}
+
+	for i := 0; i+1 < len(v.Data); i += 2 {
+		order = append(order, fmt.Sprintf("%04X", binary.LittleEndian.Uint16(v.Data[i:])))
+	}
+
+	return order, nil
+}
+
+// SetBootOrder sets the order in which the firmware tries the boot entries.
+func (s *VarStore) SetBootOrder(order []string) error {
+	data := make([]byte, len(order)*2)
+	for i, id := range order {
+		n, err := strconv.ParseUint(id, 16, 16)
+		if err != nil || len(id) != 4 {
+			return fmt.Errorf("Invalid boot entry ID %q", id)
+		}
+
+		v, err := s.Get(fmt.Sprintf("Boot%s", strings.ToUpper(id)), GlobalVariable)
+		if err != nil {
+			return err
+		}
+
+		if v == nil {
+			return fmt.Errorf("Boot entry %q doesn't exist", id)
+		}
+
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(n))
+	}
+
+	return s.Set(Variable{
+		Name:       "BootOrder",
+		Vendor:     GlobalVariable,
+		Attributes: AttributeNonVolatile | AttributeBootServiceAccess | AttributeRuntimeAccess,
+		Data:       data,
+	})
+}
+
+// SecureBootKeys returns whether a platform key is enrolled, in which case the firmware enforces secure boot.
+func (s *VarStore) SecureBootKeys() (bool, error) {
+	v, err := s.Get("PK", GlobalVariable)
+	if err != nil {
+		return false, err
+	}
+
+	return v != nil, nil
+}
+
+// ClearSecureBootKeys removes the secure boot keys and signature databases, putting the firmware in setup mode.
+func (s *VarStore) ClearSecureBootKeys() error {
+	for _, key := range SecureBootVariables {
+		_, err := s.Delete(key.Name, key.Vendor)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnrollSecureBootKeys copies the secure boot keys and signature databases from another store.
+func (s *VarStore) EnrollSecureBootKeys(from *VarStore) error {
+	for _, key := range SecureBootVariables {
+		v, err := from.Get(key.Name, key.Vendor)
+		if err != nil {
+			return err
+		}
+
+		if v == nil {
+			return fmt.Errorf("Secure boot variable %q missing from the firmware template", key.Name)
+		}
+
+		err = s.Set(*v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
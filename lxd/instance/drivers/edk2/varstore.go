@@ -0,0 +1,359 @@
+// Package edk2 reads and modifies the UEFI variables stored in the NVRAM files of the EDK2 (OVMF) firmware.
+package edk2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Offsets and sizes of the firmware volume holding the variable store.
+const (
+	fvSignatureOffset    = 40
+	fvHeaderLengthOffset = 48
+	storeHeaderSize      = 28
+	storeSizeOffset      = 16
+	varHeaderSize        = 60
+	varStartID           = 0x55aa
+)
+
+// States of a variable, bits are cleared as the variable goes through them.
+const (
+	varAdded                = 0x3f
+	varInDeletedTransition  = 0xfe
+	varDeleted              = 0xfd
+	varAddedInDeletedPrefix = varAdded & varInDeletedTransition
+)
+
+// authenticatedStoreGUID identifies variable stores using authenticated variable headers.
+var authenticatedStoreGUID = MustParseGUID("aaf32c78-947b-439a-a180-2e144ec37792")
+
+// GlobalVariable is the vendor GUID of the variables defined by the UEFI specification (BootOrder, PK, KEK...).
+var GlobalVariable = MustParseGUID("8be4df61-93ca-11d2-aa0d-00e098032b8c")
+
+// ImageSecurityDatabase is the vendor GUID of the secure boot signature databases (db and dbx).
+var ImageSecurityDatabase = MustParseGUID("d719b2cb-3d3a-4596-a3bc-dad00e67656f")
+
+// GUID is an EFI GUID in its binary (mixed endian) form.
+type GUID [16]byte
+
+// ParseGUID parses the textual form of an EFI GUID.
+func ParseGUID(s string) (GUID, error) {
+	guid := GUID{}
+
+	raw, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(raw) != 16 || strings.Count(s, "-") != 4 {
+		return guid, fmt.Errorf("Invalid GUID %q", s)
+	}
+
+	// The first three groups are stored little endian.
+	copy(guid[:], raw)
+	guid[0], guid[1], guid[2], guid[3] = raw[3], raw[2], raw[1], raw[0]
+	guid[4], guid[5] = raw[5], raw[4]
+	guid[6], guid[7] = raw[7], raw[6]
+
+	return guid, nil
+}
+
+// MustParseGUID parses the textual form of an EFI GUID and panics on failure.
+func MustParseGUID(s string) GUID {
+	guid, err := ParseGUID(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return guid
+}
+
+// String returns the textual form of the GUID.
+func (g GUID) String() string {
+	return fmt.Sprintf("%02x%02x%02x%02x-%02x%02x-%02x%02x-%x-%x", g[3], g[2], g[1], g[0], g[5], g[4], g[7], g[6], g[8:10], g[10:])
+}
+
+// Variable is a UEFI variable.
+type Variable struct {
+	Name       string
+	Vendor     GUID
+	Attributes uint32
+	Data       []byte
+
+	// Authentication fields, kept as is when copying variables around.
+	MonotonicCount uint64
+	TimeStamp      [16]byte
+	PubKeyIndex    uint32
+}
+
+// VarStore is an EDK2 authenticated variable store held in an NVRAM file.
+type VarStore struct {
+	data []byte
+
+	// start and end of the variables area
+	start int
+	end   int
+}
+
+// Parse returns the variable store held in the content of an NVRAM file.
+func Parse(data []byte) (*VarStore, error) {
+	if len(data) < fvHeaderLengthOffset+2 || string(data[fvSignatureOffset:fvSignatureOffset+4]) != "_FVH" {
+		return nil, fmt.Errorf("Not an EDK2 firmware volume")
+	}
+
+	offset := int(binary.LittleEndian.Uint16(data[fvHeaderLengthOffset:]))
+	if len(data) < offset+storeHeaderSize {
+		return nil, fmt.Errorf("Truncated variable store")
+	}
+
+	if !bytes.Equal(data[offset:offset+16], authenticatedStoreGUID[:]) {
+		return nil, fmt.Errorf("Unsupported variable store format")
+	}
+
+	size := int(binary.LittleEndian.Uint32(data[offset+storeSizeOffset:]))
+	if size < storeHeaderSize || len(data) < offset+size {
+		return nil, fmt.Errorf("Invalid variable store size %d", size)
+	}
+
+	s := &VarStore{
+		data:  append([]byte{}, data...),
+		start: offset + storeHeaderSize,
+		end:   offset + size,
+	}
+
+	return s, nil
+}
+
+// Bytes returns the content of the NVRAM file.
+func (s *VarStore) Bytes() []byte {
+	return s.data
+}
+
+// Variables returns the variables currently set in the store.
+func (s *VarStore) Variables() ([]Variable, error) {
+	vars := []Variable{}
+
+	err := s.walk(func(offset int, v Variable) {
+		vars = append(vars, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// Get returns the variable with the given name and vendor, or nil if it isn't set.
+func (s *VarStore) Get(name string, vendor GUID) (*Variable, error) {
+	var found *Variable
+
+	err := s.walk(func(offset int, v Variable) {
+		if v.Name == name && v.Vendor == vendor {
+			found = &v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// Delete removes the variable with the given name and vendor, it returns whether it was set.
+func (s *VarStore) Delete(name string, vendor GUID) (bool, error) {
+	deleted := false
+
+	err := s.walk(func(offset int, v Variable) {
+		if v.Name == name && v.Vendor == vendor {
+			s.data[offset+2] &= varDeleted
+			deleted = true
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// Set adds or replaces a variable. Deleted variables are reclaimed if the store runs out of space.
+func (s *VarStore) Set(v Variable) error {
+	record := encodeVariable(v)
+
+	// Replace the data in place when the size doesn't change, as the firmware would.
+	replaced := false
+	err := s.walk(func(offset int, old Variable) {
+		if replaced || old.Name != v.Name || old.Vendor != v.Vendor || len(old.Data) != len(v.Data) {
+			return
+		}
+
+		copy(s.data[offset:], record)
+		replaced = true
+	})
+	if err != nil {
+		return err
+	}
+
+	if replaced {
+		return nil
+	}
+
+	_, err = s.Delete(v.Name, v.Vendor)
+	if err != nil {
+		return err
+	}
+
+	free, err := s.freeOffset()
+	if err != nil {
+		return err
+	}
+
+	if free+len(record) > s.end {
+		err = s.reclaim()
+		if err != nil {
+			return err
+		}
+
+		free, err = s.freeOffset()
+		if err != nil {
+			return err
+		}
+
+		if free+len(record) > s.end {
+			return fmt.Errorf("Not enough space left in the variable store for %q", v.Name)
+		}
+	}
+
+	copy(s.data[free:], record)
+
+	return nil
+}
+
+// walk calls fn with the offset and content of each variable currently set in the store.
+func (s *VarStore) walk(fn func(offset int, v Variable)) error {
+	offset := s.start
+	for offset+varHeaderSize <= s.end {
+		header := s.data[offset : offset+varHeaderSize]
+		if binary.LittleEndian.Uint16(header) != varStartID {
+			break
+		}
+
+		state := header[2]
+		nameSize := int(binary.LittleEndian.Uint32(header[36:]))
+		dataSize := int(binary.LittleEndian.Uint32(header[40:]))
+
+		nameOffset := offset + varHeaderSize
+		dataOffset := nameOffset + align(nameSize)
+		next := align(dataOffset + dataSize)
+		if nameSize < 0 || dataSize < 0 || dataOffset+dataSize > s.end {
+			return fmt.Errorf("Corrupted variable at offset %d", offset)
+		}
+
+		if state == varAdded || state == varAddedInDeletedPrefix {
+			v := Variable{
+				Name:           decodeName(s.data[nameOffset : nameOffset+nameSize]),
+				Attributes:     binary.LittleEndian.Uint32(header[4:]),
+				MonotonicCount: binary.LittleEndian.Uint64(header[8:]),
+				PubKeyIndex:    binary.LittleEndian.Uint32(header[32:]),
+				Data:           append([]byte{}, s.data[dataOffset:dataOffset+dataSize]...),
+			}
+
+			copy(v.TimeStamp[:], header[16:32])
+			copy(v.Vendor[:], header[44:60])
+
+			fn(offset, v)
+		}
+
+		offset = next
+	}
+
+	return nil
+}
+
+// freeOffset returns the offset following the last variable of the store.
+func (s *VarStore) freeOffset() (int, error) {
+	offset := s.start
+	for offset+varHeaderSize <= s.end && binary.LittleEndian.Uint16(s.data[offset:]) == varStartID {
+		nameSize := int(binary.LittleEndian.Uint32(s.data[offset+36:]))
+		dataSize := int(binary.LittleEndian.Uint32(s.data[offset+40:]))
+
+		offset = align(offset + varHeaderSize + align(nameSize) + dataSize)
+		if offset > s.end {
+			return 0, fmt.Errorf("Corrupted variable store")
+		}
+	}
+
+	return offset, nil
+}
+
+// reclaim rewrites the store without its deleted variables.
+func (s *VarStore) reclaim() error {
+	vars, err := s.Variables()
+	if err != nil {
+		return err
+	}
+
+	area := s.data[s.start:s.end]
+	for i := range area {
+		area[i] = 0xff
+	}
+
+	offset := s.start
+	for _, v := range vars {
+		offset += copy(s.data[offset:], encodeVariable(v))
+	}
+
+	return nil
+}
+
+// encodeVariable returns the variable as stored, padded to the header alignment.
+func encodeVariable(v Variable) []byte {
+	name := encodeName(v.Name)
+	record := bytes.Repeat([]byte{0xff}, align(varHeaderSize+align(len(name))+len(v.Data)))
+
+	binary.LittleEndian.PutUint16(record[0:], varStartID)
+	record[2] = varAdded
+	record[3] = 0
+	binary.LittleEndian.PutUint32(record[4:], v.Attributes)
+	binary.LittleEndian.PutUint64(record[8:], v.MonotonicCount)
+	copy(record[16:32], v.TimeStamp[:])
+	binary.LittleEndian.PutUint32(record[32:], v.PubKeyIndex)
+	binary.LittleEndian.PutUint32(record[36:], uint32(len(name)))
+	binary.LittleEndian.PutUint32(record[40:], uint32(len(v.Data)))
+	copy(record[44:60], v.Vendor[:])
+	copy(record[varHeaderSize:], name)
+	copy(record[varHeaderSize+align(len(name)):], v.Data)
+
+	return record
+}
+
+// encodeName returns the NUL terminated UCS-2 form of a variable name.
+func encodeName(name string) []byte {
+	chars := append(utf16.Encode([]rune(name)), 0)
+	raw := make([]byte, len(chars)*2)
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(raw[i*2:], c)
+	}
+
+	return raw
+}
+
+// decodeName returns the string held in a NUL terminated UCS-2 buffer.
+func decodeName(raw []byte) string {
+	chars := []uint16{}
+	for i := 0; i+1 < len(raw); i += 2 {
+		c := binary.LittleEndian.Uint16(raw[i:])
+		if c == 0 {
+			break
+		}
+
+		chars = append(chars, c)
+	}
+
+	return string(utf16.Decode(chars))
+}
+
+// align rounds n up to the 4 bytes alignment of the store.
+func align(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,137 @@
+package edk2
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore returns an empty NVRAM file holding a variable store of the given size.
+func newTestStore(size int) []byte {
+	headerLength := 72
+	data := make([]byte, headerLength+size)
+
+	copy(data[fvSignatureOffset:], "_FVH")
+	binary.LittleEndian.PutUint16(data[fvHeaderLengthOffset:], uint16(headerLength))
+	copy(data[headerLength:], authenticatedStoreGUID[:])
+	binary.LittleEndian.PutUint32(data[headerLength+storeSizeOffset:], uint32(size))
+
+	for i := headerLength + storeHeaderSize; i < len(data); i++ {
+		data[i] = 0xff
+	}
+
+	return data
+}
+
+// loadOption returns an active EFI_LOAD_OPTION with the given description and no device path.
+func loadOption(description string) []byte {
+	data := make([]byte, 6)
+	binary.LittleEndian.PutUint32(data, loadOptionActive)
+
+	return append(data, encodeName(description)...)
+}
+
+func TestGUID(t *testing.T) {
+	assert.Equal(t, "8be4df61-93ca-11d2-aa0d-00e098032b8c", GlobalVariable.String())
+	assert.Equal(t, byte(0x61), GlobalVariable[0])
+
+	_, err := ParseGUID("8be4df61")
+	assert.Error(t, err)
+}
+
+func TestVarStore(t *testing.T) {
+	_, err := Parse(make([]byte, 4096))
+	assert.Error(t, err)
+
+	s, err := Parse(newTestStore(4096))
+	require.NoError(t, err)
+
+	for _, name := range []string{"Boot0000", "Boot0001", "Boot0002"} {
+		err = s.Set(Variable{Name: name, Vendor: GlobalVariable, Attributes: AttributeNonVolatile, Data: loadOption("Entry " + name)})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s.SetBootOrder([]string{"0000", "0001", "0002"}))
+
+	// Round trip through the file content.
+	s, err = Parse(s.Bytes())
+	require.NoError(t, err)
+
+	options, err := s.BootOptions()
+	require.NoError(t, err)
+	require.Len(t, options, 3)
+	assert.Equal(t, BootOption{ID: "0001", Description: "Entry Boot0001", Active: true}, options[1])
+
+	// Same size, replaced in place.
+	require.NoError(t, s.SetBootOrder([]string{"0002", "0000", "0001"}))
+	order, err := s.BootOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0002", "0000", "0001"}, order)
+
+	// Different size, appended.
+	require.NoError(t, s.SetBootOrder([]string{"0001"}))
+	order, err = s.BootOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0001"}, order)
+
+	assert.Error(t, s.SetBootOrder([]string{"0003"}))
+	assert.Error(t, s.SetBootOrder([]string{"boot"}))
+
+	vars, err := s.Variables()
+	require.NoError(t, err)
+	assert.Len(t, vars, 4)
+}
+
+func TestVarStoreReclaim(t *testing.T) {
+	s, err := Parse(newTestStore(512))
+	require.NoError(t, err)
+
+	// Each update appends a new record, deleted ones have to be reclaimed to fit.
+	for i := 0; i < 50; i++ {
+		err = s.Set(Variable{Name: "Test", Vendor: GlobalVariable, Data: make([]byte, i%2+1)})
+		require.NoError(t, err)
+	}
+
+	vars, err := s.Variables()
+	require.NoError(t, err)
+	require.Len(t, vars, 1)
+	assert.Equal(t, []byte{0, 0}, vars[0].Data)
+
+	err = s.Set(Variable{Name: "Large", Vendor: GlobalVariable, Data: make([]byte, 1024)})
+	assert.Error(t, err)
+}
+
+func TestSecureBootKeys(t *testing.T) {
+	template, err := Parse(newTestStore(4096))
+	require.NoError(t, err)
+
+	for _, key := range SecureBootVariables {
+		require.NoError(t, template.Set(Variable{Name: key.Name, Vendor: key.Vendor, Data: []byte(key.Name)}))
+	}
+
+	s, err := Parse(newTestStore(4096))
+	require.NoError(t, err)
+
+	enrolled, err := s.SecureBootKeys()
+	require.NoError(t, err)
+	assert.False(t, enrolled)
+
+	require.NoError(t, s.EnrollSecureBootKeys(template))
+
+	enrolled, err = s.SecureBootKeys()
+	require.NoError(t, err)
+	assert.True(t, enrolled)
+
+	v, err := s.Get("db", ImageSecurityDatabase)
+	require.NoError(t, err)
+	require.NotNil(t, v)
+	assert.Equal(t, []byte("db"), v.Data)
+
+	require.NoError(t, s.ClearSecureBootKeys())
+
+	enrolled, err = s.SecureBootKeys()
+	require.NoError(t, err)
+	assert.False(t, enrolled)
+}
@@ -338,6 +338,160 @@ func (m *Monitor) RemoveNIC(netDevID string, deviceID string) error {
 	return nil
 }
 
+// AddFDSet adds a file descriptor to a new fd set identified by name and returns the fd set ID.
+// The file can then be opened by QEMU through the /dev/fdset/<ID> path.
+func (m *Monitor) AddFDSet(name string, file *os.File) (int, error) {
+	// Check if disconnected
+	if m.disconnected {
+		return -1, ErrMonitorDisconnect
+	}
+
+	out, err := m.qmp.RunWithFile([]byte(fmt.Sprintf("{'execute': 'add-fd', 'arguments': {'opaque': '%s'}}", name)), file)
+	if err != nil {
+		// Confirm the daemon didn't die.
+		errPing := m.ping()
+		if errPing != nil {
+			return -1, errPing
+		}
+
+		return -1, errors.Wrapf(err, "Failed adding fd set")
+	}
+
+	var resp struct {
+		Return struct {
+			ID int `json:"fdset-id"`
+		} `json:"return"`
+	}
+
+	err = json.Unmarshal(out, &resp)
+	if err != nil {
+		return -1, ErrMonitorBadReturn
+	}
+
+	return resp.Return.ID, nil
+}
+
+// RemoveFDSet removes the fd sets identified by name.
+func (m *Monitor) RemoveFDSet(name string) error {
+	var resp struct {
+		Return []struct {
+			ID  int `json:"fdset-id"`
+			FDs []struct {
+				Opaque string `json:"opaque"`
+			} `json:"fds"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-fdsets", "", &resp)
+	if err != nil {
+		return errors.Wrapf(err, "Failed querying fd sets")
+	}
+
+	for _, fdSet := range resp.Return {
+		for _, fd := range fdSet.FDs {
+			if fd.Opaque != name {
+				continue
+			}
+
+			err = m.run("remove-fd", fmt.Sprintf("{'fdset-id': %d}", fdSet.ID), nil)
+			if err != nil {
+				return errors.Wrapf(err, "Failed removing fd set %d", fdSet.ID)
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// AddBlockDevice adds a block device.
+func (m *Monitor) AddBlockDevice(blockDev map[string]interface{}, device map[string]string) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	nodeName, ok := blockDev["node-name"].(string)
+	if !ok {
+		return fmt.Errorf("Block device node name is required")
+	}
+
+	args, err := json.Marshal(blockDev)
+	if err != nil {
+		return err
+	}
+
+	err = m.run("blockdev-add", string(args), nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding block device")
+	}
+
+	revert.Add(func() {
+		args, err := json.Marshal(map[string]string{"node-name": nodeName})
+		if err != nil {
+			return
+		}
+
+		m.run("blockdev-del", string(args), nil)
+	})
+
+	if device != nil {
+		args, err := json.Marshal(device)
+		if err != nil {
+			return err
+		}
+
+		err = m.run("device_add", string(args), nil)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding block device's device")
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// RemoveBlockDevice removes a block device and the device using it.
+func (m *Monitor) RemoveBlockDevice(nodeName string, deviceID string) error {
+	if deviceID != "" {
+		args, err := json.Marshal(map[string]string{"id": deviceID})
+		if err != nil {
+			return err
+		}
+
+		err = m.run("device_del", string(args), nil)
+
+		// If the device has already been removed then all good.
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return errors.Wrapf(err, "Failed removing block device's device")
+		}
+	}
+
+	if nodeName != "" {
+		args, err := json.Marshal(map[string]string{"node-name": nodeName})
+		if err != nil {
+			return err
+		}
+
+		// The guest releases the device asynchronously, so wait for the block device not to be in use.
+		// Block devices attached when the VM was started are removed along with their device.
+		waitUntil := time.Now().Add(10 * time.Second)
+		for {
+			err = m.run("blockdev-del", string(args), nil)
+			if err == nil || strings.Contains(err.Error(), "Failed to find node") {
+				break
+			}
+
+			if time.Now().After(waitUntil) {
+				return errors.Wrapf(err, "Failed removing block device")
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
 // Reset VM.
 func (m *Monitor) Reset() error {
 	err := m.run("system_reset", "", nil)
@@ -390,3 +544,67 @@ func (m *Monitor) QueryPCI() ([]PCIDevice, error) {
 
 	return nil, nil
 }
+
+// SEVInfo represents the memory encryption state of a guest as reported by the firmware.
+type SEVInfo struct {
+	Enabled  bool   `json:"enabled"`
+	APIMajor int    `json:"api-major"`
+	APIMinor int    `json:"api-minor"`
+	BuildID  int    `json:"build-id"`
+	State    string `json:"state"`
+}
+
+// QuerySEV returns the memory encryption state of the guest.
+func (m *Monitor) QuerySEV() (*SEVInfo, error) {
+	// Prepare the response.
+	var resp struct {
+		Return SEVInfo `json:"return"`
+	}
+
+	err := m.run("query-sev", "", &resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed querying SEV state")
+	}
+
+	return &resp.Return, nil
+}
+
+// SEVLaunchMeasurement returns the base64 encoded launch measurement of a SEV or SEV-ES guest.
+func (m *Monitor) SEVLaunchMeasurement() (string, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			Data string `json:"data"`
+		} `json:"return"`
+	}
+
+	err := m.run("query-sev-launch-measure", "", &resp)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed querying SEV launch measurement")
+	}
+
+	return resp.Return.Data, nil
+}
+
+// SEVAttestationReport returns the base64 encoded attestation report of a SEV or SEV-ES guest, including the
+// base64 encoded nonce.
+func (m *Monitor) SEVAttestationReport(nonce string) (string, error) {
+	// Prepare the response.
+	var resp struct {
+		Return struct {
+			Data string `json:"data"`
+		} `json:"return"`
+	}
+
+	args, err := json.Marshal(map[string]string{"mnonce": nonce})
+	if err != nil {
+		return "", err
+	}
+
+	err = m.run("query-sev-attestation-report", string(args), &resp)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed querying SEV attestation report")
+	}
+
+	return resp.Return.Data, nil
+}
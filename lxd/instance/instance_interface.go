@@ -1,6 +1,7 @@
 package instance
 
 import (
+	"context"
 	"io"
 	"os"
 	"time"
@@ -99,15 +100,20 @@ type Instance interface {
 	FilePull(srcpath string, dstpath string) (int64, int64, os.FileMode, string, []string, error)
 	FilePush(fileType string, srcpath string, dstpath string, uid int64, gid int64, mode int, write string) error
 	FileRemove(path string) error
+	FilePullRecursive(srcPath string, w io.Writer) error
+	FilePushRecursive(dstPath string, r io.Reader) error
+	FileWatch(ctx context.Context, path string, recursive bool, handler func(event api.InstanceFileEvent)) error
 
 	// Console - Allocate and run a console tty or a spice Unix socket.
 	Console(protocol string) (*os.File, chan error, error)
+	ConsoleLogCapture() error
 	Exec(req api.InstanceExecPost, stdin *os.File, stdout *os.File, stderr *os.File) (Cmd, error)
 
 	// Status
 	Render(options ...func(response interface{}) error) (interface{}, interface{}, error)
 	RenderFull() (*api.InstanceFull, interface{}, error)
 	RenderState() (*api.InstanceState, error)
+	Processes(sortBy string, limit int) (*api.InstanceProcesses, error)
 	IsRunning() bool
 	IsFrozen() bool
 	IsEphemeral() bool
@@ -168,6 +174,27 @@ type Container interface {
 	IdmappedStorage(path string) idmap.IdmapStorageType
 }
 
+// VM interface is for VM specific functions.
+type VM interface {
+	Instance
+
+	SetAffinity(set []string) error
+	SetMemoryBalloon(sizeBytes int64) error
+
+	// UEFI variables.
+	NVRAM() (*api.InstanceNVRAM, error)
+	NVRAMUpdate(nvram api.InstanceNVRAMPut) error
+	NVRAMReset() error
+
+	// QEMU machine type.
+	MachineType() (*api.InstanceMachineType, error)
+	MachineTypeUpgrade(machineType string) error
+
+	// AMD SEV memory encryption.
+	SEV() (*api.InstanceSEV, error)
+	SEVReport(nonce []byte) ([]byte, error)
+}
+
 // CriuMigrationArgs arguments for CRIU migration.
 type CriuMigrationArgs struct {
 	Cmd          uint
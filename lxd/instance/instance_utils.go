@@ -184,6 +184,15 @@ func ValidConfig(sysOS *sys.OS, config map[string]string, expanded bool, instanc
 		return err
 	}
 
+	_, err = project.AppArmorExtraRules(config["raw.apparmor.extra"])
+	if err != nil {
+		return errors.Wrap(err, "Invalid raw.apparmor.extra")
+	}
+
+	if shared.IsTrue(config["security.sev"]) && shared.IsTrue(config["migration.stateful"]) {
+		return fmt.Errorf("security.sev can't be used with migration.stateful as the guest memory can't be saved")
+	}
+
 	if expanded && (config["security.privileged"] == "" || !shared.IsTrue(config["security.privileged"])) && sysOS.IdmapSet == nil {
 		return fmt.Errorf("LXD doesn't have a uid/gid allocation. In this mode, only privileged containers are supported")
 	}
@@ -0,0 +1,220 @@
+package processes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// SampleInterval is the interval over which the CPU usage of the processes is measured.
+const SampleInterval = 500 * time.Millisecond
+
+// DefaultLimit is the number of processes returned when no limit is requested.
+const DefaultLimit = 10
+
+// userHZ is the unit of the CPU times reported in /proc, fixed for userspace regardless of the kernel tick rate.
+const userHZ = 100
+
+// ParseQuery returns the sort order ("cpu" or "memory") and the number of processes (0 for all of them)
+// requested through the sort and limit query parameters.
+func ParseQuery(values url.Values) (string, int, error) {
+	sortBy := values.Get("sort")
+	if sortBy == "" {
+		sortBy = "cpu"
+	}
+
+	if sortBy != "cpu" && sortBy != "memory" {
+		return "", -1, fmt.Errorf("Invalid sort order %q (must be cpu or memory)", sortBy)
+	}
+
+	limit := DefaultLimit
+	if values.Get("limit") != "" {
+		var err error
+		limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil || limit < 0 {
+			return "", -1, fmt.Errorf("Invalid limit %q", values.Get("limit"))
+		}
+	}
+
+	return sortBy, limit, nil
+}
+
+// Top returns the processes of /proc accepted by the selector (all of them when nil), with their CPU usage
+// sampled over SampleInterval, sorted by decreasing CPU usage or memory usage depending on sortBy and truncated
+// to the limit (all of them when 0). The UIDs are translated by mapUID when set. The processes started during
+// the sampling interval aren't reported.
+func Top(selector func(pid int64) bool, mapUID func(uid int64) int64, sortBy string, limit int) (*api.InstanceProcesses, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	// First sample of the CPU time of the processes.
+	type sample struct {
+		ticks     int64
+		startTime string
+	}
+
+	samples := map[int64]sample{}
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if selector != nil && !selector(pid) {
+			continue
+		}
+
+		ticks, startTime, err := readStat(pid)
+		if err != nil {
+			// The process exited.
+			continue
+		}
+
+		samples[pid] = sample{ticks: ticks, startTime: startTime}
+	}
+
+	time.Sleep(SampleInterval)
+
+	processes := []api.InstanceProcess{}
+	for pid, first := range samples {
+		ticks, startTime, err := readStat(pid)
+		if err != nil || startTime != first.startTime {
+			// The process exited (and the PID may have been reused).
+			continue
+		}
+
+		process, err := readStatus(pid)
+		if err != nil {
+			continue
+		}
+
+		if mapUID != nil {
+			process.UID = mapUID(process.UID)
+		}
+
+		process.CPUUsage = float64(ticks-first.ticks) / userHZ / SampleInterval.Seconds() * 100
+		process.Command = readCmdline(pid)
+		if process.Command == "" {
+			// Kernel threads don't have a command line.
+			process.Command = fmt.Sprintf("[%s]", process.Name)
+		}
+
+		processes = append(processes, *process)
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		if sortBy == "memory" && processes[i].MemoryUsage != processes[j].MemoryUsage {
+			return processes[i].MemoryUsage > processes[j].MemoryUsage
+		}
+
+		if processes[i].CPUUsage != processes[j].CPUUsage {
+			return processes[i].CPUUsage > processes[j].CPUUsage
+		}
+
+		if processes[i].MemoryUsage != processes[j].MemoryUsage {
+			return processes[i].MemoryUsage > processes[j].MemoryUsage
+		}
+
+		return processes[i].PID < processes[j].PID
+	})
+
+	top := &api.InstanceProcesses{Total: int64(len(processes))}
+	if limit > 0 && len(processes) > limit {
+		processes = processes[:limit]
+	}
+
+	top.Processes = processes
+
+	return top, nil
+}
+
+// readStat returns the CPU time (in ticks) and the start time of the process.
+func readStat(pid int64) (int64, string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return -1, "", err
+	}
+
+	// Skip past the command name as it may contain spaces.
+	idx := strings.LastIndex(string(content), ")")
+	if idx < 0 {
+		return -1, "", fmt.Errorf("Invalid stat file for process %d", pid)
+	}
+
+	// The user and system times are the 14th and 15th fields, the start time the 22nd (the 12th, 13th and
+	// 20th after the command name).
+	fields := strings.Fields(string(content[idx+1:]))
+	if len(fields) < 20 {
+		return -1, "", fmt.Errorf("Invalid stat file for process %d", pid)
+	}
+
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return -1, "", err
+	}
+
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return -1, "", err
+	}
+
+	return utime + stime, fields[19], nil
+}
+
+// readStatus returns the process with the details found in its status file.
+func readStatus(pid int64) (*api.InstanceProcess, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	process := &api.InstanceProcess{PID: pid, UID: -1}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		value := strings.Fields(fields[1])
+		if len(value) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "Name":
+			process.Name = strings.TrimSpace(fields[1])
+		case "State":
+			process.State = value[0]
+		case "Uid":
+			process.UID, _ = strconv.ParseInt(value[0], 10, 64)
+		case "Threads":
+			process.Threads, _ = strconv.ParseInt(value[0], 10, 64)
+		case "VmRSS":
+			rss, _ := strconv.ParseInt(value[0], 10, 64)
+			process.MemoryUsage = rss * 1024
+		case "NSpid":
+			// The last entry is the PID in the innermost PID namespace of the process.
+			process.PID, _ = strconv.ParseInt(value[len(value)-1], 10, 64)
+		}
+	}
+
+	return process, nil
+}
+
+// readCmdline returns the command line of the process, with its arguments separated by spaces.
+func readCmdline(pid int64) string {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.Replace(string(content), "\x00", " ", -1))
+}
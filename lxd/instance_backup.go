@@ -294,7 +294,32 @@ func instanceBackupsPost(d *Daemon, r *http.Request) response.Response {
 	fullName := name + shared.SnapshotDelimiter + req.Name
 	instanceOnly := req.InstanceOnly || req.ContainerOnly
 
+	// Validate the format.
+	switch req.Format {
+	case "", backupFormatTarball:
+	case backupFormatQcow2, backupFormatOVA:
+		if inst.Type() != instancetype.VM {
+			return response.BadRequest(fmt.Errorf("Only virtual machines can be exported as %s", req.Format))
+		}
+
+		if req.OptimizedStorage {
+			return response.BadRequest(fmt.Errorf("Optimized storage can't be used with the %s format", req.Format))
+		}
+
+		// The snapshots can't be included in the disk image.
+		instanceOnly = true
+	default:
+		return response.BadRequest(fmt.Errorf("Invalid backup format %q", req.Format))
+	}
+
 	backup := func(op *operations.Operation) error {
+		// Wait for our turn if the number of concurrent backups is limited.
+		releaseQueue, err := operations.QueueAcquire(d.ctx, op, operations.QueueBackups, operations.QueuePriorityNormal)
+		if err != nil {
+			return errors.Wrapf(err, "Failed waiting for backup slot")
+		}
+		defer releaseQueue()
+
 		args := db.InstanceBackup{
 			Name:                 fullName,
 			InstanceID:           inst.ID(),
@@ -305,7 +330,7 @@ func instanceBackupsPost(d *Daemon, r *http.Request) response.Response {
 			CompressionAlgorithm: req.CompressionAlgorithm,
 		}
 
-		err := backupCreate(d.State(), args, inst)
+		err = backupCreate(d.State(), args, inst, req.Format)
 		if err != nil {
 			return errors.Wrap(err, "Create backup")
 		}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -15,14 +18,18 @@ import (
 	liblxc "gopkg.in/lxc/go-lxc.v2"
 
 	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/consolelog"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/termios"
 )
@@ -506,15 +513,28 @@ func instanceConsolePost(d *Daemon, r *http.Request) response.Response {
 //
 // Gets the console log for the instance.
 //
+// The console output is kept across restarts of the instance and of LXD, up to a size limit.
+//
 // ---
 // produces:
 //   - application/json
+//   - application/octet-stream
 // parameters:
 //   - in: query
 //     name: project
 //     description: Project name
 //     type: string
 //     example: default
+//   - in: query
+//     name: since
+//     description: Only return the output captured at or after this time (RFC3339)
+//     type: string
+//     example: 2021-03-23T17:38:37Z
+//   - in: query
+//     name: timestamps
+//     description: Return the output as a list of timestamped entries
+//     type: boolean
+//     example: true
 // responses:
 //   "200":
 //      description: Raw console log
@@ -523,6 +543,11 @@ func instanceConsolePost(d *Daemon, r *http.Request) response.Response {
 //          schema:
 //            type: string
 //            example: some-text
+//        application/json:
+//          schema:
+//            type: array
+//            items:
+//              $ref: "#/definitions/InstanceConsoleLogEntry"
 //   "400":
 //     $ref: "#/responses/BadRequest"
 //   "403":
@@ -549,8 +574,12 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
-	if !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) {
-		return response.BadRequest(fmt.Errorf("Querying the console buffer requires liblxc >= 3.0"))
+	since := time.Time{}
+	if r.FormValue("since") != "" {
+		since, err = time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid since time %q: %v", r.FormValue("since"), err))
+		}
 	}
 
 	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
@@ -558,44 +587,34 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if inst.Type() != instancetype.Container {
-		return response.SmartError(fmt.Errorf("Instance is not container type"))
+	if inst.Type() == instancetype.Container && !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) {
+		return response.BadRequest(fmt.Errorf("Querying the console buffer requires liblxc >= 3.0"))
 	}
 
-	c := inst.(instance.Container)
-	ent := response.FileResponseEntry{}
-	if !c.IsRunning() {
-		// Hand back the contents of the console ringbuffer logfile.
-		consoleBufferLogPath := c.ConsoleBufferLogPath()
-		ent.Path = consoleBufferLogPath
-		ent.Filename = consoleBufferLogPath
-		return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
+	// Include the output produced since the last capture.
+	err = inst.ConsoleLogCapture()
+	if err != nil {
+		return response.SmartError(err)
 	}
 
-	// Query the container's console ringbuffer.
-	console := liblxc.ConsoleLogOptions{
-		ClearLog:       false,
-		ReadLog:        true,
-		ReadMax:        0,
-		WriteToLogFile: true,
+	entries, err := consolelog.Get(inst.LogPath()).Entries(since)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
-	// Send a ringbuffer request to the container.
-	logContents, err := c.ConsoleLog(console)
-	if err != nil {
-		errno, isErrno := shared.GetErrno(err)
-		if !isErrno {
-			return response.SmartError(err)
-		}
+	d.State().Events.SendLifecycle(projectName, lifecycle.InstanceConsoleRetrieved.Event(inst, nil))
 
-		if errno == unix.ENODATA {
-			return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
-		}
+	if shared.IsTrue(r.FormValue("timestamps")) {
+		return response.SyncResponse(true, entries)
+	}
 
-		return response.SmartError(err)
+	buf := bytes.Buffer{}
+	for _, entry := range entries {
+		buf.WriteString(entry.Output)
 	}
 
-	ent.Buffer = []byte(logContents)
+	ent := response.FileResponseEntry{}
+	ent.Buffer = buf.Bytes()
 	return response.FileResponse(r, []response.FileResponseEntry{ent}, nil, false)
 }
 
@@ -626,10 +645,6 @@ func instanceConsoleLogGet(d *Daemon, r *http.Request) response.Response {
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func instanceConsoleLogDelete(d *Daemon, r *http.Request) response.Response {
-	if !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) {
-		return response.BadRequest(fmt.Errorf("Clearing the console buffer requires liblxc >= 3.0"))
-	}
-
 	name := mux.Vars(r)["name"]
 	projectName := projectParam(r)
 
@@ -638,12 +653,34 @@ func instanceConsoleLogDelete(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if inst.Type() != instancetype.Container {
-		return response.SmartError(fmt.Errorf("Instance is not container type"))
+	if inst.Type() == instancetype.Container {
+		if !util.RuntimeLiblxcVersionAtLeast(3, 0, 0) {
+			return response.BadRequest(fmt.Errorf("Clearing the console buffer requires liblxc >= 3.0"))
+		}
+
+		err = instanceConsoleBufferClear(inst.(instance.Container))
+		if err != nil {
+			return response.SmartError(err)
+		}
+	} else {
+		// Drop the output QEMU wrote since the last capture.
+		err = os.Truncate(inst.ConsoleBufferLogPath(), 0)
+		if err != nil && !os.IsNotExist(err) {
+			return response.SmartError(err)
+		}
+	}
+
+	err = consolelog.Get(inst.LogPath()).Clear()
+	if err != nil {
+		return response.SmartError(err)
 	}
 
-	c := inst.(instance.Container)
+	return response.EmptySyncResponse
+}
 
+// instanceConsoleBufferClear clears the console ringbuffer of a running container or the console log file of a
+// stopped one.
+func instanceConsoleBufferClear(c instance.Container) error {
 	truncateConsoleLogFile := func(path string) error {
 		// Check that this is a regular file. We don't want to try and unlink
 		// /dev/stderr or /dev/null or something.
@@ -663,9 +700,13 @@ func instanceConsoleLogDelete(d *Daemon, r *http.Request) response.Response {
 		return os.Truncate(path, 0)
 	}
 
-	if !inst.IsRunning() {
-		consoleLogpath := c.ConsoleBufferLogPath()
-		return response.SmartError(truncateConsoleLogFile(consoleLogpath))
+	if !c.IsRunning() {
+		err := truncateConsoleLogFile(c.ConsoleBufferLogPath())
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
 	}
 
 	// Send a ringbuffer request to the container.
@@ -676,19 +717,49 @@ func instanceConsoleLogDelete(d *Daemon, r *http.Request) response.Response {
 		WriteToLogFile: false,
 	}
 
-	_, err = c.ConsoleLog(console)
+	_, err := c.ConsoleLog(console)
 	if err != nil {
 		errno, isErrno := shared.GetErrno(err)
-		if !isErrno {
-			return response.SmartError(err)
+		if isErrno && errno == unix.ENODATA {
+			return nil
 		}
 
-		if errno == unix.ENODATA {
-			return response.SmartError(nil)
+		return err
+	}
+
+	return nil
+}
+
+// instanceConsoleLogInterval is how often the console output of the instances is captured.
+const instanceConsoleLogInterval = 15 * time.Second
+
+// instanceConsoleLogTask moves the console output of the running local instances to their persistent console log.
+// The instances are tracked as they start and stop, the running ones are only loaded on the first run to pick up
+// those started before LXD.
+func instanceConsoleLogTask(d *Daemon) (task.Func, task.Schedule) {
+	loaded := false
+
+	f := func(ctx context.Context) {
+		if !loaded {
+			instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+			if err != nil {
+				logger.Error("Problem loading instances list", log.Ctx{"err": err})
+				return
+			}
+
+			for _, inst := range instances {
+				if inst.IsRunning() {
+					consolelog.Track(inst.LogPath(), inst.ConsoleLogCapture)
+				}
+			}
+
+			loaded = true
 		}
 
-		return response.SmartError(err)
+		for logPath, err := range consolelog.CaptureAll() {
+			logger.Debug("Failed capturing console output", log.Ctx{"path": logPath, "err": err})
+		}
 	}
 
-	return response.SmartError(nil)
+	return f, task.Every(instanceConsoleLogInterval)
 }
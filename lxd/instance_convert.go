@@ -0,0 +1,524 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/instancewriter"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/logging"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// instanceConvertKeptPaths are the paths of the base image kept in the virtual machine converted from a container:
+// the kernel, bootloader, firmware, filesystem table and the LXD agent setup.
+var instanceConvertKeptPaths = []string{
+	"boot",
+	"etc/default/grub",
+	"etc/default/grub.d",
+	"etc/fstab",
+	"etc/systemd/system/lxd-agent.service",
+	"etc/systemd/system/multi-user.target.wants/lxd-agent.service",
+	"lib/firmware",
+	"lib/modules",
+	"lib/systemd/lxd-agent",
+	"lib/systemd/system/lxd-agent.service",
+	"lib/udev/rules.d/99-lxd-agent.rules",
+	"lost+found",
+	"usr/lib/firmware",
+	"usr/lib/modules",
+	"usr/lib/systemd/lxd-agent",
+	"usr/lib/systemd/system/lxd-agent.service",
+	"usr/lib/udev/rules.d/99-lxd-agent.rules",
+}
+
+// createFromConversion creates a virtual machine from a stopped container, replacing the root filesystem of a base
+// virtual machine image by the one of the container while keeping the kernel and bootloader of the image. The
+// configuration and devices of the container are carried over when they apply to virtual machines.
+func createFromConversion(d *Daemon, r *http.Request, projectName string, req *api.InstancesPost) response.Response {
+	if d.cluster.LocalNodeIsEvacuated() {
+		return response.Forbidden(fmt.Errorf("Node is evacuated"))
+	}
+
+	if req.Type != api.InstanceTypeVM {
+		return response.BadRequest(fmt.Errorf("Containers can only be converted to virtual machines"))
+	}
+
+	if req.Source.Source == "" {
+		return response.BadRequest(fmt.Errorf("Must specify a source container"))
+	}
+
+	if req.Source.Alias == "" && req.Source.Fingerprint == "" {
+		return response.BadRequest(fmt.Errorf("Must specify a base virtual machine image"))
+	}
+
+	source, err := instance.LoadByProjectAndName(d.State(), projectName, req.Source.Source)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if source.Type() != instancetype.Container {
+		return response.BadRequest(fmt.Errorf("Only containers can be converted"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if clustered {
+		var serverName string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			serverName, err = tx.GetLocalNodeName()
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if serverName != source.Location() {
+			return response.BadRequest(fmt.Errorf("The virtual machine must be created on the cluster member of the container (%s)", source.Location()))
+		}
+	}
+
+	// Only use the local images, the base is only needed for its kernel and bootloader.
+	hash, err := instance.ResolveImage(d.State(), projectName, api.InstanceSource{
+		Alias:       req.Source.Alias,
+		Fingerprint: req.Source.Fingerprint,
+	})
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, img, err := d.cluster.GetImage(hash, db.ImageFilter{Project: &projectName})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if img.Type != string(api.InstanceTypeVM) {
+		return response.BadRequest(fmt.Errorf("The base image %q isn't a virtual machine image", hash))
+	}
+
+	config, devices := instanceConvertConfig(source)
+	for k, v := range req.Config {
+		config[k] = v
+	}
+
+	for name, dev := range req.Devices {
+		devices[name] = dev
+	}
+
+	profiles := req.Profiles
+	if profiles == nil {
+		profiles = source.Profiles()
+	}
+
+	description := req.Description
+	if description == "" {
+		description = source.Description()
+	}
+
+	run := func(op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+
+		if source.IsRunning() {
+			return fmt.Errorf("The container must be stopped to be converted")
+		}
+
+		args := db.InstanceArgs{
+			Project:     projectName,
+			Config:      config,
+			Type:        instancetype.VM,
+			Description: description,
+			Devices:     deviceConfig.NewDevices(devices),
+			Name:        req.Name,
+			Profiles:    profiles,
+		}
+
+		err := instance.ValidName(args.Name, args.Snapshot)
+		if err != nil {
+			return err
+		}
+
+		args.Architecture, err = osarch.ArchitectureId(img.Architecture)
+		if err != nil {
+			return err
+		}
+
+		inst, err := instanceCreateFromImage(d, r, args, img.Fingerprint, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { inst.Delete(true) })
+
+		err = instanceConvertRootfs(d.State(), source.(instance.Container), inst, op)
+		if err != nil {
+			return errors.Wrap(err, "Failed converting the container root filesystem")
+		}
+
+		revert.Success()
+		return instanceUpdateTags(d.State(), inst, req.Tags)
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{req.Name, req.Source.Source}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationInstanceCreate, resources, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceConvertConfig returns the local configuration and devices of the container which apply to virtual
+// machines. The network interfaces keep their MAC addresses.
+func instanceConvertConfig(source instance.Instance) (map[string]string, map[string]map[string]string) {
+	devices := map[string]map[string]string{}
+	for name, dev := range source.LocalDevices().CloneNative() {
+		switch dev["type"] {
+		case "nic", "disk", "usb", "tpm":
+		case "gpu":
+			if !shared.StringInSlice(dev["gputype"], []string{"", "physical", "mdev"}) {
+				logger.Warn("Skipping device not supported by virtual machines", log.Ctx{"instance": source.Name(), "device": name})
+				continue
+			}
+
+		case "proxy":
+			if !shared.IsTrue(dev["nat"]) {
+				logger.Warn("Skipping device not supported by virtual machines", log.Ctx{"instance": source.Name(), "device": name})
+				continue
+			}
+
+		default:
+			logger.Warn("Skipping device not supported by virtual machines", log.Ctx{"instance": source.Name(), "device": name})
+			continue
+		}
+
+		devices[name] = dev
+	}
+
+	config := map[string]string{}
+	for k, v := range source.LocalConfig() {
+		if strings.HasPrefix(k, shared.ConfigVolatilePrefix) {
+			fields := strings.SplitN(k, ".", 3)
+			if len(fields) != 3 || fields[2] != "hwaddr" || devices[fields[1]]["type"] != "nic" {
+				continue
+			}
+		} else {
+			_, err := shared.ConfigKeyChecker(k, instancetype.VM)
+			if err != nil {
+				logger.Warn("Skipping configuration key not supported by virtual machines", log.Ctx{"instance": source.Name(), "key": k})
+				continue
+			}
+		}
+
+		config[k] = v
+	}
+
+	return config, devices
+}
+
+// instanceConvertRootfs replaces the userspace of the root partition of the virtual machine by the root filesystem
+// of the container, keeping the instanceConvertKeptPaths of the virtual machine. The root partition is first grown
+// to the size of the volume when growpart is available.
+func instanceConvertRootfs(s *state.State, source instance.Container, inst instance.Instance, op *operations.Operation) error {
+	l := logging.AddContext(logger.Log, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "source": source.Name()})
+
+	sourcePool, err := storagePools.GetPoolByInstance(s, source)
+	if err != nil {
+		return err
+	}
+
+	_, err = sourcePool.MountInstance(source, op)
+	if err != nil {
+		return err
+	}
+	defer sourcePool.UnmountInstance(source, op)
+
+	idmap, err := source.DiskIdmap()
+	if err != nil {
+		return errors.Wrap(err, "Failed getting the container disk idmap")
+	}
+
+	pool, err := storagePools.GetPoolByInstance(s, inst)
+	if err != nil {
+		return err
+	}
+
+	mountInfo, err := pool.MountInstance(inst, op)
+	if err != nil {
+		return err
+	}
+	defer pool.UnmountInstance(inst, op)
+
+	if mountInfo.DiskPath == "" {
+		return fmt.Errorf("No disk path available from mount")
+	}
+
+	// Expose the partitions of the disk.
+	out, err := shared.RunCommand("losetup", "--find", "--show", "--partscan", mountInfo.DiskPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed attaching the disk to a loop device")
+	}
+
+	loopDev := strings.TrimSpace(out)
+	defer shared.RunCommand("losetup", "--detach", loopDev)
+
+	partition, fsType, err := instanceConvertRootPartition(loopDev)
+	if err != nil {
+		return err
+	}
+
+	l.Debug("Found root partition", log.Ctx{"partition": partition, "fstype": fsType})
+
+	_, err = exec.LookPath("growpart")
+	if err == nil {
+		partNum := strings.TrimPrefix(partition, loopDev+"p")
+		_, err = shared.RunCommand("growpart", loopDev, partNum)
+		if err != nil && !strings.Contains(err.Error(), "NOCHANGE") {
+			return errors.Wrap(err, "Failed growing the root partition")
+		}
+
+		if fsType == "ext4" {
+			// e2fsck exits with 1 when it fixed errors.
+			_, err = shared.RunCommand("e2fsck", "-f", "-p", partition)
+			if err != nil {
+				runErr, ok := err.(shared.RunError)
+				exitErr, isExitErr := runErr.Err.(*exec.ExitError)
+				if !ok || !isExitErr || exitErr.ExitCode() != 1 {
+					return errors.Wrap(err, "Failed checking the root filesystem")
+				}
+			}
+
+			_, err = shared.RunCommand("resize2fs", partition)
+			if err != nil {
+				return errors.Wrap(err, "Failed growing the root filesystem")
+			}
+		}
+	}
+
+	rootPath, err := ioutil.TempDir(shared.VarPath("images"), "lxd_convert_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(rootPath)
+
+	err = unix.Mount(partition, rootPath, fsType, 0, "")
+	if err != nil {
+		return errors.Wrapf(err, "Failed mounting the root partition %q", partition)
+	}
+	defer unix.Unmount(rootPath, unix.MNT_DETACH)
+
+	switch fsType {
+	case "xfs":
+		_, err = shared.RunCommand("xfs_growfs", rootPath)
+	case "btrfs":
+		_, err = shared.RunCommand("btrfs", "filesystem", "resize", "max", rootPath)
+	}
+	if err != nil {
+		l.Warn("Failed growing the root filesystem", log.Ctx{"err": err})
+	}
+
+	// Remove the userspace of the base image.
+	err = filepath.Walk(rootPath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." {
+			return nil
+		}
+
+		if instanceConvertKept(relPath) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		// Keep the parent directories of the kept paths.
+		for _, keptPath := range instanceConvertKeptPaths {
+			if strings.HasPrefix(keptPath, relPath+"/") {
+				return nil
+			}
+		}
+
+		err = os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed cleaning the root partition")
+	}
+
+	// Copy the root filesystem of the container, unshifting it.
+	l.Debug("Copying the container root filesystem")
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("tar", "-C", rootPath, "-xpf", "-", "--numeric-owner", "--xattrs", "--xattrs-include=*", "--acls")
+	cmd.Stderr = stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	sourcePath := source.RootfsPath()
+	tarWriter := instancewriter.NewInstanceTarWriter(stdin, idmap)
+	err = filepath.Walk(sourcePath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." {
+			return nil
+		}
+
+		if instanceConvertKept(relPath) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		return tarWriter.WriteFile(relPath, path, fi, false)
+	})
+	if err == nil {
+		err = tarWriter.Close()
+	}
+
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if err != nil {
+		return err
+	}
+
+	if waitErr != nil {
+		return fmt.Errorf("Failed extracting the container root filesystem: %v (%s)", waitErr, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// instanceConvertKept returns whether the path (relative to the root) is one of the instanceConvertKeptPaths or
+// is inside one of them.
+func instanceConvertKept(relPath string) bool {
+	for _, keptPath := range instanceConvertKeptPaths {
+		if relPath == keptPath || strings.HasPrefix(relPath, keptPath+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// instanceConvertRootPartition returns the path and filesystem of the root partition of the disk attached to the
+// loop device, that is its largest partition with a Linux filesystem.
+func instanceConvertRootPartition(loopDev string) (string, string, error) {
+	out, err := shared.RunCommand("lsblk", "--json", "--bytes", "--output", "PATH,SIZE,TYPE", loopDev)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed listing the disk partitions")
+	}
+
+	disks := struct {
+		BlockDevices []struct {
+			Children []struct {
+				Path string      `json:"path"`
+				Size json.Number `json:"size"`
+				Type string      `json:"type"`
+			} `json:"children"`
+		} `json:"blockdevices"`
+	}{}
+
+	err = json.Unmarshal([]byte(out), &disks)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Failed parsing the disk partitions")
+	}
+
+	partition := ""
+	fsType := ""
+	var partitionSize int64
+	for _, disk := range disks.BlockDevices {
+		for _, part := range disk.Children {
+			if part.Type != "part" {
+				continue
+			}
+
+			size, err := strconv.ParseInt(part.Size.String(), 10, 64)
+			if err != nil || size <= partitionSize {
+				continue
+			}
+
+			out, err := shared.RunCommand("blkid", "-o", "value", "-s", "TYPE", part.Path)
+			if err != nil {
+				continue
+			}
+
+			partType := strings.TrimSpace(out)
+			if !shared.StringInSlice(partType, []string{"ext4", "xfs", "btrfs"}) {
+				continue
+			}
+
+			partition = part.Path
+			fsType = partType
+			partitionSize = size
+		}
+	}
+
+	if partition == "" {
+		return "", "", fmt.Errorf("No root partition found in the base image")
+	}
+
+	return partition, fsType, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -20,6 +22,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
@@ -31,6 +34,9 @@ import (
 	"github.com/lxc/lxd/shared/version"
 )
 
+// execKeepaliveInterval is how often the data websocket of resumable sessions is pinged.
+const execKeepaliveInterval = 10 * time.Second
+
 type execWs struct {
 	req api.InstanceExecPost
 
@@ -46,6 +52,9 @@ type execWs struct {
 	fds                  map[int]string
 	devptsFd             *os.File
 	s                    *state.State
+
+	// Websockets reconnected by the client to resume the session, keyed by fd.
+	resumed map[int]chan *websocket.Conn
 }
 
 func (s *execWs) Metadata() interface{} {
@@ -74,33 +83,44 @@ func (s *execWs) Connect(op *operations.Operation, r *http.Request, w http.Respo
 
 	for fd, fdSecret := range s.fds {
 		if secret == fdSecret {
+			s.connsLock.Lock()
+			started := (fd == -1 && s.controlConnectedDone) || (fd != -1 && s.allConnectedDone)
+			s.connsLock.Unlock()
+
+			if started {
+				return s.resume(fd, r, w)
+			}
+
 			conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
 			if err != nil {
 				return err
 			}
 
 			s.connsLock.Lock()
-			s.conns[fd] = conn
+			defer s.connsLock.Unlock()
 
-			if fd == -1 {
-				if s.controlConnectedDone {
+			// Another client may have connected the same websocket while upgrading.
+			if s.conns[fd] != nil {
+				conn.Close()
+
+				if fd == -1 {
 					return fmt.Errorf("Control websocket already connected")
 				}
 
+				return fmt.Errorf("Websocket %d already connected", fd)
+			}
+
+			s.conns[fd] = conn
+
+			if fd == -1 {
 				// Control WS is now connected.
 				s.controlConnectedDone = true
 				close(s.controlConnected)
-				s.connsLock.Unlock()
 				return nil
 			}
 
-			if s.allConnectedDone {
-				return fmt.Errorf("All websockets already connected")
-			}
-
 			for i, c := range s.conns {
 				if i != -1 && c == nil {
-					s.connsLock.Unlock()
 					return nil
 				}
 			}
@@ -109,8 +129,6 @@ func (s *execWs) Connect(op *operations.Operation, r *http.Request, w http.Respo
 			s.allConnectedDone = true
 			close(s.allConnected)
 
-			s.connsLock.Unlock()
-
 			return nil
 		}
 	}
@@ -120,6 +138,60 @@ func (s *execWs) Connect(op *operations.Operation, r *http.Request, w http.Respo
 	return os.ErrPermission
 }
 
+// resume hands a websocket reconnected by the client over to the running session, replacing the previous one.
+func (s *execWs) resume(fd int, r *http.Request, w http.ResponseWriter) error {
+	if s.req.ResumeTimeout <= 0 {
+		if fd == -1 {
+			return fmt.Errorf("Control websocket already connected")
+		}
+
+		return fmt.Errorf("All websockets already connected")
+	}
+
+	conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	// Closing the previous websocket makes the session notice it's gone if it didn't already.
+	s.connsLock.Lock()
+	old := s.conns[fd]
+	s.connsLock.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	for {
+		select {
+		case s.resumed[fd] <- conn:
+			return nil
+		case pending := <-s.resumed[fd]:
+			pending.Close()
+		}
+	}
+}
+
+// waitResume waits for the client to reconnect the websocket of the given fd, returning nil if the session
+// ended first or the resume timeout expired.
+func (s *execWs) waitResume(fd int, exited chan struct{}) *websocket.Conn {
+	timer := time.NewTimer(time.Duration(s.req.ResumeTimeout) * time.Second)
+	defer timer.Stop()
+
+	select {
+	case conn := <-s.resumed[fd]:
+		s.connsLock.Lock()
+		s.conns[fd] = conn
+		s.connsLock.Unlock()
+
+		return conn
+	case <-exited:
+		return nil
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (s *execWs) Do(op *operations.Operation) error {
 	<-s.allConnected
 
@@ -240,6 +312,18 @@ func (s *execWs) Do(op *operations.Operation) error {
 
 				if err != nil {
 					logger.Debug("Got error getting next reader", log.Ctx{"err": err})
+
+					// Wait for the client to come back, the data websocket handling kills the process if
+					// the session isn't resumed in time.
+					if s.req.ResumeTimeout > 0 && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+						if s.waitResume(-1, attachedChildIsDead) == nil {
+							return
+						}
+
+						logger.Debug("Control websocket resumed")
+						continue
+					}
+
 					er, ok := err.(*websocket.CloseError)
 					if !ok {
 						break
@@ -307,6 +391,13 @@ func (s *execWs) Do(op *operations.Operation) error {
 
 			logger.Debug("Started mirroring websocket")
 			defer logger.Debug("Finished mirroring websocket")
+
+			if s.req.ResumeTimeout > 0 {
+				s.mirrorResumable(cmd, ptys[0], attachedChildIsDead, logger)
+				wgEOF.Done()
+				return
+			}
+
 			readDone, writeDone := netutils.WebsocketExecMirror(conn, ptys[0], ptys[0], attachedChildIsDead, int(ptys[0].Fd()))
 
 			<-readDone
@@ -409,6 +500,128 @@ func (s *execWs) Do(op *operations.Operation) error {
 	return finisher(exitCode, err)
 }
 
+// mirrorResumable mirrors the PTY of an interactive session over its data websocket until the process exits.
+// When the websocket drops, the PTY is left alone while waiting for the client to reconnect, and the process is
+// killed if it doesn't within the resume timeout. Output read from the PTY but not sent yet goes to the next
+// websocket.
+func (s *execWs) mirrorResumable(cmd instance.Cmd, pty *os.File, exited chan struct{}, logger logger.Logger) {
+	s.connsLock.Lock()
+	conn := s.conns[0]
+	s.connsLock.Unlock()
+
+	in := shared.ExecReaderToChannel(pty, -1, exited, int(pty.Fd()))
+	inputDone := false
+	var pending []byte
+
+	for {
+		keepaliveDone := make(chan struct{})
+		shared.WebsocketKeepalive(conn, execKeepaliveInterval, keepaliveDone)
+
+		chInput := make(chan error, 1)
+		if !inputDone {
+			go func(conn *websocket.Conn) {
+				chInput <- execWebsocketToPty(conn, pty)
+			}(conn)
+		}
+
+		finished := false
+		for {
+			if pending != nil {
+				err := conn.WriteMessage(websocket.BinaryMessage, pending)
+				if err != nil {
+					logger.Debug("Failed writing to websocket", log.Ctx{"err": err})
+					break
+				}
+
+				pending = nil
+			}
+
+			var err error
+			select {
+			case buf, ok := <-in:
+				if ok {
+					pending = buf
+					continue
+				}
+
+				finished = true
+			case err = <-chInput:
+				if err == nil {
+					// The client is done sending input, hang up the terminal like other sessions do.
+					inputDone = true
+					pty.Close()
+					continue
+				}
+
+				logger.Debug("Failed reading from websocket", log.Ctx{"err": err})
+			}
+
+			break
+		}
+
+		close(keepaliveDone)
+
+		if finished {
+			logger.Debug("Sending write barrier")
+			conn.WriteMessage(websocket.TextMessage, []byte{})
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			conn.Close()
+			return
+		}
+
+		conn.Close()
+
+		logger.Debug("Data websocket lost, waiting for the session to be resumed")
+		conn = s.waitResume(0, exited)
+		if conn == nil {
+			break
+		}
+
+		logger.Debug("Data websocket resumed")
+	}
+
+	select {
+	case <-exited:
+	default:
+		logger.Debug("Session wasn't resumed in time")
+		err := cmd.Signal(unix.SIGKILL)
+		if err != nil {
+			logger.Debug("Failed to send SIGKILL signal", log.Ctx{"err": err})
+		} else {
+			logger.Debug("Sent SIGKILL signal")
+		}
+	}
+
+	// Drain the output until the process is gone.
+	for range in {
+	}
+}
+
+// execWebsocketToPty writes the data received on the websocket to the PTY. It returns nil once the client is done
+// sending data or the PTY is closed, and an error if the websocket failed.
+func execWebsocketToPty(conn *websocket.Conn, pty *os.File) error {
+	for {
+		mt, r, err := conn.NextReader()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+
+			return err
+		}
+
+		// A text message is the barrier sent once the input reached EOF.
+		if mt == websocket.TextMessage {
+			return nil
+		}
+
+		_, err = io.Copy(pty, r)
+		if err != nil {
+			return nil
+		}
+	}
+}
+
 // swagger:operation POST /1.0/instances/{name}/exec instances instance_exec_post
 //
 // Run a command
@@ -487,6 +700,14 @@ func instanceExecPost(d *Daemon, r *http.Request) response.Response {
 		return operations.ForwardedOperationResponse(projectName, opAPI)
 	}
 
+	if post.ResumeTimeout < 0 {
+		return response.BadRequest(fmt.Errorf("Invalid resume timeout"))
+	}
+
+	if post.ResumeTimeout > 0 && (!post.Interactive || !post.WaitForWS) {
+		return response.BadRequest(fmt.Errorf("Only interactive sessions can be resumed"))
+	}
+
 	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
 	if err != nil {
 		return response.SmartError(err)
@@ -515,6 +736,16 @@ func instanceExecPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Apply the environment variables enforced by the project, those can't be overridden.
+	p, err := d.cluster.GetProject(projectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for k, v := range project.EnforcedEnvironment(p) {
+		post.Environment[k] = v
+	}
+
 	// Set default value for PATH.
 	_, ok := post.Environment["PATH"]
 	if !ok {
@@ -576,6 +807,11 @@ func instanceExecPost(d *Daemon, r *http.Request) response.Response {
 		}
 		ws.allConnected = make(chan struct{})
 		ws.controlConnected = make(chan struct{})
+		ws.resumed = map[int]chan *websocket.Conn{}
+		for fd := range ws.conns {
+			ws.resumed[fd] = make(chan *websocket.Conn, 1)
+		}
+
 		for i := -1; i < len(ws.conns)-1; i++ {
 			ws.fds[i], err = shared.RandomCryptoString()
 			if err != nil {
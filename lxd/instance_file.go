@@ -42,10 +42,20 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Missing path argument"))
 	}
 
+	recursive := shared.IsTrue(r.FormValue("recursive"))
+
 	switch r.Method {
 	case "GET":
+		if recursive {
+			return &instanceFileTarResponse{inst: c, path: path}
+		}
+
 		return instanceFileGet(c, path, r)
 	case "POST":
+		if recursive {
+			return instanceFilePostRecursive(c, path, r)
+		}
+
 		return instanceFilePost(c, path, r)
 	case "DELETE":
 		return instanceFileDelete(c, path, r)
@@ -54,16 +64,34 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 	}
 }
 
+// instanceFileTarResponse streams the tree at a path of the instance as a tarball.
+type instanceFileTarResponse struct {
+	inst instance.Instance
+	path string
+}
+
+func (r *instanceFileTarResponse) Render(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	return r.inst.FilePullRecursive(r.path, w)
+}
+
+func (r *instanceFileTarResponse) String() string {
+	return fmt.Sprintf("tarball of %s", r.path)
+}
+
 // swagger:operation GET /1.0/instances/{name}/files instances instance_files_get
 //
 // Get a file
 //
 // Gets the file content. If it's a directory, a json list of files will be returned instead.
+// In recursive mode, the whole tree is returned as a tarball.
 //
 // ---
 // produces:
 //   - application/json
 //   - application/octet-stream
+//   - application/x-tar
 // parameters:
 //   - in: query
 //     name: path
@@ -71,6 +99,11 @@ func instanceFileHandler(d *Daemon, r *http.Request) response.Response {
 //     type: string
 //     example: default
 //   - in: query
+//     name: recursive
+//     description: Whether to return the tree at the path as a tarball
+//     type: boolean
+//     example: true
+//   - in: query
 //     name: project
 //     description: Project name
 //     type: string
@@ -168,10 +201,12 @@ func instanceFileGet(c instance.Instance, path string, r *http.Request) response
 // Create or replace a file
 //
 // Creates a new file in the instance.
+// In recursive mode, the tarball in the body is extracted into the directory at the path.
 //
 // ---
 // consumes:
 //   - application/octet-stream
+//   - application/x-tar
 // produces:
 //   - application/json
 // parameters:
@@ -181,6 +216,11 @@ func instanceFileGet(c instance.Instance, path string, r *http.Request) response
 //     type: string
 //     example: default
 //   - in: query
+//     name: recursive
+//     description: Whether the body is a tarball to extract into the directory at the path
+//     type: boolean
+//     example: true
+//   - in: query
 //     name: project
 //     description: Project name
 //     type: string
@@ -282,6 +322,16 @@ func instanceFilePost(c instance.Instance, path string, r *http.Request) respons
 	}
 }
 
+// instanceFilePostRecursive extracts the tarball from the request body into the directory at path.
+func instanceFilePostRecursive(c instance.Instance, path string, r *http.Request) response.Response {
+	err := c.FilePushRecursive(path, r.Body)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 // swagger:operation DELETE /1.0/instances/{name}/files instances instance_files_delete
 //
 // Delete a file
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+type fileWatchWs struct {
+	// instance currently worked on
+	instance instance.Instance
+
+	// watch request
+	req api.InstanceFileWatchPost
+
+	// secret of the events websocket and its connection once established
+	secret    string
+	conn      *websocket.Conn
+	connLock  sync.Mutex
+	connected chan struct{}
+
+	// stops the watch
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *fileWatchWs) Metadata() interface{} {
+	return shared.Jmap{"fds": shared.Jmap{"0": s.secret}}
+}
+
+func (s *fileWatchWs) Connect(op *operations.Operation, r *http.Request, w http.ResponseWriter) error {
+	if r.FormValue("secret") != s.secret {
+		// If we didn't find the right secret, the user provided a bad one,
+		// which 403, not 404, since this operation actually exists.
+		return os.ErrPermission
+	}
+
+	s.connLock.Lock()
+	connected := s.conn != nil
+	s.connLock.Unlock()
+
+	if connected {
+		return fmt.Errorf("Already connected")
+	}
+
+	conn, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	s.connLock.Lock()
+	defer s.connLock.Unlock()
+
+	// Another client may have connected while upgrading.
+	if s.conn != nil {
+		conn.Close()
+		return fmt.Errorf("Already connected")
+	}
+
+	s.conn = conn
+	close(s.connected)
+
+	return nil
+}
+
+func (s *fileWatchWs) Do(op *operations.Operation) error {
+	select {
+	case <-s.connected:
+	case <-s.ctx.Done():
+		return nil
+	}
+
+	defer s.conn.Close()
+
+	// Stop watching once the client goes away.
+	go func() {
+		for {
+			_, _, err := s.conn.NextReader()
+			if err != nil {
+				s.cancel()
+				return
+			}
+		}
+	}()
+
+	return s.instance.FileWatch(s.ctx, s.req.Path, s.req.Recursive, func(event api.InstanceFileEvent) {
+		err := s.conn.WriteJSON(event)
+		if err != nil {
+			logger.Debugf("Failed sending file event to client: %v", err)
+			s.cancel()
+		}
+	})
+}
+
+func (s *fileWatchWs) Cancel(op *operations.Operation) error {
+	s.cancel()
+	return nil
+}
+
+// swagger:operation POST /1.0/instances/{name}/files/watch instances instance_files_watch_post
+//
+// Watch files
+//
+// Watches a path of the instance for changes.
+//
+// The returned operation metadata will contain a websocket on which the
+// create, modify and delete events are sent as they happen.
+// The watch stops when the websocket or the operation is closed.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: body
+//     name: watch
+//     description: Watch request
+//     schema:
+//       $ref: "#/definitions/InstanceFileWatchPost"
+// responses:
+//   "200":
+//     $ref: "#/responses/Operation"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceFileWatchPost(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	post := api.InstanceFileWatchPost{}
+	err = json.NewDecoder(r.Body).Decode(&post)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Forward the request if the instance is remote.
+	client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, projectName, name, d.endpoints.NetworkCert(), d.serverCert(), r, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if client != nil {
+		url := fmt.Sprintf("/1.0/instances/%s/files/watch?project=%s", name, projectName)
+		resp, _, err := client.RawQuery("POST", url, post, "")
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		opAPI, err := resp.MetadataAsOperation()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return operations.ForwardedOperationResponse(projectName, opAPI)
+	}
+
+	if post.Path == "" {
+		return response.BadRequest(fmt.Errorf("A path must be provided"))
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !inst.IsRunning() {
+		return response.BadRequest(fmt.Errorf("Instance is not running"))
+	}
+
+	ws := &fileWatchWs{}
+	ws.instance = inst
+	ws.req = post
+	ws.connected = make(chan struct{})
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+
+	ws.secret, err = shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{inst.Name()}
+
+	if inst.Type() == instancetype.Container {
+		resources["containers"] = resources["instances"]
+	}
+
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassWebsocket, db.OperationInstanceFileWatch, resources, ws.Metadata(), ws.Do, ws.Cancel, ws.Connect, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
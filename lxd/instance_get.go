@@ -71,7 +71,7 @@ func instanceGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	state, etag, err := c.Render()
+	state, etag, err := c.Render(instanceRenderTags(d.State(), c))
 	if err != nil {
 		return response.SmartError(err)
 	}
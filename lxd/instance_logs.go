@@ -1,20 +1,29 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/request"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/version"
 )
 
@@ -305,3 +314,130 @@ func instanceLogDelete(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 
 }
+
+// instanceLogFollowInterval is how often the log file of an instance is checked for new lines when followed.
+const instanceLogFollowInterval = time.Second
+
+// instanceLogFollow sends the lines appended to the log file of the lxc or qemu process of the instance to the
+// listener as logging events, until the context is cancelled.
+func instanceLogFollow(ctx context.Context, server *events.Server, listener *events.Listener, inst instance.Instance) {
+	source := "lxc"
+	if inst.Type() == instancetype.VM {
+		source = "qemu"
+	}
+
+	logPath := inst.LogFilePath()
+
+	var f *os.File
+	var reader *bufio.Reader
+	var offset int64
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	// Only the lines logged from now on are sent.
+	f, err := os.Open(logPath)
+	if err == nil {
+		offset, err = f.Seek(0, io.SeekEnd)
+		if err != nil {
+			logger.Warn("Failed following instance log", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			return
+		}
+
+		reader = bufio.NewReader(f)
+	}
+
+	partial := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(instanceLogFollowInterval):
+		}
+
+		// Start over when the log file is recreated (e.g. on instance start) or truncated.
+		current, err := os.Stat(logPath)
+		if err != nil {
+			continue
+		}
+
+		if f != nil {
+			info, err := f.Stat()
+			if err != nil || !os.SameFile(info, current) {
+				f.Close()
+				f = nil
+			} else if current.Size() < offset {
+				offset, err = f.Seek(0, io.SeekStart)
+				if err != nil {
+					continue
+				}
+
+				reader.Reset(f)
+				partial = ""
+			}
+		}
+
+		if f == nil {
+			f, err = os.Open(logPath)
+			if err != nil {
+				continue
+			}
+
+			offset = 0
+			reader = bufio.NewReader(f)
+			partial = ""
+		}
+
+		for {
+			line, err := reader.ReadString('\n')
+			offset += int64(len(line))
+			if err != nil {
+				// Keep the incomplete line until the rest of it is written.
+				partial += line
+				break
+			}
+
+			line = strings.TrimRight(partial+line, "\n")
+			partial = ""
+			if line == "" {
+				continue
+			}
+
+			server.SendToListener(listener, "logging", api.EventLogging{
+				Message: line,
+				Level:   instanceLogLevel(source, line),
+				Context: map[string]string{
+					"instance": inst.Name(),
+					"project":  inst.Project(),
+					"source":   source,
+				},
+			})
+		}
+	}
+}
+
+// instanceLogLevel returns the level of a line of the lxc or qemu log (with the names used for LXD's own logs).
+// The lxc lines carry their level after the instance name and timestamp, the qemu ones are reported as info.
+func instanceLogLevel(source string, line string) string {
+	if source != "lxc" {
+		return "info"
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return "info"
+	}
+
+	switch fields[3] {
+	case "TRACE", "DEBUG":
+		return "dbug"
+	case "WARN":
+		return "warn"
+	case "ERROR", "CRIT", "ALERT", "FATAL":
+		return "eror"
+	}
+
+	return "info"
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// instanceMachineTypeLoad loads the virtual machine targeted by the request, or returns the response to send if
+// it's handled elsewhere or can't be loaded.
+func instanceMachineTypeLoad(d *Daemon, r *http.Request) (instance.VM, response.Response) {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(d, r, projectName, name, instanceType)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if resp != nil {
+		return nil, resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok {
+		return nil, response.BadRequest(fmt.Errorf("Machine types are only available on virtual machines"))
+	}
+
+	return vm, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/machine-type instances instance_machine_type_get
+//
+// Get the machine type
+//
+// Gets the QEMU machine type the virtual machine is pinned to and the ones it can be upgraded to.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+// responses:
+//   "200":
+//     description: Machine type
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/InstanceMachineType"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceMachineTypeGet(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceMachineTypeLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	machineType, err := vm.MachineType()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, machineType)
+}
+
+// swagger:operation POST /1.0/instances/{name}/machine-type instances instance_machine_type_post
+//
+// Upgrade the machine type
+//
+// Moves the virtual machine to a newer machine type of the same family, the latest one by default.
+// The virtual machine must be stopped.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: body
+//     name: machine-type
+//     description: Machine type upgrade request
+//     required: true
+//     schema:
+//       $ref: "#/definitions/InstanceMachineTypePost"
+// responses:
+//   "200":
+//     $ref: "#/responses/EmptySyncResponse"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceMachineTypePost(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceMachineTypeLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.InstanceMachineTypePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = vm.MachineTypeUpgrade(req.MachineType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -100,27 +100,7 @@ func instanceMetadataGet(d *Daemon, r *http.Request) response.Response {
 	}
 	defer storagePools.InstanceUnmount(pool, c, nil)
 
-	// If missing, just return empty result
-	metadataPath := filepath.Join(c.Path(), "metadata.yaml")
-	if !shared.PathExists(metadataPath) {
-		return response.SyncResponse(true, api.ImageMetadata{})
-	}
-
-	// Read the metadata
-	metadataFile, err := os.Open(metadataPath)
-	if err != nil {
-		return response.InternalError(err)
-	}
-	defer metadataFile.Close()
-
-	data, err := ioutil.ReadAll(metadataFile)
-	if err != nil {
-		return response.InternalError(err)
-	}
-
-	// Parse into the API struct
-	metadata := api.ImageMetadata{}
-	err = yaml.Unmarshal(data, &metadata)
+	metadata, err := instanceMetadataLoad(c)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -201,25 +181,9 @@ func instanceMetadataPatch(d *Daemon, r *http.Request) response.Response {
 	defer storagePools.InstanceUnmount(pool, inst, nil)
 
 	// Read the existing data.
-	metadataPath := filepath.Join(inst.Path(), "metadata.yaml")
-	metadata := api.ImageMetadata{}
-	if shared.PathExists(metadataPath) {
-		metadataFile, err := os.Open(metadataPath)
-		if err != nil {
-			return response.InternalError(err)
-		}
-		defer metadataFile.Close()
-
-		data, err := ioutil.ReadAll(metadataFile)
-		if err != nil {
-			return response.InternalError(err)
-		}
-
-		// Parse into the API struct
-		err = yaml.Unmarshal(data, &metadata)
-		if err != nil {
-			return response.SmartError(err)
-		}
+	metadata, err := instanceMetadataLoad(inst)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
 	// Validate ETag
@@ -290,13 +254,6 @@ func instanceMetadataPut(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
-	// Read the new metadata.
-	metadata := api.ImageMetadata{}
-	err = json.NewDecoder(r.Body).Decode(&metadata)
-	if err != nil {
-		return response.BadRequest(err)
-	}
-
 	// Load the instance.
 	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
 	if err != nil {
@@ -315,9 +272,50 @@ func instanceMetadataPut(d *Daemon, r *http.Request) response.Response {
 	}
 	defer storagePools.InstanceUnmount(pool, inst, nil)
 
+	// Validate ETag
+	current, err := instanceMetadataLoad(inst)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = util.EtagCheck(r, current)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	// Read the new metadata.
+	metadata := api.ImageMetadata{}
+	err = json.NewDecoder(r.Body).Decode(&metadata)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	return doInstanceMetadataUpdate(d, inst, metadata, r)
 }
 
+// instanceMetadataLoad reads the metadata.yaml file of a mounted instance, returning empty metadata if missing.
+func instanceMetadataLoad(inst instance.Instance) (api.ImageMetadata, error) {
+	metadata := api.ImageMetadata{}
+
+	metadataPath := filepath.Join(inst.Path(), "metadata.yaml")
+	if !shared.PathExists(metadataPath) {
+		return metadata, nil
+	}
+
+	data, err := ioutil.ReadFile(metadataPath)
+	if err != nil {
+		return metadata, err
+	}
+
+	// Parse into the API struct
+	err = yaml.Unmarshal(data, &metadata)
+	if err != nil {
+		return metadata, err
+	}
+
+	return metadata, nil
+}
+
 func doInstanceMetadataUpdate(d *Daemon, inst instance.Instance, metadata api.ImageMetadata, r *http.Request) response.Response {
 	// Convert YAML.
 	data, err := yaml.Marshal(metadata)
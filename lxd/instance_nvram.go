@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// instanceNVRAMLoad loads the virtual machine targeted by the request, or returns the response to send if it's
+// handled elsewhere or can't be loaded.
+func instanceNVRAMLoad(d *Daemon, r *http.Request) (instance.VM, response.Response) {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(d, r, projectName, name, instanceType)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if resp != nil {
+		return nil, resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok {
+		return nil, response.BadRequest(fmt.Errorf("The NVRAM is only available on virtual machines"))
+	}
+
+	return vm, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/nvram instances instance_nvram_get
+//
+// Get the UEFI variables
+//
+// Gets the boot entries, boot order and secure boot state of the virtual machine.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+// responses:
+//   "200":
+//     description: UEFI variables
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/InstanceNVRAM"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceNVRAMGet(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceNVRAMLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	nvram, err := vm.NVRAM()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, nvram, nvram.InstanceNVRAMPut)
+}
+
+// swagger:operation PUT /1.0/instances/{name}/nvram instances instance_nvram_put
+//
+// Update the UEFI variables
+//
+// Sets the boot order of the virtual machine and enrolls or removes its secure boot keys.
+// The virtual machine must be stopped.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: body
+//     name: nvram
+//     description: UEFI variables
+//     required: true
+//     schema:
+//       $ref: "#/definitions/InstanceNVRAMPut"
+// responses:
+//   "200":
+//     $ref: "#/responses/EmptySyncResponse"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "412":
+//     $ref: "#/responses/PreconditionFailed"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceNVRAMPut(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceNVRAMLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	// Validate ETag
+	current, err := vm.NVRAM()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = util.EtagCheck(r, current.InstanceNVRAMPut)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.InstanceNVRAMPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = vm.NVRAMUpdate(req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// swagger:operation DELETE /1.0/instances/{name}/nvram instances instance_nvram_delete
+//
+// Reset the UEFI variables
+//
+// Recreates the NVRAM of the virtual machine from the firmware defaults.
+// All boot entries are dropped and get rebuilt by the firmware on next boot.
+// The virtual machine must be stopped.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+// responses:
+//   "200":
+//     $ref: "#/responses/EmptySyncResponse"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceNVRAMDelete(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceNVRAMLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err := vm.NVRAMReset()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -105,6 +105,11 @@ func instancePatch(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Can't call PATCH in restore mode"))
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Check if architecture was passed
 	var architecture int
 	_, err = reqRaw.GetString("architecture")
@@ -176,5 +181,10 @@ func instancePatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = instanceUpdateTags(d.State(), c, req.Tags)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
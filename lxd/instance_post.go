@@ -18,7 +18,9 @@ import (
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -214,6 +216,35 @@ func instancePost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if req.Migration {
+		// Server-side project migration.
+		if req.Project != "" {
+			if req.Pool != "" || targetNode != "" {
+				return response.BadRequest(fmt.Errorf("Moving to another project can't be combined with a pool or cluster member move"))
+			}
+
+			if !rbac.UserHasPermission(r, req.Project, "manage-containers") {
+				return response.Forbidden(nil)
+			}
+
+			newName := req.Name
+			if newName == "" {
+				newName = name
+			}
+
+			run := func(op *operations.Operation) error {
+				return instancePostProjectMigration(d, inst, newName, req.Project, req.InstanceOnly, op)
+			}
+
+			resources := map[string][]string{}
+			resources["instances"] = []string{name}
+			op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationInstanceMigrate, resources, nil, run, nil, nil, r)
+			if err != nil {
+				return response.InternalError(err)
+			}
+
+			return operations.OperationResponse(op)
+		}
+
 		// Server-side pool migration.
 		if req.Pool != "" {
 			// Setup the instance move operation.
@@ -415,6 +446,235 @@ func instancePostPoolMigration(d *Daemon, inst instance.Instance, newName string
 	return nil
 }
 
+// instanceProjectMoveVolume is a custom volume moved along with an instance into another project.
+type instanceProjectMoveVolume struct {
+	pool          string
+	name          string
+	sourceProject string
+	targetProject string
+}
+
+// instanceProjectMoveValidate checks that the instance can be moved into the new project under the new name and
+// returns the custom volumes which need to be moved along with it.
+func instanceProjectMoveValidate(d *Daemon, inst instance.Instance, newName string, newProject string) ([]instanceProjectMoveVolume, error) {
+	if inst.IsSnapshot() {
+		return nil, fmt.Errorf("Instance snapshots cannot be moved between projects")
+	}
+
+	if inst.IsRunning() {
+		return nil, fmt.Errorf("Instance must not be running to move between projects")
+	}
+
+	if newProject == inst.Project() {
+		return nil, fmt.Errorf("Instance is already in project %q", newProject)
+	}
+
+	targetProject, err := d.cluster.GetProject(newProject)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading project %q", newProject)
+	}
+
+	id, _ := d.cluster.GetInstanceID(newProject, newName)
+	if id > 0 {
+		return nil, fmt.Errorf("Name %q already in use in project %q", newName, newProject)
+	}
+
+	// Backups are tied to the project and would be lost.
+	backups, err := d.cluster.GetInstanceBackups(inst.Project(), inst.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch instance's backups")
+	}
+
+	if len(backups) > 0 {
+		return nil, fmt.Errorf("Instance has backups")
+	}
+
+	// Check that the profiles are available in the new project.
+	profileProject := project.ProfileProjectFromRecord(targetProject)
+	profiles, err := d.cluster.GetProfileNames(profileProject)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading profiles of project %q", newProject)
+	}
+
+	for _, profile := range inst.Profiles() {
+		if !shared.StringInSlice(profile, profiles) {
+			return nil, fmt.Errorf("Profile %q isn't available in project %q", profile, newProject)
+		}
+	}
+
+	// Check that the managed networks used by the NICs are available in the new project.
+	networkProject := project.NetworkProjectFromRecord(targetProject)
+	for devName, dev := range inst.ExpandedDevices() {
+		if dev["type"] != "nic" || dev["network"] == "" {
+			continue
+		}
+
+		_, _, _, err := d.cluster.GetNetworkInAnyState(networkProject, dev["network"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Network %q used by device %q isn't available in project %q", dev["network"], devName, newProject)
+		}
+	}
+
+	// Check the limits and restrictions of the new project.
+	req := api.InstancesPost{
+		Name: newName,
+		Type: api.InstanceType(inst.Type().String()),
+		InstancePut: api.InstancePut{
+			Config:   inst.LocalConfig(),
+			Devices:  inst.LocalDevices().CloneNative(),
+			Profiles: inst.Profiles(),
+		},
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return project.AllowInstanceCreation(tx, newProject, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom volumes only need to be moved if the projects don't share them.
+	sourceVolumeProject, err := project.StorageVolumeProject(d.cluster, inst.Project(), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return nil, err
+	}
+
+	targetVolumeProject := project.StorageVolumeProjectFromRecord(targetProject, db.StoragePoolVolumeTypeCustom)
+	if sourceVolumeProject == targetVolumeProject {
+		return nil, nil
+	}
+
+	volumes := []instanceProjectMoveVolume{}
+	for devName, dev := range inst.ExpandedDevices() {
+		if dev["type"] != "disk" || dev["pool"] == "" || dev["source"] == "" || dev["path"] == "/" {
+			continue
+		}
+
+		// Volumes attached to other instances can't be moved.
+		vol := &api.StorageVolume{Name: dev["source"], Type: db.StoragePoolVolumeTypeNameCustom}
+		err = storagePools.VolumeUsedByInstanceDevices(d.State(), dev["pool"], sourceVolumeProject, vol, true, func(dbInst db.Instance, p db.Project, profiles []api.Profile, usedByDevices []string) error {
+			if dbInst.Project == inst.Project() && dbInst.Name == inst.Name() {
+				return nil
+			}
+
+			return fmt.Errorf("Custom volume %q used by device %q is also used by instance %q in project %q", dev["source"], devName, dbInst.Name, dbInst.Project)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		poolID, err := d.cluster.GetStoragePoolID(dev["pool"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed loading storage pool %q", dev["pool"])
+		}
+
+		_, err = d.cluster.GetStoragePoolNodeVolumeID(targetVolumeProject, dev["source"], db.StoragePoolVolumeTypeCustom, poolID)
+		if err == nil {
+			return nil, fmt.Errorf("Custom volume %q already exists in project %q", dev["source"], newProject)
+		}
+
+		volumes = append(volumes, instanceProjectMoveVolume{
+			pool:          dev["pool"],
+			name:          dev["source"],
+			sourceProject: sourceVolumeProject,
+			targetProject: targetVolumeProject,
+		})
+	}
+
+	return volumes, nil
+}
+
+// instancePostProjectMigration moves an instance along with its snapshots, and the custom volumes only it uses,
+// into another project on the same server. The devices keep referring to the same profiles, networks and pools,
+// which must be available in the new project.
+func instancePostProjectMigration(d *Daemon, inst instance.Instance, newName string, newProject string, instanceOnly bool, op *operations.Operation) error {
+	volumes, err := instanceProjectMoveValidate(d, inst, newName, newProject)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Move the custom volumes first so the devices of the new instance can be validated.
+	for _, vol := range volumes {
+		pool, err := storagePools.GetPoolByName(d.State(), vol.pool)
+		if err != nil {
+			return err
+		}
+
+		poolID, err := d.cluster.GetStoragePoolID(vol.pool)
+		if err != nil {
+			return err
+		}
+
+		_, dbVol, err := d.cluster.GetLocalStoragePoolVolume(vol.sourceProject, vol.name, db.StoragePoolVolumeTypeCustom, poolID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading custom volume %q", vol.name)
+		}
+
+		err = pool.CreateCustomVolumeFromCopy(vol.targetProject, vol.sourceProject, vol.name, dbVol.Description, dbVol.Config, vol.pool, vol.name, false, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed copying custom volume %q", vol.name)
+		}
+
+		vol := vol
+		revert.Add(func() { pool.DeleteCustomVolume(vol.targetProject, vol.name, op) })
+	}
+
+	// Copy config from instance to avoid modifying it.
+	localConfig := make(map[string]string)
+	for k, v := range inst.LocalConfig() {
+		localConfig[k] = v
+	}
+
+	args := db.InstanceArgs{
+		Name:         newName,
+		BaseImage:    localConfig["volatile.base_image"],
+		Config:       localConfig,
+		Devices:      inst.LocalDevices().Clone(),
+		Project:      newProject,
+		Type:         inst.Type(),
+		Architecture: inst.Architecture(),
+		Description:  inst.Description(),
+		Ephemeral:    inst.IsEphemeral(),
+		Profiles:     inst.Profiles(),
+		Stateful:     inst.IsStateful(),
+	}
+
+	_, err = instanceCreateAsCopy(d.State(), instanceCreateAsCopyOpts{
+		sourceInstance:       inst,
+		targetInstance:       args,
+		instanceOnly:         instanceOnly,
+		applyTemplateTrigger: false, // Don't apply templates when moving.
+	}, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+
+	// Delete the original instance and volumes.
+	err = inst.Delete(true)
+	if err != nil {
+		return err
+	}
+
+	for _, vol := range volumes {
+		pool, err := storagePools.GetPoolByName(d.State(), vol.pool)
+		if err != nil {
+			return err
+		}
+
+		err = pool.DeleteCustomVolume(vol.sourceProject, vol.name, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed deleting custom volume %q from project %q", vol.name, vol.sourceProject)
+		}
+	}
+
+	return nil
+}
+
 // Move a non-ceph container to another cluster node.
 func instancePostClusteringMigrate(d *Daemon, r *http.Request, inst instance.Instance, oldName, newName, newNode string) (func(op *operations.Operation) error, error) {
 	var sourceAddress string
@@ -423,6 +683,7 @@ func instancePostClusteringMigrate(d *Daemon, r *http.Request, inst instance.Ins
 	// Save the original value of the "volatile.apply_template" config key,
 	// since we'll want to preserve it in the copied container.
 	origVolatileApplyTemplate := inst.LocalConfig()["volatile.apply_template"]
+	origVolatileClonePending := inst.LocalConfig()["volatile.clone_pending"]
 
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
@@ -530,7 +791,7 @@ func instancePostClusteringMigrate(d *Daemon, r *http.Request, inst instance.Ins
 			destName = oldName
 		}
 
-		// Restore the original value of "volatile.apply_template"
+		// Restore the original values of "volatile.apply_template" and "volatile.clone_pending"
 		project := inst.Project()
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			id, err := tx.GetInstanceID(project, destName)
@@ -552,6 +813,21 @@ func instancePostClusteringMigrate(d *Daemon, r *http.Request, inst instance.Ins
 				}
 			}
 
+			err = tx.DeleteInstanceConfigKey(id, "volatile.clone_pending")
+			if err != nil {
+				return errors.Wrap(err, "Failed to remove volatile.clone_pending config key")
+			}
+
+			if origVolatileClonePending != "" {
+				config := map[string]string{
+					"volatile.clone_pending": origVolatileClonePending,
+				}
+				err = tx.CreateInstanceConfig(int(id), config)
+				if err != nil {
+					return errors.Wrap(err, "Failed to set volatile.clone_pending config key")
+				}
+			}
+
 			return nil
 		})
 		if err != nil {
@@ -575,18 +851,18 @@ func instancePostClusteringMigrateWithCeph(d *Daemon, r *http.Request, inst inst
 			return errors.Wrap(err, "Failed to get source instance's storage pool")
 		}
 
-		if pool.Driver().Info().Name != "ceph" {
-			return fmt.Errorf("Source instance's storage pool is not of type ceph")
+		if !pool.Driver().Info().Remote {
+			return fmt.Errorf("Source instance's storage pool is not remote")
 		}
 
 		args := migration.VolumeSourceArgs{
 			Data: project.Instance(projectName, newName),
 		}
 
-		// Trigger a rename in the Ceph driver.
+		// Trigger a rename in the remote storage driver.
 		err = pool.MigrateInstance(inst, nil, &args, op)
 		if err != nil {
-			return errors.Wrap(err, "Failed to migrate ceph RBD volume")
+			return errors.Wrap(err, "Failed to migrate remote storage volume")
 		}
 
 		// Re-link the database entries against the new node name.
@@ -678,7 +954,7 @@ func migrateInstance(d *Daemon, r *http.Request, inst instance.Instance, project
 		err = errors.Wrap(err, "Failed to fetch instance's pool info")
 		return err
 	}
-	if pool.Driver == "ceph" {
+	if shared.StringInSlice(pool.Driver, db.StorageRemoteDriverNames()) {
 		f, err := instancePostClusteringMigrateWithCeph(d, r, inst, projectName, name, req.Name, targetNode, instanceType)
 		if err != nil {
 			return err
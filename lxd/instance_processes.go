@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/processes"
+	"github.com/lxc/lxd/lxd/response"
+)
+
+// swagger:operation GET /1.0/instances/{name}/processes instances instance_processes_get
+//
+// Get the top processes
+//
+// Gets the processes of the running instance using the most CPU or memory. The CPU usage is sampled over half a
+// second. For virtual machines, the processes are reported by the agent.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: query
+//     name: sort
+//     description: Sort order (cpu or memory)
+//     type: string
+//     example: memory
+//   - in: query
+//     name: limit
+//     description: Maximum number of processes to return (0 for all, defaults to 10)
+//     type: integer
+//     example: 5
+// responses:
+//   "200":
+//     description: Top processes
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/InstanceProcesses"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceProcessesGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(d, r, projectName, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if resp != nil {
+		return resp
+	}
+
+	sortBy, limit, err := processes.ParseQuery(r.URL.Query())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	top, err := inst.Processes(sortBy, limit)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, top)
+}
@@ -89,6 +89,11 @@ func instancePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateTags(configRaw.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	architecture, err := osarch.ArchitectureId(configRaw.Architecture)
 	if err != nil {
 		architecture = 0
@@ -122,7 +127,7 @@ func instancePut(d *Daemon, r *http.Request) response.Response {
 				return err
 			}
 
-			return nil
+			return instanceUpdateTags(d.State(), inst, configRaw.Tags)
 		}
 
 		opType = db.OperationInstanceUpdate
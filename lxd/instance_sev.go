@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// instanceSEVLoad loads the virtual machine targeted by the request, or returns the response to send if
+// it's handled elsewhere or can't be loaded.
+func instanceSEVLoad(d *Daemon, r *http.Request) (instance.VM, response.Response) {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node.
+	resp, err := forwardedResponseIfInstanceIsRemote(d, r, projectName, name, instanceType)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if resp != nil {
+		return nil, resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	vm, ok := inst.(instance.VM)
+	if !ok {
+		return nil, response.BadRequest(fmt.Errorf("AMD SEV is only available on virtual machines"))
+	}
+
+	return vm, nil
+}
+
+// swagger:operation GET /1.0/instances/{name}/sev instances instance_sev_get
+//
+// Get the memory encryption state
+//
+// Gets the AMD SEV mode and policy of the virtual machine and, when running, the firmware state and launch
+// measurement.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+// responses:
+//   "200":
+//     description: Memory encryption state
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/InstanceSEV"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceSEVGet(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceSEVLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	sev, err := vm.SEV()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, sev)
+}
+
+// swagger:operation POST /1.0/instances/{name}/sev/report instances instance_sev_report_post
+//
+// Get an attestation report
+//
+// Gets an attestation report including the nonce, signed by the platform, for the running virtual machine.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: body
+//     name: report
+//     description: Attestation report request
+//     required: true
+//     schema:
+//       $ref: "#/definitions/InstanceSEVReportPost"
+// responses:
+//   "200":
+//     description: Attestation report
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/InstanceSEVReport"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func instanceSEVReportPost(d *Daemon, r *http.Request) response.Response {
+	vm, resp := instanceSEVLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.InstanceSEVReportPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(req.Nonce)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid nonce: %v", err))
+	}
+
+	report, err := vm.SEVReport(nonce)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, api.InstanceSEVReport{Report: base64.StdEncoding.EncodeToString(report)})
+}
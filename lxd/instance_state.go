@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
@@ -171,7 +174,7 @@ func instanceStatePut(d *Daemon, r *http.Request) response.Response {
 	do := func(op *operations.Operation) error {
 		inst.SetOperation(op)
 
-		return doInstanceStatePut(inst, req)
+		return doInstanceStatePut(d.State(), inst, req)
 	}
 
 	resources := map[string][]string{}
@@ -201,8 +204,30 @@ func instanceActionToOptype(action string) (db.OperationType, error) {
 	return db.OperationUnknown, fmt.Errorf("Unknown action: '%s'", action)
 }
 
-func doInstanceStatePut(inst instance.Instance, req api.InstanceStatePut) error {
-	switch shared.InstanceAction(req.Action) {
+// instanceCheckStopProtection returns an error if the instance is protected against being stopped.
+func instanceCheckStopProtection(s *state.State, inst instance.Instance) error {
+	p, err := s.Cluster.GetProject(inst.Project())
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading project %q", inst.Project())
+	}
+
+	if project.InstanceIsProtected(p, inst.ExpandedConfig(), "stop") {
+		return fmt.Errorf("Instance %q is protected against being stopped", inst.Name())
+	}
+
+	return nil
+}
+
+func doInstanceStatePut(s *state.State, inst instance.Instance, req api.InstanceStatePut) error {
+	action := shared.InstanceAction(req.Action)
+	if (action == shared.Stop || action == shared.Restart) && !req.ForceProtected {
+		err := instanceCheckStopProtection(s, inst)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch action {
 	case shared.Start:
 		return inst.Start(req.Stateful)
 	case shared.Stop:
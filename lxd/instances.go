@@ -9,10 +9,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
-	"github.com/lxc/lxd/lxd/db/cluster"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/warnings"
@@ -75,6 +77,71 @@ var instanceFileCmd = APIEndpoint{
 	Delete: APIEndpointAction{Handler: instanceFileHandler, AccessHandler: allowProjectPermission("containers", "operate-containers")},
 }
 
+var instanceFileWatchCmd = APIEndpoint{
+	Name: "instanceFileWatch",
+	Path: "instances/{name}/files/watch",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerFileWatch", Path: "containers/{name}/files/watch"},
+		{Name: "vmFileWatch", Path: "virtual-machines/{name}/files/watch"},
+	},
+
+	Post: APIEndpointAction{Handler: instanceFileWatchPost, AccessHandler: allowProjectPermission("containers", "operate-containers")},
+}
+
+var instanceNVRAMCmd = APIEndpoint{
+	Name: "instanceNVRAM",
+	Path: "instances/{name}/nvram",
+	Aliases: []APIEndpointAlias{
+		{Name: "vmNVRAM", Path: "virtual-machines/{name}/nvram"},
+	},
+
+	Get:    APIEndpointAction{Handler: instanceNVRAMGet, AccessHandler: allowProjectPermission("containers", "view")},
+	Put:    APIEndpointAction{Handler: instanceNVRAMPut, AccessHandler: allowProjectPermission("containers", "manage-containers")},
+	Delete: APIEndpointAction{Handler: instanceNVRAMDelete, AccessHandler: allowProjectPermission("containers", "manage-containers")},
+}
+
+var instanceMachineTypeCmd = APIEndpoint{
+	Name: "instanceMachineType",
+	Path: "instances/{name}/machine-type",
+	Aliases: []APIEndpointAlias{
+		{Name: "vmMachineType", Path: "virtual-machines/{name}/machine-type"},
+	},
+
+	Get:  APIEndpointAction{Handler: instanceMachineTypeGet, AccessHandler: allowProjectPermission("containers", "view")},
+	Post: APIEndpointAction{Handler: instanceMachineTypePost, AccessHandler: allowProjectPermission("containers", "manage-containers")},
+}
+
+var instanceProcessesCmd = APIEndpoint{
+	Name: "instanceProcesses",
+	Path: "instances/{name}/processes",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerProcesses", Path: "containers/{name}/processes"},
+		{Name: "vmProcesses", Path: "virtual-machines/{name}/processes"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceProcessesGet, AccessHandler: allowProjectPermission("containers", "view")},
+}
+
+var instanceSEVCmd = APIEndpoint{
+	Name: "instanceSEV",
+	Path: "instances/{name}/sev",
+	Aliases: []APIEndpointAlias{
+		{Name: "vmSEV", Path: "virtual-machines/{name}/sev"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceSEVGet, AccessHandler: allowProjectPermission("containers", "view")},
+}
+
+var instanceSEVReportCmd = APIEndpoint{
+	Name: "instanceSEVReport",
+	Path: "instances/{name}/sev/report",
+	Aliases: []APIEndpointAlias{
+		{Name: "vmSEVReport", Path: "virtual-machines/{name}/sev/report"},
+	},
+
+	Post: APIEndpointAction{Handler: instanceSEVReportPost, AccessHandler: allowProjectPermission("containers", "view")},
+}
+
 var instanceSnapshotsCmd = APIEndpoint{
 	Name: "instanceSnapshots",
 	Path: "instances/{name}/snapshots",
@@ -254,7 +321,8 @@ func instancesRestart(s *state.State) error {
 		var attempt = 0
 		for {
 			attempt++
-			err = inst.Start(false)
+			// Restore the instance state if it was stopped statefully on host shutdown.
+			err = inst.Start(inst.IsStateful())
 			if err != nil {
 				instLogger.Warn("Failed auto start instance attempt", log.Ctx{"attempt": attempt, "maxAttempts": maxAttempts, "err": err})
 
@@ -265,7 +333,7 @@ func instancesRestart(s *state.State) error {
 				time.Sleep(5 * time.Second)
 			} else {
 				// Resolve any previous warning.
-				warnErr := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, inst.Project(), db.WarningInstanceAutostartFailure, cluster.TypeInstance, inst.ID())
+				warnErr := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, inst.Project(), db.WarningInstanceAutostartFailure, dbCluster.TypeInstance, inst.ID())
 				if warnErr != nil {
 					instLogger.Warn("Failed to resolve instance autostart failure warning", log.Ctx{"err": warnErr})
 				}
@@ -276,7 +344,7 @@ func instancesRestart(s *state.State) error {
 
 		if err != nil {
 			// If unable to start after 3 tries, record a warning.
-			warnErr := s.Cluster.UpsertWarningLocalNode(inst.Project(), cluster.TypeInstance, inst.ID(), db.WarningInstanceAutostartFailure, fmt.Sprintf("%v", err))
+			warnErr := s.Cluster.UpsertWarningLocalNode(inst.Project(), dbCluster.TypeInstance, inst.ID(), db.WarningInstanceAutostartFailure, fmt.Sprintf("%v", err))
 			if warnErr != nil {
 				instLogger.Warn("Failed to create instance autostart failure warning", log.Ctx{"err": warnErr})
 			}
@@ -390,13 +458,70 @@ func instancesShutdown(s *state.State) error {
 		}
 	}
 
+	// Load the server-wide shutdown settings, falling back to the defaults if the database isn't available.
+	parallelism := int64(0)
+	defaultTimeout := 30 * time.Second
+	if dbAvailable {
+		err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			parallelism = config.InstancesShutdownParallelism()
+			defaultTimeout = config.InstancesShutdownTimeout()
+			return nil
+		})
+		if err != nil {
+			logger.Warn("Failed to load instances shutdown configuration, using defaults", log.Ctx{"err": err})
+		}
+	}
+
+	// Record the current state of all instances.
+	lastStates := make([]string, len(instances))
+	total := 0
+	for i, inst := range instances {
+		lastStates[i] = inst.State()
+		if lastStates[i] != "ERROR" && lastStates[i] != "STOPPED" {
+			total++
+		}
+	}
+
+	var progressMu sync.Mutex
+	stopped := 0
+	sendProgress := func(inst instance.Instance) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+
+		ctx := map[string]interface{}{"stopped": stopped, "total": total}
+		if inst != nil {
+			stopped++
+			ctx["stopped"] = stopped
+			ctx["project"] = inst.Project()
+			ctx["instance"] = inst.Name()
+		}
+
+		s.Events.SendLifecycle(project.Default, lifecycle.ServerInstancesShutdownProgress.Event(ctx))
+	}
+
+	if total > 0 {
+		logger.Info("Stopping instances", log.Ctx{"total": total, "parallelism": parallelism})
+		sendProgress(nil)
+	}
+
+	// Limit the number of instances being stopped at the same time.
+	var slots chan struct{}
+	if parallelism > 0 {
+		slots = make(chan struct{}, parallelism)
+	}
+
 	var lastPriority int
 
 	if len(instances) != 0 {
 		lastPriority, _ = strconv.Atoi(instances[0].ExpandedConfig()["boot.stop.priority"])
 	}
 
-	for _, c := range instances {
+	for i, c := range instances {
 		priority, _ := strconv.Atoi(c.ExpandedConfig()["boot.stop.priority"])
 
 		// Enforce shutdown priority
@@ -407,28 +532,36 @@ func instancesShutdown(s *state.State) error {
 			wg.Wait()
 		}
 
-		// Record the current state
-		lastState := c.State()
+		lastState := lastStates[i]
 
 		// Stop the container
 		if lastState != "ERROR" && lastState != "STOPPED" {
 			// Determinate how long to wait for the instance to shutdown cleanly
-			var timeoutSeconds int
+			timeout := defaultTimeout
 			value, ok := c.ExpandedConfig()["boot.host_shutdown_timeout"]
 			if ok {
-				timeoutSeconds, _ = strconv.Atoi(value)
-			} else {
-				timeoutSeconds = 30
+				timeoutSeconds, _ := strconv.Atoi(value)
+				timeout = time.Second * time.Duration(timeoutSeconds)
+			}
+
+			action := c.ExpandedConfig()["boot.host_shutdown_action"]
+
+			if slots != nil {
+				slots <- struct{}{}
 			}
 
 			// Stop the instance
 			wg.Add(1)
 			go func(c instance.Instance, lastState string) {
-				c.Shutdown(time.Second * time.Duration(timeoutSeconds))
-				c.Stop(false)
-				c.VolatileSet(map[string]string{"volatile.last_state.power": lastState})
+				defer wg.Done()
 
-				wg.Done()
+				if slots != nil {
+					defer func() { <-slots }()
+				}
+
+				instanceHostShutdown(c, action, timeout)
+				c.VolatileSet(map[string]string{"volatile.last_state.power": lastState})
+				sendProgress(c)
 			}(c, lastState)
 		} else {
 			c.VolatileSet(map[string]string{"volatile.last_state.power": lastState})
@@ -438,3 +571,23 @@ func instancesShutdown(s *state.State) error {
 
 	return nil
 }
+
+// instanceHostShutdown stops an instance as part of the host shutdown according to its boot.host_shutdown_action.
+func instanceHostShutdown(inst instance.Instance, action string, timeout time.Duration) {
+	if action == "force-stop" {
+		inst.Stop(false)
+		return
+	}
+
+	if action == "stateful-stop" {
+		err := inst.Stop(true)
+		if err == nil {
+			return
+		}
+
+		logger.Warn("Failed stateful stop of instance, falling back to clean shutdown", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+	}
+
+	inst.Shutdown(timeout)
+	inst.Stop(false)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/db/query"
@@ -60,6 +62,15 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
@@ -114,10 +125,24 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
 //     example: default
+//   - in: query
+//     name: fields
+//     description: Comma separated list of the fields to return for each instance
+//     type: string
+//     example: name,status,state.network
 // responses:
 //   "200":
 //     description: API endpoints
@@ -167,10 +192,24 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 //     type: string
 //     example: default
 //   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: filter
 //     description: Collection filter
 //     type: string
 //     example: default
+//   - in: query
+//     name: fields
+//     description: Comma separated list of the fields to return for each instance
+//     type: string
+//     example: name,status,state.network
 // responses:
 //   "200":
 //     description: API endpoints
@@ -200,10 +239,15 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func instancesGet(d *Daemon, r *http.Request) response.Response {
+	page, err := paginationParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	for i := 0; i < 100; i++ {
-		result, err := doInstancesGet(d, r)
+		result, count, last, err := doInstancesGet(d, r, page)
 		if err == nil {
-			return response.SyncResponse(true, result)
+			return paginatedResponse(page, count, last, result)
 		}
 		if !query.IsRetriableError(err) {
 			logger.Debugf("DBERR: containersGet: error %q", err)
@@ -219,7 +263,55 @@ func instancesGet(d *Daemon, r *http.Request) response.Response {
 	return response.InternalError(fmt.Errorf("DB is locked"))
 }
 
-func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
+// instancesDBFilter returns the database filter selecting the instances of the given type which may match the
+// clauses, and whether the clauses narrowed it down.
+func instancesDBFilter(instanceType instancetype.Type, clauses []filter.Clause) (db.InstanceFilter, bool) {
+	dbFilter := db.InstanceTypeFilter(instanceType)
+	narrowed := false
+
+	name, ok := filter.ExactValue(clauses, "name")
+	if ok {
+		dbFilter.Name = &name
+		narrowed = true
+	}
+
+	location, ok := filter.ExactValue(clauses, "location")
+	if ok {
+		dbFilter.Node = &location
+		narrowed = true
+	}
+
+	typeName, ok := filter.ExactValue(clauses, "type")
+	if ok && dbFilter.Type == nil {
+		filterType, err := instancetype.New(typeName)
+		if err == nil {
+			dbFilter.Type = &filterType
+			narrowed = true
+		}
+	}
+
+	return dbFilter, narrowed
+}
+
+// instancesFieldsRecursion returns the recursion level needed to return the given fields of the instances. The full
+// recursion is only kept if one of the fields needs the state, snapshots or backups of the instances.
+func instancesFieldsRecursion(recursion int, fields []string) int {
+	if recursion < 2 || len(fields) == 0 {
+		return recursion
+	}
+
+	for _, field := range fields {
+		if shared.StringInSlice(strings.SplitN(field, ".", 2)[0], []string{"state", "snapshots", "backups"}) {
+			return recursion
+		}
+	}
+
+	return 1
+}
+
+// doInstancesGet returns the instances part of the given page, along with the number of instances in the page
+// (before filtering) and the name of the last one.
+func doInstancesGet(d *Daemon, r *http.Request, page db.Pagination) (interface{}, int, string, error) {
 	resultString := []string{}
 	resultList := []*api.Instance{}
 	resultFullList := []*api.InstanceFull{}
@@ -227,7 +319,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 
 	instanceType, err := urlInstanceTypeDetect(r)
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
 
 	// Parse the recursion field
@@ -238,31 +330,37 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 		recursion = 0
 	}
 
+	// Skip gathering the state, snapshots and backups of the instances when none of the requested fields needs them.
+	recursion = instancesFieldsRecursion(recursion, fieldsParam(r))
+
 	// Parse filter value
 	filterStr := r.FormValue("filter")
 	var clauses []filter.Clause
 	if filterStr != "" {
 		clauses, err = filter.Parse(filterStr)
 		if err != nil {
-			return nil, errors.Wrap(err, "Invalid filter")
+			return nil, 0, "", errors.Wrap(err, "Invalid filter")
 		}
 	}
 
 	// Parse the project field
 	projectName := projectParam(r)
 
+	// Let the database narrow down the instances when the filter requires specific values.
+	dbFilter, narrowed := instancesDBFilter(instanceType, clauses)
+
 	// Get the list and location of all containers
 	var result map[string][]string // Containers by node address
 	var nodes map[string]string    // Node names by container
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	err = d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 		var err error
 
-		result, err = tx.GetInstanceNamesByNodeAddress(projectName, db.InstanceTypeFilter(instanceType))
+		result, err = tx.GetInstanceNamesByNodeAddress(projectName, dbFilter, page)
 		if err != nil {
 			return err
 		}
 
-		nodes, err = tx.GetInstanceToNodeMap(projectName, db.InstanceTypeFilter(instanceType))
+		nodes, err = tx.GetInstanceToNodeMap(projectName, dbFilter, page)
 		if err != nil {
 			return err
 		}
@@ -270,16 +368,38 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 		return nil
 	})
 	if err != nil {
-		return []string{}, err
+		return []string{}, 0, "", err
+	}
+
+	// Find the last instance of the page.
+	count := 0
+	last := ""
+	for _, instanceNames := range result {
+		count += len(instanceNames)
+		for _, instanceName := range instanceNames {
+			if instanceName > last {
+				last = instanceName
+			}
+		}
 	}
 
 	// Get the local instances
 	nodeInstances := map[string]instance.Instance{}
 	mustLoadObjects := recursion > 0 || (recursion == 0 && clauses != nil)
-	if mustLoadObjects {
+	if mustLoadObjects && (page.IsSet() || narrowed) {
+		// Only load the local instances part of the page or matching the filter.
+		for _, instanceName := range result[""] {
+			inst, err := instance.LoadByProjectAndName(d.State(), projectName, instanceName)
+			if err != nil {
+				return nil, 0, "", err
+			}
+
+			nodeInstances[inst.Name()] = inst
+		}
+	} else if mustLoadObjects {
 		insts, err := instanceLoadNodeProjectAll(d.State(), projectName, instanceType)
 		if err != nil {
-			return nil, err
+			return nil, 0, "", err
 		}
 
 		for _, inst := range insts {
@@ -348,7 +468,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 				defer wg.Done()
 
 				if recursion == 1 {
-					cs, err := doContainersGetFromNode(projectName, address, networkCert, d.serverCert(), r, instanceType)
+					cs, err := doContainersGetFromNode(projectName, address, networkCert, d.serverCert(), r, instanceType, page)
 					if err != nil {
 						for _, name := range containers {
 							resultListAppend(name, api.Instance{}, err)
@@ -364,7 +484,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 					return
 				}
 
-				cs, err := doContainersFullGetFromNode(projectName, address, networkCert, d.serverCert(), r, instanceType)
+				cs, err := doContainersFullGetFromNode(projectName, address, networkCert, d.serverCert(), r, instanceType, page)
 				if err != nil {
 					for _, name := range containers {
 						resultFullListAppend(name, api.InstanceFull{}, err)
@@ -415,7 +535,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 						}
 
 						if recursion < 2 {
-							c, _, err := inst.Render()
+							c, _, err := inst.Render(instanceRenderTags(d.State(), inst))
 							if err != nil {
 								resultListAppend(instanceName, api.Instance{}, err)
 							} else {
@@ -426,6 +546,10 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 						}
 
 						c, _, err := inst.RenderFull()
+						if err == nil {
+							err = instanceRenderTags(d.State(), inst)(&c.Instance)
+						}
+
 						if err != nil {
 							resultFullListAppend(instanceName, api.InstanceFull{}, err)
 						} else {
@@ -459,7 +583,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 				resultString = append(resultString, url)
 			}
 		}
-		return resultString, nil
+		return resultString, count, last, nil
 	}
 
 	if recursion == 1 {
@@ -470,7 +594,7 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 		if clauses != nil {
 			resultList = instance.Filter(resultList, clauses)
 		}
-		return resultList, nil
+		return resultList, count, last, nil
 	}
 
 	// Sort the result list by name.
@@ -481,12 +605,12 @@ func doInstancesGet(d *Daemon, r *http.Request) (interface{}, error) {
 	if clauses != nil {
 		resultFullList = instance.FilterFull(resultFullList, clauses)
 	}
-	return resultFullList, nil
+	return resultFullList, count, last, nil
 }
 
 // Fetch information about the containers on the given remote node, using the
 // rest API and with a timeout of 30 seconds.
-func doContainersGetFromNode(project, node string, networkCert *shared.CertInfo, serverCert *shared.CertInfo, r *http.Request, instanceType instancetype.Type) ([]api.Instance, error) {
+func doContainersGetFromNode(project, node string, networkCert *shared.CertInfo, serverCert *shared.CertInfo, r *http.Request, instanceType instancetype.Type, page db.Pagination) ([]api.Instance, error) {
 	f := func() ([]api.Instance, error) {
 		client, err := cluster.Connect(node, networkCert, serverCert, r, true)
 		if err != nil {
@@ -495,7 +619,12 @@ func doContainersGetFromNode(project, node string, networkCert *shared.CertInfo,
 
 		client = client.UseProject(project)
 
-		containers, err := client.GetInstances(api.InstanceType(instanceType.String()))
+		var containers []api.Instance
+		if page.IsSet() {
+			err = instancesGetPageFromNode(client, project, instanceType, 1, page, &containers)
+		} else {
+			containers, err = client.GetInstances(api.InstanceType(instanceType.String()))
+		}
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to get instances from node %s", node)
 		}
@@ -523,7 +652,7 @@ func doContainersGetFromNode(project, node string, networkCert *shared.CertInfo,
 	return containers, err
 }
 
-func doContainersFullGetFromNode(project, node string, networkCert *shared.CertInfo, serverCert *shared.CertInfo, r *http.Request, instanceType instancetype.Type) ([]api.InstanceFull, error) {
+func doContainersFullGetFromNode(project, node string, networkCert *shared.CertInfo, serverCert *shared.CertInfo, r *http.Request, instanceType instancetype.Type, page db.Pagination) ([]api.InstanceFull, error) {
 	f := func() ([]api.InstanceFull, error) {
 		client, err := cluster.Connect(node, networkCert, serverCert, r, true)
 		if err != nil {
@@ -532,7 +661,12 @@ func doContainersFullGetFromNode(project, node string, networkCert *shared.CertI
 
 		client = client.UseProject(project)
 
-		instances, err := client.GetInstancesFull(api.InstanceType(instanceType.String()))
+		var instances []api.InstanceFull
+		if page.IsSet() {
+			err = instancesGetPageFromNode(client, project, instanceType, 2, page, &instances)
+		} else {
+			instances, err = client.GetInstancesFull(api.InstanceType(instanceType.String()))
+		}
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to get instances from node %s", node)
 		}
@@ -559,3 +693,24 @@ func doContainersFullGetFromNode(project, node string, networkCert *shared.CertI
 
 	return instances, err
 }
+
+// instancesGetPageFromNode fetches the instances of the given page which are hosted on the remote node the client
+// is connected to.
+func instancesGetPageFromNode(client lxd.InstanceServer, project string, instanceType instancetype.Type, recursion int, page db.Pagination, target interface{}) error {
+	v := url.Values{}
+	v.Set("recursion", strconv.Itoa(recursion))
+	v.Set("project", project)
+	v.Set("limit", strconv.Itoa(page.Limit))
+	v.Set("after", page.After)
+
+	if instanceType != instancetype.Any {
+		v.Set("instance-type", instanceType.String())
+	}
+
+	resp, _, err := client.RawQuery("GET", fmt.Sprintf("/%s/instances?%s", version.APIVersion, v.Encode()), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return resp.MetadataAsStruct(target)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestInstancesFieldsRecursion(t *testing.T) {
+	cases := []struct {
+		recursion int
+		fields    []string
+		expected  int
+	}{
+		{0, []string{"name"}, 0},
+		{1, []string{"name"}, 1},
+		{2, nil, 2},
+		{2, []string{"name", "status", "config.user.foo"}, 1},
+		{2, []string{"name", "state"}, 2},
+		{2, []string{"state.network"}, 2},
+		{2, []string{"snapshots"}, 2},
+		{2, []string{"backups.name"}, 2},
+
+		// Only the first part of the path counts.
+		{2, []string{"config.state"}, 1},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, instancesFieldsRecursion(c.recursion, c.fields), "recursion %d with fields %v", c.recursion, c.fields)
+	}
+}
+
+func TestFieldsParam(t *testing.T) {
+	cases := map[string][]string{
+		"/1.0/instances":                                    nil,
+		"/1.0/instances?fields=":                            nil,
+		"/1.0/instances?fields=name":                        {"name"},
+		"/1.0/instances?fields=name,%20state.network,,":     {"name", "state.network"},
+		"/1.0/instances?recursion=2&fields=config.user.foo": {"config.user.foo"},
+	}
+
+	for url, expected := range cases {
+		r := httptest.NewRequest("GET", url, nil)
+		assert.Equal(t, expected, fieldsParam(r), url)
+	}
+}
+
+// Requesting fields which don't need the state of the instances downgrades the recursion.
+func (suite *containerTestSuite) TestInstancesGet_FieldsRecursion() {
+	args := db.InstanceArgs{
+		Type:      instancetype.Container,
+		Ephemeral: false,
+		Name:      "testFoo",
+	}
+
+	c, op, err := instance.CreateInternal(suite.d.State(), args, true, nil, revert.New())
+	suite.Req.Nil(err)
+	op.Done(nil)
+	defer c.Delete(true)
+
+	// The instance type is detected from the route of the request.
+	get := func(url string) interface{} {
+		var result interface{}
+
+		router := mux.NewRouter()
+		router.HandleFunc("/1.0/instances", func(w http.ResponseWriter, r *http.Request) {
+			result, _, _, err = doInstancesGet(suite.d, r, db.Pagination{})
+		}).Name("instances")
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+		suite.Req.Nil(err)
+
+		return result
+	}
+
+	instances, ok := get("/1.0/instances?recursion=2&fields=name,config.user.foo").([]*api.Instance)
+	suite.Req.True(ok, "Expected the instances without their state")
+	suite.Req.Len(instances, 1)
+	suite.Equal("testFoo", instances[0].Name)
+
+	suite.Equal([]string{"/1.0/instances/testFoo"}, get("/1.0/instances?fields=name"))
+}
+
+// The fields query parameter trims the metadata of the GET responses, whatever the endpoint.
+func (suite *containerTestSuite) TestCreateCmd_Fields() {
+	metadata := []map[string]interface{}{
+		{"name": "c1", "config": map[string]string{"user.foo": "bar", "limits.cpu": "2"}},
+		{"name": "c2", "config": map[string]string{"user.foo": "baz"}},
+	}
+
+	handler := func(d *Daemon, r *http.Request) response.Response {
+		return response.SyncResponse(true, metadata)
+	}
+
+	router := mux.NewRouter()
+	suite.d.createCmd(router, "1.0", APIEndpoint{
+		Path: "fields-test",
+		Get:  APIEndpointAction{Handler: handler, AllowUntrusted: true},
+		Post: APIEndpointAction{Handler: handler, AllowUntrusted: true},
+	})
+
+	request := func(method string, url string) interface{} {
+		r := httptest.NewRequest(method, url, nil)
+		r.TLS = &tls.ConnectionState{}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		resp := api.ResponseRaw{}
+		suite.Req.Nil(json.NewDecoder(w.Body).Decode(&resp))
+		suite.Req.Equal(http.StatusOK, resp.StatusCode)
+
+		return resp.Metadata
+	}
+
+	suite.Equal([]interface{}{
+		map[string]interface{}{"name": "c1", "config": map[string]interface{}{"user.foo": "bar"}},
+		map[string]interface{}{"name": "c2", "config": map[string]interface{}{"user.foo": "baz"}},
+	}, request("GET", "/1.0/fields-test?fields=name,config.user.foo"))
+
+	suite.Len(request("GET", "/1.0/fields-test").([]interface{})[0], 2)
+
+	// Only GET responses are trimmed.
+	suite.Len(request("POST", "/1.0/fields-test?fields=name").([]interface{})[0], 2)
+}
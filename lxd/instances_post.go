@@ -127,8 +127,12 @@ func createFromImage(d *Daemon, r *http.Request, projectName string, req *api.In
 			return err
 		}
 
-		_, err = instanceCreateFromImage(d, r, args, info.Fingerprint, op)
-		return err
+		inst, err := instanceCreateFromImage(d, r, args, info.Fingerprint, op)
+		if err != nil {
+			return err
+		}
+
+		return instanceUpdateTags(d.State(), inst, req.Tags)
 	}
 
 	resources := map[string][]string{}
@@ -176,8 +180,12 @@ func createFromNone(d *Daemon, r *http.Request, projectName string, req *api.Ins
 	}
 
 	run := func(op *operations.Operation) error {
-		_, err := instanceCreateAsEmpty(d, args)
-		return err
+		inst, err := instanceCreateAsEmpty(d, args)
+		if err != nil {
+			return err
+		}
+
+		return instanceUpdateTags(d.State(), inst, req.Tags)
 	}
 
 	resources := map[string][]string{}
@@ -381,6 +389,11 @@ func createFromMigration(d *Daemon, r *http.Request, projectName string, req *ap
 			return err
 		}
 
+		err = instanceUpdateTags(d.State(), inst, req.Tags)
+		if err != nil {
+			return err
+		}
+
 		runRevert.Success()
 		return nil
 	}
@@ -467,7 +480,7 @@ func createFromCopy(d *Daemon, r *http.Request, projectName string, req *api.Ins
 				return response.SmartError(err)
 			}
 
-			if pool.Driver != "ceph" {
+			if !shared.StringInSlice(pool.Driver, db.StorageRemoteDriverNames()) {
 				// Redirect to migration
 				return clusterCopyContainerInternal(d, r, source, projectName, req)
 			}
@@ -562,7 +575,7 @@ func createFromCopy(d *Daemon, r *http.Request, projectName string, req *api.Ins
 	}
 
 	run := func(op *operations.Operation) error {
-		_, err := instanceCreateAsCopy(d.State(), instanceCreateAsCopyOpts{
+		inst, err := instanceCreateAsCopy(d.State(), instanceCreateAsCopyOpts{
 			sourceInstance:       source,
 			targetInstance:       args,
 			instanceOnly:         req.Source.InstanceOnly || req.Source.ContainerOnly,
@@ -572,7 +585,8 @@ func createFromCopy(d *Daemon, r *http.Request, projectName string, req *api.Ins
 		if err != nil {
 			return err
 		}
-		return nil
+
+		return instanceUpdateTags(d.State(), inst, req.Tags)
 	}
 
 	resources := map[string][]string{}
@@ -703,6 +717,13 @@ func createFromBackup(d *Daemon, r *http.Request, projectName string, data io.Re
 		defer backupFile.Close()
 		defer runRevert.Fail()
 
+		// Wait for our turn if the number of concurrent backups is limited.
+		releaseQueue, err := operations.QueueAcquire(d.ctx, op, operations.QueueBackups, operations.QueuePriorityNormal)
+		if err != nil {
+			return errors.Wrapf(err, "Failed waiting for backup slot")
+		}
+		defer releaseQueue()
+
 		pool, err := storagePools.GetPoolByName(d.State(), bInfo.Pool)
 		if err != nil {
 			return err
@@ -833,6 +854,11 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 		req.Type = api.InstanceType(urlType.String())
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	targetNode := queryParam(r, "target")
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		return project.CheckClusterTargetRestriction(tx, r, targetProject, targetNode)
@@ -840,6 +866,30 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
+
+	// Conversions happen on the member holding the source container.
+	if targetNode == "" && req.Source.Type == "conversion" && req.Source.Source != "" {
+		clustered, err := cluster.Enabled(d.db)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if clustered {
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				source, err := instance.LoadInstanceDatabaseObject(tx, targetProject, req.Source.Source)
+				if err != nil {
+					return err
+				}
+
+				targetNode = source.Node
+				return nil
+			})
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
 	if targetNode == "" {
 		// If no target node was specified, pick the node with the
 		// least number of containers. If there's just one node, or if
@@ -874,9 +924,15 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
+		// Skip the members whose GPUs are fully allocated.
+		excluded, err := gpuExcludedMembers(d, r, targetProject, req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			var err error
-			targetNode, err = tx.GetNodeWithLeastInstances(architectures, defaultArchId)
+			targetNode, err = tx.GetNodeWithLeastInstances(architectures, defaultArchId, excluded)
 			return err
 		})
 		if err != nil {
@@ -965,6 +1021,8 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 				req.Type = api.InstanceType(source.Type.String())
 			case "migration":
 				req.Type = api.InstanceTypeContainer // Default to container if not specified.
+			case "conversion":
+				req.Type = api.InstanceTypeVM
 			}
 		}
 
@@ -1009,6 +1067,8 @@ func instancesPost(d *Daemon, r *http.Request) response.Response {
 		return createFromMigration(d, r, targetProject, &req)
 	case "copy":
 		return createFromCopy(d, r, targetProject, &req)
+	case "conversion":
+		return createFromConversion(d, r, targetProject, &req)
 	default:
 		return response.BadRequest(fmt.Errorf("Unknown source type %s", req.Source.Type))
 	}
@@ -91,6 +91,19 @@ func instancesPut(d *Daemon, r *http.Request) response.Response {
 
 	action := shared.InstanceAction(req.State.Action)
 
+	// Get the instance tags if only the instances with some tags are targeted.
+	var instTags map[string][]string
+	if len(req.Tags) > 0 {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			instTags, err = tx.GetInstancesTags(projectName)
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	var names []string
 	var instances []instance.Instance
 	for _, inst := range c {
@@ -98,6 +111,10 @@ func instancesPut(d *Daemon, r *http.Request) response.Response {
 			continue
 		}
 
+		if !tagsContainAll(instTags[inst.Name()], req.Tags) {
+			continue
+		}
+
 		switch action {
 		case shared.Freeze:
 			if !inst.IsRunning() {
@@ -144,7 +161,7 @@ func instancesPut(d *Daemon, r *http.Request) response.Response {
 					defer wgAction.Done()
 
 					inst.SetOperation(op)
-					err := doInstanceStatePut(inst, *req.State)
+					err := doInstanceStatePut(d.State(), inst, *req.State)
 					if err != nil {
 						failuresLock.Lock()
 						failures[inst.Name()] = err
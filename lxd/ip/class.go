@@ -19,7 +19,16 @@ type ClassHTB struct {
 
 // Add adds class to a node
 func (class *ClassHTB) Add() error {
-	cmd := []string{"class", "add", "dev", class.Dev, "parent", class.Parent}
+	return class.run("add")
+}
+
+// Replace adds the class to a node or updates it if it already exists
+func (class *ClassHTB) Replace() error {
+	return class.run("replace")
+}
+
+func (class *ClassHTB) run(action string) error {
+	cmd := []string{"class", action, "dev", class.Dev, "parent", class.Parent}
 	if class.Classid != "" {
 		cmd = append(cmd, "classid", class.Classid)
 	}
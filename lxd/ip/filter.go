@@ -15,6 +15,7 @@ type ActionPolice struct {
 	Burst string
 	Mtu   string
 	Drop  bool
+	Pipe  bool // Pass the conforming packets on to the next action
 }
 
 // AddAction generates a part of command specific for 'police' action
@@ -32,12 +33,33 @@ func (a *ActionPolice) AddAction() []string {
 		result = append(result, "mtu", a.Mtu)
 	}
 
-	if a.Drop == true {
+	if a.Drop == true && a.Pipe == true {
+		result = append(result, "conform-exceed", "drop/pipe")
+	} else if a.Drop == true {
 		result = append(result, "drop")
 	}
 	return result
 }
 
+// ActionMirred represents an action of 'mirred' type
+type ActionMirred struct {
+	Direction string // Either "egress" or "ingress"
+	Redirect  bool   // Redirect the packets instead of mirroring them
+	Dev       string
+}
+
+// AddAction generates a part of command specific for 'mirred' action
+func (a *ActionMirred) AddAction() []string {
+	result := []string{"action", "mirred", a.Direction}
+	if a.Redirect {
+		result = append(result, "redirect")
+	} else {
+		result = append(result, "mirror")
+	}
+
+	return append(result, "dev", a.Dev)
+}
+
 // Filter represents filter object
 type Filter struct {
 	Dev      string
@@ -49,9 +71,10 @@ type Filter struct {
 // U32Filter represents universal 32bit traffic control filter
 type U32Filter struct {
 	Filter
-	Value   string
-	Mask    string
-	Actions []Action
+	Priority string
+	Value    string
+	Mask     string
+	Actions  []Action
 }
 
 // Add adds universal 32bit traffic control filter to a node
@@ -62,6 +85,10 @@ func (u32 *U32Filter) Add() error {
 	}
 
 	cmd = append(cmd, "protocol", u32.Protocol)
+	if u32.Priority != "" {
+		cmd = append(cmd, "prio", u32.Priority)
+	}
+
 	cmd = append(cmd, "u32", "match", "u32", u32.Value, u32.Mask)
 
 	for _, action := range u32.Actions {
@@ -79,3 +106,37 @@ func (u32 *U32Filter) Add() error {
 	}
 	return nil
 }
+
+// FlowerFilter represents flow based traffic control filter
+type FlowerFilter struct {
+	Filter
+	Priority string
+	SrcMAC   string
+	Action   string
+}
+
+// Add adds flow based traffic control filter to a node
+func (flower *FlowerFilter) Add() error {
+	cmd := []string{"filter", "add", "dev", flower.Dev}
+	if flower.Parent != "" {
+		cmd = append(cmd, "parent", flower.Parent)
+	}
+
+	cmd = append(cmd, "protocol", flower.Protocol)
+	if flower.Priority != "" {
+		cmd = append(cmd, "prio", flower.Priority)
+	}
+
+	cmd = append(cmd, "flower")
+	if flower.SrcMAC != "" {
+		cmd = append(cmd, "src_mac", flower.SrcMAC)
+	}
+
+	cmd = append(cmd, "action", flower.Action)
+
+	_, err := shared.RunCommand("tc", cmd...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
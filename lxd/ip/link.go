@@ -150,6 +150,63 @@ type VirtFuncInfo struct {
 	MAC        string           `json:"mac"` // Deprecated
 	VLANs      []map[string]int `json:"vlan_list"`
 	SpoofCheck bool             `json:"spoofchk"`
+	LinkState  string           `json:"link_state"`
+}
+
+// VirtFuncStats holds the traffic counters of a vf.
+type VirtFuncStats struct {
+	RxBytes   uint64
+	RxPackets uint64
+	TxBytes   uint64
+	TxPackets uint64
+}
+
+// GetVFStats returns the traffic counters of a virtual function
+func (l *Link) GetVFStats(vfID int) (VirtFuncStats, error) {
+	stats := VirtFuncStats{}
+
+	out, err := shared.RunCommand("ip", "-s", "-j", "link", "show", l.Name)
+	if err != nil {
+		return stats, err
+	}
+
+	// The transmit counters of the VFs are prefixed with tx_ by ip.
+	var ifInfo []struct {
+		VFList []struct {
+			VF    int `json:"vf"`
+			Stats struct {
+				RX struct {
+					Bytes   uint64 `json:"bytes"`
+					Packets uint64 `json:"packets"`
+				} `json:"rx"`
+				TX struct {
+					Bytes   uint64 `json:"tx_bytes"`
+					Packets uint64 `json:"tx_packets"`
+				} `json:"tx"`
+			} `json:"stats"`
+		} `json:"vfinfo_list"`
+	}
+
+	err = json.Unmarshal([]byte(out), &ifInfo)
+	if err != nil {
+		return stats, err
+	}
+
+	if len(ifInfo) == 0 {
+		return stats, fmt.Errorf("no matching virtual function found")
+	}
+
+	for _, vfInfo := range ifInfo[0].VFList {
+		if vfInfo.VF == vfID {
+			stats.RxBytes = vfInfo.Stats.RX.Bytes
+			stats.RxPackets = vfInfo.Stats.RX.Packets
+			stats.TxBytes = vfInfo.Stats.TX.Bytes
+			stats.TxPackets = vfInfo.Stats.TX.Packets
+			return stats, nil
+		}
+	}
+
+	return stats, fmt.Errorf("no matching virtual function found")
 }
 
 // GetVFInfo returns info about virtual function
@@ -1,6 +1,8 @@
 package ip
 
 import (
+	"strings"
+
 	"github.com/lxc/lxd/shared"
 )
 
@@ -19,6 +21,33 @@ func (n *Neigh) Show() (string, error) {
 	return out, nil
 }
 
+// ShowProxy lists the addresses of the proxy neighbour entries
+func (n *Neigh) ShowProxy() ([]string, error) {
+	out, err := shared.RunCommand("ip", "neigh", "show", "proxy", "dev", n.DevName)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			addresses = append(addresses, fields[0])
+		}
+	}
+
+	return addresses, nil
+}
+
+// AddProxy adds a proxy neighbour entry
+func (n *Neigh) AddProxy() error {
+	_, err := shared.RunCommand("ip", "neigh", "add", "proxy", n.Proxy, "dev", n.DevName)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // Delete deletes a neighbour entry
 func (n *Neigh) Delete() error {
 	_, err := shared.RunCommand("ip", "neigh", "delete", "proxy", n.Proxy, "dev", n.DevName)
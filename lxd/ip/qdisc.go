@@ -77,3 +77,17 @@ func (qdisc *QdiscHTB) Add() error {
 	}
 	return nil
 }
+
+// QdiscClsact represents the classifier-action qdisc object, used to attach filters to ingress and egress
+type QdiscClsact struct {
+	Dev string
+}
+
+// Add adds qdisc to a node
+func (qdisc *QdiscClsact) Add() error {
+	_, err := shared.RunCommand("tc", "qdisc", "add", "dev", qdisc.Dev, "clsact")
+	if err != nil {
+		return err
+	}
+	return nil
+}
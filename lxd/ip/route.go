@@ -14,6 +14,7 @@ type Route struct {
 	Src     string
 	Proto   string
 	Family  string
+	Via     string
 }
 
 // Add adds new route
@@ -22,7 +23,11 @@ func (r *Route) Add() error {
 	if r.Table != "" {
 		cmd = append(cmd, "table", r.Table)
 	}
-	cmd = append(cmd, r.Route, "dev", r.DevName)
+	cmd = append(cmd, r.Route)
+	if r.Via != "" {
+		cmd = append(cmd, "via", r.Via)
+	}
+	cmd = append(cmd, "dev", r.DevName)
 	if r.Src != "" {
 		cmd = append(cmd, "src", r.Src)
 	}
@@ -36,6 +36,9 @@ const (
 	InstanceConsole          = InstanceAction("console")
 	InstanceConsoleRetrieved = InstanceAction("console-retrieved")
 	InstanceConsoleReset     = InstanceAction("console-reset")
+	InstanceCPURebalanced    = InstanceAction("cpu-rebalanced")
+	InstanceDeviceAdded      = InstanceAction("device-added")
+	InstanceDeviceRemoved    = InstanceAction("device-removed")
 	InstanceFileRetrieved    = InstanceAction("file-retrieved")
 	InstanceFilePushed       = InstanceAction("file-pushed")
 	InstanceFileDeleted      = InstanceAction("file-deleted")
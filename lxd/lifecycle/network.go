@@ -23,6 +23,10 @@ const (
 	NetworkDeleted = NetworkAction("deleted")
 	NetworkUpdated = NetworkAction("updated")
 	NetworkRenamed = NetworkAction("renamed")
+
+	NetworkLeaseIssued   = NetworkAction("lease-issued")
+	NetworkLeaseRenewed  = NetworkAction("lease-renewed")
+	NetworkLeaseReleased = NetworkAction("lease-released")
 )
 
 // Event creates the lifecycle event for an action on a network device.
@@ -0,0 +1,27 @@
+package lifecycle
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ServerAction represents a lifecycle event action for the server.
+type ServerAction string
+
+// All supported lifecycle events for the server.
+const (
+	ServerInstancesShutdownProgress = ServerAction("instances-shutdown-progress")
+)
+
+// Event creates the lifecycle event for an action on the server.
+func (a ServerAction) Event(ctx map[string]interface{}) api.EventLifecycle {
+	eventType := fmt.Sprintf("server-%s", a)
+	u := "/1.0"
+
+	return api.EventLifecycle{
+		Action:  eventType,
+		Source:  u,
+		Context: ctx,
+	}
+}
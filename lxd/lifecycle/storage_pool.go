@@ -16,6 +16,8 @@ const (
 	StoragePoolCreated = StoragePoolAction("created")
 	StoragePoolDeleted = StoragePoolAction("deleted")
 	StoragePoolUpdated = StoragePoolAction("updated")
+
+	StoragePoolUsageWarning = StoragePoolAction("usage-warning")
 )
 
 // Event creates the lifecycle event for an action on an storage pool.
@@ -2,10 +2,13 @@ package maas
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/juju/gomaasapi"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/project"
 )
@@ -67,11 +70,30 @@ func parseInterfaces(interfaces []ContainerInterface) (map[string]ContainerInter
 	return macInterfaces, nil
 }
 
-// NewController returns a new Controller using the specific MAAS server and machine
-func NewController(url string, key string, machine string) (*Controller, error) {
-	baseURL := fmt.Sprintf("%s/api/2.0/", url)
+// checkSubnetAddress checks that the static address requested for an interface is part of the MAAS subnet.
+func checkSubnetAddress(subnet gomaasapi.Subnet, address string) error {
+	if address == "" {
+		return nil
+	}
 
-	// Connect to MAAS
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("Invalid address '%s'", address)
+	}
+
+	_, cidr, err := net.ParseCIDR(subnet.CIDR())
+	if err != nil {
+		return err
+	}
+
+	if !cidr.Contains(ip) {
+		return fmt.Errorf("Address '%s' isn't part of MAAS subnet '%s' (%s)", address, subnet.Name(), subnet.CIDR())
+	}
+
+	return nil
+}
+
+func connect(baseURL string, key string) (gomaasapi.Controller, error) {
 	srv, err := gomaasapi.NewController(gomaasapi.ControllerArgs{
 		BaseURL: baseURL,
 		APIKey:  key,
@@ -86,6 +108,54 @@ func NewController(url string, key string, machine string) (*Controller, error)
 			strings.Split(strings.Split(err.Error(), "unsupported version: ")[1], " (")[0])
 	}
 
+	return srv, nil
+}
+
+// Subnets returns the sorted names of the IPv4 and IPv6 subnets known to the MAAS server
+func Subnets(url string, key string) ([]string, []string, error) {
+	srv, err := connect(fmt.Sprintf("%s/api/2.0/", url), key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spaces, err := srv.Spaces()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subnetsV4 := []string{}
+	subnetsV6 := []string{}
+	for _, space := range spaces {
+		for _, subnet := range space.Subnets() {
+			ip, _, err := net.ParseCIDR(subnet.CIDR())
+			if err != nil {
+				continue
+			}
+
+			if ip.To4() != nil {
+				subnetsV4 = append(subnetsV4, subnet.Name())
+			} else {
+				subnetsV6 = append(subnetsV6, subnet.Name())
+			}
+		}
+	}
+
+	sort.Strings(subnetsV4)
+	sort.Strings(subnetsV6)
+
+	return subnetsV4, subnetsV6, nil
+}
+
+// NewController returns a new Controller using the specific MAAS server and machine
+func NewController(url string, key string, machine string) (*Controller, error) {
+	baseURL := fmt.Sprintf("%s/api/2.0/", url)
+
+	// Connect to MAAS
+	srv, err := connect(baseURL, key)
+	if err != nil {
+		return nil, err
+	}
+
 	srvRaw, err := gomaasapi.NewAuthenticatedClient(baseURL, key)
 	if err != nil {
 		return nil, err
@@ -112,14 +182,18 @@ func NewController(url string, key string, machine string) (*Controller, error)
 }
 
 func (c *Controller) getDomain(inst Instance) string {
+	return c.getProjectDomain(inst.Project())
+}
+
+func (c *Controller) getProjectDomain(projectName string) string {
 	fields := strings.Split(c.machine.FQDN(), ".")
 	domain := strings.Join(fields[1:], ".")
 
-	if inst.Project() == project.Default {
+	if projectName == project.Default {
 		return domain
 	}
 
-	return fmt.Sprintf("%s.%s", inst.Project(), domain)
+	return fmt.Sprintf("%s.%s", projectName, domain)
 }
 
 func (c *Controller) getDevice(name string, domain string) (gomaasapi.Device, error) {
@@ -181,9 +255,14 @@ func (c *Controller) CreateContainer(inst Instance, interfaces []ContainerInterf
 		}
 
 		for _, subnet := range iface.Subnets {
-			_, ok := subnets[subnet.Name]
+			maasSubnet, ok := subnets[subnet.Name]
 			if !ok {
-				return fmt.Errorf("Subnet '%s' doesn't exist in MAAS", interfaces[0].Subnets[0].Name)
+				return fmt.Errorf("Subnet '%s' doesn't exist in MAAS", subnet.Name)
+			}
+
+			err := checkSubnetAddress(maasSubnet, subnet.Address)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -300,9 +379,14 @@ func (c *Controller) UpdateContainer(inst Instance, interfaces []ContainerInterf
 		}
 
 		for _, subnet := range iface.Subnets {
-			_, ok := subnets[subnet.Name]
+			maasSubnet, ok := subnets[subnet.Name]
 			if !ok {
-				return fmt.Errorf("Subnet '%s' doesn't exist in MAAS", interfaces[0].Subnets[0].Name)
+				return fmt.Errorf("Subnet '%s' doesn't exist in MAAS", subnet.Name)
+			}
+
+			err := checkSubnetAddress(maasSubnet, subnet.Address)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -407,27 +491,37 @@ func (c *Controller) UpdateContainer(inst Instance, interfaces []ContainerInterf
 	return nil
 }
 
-// RenameContainer renames the MAAS device for the container without releasing any allocation
+// RenameContainer renames the MAAS device for the container without releasing any allocation.
+// MAAS updates the DNS records of the device's addresses to the new name.
 func (c *Controller) RenameContainer(inst Instance, newName string) error {
-	device, err := c.getDevice(inst.Name(), c.getDomain(inst))
+	domain := c.getDomain(inst)
+
+	device, err := c.getDevice(inst.Name(), domain)
 	if err != nil {
 		return err
 	}
 
 	// FIXME: We should convince the Juju folks to implement an Update() method on Device
-	uri, err := url.Parse(fmt.Sprintf("%s/devices/%s/", c.url, device.SystemID()))
+	uri, err := url.Parse(fmt.Sprintf("%sdevices/%s/", c.url, device.SystemID()))
 	if err != nil {
 		return err
 	}
 
 	values := url.Values{}
 	values.Set("hostname", newName)
+	values.Set("domain", domain)
 
 	_, err = c.srvRaw.Put(uri, values)
 	if err != nil {
 		return err
 	}
 
+	// Check that the device (and so its DNS records) is now known under the new name.
+	_, err = c.getDevice(newName, domain)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to find renamed MAAS device")
+	}
+
 	return nil
 }
 
@@ -445,3 +539,22 @@ func (c *Controller) DeleteContainer(inst Instance) error {
 
 	return nil
 }
+
+// DeleteProject removes the MAAS devices left in the domain of the project.
+func (c *Controller) DeleteProject(projectName string) error {
+	devs, err := c.srv.Devices(gomaasapi.DevicesArgs{
+		Domain: c.getProjectDomain(projectName),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dev := range devs {
+		err = dev.Delete()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to delete MAAS device '%s'", dev.Hostname())
+		}
+	}
+
+	return nil
+}
@@ -114,10 +114,18 @@ func main() {
 	activateifneededCmd := cmdActivateifneeded{global: &globalCmd}
 	app.AddCommand(activateifneededCmd.Command())
 
+	// apply-config sub-command
+	applyConfigCmd := cmdApplyConfig{global: &globalCmd}
+	app.AddCommand(applyConfigCmd.Command())
+
 	// callhook sub-command
 	callhookCmd := cmdCallhook{global: &globalCmd}
 	app.AddCommand(callhookCmd.Command())
 
+	// export-config sub-command
+	exportConfigCmd := cmdExportConfig{global: &globalCmd}
+	app.AddCommand(exportConfigCmd.Command())
+
 	// forkconsole sub-command
 	forkconsoleCmd := cmdForkconsole{global: &globalCmd}
 	app.AddCommand(forkconsoleCmd.Command())
@@ -126,6 +134,10 @@ func main() {
 	forkDNSCmd := cmdForkDNS{global: &globalCmd}
 	app.AddCommand(forkDNSCmd.Command())
 
+	// forkdns64 sub-command
+	forkDNS64Cmd := cmdForkDNS64{global: &globalCmd}
+	app.AddCommand(forkDNS64Cmd.Command())
+
 	// forkexec sub-command
 	forkexecCmd := cmdForkexec{global: &globalCmd}
 	app.AddCommand(forkexecCmd.Command())
@@ -194,6 +206,10 @@ func main() {
 	recoverCmd := cmdRecover{global: &globalCmd}
 	app.AddCommand(recoverCmd.Command())
 
+	// reload sub-command
+	reloadCmd := cmdReload{global: &globalCmd}
+	app.AddCommand(reloadCmd.Command())
+
 	// shutdown sub-command
 	shutdownCmd := cmdShutdown{global: &globalCmd}
 	app.AddCommand(shutdownCmd.Command())
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+type cmdExportConfig struct {
+	global *cmdGlobal
+}
+
+func (c *cmdExportConfig) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "export-config"
+	cmd.Short = "Export the server configuration as YAML"
+	cmd.Long = `Description:
+  Export the server configuration as YAML
+
+  This prints the configuration of the server, projects, network ACLs,
+  networks, storage pools and profiles in the format accepted by
+  "lxd apply-config" (and "lxd init --preseed").
+`
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdExportConfig) Run(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("Invalid arguments")
+	}
+
+	d, err := lxd.ConnectLXDUnix("", nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to local LXD")
+	}
+
+	config, err := initDataNodeDump(d)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render the server configuration")
+	}
+
+	fmt.Printf("%s", out)
+
+	return nil
+}
+
+type cmdApplyConfig struct {
+	global *cmdGlobal
+
+	flagDryRun bool
+}
+
+func (c *cmdApplyConfig) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "apply-config [<file>]"
+	cmd.Short = "Apply a declarative server configuration"
+	cmd.Long = `Description:
+  Apply a declarative server configuration
+
+  This reads a YAML configuration as produced by "lxd export-config"
+  (from the given file or stdin) and changes the server so that it
+  matches it.
+
+  The server configuration and the projects, network ACLs, networks,
+  storage pools and profiles listed in the file are created or updated
+  to match it exactly, keys which aren't listed are removed. Those which
+  aren't listed at all are left untouched. Only the entities which differ
+  are changed, so applying the same file again does nothing.
+
+  All the changes are reverted if one of them fails.
+`
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, "Only show the changes which would be made")
+
+	return cmd
+}
+
+func (c *cmdApplyConfig) Run(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("Invalid arguments")
+	}
+
+	// Read the YAML.
+	var content []byte
+	var err error
+	if len(args) == 0 || args[0] == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(args[0])
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to read the configuration")
+	}
+
+	config := initDataNode{}
+	err = yaml.Unmarshal(content, &config)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse the configuration")
+	}
+
+	d, err := lxd.ConnectLXDUnix("", nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to local LXD")
+	}
+
+	changes, err := configApplyPlan(d, config)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("The server already matches the configuration")
+		return nil
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	for _, change := range changes {
+		fmt.Println(change.description())
+		if c.flagDryRun {
+			continue
+		}
+
+		err := change.apply()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to apply change %q", change.description())
+		}
+
+		revert.Add(change.revert)
+	}
+
+	revert.Success()
+	return nil
+}
+
+// configChange is a change to make to the server to bring it to the declared configuration.
+type configChange struct {
+	action     string
+	entityType string
+	name       string
+	project    string
+	fields     []string
+	apply      func() error
+	revert     func()
+}
+
+// configEntityNames are the human readable names of the entity types of configChange.
+var configEntityNames = map[string]string{
+	"server":       "server configuration",
+	"project":      "project",
+	"network_acl":  "network ACL",
+	"network":      "network",
+	"storage_pool": "storage pool",
+	"profile":      "profile",
+}
+
+// description returns a human readable description of the change.
+func (c configChange) description() string {
+	description := fmt.Sprintf("%s %s", strings.Title(c.action), configEntityNames[c.entityType])
+	if c.name != "" {
+		description += fmt.Sprintf(" %q", c.name)
+	}
+
+	if c.project != "" {
+		description += fmt.Sprintf(" in project %q", c.project)
+	}
+
+	if len(c.fields) > 0 {
+		description += fmt.Sprintf(" (%s)", strings.Join(c.fields, ", "))
+	}
+
+	return description
+}
+
+// planAction returns the API representation of the change.
+func (c configChange) planAction() api.ConfigPlanAction {
+	return api.ConfigPlanAction{
+		Action:      c.action,
+		EntityType:  c.entityType,
+		Name:        c.name,
+		Project:     c.project,
+		Fields:      c.fields,
+		Description: c.description(),
+	}
+}
+
+// configStringify returns the config with all the values converted to strings.
+func configStringify(config map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(config))
+	for k, v := range config {
+		result[k] = fmt.Sprintf("%v", v)
+	}
+
+	return result
+}
+
+// configWithVolatile returns the declared config along with the volatile keys of the current one, as those are
+// managed by LXD.
+func configWithVolatile(declared map[string]string, current map[string]string) map[string]string {
+	result := make(map[string]string, len(declared))
+	for k, v := range declared {
+		result[k] = v
+	}
+
+	for k, v := range current {
+		if strings.HasPrefix(k, "volatile.") {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// configChangedKeys returns the sorted list of keys whose values differ between the two configs.
+func configChangedKeys(current map[string]string, declared map[string]string) []string {
+	keys := []string{}
+	for k, v := range current {
+		if declared[k] != v {
+			keys = append(keys, k)
+		}
+	}
+
+	for k, v := range declared {
+		_, found := current[k]
+		if !found && v != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// configChangedFields returns the fields which differ between the current and declared description and config,
+// config keys being prefixed with "config.".
+func configChangedFields(curDescription string, newDescription string, curConfig map[string]string, newConfig map[string]string) []string {
+	fields := []string{}
+	if curDescription != newDescription {
+		fields = append(fields, "description")
+	}
+
+	for _, k := range configChangedKeys(curConfig, newConfig) {
+		fields = append(fields, "config."+k)
+	}
+
+	return fields
+}
+
+// configApplyPlan returns the changes needed to bring the server to the declared configuration, in the order
+// they need to be applied. The entities which aren't declared are left untouched.
+func configApplyPlan(d lxd.InstanceServer, declared initDataNode) ([]configChange, error) {
+	changes := []configChange{}
+
+	// Server configuration.
+	if declared.Config != nil {
+		current, _, err := d.GetServer()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to retrieve current server configuration")
+		}
+
+		curConfig := configStringify(current.Config)
+		newConfig := configStringify(declared.Config)
+		fields := configChangedFields("", "", curConfig, newConfig)
+		if len(fields) > 0 {
+			newServer := current.Writable()
+			newServer.Config = map[string]interface{}{}
+			for k, v := range newConfig {
+				newServer.Config[k] = v
+			}
+
+			changes = append(changes, configChange{
+				action:     "update",
+				entityType: "server",
+				fields:     fields,
+				apply:      func() error { return d.UpdateServer(newServer, "") },
+				revert:     func() { d.UpdateServer(current.Writable(), "") },
+			})
+		}
+	}
+
+	// Projects.
+	projectNames, err := d.GetProjectNames()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve list of projects")
+	}
+
+	// newProjects records the projects which will be created, those don't have any entity yet.
+	newProjects := map[string]bool{}
+
+	for i := range declared.Projects {
+		p := declared.Projects[i]
+
+		if !shared.StringInSlice(p.Name, projectNames) {
+			newProjects[p.Name] = true
+			changes = append(changes, configChange{
+				action:     "create",
+				entityType: "project",
+				name:       p.Name,
+				apply:      func() error { return d.CreateProject(p) },
+				revert:     func() { d.DeleteProject(p.Name) },
+			})
+
+			continue
+		}
+
+		current, _, err := d.GetProject(p.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current project %q", p.Name)
+		}
+
+		newProject := api.ProjectPut{Description: p.Description, Config: configWithVolatile(p.Config, current.Config)}
+		fields := configChangedFields(current.Description, newProject.Description, current.Config, newProject.Config)
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, configChange{
+			action:     "update",
+			entityType: "project",
+			name:       p.Name,
+			fields:     fields,
+			apply:      func() error { return d.UpdateProject(p.Name, newProject, "") },
+			revert:     func() { d.UpdateProject(current.Name, current.Writable(), "") },
+		})
+	}
+
+	// names returns the names of the entities of a project, which is empty for projects yet to be created.
+	names := func(projectName string, get func(d lxd.InstanceServer) ([]string, error)) ([]string, error) {
+		if newProjects[projectName] {
+			return []string{}, nil
+		}
+
+		return get(d.UseProject(projectName))
+	}
+
+	// Network ACLs.
+	for i := range declared.NetworkACLs {
+		acl := declared.NetworkACLs[i]
+		if acl.Project == "" {
+			acl.Project = project.Default
+		}
+
+		server := d.UseProject(acl.Project)
+		aclNames, err := names(acl.Project, func(d lxd.InstanceServer) ([]string, error) { return d.GetNetworkACLNames() })
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve list of network ACLs in project %q", acl.Project)
+		}
+
+		if !shared.StringInSlice(acl.Name, aclNames) {
+			changes = append(changes, configChange{
+				action:     "create",
+				entityType: "network_acl",
+				name:       acl.Name,
+				project:    acl.Project,
+				apply:      func() error { return server.CreateNetworkACL(acl.NetworkACLsPost) },
+				revert:     func() { server.DeleteNetworkACL(acl.Name) },
+			})
+
+			continue
+		}
+
+		current, _, err := server.GetNetworkACL(acl.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current network ACL %q in project %q", acl.Name, acl.Project)
+		}
+
+		newACL := acl.NetworkACLPut
+		newACL.Config = configWithVolatile(acl.Config, current.Config)
+		fields := configChangedFields(current.Description, newACL.Description, current.Config, newACL.Config)
+		if !reflect.DeepEqual(current.Ingress, newACL.Ingress) || !reflect.DeepEqual(current.Egress, newACL.Egress) {
+			fields = append(fields, "rules")
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, configChange{
+			action:     "update",
+			entityType: "network_acl",
+			name:       acl.Name,
+			project:    acl.Project,
+			fields:     fields,
+			apply:      func() error { return server.UpdateNetworkACL(acl.Name, newACL, "") },
+			revert:     func() { server.UpdateNetworkACL(current.Name, current.Writable(), "") },
+		})
+	}
+
+	// Networks.
+	for i := range declared.Networks {
+		network := declared.Networks[i]
+		if network.Project == "" {
+			network.Project = project.Default
+		}
+
+		server := d.UseProject(network.Project)
+		networkNames, err := names(network.Project, func(d lxd.InstanceServer) ([]string, error) { return d.GetNetworkNames() })
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve list of networks in project %q", network.Project)
+		}
+
+		if !shared.StringInSlice(network.Name, networkNames) {
+			changes = append(changes, configChange{
+				action:     "create",
+				entityType: "network",
+				name:       network.Name,
+				project:    network.Project,
+				apply:      func() error { return server.CreateNetwork(network.NetworksPost) },
+				revert:     func() { server.DeleteNetwork(network.Name) },
+			})
+
+			continue
+		}
+
+		current, _, err := server.GetNetwork(network.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current network %q in project %q", network.Name, network.Project)
+		}
+
+		if current.Type != network.Type {
+			return nil, fmt.Errorf("Network %q in project %q is of type %q instead of %q", network.Name, network.Project, current.Type, network.Type)
+		}
+
+		newNetwork := api.NetworkPut{Description: network.Description, Config: configWithVolatile(network.Config, current.Config)}
+		fields := configChangedFields(current.Description, newNetwork.Description, current.Config, newNetwork.Config)
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, configChange{
+			action:     "update",
+			entityType: "network",
+			name:       network.Name,
+			project:    network.Project,
+			fields:     fields,
+			apply:      func() error { return server.UpdateNetwork(network.Name, newNetwork, "") },
+			revert:     func() { server.UpdateNetwork(current.Name, current.Writable(), "") },
+		})
+	}
+
+	// Storage pools.
+	poolNames, err := d.GetStoragePoolNames()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve list of storage pools")
+	}
+
+	for i := range declared.StoragePools {
+		pool := declared.StoragePools[i]
+
+		if !shared.StringInSlice(pool.Name, poolNames) {
+			changes = append(changes, configChange{
+				action:     "create",
+				entityType: "storage_pool",
+				name:       pool.Name,
+				apply:      func() error { return d.CreateStoragePool(pool) },
+				revert:     func() { d.DeleteStoragePool(pool.Name) },
+			})
+
+			continue
+		}
+
+		current, _, err := d.GetStoragePool(pool.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current storage pool %q", pool.Name)
+		}
+
+		if current.Driver != pool.Driver {
+			return nil, fmt.Errorf("Storage pool %q is of type %q instead of %q", pool.Name, current.Driver, pool.Driver)
+		}
+
+		newPool := api.StoragePoolPut{Description: pool.Description, Config: configWithVolatile(pool.Config, current.Config)}
+		fields := configChangedFields(current.Description, newPool.Description, current.Config, newPool.Config)
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, configChange{
+			action:     "update",
+			entityType: "storage_pool",
+			name:       pool.Name,
+			fields:     fields,
+			apply:      func() error { return d.UpdateStoragePool(pool.Name, newPool, "") },
+			revert:     func() { d.UpdateStoragePool(current.Name, current.Writable(), "") },
+		})
+	}
+
+	// Profiles.
+	for i := range declared.Profiles {
+		profile := declared.Profiles[i]
+		if profile.Project == "" {
+			profile.Project = project.Default
+		}
+
+		server := d.UseProject(profile.Project)
+		profileNames, err := names(profile.Project, func(d lxd.InstanceServer) ([]string, error) { return d.GetProfileNames() })
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve list of profiles in project %q", profile.Project)
+		}
+
+		// New projects come with their own default profile if they have the profiles feature.
+		if !shared.StringInSlice(profile.Name, profileNames) && !(newProjects[profile.Project] && profile.Name == "default") {
+			changes = append(changes, configChange{
+				action:     "create",
+				entityType: "profile",
+				name:       profile.Name,
+				project:    profile.Project,
+				apply:      func() error { return server.CreateProfile(profile.ProfilesPost) },
+				revert:     func() { server.DeleteProfile(profile.Name) },
+			})
+
+			continue
+		}
+
+		newProfile := profile.ProfilePut
+		if newProjects[profile.Project] {
+			changes = append(changes, configChange{
+				action:     "update",
+				entityType: "profile",
+				name:       profile.Name,
+				project:    profile.Project,
+				fields:     []string{"description", "config", "devices"},
+				apply:      func() error { return server.UpdateProfile(profile.Name, newProfile, "") },
+				revert:     func() {},
+			})
+
+			continue
+		}
+
+		current, _, err := server.GetProfile(profile.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current profile %q in project %q", profile.Name, profile.Project)
+		}
+
+		newProfile.Config = configWithVolatile(profile.Config, current.Config)
+		fields := configChangedFields(current.Description, newProfile.Description, current.Config, newProfile.Config)
+		if !reflect.DeepEqual(current.Devices, newProfile.Devices) && (len(current.Devices) > 0 || len(newProfile.Devices) > 0) {
+			fields = append(fields, "devices")
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, configChange{
+			action:     "update",
+			entityType: "profile",
+			name:       profile.Name,
+			project:    profile.Project,
+			fields:     fields,
+			apply:      func() error { return server.UpdateProfile(profile.Name, newProfile, "") },
+			revert:     func() { server.UpdateProfile(current.Name, current.Writable(), "") },
+		})
+	}
+
+	return changes, nil
+}
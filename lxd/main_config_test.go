@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigWithVolatile(t *testing.T) {
+	declared := map[string]string{"ipv4.address": "10.0.0.1/24"}
+	current := map[string]string{"ipv4.address": "10.0.1.1/24", "ipv6.address": "none", "volatile.bridge.hwaddr": "00:16:3e:00:00:01"}
+
+	assert.Equal(t, map[string]string{"ipv4.address": "10.0.0.1/24", "volatile.bridge.hwaddr": "00:16:3e:00:00:01"}, configWithVolatile(declared, current))
+	assert.Equal(t, map[string]string{"ipv4.address": "10.0.0.1/24"}, declared)
+}
+
+func TestConfigChangedKeys(t *testing.T) {
+	current := map[string]string{"a": "1", "b": "2", "c": "3"}
+
+	assert.Equal(t, []string{}, configChangedKeys(current, current))
+	assert.Equal(t, []string{"b", "c", "d"}, configChangedKeys(current, map[string]string{"a": "1", "b": "4", "d": "5"}))
+	assert.Equal(t, []string{}, configChangedKeys(nil, map[string]string{"a": ""}))
+}
+
+func TestConfigChangedFields(t *testing.T) {
+	assert.Equal(t, []string{}, configChangedFields("foo", "foo", nil, nil))
+	assert.Equal(t, []string{"description", "config.a"}, configChangedFields("foo", "bar", map[string]string{"a": "1"}, nil))
+}
+
+func TestConfigChangeDescription(t *testing.T) {
+	change := configChange{action: "update", entityType: "network", name: "lxdbr0", project: "default", fields: []string{"config.ipv4.address"}}
+	assert.Equal(t, `Update network "lxdbr0" in project "default" (config.ipv4.address)`, change.description())
+
+	change = configChange{action: "create", entityType: "storage_pool", name: "data"}
+	assert.Equal(t, `Create storage pool "data"`, change.description())
+}
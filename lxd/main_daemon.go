@@ -111,6 +111,9 @@ func (c *cmdDaemon) Run(cmd *cobra.Command, args []string) error {
 		d.Kill()
 	}
 
+	var handoff *daemonHandoff
+
+wait:
 	select {
 	case sig := <-ch:
 		if sig == unix.SIGPWR {
@@ -133,7 +136,30 @@ func (c *cmdDaemon) Run(cmd *cobra.Command, args []string) error {
 
 		instancesShutdown(s)
 		networkShutdown(s)
+
+	case <-d.reloadChan:
+		logger.Infof("Asked to reload by API, waiting for all operations to finish")
+
+		// Stop accepting connections first, so that the requests made while the running operations
+		// finish are queued for the next process rather than refused.
+		var err error
+		handoff, err = daemonReloadPrepare(d)
+		if err != nil {
+			logger.Error("Failed preparing reload", log.Ctx{"err": err})
+			goto wait
+		}
+
+		cleanStop()
+	}
+
+	err = d.Stop()
+	if handoff != nil {
+		if err != nil {
+			logger.Warn("Failed stopping cleanly before reload", log.Ctx{"err": err})
+		}
+
+		return handoff.exec()
 	}
 
-	return d.Stop()
+	return err
 }
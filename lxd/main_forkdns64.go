@@ -0,0 +1,208 @@
+// forkdns64 provides a DNS64 resolver for the networks using NAT64.
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/logging"
+)
+
+type cmdForkDNS64 struct {
+	global *cmdGlobal
+}
+
+type dns64Handler struct {
+	prefix  net.IP
+	servers []string
+}
+
+// ServeDNS relays each DNS request to the upstream servers, synthesizing the AAAA records from the A records
+// for the names which don't have any.
+func (h *dns64Handler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	resp, err := h.forward(r)
+	if err != nil {
+		logger.Errorf("Upstream lookup failed: %v", err)
+
+		msg := &dns.Msg{}
+		msg.SetRcode(r, dns.RcodeServerFailure)
+		resp = msg
+	} else if len(r.Question) == 1 && r.Question[0].Qtype == dns.TypeAAAA && r.Question[0].Qclass == dns.ClassINET && resp.Rcode == dns.RcodeSuccess && !dns64HasAAAA(resp) {
+		synth, err := h.synthesize(r)
+		if err != nil {
+			logger.Errorf("AAAA record synthesis failed for %s: %v", r.Question[0].Name, err)
+		} else if synth != nil {
+			resp = synth
+		}
+	}
+
+	resp.Id = r.Id
+
+	err = w.WriteMsg(resp)
+	if err != nil {
+		logger.Errorf("Failed sending response: %v", err)
+	}
+}
+
+// forward sends the request to the upstream servers, returning the first answer.
+func (h *dns64Handler) forward(r *dns.Msg) (*dns.Msg, error) {
+	var err error
+
+	for _, server := range h.servers {
+		client := &dns.Client{Net: "udp"}
+
+		var resp *dns.Msg
+		resp, _, err = client.Exchange(r, server)
+		if err == nil && resp.Truncated {
+			client.Net = "tcp"
+			resp, _, err = client.Exchange(r, server)
+		}
+
+		if err != nil {
+			// Error sending request, try next server.
+			continue
+		}
+
+		return resp, nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("No upstream servers")
+	}
+
+	return nil, err
+}
+
+// synthesize looks up the A records of the requested name and maps them into the NAT64 prefix.
+// Returns nil if the name doesn't have any A records either.
+func (h *dns64Handler) synthesize(r *dns.Msg) (*dns.Msg, error) {
+	req := r.Copy()
+	req.Question[0].Qtype = dns.TypeA
+
+	resp, err := h.forward(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, nil
+	}
+
+	msg := &dns.Msg{}
+	msg.SetReply(r)
+	msg.RecursionAvailable = resp.RecursionAvailable
+
+	found := false
+	for _, rr := range resp.Answer {
+		switch record := rr.(type) {
+		case *dns.CNAME:
+			msg.Answer = append(msg.Answer, record)
+		case *dns.A:
+			address := make(net.IP, net.IPv6len)
+			copy(address, h.prefix[:12])
+			copy(address[12:], record.A.To4())
+
+			msg.Answer = append(msg.Answer, &dns.AAAA{
+				Hdr: dns.RR_Header{
+					Name:   record.Hdr.Name,
+					Rrtype: dns.TypeAAAA,
+					Class:  dns.ClassINET,
+					Ttl:    record.Hdr.Ttl,
+				},
+				AAAA: address,
+			})
+
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return msg, nil
+}
+
+// dns64HasAAAA returns whether the response contains AAAA records.
+func dns64HasAAAA(msg *dns.Msg) bool {
+	for _, rr := range msg.Answer {
+		if rr.Header().Rrtype == dns.TypeAAAA {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *cmdForkDNS64) Command() *cobra.Command {
+	// Main subcommand
+	cmd := &cobra.Command{}
+	cmd.Use = "forkdns64 <listen address> <NAT64 prefix>"
+	cmd.Short = "Internal DNS64 resolver"
+	cmd.Long = `Description:
+  Spawns a DNS64 resolver relaying the queries it receives to the upstream servers of the host.
+  When a name has no AAAA records but has A records, AAAA records are synthesized by mapping the IPv4
+  addresses into the NAT64 prefix so that IPv6-only clients can reach them through the NAT64 translator.
+`
+	cmd.RunE = c.Run
+	cmd.Hidden = true
+
+	return cmd
+}
+
+func (c *cmdForkDNS64) Run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	if len(args) < 2 {
+		cmd.Help()
+
+		if len(args) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("Missing required arguments")
+	}
+
+	log, err := logging.GetLogger("lxd-forkdns64", "", c.global.flagLogVerbose, c.global.flagLogDebug, nil)
+	if err != nil {
+		return err
+	}
+	logger.Log = log
+
+	_, prefix, err := net.ParseCIDR(args[1])
+	if err != nil || prefix.IP.To4() != nil {
+		return fmt.Errorf("Invalid NAT64 prefix %q", args[1])
+	}
+
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return fmt.Errorf("Unable to load the upstream DNS servers: %v", err)
+	}
+
+	servers := []string{}
+	for _, server := range config.Servers {
+		servers = append(servers, net.JoinHostPort(server, config.Port))
+	}
+
+	logger.Infof("Started with upstream servers: %v", servers)
+
+	srv := &dns.Server{
+		Addr: args[0],
+		Net:  "udp",
+	}
+
+	srv.Handler = &dns64Handler{
+		prefix:  prefix.IP.To16(),
+		servers: servers,
+	}
+
+	err = srv.ListenAndServe()
+	if err != nil {
+		return fmt.Errorf("Failed to set udp listener: %v\n", err)
+	}
+
+	return nil
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/filewatch"
+	"github.com/lxc/lxd/shared/idmap"
+	"github.com/lxc/lxd/shared/tarstream"
 )
 
 /*
@@ -405,6 +415,29 @@ void forkremovefile(char *rootfs, int pidfd, int ns_fd)
 	_exit(0);
 }
 
+void forkfileattach(char *rootfs, int pidfd, int ns_fd)
+{
+	// Only attach to the container, the rest is handled in Go.
+	if (ns_fd >= 0) {
+		attach_userns_fd(ns_fd);
+
+		if (!change_namespaces(pidfd, ns_fd, CLONE_NEWNS)) {
+			error("error: setns");
+			_exit(1);
+		}
+	} else {
+		if (chroot(rootfs) < 0) {
+			error("error: chroot");
+			_exit(1);
+		}
+
+		if (chdir("/") < 0) {
+			error("error: chdir");
+			_exit(1);
+		}
+	}
+}
+
 void forkfile(void)
 {
 	int ns_fd = -EBADF, pidfd = -EBADF;
@@ -449,6 +482,8 @@ void forkfile(void)
 		forkcheckfile(rootfs, pidfd, ns_fd);
 	} else if (strcmp(command, "remove") == 0) {
 		forkremovefile(rootfs, pidfd, ns_fd);
+	} else if (strcmp(command, "push-tar") == 0 || strcmp(command, "pull-tar") == 0 || strcmp(command, "watch") == 0) {
+		forkfileattach(rootfs, pidfd, ns_fd);
 	}
 }
 */
@@ -500,6 +535,27 @@ func (c *cmdForkfile) Command() *cobra.Command {
 	cmdRemove.RunE = c.Run
 	cmd.AddCommand(cmdRemove)
 
+	// push-tar
+	cmdPushTar := &cobra.Command{}
+	cmdPushTar.Use = "push-tar <rootfs> <PID> <PidFd> <destination> <idmap>"
+	cmdPushTar.Args = cobra.ExactArgs(5)
+	cmdPushTar.RunE = c.RunPushTar
+	cmd.AddCommand(cmdPushTar)
+
+	// pull-tar
+	cmdPullTar := &cobra.Command{}
+	cmdPullTar.Use = "pull-tar <rootfs> <PID> <PidFd> <source> <idmap>"
+	cmdPullTar.Args = cobra.ExactArgs(5)
+	cmdPullTar.RunE = c.RunPullTar
+	cmd.AddCommand(cmdPullTar)
+
+	// watch
+	cmdWatch := &cobra.Command{}
+	cmdWatch.Use = "watch <rootfs> <PID> <PidFd> <path> <recursive>"
+	cmdWatch.Args = cobra.ExactArgs(5)
+	cmdWatch.RunE = c.RunWatch
+	cmd.AddCommand(cmdWatch)
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
@@ -509,3 +565,64 @@ func (c *cmdForkfile) Command() *cobra.Command {
 func (c *cmdForkfile) Run(cmd *cobra.Command, args []string) error {
 	return fmt.Errorf("This command should have been intercepted in cgo")
 }
+
+// RunPushTar extracts the tarball read from stdin into the destination directory. It runs after the cgo part
+// attached to the container.
+func (c *cmdForkfile) RunPushTar(cmd *cobra.Command, args []string) error {
+	var shift tarstream.ShiftFunc
+
+	// Stopped containers are accessed from the host, so the IDs need shifting.
+	if args[4] != "" {
+		idmapset, err := idmap.JSONUnmarshal(args[4])
+		if err != nil {
+			return err
+		}
+
+		if idmapset != nil {
+			shift = idmapset.ShiftIntoNs
+		}
+	}
+
+	return tarstream.Extract(os.Stdin, args[3], shift, true)
+}
+
+// RunPullTar writes the tree at the source path to stdout as a tarball. It runs after the cgo part attached to
+// the container.
+func (c *cmdForkfile) RunPullTar(cmd *cobra.Command, args []string) error {
+	var shift tarstream.ShiftFunc
+
+	// Stopped containers are accessed from the host, so the IDs need unshifting.
+	if args[4] != "" {
+		idmapset, err := idmap.JSONUnmarshal(args[4])
+		if err != nil {
+			return err
+		}
+
+		if idmapset != nil {
+			shift = idmapset.ShiftFromNs
+		}
+	}
+
+	name := filepath.Base(args[3])
+	if name == "/" {
+		name = "."
+	}
+
+	return tarstream.Write(os.Stdout, args[3], name, shift)
+}
+
+// RunWatch writes the changes made under the path to stdout as JSON, one per line, until the path is deleted or
+// stdout is closed. It runs after the cgo part attached to the container.
+func (c *cmdForkfile) RunWatch(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	return filewatch.Watch(ctx, args[3], shared.IsTrue(args[4]), func(event api.InstanceFileEvent) {
+		err := encoder.Encode(event)
+		if err != nil {
+			cancel()
+		}
+	})
+}
@@ -113,7 +113,7 @@ void forknet(void)
 		forkdonetinfo(pidfd, ns_fd);
 	}
 
-	if (strcmp(command, "detach") == 0)
+	if (strcmp(command, "detach") == 0 || strcmp(command, "route") == 0)
 		forkdonetdetach(cur);
 }
 */
@@ -150,6 +150,13 @@ func (c *cmdForknet) Command() *cobra.Command {
 	cmdDetach.RunE = c.RunDetach
 	cmd.AddCommand(cmdDetach)
 
+	// route
+	cmdRoute := &cobra.Command{}
+	cmdRoute.Use = "route <netns file> <family> <ifname> <gateway> <route>..."
+	cmdRoute.Args = cobra.MinimumNArgs(5)
+	cmdRoute.RunE = c.RunRoute
+	cmd.AddCommand(cmdRoute)
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, args []string) { cmd.Usage() }
@@ -219,3 +226,35 @@ func (c *cmdForknet) RunDetach(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func (c *cmdForknet) RunRoute(cmd *cobra.Command, args []string) error {
+	ifName := args[2]
+	gateway := args[3]
+
+	var family string
+	switch args[1] {
+	case "4":
+		family = ip.FamilyV4
+	case "6":
+		family = ip.FamilyV6
+	default:
+		return fmt.Errorf("Invalid family %q", args[1])
+	}
+
+	// Add the routes through the given gateway.
+	for _, route := range args[4:] {
+		r := &ip.Route{
+			DevName: ifName,
+			Route:   route,
+			Via:     gateway,
+			Family:  family,
+		}
+
+		err := r.Add()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
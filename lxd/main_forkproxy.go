@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 	"unsafe"
 
@@ -276,10 +278,13 @@ type cmdForkproxy struct {
 	global *cmdGlobal
 }
 
-// UDP session tracking (map "client tuple" to udp session)
+// UDP session tracking (map "listener address/client address" to udp session)
 var udpSessions = map[string]*udpSession{}
 var udpSessionsLock sync.Mutex
 
+// udpSessionTimeout is the time after which an idle UDP session is closed.
+const udpSessionTimeout = 30 * time.Minute
+
 type udpSession struct {
 	client    net.Addr
 	target    net.Conn
@@ -290,7 +295,7 @@ type udpSession struct {
 func (c *cmdForkproxy) Command() *cobra.Command {
 	// Main subcommand
 	cmd := &cobra.Command{}
-	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect address> <log path> <pid path> <listen gid> <listen uid> <listen mode> <security gid> <security uid>"
+	cmd.Use = "forkproxy <listen PID> <listen PidFd> <listen address> <connect PID> <connect PidFd> <connect address> <log path> <pid path> <listen gid> <listen uid> <listen mode> <security gid> <security uid> <proxy protocol> <listen interface>"
 	cmd.Short = "Setup network connection proxying"
 	cmd.Long = `Description:
   Setup network connection proxying
@@ -299,7 +304,7 @@ func (c *cmdForkproxy) Command() *cobra.Command {
   container, connecting one side to the host and the other to the
   container.
 `
-	cmd.Args = cobra.ExactArgs(12)
+	cmd.Args = cobra.ExactArgs(13)
 	cmd.RunE = c.Run
 	cmd.Hidden = true
 
@@ -316,7 +321,7 @@ func rearmUDPFd(epFd C.int, connFd C.int) {
 	}
 }
 
-func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *deviceConfig.ProxyAddress, connFd C.int, lStruct *lStruct, proxy bool) error {
+func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *deviceConfig.ProxyAddress, connFd C.int, lStruct *lStruct, proxy string) error {
 	if lAddr.ConnType == "udp" {
 		// This only handles udp <-> udp. The C constructor will have
 		// verified this before.
@@ -370,7 +375,9 @@ func listenerInstance(epFd C.int, lAddr *deviceConfig.ProxyAddress, cAddr *devic
 		return err
 	}
 
-	if proxy && cAddr.ConnType == "tcp" {
+	if proxy == "v2" && cAddr.ConnType == "tcp" {
+		dstConn.Write(device.ProxyProtocolV2Header(srcConn.RemoteAddr(), srcConn.LocalAddr()))
+	} else if proxy == "v1" && cAddr.ConnType == "tcp" {
 		if lAddr.ConnType == "unix" {
 			dstConn.Write([]byte(fmt.Sprintf("PROXY UNKNOWN\r\n")))
 		} else {
@@ -420,7 +427,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Quick checks.
-	if len(args) != 12 {
+	if len(args) != 13 {
 		cmd.Help()
 
 		if len(args) == 0 {
@@ -469,7 +476,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 		}
 
 		for _, addr := range lAddr.Addr {
-			file, err := getListenerFile(lAddr.ConnType, addr)
+			file, err := getListenerFile(lAddr.ConnType, addr, args[12])
 			if err != nil {
 				return err
 			}
@@ -671,7 +678,7 @@ func (c *cmdForkproxy) Run(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			err := listenerInstance(epFd, lAddr, cAddr, curFd, srcConn, args[11] == "true")
+			err := listenerInstance(epFd, lAddr, cAddr, curFd, srcConn, args[11])
 			if err != nil {
 				fmt.Printf("Warning: Failed to prepare new listener instance: %s\n", err)
 			}
@@ -687,7 +694,6 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 
 	// Attempt casting to UDP connections
 	srcUdp, srcIsUdp := src.(*net.UDPConn)
-	dstUdp, dstIsUdp := dst.(*net.UDPConn)
 
 	buf := make([]byte, 32*1024)
 	for {
@@ -699,9 +705,12 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 			var addr net.Addr
 			nr, addr, er = srcUdp.ReadFrom(buf)
 			if er == nil {
-				// Look for existing UDP session
+				// Look for existing UDP session. Sessions are tracked per listener so that a client
+				// talking to several ports of a range gets a separate session for each of them.
+				key := fmt.Sprintf("%s/%s", srcUdp.LocalAddr(), addr)
+
 				udpSessionsLock.Lock()
-				us, ok := udpSessions[addr.String()]
+				us, ok := udpSessions[key]
 				udpSessionsLock.Unlock()
 
 				if !ok {
@@ -715,26 +724,24 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 						target: dc,
 					}
 
-					udpSessionsLock.Lock()
-					udpSessions[addr.String()] = us
-					udpSessionsLock.Unlock()
-
-					go proxyCopy(src, dc)
-					us.timer = time.AfterFunc(30*time.Minute, func() {
+					us.timer = time.AfterFunc(udpSessionTimeout, func() {
 						us.target.Close()
 
 						udpSessionsLock.Lock()
-						delete(udpSessions, addr.String())
+						delete(udpSessions, key)
 						udpSessionsLock.Unlock()
 					})
+
+					udpSessionsLock.Lock()
+					udpSessions[key] = us
+					udpSessionsLock.Unlock()
+
+					go udpSessionReturn(srcUdp, us)
 				}
 
-				us.timerLock.Lock()
-				us.timer.Reset(30 * time.Minute)
-				us.timerLock.Unlock()
+				us.resetTimer()
 
 				dst = us.target
-				dstUdp, dstIsUdp = dst.(*net.UDPConn)
 			}
 		} else {
 			nr, er = src.Read(buf)
@@ -751,30 +758,7 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 			var nw int
 			var ew error
 
-			if dstIsUdp && dstUdp.RemoteAddr() == nil {
-				var us *udpSession
-
-				udpSessionsLock.Lock()
-				for _, v := range udpSessions {
-					if v.target.LocalAddr() == src.LocalAddr() {
-						us = v
-						break
-					}
-				}
-				udpSessionsLock.Unlock()
-
-				if us == nil {
-					return fmt.Errorf("Connection expired")
-				}
-
-				us.timerLock.Lock()
-				us.timer.Reset(30 * time.Minute)
-				us.timerLock.Unlock()
-
-				nw, ew = dstUdp.WriteTo(buf[0:nr], us.client)
-			} else {
-				nw, ew = dst.Write(buf[0:nr])
-			}
+			nw, ew = dst.Write(buf[0:nr])
 
 			// keep retrying on EAGAIN
 			errno, ok := shared.GetErrno(ew)
@@ -803,6 +787,45 @@ func proxyCopy(dst net.Conn, src net.Conn) error {
 	return err
 }
 
+// resetTimer postpones the expiry of the UDP session.
+func (us *udpSession) resetTimer() {
+	us.timerLock.Lock()
+	us.timer.Reset(udpSessionTimeout)
+	us.timerLock.Unlock()
+}
+
+// udpSessionReturn relays the replies of the target of a UDP session back to its client through the listener it
+// came in from, until the session expires.
+func udpSessionReturn(listener *net.UDPConn, us *udpSession) {
+	buf := make([]byte, 32*1024)
+	for {
+		nr, err := us.target.Read(buf)
+		if err != nil {
+			errno, ok := shared.GetErrno(err)
+			if ok && errno == unix.EAGAIN {
+				continue
+			}
+
+			return
+		}
+
+		us.resetTimer()
+
+	wAgain:
+		_, err = listener.WriteTo(buf[0:nr], us.client)
+		if err != nil {
+			errno, ok := shared.GetErrno(err)
+			if ok && errno == unix.EAGAIN {
+				goto wAgain
+			}
+
+			if daemon.Debug {
+				fmt.Printf("Warning: Failed to send reply to %s: %v\n", us.client, err)
+			}
+		}
+	}
+}
+
 func genericRelay(dst net.Conn, src net.Conn, timeout bool) {
 	relayer := func(src net.Conn, dst net.Conn, ch chan error) {
 		ch <- proxyCopy(src, dst)
@@ -932,12 +955,38 @@ func unixRelay(dst io.ReadWriteCloser, src io.ReadWriteCloser) {
 	<-chRecv
 }
 
-func tryListen(protocol string, addr string) (net.Listener, error) {
+// listenConfig returns the listener configuration binding the socket to the given interface if any.
+func listenConfig(iface string) *net.ListenConfig {
+	lc := &net.ListenConfig{}
+	if iface == "" {
+		return lc
+	}
+
+	lc.Control = func(network string, address string, c syscall.RawConn) error {
+		var err error
+		errControl := c.Control(func(fd uintptr) {
+			err = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface)
+		})
+		if errControl != nil {
+			return errControl
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to bind to interface %q: %v", iface, err)
+		}
+
+		return nil
+	}
+
+	return lc
+}
+
+func tryListen(protocol string, addr string, iface string) (net.Listener, error) {
 	var listener net.Listener
 	var err error
 
 	for i := 0; i < 10; i++ {
-		listener, err = net.Listen(protocol, addr)
+		listener, err = listenConfig(iface).Listen(context.Background(), protocol, addr)
 		if err == nil {
 			break
 		}
@@ -952,7 +1001,7 @@ func tryListen(protocol string, addr string) (net.Listener, error) {
 	return listener, nil
 }
 
-func tryListenUDP(protocol string, addr string) (*os.File, error) {
+func tryListenUDP(protocol string, addr string, iface string) (*os.File, error) {
 	var UDPConn *net.UDPConn
 	var err error
 
@@ -962,8 +1011,10 @@ func tryListenUDP(protocol string, addr string) (*os.File, error) {
 	}
 
 	for i := 0; i < 10; i++ {
-		UDPConn, err = net.ListenUDP(protocol, udpAddr)
+		var conn net.PacketConn
+		conn, err = listenConfig(iface).ListenPacket(context.Background(), protocol, udpAddr.String())
 		if err == nil {
+			UDPConn = conn.(*net.UDPConn)
 			file, err := UDPConn.File()
 			UDPConn.Close()
 			return file, err
@@ -984,12 +1035,12 @@ func tryListenUDP(protocol string, addr string) (*os.File, error) {
 	return file, err
 }
 
-func getListenerFile(protocol string, addr string) (*os.File, error) {
+func getListenerFile(protocol string, addr string, iface string) (*os.File, error) {
 	if protocol == "udp" {
-		return tryListenUDP("udp", addr)
+		return tryListenUDP("udp", addr, iface)
 	}
 
-	listener, err := tryListen(protocol, addr)
+	listener, err := tryListen(protocol, addr, iface)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to listen on %s: %v", addr, err)
 	}
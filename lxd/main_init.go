@@ -39,6 +39,15 @@ type cmdInit struct {
 	flagStorageLoopSize int
 	flagStoragePool     string
 	flagTrustPassword   string
+
+	flagClusterAddress  string
+	flagClusterName     string
+	flagClusterPassword string
+	flagClusterTokenOut []string
+
+	// Subnets retrieved from the MAAS server during interactive configuration.
+	maasSubnetsV4 []string
+	maasSubnetsV6 []string
 }
 
 func (c *cmdInit) Command() *cobra.Command {
@@ -52,6 +61,8 @@ func (c *cmdInit) Command() *cobra.Command {
   init --auto [--network-address=IP] [--network-port=8443] [--storage-backend=dir]
               [--storage-create-device=DEVICE] [--storage-create-loop=SIZE]
               [--storage-pool=POOL] [--trust-password=PASSWORD]
+              [--cluster-address=IP] [--cluster-name=NAME]
+              [--cluster-password=PASSWORD] [--cluster-token-out=NAME:PATH]
   init --dump
 `
 	cmd.RunE = c.Run
@@ -66,6 +77,10 @@ func (c *cmdInit) Command() *cobra.Command {
 	cmd.Flags().IntVar(&c.flagStorageLoopSize, "storage-create-loop", -1, "Setup loop based storage with SIZE in GB"+"``")
 	cmd.Flags().StringVar(&c.flagStoragePool, "storage-pool", "", "Storage pool to use or create"+"``")
 	cmd.Flags().StringVar(&c.flagTrustPassword, "trust-password", "", "Password required to add new clients"+"``")
+	cmd.Flags().StringVar(&c.flagClusterAddress, "cluster-address", "", "Address to use for clustering, bootstraps a new cluster (default: none)"+"``")
+	cmd.Flags().StringVar(&c.flagClusterName, "cluster-name", "", "Name of this server in the new cluster (default: hostname)"+"``")
+	cmd.Flags().StringVar(&c.flagClusterPassword, "cluster-password", "", "Password required to join new members to the cluster"+"``")
+	cmd.Flags().StringArrayVar(&c.flagClusterTokenOut, "cluster-token-out", nil, "Write a join token for the member NAME to PATH (NAME:PATH, can be repeated)"+"``")
 
 	return cmd
 }
@@ -79,14 +94,15 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 	if !c.flagAuto && (c.flagNetworkAddress != "" || c.flagNetworkPort != -1 ||
 		c.flagStorageBackend != "" || c.flagStorageDevice != "" ||
 		c.flagStorageLoopSize != -1 || c.flagStoragePool != "" ||
-		c.flagTrustPassword != "") {
+		c.flagTrustPassword != "" || c.clusterFlagsSet()) {
 		return fmt.Errorf("Configuration flags require --auto")
 	}
 
 	if c.flagDump && (c.flagAuto || c.flagPreseed || c.flagNetworkAddress != "" ||
 		c.flagNetworkPort != -1 || c.flagStorageBackend != "" ||
 		c.flagStorageDevice != "" || c.flagStorageLoopSize != -1 ||
-		c.flagStoragePool != "" || c.flagTrustPassword != "") {
+		c.flagStoragePool != "" || c.flagTrustPassword != "" ||
+		c.clusterFlagsSet()) {
 		return fmt.Errorf("Can't use --dump with other flags")
 	}
 
@@ -188,9 +204,23 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	revert.Success()
+
+	// Generate the join tokens requested through --cluster-token-out.
+	if len(c.flagClusterTokenOut) > 0 {
+		err = c.writeClusterJoinTokens(d)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// clusterFlagsSet returns whether any of the clustering flags was passed.
+func (c *cmdInit) clusterFlagsSet() bool {
+	return c.flagClusterAddress != "" || c.flagClusterName != "" || c.flagClusterPassword != "" || len(c.flagClusterTokenOut) > 0
+}
+
 func (c *cmdInit) availableStorageDrivers(supportedDrivers []api.ServerStorageDriverInfo, poolType poolType) []string {
 	backingFs, err := filesystem.Detect(shared.VarPath())
 	if err != nil {
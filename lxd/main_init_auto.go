@@ -2,6 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -44,6 +48,33 @@ func (c *cmdInit) RunAuto(cmd *cobra.Command, args []string, d lxd.InstanceServe
 		}
 	}
 
+	if c.flagClusterAddress == "" {
+		if c.flagClusterName != "" || c.flagClusterPassword != "" || len(c.flagClusterTokenOut) > 0 {
+			return nil, fmt.Errorf("None of --cluster-name, --cluster-password or --cluster-token-out may be used without --cluster-address")
+		}
+	} else {
+		if server.Environment.ServerClustered {
+			return nil, fmt.Errorf("This server is already clustered")
+		}
+
+		if c.flagClusterPassword != "" && c.flagTrustPassword != "" {
+			return nil, fmt.Errorf("Only one of --trust-password or --cluster-password can be specified")
+		}
+
+		host, _, _ := net.SplitHostPort(util.CanonicalNetworkAddress(c.flagClusterAddress))
+		ip := net.ParseIP(host)
+		if host == "" || (ip != nil && ip.IsUnspecified()) {
+			return nil, fmt.Errorf("Invalid --cluster-address, a specific IP address or DNS name is required")
+		}
+
+		for _, entry := range c.flagClusterTokenOut {
+			fields := strings.SplitN(entry, ":", 2)
+			if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+				return nil, fmt.Errorf("Invalid --cluster-token-out value %q, expected NAME:PATH", entry)
+			}
+		}
+	}
+
 	storagePools, err := d.GetStoragePoolNames()
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to retrieve list of storage pools")
@@ -75,6 +106,32 @@ func (c *cmdInit) RunAuto(cmd *cobra.Command, args []string, d lxd.InstanceServe
 		}
 	}
 
+	// Clustering
+	var clusterConfig *initDataCluster
+	if c.flagClusterAddress != "" {
+		clusterAddress := util.CanonicalNetworkAddress(c.flagClusterAddress)
+		config.Config["cluster.https_address"] = clusterAddress
+
+		// The API must be reachable for other members to join.
+		if config.Config["core.https_address"] == nil {
+			config.Config["core.https_address"] = clusterAddress
+		}
+
+		if c.flagClusterPassword != "" {
+			config.Config["core.trust_password"] = c.flagClusterPassword
+		}
+
+		clusterConfig = &initDataCluster{}
+		clusterConfig.Enabled = true
+		clusterConfig.ServerName = c.flagClusterName
+		if clusterConfig.ServerName == "" {
+			clusterConfig.ServerName, err = os.Hostname()
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to get hostname")
+			}
+		}
+	}
+
 	// Storage configuration
 	if len(storagePools) == 0 {
 		// Storage pool
@@ -100,14 +157,16 @@ func (c *cmdInit) RunAuto(cmd *cobra.Command, args []string, d lxd.InstanceServe
 		config.StoragePools = []api.StoragePoolsPost{pool}
 
 		// Profile entry
-		config.Profiles = []api.ProfilesPost{{
-			Name: "default",
-			ProfilePut: api.ProfilePut{
-				Devices: map[string]map[string]string{
-					"root": {
-						"type": "disk",
-						"path": "/",
-						"pool": pool.Name,
+		config.Profiles = []initDataProfile{{
+			ProfilesPost: api.ProfilesPost{
+				Name: "default",
+				ProfilePut: api.ProfilePut{
+					Devices: map[string]map[string]string{
+						"root": {
+							"type": "disk",
+							"path": "/",
+							"pool": pool.Name,
+						},
 					},
 				},
 			},
@@ -161,14 +220,16 @@ func (c *cmdInit) RunAuto(cmd *cobra.Command, args []string, d lxd.InstanceServe
 
 		// Add it to the profile
 		if config.Profiles == nil {
-			config.Profiles = []api.ProfilesPost{{
-				Name: "default",
-				ProfilePut: api.ProfilePut{
-					Devices: map[string]map[string]string{
-						"eth0": {
-							"type":    "nic",
-							"network": network.Name,
-							"name":    "eth0",
+			config.Profiles = []initDataProfile{{
+				ProfilesPost: api.ProfilesPost{
+					Name: "default",
+					ProfilePut: api.ProfilePut{
+						Devices: map[string]map[string]string{
+							"eth0": {
+								"type":    "nic",
+								"network": network.Name,
+								"name":    "eth0",
+							},
 						},
 					},
 				},
@@ -182,5 +243,32 @@ func (c *cmdInit) RunAuto(cmd *cobra.Command, args []string, d lxd.InstanceServe
 		}
 	}
 
-	return &cmdInitData{Node: config}, nil
+	return &cmdInitData{Node: config, Cluster: clusterConfig}, nil
+}
+
+// writeClusterJoinTokens generates the join tokens requested through --cluster-token-out and writes each of
+// them to its file.
+func (c *cmdInit) writeClusterJoinTokens(d lxd.InstanceServer) error {
+	for _, entry := range c.flagClusterTokenOut {
+		fields := strings.SplitN(entry, ":", 2)
+		name, path := fields[0], fields[1]
+
+		op, err := d.CreateClusterMember(api.ClusterMembersPost{ServerName: name})
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create join token for member %q", name)
+		}
+
+		opAPI := op.Get()
+		joinToken, err := opAPI.ToClusterJoinToken()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create join token for member %q", name)
+		}
+
+		err = ioutil.WriteFile(path, []byte(joinToken.String()+"\n"), 0600)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write join token for member %q", name)
+		}
+	}
+
+	return nil
 }
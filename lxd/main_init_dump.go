@@ -8,44 +8,97 @@ import (
 
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
 func (c *cmdInit) RunDump(d lxd.InstanceServer) error {
-	currentServer, _, err := d.GetServer()
+	config, err := initDataNodeDump(d)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(config)
 	if err != nil {
 		return errors.Wrap(err, "Failed to retrieve current server configuration")
 	}
 
+	fmt.Printf("%s\n", out)
+
+	return nil
+}
+
+// initDataNodeDump returns the current configuration of the server, projects, network ACLs, networks, storage
+// pools and profiles.
+func initDataNodeDump(d lxd.InstanceServer) (*initDataNode, error) {
+	currentServer, _, err := d.GetServer()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve current server configuration")
+	}
+
 	var config initDataNode
 	config.Config = currentServer.Config
 
-	// Only retrieve networks in the default project as the preseed format doesn't support creating
-	// projects at this time.
-	networks, err := d.UseProject(project.Default).GetNetworks()
+	projects, err := d.GetProjects()
 	if err != nil {
-		return errors.Wrapf(err, "Failed to retrieve current server network configuration for project %q", project.Default)
+		return nil, errors.Wrap(err, "Failed to retrieve current server configuration")
 	}
 
-	for _, network := range networks {
-		// Only list managed networks.
-		if !network.Managed {
+	for _, p := range projects {
+		projectsPost := api.ProjectsPost{}
+		projectsPost.Config = p.Config
+		projectsPost.Description = p.Description
+		projectsPost.Name = p.Name
+
+		config.Projects = append(config.Projects, projectsPost)
+	}
+
+	// Only retrieve networks and network ACLs from projects which have their own, as the others share the ones
+	// of the default project.
+	for _, p := range projects {
+		if p.Name != project.Default && !shared.IsTrue(p.Config["features.networks"]) {
 			continue
 		}
 
-		networksPost := internalClusterPostNetwork{}
-		networksPost.Config = network.Config
-		networksPost.Description = network.Description
-		networksPost.Name = network.Name
-		networksPost.Type = network.Type
-		networksPost.Project = project.Default
+		networks, err := d.UseProject(p.Name).GetNetworks()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current server network configuration for project %q", p.Name)
+		}
+
+		for _, network := range networks {
+			// Only list managed networks.
+			if !network.Managed {
+				continue
+			}
+
+			networksPost := internalClusterPostNetwork{}
+			networksPost.Config = network.Config
+			networksPost.Description = network.Description
+			networksPost.Name = network.Name
+			networksPost.Type = network.Type
+			networksPost.Project = p.Name
+
+			config.Networks = append(config.Networks, networksPost)
+		}
+
+		acls, err := d.UseProject(p.Name).GetNetworkACLs()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current server network ACL configuration for project %q", p.Name)
+		}
 
-		config.Networks = append(config.Networks, networksPost)
+		for _, acl := range acls {
+			aclsPost := initDataNetworkACL{}
+			aclsPost.Name = acl.Name
+			aclsPost.NetworkACLPut = acl.Writable()
+			aclsPost.Project = p.Name
+
+			config.NetworkACLs = append(config.NetworkACLs, aclsPost)
+		}
 	}
 
 	storagePools, err := d.GetStoragePools()
 	if err != nil {
-		return errors.Wrap(err, "Failed to retrieve current server configuration")
+		return nil, errors.Wrap(err, "Failed to retrieve current server configuration")
 	}
 
 	for _, storagePool := range storagePools {
@@ -58,41 +111,32 @@ func (c *cmdInit) RunDump(d lxd.InstanceServer) error {
 		config.StoragePools = append(config.StoragePools, storagePoolsPost)
 	}
 
-	profiles, err := d.GetProfiles()
-	if err != nil {
-		return errors.Wrap(err, "Failed to retrieve current server configuration")
-	}
-
-	for _, profile := range profiles {
-		profilesPost := api.ProfilesPost{}
-		profilesPost.Config = profile.Config
-		profilesPost.Description = profile.Description
-		profilesPost.Devices = profile.Devices
-		profilesPost.Name = profile.Name
-
-		config.Profiles = append(config.Profiles, profilesPost)
-	}
+	// Only retrieve profiles from projects which have their own.
+	for _, p := range projects {
+		if p.Name != project.Default && !shared.IsTrue(p.Config["features.profiles"]) {
+			continue
+		}
 
-	projects, err := d.GetProjects()
-	if err != nil {
-		return errors.Wrap(err, "Failed to retrieve current server configuration")
-	}
+		profiles, err := d.UseProject(p.Name).GetProfiles()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve current server profile configuration for project %q", p.Name)
+		}
 
-	for _, project := range projects {
-		projectsPost := api.ProjectsPost{}
-		projectsPost.Config = project.Config
-		projectsPost.Description = project.Description
-		projectsPost.Name = project.Name
+		for _, profile := range profiles {
+			profilesPost := initDataProfile{}
+			profilesPost.Config = profile.Config
+			profilesPost.Description = profile.Description
+			profilesPost.Devices = profile.Devices
+			profilesPost.Name = profile.Name
 
-		config.Projects = append(config.Projects, projectsPost)
-	}
+			// Keep the profiles of the default project compatible with older preseed files.
+			if p.Name != project.Default {
+				profilesPost.Project = p.Name
+			}
 
-	out, err := yaml.Marshal(config)
-	if err != nil {
-		return errors.Wrap(err, "Failed to retrieve current server configuration")
+			config.Profiles = append(config.Profiles, profilesPost)
+		}
 	}
 
-	fmt.Printf("%s\n", out)
-
-	return nil
+	return &config, nil
 }
@@ -17,6 +17,7 @@ import (
 
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/maas"
 	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/storage/filesystem"
@@ -35,12 +36,14 @@ func (c *cmdInit) RunInteractive(cmd *cobra.Command, args []string, d lxd.Instan
 	config.Node.Config = map[string]interface{}{}
 	config.Node.Networks = []internalClusterPostNetwork{}
 	config.Node.StoragePools = []api.StoragePoolsPost{}
-	config.Node.Profiles = []api.ProfilesPost{
+	config.Node.Profiles = []initDataProfile{
 		{
-			Name: "default",
-			ProfilePut: api.ProfilePut{
-				Config:  map[string]string{},
-				Devices: map[string]map[string]string{},
+			ProfilesPost: api.ProfilesPost{
+				Name: "default",
+				ProfilePut: api.ProfilePut{
+					Config:  map[string]string{},
+					Devices: map[string]map[string]string{},
+				},
 			},
 		},
 	}
@@ -395,12 +398,12 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer, serve
 }
 
 func (c *cmdInit) askMAAS(config *cmdInitData, d lxd.InstanceServer) error {
-	maas, err := cli.AskBool("Would you like to connect to a MAAS server? (yes/no) [default=no]: ", "no")
+	useMAAS, err := cli.AskBool("Would you like to connect to a MAAS server? (yes/no) [default=no]: ", "no")
 	if err != nil {
 		return err
 	}
 
-	if !maas {
+	if !useMAAS {
 		return nil
 	}
 
@@ -428,9 +431,32 @@ func (c *cmdInit) askMAAS(config *cmdInitData, d lxd.InstanceServer) error {
 		return err
 	}
 
+	// Retrieve the available subnets so they can be offered when configuring the network.
+	c.maasSubnetsV4, c.maasSubnetsV6, err = maas.Subnets(config.Node.Config["maas.api.url"].(string), config.Node.Config["maas.api.key"].(string))
+	if err != nil {
+		fmt.Printf("Unable to retrieve the subnets from the MAAS server: %v\n", err)
+		c.maasSubnetsV4 = nil
+		c.maasSubnetsV6 = nil
+	}
+
 	return nil
 }
 
+func (c *cmdInit) askMAASSubnet(family string, subnets []string) (string, error) {
+	// Fallback to free-form input if the subnets couldn't be retrieved.
+	if subnets == nil {
+		return cli.AskString(fmt.Sprintf("MAAS %s subnet name for this interface (empty for no subnet): ", family), "", validate.Optional())
+	}
+
+	if len(subnets) == 0 {
+		fmt.Printf("No %s subnet is available on the MAAS server.\n", family)
+		return "", nil
+	}
+
+	choices := append([]string{""}, subnets...)
+	return cli.AskChoice(fmt.Sprintf("MAAS %s subnet name for this interface (%s, empty for no subnet): ", family, strings.Join(subnets, ", ")), choices, "")
+}
+
 func (c *cmdInit) askNetworking(config *cmdInitData, d lxd.InstanceServer) error {
 	var err error
 	localBridgeCreate := false
@@ -488,7 +514,7 @@ func (c *cmdInit) askNetworking(config *cmdInitData, d lxd.InstanceServer) error
 					}
 
 					if maasConnect {
-						maasSubnetV4, err := cli.AskString("MAAS IPv4 subnet name for this interface (empty for no subnet): ", "", validate.Optional())
+						maasSubnetV4, err := c.askMAASSubnet("IPv4", c.maasSubnetsV4)
 						if err != nil {
 							return err
 						}
@@ -497,7 +523,7 @@ func (c *cmdInit) askNetworking(config *cmdInitData, d lxd.InstanceServer) error
 							config.Node.Profiles[0].Devices["eth0"]["maas.subnet.ipv4"] = maasSubnetV4
 						}
 
-						maasSubnetV6, err := cli.AskString("MAAS IPv6 subnet name for this interface (empty for no subnet): ", "", validate.Optional())
+						maasSubnetV6, err := c.askMAASSubnet("IPv6", c.maasSubnetsV6)
 						if err != nil {
 							return err
 						}
@@ -684,16 +710,66 @@ func (c *cmdInit) askStorage(config *cmdInitData, d lxd.InstanceServer, server *
 		return nil
 	}
 
-	storagePool, err := cli.AskBool("Do you want to configure a new storage pool? (yes/no) [default=yes]: ", "yes")
+	// Offer to use one of the existing storage pools for the default profile.
+	existingPools, err := d.GetStoragePoolNames()
 	if err != nil {
-		return err
+		return errors.Wrap(err, "Failed to retrieve existing storage pools")
 	}
 
-	if !storagePool {
-		return nil
+	if len(existingPools) > 0 {
+		useExisting, err := cli.AskBool("Would you like to use an existing storage pool for the default profile? (yes/no) [default=no]: ", "no")
+		if err != nil {
+			return err
+		}
+
+		if useExisting {
+			poolName, err := cli.AskChoice(fmt.Sprintf("Name of the existing storage pool to use (%s) [default=%s]: ", strings.Join(existingPools, ", "), existingPools[0]), existingPools, existingPools[0])
+			if err != nil {
+				return err
+			}
+
+			c.setDefaultRootPool(config, poolName)
+		}
+	}
+
+	question := "Do you want to configure a new storage pool? (yes/no) [default=yes]: "
+	defaultAnswer := "yes"
+	if config.Node.Profiles[0].Devices["root"] != nil {
+		defaultAnswer = "no"
+		question = "Do you want to configure a new storage pool? (yes/no) [default=no]: "
+	}
+
+	for {
+		storagePool, err := cli.AskBool(question, defaultAnswer)
+		if err != nil {
+			return err
+		}
+
+		if !storagePool {
+			return nil
+		}
+
+		err = c.askStoragePool(config, d, server, poolTypeAny)
+		if err != nil {
+			return err
+		}
+
+		question = "Would you like to configure another storage pool? (yes/no) [default=no]: "
+		defaultAnswer = "no"
 	}
+}
 
-	return c.askStoragePool(config, d, server, poolTypeAny)
+// setDefaultRootPool sets the pool of the default profile's root disk, unless one was already picked.
+func (c *cmdInit) setDefaultRootPool(config *cmdInitData, poolName string) {
+	if config.Node.Profiles[0].Devices["root"] != nil {
+		return
+	}
+
+	config.Node.Profiles[0].Devices["root"] = map[string]string{
+		"type": "disk",
+		"path": "/",
+		"pool": poolName,
+	}
 }
 
 func (c *cmdInit) askStoragePool(config *cmdInitData, d lxd.InstanceServer, server *api.Server, poolType poolType) error {
@@ -736,8 +812,39 @@ func (c *cmdInit) askStoragePool(config *cmdInitData, d lxd.InstanceServer, serv
 			pool.Name = string(poolType)
 		}
 
+		// Check for pools already defined earlier on.
+		definedPool := false
+		for _, definedPoolConfig := range config.Node.StoragePools {
+			if definedPoolConfig.Name == pool.Name {
+				definedPool = true
+				break
+			}
+		}
+
+		if definedPool {
+			if poolType == poolTypeAny {
+				fmt.Printf("The storage pool \"%s\" was already configured. Please choose another name.\n", pool.Name)
+				continue
+			}
+
+			return fmt.Errorf("The %s storage pool was already configured", poolType)
+		}
+
 		_, _, err := d.GetStoragePool(pool.Name)
 		if err == nil {
+			useExisting := false
+			if config.Node.Profiles[0].Devices["root"] == nil {
+				useExisting, err = cli.AskBool(fmt.Sprintf("The storage pool \"%s\" already exists. Would you like to use it for the default profile? (yes/no) [default=yes]: ", pool.Name), "yes")
+				if err != nil {
+					return err
+				}
+			}
+
+			if useExisting {
+				c.setDefaultRootPool(config, pool.Name)
+				return nil
+			}
+
 			if poolType == poolTypeAny {
 				fmt.Printf("The requested storage pool \"%s\" already exists. Please choose another name.\n", pool.Name)
 				continue
@@ -747,13 +854,7 @@ func (c *cmdInit) askStoragePool(config *cmdInitData, d lxd.InstanceServer, serv
 		}
 
 		// Add to the default profile
-		if config.Node.Profiles[0].Devices["root"] == nil {
-			config.Node.Profiles[0].Devices["root"] = map[string]string{
-				"type": "disk",
-				"path": "/",
-				"pool": pool.Name,
-			}
-		}
+		c.setDefaultRootPool(config, pool.Name)
 
 		// Storage backend
 		if len(availableBackends) > 1 {
@@ -15,6 +15,8 @@ import (
 
 type cmdRecover struct {
 	global *cmdGlobal
+
+	flagDryRun bool
 }
 
 func (c *cmdRecover) Command() *cobra.Command {
@@ -27,8 +29,12 @@ func (c *cmdRecover) Command() *cobra.Command {
   This command is mostly used for disaster recovery. It will ask you about unknown storage pools and attempt to
   access them, along with existing storage pools, and identify any missing instances and volumes that exist on the
   pools but are not in the LXD database. It will then offer to recreate these database records.
+
+  Missing profiles are reconstructed from the configuration stored alongside the instances and their snapshots,
+  missing networks are reported and must be created manually. With --dry-run, only the report is shown.
 `
 	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, "Only report what would be recovered")
 
 	return cmd
 }
@@ -200,14 +206,34 @@ func (c *cmdRecover) Run(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		if len(res.RecoverDependencies) > 0 {
+			fmt.Print("The following missing profiles will be recreated:\n")
+			for _, dep := range res.RecoverDependencies {
+				fmt.Printf(" - %s %q in project %q (from %s)\n", strings.Title(dep.Type), dep.Name, dep.Project, dep.Source)
+			}
+		}
+
+		if len(res.Conflicts) > 0 {
+			fmt.Print("The following conflicts have been found:\n")
+			for _, conflict := range res.Conflicts {
+				fmt.Printf(" - %s\n", conflict)
+			}
+		}
+
 		if len(res.DependencyErrors) > 0 {
 			fmt.Print("You are currently missing the following:\n")
 
 			for _, depErr := range res.DependencyErrors {
 				fmt.Printf(" - %s\n", depErr)
 			}
+		}
+
+		if c.flagDryRun {
+			return nil
+		}
 
-			cli.AskString("Please create those missing entries and then hit ENTER: ", "", validate.Optional())
+		if len(res.DependencyErrors) > 0 || len(res.Conflicts) > 0 {
+			cli.AskString("Please resolve the above and then hit ENTER: ", "", validate.Optional())
 		} else {
 			if len(res.UnknownVolumes) <= 0 {
 				fmt.Print("No unknown volumes found. Nothing to do.\n")
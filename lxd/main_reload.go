@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+)
+
+type cmdReload struct {
+	global *cmdGlobal
+
+	flagTimeout int
+}
+
+func (c *cmdReload) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "reload"
+	cmd.Short = "Tell LXD to replace itself with the current binary"
+	cmd.Long = `Description:
+  Tell LXD to replace itself with the current binary
+
+  This will tell LXD to wait for its running operations to finish and to
+  then re-execute itself in place, handing its API sockets and outstanding
+  tokens over to the new process. Instances keep running throughout and
+  requests made in the meantime are served by the new process.
+
+  This is typically used to apply an upgrade of the LXD binary without
+  the socket downtime of a full restart.
+`
+	cmd.RunE = c.Run
+	cmd.Flags().IntVarP(&c.flagTimeout, "timeout", "t", 0, "Number of seconds to wait before giving up"+"``")
+
+	return cmd
+}
+
+func (c *cmdReload) Run(cmd *cobra.Command, args []string) error {
+	connArgs := &lxd.ConnectionArgs{
+		SkipGetServer: true,
+	}
+
+	d, err := lxd.ConnectLXDUnix("", connArgs)
+	if err != nil {
+		return err
+	}
+
+	// Watch for the current process to go away before requesting the reload.
+	chMonitor := make(chan bool, 1)
+	monitor, err := d.GetEvents()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		monitor.Wait()
+		close(chMonitor)
+	}()
+
+	_, _, err = d.RawQuery("PUT", "/internal/reload", nil, "")
+	if err != nil && !strings.HasSuffix(err.Error(), ": EOF") {
+		monitor.Disconnect()
+		return err
+	}
+
+	start := time.Now()
+	if c.flagTimeout > 0 {
+		select {
+		case <-chMonitor:
+			break
+		case <-time.After(time.Second * time.Duration(c.flagTimeout)):
+			return fmt.Errorf("LXD still not reloaded after %ds timeout", c.flagTimeout)
+		}
+	} else {
+		<-chMonitor
+	}
+
+	// Then wait for the new process to be ready.
+	waitready := cmdWaitready{global: c.global}
+	if c.flagTimeout > 0 {
+		waitready.flagTimeout = c.flagTimeout - int(time.Since(start).Seconds())
+		if waitready.flagTimeout <= 0 {
+			return fmt.Errorf("LXD still not reloaded after %ds timeout", c.flagTimeout)
+		}
+	}
+
+	return waitready.Run(cmd, nil)
+}
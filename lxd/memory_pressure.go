@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cgroup"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// memoryPressureShrinkStep and memoryPressureGrowStep are the fractions of an instance's memory limit by which its
+// target is lowered under pressure and raised back once the pressure is gone. Memory is given back more slowly
+// than it's taken to avoid oscillating around the threshold.
+const memoryPressureShrinkStep = 0.10
+const memoryPressureGrowStep = 0.05
+
+// memoryPressureDefaultMin is the lower bound of an instance's target when limits.memory.reclaim.min isn't set.
+const memoryPressureDefaultMin = "50%"
+
+// memoryTarget is the range within which the memory pressure policy moves the memory target of an instance.
+type memoryTarget struct {
+	min int64
+	max int64
+}
+
+// memoryPressureTask periodically checks the host memory pressure (PSI) and shrinks the VM balloons and
+// container soft limits of the instances which allow it, growing them back once the pressure is gone.
+// The interval is controlled through scheduler.memory_pressure_interval (0 disables it).
+func memoryPressureTask(d *Daemon) (task.Func, task.Schedule) {
+	targets := map[string]int64{}
+
+	f := func(ctx context.Context) {
+		threshold, err := cluster.MemberConfigGetInt64(d.cluster, "scheduler.memory_pressure_threshold")
+		if err != nil {
+			logger.Error("Failed to load memory pressure threshold", log.Ctx{"err": err})
+			return
+		}
+
+		pressure, err := memoryPressureRead()
+		if err != nil {
+			logger.Error("Failed to read host memory pressure", log.Ctx{"err": err})
+			return
+		}
+
+		// Only act outside of the band between half the threshold and the threshold.
+		var step float64
+		if pressure >= float64(threshold) {
+			step = -memoryPressureShrinkStep
+		} else if pressure < float64(threshold)/2 {
+			step = memoryPressureGrowStep
+		}
+
+		memoryPressureApply(d.State(), targets, step)
+	}
+
+	schedule := func() (time.Duration, error) {
+		interval, err := cluster.MemberConfigGetInt64(d.cluster, "scheduler.memory_pressure_interval")
+		if err != nil {
+			return 0, err
+		}
+
+		if interval <= 0 {
+			return 0, nil
+		}
+
+		return time.Duration(interval) * time.Second, nil
+	}
+
+	return f, schedule
+}
+
+// memoryPressureRead returns the share of time (in percent) over the last 10 seconds during which some tasks of
+// the host were stalled on memory.
+func memoryPressureRead() (float64, error) {
+	content, err := ioutil.ReadFile("/proc/pressure/memory")
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to read /proc/pressure/memory (is PSI enabled?)")
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "some" {
+			continue
+		}
+
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "avg10=") {
+				continue
+			}
+
+			return strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64)
+		}
+	}
+
+	return -1, fmt.Errorf("Failed to find memory pressure average in /proc/pressure/memory")
+}
+
+// memoryPressureApply moves the memory target of the running instances by step (as a fraction of their limit)
+// within their bounds. Instances whose target wasn't seen yet start from their limit, which is then applied to
+// restore a consistent state.
+func memoryPressureApply(s *state.State, targets map[string]int64, step float64) {
+	instances, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		logger.Error("Problem loading instances list", log.Ctx{"err": err})
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, inst := range instances {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", inst.Project(), inst.Name())
+
+		bounds, err := memoryPressureBounds(inst)
+		if err != nil {
+			logger.Warn("Failed to get memory bounds of instance", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		if bounds == nil {
+			continue
+		}
+
+		seen[key] = true
+
+		current, found := targets[key]
+		if !found {
+			current = -1
+		}
+
+		target := memoryPressureNextTarget(current, *bounds, step)
+		if target == current {
+			continue
+		}
+
+		err = memoryPressureSetTarget(s, inst, target)
+		if err != nil {
+			logger.Warn("Failed to set memory target of instance", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "target": target, "err": err})
+			continue
+		}
+
+		if found {
+			logger.Debug("Adjusted memory target of instance", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "old": current, "new": target})
+		}
+
+		targets[key] = target
+	}
+
+	// Forget the instances which stopped or opted out, they're reset when started or updated.
+	for key := range targets {
+		if !seen[key] {
+			delete(targets, key)
+		}
+	}
+}
+
+// memoryPressureNextTarget returns the memory target following current once moved by step, within bounds.
+// A negative current target means that it's unknown and the limit is used.
+func memoryPressureNextTarget(current int64, bounds memoryTarget, step float64) int64 {
+	if current < 0 {
+		return bounds.max
+	}
+
+	target := current + int64(float64(bounds.max)*step)
+	if target < bounds.min {
+		target = bounds.min
+	}
+
+	if target > bounds.max {
+		target = bounds.max
+	}
+
+	return target
+}
+
+// memoryPressureBounds returns the range within which the memory target of an instance can be moved, or nil if
+// the instance isn't managed by the memory pressure policy.
+func memoryPressureBounds(inst instance.Instance) (*memoryTarget, error) {
+	config := inst.ExpandedConfig()
+
+	if config["limits.memory"] == "" || (config["limits.memory.reclaim"] != "" && !shared.IsTrue(config["limits.memory.reclaim"])) {
+		return nil, nil
+	}
+
+	if inst.Type() == instancetype.VM && shared.IsTrue(config["limits.memory.hugepages"]) {
+		return nil, nil
+	}
+
+	limit, err := memoryPressureParseSize(config["limits.memory"], -1)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid limits.memory")
+	}
+
+	// Containers keep the soft limit LXD applies to them by default as their upper bound.
+	max := limit
+	if inst.Type() == instancetype.Container && config["limits.memory.enforce"] != "soft" {
+		max = int64(float64(limit) * 0.9)
+	}
+
+	min := config["limits.memory.reclaim.min"]
+	if min == "" {
+		min = memoryPressureDefaultMin
+	}
+
+	minBytes, err := memoryPressureParseSize(min, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid limits.memory.reclaim.min")
+	}
+
+	if minBytes > max {
+		minBytes = max
+	}
+
+	return &memoryTarget{min: minBytes, max: max}, nil
+}
+
+// memoryPressureParseSize parses a memory size, percentages are relative to base or to the host memory if
+// base is negative.
+func memoryPressureParseSize(value string, base int64) (int64, error) {
+	if !strings.HasSuffix(value, "%") {
+		return units.ParseByteSizeString(value)
+	}
+
+	percent, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	if base < 0 {
+		memoryTotal, err := shared.DeviceTotalMemory()
+		if err != nil {
+			return -1, err
+		}
+
+		base = memoryTotal
+	}
+
+	return (base / 100) * percent, nil
+}
+
+// memoryPressureSetTarget applies the memory target of an instance, through its balloon for VMs and its memory
+// soft limit for containers.
+func memoryPressureSetTarget(s *state.State, inst instance.Instance, target int64) error {
+	switch inst.Type() {
+	case instancetype.VM:
+		vm, ok := inst.(instance.VM)
+		if !ok {
+			return fmt.Errorf("Instance is not a VM")
+		}
+
+		return vm.SetMemoryBalloon(target)
+	case instancetype.Container:
+		cg, err := inst.CGroup()
+		if err != nil {
+			return err
+		}
+
+		if !s.OS.CGInfo.Supports(cgroup.Memory, cg) {
+			return nil
+		}
+
+		return cg.SetMemorySoftLimit(target)
+	}
+
+	return nil
+}
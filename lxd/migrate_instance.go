@@ -778,6 +778,13 @@ func newMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 func (c *migrationSink) Do(state *state.State, revert *revert.Reverter, migrateOp *operations.Operation) error {
 	var err error
 
+	// Wait for our turn if the number of concurrent incoming migrations is limited.
+	releaseQueue, err := operations.QueueAcquire(state.Context, migrateOp, operations.QueueMigrations, operations.QueuePriorityNormal)
+	if err != nil {
+		return errors.Wrapf(err, "Failed waiting for migration slot")
+	}
+	defer releaseQueue()
+
 	if c.push {
 		<-c.allConnected
 	}
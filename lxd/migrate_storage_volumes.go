@@ -5,6 +5,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/migration"
@@ -200,6 +201,13 @@ func newStorageMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 func (c *migrationSink) DoStorage(state *state.State, projectName string, poolName string, req *api.StorageVolumesPost, op *operations.Operation) error {
 	var err error
 
+	// Wait for our turn if the number of concurrent incoming migrations is limited.
+	releaseQueue, err := operations.QueueAcquire(state.Context, op, operations.QueueMigrations, operations.QueuePriorityNormal)
+	if err != nil {
+		return errors.Wrapf(err, "Failed waiting for migration slot")
+	}
+	defer releaseQueue()
+
 	if c.push {
 		<-c.allConnected
 	}
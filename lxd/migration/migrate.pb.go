@@ -393,6 +393,7 @@ type RsyncFeatures struct {
 	Delete               *bool    `protobuf:"varint,2,opt,name=delete" json:"delete,omitempty"`
 	Compress             *bool    `protobuf:"varint,3,opt,name=compress" json:"compress,omitempty"`
 	Bidirectional        *bool    `protobuf:"varint,4,opt,name=bidirectional" json:"bidirectional,omitempty"`
+	BlockDelta           *bool    `protobuf:"varint,5,opt,name=block_delta,json=blockDelta" json:"block_delta,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -451,6 +452,13 @@ func (m *RsyncFeatures) GetBidirectional() bool {
 	return false
 }
 
+func (m *RsyncFeatures) GetBlockDelta() bool {
+	if m != nil && m.BlockDelta != nil {
+		return *m.BlockDelta
+	}
+	return false
+}
+
 type ZfsFeatures struct {
 	Compress             *bool    `protobuf:"varint,1,opt,name=compress" json:"compress,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -493,6 +501,7 @@ func (m *ZfsFeatures) GetCompress() bool {
 type BtrfsFeatures struct {
 	MigrationHeader      *bool    `protobuf:"varint,1,opt,name=migration_header,json=migrationHeader" json:"migration_header,omitempty"`
 	HeaderSubvolumes     *bool    `protobuf:"varint,2,opt,name=header_subvolumes,json=headerSubvolumes" json:"header_subvolumes,omitempty"`
+	CompressedData       *bool    `protobuf:"varint,3,opt,name=compressed_data,json=compressedData" json:"compressed_data,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -537,6 +546,13 @@ func (m *BtrfsFeatures) GetHeaderSubvolumes() bool {
 	return false
 }
 
+func (m *BtrfsFeatures) GetCompressedData() bool {
+	if m != nil && m.CompressedData != nil {
+		return *m.CompressedData
+	}
+	return false
+}
+
 type MigrationHeader struct {
 	Fs                   *MigrationFSType `protobuf:"varint,1,req,name=fs,enum=migration.MigrationFSType" json:"fs,omitempty"`
 	Criu                 *CRIUType        `protobuf:"varint,2,opt,name=criu,enum=migration.CRIUType" json:"criu,omitempty"`
@@ -1004,77 +1020,78 @@ func init() {
 }
 
 var fileDescriptor_fe8772548dc4b615 = []byte{
-	// 1143 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0x5f, 0x6f, 0xdc, 0x44,
-	0x10, 0xe7, 0xfe, 0x24, 0xb9, 0x1b, 0xdf, 0x25, 0xd7, 0x6d, 0x55, 0x59, 0x2d, 0x94, 0xe0, 0x16,
-	0x91, 0x06, 0xa9, 0x2d, 0x57, 0x21, 0xf5, 0x09, 0xa9, 0xb9, 0x10, 0x5a, 0xd1, 0xa6, 0xd1, 0x5e,
-	0x2b, 0x04, 0x2f, 0xd6, 0xc6, 0x1e, 0x5f, 0x56, 0xf5, 0x3f, 0xed, 0xda, 0x69, 0x2e, 0x2f, 0x88,
-	0x0f, 0xc3, 0x47, 0xe2, 0x7b, 0xf0, 0xca, 0x1b, 0xda, 0x59, 0xdb, 0xb1, 0x13, 0x24, 0xde, 0x76,
-	0x7e, 0xf3, 0xf3, 0xcc, 0xec, 0xcc, 0x6f, 0xc7, 0x70, 0x3f, 0xbe, 0x08, 0x9f, 0x26, 0x72, 0xa5,
-	0x44, 0x21, 0xb3, 0xb4, 0x3a, 0xe1, 0x93, 0x5c, 0x65, 0x45, 0xc6, 0xc6, 0x8d, 0xc3, 0xfb, 0x1d,
-	0xc6, 0xaf, 0x0f, 0xdf, 0x8a, 0xfc, 0xfd, 0x3a, 0x47, 0x76, 0x07, 0x36, 0xa4, 0x2e, 0x65, 0xe8,
-	0xf6, 0x76, 0xfb, 0x7b, 0x23, 0x6e, 0x0d, 0x8b, 0xae, 0x64, 0xe8, 0xf6, 0x6b, 0x74, 0x25, 0x43,
-	0x76, 0x17, 0x36, 0xcf, 0x32, 0x5d, 0xc8, 0xd0, 0x1d, 0xec, 0xf6, 0xf7, 0x36, 0x78, 0x65, 0x31,
-	0x06, 0xc3, 0x54, 0xcb, 0xd0, 0x1d, 0x12, 0x4a, 0x67, 0x76, 0x0f, 0x46, 0x89, 0xc8, 0x95, 0x48,
-	0x57, 0xe8, 0x6e, 0x10, 0xde, 0xd8, 0xde, 0x33, 0xd8, 0x5c, 0x64, 0x69, 0x24, 0x57, 0x6c, 0x06,
-	0x83, 0x8f, 0xb8, 0xa6, 0xdc, 0x63, 0x6e, 0x8e, 0x26, 0xf3, 0xb9, 0x88, 0x4b, 0xa4, 0xcc, 0x63,
-	0x6e, 0x0d, 0xef, 0x27, 0xd8, 0x3c, 0xc4, 0x73, 0x19, 0x20, 0xe5, 0x12, 0x09, 0x56, 0x9f, 0xd0,
-	0x99, 0x3d, 0x86, 0xcd, 0x80, 0xe2, 0xb9, 0xfd, 0xdd, 0xc1, 0x9e, 0x33, 0xbf, 0xf5, 0xa4, 0xb9,
-	0xec, 0x13, 0x9b, 0x88, 0x57, 0x04, 0xef, 0xef, 0x3e, 0x8c, 0x96, 0xa9, 0xc8, 0xf5, 0x59, 0x56,
-	0xfc, 0x67, 0xac, 0xe7, 0xe0, 0xc4, 0x59, 0x20, 0xe2, 0xc5, 0xff, 0x04, 0x6c, 0xb3, 0xcc, 0x65,
-	0x73, 0x95, 0x45, 0x32, 0x46, 0xed, 0x0e, 0x76, 0x07, 0x7b, 0x63, 0xde, 0xd8, 0xec, 0x73, 0x18,
-	0x63, 0x7e, 0x86, 0x09, 0x2a, 0x11, 0x53, 0x87, 0x46, 0xfc, 0x0a, 0x60, 0xdf, 0xc3, 0x84, 0x02,
-	0xd9, 0xdb, 0x69, 0x77, 0xe3, 0x46, 0x3e, 0xeb, 0xe1, 0x1d, 0x1a, 0xf3, 0x60, 0x22, 0x54, 0x70,
-	0x26, 0x0b, 0x0c, 0x8a, 0x52, 0xa1, 0xbb, 0x49, 0x1d, 0xee, 0x60, 0xa6, 0x28, 0x5d, 0x88, 0x02,
-	0xa3, 0x32, 0x76, 0xb7, 0x28, 0x6f, 0x63, 0xb3, 0x87, 0x30, 0x0d, 0x14, 0x52, 0x02, 0x3f, 0x14,
-	0x05, 0xba, 0xa3, 0xdd, 0xde, 0xde, 0x80, 0x4f, 0x6a, 0xf0, 0x50, 0x14, 0xc8, 0x1e, 0xc1, 0x76,
-	0x2c, 0x74, 0xe1, 0x97, 0x1a, 0x43, 0xcb, 0x1a, 0x5b, 0x96, 0x41, 0x3f, 0x68, 0x0c, 0x89, 0xf5,
-	0x25, 0x38, 0x78, 0x91, 0x4b, 0xb5, 0xb6, 0x14, 0x20, 0x0a, 0x58, 0xc8, 0x10, 0xbc, 0x3f, 0x7a,
-	0x30, 0x55, 0x7a, 0x9d, 0x06, 0x47, 0x28, 0x4c, 0x61, 0xda, 0xe8, 0xe8, 0x42, 0x14, 0x85, 0xd2,
-	0x6e, 0x6f, 0xb7, 0xb7, 0x37, 0xe2, 0x95, 0x65, 0xf0, 0x10, 0x63, 0x2c, 0xcc, 0xf0, 0x09, 0xb7,
-	0x96, 0xb9, 0x49, 0x90, 0x25, 0xb9, 0x42, 0x6d, 0xda, 0x6b, 0x3c, 0x8d, 0xcd, 0x1e, 0xc1, 0xf4,
-	0x54, 0x86, 0x52, 0x61, 0x60, 0xea, 0xa6, 0x16, 0x1b, 0x42, 0x17, 0xf4, 0x1e, 0x83, 0x73, 0x19,
-	0xe9, 0xa6, 0x80, 0x76, 0xc0, 0x5e, 0x37, 0xa0, 0xb7, 0x82, 0xe9, 0x69, 0xa1, 0x5a, 0xe4, 0xc7,
-	0x30, 0x6b, 0xa6, 0xe1, 0x9f, 0xa1, 0x08, 0x51, 0x55, 0x1f, 0xed, 0x34, 0xf8, 0x2b, 0x82, 0xd9,
-	0xb7, 0x70, 0xcb, 0x12, 0x7c, 0x5d, 0x9e, 0x9e, 0x67, 0x71, 0x99, 0xa0, 0xae, 0xee, 0x32, 0xb3,
-	0x8e, 0x65, 0x83, 0x7b, 0xff, 0x0c, 0x60, 0xe7, 0xed, 0xb5, 0x00, 0xfb, 0xd0, 0x8f, 0x34, 0xe9,
-	0x71, 0x7b, 0x7e, 0xaf, 0x25, 0x82, 0x86, 0x77, 0xb4, 0x34, 0xaf, 0x96, 0xf7, 0x23, 0xcd, 0xbe,
-	0x81, 0x61, 0xa0, 0x64, 0x49, 0xf1, 0xb7, 0xe7, 0xb7, 0xdb, 0x12, 0xe5, 0xaf, 0x3f, 0x10, 0x8d,
-	0x08, 0x6c, 0x1f, 0x36, 0x64, 0x98, 0x88, 0x9c, 0xa4, 0xe9, 0xcc, 0xef, 0xb4, 0x98, 0xcd, 0x1e,
-	0xe0, 0x96, 0x62, 0xda, 0xa9, 0xab, 0xe7, 0x71, 0x2c, 0x4c, 0xf5, 0x43, 0x92, 0x73, 0x17, 0x64,
-	0xdf, 0xc1, 0xb8, 0x06, 0x6a, 0xc9, 0xb6, 0xf3, 0xd7, 0x0f, 0x8c, 0x5f, 0xb1, 0x98, 0x0b, 0x5b,
-	0xb9, 0xc2, 0xb0, 0x4c, 0x72, 0x77, 0x8b, 0x1a, 0x52, 0x9b, 0xec, 0x87, 0x6b, 0xf2, 0x20, 0x2d,
-	0x3a, 0x73, 0xb7, 0x15, 0xb0, 0xe3, 0xe7, 0xd7, 0xd4, 0xe4, 0xc2, 0x96, 0xc2, 0x48, 0xa1, 0x3e,
-	0x23, 0x7d, 0x8e, 0x78, 0x6d, 0xb2, 0x17, 0x9d, 0xa9, 0x93, 0x34, 0x9d, 0xf9, 0xdd, 0x56, 0xdc,
-	0x96, 0x97, 0x77, 0x04, 0xf2, 0x00, 0xc0, 0x8e, 0x69, 0x29, 0x2f, 0xd1, 0x75, 0xac, 0xa6, 0xaf,
-	0x10, 0x53, 0x73, 0x47, 0x24, 0xee, 0xe4, 0x46, 0xcd, 0x1d, 0x3f, 0xef, 0xd2, 0xbd, 0x23, 0x98,
-	0x35, 0x23, 0x5d, 0x64, 0x69, 0xa1, 0xb2, 0xd8, 0xdc, 0x43, 0x97, 0x41, 0x60, 0x35, 0x69, 0x9e,
-	0x6b, 0x6d, 0x1a, 0x4f, 0x82, 0x5a, 0x8b, 0x95, 0x7d, 0x18, 0x63, 0x5e, 0x9b, 0xde, 0x73, 0x98,
-	0x36, 0x71, 0x96, 0xeb, 0x34, 0x30, 0x8b, 0x21, 0x92, 0xa9, 0x88, 0x4f, 0x14, 0x1e, 0x9a, 0x5e,
-	0xdb, 0x48, 0x1d, 0xcc, 0xfb, 0x73, 0x00, 0x33, 0xd3, 0x79, 0xdf, 0xac, 0x03, 0xed, 0x63, 0x5a,
-	0xa8, 0xb5, 0xd9, 0x08, 0x91, 0x42, 0xbc, 0x94, 0xe9, 0xca, 0x2f, 0x64, 0xb5, 0x14, 0xa7, 0x7c,
-	0x52, 0x83, 0xef, 0x65, 0x42, 0x6f, 0x3d, 0x52, 0xd9, 0x25, 0xa6, 0x96, 0xd2, 0x27, 0x0a, 0x58,
-	0x88, 0x08, 0x5f, 0xc1, 0x24, 0xc1, 0x84, 0x82, 0x13, 0x63, 0x40, 0x0c, 0xa7, 0xc2, 0x88, 0xf2,
-	0x10, 0xa6, 0x09, 0x26, 0x9f, 0x94, 0x2c, 0xd0, 0x72, 0x86, 0x36, 0x51, 0x0d, 0xd6, 0xa4, 0x5c,
-	0xac, 0x50, 0xfb, 0x3a, 0x10, 0x69, 0x8a, 0x21, 0xfd, 0x42, 0x86, 0x7c, 0x42, 0xe0, 0xd2, 0x62,
-	0xec, 0x19, 0xdc, 0xa9, 0x48, 0x1f, 0x65, 0x9e, 0x63, 0xe8, 0xe7, 0x42, 0x61, 0x5a, 0xd0, 0x32,
-	0x1c, 0x72, 0x66, 0xb9, 0xd6, 0x75, 0x42, 0x9e, 0xab, 0xb0, 0x26, 0x53, 0x81, 0x29, 0xed, 0xc5,
-	0x3a, 0xec, 0x2f, 0x16, 0x33, 0x24, 0xa9, 0x12, 0x91, 0xfb, 0x0a, 0x75, 0x16, 0x9f, 0xdb, 0xdd,
-	0x38, 0xe5, 0x13, 0x02, 0xb9, 0xc5, 0xd8, 0x17, 0x00, 0x36, 0x52, 0x2c, 0x2e, 0xd7, 0xee, 0x98,
-	0xc2, 0x8c, 0x09, 0x79, 0x23, 0x2e, 0xd7, 0xb5, 0xdb, 0xcf, 0x65, 0x5e, 0x09, 0xaf, 0x72, 0x9f,
-	0x18, 0xc0, 0x6c, 0xd6, 0xc6, 0xed, 0x9f, 0x96, 0x91, 0x26, 0x89, 0x55, 0x85, 0x18, 0xca, 0x41,
-	0x19, 0x69, 0xef, 0xaf, 0x1e, 0xdc, 0x56, 0xa8, 0x8b, 0x4c, 0x61, 0x67, 0x54, 0x5f, 0xdb, 0xaf,
-	0xb5, 0x6f, 0x76, 0x96, 0x50, 0x68, 0xff, 0xdd, 0x43, 0x6e, 0xef, 0xb6, 0xa8, 0x40, 0xb6, 0x0f,
-	0xb7, 0xba, 0xed, 0x09, 0xb2, 0x4f, 0x34, 0xb2, 0x21, 0xdf, 0x69, 0xf7, 0x66, 0x91, 0x7d, 0x32,
-	0x73, 0x8b, 0x32, 0xf5, 0xb1, 0x19, 0x7e, 0x35, 0xb7, 0x0a, 0xab, 0x47, 0x5b, 0x17, 0xd3, 0x1a,
-	0x9b, 0x53, 0x61, 0x44, 0x69, 0x0a, 0xab, 0x40, 0x33, 0xb6, 0x5e, 0x53, 0x18, 0xaf, 0x40, 0xef,
-	0x02, 0x9c, 0xf6, 0x75, 0x9e, 0xc2, 0x30, 0xb4, 0x52, 0x35, 0x4f, 0xe8, 0x7e, 0xeb, 0x09, 0x5d,
-	0x17, 0x29, 0x27, 0x22, 0x7b, 0x61, 0x1e, 0x3c, 0xc5, 0xa2, 0xe7, 0xe0, 0xcc, 0x1f, 0xb4, 0x57,
-	0xc5, 0xcd, 0x86, 0xf1, 0x9a, 0xbe, 0x7f, 0xdc, 0xda, 0xb8, 0x76, 0x93, 0xb2, 0x31, 0x6c, 0xf0,
-	0xe5, 0xaf, 0xc7, 0x8b, 0xd9, 0x67, 0xe6, 0x78, 0xf0, 0x9e, 0x1f, 0x2d, 0x67, 0x3d, 0xb6, 0x05,
-	0x83, 0xdf, 0x8e, 0x96, 0xb3, 0xbe, 0x39, 0xf0, 0x83, 0xc3, 0xd9, 0x80, 0xdd, 0x86, 0x9d, 0x83,
-	0x37, 0xef, 0x16, 0x3f, 0xfb, 0x2f, 0x8f, 0x0f, 0x7d, 0xfb, 0xc5, 0x70, 0xff, 0x29, 0x8c, 0xea,
-	0x5d, 0xcb, 0xb6, 0x01, 0xcc, 0xd9, 0x6f, 0x45, 0x3b, 0x79, 0xf5, 0xf2, 0xc3, 0x9b, 0x59, 0x8f,
-	0x8d, 0x60, 0x78, 0xfc, 0xee, 0xf8, 0xc7, 0x59, 0xff, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x77,
-	0x67, 0x3e, 0xf5, 0xa3, 0x09, 0x00, 0x00,
+	// 1158 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x85, 0x56, 0x5f, 0x6f, 0xe3, 0x44,
+	0x10, 0x27, 0x89, 0xdb, 0x26, 0xe3, 0xb4, 0x4d, 0xb7, 0xd5, 0x29, 0xba, 0x83, 0xe3, 0x30, 0x20,
+	0xda, 0x22, 0xb5, 0x47, 0x4e, 0x48, 0x3c, 0x21, 0x5d, 0x13, 0xca, 0x9d, 0xe8, 0xe5, 0xaa, 0x4d,
+	0x2b, 0x04, 0x2f, 0x96, 0x6b, 0x6f, 0x12, 0xab, 0x8e, 0x6d, 0xad, 0x9d, 0xb6, 0xe9, 0x0b, 0x5f,
+	0x81, 0xcf, 0x80, 0xc4, 0x47, 0xe2, 0x7b, 0xf0, 0xca, 0x1b, 0xb3, 0xb3, 0x6b, 0xd7, 0xee, 0x21,
+	0xf1, 0xb6, 0xf3, 0x9b, 0x9f, 0xe7, 0xff, 0x4c, 0x02, 0xcf, 0xa2, 0xbb, 0xe0, 0x78, 0x11, 0xce,
+	0xa4, 0x97, 0x87, 0x49, 0x6c, 0x5e, 0xe2, 0x28, 0x95, 0x49, 0x9e, 0xb0, 0x4e, 0xa9, 0x70, 0x7e,
+	0x83, 0xce, 0xdb, 0xd1, 0x3b, 0x2f, 0xbd, 0x58, 0xa5, 0x82, 0xed, 0xc1, 0x5a, 0x98, 0x2d, 0xc3,
+	0xa0, 0xdf, 0x78, 0xd1, 0xdc, 0x6f, 0x73, 0x2d, 0x68, 0x74, 0x86, 0x68, 0xb3, 0x40, 0x51, 0x60,
+	0x4f, 0x60, 0x7d, 0x9e, 0x64, 0x39, 0xc2, 0x2d, 0x84, 0xd7, 0xb8, 0x91, 0x18, 0x03, 0x2b, 0xce,
+	0x10, 0xb5, 0x08, 0xa5, 0x37, 0x7b, 0x0a, 0xed, 0x85, 0x97, 0x4a, 0x2f, 0x9e, 0x89, 0xfe, 0x1a,
+	0xe1, 0xa5, 0xec, 0xbc, 0x84, 0xf5, 0x61, 0x12, 0x4f, 0xc3, 0x19, 0xeb, 0x41, 0xeb, 0x5a, 0xac,
+	0xc8, 0x77, 0x87, 0xab, 0xa7, 0xf2, 0x7c, 0xe3, 0x45, 0x4b, 0x41, 0x9e, 0x3b, 0x5c, 0x0b, 0xce,
+	0x8f, 0xb0, 0x3e, 0x12, 0x37, 0xa1, 0x2f, 0xc8, 0x97, 0xb7, 0x10, 0xe6, 0x13, 0x7a, 0xb3, 0x03,
+	0x58, 0xf7, 0xc9, 0x1e, 0x7e, 0xd4, 0xda, 0xb7, 0x07, 0x3b, 0x47, 0x65, 0xb2, 0x47, 0xda, 0x11,
+	0x37, 0x04, 0xe7, 0xef, 0x26, 0xb4, 0x27, 0xb1, 0x97, 0x66, 0xf3, 0x24, 0xff, 0x4f, 0x5b, 0xaf,
+	0xc0, 0x8e, 0x12, 0xdf, 0x8b, 0x86, 0xff, 0x63, 0xb0, 0xca, 0x52, 0xc9, 0x62, 0x95, 0xa7, 0x61,
+	0x24, 0x32, 0x2c, 0x4d, 0x0b, 0x8d, 0x95, 0x32, 0xfb, 0x18, 0x3a, 0x22, 0x9d, 0x8b, 0x85, 0x90,
+	0x5e, 0x44, 0x15, 0x6a, 0xf3, 0x07, 0x80, 0x7d, 0x0b, 0x5d, 0x32, 0xa4, 0xb3, 0xcb, 0xb0, 0x54,
+	0x8f, 0xfd, 0x69, 0x0d, 0xaf, 0xd1, 0x98, 0x03, 0x5d, 0x4f, 0xfa, 0xf3, 0x30, 0x17, 0x7e, 0xbe,
+	0x94, 0xa2, 0xbf, 0x4e, 0x15, 0xae, 0x61, 0x2a, 0xa8, 0x2c, 0xc7, 0x01, 0x98, 0x2e, 0xa3, 0xfe,
+	0x06, 0xf9, 0x2d, 0x65, 0xf6, 0x39, 0x6c, 0xfa, 0x52, 0x90, 0x03, 0x37, 0x40, 0xac, 0xdf, 0x7e,
+	0xd1, 0xd8, 0x6f, 0xf1, 0x6e, 0x01, 0x8e, 0x10, 0x63, 0x5f, 0xc0, 0x56, 0xe4, 0x65, 0xb9, 0xbb,
+	0xcc, 0x44, 0xa0, 0x59, 0x1d, 0xcd, 0x52, 0xe8, 0x25, 0x82, 0xc4, 0xfa, 0x14, 0x6c, 0x71, 0x97,
+	0x86, 0x72, 0xa5, 0x29, 0x40, 0x14, 0xd0, 0x90, 0x22, 0x38, 0x7f, 0x34, 0x60, 0x53, 0x66, 0xab,
+	0xd8, 0x3f, 0x45, 0xdb, 0x18, 0x58, 0xa6, 0xe6, 0xe8, 0xce, 0xcb, 0x73, 0x99, 0x61, 0xe5, 0x1b,
+	0x18, 0x97, 0x91, 0x14, 0x1e, 0x88, 0x48, 0xe4, 0xaa, 0xf9, 0x84, 0x6b, 0x49, 0x65, 0xe2, 0x27,
+	0x8b, 0x14, 0x3f, 0x55, 0xe5, 0x55, 0x9a, 0x52, 0xc6, 0x20, 0x37, 0xaf, 0xc2, 0x20, 0x94, 0x98,
+	0x34, 0xc6, 0x4d, 0x25, 0x56, 0x84, 0x3a, 0xa8, 0x82, 0xbc, 0xc2, 0x02, 0x5e, 0xbb, 0x68, 0x31,
+	0xf7, 0xb0, 0xca, 0x8a, 0x03, 0x04, 0x8d, 0x14, 0xe2, 0x1c, 0x80, 0x7d, 0x3f, 0xcd, 0xca, 0x08,
+	0xab, 0x1e, 0x1b, 0x75, 0x8f, 0xce, 0xef, 0x98, 0xcf, 0x55, 0x2e, 0x2b, 0xec, 0x03, 0xe8, 0x95,
+	0xfd, 0x72, 0xe7, 0xc2, 0x0b, 0x84, 0x34, 0x5f, 0x6d, 0x97, 0xf8, 0x1b, 0x82, 0xd9, 0xd7, 0xb0,
+	0xa3, 0x09, 0x6e, 0xb6, 0xbc, 0xba, 0x49, 0xa2, 0xe5, 0x02, 0x9b, 0xae, 0xb3, 0xed, 0x69, 0xc5,
+	0xa4, 0xc4, 0xd9, 0x57, 0xb0, 0x5d, 0x78, 0xd5, 0x1d, 0xf0, 0x4c, 0xfa, 0x5b, 0x0f, 0x30, 0x96,
+	0xd8, 0x73, 0xfe, 0x69, 0xc1, 0xf6, 0xbb, 0x47, 0x9e, 0x0e, 0xa1, 0x39, 0xcd, 0x68, 0xb4, 0xb7,
+	0x06, 0x4f, 0x2b, 0xf3, 0x54, 0xf2, 0x4e, 0x27, 0xea, 0x00, 0x70, 0x64, 0xa1, 0x23, 0xcb, 0x97,
+	0xe1, 0x92, 0x02, 0xd9, 0x1a, 0xec, 0x56, 0xa7, 0x9d, 0xbf, 0xbd, 0x24, 0x1a, 0x11, 0xd0, 0xe8,
+	0x5a, 0x18, 0xe0, 0x1e, 0xd3, 0x94, 0xdb, 0x83, 0xbd, 0x0a, 0xb3, 0x3c, 0x29, 0x5c, 0x53, 0x54,
+	0x67, 0x32, 0xb3, 0x69, 0x63, 0x4f, 0xa5, 0x69, 0xd1, 0x66, 0xd4, 0x41, 0xf6, 0x0d, 0x74, 0x0a,
+	0xa0, 0x98, 0xfe, 0xaa, 0xff, 0x62, 0x57, 0xf9, 0x03, 0x8b, 0xf5, 0x61, 0x03, 0x93, 0x0f, 0x96,
+	0x8b, 0x14, 0xe7, 0x5a, 0x95, 0xa3, 0x10, 0xd9, 0xf7, 0x8f, 0x26, 0x8d, 0xc6, 0xda, 0x1e, 0xf4,
+	0x2b, 0x06, 0x6b, 0x7a, 0xfe, 0x68, 0x30, 0xd1, 0xb2, 0x14, 0x53, 0x7c, 0xcd, 0x69, 0xd4, 0xd1,
+	0xb2, 0x11, 0xd9, 0x77, 0xb5, 0xf9, 0xa0, 0x29, 0xb7, 0x07, 0x4f, 0x2a, 0x76, 0x2b, 0x5a, 0x5e,
+	0x1b, 0xa5, 0xe7, 0x00, 0xba, 0x9f, 0x93, 0xf0, 0x5e, 0xf4, 0x6d, 0xbd, 0x1e, 0x0f, 0x88, 0x8a,
+	0xb9, 0x36, 0x4d, 0xfd, 0xee, 0x07, 0x31, 0xd7, 0xf4, 0xbc, 0x4e, 0x77, 0x4e, 0xa1, 0x57, 0xb6,
+	0x14, 0xcf, 0x51, 0x2e, 0x93, 0x48, 0xe5, 0x91, 0x2d, 0x7d, 0x5f, 0x4f, 0xaf, 0xda, 0xfc, 0x42,
+	0x54, 0x1a, 0xac, 0x7a, 0xe6, 0xcd, 0xf4, 0x8e, 0x75, 0x78, 0x21, 0x3a, 0xaf, 0x60, 0xb3, 0xb4,
+	0x33, 0xc1, 0xa2, 0xa8, 0x1b, 0x33, 0x0d, 0x71, 0x79, 0xce, 0xa5, 0x18, 0xa9, 0x5a, 0x6b, 0x4b,
+	0x35, 0xcc, 0xf9, 0xb3, 0x05, 0x3d, 0x55, 0x79, 0x57, 0x5d, 0x96, 0xcc, 0x15, 0xe8, 0x7e, 0xa5,
+	0x8e, 0x0b, 0x16, 0x4d, 0xdc, 0x87, 0xf1, 0xcc, 0xcd, 0x43, 0x73, 0x5f, 0x37, 0xf1, 0x4b, 0x03,
+	0x5e, 0x20, 0xa6, 0x36, 0x72, 0x2a, 0x93, 0x7b, 0x11, 0x6b, 0x4a, 0x93, 0x28, 0xa0, 0x21, 0x22,
+	0x7c, 0x06, 0xdd, 0x85, 0x58, 0x90, 0x71, 0x62, 0xb4, 0x88, 0x61, 0x1b, 0x8c, 0x28, 0xe8, 0x08,
+	0xc5, 0x5b, 0x89, 0x27, 0x4f, 0x73, 0x2c, 0xed, 0xa8, 0x00, 0x0b, 0x52, 0x8a, 0xf9, 0x65, 0x6e,
+	0xe6, 0x7b, 0x71, 0x2c, 0x02, 0xfa, 0x35, 0xb2, 0x78, 0x97, 0xc0, 0x89, 0xc6, 0xd8, 0x4b, 0xd8,
+	0x33, 0xa4, 0xeb, 0x30, 0x4d, 0x71, 0xd9, 0x52, 0x4f, 0x62, 0x32, 0x74, 0x57, 0x2d, 0xce, 0x34,
+	0x57, 0xab, 0xce, 0x49, 0xf3, 0x60, 0x56, 0x79, 0xca, 0x45, 0x4c, 0x27, 0xb6, 0x30, 0xfb, 0xb3,
+	0xc6, 0x14, 0x29, 0x94, 0xb8, 0x0b, 0x2e, 0x36, 0x2a, 0x89, 0x6e, 0xf4, 0x99, 0xc5, 0x00, 0x09,
+	0xe4, 0x1a, 0x63, 0x9f, 0x00, 0x68, 0x4b, 0x91, 0x77, 0xbf, 0xc2, 0xb9, 0x53, 0x66, 0x3a, 0x84,
+	0x9c, 0x21, 0x50, 0xa8, 0xdd, 0x34, 0x4c, 0xcd, 0xe0, 0x19, 0xf5, 0xb9, 0x02, 0xd4, 0x91, 0x2e,
+	0xd5, 0xee, 0xd5, 0x12, 0x57, 0xde, 0x26, 0x4a, 0xb7, 0xa0, 0x9c, 0x20, 0xe6, 0xfc, 0xd5, 0x80,
+	0x5d, 0x8c, 0x21, 0x4f, 0xa4, 0xa8, 0xb5, 0xea, 0x4b, 0xfd, 0x75, 0xe6, 0xaa, 0x83, 0x82, 0x89,
+	0xe9, 0xbf, 0x01, 0x16, 0xd7, 0xb9, 0x0d, 0x0d, 0x88, 0x6b, 0xbf, 0x53, 0x2f, 0x8f, 0x9f, 0xdc,
+	0x52, 0xcb, 0x2c, 0xbe, 0x5d, 0xad, 0xcd, 0x30, 0xb9, 0x55, 0x7d, 0x9b, 0x26, 0xf2, 0xba, 0x6c,
+	0xbe, 0xe9, 0x9b, 0xc1, 0x8a, 0xd6, 0x16, 0xc1, 0x54, 0xda, 0x66, 0x1b, 0x8c, 0x28, 0x65, 0x60,
+	0x06, 0x0c, 0xe8, 0x66, 0x17, 0x81, 0x71, 0x03, 0x3a, 0x77, 0x60, 0x57, 0xd3, 0x39, 0x06, 0x2b,
+	0xd0, 0xa3, 0xaa, 0x56, 0xe8, 0x59, 0x65, 0x85, 0x1e, 0x0f, 0x29, 0x27, 0x22, 0xae, 0xf5, 0x86,
+	0x71, 0x40, 0xeb, 0x60, 0x0f, 0x9e, 0x57, 0x4f, 0xc5, 0x87, 0x05, 0xe3, 0x05, 0xfd, 0x70, 0x5c,
+	0xb9, 0xb8, 0xfa, 0x92, 0xb2, 0x0e, 0xac, 0xf1, 0xc9, 0x2f, 0xe3, 0x61, 0xef, 0x23, 0xf5, 0x3c,
+	0xb9, 0xe0, 0xa7, 0x93, 0x5e, 0x83, 0x6d, 0x40, 0xeb, 0x57, 0x7c, 0x34, 0xd5, 0x83, 0x9f, 0x8c,
+	0x7a, 0x2d, 0xb6, 0x0b, 0xdb, 0x27, 0x67, 0xef, 0x87, 0x3f, 0xb9, 0xaf, 0xc7, 0x23, 0x57, 0x7f,
+	0x61, 0x1d, 0x1e, 0x43, 0xbb, 0xb8, 0xb5, 0x6c, 0x0b, 0x40, 0xbd, 0xdd, 0x8a, 0xb5, 0xf3, 0x37,
+	0xaf, 0x2f, 0xcf, 0xd0, 0x5a, 0x1b, 0xac, 0xf1, 0xfb, 0xf1, 0x0f, 0xbd, 0xe6, 0xbf, 0x4e, 0x49,
+	0x79, 0x5a, 0xee, 0x09, 0x00, 0x00,
 }
@@ -79,12 +79,15 @@ func TypesToHeader(types ...Type) *MigrationHeader {
 		features := BtrfsFeatures{
 			MigrationHeader:  &missingFeature,
 			HeaderSubvolumes: &missingFeature,
+			CompressedData:   &missingFeature,
 		}
 		for _, feature := range preferredType.Features {
 			if feature == BTRFSFeatureMigrationHeader {
 				features.MigrationHeader = &hasFeature
 			} else if feature == BTRFSFeatureSubvolumes {
 				features.HeaderSubvolumes = &hasFeature
+			} else if feature == BTRFSFeatureCompressedData {
+				features.CompressedData = &hasFeature
 			}
 		}
 
@@ -102,6 +105,7 @@ func TypesToHeader(types ...Type) *MigrationHeader {
 			Delete:        &missingFeature,
 			Compress:      &missingFeature,
 			Bidirectional: &missingFeature,
+			BlockDelta:    &missingFeature,
 		}
 
 		for _, feature := range t.Features {
@@ -113,6 +117,8 @@ func TypesToHeader(types ...Type) *MigrationHeader {
 				features.Compress = &hasFeature
 			} else if feature == "bidirectional" {
 				features.Bidirectional = &hasFeature
+			} else if feature == BlockFeatureDelta {
+				features.BlockDelta = &hasFeature
 			}
 		}
 
@@ -149,7 +155,7 @@ func MatchTypes(offer *MigrationHeader, fallbackType MigrationFSType, ourTypes [
 				offeredFeatures = offer.GetZfsFeaturesSlice()
 			} else if offerFSType == MigrationFSType_BTRFS {
 				offeredFeatures = offer.GetBtrfsFeaturesSlice()
-			} else if offerFSType == MigrationFSType_RSYNC {
+			} else if offerFSType == MigrationFSType_RSYNC || offerFSType == MigrationFSType_BLOCK_AND_RSYNC {
 				offeredFeatures = offer.GetRsyncFeaturesSlice()
 				if !shared.StringInSlice("bidirectional", offeredFeatures) {
 					// If no bi-directional support, this means we are getting a response from
@@ -6,6 +6,12 @@ const BTRFSFeatureMigrationHeader = "migration_header"
 // BTRFSFeatureSubvolumes indicates migration can send/recv subvolumes.
 const BTRFSFeatureSubvolumes = "header_subvolumes"
 
+// BTRFSFeatureCompressedData indicates migration can send/recv compressed extents without decompressing them.
+const BTRFSFeatureCompressedData = "compressed_data"
+
+// BlockFeatureDelta indicates block volumes can be sent/recv as the chunks which differ from the target.
+const BlockFeatureDelta = "block_delta"
+
 // GetRsyncFeaturesSlice returns a slice of strings representing the supported RSYNC features
 func (m *MigrationHeader) GetRsyncFeaturesSlice() []string {
 	features := []string{}
@@ -28,6 +34,10 @@ func (m *MigrationHeader) GetRsyncFeaturesSlice() []string {
 		if m.RsyncFeatures.Bidirectional != nil && *m.RsyncFeatures.Bidirectional == true {
 			features = append(features, "bidirectional")
 		}
+
+		if m.RsyncFeatures.BlockDelta != nil && *m.RsyncFeatures.BlockDelta == true {
+			features = append(features, BlockFeatureDelta)
+		}
 	}
 
 	return features
@@ -64,6 +74,10 @@ func (m *MigrationHeader) GetBtrfsFeaturesSlice() []string {
 		if m.BtrfsFeatures.HeaderSubvolumes != nil && *m.BtrfsFeatures.HeaderSubvolumes == true {
 			features = append(features, BTRFSFeatureSubvolumes)
 		}
+
+		if m.BtrfsFeatures.CompressedData != nil && *m.BtrfsFeatures.CompressedData == true {
+			features = append(features, BTRFSFeatureCompressedData)
+		}
 	}
 
 	return features
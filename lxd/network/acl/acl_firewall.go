@@ -36,6 +36,7 @@ func FirewallApplyACLRules(s *state.State, logger logger.Logger, aclProjectName
 				DestinationPort: rule.DestinationPort,
 				ICMPType:        rule.ICMPType,
 				ICMPCode:        rule.ICMPCode,
+				DSCP:            rule.DSCP,
 			}
 
 			if rule.State == "logged" {
@@ -3,6 +3,7 @@ package acl
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -32,6 +33,10 @@ const ovnACLPriorityPortGroupAllow = 300
 const ovnACLPriorityPortGroupReject = 400
 const ovnACLPriorityPortGroupDrop = 500
 
+// ovnQoSPriorityACL is higher than the priority of the QoS rules set for instance NICs so that ACL rules override
+// the DSCP set on the NIC.
+const ovnQoSPriorityACL = 200
+
 // ovnACLPortGroupPrefix prefix used when naming ACL related port groups in OVN.
 const ovnACLPortGroupPrefix = "lxd_acl"
 
@@ -308,6 +313,7 @@ func ovnApplyToPortGroup(logger logger.Logger, client *openvswitch.OVN, aclInfo
 	// Create slice for port group rules that has the capacity for ingress and egress rules, plus default rule.
 	portGroupRules := make([]openvswitch.OVNACLRule, 0, len(aclInfo.Ingress)+len(aclInfo.Egress)+1)
 	networkRules := make([]openvswitch.OVNACLRule, 0)
+	qosRules := make([]openvswitch.OVNQoSRule, 0)
 
 	// convertACLRules converts the ACL rules to OVN ACL rules.
 	convertACLRules := func(direction string, rules ...api.NetworkACLRule) error {
@@ -332,6 +338,28 @@ func ovnApplyToPortGroup(logger logger.Logger, client *openvswitch.OVN, aclInfo
 			} else {
 				portGroupRules = append(portGroupRules, ovnACLRule)
 			}
+
+			// OVN ACLs can't change packets, so DSCP marking is done with QoS rules on the network's switch
+			// using the same match. Those are applied to each network below.
+			if rule.DSCP != "" {
+				dscp, err := strconv.ParseUint(rule.DSCP, 10, 8)
+				if err != nil {
+					return errors.Wrapf(err, "Invalid DSCP value %q", rule.DSCP)
+				}
+
+				qosRule := openvswitch.OVNQoSRule{
+					Direction: "to-lport", // Ingress rules match on outport.
+					Match:     ovnACLRule.Match,
+					Priority:  ovnQoSPriorityACL,
+					DSCP:      uint8(dscp),
+				}
+
+				if direction == "egress" {
+					qosRule.Direction = "from-lport" // Egress rules match on inport.
+				}
+
+				qosRules = append(qosRules, qosRule)
+			}
 		}
 
 		return nil
@@ -382,6 +410,12 @@ func ovnApplyToPortGroup(logger logger.Logger, client *openvswitch.OVN, aclInfo
 		if err != nil {
 			return errors.Wrapf(err, "Failed applying ACL %q rules to port group %q for network %q ", aclInfo.Name, netPortGroupName, aclNet.Name)
 		}
+
+		// Apply the DSCP marking rules to the network's switch (even if qosRules is empty to clear old ones).
+		err = client.LogicalSwitchSetQoSRules(OVNIntSwitchName(aclNet.ID), string(portGroupName), matchReplace, qosRules...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed applying ACL %q QoS rules for network %q", aclInfo.Name, aclNet.Name)
+		}
 	}
 
 	return nil
@@ -352,6 +352,18 @@ func (d *common) validateRule(direction ruleDirection, rule api.NetworkACLRule)
 		}
 	}
 
+	// Validate DSCP field.
+	if rule.DSCP != "" {
+		if rule.Action != "allow" {
+			return fmt.Errorf("DSCP can only be used with %q action", "allow")
+		}
+
+		err := validate.IsNetworkDSCP(rule.DSCP)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid DSCP")
+		}
+	}
+
 	// Validate Protocol field.
 	if rule.Protocol != "" {
 		validProtocols := []string{"icmp4", "icmp6", "tcp", "udp"}
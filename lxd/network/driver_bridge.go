@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -27,6 +28,7 @@ import (
 	firewallDrivers "github.com/lxc/lxd/lxd/firewall/drivers"
 	"github.com/lxc/lxd/lxd/ip"
 	"github.com/lxc/lxd/lxd/network/acl"
+	"github.com/lxc/lxd/lxd/network/ipam"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/revert"
@@ -182,6 +184,22 @@ func (n *bridge) ValidateName(name string) error {
 	return n.common.ValidateName(name)
 }
 
+// isNAT64Prefix validates a NAT64 prefix (an IPv6 /96 subnet).
+func isNAT64Prefix(value string) error {
+	err := validate.IsNetworkV6(value)
+	if err != nil {
+		return err
+	}
+
+	_, subnet, _ := net.ParseCIDR(value)
+	ones, _ := subnet.Mask.Size()
+	if ones != 96 {
+		return fmt.Errorf("NAT64 prefix must be a /96")
+	}
+
+	return nil
+}
+
 // Validate network config.
 func (n *bridge) Validate(config map[string]string) error {
 	// Build driver specific rules dynamically.
@@ -197,9 +215,12 @@ func (n *bridge) Validate(config map[string]string) error {
 
 			return nil
 		}),
-		"bridge.hwaddr": validate.Optional(validate.IsNetworkMAC),
-		"bridge.mtu":    validate.Optional(validate.IsNetworkMTU),
-		"bridge.mode":   validate.Optional(validate.IsOneOf("standard", "fan")),
+		"bridge.hwaddr":         validate.Optional(validate.IsNetworkMAC),
+		"bridge.mtu":            validate.Optional(validate.IsNetworkMTU),
+		"bridge.mode":           validate.Optional(validate.IsOneOf("standard", "fan")),
+		"bridge.stp":            validate.Optional(validate.IsBool),
+		"bridge.forward_delay":  validate.Optional(validate.IsUint8),
+		"bridge.vlan_filtering": validate.Optional(validate.IsBool),
 
 		"fan.overlay_subnet": validate.Optional(validate.IsNetworkV4),
 		"fan.underlay_subnet": validate.Optional(func(value string) error {
@@ -241,6 +262,9 @@ func (n *bridge) Validate(config map[string]string) error {
 		"ipv6.nat":                             validate.Optional(validate.IsBool),
 		"ipv6.nat.order":                       validate.Optional(validate.IsOneOf("before", "after")),
 		"ipv6.nat.address":                     validate.Optional(validate.IsNetworkAddressV6),
+		"ipv6.nat64":                           validate.Optional(validate.IsBool),
+		"ipv6.nat64.prefix":                    validate.Optional(isNAT64Prefix),
+		"ipv6.nat64.pool":                      validate.Optional(validate.IsNetworkV4),
 		"ipv6.dhcp":                            validate.Optional(validate.IsBool),
 		"ipv6.dhcp.expiry":                     validate.IsAny,
 		"ipv6.dhcp.stateful":                   validate.Optional(validate.IsBool),
@@ -252,6 +276,9 @@ func (n *bridge) Validate(config map[string]string) error {
 		"dns.search":                           validate.IsAny,
 		"dns.mode":                             validate.Optional(validate.IsOneOf("dynamic", "managed", "none")),
 		"raw.dnsmasq":                          validate.IsAny,
+		"ipam.driver":                          validate.Optional(validate.IsOneOf(ipam.Drivers...)),
+		"ipam.url":                             validate.IsAny,
+		"ipam.token":                           validate.IsAny,
 		"maas.subnet.ipv4":                     validate.IsAny,
 		"maas.subnet.ipv6":                     validate.IsAny,
 		"security.acls":                        validate.IsAny,
@@ -259,6 +286,10 @@ func (n *bridge) Validate(config map[string]string) error {
 		"security.acls.default.egress.action":  validate.Optional(validate.IsOneOf(acl.ValidActions...)),
 		"security.acls.default.ingress.logged": validate.Optional(validate.IsBool),
 		"security.acls.default.egress.logged":  validate.Optional(validate.IsBool),
+		"security.mac_filtering":               validate.Optional(validate.IsBool),
+		"security.ipv4_filtering":              validate.Optional(validate.IsBool),
+		"security.ipv6_filtering":              validate.Optional(validate.IsBool),
+		"security.port_isolation":              validate.Optional(validate.IsBool),
 	}
 
 	// Add dynamic validation rules.
@@ -312,6 +343,26 @@ func (n *bridge) Validate(config map[string]string) error {
 		return fmt.Errorf("Network name too long to use with the FAN (must be 11 characters or less)")
 	}
 
+	// Validate NAT64 settings, the translator interface is named after the network and the translator uses
+	// an address within the IPv6 subnet of the bridge.
+	if shared.IsTrue(config["ipv6.nat64"]) {
+		if len(n.name) > 11 {
+			return fmt.Errorf("Network name too long to use with NAT64 (must be 11 characters or less)")
+		}
+
+		if shared.StringInSlice(config["ipv6.address"], []string{"", "none"}) {
+			return fmt.Errorf(`"ipv6.nat64" requires "ipv6.address" to be set`)
+		}
+
+		_, subnet, err := net.ParseCIDR(config["ipv6.address"])
+		if err == nil {
+			ones, _ := subnet.Mask.Size()
+			if ones > 96 {
+				return fmt.Errorf(`"ipv6.nat64" requires an "ipv6.address" subnet of /96 or larger`)
+			}
+		}
+	}
+
 	for k, v := range config {
 		key := k
 		// Bridge mode checks
@@ -358,6 +409,24 @@ func (n *bridge) Validate(config map[string]string) error {
 		}
 	}
 
+	// Check the spanning tree settings, the kernel only accepts forward delays between 2 and 30s with STP.
+	if shared.IsTrue(config["bridge.stp"]) && config["bridge.forward_delay"] != "" {
+		delay, _ := strconv.ParseUint(config["bridge.forward_delay"], 10, 8)
+		if delay < 2 || delay > 30 {
+			return fmt.Errorf(`"bridge.forward_delay" must be between 2 and 30 when "bridge.stp" is enabled`)
+		}
+	}
+
+	if config["bridge.driver"] == "openvswitch" && config["bridge.vlan_filtering"] != "" {
+		return fmt.Errorf(`"bridge.vlan_filtering" is only supported with native bridges`)
+	}
+
+	// Check the IPAM backend settings.
+	_, err = ipam.Load(config)
+	if err != nil {
+		return err
+	}
+
 	// Check using same MAC address on every cluster node is safe.
 	if config["bridge.hwaddr"] != "" {
 		err = n.checkClusterWideMACSafe(config)
@@ -622,6 +691,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		return err
 	}
 
+	// Stop the NAT64 translator as its interface is removed with the tunnels.
+	err = n.killNAT64()
+	if err != nil {
+		return err
+	}
+
 	// Cleanup any existing tunnel device.
 	for _, iface := range ifaces {
 		if strings.HasPrefix(iface.Name, fmt.Sprintf("%s-", n.name)) {
@@ -718,17 +793,55 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
-	// Enable VLAN filtering for Linux bridges.
+	// Enable VLAN filtering for Linux bridges (unless disabled).
 	if n.config["bridge.driver"] != "openvswitch" {
-		err = BridgeVLANFilterSetStatus(n.name, "1")
+		vlanFiltering := n.config["bridge.vlan_filtering"] == "" || shared.IsTrue(n.config["bridge.vlan_filtering"])
+		if vlanFiltering {
+			err = BridgeVLANFilterSetStatus(n.name, "1")
+		} else {
+			err = BridgeVLANFilterSetStatus(n.name, "0")
+		}
+
 		if err != nil {
 			n.logger.Warn(fmt.Sprintf("%v", err))
 		}
 
 		// Set the default PVID for new ports to 1.
-		err = BridgeVLANSetDefaultPVID(n.name, "1")
+		if vlanFiltering {
+			err = BridgeVLANSetDefaultPVID(n.name, "1")
+			if err != nil {
+				n.logger.Warn(fmt.Sprintf("%v", err))
+			}
+		}
+	}
+
+	// Configure the spanning tree protocol (the forward delay must be set before enabling STP as the kernel
+	// checks it's within the range allowed by STP).
+	forwardDelay := n.config["bridge.forward_delay"]
+	if forwardDelay == "" {
+		forwardDelay = "15"
+	}
+
+	delay, err := strconv.ParseUint(forwardDelay, 10, 8)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid bridge.forward_delay")
+	}
+
+	if n.config["bridge.driver"] == "openvswitch" {
+		ovs := openvswitch.NewOVS()
+		err = ovs.BridgeSet(n.name, fmt.Sprintf("other_config:stp-forward-delay=%d", delay), fmt.Sprintf("stp_enable=%t", shared.IsTrue(n.config["bridge.stp"])))
 		if err != nil {
-			n.logger.Warn(fmt.Sprintf("%v", err))
+			return err
+		}
+	} else {
+		err = BridgeSetForwardDelay(n.name, delay)
+		if err != nil {
+			return err
+		}
+
+		err = BridgeSetSTP(n.name, shared.IsTrue(n.config["bridge.stp"]))
+		if err != nil {
+			return err
 		}
 	}
 
@@ -768,6 +881,20 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			if err != nil {
 				return err
 			}
+
+			// Listen for LLDP frames to report the switch the external interface is connected to.
+			err = lldpListenerStart(n.name, entry)
+			if err != nil {
+				n.logger.Warn("Failed starting LLDP listener", log.Ctx{"interface": entry, "err": err})
+			}
+		}
+	}
+
+	// Stop listening for LLDP frames on the external interfaces which were removed.
+	externalInterfaces := util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true)
+	for _, entry := range util.SplitNTrimSpace(oldConfig["bridge.external_interfaces"], ",", -1, true) {
+		if !shared.StringInSlice(entry, externalInterfaces) {
+			lldpListenerStop(n.name, entry)
 		}
 	}
 
@@ -1147,6 +1274,20 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		n.applyBootRoutesV6(ctRoutes)
 	}
 
+	// Configure NAT64.
+	if shared.IsTrue(n.config["ipv6.nat64"]) {
+		pool, err := n.setupNAT64()
+		if err != nil {
+			return errors.Wrapf(err, "Failed setting up NAT64")
+		}
+
+		// Masquerade the IPv4 addresses the translator maps the instances to.
+		fwOpts.SNATNAT64 = &firewallDrivers.SNATOpts{
+			Subnet: pool,
+			Append: true,
+		}
+	}
+
 	// Configure the fan.
 	dnsClustered := false
 	dnsClusteredAddress := ""
@@ -1483,6 +1624,11 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			}
 		}
 
+		// Forward the other queries to the DNS64 resolver when using NAT64.
+		if shared.IsTrue(n.config["ipv6.nat64"]) {
+			dnsmasqCmd = append(dnsmasqCmd, "--no-resolv", "-S", fmt.Sprintf("%s#1064", n.nat64DNSAddress()))
+		}
+
 		// Create a config file to contain additional config (and to prevent dnsmasq from reading /etc/dnsmasq.conf)
 		err = ioutil.WriteFile(shared.VarPath("networks", n.name, "dnsmasq.raw"), []byte(fmt.Sprintf("%s\n", n.config["raw.dnsmasq"])), 0644)
 		if err != nil {
@@ -1490,6 +1636,9 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 		dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--conf-file=%s", shared.VarPath("networks", n.name, "dnsmasq.raw")))
 
+		// Serve the additional DNS names of the instances (reloaded by dnsmasq on change).
+		dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--hostsdir=%s", shared.VarPath("networks", n.name, "dnsmasq.aliases")))
+
 		// Attempt to drop privileges.
 		if n.state.OS.UnprivUser != "" {
 			dnsmasqCmd = append(dnsmasqCmd, []string{"-u", n.state.OS.UnprivUser}...)
@@ -1506,6 +1655,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			}
 		}
 
+		// Create DNS aliases directory.
+		if !shared.PathExists(shared.VarPath("networks", n.name, "dnsmasq.aliases")) {
+			err = os.MkdirAll(shared.VarPath("networks", n.name, "dnsmasq.aliases"), 0755)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Check for dnsmasq.
 		_, err := exec.LookPath("dnsmasq")
 		if err != nil {
@@ -1571,6 +1728,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 			return fmt.Errorf("Failed to save subprocess details: %s", err)
 		}
 
+		// Emit lifecycle events for the DHCP leases handed out by dnsmasq.
+		err = leaseMonitorStart(n)
+		if err != nil {
+			n.logger.Warn("Failed starting DHCP lease monitor", log.Ctx{"err": err})
+		}
+
 		// Spawn DNS forwarder if needed (backgrounded to avoid deadlocks during cluster boot).
 		if dnsClustered {
 			// Create forkdns servers directory.
@@ -1593,6 +1756,14 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 				return err
 			}
 		}
+
+		// Spawn DNS64 resolver if needed.
+		if shared.IsTrue(n.config["ipv6.nat64"]) {
+			err = n.spawnForkDNS64()
+			if err != nil {
+				return err
+			}
+		}
 	} else {
 		// Clean up old dnsmasq config if exists and we are not starting dnsmasq.
 		leasesPath := shared.VarPath("networks", n.name, "dnsmasq.leases")
@@ -1647,6 +1818,10 @@ func (n *bridge) Stop() error {
 		return nil
 	}
 
+	for _, entry := range util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true) {
+		lldpListenerStop(n.name, entry)
+	}
+
 	// Destroy the bridge interface
 	if n.config["bridge.driver"] == "openvswitch" {
 		ovs := openvswitch.NewOVS()
@@ -1687,11 +1862,18 @@ func (n *bridge) Stop() error {
 		return err
 	}
 
+	leaseMonitorStop(n.name)
+
 	err = n.killForkDNS()
 	if err != nil {
 		return err
 	}
 
+	err = n.killNAT64()
+	if err != nil {
+		return err
+	}
+
 	// Get a list of interfaces
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -2093,6 +2275,188 @@ func (n *bridge) killForkDNS() error {
 	return nil
 }
 
+// nat64Prefix returns the IPv6 prefix the NAT64 translator maps the IPv4 addresses into.
+func (n *bridge) nat64Prefix() string {
+	if n.config["ipv6.nat64.prefix"] != "" {
+		return n.config["ipv6.nat64.prefix"]
+	}
+
+	return "64:ff9b::/96"
+}
+
+// nat64DNSAddress returns the address the DNS64 resolver of the network listens on.
+func (n *bridge) nat64DNSAddress() string {
+	ipAddress, _, _ := net.ParseCIDR(n.config["ipv6.address"])
+	return fmt.Sprintf("[%s]", ipAddress.String())
+}
+
+// setupNAT64 creates the interface of the NAT64 translator (tayga), routes the NAT64 prefix and the IPv4 pool
+// through it and starts the translator. Returns the IPv4 pool which needs to be masqueraded.
+func (n *bridge) setupNAT64() (*net.IPNet, error) {
+	command, err := exec.LookPath("tayga")
+	if err != nil {
+		return nil, fmt.Errorf("tayga is required for NAT64 on LXD managed bridges")
+	}
+
+	poolAddress := n.config["ipv6.nat64.pool"]
+	if poolAddress == "" {
+		poolAddress = "192.168.255.0/24"
+	}
+
+	_, pool, err := net.ParseCIDR(poolAddress)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing ipv6.nat64.pool")
+	}
+
+	_, subnet, err := net.ParseCIDR(n.config["ipv6.address"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing ipv6.address")
+	}
+
+	// The translator needs its own IPv6 address (which can't be within the well-known prefix), use one from
+	// the bridge subnet that SLAAC won't hand out and route it to the translator.
+	taygaAddressV6 := make(net.IP, net.IPv6len)
+	copy(taygaAddressV6, subnet.IP.To16())
+	copy(taygaAddressV6[12:], []byte{0x00, 0x64, 0x00, 0x64})
+
+	tunName := fmt.Sprintf("%s-n64", n.name)
+	dataDir := shared.VarPath("networks", n.name, "tayga")
+	err = os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := shared.VarPath("networks", n.name, "tayga.conf")
+	config := fmt.Sprintf("tun-device %s\nipv4-addr %s\nipv6-addr %s\nprefix %s\ndynamic-pool %s\ndata-dir %s\n", tunName, dhcpalloc.GetIP(pool, 1).String(), taygaAddressV6.String(), n.nat64Prefix(), pool.String(), dataDir)
+	err = ioutil.WriteFile(configPath, []byte(config), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = shared.RunCommand(command, "--mktun", "--config", configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed creating NAT64 interface")
+	}
+
+	tunLink := &ip.Link{Name: tunName}
+	err = tunLink.SetUp()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []*ip.Route{
+		{DevName: tunName, Route: pool.String(), Proto: "static", Family: ip.FamilyV4},
+		{DevName: tunName, Route: n.nat64Prefix(), Proto: "static", Family: ip.FamilyV6},
+		{DevName: tunName, Route: fmt.Sprintf("%s/128", taygaAddressV6.String()), Proto: "static", Family: ip.FamilyV6},
+	}
+
+	for _, r := range routes {
+		err = r.Add()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The translated traffic leaves through the uplink.
+	err = util.SysctlSet("net/ipv4/ip_forward", "1")
+	if err != nil {
+		return nil, err
+	}
+
+	logPath := shared.LogPath(fmt.Sprintf("tayga.%s.log", n.name))
+	p, err := subprocess.NewProcess(command, []string{"--nodetach", "--config", configPath}, logPath, logPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create subprocess: %s", err)
+	}
+
+	err = p.Start()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to run: %s --nodetach --config %s: %v", command, configPath, err)
+	}
+
+	err = p.Save(shared.VarPath("networks", n.name, "tayga.pid"))
+	if err != nil {
+		// Kill Process if started, but could not save the file
+		err2 := p.Stop()
+		if err2 != nil {
+			return nil, fmt.Errorf("Could not kill subprocess while handling saving error: %s: %s", err, err2)
+		}
+
+		return nil, fmt.Errorf("Failed to save subprocess details: %s", err)
+	}
+
+	return pool, nil
+}
+
+// spawnForkDNS64 starts the DNS64 resolver of the network, which synthesizes AAAA records within the NAT64
+// prefix for the names only having A records.
+func (n *bridge) spawnForkDNS64() error {
+	command := n.state.OS.ExecPath
+	forkdnsargs := []string{"forkdns64",
+		fmt.Sprintf("%s:1064", n.nat64DNSAddress()),
+		n.nat64Prefix()}
+
+	logPath := shared.LogPath(fmt.Sprintf("forkdns64.%s.log", n.name))
+
+	p, err := subprocess.NewProcess(command, forkdnsargs, logPath, logPath)
+	if err != nil {
+		return fmt.Errorf("Failed to create subprocess: %s", err)
+	}
+
+	// Drop privileges.
+	p.SetCreds(n.state.OS.UnprivUID, n.state.OS.UnprivGID)
+
+	// Apply AppArmor profile.
+	p.SetApparmor(apparmor.ForkdnsProfileName(n))
+
+	err = p.Start()
+	if err != nil {
+		return fmt.Errorf("Failed to run: %s %s: %v", command, strings.Join(forkdnsargs, " "), err)
+	}
+
+	err = p.Save(shared.VarPath("networks", n.name, "forkdns64.pid"))
+	if err != nil {
+		// Kill Process if started, but could not save the file
+		err2 := p.Stop()
+		if err2 != nil {
+			return fmt.Errorf("Could not kill subprocess while handling saving error: %s: %s", err, err2)
+		}
+
+		return fmt.Errorf("Failed to save subprocess details: %s", err)
+	}
+
+	return nil
+}
+
+// killNAT64 stops the NAT64 translator and DNS64 resolver of the network (if running).
+func (n *bridge) killNAT64() error {
+	for _, name := range []string{"tayga", "forkdns64"} {
+		pidPath := shared.VarPath("networks", n.name, fmt.Sprintf("%s.pid", name))
+
+		// If the pid file doesn't exist, there is no process to kill.
+		if !shared.PathExists(pidPath) {
+			continue
+		}
+
+		p, err := subprocess.ImportProcess(pidPath)
+		if err != nil {
+			return fmt.Errorf("Could not read pid file: %s", err)
+		}
+
+		err = p.Stop()
+		if err != nil && err != subprocess.ErrNotRunning {
+			return fmt.Errorf("Unable to kill %s: %s", name, err)
+		}
+
+		err = os.Remove(pidPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to remove %s pid file %q", name, pidPath)
+		}
+	}
+
+	return nil
+}
+
 // updateForkdnsServersFile takes a list of node addresses and writes them atomically to
 // the forkdns.servers file ready for forkdns to notice and re-apply its config.
 func (n *bridge) updateForkdnsServersFile(addresses []string) error {
@@ -2128,6 +2492,157 @@ func (n *bridge) updateForkdnsServersFile(addresses []string) error {
 	return nil
 }
 
+// State returns the state of the bridge along with the LLDP neighbours of its external interfaces.
+func (n *bridge) State() (*api.NetworkState, error) {
+	state, err := n.common.State()
+	if err != nil {
+		return nil, err
+	}
+
+	state.LLDP = lldpNeighbours(util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true)...)
+
+	return state, nil
+}
+
+// Firewall returns the firewall rules generated on this member for the network and the instances connected to it.
+// Warnings are returned when the rules look to have been removed or could be overridden by other tools (e.g. docker
+// or firewalld), in which case a warning is also recorded for the network until the problem goes away.
+func (n *bridge) Firewall() (*api.NetworkFirewall, error) {
+	instances := []firewallDrivers.InstanceRef{}
+	err := usedByInstanceDevices(n.state, n.project, n.name, func(inst db.Instance, nicName string, nicConfig map[string]string) error {
+		ref := firewallDrivers.InstanceRef{Project: inst.Project, Name: inst.Name}
+		for _, existing := range instances {
+			if existing == ref {
+				return nil
+			}
+		}
+
+		instances = append(instances, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ruleset, err := n.state.Firewall.NetworkRuleset(n.name, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	fw := &api.NetworkFirewall{
+		Driver:        n.state.Firewall.String(),
+		Rules:         ruleset.Network,
+		InstanceRules: ruleset.Instances,
+		Warnings:      ruleset.Conflicts,
+	}
+
+	if n.isRunning() && n.needsFirewallRules() && len(ruleset.Network) == 0 {
+		fw.Warnings = append(fw.Warnings, fmt.Sprintf("No %s rules found for the network, they may have been removed by another tool", fw.Driver))
+	}
+
+	if len(fw.Warnings) > 0 {
+		err = n.state.Cluster.UpsertWarningLocalNode(n.project, dbCluster.TypeNetwork, int(n.id), db.WarningNetworkFirewallDrift, strings.Join(fw.Warnings, "; "))
+		if err != nil {
+			n.logger.Warn("Failed to create warning", log.Ctx{"err": err})
+		}
+	} else {
+		err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(n.state.Cluster, n.project, db.WarningNetworkFirewallDrift, dbCluster.TypeNetwork, int(n.id))
+		if err != nil {
+			n.logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+
+	return fw, nil
+}
+
+// Drift returns the differences between the host state of the bridge (interface, MTU, addresses, external
+// interfaces and firewall rules) and its configuration, such as those caused by changes made outside of LXD.
+func (n *bridge) Drift() ([]string, error) {
+	if !n.isRunning() {
+		return []string{fmt.Sprintf("Bridge interface %q is missing", n.name)}, nil
+	}
+
+	iface, err := net.InterfaceByName(n.name)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := []string{}
+
+	if iface.Flags&net.FlagUp == 0 {
+		drift = append(drift, fmt.Sprintf("Bridge interface %q is down", n.name))
+	}
+
+	// The MTU of fan bridges follows the underlay, so only check explicitly configured ones.
+	if n.config["bridge.mtu"] != "" && n.config["bridge.mode"] != "fan" && fmt.Sprintf("%d", iface.MTU) != n.config["bridge.mtu"] {
+		drift = append(drift, fmt.Sprintf("Bridge MTU is %d instead of %s", iface.MTU, n.config["bridge.mtu"]))
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		if key == "ipv4.address" && n.config["bridge.mode"] == "fan" {
+			continue
+		}
+
+		address, subnet, err := net.ParseCIDR(n.config[key])
+		if err != nil {
+			continue
+		}
+
+		expected := (&net.IPNet{IP: address, Mask: subnet.Mask}).String()
+		found := false
+		for _, addr := range addrs {
+			if addr.String() == expected {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			drift = append(drift, fmt.Sprintf("Bridge address %q is missing", expected))
+		}
+	}
+
+	for _, entry := range util.SplitNTrimSpace(n.config["bridge.external_interfaces"], ",", -1, true) {
+		if !InterfaceExists(entry) {
+			drift = append(drift, fmt.Sprintf("External interface %q is missing", entry))
+			continue
+		}
+
+		// Open vSwitch ports don't have the bridge as their master.
+		if n.config["bridge.driver"] == "openvswitch" {
+			continue
+		}
+
+		master, err := os.Readlink(fmt.Sprintf("/sys/class/net/%s/master", entry))
+		if err != nil || filepath.Base(master) != n.name {
+			drift = append(drift, fmt.Sprintf("External interface %q isn't attached to the bridge", entry))
+		}
+	}
+
+	if n.needsFirewallRules() {
+		ruleset, err := n.state.Firewall.NetworkRuleset(n.name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ruleset.Network) == 0 {
+			drift = append(drift, fmt.Sprintf("No %s rules found for the network", n.state.Firewall.String()))
+		}
+	}
+
+	return drift, nil
+}
+
+// needsFirewallRules indicates whether the network relies on host firewall rules.
+func (n *bridge) needsFirewallRules() bool {
+	return n.hasIPv4Firewall() || n.hasIPv6Firewall() || shared.IsTrue(n.config["ipv4.nat"]) || shared.IsTrue(n.config["ipv6.nat"])
+}
+
 // hasIPv4Firewall indicates whether the network has IPv4 firewall enabled.
 func (n *bridge) hasIPv4Firewall() bool {
 	// IPv4 firewall is only enabled if there is a bridge ipv4.address or fan mode, and ipv4.firewall enabled.
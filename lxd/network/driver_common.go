@@ -13,6 +13,7 @@ import (
 	"github.com/lxc/lxd/lxd/cluster/request"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -231,6 +232,22 @@ func (n *common) DHCPv6Ranges() []shared.IPRange {
 	return dhcpRanges
 }
 
+// Firewall returns the firewall rules generated for the network. Only bridge networks rely on the host firewall.
+func (n *common) Firewall() (*api.NetworkFirewall, error) {
+	return nil, fmt.Errorf("Network %q doesn't use the host firewall", n.name)
+}
+
+// Drift returns the differences between the host state of the network and its configuration.
+// Only the drivers managing host interfaces check for them.
+func (n *common) Drift() ([]string, error) {
+	return nil, nil
+}
+
+// State returns the state of the network's host interface.
+func (n *common) State() (*api.NetworkState, error) {
+	return resources.GetNetworkState(n.name)
+}
+
 // update the internal config variables, and if not cluster notification, notifies all nodes and updates database.
 func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientType request.ClientType) error {
 	// Update internal config before database has been updated (so that if update is a notification we apply
@@ -27,12 +27,13 @@ func (n *macvlan) DBType() db.NetworkType {
 // Validate network config.
 func (n *macvlan) Validate(config map[string]string) error {
 	rules := map[string]func(value string) error{
-		"parent":           validate.Required(validate.IsNotEmpty, validate.IsInterfaceName),
-		"mtu":              validate.Optional(validate.IsNetworkMTU),
-		"vlan":             validate.Optional(validate.IsNetworkVLAN),
-		"gvrp":             validate.Optional(validate.IsBool),
-		"maas.subnet.ipv4": validate.IsAny,
-		"maas.subnet.ipv6": validate.IsAny,
+		"parent":                 validate.Required(validate.IsNotEmpty, validate.IsInterfaceName),
+		"mtu":                    validate.Optional(validate.IsNetworkMTU),
+		"vlan":                   validate.Optional(validate.IsNetworkVLAN),
+		"gvrp":                   validate.Optional(validate.IsBool),
+		"maas.subnet.ipv4":       validate.IsAny,
+		"maas.subnet.ipv6":       validate.IsAny,
+		"security.mac_filtering": validate.Optional(validate.IsBool),
 	}
 
 	err := n.validate(config, rules)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"math/rand"
 	"net"
 	"sort"
 	"strconv"
@@ -33,6 +34,7 @@ import (
 )
 
 const ovnChassisPriorityMax = 32767
+const ovnQoSPriorityNIC = 100
 const ovnVolatileUplinkIPv4 = "volatile.network.ipv4.address"
 const ovnVolatileUplinkIPv6 = "volatile.network.ipv6.address"
 
@@ -187,9 +189,10 @@ func (n *ovn) validateExternalSubnet(uplinkRoutes []*net.IPNet, projectRestricte
 // Validate network config.
 func (n *ovn) Validate(config map[string]string) error {
 	rules := map[string]func(value string) error{
-		"network":       validate.IsAny,
-		"bridge.hwaddr": validate.Optional(validate.IsNetworkMAC),
-		"bridge.mtu":    validate.Optional(validate.IsNetworkMTU),
+		"network":         validate.IsAny,
+		"bridge.hwaddr":   validate.Optional(validate.IsNetworkMAC),
+		"bridge.mtu":      validate.Optional(validate.IsNetworkMTU),
+		"gateway.members": validate.IsAny,
 		"ipv4.address": validate.Optional(func(value string) error {
 			if validate.IsOneOf("none", "auto")(value) == nil {
 				return nil
@@ -1984,9 +1987,49 @@ func (n *ovn) ensureNetworkPortGroup(projectID int64) error {
 	return nil
 }
 
+// getGatewayMembers returns the cluster members preferred to host the network's gateway, in order of preference.
+// Taken from the gateway.members setting, or the uplink's ovn.gateway.members setting if not set.
+func (n *ovn) getGatewayMembers() ([]string, error) {
+	members := n.config["gateway.members"]
+	if members == "" {
+		_, uplink, _, err := n.state.Cluster.GetNetworkInAnyState(project.Default, n.config["network"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load uplink network %q", n.config["network"])
+		}
+
+		members = uplink.Config["ovn.gateway.members"]
+	}
+
+	return util.SplitNTrimSpace(members, ",", -1, true), nil
+}
+
+// ovnChassisPriority returns the chassis group priority of a cluster member.
+// The preferred members get the highest priorities in order of preference. The others get a stable random priority
+// below those, generated from the seeded generator in the order of the sorted member IDs.
+func ovnChassisPriority(r *rand.Rand, nodeIDs []int, ourNodeID int, ourNodeName string, preferred []string) uint {
+	for i, name := range preferred {
+		if name == ourNodeName {
+			return uint(ovnChassisPriorityMax - i)
+		}
+	}
+
+	// Generate a random priority from the seed for each node until we find a match for our node ID.
+	// In this way the chassis priority for this node will be set to a per-node stable random value.
+	var priority uint
+	for _, nodeID := range nodeIDs {
+		priority = uint(r.Intn(ovnChassisPriorityMax - len(preferred) + 1))
+		if nodeID == ourNodeID {
+			break
+		}
+	}
+
+	return priority
+}
+
 // addChassisGroupEntry adds an entry for the local OVS chassis to the OVN logical network's chassis group.
 // The chassis priority value is a stable-random value derived from chassis group name and node ID. This is so we
 // don't end up using the same chassis for the primary uplink chassis for all OVN networks in a cluster.
+// The members preferred to host the gateway get higher priorities than the others.
 func (n *ovn) addChassisGroupEntry() error {
 	client, err := openvswitch.NewOVN(n.state)
 	if err != nil {
@@ -2011,6 +2054,7 @@ func (n *ovn) addChassisGroupEntry() error {
 
 	// Get all nodes in cluster.
 	ourNodeID := int(n.state.Cluster.GetNodeID())
+	var ourNodeName string
 	var nodeIDs []int
 	err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		nodes, err := tx.GetNodes()
@@ -2020,6 +2064,10 @@ func (n *ovn) addChassisGroupEntry() error {
 
 		for _, node := range nodes {
 			nodeIDs = append(nodeIDs, int(node.ID))
+
+			if int(node.ID) == ourNodeID {
+				ourNodeName = node.Name
+			}
 		}
 
 		return nil
@@ -2031,16 +2079,13 @@ func (n *ovn) addChassisGroupEntry() error {
 	// Sort the nodes based on ID for stable priority generation.
 	sort.Sort(sort.IntSlice(nodeIDs))
 
-	// Generate a random priority from the seed for each node until we find a match for our node ID.
-	// In this way the chassis priority for this node will be set to a per-node stable random value.
-	var priority uint
-	for _, nodeID := range nodeIDs {
-		priority = uint(r.Intn(ovnChassisPriorityMax + 1))
-		if nodeID == ourNodeID {
-			break
-		}
+	preferred, err := n.getGatewayMembers()
+	if err != nil {
+		return err
 	}
 
+	priority := ovnChassisPriority(r, nodeIDs, ourNodeID, ourNodeName, preferred)
+
 	err = client.ChassisGroupChassisAdd(chassisGroupName, chassisID, priority)
 	if err != nil {
 		return errors.Wrapf(err, "Failed adding OVS chassis %q with priority %d to chassis group %q", chassisID, priority, chassisGroupName)
@@ -2290,6 +2335,14 @@ func (n *ovn) Update(newNetwork api.NetworkPut, targetNode string, clientType re
 		return err
 	}
 
+	// Each member updates the priority of its own chassis when the preferred gateway members change.
+	if shared.StringInSlice("gateway.members", changedKeys) {
+		err = n.addChassisGroupEntry()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Re-setup the logical network after config applied if needed.
 	if len(changedKeys) > 0 && clientType == request.ClientTypeNormal {
 		err = n.setup(true)
@@ -2962,6 +3015,27 @@ func (n *ovn) InstanceDevicePortSetup(opts *OVNInstanceNICSetupOpts, securityACL
 		n.logger.Debug("Cleared NIC default rule", log.Ctx{"port": instancePortName})
 	}
 
+	// Set the DSCP marking of the traffic leaving the port (clears it if not set).
+	qosRules := []openvswitch.OVNQoSRule{}
+	if opts.DeviceConfig["qos.dscp"] != "" {
+		dscp, err := strconv.ParseUint(opts.DeviceConfig["qos.dscp"], 10, 8)
+		if err != nil {
+			return "", errors.Wrapf(err, "Invalid DSCP value %q", opts.DeviceConfig["qos.dscp"])
+		}
+
+		qosRules = append(qosRules, openvswitch.OVNQoSRule{
+			Direction: "from-lport",
+			Match:     fmt.Sprintf(`inport == "%s"`, instancePortName),
+			Priority:  ovnQoSPriorityNIC,
+			DSCP:      uint8(dscp),
+		})
+	}
+
+	err = client.LogicalSwitchSetQoSRules(n.getIntSwitchName(), string(instancePortName), nil, qosRules...)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed applying OVN QoS rules for instance NIC")
+	}
+
 	revert.Success()
 	return instancePortName, nil
 }
@@ -3263,6 +3337,83 @@ func (n *ovn) uplinkHasIngressRoutedAnycastIPv6(uplink *api.Network) bool {
 	return shared.IsTrue(uplink.Config["ipv6.routes.anycast"]) && uplink.Config["ovn.ingress_mode"] == "routed"
 }
 
+// State returns the state of the logical network, including the chassis currently hosting its gateway.
+func (n *ovn) State() (*api.NetworkState, error) {
+	client, err := openvswitch.NewOVN(n.state)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get OVN client")
+	}
+
+	chassis, err := client.Chassis()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed getting OVN chassis")
+	}
+
+	hostnamesByUUID := map[string]string{}
+	hostnamesByName := map[string]string{}
+	for _, c := range chassis {
+		hostnamesByUUID[c.UUID] = c.Hostname
+		hostnamesByName[c.Name] = c.Hostname
+	}
+
+	activeChassis, err := client.LogicalRouterPortActiveChassis(n.getRouterExtPortName())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed getting chassis hosting the gateway")
+	}
+
+	priorities, err := client.ChassisGroupChassis(n.getChassisGroupName())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed getting chassis group entries")
+	}
+
+	gateways := make([]api.NetworkStateOVNGateway, 0, len(priorities))
+	for name, priority := range priorities {
+		hostname, found := hostnamesByName[name]
+		if !found {
+			hostname = name
+		}
+
+		gateways = append(gateways, api.NetworkStateOVNGateway{Chassis: hostname, Priority: priority})
+	}
+
+	sort.Slice(gateways, func(i, j int) bool {
+		return gateways[i].Priority > gateways[j].Priority
+	})
+
+	addresses := []api.NetworkStateAddress{}
+	for _, key := range []string{"ipv4.address", "ipv6.address"} {
+		ip, subnet, err := net.ParseCIDR(n.config[key])
+		if err != nil {
+			continue
+		}
+
+		family := "inet"
+		if ip.To4() == nil {
+			family = "inet6"
+		}
+
+		ones, _ := subnet.Mask.Size()
+		addresses = append(addresses, api.NetworkStateAddress{
+			Family:  family,
+			Address: ip.String(),
+			Netmask: fmt.Sprintf("%d", ones),
+			Scope:   "global",
+		})
+	}
+
+	return &api.NetworkState{
+		Addresses: addresses,
+		Hwaddr:    n.config["bridge.hwaddr"],
+		Mtu:       int(n.getBridgeMTU()),
+		State:     "up",
+		Type:      "broadcast",
+		OVN: &api.NetworkStateOVN{
+			Chassis:  hostnamesByUUID[activeChassis],
+			Gateways: gateways,
+		},
+	}, nil
+}
+
 // handleDependencyChange applies changes from uplink network if specific watched keys have changed.
 func (n *ovn) handleDependencyChange(uplinkName string, uplinkConfig map[string]string, changedKeys []string) error {
 	// Detect changes that need to be applied to the network.
@@ -3280,6 +3431,17 @@ func (n *ovn) handleDependencyChange(uplinkName string, uplinkConfig map[string]
 		}
 	}
 
+	// Update the priority of the local chassis if the uplink's preferred gateway members have changed and the
+	// network doesn't override them.
+	if shared.StringInSlice("ovn.gateway.members", changedKeys) && n.config["gateway.members"] == "" {
+		n.logger.Debug("Applying gateway members changes from uplink network", log.Ctx{"uplink": uplinkName})
+
+		err := n.addChassisGroupEntry()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Add or remove the instance NIC l2proxy DNAT_AND_SNAT rules if uplink's ovn.ingress_mode has changed.
 	if shared.StringInSlice("ovn.ingress_mode", changedKeys) {
 		n.logger.Debug("Applying ingress mode changes from uplink network to instance NICs", log.Ctx{"uplink": uplinkName})
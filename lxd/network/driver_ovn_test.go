@@ -0,0 +1,30 @@
+package network
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/lxc/lxd/shared"
+)
+
+func Example_ovnChassisPriority() {
+	nodeIDs := []int{1, 2, 3, 4}
+	nodeNames := map[int]string{1: "lxd01", 2: "lxd02", 3: "lxd03", 4: "lxd04"}
+	preferred := []string{"lxd03", "lxd01"}
+
+	for _, nodeID := range nodeIDs {
+		r := rand.New(rand.NewSource(1))
+		priority := ovnChassisPriority(r, nodeIDs, nodeID, nodeNames[nodeID], preferred)
+
+		if shared.StringInSlice(nodeNames[nodeID], preferred) {
+			fmt.Printf("%s: %d\n", nodeNames[nodeID], priority)
+		} else {
+			fmt.Printf("%s: below preferred %t\n", nodeNames[nodeID], priority < ovnChassisPriorityMax-1)
+		}
+	}
+
+	// Output: lxd01: 32766
+	// lxd02: below preferred true
+	// lxd03: 32767
+	// lxd04: below preferred true
+}
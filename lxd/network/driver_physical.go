@@ -2,7 +2,9 @@ package network
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -11,6 +13,7 @@ import (
 	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/ip"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/warnings"
 	"github.com/lxc/lxd/shared"
@@ -53,6 +56,7 @@ func (n *physical) Validate(config map[string]string) error {
 		"ipv6.routes.anycast":         validate.Optional(validate.IsBool),
 		"dns.nameservers":             validate.Optional(validate.IsNetworkAddressList),
 		"ovn.ingress_mode":            validate.Optional(validate.IsOneOf("l2proxy", "routed")),
+		"ovn.gateway.members":         validate.IsAny,
 		"volatile.last_state.created": validate.Optional(validate.IsBool),
 	}
 
@@ -200,6 +204,13 @@ func (n *physical) start() error {
 		}
 	}
 
+	// Listen for LLDP frames on the parent interface (VLAN interfaces don't receive them) to report the
+	// switch it's connected to. This is informational only, so don't fail if it can't be done.
+	err = lldpListenerStart(n.name, n.config["parent"])
+	if err != nil {
+		n.logger.Warn("Failed starting LLDP listener", log.Ctx{"interface": n.config["parent"], "err": err})
+	}
+
 	revert.Success()
 	return nil
 }
@@ -208,6 +219,8 @@ func (n *physical) start() error {
 func (n *physical) Stop() error {
 	n.logger.Debug("Stop")
 
+	lldpListenerStop(n.name, n.config["parent"])
+
 	hostName := GetHostDevice(n.config["parent"], n.config["vlan"])
 
 	// Only try and remove created VLAN interfaces.
@@ -318,11 +331,64 @@ func (n *physical) Update(newNetwork api.NetworkPut, targetNode string, clientTy
 	// doesn't prevent the network itself from being updated.
 	if clientType == request.ClientTypeNormal && len(changedKeys) > 0 {
 		n.common.notifyDependentNetworks(changedKeys)
+	} else if clientType == request.ClientTypeNotifier && shared.StringInSlice("ovn.gateway.members", changedKeys) {
+		// Each member updates the gateway priority of its own chassis.
+		n.common.notifyDependentNetworks([]string{"ovn.gateway.members"})
 	}
 
 	return nil
 }
 
+// State returns the state of the network's host interface along with the LLDP neighbours of its parent.
+func (n *physical) State() (*api.NetworkState, error) {
+	state, err := resources.GetNetworkState(GetHostDevice(n.config["parent"], n.config["vlan"]))
+	if err != nil {
+		return nil, err
+	}
+
+	state.LLDP = lldpNeighbours(n.config["parent"])
+
+	return state, nil
+}
+
+// Drift returns the differences between the host state of the network's interface (existence, VLAN and MTU) and
+// its configuration, such as those caused by changes made outside of LXD.
+func (n *physical) Drift() ([]string, error) {
+	hostName := GetHostDevice(n.config["parent"], n.config["vlan"])
+	if !InterfaceExists(hostName) {
+		return []string{fmt.Sprintf("Interface %q is missing", hostName)}, nil
+	}
+
+	drift := []string{}
+
+	if n.config["vlan"] != "" {
+		// The VLAN interfaces are listed as "<name> VID: <vlan> REORDER_HDR: ..." on the first line.
+		content, err := ioutil.ReadFile(fmt.Sprintf("/proc/net/vlan/%s", hostName))
+		if err == nil {
+			fields := strings.Fields(string(content))
+			for i, field := range fields {
+				if field == "VID:" && i+1 < len(fields) && fields[i+1] != n.config["vlan"] {
+					drift = append(drift, fmt.Sprintf("Interface %q uses VLAN %s instead of %s", hostName, fields[i+1], n.config["vlan"]))
+					break
+				}
+			}
+		}
+	}
+
+	if n.config["mtu"] != "" {
+		mtu, err := GetDevMTU(hostName)
+		if err != nil {
+			return nil, err
+		}
+
+		if fmt.Sprintf("%d", mtu) != n.config["mtu"] {
+			drift = append(drift, fmt.Sprintf("Interface %q MTU is %d instead of %s", hostName, mtu, n.config["mtu"]))
+		}
+	}
+
+	return drift, nil
+}
+
 // DHCPv4Subnet returns the DHCPv4 subnet (if DHCP is enabled on network).
 func (n *physical) DHCPv4Subnet() *net.IPNet {
 	_, subnet, err := net.ParseCIDR(n.config["ipv4.gateway"])
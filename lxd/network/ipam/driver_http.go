@@ -0,0 +1,116 @@
+package ipam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// httpBackend delegates the allocations to an external plugin (for example a bridge to Netbox or phpIPAM).
+//
+// The plugin is sent the request as JSON through a POST to <url>/allocate, answering with the allocated address
+// as {"address": "<ip>"} (or an empty address to leave it to DHCP), and through a POST to <url>/release.
+type httpBackend struct {
+	url   string
+	token string
+}
+
+func newHTTP(pluginURL string, token string) (*httpBackend, error) {
+	if pluginURL == "" {
+		return nil, fmt.Errorf(`The "ipam.url" key is required by the %q IPAM driver`, DriverHTTP)
+	}
+
+	u, err := url.Parse(pluginURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid IPAM plugin URL %q", pluginURL)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid IPAM plugin URL %q: scheme must be http or https", pluginURL)
+	}
+
+	return &httpBackend{url: strings.TrimSuffix(pluginURL, "/"), token: token}, nil
+}
+
+// Allocate asks the plugin for an address of the subnet.
+func (b *httpBackend) Allocate(req Request, subnet *net.IPNet) (net.IP, error) {
+	resp := struct {
+		Address string `json:"address"`
+	}{}
+
+	err := b.query("allocate", req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Address == "" {
+		return nil, nil
+	}
+
+	ip := net.ParseIP(resp.Address)
+	if ip == nil {
+		return nil, fmt.Errorf("IPAM plugin returned an invalid address %q", resp.Address)
+	}
+
+	if !subnet.Contains(ip) {
+		return nil, fmt.Errorf("IPAM plugin returned address %q outside of subnet %q", ip, subnet)
+	}
+
+	return ip, nil
+}
+
+// Release tells the plugin the address isn't used anymore.
+func (b *httpBackend) Release(req Request) error {
+	return b.query("release", req, nil)
+}
+
+// query sends the request to the plugin endpoint and decodes the response into resp if not nil.
+func (b *httpBackend) query(endpoint string, req Request, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/%s", b.url, endpoint), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", b.token))
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return errors.Wrapf(err, "Failed contacting IPAM plugin")
+	}
+
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(httpResp.Body, 4096))
+		return fmt.Errorf("IPAM plugin %s failed with %q: %s", endpoint, httpResp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(httpResp.Body).Decode(resp)
+	if err != nil {
+		return errors.Wrapf(err, "Failed parsing IPAM plugin response")
+	}
+
+	return nil
+}
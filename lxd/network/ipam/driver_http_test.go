@@ -0,0 +1,80 @@
+package ipam
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPBackend(t *testing.T) {
+	released := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		req := Request{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch r.URL.Path {
+		case "/allocate":
+			address := map[string]string{"eth0": "10.0.0.10", "eth1": "192.168.0.10", "eth2": ""}[req.Device]
+			json.NewEncoder(w).Encode(map[string]string{"address": address})
+		case "/release":
+			released = append(released, req.Address)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+
+	backend, err := Load(map[string]string{"ipam.driver": "http", "ipam.url": server.URL + "/", "ipam.token": "secret"})
+	require.NoError(t, err)
+
+	ip, err := backend.Allocate(Request{Device: "eth0", Subnet: subnet.String()}, subnet)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.10", ip.String())
+
+	// Addresses outside of the subnet are refused.
+	_, err = backend.Allocate(Request{Device: "eth1", Subnet: subnet.String()}, subnet)
+	assert.Error(t, err)
+
+	// An empty address leaves the allocation to DHCP.
+	ip, err = backend.Allocate(Request{Device: "eth2", Subnet: subnet.String()}, subnet)
+	require.NoError(t, err)
+	assert.Nil(t, ip)
+
+	require.NoError(t, backend.Release(Request{Device: "eth0", Address: "10.0.0.10"}))
+	assert.Equal(t, []string{"10.0.0.10"}, released)
+
+	// Plugin errors are reported.
+	backend, err = Load(map[string]string{"ipam.driver": "http", "ipam.url": server.URL})
+	require.NoError(t, err)
+
+	_, err = backend.Allocate(Request{Device: "eth0"}, subnet)
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	backend, err := Load(map[string]string{})
+	require.NoError(t, err)
+	assert.Nil(t, backend)
+
+	_, err = Load(map[string]string{"ipam.driver": "http"})
+	assert.Error(t, err)
+
+	_, err = Load(map[string]string{"ipam.driver": "http", "ipam.url": "ftp://example.com"})
+	assert.Error(t, err)
+
+	_, err = Load(map[string]string{"ipam.driver": "foo"})
+	assert.Error(t, err)
+}
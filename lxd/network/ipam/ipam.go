@@ -0,0 +1,50 @@
+package ipam
+
+import (
+	"fmt"
+	"net"
+)
+
+// DriverDnsmasq is the built-in IPAM driver, leaving the dynamic allocations to the dnsmasq ranges.
+const DriverDnsmasq = "dnsmasq"
+
+// DriverHTTP is the IPAM driver delegating the allocations to an external HTTP plugin.
+const DriverHTTP = "http"
+
+// Drivers lists the supported IPAM drivers.
+var Drivers = []string{DriverDnsmasq, DriverHTTP}
+
+// Request represents an address allocation or release request for an instance NIC.
+type Request struct {
+	Network  string `json:"network"`
+	Project  string `json:"project"`
+	Instance string `json:"instance"`
+	Device   string `json:"device"`
+	MAC      string `json:"hwaddr"`
+	Subnet   string `json:"subnet"`
+
+	// Address is only set for releases.
+	Address string `json:"address,omitempty"`
+}
+
+// Backend represents an IPAM source of truth.
+type Backend interface {
+	// Allocate returns an address of the subnet for the NIC. A nil address means the backend leaves the
+	// allocation to the network's DHCP server.
+	Allocate(req Request, subnet *net.IPNet) (net.IP, error)
+
+	// Release gives back an address previously allocated for the NIC.
+	Release(req Request) error
+}
+
+// Load returns the IPAM backend configured on the network. Returns nil when the built-in dnsmasq ranges are used.
+func Load(config map[string]string) (Backend, error) {
+	switch config["ipam.driver"] {
+	case "", DriverDnsmasq:
+		return nil, nil
+	case DriverHTTP:
+		return newHTTP(config["ipam.url"], config["ipam.token"])
+	}
+
+	return nil, fmt.Errorf("Unknown IPAM driver %q", config["ipam.driver"])
+}
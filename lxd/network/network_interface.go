@@ -42,6 +42,9 @@ type Network interface {
 	DHCPv6Subnet() *net.IPNet
 	DHCPv4Ranges() []shared.IPRange
 	DHCPv6Ranges() []shared.IPRange
+	Firewall() (*api.NetworkFirewall, error)
+	Drift() ([]string, error)
+	State() (*api.NetworkState, error)
 
 	// Actions.
 	Create(clientType request.ClientType) error
@@ -439,7 +439,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 				}
 			}
 
-			entries[d["parent"]] = append(entries[d["parent"]], []string{d["hwaddr"], inst.Project(), inst.Name(), d["ipv4.address"], d["ipv6.address"]})
+			entries[d["parent"]] = append(entries[d["parent"]], []string{d["hwaddr"], inst.Project(), inst.Name(), d["ipv4.address"], d["ipv6.address"], d["dns.name"], d["dns.aliases"]})
 		}
 	}
 
@@ -473,6 +473,18 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			}
 		}
 
+		files, err = ioutil.ReadDir(shared.VarPath("networks", network, "dnsmasq.aliases"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for _, entry := range files {
+			err = os.Remove(shared.VarPath("networks", network, "dnsmasq.aliases", entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Apply the changes.
 		for entryIdx, entry := range entries {
 			hwaddr := entry[0]
@@ -516,7 +528,12 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			}
 
 			// Generate the dhcp-host line.
-			err := dnsmasq.UpdateStaticEntry(network, projectName, cName, config, hwaddr, ipv4Address, ipv6Address)
+			err := dnsmasq.UpdateStaticEntry(network, projectName, cName, entry[5], config, hwaddr, ipv4Address, ipv6Address)
+			if err != nil {
+				return err
+			}
+
+			err = dnsmasq.UpdateAliasesEntry(network, projectName, cName, config, util.SplitNTrimSpace(entry[6], ",", -1, true), ipv4Address, ipv6Address)
 			if err != nil {
 				return err
 			}
@@ -947,6 +964,11 @@ func usesIPv4Firewall(netConfig map[string]string) bool {
 		return true
 	}
 
+	// The IPv4 pool of the NAT64 translator is masqueraded.
+	if shared.IsTrue(netConfig["ipv6.nat64"]) {
+		return true
+	}
+
 	return false
 }
 
@@ -26,7 +26,33 @@ func BridgeVLANFilteringStatus(interfaceName string) (string, error) {
 func BridgeVLANFilterSetStatus(interfaceName string, status string) error {
 	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/vlan_filtering", interfaceName), []byte(status), 0)
 	if err != nil {
-		return errors.Wrapf(err, "Failed enabling VLAN filtering on bridge %q", interfaceName)
+		return errors.Wrapf(err, "Failed setting VLAN filtering on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetSTP enables or disables the spanning tree protocol on a bridge interface.
+func BridgeSetSTP(interfaceName string, enabled bool) error {
+	status := "0"
+	if enabled {
+		status = "1"
+	}
+
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/stp_state", interfaceName), []byte(status), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting STP on bridge %q", interfaceName)
+	}
+
+	return nil
+}
+
+// BridgeSetForwardDelay sets the forward delay (in seconds) of a bridge interface.
+func BridgeSetForwardDelay(interfaceName string, seconds uint64) error {
+	// The kernel expects the delay in hundredths of a second.
+	err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/forward_delay", interfaceName), []byte(fmt.Sprintf("%d", seconds*100)), 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed setting forward delay on bridge %q", interfaceName)
 	}
 
 	return nil
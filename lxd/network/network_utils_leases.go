@@ -0,0 +1,209 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/fsnotify.v0"
+
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// leaseMonitorDelay is how long to wait for dnsmasq to finish writing the leases file before reading it.
+const leaseMonitorDelay = 100 * time.Millisecond
+
+// leaseMonitors holds the stop channel of the running DHCP lease monitors, keyed by network name.
+var leaseMonitors = map[string]chan struct{}{}
+var leaseMonitorsMu sync.Mutex
+
+// dhcpLease represents a dynamic lease from the dnsmasq leases file.
+type dhcpLease struct {
+	Expiry   string
+	Hwaddr   string
+	Address  string
+	Hostname string
+}
+
+// dhcpLeasesLoad returns the dynamic leases of a network, keyed by address.
+func dhcpLeasesLoad(networkName string) (map[string]dhcpLease, error) {
+	leases := map[string]dhcpLease{}
+
+	content, err := ioutil.ReadFile(shared.VarPath("networks", networkName, "dnsmasq.leases"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return leases, nil
+		}
+
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		// Parse the MAC (IPv6 leases only have it at the end of the client ID).
+		hwaddr := strings.Join(GetMACSlice(fields[1]), ":")
+		if len(hwaddr) < 17 && len(fields[4]) >= 17 {
+			hwaddr = fields[4][len(fields[4])-17:]
+		}
+
+		leases[fields[2]] = dhcpLease{
+			Expiry:   fields[0],
+			Hwaddr:   hwaddr,
+			Address:  fields[2],
+			Hostname: fields[3],
+		}
+	}
+
+	return leases, nil
+}
+
+// dhcpLeaseInstance returns the project and name of the instance using the given MAC address on a network, based
+// on the dnsmasq static host entries. Returns empty strings if not found.
+func dhcpLeaseInstance(networkName string, hwaddr string) (string, string) {
+	hostsPath := shared.VarPath("networks", networkName, "dnsmasq.hosts")
+
+	entries, err := ioutil.ReadDir(hostsPath)
+	if err != nil {
+		return "", ""
+	}
+
+	for _, entry := range entries {
+		content, err := ioutil.ReadFile(filepath.Join(hostsPath, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		if strings.SplitN(strings.TrimSpace(string(content)), ",", 2)[0] == strings.ToLower(hwaddr) {
+			projectName, instanceName := project.InstanceParts(entry.Name())
+			return projectName, instanceName
+		}
+	}
+
+	return "", ""
+}
+
+// leaseMonitorStart starts watching the dnsmasq leases file of the bridge and emits lifecycle events when a DHCP
+// lease is issued, renewed or released. Does nothing if the monitor is already running.
+func leaseMonitorStart(n *bridge) error {
+	leaseMonitorsMu.Lock()
+	defer leaseMonitorsMu.Unlock()
+
+	_, found := leaseMonitors[n.name]
+	if found {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = watcher.Watch(shared.VarPath("networks", n.name))
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	leases, err := dhcpLeasesLoad(n.name)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	stop := make(chan struct{})
+	leaseMonitors[n.name] = stop
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(leaseMonitorDelay)
+		timer.Stop()
+
+		for {
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case ev := <-watcher.Event:
+				if filepath.Base(ev.Name) == "dnsmasq.leases" {
+					timer.Reset(leaseMonitorDelay)
+				}
+			case err := <-watcher.Error:
+				n.logger.Warn("Failed watching DHCP leases", log.Ctx{"err": err})
+			case <-timer.C:
+				current, err := dhcpLeasesLoad(n.name)
+				if err != nil {
+					n.logger.Warn("Failed loading DHCP leases", log.Ctx{"err": err})
+					continue
+				}
+
+				leaseMonitorNotify(n, leases, current)
+				leases = current
+			}
+		}
+	}()
+
+	return nil
+}
+
+// leaseMonitorStop stops the DHCP lease monitor of the network if running.
+func leaseMonitorStop(networkName string) {
+	leaseMonitorsMu.Lock()
+	defer leaseMonitorsMu.Unlock()
+
+	stop, found := leaseMonitors[networkName]
+	if !found {
+		return
+	}
+
+	close(stop)
+	delete(leaseMonitors, networkName)
+}
+
+// leaseMonitorNotify emits the lifecycle events for the differences between the old and the new leases.
+func leaseMonitorNotify(n *bridge, old map[string]dhcpLease, current map[string]dhcpLease) {
+	send := func(action lifecycle.NetworkAction, lease dhcpLease) {
+		ctx := map[string]interface{}{
+			"address":  lease.Address,
+			"hwaddr":   lease.Hwaddr,
+			"hostname": lease.Hostname,
+		}
+
+		// Send the event to the project of the instance if known, so it reaches its project scoped listeners.
+		eventProject := n.project
+		projectName, instanceName := dhcpLeaseInstance(n.name, lease.Hwaddr)
+		if instanceName != "" {
+			ctx["project"] = projectName
+			ctx["instance"] = instanceName
+			eventProject = projectName
+		}
+
+		n.state.Events.SendLifecycle(eventProject, action.Event(n, nil, ctx))
+	}
+
+	for address, lease := range current {
+		oldLease, found := old[address]
+		if !found || oldLease.Hwaddr != lease.Hwaddr {
+			send(lifecycle.NetworkLeaseIssued, lease)
+		} else if oldLease.Expiry != lease.Expiry {
+			send(lifecycle.NetworkLeaseRenewed, lease)
+		}
+	}
+
+	for address, lease := range old {
+		_, found := current[address]
+		if !found {
+			send(lifecycle.NetworkLeaseReleased, lease)
+		}
+	}
+}
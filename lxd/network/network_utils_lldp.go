@@ -0,0 +1,324 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// lldpMulticastMAC is the nearest bridge group address that LLDP frames are sent to.
+var lldpMulticastMAC = [8]byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+
+// LLDP TLV types.
+const (
+	lldpTLVEnd               = 0
+	lldpTLVChassisID         = 1
+	lldpTLVPortID            = 2
+	lldpTLVTTL               = 3
+	lldpTLVPortDescription   = 4
+	lldpTLVSystemName        = 5
+	lldpTLVSystemDescription = 6
+	lldpTLVManagementAddress = 8
+	lldpTLVOrganization      = 127
+)
+
+// lldpOUI8021 is the IEEE 802.1 organizationally unique identifier used for the VLAN TLVs.
+var lldpOUI8021 = []byte{0x00, 0x80, 0xc2}
+
+// lldpNeighbour represents a neighbour decoded from an LLDP frame.
+type lldpNeighbour struct {
+	api.NetworkStateLLDP
+
+	TTL time.Duration // How long the information is valid for (zero if the neighbour is going away).
+}
+
+// lldpListener holds the state of the LLDP listener of an interface.
+type lldpListener struct {
+	users      map[string]struct{} // Names of the networks using the listener.
+	file       *os.File
+	mu         sync.Mutex
+	neighbours map[string]*lldpNeighbour // Keyed by chassis and port ID.
+}
+
+// lldpListeners holds the running LLDP listeners, keyed by interface name.
+var lldpListeners = map[string]*lldpListener{}
+var lldpListenersMu sync.Mutex
+
+// lldpParse decodes an LLDP data unit (without the ethernet header).
+func lldpParse(data []byte) (*lldpNeighbour, error) {
+	neighbour := &lldpNeighbour{}
+	hasTTL := false
+
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("Truncated TLV header")
+		}
+
+		tlvType := data[0] >> 1
+		tlvLen := int(binary.BigEndian.Uint16(data[0:2]) & 0x1ff)
+		data = data[2:]
+
+		if len(data) < tlvLen {
+			return nil, fmt.Errorf("Truncated TLV of type %d", tlvType)
+		}
+
+		value := data[:tlvLen]
+		data = data[tlvLen:]
+
+		switch tlvType {
+		case lldpTLVEnd:
+			data = nil
+		case lldpTLVChassisID:
+			if len(value) < 2 {
+				return nil, fmt.Errorf("Invalid chassis ID")
+			}
+
+			neighbour.ChassisID = lldpFormatID(value[0], 4, 5, value[1:])
+		case lldpTLVPortID:
+			if len(value) < 2 {
+				return nil, fmt.Errorf("Invalid port ID")
+			}
+
+			neighbour.PortID = lldpFormatID(value[0], 3, 4, value[1:])
+		case lldpTLVTTL:
+			if len(value) != 2 {
+				return nil, fmt.Errorf("Invalid TTL")
+			}
+
+			neighbour.TTL = time.Duration(binary.BigEndian.Uint16(value)) * time.Second
+			hasTTL = true
+		case lldpTLVPortDescription:
+			neighbour.PortDescription = lldpString(value)
+		case lldpTLVSystemName:
+			neighbour.SystemName = lldpString(value)
+		case lldpTLVSystemDescription:
+			neighbour.SystemDescription = lldpString(value)
+		case lldpTLVManagementAddress:
+			// Address string length (including the subtype) followed by the address subtype and the address.
+			if len(value) < 2 || int(value[0]) < 2 || len(value) < int(value[0])+1 {
+				continue
+			}
+
+			address := lldpFormatAddress(value[1], value[2:int(value[0])+1])
+			if address != "" {
+				neighbour.ManagementAddresses = append(neighbour.ManagementAddresses, address)
+			}
+		case lldpTLVOrganization:
+			if len(value) < 4 || !bytes.Equal(value[0:3], lldpOUI8021) {
+				continue
+			}
+
+			switch value[3] {
+			case 1: // Port VLAN ID.
+				if len(value) >= 6 {
+					neighbour.PortVLAN = uint64(binary.BigEndian.Uint16(value[4:6]))
+				}
+			case 3: // VLAN name (VLAN ID, name length and name).
+				if len(value) >= 6 {
+					neighbour.VLANs = append(neighbour.VLANs, uint64(binary.BigEndian.Uint16(value[4:6])))
+				}
+			}
+		}
+	}
+
+	if neighbour.ChassisID == "" || neighbour.PortID == "" || !hasTTL {
+		return nil, fmt.Errorf("Missing mandatory TLVs")
+	}
+
+	return neighbour, nil
+}
+
+// lldpFormatID formats a chassis or port ID based on its subtype. IDs using the MAC address or network address
+// subtypes are formatted as such, all others are treated as strings.
+func lldpFormatID(subtype byte, macSubtype byte, addressSubtype byte, id []byte) string {
+	switch subtype {
+	case macSubtype:
+		if len(id) == 6 {
+			return net.HardwareAddr(id).String()
+		}
+	case addressSubtype:
+		if len(id) > 1 {
+			address := lldpFormatAddress(id[0], id[1:])
+			if address != "" {
+				return address
+			}
+		}
+	}
+
+	return lldpString(id)
+}
+
+// lldpFormatAddress formats an IPv4 or IPv6 address using the IANA address family numbers used by LLDP.
+// Returns an empty string for other families.
+func lldpFormatAddress(family byte, address []byte) string {
+	if (family == 1 && len(address) == net.IPv4len) || (family == 2 && len(address) == net.IPv6len) {
+		return net.IP(address).String()
+	}
+
+	return ""
+}
+
+// lldpString returns the printable version of a string value.
+func lldpString(value []byte) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, string(value)))
+}
+
+// lldpListenerStart starts listening for LLDP frames on the interface for the network. The listener is shared
+// between the networks using the same interface and runs until all of them called lldpListenerStop.
+func lldpListenerStart(networkName string, ifaceName string) error {
+	lldpListenersMu.Lock()
+	defer lldpListenersMu.Unlock()
+
+	listener, found := lldpListeners[ifaceName]
+	if found {
+		listener.users[networkName] = struct{}{}
+		return nil
+	}
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return err
+	}
+
+	// ETH_P_LLDP in network byte order.
+	proto := (unix.ETH_P_LLDP&0xff)<<8 | unix.ETH_P_LLDP>>8
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, proto)
+	if err != nil {
+		return errors.Wrapf(err, "Failed creating LLDP socket")
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: uint16(proto), Ifindex: iface.Index})
+	if err != nil {
+		unix.Close(fd)
+		return errors.Wrapf(err, "Failed binding LLDP socket")
+	}
+
+	// Receive the frames sent to the LLDP multicast address (not passed up by the NIC otherwise).
+	err = unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &unix.PacketMreq{
+		Ifindex: int32(iface.Index),
+		Type:    unix.PACKET_MR_MULTICAST,
+		Alen:    6,
+		Address: lldpMulticastMAC,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return errors.Wrapf(err, "Failed joining LLDP multicast group")
+	}
+
+	listener = &lldpListener{
+		users:      map[string]struct{}{networkName: {}},
+		file:       os.NewFile(uintptr(fd), fmt.Sprintf("lldp-%s", ifaceName)),
+		neighbours: map[string]*lldpNeighbour{},
+	}
+
+	lldpListeners[ifaceName] = listener
+
+	go func() {
+		buf := make([]byte, 1500)
+
+		for {
+			n, err := listener.file.Read(buf)
+			if err != nil {
+				// Closed by lldpListenerStop.
+				return
+			}
+
+			neighbour, err := lldpParse(buf[:n])
+			if err != nil {
+				logger.Debug("Ignoring invalid LLDP frame", log.Ctx{"interface": ifaceName, "err": err})
+				continue
+			}
+
+			neighbour.Interface = ifaceName
+			neighbour.LastSeen = time.Now()
+			key := fmt.Sprintf("%s/%s", neighbour.ChassisID, neighbour.PortID)
+
+			listener.mu.Lock()
+			if neighbour.TTL == 0 {
+				delete(listener.neighbours, key)
+			} else {
+				listener.neighbours[key] = neighbour
+			}
+			listener.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// lldpListenerStop stops listening for LLDP frames on the interface for the network, unless other networks still
+// use it.
+func lldpListenerStop(networkName string, ifaceName string) {
+	lldpListenersMu.Lock()
+	defer lldpListenersMu.Unlock()
+
+	listener, found := lldpListeners[ifaceName]
+	if !found {
+		return
+	}
+
+	delete(listener.users, networkName)
+	if len(listener.users) > 0 {
+		return
+	}
+
+	listener.file.Close()
+	delete(lldpListeners, ifaceName)
+}
+
+// lldpNeighbours returns the neighbours discovered on the interfaces whose information hasn't expired yet.
+func lldpNeighbours(ifaceNames ...string) []api.NetworkStateLLDP {
+	lldpListenersMu.Lock()
+	defer lldpListenersMu.Unlock()
+
+	neighbours := []api.NetworkStateLLDP{}
+	now := time.Now()
+
+	for _, ifaceName := range ifaceNames {
+		listener, found := lldpListeners[ifaceName]
+		if !found {
+			continue
+		}
+
+		listener.mu.Lock()
+		for key, neighbour := range listener.neighbours {
+			if neighbour.LastSeen.Add(neighbour.TTL).Before(now) {
+				delete(listener.neighbours, key)
+				continue
+			}
+
+			neighbours = append(neighbours, neighbour.NetworkStateLLDP)
+		}
+		listener.mu.Unlock()
+	}
+
+	sort.Slice(neighbours, func(i, j int) bool {
+		if neighbours[i].Interface != neighbours[j].Interface {
+			return neighbours[i].Interface < neighbours[j].Interface
+		}
+
+		return neighbours[i].ChassisID < neighbours[j].ChassisID
+	})
+
+	return neighbours
+}
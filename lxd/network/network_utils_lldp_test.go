@@ -0,0 +1,42 @@
+package network
+
+import (
+	"fmt"
+)
+
+func Example_lldpParse() {
+	tlv := func(tlvType int, value ...byte) []byte {
+		return append([]byte{byte(tlvType<<1 | len(value)>>8), byte(len(value))}, value...)
+	}
+
+	frame := []byte{}
+	frame = append(frame, tlv(1, append([]byte{4}, 0x00, 0x16, 0x3e, 0x5a, 0x83, 0x57)...)...)      // MAC chassis ID.
+	frame = append(frame, tlv(2, append([]byte{5}, []byte("Ethernet12")...)...)...)                 // Interface name port ID.
+	frame = append(frame, tlv(3, 0x00, 0x78)...)                                                    // TTL of 120s.
+	frame = append(frame, tlv(4, []byte("server01 uplink")...)...)                                  // Port description.
+	frame = append(frame, tlv(5, []byte("switch01")...)...)                                         // System name.
+	frame = append(frame, tlv(8, 5, 1, 10, 0, 0, 2, 2, 0, 0, 0, 1, 0)...)                           // IPv4 management address.
+	frame = append(frame, tlv(127, 0x00, 0x80, 0xc2, 1, 0x00, 0x01)...)                             // Port VLAN ID 1.
+	frame = append(frame, tlv(127, 0x00, 0x80, 0xc2, 3, 0x00, 0x64, 4, 'p', 'r', 'o', 'd')...)      // VLAN 100.
+	frame = append(frame, tlv(127, 0x00, 0x80, 0xc2, 3, 0x00, 0xc8, 5, 's', 't', 'a', 'g', 'e')...) // VLAN 200.
+	frame = append(frame, tlv(0)...)
+
+	neighbour, err := lldpParse(frame)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(neighbour.ChassisID, neighbour.PortID, neighbour.TTL)
+	fmt.Println(neighbour.SystemName, neighbour.PortDescription)
+	fmt.Println(neighbour.PortVLAN, neighbour.VLANs, neighbour.ManagementAddresses)
+
+	// Frames missing mandatory TLVs (here the chassis ID) are rejected.
+	_, err = lldpParse(frame[9:])
+	fmt.Println(err)
+
+	// Output: 00:16:3e:5a:83:57 Ethernet12 2m0s
+	// switch01 server01 uplink
+	// 1 [100 200] [10.0.0.2]
+	// Missing mandatory TLVs
+}
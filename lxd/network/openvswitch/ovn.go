@@ -69,6 +69,7 @@ const ovnExtIDLXDSwitch = "lxd_switch"
 const ovnExtIDLXDSwitchPort = "lxd_switch_port"
 const ovnExtIDLXDProjectID = "lxd_project_id"
 const ovnExtIDLXDPortGroup = "lxd_port_group"
+const ovnExtIDLXDQoSOwner = "lxd_qos_owner"
 
 // ErrOVNNoPortIPs used when no IPs are found for a logical port.
 var ErrOVNNoPortIPs = fmt.Errorf("No port IPs")
@@ -126,6 +127,14 @@ type OVNACLRule struct {
 	LogName   string // Log label name (requires Log be true).
 }
 
+// OVNQoSRule represents a QoS rule that sets the DSCP of matching packets on a logical switch.
+type OVNQoSRule struct {
+	Direction string // Either "from-lport" or "to-lport".
+	Match     string // Match criteria. See OVN Southbound database's Logical_Flow table match column usage.
+	Priority  int    // Priority (between 0 and 32767, inclusive). Higher values take precedence.
+	DSCP      uint8  // DSCP value (between 0 and 63, inclusive) to set on matching packets.
+}
+
 // NewOVN initialises new OVN client wrapper with the connection set in network.ovn.northbound_connection config.
 func NewOVN(s *state.State) (*OVN, error) {
 	nbConnection, err := cluster.ConfigGetString(s.Cluster, "network.ovn.northbound_connection")
@@ -139,9 +148,17 @@ func NewOVN(s *state.State) (*OVN, error) {
 	return client, err
 }
 
+// OVNChassis represents an OVN chassis registered in the southbound database.
+type OVNChassis struct {
+	UUID     string
+	Name     string
+	Hostname string
+}
+
 // OVN command wrapper.
 type OVN struct {
-	dbAddr string
+	dbAddr   string
+	sbDBAddr string
 }
 
 // SetDatabaseAddress sets the address that runs the OVN northbound and southbound databases.
@@ -168,6 +185,25 @@ func (o *OVN) nbctl(args ...string) (string, error) {
 	return shared.RunCommand("ovn-nbctl", append([]string{"--db", dbAddr}, args...)...)
 }
 
+// sbctl executes ovn-sbctl with arguments to connect to the southbound database used by the local chassis.
+func (o *OVN) sbctl(args ...string) (string, error) {
+	if o.sbDBAddr == "" {
+		ovs := NewOVS()
+		dbAddr, err := ovs.OVNSouthboundDBRemoteAddress()
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed getting OVN southbound database address")
+		}
+
+		if strings.HasPrefix(dbAddr, "unix:") {
+			dbAddr = fmt.Sprintf("unix:%s", shared.HostPathFollow(strings.TrimPrefix(dbAddr, "unix:")))
+		}
+
+		o.sbDBAddr = dbAddr
+	}
+
+	return shared.RunCommand("ovn-sbctl", append([]string{"--db", o.sbDBAddr}, args...)...)
+}
+
 // LogicalRouterAdd adds a named logical router.
 func (o *OVN) LogicalRouterAdd(routerName OVNRouter, mayExist bool) error {
 	args := []string{}
@@ -812,6 +848,98 @@ func (o *OVN) LogicalSwitchSetACLRules(switchName OVNSwitch, aclRules ...OVNACLR
 	return nil
 }
 
+// LogicalSwitchSetQoSRules applies a set of QoS rules belonging to owner to the specified logical switch. Any
+// existing rules of that owner on the switch are removed.
+func (o *OVN) LogicalSwitchSetQoSRules(switchName OVNSwitch, owner string, matchReplace map[string]string, qosRules ...OVNQoSRule) error {
+	// Remove any existing rules of the owner assigned to the switch.
+	output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--colum=_uuid", "find", "qos",
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDQoSOwner, owner),
+		fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitch, string(switchName)),
+	)
+	if err != nil {
+		return err
+	}
+
+	args := []string{}
+	for _, qosRuleUUID := range util.SplitNTrimSpace(strings.TrimSpace(output), "\n", -1, true) {
+		if len(args) > 0 {
+			args = append(args, "--")
+		}
+
+		args = append(args, "remove", "logical_switch", string(switchName), "qos_rules", qosRuleUUID)
+	}
+
+	// Add new rules.
+	for i, rule := range qosRules {
+		if len(args) > 0 {
+			args = append(args, "--")
+		}
+
+		// Perform any replacements requested on the Match string.
+		for find, replace := range matchReplace {
+			rule.Match = strings.ReplaceAll(rule.Match, find, replace)
+		}
+
+		args = append(args, fmt.Sprintf("--id=@qos%d", i), "create", "qos",
+			fmt.Sprintf("direction=%s", rule.Direction),
+			fmt.Sprintf("priority=%d", rule.Priority),
+			fmt.Sprintf("match=%s", strconv.Quote(rule.Match)),
+			fmt.Sprintf("action:dscp=%d", rule.DSCP),
+			fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDQoSOwner, owner),
+			fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDSwitch, string(switchName)),
+			"--", "add", "logical_switch", string(switchName), "qos_rules", fmt.Sprintf("@qos%d", i),
+		)
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, err = o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// qosRuleDeleteAppendArgs adds the commands to args that delete the QoS rules belonging to the specified owners
+// from whichever logical switches they are assigned to.
+// Returns args with the QoS rule delete commands added to it.
+func (o *OVN) qosRuleDeleteAppendArgs(args []string, owners ...string) ([]string, error) {
+	for _, owner := range owners {
+		output, err := o.nbctl("--format=csv", "--no-headings", "--data=bare", "--colum=_uuid,external_ids", "find", "qos",
+			fmt.Sprintf("external_ids:%s=%s", ovnExtIDLXDQoSOwner, owner),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range util.SplitNTrimSpace(strings.TrimSpace(output), "\n", -1, true) {
+			// E.g. "2a2f6b3c-...,lxd_qos_owner=lxd_acl1 lxd_switch=lxd-net2-ls-int"
+			fields := strings.SplitN(line, ",", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("Too few columns in output")
+			}
+
+			for _, externalID := range strings.Fields(fields[1]) {
+				if !strings.HasPrefix(externalID, fmt.Sprintf("%s=", ovnExtIDLXDSwitch)) {
+					continue
+				}
+
+				if len(args) > 0 {
+					args = append(args, "--")
+				}
+
+				switchName := strings.TrimPrefix(externalID, fmt.Sprintf("%s=", ovnExtIDLXDSwitch))
+				args = append(args, "--if-exists", "remove", "logical_switch", switchName, "qos_rules", fields[0])
+			}
+		}
+	}
+
+	return args, nil
+}
+
 // logicalSwitchPortACLRules returns the ACL rule UUIDs belonging to a logical switch port.
 func (o *OVN) logicalSwitchPortACLRules(portName OVNSwitchPort) ([]string, error) {
 	// Remove any existing rules assigned to the entity.
@@ -1161,6 +1289,12 @@ func (o *OVN) LogicalSwitchPortCleanup(portName OVNSwitchPort, switchName OVNSwi
 
 	args := o.aclRuleDeleteAppendArgs(nil, "port_group", string(switchPortGroupName), removeACLRuleUUIDs)
 
+	// Remove any QoS rules belonging to the port.
+	args, err = o.qosRuleDeleteAppendArgs(args, string(portName))
+	if err != nil {
+		return err
+	}
+
 	// Remove logical switch port.
 	args = o.logicalSwitchPortDeleteAppendArgs(args, portName)
 
@@ -1283,6 +1417,81 @@ func (o *OVN) ChassisGroupChassisDelete(haChassisGroupName OVNChassisGroup, chas
 	return nil
 }
 
+// ChassisGroupChassis returns the priorities of the chassis in an HA chassis group, indexed by chassis name.
+func (o *OVN) ChassisGroupChassis(haChassisGroupName OVNChassisGroup) (map[string]uint, error) {
+	uuids, err := o.nbctl("--no-headings", "--data=bare", "--colum=ha_chassis", "find", "ha_chassis_group", fmt.Sprintf("name=%s", string(haChassisGroupName)))
+	if err != nil {
+		return nil, err
+	}
+
+	chassis := map[string]uint{}
+
+	uuids = strings.TrimSpace(uuids)
+	if uuids == "" {
+		return chassis, nil
+	}
+
+	output, err := o.nbctl(append([]string{"--format=csv", "--no-headings", "--data=bare", "--colum=chassis_name,priority", "list", "ha_chassis"}, strings.Fields(uuids)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Unrecognised HA chassis %q", line)
+		}
+
+		priority, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid priority of HA chassis %q", fields[0])
+		}
+
+		chassis[fields[0]] = uint(priority)
+	}
+
+	return chassis, nil
+}
+
+// Chassis returns the chassis registered in the southbound database.
+func (o *OVN) Chassis() ([]OVNChassis, error) {
+	output, err := o.sbctl("--format=csv", "--no-headings", "--data=bare", "--colum=_uuid,name,hostname", "list", "chassis")
+	if err != nil {
+		return nil, err
+	}
+
+	chassis := []OVNChassis{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Unrecognised chassis %q", line)
+		}
+
+		chassis = append(chassis, OVNChassis{UUID: fields[0], Name: fields[1], Hostname: fields[2]})
+	}
+
+	return chassis, nil
+}
+
+// LogicalRouterPortActiveChassis returns the UUID of the chassis currently hosting the gateway of a router port
+// linked to an HA chassis group, or empty string if none is.
+func (o *OVN) LogicalRouterPortActiveChassis(portName OVNRouterPort) (string, error) {
+	chassisUUID, err := o.sbctl("--no-headings", "--data=bare", "--colum=chassis", "find", "port_binding", fmt.Sprintf("logical_port=cr-%s", string(portName)))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(chassisUUID), nil
+}
+
 // PortGroupInfo returns the port group UUID or empty string if port doesn't exist, and whether the port group has
 // any ACL rules defined on it.
 func (o *OVN) PortGroupInfo(portGroupName OVNPortGroup) (OVNPortGroupUUID, bool, error) {
@@ -1339,6 +1548,14 @@ func (o *OVN) PortGroupDelete(portGroupNames ...OVNPortGroup) error {
 	args := make([]string, 0)
 
 	for _, portGroupName := range portGroupNames {
+		var err error
+
+		// Remove any QoS rules matching on the port group first.
+		args, err = o.qosRuleDeleteAppendArgs(args, string(portGroupName))
+		if err != nil {
+			return err
+		}
+
 		if len(args) > 0 {
 			args = append(args, "--")
 		}
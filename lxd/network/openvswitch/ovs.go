@@ -91,6 +91,16 @@ func (o *OVS) BridgeDelete(bridgeName string) error {
 	return nil
 }
 
+// BridgeSet sets bridge options.
+func (o *OVS) BridgeSet(bridgeName string, options ...string) error {
+	_, err := shared.RunCommand("ovs-vsctl", append([]string{"set", "bridge", bridgeName}, options...)...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // BridgePortAdd adds a port to the bridge (if already attached does nothing).
 func (o *OVS) BridgePortAdd(bridgeName string, portName string, mayExist bool) error {
 	args := []string{}
@@ -192,6 +202,22 @@ func (o *OVS) ChassisID() (string, error) {
 	return chassisID, nil
 }
 
+// OVNSouthboundDBRemoteAddress returns the address of the OVN southbound database used by the local chassis.
+func (o *OVS) OVNSouthboundDBRemoteAddress() (string, error) {
+	// ovs-vsctl's get command doesn't support its --format flag, so we always get the output quoted.
+	result, err := shared.RunCommand("ovs-vsctl", "get", "open_vswitch", ".", "external_ids:ovn-remote")
+	if err != nil {
+		return "", err
+	}
+
+	addr, err := unquote(strings.TrimSpace(result))
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed unquoting")
+	}
+
+	return addr, nil
+}
+
 // OVNEncapIP returns the enscapsulation IP used for OVN underlay tunnels.
 func (o *OVS) OVNEncapIP() (net.IP, error) {
 	// ovs-vsctl's get command doesn't support its --format flag, so we always get the output quoted.
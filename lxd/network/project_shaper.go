@@ -0,0 +1,144 @@
+package network
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/ip"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// ProjectShaperKeys are the project configuration keys holding the aggregate network limits of its instances,
+// keyed by traffic direction (from the instances' point of view).
+var ProjectShaperKeys = map[string]string{
+	"ingress": "limits.network.ingress",
+	"egress":  "limits.network.egress",
+}
+
+// ProjectShaperName returns the name of the IFB device shaping the traffic of the project's instances in the
+// given direction ("ingress" or "egress").
+func ProjectShaperName(projectName string, direction string) string {
+	hash := sha256.Sum256([]byte(projectName))
+	return fmt.Sprintf("lxdp%s%x", direction[:1], hash[:4])
+}
+
+// ProjectShaperSetup ensures the IFB devices shaping the aggregate traffic of the project's instances exist on the
+// local host and apply the limits from the project's configuration.
+// Returns the devices the ingress and egress traffic of the instances should be redirected to (empty if unlimited).
+func ProjectShaperSetup(projectName string, config map[string]string) (string, string, error) {
+	devices := map[string]string{}
+
+	for direction, key := range ProjectShaperKeys {
+		dev, err := projectShaperSetupDirection(projectName, direction, config[key], true)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "Failed setting up %s shaper for project %q", direction, projectName)
+		}
+
+		devices[direction] = dev
+	}
+
+	return devices["ingress"], devices["egress"], nil
+}
+
+// ProjectShaperUpdate applies the project's limits to its existing IFB devices on the local host.
+// It doesn't create any missing device, those are created when an instance NIC of the project starts.
+func ProjectShaperUpdate(projectName string, config map[string]string) error {
+	for direction, key := range ProjectShaperKeys {
+		_, err := projectShaperSetupDirection(projectName, direction, config[key], false)
+		if err != nil {
+			return errors.Wrapf(err, "Failed updating %s shaper for project %q", direction, projectName)
+		}
+	}
+
+	return nil
+}
+
+// ProjectShaperDelete removes the IFB devices of the project from the local host.
+func ProjectShaperDelete(projectName string) error {
+	for direction := range ProjectShaperKeys {
+		name := ProjectShaperName(projectName, direction)
+		if !InterfaceExists(name) {
+			continue
+		}
+
+		ifb := &ip.IFB{Link: ip.Link{Name: name}}
+		err := ifb.Delete()
+		if err != nil {
+			return errors.Wrapf(err, "Failed deleting %q", name)
+		}
+	}
+
+	return nil
+}
+
+// projectShaperSetupDirection configures the IFB device of one direction of the project's traffic.
+// When the limit is unset, the device is left in place without shaping as instance NICs may still be
+// redirecting their traffic to it.
+func projectShaperSetupDirection(projectName string, direction string, limit string, create bool) (string, error) {
+	name := ProjectShaperName(projectName, direction)
+	exists := InterfaceExists(name)
+
+	if limit == "" {
+		if exists {
+			qdisc := &ip.Qdisc{Dev: name, Root: true}
+			qdisc.Delete()
+		}
+
+		return "", nil
+	}
+
+	if !exists && !create {
+		return "", nil
+	}
+
+	rate, err := units.ParseBitSizeString(limit)
+	if err != nil {
+		return "", err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	if !exists {
+		ifb := &ip.IFB{Link: ip.Link{Name: name}}
+		err = ifb.Add()
+		if err != nil {
+			return "", err
+		}
+
+		revert.Add(func() { ifb.Delete() })
+
+		err = ifb.SetUp()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Update the rate of the existing class, keeping the queued packets.
+	class := &ip.ClassHTB{Class: ip.Class{Dev: name, Parent: "1:0", Classid: "1:10"}, Rate: fmt.Sprintf("%dbit", rate)}
+	if exists && class.Replace() == nil {
+		revert.Success()
+		return name, nil
+	}
+
+	// Otherwise (re)create the shaping token bucket.
+	qdisc := &ip.Qdisc{Dev: name, Root: true}
+	qdisc.Delete()
+
+	qdiscHTB := &ip.QdiscHTB{Qdisc: ip.Qdisc{Dev: name, Handle: "1:0", Root: true}, Default: "10"}
+	err = qdiscHTB.Add()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create root tc qdisc")
+	}
+
+	err = class.Add()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create limit tc class")
+	}
+
+	revert.Success()
+	return name, nil
+}
@@ -0,0 +1,20 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectShaperName(t *testing.T) {
+	ingress := ProjectShaperName("a-very-long-project-name", "ingress")
+	egress := ProjectShaperName("a-very-long-project-name", "egress")
+
+	// Interface names are limited to 15 characters.
+	assert.Len(t, ingress, 13)
+	assert.Equal(t, "lxdpi", ingress[:5])
+	assert.Equal(t, "lxdpe", egress[:5])
+	assert.Equal(t, ingress[5:], egress[5:])
+
+	assert.NotEqual(t, ingress, ProjectShaperName("default", "ingress"))
+}
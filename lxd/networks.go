@@ -20,6 +20,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/device/nictype"
+	"github.com/lxc/lxd/lxd/filter"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/lifecycle"
 	"github.com/lxc/lxd/lxd/network"
@@ -65,6 +66,12 @@ var networkLeasesCmd = APIEndpoint{
 	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowProjectPermission("networks", "view")},
 }
 
+var networkFirewallCmd = APIEndpoint{
+	Path: "networks/{name}/firewall",
+
+	Get: APIEndpointAction{Handler: networkFirewallGet, AccessHandler: allowProjectPermission("networks", "view")},
+}
+
 var networkStateCmd = APIEndpoint{
 	Path: "networks/{name}/state",
 
@@ -88,6 +95,11 @@ var networkStateCmd = APIEndpoint{
 //     description: Project name
 //     type: string
 //     example: default
+//   - in: query
+//     name: filter
+//     description: Collection filter
+//     type: string
+//     example: default
 // responses:
 //   "200":
 //     description: API endpoints
@@ -137,6 +149,11 @@ var networkStateCmd = APIEndpoint{
 //     description: Project name
 //     type: string
 //     example: default
+//   - in: query
+//     name: filter
+//     description: Collection filter
+//     type: string
+//     example: default
 // responses:
 //   "200":
 //     description: API endpoints
@@ -173,6 +190,17 @@ func networksGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := util.IsRecursionRequest(r)
 
+	// Parse filter value
+	var clauses []filter.Clause
+
+	filterStr := r.FormValue("filter")
+	if filterStr != "" {
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
 	// Get list of managed networks (that may or may not have network interfaces on the host).
 	networks, err := d.cluster.GetNetworks(projectName)
 	if err != nil {
@@ -199,16 +227,32 @@ func networksGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	// Only consider the network the filter is about, if any.
+	name, ok := filter.ExactValue(clauses, "name")
+	if ok {
+		if shared.StringInSlice(name, networks) {
+			networks = []string{name}
+		} else {
+			networks = []string{}
+		}
+	}
+
 	resultString := []string{}
 	resultMap := []api.Network{}
 	for _, network := range networks {
-		if !recursion {
+		if !recursion && clauses == nil {
 			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, network))
 		} else {
 			net, err := doNetworkGet(d, r, projectName, network)
 			if err != nil {
 				continue
 			}
+
+			if clauses != nil && !filter.Match(net, clauses) {
+				continue
+			}
+
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, network))
 			resultMap = append(resultMap, net)
 		}
 	}
@@ -1649,12 +1693,106 @@ func networkStateGet(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
-	state, err := resources.GetNetworkState(name)
+	// Managed networks report their own state (OVN networks don't have a host interface).
+	var state *api.NetworkState
+	n, err := network.LoadByName(d.State(), projectName, name)
+	if err == nil {
+		state, err = n.State()
+	} else if err == db.ErrNoSuchObject {
+		state, err = resources.GetNetworkState(name)
+	}
+
 	if err != nil {
 		return response.SmartError(err)
 	}
 
 	return response.SyncResponse(true, state)
 }
+
+// swagger:operation GET /1.0/networks/{name}/firewall networks networks_firewall_get
+//
+// Get the network firewall rules
+//
+// Returns the firewall rules generated on the cluster member for the network and the instances connected to it,
+// along with warnings about rules which were removed or may be overridden by other tools.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: project
+//     description: Project name
+//     type: string
+//     example: default
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+// responses:
+//   "200":
+//     description: API endpoints
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/NetworkFirewall"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "404":
+//     $ref: "#/responses/NotFound"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+
+func networkFirewallGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, _, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	name := mux.Vars(r)["name"]
+
+	n, err := network.LoadByName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only bridge networks rely on the host firewall.
+	if n.Type() != "bridge" {
+		return response.NotFound(fmt.Errorf("Firewall rules not found"))
+	}
+
+	fw, err := n.Firewall()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, fw)
+}
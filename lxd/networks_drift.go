@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/warnings"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// networkDriftInterval is how often the host state of the networks is compared with their configuration.
+const networkDriftInterval = 5 * time.Minute
+
+// networkDriftTask periodically checks that the host state of the local networks (interfaces, addresses, MTU,
+// VLANs and firewall rules) still matches their configuration, raising a warning for the networks altered outside
+// of LXD. When network.reconcile_drift is enabled, those networks are restarted to restore their configuration.
+func networkDriftTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		if d.os.MockMode {
+			return
+		}
+
+		reconcile := false
+		err := d.db.Transaction(func(tx *db.NodeTx) error {
+			config, err := node.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			reconcile = config.NetworkReconcileDrift()
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to load node config", log.Ctx{"err": err})
+			return
+		}
+
+		networkDriftCheck(d.State(), reconcile)
+	}
+
+	return f, task.Every(networkDriftInterval)
+}
+
+// networkDriftCheck checks the local networks for drift, updating their warnings and reconciling them if requested.
+func networkDriftCheck(s *state.State, reconcile bool) {
+	var projectNames []string
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectNames, err = tx.GetProjectNames()
+		return err
+	})
+	if err != nil {
+		logger.Error("Failed to load projects", log.Ctx{"err": err})
+		return
+	}
+
+	for _, projectName := range projectNames {
+		networks, err := s.Cluster.GetCreatedNetworks(projectName)
+		if err != nil {
+			logger.Error("Failed to load networks", log.Ctx{"project": projectName, "err": err})
+			continue
+		}
+
+		for _, name := range networks {
+			n, err := network.LoadByName(s, projectName, name)
+			if err != nil {
+				logger.Error("Failed to load network", log.Ctx{"project": projectName, "network": name, "err": err})
+				continue
+			}
+
+			if n.Status() != api.NetworkStatusCreated {
+				continue
+			}
+
+			drift, err := n.Drift()
+			if err != nil {
+				logger.Warn("Failed checking network for drift", log.Ctx{"project": projectName, "network": name, "err": err})
+				continue
+			}
+
+			if len(drift) > 0 && reconcile {
+				logger.Warn("Restarting network altered outside of LXD", log.Ctx{"project": projectName, "network": name, "drift": drift})
+
+				err = n.Start()
+				if err != nil {
+					logger.Error("Failed to restart network", log.Ctx{"project": projectName, "network": name, "err": err})
+				} else {
+					// Keep the warning if the restart didn't restore the configuration.
+					drift, err = n.Drift()
+					if err != nil {
+						logger.Warn("Failed checking network for drift", log.Ctx{"project": projectName, "network": name, "err": err})
+						continue
+					}
+				}
+			}
+
+			if len(drift) > 0 {
+				if !reconcile {
+					logger.Warn("Network altered outside of LXD", log.Ctx{"project": projectName, "network": name, "drift": drift})
+				}
+
+				err = s.Cluster.UpsertWarningLocalNode(projectName, dbCluster.TypeNetwork, int(n.ID()), db.WarningNetworkConfigDrift, strings.Join(drift, "; "))
+				if err != nil {
+					logger.Warn("Failed to create warning", log.Ctx{"project": projectName, "network": name, "err": err})
+				}
+			} else {
+				err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, projectName, db.WarningNetworkConfigDrift, dbCluster.TypeNetwork, int(n.ID()))
+				if err != nil {
+					logger.Warn("Failed to resolve warning", log.Ctx{"project": projectName, "network": name, "err": err})
+				}
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package node
 
 import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/config"
@@ -44,6 +48,17 @@ func (c *Config) ClusterAddress() string {
 	return c.m.GetString("cluster.https_address")
 }
 
+// ClusterReplicaStaleness returns how many seconds old the local replica of the global database may be to serve
+// the read-only API queries (0 disables it).
+func (c *Config) ClusterReplicaStaleness() int64 {
+	return c.m.GetInt64("cluster.replica_staleness")
+}
+
+// ACMEAddress returns the address and port to setup the ACME HTTP-01 challenge listener on
+func (c *Config) ACMEAddress() string {
+	return c.m.GetString("acme.http_address")
+}
+
 // DebugAddress returns the address and port to setup the pprof listener on
 func (c *Config) DebugAddress() string {
 	return c.m.GetString("core.debug_address")
@@ -55,6 +70,17 @@ func (c *Config) MAASMachine() string {
 	return c.m.GetString("maas.machine")
 }
 
+// BackupsDatabaseInterval returns the interval in hours at which to automatically back up the database
+// (0 disables it).
+func (c *Config) BackupsDatabaseInterval() int64 {
+	return c.m.GetInt64("backups.database_interval")
+}
+
+// BackupsDatabaseRetention returns the number of automatic database backups to keep.
+func (c *Config) BackupsDatabaseRetention() int64 {
+	return c.m.GetInt64("backups.database_retention")
+}
+
 // StorageBackupsVolume returns the name of the pool/volume to use for storing backup tarballs
 func (c *Config) StorageBackupsVolume() string {
 	return c.m.GetString("storage.backups_volume")
@@ -65,6 +91,62 @@ func (c *Config) StorageImagesVolume() string {
 	return c.m.GetString("storage.images_volume")
 }
 
+// StorageSkipUnavailablePools returns whether storage pools which fail to mount at startup should be skipped
+// rather than prevent LXD from starting.
+func (c *Config) StorageSkipUnavailablePools() bool {
+	return c.m.GetBool("storage.skip_unavailable_pools")
+}
+
+// StorageImagesDedup returns whether the unpacked image volumes should share their identical blocks through
+// reflinks on the drivers supporting them.
+func (c *Config) StorageImagesDedup() bool {
+	return c.m.GetBool("storage.images_dedup")
+}
+
+// InstancesVirtioDriversISO returns the path to the virtio-win drivers ISO attached to virtual machines which
+// request it.
+func (c *Config) InstancesVirtioDriversISO() string {
+	return c.m.GetString("instances.virtio_drivers_iso")
+}
+
+// InstancesIdmapManage returns whether LXD may grow its allocation in /etc/subuid and /etc/subgid when isolated
+// instances don't fit in it.
+func (c *Config) InstancesIdmapManage() bool {
+	return c.m.GetBool("instances.idmap_manage")
+}
+
+// InstancesIdmapMaxSize returns the size up to which LXD may grow its allocation in /etc/subuid and /etc/subgid.
+func (c *Config) InstancesIdmapMaxSize() int64 {
+	return c.m.GetInt64("instances.idmap_max_size")
+}
+
+// NetworkFirewallMode returns the firewall manager of the host LXD networks are registered with, if any.
+func (c *Config) NetworkFirewallMode() string {
+	return c.m.GetString("network.firewall_mode")
+}
+
+// NetworkReconcileDrift returns whether networks whose host state drifted from their configuration should be
+// restarted to reconcile it.
+func (c *Config) NetworkReconcileDrift() bool {
+	return c.m.GetBool("network.reconcile_drift")
+}
+
+// OperationsMaxImageDownloads returns how many image downloads may run at once on this member (0 for no limit).
+func (c *Config) OperationsMaxImageDownloads() int64 {
+	return c.m.GetInt64("operations.max_image_downloads")
+}
+
+// OperationsMaxMigrations returns how many incoming migrations may run at once on this member (0 for no limit).
+func (c *Config) OperationsMaxMigrations() int64 {
+	return c.m.GetInt64("operations.max_migrations")
+}
+
+// OperationsMaxBackups returns how many backup creations and restores may run at once on this member (0 for no
+// limit).
+func (c *Config) OperationsMaxBackups() int64 {
+	return c.m.GetInt64("operations.max_backups")
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -118,6 +200,22 @@ func ClusterAddress(node *db.Node) (string, error) {
 	return config.ClusterAddress(), nil
 }
 
+// ACMEAddress is a convenience for loading the node configuration and
+// returning the value of acme.http_address.
+func ACMEAddress(node *db.Node) (string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return config.ACMEAddress(), nil
+}
+
 // DebugAddress is a convenience for loading the node configuration and
 // returning the value of core.debug_address.
 func DebugAddress(node *db.Node) (string, error) {
@@ -156,13 +254,68 @@ var ConfigSchema = config.Schema{
 	// Network address for cluster communication
 	"cluster.https_address": {Validator: validate.Optional(validate.IsListenAddress(true, false, false))},
 
+	// Staleness bound of the local replica of the global database
+	"cluster.replica_staleness": {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+
+	// Network address for the plain HTTP server answering the ACME HTTP-01 challenges
+	"acme.http_address": {Validator: validate.Optional(validate.IsListenAddress(true, true, false))},
+
 	// Network address for the debug server
 	"core.debug_address": {Validator: validate.Optional(validate.IsListenAddress(true, true, false))},
 
+	// Automatic database backups
+	"backups.database_interval":  {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+	"backups.database_retention": {Type: config.Int64, Default: "7", Validator: databaseRetentionValidator},
+
 	// MAAS machine this LXD instance is associated with
 	"maas.machine": {},
 
 	// Storage volumes to store backups/images on
 	"storage.backups_volume": {},
 	"storage.images_volume":  {},
+
+	// Whether to skip storage pools which can't be mounted at startup
+	"storage.skip_unavailable_pools": {Type: config.Bool},
+
+	// Whether to deduplicate the unpacked image volumes
+	"storage.images_dedup": {Type: config.Bool},
+
+	// Firewall manager of the host to cooperate with
+	"network.firewall_mode": {Validator: validate.Optional(validate.IsOneOf("firewalld", "ufw"))},
+
+	// Whether to restart networks altered outside of LXD
+	"network.reconcile_drift": {Type: config.Bool},
+
+	// Drivers ISO for Windows virtual machines
+	"instances.virtio_drivers_iso": {Validator: validate.Optional(absolutePathValidator)},
+
+	// Whether LXD may grow its own allocation in /etc/subuid and /etc/subgid, and up to which size
+	"instances.idmap_manage":   {Type: config.Bool},
+	"instances.idmap_max_size": {Type: config.Int64, Default: "1000000000", Validator: validate.IsUint32},
+
+	// Maximum number of concurrent operations of each class, any others being queued
+	"operations.max_image_downloads": {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+	"operations.max_migrations":      {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+	"operations.max_backups":         {Type: config.Int64, Default: "0", Validator: validate.IsUint32},
+}
+
+func databaseRetentionValidator(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Value is not a number")
+	}
+
+	if n < 1 {
+		return fmt.Errorf("Value must be at least 1")
+	}
+
+	return nil
+}
+
+func absolutePathValidator(value string) error {
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("Value must be an absolute path")
+	}
+
+	return nil
 }
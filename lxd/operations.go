@@ -51,6 +51,13 @@ var operationWebsocket = APIEndpoint{
 	Get: APIEndpointAction{Handler: operationWebsocketGet, AllowUntrusted: true},
 }
 
+// operationsSetQueueLimits applies the limits on concurrent operations from the member configuration.
+func operationsSetQueueLimits(config *node.Config) {
+	operations.SetQueueLimit(operations.QueueImageDownloads, int(config.OperationsMaxImageDownloads()))
+	operations.SetQueueLimit(operations.QueueMigrations, int(config.OperationsMaxMigrations()))
+	operations.SetQueueLimit(operations.QueueBackups, int(config.OperationsMaxBackups()))
+}
+
 // waitForOperations waits for operations to finish. There's a timeout for console/exec operations
 // that when reached will shut down the instances forcefully.
 // It also watches the cancel channel, and will return if it receives data.
@@ -361,6 +368,16 @@ func operationCancel(d *Daemon, r *http.Request, projectName string, op *api.Ope
 // ---
 // produces:
 //   - application/json
+// parameters:
+//   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
 // responses:
 //   "200":
 //     description: API endpoints
@@ -407,6 +424,16 @@ func operationCancel(d *Daemon, r *http.Request, projectName string, op *api.Ope
 // ---
 // produces:
 //   - application/json
+// parameters:
+//   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
 // responses:
 //   "200":
 //     description: API endpoints
@@ -514,9 +541,17 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		return response.SyncResponse(true, body)
 	}
 
+	page, err := paginationParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if page.IsSet() {
+		return operationsGetPage(d, r, projectName, recursion, page)
+	}
+
 	// Start with local operations
 	var md shared.Jmap
-	var err error
 
 	if recursion {
 		md, err = localOperations()
@@ -606,6 +641,96 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, md)
 }
 
+// operationsGetPage returns the operations of the project which are part of the given page, grouped by status.
+func operationsGetPage(d *Daemon, r *http.Request, projectName string, recursion bool, page db.Pagination) response.Response {
+	var uuids map[string][]string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		uuids, err = tx.GetOperationUUIDsByNodeAddress(projectName, page)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	localAddress, err := node.HTTPSAddress(d.db)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	md := shared.Jmap{}
+	appendOp := func(op *api.Operation) {
+		status := strings.ToLower(op.Status)
+
+		_, ok := md[status]
+		if !ok {
+			if recursion {
+				md[status] = make([]*api.Operation, 0)
+			} else {
+				md[status] = make([]string, 0)
+			}
+		}
+
+		if recursion {
+			md[status] = append(md[status].([]*api.Operation), op)
+		} else {
+			md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
+		}
+	}
+
+	count := 0
+	last := ""
+	networkCert := d.endpoints.NetworkCert()
+	for address, opUUIDs := range uuids {
+		count += len(opUUIDs)
+		if opUUIDs[len(opUUIDs)-1] > last {
+			last = opUUIDs[len(opUUIDs)-1]
+		}
+
+		if !clustered || address == localAddress {
+			for _, opUUID := range opUUIDs {
+				op, err := operations.OperationGetInternal(opUUID)
+				if err != nil {
+					continue // The operation has completed in the meantime.
+				}
+
+				_, apiOp, err := op.Render()
+				if err != nil {
+					return response.InternalError(err)
+				}
+
+				appendOp(apiOp)
+			}
+
+			continue
+		}
+
+		// Connect to the remote server.
+		client, err := cluster.Connect(address, networkCert, d.serverCert(), r, true)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		client = client.UseProject(projectName)
+
+		for _, opUUID := range opUUIDs {
+			apiOp, _, err := client.GetOperation(opUUID)
+			if err != nil {
+				continue // The operation has completed in the meantime.
+			}
+
+			appendOp(apiOp)
+		}
+	}
+
+	return paginatedResponse(page, count, last, md)
+}
+
 // operationsGetByType gets all operations for a project and type.
 func operationsGetByType(d *Daemon, r *http.Request, projectName string, opType db.OperationType) ([]*api.Operation, error) {
 	ops := make([]*api.Operation, 0)
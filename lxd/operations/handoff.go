@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// TokenState represents a token operation passed on to the next LXD process on reload.
+type TokenState struct {
+	ID        string                 `json:"id"`
+	Project   string                 `json:"project"`
+	Type      db.OperationType       `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Resources map[string][]string    `json:"resources"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// Tokens returns the state of the outstanding token operations.
+func Tokens() []TokenState {
+	tokens := []TokenState{}
+
+	for _, op := range Clone() {
+		if op.Class() != OperationClassToken || op.Status() != api.Running {
+			continue
+		}
+
+		op.lock.Lock()
+		tokens = append(tokens, TokenState{
+			ID:        op.id,
+			Project:   op.projectName,
+			Type:      op.dbOpType,
+			CreatedAt: op.createdAt,
+			Resources: op.resources,
+			Metadata:  op.metadata,
+		})
+		op.lock.Unlock()
+	}
+
+	return tokens
+}
+
+// RestoreToken recreates a token operation passed on by the previous LXD process, keeping its ID so that the
+// tokens handed out remain valid.
+func RestoreToken(s *state.State, token TokenState) (*Operation, error) {
+	op, err := operationCreate(s, token.ID, token.CreatedAt, token.Project, OperationClassToken, token.Type, token.Resources, token.Metadata, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = op.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed restoring token operation %q", token.ID)
+	}
+
+	return op, nil
+}
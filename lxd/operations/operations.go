@@ -116,6 +116,10 @@ type Operation struct {
 	// Channels used for error reporting and state tracking of background actions
 	chanDone chan error
 
+	// Context cancelled once the operation is cancelled or done
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+
 	// Locking for concurent access to the Operation
 	lock sync.Mutex
 
@@ -126,6 +130,11 @@ type Operation struct {
 // OperationCreate creates a new operation and returns it. If it cannot be
 // created, it returns an error.
 func OperationCreate(s *state.State, projectName string, opClass OperationClass, opType db.OperationType, opResources map[string][]string, opMetadata interface{}, onRun func(*Operation) error, onCancel func(*Operation) error, onConnect func(*Operation, *http.Request, http.ResponseWriter) error, r *http.Request) (*Operation, error) {
+	return operationCreate(s, uuid.NewRandom().String(), time.Now(), projectName, opClass, opType, opResources, opMetadata, onRun, onCancel, onConnect, r)
+}
+
+// operationCreate creates a new operation with the given ID and creation date.
+func operationCreate(s *state.State, id string, createdAt time.Time, projectName string, opClass OperationClass, opType db.OperationType, opResources map[string][]string, opMetadata interface{}, onRun func(*Operation) error, onCancel func(*Operation) error, onConnect func(*Operation, *http.Request, http.ResponseWriter) error, r *http.Request) (*Operation, error) {
 	// Don't allow new operations when LXD is shutting down.
 	if s != nil && s.Context.Err() == context.Canceled {
 		return nil, fmt.Errorf("LXD is shutting down")
@@ -134,12 +143,12 @@ func OperationCreate(s *state.State, projectName string, opClass OperationClass,
 	// Main attributes
 	op := Operation{}
 	op.projectName = projectName
-	op.id = uuid.NewRandom().String()
+	op.id = id
 	op.description = opType.Description()
 	op.permission = opType.Permission()
 	op.dbOpType = opType
 	op.class = opClass
-	op.createdAt = time.Now()
+	op.createdAt = createdAt
 	op.updatedAt = op.createdAt
 	op.status = api.Pending
 	op.url = fmt.Sprintf("/%s/operations/%s", version.APIVersion, op.id)
@@ -148,7 +157,10 @@ func OperationCreate(s *state.State, projectName string, opClass OperationClass,
 	op.state = s
 
 	if s != nil {
+		op.ctx, op.ctxCancel = context.WithCancel(s.Context)
 		op.SetEventServer(s.Events)
+	} else {
+		op.ctx, op.ctxCancel = context.WithCancel(context.Background())
 	}
 
 	newMetadata, err := shared.ParseMetadata(opMetadata)
@@ -229,6 +241,7 @@ func (op *Operation) done() {
 	op.onCancel = nil
 	op.onConnect = nil
 	close(op.chanDone)
+	op.ctxCancel()
 	op.lock.Unlock()
 
 	time.AfterFunc(time.Second*5, func() {
@@ -270,6 +283,14 @@ func (op *Operation) Run() (chan error, error) {
 			err := op.onRun(op)
 			if err != nil {
 				op.lock.Lock()
+
+				// The operation failed because it got cancelled (e.g. while waiting in a queue).
+				if op.status == api.Cancelled {
+					op.lock.Unlock()
+					chanRun <- err
+					return
+				}
+
 				op.status = api.Failure
 				op.err = response.SmartError(err).String()
 				op.lock.Unlock()
@@ -445,6 +466,11 @@ func (op *Operation) mayCancel() bool {
 		return true
 	}
 
+	// Operations waiting in a queue can always be cancelled.
+	if queueWaiting(op) {
+		return true
+	}
+
 	return false
 }
 
@@ -601,6 +627,11 @@ func (op *Operation) Resources() map[string][]string {
 	return op.resources
 }
 
+// Context returns a context which is cancelled once the operation is cancelled or done, or when LXD shuts down.
+func (op *Operation) Context() context.Context {
+	return op.ctx
+}
+
 // SetCanceler sets a canceler.
 func (op *Operation) SetCanceler(canceler *cancel.Canceler) {
 	op.canceler = canceler
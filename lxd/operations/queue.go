@@ -0,0 +1,224 @@
+package operations
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// QueueClass represents a class of operations sharing a limit on how many of them may run at once.
+type QueueClass string
+
+const (
+	// QueueImageDownloads is the queue of image downloads.
+	QueueImageDownloads QueueClass = "image_downloads"
+	// QueueMigrations is the queue of incoming instance and storage volume migrations.
+	QueueMigrations QueueClass = "migrations"
+	// QueueBackups is the queue of backup creations and restores.
+	QueueBackups QueueClass = "backups"
+)
+
+// QueuePriority represents the priority of a queued operation. Higher priorities are started first.
+type QueuePriority int
+
+const (
+	// QueuePriorityNormal is the priority of the operations requested through the API.
+	QueuePriorityNormal QueuePriority = 0
+	// QueuePriorityLow is the priority of background tasks.
+	QueuePriorityLow QueuePriority = -1
+)
+
+// queueEntry represents an operation waiting in a queue.
+type queueEntry struct {
+	op       *Operation
+	priority QueuePriority
+	ready    chan struct{}
+}
+
+// queue holds the state of a queue class.
+type queue struct {
+	limit   int
+	running int
+	waiting []*queueEntry
+}
+
+var queuesLock sync.Mutex
+var queues = map[QueueClass]*queue{}
+
+// queueGet returns the queue of the class, creating it if needed. The caller must hold queuesLock.
+func queueGet(class QueueClass) *queue {
+	q, ok := queues[class]
+	if !ok {
+		q = &queue{}
+		queues[class] = q
+	}
+
+	return q
+}
+
+// SetQueueLimit sets how many operations of the class may run at once (0 for no limit).
+// Waiting operations are started straight away if the new limit allows it.
+func SetQueueLimit(class QueueClass, limit int) {
+	queuesLock.Lock()
+	defer queuesLock.Unlock()
+
+	q := queueGet(class)
+	q.limit = limit
+	q.schedule(class)
+}
+
+// schedule starts as many waiting operations as the limit allows and updates the queue position of the others.
+// The caller must hold queuesLock.
+func (q *queue) schedule(class QueueClass) {
+	for len(q.waiting) > 0 && (q.limit <= 0 || q.running < q.limit) {
+		entry := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.running++
+
+		queueSetPosition(entry.op, class, 0)
+		close(entry.ready)
+	}
+
+	for i, entry := range q.waiting {
+		queueSetPosition(entry.op, class, i+1)
+	}
+}
+
+// remove drops the entry from the waiting list. Returns false if the entry isn't waiting anymore.
+// The caller must hold queuesLock.
+func (q *queue) remove(entry *queueEntry) bool {
+	for i, waiting := range q.waiting {
+		if waiting == entry {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// queueSetPosition records the position of the operation in the queue in its metadata (0 once started).
+func queueSetPosition(op *Operation, class QueueClass, position int) {
+	if op == nil {
+		return
+	}
+
+	meta := map[string]interface{}{}
+	for k, v := range op.Metadata() {
+		meta[k] = v
+	}
+
+	if position > 0 {
+		if meta["queue"] == string(class) && meta["queue_position"] == position {
+			return
+		}
+
+		meta["queue"] = string(class)
+		meta["queue_position"] = position
+	} else {
+		_, found := meta["queue_position"]
+		if !found {
+			return
+		}
+
+		delete(meta, "queue")
+		delete(meta, "queue_position")
+	}
+
+	err := op.UpdateMetadata(meta)
+	if err != nil {
+		logger.Debug("Failed updating operation queue position", log.Ctx{"operation": op.ID(), "err": err})
+	}
+}
+
+// queueWaiting returns whether the operation is waiting in a queue.
+func queueWaiting(op *Operation) bool {
+	queuesLock.Lock()
+	defer queuesLock.Unlock()
+
+	for _, q := range queues {
+		for _, entry := range q.waiting {
+			if entry.op == op {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// QueueAcquire waits until the operation may run within the limit of its class and returns the function to call
+// once it's done. Operations of a higher priority are started first, then in the order they were queued.
+// While waiting, the position in the queue is exposed in the operation's metadata. The wait stops with an error
+// when the context or the operation gets cancelled. The operation may be nil for internal tasks not tracked by an
+// operation.
+func QueueAcquire(ctx context.Context, op *Operation, class QueueClass, priority QueuePriority) (func(), error) {
+	entry := &queueEntry{op: op, priority: priority, ready: make(chan struct{})}
+
+	queuesLock.Lock()
+	q := queueGet(class)
+
+	// Queue after the entries of the same or a higher priority.
+	i := len(q.waiting)
+	for i > 0 && q.waiting[i-1].priority < priority {
+		i--
+	}
+
+	q.waiting = append(q.waiting, nil)
+	copy(q.waiting[i+1:], q.waiting[i:])
+	q.waiting[i] = entry
+
+	q.schedule(class)
+	queuesLock.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			queuesLock.Lock()
+			defer queuesLock.Unlock()
+
+			q.running--
+			q.schedule(class)
+		})
+	}
+
+	select {
+	case <-entry.ready:
+		return release, nil
+	default:
+	}
+
+	// Also stop waiting when the operation gets cancelled (never for internal tasks).
+	var opDone <-chan struct{}
+	if op != nil {
+		opDone = op.Context().Done()
+		logger.Debug("Operation queued", log.Ctx{"operation": op.ID(), "queue": class})
+	}
+
+	cancelled := func(err error) (func(), error) {
+		queuesLock.Lock()
+		removed := q.remove(entry)
+		if removed {
+			q.schedule(class)
+		}
+		queuesLock.Unlock()
+
+		// The operation may have been started just as the wait got cancelled.
+		if !removed {
+			release()
+		}
+
+		return nil, err
+	}
+
+	select {
+	case <-entry.ready:
+		return release, nil
+	case <-ctx.Done():
+		return cancelled(ctx.Err())
+	case <-opDone:
+		return cancelled(op.Context().Err())
+	}
+}
@@ -0,0 +1,191 @@
+package operations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// queueWaitFor waits until the given numbers of entries are waiting and running in the queue of the class.
+func queueWaitFor(t *testing.T, class QueueClass, waiting int, running int) {
+	for i := 0; i < 100; i++ {
+		queuesLock.Lock()
+		q := queueGet(class)
+		done := len(q.waiting) == waiting && q.running == running
+		queuesLock.Unlock()
+
+		if done {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("Expected %d entries waiting and %d running in queue %q", waiting, running, class)
+}
+
+// queueStart queues an internal task of the given priority, which reports its name on started once it runs.
+func queueStart(t *testing.T, class QueueClass, priority QueuePriority, name string, started chan string) {
+	queuesLock.Lock()
+	waiting := len(queueGet(class).waiting)
+	running := queueGet(class).running
+	queuesLock.Unlock()
+
+	go func() {
+		release, err := QueueAcquire(context.Background(), nil, class, priority)
+		if err != nil {
+			started <- err.Error()
+			return
+		}
+
+		started <- name
+		release()
+	}()
+
+	queueWaitFor(t, class, waiting+1, running)
+}
+
+// queueCollect returns the names reported on started, in order.
+func queueCollect(t *testing.T, started chan string, count int) []string {
+	names := []string{}
+	for i := 0; i < count; i++ {
+		select {
+		case name := <-started:
+			names = append(names, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Only %d of %d queued entries started", i, count)
+		}
+	}
+
+	return names
+}
+
+// Entries of a higher priority start first, in the order they were queued within the same priority.
+func TestQueueAcquire_Order(t *testing.T) {
+	class := QueueClass("test_order")
+	SetQueueLimit(class, 1)
+
+	release, err := QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+	require.NoError(t, err)
+
+	started := make(chan string)
+	queueStart(t, class, QueuePriorityLow, "low1", started)
+	queueStart(t, class, QueuePriorityNormal, "normal1", started)
+	queueStart(t, class, QueuePriorityLow, "low2", started)
+	queueStart(t, class, QueuePriorityNormal, "normal2", started)
+	queueStart(t, class, QueuePriority(1), "high", started)
+
+	release()
+	assert.Equal(t, []string{"high", "normal1", "normal2", "low1", "low2"}, queueCollect(t, started, 5))
+
+	// Releasing more than once has no effect.
+	release()
+	queueWaitFor(t, class, 0, 0)
+}
+
+// Raising the limit starts the waiting entries straight away, lowering it only applies to the next ones.
+func TestSetQueueLimit(t *testing.T) {
+	class := QueueClass("test_limit")
+	SetQueueLimit(class, 1)
+
+	release, err := QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+	require.NoError(t, err)
+
+	started := make(chan string)
+	queueStart(t, class, QueuePriorityNormal, "first", started)
+	queueStart(t, class, QueuePriorityNormal, "second", started)
+
+	SetQueueLimit(class, 3)
+	assert.ElementsMatch(t, []string{"first", "second"}, queueCollect(t, started, 2))
+	queueWaitFor(t, class, 0, 1)
+
+	// The initial entry is still running when the limit gets lowered.
+	SetQueueLimit(class, 1)
+	queueStart(t, class, QueuePriorityNormal, "third", started)
+
+	select {
+	case name := <-started:
+		t.Fatalf("Entry %q started above the limit", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	assert.Equal(t, []string{"third"}, queueCollect(t, started, 1))
+
+	// No limit.
+	SetQueueLimit(class, 0)
+	for i := 0; i < 3; i++ {
+		release, err := QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+		require.NoError(t, err)
+		defer release()
+	}
+}
+
+// Entries stop waiting when their context is cancelled, without taking a slot.
+func TestQueueAcquire_ContextCancelled(t *testing.T) {
+	class := QueueClass("test_context")
+	SetQueueLimit(class, 1)
+
+	release, err := QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := QueueAcquire(ctx, nil, class, QueuePriorityNormal)
+		errs <- err
+	}()
+
+	queueWaitFor(t, class, 1, 1)
+	cancel()
+	assert.Equal(t, context.Canceled, <-errs)
+	queueWaitFor(t, class, 0, 1)
+
+	release()
+
+	release, err = QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+	require.NoError(t, err)
+	release()
+}
+
+// Operations waiting in a queue can be cancelled, which releases their position in the queue.
+func TestQueueAcquire_OperationCancelled(t *testing.T) {
+	class := QueueClass("test_operation")
+	SetQueueLimit(class, 1)
+
+	release, err := QueueAcquire(context.Background(), nil, class, QueuePriorityNormal)
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	onRun := func(op *Operation) error {
+		_, err := QueueAcquire(context.Background(), op, class, QueuePriorityNormal)
+		errs <- err
+		return err
+	}
+
+	op, err := OperationCreate(nil, "", OperationClassTask, db.OperationBackupCreate, nil, nil, onRun, nil, nil, nil)
+	require.NoError(t, err)
+
+	_, err = op.Run()
+	require.NoError(t, err)
+
+	queueWaitFor(t, class, 1, 1)
+	assert.Equal(t, string(class), op.Metadata()["queue"])
+	assert.Equal(t, 1, op.Metadata()["queue_position"])
+
+	_, err = op.Cancel()
+	require.NoError(t, err)
+
+	assert.Equal(t, context.Canceled, <-errs)
+	queueWaitFor(t, class, 0, 1)
+	assert.Equal(t, api.Cancelled, op.Status())
+
+	release()
+	queueWaitFor(t, class, 0, 0)
+}
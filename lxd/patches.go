@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/deprecation"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/node"
@@ -124,6 +125,7 @@ var patches = []patch{
 	{name: "network_acl_remove_defaults", stage: patchPostDaemonStorage, run: patchNetworkACLRemoveDefaults},
 	{name: "clustering_server_cert_trust", stage: patchPreDaemonStorage, run: patchClusteringServerCertTrust},
 	{name: "warnings_remove_empty_node", stage: patchPostDaemonStorage, run: patchRemoveWarningsWithEmptyNode},
+	{name: "config_keys_v1", stage: patchPostDaemonStorage, run: patchConfigKeys(1)},
 }
 
 type patch struct {
@@ -188,6 +190,16 @@ func patchesApply(d *Daemon, stage patchStage) error {
 
 // Patches begin here
 
+// patchConfigKeys returns a patch rewriting the configuration keys deprecated by the given configuration schema
+// version. The keys are rewritten in the global database so the other cluster members find nothing left to do.
+func patchConfigKeys(version int) func(name string, d *Daemon) error {
+	return func(name string, d *Daemon) error {
+		return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return deprecation.Migrate(tx, version)
+		})
+	}
+}
+
 func patchRemoveWarningsWithEmptyNode(name string, d *Daemon) error {
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		warnings, err := tx.GetWarnings()
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Plugin is a set of API extensions and endpoints added to the daemon by a downstream distribution.
+//
+// Plugins are compiled into the daemon, registering themselves from an init function of their package which is
+// imported (usually as a blank import) by a file added to the daemon's main package.
+type Plugin interface {
+	// Name returns the name of the plugin, used in logs.
+	Name() string
+
+	// APIExtensions returns the API extensions advertised by the plugin.
+	APIExtensions() []string
+
+	// Endpoints returns the API endpoints served by the plugin.
+	Endpoints() []Endpoint
+}
+
+// Endpoint is an API endpoint served by a plugin under /1.0.
+type Endpoint struct {
+	Path   string // Path pattern relative to /1.0, e.g. "example/widgets/{name}".
+	Get    Action
+	Put    Action
+	Post   Action
+	Delete Action
+	Patch  Action
+}
+
+// Action is an action on a plugin endpoint.
+type Action struct {
+	Handler func(s *state.State, r *http.Request) response.Response
+
+	// AccessHandler checks the request is allowed, returning response.EmptySyncResponse if so. Only the
+	// administrators are allowed when not set.
+	AccessHandler  func(s *state.State, r *http.Request) response.Response
+	AllowUntrusted bool
+}
+
+var plugins []Plugin
+var pluginsLock sync.Mutex
+
+// Register adds the plugin to the daemon, advertising its API extensions after the upstream ones. It must be
+// called from an init function and panics if a plugin with the same name is already registered.
+func Register(p Plugin) {
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+
+	for _, entry := range plugins {
+		if entry.Name() == p.Name() {
+			panic(fmt.Sprintf("Plugin %q is already registered", p.Name()))
+		}
+	}
+
+	version.RegisterAPIExtensions(p.APIExtensions()...)
+	plugins = append(plugins, p)
+}
+
+// Plugins returns the registered plugins, in the order they were registered.
+func Plugins() []Plugin {
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+
+	return append([]Plugin{}, plugins...)
+}
@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/version"
+)
+
+type testPlugin struct{}
+
+func (p *testPlugin) Name() string {
+	return "test"
+}
+
+func (p *testPlugin) APIExtensions() []string {
+	return []string{"test_widgets"}
+}
+
+func (p *testPlugin) Endpoints() []Endpoint {
+	return []Endpoint{{
+		Path: "test/widgets",
+		Get: Action{Handler: func(s *state.State, r *http.Request) response.Response {
+			return response.SyncResponse(true, []string{})
+		}},
+	}}
+}
+
+func TestRegister(t *testing.T) {
+	Register(&testPlugin{})
+
+	assert.Len(t, Plugins(), 1)
+	assert.Equal(t, "test_widgets", version.APIExtensions[len(version.APIExtensions)-1])
+	assert.True(t, shared.StringInSlice("test_widgets", version.APIExtensions))
+
+	// Registering the same plugin or extension twice is a programming error.
+	assert.Panics(t, func() { Register(&testPlugin{}) })
+	assert.Panics(t, func() { version.RegisterAPIExtensions("test_widgets") })
+	assert.Len(t, Plugins(), 1)
+}
@@ -146,7 +146,7 @@ func profilesGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
 	var result interface{}
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	err = d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 		filter := db.ProfileFilter{
 			Project: &projectName,
 		}
@@ -318,7 +318,7 @@ func profileGet(d *Daemon, r *http.Request) response.Response {
 
 	var resp *api.Profile
 
-	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+	err = d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 		profile, err := tx.GetProfile(projectName, name)
 		if err != nil {
 			return errors.Wrap(err, "Fetch profile")
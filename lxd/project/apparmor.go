@@ -0,0 +1,119 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// AppArmorRuleClasses lists the classes of AppArmor rules which can be used in raw.apparmor.extra.
+var AppArmorRuleClasses = []string{
+	"capability",
+	"dbus",
+	"file",
+	"mount",
+	"network",
+	"ptrace",
+	"signal",
+	"unix",
+}
+
+// appArmorRuleQualifiers are the keywords which may prefix an AppArmor rule.
+var appArmorRuleQualifiers = []string{"allow", "audit", "deny", "owner"}
+
+// appArmorFilePerms matches the permissions field of a file rule.
+var appArmorFilePerms = regexp.MustCompile(`^[rwaklmixpcuPCU]+$`)
+
+// AppArmorExtraRules parses the content of raw.apparmor.extra and returns the class of each of its rules.
+// Every non-empty line must contain exactly one rule terminated by a comma. Blocks, includes, profile
+// transitions and any other rule type which isn't part of AppArmorRuleClasses are rejected.
+func AppArmorExtraRules(value string) ([]string, error) {
+	classes := []string{}
+
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#include") {
+			return nil, fmt.Errorf("Includes aren't allowed")
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		class, err := appArmorRuleClass(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid AppArmor rule %q: %v", line, err)
+		}
+
+		classes = append(classes, class)
+	}
+
+	return classes, nil
+}
+
+// appArmorRuleClass validates a single AppArmor rule and returns its class.
+func appArmorRuleClass(rule string) (string, error) {
+	if !strings.HasSuffix(rule, ",") {
+		return "", fmt.Errorf("Rules must be terminated by a comma")
+	}
+
+	// Only allow braces for variables and alternations and a single top-level rule per line.
+	depth := 0
+	for i, r := range rule {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return "", fmt.Errorf("Unbalanced braces")
+			}
+		case ',':
+			if depth == 0 && i != len(rule)-1 {
+				return "", fmt.Errorf("Only one rule per line is allowed")
+			}
+		case '#', '^':
+			if depth == 0 {
+				return "", fmt.Errorf("Character %q isn't allowed", r)
+			}
+		}
+	}
+
+	if depth != 0 {
+		return "", fmt.Errorf("Unbalanced braces")
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(rule, ","))
+	for len(fields) > 0 && shared.StringInSlice(fields[0], appArmorRuleQualifiers) {
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		return "", fmt.Errorf("Missing rule")
+	}
+
+	keyword := fields[0]
+	switch {
+	case keyword == "file" || strings.HasPrefix(keyword, "/") || strings.HasPrefix(keyword, "@{"):
+		// Don't allow executing programs under another profile or unconfined.
+		if strings.Contains(rule, "->") {
+			return "", fmt.Errorf("Profile transitions aren't allowed")
+		}
+
+		for _, field := range fields[1:] {
+			if appArmorFilePerms.MatchString(field) && strings.ContainsAny(field, "pcuPCU") {
+				return "", fmt.Errorf("Profile transitions aren't allowed")
+			}
+		}
+
+		return "file", nil
+	case keyword == "umount" || keyword == "remount":
+		return "mount", nil
+	case shared.StringInSlice(keyword, AppArmorRuleClasses):
+		return keyword, nil
+	}
+
+	return "", fmt.Errorf("Rule type %q isn't allowed", keyword)
+}
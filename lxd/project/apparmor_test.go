@@ -0,0 +1,61 @@
+package project_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/project"
+)
+
+func TestAppArmorExtraRules(t *testing.T) {
+	cases := []struct {
+		value   string
+		classes []string
+	}{
+		{"", []string{}},
+		{"# comment\n\n", []string{}},
+		{"/srv/data/** rw,", []string{"file"}},
+		{"owner @{HOME}/.cache/{a,b}/** rwk,", []string{"file"}},
+		{"file rw /dev/fuse,", []string{"file"}},
+		{"/usr/bin/tool ix,", []string{"file"}},
+		{"deny capability sys_module,\ncapability net_admin,", []string{"capability", "capability"}},
+		{"network inet stream,", []string{"network"}},
+		{"  mount fstype=tmpfs -> /run/foo/,  ", []string{"mount"}},
+		{"umount /run/foo/,", []string{"mount"}},
+		{"audit signal (send) peer=unconfined,", []string{"signal"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			classes, err := project.AppArmorExtraRules(c.value)
+			assert.NoError(t, err)
+			assert.Equal(t, c.classes, classes)
+		})
+	}
+}
+
+func TestAppArmorExtraRules_Invalid(t *testing.T) {
+	cases := []string{
+		"/srv/data/** rw",
+		"/srv/a r, /srv/b w,",
+		"/srv/a r, } profile evil { /** rwx,",
+		"/usr/bin/tool ux,",
+		"/usr/bin/tool Px,",
+		"/usr/bin/tool rix -> other,",
+		"change_profile -> unconfined,",
+		"pivot_root,",
+		"profile foo,",
+		"#include <abstractions/base>",
+		"include <abstractions/base>,",
+		"^hat,",
+		"deny,",
+	}
+
+	for _, value := range cases {
+		t.Run(value, func(t *testing.T) {
+			_, err := project.AppArmorExtraRules(value)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// EnvironmentPrefix is the prefix of the configuration keys holding environment variables, both on instances
+// and on projects.
+const EnvironmentPrefix = "environment."
+
+// ValidEnvironmentKey returns an error if the given environment.* key can't be used as an environment variable.
+func ValidEnvironmentKey(key string) error {
+	name := strings.TrimPrefix(key, EnvironmentPrefix)
+	if name == "" {
+		return fmt.Errorf("Environment variable name cannot be empty")
+	}
+
+	if strings.ContainsAny(name, "=\x00") {
+		return fmt.Errorf("Environment variable name %q cannot contain '=' or NUL characters", name)
+	}
+
+	return nil
+}
+
+// EnforcedEnvironment returns the environment variables set by the project's environment.* keys.
+// Those are applied to all instances of the project and can't be overridden by them.
+func EnforcedEnvironment(project *db.Project) map[string]string {
+	env := map[string]string{}
+	if project == nil {
+		return env
+	}
+
+	for k, v := range project.Config {
+		if strings.HasPrefix(k, EnvironmentPrefix) {
+			env[strings.TrimPrefix(k, EnvironmentPrefix)] = v
+		}
+	}
+
+	return env
+}
+
+// Environment returns the environment variables of an instance, made of the environment.* keys of its expanded
+// configuration with the values enforced by its project applied on top.
+func Environment(project *db.Project, instanceConfig map[string]string) map[string]string {
+	env := map[string]string{}
+	for k, v := range instanceConfig {
+		if strings.HasPrefix(k, EnvironmentPrefix) {
+			env[strings.TrimPrefix(k, EnvironmentPrefix)] = v
+		}
+	}
+
+	for k, v := range EnforcedEnvironment(project) {
+		env[k] = v
+	}
+
+	return env
+}
@@ -0,0 +1,40 @@
+package project_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+)
+
+func TestEnvironment(t *testing.T) {
+	p := &db.Project{
+		Name: "p1",
+		Config: map[string]string{
+			"environment.http_proxy": "http://proxy.example.net:3128",
+			"limits.instances":       "10",
+		},
+	}
+
+	config := map[string]string{
+		"environment.http_proxy": "http://other:8080",
+		"environment.LANG":       "C.UTF-8",
+		"user.foo":               "bar",
+	}
+
+	assert.Equal(t, map[string]string{
+		"http_proxy": "http://proxy.example.net:3128",
+		"LANG":       "C.UTF-8",
+	}, project.Environment(p, config))
+
+	assert.Equal(t, map[string]string{"LANG": "C.UTF-8"}, project.Environment(nil, config))
+	assert.Equal(t, map[string]string{"http_proxy": "http://proxy.example.net:3128"}, project.EnforcedEnvironment(p))
+}
+
+func TestValidEnvironmentKey(t *testing.T) {
+	assert.NoError(t, project.ValidEnvironmentKey("environment.HTTPS_PROXY"))
+	assert.Error(t, project.ValidEnvironmentKey("environment."))
+	assert.Error(t, project.ValidEnvironmentKey("environment.A=B"))
+}
@@ -0,0 +1,123 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// IdmapRangeOffsetKey is the volatile project configuration key recording where the dedicated uid/gid range of a
+// project starts, relative to the start of the host allocation.
+const IdmapRangeOffsetKey = "volatile.idmap.offset"
+
+// IdmapRange is a range of host uid/gid dedicated to the instances of a project.
+type IdmapRange struct {
+	Base int64
+	Size int64
+}
+
+// overlaps returns whether the two ranges share any uid/gid.
+func (r IdmapRange) overlaps(other IdmapRange) bool {
+	return r.Base < other.Base+other.Size && other.Base < r.Base+r.Size
+}
+
+// IsolatedIdmapRanges returns the uid/gid ranges requested by projects through security.idmap.isolated_ranges
+// within the host allocation (starting at hostBase and of hostSize entries). The first 65536 entries of the host
+// allocation are never handed out to projects.
+//
+// Projects keep the range recorded in their IdmapRangeOffsetKey as long as it still fits, so that ranges never
+// move under existing containers. The other projects get a new range carved out of the highest free part of the
+// host allocation, in order of creation (by project ID).
+//
+// Returns the ranges keyed by project name, the new values of IdmapRangeOffsetKey to record (empty to clear it)
+// and the names of the projects whose range couldn't be allocated.
+func IsolatedIdmapRanges(projectIDs map[int64]string, projectConfigs map[string]map[string]string, hostBase int64, hostSize int64) (map[string]IdmapRange, map[string]string, []string, error) {
+	ids := make([]int64, 0, len(projectIDs))
+	for id := range projectIDs {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	ranges := map[string]IdmapRange{}
+	updates := map[string]string{}
+	exhausted := []string{}
+
+	fits := func(r IdmapRange) bool {
+		if r.Base < hostBase+65536 || r.Base+r.Size > hostBase+hostSize {
+			return false
+		}
+
+		for _, other := range ranges {
+			if r.overlaps(other) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	// Keep the recorded ranges first.
+	pending := []string{}
+	for _, id := range ids {
+		name := projectIDs[id]
+		config := projectConfigs[name]
+
+		value := config["security.idmap.isolated_ranges"]
+		if value == "" {
+			if config[IdmapRangeOffsetKey] != "" {
+				updates[name] = ""
+			}
+
+			continue
+		}
+
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Invalid security.idmap.isolated_ranges value %q for project %q", value, name)
+		}
+
+		if config[IdmapRangeOffsetKey] != "" {
+			offset, err := strconv.ParseInt(config[IdmapRangeOffsetKey], 10, 64)
+			if err == nil && fits(IdmapRange{Base: hostBase + offset, Size: size}) {
+				ranges[name] = IdmapRange{Base: hostBase + offset, Size: size}
+				continue
+			}
+		}
+
+		pending = append(pending, name)
+	}
+
+	// Then allocate the new ones, from the end of the host allocation.
+	for _, name := range pending {
+		size, _ := strconv.ParseInt(projectConfigs[name]["security.idmap.isolated_ranges"], 10, 64)
+
+		allocated := false
+		end := hostBase + hostSize
+		for end-size >= hostBase+65536 {
+			candidate := IdmapRange{Base: end - size, Size: size}
+			if fits(candidate) {
+				ranges[name] = candidate
+				updates[name] = strconv.FormatInt(candidate.Base-hostBase, 10)
+				allocated = true
+				break
+			}
+
+			// Move below the lowest range overlapping the candidate.
+			for _, other := range ranges {
+				if candidate.overlaps(other) && other.Base < end {
+					end = other.Base
+				}
+			}
+		}
+
+		if !allocated {
+			exhausted = append(exhausted, name)
+			if projectConfigs[name][IdmapRangeOffsetKey] != "" {
+				updates[name] = ""
+			}
+		}
+	}
+
+	return ranges, updates, exhausted, nil
+}
@@ -0,0 +1,71 @@
+package project_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/project"
+)
+
+func TestIsolatedIdmapRanges(t *testing.T) {
+	ids := map[int64]string{
+		1: "default",
+		2: "foo",
+		3: "bar",
+		4: "baz",
+		5: "big",
+	}
+
+	configs := map[string]map[string]string{
+		"default": {},
+		"foo":     {"security.idmap.isolated_ranges": "131072"},
+		"bar":     {"security.idmap.isolated_ranges": "65536"},
+		"baz":     {},
+		"big":     {"security.idmap.isolated_ranges": "1000000"},
+	}
+
+	ranges, updates, exhausted, err := project.IsolatedIdmapRanges(ids, configs, 1000000, 393216)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]project.IdmapRange{
+		"foo": {Base: 1262144, Size: 131072},
+		"bar": {Base: 1196608, Size: 65536},
+	}, ranges)
+	assert.Equal(t, map[string]string{"foo": "262144", "bar": "196608"}, updates)
+	assert.Equal(t, []string{"big"}, exhausted)
+}
+
+func TestIsolatedIdmapRanges_Recorded(t *testing.T) {
+	// A project created before foo but given a range afterwards doesn't move the range of foo.
+	ids := map[int64]string{
+		1: "new",
+		2: "foo",
+		3: "old",
+	}
+
+	configs := map[string]map[string]string{
+		"new": {"security.idmap.isolated_ranges": "65536"},
+		"foo": {"security.idmap.isolated_ranges": "131072", project.IdmapRangeOffsetKey: "262144"},
+		"old": {project.IdmapRangeOffsetKey: "196608"},
+	}
+
+	ranges, updates, exhausted, err := project.IsolatedIdmapRanges(ids, configs, 1000000, 393216)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]project.IdmapRange{
+		"foo": {Base: 1262144, Size: 131072},
+		"new": {Base: 1196608, Size: 65536},
+	}, ranges)
+	assert.Equal(t, map[string]string{"new": "196608", "old": ""}, updates)
+	assert.Empty(t, exhausted)
+}
+
+func TestIsolatedIdmapRanges_Invalid(t *testing.T) {
+	ids := map[int64]string{1: "foo"}
+	configs := map[string]map[string]string{"foo": {"security.idmap.isolated_ranges": "lots"}}
+
+	_, _, _, err := project.IsolatedIdmapRanges(ids, configs, 1000000, 393216)
+	assert.Error(t, err)
+}
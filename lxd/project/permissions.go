@@ -406,8 +406,11 @@ func checkRestrictions(project *db.Project, instances []db.Instance, profiles []
 	containerConfigChecks := map[string]func(value string) error{}
 	devicesChecks := map[string]func(value map[string]string) error{}
 
+	instanceConfigChecks := map[string]func(value string) error{}
 	allowContainerLowLevel := false
 	allowVMLowLevel := false
+	allowKVM := false
+	allowedAppArmorRuleClasses := []string{}
 
 	for _, key := range AllRestrictions {
 		// Check if this particular restriction is defined explicitly
@@ -418,6 +421,10 @@ func checkRestrictions(project *db.Project, instances []db.Instance, profiles []
 		}
 
 		switch key {
+		case "restricted.apparmor.extra":
+			for _, class := range strings.Split(restrictionValue, ",") {
+				allowedAppArmorRuleClasses = append(allowedAppArmorRuleClasses, strings.TrimSpace(class))
+			}
 		case "restricted.containers.nesting":
 			containerConfigChecks["security.nesting"] = func(instanceValue string) error {
 				if restrictionValue == "block" && shared.IsTrue(instanceValue) {
@@ -445,10 +452,44 @@ func checkRestrictions(project *db.Project, instances []db.Instance, profiles []
 
 				return nil
 			}
+		case "restricted.containers.interception":
+			// Some of the keys are lists or limits rather than booleans, only an explicit false disables them.
+			isSet := func(value string) bool {
+				return value != "" && !shared.StringInSlice(strings.ToLower(value), []string{"0", "false", "no", "off"})
+			}
+
+			for _, interceptKey := range allowedInterceptionKeys {
+				containerConfigChecks[interceptKey] = func(instanceValue string) error {
+					if restrictionValue == "block" && !allowContainerLowLevel && isSet(instanceValue) {
+						return fmt.Errorf("System call interception is forbidden")
+					}
+
+					return nil
+				}
+			}
+
+			for _, interceptKey := range fullInterceptionKeys {
+				containerConfigChecks[interceptKey] = func(instanceValue string) error {
+					if restrictionValue != "full" && !allowContainerLowLevel && isSet(instanceValue) {
+						return fmt.Errorf("Use of this system call interception requires full interception to be allowed")
+					}
+
+					return nil
+				}
+			}
 		case "restricted.virtual-machines.lowlevel":
 			if restrictionValue == "allow" {
 				allowVMLowLevel = true
 			}
+		case "restricted.devices.kvm":
+			allowKVM = restrictionValue == "allow"
+			instanceConfigChecks["security.nesting.kvm"] = func(instanceValue string) error {
+				if restrictionValue == "block" && shared.IsTrue(instanceValue) {
+					return fmt.Errorf("Nested KVM is forbidden")
+				}
+
+				return nil
+			}
 		case "restricted.devices.unix-char":
 			devicesChecks["unix-char"] = func(device map[string]string) error {
 				if restrictionValue != "allow" {
@@ -550,8 +591,23 @@ func checkRestrictions(project *db.Project, instances []db.Instance, profiles []
 					key, entityType, entityName, project.Name)
 			}
 
-			var checker func(value string) error
-			if isContainerOrProfile {
+			// Extra AppArmor rules are limited to the allowed classes unless raw.apparmor itself is allowed.
+			if key == "raw.apparmor.extra" && (isContainerOrProfile && !allowContainerLowLevel || isVMOrProfile && !allowVMLowLevel) {
+				classes, err := AppArmorExtraRules(value)
+				if err != nil {
+					return err
+				}
+
+				for _, class := range classes {
+					if !shared.StringInSlice(class, allowedAppArmorRuleClasses) {
+						return fmt.Errorf("Use of AppArmor %s rules in %q on %s %q of project %q is forbidden",
+							class, key, entityType, entityName, project.Name)
+					}
+				}
+			}
+
+			checker := instanceConfigChecks[key]
+			if checker == nil && isContainerOrProfile {
 				checker = containerConfigChecks[key]
 			}
 
@@ -572,6 +628,12 @@ func checkRestrictions(project *db.Project, instances []db.Instance, profiles []
 	// Common devices check logic between instances and profiles.
 	entityDevicesChecker := func(entityType, entityName string, devices map[string]map[string]string) error {
 		for name, device := range devices {
+			// Passing /dev/kvm is controlled separately from other character devices.
+			if !allowKVM && device["type"] == "unix-char" && (device["source"] == "/dev/kvm" || device["path"] == "/dev/kvm") {
+				return fmt.Errorf("Invalid device %q on %s %q of project %q: Access to /dev/kvm is forbidden",
+					name, entityType, entityName, project.Name)
+			}
+
 			check, ok := devicesChecks[device["type"]]
 			if !ok {
 				continue
@@ -630,12 +692,15 @@ var allAggregateLimits = []string{
 
 // AllRestrictions lists all available 'restrict.*' config keys.
 var AllRestrictions = []string{
+	"restricted.apparmor.extra",
 	"restricted.backups",
 	"restricted.cluster.target",
 	"restricted.containers.nesting",
 	"restricted.containers.lowlevel",
 	"restricted.containers.privilege",
+	"restricted.containers.interception",
 	"restricted.virtual-machines.lowlevel",
+	"restricted.devices.kvm",
 	"restricted.devices.unix-char",
 	"restricted.devices.unix-block",
 	"restricted.devices.unix-hotplug",
@@ -645,6 +710,7 @@ var AllRestrictions = []string{
 	"restricted.devices.nic",
 	"restricted.devices.disk",
 	"restricted.snapshots",
+	"restricted.volumes.export",
 }
 
 var defaultRestrictionsValues = map[string]string{
@@ -653,7 +719,9 @@ var defaultRestrictionsValues = map[string]string{
 	"restricted.containers.nesting":        "block",
 	"restricted.containers.lowlevel":       "block",
 	"restricted.containers.privilege":      "unprivileged",
+	"restricted.containers.interception":   "block",
 	"restricted.virtual-machines.lowlevel": "block",
+	"restricted.devices.kvm":               "block",
 	"restricted.devices.unix-char":         "block",
 	"restricted.devices.unix-block":        "block",
 	"restricted.devices.unix-hotplug":      "block",
@@ -663,11 +731,32 @@ var defaultRestrictionsValues = map[string]string{
 	"restricted.devices.nic":               "managed",
 	"restricted.devices.disk":              "managed",
 	"restricted.snapshots":                 "block",
+	"restricted.volumes.export":            "block",
+}
+
+// System call interception keys allowed when restricted.containers.interception is set to "allow".
+var allowedInterceptionKeys = []string{
+	"security.syscalls.intercept.mknod",
+	"security.syscalls.intercept.setxattr",
+	"security.syscalls.intercept.sysinfo",
+}
+
+// System call interception keys which require restricted.containers.interception to be set to "full".
+var fullInterceptionKeys = []string{
+	"security.syscalls.intercept.bpf",
+	"security.syscalls.intercept.bpf.devices",
+	"security.syscalls.intercept.mount",
+	"security.syscalls.intercept.mount.allowed",
+	"security.syscalls.intercept.mount.fuse",
+	"security.syscalls.intercept.mount.shift",
+	"security.syscalls.intercept.sched_setscheduler",
+	"security.syscalls.intercept.sched_setscheduler.priority",
 }
 
 // Return true if a low-level container option is forbidden.
 func isContainerLowLevelOptionForbidden(key string) bool {
-	if strings.HasPrefix(key, "security.syscalls.intercept") {
+	// The known interception keys are covered by restricted.containers.interception.
+	if strings.HasPrefix(key, "security.syscalls.intercept") && !shared.StringInSlice(key, allowedInterceptionKeys) && !shared.StringInSlice(key, fullInterceptionKeys) {
 		return true
 	}
 
@@ -679,6 +768,9 @@ func isContainerLowLevelOptionForbidden(key string) bool {
 		"raw.lxc",
 		"raw.seccomp",
 		"security.devlxd.images",
+		"security.devlxd.management",
+		"security.devlxd.management.root_size_max",
+		"security.devlxd.management.snapshots_max",
 		"security.idmap.base",
 		"security.idmap.size",
 	}) {
@@ -694,6 +786,9 @@ func isVMLowLevelOptionForbidden(key string) bool {
 		"boot.host_shutdown_timeout",
 		"limits.memory.hugepages",
 		"raw.qemu",
+		"security.devlxd.management",
+		"security.devlxd.management.root_size_max",
+		"security.devlxd.management.snapshots_max",
 	}) {
 		return true
 	}
@@ -1291,6 +1386,38 @@ func projectHasRestriction(project *db.Project, restrictionKey string, blockValu
 	return false
 }
 
+// AllowNestedKVM returns whether instances of the project may use KVM, either through nested virtualization in
+// virtual machines or through /dev/kvm in containers.
+func AllowNestedKVM(project *db.Project) bool {
+	return !projectHasRestriction(project, "restricted.devices.kvm", "block")
+}
+
+// InstanceProtections are the protections which can be enabled on instances through the security.protection.*
+// configuration keys.
+var InstanceProtections = []string{"delete", "shift", "stop"}
+
+// InstanceIsProtected returns whether the given protection (delete, shift or stop) is enabled on an instance,
+// either through its own security.protection.* key or, when that isn't set, through the project's
+// security.protection.defaults.
+func InstanceIsProtected(project *db.Project, instanceConfig map[string]string, protection string) bool {
+	value := instanceConfig[fmt.Sprintf("security.protection.%s", protection)]
+	if value != "" {
+		return shared.IsTrue(value)
+	}
+
+	if project == nil {
+		return false
+	}
+
+	for _, entry := range strings.Split(project.Config["security.protection.defaults"], ",") {
+		if strings.TrimSpace(entry) == protection {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AllowBackupCreation returns an error if any project-specific restriction is violated
 // when creating a new backup in a project.
 func AllowBackupCreation(tx *db.ClusterTx, projectName string) error {
@@ -1318,3 +1445,17 @@ func AllowSnapshotCreation(tx *db.ClusterTx, projectName string) error {
 	}
 	return nil
 }
+
+// AllowVolumeExport returns an error if any project-specific restriction is violated
+// when exporting a custom volume from the host in a project.
+func AllowVolumeExport(tx *db.ClusterTx, projectName string) error {
+	project, err := tx.GetProject(projectName)
+	if err != nil {
+		return err
+	}
+
+	if projectHasRestriction(project, "restricted.volumes.export", "block") {
+		return fmt.Errorf("Project %s doesn't allow for volume exports", projectName)
+	}
+	return nil
+}
@@ -151,3 +151,20 @@ func TestAllowInstanceCreation_AboveInstances(t *testing.T) {
 	err = project.AllowInstanceCreation(tx, "p1", req)
 	assert.EqualError(t, err, `Reached maximum number of instances in project "p1"`)
 }
+
+// Instance protection keys take precedence over the project defaults.
+func TestInstanceIsProtected(t *testing.T) {
+	p := &db.Project{
+		Name: "p1",
+		Config: map[string]string{
+			"security.protection.defaults": "delete, stop",
+		},
+	}
+
+	assert.False(t, project.InstanceIsProtected(nil, map[string]string{}, "delete"))
+	assert.True(t, project.InstanceIsProtected(nil, map[string]string{"security.protection.delete": "true"}, "delete"))
+	assert.True(t, project.InstanceIsProtected(p, map[string]string{}, "delete"))
+	assert.True(t, project.InstanceIsProtected(p, map[string]string{}, "stop"))
+	assert.False(t, project.InstanceIsProtected(p, map[string]string{}, "shift"))
+	assert.False(t, project.InstanceIsProtected(p, map[string]string{"security.protection.stop": "false"}, "stop"))
+}
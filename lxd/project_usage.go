@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/rbac"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// projectUsageInterval is how often the resource usage of the local instances is sampled.
+const projectUsageInterval = 5 * time.Minute
+
+// projectUsageDefaultRange is the time range returned when the start of the range isn't specified.
+const projectUsageDefaultRange = 30 * 24 * time.Hour
+
+var projectUsageCmd = APIEndpoint{
+	Path: "projects/{name}/usage",
+
+	Get: APIEndpointAction{Handler: projectUsageGet, AccessHandler: allowAuthenticated},
+}
+
+// projectUsageCounters are the cumulative counters of an instance at the time of the previous sample.
+type projectUsageCounters struct {
+	cpu int64
+	rx  int64
+	tx  int64
+}
+
+// projectUsageTask periodically samples the resource usage of the local instances and accounts it to their
+// project in the database. It also prunes the usage history older than usage.retention.
+func projectUsageTask(d *Daemon) (task.Func, task.Schedule) {
+	previous := map[int]projectUsageCounters{}
+	var last time.Time
+
+	f := func(ctx context.Context) {
+		now := time.Now().UTC()
+
+		elapsed := time.Duration(0)
+		if !last.IsZero() {
+			elapsed = now.Sub(last)
+		}
+
+		last = now
+
+		projectUsageSample(d.State(), previous, elapsed, now)
+
+		retention, err := projectUsageRetention(d.State())
+		if err != nil {
+			logger.Warn("Failed to get usage retention", log.Ctx{"err": err})
+			return
+		}
+
+		if retention > 0 {
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				return tx.DeleteProjectUsageBefore(now.Add(-retention))
+			})
+			if err != nil {
+				logger.Warn("Failed to prune project usage history", log.Ctx{"err": err})
+			}
+		}
+	}
+
+	return f, task.Every(projectUsageInterval)
+}
+
+// projectUsageRetention returns how long the usage history is kept.
+func projectUsageRetention(s *state.State) (time.Duration, error) {
+	var retention time.Duration
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		retention = config.UsageRetention()
+		return nil
+	})
+
+	return retention, err
+}
+
+// projectUsageDelta returns the increase of a cumulative counter, treating a decrease as a counter reset
+// (for example after the instance was restarted).
+func projectUsageDelta(previous int64, current int64) int64 {
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
+
+// projectUsageSample samples the local instances and adds their usage since the previous sample to their project.
+// CPU and network usage come from cumulative counters, so the first sample of an instance only records them.
+// Memory and disk usage are accounted for the time elapsed since the previous sample.
+func projectUsageSample(s *state.State, previous map[int]projectUsageCounters, elapsed time.Duration, now time.Time) {
+	insts, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		logger.Warn("Failed to load instances for usage accounting", log.Ctx{"err": err})
+		return
+	}
+
+	usages := map[string]*db.ProjectUsage{}
+	seen := map[int]bool{}
+	hours := elapsed.Hours()
+
+	for _, inst := range insts {
+		instState, err := inst.RenderState()
+		if err != nil {
+			logger.Debug("Failed to get instance state for usage accounting", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			continue
+		}
+
+		usage, found := usages[inst.Project()]
+		if !found {
+			usage = &db.ProjectUsage{Period: now}
+			usages[inst.Project()] = usage
+		}
+
+		for _, disk := range instState.Disk {
+			usage.DiskByteHours += float64(disk.Usage) * hours
+		}
+
+		if !inst.IsRunning() {
+			continue
+		}
+
+		usage.MemoryByteHours += float64(instState.Memory.Usage) * hours
+
+		counters := projectUsageCounters{cpu: instState.CPU.Usage}
+		for name, nic := range instState.Network {
+			if name == "lo" {
+				continue
+			}
+
+			counters.rx += nic.Counters.BytesReceived
+			counters.tx += nic.Counters.BytesSent
+		}
+
+		prev, found := previous[inst.ID()]
+		previous[inst.ID()] = counters
+		seen[inst.ID()] = true
+
+		if !found {
+			continue
+		}
+
+		usage.CPUSeconds += float64(projectUsageDelta(prev.cpu, counters.cpu)) / float64(time.Second)
+		usage.NetworkRxBytes += projectUsageDelta(prev.rx, counters.rx)
+		usage.NetworkTxBytes += projectUsageDelta(prev.tx, counters.tx)
+	}
+
+	// Forget the counters of the instances which were stopped or deleted.
+	for id := range previous {
+		if !seen[id] {
+			delete(previous, id)
+		}
+	}
+
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		for projectName, usage := range usages {
+			err := tx.AddProjectUsage(projectName, *usage)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to record project usage", log.Ctx{"err": err})
+	}
+}
+
+// projectUsagePeriodStart returns the start of the period of the given interval the time falls in.
+func projectUsagePeriodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+
+	switch interval {
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return t.Truncate(time.Hour)
+}
+
+// projectUsageAggregate sums the hourly usage records into periods of the given interval.
+func projectUsageAggregate(records []db.ProjectUsage, start time.Time, end time.Time, interval string) api.ProjectUsage {
+	result := api.ProjectUsage{
+		Start:    start,
+		End:      end,
+		Interval: interval,
+		Periods:  []api.ProjectUsagePeriod{},
+		Total:    api.ProjectUsagePeriod{Start: start},
+	}
+
+	for _, record := range records {
+		periodStart := projectUsagePeriodStart(record.Period, interval)
+
+		if len(result.Periods) == 0 || !result.Periods[len(result.Periods)-1].Start.Equal(periodStart) {
+			result.Periods = append(result.Periods, api.ProjectUsagePeriod{Start: periodStart})
+		}
+
+		for _, period := range []*api.ProjectUsagePeriod{&result.Periods[len(result.Periods)-1], &result.Total} {
+			period.CPUSeconds += record.CPUSeconds
+			period.MemoryByteHours += record.MemoryByteHours
+			period.DiskByteHours += record.DiskByteHours
+			period.NetworkRxBytes += record.NetworkRxBytes
+			period.NetworkTxBytes += record.NetworkTxBytes
+		}
+	}
+
+	return result
+}
+
+// swagger:operation GET /1.0/projects/{name}/usage projects project_usage_get
+//
+// Get the project usage
+//
+// Gets the resource usage of the project's instances over a time range, for chargeback and showback.
+//
+// ---
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: start
+//     description: Start of the time range (RFC3339, defaults to 30 days before the end)
+//     type: string
+//     example: 2021-06-01T00:00:00Z
+//   - in: query
+//     name: end
+//     description: End of the time range (RFC3339, defaults to now)
+//     type: string
+//     example: 2021-07-01T00:00:00Z
+//   - in: query
+//     name: interval
+//     description: Granularity of the returned periods (hour, day or month, defaults to day)
+//     type: string
+//     example: day
+// responses:
+//   "200":
+//     description: Project usage
+//     schema:
+//       type: object
+//       description: Sync response
+//       properties:
+//         type:
+//           type: string
+//           description: Response type
+//           example: sync
+//         status:
+//           type: string
+//           description: Status description
+//           example: Success
+//         status_code:
+//           type: integer
+//           description: Status code
+//           example: 200
+//         metadata:
+//           $ref: "#/definitions/ProjectUsage"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func projectUsageGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	// Check user permissions.
+	if !rbac.UserHasPermission(r, name, "view") {
+		return response.Forbidden(nil)
+	}
+
+	var err error
+
+	end := time.Now().UTC()
+	if r.FormValue("end") != "" {
+		end, err = time.Parse(time.RFC3339, r.FormValue("end"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid end time: %v", err))
+		}
+	}
+
+	start := end.Add(-projectUsageDefaultRange)
+	if r.FormValue("start") != "" {
+		start, err = time.Parse(time.RFC3339, r.FormValue("start"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid start time: %v", err))
+		}
+	}
+
+	start = db.ProjectUsagePeriod(start)
+	end = end.UTC()
+	if !start.Before(end) {
+		return response.BadRequest(fmt.Errorf("The start of the time range must be before its end"))
+	}
+
+	interval := r.FormValue("interval")
+	if interval == "" {
+		interval = "day"
+	}
+
+	if interval != "hour" && interval != "day" && interval != "month" {
+		return response.BadRequest(fmt.Errorf("Invalid interval %q (must be hour, day or month)", interval))
+	}
+
+	var records []db.ProjectUsage
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		// Check the project exists.
+		_, err := tx.GetProject(name)
+		if err != nil {
+			return err
+		}
+
+		records, err = tx.GetProjectUsage(name, start, end)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, projectUsageAggregate(records, start, end, interval))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+func TestProjectUsageAggregate(t *testing.T) {
+	start := time.Date(2021, 1, 31, 22, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+
+	records := []db.ProjectUsage{
+		{Period: start, CPUSeconds: 1, NetworkRxBytes: 10},
+		{Period: start.Add(time.Hour), CPUSeconds: 2, MemoryByteHours: 100},
+		{Period: start.Add(2 * time.Hour), CPUSeconds: 4, DiskByteHours: 1000},
+		{Period: start.Add(20 * time.Hour), CPUSeconds: 8, NetworkTxBytes: 20},
+	}
+
+	// Daily periods.
+	usage := projectUsageAggregate(records, start, end, "day")
+	require.Len(t, usage.Periods, 2)
+	assert.Equal(t, time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC), usage.Periods[0].Start)
+	assert.Equal(t, float64(3), usage.Periods[0].CPUSeconds)
+	assert.Equal(t, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), usage.Periods[1].Start)
+	assert.Equal(t, float64(12), usage.Periods[1].CPUSeconds)
+	assert.Equal(t, float64(1000), usage.Periods[1].DiskByteHours)
+
+	assert.Equal(t, start, usage.Total.Start)
+	assert.Equal(t, float64(15), usage.Total.CPUSeconds)
+	assert.Equal(t, float64(100), usage.Total.MemoryByteHours)
+	assert.Equal(t, int64(10), usage.Total.NetworkRxBytes)
+	assert.Equal(t, int64(20), usage.Total.NetworkTxBytes)
+
+	// Hourly periods map one to one to the records.
+	usage = projectUsageAggregate(records, start, end, "hour")
+	assert.Len(t, usage.Periods, 4)
+
+	// Monthly periods.
+	usage = projectUsageAggregate(records, start, end, "month")
+	require.Len(t, usage.Periods, 2)
+	assert.Equal(t, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), usage.Periods[1].Start)
+
+	// No records.
+	usage = projectUsageAggregate(nil, start, end, "day")
+	assert.Len(t, usage.Periods, 0)
+	assert.Equal(t, float64(0), usage.Total.CPUSeconds)
+}
+
+func TestProjectUsageDelta(t *testing.T) {
+	assert.Equal(t, int64(5), projectUsageDelta(10, 15))
+
+	// Counter reset.
+	assert.Equal(t, int64(3), projectUsageDelta(10, 3))
+}
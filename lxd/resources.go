@@ -76,6 +76,11 @@ func api10ResourcesGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = gpuFillAllocations(d.State(), res)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.SyncResponse(true, res)
 }
 
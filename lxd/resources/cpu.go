@@ -460,5 +460,13 @@ func GetCPU() (*api.ResourcesCPU, error) {
 
 	cpu.Architecture = strings.TrimRight(string(uname.Machine[:]), "\x00")
 
+	// Get the AMD SEV capabilities
+	if cpu.Architecture == "x86_64" {
+		cpu.SEV, err = GetCPUSEV()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get SEV capabilities")
+		}
+	}
+
 	return &cpu, nil
 }
@@ -0,0 +1,67 @@
+package resources
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+var sysModuleKVMAMD = "/sys/module/kvm_amd/parameters"
+
+// sevCPUIDLeaf is the CPUID leaf describing the AMD memory encryption capabilities.
+const sevCPUIDLeaf = 0x8000001f
+
+// kvmAMDParameterEnabled returns whether a boolean parameter of the kvm_amd module is enabled.
+func kvmAMDParameterEnabled(name string) bool {
+	content, err := ioutil.ReadFile(filepath.Join(sysModuleKVMAMD, name))
+	if err != nil {
+		return false
+	}
+
+	return stringInSlice(strings.TrimSpace(string(content)), []string{"1", "Y"})
+}
+
+// GetCPUSEV returns the AMD SEV capabilities of the host, or nil if KVM can't run SEV guests.
+// The position of the encryption bit is only reported when the cpuid kernel module is loaded.
+func GetCPUSEV() (*api.ResourcesCPUSEV, error) {
+	if !kvmAMDParameterEnabled("sev") {
+		return nil, nil
+	}
+
+	sev := api.ResourcesCPUSEV{
+		ES:  kvmAMDParameterEnabled("sev_es"),
+		SNP: kvmAMDParameterEnabled("sev_snp"),
+	}
+
+	f, err := os.Open("/dev/cpu/0/cpuid")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &sev, nil
+		}
+
+		return nil, errors.Wrap(err, "Failed to open the CPUID device")
+	}
+	defer f.Close()
+
+	// The device returns EAX, EBX, ECX and EDX for the leaf given as the offset.
+	regs := make([]byte, 16)
+	_, err = f.ReadAt(regs, sevCPUIDLeaf)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the memory encryption CPUID leaf")
+	}
+
+	ebx := binary.LittleEndian.Uint32(regs[4:8])
+	ecx := binary.LittleEndian.Uint32(regs[8:12])
+
+	sev.CBitPosition = uint64(ebx & 0x3f)
+	sev.ReducedPhysBits = uint64((ebx >> 6) & 0x3f)
+	sev.MaxGuests = uint64(ecx)
+
+	return &sev, nil
+}
@@ -2,9 +2,11 @@ package resources
 
 import (
 	"bufio"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -197,6 +199,150 @@ func getTotalMemory(sysDevicesBase string) uint64 {
 	return blockSize * count
 }
 
+func getNodeHugepages(nodePath string) ([]api.ResourcesMemoryNodeHugepages, error) {
+	hugepages := []api.ResourcesMemoryNodeHugepages{}
+
+	hugepagesPath := filepath.Join(nodePath, "hugepages")
+	if !sysfsExists(hugepagesPath) {
+		return hugepages, nil
+	}
+
+	entries, err := ioutil.ReadDir(hugepagesPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list %q", hugepagesPath)
+	}
+
+	for _, entry := range entries {
+		entryName := entry.Name()
+		entryPath := filepath.Join(hugepagesPath, entryName)
+
+		// Entries are named after the page size (e.g. hugepages-2048kB).
+		if !strings.HasPrefix(entryName, "hugepages-") {
+			continue
+		}
+
+		size, err := units.ParseByteSizeString(strings.Replace(strings.TrimPrefix(entryName, "hugepages-"), "kB", "KiB", 1))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse huge page size of %q", entryPath)
+		}
+
+		total, err := readUint(filepath.Join(entryPath, "nr_hugepages"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %q", filepath.Join(entryPath, "nr_hugepages"))
+		}
+
+		free, err := readUint(filepath.Join(entryPath, "free_hugepages"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %q", filepath.Join(entryPath, "free_hugepages"))
+		}
+
+		hugepages = append(hugepages, api.ResourcesMemoryNodeHugepages{
+			Size:  uint64(size),
+			Total: total * uint64(size),
+			Used:  (total - free) * uint64(size),
+		})
+	}
+
+	return hugepages, nil
+}
+
+func getNodeDistances(nodePath string, nodes []uint64) ([]api.ResourcesMemoryNodeDistance, error) {
+	distancePath := filepath.Join(nodePath, "distance")
+	if !sysfsExists(distancePath) {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(distancePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %q", distancePath)
+	}
+
+	// The kernel lists the distances in the same order as the online nodes.
+	fields := strings.Fields(string(content))
+	if len(fields) != len(nodes) {
+		return nil, fmt.Errorf("Unexpected number of entries in %q", distancePath)
+	}
+
+	distances := []api.ResourcesMemoryNodeDistance{}
+	for i, field := range fields {
+		distance, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %q", distancePath)
+		}
+
+		distances = append(distances, api.ResourcesMemoryNodeDistance{
+			NUMANode: nodes[i],
+			Distance: distance,
+		})
+	}
+
+	return distances, nil
+}
+
+// GetNUMANodes returns the sorted list of NUMA node identifiers present on the system.
+func GetNUMANodes() ([]uint64, error) {
+	nodes := []uint64{}
+
+	if !sysfsExists(sysDevicesNode) {
+		return nodes, nil
+	}
+
+	entries, err := ioutil.ReadDir(sysDevicesNode)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list %q", sysDevicesNode)
+	}
+
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if !strings.HasPrefix(entryName, "node") || !sysfsExists(filepath.Join(sysDevicesNode, entryName, "meminfo")) {
+			continue
+		}
+
+		nodeNumber, err := strconv.ParseUint(strings.TrimPrefix(entryName, "node"), 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to find NUMA node")
+		}
+
+		nodes = append(nodes, nodeNumber)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+	return nodes, nil
+}
+
+// GetNUMANodeCPUs returns the list of CPU threads which are part of the provided NUMA nodes.
+func GetNUMANodeCPUs(nodes []int64) ([]int64, error) {
+	cpus := []int64{}
+
+	for _, node := range nodes {
+		cpulistPath := filepath.Join(sysDevicesNode, fmt.Sprintf("node%d", node), "cpulist")
+		if !sysfsExists(cpulistPath) {
+			return nil, fmt.Errorf("NUMA node %d doesn't exist", node)
+		}
+
+		content, err := ioutil.ReadFile(cpulistPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read %q", cpulistPath)
+		}
+
+		// Memory-only nodes don't have any CPU.
+		cpulist := strings.TrimSpace(string(content))
+		if cpulist == "" {
+			continue
+		}
+
+		nodeCPUs, err := ParseCpuset(cpulist)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %q", cpulistPath)
+		}
+
+		cpus = append(cpus, nodeCPUs...)
+	}
+
+	return cpus, nil
+}
+
 // GetMemory returns a filled api.ResourcesMemory struct ready for use by LXD
 func GetMemory() (*api.ResourcesMemory, error) {
 	memory := api.ResourcesMemory{}
@@ -228,26 +374,14 @@ func GetMemory() (*api.ResourcesMemory, error) {
 		memory.Nodes = []api.ResourcesMemoryNode{}
 
 		// List all the nodes
-		entries, err := ioutil.ReadDir(sysDevicesNode)
+		nodes, err := GetNUMANodes()
 		if err != nil {
-			return nil, errors.Wrapf(err, "Failed to list %q", sysDevicesNode)
+			return nil, err
 		}
 
 		// Iterate and add to our list
-		for _, entry := range entries {
-			entryName := entry.Name()
-			entryPath := filepath.Join(sysDevicesNode, entryName)
-
-			if !sysfsExists(filepath.Join(entryPath, "meminfo")) {
-				continue
-			}
-
-			// Get NUMA node number
-			nodeName := strings.TrimPrefix(entryName, "node")
-			nodeNumber, err := strconv.ParseUint(nodeName, 10, 64)
-			if err != nil {
-				return nil, errors.Wrap(err, "Failed to find NUMA node")
-			}
+		for _, nodeNumber := range nodes {
+			entryPath := filepath.Join(sysDevicesNode, fmt.Sprintf("node%d", nodeNumber))
 
 			// Parse NUMA meminfo
 			info, err := parseMeminfo(filepath.Join(entryPath, "meminfo"))
@@ -273,6 +407,18 @@ func GetMemory() (*api.ResourcesMemory, error) {
 				node.Total = memTotal
 			}
 
+			// Get the per-size huge page pools.
+			node.Hugepages, err = getNodeHugepages(entryPath)
+			if err != nil {
+				return nil, err
+			}
+
+			// Get the distances to the other nodes.
+			node.Distances, err = getNodeDistances(entryPath, nodes)
+			if err != nil {
+				return nil, err
+			}
+
 			memory.Nodes = append(memory.Nodes, node)
 		}
 	}
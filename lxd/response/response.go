@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	lxd "github.com/lxc/lxd/client"
@@ -117,6 +118,82 @@ func (r *syncResponse) String() string {
 	return "failure"
 }
 
+// SelectFields returns a response only containing the given fields of the metadata of a successful sync
+// response. Fields of nested objects are selected with dotted paths (e.g. "state.network"). When the metadata
+// is a list, the fields are selected from each of its entries. Other responses are returned unchanged.
+func SelectFields(resp Response, fields []string) (Response, error) {
+	syncResp, ok := resp.(*syncResponse)
+	if !ok || !syncResp.success || syncResp.metadata == nil || len(fields) == 0 {
+		return resp, nil
+	}
+
+	// Work on the JSON representation so that fields are selected by their API name.
+	data, err := json.Marshal(syncResp.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata interface{}
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := *syncResp
+	selected.metadata = selectFields(metadata, fields)
+
+	return &selected, nil
+}
+
+// selectFields returns the given fields of an object, or of each object of a list.
+func selectFields(value interface{}, fields []string) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, entry := range v {
+			result = append(result, selectFields(entry, fields))
+		}
+
+		return result
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		nested := map[string][]string{}
+
+		for _, field := range fields {
+			// Keys may themselves contain dots (e.g. config keys).
+			entry, ok := v[field]
+			if ok {
+				result[field] = entry
+				delete(nested, field)
+				continue
+			}
+
+			parts := strings.SplitN(field, ".", 2)
+
+			_, ok = v[parts[0]]
+			if !ok {
+				continue
+			}
+
+			// The whole object was already selected.
+			if _, ok := result[parts[0]]; ok && nested[parts[0]] == nil {
+				continue
+			}
+
+			nested[parts[0]] = append(nested[parts[0]], parts[1])
+		}
+
+		for key, subFields := range nested {
+			result[key] = selectFields(v[key], subFields)
+		}
+
+		return result
+	}
+
+	// Strings (e.g. URLs of non-recursive responses) can't be trimmed.
+	return value
+}
+
 // Error response
 type errorResponse struct {
 	code int
@@ -331,6 +408,13 @@ func (r *fileResponse) Render(w http.ResponseWriter) error {
 		if err != nil {
 			return err
 		}
+
+		if entry.Path != "" && r.removeAfterServe {
+			err := os.Remove(entry.Path)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
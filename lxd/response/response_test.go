@@ -0,0 +1,132 @@
+package response
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInstance mimics the API representation of an instance, with a nested state and config keys containing dots.
+type testInstance struct {
+	Name   string             `json:"name"`
+	Status string             `json:"status"`
+	Config map[string]string  `json:"config"`
+	State  *testInstanceState `json:"state"`
+}
+
+type testInstanceState struct {
+	Status  string            `json:"status"`
+	Network map[string]string `json:"network"`
+}
+
+func TestSelectFields(t *testing.T) {
+	instance := testInstance{
+		Name:   "c1",
+		Status: "Running",
+		Config: map[string]string{
+			"image.os":         "Ubuntu",
+			"user.foo":         "bar",
+			"limits.cpu":       "2",
+			"security.nesting": "true",
+		},
+		State: &testInstanceState{
+			Status:  "Running",
+			Network: map[string]string{"eth0": "10.0.0.2"},
+		},
+	}
+
+	network := map[string]interface{}{"eth0": "10.0.0.2"}
+	state := map[string]interface{}{"status": "Running", "network": network}
+
+	cases := []struct {
+		name     string
+		metadata interface{}
+		fields   []string
+		expected interface{}
+	}{
+		{
+			name:     "top level fields",
+			metadata: instance,
+			fields:   []string{"name", "status"},
+			expected: map[string]interface{}{"name": "c1", "status": "Running"},
+		},
+		{
+			name:     "unknown fields are skipped",
+			metadata: instance,
+			fields:   []string{"name", "missing", "missing.nested"},
+			expected: map[string]interface{}{"name": "c1"},
+		},
+		{
+			name:     "nested path",
+			metadata: instance,
+			fields:   []string{"state.network"},
+			expected: map[string]interface{}{"state": map[string]interface{}{"network": network}},
+		},
+		{
+			name:     "config keys with dots",
+			metadata: instance,
+			fields:   []string{"config.user.foo", "config.image.os"},
+			expected: map[string]interface{}{"config": map[string]interface{}{"user.foo": "bar", "image.os": "Ubuntu"}},
+		},
+		{
+			name:     "overlapping selections with the whole object first",
+			metadata: instance,
+			fields:   []string{"state", "state.network"},
+			expected: map[string]interface{}{"state": state},
+		},
+		{
+			name:     "overlapping selections with the whole object last",
+			metadata: instance,
+			fields:   []string{"state.network", "state"},
+			expected: map[string]interface{}{"state": state},
+		},
+		{
+			name:     "list metadata",
+			metadata: []testInstance{instance, instance},
+			fields:   []string{"name", "state.status"},
+			expected: []interface{}{
+				map[string]interface{}{"name": "c1", "state": map[string]interface{}{"status": "Running"}},
+				map[string]interface{}{"name": "c1", "state": map[string]interface{}{"status": "Running"}},
+			},
+		},
+		{
+			name:     "non-recursive string list",
+			metadata: []string{"/1.0/instances/c1", "/1.0/instances/c2"},
+			fields:   []string{"name"},
+			expected: []interface{}{"/1.0/instances/c1", "/1.0/instances/c2"},
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("%d: %s", i, c.name), func(t *testing.T) {
+			resp, err := SelectFields(SyncResponse(true, c.metadata), c.fields)
+			require.NoError(t, err)
+
+			syncResp, ok := resp.(*syncResponse)
+			require.True(t, ok)
+			assert.Equal(t, c.expected, syncResp.metadata)
+		})
+	}
+}
+
+func TestSelectFields_Unchanged(t *testing.T) {
+	metadata := testInstance{Name: "c1"}
+
+	// Without fields, failed sync responses and other responses are returned as is.
+	resp := SyncResponse(true, metadata)
+	selected, err := SelectFields(resp, nil)
+	require.NoError(t, err)
+	assert.Equal(t, resp, selected)
+
+	resp = SyncResponse(false, metadata)
+	selected, err = SelectFields(resp, []string{"name"})
+	require.NoError(t, err)
+	assert.Equal(t, resp, selected)
+
+	resp = NotFound(nil)
+	selected, err = SelectFields(resp, []string{"name"})
+	require.NoError(t, err)
+	assert.Equal(t, resp, selected)
+}
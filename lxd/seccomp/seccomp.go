@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"os"
 	"path"
@@ -23,6 +24,7 @@ import (
 	// Used by cgo
 	_ "github.com/lxc/lxd/lxd/include"
 
+	"github.com/lxc/lxd/lxd/cgroup"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
@@ -46,6 +48,7 @@ import (
 #include <linux/seccomp.h>
 #include <linux/types.h>
 #include <linux/kdev_t.h>
+#include <sched.h>
 #include <stdbool.h>
 #include <stdio.h>
 #include <stdint.h>
@@ -55,6 +58,7 @@ import (
 #include <sys/socket.h>
 #include <sys/stat.h>
 #include <sys/syscall.h>
+#include <sys/sysinfo.h>
 #include <sys/sysmacros.h>
 #include <sys/types.h>
 #include <unistd.h>
@@ -129,6 +133,8 @@ struct lxd_seccomp_data_arch {
 	int nr_setxattr;
 	int nr_mount;
 	int nr_bpf;
+	int nr_sched_setscheduler;
+	int nr_sysinfo;
 };
 
 #define LXD_SECCOMP_NOTIFY_MKNOD    0
@@ -136,63 +142,65 @@ struct lxd_seccomp_data_arch {
 #define LXD_SECCOMP_NOTIFY_SETXATTR 2
 #define LXD_SECCOMP_NOTIFY_MOUNT 3
 #define LXD_SECCOMP_NOTIFY_BPF 4
+#define LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER 5
+#define LXD_SECCOMP_NOTIFY_SYSINFO 6
 
 // ordered by likelihood of usage...
 static const struct lxd_seccomp_data_arch seccomp_notify_syscall_table[] = {
-	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT, LXD_SECCOMP_NOTIFY_BPF },
+	{ -1, LXD_SECCOMP_NOTIFY_MKNOD, LXD_SECCOMP_NOTIFY_MKNODAT, LXD_SECCOMP_NOTIFY_SETXATTR, LXD_SECCOMP_NOTIFY_MOUNT, LXD_SECCOMP_NOTIFY_BPF, LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER, LXD_SECCOMP_NOTIFY_SYSINFO },
 #ifdef AUDIT_ARCH_X86_64
-	{ AUDIT_ARCH_X86_64,      133, 259, 188, 165, 321 },
+	{ AUDIT_ARCH_X86_64,      133, 259, 188, 165, 321, 144,  99 },
 #endif
 #ifdef AUDIT_ARCH_I386
-	{ AUDIT_ARCH_I386,         14, 297, 226,  21, 357 },
+	{ AUDIT_ARCH_I386,         14, 297, 226,  21, 357, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_AARCH64
-	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21, 386 },
+	{ AUDIT_ARCH_AARCH64,      -1,  33,   5,  21, 386, 119, 179 },
 #endif
 #ifdef AUDIT_ARCH_ARM
-	{ AUDIT_ARCH_ARM,          14, 324, 226,  21, 386 },
+	{ AUDIT_ARCH_ARM,          14, 324, 226,  21, 386, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_ARMEB
-	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21, 386 },
+	{ AUDIT_ARCH_ARMEB,        14, 324, 226,  21, 386, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_S390
-	{ AUDIT_ARCH_S390,         14, 290, 224,  21, 386 },
+	{ AUDIT_ARCH_S390,         14, 290, 224,  21, 386, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_S390X
-	{ AUDIT_ARCH_S390X,        14, 290, 224,  21, 351 },
+	{ AUDIT_ARCH_S390X,        14, 290, 224,  21, 351, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_PPC
-	{ AUDIT_ARCH_PPC,          14, 288, 209,  21, 361 },
+	{ AUDIT_ARCH_PPC,          14, 288, 209,  21, 361, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_PPC64
-	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21, 361 },
+	{ AUDIT_ARCH_PPC64,        14, 288, 209,  21, 361, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_PPC64LE
-	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21, 361 },
+	{ AUDIT_ARCH_PPC64LE,      14, 288, 209,  21, 361, 156, 116 },
 #endif
 #ifdef AUDIT_ARCH_SPARC
-	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167, 349 },
+	{ AUDIT_ARCH_SPARC,        14, 286, 169, 167, 349, 243, 214 },
 #endif
 #ifdef AUDIT_ARCH_SPARC64
-	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167, 349 },
+	{ AUDIT_ARCH_SPARC64,      14, 286, 169, 167, 349, 243, 214 },
 #endif
 #ifdef AUDIT_ARCH_MIPS
-	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21,  -1 },
+	{ AUDIT_ARCH_MIPS,         14, 290, 224,  21,  -1, 160, 116 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL
-	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21,  -1 },
+	{ AUDIT_ARCH_MIPSEL,       14, 290, 224,  21,  -1, 160, 116 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64
-	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160,  -1 },
+	{ AUDIT_ARCH_MIPS64,      131, 249, 180, 160,  -1, 141,  97 },
 #endif
 #ifdef AUDIT_ARCH_MIPS64N32
-	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160,  -1 },
+	{ AUDIT_ARCH_MIPS64N32,   131, 253, 180, 160,  -1, 141,  97 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64
-	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160,  -1 },
+	{ AUDIT_ARCH_MIPSEL64,    131, 249, 180, 160,  -1, 141,  97 },
 #endif
 #ifdef AUDIT_ARCH_MIPSEL64N32
-	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160,  -1 },
+	{ AUDIT_ARCH_MIPSEL64N32, 131, 253, 180, 160,  -1, 141,  97 },
 #endif
 };
 
@@ -227,6 +235,12 @@ static int seccomp_notify_get_syscall(struct seccomp_notif *req,
 		if (entry->nr_bpf == req->data.nr)
 			return LXD_SECCOMP_NOTIFY_BPF;
 
+		if (entry->nr_sched_setscheduler == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER;
+
+		if (entry->nr_sysinfo == req->data.nr)
+			return LXD_SECCOMP_NOTIFY_SYSINFO;
+
 		break;
 	}
 
@@ -234,6 +248,14 @@ static int seccomp_notify_get_syscall(struct seccomp_notif *req,
 	return -EINVAL;
 }
 
+static int seccomp_notify_id_valid(int notify_fd, struct seccomp_notif *req)
+{
+	if (ioctl(notify_fd, SECCOMP_IOCTL_NOTIF_ID_VALID, &req->id))
+		return -errno;
+
+	return 0;
+}
+
 static void seccomp_notify_update_response(struct seccomp_notif_resp *resp,
 					   int new_neg_errno, uint32_t flags)
 {
@@ -436,6 +458,8 @@ const lxdSeccompNotifyMknodat = C.LXD_SECCOMP_NOTIFY_MKNODAT
 const lxdSeccompNotifySetxattr = C.LXD_SECCOMP_NOTIFY_SETXATTR
 const lxdSeccompNotifyMount = C.LXD_SECCOMP_NOTIFY_MOUNT
 const lxdSeccompNotifyBpf = C.LXD_SECCOMP_NOTIFY_BPF
+const lxdSeccompNotifySchedSetscheduler = C.LXD_SECCOMP_NOTIFY_SCHED_SETSCHEDULER
+const lxdSeccompNotifySysinfo = C.LXD_SECCOMP_NOTIFY_SYSINFO
 
 const seccompHeader = `2
 `
@@ -513,6 +537,13 @@ bpf notify [0,8,SCMP_CMP_EQ]
 bpf notify [0,9,SCMP_CMP_EQ]
 `
 
+// Only calls targeting the calling thread (pid 0) are intercepted.
+const seccompNotifySchedSetscheduler = `sched_setscheduler notify [0,0,SCMP_CMP_EQ]
+`
+
+const seccompNotifySysinfo = `sysinfo notify
+`
+
 const compatBlockingPolicy = `[%s]
 compat_sys_rt_sigaction errno 38
 stub_x32_rt_sigreturn errno 38
@@ -563,6 +594,7 @@ type Instance interface {
 	DiskIdmap() (*idmap.IdmapSet, error)
 	IdmappedStorage(path string) idmap.IdmapStorageType
 	InsertSeccompUnixDevice(prefix string, m deviceConfig.Device, pid int) error
+	CGroup() (*cgroup.CGroup, error)
 }
 
 var seccompPath = shared.VarPath("security", "seccomp")
@@ -600,6 +632,8 @@ func InstanceNeedsPolicy(c Instance) bool {
 		"security.syscalls.intercept.setxattr",
 		"security.syscalls.intercept.mount",
 		"security.syscalls.intercept.bpf",
+		"security.syscalls.intercept.sched_setscheduler",
+		"security.syscalls.intercept.sysinfo",
 	}
 
 	for _, k := range keys {
@@ -635,10 +669,12 @@ func InstanceNeedsIntercept(s *state.State, c Instance) (bool, error) {
 	config := c.ExpandedConfig()
 
 	var keys = map[string]func(state *state.State) error{
-		"security.syscalls.intercept.mknod":    lxcSupportSeccompNotify,
-		"security.syscalls.intercept.setxattr": lxcSupportSeccompNotify,
-		"security.syscalls.intercept.mount":    lxcSupportSeccompNotifyContinue,
-		"security.syscalls.intercept.bpf":      lxcSupportSeccompNotifyAddfd,
+		"security.syscalls.intercept.mknod":              lxcSupportSeccompNotify,
+		"security.syscalls.intercept.setxattr":           lxcSupportSeccompNotify,
+		"security.syscalls.intercept.mount":              lxcSupportSeccompNotifyContinue,
+		"security.syscalls.intercept.bpf":                lxcSupportSeccompNotifyAddfd,
+		"security.syscalls.intercept.sched_setscheduler": lxcSupportSeccompNotifyContinue,
+		"security.syscalls.intercept.sysinfo":            lxcSupportSeccompNotifyContinue,
 	}
 
 	needed := false
@@ -741,6 +777,14 @@ func seccompGetPolicyContent(s *state.State, c Instance) (string, error) {
 		if shared.IsTrue(config["security.syscalls.intercept.bpf"]) {
 			policy += seccompNotifyBpf
 		}
+
+		if shared.IsTrue(config["security.syscalls.intercept.sched_setscheduler"]) {
+			policy += seccompNotifySchedSetscheduler
+		}
+
+		if shared.IsTrue(config["security.syscalls.intercept.sysinfo"]) {
+			policy += seccompNotifySysinfo
+		}
 	}
 
 	if allowlist != "" {
@@ -1031,6 +1075,30 @@ func NewSeccompServer(s *state.State, path string, findPID func(pid int32, state
 	return &server, nil
 }
 
+// taskHasCapability returns whether a process has the given capability in its effective set, relative to its own
+// user namespace.
+func taskHasCapability(pid int, capability uint) (bool, error) {
+	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false, err
+		}
+
+		return caps&(1<<capability) != 0, nil
+	}
+
+	return false, fmt.Errorf("No effective capabilities found for process %d", pid)
+}
+
 // TaskIDs returns the task IDs for a process.
 func TaskIDs(pid int) (int64, int64, int64, int64, error) {
 	status, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
@@ -1840,6 +1908,255 @@ func (s *Server) HandleBpfSyscall(c Instance, siov *Iovec) int {
 	return 0
 }
 
+// HandleSchedSetschedulerSyscall handles sched_setscheduler syscalls.
+func (s *Server) HandleSchedSetschedulerSyscall(c Instance, siov *Iovec) int {
+	ctx := log.Ctx{"container": c.Name(),
+		"project":               c.Project(),
+		"syscall_number":        siov.req.data.nr,
+		"audit_architecture":    siov.req.data.arch,
+		"seccomp_notify_id":     siov.req.id,
+		"seccomp_notify_flags":  siov.req.flags,
+		"seccomp_notify_pid":    siov.req.pid,
+		"seccomp_notify_fd":     siov.notifyFd,
+		"seccomp_notify_mem_fd": siov.memFd,
+	}
+
+	defer logger.Debug("Handling sched_setscheduler syscall", ctx)
+
+	// int policy
+	policy := C.int(siov.req.data.args[1])
+	ctx["sched_policy"] = fmt.Sprintf("%d", policy)
+
+	// Only real-time policies need handling, the kernel applies the other ones (and its own checks) as usual.
+	switch policy &^ C.SCHED_RESET_ON_FORK {
+	case C.SCHED_FIFO, C.SCHED_RR:
+	default:
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	// const struct sched_param *param
+	param := C.struct_sched_param{}
+	_, err := C.pread(C.int(siov.memFd), unsafe.Pointer(&param), C.sizeof_struct_sched_param, C.off_t(siov.req.data.args[2]))
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to read memory for sched_setscheduler syscall: %s", err)
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	ctx["sched_priority"] = fmt.Sprintf("%d", param.sched_priority)
+
+	// Real-time priorities are capped by the instance configuration, real-time policies being refused when
+	// no maximum is set.
+	maxPriority := 0
+	value := c.ExpandedConfig()["security.syscalls.intercept.sched_setscheduler.priority"]
+	if value != "" {
+		maxPriority, err = strconv.Atoi(value)
+		if err != nil {
+			ctx["err"] = fmt.Sprintf("Invalid maximum real-time priority: %s", err)
+			return -int(unix.EPERM)
+		}
+	}
+
+	if maxPriority < 1 {
+		ctx["err"] = "Real-time priorities aren't allowed"
+		return -int(unix.EPERM)
+	}
+
+	if int(param.sched_priority) > maxPriority {
+		param.sched_priority = C.int(maxPriority)
+		ctx["sched_priority_clamped"] = fmt.Sprintf("%d", maxPriority)
+	}
+
+	// Apply the checks the kernel would for an unprivileged thread: without CAP_SYS_NICE in its user namespace,
+	// the priority is limited by RLIMIT_RTPRIO.
+	pid := int(siov.req.pid)
+	hasCap, err := taskHasCapability(pid, unix.CAP_SYS_NICE)
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to get the capabilities of the calling thread: %s", err)
+		return -int(unix.EPERM)
+	}
+
+	if !hasCap {
+		rlimit := unix.Rlimit{}
+		err = unix.Prlimit(pid, unix.RLIMIT_RTPRIO, nil, &rlimit)
+		if err != nil {
+			ctx["err"] = fmt.Sprintf("Failed to get the real-time priority limit of the calling thread: %s", err)
+			return -int(unix.EPERM)
+		}
+
+		if uint64(param.sched_priority) > rlimit.Cur {
+			ctx["err"] = fmt.Sprintf("Real-time priority is above the thread's limit of %d", rlimit.Cur)
+			return -int(unix.EPERM)
+		}
+	}
+
+	// Make sure the calling thread is still the one blocked on the syscall before acting on its PID.
+	ret := C.seccomp_notify_id_valid(C.int(siov.notifyFd), siov.req)
+	if ret < 0 {
+		return int(ret)
+	}
+
+	// The syscall is only intercepted when targeting the calling thread (pid 0), so apply it to the thread
+	// which triggered the notification.
+	_, _, errno := unix.Syscall(unix.SYS_SCHED_SETSCHEDULER, uintptr(siov.req.pid), uintptr(policy), uintptr(unsafe.Pointer(&param)))
+	if errno != 0 {
+		ctx["syscall_handler_error"] = fmt.Sprintf("%s - Failed to handle sched_setscheduler syscall", errno)
+		return -int(errno)
+	}
+
+	return 0
+}
+
+// HandleSysinfoSyscall handles sysinfo syscalls by reporting the uptime, memory and process count of the
+// instance rather than those of the host.
+func (s *Server) HandleSysinfoSyscall(c Instance, siov *Iovec) int {
+	ctx := log.Ctx{"container": c.Name(),
+		"project":               c.Project(),
+		"syscall_number":        siov.req.data.nr,
+		"audit_architecture":    siov.req.data.arch,
+		"seccomp_notify_id":     siov.req.id,
+		"seccomp_notify_flags":  siov.req.flags,
+		"seccomp_notify_pid":    siov.req.pid,
+		"seccomp_notify_fd":     siov.notifyFd,
+		"seccomp_notify_mem_fd": siov.memFd,
+	}
+
+	defer logger.Debug("Handling sysinfo syscall", ctx)
+
+	// The structure layout differs for personalities other than the host's, leave those to the kernel.
+	if len(s.s.OS.Architectures) > 0 && c.Architecture() != s.s.OS.Architectures[0] {
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	info := C.struct_sysinfo{}
+	_, err := C.sysinfo(&info)
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to retrieve host sysinfo: %s", err)
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	cg, err := c.CGroup()
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to get cgroup: %s", err)
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	// Uptime of the instance.
+	uptime, err := processUptime(int(siov.msg.init_pid), int64(info.uptime))
+	if err == nil {
+		info.uptime = C.long(uptime)
+	}
+
+	// Memory, reported in bytes.
+	unit := uint64(info.mem_unit)
+	if unit == 0 {
+		unit = 1
+	}
+
+	info.totalram = C.ulong(uint64(info.totalram) * unit)
+	info.freeram = C.ulong(uint64(info.freeram) * unit)
+	info.sharedram = C.ulong(uint64(info.sharedram) * unit)
+	info.bufferram = C.ulong(uint64(info.bufferram) * unit)
+	info.totalswap = C.ulong(uint64(info.totalswap) * unit)
+	info.freeswap = C.ulong(uint64(info.freeswap) * unit)
+	info.totalhigh = C.ulong(uint64(info.totalhigh) * unit)
+	info.freehigh = C.ulong(uint64(info.freehigh) * unit)
+	info.mem_unit = 1
+
+	memLimit, err := cg.GetMemoryLimit()
+	if err == nil && memLimit > 0 && uint64(memLimit) < uint64(info.totalram) {
+		memUsage, err := cg.GetMemoryUsage()
+		if err == nil {
+			info.totalram = C.ulong(memLimit)
+			info.freeram = 0
+			if memUsage < memLimit {
+				info.freeram = C.ulong(memLimit - memUsage)
+			}
+
+			info.sharedram = 0
+			info.bufferram = 0
+		}
+	}
+
+	swapLimit, err := cg.GetMemorySwapLimit()
+	if err == nil && swapLimit >= 0 && uint64(swapLimit) < uint64(info.totalswap) {
+		swapUsage, err := cg.GetMemorySwapUsage()
+		if err == nil {
+			info.totalswap = C.ulong(swapLimit)
+			info.freeswap = 0
+			if swapUsage < swapLimit {
+				info.freeswap = C.ulong(swapLimit - swapUsage)
+			}
+		}
+	}
+
+	// Number of processes (the field is only 16 bits wide, so clamp rather than wrap around).
+	procs, err := cg.GetProcessesUsage()
+	if err == nil && procs >= 0 {
+		if procs > math.MaxUint16 {
+			procs = math.MaxUint16
+		}
+
+		info.procs = C.ushort(procs)
+	}
+
+	_, err = C.pwrite(C.int(siov.memFd), unsafe.Pointer(&info), C.sizeof_struct_sysinfo, C.off_t(siov.req.data.args[0]))
+	if err != nil {
+		ctx["err"] = fmt.Sprintf("Failed to write memory for sysinfo syscall: %s", err)
+		ctx["syscall_continue"] = "true"
+		C.seccomp_notify_update_response(siov.resp, 0, C.uint32_t(seccompUserNotifFlagContinue))
+		return 0
+	}
+
+	return 0
+}
+
+// processUptime returns the number of seconds since the given process was started.
+func processUptime(pid int, hostUptime int64) (int64, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return -1, err
+	}
+
+	// Skip past the command name as it may contain spaces.
+	idx := strings.LastIndex(string(content), ")")
+	if idx < 0 {
+		return -1, fmt.Errorf("Invalid stat file for process %d", pid)
+	}
+
+	// The start time is the 22nd field, the 20th after the command name.
+	fields := strings.Fields(string(content[idx+1:]))
+	if len(fields) < 20 {
+		return -1, fmt.Errorf("Invalid stat file for process %d", pid)
+	}
+
+	startTime, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	ticks := int64(C.sysconf(C._SC_CLK_TCK))
+	if ticks <= 0 {
+		return -1, fmt.Errorf("Failed to get clock ticks")
+	}
+
+	uptime := hostUptime - startTime/ticks
+	if uptime < 0 {
+		return 0, nil
+	}
+
+	return uptime, nil
+}
+
 func (s *Server) handleSyscall(c Instance, siov *Iovec) int {
 	switch int(C.seccomp_notify_get_syscall(siov.req, siov.resp)) {
 	case lxdSeccompNotifyMknod:
@@ -1852,6 +2169,10 @@ func (s *Server) handleSyscall(c Instance, siov *Iovec) int {
 		return s.HandleMountSyscall(c, siov)
 	case lxdSeccompNotifyBpf:
 		return s.HandleBpfSyscall(c, siov)
+	case lxdSeccompNotifySchedSetscheduler:
+		return s.HandleSchedSetschedulerSyscall(c, siov)
+	case lxdSeccompNotifySysinfo:
+		return s.HandleSysinfoSyscall(c, siov)
 	}
 
 	return int(-C.EINVAL)
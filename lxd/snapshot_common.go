@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"gopkg.in/robfig/cron.v2"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 )
 
 // SnapshotScheduleAliases contains the mapping of scheduling aliases to cron syntax
@@ -23,12 +30,16 @@ var SnapshotScheduleAliases = map[string]string{
 	"@yearly":   "* %s %s 1 1 *",
 }
 
-func snapshotIsScheduledNow(spec string, subjectID int64) bool {
+// snapshotIsScheduledNow returns whether the schedule, delayed by the given duration, is due now.
+func snapshotIsScheduledNow(spec string, subjectID int64, delay time.Duration) bool {
 	var result = false
 
+	// A delayed schedule is due now if the undelayed one was due delay ago.
+	now := time.Now().Add(-delay)
+
 	specs := buildCronSpecs(spec, subjectID)
 	for _, curSpec := range specs {
-		isNow, err := cronSpecIsNow(curSpec)
+		isNow, err := cronSpecIsNow(curSpec, now)
 		if err == nil && isNow {
 			result = true
 		}
@@ -81,15 +92,12 @@ func getObfuscatedTimeValuesForSubject(subjectID int64) (string, string) {
 	return minuteResult, hourResult
 }
 
-func cronSpecIsNow(spec string) (bool, error) {
+func cronSpecIsNow(spec string, now time.Time) (bool, error) {
 	sched, err := cron.Parse(spec)
 	if err != nil {
 		return false, fmt.Errorf("Could not parse cron '%s'", spec)
 	}
 
-	// Check if it's time to snapshot
-	now := time.Now()
-
 	// Truncate the time now back to the start of the minute, before passing to
 	// the cron scheduler, as it will add 1s to the scheduled time and we don't
 	// want the next scheduled time to roll over to the next minute and break
@@ -109,3 +117,116 @@ func cronSpecIsNow(spec string) (bool, error) {
 
 	return true, nil
 }
+
+// snapshotScheduleDelay returns how long the scheduled snapshots of the subject are delayed by, that is the
+// snapshots.schedule.offset plus a stable random share (in minutes) of the snapshots.schedule.jitter.
+func snapshotScheduleDelay(offset string, jitter string, subjectID int64) (time.Duration, error) {
+	// Use a fixed reference date in UTC so that the durations don't depend on the current date.
+	refDate := time.Unix(0, 0).UTC()
+
+	offsetDate, err := shared.GetSnapshotExpiry(refDate, offset)
+	if err != nil {
+		return 0, errors.Wrap(err, "Invalid snapshots.schedule.offset")
+	}
+
+	delay := time.Duration(0)
+	if !offsetDate.IsZero() {
+		delay = offsetDate.Sub(refDate)
+	}
+
+	jitterDate, err := shared.GetSnapshotExpiry(refDate, jitter)
+	if err != nil {
+		return 0, errors.Wrap(err, "Invalid snapshots.schedule.jitter")
+	}
+
+	if !jitterDate.IsZero() {
+		jitterMinutes := int64(jitterDate.Sub(refDate) / time.Minute)
+		if jitterMinutes > 0 {
+			// Use a different seed than the one obfuscating the schedule aliases so both are independent.
+			r, err := util.GetStableRandomGenerator(fmt.Sprintf("snapshot-jitter-%d", subjectID))
+			if err != nil {
+				return 0, err
+			}
+
+			delay += time.Duration(r.Int63n(jitterMinutes+1)) * time.Minute
+		}
+	}
+
+	return delay, nil
+}
+
+// snapshotMemberSlot returns the start of the slot of the member within the interval. The online members take
+// turns in the order of their IDs, so that they don't all start taking their scheduled snapshots at the same time
+// on shared storage. The slot of a member not in the list, or of a standalone server, starts at zero.
+func snapshotMemberSlot(nodeID int64, onlineNodeIDs []int64, interval time.Duration) time.Duration {
+	if len(onlineNodeIDs) <= 1 {
+		return 0
+	}
+
+	nodeIDs := append([]int64{}, onlineNodeIDs...)
+	sort.Slice(nodeIDs, func(i, j int) bool { return nodeIDs[i] < nodeIDs[j] })
+
+	for i, id := range nodeIDs {
+		if id == nodeID {
+			return interval * time.Duration(i) / time.Duration(len(nodeIDs))
+		}
+	}
+
+	return 0
+}
+
+// snapshotMemberDelay returns how long this member should wait within the scheduling interval before taking its
+// scheduled snapshots.
+func snapshotMemberDelay(d *Daemon, interval time.Duration) (time.Duration, error) {
+	var onlineNodeIDs []int64
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		offlineThreshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.IsOffline(offlineThreshold) {
+				continue
+			}
+
+			onlineNodeIDs = append(onlineNodeIDs, node.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed getting online cluster members")
+	}
+
+	return snapshotMemberSlot(d.cluster.GetNodeID(), onlineNodeIDs, interval), nil
+}
+
+// snapshotWaitMemberSlot waits for the slot of this member within the scheduling interval.
+// Returns false if the context was cancelled while waiting.
+func snapshotWaitMemberSlot(ctx context.Context, d *Daemon) bool {
+	delay, err := snapshotMemberDelay(d, time.Minute)
+	if err != nil {
+		logger.Warn("Failed getting scheduled snapshot slot, not waiting", log.Ctx{"err": err})
+		return true
+	}
+
+	if delay <= 0 {
+		return true
+	}
+
+	logger.Debug("Waiting for scheduled snapshot slot", log.Ctx{"delay": delay})
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/lxc/lxd/lxd/db"
@@ -21,7 +23,7 @@ func (suite *containerTestSuite) TestSnapshotScheduling() {
 	c, op, err := instance.CreateInternal(suite.d.State(), args, true, nil, revert.New())
 	suite.Req.Nil(err)
 	suite.Equal(true, snapshotIsScheduledNow("* * * * *",
-		int64(c.ID())),
+		int64(c.ID()), 0),
 		"snapshot.schedule config '* * * * *' should have matched now")
 	suite.Equal(true, snapshotIsScheduledNow("@daily,"+
 		"@hourly,"+
@@ -31,11 +33,45 @@ func (suite *containerTestSuite) TestSnapshotScheduling() {
 		"@annually,"+
 		"@yearly,"+
 		" * * * * *",
-		int64(c.ID())),
+		int64(c.ID()), 0),
 		"snapshot.schedule config '* * * * *' should have matched now")
 	op.Done(nil)
 }
 
+func TestSnapshotScheduleDelay(t *testing.T) {
+	delay, err := snapshotScheduleDelay("", "", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), delay)
+
+	delay, err = snapshotScheduleDelay("1H 30M", "", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, delay)
+
+	// The jitter is stable for a given subject and within bounds.
+	for id := int64(0); id < 100; id++ {
+		delay, err := snapshotScheduleDelay("5M", "30M", id)
+		assert.NoError(t, err)
+		assert.True(t, delay >= 5*time.Minute && delay <= 35*time.Minute)
+		assert.Equal(t, time.Duration(0), delay%time.Minute)
+
+		again, err := snapshotScheduleDelay("5M", "30M", id)
+		assert.NoError(t, err)
+		assert.Equal(t, delay, again)
+	}
+
+	_, err = snapshotScheduleDelay("", "30s", 1)
+	assert.Error(t, err)
+}
+
+func TestSnapshotMemberSlot(t *testing.T) {
+	assert.Equal(t, time.Duration(0), snapshotMemberSlot(1, nil, time.Minute))
+	assert.Equal(t, time.Duration(0), snapshotMemberSlot(1, []int64{1}, time.Minute))
+	assert.Equal(t, time.Duration(0), snapshotMemberSlot(2, []int64{3, 2, 5, 4}, time.Minute))
+	assert.Equal(t, 15*time.Second, snapshotMemberSlot(3, []int64{3, 2, 5, 4}, time.Minute))
+	assert.Equal(t, 45*time.Second, snapshotMemberSlot(5, []int64{3, 2, 5, 4}, time.Minute))
+	assert.Equal(t, time.Duration(0), snapshotMemberSlot(6, []int64{3, 2, 5, 4}, time.Minute))
+}
+
 func TestSnapshotCommon(t *testing.T) {
 	suite.Run(t, new(containerTestSuite))
 }
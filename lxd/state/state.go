@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/lxc/lxd/lxd/cgroup"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/endpoints"
 	"github.com/lxc/lxd/lxd/events"
@@ -52,4 +53,7 @@ type State struct {
 
 	// Available instance types based on operational drivers.
 	InstanceTypes map[instancetype.Type]struct{}
+
+	// Cached resource usage of the instance cgroups.
+	InstanceStats *cgroup.Collector
 }
@@ -33,7 +33,7 @@ func NewTestState(t *testing.T) (*State, func()) {
 		Node:                   node,
 		Cluster:                cluster,
 		OS:                     os,
-		Firewall:               firewall.New(),
+		Firewall:               firewall.New(""),
 		UpdateCertificateCache: func() {},
 	}
 
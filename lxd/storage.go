@@ -9,10 +9,13 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/warnings"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
@@ -107,6 +110,20 @@ func setupStorageDriver(s *state.State, forceCheck bool) error {
 
 	}
 
+	skipUnavailable := false
+	err = s.Node.Transaction(func(tx *db.NodeTx) error {
+		config, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		skipUnavailable = config.StorageSkipUnavailablePools()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load member configuration")
+	}
+
 	for _, poolName := range pools {
 		logger.Debug("Initializing and checking storage pool", log.Ctx{"pool": poolName})
 		errPrefix := fmt.Sprintf("Failed initializing storage pool %q", poolName)
@@ -116,9 +133,38 @@ func setupStorageDriver(s *state.State, forceCheck bool) error {
 			return errors.Wrap(err, errPrefix)
 		}
 
+		// Mount the pool in the background instead.
+		if shared.IsTrue(pool.Driver().Config()["mount.lazy"]) {
+			logger.Debug("Deferring storage pool mount", log.Ctx{"pool": poolName})
+			storagePools.DeferPoolMount(s, pool, nil)
+			continue
+		}
+
 		_, err = pool.Mount()
 		if err != nil {
-			return errors.Wrap(err, errPrefix)
+			if !skipUnavailable {
+				return errors.Wrap(err, errPrefix)
+			}
+
+			// Don't prevent LXD from starting, mounting will be attempted again in the background.
+			logger.Error("Failed mounting storage pool, skipping", log.Ctx{"pool": poolName, "err": err})
+			storagePools.DeferPoolMount(s, pool, err)
+			continue
+		}
+
+		// Clear the errored status left by a previous failure.
+		if pool.LocalStatus() == api.StoragePoolStatusErrored {
+			err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+				return tx.StoragePoolNodeCreated(pool.ID())
+			})
+			if err != nil {
+				logger.Warn("Failed to mark storage pool as created", log.Ctx{"pool": poolName, "err": err})
+			}
+		}
+
+		err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, "", db.WarningStoragePoolUnavailable, dbCluster.TypeStoragePool, int(pool.ID()))
+		if err != nil {
+			logger.Warn("Failed to resolve storage pool unavailable warning", log.Ctx{"pool": poolName, "err": err})
 		}
 	}
 
@@ -161,6 +161,11 @@ func (b *lxdBackend) Create(clientType request.ClientType, op *operations.Operat
 
 // newVolume returns a new Volume instance containing copies of the supplied volume config and the pools config,
 func (b *lxdBackend) newVolume(volType drivers.VolumeType, contentType drivers.ContentType, volName string, volConfig map[string]string) drivers.Volume {
+	// ISO volumes are stored as block volumes.
+	if contentType == drivers.ContentTypeISO {
+		contentType = drivers.ContentTypeBlock
+	}
+
 	// Copy the config map to avoid internal modifications affecting external state.
 	newConfig := map[string]string{}
 	for k, v := range volConfig {
@@ -225,10 +230,17 @@ func (b *lxdBackend) Update(clientType request.ClientType, newDesc string, newCo
 	// Diff the configurations.
 	changedConfig, userOnly := b.detectChangedConfig(b.db.Config, newConfig)
 
-	// Check if the pool source is being changed that the local state is still pending, otherwise prevent it.
+	// Once the pool is created on this member, its source can only be pointed at the same storage under a
+	// new name or path, which the driver checks. Remote pools share their source with all the members.
 	_, sourceChanged := changedConfig["source"]
 	if sourceChanged && b.LocalStatus() != api.StoragePoolStatusPending {
-		return fmt.Errorf("Pool source cannot be changed when not in pending state")
+		if b.driver.Info().Remote {
+			return fmt.Errorf("Pool source cannot be changed when not in pending state")
+		}
+
+		if newConfig["source"] == "" {
+			return fmt.Errorf("Pool source cannot be removed")
+		}
 	}
 
 	// Apply changes to local node if both global pool and node are not pending and non-user config changed.
@@ -252,6 +264,51 @@ func (b *lxdBackend) Update(clientType request.ClientType, newDesc string, newCo
 
 }
 
+// MoveToBlockDevice moves a loop file backed pool onto the block device on this member, while in use, and records
+// the block device as the pool's source.
+func (b *lxdBackend) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"devPath": devPath})
+	logger.Debug("MoveToBlockDevice started")
+	defer logger.Debug("MoveToBlockDevice finished")
+
+	if b.driver.Info().Remote {
+		return fmt.Errorf("Remote storage pools can't be moved onto a block device")
+	}
+
+	if b.LocalStatus() != api.StoragePoolStatusCreated {
+		return fmt.Errorf("The storage pool isn't created on this member")
+	}
+
+	if !shared.IsBlockdevPath(devPath) {
+		return fmt.Errorf("%q isn't a block device", devPath)
+	}
+
+	// The pool needs to be mounted to be moved while in use.
+	_, err := b.driver.Mount()
+	if err != nil {
+		return err
+	}
+
+	err = b.driver.MoveToBlockDevice(devPath, op)
+	if err != nil {
+		if errors.Cause(err) == drivers.ErrNotSupported {
+			return fmt.Errorf("Storage pools using the %q driver can't be moved onto a block device", b.driver.Info().Name)
+		}
+
+		return err
+	}
+
+	// Record the new source of the pool on this member.
+	err = b.state.Cluster.UpdateStoragePool(b.name, b.db.Description, b.driver.Config())
+	if err != nil {
+		return errors.Wrapf(err, "Failed recording the new source of the storage pool")
+	}
+
+	b.db.Config = b.driver.Config()
+
+	return nil
+}
+
 // Delete removes the pool.
 func (b *lxdBackend) Delete(clientType request.ClientType, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"clientType": clientType})
@@ -265,15 +322,16 @@ func (b *lxdBackend) Delete(clientType request.ClientType, op *operations.Operat
 	}
 
 	if clientType != request.ClientTypeNormal && b.driver.Info().Remote {
-		if b.driver.Info().MountedRoot {
-			_, err := b.driver.Unmount()
-			if err != nil {
-				return err
-			}
-		} else {
+		// Release any connection to the remote storage held by this member.
+		_, err := b.driver.Unmount()
+		if err != nil {
+			return err
+		}
+
+		if !b.driver.Info().MountedRoot {
 			// Remote storage may have leftover entries caused by
 			// volumes that were moved or delete while a particular system was offline.
-			err := os.RemoveAll(path)
+			err = os.RemoveAll(path)
 			if err != nil {
 				return err
 			}
@@ -1089,10 +1147,41 @@ func (b *lxdBackend) imageFiller(fingerprint string, op *operations.Operation) f
 				}}
 		}
 		imageFile := shared.VarPath("images", fingerprint)
-		return ImageUnpack(imageFile, vol, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, allowUnsafeResize, tracker)
+		size, err := ImageUnpack(imageFile, vol, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, allowUnsafeResize, tracker)
+		if err != nil {
+			return -1, err
+		}
+
+		if !b.driver.Info().BlockBacking {
+			b.imageDedup(vol)
+		}
+
+		return size, nil
 	}
 }
 
+// imageDedup shares the identical blocks of the unpacked image volume with the ones of the other image volumes
+// when enabled. Failures aren't fatal as the volume is usable as is.
+func (b *lxdBackend) imageDedup(vol drivers.Volume) {
+	enabled, err := imageDedupEnabled(b.state)
+	if err != nil {
+		b.logger.Warn("Failed loading image deduplication config", log.Ctx{"err": err})
+		return
+	}
+
+	if !enabled {
+		return
+	}
+
+	stats, err := filesystem.Dedup(vol.MountPath(), imageDedupStores(b.name))
+	if err != nil {
+		b.logger.Warn("Failed deduplicating image volume", log.Ctx{"volName": vol.Name(), "err": err})
+		return
+	}
+
+	b.logger.Debug("Deduplicated image volume", log.Ctx{"volName": vol.Name(), "scanned": stats.Scanned, "shared": stats.Shared, "stored": stats.Stored})
+}
+
 // CreateInstanceFromImage creates a new volume for an instance populated with the image requested.
 // On failure caller is expected to call DeleteInstance() to clean up.
 func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint string, op *operations.Operation) error {
@@ -2519,6 +2608,9 @@ func (b *lxdBackend) UpdateImage(fingerprint, newDesc string, newConfig map[stri
 
 // CreateCustomVolume creates an empty custom volume.
 func (b *lxdBackend) CreateCustomVolume(projectName string, volName string, desc string, config map[string]string, contentType drivers.ContentType, op *operations.Operation) error {
+	// Only store the hash of the SMB export password.
+	config = hashExportPassword(config)
+
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "desc": desc, "config": config, "contentType": contentType})
 	logger.Debug("CreateCustomVolume started")
 	defer logger.Debug("CreateCustomVolume finished")
@@ -2549,6 +2641,11 @@ func (b *lxdBackend) CreateCustomVolume(projectName string, volName string, desc
 		return fmt.Errorf("Storage pool does not support custom volume type")
 	}
 
+	err = b.validateCustomVolumeExport(projectName, volName, contentType, config)
+	if err != nil {
+		return err
+	}
+
 	// Create database entry for new storage volume.
 	err = VolumeDBCreate(b.state, b, projectName, volName, desc, vol.Type(), false, vol.Config(), time.Time{}, vol.ContentType())
 	if err != nil {
@@ -2568,12 +2665,107 @@ func (b *lxdBackend) CreateCustomVolume(projectName string, volName string, desc
 		return err
 	}
 
+	// Export the volume if requested.
+	err = b.updateCustomVolumeExport(projectName, volName, nil, config, op)
+	if err != nil {
+		b.driver.DeleteVolume(vol, op)
+		return err
+	}
+
 	b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeCreated.Event(vol, string(vol.Type()), projectName, op, log.Ctx{"type": vol.Type()}))
 
 	revertDB = false
 	return nil
 }
 
+// CreateCustomVolumeFromISO creates a custom ISO volume from the supplied ISO image.
+func (b *lxdBackend) CreateCustomVolumeFromISO(projectName string, volName string, srcData io.ReadSeeker, size int64, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "size": size})
+	logger.Debug("CreateCustomVolumeFromISO started")
+	defer logger.Debug("CreateCustomVolumeFromISO finished")
+
+	if b.Status() == api.StoragePoolStatusPending {
+		return fmt.Errorf("Specified pool is not fully created")
+	}
+
+	if size <= 0 {
+		return fmt.Errorf("Invalid ISO image size %d", size)
+	}
+
+	storagePoolSupported := false
+	for _, supportedType := range b.Driver().Info().VolumeTypes {
+		if supportedType == drivers.VolumeTypeCustom {
+			storagePoolSupported = true
+			break
+		}
+	}
+
+	if !storagePoolSupported {
+		return fmt.Errorf("Storage pool does not support custom volume type")
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(projectName, volName)
+
+	// The volume is sized to fit the ISO image.
+	config := map[string]string{
+		"size": fmt.Sprintf("%d", size),
+	}
+
+	// Validate config.
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeISO, volStorageName, config)
+	err := b.driver.ValidateVolume(vol, false)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Create database entry for new storage volume.
+	err = VolumeDBCreate(b.state, b, projectName, volName, "", vol.Type(), false, vol.Config(), time.Time{}, drivers.ContentTypeISO)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.state.Cluster.RemoveStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
+	})
+
+	volFiller := drivers.VolumeFiller{
+		Fill: func(vol drivers.Volume, rootBlockPath string, allowUnsafeResize bool) (int64, error) {
+			_, err := srcData.Seek(0, 0)
+			if err != nil {
+				return -1, err
+			}
+
+			to, err := os.OpenFile(rootBlockPath, os.O_CREATE|os.O_WRONLY, 0600)
+			if err != nil {
+				return -1, errors.Wrapf(err, "Failed opening %q", rootBlockPath)
+			}
+			defer to.Close()
+
+			n, err := io.Copy(to, srcData)
+			if err != nil {
+				return -1, errors.Wrapf(err, "Failed writing ISO image to %q", rootBlockPath)
+			}
+
+			return n, to.Close()
+		},
+	}
+
+	// Create the custom volume on the storage device and fill it with the ISO image.
+	err = b.driver.CreateVolume(vol, &volFiller, op)
+	if err != nil {
+		return err
+	}
+
+	b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeCreated.Event(vol, string(vol.Type()), projectName, op, log.Ctx{"type": vol.Type()}))
+
+	revert.Success()
+	return nil
+}
+
 // CreateCustomVolumeFromCopy creates a custom volume from an existing custom volume.
 // It copies the snapshots from the source volume by default, but can be disabled if requested.
 func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectName string, volName string, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
@@ -2624,6 +2816,9 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 		config = srcVolRow.Config
 	}
 
+	// Exports aren't copied, they need to be set up on the new volume.
+	config = withoutExportConfig(config)
+
 	// Use the source volume's description if not supplied.
 	if desc == "" {
 		desc = srcVolRow.Description
@@ -2635,12 +2830,14 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 	}
 
 	// Get the source volume's content type.
-	contentType := drivers.ContentTypeFS
-
-	if contentDBType == db.StoragePoolVolumeContentTypeBlock {
-		contentType = drivers.ContentTypeBlock
+	contentType, err := VolumeDBContentTypeToContentType(contentDBType)
+	if err != nil {
+		return err
 	}
 
+	// Keep the content type recorded in the DB (ISO volumes are block volumes to the storage drivers).
+	volDBContentType := drivers.ContentType(srcVolRow.ContentType)
+
 	storagePoolSupported := false
 	for _, supportedType := range b.Driver().Info().VolumeTypes {
 		if supportedType == drivers.VolumeTypeCustom {
@@ -2696,7 +2893,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 		}
 
 		// Create database entry for new storage volume.
-		err = VolumeDBCreate(b.state, b, projectName, volName, desc, vol.Type(), false, vol.Config(), time.Time{}, vol.ContentType())
+		err = VolumeDBCreate(b.state, b, projectName, volName, desc, vol.Type(), false, vol.Config(), time.Time{}, volDBContentType)
 		if err != nil {
 			return err
 		}
@@ -2708,7 +2905,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 				newSnapshotName := drivers.GetSnapshotVolumeName(volName, snapName)
 
 				// Create database entry for new storage volume snapshot.
-				err = VolumeDBCreate(b.state, b, projectName, newSnapshotName, desc, vol.Type(), true, vol.Config(), time.Time{}, vol.ContentType())
+				err = VolumeDBCreate(b.state, b, projectName, newSnapshotName, desc, vol.Type(), true, vol.Config(), time.Time{}, volDBContentType)
 				if err != nil {
 					return err
 				}
@@ -2796,7 +2993,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 			Snapshots:     snapshotNames,
 			MigrationType: migrationTypes[0],
 			TrackProgress: false, // Do not use a progress tracker on receiver.
-			ContentType:   string(contentType),
+			ContentType:   string(volDBContentType),
 			VolumeSize:    volSize, // Block size setting override.
 		}, op)
 
@@ -2908,7 +3105,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	}
 
 	// Create database entry for new storage volume.
-	err = VolumeDBCreate(b.state, b, projectName, args.Name, args.Description, vol.Type(), false, vol.Config(), time.Time{}, vol.ContentType())
+	err = VolumeDBCreate(b.state, b, projectName, args.Name, args.Description, vol.Type(), false, vol.Config(), time.Time{}, drivers.ContentType(args.ContentType))
 	if err != nil {
 		return err
 	}
@@ -2920,7 +3117,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 			newSnapshotName := drivers.GetSnapshotVolumeName(args.Name, snapName)
 
 			// Create database entry for new storage volume snapshot.
-			err = VolumeDBCreate(b.state, b, projectName, newSnapshotName, args.Description, vol.Type(), true, vol.Config(), time.Time{}, vol.ContentType())
+			err = VolumeDBCreate(b.state, b, projectName, newSnapshotName, args.Description, vol.Type(), true, vol.Config(), time.Time{}, drivers.ContentType(args.ContentType))
 			if err != nil {
 				return err
 			}
@@ -3016,6 +3213,16 @@ func (b *lxdBackend) RenameCustomVolume(projectName string, volName string, newV
 	volStorageName := project.StorageVolume(projectName, volName)
 	newVolStorageName := project.StorageVolume(projectName, newVolName)
 
+	// Stop exporting the volume under its old name.
+	err = b.updateCustomVolumeExport(projectName, volName, volume.Config, nil, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.updateCustomVolumeExport(projectName, volName, nil, volume.Config, op)
+	})
+
 	// There's no need to pass the config as it's not needed when renaming a volume.
 	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentType(volume.ContentType), volStorageName, nil)
 
@@ -3024,6 +3231,12 @@ func (b *lxdBackend) RenameCustomVolume(projectName string, volName string, newV
 		return err
 	}
 
+	// Export the volume under its new name. The volume has been renamed at this point so don't fail.
+	err = b.updateCustomVolumeExport(projectName, newVolName, nil, volume.Config, op)
+	if err != nil {
+		logger.Warn("Failed exporting renamed volume", log.Ctx{"err": err})
+	}
+
 	vol = b.newVolume(drivers.VolumeTypeCustom, drivers.ContentType(volume.ContentType), newVolStorageName, nil)
 	b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeRenamed.Event(vol, string(vol.Type()), projectName, op, log.Ctx{"old_name": volName}))
 
@@ -3063,6 +3276,9 @@ func (b *lxdBackend) detectChangedConfig(curConfig, newConfig map[string]string)
 
 // UpdateCustomVolume applies the supplied config to the custom volume.
 func (b *lxdBackend) UpdateCustomVolume(projectName string, volName string, newDesc string, newConfig map[string]string, op *operations.Operation) error {
+	// Only store the hash of the SMB export password.
+	newConfig = hashExportPassword(newConfig)
+
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newDesc": newDesc, "newConfig": newConfig})
 	logger.Debug("UpdateCustomVolume started")
 	defer logger.Debug("UpdateCustomVolume finished")
@@ -3102,6 +3318,11 @@ func (b *lxdBackend) UpdateCustomVolume(projectName string, volName string, newD
 		return err
 	}
 
+	err = b.validateCustomVolumeExport(projectName, volName, contentType, newConfig)
+	if err != nil {
+		return err
+	}
+
 	// Apply config changes if there are any.
 	changedConfig, userOnly := b.detectChangedConfig(curVol.Config, newConfig)
 	if len(changedConfig) != 0 {
@@ -3110,6 +3331,11 @@ func (b *lxdBackend) UpdateCustomVolume(projectName string, volName string, newD
 			return fmt.Errorf("Custom volume 'block.filesystem' property cannot be changed")
 		}
 
+		// Check that the size of ISO volumes isn't being changed.
+		if changedConfig["size"] != "" && dbContentType == db.StoragePoolVolumeContentTypeISO {
+			return fmt.Errorf("Custom ISO volume 'size' property cannot be changed")
+		}
+
 		// Check that security.unmapped and security.shifted aren't set together.
 		if shared.IsTrue(newConfig["security.unmapped"]) && shared.IsTrue(newConfig["security.shifted"]) {
 			return fmt.Errorf("security.unmapped and security.shifted are mutually exclusive")
@@ -3158,6 +3384,18 @@ func (b *lxdBackend) UpdateCustomVolume(projectName string, volName string, newD
 		}
 	}
 
+	// Update the export if its settings changed.
+	for key := range changedConfig {
+		if strings.HasPrefix(key, "export.") {
+			err = b.updateCustomVolumeExport(projectName, volName, curVol.Config, newConfig, op)
+			if err != nil {
+				return err
+			}
+
+			break
+		}
+	}
+
 	b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeUpdated.Event(newVol, string(newVol.Type()), projectName, op, nil))
 
 	return nil
@@ -3255,6 +3493,12 @@ func (b *lxdBackend) DeleteCustomVolume(projectName string, volName string, op *
 		return err
 	}
 
+	// Stop exporting the volume.
+	err = b.updateCustomVolumeExport(projectName, volName, poolVol.Config, nil, op)
+	if err != nil {
+		return err
+	}
+
 	// There's no need to pass config as it's not needed when deleting a volume.
 	vol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, nil)
 
@@ -4396,7 +4640,7 @@ func (b *lxdBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData
 	}
 
 	// Create database entry for new storage volume using the validated config.
-	err = VolumeDBCreate(b.state, b, srcBackup.Project, srcBackup.Name, srcBackup.Config.Volume.Description, vol.Type(), false, vol.Config(), time.Time{}, vol.ContentType())
+	err = VolumeDBCreate(b.state, b, srcBackup.Project, srcBackup.Name, srcBackup.Config.Volume.Description, vol.Type(), false, vol.Config(), time.Time{}, drivers.ContentType(srcBackup.Config.Volume.ContentType))
 	if err != nil {
 		return err
 	}
@@ -4426,7 +4670,7 @@ func (b *lxdBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData
 			return err
 		}
 
-		err = VolumeDBCreate(b.state, b, srcBackup.Project, fullSnapName, snapshot.Description, snapVol.Type(), true, snapVol.Config(), *snapshot.ExpiresAt, snapVol.ContentType())
+		err = VolumeDBCreate(b.state, b, srcBackup.Project, fullSnapName, snapshot.Description, snapVol.Type(), true, snapVol.Config(), *snapshot.ExpiresAt, drivers.ContentType(srcBackup.Config.Volume.ContentType))
 		if err != nil {
 			return err
 		}
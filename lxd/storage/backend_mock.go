@@ -73,6 +73,10 @@ func (b *mockBackend) Update(clientType request.ClientType, newDescription strin
 	return nil
 }
 
+func (b *mockBackend) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) Create(clientType request.ClientType, op *operations.Operation) error {
 	return nil
 }
@@ -217,6 +221,10 @@ func (b *mockBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectN
 	return nil
 }
 
+func (b *mockBackend) CreateCustomVolumeFromISO(projectName string, volName string, srcData io.ReadSeeker, size int64, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) RenameCustomVolume(projectName string, volName string, newName string, op *operations.Operation) error {
 	return nil
 }
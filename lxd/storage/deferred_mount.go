@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/warnings"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// deferredMountMinDelay and deferredMountMaxDelay bound the delay between two attempts at mounting a pool.
+const deferredMountMinDelay = 10 * time.Second
+const deferredMountMaxDelay = 10 * time.Minute
+
+// deferredPools holds the names of the pools which weren't mounted at startup and are being mounted in the
+// background instead.
+var deferredPools = map[string]bool{}
+var deferredPoolsMu sync.Mutex
+
+// DeferPoolMount mounts the pool in the background rather than now, mountErr being the error of a failed attempt
+// at mounting it if any. On failure, the local status of the pool is set to errored, a warning is recorded and
+// mounting is attempted again after an increasing delay until it succeeds or LXD stops.
+func DeferPoolMount(s *state.State, pool Pool, mountErr error) {
+	deferredPoolsMu.Lock()
+	if deferredPools[pool.Name()] {
+		deferredPoolsMu.Unlock()
+		return
+	}
+
+	deferredPools[pool.Name()] = true
+	deferredPoolsMu.Unlock()
+
+	delay := time.Duration(0)
+	if mountErr != nil {
+		deferredPoolMountFailed(s, pool, mountErr)
+		delay = deferredMountMinDelay
+	}
+
+	go func(poolName string) {
+		defer func() {
+			deferredPoolsMu.Lock()
+			delete(deferredPools, poolName)
+			deferredPoolsMu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-time.After(delay):
+			case <-s.Context.Done():
+				return
+			}
+
+			if mountDeferredPool(s, poolName) {
+				return
+			}
+
+			delay *= 2
+			if delay < deferredMountMinDelay {
+				delay = deferredMountMinDelay
+			} else if delay > deferredMountMaxDelay {
+				delay = deferredMountMaxDelay
+			}
+		}
+	}(pool.Name())
+}
+
+// IsPoolMountDeferred returns whether the pool is being mounted in the background rather than being mounted already.
+func IsPoolMountDeferred(poolName string) bool {
+	deferredPoolsMu.Lock()
+	defer deferredPoolsMu.Unlock()
+
+	return deferredPools[poolName]
+}
+
+// mountDeferredPool attempts to mount a deferred pool. Returns whether there is no need to try again, either
+// because the pool got mounted or because it's gone.
+func mountDeferredPool(s *state.State, poolName string) bool {
+	pool, err := GetPoolByName(s, poolName)
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return true
+		}
+
+		logger.Warn("Failed loading deferred storage pool", log.Ctx{"pool": poolName, "err": err})
+		return false
+	}
+
+	_, err = pool.Mount()
+	if err != nil {
+		logger.Warn("Failed mounting deferred storage pool", log.Ctx{"pool": poolName, "err": err})
+		deferredPoolMountFailed(s, pool, err)
+		return false
+	}
+
+	if pool.LocalStatus() == api.StoragePoolStatusErrored {
+		err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.StoragePoolNodeCreated(pool.ID())
+		})
+		if err != nil {
+			logger.Warn("Failed to mark storage pool as created", log.Ctx{"pool": poolName, "err": err})
+		}
+	}
+
+	err = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, "", db.WarningStoragePoolUnavailable, dbCluster.TypeStoragePool, int(pool.ID()))
+	if err != nil {
+		logger.Warn("Failed to resolve storage pool unavailable warning", log.Ctx{"pool": poolName, "err": err})
+	}
+
+	return true
+}
+
+// deferredPoolMountFailed marks the pool as errored on the local member and records a warning.
+func deferredPoolMountFailed(s *state.State, pool Pool, mountErr error) {
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.StoragePoolNodeErrored(pool.ID())
+	})
+	if err != nil {
+		logger.Warn("Failed to mark storage pool as errored", log.Ctx{"pool": pool.Name(), "err": err})
+	}
+
+	err = s.Cluster.UpsertWarningLocalNode("", dbCluster.TypeStoragePool, int(pool.ID()), db.WarningStoragePoolUnavailable, mountErr.Error())
+	if err != nil {
+		logger.Warn("Failed to create storage pool unavailable warning", log.Ctx{"pool": pool.Name(), "err": err})
+	}
+}
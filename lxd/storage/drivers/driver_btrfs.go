@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
@@ -15,12 +16,14 @@ import (
 	"github.com/lxc/lxd/lxd/storage/filesystem"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/validate"
 )
 
 var btrfsVersion string
 var btrfsLoaded bool
+var btrfsCompressedSend bool
 
 type btrfs struct {
 	common
@@ -64,6 +67,9 @@ func (d *btrfs) load() error {
 		}
 	}
 
+	// Detect support for sending compressed extents (requires btrfs-progs and kernel 5.18 or later).
+	btrfsCompressedSend = btrfsSupportsCompressedSend()
+
 	btrfsLoaded = true
 	return nil
 }
@@ -266,7 +272,28 @@ func (d *btrfs) Validate(config map[string]string) error {
 
 // Update applies any driver changes required from a configuration change.
 func (d *btrfs) Update(changedConfig map[string]string) error {
-	// We only care about btrfs.mount_options.
+	// A new source must be an existing btrfs device, path or filesystem UUID, it's used the next time the
+	// pool is mounted.
+	source, ok := changedConfig["source"]
+	if ok {
+		if filepath.IsAbs(source) {
+			sourcePath := shared.HostPath(source)
+			if !shared.PathExists(sourcePath) {
+				return fmt.Errorf("Source path %q doesn't exist", source)
+			}
+
+			if !shared.IsBlockdevPath(sourcePath) && source != loopFilePath(d.name) {
+				sourceFS, _ := filesystem.Detect(sourcePath)
+				if sourceFS != "btrfs" {
+					return fmt.Errorf("Source path %q isn't btrfs", source)
+				}
+			}
+		} else if !shared.PathExists(fmt.Sprintf("/dev/disk/by-uuid/%s", source)) {
+			return fmt.Errorf("Filesystem with UUID %q doesn't exist", source)
+		}
+	}
+
+	// Otherwise we only care about btrfs.mount_options.
 	val, ok := changedConfig["btrfs.mount_options"]
 	if !ok {
 		return nil
@@ -380,6 +407,74 @@ func (d *btrfs) Unmount() (bool, error) {
 	return ourUnmount, nil
 }
 
+// MoveToBlockDevice moves a loop file backed pool onto the block device while in use. The loop device is
+// replaced by the block device in the filesystem, then the filesystem is grown to its size.
+func (d *btrfs) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	loopPath := loopFilePath(d.name)
+	if d.config["source"] != loopPath {
+		return fmt.Errorf("Only loop file backed pools can be moved onto a block device")
+	}
+
+	mountPath := GetPoolMountPath(d.name)
+
+	// Get the loop device the filesystem is mounted from.
+	loopF, err := PrepareLoopDev(loopPath, LoFlagsAutoclear)
+	if err != nil {
+		return err
+	}
+	defer loopF.Close()
+
+	_, err = shared.RunCommand("btrfs", "replace", "start", "-f", loopF.Name(), devPath, mountPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed replacing the loop device with %q", devPath)
+	}
+
+	// Wait for the data to be copied onto the block device.
+	for {
+		out, err := shared.RunCommand("btrfs", "replace", "status", "-1", mountPath)
+		if err != nil {
+			return err
+		}
+
+		out = strings.TrimSpace(out)
+		if strings.HasPrefix(out, "Started on") && strings.Contains(out, "finished on") {
+			break
+		}
+
+		if !strings.Contains(out, "% done") {
+			return fmt.Errorf("Failed replacing the loop device with %q: %s", devPath, out)
+		}
+
+		d.moveProgress(op, out)
+		time.Sleep(5 * time.Second)
+	}
+
+	// Grow the filesystem to the size of the block device.
+	_, err = shared.RunCommand("btrfs", "filesystem", "resize", "max", mountPath)
+	if err != nil {
+		d.logger.Warn("Failed growing the filesystem onto the block device", log.Ctx{"dev": devPath, "err": err})
+	}
+
+	// The loop device is released when closed.
+	loopF.Close()
+
+	err = os.Remove(loopPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing loop file %q", loopPath)
+	}
+
+	// Record the filesystem UUID as the source, as when created on a block device.
+	d.config["source"] = devPath
+	d.config["size"] = ""
+
+	devUUID, err := fsUUID(devPath)
+	if err == nil && tryExists(fmt.Sprintf("/dev/disk/by-uuid/%s", devUUID)) {
+		d.config["source"] = devUUID
+	}
+
+	return nil
+}
+
 // GetResources returns the pool resource usage information.
 func (d *btrfs) GetResources() (*api.ResourcesStoragePool, error) {
 	return genericVFSGetResources(d)
@@ -390,6 +485,10 @@ func (d *btrfs) MigrationTypes(contentType ContentType, refresh bool) []migratio
 	var rsyncFeatures []string
 	btrfsFeatures := []string{migration.BTRFSFeatureMigrationHeader, migration.BTRFSFeatureSubvolumes}
 
+	if btrfsCompressedSend {
+		btrfsFeatures = append(btrfsFeatures, migration.BTRFSFeatureCompressedData)
+	}
+
 	// Do not pass compression argument to rsync if the associated
 	// config key, that is rsync.compression, is set to false.
 	if d.Config()["rsync.compression"] != "" && !shared.IsTrue(d.Config()["rsync.compression"]) {
@@ -398,6 +497,11 @@ func (d *btrfs) MigrationTypes(contentType ContentType, refresh bool) []migratio
 		rsyncFeatures = []string{"xattrs", "delete", "compress", "bidirectional"}
 	}
 
+	// Block volumes can be transferred as the chunks which differ from the target.
+	if contentType == ContentTypeBlock {
+		rsyncFeatures = append(rsyncFeatures, migration.BlockFeatureDelta)
+	}
+
 	// Only offer rsync for refreshes or if running in an unprivileged container.
 	if refresh || d.state.OS.RunningInUserNS {
 		var transportType migration.MigrationFSType
@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
 )
 
 // Errors
@@ -230,12 +231,15 @@ func (d *btrfs) getQGroup(path string) (string, int64, error) {
 	return qgroup, usage, nil
 }
 
-func (d *btrfs) sendSubvolume(path string, parent string, conn io.ReadWriteCloser, tracker *ioprogress.ProgressTracker) error {
+func (d *btrfs) sendSubvolume(path string, parent string, compressed bool, conn io.ReadWriteCloser, tracker *ioprogress.ProgressTracker) error {
 	// Assemble btrfs send command.
 	args := []string{"send"}
 	if parent != "" {
 		args = append(args, "-p", parent)
 	}
+	if compressed {
+		args = append(args, "--compressed-data")
+	}
 	args = append(args, path)
 	cmd := exec.Command("btrfs", args...)
 
@@ -359,7 +363,8 @@ func (d *btrfs) getSubvolumesMetaData(vol Volume) ([]BTRFSSubVolume, error) {
 // BTRFSMetaDataHeader is the meta data header about the volumes being sent/stored.
 // Note: This is used by both migration and backup subsystems so do not modify without considering both!
 type BTRFSMetaDataHeader struct {
-	Subvolumes []BTRFSSubVolume `json:"subvolumes" yaml:"subvolumes"` // Sub volumes inside the volume (including the top level ones).
+	Subvolumes     []BTRFSSubVolume `json:"subvolumes" yaml:"subvolumes"`                               // Sub volumes inside the volume (including the top level ones).
+	CompressedData bool             `json:"compressed_data,omitempty" yaml:"compressed_data,omitempty"` // Whether the subvolumes were sent with their compressed extents.
 }
 
 // restorationHeader scans the volume and any specified snapshots, returning a header containing subvolume metadata
@@ -475,3 +480,29 @@ func (d *btrfs) receiveSubVolume(r io.Reader, receivePath string) (string, error
 
 	return subVolPath, nil
 }
+
+// btrfsSupportsCompressedSend returns whether both the btrfs tool and the kernel support sending compressed extents
+// as-is (btrfs send protocol version 2).
+func btrfsSupportsCompressedSend() bool {
+	minVersion, err := version.NewDottedVersion("5.18")
+	if err != nil {
+		return false
+	}
+
+	toolVersion, err := version.Parse(btrfsVersion)
+	if err != nil || toolVersion.Compare(minVersion) < 0 {
+		return false
+	}
+
+	uname, err := shared.Uname()
+	if err != nil {
+		return false
+	}
+
+	kernelVersion, err := version.Parse(uname.Release)
+	if err != nil || kernelVersion.Compare(minVersion) < 0 {
+		return false
+	}
+
+	return true
+}
@@ -161,6 +161,11 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		}
 	}
 
+	// Backups made with compressed extents can only be received by a btrfs tool supporting them.
+	if optimizedHeader != nil && optimizedHeader.CompressedData && !btrfsCompressedSend {
+		return nil, nil, fmt.Errorf("Backup contains compressed btrfs send streams which require btrfs-progs and kernel 5.18 or later")
+	}
+
 	// Populate optimized header with pseudo data for unified handling when backup doesn't contain the
 	// optimized header file. This approach can only be used to restore root subvolumes (not sub-subvolumes).
 	if optimizedHeader == nil {
@@ -874,6 +879,9 @@ func (d *btrfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *m
 		d.logger.Debug("Sent migration meta data header", log.Ctx{"name": vol.name})
 	}
 
+	// Keep the extents compressed if both sides support it.
+	compressed := shared.StringInSlice(migration.BTRFSFeatureCompressedData, volSrcArgs.MigrationType.Features)
+
 	// sendVolume sends a volume and its subvolumes (if negotiated subvolumes feature) to recipient.
 	sendVolume := func(v Volume, sourcePrefix string, parentPrefix string) error {
 		snapName := "" // Default to empty (sending main volume) from migrationHeader.Subvolumes.
@@ -928,7 +936,7 @@ func (d *btrfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *m
 			}
 
 			d.logger.Debug("Sending subvolume", log.Ctx{"name": v.name, "source": sourcePath, "parent": parentPath, "path": subVolume.Path})
-			err = d.sendSubvolume(sourcePath, parentPath, conn, wrapper)
+			err = d.sendSubvolume(sourcePath, parentPath, compressed, conn, wrapper)
 			if err != nil {
 				return errors.Wrapf(err, "Failed sending volume %v:%s", v.name, subVolume.Path)
 			}
@@ -1047,6 +1055,9 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 		return err
 	}
 
+	// Keep the extents compressed when supported, the restoring system then needs the same support.
+	optimizedHeader.CompressedData = btrfsCompressedSend
+
 	// Convert to YAML.
 	optimizedHeaderYAML, err := yaml.Marshal(&optimizedHeader)
 	if err != nil {
@@ -1074,6 +1085,9 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 		if parent != "" {
 			args = append(args, "-p", parent)
 		}
+		if optimizedHeader.CompressedData {
+			args = append(args, "--compressed-data")
+		}
 		args = append(args, path)
 
 		// Create temporary file to store output of btrfs send.
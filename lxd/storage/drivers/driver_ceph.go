@@ -247,17 +247,18 @@ func (d *ceph) Delete(op *operations.Operation) error {
 // Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
 func (d *ceph) Validate(config map[string]string) error {
 	rules := map[string]func(value string) error{
-		"ceph.cluster_name":          validate.IsAny,
-		"ceph.osd.force_reuse":       validate.Optional(validate.IsBool),
-		"ceph.osd.pg_num":            validate.IsAny,
-		"ceph.osd.pool_name":         validate.IsAny,
-		"ceph.osd.data_pool_name":    validate.IsAny,
-		"ceph.rbd.clone_copy":        validate.Optional(validate.IsBool),
-		"ceph.rbd.features":          validate.IsAny,
-		"ceph.user.name":             validate.IsAny,
-		"volatile.pool.pristine":     validate.IsAny,
-		"volume.block.filesystem":    validate.Optional(validate.IsOneOf(cephAllowedFilesystems...)),
-		"volume.block.mount_options": validate.IsAny,
+		"ceph.cluster_name":              validate.IsAny,
+		"ceph.osd.force_reuse":           validate.Optional(validate.IsBool),
+		"ceph.osd.namespace_per_project": validate.Optional(validate.IsBool),
+		"ceph.osd.pg_num":                validate.IsAny,
+		"ceph.osd.pool_name":             validate.IsAny,
+		"ceph.osd.data_pool_name":        validate.IsAny,
+		"ceph.rbd.clone_copy":            validate.Optional(validate.IsBool),
+		"ceph.rbd.features":              validate.IsAny,
+		"ceph.user.name":                 validate.IsAny,
+		"volatile.pool.pristine":         validate.IsAny,
+		"volume.block.filesystem":        validate.Optional(validate.IsOneOf(cephAllowedFilesystems...)),
+		"volume.block.mount_options":     validate.IsAny,
 	}
 
 	return d.validatePool(config, rules)
@@ -265,6 +266,11 @@ func (d *ceph) Validate(config map[string]string) error {
 
 // Update applies any driver changes required from a configuration change.
 func (d *ceph) Update(changedConfig map[string]string) error {
+	_, changed := changedConfig["ceph.osd.namespace_per_project"]
+	if changed {
+		return fmt.Errorf("ceph.osd.namespace_per_project cannot be changed")
+	}
+
 	return nil
 }
 
@@ -350,6 +356,11 @@ func (d *ceph) MigrationTypes(contentType ContentType, refresh bool) []migration
 		rsyncFeatures = []string{"delete", "compress", "bidirectional"}
 	}
 
+	// Block volumes can be transferred as the chunks which differ from the target.
+	if contentType == ContentTypeBlock {
+		rsyncFeatures = append(rsyncFeatures, migration.BlockFeatureDelta)
+	}
+
 	if refresh {
 		var transportType migration.MigrationFSType
 
@@ -13,8 +13,10 @@ import (
 	"time"
 
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/ioprogress"
@@ -35,6 +37,9 @@ var cephVolTypePrefixes = map[VolumeType]string{
 	VolumeTypeCustom:    db.StoragePoolVolumeTypeNameCustom,
 }
 
+// cephZombieSuffixRegex matches the random suffix added to the names of deleted volumes kept for their dependants.
+var cephZombieSuffixRegex = regexp.MustCompile(`_[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
 // osdPoolExists checks whether a given OSD pool exists.
 func (d *ceph) osdPoolExists() bool {
 	_, err := shared.RunCommand(
@@ -74,6 +79,98 @@ func (d *ceph) osdDeletePool() error {
 	return nil
 }
 
+// rbdNamespace returns the RBD namespace holding the volume. When ceph.osd.namespace_per_project is enabled this is
+// the volume's project, except for the default project and images (which are shared by all projects) that remain
+// in the default namespace.
+func (d *ceph) rbdNamespace(vol Volume) string {
+	if !shared.IsTrue(d.config["ceph.osd.namespace_per_project"]) {
+		return ""
+	}
+
+	if vol.volType == VolumeTypeImage || vol.volType == cephVolumeTypeZombieImage {
+		return ""
+	}
+
+	name, _, _ := shared.InstanceGetParentAndSnapshotName(vol.name)
+
+	// Instance volumes of the default project aren't project prefixed, so drop the suffix of deleted
+	// instance volumes before looking for the project separator (instance names can't contain underscores).
+	if strings.TrimPrefix(string(vol.volType), "zombie_") != string(VolumeTypeCustom) {
+		name = cephZombieSuffixRegex.ReplaceAllString(name, "")
+	}
+
+	idx := strings.Index(name, "_")
+	if idx < 0 || name[:idx] == project.Default {
+		return ""
+	}
+
+	return name[:idx]
+}
+
+// rbdListNamespaces returns the RBD namespaces of the OSD pool.
+func (d *ceph) rbdListNamespaces() ([]string, error) {
+	msg, err := shared.RunCommand(
+		"rbd",
+		"--id", d.config["ceph.user.name"],
+		"--cluster", d.config["ceph.cluster_name"],
+		"--format", "json",
+		"namespace",
+		"ls",
+		"--pool", d.config["ceph.osd.pool_name"])
+	if err != nil {
+		return nil, err
+	}
+
+	var data []struct {
+		Name string `json:"name"`
+	}
+
+	err = json.Unmarshal([]byte(msg), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(data))
+	for _, entry := range data {
+		namespaces = append(namespaces, entry.Name)
+	}
+
+	return namespaces, nil
+}
+
+// rbdEnsureNamespace creates the RBD namespace holding the volume if missing.
+func (d *ceph) rbdEnsureNamespace(vol Volume) error {
+	namespace := d.rbdNamespace(vol)
+	if namespace == "" {
+		return nil
+	}
+
+	namespaces, err := d.rbdListNamespaces()
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(namespace, namespaces) {
+		return nil
+	}
+
+	_, err = shared.RunCommand(
+		"rbd",
+		"--id", d.config["ceph.user.name"],
+		"--cluster", d.config["ceph.cluster_name"],
+		"namespace",
+		"create",
+		"--pool", d.config["ceph.osd.pool_name"],
+		"--namespace", namespace)
+	if err != nil {
+		return errors.Wrapf(err, "Failed creating RBD namespace %q", namespace)
+	}
+
+	d.logger.Debug("Created RBD namespace", log.Ctx{"namespace": namespace})
+
+	return nil
+}
+
 // rbdCreateVolume creates an RBD storage volume.
 // Note that the default set of features is intentionally limited
 // by passing --image-feature explicitly. This is done to ensure that
@@ -86,10 +183,14 @@ func (d *ceph) rbdCreateVolume(vol Volume, size string) error {
 		return err
 	}
 
+	err = d.rbdEnsureNamespace(vol)
+	if err != nil {
+		return err
+	}
+
 	cmd := []string{
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 	}
 
 	if d.config["ceph.rbd.features"] != "" {
@@ -107,7 +208,7 @@ func (d *ceph) rbdCreateVolume(vol Volume, size string) error {
 	cmd = append(cmd,
 		"--size", fmt.Sprintf("%dB", sizeBytes),
 		"create",
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 
 	_, err = shared.RunCommand("rbd", cmd...)
 	return err
@@ -123,9 +224,8 @@ func (d *ceph) rbdDeleteVolume(vol Volume) error {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"rm",
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return err
 	}
@@ -137,12 +237,11 @@ func (d *ceph) rbdDeleteVolume(vol Volume) error {
 // This will ensure that the RBD storage volume is accessible as a block device
 // in the /dev directory and is therefore necessary in order to mount it.
 func (d *ceph) rbdMapVolume(vol Volume) (string, error) {
-	rbdName := d.getRBDVolumeName(vol, "", false, false)
+	rbdName := d.getRBDVolumeName(vol, "", false, true)
 	devPath, err := shared.RunCommand(
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"map",
 		rbdName)
 	if err != nil {
@@ -164,7 +263,7 @@ func (d *ceph) rbdMapVolume(vol Volume) (string, error) {
 // This is a precondition in order to delete an RBD storage volume can.
 func (d *ceph) rbdUnmapVolume(vol Volume, unmapUntilEINVAL bool) error {
 	busyCount := 0
-	rbdVol := d.getRBDVolumeName(vol, "", false, false)
+	rbdVol := d.getRBDVolumeName(vol, "", false, true)
 
 	ourDeactivate := false
 
@@ -173,7 +272,6 @@ again:
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"unmap",
 		rbdVol)
 	if err != nil {
@@ -225,9 +323,8 @@ again:
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"unmap",
-		d.getRBDVolumeName(vol, snapshotName, false, false))
+		d.getRBDVolumeName(vol, snapshotName, false, true))
 	if err != nil {
 		runError, ok := err.(shared.RunError)
 		if ok {
@@ -256,11 +353,10 @@ func (d *ceph) rbdCreateVolumeSnapshot(vol Volume, snapshotName string) error {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"create",
 		"--snap", snapshotName,
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return err
 	}
@@ -275,11 +371,10 @@ func (d *ceph) rbdProtectVolumeSnapshot(vol Volume, snapshotName string) error {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"protect",
 		"--snap", snapshotName,
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		runError, ok := err.(shared.RunError)
 		if ok {
@@ -305,11 +400,10 @@ func (d *ceph) rbdUnprotectVolumeSnapshot(vol Volume, snapshotName string) error
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"unprotect",
 		"--snap", snapshotName,
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		runError, ok := err.(shared.RunError)
 		if ok {
@@ -329,6 +423,11 @@ func (d *ceph) rbdUnprotectVolumeSnapshot(vol Volume, snapshotName string) error
 
 // rbdCreateClone creates a clone from a protected RBD snapshot.
 func (d *ceph) rbdCreateClone(sourceVol Volume, sourceSnapshotName string, targetVol Volume) error {
+	err := d.rbdEnsureNamespace(targetVol)
+	if err != nil {
+		return err
+	}
+
 	cmd := []string{
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
@@ -351,7 +450,7 @@ func (d *ceph) rbdCreateClone(sourceVol Volume, sourceSnapshotName string, targe
 		d.getRBDVolumeName(sourceVol, sourceSnapshotName, false, true),
 		d.getRBDVolumeName(targetVol, "", false, true))
 
-	_, err := shared.RunCommand("rbd", cmd...)
+	_, err = shared.RunCommand("rbd", cmd...)
 	if err != nil {
 		return err
 	}
@@ -365,10 +464,9 @@ func (d *ceph) rbdListSnapshotClones(vol Volume, snapshotName string) ([]string,
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"children",
-		"--image", d.getRBDVolumeName(vol, "", false, false),
-		"--snap", snapshotName)
+		"--snap", snapshotName,
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return nil, err
 	}
@@ -469,9 +567,8 @@ func (d *ceph) rbdGetVolumeParent(vol Volume) (string, error) {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"info",
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return "", err
 	}
@@ -503,10 +600,9 @@ func (d *ceph) rbdDeleteVolumeSnapshot(vol Volume, snapshotName string) error {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"rm",
-		d.getRBDVolumeName(vol, snapshotName, false, false))
+		d.getRBDVolumeName(vol, snapshotName, false, true))
 	if err != nil {
 		return err
 	}
@@ -526,10 +622,9 @@ func (d *ceph) rbdListVolumeSnapshots(vol Volume) ([]string, error) {
 		"--id", d.config["ceph.user.name"],
 		"--format", "json",
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"ls",
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return []string{}, err
 	}
@@ -846,7 +941,7 @@ func (d *ceph) deleteVolumeSnapshot(vol Volume, snapshotName string) (int, error
 }
 
 // parseParent splits a string describing a RBD storage entity into its components.
-// This can be used on strings like: <osd-pool-name>/<lxd-specific-prefix>_<rbd-storage-volume>@<rbd-snapshot-name>
+// This can be used on strings like: <osd-pool-name>/[<namespace>/]<lxd-specific-prefix>_<rbd-storage-volume>@<rbd-snapshot-name>
 // and will return a Volume and snapshot name.
 func (d *ceph) parseParent(parent string) (Volume, string, error) {
 	vol := Volume{}
@@ -855,7 +950,7 @@ func (d *ceph) parseParent(parent string) (Volume, string, error) {
 	if idx == -1 {
 		return vol, "", fmt.Errorf("Pool delimiter not found")
 	}
-	slider := parent[(idx + 1):]
+	slider := parent[(strings.LastIndex(parent, "/") + 1):]
 	poolName := parent[:idx]
 
 	// Match image volumes and extract their various parts into a Volume struct.
@@ -903,7 +998,7 @@ func (d *ceph) parseParent(parent string) (Volume, string, error) {
 
 // parseClone splits a strings describing an RBD storage volume.
 // For example a string like
-// <osd-pool-name>/<lxd-specific-prefix>_<rbd-storage-volume>
+// <osd-pool-name>/[<namespace>/]<lxd-specific-prefix>_<rbd-storage-volume>
 // will be split into
 // <osd-pool-name>, <lxd-specific-prefix>, <rbd-storage-volume>
 func (d *ceph) parseClone(clone string) (string, string, string, error) {
@@ -911,7 +1006,7 @@ func (d *ceph) parseClone(clone string) (string, string, string, error) {
 	if idx == -1 {
 		return "", "", "", fmt.Errorf("Unexpected parsing error")
 	}
-	slider := clone[(idx + 1):]
+	slider := clone[(strings.LastIndex(clone, "/") + 1):]
 	poolName := clone[:idx]
 
 	volumeType := slider
@@ -987,6 +1082,17 @@ func (d *ceph) getRBDMappedDevPath(vol Volume, mapIfMissing bool) (bool, string,
 			continue
 		}
 
+		// Get the namespace for the RBD device (not reported by older kernels).
+		devNamespace, err := ioutil.ReadFile(fmt.Sprintf("/sys/devices/rbd/%s/pool_ns", fName))
+		if err != nil && !os.IsNotExist(err) {
+			return false, "", err
+		}
+
+		// Skip if the namespaces don't match.
+		if strings.TrimSpace(string(devNamespace)) != d.rbdNamespace(vol) {
+			continue
+		}
+
 		// Get the volume name for the RBD device.
 		devName, err := ioutil.ReadFile(fmt.Sprintf("/sys/devices/rbd/%s/name", fName))
 		if err != nil {
@@ -1097,10 +1203,15 @@ func (d *ceph) getRBDVolumeName(vol Volume, snapName string, zombie bool, withPo
 		out = fmt.Sprintf("zombie_%s", out)
 	}
 
-	// If needed, the output will be prefixed with the pool name, e.g.
-	// <pool>/<type>_<volname>@<snapname>.
+	// If needed, the output will be prefixed with the pool name (and namespace if any), e.g.
+	// <pool>/<type>_<volname>@<snapname> or <pool>/<namespace>/<type>_<volname>@<snapname>.
 	if withPoolName {
-		out = fmt.Sprintf("%s/%s", d.config["ceph.osd.pool_name"], out)
+		namespace := d.rbdNamespace(vol)
+		if namespace != "" {
+			out = fmt.Sprintf("%s/%s/%s", d.config["ceph.osd.pool_name"], namespace, out)
+		} else {
+			out = fmt.Sprintf("%s/%s", d.config["ceph.osd.pool_name"], out)
+		}
 	}
 
 	return out
@@ -1261,9 +1372,8 @@ func (d *ceph) resizeVolume(vol Volume, sizeBytes int64, allowShrink bool) error
 	args = append(args,
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"--size", fmt.Sprintf("%dB", sizeBytes),
-		d.getRBDVolumeName(vol, "", false, false),
+		d.getRBDVolumeName(vol, "", false, true),
 	)
 
 	// Resize the block device.
@@ -127,6 +127,7 @@ func Example_ceph_parseParent() {
 		"pool/container_bar@zombie_snapshot_ce77e971-6c1b-45c0-b193-dba9ec5e7d82",
 		"pool/container_test-project_c4.block",
 		"pool/zombie_container_test-project_c1_28e7a7ab-740a-490c-8118-7caf7810f83b@zombie_snapshot_1027f4ab-de11-4cee-8015-bd532a1fed76",
+		"pool/test-project/container_test-project_c4.block@snapshot_snap0",
 	}
 
 	for _, parent := range parents {
@@ -144,4 +145,74 @@ func Example_ceph_parseParent() {
 	// pool container bar  filesystem zombie_snapshot_ce77e971-6c1b-45c0-b193-dba9ec5e7d82 <nil>
 	// pool container test-project_c4  block  <nil>
 	// pool zombie_container test-project_c1_28e7a7ab-740a-490c-8118-7caf7810f83b  filesystem zombie_snapshot_1027f4ab-de11-4cee-8015-bd532a1fed76 <nil>
+	// pool container test-project_c4  block snapshot_snap0 <nil>
+}
+
+func Test_ceph_rbdNamespace(t *testing.T) {
+	d := &ceph{}
+	d.config = map[string]string{
+		"ceph.osd.pool_name":             "testosdpool",
+		"ceph.osd.namespace_per_project": "true",
+	}
+
+	tests := []struct {
+		vol       Volume
+		namespace string
+		rbdName   string
+	}{
+		{
+			NewVolume(nil, "testpool", VolumeTypeContainer, ContentTypeFS, "c1", nil, nil),
+			"",
+			"testosdpool/container_c1",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeContainer, ContentTypeFS, "c1_28e7a7ab-740a-490c-8118-7caf7810f83b", nil, nil),
+			"",
+			"testosdpool/container_c1_28e7a7ab-740a-490c-8118-7caf7810f83b",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeContainer, ContentTypeFS, "proj_c1/snap0", nil, nil),
+			"proj",
+			"testosdpool/proj/container_proj_c1@snapshot_snap0",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeVM, ContentTypeBlock, "proj_v1_28e7a7ab-740a-490c-8118-7caf7810f83b", nil, nil),
+			"proj",
+			"testosdpool/proj/virtual-machine_proj_v1_28e7a7ab-740a-490c-8118-7caf7810f83b.block",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeCustom, ContentTypeFS, "default_vol", nil, nil),
+			"",
+			"testosdpool/custom_default_vol",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeCustom, ContentTypeFS, "proj_28e7a7ab-740a-490c-8118-7caf7810f83b", nil, nil),
+			"proj",
+			"testosdpool/proj/custom_proj_28e7a7ab-740a-490c-8118-7caf7810f83b",
+		},
+		{
+			NewVolume(nil, "testpool", VolumeTypeImage, ContentTypeFS, "9e90b7b9ccdd", map[string]string{"block.filesystem": "ext4"}, nil),
+			"",
+			"testosdpool/image_9e90b7b9ccdd_ext4",
+		},
+	}
+
+	for _, tt := range tests {
+		namespace := d.rbdNamespace(tt.vol)
+		if namespace != tt.namespace {
+			t.Errorf("ceph.rbdNamespace(%q) = %q, want %q", tt.vol.name, namespace, tt.namespace)
+		}
+
+		rbdName := d.getRBDVolumeName(tt.vol, "", false, true)
+		if rbdName != tt.rbdName {
+			t.Errorf("ceph.getRBDVolumeName(%q) = %q, want %q", tt.vol.name, rbdName, tt.rbdName)
+		}
+	}
+
+	// Everything is in the default namespace unless enabled.
+	d.config["ceph.osd.namespace_per_project"] = ""
+	vol := NewVolume(nil, "testpool", VolumeTypeContainer, ContentTypeFS, "proj_c1", nil, nil)
+	if d.rbdNamespace(vol) != "" {
+		t.Errorf("ceph.rbdNamespace() returned a namespace while disabled")
+	}
 }
@@ -275,7 +275,6 @@ func (d *ceph) getVolumeSize(volumeName string) (int64, error) {
 		"--format", "json",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		volumeName,
 	)
 	if err != nil {
@@ -351,6 +350,11 @@ func (d *ceph) CreateVolumeFromCopy(vol Volume, srcVol Volume, copySnapshots boo
 	if !copySnapshots || len(snapshots) == 0 {
 		// If lightweight clone mode isn't enabled, perform a full copy of the volume.
 		if d.config["ceph.rbd.clone_copy"] != "" && !shared.IsTrue(d.config["ceph.rbd.clone_copy"]) {
+			err = d.rbdEnsureNamespace(vol)
+			if err != nil {
+				return err
+			}
+
 			_, err = shared.RunCommand(
 				"rbd",
 				"--id", d.config["ceph.user.name"],
@@ -617,7 +621,7 @@ func (d *ceph) DeleteVolume(vol Volume, op *operations.Operation) error {
 			return err
 		}
 
-		hasReadonlySnapshot := d.hasVolume(d.getRBDVolumeName(vol, "readonly", false, false))
+		hasReadonlySnapshot := d.hasVolume(d.getRBDVolumeName(vol, "readonly", false, true))
 		hasDependendantSnapshots := false
 
 		if hasReadonlySnapshot {
@@ -650,10 +654,9 @@ func (d *ceph) DeleteVolume(vol Volume, op *operations.Operation) error {
 				"rbd",
 				"--id", d.config["ceph.user.name"],
 				"--cluster", d.config["ceph.cluster_name"],
-				"--pool", d.config["ceph.osd.pool_name"],
 				"snap",
 				"purge",
-				d.getRBDVolumeName(vol, "", false, false))
+				d.getRBDVolumeName(vol, "", false, true))
 			if err != nil {
 				return err
 			}
@@ -709,7 +712,6 @@ func (d *ceph) hasVolume(rbdVolumeName string) bool {
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"info",
 		rbdVolumeName,
 	)
@@ -719,7 +721,7 @@ func (d *ceph) hasVolume(rbdVolumeName string) bool {
 
 // HasVolume indicates whether a specific volume exists on the storage pool.
 func (d *ceph) HasVolume(vol Volume) bool {
-	return d.hasVolume(d.getRBDVolumeName(vol, "", false, false))
+	return d.hasVolume(d.getRBDVolumeName(vol, "", false, true))
 }
 
 // FillVolumeConfig populate volume with default config.
@@ -815,8 +817,7 @@ func (d *ceph) GetVolumeUsage(vol Volume) (int64, error) {
 		"--format", "json",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
-		d.getRBDVolumeName(vol, "", false, false),
+		d.getRBDVolumeName(vol, "", false, true),
 	)
 	if err != nil {
 		return -1, err
@@ -978,86 +979,114 @@ func (d *ceph) GetVolumeDiskPath(vol Volume) (string, error) {
 func (d *ceph) ListVolumes() ([]Volume, error) {
 	vols := make(map[string]Volume)
 
-	cmd := exec.Command("rbd",
-		"--id", d.config["ceph.user.name"],
-		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
-		"ls",
-	)
+	// Volumes of projects may be held in their own namespaces.
+	namespaces := []string{""}
+	if shared.IsTrue(d.config["ceph.osd.namespace_per_project"]) {
+		projectNamespaces, err := d.rbdListNamespaces()
+		if err != nil {
+			return nil, err
+		}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
+		namespaces = append(namespaces, projectNamespaces...)
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
-	}
+	listNamespace := func(namespace string) error {
+		args := []string{
+			"--id", d.config["ceph.user.name"],
+			"--cluster", d.config["ceph.cluster_name"],
+			"--pool", d.config["ceph.osd.pool_name"],
+			"ls",
+		}
 
-	err = cmd.Start()
-	if err != nil {
-		return nil, err
-	}
+		if namespace != "" {
+			args = append(args, "--namespace", namespace)
+		}
+
+		cmd := exec.Command("rbd", args...)
 
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		rawName := strings.TrimSpace(scanner.Text())
-		var volType VolumeType
-		var volName string
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return err
+		}
 
-		for _, volumeType := range d.Info().VolumeTypes {
-			prefix := cephVolTypePrefixes[volumeType]
-			if prefix == "" {
-				continue // Unknown volume type.
+		err = cmd.Start()
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			rawName := strings.TrimSpace(scanner.Text())
+			var volType VolumeType
+			var volName string
+
+			for _, volumeType := range d.Info().VolumeTypes {
+				prefix := cephVolTypePrefixes[volumeType]
+				if prefix == "" {
+					continue // Unknown volume type.
+				}
+
+				prefix = fmt.Sprintf("%s_", prefix)
+
+				if strings.HasPrefix(rawName, prefix) {
+					volType = volumeType
+					volName = strings.TrimPrefix(rawName, prefix)
+				}
+			}
+
+			if volType == "" {
+				d.logger.Debug("Ignoring unrecognised volume type", log.Ctx{"name": rawName})
+				continue // Ignore unrecognised volume.
 			}
 
-			prefix = fmt.Sprintf("%s_", prefix)
+			isBlock := strings.HasSuffix(volName, cephBlockVolSuffix)
 
-			if strings.HasPrefix(rawName, prefix) {
-				volType = volumeType
-				volName = strings.TrimPrefix(rawName, prefix)
+			if volType == VolumeTypeVM && !isBlock {
+				continue // Ignore VM filesystem volumes as we will just return the VM's block volume.
 			}
-		}
 
-		if volType == "" {
-			d.logger.Debug("Ignoring unrecognised volume type", log.Ctx{"name": rawName})
-			continue // Ignore unrecognised volume.
-		}
+			contentType := ContentTypeFS
+			if volType == VolumeTypeVM || isBlock {
+				contentType = ContentTypeBlock
+				volName = strings.TrimSuffix(volName, cephBlockVolSuffix)
+			}
 
-		isBlock := strings.HasSuffix(volName, cephBlockVolSuffix)
+			// If a new volume has been found, or the volume will replace an existing image filesystem volume
+			// then proceed to add the volume to the map. We allow image volumes to overwrite existing
+			// filesystem volumes of the same name so that for VM images we only return the block content type
+			// volume (so that only the single "logical" volume is returned).
+			existingVol, foundExisting := vols[volName]
+			if !foundExisting || (existingVol.Type() == VolumeTypeImage && existingVol.ContentType() == ContentTypeFS) {
+				vols[volName] = NewVolume(d, d.name, volType, contentType, volName, make(map[string]string), d.config)
+				continue
+			}
 
-		if volType == VolumeTypeVM && !isBlock {
-			continue // Ignore VM filesystem volumes as we will just return the VM's block volume.
+			return fmt.Errorf("Unexpected duplicate volume %q found", volName)
 		}
 
-		contentType := ContentTypeFS
-		if volType == VolumeTypeVM || isBlock {
-			contentType = ContentTypeBlock
-			volName = strings.TrimSuffix(volName, cephBlockVolSuffix)
+		errMsg, err := ioutil.ReadAll(stderr)
+		if err != nil {
+			return err
 		}
 
-		// If a new volume has been found, or the volume will replace an existing image filesystem volume
-		// then proceed to add the volume to the map. We allow image volumes to overwrite existing
-		// filesystem volumes of the same name so that for VM images we only return the block content type
-		// volume (so that only the single "logical" volume is returned).
-		existingVol, foundExisting := vols[volName]
-		if !foundExisting || (existingVol.Type() == VolumeTypeImage && existingVol.ContentType() == ContentTypeFS) {
-			vols[volName] = NewVolume(d, d.name, volType, contentType, volName, make(map[string]string), d.config)
-			continue
+		err = cmd.Wait()
+		if err != nil {
+			return errors.Wrapf(err, "Failed getting volume list: %v", strings.TrimSpace(string(errMsg)))
 		}
 
-		return nil, fmt.Errorf("Unexpected duplicate volume %q found", volName)
-	}
-
-	errMsg, err := ioutil.ReadAll(stderr)
-	if err != nil {
-		return nil, err
+		return nil
 	}
 
-	err = cmd.Wait()
-	if err != nil {
-		return nil, errors.Wrapf(err, "Failed getting volume list: %v", strings.TrimSpace(string(errMsg)))
+	for _, namespace := range namespaces {
+		err := listNamespace(namespace)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	volList := make([]Volume, len(vols))
@@ -1427,9 +1456,8 @@ func (d *ceph) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) er
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"info",
-		d.getRBDVolumeName(snapVol, "", false, false))
+		d.getRBDVolumeName(snapVol, "", false, true))
 	if err != nil {
 		return nil
 	}
@@ -1665,11 +1693,10 @@ func (d *ceph) RestoreVolume(vol Volume, snapshotName string, op *operations.Ope
 		"rbd",
 		"--id", d.config["ceph.user.name"],
 		"--cluster", d.config["ceph.cluster_name"],
-		"--pool", d.config["ceph.osd.pool_name"],
 		"snap",
 		"rollback",
 		"--snap", fmt.Sprintf("snapshot_%s", snapshotName),
-		d.getRBDVolumeName(vol, "", false, false))
+		d.getRBDVolumeName(vol, "", false, true))
 	if err != nil {
 		return err
 	}
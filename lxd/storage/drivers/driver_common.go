@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	log "github.com/lxc/lxd/shared/log15"
@@ -149,6 +150,9 @@ func (d *common) MigrationTypes(contentType ContentType, refresh bool) []migrati
 
 	if contentType == ContentTypeBlock {
 		transportType = migration.MigrationFSType_BLOCK_AND_RSYNC
+
+		// Block volumes can be transferred as the chunks which differ from the target.
+		rsyncFeatures = append(rsyncFeatures, migration.BlockFeatureDelta)
 	} else {
 		transportType = migration.MigrationFSType_RSYNC
 	}
@@ -201,6 +205,20 @@ func (d *common) ApplyPatch(name string) error {
 	return patch()
 }
 
+// MoveToBlockDevice isn't supported by default.
+func (d *common) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	return ErrNotSupported
+}
+
+// moveProgress reports the progress of moving the pool onto a block device in the operation metadata.
+func (d *common) moveProgress(op *operations.Operation, progress string) {
+	if op == nil {
+		return
+	}
+
+	_ = op.UpdateMetadata(map[string]interface{}{"move_progress": progress})
+}
+
 // moveGPTAltHeader moves the GPT alternative header to the end of the disk device supplied.
 // If the device supplied is not detected as not being a GPT disk then no action is taken and nil is returned.
 // If the required sgdisk command is not available a warning is logged, but no error is returned, as really it is
@@ -104,6 +104,12 @@ func (d *dir) Validate(config map[string]string) error {
 
 // Update applies any driver changes required from a configuration change.
 func (d *dir) Update(changedConfig map[string]string) error {
+	// A new source must be an existing directory, it's used the next time the pool is mounted.
+	source, ok := changedConfig["source"]
+	if ok && !shared.IsDir(shared.HostPath(source)) {
+		return fmt.Errorf("Source path %q doesn't exist or isn't a directory", source)
+	}
+
 	return nil
 }
 
@@ -12,6 +12,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
@@ -110,6 +111,9 @@ func (d *lvm) Create() error {
 	var pvName string
 	var vgTags []string
 
+	// Additional physical devices to spread RAID volumes across.
+	raidDevices := util.SplitNTrimSpace(d.config["lvm.raid.devices"], ",", -1, true)
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -171,6 +175,12 @@ func (d *lvm) Create() error {
 			return fmt.Errorf("Custom loop file locations are not supported")
 		}
 
+		for _, raidDevice := range raidDevices {
+			if !shared.IsBlockdevPath(shared.HostPath(raidDevice)) {
+				return fmt.Errorf("RAID device %q isn't a block device", raidDevice)
+			}
+		}
+
 		// Check if the volume group already exists.
 		vgExists, vgTags, err = d.volumeGroupExists(d.config["lvm.vg_name"])
 		if err != nil {
@@ -213,6 +223,10 @@ func (d *lvm) Create() error {
 		return fmt.Errorf("Invalid source property")
 	}
 
+	if len(raidDevices) > 0 && !filepath.IsAbs(d.config["volatile.initial_source"]) {
+		return fmt.Errorf("The lvm.raid.devices key can only be used with a physical device source")
+	}
+
 	// This is an internal error condition which should never be hit.
 	if d.config["lvm.vg_name"] == "" {
 		return fmt.Errorf("No name for volume group detected")
@@ -279,12 +293,31 @@ func (d *lvm) Create() error {
 			revert.Add(func() { shared.TryRunCommand("pvremove", pvName) })
 		}
 
+		for _, raidDevice := range raidDevices {
+			raidDevice := raidDevice
+
+			exists, err := d.pysicalVolumeExists(raidDevice)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				continue
+			}
+
+			_, err = shared.TryRunCommand("pvcreate", raidDevice)
+			if err != nil {
+				return err
+			}
+			revert.Add(func() { shared.TryRunCommand("pvremove", raidDevice) })
+		}
+
 		// Create volume group.
-		_, err := shared.TryRunCommand("vgcreate", d.config["lvm.vg_name"], pvName)
+		_, err := shared.TryRunCommand("vgcreate", append([]string{d.config["lvm.vg_name"], pvName}, raidDevices...)...)
 		if err != nil {
 			return err
 		}
-		d.logger.Debug("Volume group created", log.Ctx{"pv_name": pvName, "vg_name": d.config["lvm.vg_name"]})
+		d.logger.Debug("Volume group created", log.Ctx{"pv_name": pvName, "raid_devices": raidDevices, "vg_name": d.config["lvm.vg_name"]})
 		revert.Add(func() { shared.TryRunCommand("vgremove", d.config["lvm.vg_name"]) })
 	}
 
@@ -301,6 +334,16 @@ func (d *lvm) Create() error {
 		})
 	}
 
+	// Attach the cache to the thin pool if requested.
+	if d.config["lvm.cache.device"] != "" {
+		err = d.attachCache(d.config["lvm.vg_name"], d.thinpoolName(), d.config["lvm.cache.device"], d.cacheMode(d.config))
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { d.detachCache(d.config["lvm.vg_name"], d.thinpoolName(), d.config["lvm.cache.device"]) })
+	}
+
 	// Mark the volume group with the lvmVgPoolMarker tag to indicate it is now in use by LXD.
 	_, err = shared.TryRunCommand("vgchange", "--addtag", lvmVgPoolMarker, d.config["lvm.vg_name"])
 	if err != nil {
@@ -436,6 +479,10 @@ func (d *lvm) Validate(config map[string]string) error {
 		"volume.lvm.stripes":         validate.Optional(validate.IsUint32),
 		"volume.lvm.stripes.size":    validate.Optional(validate.IsSize),
 		"lvm.vg.force_reuse":         validate.Optional(validate.IsBool),
+		"lvm.raid.type":              validate.Optional(validate.IsOneOf("raid1", "raid5")),
+		"lvm.raid.devices":           validate.IsAny,
+		"lvm.cache.device":           validate.IsAny,
+		"lvm.cache.mode":             validate.Optional(validate.IsOneOf("writethrough", "writeback", "writecache")),
 	}
 
 	err := d.validatePool(config, rules)
@@ -447,11 +494,42 @@ func (d *lvm) Validate(config map[string]string) error {
 		return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_name is set")
 	}
 
+	if v, found := config["lvm.use_thinpool"]; found && !shared.IsTrue(v) && config["lvm.cache.device"] != "" {
+		return fmt.Errorf("The key lvm.cache.device can only be used with a thin pool")
+	}
+
+	if config["lvm.raid.type"] == "raid1" && config["volume.lvm.stripes"] != "" {
+		return fmt.Errorf("The key volume.lvm.stripes cannot be used with raid1")
+	}
+
+	if config["lvm.raid.devices"] != "" && config["lvm.raid.type"] == "" {
+		return fmt.Errorf("The key lvm.raid.devices requires lvm.raid.type to be set")
+	}
+
 	return nil
 }
 
 // Update updates the storage pool settings.
 func (d *lvm) Update(changedConfig map[string]string) error {
+	// A new source must be an existing loop file or device, or an existing volume group.
+	source, ok := changedConfig["source"]
+	if ok {
+		if filepath.IsAbs(source) {
+			if !shared.PathExists(shared.HostPath(source)) {
+				return fmt.Errorf("Source path %q doesn't exist", source)
+			}
+		} else {
+			exists, _, err := d.volumeGroupExists(source)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return fmt.Errorf("LVM volume group %q doesn't exist", source)
+			}
+		}
+	}
+
 	if _, changed := changedConfig["lvm.use_thinpool"]; changed {
 		return fmt.Errorf("lvm.use_thinpool cannot be changed")
 	}
@@ -464,6 +542,44 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 		return fmt.Errorf("volume.lvm.stripes.size cannot be changed when using thin pool")
 	}
 
+	for _, key := range []string{"lvm.raid.type", "lvm.raid.devices"} {
+		if _, changed := changedConfig[key]; changed {
+			return fmt.Errorf("%s cannot be changed", key)
+		}
+	}
+
+	// Replace the thin pool cache if its device or mode changed.
+	_, cacheDeviceChanged := changedConfig["lvm.cache.device"]
+	_, cacheModeChanged := changedConfig["lvm.cache.mode"]
+	if cacheDeviceChanged || cacheModeChanged {
+		newConfig := make(map[string]string, len(d.config))
+		for k, v := range d.config {
+			newConfig[k] = v
+		}
+
+		for k, v := range changedConfig {
+			newConfig[k] = v
+		}
+
+		if newConfig["lvm.cache.device"] != "" && !d.usesThinpool() {
+			return fmt.Errorf("The key lvm.cache.device can only be used with a thin pool")
+		}
+
+		if d.config["lvm.cache.device"] != "" {
+			err := d.detachCache(d.config["lvm.vg_name"], d.thinpoolName(), d.config["lvm.cache.device"])
+			if err != nil {
+				return err
+			}
+		}
+
+		if newConfig["lvm.cache.device"] != "" {
+			err := d.attachCache(d.config["lvm.vg_name"], d.thinpoolName(), newConfig["lvm.cache.device"], d.cacheMode(newConfig))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	if changedConfig["lvm.vg_name"] != "" {
 		_, err := shared.TryRunCommand("vgrename", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"])
 		if err != nil {
@@ -529,6 +645,78 @@ func (d *lvm) Unmount() (bool, error) {
 	return false, nil
 }
 
+// MoveToBlockDevice moves a loop file backed pool onto the block device while in use. The block device is added
+// to the volume group, the extents are moved onto it and the loop device is then removed from the volume group.
+func (d *lvm) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	loopPath := loopFilePath(d.name)
+	if d.config["source"] != loopPath {
+		return fmt.Errorf("Only loop file backed pools can be moved onto a block device")
+	}
+
+	vgName := d.config["lvm.vg_name"]
+
+	loopFile, err := d.openLoopFile(loopPath)
+	if err != nil {
+		return err
+	}
+	defer loopFile.Close()
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	_, err = shared.TryRunCommand("pvcreate", devPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed creating physical volume on %q", devPath)
+	}
+
+	revert.Add(func() { shared.TryRunCommand("pvremove", devPath) })
+
+	_, err = shared.TryRunCommand("vgextend", vgName, devPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding %q to the volume group", devPath)
+	}
+
+	revert.Add(func() { shared.TryRunCommand("vgreduce", vgName, devPath) })
+
+	d.moveProgress(op, fmt.Sprintf("Moving extents from %s to %s", loopFile.Name(), devPath))
+
+	_, err = shared.RunCommand("pvmove", loopFile.Name(), devPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed moving the extents onto %q", devPath)
+	}
+
+	_, err = shared.TryRunCommand("vgreduce", vgName, loopFile.Name())
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing the loop device from the volume group")
+	}
+
+	revert.Success()
+
+	_, err = shared.TryRunCommand("pvremove", "-f", loopFile.Name())
+	if err != nil {
+		d.logger.Warn("Failed removing the physical volume of the loop device", log.Ctx{"dev": loopFile.Name(), "err": err})
+	}
+
+	// Release the loop device and remove its file.
+	err = SetAutoclearOnLoopDev(int(loopFile.Fd()))
+	if err != nil {
+		d.logger.Warn("Failed to set LO_FLAGS_AUTOCLEAR on loop device, manual cleanup needed", log.Ctx{"dev": loopFile.Name(), "err": err})
+	}
+
+	loopFile.Close()
+
+	err = os.Remove(loopPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing loop file %q", loopPath)
+	}
+
+	// The volume group is used directly from now on, as when created on a block device.
+	d.config["source"] = vgName
+	d.config["size"] = ""
+
+	return nil
+}
+
 // GetResources returns utilisation and space info about the pool.
 func (d *lvm) GetResources() (*api.ResourcesStoragePool, error) {
 	res := api.ResourcesStoragePool{}
@@ -49,6 +49,15 @@ func (d *lvm) thinpoolName() string {
 	return "LXDThinPool"
 }
 
+// cacheMode returns the cache mode from the given config, defaulting to writethrough.
+func (d *lvm) cacheMode(config map[string]string) string {
+	if config["lvm.cache.mode"] == "" {
+		return "writethrough"
+	}
+
+	return config["lvm.cache.mode"]
+}
+
 // openLoopFile opens a loopback file and disable auto detach.
 func (d *lvm) openLoopFile(source string) (*os.File, error) {
 	if source == "" {
@@ -214,6 +223,15 @@ func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) err
 
 	lvmThinPool := fmt.Sprintf("%s/%s", vgName, thinPoolName)
 
+	// RAID thin pools are made of separate RAID data and meta data volumes converted into a thin pool.
+	if d.config["lvm.raid.type"] != "" {
+		if !isRecent {
+			return fmt.Errorf("RAID thin pools require LVM 2.02.99 or newer")
+		}
+
+		return d.createRAIDThinPool(vgName, thinPoolName)
+	}
+
 	args := []string{
 		"--yes",
 		"--wipesignatures", "y",
@@ -229,19 +247,13 @@ func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) err
 
 	// Because the thin pool is created as an LVM volume, if the volume stripes option is set we need to apply
 	// it to the thin pool volume, as it cannot be applied to the thin volumes themselves.
-	if d.config["volume.lvm.stripes"] != "" {
-		args = append(args, "--stripes", d.config["volume.lvm.stripes"])
-
-		if d.config["volume.lvm.stripes.size"] != "" {
-			stripSizeBytes, err := d.roundedSizeBytesString(d.config["volume.lvm.stripes.size"])
-			if err != nil {
-				return errors.Wrapf(err, "Invalid volume stripe size %q", d.config["volume.lvm.stripes.size"])
-			}
-
-			args = append(args, "--stripesize", fmt.Sprintf("%db", stripSizeBytes))
-		}
+	stripeArgs, err := d.thinPoolStripeArgs()
+	if err != nil {
+		return err
 	}
 
+	args = append(args, stripeArgs...)
+
 	// Create the thin pool volume.
 	_, err = shared.TryRunCommand("lvcreate", args...)
 	if err != nil {
@@ -259,6 +271,171 @@ func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) err
 	return nil
 }
 
+// thinPoolStripeArgs returns the lvcreate arguments applying the pool's volume stripes settings.
+func (d *lvm) thinPoolStripeArgs() ([]string, error) {
+	if d.config["volume.lvm.stripes"] == "" {
+		return nil, nil
+	}
+
+	args := []string{"--stripes", d.config["volume.lvm.stripes"]}
+
+	if d.config["volume.lvm.stripes.size"] != "" {
+		stripSizeBytes, err := d.roundedSizeBytesString(d.config["volume.lvm.stripes.size"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid volume stripe size %q", d.config["volume.lvm.stripes.size"])
+		}
+
+		args = append(args, "--stripesize", fmt.Sprintf("%db", stripSizeBytes))
+	}
+
+	return args, nil
+}
+
+// raidArgs returns the lvcreate arguments needed to create a logical volume of the given RAID type.
+func (d *lvm) raidArgs(raidType string) []string {
+	switch raidType {
+	case "raid1":
+		return []string{"--type", "raid1", "--mirrors", "1"}
+	case "raid5":
+		return []string{"--type", "raid5"}
+	}
+
+	return nil
+}
+
+// createRAIDThinPool creates the default thinpool out of a RAID data volume using all the free space of the
+// volume group and a 1G RAID meta data volume.
+func (d *lvm) createRAIDThinPool(vgName, thinPoolName string) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	raidArgs := d.raidArgs(d.config["lvm.raid.type"])
+	metaName := fmt.Sprintf("%s_meta", thinPoolName)
+
+	// Create the meta data volume first so that the data volume can use the rest of the volume group.
+	args := append([]string{"--yes", "--wipesignatures", "y", "--name", metaName, "--size", "1G"}, raidArgs...)
+	args = append(args, vgName)
+
+	_, err := shared.TryRunCommand("lvcreate", args...)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating LVM thin pool meta data volume named %q", metaName)
+	}
+
+	revert.Add(func() { d.removeLogicalVolume(d.lvmDevPath(vgName, "", "", metaName)) })
+
+	stripeArgs, err := d.thinPoolStripeArgs()
+	if err != nil {
+		return err
+	}
+
+	args = append([]string{"--yes", "--wipesignatures", "y", "--name", thinPoolName, "--extents", "100%FREE"}, raidArgs...)
+	args = append(args, stripeArgs...)
+	args = append(args, vgName)
+
+	_, err = shared.TryRunCommand("lvcreate", args...)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating LVM thin pool data volume named %q", thinPoolName)
+	}
+
+	revert.Add(func() { d.removeLogicalVolume(d.lvmDevPath(vgName, "", "", thinPoolName)) })
+
+	_, err = shared.TryRunCommand("lvconvert", "--yes", "--type", "thin-pool", "--poolmetadata", fmt.Sprintf("%s/%s", vgName, metaName), fmt.Sprintf("%s/%s", vgName, thinPoolName))
+	if err != nil {
+		return errors.Wrapf(err, "Error converting LVM volume %q into a thin pool", thinPoolName)
+	}
+
+	revert.Success()
+	return nil
+}
+
+// cacheVolumeName returns the name of the logical volume holding the cache of the given logical volume.
+func (d *lvm) cacheVolumeName(lvName string) string {
+	return fmt.Sprintf("%s_cache", lvName)
+}
+
+// attachCache adds the cache device to the volume group and uses it to cache the given logical volume.
+// The mode is either one of the dm-cache modes (writethrough or writeback) or writecache.
+func (d *lvm) attachCache(vgName, lvName, cacheDevice, mode string) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	if !shared.IsBlockdevPath(shared.HostPath(cacheDevice)) {
+		return fmt.Errorf("Cache device %q isn't a block device", cacheDevice)
+	}
+
+	pvExists, err := d.pysicalVolumeExists(cacheDevice)
+	if err != nil {
+		return err
+	}
+
+	if !pvExists {
+		_, err = shared.TryRunCommand("pvcreate", cacheDevice)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { shared.TryRunCommand("pvremove", cacheDevice) })
+	}
+
+	_, err = shared.TryRunCommand("vgextend", vgName, cacheDevice)
+	if err != nil {
+		return errors.Wrapf(err, "Error adding cache device %q to volume group %q", cacheDevice, vgName)
+	}
+
+	revert.Add(func() { shared.TryRunCommand("vgreduce", vgName, cacheDevice) })
+
+	// Create the cache volume on the cache device only.
+	cacheName := d.cacheVolumeName(lvName)
+	_, err = shared.TryRunCommand("lvcreate", "--yes", "--wipesignatures", "y", "--name", cacheName, "--extents", "100%PVS", vgName, cacheDevice)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating LVM cache volume %q", cacheName)
+	}
+
+	revert.Add(func() { d.removeLogicalVolume(d.lvmDevPath(vgName, "", "", cacheName)) })
+
+	cacheVol := fmt.Sprintf("%s/%s", vgName, cacheName)
+	lv := fmt.Sprintf("%s/%s", vgName, lvName)
+
+	if mode == "writecache" {
+		_, err = shared.TryRunCommand("lvconvert", "--yes", "--type", "writecache", "--cachevol", cacheVol, lv)
+	} else {
+		_, err = shared.TryRunCommand("lvconvert", "--yes", "--type", "cache", "--cachevol", cacheVol, "--cachemode", mode, lv)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Error attaching LVM cache volume %q to %q", cacheName, lvName)
+	}
+
+	d.logger.Debug("Cache attached", log.Ctx{"vg_name": vgName, "lv_name": lvName, "cache_device": cacheDevice, "mode": mode})
+
+	revert.Success()
+	return nil
+}
+
+// detachCache flushes and removes the cache of the given logical volume and removes the cache device from the
+// volume group.
+func (d *lvm) detachCache(vgName, lvName, cacheDevice string) error {
+	// This flushes any dirty blocks and removes the cache volume.
+	_, err := shared.TryRunCommand("lvconvert", "--yes", "--uncache", fmt.Sprintf("%s/%s", vgName, lvName))
+	if err != nil {
+		return errors.Wrapf(err, "Error detaching LVM cache from %q", lvName)
+	}
+
+	_, err = shared.TryRunCommand("vgreduce", vgName, cacheDevice)
+	if err != nil {
+		return errors.Wrapf(err, "Error removing cache device %q from volume group %q", cacheDevice, vgName)
+	}
+
+	_, err = shared.TryRunCommand("pvremove", cacheDevice)
+	if err != nil {
+		d.logger.Warn("Failed to destroy the physical volume of the cache device", log.Ctx{"dev": cacheDevice, "err": err})
+	}
+
+	d.logger.Debug("Cache detached", log.Ctx{"vg_name": vgName, "lv_name": lvName, "cache_device": cacheDevice})
+
+	return nil
+}
+
 // lvmVersionIsAtLeast checks whether the installed version of LVM is at least the specific version.
 func (d *lvm) lvmVersionIsAtLeast(sTypeVersion string, versionString string) (bool, error) {
 	lvmVersionString := strings.Split(sTypeVersion, "/")[0]
@@ -329,6 +506,9 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 			vgName,
 		)
 
+		// Normal logical volumes are created with the pool's RAID type directly.
+		args = append(args, d.raidArgs(d.config["lvm.raid.type"])...)
+
 		// As we are creating a normal logical volume we can apply stripes settings if specified.
 		stripes := vol.ExpandedConfig("lvm.stripes")
 		if stripes != "" {
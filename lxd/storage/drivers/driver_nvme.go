@@ -0,0 +1,278 @@
+package drivers
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+var nvmeLoaded bool
+var nvmeVersion string
+
+var nvmeAllowedFilesystems = []string{"btrfs", "ext4", "xfs"}
+
+type nvme struct {
+	common
+}
+
+// load is used to run one-time action per-driver rather than per-pool.
+func (d *nvme) load() error {
+	// Register the patches.
+	d.patches = map[string]func() error{
+		"storage_create_vm":                        nil,
+		"storage_zfs_mount":                        nil,
+		"storage_create_vm_again":                  nil,
+		"storage_zfs_volmode":                      nil,
+		"storage_rename_custom_volume_add_project": nil,
+		"storage_lvm_skipactivation":               nil,
+	}
+
+	// Done if previously loaded.
+	if nvmeLoaded {
+		return nil
+	}
+
+	// Validate the required binaries.
+	for _, tool := range []string{"nvme", "sgdisk", "udevadm"} {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return fmt.Errorf("Required tool %q is missing", tool)
+		}
+	}
+
+	// Detect and record the version.
+	if nvmeVersion == "" {
+		out, err := shared.RunCommand("nvme", "version")
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(strings.TrimSpace(out))
+		if len(fields) > 2 && fields[0] == "nvme" && fields[1] == "version" {
+			nvmeVersion = fields[2]
+		} else {
+			nvmeVersion = strings.TrimSpace(out)
+		}
+	}
+
+	nvmeLoaded = true
+	return nil
+}
+
+// isRemote returns true indicating this driver uses remote storage.
+func (d *nvme) isRemote() bool {
+	return true
+}
+
+// Info returns info about the driver and its environment.
+func (d *nvme) Info() Info {
+	return Info{
+		Name:              "nvme",
+		Version:           nvmeVersion,
+		OptimizedImages:   false,
+		PreservesInodes:   false,
+		Remote:            d.isRemote(),
+		VolumeTypes:       []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM},
+		BlockBacking:      true,
+		RunningCopyFreeze: false,
+		DirectIO:          true,
+		MountedRoot:       false,
+	}
+}
+
+// Create is called during pool creation and is effectively using an empty driver struct.
+// WARNING: The Create() function cannot rely on any of the struct attributes being set.
+func (d *nvme) Create() error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	// The source is an alias for the subsystem NQN.
+	if d.config["nvme.nqn"] == "" {
+		d.config["nvme.nqn"] = d.config["source"]
+	} else if d.config["source"] != "" && d.config["source"] != d.config["nvme.nqn"] {
+		return fmt.Errorf("The source must match nvme.nqn")
+	}
+
+	if d.config["nvme.nqn"] == "" {
+		return fmt.Errorf("Missing required NVMe subsystem NQN")
+	}
+
+	if d.config["nvme.target.address"] == "" {
+		return fmt.Errorf("Missing required NVMe target address")
+	}
+
+	d.config["source"] = d.config["nvme.nqn"]
+
+	// Set default properties if missing.
+	if d.config["nvme.transport"] == "" {
+		d.config["nvme.transport"] = "tcp"
+	}
+
+	if d.config["nvme.target.port"] == "" {
+		d.config["nvme.target.port"] = nvmeDefaultPort
+	}
+
+	// Connect to the target.
+	ourConnect, err := d.Mount()
+	if err != nil {
+		return err
+	}
+
+	if ourConnect {
+		revert.Add(func() { d.Unmount() })
+	}
+
+	ctrl, err := d.controller()
+	if err != nil {
+		return err
+	}
+
+	// Check that the subsystem lets us manage namespaces.
+	info, err := d.identifyController(ctrl)
+	if err != nil {
+		return err
+	}
+
+	if info.OACS&nvmeOACSNamespaceManagement == 0 {
+		return fmt.Errorf("The NVMe subsystem %q doesn't support namespace management", d.config["nvme.nqn"])
+	}
+
+	revert.Success()
+	return nil
+}
+
+// Delete removes the storage pool from the storage device.
+func (d *nvme) Delete(op *operations.Operation) error {
+	// Disconnect from the target.
+	_, err := d.Unmount()
+	if err != nil {
+		return err
+	}
+
+	// Wipe everything in the storage pool directory.
+	err = wipeDirectory(GetPoolMountPath(d.name))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
+func (d *nvme) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		"nvme.transport":             validate.Optional(validate.IsOneOf("tcp", "rdma")),
+		"nvme.target.address":        validate.Optional(validate.IsNetworkAddress),
+		"nvme.target.port":           validate.Optional(validate.IsNetworkPort),
+		"nvme.nqn":                   validate.IsAny,
+		"nvme.host_nqn":              validate.IsAny,
+		"volume.block.filesystem":    validate.Optional(validate.IsOneOf(nvmeAllowedFilesystems...)),
+		"volume.block.mount_options": validate.IsAny,
+	}
+
+	return d.validatePool(config, rules)
+}
+
+// Update applies any driver changes required from a configuration change.
+func (d *nvme) Update(changedConfig map[string]string) error {
+	for _, key := range []string{"nvme.transport", "nvme.target.address", "nvme.target.port", "nvme.nqn", "nvme.host_nqn"} {
+		_, changed := changedConfig[key]
+		if changed {
+			return fmt.Errorf("%s cannot be changed", key)
+		}
+	}
+
+	return nil
+}
+
+// Mount connects to the NVMe target if not already connected.
+func (d *nvme) Mount() (bool, error) {
+	ctrl, err := d.controller()
+	if err != nil {
+		return false, err
+	}
+
+	if ctrl != "" {
+		return false, nil
+	}
+
+	args := []string{
+		"connect",
+		"--transport", d.config["nvme.transport"],
+		"--traddr", d.config["nvme.target.address"],
+		"--trsvcid", d.config["nvme.target.port"],
+		"--nqn", d.config["nvme.nqn"],
+	}
+
+	if d.config["nvme.host_nqn"] != "" {
+		args = append(args, "--hostnqn", d.config["nvme.host_nqn"])
+	}
+
+	_, err = shared.RunCommand("nvme", args...)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed connecting to NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	d.logger.Debug("Connected to NVMe subsystem", log.Ctx{"nqn": d.config["nvme.nqn"], "address": d.config["nvme.target.address"]})
+
+	// Wait for the namespaces to show up.
+	_, err = shared.RunCommand("udevadm", "settle")
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Unmount disconnects from the NVMe target if connected.
+func (d *nvme) Unmount() (bool, error) {
+	ctrl, err := d.controller()
+	if err != nil {
+		return false, err
+	}
+
+	if ctrl == "" {
+		return false, nil
+	}
+
+	_, err = shared.RunCommand("nvme", "disconnect", "--device", ctrl)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed disconnecting from NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	d.logger.Debug("Disconnected from NVMe subsystem", log.Ctx{"nqn": d.config["nvme.nqn"], "controller": ctrl})
+
+	return true, nil
+}
+
+// GetResources returns the pool resource usage information.
+func (d *nvme) GetResources() (*api.ResourcesStoragePool, error) {
+	ctrl, err := d.controller()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctrl == "" {
+		return nil, fmt.Errorf("Not connected to NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	info, err := d.identifyController(ctrl)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Total = info.TNVMCap
+	res.Space.Used = info.TNVMCap - info.UNVMCap
+
+	return &res, nil
+}
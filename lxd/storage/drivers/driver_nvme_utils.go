@@ -0,0 +1,465 @@
+package drivers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// nvmeDefaultPort is the default NVMe over fabrics service port.
+const nvmeDefaultPort = "4420"
+
+// nvmeOACSNamespaceManagement is the bit of the controller OACS field indicating namespace management support.
+const nvmeOACSNamespaceManagement = 1 << 3
+
+// nvmePartLabelPrefix is the prefix of the GPT partition labels used to identify LXD volumes on namespaces.
+const nvmePartLabelPrefix = "lxd-"
+
+// nvmeGPTOverhead is the space added to each namespace to hold the partition table around the volume.
+const nvmeGPTOverhead = 2 * 1024 * 1024
+
+// nvmeHiddenPathDevice matches the per-path block devices hidden behind a native multipath device.
+var nvmeHiddenPathDevice = regexp.MustCompile(`^nvme[0-9]+c[0-9]+n[0-9]+$`)
+
+// nvmeControllerInfo represents the fields of the identify controller data used by the driver.
+type nvmeControllerInfo struct {
+	OACS    uint64 `json:"oacs"`
+	TNVMCap uint64 `json:"tnvmcap"`
+	UNVMCap uint64 `json:"unvmcap"`
+}
+
+// nvmeNamespaceInfo represents the fields of the identify namespace data used by the driver.
+type nvmeNamespaceInfo struct {
+	LBAFs []struct {
+		DS uint `json:"ds"`
+	} `json:"lbafs"`
+}
+
+// nvmeReadSysfs returns the trimmed content of a sysfs file.
+func nvmeReadSysfs(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// controller returns the name of the fabrics controller connected to the pool's subsystem.
+// An empty name is returned if no matching connection exists.
+func (d *nvme) controller() (string, error) {
+	ctlPath := "/sys/class/nvme-fabrics/ctl"
+
+	entries, err := ioutil.ReadDir(ctlPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	for _, entry := range entries {
+		nqn, err := nvmeReadSysfs(filepath.Join(ctlPath, entry.Name(), "subsysnqn"))
+		if err != nil || nqn != d.config["nvme.nqn"] {
+			continue
+		}
+
+		transport, err := nvmeReadSysfs(filepath.Join(ctlPath, entry.Name(), "transport"))
+		if err != nil || transport != d.config["nvme.transport"] {
+			continue
+		}
+
+		address, err := nvmeReadSysfs(filepath.Join(ctlPath, entry.Name(), "address"))
+		if err != nil {
+			continue
+		}
+
+		fields := strings.Split(address, ",")
+		if !shared.StringInSlice(fmt.Sprintf("traddr=%s", d.config["nvme.target.address"]), fields) {
+			continue
+		}
+
+		if !shared.StringInSlice(fmt.Sprintf("trsvcid=%s", d.config["nvme.target.port"]), fields) {
+			continue
+		}
+
+		return entry.Name(), nil
+	}
+
+	return "", nil
+}
+
+// connectedController returns the name of the controller connected to the pool's subsystem, failing if there is none.
+func (d *nvme) connectedController() (string, error) {
+	ctrl, err := d.controller()
+	if err != nil {
+		return "", err
+	}
+
+	if ctrl == "" {
+		return "", fmt.Errorf("Not connected to NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	return ctrl, nil
+}
+
+// identifyController returns the identify controller data of the given controller.
+func (d *nvme) identifyController(ctrl string) (*nvmeControllerInfo, error) {
+	out, err := shared.RunCommand("nvme", "id-ctrl", filepath.Join("/dev", ctrl), "--output-format=json")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed identifying NVMe controller %q", ctrl)
+	}
+
+	info := nvmeControllerInfo{}
+	err = json.Unmarshal([]byte(out), &info)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing identify data of NVMe controller %q", ctrl)
+	}
+
+	return &info, nil
+}
+
+// blockSize returns the size in bytes of the first LBA format supported by the subsystem.
+func (d *nvme) blockSize(ctrl string) (int64, error) {
+	// The broadcast namespace ID returns the capabilities common to all namespaces.
+	out, err := shared.RunCommand("nvme", "id-ns", filepath.Join("/dev", ctrl), "--namespace-id=4294967295", "--output-format=json")
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed identifying namespace capabilities of NVMe controller %q", ctrl)
+	}
+
+	info := nvmeNamespaceInfo{}
+	err = json.Unmarshal([]byte(out), &info)
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed parsing namespace capabilities of NVMe controller %q", ctrl)
+	}
+
+	if len(info.LBAFs) == 0 || info.LBAFs[0].DS == 0 {
+		return 512, nil
+	}
+
+	return 1 << info.LBAFs[0].DS, nil
+}
+
+// controllerIDs returns the IDs of all the controllers of the subsystem, including those of other hosts.
+func (d *nvme) controllerIDs(ctrl string) ([]string, error) {
+	out, err := shared.RunCommand("nvme", "list-ctrl", filepath.Join("/dev", ctrl))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed listing controllers of NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	ids := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "]:", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSpace(fields[1]), 0, 16)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid NVMe controller ID %q", fields[1])
+		}
+
+		ids = append(ids, strconv.FormatUint(id, 10))
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("No controllers found on NVMe subsystem %q", d.config["nvme.nqn"])
+	}
+
+	return ids, nil
+}
+
+// createNamespace creates a namespace of at least sizeBytes and attaches it to all the controllers of the subsystem.
+func (d *nvme) createNamespace(ctrl string, sizeBytes int64) (uint32, error) {
+	blockSize, err := d.blockSize(ctrl)
+	if err != nil {
+		return 0, err
+	}
+
+	blocks := (sizeBytes + blockSize - 1) / blockSize
+	ctrlDevPath := filepath.Join("/dev", ctrl)
+
+	out, err := shared.RunCommand("nvme", "create-ns", ctrlDevPath, fmt.Sprintf("--nsze=%d", blocks), fmt.Sprintf("--ncap=%d", blocks), "--flbas=0")
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed creating NVMe namespace")
+	}
+
+	// Output is of the form "create-ns: Success, created nsid:<nsid>".
+	idx := strings.LastIndex(out, "nsid:")
+	if idx < 0 {
+		return 0, fmt.Errorf("Unexpected output from nvme create-ns: %q", strings.TrimSpace(out))
+	}
+
+	nsid, err := strconv.ParseUint(strings.TrimSpace(out[idx+len("nsid:"):]), 0, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed parsing NVMe namespace ID")
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	revert.Add(func() { shared.RunCommand("nvme", "delete-ns", ctrlDevPath, fmt.Sprintf("--namespace-id=%d", nsid)) })
+
+	ids, err := d.controllerIDs(ctrl)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = shared.RunCommand("nvme", "attach-ns", ctrlDevPath, fmt.Sprintf("--namespace-id=%d", nsid), fmt.Sprintf("--controllers=%s", strings.Join(ids, ",")))
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed attaching NVMe namespace %d", nsid)
+	}
+
+	err = d.rescan(ctrl)
+	if err != nil {
+		return 0, err
+	}
+
+	d.logger.Debug("Created NVMe namespace", log.Ctx{"nsid": nsid, "blocks": blocks, "controllers": ids})
+
+	revert.Success()
+	return uint32(nsid), nil
+}
+
+// deleteNamespace detaches a namespace from all the controllers of the subsystem and deletes it.
+func (d *nvme) deleteNamespace(ctrl string, nsid uint32) error {
+	ctrlDevPath := filepath.Join("/dev", ctrl)
+
+	ids, err := d.controllerIDs(ctrl)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("nvme", "detach-ns", ctrlDevPath, fmt.Sprintf("--namespace-id=%d", nsid), fmt.Sprintf("--controllers=%s", strings.Join(ids, ",")))
+	if err != nil {
+		return errors.Wrapf(err, "Failed detaching NVMe namespace %d", nsid)
+	}
+
+	_, err = shared.RunCommand("nvme", "delete-ns", ctrlDevPath, fmt.Sprintf("--namespace-id=%d", nsid))
+	if err != nil {
+		return errors.Wrapf(err, "Failed deleting NVMe namespace %d", nsid)
+	}
+
+	d.logger.Debug("Deleted NVMe namespace", log.Ctx{"nsid": nsid})
+
+	return d.rescan(ctrl)
+}
+
+// namespaceDevices returns the block devices of the subsystem's namespaces indexed by namespace ID.
+func (d *nvme) namespaceDevices() (map[uint32]string, error) {
+	entries, err := ioutil.ReadDir("/sys/block")
+	if err != nil {
+		return nil, err
+	}
+
+	devices := map[uint32]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "nvme") || nvmeHiddenPathDevice.MatchString(name) {
+			continue
+		}
+
+		nqn, err := nvmeReadSysfs(filepath.Join("/sys/block", name, "device", "subsysnqn"))
+		if err != nil || nqn != d.config["nvme.nqn"] {
+			continue
+		}
+
+		value, err := nvmeReadSysfs(filepath.Join("/sys/block", name, "nsid"))
+		if err != nil {
+			continue
+		}
+
+		nsid, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		devices[uint32(nsid)] = filepath.Join("/dev", name)
+	}
+
+	return devices, nil
+}
+
+// rescan picks up namespace and partition changes made to the subsystem, possibly by other hosts.
+func (d *nvme) rescan(ctrl string) error {
+	_, err := shared.RunCommand("nvme", "ns-rescan", filepath.Join("/dev", ctrl))
+	if err != nil {
+		return errors.Wrapf(err, "Failed rescanning namespaces of NVMe controller %q", ctrl)
+	}
+
+	_, err = shared.RunCommand("udevadm", "settle")
+	if err != nil {
+		return err
+	}
+
+	devices, err := d.namespaceDevices()
+	if err != nil {
+		return err
+	}
+
+	// Partition tables may have been changed by other hosts. Devices with mounted partitions can't be
+	// re-read, but those partitions are then in use here and so can't have been changed elsewhere.
+	for _, devPath := range devices {
+		shared.RunCommand("blockdev", "--rereadpt", devPath)
+	}
+
+	_, err = shared.RunCommand("udevadm", "settle")
+	return err
+}
+
+// partitionLabel returns the GPT partition label identifying the volume.
+// Labels are limited to 36 characters, so a truncated hash of the volume's identity is used.
+func (d *nvme) partitionLabel(volType VolumeType, contentType ContentType, volName string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%s", d.name, volType, contentType, volName)))
+	return fmt.Sprintf("%s%x", nvmePartLabelPrefix, hash[:16])
+}
+
+// volumeDevPath returns the path of the partition holding the volume.
+func (d *nvme) volumeDevPath(volType VolumeType, contentType ContentType, volName string) string {
+	return filepath.Join("/dev/disk/by-partlabel", d.partitionLabel(volType, contentType, volName))
+}
+
+// volumeNamespace returns the namespace block device and namespace ID holding the volume.
+func (d *nvme) volumeNamespace(vol Volume) (string, uint32, error) {
+	partDevPath, err := filepath.EvalSymlinks(d.volumeDevPath(vol.volType, vol.contentType, vol.name))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "Failed resolving device of volume %q", vol.name)
+	}
+
+	// The parent of the partition in sysfs is the namespace block device.
+	partSysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", filepath.Base(partDevPath)))
+	if err != nil {
+		return "", 0, err
+	}
+
+	nsName := filepath.Base(filepath.Dir(partSysPath))
+
+	value, err := nvmeReadSysfs(filepath.Join("/sys/block", nsName, "nsid"))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "Failed getting namespace ID of %q", nsName)
+	}
+
+	nsid, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return filepath.Join("/dev", nsName), uint32(nsid), nil
+}
+
+// activateVolume makes sure the volume's device exists, rescanning the subsystem if needed.
+func (d *nvme) activateVolume(vol Volume) (string, error) {
+	volDevPath := d.volumeDevPath(vol.volType, vol.contentType, vol.name)
+	if shared.PathExists(volDevPath) {
+		return volDevPath, nil
+	}
+
+	ctrl, err := d.connectedController()
+	if err != nil {
+		return "", err
+	}
+
+	err = d.rescan(ctrl)
+	if err != nil {
+		return "", err
+	}
+
+	if !shared.PathExists(volDevPath) {
+		return "", fmt.Errorf("No NVMe namespace found for volume %q", vol.name)
+	}
+
+	return volDevPath, nil
+}
+
+// createVolumeNamespace creates a namespace for the volume, partitions it and formats filesystem volumes.
+func (d *nvme) createVolumeNamespace(vol Volume) error {
+	sizeBytes, err := units.ParseByteSizeString(vol.ConfigSize())
+	if err != nil {
+		return err
+	}
+
+	ctrl, err := d.connectedController()
+	if err != nil {
+		return err
+	}
+
+	nsid, err := d.createNamespace(ctrl, sizeBytes+nvmeGPTOverhead)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	revert.Add(func() { d.deleteNamespace(ctrl, nsid) })
+
+	devices, err := d.namespaceDevices()
+	if err != nil {
+		return err
+	}
+
+	nsDevPath, ok := devices[nsid]
+	if !ok {
+		return fmt.Errorf("Block device of NVMe namespace %d not found", nsid)
+	}
+
+	// Create a single partition labelled after the volume so that all hosts can locate it.
+	label := d.partitionLabel(vol.volType, vol.contentType, vol.name)
+	_, err = shared.RunCommand("sgdisk", "--zap-all", "--new=1:0:0", fmt.Sprintf("--change-name=1:%s", label), nsDevPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed partitioning NVMe namespace %d", nsid)
+	}
+
+	_, err = shared.RunCommand("udevadm", "settle")
+	if err != nil {
+		return err
+	}
+
+	volDevPath := d.volumeDevPath(vol.volType, vol.contentType, vol.name)
+	if !shared.PathExists(volDevPath) {
+		return fmt.Errorf("Partition of NVMe namespace %d not found", nsid)
+	}
+
+	if vol.contentType == ContentTypeFS {
+		_, err = makeFSType(volDevPath, vol.ConfigBlockFilesystem(), nil)
+		if err != nil {
+			return errors.Wrapf(err, "Error making filesystem on NVMe namespace %d", nsid)
+		}
+	}
+
+	d.logger.Debug("Created NVMe volume", log.Ctx{"nsid": nsid, "dev": volDevPath})
+
+	revert.Success()
+	return nil
+}
+
+// renameVolumeNamespace relabels the partition of the volume after its new name.
+func (d *nvme) renameVolumeNamespace(vol Volume, newVolName string) error {
+	nsDevPath, _, err := d.volumeNamespace(vol)
+	if err != nil {
+		return err
+	}
+
+	label := d.partitionLabel(vol.volType, vol.contentType, newVolName)
+	_, err = shared.RunCommand("sgdisk", fmt.Sprintf("--change-name=1:%s", label), nsDevPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed relabelling partition of %q", nsDevPath)
+	}
+
+	_, err = shared.RunCommand("udevadm", "settle")
+	return err
+}
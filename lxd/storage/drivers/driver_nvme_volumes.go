@@ -0,0 +1,492 @@
+package drivers
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/storage/filesystem"
+	"github.com/lxc/lxd/shared/instancewriter"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+// CreateVolume creates an empty volume and can optionally fill it by executing the supplied filler function.
+func (d *nvme) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	volPath := vol.MountPath()
+	err := vol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+	revert.Add(func() { os.RemoveAll(volPath) })
+
+	err = d.createVolumeNamespace(vol)
+	if err != nil {
+		return err
+	}
+	revert.Add(func() { d.DeleteVolume(vol, op) })
+
+	// For VMs, also create the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.CreateVolume(fsVol, nil, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { d.DeleteVolume(fsVol, op) })
+	}
+
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		// Run the volume filler function if supplied.
+		if filler != nil && filler.Fill != nil {
+			var err error
+			var devPath string
+
+			if vol.contentType == ContentTypeBlock {
+				// Get the device path.
+				devPath, err = d.GetVolumeDiskPath(vol)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Run the filler.
+			err = d.runFiller(vol, devPath, filler)
+			if err != nil {
+				return err
+			}
+
+			// Move the GPT alt header to end of disk if needed.
+			if vol.IsVMBlock() {
+				err = d.moveGPTAltHeader(devPath)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if vol.contentType == ContentTypeFS {
+			// Run EnsureMountPath again after mounting and filling to ensure the mount directory has
+			// the correct permissions set.
+			err = vol.EnsureMountPath()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// CreateVolumeFromBackup restores a backup tarball onto the storage device.
+func (d *nvme) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) (VolumePostHook, revert.Hook, error) {
+	return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
+}
+
+// CreateVolumeFromCopy provides same-pool volume copying functionality.
+func (d *nvme) CreateVolumeFromCopy(vol, srcVol Volume, copySnapshots bool, op *operations.Operation) error {
+	// Namespaces have no snapshots, so there is never anything more than the volume itself to copy.
+	return genericVFSCopyVolume(d, nil, vol, srcVol, nil, false, op)
+}
+
+// CreateVolumeFromMigration creates a volume being sent via a migration.
+func (d *nvme) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, volTargetArgs migration.VolumeTargetArgs, preFiller *VolumeFiller, op *operations.Operation) error {
+	if len(volTargetArgs.Snapshots) > 0 {
+		return fmt.Errorf("Volume snapshots aren't supported by the NVMe driver")
+	}
+
+	return genericVFSCreateVolumeFromMigration(d, nil, vol, conn, volTargetArgs, preFiller, op)
+}
+
+// RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
+func (d *nvme) RefreshVolume(vol, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
+	return genericVFSCopyVolume(d, nil, vol, srcVol, nil, true, op)
+}
+
+// DeleteVolume deletes a volume of the storage device.
+func (d *nvme) DeleteVolume(vol Volume, op *operations.Operation) error {
+	if d.HasVolume(vol) {
+		if vol.contentType == ContentTypeFS {
+			_, err := d.UnmountVolume(vol, false, op)
+			if err != nil {
+				return errors.Wrapf(err, "Error unmounting NVMe volume")
+			}
+		}
+
+		_, nsid, err := d.volumeNamespace(vol)
+		if err != nil {
+			return err
+		}
+
+		ctrl, err := d.connectedController()
+		if err != nil {
+			return err
+		}
+
+		err = d.deleteNamespace(ctrl, nsid)
+		if err != nil {
+			return err
+		}
+	}
+
+	if vol.contentType == ContentTypeFS {
+		// Remove the volume from the storage device.
+		mountPath := vol.MountPath()
+		err := os.RemoveAll(mountPath)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Error removing NVMe volume mount path %q", mountPath)
+		}
+	}
+
+	// For VMs, also delete the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.DeleteVolume(fsVol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HasVolume indicates whether a specific volume exists on the storage pool.
+func (d *nvme) HasVolume(vol Volume) bool {
+	_, err := d.activateVolume(vol)
+	return err == nil
+}
+
+// FillVolumeConfig populate volume with default config.
+func (d *nvme) FillVolumeConfig(vol Volume) error {
+	// Only validate filesystem config keys for filesystem volumes or VM block volumes (which have an
+	// associated filesystem volume).
+	if vol.ContentType() == ContentTypeFS || vol.IsVMBlock() {
+		// Inherit filesystem from pool if not set.
+		if vol.config["block.filesystem"] == "" {
+			vol.config["block.filesystem"] = d.config["volume.block.filesystem"]
+		}
+
+		// Default filesystem if neither volume nor pool specify an override.
+		if vol.config["block.filesystem"] == "" {
+			// Unchangeable volume property: Set unconditionally.
+			vol.config["block.filesystem"] = DefaultFilesystem
+		}
+
+		// Inherit filesystem mount options from pool if not set.
+		if vol.config["block.mount_options"] == "" {
+			vol.config["block.mount_options"] = d.config["volume.block.mount_options"]
+		}
+
+		// Default filesystem mount options if neither volume nor pool specify an override.
+		if vol.config["block.mount_options"] == "" {
+			// Unchangeable volume property: Set unconditionally.
+			vol.config["block.mount_options"] = "discard"
+		}
+	}
+
+	return nil
+}
+
+// ValidateVolume validates the supplied volume config.
+func (d *nvme) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
+	rules := map[string]func(value string) error{
+		"block.filesystem": validate.Optional(validate.IsOneOf(nvmeAllowedFilesystems...)),
+	}
+
+	return d.validateVolume(vol, rules, removeUnknownKeys)
+}
+
+// UpdateVolume applies config changes to the volume.
+func (d *nvme) UpdateVolume(vol Volume, changedConfig map[string]string) error {
+	newSize, sizeChanged := changedConfig["size"]
+	if sizeChanged {
+		err := d.SetVolumeQuota(vol, newSize, false, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetVolumeUsage returns the disk space used by the volume.
+func (d *nvme) GetVolumeUsage(vol Volume) (int64, error) {
+	// Only mounted filesystem volumes report their usage, as namespaces are fully allocated.
+	if vol.contentType == ContentTypeFS && filesystem.IsMountPoint(vol.MountPath()) {
+		var stat unix.Statfs_t
+		err := unix.Statfs(vol.MountPath(), &stat)
+		if err != nil {
+			return -1, err
+		}
+
+		return int64(stat.Blocks-stat.Bfree) * int64(stat.Bsize), nil
+	}
+
+	return -1, ErrNotSupported
+}
+
+// SetVolumeQuota applies a size limit on volume.
+// Does nothing if supplied with an empty/zero size or with the current size of the volume.
+func (d *nvme) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op *operations.Operation) error {
+	// Do nothing if size isn't specified.
+	if size == "" || size == "0" {
+		return nil
+	}
+
+	sizeBytes, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return err
+	}
+
+	volDevPath, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	oldSizeBytes, err := BlockDiskSizeBytes(volDevPath)
+	if err != nil {
+		return errors.Wrapf(err, "Error getting current size")
+	}
+
+	// Namespaces can't be resized, but the partition may be slightly larger than requested at creation.
+	if sizeBytes > oldSizeBytes {
+		return errors.Wrapf(ErrNotSupported, "NVMe volumes cannot be grown")
+	}
+
+	if sizeBytes < oldSizeBytes-nvmeGPTOverhead {
+		return ErrCannotBeShrunk
+	}
+
+	return nil
+}
+
+// GetVolumeDiskPath returns the location of a disk volume.
+func (d *nvme) GetVolumeDiskPath(vol Volume) (string, error) {
+	if vol.IsVMBlock() || vol.volType == VolumeTypeCustom && vol.contentType == ContentTypeBlock {
+		return d.volumeDevPath(vol.volType, vol.contentType, vol.name), nil
+	}
+
+	return "", ErrNotSupported
+}
+
+// ListVolumes returns a list of LXD volumes in storage pool.
+// Volumes are identified by a hash of their name, so they can't be listed back.
+func (d *nvme) ListVolumes() ([]Volume, error) {
+	return nil, ErrNotSupported
+}
+
+// MountVolume mounts a volume and increments ref counter. Please call UnmountVolume() when done with the volume.
+func (d *nvme) MountVolume(vol Volume, op *operations.Operation) error {
+	unlock := vol.MountLock()
+	defer unlock()
+
+	volDevPath, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if vol.contentType == ContentTypeFS {
+		// Check if already mounted.
+		mountPath := vol.MountPath()
+		if !filesystem.IsMountPoint(mountPath) {
+			fsType := vol.ConfigBlockFilesystem()
+
+			if vol.mountFilesystemProbe {
+				fsType, err = fsProbe(volDevPath)
+				if err != nil {
+					return errors.Wrapf(err, "Failed probing filesystem")
+				}
+			}
+
+			err = vol.EnsureMountPath()
+			if err != nil {
+				return err
+			}
+
+			mountFlags, mountOptions := resolveMountOptions(vol.ConfigBlockMountOptions())
+			err = TryMount(volDevPath, mountPath, fsType, mountFlags, mountOptions)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to mount NVMe volume")
+			}
+			d.logger.Debug("Mounted NVMe volume", log.Ctx{"dev": volDevPath, "path": mountPath, "options": mountOptions})
+		}
+	} else if vol.contentType == ContentTypeBlock {
+		// For VMs, mount the filesystem volume.
+		if vol.IsVMBlock() {
+			fsVol := vol.NewVMBlockFilesystemVolume()
+			err = d.MountVolume(fsVol, op)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	vol.MountRefCountIncrement() // From here on it is up to caller to call UnmountVolume() when done.
+	return nil
+}
+
+// UnmountVolume unmounts volume if mounted and not in use. Returns true if this unmounted the volume.
+// The namespaces stay attached, so keepBlockDev has no effect.
+func (d *nvme) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operation) (bool, error) {
+	unlock := vol.MountLock()
+	defer unlock()
+
+	ourUnmount := false
+	refCount := vol.MountRefCountDecrement()
+
+	// Check if already mounted.
+	if vol.contentType == ContentTypeFS {
+		mountPath := vol.MountPath()
+		if filesystem.IsMountPoint(mountPath) {
+			if refCount > 0 {
+				d.logger.Debug("Skipping unmount as in use", "refCount", refCount)
+				return false, ErrInUse
+			}
+
+			err := TryUnmount(mountPath, 0)
+			if err != nil {
+				return false, errors.Wrapf(err, "Failed to unmount NVMe volume")
+			}
+			d.logger.Debug("Unmounted NVMe volume", log.Ctx{"path": mountPath})
+
+			ourUnmount = true
+		}
+	} else if vol.IsVMBlock() {
+		// For VMs, unmount the filesystem volume.
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		return d.UnmountVolume(fsVol, false, op)
+	}
+
+	return ourUnmount, nil
+}
+
+// RenameVolume renames a volume.
+func (d *nvme) RenameVolume(vol Volume, newVolName string, op *operations.Operation) error {
+	return vol.UnmountTask(func(op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+
+		_, err := d.activateVolume(vol)
+		if err != nil {
+			return err
+		}
+
+		err = d.renameVolumeNamespace(vol, newVolName)
+		if err != nil {
+			return err
+		}
+
+		newVol := NewVolume(d, d.name, vol.volType, vol.contentType, newVolName, vol.config, vol.poolConfig)
+		revert.Add(func() { d.renameVolumeNamespace(newVol, vol.name) })
+
+		// Rename volume dir.
+		if vol.contentType == ContentTypeFS {
+			srcVolumePath := GetVolumeMountPath(d.name, vol.volType, vol.name)
+			dstVolumePath := GetVolumeMountPath(d.name, vol.volType, newVolName)
+			err = os.Rename(srcVolumePath, dstVolumePath)
+			if err != nil {
+				return errors.Wrapf(err, "Error renaming NVMe volume mount path from %q to %q", srcVolumePath, dstVolumePath)
+			}
+			revert.Add(func() { os.Rename(dstVolumePath, srcVolumePath) })
+		}
+
+		// For VMs, also rename the filesystem volume.
+		if vol.IsVMBlock() {
+			fsVol := vol.NewVMBlockFilesystemVolume()
+			err = d.RenameVolume(fsVol, newVolName, op)
+			if err != nil {
+				return err
+			}
+		}
+
+		revert.Success()
+		return nil
+	}, false, op)
+}
+
+// MigrateVolume sends a volume for migration.
+func (d *nvme) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
+	// If data is set, this request is coming from the clustering code.
+	// In this case, the namespace stays in place and we only need to relabel it.
+	if volSrcArgs.Data != nil {
+		data, ok := volSrcArgs.Data.(string)
+		if ok {
+			if vol.name != data {
+				return d.RenameVolume(vol, data, op)
+			}
+
+			return nil
+		}
+	}
+
+	if len(volSrcArgs.Snapshots) > 0 {
+		return fmt.Errorf("Volume snapshots aren't supported by the NVMe driver")
+	}
+
+	_, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+}
+
+// BackupVolume copies a volume to a specified target path.
+// This driver does not support optimized backups.
+func (d *nvme) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, _ bool, snapshots []string, op *operations.Operation) error {
+	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+}
+
+// CreateVolumeSnapshot creates a snapshot of a volume.
+func (d *nvme) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
+	return ErrNotSupported
+}
+
+// DeleteVolumeSnapshot removes a snapshot from the storage device.
+func (d *nvme) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
+	return ErrNotSupported
+}
+
+// MountVolumeSnapshot sets up a read-only mount on top of the snapshot to avoid accidental modifications.
+func (d *nvme) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) (bool, error) {
+	return false, ErrNotSupported
+}
+
+// UnmountVolumeSnapshot removes the read-only mount placed on top of a snapshot.
+func (d *nvme) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (bool, error) {
+	return false, ErrNotSupported
+}
+
+// VolumeSnapshots returns a list of snapshots for the volume.
+// Namespaces have no snapshots.
+func (d *nvme) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, error) {
+	return []string{}, nil
+}
+
+// RestoreVolume restores a volume from a snapshot.
+func (d *nvme) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
+	return ErrNotSupported
+}
+
+// RenameVolumeSnapshot renames a volume snapshot.
+func (d *nvme) RenameVolumeSnapshot(snapVol Volume, newSnapshotName string, op *operations.Operation) error {
+	return ErrNotSupported
+}
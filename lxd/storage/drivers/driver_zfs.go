@@ -7,14 +7,17 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/validate"
 	"github.com/lxc/lxd/shared/version"
@@ -349,8 +352,10 @@ func (d *zfs) Validate(config map[string]string) error {
 
 			return validate.IsBool(value)
 		}),
-		"volume.zfs.remove_snapshots": validate.Optional(validate.IsBool),
-		"volume.zfs.use_refquota":     validate.Optional(validate.IsBool),
+		"volume.zfs.block_properties":   validate.Optional(zfsValidateProperties(ContentTypeBlock)),
+		"volume.zfs.dataset_properties": validate.Optional(zfsValidateProperties(ContentTypeFS)),
+		"volume.zfs.remove_snapshots":   validate.Optional(validate.IsBool),
+		"volume.zfs.use_refquota":       validate.Optional(validate.IsBool),
 	}
 
 	return d.validatePool(config, rules)
@@ -358,9 +363,22 @@ func (d *zfs) Validate(config map[string]string) error {
 
 // Update applies any driver changes required from a configuration change.
 func (d *zfs) Update(changedConfig map[string]string) error {
-	_, ok := changedConfig["zfs.pool_name"]
+	// The dataset can only be changed to point at the pool's dataset under its new name.
+	poolName, ok := changedConfig["zfs.pool_name"]
+	if ok && !d.checkDataset(poolName) {
+		return fmt.Errorf("ZFS dataset %q doesn't exist", poolName)
+	}
+
+	// A new source must be an existing file or device, or an existing zpool.
+	source, ok := changedConfig["source"]
 	if ok {
-		return fmt.Errorf("zfs.pool_name cannot be modified")
+		if filepath.IsAbs(source) {
+			if !shared.PathExists(shared.HostPath(source)) {
+				return fmt.Errorf("Source path %q doesn't exist", source)
+			}
+		} else if !d.checkDataset(strings.Split(source, "/")[0]) {
+			return fmt.Errorf("ZFS zpool %q doesn't exist", source)
+		}
 	}
 
 	return nil
@@ -405,6 +423,75 @@ func (d *zfs) Mount() (bool, error) {
 	return false, fmt.Errorf("ZFS zpool exists but dataset is missing")
 }
 
+// MoveToBlockDevice moves a loop file backed pool onto the block device while in use. The block device is
+// attached as a mirror of the loop file, which is detached and removed once the mirror is in sync.
+func (d *zfs) MoveToBlockDevice(devPath string, op *operations.Operation) error {
+	loopPath := loopFilePath(d.name)
+	if d.config["source"] != loopPath {
+		return fmt.Errorf("Only loop file backed pools can be moved onto a block device")
+	}
+
+	poolName := d.config["zfs.pool_name"]
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	_, err := shared.RunCommand("zpool", "attach", "-f", poolName, loopPath, devPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed attaching %q to the zpool", devPath)
+	}
+
+	revert.Add(func() { shared.RunCommand("zpool", "detach", poolName, devPath) })
+
+	// Wait for the block device to be in sync with the loop file.
+	for {
+		out, err := shared.RunCommand("zpool", "status", poolName)
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(out, "resilver in progress") {
+			if !strings.Contains(out, "state: ONLINE") {
+				return fmt.Errorf("The zpool isn't healthy after resilvering onto %q", devPath)
+			}
+
+			break
+		}
+
+		for _, line := range strings.Split(out, "\n") {
+			if strings.Contains(line, "% done") {
+				d.moveProgress(op, strings.TrimSpace(line))
+			}
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+
+	_, err = shared.RunCommand("zpool", "detach", poolName, loopPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed detaching the loop file from the zpool")
+	}
+
+	revert.Success()
+
+	// Grow the zpool to the size of the block device.
+	_, err = shared.RunCommand("zpool", "online", "-e", poolName, devPath)
+	if err != nil {
+		d.logger.Warn("Failed expanding the zpool onto the block device", log.Ctx{"dev": devPath, "err": err})
+	}
+
+	err = os.Remove(loopPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing loop file %q", loopPath)
+	}
+
+	// The zpool is found by name when imported from now on.
+	d.config["source"] = poolName
+	d.config["size"] = ""
+
+	return nil
+}
+
 // Unmount unmounts the storage pool.
 func (d *zfs) Unmount() (bool, error) {
 	// Skip if using a dataset and not a full pool.
@@ -471,6 +558,11 @@ func (d *zfs) MigrationTypes(contentType ContentType, refresh bool) []migration.
 		rsyncFeatures = []string{"xattrs", "delete", "compress", "bidirectional"}
 	}
 
+	// Block volumes can be transferred as the chunks which differ from the target.
+	if contentType == ContentTypeBlock {
+		rsyncFeatures = append(rsyncFeatures, migration.BlockFeatureDelta)
+	}
+
 	// When performing a refresh, always use rsync. Using zfs send/receive
 	// here doesn't make sense since it would need to send everything again
 	// which defeats the purpose of a refresh.
@@ -6,18 +6,172 @@ import (
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/ioprogress"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/validate"
 )
 
 // zfsBlockVolSuffix suffix used for block content type volumes.
 const zfsBlockVolSuffix = ".block"
 
+// zfsCompressionRegex matches the supported values of the compression property.
+var zfsCompressionRegex = regexp.MustCompile(`^(on|off|lzjb|lz4|zle|gzip(-[1-9])?|zstd(-([1-9]|1[0-9]))?|zstd-fast(-[0-9]+)?)$`)
+
+// zfsPropertyRules are the validators of the properties which can be set through zfs.dataset_properties and
+// zfs.block_properties.
+var zfsPropertyRules = map[string]func(value string) error{
+	"atime":                validate.IsOneOf("on", "off"),
+	"checksum":             validate.IsOneOf("on", "off", "fletcher2", "fletcher4", "sha256", "sha512", "skein", "edonr", "blake3"),
+	"compression":          zfsValidateCompression,
+	"copies":               validate.IsOneOf("1", "2", "3"),
+	"dedup":                zfsValidateToken,
+	"dnodesize":            validate.IsOneOf("legacy", "auto", "1k", "2k", "4k", "8k", "16k"),
+	"logbias":              validate.IsOneOf("latency", "throughput"),
+	"primarycache":         validate.IsOneOf("all", "none", "metadata"),
+	"recordsize":           zfsValidateBlockSize,
+	"redundant_metadata":   validate.IsOneOf("all", "most", "some", "none"),
+	"relatime":             validate.IsOneOf("on", "off"),
+	"secondarycache":       validate.IsOneOf("all", "none", "metadata"),
+	"special_small_blocks": zfsValidateToken,
+	"sync":                 validate.IsOneOf("standard", "always", "disabled"),
+	"volblocksize":         zfsValidateBlockSize,
+	"xattr":                validate.IsOneOf("on", "off", "sa", "dir"),
+}
+
+// zfsDatasetOnlyProperties are the properties which only apply to filesystem datasets.
+var zfsDatasetOnlyProperties = []string{"atime", "dnodesize", "recordsize", "relatime", "special_small_blocks", "xattr"}
+
+// zfsBlockOnlyProperties are the properties which only apply to volumes.
+var zfsBlockOnlyProperties = []string{"volblocksize"}
+
+// zfsCreationOnlyProperties are the properties which can only be set when the dataset is created.
+var zfsCreationOnlyProperties = []string{"volblocksize"}
+
+// zfsValidateCompression validates the value of the compression property.
+func zfsValidateCompression(value string) error {
+	if !zfsCompressionRegex.MatchString(value) {
+		return fmt.Errorf("Invalid compression algorithm %q", value)
+	}
+
+	return nil
+}
+
+// zfsValidateBlockSize validates a recordsize or volblocksize value, which must be a power of two of at least 512 bytes.
+func zfsValidateBlockSize(value string) error {
+	// ZFS uses single letter binary suffixes (e.g. 128K).
+	if strings.HasSuffix(value, "K") || strings.HasSuffix(value, "M") {
+		value = fmt.Sprintf("%siB", value)
+	}
+
+	size, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return err
+	}
+
+	if size < 512 || size&(size-1) != 0 {
+		return fmt.Errorf("Block size must be a power of two of at least 512 bytes")
+	}
+
+	return nil
+}
+
+// zfsValidateToken validates a free-form property value.
+func zfsValidateToken(value string) error {
+	if value == "" || strings.ContainsAny(value, ", \t") {
+		return fmt.Errorf("Invalid property value %q", value)
+	}
+
+	return nil
+}
+
+// zfsParseProperties parses a comma separated list of ZFS properties (key=value).
+func zfsParseProperties(value string) (map[string]string, error) {
+	properties := map[string]string{}
+	if value == "" {
+		return properties, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		fields := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid property %q, must be key=value", entry)
+		}
+
+		_, found := properties[fields[0]]
+		if found {
+			return nil, fmt.Errorf("Duplicate property %q", fields[0])
+		}
+
+		properties[fields[0]] = fields[1]
+	}
+
+	return properties, nil
+}
+
+// zfsValidateProperties returns a validator for a list of ZFS properties applying to the given content type.
+func zfsValidateProperties(contentType ContentType) func(value string) error {
+	excluded := zfsBlockOnlyProperties
+	if contentType == ContentTypeBlock {
+		excluded = zfsDatasetOnlyProperties
+	}
+
+	return func(value string) error {
+		properties, err := zfsParseProperties(value)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range properties {
+			validator, found := zfsPropertyRules[key]
+			if !found || shared.StringInSlice(key, excluded) {
+				return fmt.Errorf("Unsupported property %q", key)
+			}
+
+			err := validator(value)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid value for property %q", key)
+			}
+		}
+
+		return nil
+	}
+}
+
+// zfsPropertiesKey returns the volume config key holding the ZFS properties of the given content type.
+func zfsPropertiesKey(contentType ContentType) string {
+	if contentType == ContentTypeBlock {
+		return "zfs.block_properties"
+	}
+
+	return "zfs.dataset_properties"
+}
+
+// zfsPropertiesOptions returns the ZFS properties configured on the volume as key=value options.
+func zfsPropertiesOptions(vol Volume) ([]string, error) {
+	properties, err := zfsParseProperties(vol.ExpandedConfig(zfsPropertiesKey(vol.contentType)))
+	if err != nil {
+		return nil, err
+	}
+
+	options := make([]string, 0, len(properties))
+	for key, value := range properties {
+		options = append(options, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(options)
+
+	return options, nil
+}
+
 func (d *zfs) dataset(vol Volume, deleted bool) string {
 	name, snapName, _ := shared.InstanceGetParentAndSnapshotName(vol.name)
 	if (vol.volType == VolumeTypeVM || vol.volType == VolumeTypeImage) && vol.contentType == ContentTypeBlock {
@@ -195,6 +349,17 @@ func (d *zfs) setDatasetProperties(dataset string, options ...string) error {
 	return nil
 }
 
+func (d *zfs) inheritDatasetProperties(dataset string, properties ...string) error {
+	for _, property := range properties {
+		_, err := shared.RunCommand("zfs", "inherit", property, dataset)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (d *zfs) getDatasetProperty(dataset string, key string) (string, error) {
 	output, err := shared.RunCommand("zfs", "get", "-H", "-p", "-o", "value", key, dataset)
 	if err != nil {
@@ -0,0 +1,37 @@
+package drivers
+
+import (
+	"testing"
+)
+
+func Test_zfsValidateProperties(t *testing.T) {
+	tests := []struct {
+		contentType ContentType
+		value       string
+		valid       bool
+	}{
+		{ContentTypeFS, "compression=zstd", true},
+		{ContentTypeFS, "compression=zstd-19,recordsize=1M,logbias=throughput", true},
+		{ContentTypeFS, "compression=zstd-fast-10, atime=off", true},
+		{ContentTypeFS, "compression=gzip-9", true},
+		{ContentTypeFS, "compression=zstd-20", false},
+		{ContentTypeFS, "compression=brotli", false},
+		{ContentTypeFS, "recordsize=3K", false},
+		{ContentTypeFS, "recordsize=256", false},
+		{ContentTypeFS, "volblocksize=16K", false},
+		{ContentTypeFS, "mountpoint=/tmp", false},
+		{ContentTypeFS, "compression", false},
+		{ContentTypeFS, "sync=always,sync=disabled", false},
+		{ContentTypeBlock, "volblocksize=16K,compression=lz4", true},
+		{ContentTypeBlock, "recordsize=1M", false},
+	}
+
+	for _, test := range tests {
+		err := zfsValidateProperties(test.contentType)(test.value)
+		if test.valid && err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.value, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("Expected error for %q", test.value)
+		}
+	}
+}
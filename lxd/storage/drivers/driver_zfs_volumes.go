@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -127,12 +128,19 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 		}
 	}
 
+	// Get the user supplied dataset properties.
+	properties, err := zfsPropertiesOptions(vol)
+	if err != nil {
+		return err
+	}
+
 	// After this point we'll have a volume, so setup revert.
 	revert.Add(func() { d.DeleteVolume(vol, op) })
 
 	if vol.contentType == ContentTypeFS {
 		// Create the filesystem dataset.
-		err := d.createDataset(d.dataset(vol, false), fmt.Sprintf("mountpoint=%s", vol.MountPath()), "canmount=noauto")
+		opts := append([]string{fmt.Sprintf("mountpoint=%s", vol.MountPath()), "canmount=noauto"}, properties...)
+		err := d.createDataset(d.dataset(vol, false), opts...)
 		if err != nil {
 			return err
 		}
@@ -157,6 +165,8 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 			opts = append(opts, "sync=disabled")
 		}
 
+		opts = append(opts, properties...)
+
 		// Create the volume dataset.
 		err = d.createVolume(d.dataset(vol, false), sizeBytes, opts...)
 		if err != nil {
@@ -175,7 +185,7 @@ func (d *zfs) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 		revert.Add(func() { d.DeleteVolume(fsVol, op) })
 	}
 
-	err := vol.MountTask(func(mountPath string, op *operations.Operation) error {
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
 		// Run the volume filler function if supplied.
 		if filler != nil && filler.Fill != nil {
 			var err error
@@ -816,8 +826,10 @@ func (d *zfs) HasVolume(vol Volume) bool {
 // ValidateVolume validates the supplied volume config.
 func (d *zfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	rules := map[string]func(value string) error{
-		"zfs.remove_snapshots": validate.Optional(validate.IsBool),
-		"zfs.use_refquota":     validate.Optional(validate.IsBool),
+		"zfs.block_properties":   validate.Optional(zfsValidateProperties(ContentTypeBlock)),
+		"zfs.dataset_properties": validate.Optional(zfsValidateProperties(ContentTypeFS)),
+		"zfs.remove_snapshots":   validate.Optional(validate.IsBool),
+		"zfs.use_refquota":       validate.Optional(validate.IsBool),
 	}
 
 	return d.validateVolume(vol, rules, removeUnknownKeys)
@@ -864,11 +876,89 @@ func (d *zfs) UpdateVolume(vol Volume, changedConfig map[string]string) error {
 				return err
 			}
 		}
+
+		if k == "zfs.dataset_properties" || k == "zfs.block_properties" {
+			// The dataset properties of VM block volumes apply to their filesystem volume.
+			propVol := vol
+			if k == "zfs.dataset_properties" && vol.IsVMBlock() {
+				propVol = vol.NewVMBlockFilesystemVolume()
+			}
+
+			// Skip if the properties don't apply to this volume.
+			if zfsPropertiesKey(propVol.contentType) != k {
+				continue
+			}
+
+			// An unset volume key falls back to the pool default.
+			if v == "" {
+				v = vol.poolConfig[fmt.Sprintf("volume.%s", k)]
+			}
+
+			err := d.updateVolumeProperties(propVol, vol.ExpandedConfig(k), v)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// updateVolumeProperties applies the changes between two lists of ZFS properties to the volume's dataset.
+// The properties which were removed are reset to their inherited value.
+func (d *zfs) updateVolumeProperties(vol Volume, oldValue string, newValue string) error {
+	oldProperties, err := zfsParseProperties(oldValue)
+	if err != nil {
+		return err
+	}
+
+	newProperties, err := zfsParseProperties(newValue)
+	if err != nil {
+		return err
+	}
+
+	set := []string{}
+	for key, value := range newProperties {
+		if oldProperties[key] == value {
+			continue
+		}
+
+		if shared.StringInSlice(key, zfsCreationOnlyProperties) {
+			return fmt.Errorf("The %q property can only be set when the volume is created", key)
+		}
+
+		set = append(set, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	inherit := []string{}
+	for key := range oldProperties {
+		_, found := newProperties[key]
+		if found {
+			continue
+		}
+
+		if shared.StringInSlice(key, zfsCreationOnlyProperties) {
+			return fmt.Errorf("The %q property can only be set when the volume is created", key)
+		}
+
+		inherit = append(inherit, key)
+	}
+
+	sort.Strings(set)
+	sort.Strings(inherit)
+
+	dataset := d.dataset(vol, false)
+
+	if len(set) > 0 {
+		err = d.setDatasetProperties(dataset, set...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.inheritDatasetProperties(dataset, inherit...)
+}
+
 // GetVolumeUsage returns the disk space used by the volume.
 func (d *zfs) GetVolumeUsage(vol Volume) (int64, error) {
 	// Determine what key to use.
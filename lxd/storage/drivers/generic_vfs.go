@@ -206,6 +206,22 @@ func genericVFSMigrateVolume(d Driver, s *state.State, vol Volume, conn io.ReadW
 			}
 		}
 
+		// Only send the chunks which differ from the target when it supports it.
+		if shared.StringInSlice(migration.BlockFeatureDelta, volSrcArgs.MigrationType.Features) {
+			size, err := blockDeviceSize(from)
+			if err != nil {
+				return errors.Wrapf(err, "Error getting size of %q", path)
+			}
+
+			d.Logger().Debug("Sending block volume delta", log.Ctx{"volName": vol.name, "path": path})
+			err = sendBlockDelta(conn, fromPipe, size)
+			if err != nil {
+				return errors.Wrapf(err, "Error sending %q delta to migration connection", path)
+			}
+
+			return nil
+		}
+
 		d.Logger().Debug("Sending block volume", log.Ctx{"volName": vol.name, "path": path})
 		_, err = io.Copy(conn, fromPipe)
 		if err != nil {
@@ -306,6 +322,23 @@ func genericVFSCreateVolumeFromMigration(d Driver, initVolume func(vol Volume) (
 			wrapper = migration.ProgressTracker(op, "block_progress", volName)
 		}
 
+		// Only receive the chunks which differ from the existing volume when the source supports it.
+		if shared.StringInSlice(migration.BlockFeatureDelta, volTargetArgs.MigrationType.Features) {
+			to, err := os.OpenFile(path, os.O_RDWR, 0)
+			if err != nil {
+				return errors.Wrapf(err, "Error opening file for writing %q", path)
+			}
+			defer to.Close()
+
+			d.Logger().Debug("Receiving block volume delta", log.Ctx{"volName": volName, "path": path, "refresh": volTargetArgs.Refresh})
+			err = recvBlockDelta(conn, to, volTargetArgs.Refresh)
+			if err != nil {
+				return errors.Wrapf(err, "Error receiving delta from migration connection to %q", path)
+			}
+
+			return nil
+		}
+
 		to, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
 		if err != nil {
 			return errors.Wrapf(err, "Error opening file for writing %q", path)
@@ -854,6 +887,12 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (func(), error),
 
 	bwlimit := d.Config()["rsync.bwlimit"]
 
+	// When refreshing, only rewrite the parts of the block volume which changed.
+	copyBlock := copyDevice
+	if refresh {
+		copyBlock = copyDeviceDelta
+	}
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -893,7 +932,7 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (func(), error),
 							return err
 						}
 
-						err = copyDevice(srcDevPath, targetDevPath)
+						err = copyBlock(srcDevPath, targetDevPath)
 						if err != nil {
 							return err
 						}
@@ -948,7 +987,7 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (func(), error),
 					return err
 				}
 
-				err = copyDevice(srcDevPath, targetDevPath)
+				err = copyBlock(srcDevPath, targetDevPath)
 				if err != nil {
 					return err
 				}
@@ -46,6 +46,9 @@ type Driver interface {
 	Update(changedConfig map[string]string) error
 	ApplyPatch(name string) error
 
+	// MoveToBlockDevice moves a loop file backed pool onto the block device while in use.
+	MoveToBlockDevice(devPath string, op *operations.Operation) error
+
 	// Volumes.
 	FillVolumeConfig(vol Volume) error
 	ValidateVolume(vol Volume, removeUnknownKeys bool) error
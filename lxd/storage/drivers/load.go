@@ -10,6 +10,7 @@ var drivers = map[string]func() driver{
 	"cephfs": func() driver { return &cephfs{} },
 	"dir":    func() driver { return &dir{} },
 	"lvm":    func() driver { return &lvm{} },
+	"nvme":   func() driver { return &nvme{} },
 	"zfs":    func() driver { return &zfs{} },
 	"ceph":   func() driver { return &ceph{} },
 }
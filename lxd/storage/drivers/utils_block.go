@@ -0,0 +1,231 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// blockDeltaChunkSize is the size of the chunks compared when doing an incremental copy of a block volume.
+const blockDeltaChunkSize = 1024 * 1024
+
+// blockDeltaHeader precedes each chunk of data sent by sendBlockDelta.
+type blockDeltaHeader struct {
+	Offset uint64
+	Length uint32
+}
+
+// blockDeviceSize returns the size of a block device or file.
+func blockDeviceSize(f *os.File) (int64, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return -1, err
+	}
+
+	return size, nil
+}
+
+// copyDeviceDelta copies inputPath over outputPath, only writing the chunks which differ. This avoids rewriting
+// unchanged data when refreshing a block volume, which would otherwise grow the usage of its snapshots.
+func copyDeviceDelta(inputPath, outputPath string) error {
+	from, err := os.Open(inputPath)
+	if err != nil {
+		return errors.Wrapf(err, "Error opening file for reading %q", inputPath)
+	}
+	defer from.Close()
+
+	to, err := os.OpenFile(outputPath, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Error opening file for writing %q", outputPath)
+	}
+	defer to.Close()
+
+	fromBuf := make([]byte, blockDeltaChunkSize)
+	toBuf := make([]byte, blockDeltaChunkSize)
+
+	var offset int64
+	for {
+		n, err := io.ReadFull(from, fromBuf)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.Wrapf(err, "Error reading %q", inputPath)
+		}
+
+		m, err := io.ReadFull(to, toBuf[:n])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return errors.Wrapf(err, "Error reading %q", outputPath)
+		}
+
+		if m != n || !bytes.Equal(fromBuf[:n], toBuf[:n]) {
+			_, err = to.WriteAt(fromBuf[:n], offset)
+			if err != nil {
+				return errors.Wrapf(err, "Error writing to %q", outputPath)
+			}
+
+			// Keep the read position in step with the source.
+			_, err = to.Seek(offset+int64(n), io.SeekStart)
+			if err != nil {
+				return err
+			}
+		}
+
+		offset += int64(n)
+	}
+
+	return truncateBlockFile(to, offset)
+}
+
+// truncateBlockFile drops any data past size when the target is a regular file.
+// Block devices can't be resized this way and are left as is.
+func truncateBlockFile(f *os.File, size int64) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode().IsRegular() && fi.Size() > size {
+		err = f.Truncate(size)
+		if err != nil {
+			return errors.Wrapf(err, "Error truncating %q", f.Name())
+		}
+	}
+
+	return nil
+}
+
+// sendBlockDelta sends a block volume to recvBlockDelta. The receiver first sends the checksums of the data it
+// already has and only the chunks which differ are then sent. The caller must close conn once done to indicate
+// the end of the volume to the receiver.
+func sendBlockDelta(conn io.ReadWriteCloser, from io.Reader, size int64) error {
+	// Receive the checksums of the target until it signals the end of them.
+	checksums := [][sha256.Size]byte{}
+	for {
+		var checksum [sha256.Size]byte
+		_, err := io.ReadFull(conn, checksum[:])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrapf(err, "Failed receiving block checksums")
+		}
+
+		checksums = append(checksums, checksum)
+	}
+
+	w := bufio.NewWriterSize(conn, blockDeltaChunkSize)
+
+	err := binary.Write(w, binary.BigEndian, uint64(size))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, blockDeltaChunkSize)
+	for i := 0; ; i++ {
+		n, err := io.ReadFull(from, buf)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.Wrapf(err, "Failed reading block volume")
+		}
+
+		// Skip the chunks the target already has.
+		if n == blockDeltaChunkSize && i < len(checksums) && sha256.Sum256(buf[:n]) == checksums[i] {
+			continue
+		}
+
+		err = binary.Write(w, binary.BigEndian, blockDeltaHeader{Offset: uint64(i) * blockDeltaChunkSize, Length: uint32(n)})
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// recvBlockDelta receives a block volume from sendBlockDelta. When compare is false the existing data is
+// ignored and the whole volume is received.
+func recvBlockDelta(conn io.ReadWriteCloser, to *os.File, compare bool) error {
+	w := bufio.NewWriter(conn)
+
+	if compare {
+		buf := make([]byte, blockDeltaChunkSize)
+		for {
+			n, err := io.ReadFull(to, buf)
+			if err == io.EOF {
+				break
+			} else if err != nil && err != io.ErrUnexpectedEOF {
+				return errors.Wrapf(err, "Failed reading %q", to.Name())
+			}
+
+			// Partial chunks are always sent.
+			if n < blockDeltaChunkSize {
+				break
+			}
+
+			checksum := sha256.Sum256(buf)
+			_, err = w.Write(checksum[:])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+
+	// Indicate to the source that all the checksums have been sent.
+	err = conn.Close()
+	if err != nil {
+		return err
+	}
+
+	var size uint64
+	err = binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		return errors.Wrapf(err, "Failed receiving block volume size")
+	}
+
+	buf := make([]byte, blockDeltaChunkSize)
+	for {
+		hdr := blockDeltaHeader{}
+		err = binary.Read(conn, binary.BigEndian, &hdr)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrapf(err, "Failed receiving block chunk header")
+		}
+
+		if hdr.Length > blockDeltaChunkSize {
+			return errors.Errorf("Invalid block chunk length %d", hdr.Length)
+		}
+
+		_, err = io.ReadFull(conn, buf[:hdr.Length])
+		if err != nil {
+			return errors.Wrapf(err, "Failed receiving block chunk")
+		}
+
+		_, err = to.WriteAt(buf[:hdr.Length], int64(hdr.Offset))
+		if err != nil {
+			return errors.Wrapf(err, "Failed writing to %q", to.Name())
+		}
+	}
+
+	return truncateBlockFile(to, int64(size))
+}
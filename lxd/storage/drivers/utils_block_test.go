@@ -0,0 +1,117 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/storage/memorypipe"
+)
+
+// writeBlockFile writes a file of the given size filled with data derived from seed.
+func writeBlockFile(t *testing.T, path string, size int, seed int64) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+
+	err := ioutil.WriteFile(path, data, 0600)
+	require.NoError(t, err)
+
+	return data
+}
+
+// Test copyDeviceDelta
+func TestCopyDeviceDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-block-delta-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst")
+
+	// Target larger than the source with a single differing chunk.
+	src := writeBlockFile(t, srcPath, 3*blockDeltaChunkSize+100, 1)
+	dst := append(append([]byte{}, src...), make([]byte, blockDeltaChunkSize)...)
+	dst[blockDeltaChunkSize+5] ^= 0xff
+	err = ioutil.WriteFile(dstPath, dst, 0600)
+	require.NoError(t, err)
+
+	err = copyDeviceDelta(srcPath, dstPath)
+	require.NoError(t, err)
+
+	result, err := ioutil.ReadFile(dstPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(src, result))
+
+	// Target smaller than the source.
+	writeBlockFile(t, dstPath, 100, 2)
+	err = copyDeviceDelta(srcPath, dstPath)
+	require.NoError(t, err)
+
+	result, err = ioutil.ReadFile(dstPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(src, result))
+}
+
+// Test sendBlockDelta and recvBlockDelta
+func TestBlockDeltaTransfer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-block-delta-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		dstSize int
+		compare bool
+	}{
+		{"Refresh", 4*blockDeltaChunkSize + 10, true},
+		{"Refresh of smaller target", blockDeltaChunkSize, true},
+		{"Full copy", 4*blockDeltaChunkSize + 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcPath := filepath.Join(dir, "src")
+			dstPath := filepath.Join(dir, "dst")
+
+			src := writeBlockFile(t, srcPath, 3*blockDeltaChunkSize+10, 1)
+
+			dst := make([]byte, tt.dstSize)
+			copy(dst, src)
+			dst[0] ^= 0xff
+			err = ioutil.WriteFile(dstPath, dst, 0600)
+			require.NoError(t, err)
+
+			from, err := os.Open(srcPath)
+			require.NoError(t, err)
+			defer from.Close()
+
+			to, err := os.OpenFile(dstPath, os.O_RDWR, 0)
+			require.NoError(t, err)
+			defer to.Close()
+
+			aEnd, bEnd := memorypipe.NewPipePair(context.Background())
+
+			sendErr := make(chan error, 1)
+			go func() {
+				err := sendBlockDelta(aEnd, from, int64(len(src)))
+				aEnd.Close()
+				sendErr <- err
+			}()
+
+			err = recvBlockDelta(bEnd, to, tt.compare)
+			require.NoError(t, err)
+			require.NoError(t, <-sendErr)
+
+			result, err := ioutil.ReadFile(dstPath)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(src, result))
+		})
+	}
+}
@@ -57,6 +57,10 @@ const ContentTypeFS = ContentType("filesystem")
 // know which filesystem(s) (if any) are in use.
 const ContentTypeBlock = ContentType("block")
 
+// ContentTypeISO indicates the volume holds a read-only ISO image. Storage drivers handle ISO volumes as block
+// volumes, this is only used to record the content type of the volume.
+const ContentTypeISO = ContentType("iso")
+
 // VolumePostHook function returned from a storage action that should be run later to complete the action.
 type VolumePostHook func(vol Volume) error
 
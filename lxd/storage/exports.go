@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/exports"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/validate"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// validateExportClients validates a comma delimited list of IP addresses or subnets allowed to access an export.
+func validateExportClients(value string) error {
+	for _, client := range strings.Split(value, ",") {
+		client = strings.TrimSpace(client)
+		if validate.IsNetworkAddress(client) != nil && validate.IsNetwork(client) != nil {
+			return fmt.Errorf("Not an IP address or subnet %q", client)
+		}
+	}
+
+	return nil
+}
+
+// customVolumeExportName returns the name of the export of a custom volume.
+func customVolumeExportName(poolName string, projectName string, volName string) string {
+	return exports.Name(poolName, projectName, volName)
+}
+
+// hashExportPassword returns the volume config with the SMB export password replaced by its hash, so that the
+// password itself isn't stored. The given config is left unchanged.
+func hashExportPassword(config map[string]string) map[string]string {
+	hash := exports.PasswordHash(config["export.smb.password"])
+	if hash == config["export.smb.password"] {
+		return config
+	}
+
+	newConfig := make(map[string]string, len(config))
+	for k, v := range config {
+		newConfig[k] = v
+	}
+
+	newConfig["export.smb.password"] = hash
+
+	return newConfig
+}
+
+// customVolumeExport returns the export described by the config of a custom volume.
+func (b *lxdBackend) customVolumeExport(projectName string, volName string, config map[string]string) exports.Export {
+	e := exports.Export{
+		Name:         customVolumeExportName(b.name, projectName, volName),
+		Path:         drivers.GetVolumeMountPath(b.name, drivers.VolumeTypeCustom, project.StorageVolume(projectName, volName)),
+		Protocol:     config["export.protocol"],
+		ReadOnly:     shared.IsTrue(config["export.readonly"]),
+		Username:     config["export.smb.username"],
+		PasswordHash: exports.PasswordHash(config["export.smb.password"]),
+	}
+
+	if config["export.clients"] != "" {
+		for _, client := range strings.Split(config["export.clients"], ",") {
+			e.Clients = append(e.Clients, strings.TrimSpace(client))
+		}
+	}
+
+	return e
+}
+
+// validateCustomVolumeExport checks that the export described by the config of a custom volume can be set up.
+func (b *lxdBackend) validateCustomVolumeExport(projectName string, volName string, contentType drivers.ContentType, config map[string]string) error {
+	if config["export.protocol"] == "" {
+		return nil
+	}
+
+	if contentType != drivers.ContentTypeFS {
+		return fmt.Errorf("Only filesystem volumes can be exported")
+	}
+
+	if b.driver.Info().Remote {
+		return fmt.Errorf("Volumes on remote storage pools can't be exported")
+	}
+
+	err := b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return project.AllowVolumeExport(tx, projectName)
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.customVolumeExport(projectName, volName, config).Validate()
+}
+
+// updateCustomVolumeExport sets up, updates or removes the export of a custom volume to match its config. The
+// volume is kept mounted while it's exported.
+func (b *lxdBackend) updateCustomVolumeExport(projectName string, volName string, curConfig map[string]string, newConfig map[string]string, op *operations.Operation) error {
+	exported := curConfig["export.protocol"] != ""
+
+	if newConfig["export.protocol"] == "" {
+		if !exported {
+			return nil
+		}
+
+		err := exports.Remove(customVolumeExportName(b.name, projectName, volName))
+		if err != nil {
+			return err
+		}
+
+		_, err = b.UnmountCustomVolume(projectName, volName, op)
+		return err
+	}
+
+	if !exported {
+		err := b.MountCustomVolume(projectName, volName, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return exports.Add(b.customVolumeExport(projectName, volName, newConfig))
+}
+
+// withoutExportConfig returns a copy of the volume config without the export settings, as exports aren't
+// carried over to copies of a volume.
+func withoutExportConfig(config map[string]string) map[string]string {
+	newConfig := make(map[string]string, len(config))
+	for k, v := range config {
+		if strings.HasPrefix(k, "export.") {
+			continue
+		}
+
+		newConfig[k] = v
+	}
+
+	return newConfig
+}
+
+// RestoreCustomVolumeExports mounts the exported custom volumes of the local pools and sets up their exports
+// again. Failures are logged rather than returned so that they don't prevent LXD from starting.
+func RestoreCustomVolumeExports(s *state.State) {
+	volumes, err := s.Cluster.GetStoragePoolVolumesWithType(db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		logger.Warn("Failed loading custom volumes to restore exports", log.Ctx{"err": err})
+		return
+	}
+
+	nodeID := s.Cluster.GetNodeID()
+	for _, vol := range volumes {
+		if vol.Config["export.protocol"] == "" || vol.NodeID != nodeID {
+			continue
+		}
+
+		pool, err := GetPoolByName(s, vol.PoolName)
+		if err != nil {
+			logger.Warn("Failed loading storage pool to restore export", log.Ctx{"pool": vol.PoolName, "err": err})
+			continue
+		}
+
+		err = pool.MountCustomVolume(vol.ProjectName, vol.Name, nil)
+		if err != nil {
+			logger.Warn("Failed mounting exported custom volume", log.Ctx{"pool": vol.PoolName, "project": vol.ProjectName, "volume": vol.Name, "err": err})
+		}
+	}
+
+	err = exports.Restore()
+	if err != nil {
+		logger.Warn("Failed restoring custom volume exports", log.Ctx{"err": err})
+	}
+}
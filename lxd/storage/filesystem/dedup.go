@@ -0,0 +1,234 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// DedupChunkSize is the size of the chunks the files are deduplicated in.
+const DedupChunkSize = 1024 * 1024
+
+// dedupMinFileSize is the size under which files aren't worth deduplicating.
+const dedupMinFileSize = 64 * 1024
+
+// ErrDedupNotSupported is returned when none of the stores can share extents with the files to deduplicate.
+var ErrDedupNotSupported = fmt.Errorf("Deduplication isn't supported on this filesystem")
+
+// DedupStats represents the outcome of a deduplication pass.
+type DedupStats struct {
+	Scanned int64 // Bytes scanned.
+	Shared  int64 // Bytes now sharing their extents with an identical chunk seen previously.
+	Stored  int64 // Bytes of new chunks added to the store.
+}
+
+// Dedup shares the extents of the chunks of the regular files under path with the identical chunks seen
+// previously. A reflinked copy of each chunk is kept in a content-addressed store (named after its SHA256
+// checksum) which is the first of the stores on the same filesystem as path to support reflinks.
+// Returns ErrDedupNotSupported if none of them does.
+func Dedup(path string, stores []string) (*DedupStats, error) {
+	stats := &DedupStats{}
+	store := ""
+	buf := make([]byte, DedupChunkSize)
+	zero := make([]byte, DedupChunkSize)
+
+	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || info.Size() < dedupMinFileSize {
+			return nil
+		}
+
+		f, err := os.OpenFile(filePath, os.O_RDWR, 0)
+		if err != nil {
+			// Skip the files which can't be modified (e.g. immutable ones).
+			return nil
+		}
+		defer f.Close()
+
+		for offset := int64(0); offset < info.Size(); offset += DedupChunkSize {
+			n, err := io.ReadFull(f, buf)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return errors.Wrapf(err, "Failed reading %q", filePath)
+			}
+
+			if n == 0 {
+				break
+			}
+
+			chunk := buf[:n]
+			stats.Scanned += int64(n)
+
+			// Leave the holes and zeroed chunks alone.
+			if bytes.Equal(chunk, zero[:n]) {
+				continue
+			}
+
+			sum := sha256.Sum256(chunk)
+			hash := hex.EncodeToString(sum[:])
+
+			// Pick the store on the first chunk to add.
+			if store == "" {
+				for _, candidate := range stores {
+					err = dedupStore(candidate, hash, f, offset, n)
+					if err == nil {
+						store = candidate
+						break
+					}
+				}
+
+				if store == "" {
+					return ErrDedupNotSupported
+				}
+
+				stats.Stored += int64(n)
+				continue
+			}
+
+			chunkPath := dedupChunkPath(store, hash)
+			if _, err := os.Stat(chunkPath); err != nil {
+				err = dedupStore(store, hash, f, offset, n)
+				if err != nil {
+					return err
+				}
+
+				stats.Stored += int64(n)
+				continue
+			}
+
+			shared, err := dedupShare(chunkPath, f, offset, n)
+			if err != nil {
+				return errors.Wrapf(err, "Failed deduplicating %q", filePath)
+			}
+
+			if shared {
+				stats.Shared += int64(n)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// dedupChunkPath returns the path of a chunk in the store.
+func dedupChunkPath(store string, hash string) string {
+	return filepath.Join(store, hash[:2], hash)
+}
+
+// dedupStore adds a chunk of the file to the store by reflinking it.
+func dedupStore(store string, hash string, f *os.File, offset int64, length int) error {
+	dir := filepath.Dir(dedupChunkPath(store, hash))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".chunk")
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	err = unix.IoctlFileCloneRange(int(tmp.Fd()), &unix.FileCloneRange{
+		Src_fd:     int64(f.Fd()),
+		Src_offset: uint64(offset),
+		Src_length: uint64(length),
+	})
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), dedupChunkPath(store, hash))
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// dedupShare shares the extents of a chunk of the store with the matching chunk of the file. The kernel checks
+// the content is identical before doing so. Returns whether the extents were shared.
+func dedupShare(chunkPath string, f *os.File, offset int64, length int) (bool, error) {
+	chunk, err := os.Open(chunkPath)
+	if err != nil {
+		return false, err
+	}
+	defer chunk.Close()
+
+	dedupe := &unix.FileDedupeRange{
+		Src_length: uint64(length),
+		Info: []unix.FileDedupeRangeInfo{{
+			Dest_fd:     int64(f.Fd()),
+			Dest_offset: uint64(offset),
+		}},
+	}
+
+	err = unix.IoctlFileDedupeRange(int(chunk.Fd()), dedupe)
+	if err != nil {
+		return false, err
+	}
+
+	// FILE_DEDUPE_RANGE_SAME, a different status means the content differed.
+	if dedupe.Info[0].Status != 0 || dedupe.Info[0].Bytes_deduped != uint64(length) {
+		return false, nil
+	}
+
+	// Record when the chunk was last used so that the unused chunks can be pruned.
+	now := time.Now()
+	_ = os.Chtimes(chunkPath, now, now)
+
+	return true, nil
+}
+
+// DedupPrune removes the chunks of the store which weren't used for deduplication since the given duration.
+// The files sharing their extents aren't affected, the chunks just won't be shared with new files anymore.
+// Returns the number of chunks removed.
+func DedupPrune(store string, unusedFor time.Duration) (int, error) {
+	if _, err := os.Stat(store); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-unusedFor)
+
+	err := filepath.Walk(store, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, nil
+}
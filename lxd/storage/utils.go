@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/exports"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/migration"
@@ -22,6 +24,7 @@ import (
 	"github.com/lxc/lxd/lxd/rsync"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/storage/filesystem"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
@@ -157,15 +160,18 @@ func VolumeContentTypeToDBContentType(contentType drivers.ContentType) (int, err
 		return db.StoragePoolVolumeContentTypeBlock, nil
 	case drivers.ContentTypeFS:
 		return db.StoragePoolVolumeContentTypeFS, nil
+	case drivers.ContentTypeISO:
+		return db.StoragePoolVolumeContentTypeISO, nil
 	}
 
 	return -1, fmt.Errorf("Invalid volume content type")
 }
 
 // VolumeDBContentTypeToContentType converts internal content type DB code to driver representation.
+// ISO volumes are block volumes to the storage drivers.
 func VolumeDBContentTypeToContentType(volDBType int) (drivers.ContentType, error) {
 	switch volDBType {
-	case db.StoragePoolVolumeContentTypeBlock:
+	case db.StoragePoolVolumeContentTypeBlock, db.StoragePoolVolumeContentTypeISO:
 		return drivers.ContentTypeBlock, nil
 	case db.StoragePoolVolumeContentTypeFS:
 		return drivers.ContentTypeFS, nil
@@ -181,6 +187,8 @@ func VolumeContentTypeNameToContentType(contentTypeName string) (int, error) {
 		return db.StoragePoolVolumeContentTypeFS, nil
 	case db.StoragePoolVolumeContentTypeNameBlock:
 		return db.StoragePoolVolumeContentTypeBlock, nil
+	case db.StoragePoolVolumeContentTypeNameISO:
+		return db.StoragePoolVolumeContentTypeISO, nil
 	}
 
 	return -1, fmt.Errorf("Invalid volume content type name")
@@ -244,9 +252,9 @@ func VolumeDBCreate(s *state.State, pool Pool, projectName string, volumeName st
 
 // SupportedPoolTypes the types of pools supported.
 // Deprecated: this is being replaced with drivers.SupportedDrivers()
-var SupportedPoolTypes = []string{"btrfs", "ceph", "cephfs", "dir", "lvm", "zfs"}
+var SupportedPoolTypes = []string{"btrfs", "ceph", "cephfs", "dir", "lvm", "nvme", "zfs"}
 
-// StorageVolumeConfigKeys config validation for btrfs, ceph, cephfs, dir, lvm, zfs types.
+// StorageVolumeConfigKeys config validation for btrfs, ceph, cephfs, dir, lvm, nvme, zfs types.
 // Deprecated: these are being moved to the per-storage-driver implementations.
 var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 	"block.filesystem": func(value string) ([]string, error) {
@@ -255,10 +263,10 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 			return nil, err
 		}
 
-		return []string{"ceph", "lvm"}, nil
+		return []string{"ceph", "lvm", "nvme"}, nil
 	},
 	"block.mount_options": func(value string) ([]string, error) {
-		return []string{"ceph", "lvm"}, validate.IsAny(value)
+		return []string{"ceph", "lvm", "nvme"}, validate.IsAny(value)
 	},
 	"security.shifted": func(value string) ([]string, error) {
 		return SupportedPoolTypes, validate.Optional(validate.IsBool)(value)
@@ -284,6 +292,12 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 	"volatile.idmap.next": func(value string) ([]string, error) {
 		return SupportedPoolTypes, validate.IsAny(value)
 	},
+	"zfs.block_properties": func(value string) ([]string, error) {
+		return []string{"zfs"}, validate.IsAny(value)
+	},
+	"zfs.dataset_properties": func(value string) ([]string, error) {
+		return []string{"zfs"}, validate.IsAny(value)
+	},
 	"zfs.remove_snapshots": func(value string) ([]string, error) {
 		err := validate.Optional(validate.IsBool)(value)
 		if err != nil {
@@ -358,9 +372,32 @@ func validatePoolCommonRules() map[string]func(string) error {
 		"size":                    validate.Optional(validate.IsSize),
 		"rsync.bwlimit":           validate.IsAny,
 		"rsync.compression":       validate.Optional(validate.IsBool),
+		"mount.lazy":              validate.Optional(validate.IsBool),
+		"usage.forecast_horizon":  validatePoolUsageForecastHorizon,
+		"usage.warning_threshold": validatePoolUsageWarningThreshold,
 	}
 }
 
+// validatePoolUsageForecastHorizon validates the period (e.g. "7d") over which pool usage is forecast.
+func validatePoolUsageForecastHorizon(value string) error {
+	_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+	return err
+}
+
+// validatePoolUsageWarningThreshold validates the usage percentage above which a pool warning is raised.
+func validatePoolUsageWarningThreshold(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	threshold, err := strconv.ParseUint(value, 10, 8)
+	if err != nil || threshold > 100 {
+		return fmt.Errorf("Invalid percentage %q", value)
+	}
+
+	return nil
+}
+
 // validateVolumeCommonRules returns a map of volume config rules common to all drivers.
 func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error {
 	rules := map[string]func(string) error{
@@ -373,7 +410,15 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 			return err
 		},
 		"snapshots.schedule": validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly"})),
-		"snapshots.pattern":  validate.IsAny,
+		"snapshots.schedule.offset": func(value string) error {
+			_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+			return err
+		},
+		"snapshots.schedule.jitter": func(value string) error {
+			_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+			return err
+		},
+		"snapshots.pattern": validate.IsAny,
 	}
 
 	// volatile.idmap settings only make sense for filesystem volumes.
@@ -397,6 +442,13 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 	if vol.Type() == drivers.VolumeTypeCustom && vol.ContentType() == drivers.ContentTypeFS {
 		rules["security.shifted"] = validate.Optional(validate.IsBool)
 		rules["security.unmapped"] = validate.Optional(validate.IsBool)
+
+		// Exports of the volume to NFS or SMB clients.
+		rules["export.protocol"] = validate.Optional(validate.IsOneOf(exports.ProtocolNFS, exports.ProtocolSMB))
+		rules["export.clients"] = validate.Optional(validateExportClients)
+		rules["export.readonly"] = validate.Optional(validate.IsBool)
+		rules["export.smb.username"] = validate.Optional(exports.ValidateUsername)
+		rules["export.smb.password"] = validate.IsAny
 	}
 
 	// volatile.rootfs.size is only used for image volumes.
@@ -820,6 +872,64 @@ func VolumeUsedByDaemon(s *state.State, poolName string, volumeName string) (boo
 	return false, nil
 }
 
+// imageDedupUnusedFor is how long the chunks of the image deduplication stores are kept once unused.
+const imageDedupUnusedFor = 30 * 24 * time.Hour
+
+// imageDedupEnabled returns whether the unpacked image volumes should be deduplicated on this member.
+func imageDedupEnabled(s *state.State) (bool, error) {
+	var enabled bool
+	err := s.Node.Transaction(func(tx *db.NodeTx) error {
+		nodeConfig, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		enabled = nodeConfig.StorageImagesDedup()
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return enabled, nil
+}
+
+// imageDedupStores returns the content-addressed stores the image volumes of the pool can be deduplicated with.
+// The global store is shared by all the pools on the same filesystem as LXD, the pool store is used otherwise.
+func imageDedupStores(poolName string) []string {
+	return []string{
+		shared.VarPath("storage-dedup"),
+		filepath.Join(drivers.GetPoolMountPath(poolName), "dedup"),
+	}
+}
+
+// ImageDedupPrune removes the chunks of the image deduplication stores which have been unused for a while.
+func ImageDedupPrune(s *state.State) error {
+	poolNames, err := s.Cluster.GetCreatedStoragePoolNames()
+	if err != nil && err != db.ErrNoSuchObject {
+		return err
+	}
+
+	stores := []string{shared.VarPath("storage-dedup")}
+	for _, poolName := range poolNames {
+		stores = append(stores, imageDedupStores(poolName)[1])
+	}
+
+	for _, store := range stores {
+		removed, err := filesystem.DedupPrune(store, imageDedupUnusedFor)
+		if err != nil {
+			return errors.Wrapf(err, "Failed pruning image deduplication store %q", store)
+		}
+
+		if removed > 0 {
+			logger.Debug("Pruned image deduplication store", log.Ctx{"store": store, "chunks": removed})
+		}
+	}
+
+	return nil
+}
+
 // FallbackMigrationType returns the fallback migration transport to use based on volume content type.
 func FallbackMigrationType(contentType drivers.ContentType) migration.MigrationFSType {
 	if contentType == drivers.ContentTypeBlock {
@@ -15,6 +15,7 @@ import (
 	clusterRequest "github.com/lxc/lxd/lxd/cluster/request"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/request"
 	"github.com/lxc/lxd/lxd/response"
@@ -46,6 +47,12 @@ var storagePoolCmd = APIEndpoint{
 	Put:    APIEndpointAction{Handler: storagePoolPut},
 }
 
+var storagePoolSourceCmd = APIEndpoint{
+	Path: "storage-pools/{name}/source",
+
+	Post: APIEndpointAction{Handler: storagePoolSourcePost},
+}
+
 // swagger:operation GET /1.0/storage-pools storage storage_pools_get
 //
 // Get the storage pools
@@ -159,7 +166,7 @@ func storagePoolsGet(d *Daemon, r *http.Request) response.Response {
 
 			// Get all users of the storage pool.
 			poolUsedBy := []string{}
-			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			err = d.cluster.ReadTransaction(func(tx *db.ClusterTx) error {
 				poolUsedBy, err = tx.GetStoragePoolUsedBy(pool)
 				return err
 			})
@@ -591,7 +598,7 @@ func storagePoolGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	etag := []interface{}{pool.Name, pool.Driver, pool.Config}
+	etag := []interface{}{pool.Name, pool.Driver, pool.Description, pool.Config}
 
 	return response.SyncResponseETag(true, &pool, etag)
 }
@@ -673,7 +680,7 @@ func storagePoolPut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag.
-	etag := []interface{}{pool.Name(), pool.Driver().Info().Name, etagConfig}
+	etag := []interface{}{pool.Name(), pool.Driver().Info().Name, pool.Description(), etagConfig}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -948,3 +955,94 @@ func storagePoolDelete(d *Daemon, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// swagger:operation POST /1.0/storage-pools/{name}/source storage storage_pool_source_post
+//
+// Move the storage pool onto a block device
+//
+// Moves a loop file backed storage pool (btrfs, lvm or zfs) onto a block device while in use, then removes the
+// loop file. The block device becomes the source of the storage pool on the cluster member.
+//
+// ---
+// consumes:
+//   - application/json
+// produces:
+//   - application/json
+// parameters:
+//   - in: query
+//     name: target
+//     description: Cluster member name
+//     type: string
+//     example: lxd01
+//   - in: body
+//     name: storage pool
+//     description: Block device to move the storage pool onto
+//     required: true
+//     schema:
+//       $ref: "#/definitions/StoragePoolSourcePost"
+// responses:
+//   "202":
+//     $ref: "#/responses/Operation"
+//   "400":
+//     $ref: "#/responses/BadRequest"
+//   "403":
+//     $ref: "#/responses/Forbidden"
+//   "500":
+//     $ref: "#/responses/InternalServerError"
+func storagePoolSourcePost(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The source of a local storage pool differs on each member.
+	if clustered && queryParam(r, "target") == "" {
+		return response.BadRequest(fmt.Errorf("The cluster member to move the storage pool on must be targeted"))
+	}
+
+	poolName := mux.Vars(r)["name"]
+
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.StoragePoolSourcePost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !shared.IsBlockdevPath(req.Source) {
+		return response.BadRequest(fmt.Errorf("%q isn't a block device", req.Source))
+	}
+
+	requestor := request.CreateRequestor(r)
+
+	run := func(op *operations.Operation) error {
+		err := pool.MoveToBlockDevice(req.Source, op)
+		if err != nil {
+			return err
+		}
+
+		d.State().Events.SendLifecycle(project.Default, lifecycle.StoragePoolUpdated.Event(pool.Name(), project.Default, requestor, nil))
+
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_pools"] = []string{pool.Name()}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationStoragePoolMoveToBlock, resources, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
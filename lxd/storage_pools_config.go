@@ -22,10 +22,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"btrfs.mount_options": validate.IsAny,
 
 	// valid drivers: ceph
-	"ceph.cluster_name":       validate.IsAny,
-	"ceph.osd.force_reuse":    validate.Optional(validate.IsBool),
-	"ceph.osd.pool_name":      validate.IsAny,
-	"ceph.osd.data_pool_name": validate.IsAny,
+	"ceph.cluster_name":              validate.IsAny,
+	"ceph.osd.force_reuse":           validate.Optional(validate.IsBool),
+	"ceph.osd.namespace_per_project": validate.Optional(validate.IsBool),
+	"ceph.osd.pool_name":             validate.IsAny,
+	"ceph.osd.data_pool_name":        validate.IsAny,
 	"ceph.osd.pg_num": func(value string) error {
 		if value == "" {
 			return nil
@@ -43,6 +44,13 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"cephfs.path":         validate.IsAny,
 	"cephfs.user.name":    validate.IsAny,
 
+	// valid drivers: nvme
+	"nvme.host_nqn":       validate.IsAny,
+	"nvme.nqn":            validate.IsAny,
+	"nvme.target.address": validate.Optional(validate.IsNetworkAddress),
+	"nvme.target.port":    validate.Optional(validate.IsNetworkPort),
+	"nvme.transport":      validate.Optional(validate.IsOneOf("tcp", "rdma")),
+
 	// valid drivers: lvm
 	"lvm.thinpool_name":       validate.IsAny,
 	"lvm.use_thinpool":        validate.Optional(validate.IsBool),
@@ -50,6 +58,10 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"volume.lvm.stripes":      validate.Optional(validate.IsUint32),
 	"volume.lvm.stripes.size": validate.Optional(validate.IsSize),
 	"lvm.vg.force_reuse":      validate.Optional(validate.IsBool),
+	"lvm.raid.type":           validate.Optional(validate.IsOneOf("raid1", "raid5")),
+	"lvm.raid.devices":        validate.IsAny,
+	"lvm.cache.device":        validate.IsAny,
+	"lvm.cache.mode":          validate.Optional(validate.IsOneOf("writethrough", "writeback", "writecache")),
 
 	// valid drivers: btrfs, lvm, zfs
 	"size": validate.Optional(validate.IsSize),
@@ -65,16 +77,18 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"volatile.pool.pristine":  validate.IsAny,
 	"volatile.initial_source": validate.IsAny,
 
-	// valid drivers: ceph, lvm
+	// valid drivers: ceph, lvm, nvme
 	"volume.block.filesystem":    validate.Optional(validate.IsOneOf("btrfs", "ext4", "xfs")),
 	"volume.block.mount_options": validate.IsAny,
 
-	// valid drivers: ceph, lvm
+	// valid drivers: ceph, lvm, nvme
 	"volume.size": validate.Optional(validate.IsSize),
 
 	// valid drivers: zfs
-	"volume.zfs.remove_snapshots": validate.Optional(validate.IsBool),
-	"volume.zfs.use_refquota":     validate.Optional(validate.IsBool),
+	"volume.zfs.block_properties":   validate.IsAny,
+	"volume.zfs.dataset_properties": validate.IsAny,
+	"volume.zfs.remove_snapshots":   validate.Optional(validate.IsBool),
+	"volume.zfs.use_refquota":       validate.Optional(validate.IsBool),
 
 	// valid drivers: zfs
 	"zfs.clone_copy": validate.Optional(func(value string) error {
@@ -89,6 +103,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 
 	// valid drivers: btrfs, ceph, cephfs, zfs
 	"rsync.compression": validate.Optional(validate.IsBool),
+
+	// valid drivers: all
+	"mount.lazy":              validate.Optional(validate.IsBool),
+	"usage.forecast_horizon":  validate.IsAny,
+	"usage.warning_threshold": validate.IsAny,
 }
 
 func storagePoolValidateConfig(name string, driver string, config map[string]string, oldConfig map[string]string) error {
@@ -119,7 +138,7 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 		}
 
 		prfx := strings.HasPrefix
-		if driver == "dir" || driver == "ceph" || driver == "cephfs" {
+		if driver == "dir" || driver == "ceph" || driver == "cephfs" || driver == "nvme" {
 			if key == "size" {
 				return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
 			}
@@ -131,7 +150,7 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 			}
 		}
 
-		if driver != "lvm" && driver != "ceph" {
+		if driver != "lvm" && driver != "ceph" && driver != "nvme" {
 			if prfx(key, "volume.block.") {
 				return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
 			}
@@ -159,7 +178,7 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 }
 
 func storagePoolFillDefault(name string, driver string, config map[string]string) error {
-	if driver == "dir" || driver == "ceph" || driver == "cephfs" {
+	if driver == "dir" || driver == "ceph" || driver == "cephfs" || driver == "nvme" {
 		if config["size"] != "" {
 			return fmt.Errorf(`The "size" property does not apply to %q storage pools`, driver)
 		}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	dbCluster "github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/lxd/lifecycle"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/warnings"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// storagePoolUsageInterval is how often the usage of the storage pools is sampled.
+const storagePoolUsageInterval = 10 * time.Minute
+
+// storagePoolUsageWindow is the period of samples over which the growth rate of a pool is computed.
+const storagePoolUsageWindow = 24 * time.Hour
+
+// storagePoolUsageMinSamples is the number of samples needed before forecasting the usage of a pool.
+const storagePoolUsageMinSamples = 6
+
+// storagePoolUsageDefaultThreshold is the usage (in percent) above which a warning is raised when
+// usage.warning_threshold isn't set.
+const storagePoolUsageDefaultThreshold = 90
+
+// storagePoolUsageDefaultHorizon is the forecast horizon used when usage.forecast_horizon isn't set.
+const storagePoolUsageDefaultHorizon = "7d"
+
+// storagePoolUsageSample is the usage of a storage pool at a point in time.
+type storagePoolUsageSample struct {
+	time  time.Time
+	used  uint64
+	total uint64
+}
+
+// storagePoolUsageState is the usage history of a storage pool and the warnings currently raised for it.
+type storagePoolUsageState struct {
+	samples []storagePoolUsageSample
+	raised  map[db.WarningType]bool
+}
+
+// storagePoolUsageTask periodically samples the usage of the storage pools, raising warnings (and events) when
+// a pool crosses its usage threshold or is projected to fill up within its forecast horizon.
+// Remote pools are only sampled by the cluster leader as they're shared by all members.
+func storagePoolUsageTask(d *Daemon) (task.Func, task.Schedule) {
+	pools := map[string]*storagePoolUsageState{}
+
+	f := func(ctx context.Context) {
+		sampleRemote, err := storagePoolUsageIsLeader(d)
+		if err != nil {
+			logger.Error("Failed to get leader node address", log.Ctx{"err": err})
+			return
+		}
+
+		storagePoolUsageCheck(d.State(), pools, sampleRemote, time.Now())
+	}
+
+	return f, task.Every(storagePoolUsageInterval)
+}
+
+// storagePoolUsageIsLeader returns whether this member is the cluster leader (or isn't clustered).
+func storagePoolUsageIsLeader(d *Daemon) (bool, error) {
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return false, err
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrNodeIsNotClustered {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return localAddress == leader, nil
+}
+
+// storagePoolUsageCheck samples the usage of the storage pools and updates their warnings.
+func storagePoolUsageCheck(s *state.State, pools map[string]*storagePoolUsageState, sampleRemote bool, now time.Time) {
+	poolNames, err := s.Cluster.GetCreatedStoragePoolNames()
+	if err != nil && err != db.ErrNoSuchObject {
+		logger.Error("Failed to get storage pools", log.Ctx{"err": err})
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, poolName := range poolNames {
+		// Skip the pools which are still being mounted in the background.
+		if storagePools.IsPoolMountDeferred(poolName) {
+			continue
+		}
+
+		pool, err := storagePools.GetPoolByName(s, poolName)
+		if err != nil {
+			logger.Warn("Failed to load storage pool", log.Ctx{"pool": poolName, "err": err})
+			continue
+		}
+
+		if pool.Driver().Info().Remote && !sampleRemote {
+			continue
+		}
+
+		seen[poolName] = true
+
+		res, err := pool.GetResources()
+		if err != nil {
+			logger.Debug("Failed to get storage pool usage", log.Ctx{"pool": poolName, "err": err})
+			continue
+		}
+
+		if res.Space.Total == 0 {
+			continue
+		}
+
+		usage, found := pools[poolName]
+		if !found {
+			usage = &storagePoolUsageState{raised: map[db.WarningType]bool{}}
+			pools[poolName] = usage
+		}
+
+		usage.samples = storagePoolUsageAppend(usage.samples, storagePoolUsageSample{time: now, used: res.Space.Used, total: res.Space.Total})
+
+		storagePoolUsageWarn(s, pool, usage)
+	}
+
+	// Forget the pools which were deleted.
+	for poolName := range pools {
+		if !seen[poolName] {
+			delete(pools, poolName)
+		}
+	}
+}
+
+// storagePoolUsageAppend adds a sample to the history, dropping the samples older than the window.
+func storagePoolUsageAppend(samples []storagePoolUsageSample, sample storagePoolUsageSample) []storagePoolUsageSample {
+	samples = append(samples, sample)
+
+	for len(samples) > 0 && sample.time.Sub(samples[0].time) > storagePoolUsageWindow {
+		samples = samples[1:]
+	}
+
+	return samples
+}
+
+// storagePoolUsageGrowth returns the growth rate of the used space (in bytes per second) through a least squares
+// fit of the samples. Returns false if there aren't enough samples to compute it.
+func storagePoolUsageGrowth(samples []storagePoolUsageSample) (float64, bool) {
+	if len(samples) < storagePoolUsageMinSamples {
+		return 0, false
+	}
+
+	origin := samples[0].time
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := sample.time.Sub(origin).Seconds()
+		y := float64(sample.used)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// storagePoolUsageTimeToFull returns how long it will take the pool to fill up at its current growth rate.
+// Returns false if the pool isn't growing or there isn't enough history.
+func storagePoolUsageTimeToFull(samples []storagePoolUsageSample) (time.Duration, bool) {
+	rate, ok := storagePoolUsageGrowth(samples)
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+
+	last := samples[len(samples)-1]
+	if last.used >= last.total {
+		return 0, true
+	}
+
+	return time.Duration(float64(last.total-last.used) / rate * float64(time.Second)), true
+}
+
+// storagePoolUsageLimits returns the usage threshold (in percent) and forecast horizon of a pool.
+// A threshold of 0 or a horizon of 0 disable the respective warning.
+func storagePoolUsageLimits(config map[string]string) (uint64, time.Duration, error) {
+	threshold := uint64(storagePoolUsageDefaultThreshold)
+	if config["usage.warning_threshold"] != "" {
+		value, err := strconv.ParseUint(config["usage.warning_threshold"], 10, 8)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "Invalid usage.warning_threshold")
+		}
+
+		threshold = value
+	}
+
+	horizonExpr := config["usage.forecast_horizon"]
+	if horizonExpr == "" {
+		horizonExpr = storagePoolUsageDefaultHorizon
+	}
+
+	// Use UTC so that the horizon doesn't shift across daylight saving changes.
+	now := time.Now().UTC()
+	until, err := shared.GetSnapshotExpiry(now, horizonExpr)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Invalid usage.forecast_horizon")
+	}
+
+	return threshold, until.Sub(now), nil
+}
+
+// storagePoolUsageWarn raises or resolves the usage warnings of a pool from its latest samples.
+func storagePoolUsageWarn(s *state.State, pool storagePools.Pool, usage *storagePoolUsageState) {
+	threshold, horizon, err := storagePoolUsageLimits(pool.Driver().Config())
+	if err != nil {
+		logger.Warn("Failed to get storage pool usage limits", log.Ctx{"pool": pool.Name(), "err": err})
+		return
+	}
+
+	last := usage.samples[len(usage.samples)-1]
+	percent := last.used * 100 / last.total
+
+	messages := map[db.WarningType]string{}
+
+	if threshold > 0 && percent >= threshold {
+		messages[db.WarningStoragePoolUsageThreshold] = fmt.Sprintf("Storage pool %q is %d%% full (%s of %s used)", pool.Name(), percent, units.GetByteSizeStringIEC(int64(last.used), 2), units.GetByteSizeStringIEC(int64(last.total), 2))
+	}
+
+	timeToFull, ok := storagePoolUsageTimeToFull(usage.samples)
+	if horizon > 0 && ok && timeToFull <= horizon {
+		messages[db.WarningStoragePoolUsageForecast] = fmt.Sprintf("Storage pool %q is projected to fill up in %s", pool.Name(), timeToFull.Truncate(time.Minute))
+	}
+
+	for _, warningType := range []db.WarningType{db.WarningStoragePoolUsageThreshold, db.WarningStoragePoolUsageForecast} {
+		message, raise := messages[warningType]
+		if !raise {
+			// Also resolves the warnings left over from before a restart.
+			err := warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.Cluster, "", warningType, dbCluster.TypeStoragePool, int(pool.ID()))
+			if err != nil {
+				logger.Warn("Failed to resolve storage pool usage warning", log.Ctx{"pool": pool.Name(), "err": err})
+				continue
+			}
+
+			delete(usage.raised, warningType)
+			continue
+		}
+
+		err := s.Cluster.UpsertWarningLocalNode("", dbCluster.TypeStoragePool, int(pool.ID()), warningType, message)
+		if err != nil {
+			logger.Warn("Failed to create storage pool usage warning", log.Ctx{"pool": pool.Name(), "err": err})
+			continue
+		}
+
+		// Only notify on the transition, the warning itself is kept up to date on every sample.
+		if !usage.raised[warningType] {
+			logger.Warn(message)
+
+			ctx := log.Ctx{"used": last.used, "total": last.total, "warning": db.WarningTypeNames[warningType]}
+			if ok {
+				ctx["time_to_full"] = int64(timeToFull.Seconds())
+			}
+
+			s.Events.SendLifecycle(project.Default, lifecycle.StoragePoolUsageWarning.Event(pool.Name(), project.Default, nil, ctx))
+			usage.raised[warningType] = true
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoragePoolUsageTimeToFull(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Growing by 1GB an hour with 100GB left.
+	samples := []storagePoolUsageSample{}
+	for i := 0; i < 12; i++ {
+		sample := storagePoolUsageSample{
+			time:  start.Add(time.Duration(i) * time.Hour),
+			used:  uint64(i) * 1000 * 1000 * 1000,
+			total: 111 * 1000 * 1000 * 1000,
+		}
+
+		samples = storagePoolUsageAppend(samples, sample)
+	}
+
+	timeToFull, ok := storagePoolUsageTimeToFull(samples)
+	require.True(t, ok)
+	assert.Equal(t, 100*time.Hour, timeToFull.Round(time.Minute))
+
+	// Not enough history.
+	_, ok = storagePoolUsageTimeToFull(samples[:storagePoolUsageMinSamples-1])
+	assert.False(t, ok)
+
+	// Shrinking pools are never projected to fill up.
+	shrinking := []storagePoolUsageSample{}
+	for i := range samples {
+		shrinking = append(shrinking, storagePoolUsageSample{time: samples[i].time, used: samples[len(samples)-1-i].used, total: samples[i].total})
+	}
+
+	_, ok = storagePoolUsageTimeToFull(shrinking)
+	assert.False(t, ok)
+}
+
+func TestStoragePoolUsageAppend(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	samples := []storagePoolUsageSample{}
+	for i := 0; i <= 30; i++ {
+		samples = storagePoolUsageAppend(samples, storagePoolUsageSample{time: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	// Only the samples within the window are kept.
+	assert.Len(t, samples, 25)
+	assert.Equal(t, start.Add(6*time.Hour), samples[0].time)
+}
+
+func TestStoragePoolUsageLimits(t *testing.T) {
+	threshold, horizon, err := storagePoolUsageLimits(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(storagePoolUsageDefaultThreshold), threshold)
+	assert.Equal(t, 7*24*time.Hour, horizon)
+
+	threshold, horizon, err = storagePoolUsageLimits(map[string]string{"usage.warning_threshold": "0", "usage.forecast_horizon": "12H"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), threshold)
+	assert.Equal(t, 12*time.Hour, horizon)
+
+	_, _, err = storagePoolUsageLimits(map[string]string{"usage.forecast_horizon": "soon"})
+	assert.Error(t, err)
+}
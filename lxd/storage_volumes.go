@@ -14,8 +14,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/filter"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
@@ -72,6 +75,20 @@ var storagePoolVolumeTypeCmd = APIEndpoint{
 //     type: string
 //     example: default
 //   - in: query
+//     name: filter
+//     description: Collection filter
+//     type: string
+//     example: default
+//   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: target
 //     description: Cluster member name
 //     type: string
@@ -128,6 +145,20 @@ var storagePoolVolumeTypeCmd = APIEndpoint{
 //     type: string
 //     example: default
 //   - in: query
+//     name: filter
+//     description: Collection filter
+//     type: string
+//     example: default
+//   - in: query
+//     name: limit
+//     description: Maximum number of entries to return
+//     type: integer
+//     example: 100
+//   - in: query
+//     name: after
+//     description: Cursor of the last entry of the previous page
+//     type: string
+//   - in: query
 //     name: target
 //     description: Cluster member name
 //     type: string
@@ -167,6 +198,34 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := util.IsRecursionRequest(r)
 
+	page, err := paginationParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Parse filter value
+	var clauses []filter.Clause
+
+	filterStr := r.FormValue("filter")
+	if filterStr != "" {
+		clauses, err = filter.Parse(filterStr)
+		if err != nil {
+			return response.BadRequest(errors.Wrap(err, "Invalid filter"))
+		}
+	}
+
+	// Only query the volume types the filter may match.
+	volumeTypes := append([]int{db.StoragePoolVolumeTypeCustom, db.StoragePoolVolumeTypeImage}, supportedVolumeTypesInstances...)
+	typeName, ok := filter.ExactValue(clauses, "type")
+	if ok {
+		volumeType, err := storagePools.VolumeTypeNameToDBType(typeName)
+		if err != nil {
+			volumeTypes = []int{}
+		} else {
+			volumeTypes = []int{volumeType}
+		}
+	}
+
 	// Retrieve ID of the storage pool (and check if the storage pool exists).
 	poolID, err := d.cluster.GetStoragePoolID(poolName)
 	if err != nil {
@@ -174,9 +233,19 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get all instance volumes currently attached to the storage pool by ID of the pool and project.
-	volumes, err := d.cluster.GetStoragePoolVolumes(projectName, poolID, supportedVolumeTypesInstances)
-	if err != nil && err != db.ErrNoSuchObject {
-		return response.SmartError(err)
+	instanceVolumeTypes := []int{}
+	for _, volumeType := range supportedVolumeTypesInstances {
+		if shared.IntInSlice(volumeType, volumeTypes) {
+			instanceVolumeTypes = append(instanceVolumeTypes, volumeType)
+		}
+	}
+
+	volumes := []*api.StorageVolume{}
+	if len(instanceVolumeTypes) > 0 {
+		volumes, err = d.cluster.GetStoragePoolVolumes(projectName, poolID, instanceVolumeTypes, page)
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
 	}
 
 	// The project name used for custom volumes varies based on whether the project has the
@@ -187,32 +256,59 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get all custom volumes currently attached to the storage pool by ID of the pool and project.
-	custVolumes, err := d.cluster.GetStoragePoolVolumes(customVolProjectName, poolID, []int{db.StoragePoolVolumeTypeCustom})
-	if err != nil && err != db.ErrNoSuchObject {
-		return response.SmartError(err)
-	}
+	if shared.IntInSlice(db.StoragePoolVolumeTypeCustom, volumeTypes) {
+		custVolumes, err := d.cluster.GetStoragePoolVolumes(customVolProjectName, poolID, []int{db.StoragePoolVolumeTypeCustom}, page)
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
 
-	for _, volume := range custVolumes {
-		volumes = append(volumes, volume)
+		for _, volume := range custVolumes {
+			volumes = append(volumes, volume)
+		}
 	}
 
 	// We exclude volumes of type image, since those are special: they are stored using the storage_volumes
 	// table, but are effectively a cache which is not tied to projects, so we always link the to the default
 	// project. This means that we want to filter image volumes and return only the ones that have fingerprint
 	// matching images actually in use by the project.
-	imageVolumes, err := d.cluster.GetStoragePoolVolumes(project.Default, poolID, []int{db.StoragePoolVolumeTypeImage})
-	if err != nil && err != db.ErrNoSuchObject {
-		return response.SmartError(err)
+	// Those aren't paginated by the query as the filtering would otherwise cause entries of the page to be skipped.
+	if shared.IntInSlice(db.StoragePoolVolumeTypeImage, volumeTypes) {
+		imageVolumes, err := d.cluster.GetStoragePoolVolumes(project.Default, poolID, []int{db.StoragePoolVolumeTypeImage}, db.Pagination{})
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
+
+		projectImages, err := d.cluster.GetImagesFingerprints(projectName, false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+		for _, volume := range imageVolumes {
+			if shared.StringInSlice(volume.Name, projectImages) {
+				volumes = append(volumes, volume)
+			}
+		}
 	}
 
-	projectImages, err := d.cluster.GetImagesFingerprints(projectName, false)
-	if err != nil {
-		return response.SmartError(err)
+	// Each of the queries above returned its own page, only keep the overall one.
+	last := ""
+	if page.IsSet() {
+		volumes = db.PaginateStorageVolumes(poolName, volumes, page)
+		if len(volumes) > 0 {
+			last = db.StorageVolumeCursor(poolName, volumes[len(volumes)-1])
+		}
 	}
-	for _, volume := range imageVolumes {
-		if shared.StringInSlice(volume.Name, projectImages) {
-			volumes = append(volumes, volume)
+
+	// Each page is counted before filtering.
+	count := len(volumes)
+	if clauses != nil {
+		filtered := []*api.StorageVolume{}
+		for _, volume := range volumes {
+			if filter.Match(*volume, clauses) {
+				filtered = append(filtered, volume)
+			}
 		}
+
+		volumes = filtered
 	}
 
 	resultString := []string{}
@@ -250,10 +346,10 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if !recursion {
-		return response.SyncResponse(true, resultString)
+		return paginatedResponse(page, count, last, resultString)
 	}
 
-	return response.SyncResponse(true, volumes)
+	return paginatedResponse(page, count, last, volumes)
 }
 
 // swagger:operation GET /1.0/storage-pools/{name}/volumes/{type} storage storage_pool_volumes_type_get
@@ -475,6 +571,14 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) response.Response {
 
 	// If we're getting binary content, process separately.
 	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		if r.Header.Get("X-LXD-type") == db.StoragePoolVolumeContentTypeNameISO {
+			if mux.Vars(r)["type"] != db.StoragePoolVolumeTypeNameCustom {
+				return response.BadRequest(fmt.Errorf("ISO images can only be imported as custom volumes"))
+			}
+
+			return createStoragePoolVolumeFromISO(d, r, projectParam(r), projectName, r.Body, poolName, r.Header.Get("X-LXD-name"))
+		}
+
 		return createStoragePoolVolumeFromBackup(d, r, projectParam(r), projectName, r.Body, poolName, r.Header.Get("X-LXD-name"))
 	}
 
@@ -535,6 +639,11 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	switch req.Source.Type {
 	case "":
 		return doVolumeCreateOrCopy(d, r, projectParam(r), projectName, poolName, &req)
@@ -560,6 +669,11 @@ func doVolumeCreateOrCopy(d *Daemon, r *http.Request, requestProjectName string,
 		return response.SmartError(err)
 	}
 
+	// Empty ISO volumes can't be created, they must be imported from an ISO image.
+	if volumeDBContentType == db.StoragePoolVolumeContentTypeISO && req.Source.Name == "" {
+		return response.BadRequest(fmt.Errorf("ISO volumes must be imported from an ISO image"))
+	}
+
 	contentType, err := storagePools.VolumeDBContentTypeToContentType(volumeDBContentType)
 	if err != nil {
 		return response.SmartError(err)
@@ -570,10 +684,18 @@ func doVolumeCreateOrCopy(d *Daemon, r *http.Request, requestProjectName string,
 			// Use an empty operation for this sync response to pass the requestor
 			op := &operations.Operation{}
 			op.SetRequestor(r)
-			return pool.CreateCustomVolume(projectName, req.Name, req.Description, req.Config, contentType, op)
+			err := pool.CreateCustomVolume(projectName, req.Name, req.Description, req.Config, contentType, op)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := pool.CreateCustomVolumeFromCopy(projectName, req.Source.Project, req.Name, req.Description, req.Config, req.Source.Pool, req.Source.Name, req.Source.VolumeOnly, op)
+			if err != nil {
+				return err
+			}
 		}
 
-		return pool.CreateCustomVolumeFromCopy(projectName, req.Source.Project, req.Name, req.Description, req.Config, req.Source.Pool, req.Source.Name, req.Source.VolumeOnly, op)
+		return storageVolumeUpdateTags(d.State(), projectName, req.Name, db.StoragePoolVolumeTypeCustom, pool.ID(), req.Tags)
 	}
 
 	// If no source name supplied then this a volume create operation.
@@ -693,6 +815,11 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	switch req.Source.Type {
 	case "":
 		return doVolumeCreateOrCopy(d, r, projectParam(r), projectName, poolName, &req)
@@ -765,7 +892,12 @@ func doVolumeMigration(d *Daemon, r *http.Request, requestProjectName string, pr
 			return fmt.Errorf("Error transferring storage volume: %s", err)
 		}
 
-		return nil
+		poolID, err := d.cluster.GetStoragePoolID(poolName)
+		if err != nil {
+			return err
+		}
+
+		return storageVolumeUpdateTags(d.State(), projectName, req.Name, db.StoragePoolVolumeTypeCustom, poolID, req.Tags)
 	}
 
 	var op *operations.Operation
@@ -908,14 +1040,32 @@ func storagePoolVolumePost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	// Check that the name isn't already in use.
-	_, err = d.cluster.GetStoragePoolNodeVolumeID(projectName, req.Name, volumeType, targetPoolID)
-	if err != db.ErrNoSuchObject {
+	// Moving to the local cluster member is the same as a regular rename or move.
+	if req.Location != "" {
+		var localName string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			localName, err = tx.GetLocalNodeName()
+			return err
+		})
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 
-		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		if req.Location == localName {
+			req.Location = ""
+		}
+	}
+
+	// Check that the name isn't already in use (the destination member checks it itself).
+	if req.Location == "" {
+		_, err = d.cluster.GetStoragePoolNodeVolumeID(projectName, req.Name, volumeType, targetPoolID)
+		if err != db.ErrNoSuchObject {
+			if err != nil {
+				return response.InternalError(err)
+			}
+
+			return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+		}
 	}
 
 	// Check if the daemon itself is using it.
@@ -956,6 +1106,11 @@ func storagePoolVolumePost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Detect a move to another cluster member.
+	if req.Location != "" {
+		return storagePoolVolumeTypePostMemberMove(d, r, srcPoolName, projectParam(r), projectName, vol, req)
+	}
+
 	// Detect a rename request.
 	if req.Pool == "" || req.Pool == srcPoolName {
 		return storagePoolVolumeTypePostRename(d, r, srcPoolName, projectName, vol, req)
@@ -1085,6 +1240,118 @@ func storagePoolVolumeTypePostMove(d *Daemon, r *http.Request, poolName string,
 	return operations.OperationResponse(op)
 }
 
+// storagePoolVolumeTypePostMemberMove handles volume move type POST requests to another cluster member. The
+// destination member pulls the volume from this one which then deletes it, all within a single operation.
+func storagePoolVolumeTypePostMemberMove(d *Daemon, r *http.Request, poolName string, requestProjectName string, projectName string, vol *api.StorageVolume, req api.StorageVolumePost) response.Response {
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if pool.Driver().Info().Remote {
+		return response.BadRequest(fmt.Errorf("Volumes on remote storage pools aren't tied to a cluster member"))
+	}
+
+	targetPoolName := req.Pool
+	if targetPoolName == "" {
+		targetPoolName = poolName
+	}
+
+	// Volumes attached to an instance can only be used on the member the instance is on.
+	err = storagePools.VolumeUsedByInstanceDevices(d.State(), poolName, projectName, vol, true, func(dbInst db.Instance, project db.Project, profiles []api.Profile, usedByDevices []string) error {
+		return fmt.Errorf("Volume is used by instance %q and can't be moved to another cluster member", dbInst.Name)
+	})
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var sourceAddress string
+	var targetAddress string
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		sourceAddress, err = tx.GetLocalNodeAddress()
+		if err != nil {
+			return errors.Wrap(err, "Failed to get local member address")
+		}
+
+		node, err := tx.GetNodeByName(req.Location)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get cluster member %q", req.Location)
+		}
+
+		targetAddress = node.Address
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+
+		// Connect to the source member, i.e. ourselves.
+		source, err := cluster.Connect(sourceAddress, d.endpoints.NetworkCert(), d.serverCert(), r, true)
+		if err != nil {
+			return errors.Wrap(err, "Failed to connect to source server")
+		}
+		source = source.UseProject(requestProjectName)
+
+		// Connect to the destination member.
+		dest, err := cluster.Connect(targetAddress, d.endpoints.NetworkCert(), d.serverCert(), r, false)
+		if err != nil {
+			return errors.Wrap(err, "Failed to connect to destination server")
+		}
+		dest = dest.UseTarget(req.Location).UseProject(requestProjectName)
+
+		args := lxd.StoragePoolVolumeCopyArgs{
+			Name:       req.Name,
+			Mode:       "pull",
+			VolumeOnly: req.VolumeOnly,
+		}
+
+		copyOp, err := dest.CopyStoragePoolVolume(targetPoolName, source, poolName, *vol, &args)
+		if err != nil {
+			return errors.Wrap(err, "Failed to issue copy storage volume API request")
+		}
+
+		_, err = copyOp.AddHandler(func(newOp api.Operation) {
+			op.UpdateMetadata(newOp.Metadata)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = copyOp.Wait()
+		if err != nil {
+			return errors.Wrap(err, "Copy storage volume operation failed")
+		}
+
+		revert.Add(func() {
+			dest.DeleteStoragePoolVolume(targetPoolName, db.StoragePoolVolumeTypeNameCustom, req.Name)
+		})
+
+		err = pool.DeleteCustomVolume(projectName, vol.Name, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Success()
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, vol.Name)}
+
+	op, err := operations.OperationCreate(d.State(), requestProjectName, operations.OperationClassTask, db.OperationVolumeMove, resources, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
 // storageGetVolumeNameFromURL retrieves the volume name from the URL name segment.
 func storageGetVolumeNameFromURL(r *http.Request) (string, error) {
 	fields := strings.Split(mux.Vars(r)["name"], "/")
@@ -1203,7 +1470,7 @@ func storagePoolVolumeGet(d *Daemon, r *http.Request) response.Response {
 	}
 	volume.UsedBy = project.FilterUsedBy(r, volumeUsedBy)
 
-	etag := []interface{}{volumeName, volume.Type, volume.Config}
+	etag := []interface{}{volumeName, volume.Type, volume.Description, volume.Config}
 
 	return response.SyncResponseETag(true, volume, etag)
 }
@@ -1298,7 +1565,7 @@ func storagePoolVolumePut(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag
-	etag := []interface{}{volumeName, vol.Type, vol.Config}
+	etag := []interface{}{volumeName, vol.Type, vol.Description, vol.Config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -1310,6 +1577,15 @@ func storagePoolVolumePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if len(req.Tags) > 0 && volumeType != db.StoragePoolVolumeTypeCustom {
+		return response.BadRequest(fmt.Errorf("Tags are only supported on custom volumes"))
+	}
+
 	// Use an empty operation for this sync response to pass the requestor
 	op := &operations.Operation{}
 	op.SetRequestor(r)
@@ -1342,6 +1618,11 @@ func storagePoolVolumePut(d *Daemon, r *http.Request) response.Response {
 				return response.SmartError(err)
 			}
 		}
+
+		err = storageVolumeUpdateTags(d.State(), projectName, vol.Name, volumeType, pool.ID(), req.Tags)
+		if err != nil {
+			return response.SmartError(err)
+		}
 	} else if volumeType == db.StoragePoolVolumeTypeContainer || volumeType == db.StoragePoolVolumeTypeVM {
 		inst, err := instance.LoadByProjectAndName(d.State(), projectName, vol.Name)
 		if err != nil {
@@ -1455,7 +1736,7 @@ func storagePoolVolumePatch(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Validate the ETag.
-	etag := []interface{}{volumeName, vol.Type, vol.Config}
+	etag := []interface{}{volumeName, vol.Type, vol.Description, vol.Config}
 
 	err = util.EtagCheck(r, etag)
 	if err != nil {
@@ -1467,6 +1748,11 @@ func storagePoolVolumePatch(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = validateTags(req.Tags)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	if req.Config == nil {
 		req.Config = map[string]string{}
 	}
@@ -1488,6 +1774,11 @@ func storagePoolVolumePatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = storageVolumeUpdateTags(d.State(), projectName, vol.Name, volumeType, pool.ID(), req.Tags)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1748,3 +2039,78 @@ func createStoragePoolVolumeFromBackup(d *Daemon, r *http.Request, requestProjec
 	revert.Success()
 	return operations.OperationResponse(op)
 }
+
+func createStoragePoolVolumeFromISO(d *Daemon, r *http.Request, requestProjectName string, projectName string, data io.Reader, pool string, volName string) response.Response {
+	revert := revert.New()
+	defer revert.Fail()
+
+	if volName == "" {
+		return response.BadRequest(fmt.Errorf("Missing volume name"))
+	}
+
+	if strings.Contains(volName, "/") {
+		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
+	}
+
+	// Check if destination volume exists.
+	poolID, err := d.cluster.GetStoragePoolID(pool)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, _, err = d.cluster.GetLocalStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, poolID)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("Volume by that name already exists"))
+	}
+
+	// Create temporary file to store uploaded ISO data.
+	isoFile, err := ioutil.TempFile(shared.VarPath("backups"), fmt.Sprintf("%s_iso_", backup.WorkingDirPrefix))
+	if err != nil {
+		return response.InternalError(err)
+	}
+	defer os.Remove(isoFile.Name())
+	revert.Add(func() { isoFile.Close() })
+
+	// Stream uploaded ISO data into temporary file.
+	size, err := io.Copy(isoFile, data)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// Copy reverter so far so we can use it inside run after this function has finished.
+	runRevert := revert.Clone()
+
+	run := func(op *operations.Operation) error {
+		defer isoFile.Close()
+		defer runRevert.Fail()
+
+		pool, err := storagePools.GetPoolByName(d.State(), pool)
+		if err != nil {
+			return err
+		}
+
+		// Dump ISO image to storage.
+		err = pool.CreateCustomVolumeFromISO(projectName, volName, isoFile, size, op)
+		if err != nil {
+			return errors.Wrap(err, "Create custom volume from ISO")
+		}
+
+		runRevert.Success()
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{volName}
+
+	op, err := operations.OperationCreate(d.State(), requestProjectName, operations.OperationClassTask, db.OperationCustomVolumeISOImport, resources, nil, run, nil, nil, r)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	revert.Success()
+	return operations.OperationResponse(op)
+}
@@ -370,6 +370,13 @@ func storagePoolVolumeTypeCustomBackupsPost(d *Daemon, r *http.Request) response
 	volumeOnly := req.VolumeOnly
 
 	backup := func(op *operations.Operation) error {
+		// Wait for our turn if the number of concurrent backups is limited.
+		releaseQueue, err := operations.QueueAcquire(d.ctx, op, operations.QueueBackups, operations.QueuePriorityNormal)
+		if err != nil {
+			return errors.Wrapf(err, "Failed waiting for backup slot")
+		}
+		defer releaseQueue()
+
 		args := db.StoragePoolVolumeBackup{
 			Name:                 fullName,
 			VolumeID:             volumeID,
@@ -380,7 +387,7 @@ func storagePoolVolumeTypeCustomBackupsPost(d *Daemon, r *http.Request) response
 			CompressionAlgorithm: req.CompressionAlgorithm,
 		}
 
-		err := volumeBackupCreate(d.State(), args, projectName, poolName, volumeName)
+		err = volumeBackupCreate(d.State(), args, projectName, poolName, volumeName)
 		if err != nil {
 			return errors.Wrap(err, "Create volume backup")
 		}
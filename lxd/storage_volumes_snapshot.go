@@ -1048,8 +1048,14 @@ func autoCreateCustomVolumeSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
 				continue
 			}
 
+			delay, err := snapshotScheduleDelay(v.Config["snapshots.schedule.offset"], v.Config["snapshots.schedule.jitter"], v.ID)
+			if err != nil {
+				logger.Error("Failed getting snapshot schedule delay", log.Ctx{"err": err, "vol": v.Name, "project": v.ProjectName, "pool": v.PoolName})
+				continue
+			}
+
 			// Check if snapshot is scheduled.
-			if !snapshotIsScheduledNow(schedule, v.ID) {
+			if !snapshotIsScheduledNow(schedule, v.ID, delay) {
 				continue
 			}
 
@@ -1142,6 +1148,11 @@ func autoCreateCustomVolumeSnapshotsTask(d *Daemon) (task.Func, task.Schedule) {
 		}
 
 		opRun := func(op *operations.Operation) error {
+			// Take turns with the other cluster members to avoid stalling shared storage.
+			if !snapshotWaitMemberSlot(ctx, d) {
+				return nil
+			}
+
 			autoCreateCustomVolumeSnapshots(ctx, d, volumes)
 			return nil
 		}
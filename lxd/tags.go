@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// tagMaxLength is the maximum length of a tag.
+const tagMaxLength = 64
+
+// tagRegexp matches the characters allowed in tags.
+var tagRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:-]*$`)
+
+// validateTags checks that the tags are valid and unique.
+func validateTags(tags []string) error {
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if len(tag) > tagMaxLength {
+			return fmt.Errorf("Tag %q is longer than %d characters", tag, tagMaxLength)
+		}
+
+		if !tagRegexp.MatchString(tag) {
+			return fmt.Errorf("Invalid tag %q (only letters, digits, \"_\", \".\", \":\" and \"-\" are allowed)", tag)
+		}
+
+		if seen[tag] {
+			return fmt.Errorf("Duplicate tag %q", tag)
+		}
+
+		seen[tag] = true
+	}
+
+	return nil
+}
+
+// tagsContainAll returns whether all the wanted tags are part of the tags.
+func tagsContainAll(tags []string, wanted []string) bool {
+	for _, tag := range wanted {
+		if !shared.StringInSlice(tag, tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// instanceRenderTags can be used as an optional argument to Instance.Render() to return the instance tags.
+func instanceRenderTags(s *state.State, inst instance.Instance) func(response interface{}) error {
+	return func(response interface{}) error {
+		apiInst, ok := response.(*api.Instance)
+		if !ok {
+			return nil
+		}
+
+		return s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			apiInst.Tags, err = tx.GetInstanceTags(inst.ID())
+			return err
+		})
+	}
+}
+
+// instanceUpdateTags replaces the tags of the instance, nil tags leave the current ones untouched.
+func instanceUpdateTags(s *state.State, inst instance.Instance, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+
+	return s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateInstanceTags(inst.ID(), tags)
+	})
+}
+
+// storageVolumeUpdateTags replaces the tags of the storage volume, nil tags leave the current ones untouched.
+func storageVolumeUpdateTags(s *state.State, projectName string, volumeName string, volumeType int, poolID int64, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+
+	return s.Cluster.UpdateStoragePoolVolumeTags(projectName, volumeName, volumeType, poolID, tags)
+}
+
+// imageUpdateTags replaces the tags of the image, nil tags leave the current ones untouched.
+func imageUpdateTags(s *state.State, id int, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+
+	return s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateImageTags(id, tags)
+	})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTags(t *testing.T) {
+	assert.NoError(t, validateTags(nil))
+	assert.NoError(t, validateTags([]string{"prod", "web-1", "team:ops", "v1.2_3"}))
+
+	assert.Error(t, validateTags([]string{""}))
+	assert.Error(t, validateTags([]string{"-prod"}))
+	assert.Error(t, validateTags([]string{"with space"}))
+	assert.Error(t, validateTags([]string{strings.Repeat("a", tagMaxLength+1)}))
+	assert.Error(t, validateTags([]string{"prod", "prod"}))
+}
+
+func TestTagsContainAll(t *testing.T) {
+	assert.True(t, tagsContainAll([]string{"prod", "web"}, nil))
+	assert.True(t, tagsContainAll([]string{"prod", "web"}, []string{"web", "prod"}))
+	assert.False(t, tagsContainAll([]string{"prod"}, []string{"prod", "web"}))
+	assert.False(t, tagsContainAll(nil, []string{"prod"}))
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package vsock
+
+import (
+	"net"
+
+	"github.com/mdlayher/vsock"
+)
+
+// Listen listens for a connection.
+func Listen(port uint32) (net.Listener, error) {
+	return vsock.Listen(port)
+}
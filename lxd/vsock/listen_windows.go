@@ -0,0 +1,274 @@
+package vsock
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// afVsock is the address family the virtio-win socket driver (viosock) registers with Winsock.
+const afVsock = 40
+
+// cidAny accepts connections whatever the CID they were sent to.
+const cidAny = 0xffffffff
+
+var (
+	modws2_32  = windows.NewLazySystemDLL("ws2_32.dll")
+	procBind   = modws2_32.NewProc("bind")
+	procAccept = modws2_32.NewProc("accept")
+)
+
+// sockaddrVM is the vsock socket address as defined by the virtio-win socket driver.
+type sockaddrVM struct {
+	Family    uint16
+	Reserved1 uint16
+	Port      uint32
+	CID       uint32
+	Zero      [4]byte
+}
+
+// Addr is a vsock address.
+type Addr struct {
+	CID  uint32
+	Port uint32
+}
+
+// Network returns the address's network name.
+func (a *Addr) Network() string {
+	return "vsock"
+}
+
+// String returns a human readable form of the address.
+func (a *Addr) String() string {
+	return fmt.Sprintf("vm(%d):%d", a.CID, a.Port)
+}
+
+// Listen listens for a connection through the virtio-win socket driver, which must be installed in the guest.
+func Listen(port uint32) (net.Listener, error) {
+	var data windows.WSAData
+	err := windows.WSAStartup(uint32(0x202), &data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed initializing Winsock: %v", err)
+	}
+
+	s, err := windows.Socket(afVsock, windows.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating vsock socket (is the virtio-win socket driver installed?): %v", err)
+	}
+
+	addr := sockaddrVM{Family: afVsock, Port: port, CID: cidAny}
+	r, _, err := procBind.Call(uintptr(s), uintptr(unsafe.Pointer(&addr)), unsafe.Sizeof(addr))
+	if r != 0 {
+		windows.Closesocket(s)
+		return nil, fmt.Errorf("Failed binding vsock port %d: %v", port, err)
+	}
+
+	err = windows.Listen(s, windows.SOMAXCONN)
+	if err != nil {
+		windows.Closesocket(s)
+		return nil, fmt.Errorf("Failed listening on vsock port %d: %v", port, err)
+	}
+
+	return &listener{s: s, addr: &Addr{CID: cidAny, Port: port}}, nil
+}
+
+type listener struct {
+	s    windows.Handle
+	addr *Addr
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	r, _, err := procAccept.Call(uintptr(l.s), 0, 0)
+	if windows.Handle(r) == windows.InvalidHandle {
+		return nil, err
+	}
+
+	return newConn(windows.Handle(r), l.addr), nil
+}
+
+func (l *listener) Close() error {
+	return windows.Closesocket(l.s)
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// timeoutError is returned by reads which reached their deadline.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// conn is a vsock connection. Winsock reads can't be interrupted, so they're done in the background so that read
+// deadlines (which net/http relies on) can be honoured.
+type conn struct {
+	s    windows.Handle
+	addr *Addr
+
+	data    chan []byte
+	readErr error
+	pending []byte
+
+	mu              sync.Mutex
+	readDeadline    time.Time
+	deadlineChanged chan struct{}
+	closed          chan struct{}
+	closeOnce       sync.Once
+}
+
+func newConn(s windows.Handle, addr *Addr) *conn {
+	c := &conn{
+		s:               s,
+		addr:            addr,
+		data:            make(chan []byte),
+		deadlineChanged: make(chan struct{}),
+		closed:          make(chan struct{}),
+	}
+
+	go c.receive()
+
+	return c
+}
+
+// receive reads from the socket until it's closed.
+func (c *conn) receive() {
+	defer close(c.data)
+
+	for {
+		b := make([]byte, 32*1024)
+		buf := windows.WSABuf{Len: uint32(len(b)), Buf: &b[0]}
+
+		var n, flags uint32
+		err := windows.WSARecv(c.s, &buf, 1, &n, &flags, nil, nil)
+		if err != nil {
+			c.readErr = err
+			return
+		}
+
+		if n == 0 {
+			c.readErr = io.EOF
+			return
+		}
+
+		select {
+		case c.data <- b[:n]:
+		case <-c.closed:
+			c.readErr = io.EOF
+			return
+		}
+	}
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	for len(c.pending) == 0 {
+		c.mu.Lock()
+		deadline := c.readDeadline
+		changed := c.deadlineChanged
+		c.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, timeoutError{}
+			}
+
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		var chunk []byte
+		ok := true
+		timedOut := false
+
+		select {
+		case chunk, ok = <-c.data:
+		case <-timeout:
+			timedOut = true
+		case <-changed:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if !ok {
+			return 0, c.readErr
+		}
+
+		if timedOut {
+			return 0, timeoutError{}
+		}
+
+		c.pending = chunk
+	}
+
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+
+	return n, nil
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		buf := windows.WSABuf{Len: uint32(len(b) - written), Buf: &b[written]}
+
+		var n uint32
+		err := windows.WSASend(c.s, &buf, 1, &n, 0, nil, nil)
+		if err != nil {
+			return written, err
+		}
+
+		written += int(n)
+	}
+
+	return written, nil
+}
+
+func (c *conn) Close() error {
+	err := fmt.Errorf("Connection already closed")
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = windows.Closesocket(c.s)
+	})
+
+	return err
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return &Addr{CID: 2}
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the deadline of the reads, waking up the pending one.
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+	close(c.deadlineChanged)
+	c.deadlineChanged = make(chan struct{})
+
+	return nil
+}
+
+// SetWriteDeadline does nothing, writes only block while the host is busy.
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -17,11 +17,6 @@ func Dial(cid, port uint32) (net.Conn, error) {
 	return vsock.Dial(cid, port)
 }
 
-// Listen listens for a connection.
-func Listen(port uint32) (net.Listener, error) {
-	return vsock.Listen(port)
-}
-
 // HTTPClient provides an HTTP client for using over vsock.
 func HTTPClient(vsockID int, tlsClientCert string, tlsClientKey string, tlsServerCert string) (*http.Client, error) {
 	client := &http.Client{}
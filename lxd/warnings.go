@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/request"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
@@ -266,7 +267,7 @@ func warningGet(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	return response.SyncResponse(true, resp)
+	return response.SyncResponseETag(true, resp, resp.WarningPut)
 }
 
 // swagger:operation PATCH /1.0/warnings/{uuid} warnings warning_patch
@@ -294,6 +295,8 @@ func warningGet(d *Daemon, r *http.Request) response.Response {
 //     $ref: "#/responses/BadRequest"
 //   "403":
 //     $ref: "#/responses/Forbidden"
+//   "412":
+//     $ref: "#/responses/PreconditionFailed"
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func warningPatch(d *Daemon, r *http.Request) response.Response {
@@ -325,14 +328,37 @@ func warningPatch(d *Daemon, r *http.Request) response.Response {
 //     $ref: "#/responses/BadRequest"
 //   "403":
 //     $ref: "#/responses/Forbidden"
+//   "412":
+//     $ref: "#/responses/PreconditionFailed"
 //   "500":
 //     $ref: "#/responses/InternalServerError"
 func warningPut(d *Daemon, r *http.Request) response.Response {
 	id := mux.Vars(r)["id"]
 
+	var dbWarning *db.Warning
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		dbWarning, err = tx.GetWarning(id)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	current, err := dbWarning.ToAPI(d.cluster)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	err = util.EtagCheck(r, current.WarningPut)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
 	req := api.WarningPut{}
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		return response.BadRequest(err)
 	}
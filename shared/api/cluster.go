@@ -232,4 +232,112 @@ type ClusterMemberStatePost struct {
 	// The action to be performed. Valid actions are "evacuate" and "restore".
 	// Example: evacuate
 	Action string `json:"action" yaml:"action"`
+
+	// Whether to evacuate the member even if some of its instances have security.protection.stop enabled
+	// Example: false
+	//
+	// API extension: instance_protection_stop
+	ForceProtected bool `json:"force_protected" yaml:"force_protected"`
+}
+
+// ClusterFailureDomainPut represents the modifiable fields of a failure domain.
+//
+// swagger:model
+//
+// API extension: clustering_failure_domains_config
+type ClusterFailureDomainPut struct {
+	// Server configuration keys overridden for the members of the failure domain
+	// Example: {"images.auto_update_interval": "0"}
+	Config map[string]string `json:"config" yaml:"config"`
+}
+
+// ClusterFailureDomain represents a failure domain of the cluster members.
+//
+// swagger:model
+//
+// API extension: clustering_failure_domains_config
+type ClusterFailureDomain struct {
+	ClusterFailureDomainPut `yaml:",inline"`
+
+	// Name of the failure domain
+	// Example: edge
+	Name string `json:"name" yaml:"name"`
+
+	// List of members in the failure domain
+	// Example: ["lxd01", "lxd02"]
+	Members []string `json:"members" yaml:"members"`
+}
+
+// Writable converts a full ClusterFailureDomain struct into a ClusterFailureDomainPut struct (filters read-only
+// fields).
+func (domain *ClusterFailureDomain) Writable() ClusterFailureDomainPut {
+	return domain.ClusterFailureDomainPut
+}
+
+// ClusterUpgrade represents the versions run by the cluster members.
+//
+// swagger:model
+//
+// API extension: clustering_upgrade
+type ClusterUpgrade struct {
+	// Database schema version the cluster is upgrading to (most recent among the members)
+	// Example: 50
+	Schema int `json:"schema" yaml:"schema"`
+
+	// Number of API extensions the cluster is upgrading to (most recent among the members)
+	// Example: 250
+	APIExtensions int `json:"api_extensions" yaml:"api_extensions"`
+
+	// Names of the members running an older version, blocking the upgraded members until upgraded
+	// Example: ["lxd02"]
+	Blocking []string `json:"blocking" yaml:"blocking"`
+
+	// Versions run by each member
+	Members []ClusterUpgradeMember `json:"members" yaml:"members"`
+}
+
+// ClusterUpgradeMember represents the versions run by a cluster member.
+//
+// swagger:model
+//
+// API extension: clustering_upgrade
+type ClusterUpgradeMember struct {
+	// Name of the cluster member
+	// Example: lxd01
+	ServerName string `json:"server_name" yaml:"server_name"`
+
+	// LXD version of the member (empty when unreachable)
+	// Example: 4.18
+	Version string `json:"version" yaml:"version"`
+
+	// Database schema version of the member
+	// Example: 50
+	Schema int `json:"schema" yaml:"schema"`
+
+	// Number of API extensions of the member
+	// Example: 250
+	APIExtensions int `json:"api_extensions" yaml:"api_extensions"`
+
+	// Status of the member
+	// Example: Online
+	Status string `json:"status" yaml:"status"`
+
+	// Whether the member runs an older version than the most recent member
+	// Example: true
+	Outdated bool `json:"outdated" yaml:"outdated"`
+}
+
+// ClusterUpgradePost represents the fields required to upgrade the cluster members.
+//
+// swagger:model
+//
+// API extension: clustering_upgrade
+type ClusterUpgradePost struct {
+	// Members to upgrade in order (defaults to the outdated members, or all of them if none is)
+	// Example: ["lxd02", "lxd03"]
+	Members []string `json:"members" yaml:"members"`
+
+	// Whether to evacuate each member before upgrading it
+	// Example: true
+	Evacuate bool `json:"evacuate" yaml:"evacuate"`
 }
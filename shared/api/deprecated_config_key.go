@@ -0,0 +1,40 @@
+package api
+
+// DeprecatedConfigKey represents a deprecated configuration key still set on an entity
+//
+// swagger:model
+//
+// API extension: config_deprecated_keys
+type DeprecatedConfigKey struct {
+	// Type of the entity (instance, instance-snapshot, profile, network, storage-pool or storage-volume)
+	// Example: instance
+	EntityType string `json:"entity_type" yaml:"entity_type"`
+
+	// Project of the entity (empty for storage pools)
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Name of the entity (<instance>/<snapshot> for snapshots, <pool>/<volume> for storage volumes)
+	// Example: c1
+	Entity string `json:"entity" yaml:"entity"`
+
+	// Cluster member of the entity or of the member specific key (if any)
+	// Example: lxd01
+	Location string `json:"location" yaml:"location"`
+
+	// Device the key is set on (empty for the entity configuration)
+	// Example: data
+	Device string `json:"device" yaml:"device"`
+
+	// Deprecated key
+	// Example: security.syscalls.blacklist
+	Key string `json:"key" yaml:"key"`
+
+	// Value of the key
+	// Example: mknod
+	Value string `json:"value" yaml:"value"`
+
+	// Key replacing the deprecated one (empty if the key is dropped)
+	// Example: security.syscalls.deny
+	Replacement string `json:"replacement" yaml:"replacement"`
+}
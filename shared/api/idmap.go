@@ -0,0 +1,85 @@
+package api
+
+// Idmap represents the uid/gid allocation of LXD and how it's split between projects and instances
+//
+// swagger:model
+//
+// API extension: idmap_management
+type Idmap struct {
+	// Whether LXD manages its own allocation in /etc/subuid and /etc/subgid
+	// Example: false
+	Managed bool `json:"managed" yaml:"managed"`
+
+	// Allocation of LXD on the host
+	Host []IdmapEntry `json:"host" yaml:"host"`
+
+	// Ranges dedicated to projects through security.idmap.isolated_ranges
+	Projects map[string]IdmapRange `json:"projects" yaml:"projects"`
+
+	// Ranges assigned to the unprivileged instances of this server
+	Instances []IdmapInstance `json:"instances" yaml:"instances"`
+}
+
+// IdmapEntry represents a single uid or gid mapping
+//
+// swagger:model
+//
+// API extension: idmap_management
+type IdmapEntry struct {
+	// Whether the mapping applies to uids
+	// Example: true
+	UID bool `json:"uid" yaml:"uid"`
+
+	// Whether the mapping applies to gids
+	// Example: true
+	GID bool `json:"gid" yaml:"gid"`
+
+	// First id on the host
+	// Example: 1000000
+	HostID int64 `json:"host_id" yaml:"host_id"`
+
+	// First id in the namespace
+	// Example: 0
+	NSID int64 `json:"ns_id" yaml:"ns_id"`
+
+	// Number of ids
+	// Example: 65536
+	Range int64 `json:"range" yaml:"range"`
+}
+
+// IdmapRange represents a range of host ids
+//
+// swagger:model
+//
+// API extension: idmap_management
+type IdmapRange struct {
+	// First id on the host
+	// Example: 1065536
+	Base int64 `json:"base" yaml:"base"`
+
+	// Number of ids
+	// Example: 655360
+	Size int64 `json:"size" yaml:"size"`
+}
+
+// IdmapInstance represents the ids assigned to an instance
+//
+// swagger:model
+//
+// API extension: idmap_management
+type IdmapInstance struct {
+	// Instance name
+	// Example: c1
+	Name string `json:"name" yaml:"name"`
+
+	// Project of the instance
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Whether the instance has a map of its own (security.idmap.isolated or project range)
+	// Example: true
+	Isolated bool `json:"isolated" yaml:"isolated"`
+
+	// Current map of the instance
+	Map []IdmapEntry `json:"map" yaml:"map"`
+}
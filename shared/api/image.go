@@ -110,6 +110,12 @@ type ImagePut struct {
 	//
 	// API extension: image_profiles
 	Profiles []string `json:"profiles" yaml:"profiles"`
+
+	// Image tags (the current tags are kept if not set)
+	// Example: ["prod", "web"]
+	//
+	// API extension: tags
+	Tags []string `json:"tags" yaml:"tags"`
 }
 
 // Image represents a LXD image
@@ -276,6 +282,12 @@ type ImageMetadata struct {
 
 	// Template for files in the image
 	Templates map[string]*ImageMetadataTemplate `json:"templates" yaml:"templates"`
+
+	// Fingerprint of the image the root filesystem is a delta of
+	// Example: 06b86454720d36b20f94e31c6812e05ec51c1b568cf3a8abd273769d213394bb
+	//
+	// API extension: image_delta_export
+	BaseImage string `json:"base_image,omitempty" yaml:"base_image,omitempty"`
 }
 
 // ImageMetadataTemplate represents a template entry in image metadata (used in image tarball)
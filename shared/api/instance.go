@@ -48,6 +48,12 @@ type InstancesPost struct {
 type InstancesPut struct {
 	// Desired runtime state
 	State *InstanceStatePut `json:"state" yaml:"state"`
+
+	// Only change the instances having all of these tags
+	// Example: ["prod"]
+	//
+	// API extension: tags
+	Tags []string `json:"tags" yaml:"tags"`
 }
 
 // InstancePost represents the fields required to rename/move a LXD instance.
@@ -84,6 +90,12 @@ type InstancePost struct {
 	//
 	// API extension: instance_pool_move
 	Pool string `json:"pool" yaml:"pool"`
+
+	// Target project for local cross-project move
+	// Example: foo
+	//
+	// API extension: instance_project_move
+	Project string `json:"project" yaml:"project"`
 }
 
 // InstancePostTarget represents the migration target host and operation.
@@ -142,6 +154,12 @@ type InstancePut struct {
 	// Instance description
 	// Example: My test instance
 	Description string `json:"description" yaml:"description"`
+
+	// Instance tags (the current tags are kept if not set)
+	// Example: ["prod", "web"]
+	//
+	// API extension: tags
+	Tags []string `json:"tags" yaml:"tags"`
 }
 
 // Instance represents a LXD instance.
@@ -242,11 +260,11 @@ type InstanceSource struct {
 	// Example: X509 PEM certificate
 	Certificate string `json:"certificate" yaml:"certificate"`
 
-	// Image alias name (for image source)
+	// Image alias name (for image source and base image of conversion)
 	// Example: ubuntu/20.04
 	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
 
-	// Image fingerprint (for image source)
+	// Image fingerprint (for image source and base image of conversion)
 	// Example: ed56997f7c5b48e8d78986d2467a26109be6fb9f2d92e8c7b08eb8b6cec7629a
 	Fingerprint string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
 
@@ -282,7 +300,7 @@ type InstanceSource struct {
 	// Example: {"criu": "RANDOM-STRING", "rsync": "RANDOM-STRING"}
 	Websockets map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
 
-	// Existing instance name or snapshot (for copy)
+	// Existing instance name or snapshot (for copy and conversion)
 	// Example: foo/snap0
 	Source string `json:"source,omitempty" yaml:"source,omitempty"`
 
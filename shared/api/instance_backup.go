@@ -33,6 +33,12 @@ type InstanceBackupsPost struct {
 	//
 	// API extension: backup_compression_algorithm
 	CompressionAlgorithm string `json:"compression_algorithm" yaml:"compression_algorithm"`
+
+	// Format of the backup file (tarball, or qcow2 and ova to export virtual machines to other platforms)
+	// Example: ova
+	//
+	// API extension: instance_backup_format
+	Format string `json:"format" yaml:"format"`
 }
 
 // InstanceBackup represents a LXD instance backup.
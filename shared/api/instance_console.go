@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // InstanceConsoleControl represents a message on the instance console "control" socket.
 //
 // API extension: instances
@@ -28,3 +32,18 @@ type InstanceConsolePost struct {
 	// API extension: console_vga_type
 	Type string `json:"type" yaml:"type"`
 }
+
+// InstanceConsoleLogEntry represents a chunk of console output of an instance.
+//
+// swagger:model
+//
+// API extension: console_log_history
+type InstanceConsoleLogEntry struct {
+	// When the output was captured
+	// Example: 2021-03-23T17:38:37.753398689-04:00
+	Time time.Time `json:"time" yaml:"time"`
+
+	// Console output
+	// Example: Welcome to Ubuntu 20.04.2 LTS!
+	Output string `json:"output" yaml:"output"`
+}
@@ -53,4 +53,10 @@ type InstanceExecPost struct {
 	// Current working directory for the command
 	// Example: /home/foo/
 	Cwd string `json:"cwd" yaml:"cwd"`
+
+	// Seconds to keep an interactive session running after its websockets dropped, waiting for the client to reconnect
+	// Example: 30
+	//
+	// API extension: instance_exec_resume
+	ResumeTimeout int `json:"resume_timeout" yaml:"resume_timeout"`
 }
@@ -0,0 +1,35 @@
+package api
+
+// InstanceFileWatchPost represents a request to watch a path of an instance for changes
+//
+// swagger:model
+//
+// API extension: instance_files_watch
+type InstanceFileWatchPost struct {
+	// Path to watch inside the instance
+	// Example: /root/src
+	Path string `json:"path" yaml:"path"`
+
+	// Whether to also watch the subdirectories
+	// Example: true
+	Recursive bool `json:"recursive" yaml:"recursive"`
+}
+
+// InstanceFileEvent represents a change to a file of an instance
+//
+// swagger:model
+//
+// API extension: instance_files_watch
+type InstanceFileEvent struct {
+	// Type of change (create, modify or delete)
+	// Example: modify
+	Type string `json:"type" yaml:"type"`
+
+	// Path of the file inside the instance
+	// Example: /root/src/main.go
+	Path string `json:"path" yaml:"path"`
+
+	// Whether the file is a directory
+	// Example: false
+	Directory bool `json:"directory" yaml:"directory"`
+}
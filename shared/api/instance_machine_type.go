@@ -0,0 +1,31 @@
+package api
+
+// InstanceMachineType represents the QEMU machine type of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_machine_type
+type InstanceMachineType struct {
+	// Machine type the virtual machine is pinned to (empty until first started)
+	// Example: pc-q35-6.1
+	Current string `json:"current" yaml:"current"`
+
+	// Latest machine type of the same family supported by the server
+	// Example: pc-q35-6.2
+	Latest string `json:"latest" yaml:"latest"`
+
+	// Machine types of the same family the virtual machine can be upgraded to
+	// Example: ["pc-q35-6.1", "pc-q35-6.2"]
+	Supported []string `json:"supported" yaml:"supported"`
+}
+
+// InstanceMachineTypePost represents a machine type upgrade request.
+//
+// swagger:model
+//
+// API extension: instance_machine_type
+type InstanceMachineTypePost struct {
+	// Machine type to upgrade to (defaults to the latest one)
+	// Example: pc-q35-6.2
+	MachineType string `json:"machine_type" yaml:"machine_type"`
+}
@@ -0,0 +1,47 @@
+package api
+
+// InstanceNVRAM represents the UEFI variables of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_nvram
+type InstanceNVRAM struct {
+	InstanceNVRAMPut `yaml:",inline"`
+
+	// Boot entries known to the firmware
+	BootEntries []InstanceNVRAMBootEntry `json:"boot_entries" yaml:"boot_entries"`
+}
+
+// InstanceNVRAMPut represents the modifiable UEFI variables of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_nvram
+type InstanceNVRAMPut struct {
+	// IDs of the boot entries in the order the firmware tries them
+	// Example: ["0001", "0000"]
+	BootOrder []string `json:"boot_order" yaml:"boot_order"`
+
+	// Whether the secure boot keys are enrolled (the firmware enforces secure boot)
+	// Example: true
+	SecureBootKeys bool `json:"secureboot_keys" yaml:"secureboot_keys"`
+}
+
+// InstanceNVRAMBootEntry represents a firmware boot entry of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_nvram
+type InstanceNVRAMBootEntry struct {
+	// ID of the boot entry
+	// Example: 0001
+	ID string `json:"id" yaml:"id"`
+
+	// Description of the boot entry
+	// Example: UEFI QEMU QEMU HARDDISK
+	Description string `json:"description" yaml:"description"`
+
+	// Whether the firmware tries the entry
+	// Example: true
+	Active bool `json:"active" yaml:"active"`
+}
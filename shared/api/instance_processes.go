@@ -0,0 +1,54 @@
+package api
+
+// InstanceProcesses represents the processes using the most resources in an instance.
+//
+// swagger:model
+//
+// API extension: instance_processes
+type InstanceProcesses struct {
+	// Processes sorted by decreasing CPU or memory usage
+	Processes []InstanceProcess `json:"processes" yaml:"processes"`
+
+	// Total number of processes in the instance
+	// Example: 42
+	Total int64 `json:"total" yaml:"total"`
+}
+
+// InstanceProcess represents a process running in an instance.
+//
+// swagger:model
+//
+// API extension: instance_processes
+type InstanceProcess struct {
+	// Process ID (as seen from the instance)
+	// Example: 312
+	PID int64 `json:"pid" yaml:"pid"`
+
+	// Process name
+	// Example: nginx
+	Name string `json:"name" yaml:"name"`
+
+	// Command line of the process
+	// Example: nginx: worker process
+	Command string `json:"command" yaml:"command"`
+
+	// User ID the process is running as (as seen from the instance, -1 if unmapped)
+	// Example: 33
+	UID int64 `json:"uid" yaml:"uid"`
+
+	// Process state (R, S, D, Z, T...)
+	// Example: S
+	State string `json:"state" yaml:"state"`
+
+	// Number of threads of the process
+	// Example: 4
+	Threads int64 `json:"threads" yaml:"threads"`
+
+	// CPU usage (percentage of one CPU over the sampling interval)
+	// Example: 12.5
+	CPUUsage float64 `json:"cpu_usage" yaml:"cpu_usage"`
+
+	// Resident memory usage (in bytes)
+	// Example: 73400320
+	MemoryUsage int64 `json:"memory_usage" yaml:"memory_usage"`
+}
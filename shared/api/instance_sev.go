@@ -0,0 +1,54 @@
+package api
+
+// InstanceSEV represents the AMD SEV memory encryption state of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_sev
+type InstanceSEV struct {
+	// Memory encryption mode (sev, sev-es or sev-snp)
+	// Example: sev-es
+	Mode string `json:"mode" yaml:"mode"`
+
+	// Guest policy enforced by the firmware
+	// Example: 0x5
+	Policy string `json:"policy" yaml:"policy"`
+
+	// State of the guest as reported by the firmware (empty when stopped)
+	// Example: running
+	State string `json:"state" yaml:"state"`
+
+	// Version of the firmware API
+	// Example: 1.51
+	APIVersion string `json:"api_version" yaml:"api_version"`
+
+	// Build of the firmware
+	// Example: 3
+	BuildID int `json:"build_id" yaml:"build_id"`
+
+	// Base64 encoded launch measurement (SEV and SEV-ES only)
+	// Example: FBGnDSVWLJbsN0/n...
+	Measurement string `json:"measurement" yaml:"measurement"`
+}
+
+// InstanceSEVReportPost represents an attestation report request.
+//
+// swagger:model
+//
+// API extension: instance_sev
+type InstanceSEVReportPost struct {
+	// Base64 encoded nonce included in the report (16 bytes for SEV and SEV-ES, up to 64 bytes for SEV-SNP)
+	// Example: bHhkLWF0dGVzdGF0aW9u
+	Nonce string `json:"nonce" yaml:"nonce"`
+}
+
+// InstanceSEVReport represents an attestation report of a virtual machine.
+//
+// swagger:model
+//
+// API extension: instance_sev
+type InstanceSEVReport struct {
+	// Base64 encoded attestation report signed by the platform
+	// Example: AgAAAAAAAAAfAAMAAAAAAAEAAAAAAAAA...
+	Report string `json:"report" yaml:"report"`
+}
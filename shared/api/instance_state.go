@@ -21,6 +21,12 @@ type InstanceStatePut struct {
 	// Whether to store the runtime state (for stop)
 	// Example: false
 	Stateful bool `json:"stateful" yaml:"stateful"`
+
+	// Whether to stop or restart the instance even if security.protection.stop is enabled
+	// Example: false
+	//
+	// API extension: instance_protection_stop
+	ForceProtected bool `json:"force_protected" yaml:"force_protected"`
 }
 
 // InstanceState represents a LXD instance's state.
@@ -134,6 +140,18 @@ type InstanceStateNetwork struct {
 	// Type of interface (broadcast, loopback, point-to-point, ...)
 	// Example: broadcast
 	Type string `json:"type" yaml:"type"`
+
+	// Whether the interface has a carrier
+	// Example: true
+	//
+	// API extension: instance_state_network_link
+	Carrier bool `json:"carrier" yaml:"carrier"`
+
+	// Link speed in Mbit/s (0 if unknown)
+	// Example: 10000
+	//
+	// API extension: instance_state_network_link
+	LinkSpeed uint64 `json:"link_speed" yaml:"link_speed"`
 }
 
 // InstanceStateNetworkAddress represents a network address as part of the network section of a LXD
@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // NetworksPost represents the fields of a new LXD network
 //
 // swagger:model
@@ -134,6 +138,29 @@ type NetworkLease struct {
 	Location string `json:"location" yaml:"location"`
 }
 
+// NetworkFirewall represents the firewall rules generated by LXD for a network
+//
+// swagger:model
+//
+// API extension: network_firewall
+type NetworkFirewall struct {
+	// Firewall driver in use (nftables or xtables)
+	// Example: nftables
+	Driver string `json:"driver" yaml:"driver"`
+
+	// Rules generated for the network
+	// Example: ["table inet lxd {\n\tchain fwd.lxdbr0 {\n\t\t..."]
+	Rules []string `json:"rules" yaml:"rules"`
+
+	// Rules generated for the devices of the instances connected to the network
+	// Example: ["table inet lxd {\n\tchain prert.c1.proxy0 {\n\t\t..."]
+	InstanceRules []string `json:"instance_rules" yaml:"instance_rules"`
+
+	// Problems detected with the rules, such as missing rules or conflicting policies set by other tools
+	// Example: ["Chain \"FORWARD\" of ip table \"filter\" drops forward traffic by default"]
+	Warnings []string `json:"warnings" yaml:"warnings"`
+}
+
 // NetworkState represents the network state
 //
 // swagger:model
@@ -174,6 +201,16 @@ type NetworkState struct {
 	//
 	// API extension: network_state_vlan
 	VLAN *NetworkStateVLAN `json:"vlan" yaml:"vlan"`
+
+	// Additional OVN network information
+	//
+	// API extension: network_ovn_gateway_chassis
+	OVN *NetworkStateOVN `json:"ovn" yaml:"ovn"`
+
+	// Neighbours discovered through LLDP on the network's uplink interfaces
+	//
+	// API extension: network_state_lldp
+	LLDP []NetworkStateLLDP `json:"lldp" yaml:"lldp"`
 }
 
 // NetworkStateAddress represents a network address
@@ -284,6 +321,82 @@ type NetworkStateBridge struct {
 	UpperDevices []string `json:"upper_devices" yaml:"upper_devices"`
 }
 
+// NetworkStateLLDP represents a neighbour (usually a switch) discovered through LLDP
+//
+// swagger:model
+//
+// API extension: network_state_lldp
+type NetworkStateLLDP struct {
+	// Local interface the neighbour was discovered on
+	// Example: eth0
+	Interface string `json:"interface" yaml:"interface"`
+
+	// Chassis ID of the neighbour
+	// Example: 00:16:3e:5a:83:57
+	ChassisID string `json:"chassis_id" yaml:"chassis_id"`
+
+	// System name of the neighbour
+	// Example: switch01
+	SystemName string `json:"system_name" yaml:"system_name"`
+
+	// System description of the neighbour
+	// Example: Arista Networks EOS version 4.24.1F
+	SystemDescription string `json:"system_description" yaml:"system_description"`
+
+	// ID of the neighbour's port
+	// Example: Ethernet12
+	PortID string `json:"port_id" yaml:"port_id"`
+
+	// Description of the neighbour's port
+	// Example: server01 uplink
+	PortDescription string `json:"port_description" yaml:"port_description"`
+
+	// Untagged VLAN of the neighbour's port (0 if not advertised)
+	// Example: 1
+	PortVLAN uint64 `json:"port_vlan" yaml:"port_vlan"`
+
+	// VLANs advertised on the neighbour's port
+	// Example: [100, 200]
+	VLANs []uint64 `json:"vlans" yaml:"vlans"`
+
+	// Management addresses of the neighbour
+	// Example: ["10.0.0.2"]
+	ManagementAddresses []string `json:"management_addresses" yaml:"management_addresses"`
+
+	// When the neighbour was last heard from
+	// Example: 2021-03-23T17:38:37.753398689-04:00
+	LastSeen time.Time `json:"last_seen" yaml:"last_seen"`
+}
+
+// NetworkStateOVN represents OVN specific state
+//
+// swagger:model
+//
+// API extension: network_ovn_gateway_chassis
+type NetworkStateOVN struct {
+	// Host name of the chassis currently hosting the network's gateway
+	// Example: server01
+	Chassis string `json:"chassis" yaml:"chassis"`
+
+	// Chassis able to host the network's gateway, by decreasing priority
+	Gateways []NetworkStateOVNGateway `json:"gateways" yaml:"gateways"`
+}
+
+// NetworkStateOVNGateway represents a chassis able to host the gateway of an OVN network
+//
+// swagger:model
+//
+// API extension: network_ovn_gateway_chassis
+type NetworkStateOVNGateway struct {
+	// Host name of the chassis
+	// Example: server01
+	Chassis string `json:"chassis" yaml:"chassis"`
+
+	// Priority of the chassis (the available chassis with the highest priority hosts the gateway)
+	// Example: 32767
+	Priority uint `json:"priority" yaml:"priority"`
+}
+
 // NetworkStateVLAN represents VLAN specific state
 //
 // swagger:model
@@ -48,6 +48,12 @@ type NetworkACLRule struct {
 	// State of the rule
 	// Example: enabled
 	State string `json:"state" yaml:"state"`
+
+	// DSCP value to set on matching packets (for allow rules)
+	// Example: 46
+	//
+	// API extension: network_acl_dscp
+	DSCP string `json:"dscp,omitempty" yaml:"dscp,omitempty"`
 }
 
 // Normalise normalises the fields in the rule so that they are comparable with ones stored.
@@ -58,6 +64,7 @@ func (r *NetworkACLRule) Normalise() {
 	r.ICMPCode = strings.TrimSpace(r.ICMPCode)
 	r.Description = strings.TrimSpace(r.Description)
 	r.State = strings.TrimSpace(r.State)
+	r.DSCP = strings.TrimSpace(r.DSCP)
 
 	// Remove space from Source subject list.
 	subjects := strings.Split(r.Source, ",")
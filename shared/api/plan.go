@@ -0,0 +1,32 @@
+package api
+
+// ConfigPlanAction represents a change which applying a declarative configuration would make.
+//
+// swagger:model
+//
+// API extension: config_plan
+type ConfigPlanAction struct {
+	// Action which would be performed (create or update)
+	// Example: update
+	Action string `json:"action" yaml:"action"`
+
+	// Type of the entity (server, project, network_acl, network, storage_pool or profile)
+	// Example: network
+	EntityType string `json:"entity_type" yaml:"entity_type"`
+
+	// Name of the entity (empty for the server configuration)
+	// Example: lxdbr0
+	Name string `json:"name" yaml:"name"`
+
+	// Project of the entity (empty for entities which aren't project specific)
+	// Example: default
+	Project string `json:"project" yaml:"project"`
+
+	// Fields which would be changed by an update ("description", "config.<key>", "devices" or "rules")
+	// Example: ["config.ipv4.address"]
+	Fields []string `json:"fields" yaml:"fields"`
+
+	// Human readable description of the change
+	// Example: Update network "lxdbr0" in project "default" (config.ipv4.address)
+	Description string `json:"description" yaml:"description"`
+}
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // ProjectsPost represents the fields of a new LXD project
 //
 // swagger:model
@@ -91,3 +93,59 @@ type ProjectStateResource struct {
 	// Example: 4
 	Usage int64
 }
+
+// ProjectUsage represents the resource usage of a LXD project over a time range
+//
+// swagger:model
+//
+// API extension: project_usage_metering
+type ProjectUsage struct {
+	// Start of the time range (rounded down to the hour)
+	// Example: 2021-06-01T00:00:00Z
+	Start time.Time `json:"start" yaml:"start"`
+
+	// End of the time range (excluded)
+	// Example: 2021-07-01T00:00:00Z
+	End time.Time `json:"end" yaml:"end"`
+
+	// Granularity of the periods (hour, day or month)
+	// Example: day
+	Interval string `json:"interval" yaml:"interval"`
+
+	// Usage for each period of the range which had any recorded usage
+	Periods []ProjectUsagePeriod `json:"periods" yaml:"periods"`
+
+	// Total usage over the range
+	Total ProjectUsagePeriod `json:"total" yaml:"total"`
+}
+
+// ProjectUsagePeriod represents the resource usage of a LXD project over a period
+//
+// swagger:model
+//
+// API extension: project_usage_metering
+type ProjectUsagePeriod struct {
+	// Start of the period
+	// Example: 2021-06-01T00:00:00Z
+	Start time.Time `json:"start" yaml:"start"`
+
+	// CPU time used by the instances (in seconds)
+	// Example: 3600.5
+	CPUSeconds float64 `json:"cpu_seconds" yaml:"cpu_seconds"`
+
+	// Memory used by the instances over time (in byte-hours)
+	// Example: 1073741824
+	MemoryByteHours float64 `json:"memory_byte_hours" yaml:"memory_byte_hours"`
+
+	// Disk space used by the instances over time (in byte-hours)
+	// Example: 10737418240
+	DiskByteHours float64 `json:"disk_byte_hours" yaml:"disk_byte_hours"`
+
+	// Bytes received by the instances
+	// Example: 1048576
+	NetworkRxBytes int64 `json:"network_rx_bytes" yaml:"network_rx_bytes"`
+
+	// Bytes sent by the instances
+	// Example: 1048576
+	NetworkTxBytes int64 `json:"network_tx_bytes" yaml:"network_tx_bytes"`
+}
@@ -61,6 +61,38 @@ type ResourcesCPU struct {
 	// Total number of CPU threads (from all sockets and cores)
 	// Example: 1
 	Total uint64 `json:"total" yaml:"total"`
+
+	// AMD SEV capabilities (only set when KVM can run SEV guests)
+	//
+	// API extension: instance_sev
+	SEV *ResourcesCPUSEV `json:"sev,omitempty" yaml:"sev,omitempty"`
+}
+
+// ResourcesCPUSEV represents the AMD SEV memory encryption capabilities of the system
+//
+// swagger:model
+//
+// API extension: instance_sev
+type ResourcesCPUSEV struct {
+	// Whether SEV-ES (encrypted register state) guests are supported
+	// Example: true
+	ES bool `json:"es" yaml:"es"`
+
+	// Whether SEV-SNP (secure nested paging) guests are supported
+	// Example: false
+	SNP bool `json:"snp" yaml:"snp"`
+
+	// Position of the memory encryption bit in the page tables
+	// Example: 51
+	CBitPosition uint64 `json:"cbit_position" yaml:"cbit_position"`
+
+	// Number of physical address bits lost when memory encryption is enabled
+	// Example: 1
+	ReducedPhysBits uint64 `json:"reduced_phys_bits" yaml:"reduced_phys_bits"`
+
+	// Number of encrypted guests that can run simultaneously
+	// Example: 509
+	MaxGuests uint64 `json:"max_guests" yaml:"max_guests"`
 }
 
 // ResourcesCPUSocket represents a CPU socket on the system
@@ -184,6 +216,12 @@ type ResourcesGPU struct {
 	// Total number of GPUs
 	// Example: 1
 	Total uint64 `json:"total" yaml:"total"`
+
+	// Number of GPUs not allocated to instances (fractions of GPUs are added up)
+	// Example: 0.5
+	//
+	// API extension: gpu_allocation
+	Available float64 `json:"available" yaml:"available"`
 }
 
 // ResourcesGPUCard represents a GPU card on the system
@@ -219,6 +257,18 @@ type ResourcesGPUCard struct {
 	// Example: 0
 	NUMANode uint64 `json:"numa_node" yaml:"numa_node"`
 
+	// Share of the GPU allocated to instances (whole card, mediated devices, MIG slices or SR-IOV VFs)
+	// Example: 0.25
+	//
+	// API extension: gpu_allocation
+	Allocated float64 `json:"allocated" yaml:"allocated"`
+
+	// Share of the GPU left for other instances
+	// Example: 0.75
+	//
+	// API extension: gpu_allocation
+	Available float64 `json:"available" yaml:"available"`
+
 	// PCI address
 	// Example: 0000:00:02.0
 	PCIAddress string `json:"pci_address,omitempty" yaml:"pci_address,omitempty"`
@@ -744,6 +794,52 @@ type ResourcesMemoryNode struct {
 	// Total system memory (bytes)
 	// Example: 343597383680
 	Total uint64 `json:"total" yaml:"total"`
+
+	// List of huge page pools available on the node (one per page size)
+	// Example: null
+	//
+	// API extension: resources_numa_topology
+	Hugepages []ResourcesMemoryNodeHugepages `json:"hugepages,omitempty" yaml:"hugepages,omitempty"`
+
+	// List of distances from this node to all NUMA nodes
+	// Example: null
+	//
+	// API extension: resources_numa_topology
+	Distances []ResourcesMemoryNodeDistance `json:"distances,omitempty" yaml:"distances,omitempty"`
+}
+
+// ResourcesMemoryNodeHugepages represents a huge page pool on a NUMA node
+//
+// swagger:model
+//
+// API extension: resources_numa_topology
+type ResourcesMemoryNodeHugepages struct {
+	// Size of the huge pages (bytes)
+	// Example: 2097152
+	Size uint64 `json:"size" yaml:"size"`
+
+	// Total of huge pages in the pool (bytes)
+	// Example: 214536552448
+	Total uint64 `json:"total" yaml:"total"`
+
+	// Used huge pages in the pool (bytes)
+	// Example: 107268276224
+	Used uint64 `json:"used" yaml:"used"`
+}
+
+// ResourcesMemoryNodeDistance represents the distance between two NUMA nodes
+//
+// swagger:model
+//
+// API extension: resources_numa_topology
+type ResourcesMemoryNodeDistance struct {
+	// Target NUMA node identifier
+	// Example: 1
+	NUMANode uint64 `json:"numa_node" yaml:"numa_node"`
+
+	// Relative distance to the target node (10 being local access)
+	// Example: 21
+	Distance uint64 `json:"distance" yaml:"distance"`
 }
 
 // ResourcesStoragePool represents the resources available to a given storage pool
@@ -86,3 +86,14 @@ type StoragePoolPut struct {
 func (storagePool *StoragePool) Writable() StoragePoolPut {
 	return storagePool.StoragePoolPut
 }
+
+// StoragePoolSourcePost represents a request to move a loop file backed storage pool onto a block device.
+//
+// swagger:model
+//
+// API extension: storage_pool_move_to_block
+type StoragePoolSourcePost struct {
+	// Block device to move the storage pool onto
+	// Example: /dev/sdb
+	Source string `json:"source" yaml:"source"`
+}
@@ -21,7 +21,7 @@ type StorageVolumesPost struct {
 	// API extension: storage_api_local_volume_handling
 	Source StorageVolumeSource `json:"source" yaml:"source"`
 
-	// Volume content type (filesystem or block)
+	// Volume content type (filesystem, block or iso)
 	// Example: filesystem
 	//
 	// API extension: custom_block_volumes
@@ -60,6 +60,12 @@ type StorageVolumePost struct {
 	//
 	// API extension: storage_api_remote_volume_snapshots
 	VolumeOnly bool `json:"volume_only" yaml:"volume_only"`
+
+	// New cluster member (moves the volume within the cluster)
+	// Example: lxd02
+	//
+	// API extension: storage_volume_move_location
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
 }
 
 // StorageVolumePostTarget represents the migration target host and operation
@@ -107,7 +113,7 @@ type StorageVolume struct {
 	// API extension: clustering
 	Location string `json:"location" yaml:"location"`
 
-	// Volume content type (filesystem or block)
+	// Volume content type (filesystem, block or iso)
 	// Example: filesystem
 	//
 	// API extension: custom_block_volumes
@@ -135,6 +141,12 @@ type StorageVolumePut struct {
 	//
 	// API extension: storage_api_volume_snapshots
 	Restore string `json:"restore,omitempty" yaml:"restore,omitempty"`
+
+	// Storage volume tags (the current tags are kept if not set)
+	// Example: ["prod", "web"]
+	//
+	// API extension: tags
+	Tags []string `json:"tags" yaml:"tags"`
 }
 
 // StorageVolumeSource represents the creation source for a new storage volume
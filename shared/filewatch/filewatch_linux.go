@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+// Package filewatch reports the changes made to a file tree through inotify.
+package filewatch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// watchMask is the set of inotify events watched on every directory.
+const watchMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF
+
+// Watch calls handler for each file created, modified or deleted under path until ctx is done or path itself is
+// deleted. Subdirectories, including the ones created while watching, are only watched when recursive is true.
+// Files moved in and out of the watched tree are reported as created and deleted.
+func Watch(ctx context.Context, path string, recursive bool, handler func(event api.InstanceFileEvent)) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return errors.Wrap(err, "Failed to initialize inotify")
+	}
+
+	// Going through os.File lets closing it interrupt the pending read.
+	f := os.NewFile(uintptr(fd), "inotify")
+	defer f.Close()
+
+	path = filepath.Clean(path)
+	watches := map[int]string{}
+
+	addWatch := func(dir string) error {
+		wd, err := unix.InotifyAddWatch(fd, dir, watchMask)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to watch %q", dir)
+		}
+
+		watches[wd] = dir
+		return nil
+	}
+
+	addWatches := func(dir string) error {
+		if !recursive {
+			return addWatch(dir)
+		}
+
+		return filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+			// Entries may vanish while walking.
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir() {
+				return nil
+			}
+
+			return addWatch(p)
+		})
+	}
+
+	err = addWatches(path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	buf := make([]byte, 100*(unix.SizeofInotifyEvent+unix.PathMax))
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return errors.Wrap(err, "Failed to read inotify events")
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			name := string(bytes.TrimRight(buf[offset+unix.SizeofInotifyEvent:offset+unix.SizeofInotifyEvent+int(raw.Len)], "\x00"))
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			dir, found := watches[int(raw.Wd)]
+			if !found {
+				continue
+			}
+
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				delete(watches, int(raw.Wd))
+				continue
+			}
+
+			if raw.Mask&unix.IN_DELETE_SELF != 0 {
+				if dir == path {
+					return nil
+				}
+
+				continue
+			}
+
+			event := api.InstanceFileEvent{
+				Path:      filepath.Join(dir, name),
+				Directory: raw.Mask&unix.IN_ISDIR != 0,
+			}
+
+			switch {
+			case raw.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+				event.Type = "create"
+
+				if recursive && event.Directory {
+					err = addWatches(event.Path)
+					if err != nil && !os.IsNotExist(errors.Cause(err)) {
+						return err
+					}
+				}
+			case raw.Mask&unix.IN_MODIFY != 0:
+				event.Type = "modify"
+			case raw.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+				event.Type = "delete"
+			default:
+				continue
+			}
+
+			handler(event)
+		}
+	}
+}
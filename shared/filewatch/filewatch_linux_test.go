@@ -0,0 +1,59 @@
+package filewatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan api.InstanceFileEvent, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- Watch(ctx, dir, true, func(event api.InstanceFileEvent) {
+			events <- event
+		})
+	}()
+
+	next := func() api.InstanceFileEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for an event")
+		}
+
+		return api.InstanceFileEvent{}
+	}
+
+	// Wait for the watch to be set up.
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	assert.Equal(t, api.InstanceFileEvent{Type: "create", Path: filepath.Join(dir, "sub"), Directory: true}, next())
+
+	// Files created in new subdirectories are reported too.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "file"), []byte("hello"), 0644))
+	assert.Equal(t, api.InstanceFileEvent{Type: "create", Path: filepath.Join(dir, "sub", "file")}, next())
+	assert.Equal(t, api.InstanceFileEvent{Type: "modify", Path: filepath.Join(dir, "sub", "file")}, next())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "sub", "file")))
+	assert.Equal(t, api.InstanceFileEvent{Type: "delete", Path: filepath.Join(dir, "sub", "file")}, next())
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package filewatch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Watch isn't supported on this platform.
+func Watch(ctx context.Context, path string, recursive bool, handler func(event api.InstanceFileEvent)) error {
+	return fmt.Errorf("Watching files isn't supported on this platform")
+}
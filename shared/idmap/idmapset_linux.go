@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path"
@@ -686,6 +687,84 @@ func getFromShadow(fname string, username string) ([][]int64, error) {
 	return entries, nil
 }
 
+// ExpandShadowMap grows the entry of username starting at hostid in the /etc/subuid and /etc/subgid files of
+// rootfs so that it covers size ids. It fails without changing anything if the grown range would overlap the
+// entry of another user.
+func ExpandShadowMap(rootfs string, username string, hostid int64, size int64) error {
+	files := map[string][]string{}
+	for _, fname := range []string{"/etc/subuid", "/etc/subgid"} {
+		fname = path.Join(rootfs, fname)
+
+		lines, err := expandShadowEntry(fname, username, hostid, size)
+		if err != nil {
+			return err
+		}
+
+		files[fname] = lines
+	}
+
+	// Replace the files atomically as other tools may be reading them.
+	for fname, lines := range files {
+		tmpName := fmt.Sprintf("%s.lxd", fname)
+		err := ioutil.WriteFile(tmpName, []byte(strings.Join(lines, "\n")), 0644)
+		if err != nil {
+			return errors.Wrapf(err, "Failed writing %q", tmpName)
+		}
+
+		err = os.Rename(tmpName, fname)
+		if err != nil {
+			os.Remove(tmpName)
+			return errors.Wrapf(err, "Failed replacing %q", fname)
+		}
+	}
+
+	return nil
+}
+
+// expandShadowEntry returns the lines of a subuid/subgid file once the entry of username starting at hostid is
+// grown to size ids.
+func expandShadowEntry(fname string, username string, hostid int64, size int64) ([]string, error) {
+	content, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	found := false
+	for i, line := range lines {
+		fields := strings.Split(strings.SplitN(line, "#", 2)[0], ":")
+		if len(fields) < 3 {
+			continue
+		}
+
+		entryStart, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entrySize, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if !found && strings.EqualFold(fields[0], username) && entryStart == hostid {
+			found = true
+			lines[i] = fmt.Sprintf("%s:%d:%d", fields[0], hostid, size)
+			continue
+		}
+
+		if entryStart < hostid+size && hostid < entryStart+entrySize {
+			return nil, fmt.Errorf("Range %d-%d is already allocated to %q in %q", entryStart, entryStart+entrySize-1, fields[0], fname)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("No entry starting at %d for %q in %q", hostid, username, fname)
+	}
+
+	return lines, nil
+}
+
 /*
  * get a uid or gid mapping from /proc/self/{g,u}id_map
  */
@@ -5,6 +5,9 @@ package idmap
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -108,3 +111,50 @@ func TestIdmapSetIntersects(t *testing.T) {
 		return
 	}
 }
+
+func TestExpandShadowMap(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "lxd-idmap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	err = os.Mkdir(filepath.Join(rootfs, "etc"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "# comment\nuser1:100000:65536\nroot:1000000:1000000\nuser2:3000000:65536\n"
+	for _, fname := range []string{"subuid", "subgid"} {
+		err = ioutil.WriteFile(filepath.Join(rootfs, "etc", fname), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Growing into the range of another user fails.
+	err = ExpandShadowMap(rootfs, "root", 1000000, 2500000)
+	if err == nil {
+		t.Error("expected overlap error")
+		return
+	}
+
+	err = ExpandShadowMap(rootfs, "root", 1000000, 2000000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, fname := range []string{"subuid", "subgid"} {
+		entries, err := getFromShadow(filepath.Join(rootfs, "etc", fname), "root")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(entries) != 1 || entries[0][0] != 1000000 || entries[0][1] != 2000000 {
+			t.Error(fmt.Errorf("bad entries in %s: %v", fname, entries))
+			return
+		}
+	}
+}
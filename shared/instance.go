@@ -81,6 +81,12 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	"boot.autostart.priority":    validate.Optional(validate.IsInt64),
 	"boot.stop.priority":         validate.Optional(validate.IsInt64),
 	"boot.host_shutdown_timeout": validate.Optional(validate.IsInt64),
+	"boot.host_shutdown_action":  validate.Optional(validate.IsOneOf("stop", "force-stop", "stateful-stop")),
+
+	"clone.hostname":                 validate.Optional(validate.IsBool),
+	"clone.regenerate.machine_id":    validate.Optional(validate.IsBool),
+	"clone.regenerate.ssh_host_keys": validate.Optional(validate.IsBool),
+	"clone.script":                   validate.IsAny,
 
 	"cluster.evacuate": validate.Optional(validate.IsOneOf("auto", "migrate", "stop")),
 
@@ -107,39 +113,61 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 
 		return nil
 	},
-	"limits.disk.priority": validate.Optional(validate.IsPriority),
-	"limits.memory": func(value string) error {
+	"limits.cpu.nodes": func(value string) error {
 		if value == "" {
 			return nil
 		}
 
-		if strings.HasSuffix(value, "%") {
-			_, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
-			if err != nil {
-				return err
-			}
+		// Validate the syntax (e.g. 0,2-3)
+		match, _ := regexp.MatchString("^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$", value)
+		if !match {
+			return fmt.Errorf("Invalid NUMA node list syntax")
+		}
+
+		return nil
+	},
+	"limits.disk.priority":      validate.Optional(validate.IsPriority),
+	"limits.memory":             validate.Optional(isMemorySize),
+	"limits.memory.reclaim":     validate.Optional(validate.IsBool),
+	"limits.memory.reclaim.min": validate.Optional(isMemorySize),
+	"limits.network.priority":   validate.Optional(validate.IsPriority),
+
+	// Caller is responsible for full validation of any raw.* value.
+	"raw.apparmor":       validate.IsAny,
+	"raw.apparmor.extra": validate.IsAny,
 
+	"security.devlxd": validate.Optional(validate.IsBool),
+	"security.devlxd.management": func(value string) error {
+		if value == "" {
 			return nil
 		}
 
-		_, err := units.ParseByteSizeString(value)
-		if err != nil {
-			return err
+		for _, scope := range strings.Split(value, ",") {
+			if !StringInSlice(strings.TrimSpace(scope), []string{"snapshots", "root_size"}) {
+				return fmt.Errorf("Invalid devlxd management scope %q", scope)
+			}
 		}
 
 		return nil
 	},
-	"limits.network.priority": validate.Optional(validate.IsPriority),
-
-	// Caller is responsible for full validation of any raw.* value.
-	"raw.apparmor": validate.IsAny,
+	"security.devlxd.management.root_size_max": validate.Optional(validate.IsSize),
+	"security.devlxd.management.snapshots_max": validate.Optional(validate.IsUint32),
 
-	"security.devlxd":            validate.Optional(validate.IsBool),
+	"security.nesting.kvm":       validate.Optional(validate.IsBool),
 	"security.protection.delete": validate.Optional(validate.IsBool),
+	"security.protection.stop":   validate.Optional(validate.IsBool),
 
 	"snapshots.schedule":         validate.Optional(validate.IsCron([]string{"@hourly", "@daily", "@midnight", "@weekly", "@monthly", "@annually", "@yearly", "@startup"})),
 	"snapshots.schedule.stopped": validate.Optional(validate.IsBool),
-	"snapshots.pattern":          validate.IsAny,
+	"snapshots.schedule.offset": func(value string) error {
+		_, err := GetSnapshotExpiry(time.Time{}, value)
+		return err
+	},
+	"snapshots.schedule.jitter": func(value string) error {
+		_, err := GetSnapshotExpiry(time.Time{}, value)
+		return err
+	},
+	"snapshots.pattern": validate.IsAny,
 	"snapshots.expiry": func(value string) error {
 		// Validate expression
 		_, err := GetSnapshotExpiry(time.Time{}, value)
@@ -149,9 +177,12 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	// Volatile keys.
 	"volatile.apply_template":   validate.IsAny,
 	"volatile.base_image":       validate.IsAny,
+	"volatile.clone_pending":    validate.Optional(validate.IsBool),
+	"volatile.detached_devices": validate.IsAny,
 	"volatile.evacuate.origin":  validate.IsAny,
 	"volatile.last_state.idmap": validate.IsAny,
 	"volatile.last_state.power": validate.IsAny,
+	"volatile.machine_type":     validate.IsAny,
 	"volatile.idmap.base":       validate.IsAny,
 	"volatile.idmap.current":    validate.IsAny,
 	"volatile.idmap.next":       validate.IsAny,
@@ -160,6 +191,25 @@ var InstanceConfigKeysAny = map[string]func(value string) error{
 	"volatile.vsock_id":         validate.Optional(validate.IsInt64),
 }
 
+// isMemorySize validates a memory size, either a percentage or a fixed value in bytes.
+func isMemorySize(value string) error {
+	if strings.HasSuffix(value, "%") {
+		_, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	_, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // InstanceConfigKeysContainer is a map of config key to validator. (keys applying to containers only)
 var InstanceConfigKeysContainer = map[string]func(value string) error{
 	"limits.cpu.allowance": func(value string) error {
@@ -232,34 +282,83 @@ var InstanceConfigKeysContainer = map[string]func(value string) error{
 	"security.privileged":       validate.Optional(validate.IsBool),
 	"security.protection.shift": validate.Optional(validate.IsBool),
 
-	"security.syscalls.allow":                   validate.IsAny,
-	"security.syscalls.blacklist_default":       validate.Optional(validate.IsBool),
-	"security.syscalls.blacklist_compat":        validate.Optional(validate.IsBool),
-	"security.syscalls.blacklist":               validate.IsAny,
-	"security.syscalls.deny_default":            validate.Optional(validate.IsBool),
-	"security.syscalls.deny_compat":             validate.Optional(validate.IsBool),
-	"security.syscalls.deny":                    validate.IsAny,
-	"security.syscalls.intercept.bpf":           validate.Optional(validate.IsBool),
-	"security.syscalls.intercept.bpf.devices":   validate.Optional(validate.IsBool),
-	"security.syscalls.intercept.mknod":         validate.Optional(validate.IsBool),
-	"security.syscalls.intercept.mount":         validate.Optional(validate.IsBool),
-	"security.syscalls.intercept.mount.allowed": validate.IsAny,
-	"security.syscalls.intercept.mount.fuse":    validate.IsAny,
-	"security.syscalls.intercept.mount.shift":   validate.Optional(validate.IsBool),
-	"security.syscalls.intercept.setxattr":      validate.Optional(validate.IsBool),
-	"security.syscalls.whitelist":               validate.IsAny,
+	"security.syscalls.allow":                        validate.IsAny,
+	"security.syscalls.blacklist_default":            validate.Optional(validate.IsBool),
+	"security.syscalls.blacklist_compat":             validate.Optional(validate.IsBool),
+	"security.syscalls.blacklist":                    validate.IsAny,
+	"security.syscalls.deny_default":                 validate.Optional(validate.IsBool),
+	"security.syscalls.deny_compat":                  validate.Optional(validate.IsBool),
+	"security.syscalls.deny":                         validate.IsAny,
+	"security.syscalls.intercept.bpf":                validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.bpf.devices":        validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.mknod":              validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.mount":              validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.mount.allowed":      validate.IsAny,
+	"security.syscalls.intercept.mount.fuse":         validate.IsAny,
+	"security.syscalls.intercept.mount.shift":        validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.sched_setscheduler": validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.sched_setscheduler.priority": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("Invalid real-time priority %q", value)
+		}
+
+		if priority < 0 || priority > 99 {
+			return fmt.Errorf("Real-time priority must be between 0 and 99")
+		}
+
+		return nil
+	},
+	"security.syscalls.intercept.setxattr": validate.Optional(validate.IsBool),
+	"security.syscalls.intercept.sysinfo":  validate.Optional(validate.IsBool),
+	"security.syscalls.whitelist":          validate.IsAny,
 }
 
 // InstanceConfigKeysVM is a map of config key to validator. (keys applying to VM only)
 var InstanceConfigKeysVM = map[string]func(value string) error{
+	"boot.virtio_drivers": validate.Optional(validate.IsBool),
+
+	"limits.cpu.flags": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		for _, flag := range strings.Split(value, ",") {
+			match, _ := regexp.MatchString("^[+-]?[a-zA-Z0-9][a-zA-Z0-9_.-]*$", strings.TrimSpace(flag))
+			if !match {
+				return fmt.Errorf("Invalid CPU flag %q", flag)
+			}
+		}
+
+		return nil
+	},
 	"limits.memory.hugepages": validate.Optional(validate.IsBool),
 
+	"migration.cpu_model": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		match, _ := regexp.MatchString("^[a-zA-Z0-9][a-zA-Z0-9_.-]*$", value)
+		if !match {
+			return fmt.Errorf("Invalid CPU model %q", value)
+		}
+
+		return nil
+	},
 	"migration.stateful": validate.Optional(validate.IsBool),
 
 	// Caller is responsible for full validation of any raw.* value.
 	"raw.qemu": validate.IsAny,
 
-	"security.secureboot": validate.Optional(validate.IsBool),
+	"security.secureboot":    validate.Optional(validate.IsBool),
+	"security.sev":           validate.Optional(validate.IsBool),
+	"security.sev.policy.es": validate.Optional(validate.IsBool),
+	"security.sev.snp":       validate.Optional(validate.IsBool),
 }
 
 // ConfigKeyChecker returns a function that will check whether or not
@@ -298,6 +397,14 @@ func ConfigKeyChecker(key string, instanceType instancetype.Type) (func(value st
 			return validate.IsAny, nil
 		}
 
+		if strings.HasSuffix(key, ".ipam.ipv4.address") {
+			return validate.Optional(validate.IsNetworkAddressV4), nil
+		}
+
+		if strings.HasSuffix(key, ".ipam.ipv6.address") {
+			return validate.Optional(validate.IsNetworkAddressV6), nil
+		}
+
 		if strings.HasSuffix(key, ".mtu") {
 			return validate.IsAny, nil
 		}
@@ -373,6 +480,10 @@ func InstanceIncludeWhenCopying(configKey string, remoteCopy bool) bool {
 		return true // Include volatile.base_image always as it can help optimize copies.
 	}
 
+	if configKey == "volatile.machine_type" {
+		return true // Include volatile.machine_type always as the guest may depend on its virtual hardware.
+	}
+
 	if configKey == "volatile.last_state.idmap" && !remoteCopy {
 		return true // Include volatile.last_state.idmap when doing local copy to avoid needless remapping.
 	}
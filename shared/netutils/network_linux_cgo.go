@@ -62,6 +62,9 @@ const UnixFdsReceivedMore uint = C.UNIX_FDS_RECEIVED_MORE
 // UnixFdsReceivedNone indicates that no fds have been received.
 const UnixFdsReceivedNone uint = C.UNIX_FDS_RECEIVED_NONE
 
+// iffLowerUp is the IFF_LOWER_UP interface flag (carrier detected), which isn't exposed by net/if.h.
+const iffLowerUp = 0x10000
+
 // NetnsGetifaddrs returns a map of InstanceStateNetwork for a particular process.
 func NetnsGetifaddrs(initPID int32) (map[string]api.InstanceStateNetwork, error) {
 	var netnsidAware C.bool
@@ -127,6 +130,7 @@ func NetnsGetifaddrs(initPID int32) (map[string]api.InstanceStateNetwork, error)
 			netState = "up"
 		}
 		addNetwork.State = netState
+		addNetwork.Carrier = (addr.ifa_flags & iffLowerUp) > 0
 		addNetwork.Type = netType
 		addNetwork.Mtu = int(addr.ifa_mtu)
 
@@ -303,6 +303,35 @@ func WebsocketProxy(source *websocket.Conn, target *websocket.Conn) chan struct{
 	return ch
 }
 
+// WebsocketKeepalive pings the websocket every interval until done is closed. Reads from the websocket fail once
+// no pong was received for three intervals, making a dead peer visible to the reader. Pongs are only processed
+// while something reads from the websocket.
+func WebsocketKeepalive(conn *websocket.Conn, interval time.Duration, done <-chan struct{}) {
+	timeout := 3 * interval
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval))
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
 func defaultReader(conn *websocket.Conn, r io.ReadCloser, readDone chan<- bool) {
 	/* For now, we don't need to adjust buffer sizes in
 	* WebsocketMirror, since it's used for interactive things like
@@ -0,0 +1,429 @@
+// Package tarstream streams file trees as tarballs, preserving ownership, modes, extended attributes, hard links
+// and sparse files.
+package tarstream
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sparseBlockSize is the size of the blocks of zeroes turned into holes when extracting regular files.
+const sparseBlockSize = 4096
+
+// ShiftFunc maps the owner of a file, returning -1 for IDs which can't be mapped.
+type ShiftFunc func(uid int64, gid int64) (int64, int64)
+
+// Write writes the tree rooted at srcPath into w as a tarball, naming the entries after name. Sockets are skipped
+// like tar does. The shift function, if not nil, is applied to the owner of every entry.
+func Write(w io.Writer, srcPath string, name string, shift ShiftFunc) error {
+	tw := tar.NewWriter(w)
+	links := map[uint64]string{}
+
+	err := filepath.Walk(srcPath, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode()&os.ModeSocket != 0 {
+			return nil
+		}
+
+		if !fi.Mode().IsRegular() && !fi.IsDir() && fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%q isn't a supported file type", p)
+		}
+
+		rel, err := filepath.Rel(srcPath, p)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(p)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+
+		// Drop the user and group names, only the IDs are meaningful across systems.
+		hdr.Uname = ""
+		hdr.Gname = ""
+
+		uid, gid, ino, nlink := fileOwner(fi)
+		hdr.Uid = uid
+		hdr.Gid = gid
+
+		if shift != nil {
+			uid, gid := shift(int64(hdr.Uid), int64(hdr.Gid))
+			if uid >= 0 && gid >= 0 {
+				hdr.Uid = int(uid)
+				hdr.Gid = int(gid)
+			}
+		}
+
+		// If it's a hard link to a file already written, only reference it.
+		if fi.Mode().IsRegular() && nlink > 1 {
+			first, found := links[ino]
+			if found {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = first
+				hdr.Size = 0
+			} else {
+				links[ino] = hdr.Name
+			}
+		}
+
+		if link == "" {
+			xattrs, err := readXattrs(p)
+			if err != nil {
+				return err
+			}
+
+			for key, value := range xattrs {
+				// ACLs and file capabilities embed IDs which wouldn't be valid once shifted.
+				if shift != nil && (strings.HasPrefix(key, "system.posix_acl_") || key == "security.capability") {
+					continue
+				}
+
+				if hdr.PAXRecords == nil {
+					hdr.PAXRecords = map[string]string{}
+				}
+
+				hdr.PAXRecords["SCHILY.xattr."+key] = value
+			}
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Only write the size recorded in the header in case the file grew.
+		_, err = io.CopyN(tw, f, hdr.Size)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write %q", p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// Extract extracts the tarball read from r into the destDir directory, which must exist. Existing files are
+// replaced. Blocks of zeroes in regular files are turned into holes. The owner of the entries is only set when
+// chown is true, going through the shift function if not nil. Extended attributes are restored where possible.
+//
+// Entries can't be extracted outside of destDir, whether through ".." components or symlinks.
+func Extract(r io.Reader, destDir string, shift ShiftFunc, chown bool) error {
+	tr := tar.NewReader(r)
+
+	// Directories get their final mode and timestamps once all their content has been extracted.
+	dirs := []*tar.Header{}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		target, err := extractPath(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.Mkdir(target, 0700)
+			if err != nil && !os.IsExist(err) {
+				return err
+			}
+
+			fi, err := os.Lstat(target)
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir() {
+				return fmt.Errorf("%q isn't a directory", target)
+			}
+
+			dirs = append(dirs, hdr)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			err = extractFile(target, tr)
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			err = removeExisting(target)
+			if err != nil {
+				return err
+			}
+
+			err = os.Symlink(hdr.Linkname, target)
+			if err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := extractPath(destDir, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+
+			err = removeExisting(target)
+			if err != nil {
+				return err
+			}
+
+			err = os.Link(source, target)
+			if err != nil {
+				return err
+			}
+
+			continue
+		default:
+			return fmt.Errorf("Unsupported type of entry %q", hdr.Name)
+		}
+
+		err = extractMetadata(target, hdr, shift, chown)
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeSymlink && hdr.Typeflag != tar.TypeDir {
+			err = os.Chmod(target, fileMode(hdr))
+			if err != nil {
+				return err
+			}
+
+			err = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Fix up the deepest directories first as changing their content updates the parent's timestamps.
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i].Name, "/") > strings.Count(dirs[j].Name, "/")
+	})
+
+	for _, hdr := range dirs {
+		target, err := extractPath(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		err = os.Chmod(target, fileMode(hdr))
+		if err != nil {
+			return err
+		}
+
+		err = os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractPath returns the path of an entry under destDir, making sure none of its parent directories under
+// destDir is a symlink which could lead outside of it.
+func extractPath(destDir string, name string) (string, error) {
+	rel := path.Clean("/" + name)
+	if rel == "/" {
+		return destDir, nil
+	}
+
+	parent := destDir
+	for _, component := range strings.Split(path.Dir(rel), "/") {
+		if component == "" {
+			continue
+		}
+
+		parent = filepath.Join(parent, component)
+
+		fi, err := os.Lstat(parent)
+		if err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+
+			return "", err
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("Refusing to extract %q through a symlink", name)
+		}
+	}
+
+	return filepath.Join(destDir, filepath.FromSlash(rel)), nil
+}
+
+// extractFile writes the content of a regular file, leaving holes for the blocks of zeroes.
+func extractFile(target string, r io.Reader) error {
+	err := removeExisting(target)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var size int64
+	buf := make([]byte, sparseBlockSize)
+	zero := make([]byte, sparseBlockSize)
+
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if n == sparseBlockSize && bytes.Equal(buf, zero) {
+				_, err = f.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, err = f.Write(buf[:n])
+			}
+
+			if err != nil {
+				return err
+			}
+
+			size += int64(n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	// Set the size in case the file ends with a hole.
+	err = f.Truncate(size)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// fileMode returns the mode of an entry, including the setuid, setgid and sticky bits.
+func fileMode(hdr *tar.Header) os.FileMode {
+	mode := os.FileMode(hdr.Mode).Perm()
+
+	if hdr.Mode&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+
+	if hdr.Mode&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+
+	if hdr.Mode&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return mode
+}
+
+// extractMetadata sets the owner and the extended attributes of an extracted entry.
+func extractMetadata(target string, hdr *tar.Header, shift ShiftFunc, chown bool) error {
+	if chown {
+		uid := int64(hdr.Uid)
+		gid := int64(hdr.Gid)
+		if shift != nil {
+			uid, gid = shift(uid, gid)
+
+			// Leaving the entry owned by whoever extracts it would hand out its (possibly setuid) content.
+			if uid < 0 || gid < 0 {
+				return fmt.Errorf("Owner %d:%d of %q can't be mapped", hdr.Uid, hdr.Gid, hdr.Name)
+			}
+		}
+
+		err := os.Lchown(target, int(uid), int(gid))
+		if err != nil {
+			return err
+		}
+	}
+
+	if hdr.Typeflag == tar.TypeSymlink {
+		return nil
+	}
+
+	for key, value := range hdr.PAXRecords {
+		if !strings.HasPrefix(key, "SCHILY.xattr.") {
+			continue
+		}
+
+		// ACLs and file capabilities embed IDs which wouldn't be valid once shifted.
+		name := strings.TrimPrefix(key, "SCHILY.xattr.")
+		if shift != nil && (strings.HasPrefix(name, "system.posix_acl_") || name == "security.capability") {
+			continue
+		}
+
+		// Restoring extended attributes is best effort as some namespaces require privileges or aren't
+		// supported by the target filesystem.
+		_ = writeXattr(target, name, value)
+	}
+
+	return nil
+}
+
+// removeExisting removes an existing non-directory entry in the way of the target.
+func removeExisting(target string) error {
+	fi, err := os.Lstat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if fi.IsDir() {
+		return fmt.Errorf("%q is a directory", target)
+	}
+
+	return os.Remove(target)
+}
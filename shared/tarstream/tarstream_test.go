@@ -0,0 +1,102 @@
+package tarstream
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteExtract(t *testing.T) {
+	src, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "tree", "sub"), 0750))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "tree", "sub", "file"), []byte("hello"), 0640))
+	require.NoError(t, os.Link(filepath.Join(src, "tree", "sub", "file"), filepath.Join(src, "tree", "link")))
+	require.NoError(t, os.Symlink("sub/file", filepath.Join(src, "tree", "symlink")))
+
+	// A file made of a hole followed by some data.
+	f, err := os.Create(filepath.Join(src, "tree", "sparse"))
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("end"), 1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	buf := bytes.Buffer{}
+	require.NoError(t, Write(&buf, filepath.Join(src, "tree"), "tree", nil))
+	require.NoError(t, Extract(&buf, dst, nil, false))
+
+	content, err := ioutil.ReadFile(filepath.Join(dst, "tree", "sub", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	fi, err := os.Stat(filepath.Join(dst, "tree", "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+
+	fi, err = os.Stat(filepath.Join(dst, "tree", "sub", "file"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+
+	linkFi, err := os.Stat(filepath.Join(dst, "tree", "link"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(fi, linkFi))
+
+	target, err := os.Readlink(filepath.Join(dst, "tree", "symlink"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/file", target)
+
+	fi, err = os.Stat(filepath.Join(dst, "tree", "sparse"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024*1024+3), fi.Size())
+}
+
+func TestExtractThroughSymlink(t *testing.T) {
+	dst, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	buf := bytes.Buffer{}
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "/tmp"}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "escape/file", Typeflag: tar.TypeReg, Mode: 0644}))
+	require.NoError(t, tw.Close())
+
+	err = Extract(&buf, dst, nil, false)
+	assert.Error(t, err)
+}
+
+func TestExtractUnmappedOwner(t *testing.T) {
+	dst, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	buf := bytes.Buffer{}
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "file", Typeflag: tar.TypeReg, Mode: 04755, Uid: 70000, Gid: 70000}))
+	require.NoError(t, tw.Close())
+
+	shift := func(uid int64, gid int64) (int64, int64) {
+		return -1, -1
+	}
+
+	err = Extract(&buf, dst, shift, true)
+	assert.Error(t, err)
+
+	// The entry must not have been left setuid.
+	fi, err := os.Stat(filepath.Join(dst, "file"))
+	if err == nil {
+		assert.Zero(t, fi.Mode()&os.ModeSetuid)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package tarstream
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the owner, inode and number of links of a file.
+func fileOwner(fi os.FileInfo) (int, int, uint64, uint64) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, 0
+	}
+
+	return int(st.Uid), int(st.Gid), uint64(st.Ino), uint64(st.Nlink)
+}
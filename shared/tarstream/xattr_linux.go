@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package tarstream
+
+import (
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// readXattrs returns the extended attributes of a file.
+func readXattrs(path string) (map[string]string, error) {
+	xattrs, err := shared.GetAllXattr(path)
+	if err != nil {
+		// Filesystems without extended attributes support.
+		if err == unix.ENOTSUP {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return xattrs, nil
+}
+
+// writeXattr sets an extended attribute of a file.
+func writeXattr(path string, key string, value string) error {
+	return unix.Lsetxattr(path, key, []byte(value), 0)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package tarstream
+
+// readXattrs returns the extended attributes of a file, which are only supported on Linux.
+func readXattrs(path string) (map[string]string, error) {
+	return nil, nil
+}
+
+// writeXattr sets an extended attribute of a file, which is only supported on Linux.
+func writeXattr(path string, key string, value string) error {
+	return nil
+}
@@ -4,9 +4,49 @@
 package shared
 
 import (
+	"fmt"
 	"os"
+	"runtime"
+
+	"golang.org/x/sys/windows"
 )
 
 func GetOwnerMode(fInfo os.FileInfo) (os.FileMode, int, int) {
 	return fInfo.Mode(), -1, -1
 }
+
+// Utsname holds the same info as on Linux, as strings.
+type Utsname struct {
+	Sysname    string
+	Nodename   string
+	Release    string
+	Version    string
+	Machine    string
+	Domainname string
+}
+
+// Uname returns the Windows version in the same form as on Linux.
+func Uname() (*Utsname, error) {
+	info := windows.RtlGetVersion()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	machine := runtime.GOARCH
+	switch runtime.GOARCH {
+	case "amd64":
+		machine = "x86_64"
+	case "arm64":
+		machine = "aarch64"
+	}
+
+	return &Utsname{
+		Sysname:  "Windows",
+		Nodename: hostname,
+		Release:  fmt.Sprintf("%d.%d.%d", info.MajorVersion, info.MinorVersion, info.BuildNumber),
+		Version:  windows.UTF16ToString(info.CsdVersion[:]),
+		Machine:  machine,
+	}, nil
+}
@@ -141,6 +141,16 @@ func IsSize(value string) error {
 	return nil
 }
 
+// IsNetworkRate validates whether the value is a network bit rate (such as 100Mbit).
+func IsNetworkRate(value string) error {
+	_, err := units.ParseBitSizeString(value)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // IsDeviceID validates string is four lowercase hex characters suitable as Vendor or Device ID.
 func IsDeviceID(value string) error {
 	regexHexLc, err := regexp.Compile("^[0-9a-f]+$")
@@ -489,6 +499,20 @@ func IsNetworkVLAN(value string) error {
 	return nil
 }
 
+// IsNetworkDSCP validates a DSCP value (0-63).
+func IsNetworkDSCP(value string) error {
+	dscp, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return fmt.Errorf("Invalid DSCP value %q", value)
+	}
+
+	if dscp > 63 {
+		return fmt.Errorf("Out of DSCP range (0-63) %q", value)
+	}
+
+	return nil
+}
+
 // IsNetworkMTU validates MTU number >= 1280 and <= 16384.
 // Anything below 68 and the kernel doesn't allow IPv4, anything below 1280 and the kernel doesn't allow IPv6.
 // So require an IPv6-compatible MTU as the low value and cap at the max ethernet jumbo frame size.
@@ -561,6 +585,34 @@ func IsURLSegmentSafe(value string) error {
 	return nil
 }
 
+// IsHostname validates whether a value is a valid DNS host name (dot separated labels of letters, digits and dashes).
+func IsHostname(value string) error {
+	if len(value) < 1 || len(value) > 253 {
+		return fmt.Errorf("Host name must be between 1 and 253 characters")
+	}
+
+	for _, label := range strings.Split(value, ".") {
+		match, _ := regexp.MatchString(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`, label)
+		if !match {
+			return fmt.Errorf("Invalid host name label %q", label)
+		}
+	}
+
+	return nil
+}
+
+// IsHostnameList validates a comma delimited list of DNS host names.
+func IsHostnameList(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		err := IsHostname(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // IsUUID validates whether a value is a UUID.
 func IsUUID(value string) error {
 	if uuid.Parse(value) == nil {
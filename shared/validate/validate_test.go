@@ -64,6 +64,33 @@ func ExampleIsPCIAddress() {
 	// , false
 }
 
+func ExampleIsHostname() {
+	tests := []string{
+		"web",               // valid
+		"web-1.example.com", // valid
+		"1web",              // valid
+		"-web",              // leading dash
+		"web-",              // trailing dash
+		"web_1",             // invalid character
+		"web..example",      // empty label
+		"",
+	}
+
+	for _, v := range tests {
+		err := validate.IsHostname(v)
+		fmt.Printf("%s, %t\n", v, err == nil)
+	}
+
+	// Output: web, true
+	// web-1.example.com, true
+	// 1web, true
+	// -web, false
+	// web-, false
+	// web_1, false
+	// web..example, false
+	// , false
+}
+
 func ExampleOptional() {
 	tests := []string{
 		"",
@@ -103,3 +130,28 @@ func ExampleRequired() {
 	// <nil> Invalid value for a boolean "foo"
 	// <nil> <nil>
 }
+
+func ExampleIsNetworkDSCP() {
+	tests := []string{
+		"0",
+		"46",
+		"63",
+		"64", // out of range
+		"-1", // negative
+		"ef", // class names aren't supported
+		"",
+	}
+
+	for _, v := range tests {
+		err := validate.IsNetworkDSCP(v)
+		fmt.Printf("%s, %t\n", v, err == nil)
+	}
+
+	// Output: 0, true
+	// 46, true
+	// 63, true
+	// 64, false
+	// -1, false
+	// ef, false
+	// , false
+}
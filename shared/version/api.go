@@ -1,8 +1,10 @@
 package version
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // APIVersion contains the API base version. Only bumped for backward incompatible changes.
@@ -279,6 +281,120 @@ var APIExtensions = []string{
 	"event_lifecycle_requestor_address",
 	"resources_gpu_usb",
 	"clustering_evacuation",
+	"resources_numa_topology",
+	"instances_cpu_rebalance",
+	"container_syscall_intercept_sched_setscheduler_sysinfo",
+	"instances_apparmor_extra",
+	"projects_idmap_isolated_ranges",
+	"instances_host_shutdown_action",
+	"storage_pool_lazy_mount",
+	"recover_profiles_networks",
+	"database_backup",
+	"api_pagination",
+	"etag_preconditions",
+	"api_filtering_operators",
+	"api_fields",
+	"proxy_protocol_v2",
+	"proxy_nat_nft",
+	"network_firewall",
+	"network_firewall_mode",
+	"nic_routed_routes",
+	"instance_state_network_link",
+	"network_nic_security_defaults",
+	"network_lease_events",
+	"instance_project_move",
+	"storage_volume_move_location",
+	"instance_files_recursive",
+	"instance_files_watch",
+	"instance_exec_resume",
+	"console_log_history",
+	"instance_nvram",
+	"instance_windows_agent",
+	"instance_refresh_block_delta",
+	"disk_nvme_passthrough",
+	"memory_pressure_policy",
+	"instance_cpu_model",
+	"instance_nesting_kvm",
+	"idmap_management",
+	"instance_device_hotplug_events",
+	"gpu_allocation",
+	"custom_volume_iso",
+	"tags",
+	"custom_volume_export",
+	"instance_nic_group",
+	"config_plan",
+	"clustering_failure_domains_config",
+	"storage_pool_member_config_update",
+	"storage_driver_nvme",
+	"storage_pool_usage_warnings",
+	"storage_zfs_properties",
+	"storage_btrfs_compressed_send",
+	"storage_ceph_namespace_per_project",
+	"storage_lvm_raid_cache",
+	"instance_disk_block_hotplug",
+	"image_delta_export",
+	"snapshot_schedule_jitter",
+	"operations_queues",
+	"devlxd_management",
+	"instance_nic_dns_names",
+	"network_ovn_gateway_chassis",
+	"clustering_certificate_rotation",
+	"server_acme",
+	"instance_nic_maas_address",
+	"network_ipam",
+	"project_environment",
+	"project_usage_metering",
+	"projects_limits_network",
+	"instance_protection_stop",
+	"instance_machine_type",
+	"instance_sev",
+	"instance_clone_hooks",
+	"network_acl_dscp",
+	"network_state_lldp",
+	"network_drift_detection",
+	"network_bridge_stp",
+	"network_nat64",
+	"storage_images_dedup",
+	"events_instance_logging",
+	"instance_processes",
+	"storage_pool_move_to_block",
+	"cluster_replica_staleness",
+	"clustering_upgrade",
+	"instance_backup_format",
+	"instance_conversion",
+	"config_deprecated_keys",
+	"authorization_webhook",
+}
+
+// ExtraAPIExtensions is a comma separated list of API extensions advertised on top of the upstream ones. It's meant
+// to be set at build time by downstream distributions, e.g. with -ldflags "-X .../shared/version.ExtraAPIExtensions=x,y".
+var ExtraAPIExtensions string
+
+func init() {
+	if ExtraAPIExtensions == "" {
+		return
+	}
+
+	RegisterAPIExtensions(strings.Split(ExtraAPIExtensions, ",")...)
+}
+
+// RegisterAPIExtensions adds API extensions of a downstream distribution after the upstream ones. It must only be
+// called at initialization time and panics on an empty or already registered extension.
+func RegisterAPIExtensions(names ...string) {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			panic("Empty API extension name")
+		}
+
+		for _, entry := range APIExtensions {
+			if entry == name {
+				panic(fmt.Sprintf("API extension %q is already registered", name))
+			}
+		}
+
+		APIExtensions = append(APIExtensions, name)
+	}
 }
 
 // APIExtensionsCount returns the number of available API extensions.